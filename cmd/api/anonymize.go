@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/anonymize"
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres"
+	"github.com/TopThisHat/stdlib-golang-api/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+func newAnonymizeCmd() *cobra.Command {
+	var (
+		sourceDSN string
+		key       string
+		jitter    float64
+		pageSize  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "anonymize",
+		Short: "Copy users and orders from another database with personal data scrambled",
+		Long: `Copy every user and order from a source database (usually production or a
+replica of it) into the configured Postgres, for refreshing staging with
+realistic data without handling real PII.
+
+Names and emails are replaced with fakes derived from a keyed hash of the
+originals, so the same key gives every person the same fake identity on each
+refresh; emails use the reserved example.com domain. Item prices are jittered
+by up to --jitter and order amounts recomputed. IDs, statuses and timestamps
+are kept. Rows copied by an earlier refresh are overwritten.
+
+The source DSN and key are read from ANONYMIZE_SOURCE_DSN and ANONYMIZE_KEY
+when the flags are not given, which keeps them out of shell history. Refuses to
+run when ENVIRONMENT is production, since the configured database is the one
+written to.`,
+		Example: "  ANONYMIZE_SOURCE_DSN=postgres://readonly@prod-replica/app ANONYMIZE_KEY=$(cat key) api anonymize",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sourceDSN == "" {
+				sourceDSN = os.Getenv("ANONYMIZE_SOURCE_DSN")
+			}
+			if key == "" {
+				key = os.Getenv("ANONYMIZE_KEY")
+			}
+			if sourceDSN == "" {
+				return fmt.Errorf("--source-dsn or ANONYMIZE_SOURCE_DSN is required")
+			}
+			if pageSize < 1 {
+				return fmt.Errorf("--page-size must be at least 1")
+			}
+			anonymizer, err := anonymize.New([]byte(key), jitter)
+			if err != nil {
+				return fmt.Errorf("%w (set --key or ANONYMIZE_KEY)", err)
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if cfg.IsProduction() {
+				return fmt.Errorf("refusing to write anonymized data into a production database (ENVIRONMENT=production)")
+			}
+			if sourceDSN == cfg.Postgres.DSN {
+				return fmt.Errorf("the source database is the configured database")
+			}
+			logg := newLogger(cfg)
+
+			sourceCfg := cfg.Postgres
+			sourceCfg.DSN = sourceDSN
+			source, err := postgres.NewPgxPool(sourceCfg, logg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to the source database: %w", err)
+			}
+			defer source.Close()
+			target, err := postgres.NewPgxPool(cfg.Postgres, logg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to postgres: %w", err)
+			}
+			defer target.Close()
+
+			start := time.Now()
+			res, err := anonymize.Copy(cmd.Context(),
+				anonymize.Store{Users: repository.NewUserRepo(source, logg), Orders: repository.NewOrderRepo(source, logg)},
+				anonymize.Store{Users: repository.NewUserRepo(target, logg), Orders: repository.NewOrderRepo(target, logg)},
+				anonymizer, pageSize, logg)
+			if err != nil {
+				return err
+			}
+			logg.Info("anonymized copy complete",
+				"users", res.Users,
+				"orders", res.Orders,
+				"duration", time.Since(start).Round(time.Millisecond),
+			)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&sourceDSN, "source-dsn", "", "Postgres DSN to copy from (default $ANONYMIZE_SOURCE_DSN)")
+	cmd.Flags().StringVar(&key, "key", "", "secret the fake identities are derived from, at least 16 bytes (default $ANONYMIZE_KEY)")
+	cmd.Flags().Float64Var(&jitter, "jitter", 0.1, "largest relative change applied to item prices")
+	cmd.Flags().IntVar(&pageSize, "page-size", 500, "rows read from the source at a time")
+	return cmd
+}
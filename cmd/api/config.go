@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Load and validate the configuration, reporting every violation",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "configuration valid (env %s)\n", cfg.Environment)
+			return nil
+		},
+	})
+	cmd.AddCommand(newConfigPrintCmd())
+	return cmd
+}
+
+func newConfigPrintCmd() *cobra.Command {
+	var redacted bool
+
+	cmd := &cobra.Command{
+		Use:   "print",
+		Short: "Print the effective configuration and where each value came from",
+		Long: "Print every setting after defaults, profiles (CONFIG_PROFILE_DIR), the\n" +
+			"environment and CONFIG_FILE are layered, in dotenv format with each value's\n" +
+			"source. Secrets are masked unless --redacted=false is given, which is only\n" +
+			"allowed in development and test.\n\n" +
+			"An invalid configuration is still printed, followed by its violations.",
+		Example: "  api config print\n" +
+			"  ENVIRONMENT=staging api config print | grep profile",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings, profiles, err := config.Effective()
+			if !redacted && !unredactedAllowed(settings) {
+				return fmt.Errorf("--redacted=false is only allowed in development and test")
+			}
+			printSettings(cmd.OutOrStdout(), settings, profiles, redacted)
+			if err != nil {
+				return &configError{err: err}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&redacted, "redacted", true, "mask secrets and passwords in connection strings")
+	return cmd
+}
+
+// unredactedAllowed reports whether the resolved environment may print secrets
+func unredactedAllowed(settings []config.Setting) bool {
+	for _, s := range settings {
+		if s.Key == "ENVIRONMENT" {
+			return s.Value == "development" || s.Value == "test"
+		}
+	}
+	return false
+}
+
+// printSettings writes settings as dotenv lines annotated with their source
+func printSettings(w io.Writer, settings []config.Setting, profiles []string, redacted bool) {
+	if len(profiles) == 0 {
+		fmt.Fprintln(w, "# profiles: none")
+	} else {
+		fmt.Fprintf(w, "# profiles: %s\n", strings.Join(profiles, ", "))
+	}
+
+	width := 0
+	lines := make([]string, len(settings))
+	for i, s := range settings {
+		value := s.Value
+		if redacted {
+			value = s.Redacted()
+		}
+		lines[i] = s.Key + "=" + quoteValue(value)
+		width = max(width, len(lines[i]))
+	}
+	for i, s := range settings {
+		fmt.Fprintf(w, "%-*s  # %s\n", width, lines[i], s.Source)
+	}
+}
+
+// quoteValue double-quotes values that wouldn't survive dotenv parsing as-is
+func quoteValue(value string) string {
+	if strings.ContainsAny(value, " \t\n\"'#\\") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// closedAddr returns a local address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// setTestEnv sets the environment of a valid configuration whose Postgres
+// and Redis are unreachable, without a .env file or profiles
+func setTestEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("ENV_FILE", "/dev/null")
+	t.Setenv("CONFIG_PROFILE_DIR", t.TempDir())
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ENVIRONMENT", "development")
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("POSTGRES_DSN", "postgres://user:pass@"+closedAddr(t)+"/testdb?sslmode=disable")
+	t.Setenv("POSTGRES_CONNECT_TIMEOUT", "2s")
+	t.Setenv("REDIS_ADDR", closedAddr(t))
+	t.Setenv("JWT_SECRET", "this-is-a-test-secret-key-with-32-chars-minimum")
+}
+
+// runCLI runs the api command with args, returning its exit code and output
+func runCLI(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestServe(t *testing.T) {
+	t.Run("invalid configuration", func(t *testing.T) {
+		setTestEnv(t)
+		t.Setenv("PORT", "http")
+		t.Setenv("JWT_SECRET", "short")
+
+		code, _, stderr := runCLI(t, "serve")
+		if code != 1 {
+			t.Fatalf("exit code = %d, want 1", code)
+		}
+		// Every violation is listed, not only the first
+		for _, want := range []string{"invalid configuration", "PORT", "JWT_SECRET"} {
+			if !strings.Contains(stderr, want) {
+				t.Errorf("stderr = %q, want it to mention %s", stderr, want)
+			}
+		}
+	})
+
+	t.Run("unreachable database", func(t *testing.T) {
+		setTestEnv(t)
+
+		code, _, stderr := runCLI(t, "serve")
+		if code != 1 || !strings.Contains(stderr, "failed to initialize application") {
+			t.Errorf("exit code = %d, stderr = %q; want 1 and the initialization failure", code, stderr)
+		}
+		if strings.Contains(stderr, "user:pass@") {
+			t.Errorf("stderr = %q leaks the database password", stderr)
+		}
+	})
+
+	t.Run("without a subcommand", func(t *testing.T) {
+		setTestEnv(t)
+		t.Setenv("PORT", "http")
+
+		// Bare api is api serve
+		if code, _, stderr := runCLI(t); code != 1 || !strings.Contains(stderr, "PORT") {
+			t.Errorf("exit code = %d, stderr = %q; want serve's configuration failure", code, stderr)
+		}
+	})
+
+	t.Run("arguments", func(t *testing.T) {
+		if code, _, stderr := runCLI(t, "serve", "now"); code != 1 || !strings.Contains(stderr, "unknown command") {
+			t.Errorf("exit code = %d, stderr = %q; want 1 for an argument", code, stderr)
+		}
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("unreachable database", func(t *testing.T) {
+		for _, args := range [][]string{{"migrate"}, {"migrate", "up"}, {"migrate", "status"}, {"migrate", "down"}} {
+			setTestEnv(t)
+			code, stdout, stderr := runCLI(t, args...)
+			if code != 1 || !strings.Contains(stderr, "failed to connect to postgres") {
+				t.Errorf("%v: exit code = %d, stderr = %q; want 1 and the connection failure", args, code, stderr)
+			}
+			if stdout != "" {
+				t.Errorf("%v: stdout = %q, want nothing", args, stdout)
+			}
+		}
+	})
+
+	t.Run("invalid steps", func(t *testing.T) {
+		code, _, stderr := runCLI(t, "migrate", "down", "--steps", "0")
+		if code != 1 || !strings.Contains(stderr, "--steps must be positive") {
+			t.Errorf("exit code = %d, stderr = %q; want 1 and the steps error", code, stderr)
+		}
+	})
+
+	t.Run("unknown subcommand", func(t *testing.T) {
+		if code, _, _ := runCLI(t, "migrate", "sideways"); code != 1 {
+			t.Errorf("exit code = %d, want 1", code)
+		}
+	})
+}
+
+func TestHealthcheck(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" || !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("ready", func(t *testing.T) {
+		code, stdout, stderr := runCLI(t, "healthcheck", "--url", srv.URL+"/ready")
+		if code != 0 || stdout != "ok\n" {
+			t.Errorf("exit code = %d, stdout = %q, stderr = %q; want 0 and ok", code, stdout, stderr)
+		}
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		ready.Store(false)
+		defer ready.Store(true)
+		code, stdout, stderr := runCLI(t, "healthcheck", "--url", srv.URL+"/ready")
+		if code != 1 || stdout != "" || !strings.Contains(stderr, "503") {
+			t.Errorf("exit code = %d, stdout = %q, stderr = %q; want 1 and the 503", code, stdout, stderr)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		code, _, stderr := runCLI(t, "healthcheck", "--url", "http://"+closedAddr(t)+"/ready", "--timeout", "2s")
+		if code != 1 || !strings.Contains(stderr, "healthcheck failed") {
+			t.Errorf("exit code = %d, stderr = %q; want 1", code, stderr)
+		}
+	})
+
+	t.Run("url from the configuration", func(t *testing.T) {
+		setTestEnv(t)
+		t.Setenv("PORT", srv.URL[strings.LastIndex(srv.URL, ":")+1:])
+		code, stdout, stderr := runCLI(t, "healthcheck")
+		if code != 0 || stdout != "ok\n" {
+			t.Errorf("exit code = %d, stdout = %q, stderr = %q; want 0 probing localhost:PORT", code, stdout, stderr)
+		}
+	})
+
+	t.Run("url and direct together", func(t *testing.T) {
+		code, _, stderr := runCLI(t, "healthcheck", "--url", srv.URL, "--direct")
+		if code != 1 || !strings.Contains(stderr, "none of the others can be") {
+			t.Errorf("exit code = %d, stderr = %q; want 1 for exclusive flags", code, stderr)
+		}
+	})
+}
+
+func TestHealthcheckDirect(t *testing.T) {
+	t.Run("every dependency unreachable", func(t *testing.T) {
+		setTestEnv(t)
+		code, stdout, stderr := runCLI(t, "healthcheck", "--direct", "--timeout", "2s")
+		if code != 1 || !strings.Contains(stderr, "postgres, redis unreachable") {
+			t.Errorf("exit code = %d, stderr = %q; want 1 with both unreachable", code, stderr)
+		}
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "postgres: ") || !strings.HasPrefix(lines[1], "redis: ") {
+			t.Errorf("stdout = %q, want a line per dependency", stdout)
+		}
+	})
+
+	t.Run("redis reachable", func(t *testing.T) {
+		setTestEnv(t)
+		t.Setenv("REDIS_ADDR", fakeRedis(t))
+		code, stdout, stderr := runCLI(t, "healthcheck", "--direct", "--timeout", "2s")
+		if code != 1 || !strings.Contains(stderr, "postgres unreachable") || strings.Contains(stderr, "redis unreachable") {
+			t.Errorf("exit code = %d, stderr = %q; want 1 with postgres alone unreachable", code, stderr)
+		}
+		if !strings.Contains(stdout, "redis: ok\n") {
+			t.Errorf("stdout = %q, want redis: ok", stdout)
+		}
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		setTestEnv(t)
+		t.Setenv("POSTGRES_DSN", "")
+		code, stdout, stderr := runCLI(t, "healthcheck", "--direct")
+		if code != 1 || !strings.Contains(stderr, "POSTGRES_DSN") || stdout != "" {
+			t.Errorf("exit code = %d, stdout = %q, stderr = %q; want 1 before probing", code, stdout, stderr)
+		}
+	})
+}
+
+// fakeRedis serves enough of the Redis protocol for a client to connect
+// and PING, returning its address
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					command, err := readRedisCommand(r)
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(command[0]) {
+					case "HELLO":
+						// Clients fall back to RESP2
+						conn.Write([]byte("-ERR unknown command 'HELLO'\r\n"))
+					case "PING":
+						conn.Write([]byte("+PONG\r\n"))
+					default:
+						conn.Write([]byte("+OK\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// readRedisCommand reads one command, an array of bulk strings
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, strconv.ErrSyntax
+	}
+	command := make([]string, n)
+	for i := range command {
+		if _, err := r.ReadString('\n'); err != nil { // $<length>
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		command[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return command, nil
+}
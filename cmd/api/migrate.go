@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres"
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres/migrations"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply every pending migration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrator(func(m *migrations.Migrator) error {
+				applied, err := m.Up(cmd.Context())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "applied %d migration(s)\n", applied)
+				return nil
+			})
+		},
+	}
+
+	var steps int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Roll back the most recently applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if steps <= 0 {
+				return fmt.Errorf("--steps must be positive")
+			}
+			return withMigrator(func(m *migrations.Migrator) error {
+				rolledBack, err := m.Down(cmd.Context(), steps)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "rolled back %d migration(s)\n", rolledBack)
+				return nil
+			})
+		},
+	}
+	down.Flags().IntVar(&steps, "steps", 1, "number of migrations to roll back")
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "List migrations and whether they have been applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrator(func(m *migrations.Migrator) error {
+				statuses, err := m.Status(cmd.Context())
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+				for _, s := range statuses {
+					applied := "pending"
+					if s.AppliedAt != nil {
+						applied = s.AppliedAt.Format(time.RFC3339)
+					}
+					fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+				}
+				return w.Flush()
+			})
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema (bare migrate applies pending migrations)",
+		Args:  cobra.NoArgs,
+		RunE:  up.RunE,
+	}
+	cmd.AddCommand(up, down, status)
+	return cmd
+}
+
+// withMigrator connects to Postgres and runs fn with a migrator for the
+// embedded migrations
+func withMigrator(fn func(m *migrations.Migrator) error) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	logg := newLogger(cfg)
+
+	pool, err := postgres.NewPgxPool(cfg.Postgres, logg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer pool.Close()
+
+	migrator, err := migrations.New(pool, logg)
+	if err != nil {
+		return err
+	}
+	return fn(migrator)
+}
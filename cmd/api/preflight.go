@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/app"
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres"
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres/migrations"
+	"github.com/TopThisHat/stdlib-golang-api/internal/redis"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+)
+
+// Preflight check outcomes
+const (
+	checkPass = "pass"
+	checkWarn = "warn" // Deployable, but worth a look (fails with --strict)
+	checkFail = "fail"
+	checkSkip = "skip" // Not applicable to this configuration
+)
+
+// preflightCheck is one entry of the preflight report
+type preflightCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// preflightReport is the machine-readable result of api preflight
+type preflightReport struct {
+	Status      string           `json:"status"` // fail if any check failed, else warn or pass
+	Environment string           `json:"environment,omitempty"`
+	Version     string           `json:"version,omitempty"`
+	CheckedAt   time.Time        `json:"checked_at"`
+	Checks      []preflightCheck `json:"checks"`
+}
+
+func newPreflightCmd() *cobra.Command {
+	var (
+		format  string
+		timeout time.Duration
+		strict  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Verify configuration and dependencies before a deploy",
+		Long: "Verify that a release can start with the current configuration: the\n" +
+			"configuration is valid, Postgres is reachable with no pending migrations,\n" +
+			"Redis answers, the blob store (BLOB_BACKEND) accepts writes (a probe object\n" +
+			"under preflight/ is written and deleted), and JWT secrets are strong.\n\n" +
+			"Prints a report (text or JSON) and exits non-zero if any check fails, or\n" +
+			"with --strict if any check warns.",
+		Example: "  api preflight\n" +
+			"  api preflight --format json --timeout 1m --strict",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("--format must be text or json")
+			}
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			report := runPreflight(ctx, cmd.ErrOrStderr())
+			if err := writePreflightReport(cmd.OutOrStdout(), report, format); err != nil {
+				return err
+			}
+
+			var failed []string
+			for _, c := range report.Checks {
+				if c.Status == checkFail || (strict && c.Status == checkWarn) {
+					failed = append(failed, c.Name)
+				}
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("preflight failed: %s", strings.Join(failed, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "text", "report format: text or json")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "overall timeout for every check")
+	cmd.Flags().BoolVar(&strict, "strict", false, "fail on warnings too")
+	return cmd
+}
+
+// runPreflight loads the configuration and runs every check against it.
+// Library logs go to logOut so they never mix with the report.
+func runPreflight(ctx context.Context, logOut io.Writer) *preflightReport {
+	report := &preflightReport{CheckedAt: time.Now().UTC()}
+	record := func(name string, fn func() (string, string)) {
+		start := time.Now()
+		status, detail := fn()
+		report.Checks = append(report.Checks, preflightCheck{
+			Name:       name,
+			Status:     status,
+			Detail:     detail,
+			DurationMS: time.Since(start).Milliseconds(),
+		})
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		record("config", func() (string, string) {
+			return checkFail, strings.Join(config.Violations(err), "; ")
+		})
+		report.Status = checkFail
+		return report
+	}
+	report.Environment = cfg.Environment
+	report.Version = cfg.Version
+	record("config", func() (string, string) { return checkPass, "" })
+
+	logg := logger.NewWithOptions("error", logOut, true)
+
+	var pool *pgxpool.Pool
+	record("postgres", func() (string, string) {
+		if cfg.InMemory() {
+			return checkSkip, "running in memory (DEV_INMEMORY or RUN_MODE=standalone)"
+		}
+		pool, err = postgres.NewPgxPool(cfg.Postgres, logg)
+		if err != nil {
+			return checkFail, err.Error()
+		}
+		return checkPass, ""
+	})
+	if pool != nil {
+		defer pool.Close()
+	}
+
+	record("migrations", func() (string, string) { return checkMigrations(ctx, pool, logg) })
+	record("redis", func() (string, string) { return checkRedis(ctx, cfg) })
+	record("blob", func() (string, string) { return checkBlobStore(ctx, cfg, logg) })
+	record("jwt", func() (string, string) { return checkJWTKeys(cfg) })
+
+	report.Status = checkPass
+	for _, c := range report.Checks {
+		switch c.Status {
+		case checkFail:
+			report.Status = checkFail
+		case checkWarn:
+			if report.Status == checkPass {
+				report.Status = checkWarn
+			}
+		}
+	}
+	return report
+}
+
+// checkMigrations reports embedded migrations not yet applied to the database
+func checkMigrations(ctx context.Context, pool *pgxpool.Pool, logg *logger.Logger) (string, string) {
+	if pool == nil {
+		return checkSkip, "postgres unavailable"
+	}
+	migrator, err := migrations.New(pool, logg)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+
+	var pending []string
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%04d_%s", s.Version, s.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return checkWarn, fmt.Sprintf("%d pending (run `api migrate`): %s", len(pending), strings.Join(pending, ", "))
+	}
+	return checkPass, fmt.Sprintf("%d applied", len(statuses))
+}
+
+// checkRedis pings Redis
+func checkRedis(ctx context.Context, cfg *config.Config) (string, string) {
+	if cfg.InMemory() {
+		return checkSkip, "running in memory (DEV_INMEMORY or RUN_MODE=standalone)"
+	}
+	opts := redis.Options(cfg.Redis)
+	opts.MaxRetries = -1
+	client := goredis.NewClient(opts)
+	defer client.Close()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return checkFail, err.Error()
+	}
+	return checkPass, ""
+}
+
+// checkBlobStore verifies the BLOB_BACKEND store exists and the credentials
+// can list, write and delete objects in it
+func checkBlobStore(ctx context.Context, cfg *config.Config, logg *logger.Logger) (string, string) {
+	switch {
+	case cfg.Blob.Backend == config.BlobBackendMemory:
+		return checkSkip, "BLOB_BACKEND=memory keeps blobs in process"
+	case (cfg.Blob.Backend == "" || cfg.Blob.Backend == config.BlobBackendS3) && cfg.AWS.S3Bucket == "":
+		return checkSkip, "S3_BUCKET is not set"
+	}
+	store, location, err := app.NewBlobStore(ctx, cfg, logg)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+
+	if _, err := store.List(ctx, &blob.ListInput{MaxKeys: 1}); err != nil {
+		return checkFail, fmt.Sprintf("list %s: %v", location, err)
+	}
+	key := "preflight/" + uuid.New().String()
+	if _, err := store.Upload(ctx, &blob.UploadInput{Key: key, Body: bytes.NewReader([]byte("preflight")), ContentType: "text/plain"}); err != nil {
+		return checkFail, fmt.Sprintf("write to %s: %v", location, err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return checkFail, fmt.Sprintf("delete from %s (probe %s left behind): %v", location, key, err)
+	}
+	return checkPass, cfg.Blob.Backend + " " + location
+}
+
+// checkJWTKeys audits the signing and encryption secrets. Weak secrets fail
+// in staging and production and only warn elsewhere.
+func checkJWTKeys(cfg *config.Config) (string, string) {
+	keys, err := auth.LoadKeySet(cfg.Auth.JWTSecret, cfg.Auth.JWTKeysFile)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	problems := keys.Audit(time.Now())
+	if len(problems) == 0 {
+		return checkPass, ""
+	}
+
+	status := checkWarn
+	if cfg.IsProduction() || cfg.Environment == "staging" {
+		status = checkFail
+	}
+	return status, errors.Join(problems...).Error()
+}
+
+// writePreflightReport prints report as JSON or an aligned table
+func writePreflightReport(w io.Writer, report *preflightReport, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDURATION\tDETAIL")
+	for _, c := range report.Checks {
+		// Driver errors span lines; keep one row per check
+		detail := strings.Join(strings.Fields(c.Detail), " ")
+		fmt.Fprintf(tw, "%s\t%s\t%dms\t%s\n", c.Name, c.Status, c.DurationMS, detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "preflight %s\n", report.Status)
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// newRootCmd builds the api command tree. Running api without a subcommand
+// is the same as api serve, so existing deployments keep working.
+func newRootCmd() *cobra.Command {
+	serve := newServeCmd()
+
+	root := &cobra.Command{
+		Use:   "api",
+		Short: "Users and orders HTTP API",
+		Long: "Users and orders HTTP API.\n\n" +
+			"Every command reads the same environment variables (and .env file in\n" +
+			"development), so operational tasks run from the same binary and image.",
+		RunE:          serve.RunE,
+		SilenceUsage:  true, // Usage is printed for flag errors only
+		SilenceErrors: true, // main reports errors
+	}
+	root.AddCommand(
+		serve,
+		newMigrateCmd(),
+		newSeedCmd(),
+		newHealthcheckCmd(),
+		newConfigCmd(),
+		newPreflightCmd(),
+		newAnonymizeCmd(),
+		newTokenCmd(),
+	)
+	return root
+}
+
+// configError marks a configuration load failure so main can list each violation
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+func (e *configError) Unwrap() error { return e.err }
+
+func isConfigError(err error) bool {
+	var cerr *configError
+	return errors.As(err, &cerr)
+}
+
+// loadConfig loads and validates the configuration shared by every command
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, &configError{err: err}
+	}
+	return cfg, nil
+}
+
+// newLogger builds the application logger for cfg
+func newLogger(cfg *config.Config) *logger.Logger {
+	return logger.New(cfg.LogLevel)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/fixtures"
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres"
+	"github.com/TopThisHat/stdlib-golang-api/internal/redis"
+	"github.com/TopThisHat/stdlib-golang-api/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+func newSeedCmd() *cobra.Command {
+	var file string
+	var warmCache bool
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Load users and orders from a fixture file (users that already exist are skipped)",
+		Long: `Load users and orders from a YAML or JSON fixture file into Postgres.
+Without --file the built-in demo data is loaded. With --warm-cache every
+fixture user and order is also written to the Redis cache.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := fixtures.Default()
+			if file != "" {
+				f, err = fixtures.LoadFile(file)
+			}
+			if err != nil {
+				return err
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			logg := newLogger(cfg)
+
+			pool, err := postgres.NewPgxPool(cfg.Postgres, logg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to postgres: %w", err)
+			}
+			defer pool.Close()
+
+			target := fixtures.Target{
+				Users:  repository.NewUserRepo(pool, logg),
+				Orders: repository.NewOrderRepo(pool, logg),
+			}
+			if warmCache {
+				client := redis.NewRedisClient(cfg.Redis)
+				defer client.Close()
+				if err := client.Ping(cmd.Context()).Err(); err != nil {
+					return fmt.Errorf("failed to connect to redis: %w", err)
+				}
+				target.UserCache = redis.NewUserCache(client)
+				target.OrderCache = redis.NewOrderCache(client)
+			}
+
+			res, err := fixtures.Apply(cmd.Context(), f, target, logg)
+			if err != nil {
+				return err
+			}
+			logg.Info("seed complete",
+				"users_created", res.UsersCreated,
+				"users_skipped", res.UsersSkipped,
+				"orders_created", res.OrdersCreated,
+				"cache_warmed", res.CacheWarmed,
+			)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "fixture file (.yaml, .yml or .json); defaults to the built-in demo data")
+	cmd.Flags().BoolVar(&warmCache, "warm-cache", false, "also write seeded users and orders to the Redis cache")
+	return cmd
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP API until SIGINT/SIGTERM, then shut down gracefully",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// ═══════════════════════════════════════════════
+			// Phase 1: Load Configuration
+			// ═══════════════════════════════════════════════
+			// Read from environment, validate, fail fast if anything’s missing
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			// ═══════════════════════════════════════════════
+			// Phase 2: Setup Observability
+			// ═══════════════════════════════════════════════
+			// Get logging working BEFORE everything else—you’ll need it
+			logg := newLogger(cfg)
+			logg.Info("starting application", "version", cfg.Version, "env", cfg.Environment)
+
+			// ═══════════════════════════════════════════════
+			// Phase 3: Build the Application (see internal/app)
+			// ═══════════════════════════════════════════════
+			api, err := app.New(cfg, app.WithLogger(logg))
+			if err != nil {
+				return fmt.Errorf("failed to initialize application: %w", err)
+			}
+
+			// ═══════════════════════════════════════════════
+			// Phase 4: Serve until the context is cancelled, then shut down gracefully
+			// ═══════════════════════════════════════════════
+			return api.Run(cmd.Context())
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+func newTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Issue access tokens",
+	}
+	cmd.AddCommand(newTokenIssueCmd())
+	return cmd
+}
+
+func newTokenIssueCmd() *cobra.Command {
+	var (
+		subject string
+		scopes  []string
+		ttl     time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "issue",
+		Short: "Sign a session token (JWT) with the configured keys and print it",
+		Long: `Sign a session token (JWT) for a user with the configured signing keys
+(JWT_SECRET and JWT_KEYS_FILE) and print it, for bootstrapping access to a
+deployment. A token with the admin scope manages every user's personal access
+tokens (POST /api/users/{id}/tokens); browsers exchange a token for the
+session cookie with POST /api/auth/session.
+
+Tokens last JWT_EXPIRATION_HOURS unless a shorter --ttl is given, and are
+revoked like any other (POST /api/auth/logout, POST
+/api/admin/users/{id}/revoke-tokens). Revoking every token of a user is
+remembered for AUTH_REVOCATION_RETENTION, which is only guaranteed to cover
+JWT_EXPIRATION_HOURS, so --ttl cannot exceed it.`,
+		Example: "  api token issue --subject ops-admin --scope admin --ttl 1h\n" +
+			"  curl -H \"Authorization: Bearer $(api token issue --subject user-1)\" https://api.example.com/api/users/user-1",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			subject = strings.TrimSpace(subject)
+			if subject == "" {
+				return fmt.Errorf("--subject is required")
+			}
+			if ttl < 0 {
+				return fmt.Errorf("--ttl must not be negative")
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			keys, err := auth.LoadKeySet(cfg.Auth.JWTSecret, cfg.Auth.JWTKeysFile)
+			if err != nil {
+				return fmt.Errorf("failed to load jwt keys: %w", err)
+			}
+			if ttl == 0 {
+				ttl = cfg.Auth.TokenTTL()
+			} else if ttl > cfg.Auth.TokenTTL() {
+				return fmt.Errorf("--ttl must not exceed JWT_EXPIRATION_HOURS (%s)", cfg.Auth.TokenTTL())
+			}
+			tokens, err := auth.NewTokenManager(keys, ttl, auth.WithPIIEncryption(cfg.Auth.JWTEncryptPII))
+			if err != nil {
+				return fmt.Errorf("failed to configure jwt: %w", err)
+			}
+
+			token, err := tokens.Issue(auth.Claims{Subject: subject, Scope: strings.Join(scopes, " ")})
+			if err != nil {
+				return fmt.Errorf("failed to issue token: %w", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), token)
+			fmt.Fprintf(cmd.ErrOrStderr(), "token for %s expires at %s\n", subject, time.Now().Add(ttl).UTC().Format(time.RFC3339))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&subject, "subject", "", "user ID the token authenticates as")
+	cmd.Flags().StringSliceVar(&scopes, "scope", nil, "scope to grant, e.g. admin (repeatable)")
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "token lifetime, up to and by default JWT_EXPIRATION_HOURS")
+	return cmd
+}
@@ -0,0 +1,320 @@
+// Package alerting pages operators when an instance starts failing: it
+// counts the server errors and panics of API requests over a sliding window
+// and, when either crosses its threshold, posts an alert with samples of
+// the failed requests to Slack or PagerDuty (see Sink).
+//
+// A rule that fired is not sent again before its cooldown, so a flapping
+// error rate does not flood the channel; a rule still breaching after its
+// cooldown is sent again as a reminder, and a resolved alert is sent once
+// the rule stops breaching. Counts are per instance: every instance alerts
+// on its own failures, under its own source.
+//
+// Example:
+//
+//	monitor := alerting.NewMonitor(alerting.DefaultConfig(), logg,
+//		alerting.NewSlackSink(alerting.SlackConfig{WebhookURL: url}))
+//	go monitor.Run(ctx)
+//	monitor.Observe(alerting.Sample{Route: "GET /api/orders/{id}", Status: 500})
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Alert rules
+const (
+	RuleErrorRate = "error_rate" // Share of requests answered with a server error
+	RulePanics    = "panics"     // Requests whose handler panicked
+)
+
+// Alert severities, as PagerDuty names them
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+)
+
+// windowSlots is the number of buckets a window is counted in
+const windowSlots = 60
+
+// sendTimeout bounds the delivery of one alert to one sink
+const sendTimeout = 10 * time.Second
+
+// Config configures a Monitor
+type Config struct {
+	Window        time.Duration // Failures are counted over the last Window
+	ErrorRate     float64       // Share of server errors (0..1) that fires RuleErrorRate; 0 disables it
+	MinRequests   int64         // Windows with fewer requests never fire RuleErrorRate
+	Panics        int64         // Panics in a window that fire RulePanics; 0 disables it
+	Cooldown      time.Duration // Least time between two alerts of one rule
+	CheckInterval time.Duration // How often the rules are evaluated
+	Samples       int           // Failed requests sent with each alert, most recent first
+	Source        string        // Instance the alerts come from; empty for the hostname
+}
+
+// DefaultConfig returns sensible defaults: a page when 5% of the requests
+// of the last five minutes failed, or on the first panic
+func DefaultConfig() Config {
+	return Config{
+		Window:        5 * time.Minute,
+		ErrorRate:     0.05,
+		MinRequests:   50,
+		Panics:        1,
+		Cooldown:      15 * time.Minute,
+		CheckInterval: 15 * time.Second,
+		Samples:       5,
+	}
+}
+
+// Sample is one API request, as counted and sent with alerts
+type Sample struct {
+	Time      time.Time
+	Method    string
+	Route     string // Route pattern, such as "GET /api/orders/{id}"
+	Path      string // Without the query, which may carry personal data
+	Status    int
+	Duration  time.Duration
+	RequestID string
+	Panic     string // The scrubbed panic value, for requests that panicked
+}
+
+// failed reports whether s counts as a failure
+func (s Sample) failed() bool {
+	return s.Status >= 500 || s.Panic != ""
+}
+
+// Alert is a rule that started or stopped breaching
+type Alert struct {
+	Rule     string
+	Severity string
+	Resolved bool   // The rule stopped breaching
+	Summary  string // One line, for titles and notifications
+	Source   string
+	Since    time.Time // When the rule started breaching
+	Window   time.Duration
+	Requests int64 // Counted over Window
+	Errors   int64
+	Panics   int64
+	Samples  []Sample // Failed requests of the window, most recent first; none once resolved
+}
+
+// Sink delivers alerts
+type Sink interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// Monitor counts API requests and sends alerts when the failures cross the
+// configured thresholds. It is safe for concurrent use.
+type Monitor struct {
+	cfg   Config
+	sinks []Sink
+	logg  *logger.Logger
+	now   func() time.Time
+	width time.Duration // Of one slot
+
+	mu      sync.Mutex
+	slots   [windowSlots]slot
+	samples []Sample // Ring of the last cfg.Samples failures
+	next    int      // Index of the next sample to overwrite
+	rules   map[string]*ruleState
+}
+
+// slot counts the requests of one window slot
+type slot struct {
+	index    int64 // Slot number since the epoch; stale slots are reset
+	requests int64
+	errors   int64
+	panics   int64
+}
+
+// ruleState is where a rule stands
+type ruleState struct {
+	firing   bool
+	since    time.Time
+	lastSent time.Time
+}
+
+// NewMonitor creates a monitor sending alerts to sinks (none only logs them)
+func NewMonitor(cfg Config, logg *logger.Logger, sinks ...Sink) *Monitor {
+	def := DefaultConfig()
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = def.CheckInterval
+	}
+	if cfg.Source == "" {
+		cfg.Source, _ = os.Hostname()
+	}
+	return &Monitor{
+		cfg:   cfg,
+		sinks: sinks,
+		logg:  logg,
+		now:   time.Now,
+		width: max(cfg.Window/windowSlots, time.Millisecond),
+		rules: map[string]*ruleState{RuleErrorRate: {}, RulePanics: {}},
+	}
+}
+
+// Observe counts the request s
+func (m *Monitor) Observe(s Sample) {
+	if s.Time.IsZero() {
+		s.Time = m.now()
+	}
+	index := s.Time.UnixNano() / int64(m.width)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sl := &m.slots[index%windowSlots]
+	if sl.index != index {
+		*sl = slot{index: index}
+	}
+	sl.requests++
+	if !s.failed() {
+		return
+	}
+	if s.Panic != "" {
+		sl.panics++
+	} else {
+		sl.errors++
+	}
+	if m.cfg.Samples > 0 {
+		if len(m.samples) < m.cfg.Samples {
+			m.samples = append(m.samples, s)
+		} else {
+			m.samples[m.next] = s
+		}
+		m.next = (m.next + 1) % m.cfg.Samples
+	}
+}
+
+// counts sums the slots of the window ending at now. Requires m.mu.
+func (m *Monitor) counts(now time.Time) (requests, errs, panics int64) {
+	current := now.UnixNano() / int64(m.width)
+	for _, sl := range m.slots {
+		if sl.index > current-windowSlots && sl.index <= current {
+			requests += sl.requests
+			errs += sl.errors
+			panics += sl.panics
+		}
+	}
+	return requests, errs, panics
+}
+
+// recentSamples returns the samples of the window ending at now, most
+// recent first. Requires m.mu.
+func (m *Monitor) recentSamples(now time.Time) []Sample {
+	var recent []Sample
+	for i := range len(m.samples) {
+		s := m.samples[(m.next-1-i+2*len(m.samples))%len(m.samples)]
+		if now.Sub(s.Time) <= m.cfg.Window {
+			recent = append(recent, s)
+		}
+	}
+	return recent
+}
+
+// Run evaluates the rules every CheckInterval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check evaluates the rules now and sends the alerts due, returning them
+func (m *Monitor) Check(ctx context.Context) []Alert {
+	now := m.now()
+
+	m.mu.Lock()
+	requests, errs, panics := m.counts(now)
+	breaching := map[string]bool{
+		RuleErrorRate: m.cfg.ErrorRate > 0 && requests >= max(m.cfg.MinRequests, 1) &&
+			float64(errs+panics)/float64(requests) >= m.cfg.ErrorRate,
+		RulePanics: m.cfg.Panics > 0 && panics >= m.cfg.Panics,
+	}
+	var alerts []Alert
+	for _, rule := range []string{RulePanics, RuleErrorRate} {
+		state := m.rules[rule]
+		a := Alert{
+			Rule:     rule,
+			Severity: SeverityError,
+			Source:   m.cfg.Source,
+			Window:   m.cfg.Window,
+			Requests: requests,
+			Errors:   errs,
+			Panics:   panics,
+		}
+		if rule == RulePanics {
+			a.Severity = SeverityCritical
+		}
+		switch {
+		case breaching[rule] && now.Sub(state.lastSent) >= m.cfg.Cooldown:
+			if !state.firing {
+				state.firing, state.since = true, now
+			}
+			state.lastSent = now
+			a.Since, a.Samples = state.since, m.recentSamples(now)
+		case !breaching[rule] && state.firing:
+			state.firing = false
+			a.Since, a.Resolved = state.since, true
+		default:
+			continue
+		}
+		a.Summary = summary(a)
+		alerts = append(alerts, a)
+	}
+	m.mu.Unlock()
+
+	for _, a := range alerts {
+		m.send(ctx, a)
+	}
+	return alerts
+}
+
+// send logs a and delivers it to every sink
+func (m *Monitor) send(ctx context.Context, a Alert) {
+	if a.Resolved {
+		m.logg.Info("alert resolved", "rule", a.Rule, "since", a.Since)
+	} else {
+		m.logg.Error("alert firing", "rule", a.Rule, "summary", a.Summary, "requests", a.Requests, "errors", a.Errors, "panics", a.Panics)
+	}
+	var errs []error
+	for _, sink := range m.sinks {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		if err := sink.Send(sendCtx, a); err != nil {
+			errs = append(errs, err)
+		}
+		cancel()
+	}
+	if err := errors.Join(errs...); err != nil {
+		m.logg.Error("failed to send alert", "error", err, "rule", a.Rule)
+	}
+}
+
+// summary describes a in one line
+func summary(a Alert) string {
+	window := a.Window.String()
+	switch {
+	case a.Resolved && a.Rule == RulePanics:
+		return fmt.Sprintf("Resolved: no more panics on %s", a.Source)
+	case a.Resolved:
+		return fmt.Sprintf("Resolved: error rate back to normal on %s", a.Source)
+	case a.Rule == RulePanics:
+		return fmt.Sprintf("%d panics in %s on %s", a.Panics, window, a.Source)
+	}
+	rate := float64(a.Errors+a.Panics) / float64(a.Requests) * 100
+	return fmt.Sprintf("%.1f%% of requests failed in %s on %s (%d of %d)", rate, window, a.Source, a.Errors+a.Panics, a.Requests)
+}
@@ -0,0 +1,209 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+type fakeSink struct {
+	sent []Alert
+}
+
+func (f *fakeSink) Send(_ context.Context, a Alert) error {
+	f.sent = append(f.sent, a)
+	return nil
+}
+
+// newTestMonitor returns a monitor on a clock the test advances
+func newTestMonitor(cfg Config) (*Monitor, *fakeSink, *time.Time) {
+	sink := &fakeSink{}
+	cfg.Source = "api-1"
+	m := NewMonitor(cfg, logger.NewWithOptions("error", io.Discard, true), sink)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, sink, &now
+}
+
+func observe(m *Monitor, n int, status int) {
+	for range n {
+		m.Observe(Sample{Route: "GET /api/orders/{id}", Path: "/api/orders/1", Status: status})
+	}
+}
+
+func TestErrorRateRule(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Panics = 0
+	m, sink, now := newTestMonitor(cfg)
+	ctx := context.Background()
+
+	// Too few requests to judge a rate
+	observe(m, 10, http.StatusInternalServerError)
+	if alerts := m.Check(ctx); len(alerts) != 0 {
+		t.Fatalf("fired below MinRequests: %+v", alerts)
+	}
+
+	observe(m, 90, http.StatusOK)
+	alerts := m.Check(ctx)
+	if len(alerts) != 1 || alerts[0].Rule != RuleErrorRate || alerts[0].Resolved || alerts[0].Severity != SeverityError {
+		t.Fatalf("alerts = %+v, want error rate firing", alerts)
+	}
+	a := alerts[0]
+	if a.Requests != 100 || a.Errors != 10 || len(a.Samples) != cfg.Samples || a.Source != "api-1" {
+		t.Errorf("alert = %+v", a)
+	}
+	if !strings.Contains(a.Summary, "10.0% of requests failed") {
+		t.Errorf("summary = %q", a.Summary)
+	}
+
+	// Still breaching: silent until the cooldown, then a reminder
+	*now = now.Add(time.Minute)
+	if alerts := m.Check(ctx); len(alerts) != 0 {
+		t.Errorf("sent during cooldown: %+v", alerts)
+	}
+	*now = now.Add(cfg.Cooldown)
+	observe(m, 10, http.StatusBadGateway)
+	observe(m, 90, http.StatusOK)
+	if alerts := m.Check(ctx); len(alerts) != 1 || alerts[0].Resolved || !alerts[0].Since.Equal(a.Since) {
+		t.Errorf("alerts = %+v, want a reminder", alerts)
+	}
+
+	// The failures leave the window
+	*now = now.Add(cfg.Window + time.Second)
+	alerts = m.Check(ctx)
+	if len(alerts) != 1 || !alerts[0].Resolved || alerts[0].Requests != 0 || len(alerts[0].Samples) != 0 {
+		t.Fatalf("alerts = %+v, want resolved", alerts)
+	}
+	if len(sink.sent) != 3 {
+		t.Errorf("sink got %d alerts, want 3", len(sink.sent))
+	}
+	if alerts := m.Check(ctx); len(alerts) != 0 {
+		t.Errorf("resolved twice: %+v", alerts)
+	}
+}
+
+func TestPanicsRule(t *testing.T) {
+	m, _, _ := newTestMonitor(DefaultConfig())
+	m.Observe(Sample{Route: "POST /api/orders", Status: http.StatusInternalServerError, RequestID: "r-1"})
+	m.Observe(Sample{Route: "POST /api/orders", Status: http.StatusInternalServerError, RequestID: "r-2", Panic: "nil map"})
+
+	alerts := m.Check(context.Background())
+	if len(alerts) != 1 || alerts[0].Rule != RulePanics || alerts[0].Severity != SeverityCritical {
+		t.Fatalf("alerts = %+v, want panics firing", alerts)
+	}
+	a := alerts[0]
+	if a.Panics != 1 || a.Errors != 1 || a.Summary != "1 panics in 5m0s on api-1" {
+		t.Errorf("alert = %+v", a)
+	}
+	// Most recent first
+	if len(a.Samples) != 2 || a.Samples[0].RequestID != "r-2" || a.Samples[1].RequestID != "r-1" {
+		t.Errorf("samples = %+v", a.Samples)
+	}
+}
+
+func TestSamplesKeepTheMostRecent(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Samples = 3
+	m, _, now := newTestMonitor(cfg)
+	for i := range 5 {
+		*now = now.Add(time.Second)
+		m.Observe(Sample{Status: http.StatusInternalServerError, RequestID: string(rune('a' + i))})
+	}
+	m.mu.Lock()
+	samples := m.recentSamples(*now)
+	m.mu.Unlock()
+	var ids []string
+	for _, s := range samples {
+		ids = append(ids, s.RequestID)
+	}
+	if strings.Join(ids, "") != "edc" {
+		t.Errorf("samples = %v, want e d c", ids)
+	}
+}
+
+var testAlert = Alert{
+	Rule:     RulePanics,
+	Severity: SeverityCritical,
+	Summary:  "1 panics in 5m0s on api-1",
+	Source:   "api-1",
+	Since:    time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	Window:   5 * time.Minute,
+	Requests: 20,
+	Panics:   1,
+	Samples: []Sample{{
+		Route: "POST /api/orders", Path: "/api/orders", Status: 500,
+		Duration: 42 * time.Millisecond, RequestID: "r-1", Panic: "index out of range <3>",
+	}},
+}
+
+// recorder serves a webhook, keeping the JSON bodies it receives
+func recorder(t *testing.T, status int) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func TestSlackSink(t *testing.T) {
+	srv, bodies := recorder(t, http.StatusOK)
+	if err := NewSlackSink(SlackConfig{WebhookURL: srv.URL}).Send(context.Background(), testAlert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	text, _ := (*bodies)[0]["text"].(string)
+	for _, want := range []string{"*1 panics in 5m0s on api-1*", "`panics` (critical)", "request `r-1`", "index out of range &lt;3&gt;", "42ms"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q lacks %q", text, want)
+		}
+	}
+
+	failing, _ := recorder(t, http.StatusForbidden)
+	if err := NewSlackSink(SlackConfig{WebhookURL: failing.URL}).Send(context.Background(), testAlert); err == nil {
+		t.Error("Send succeeded on HTTP 403")
+	}
+}
+
+func TestPagerDutySink(t *testing.T) {
+	srv, bodies := recorder(t, http.StatusAccepted)
+	pd := NewPagerDutySink(PagerDutyConfig{RoutingKey: "R0UT1NG", URL: srv.URL})
+	ctx := context.Background()
+	if err := pd.Send(ctx, testAlert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	resolved := testAlert
+	resolved.Resolved, resolved.Samples = true, nil
+	if err := pd.Send(ctx, resolved); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	trigger, resolve := (*bodies)[0], (*bodies)[1]
+	if trigger["routing_key"] != "R0UT1NG" || trigger["event_action"] != "trigger" || trigger["dedup_key"] != "api-1/panics" {
+		t.Errorf("trigger = %v", trigger)
+	}
+	payload, _ := trigger["payload"].(map[string]any)
+	details, _ := payload["custom_details"].(map[string]any)
+	samples, _ := details["samples"].([]any)
+	if payload["severity"] != "critical" || payload["source"] != "api-1" || len(samples) != 1 {
+		t.Errorf("payload = %v", payload)
+	}
+	if sample, _ := samples[0].(map[string]any); sample["duration_ms"] != float64(42) || sample["request_id"] != "r-1" {
+		t.Errorf("sample = %v", samples[0])
+	}
+	if resolve["event_action"] != "resolve" || resolve["dedup_key"] != "api-1/panics" || resolve["payload"] != nil {
+		t.Errorf("resolve = %v", resolve)
+	}
+}
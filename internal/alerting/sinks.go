@@ -0,0 +1,170 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ensure sinks implement Sink at compile time
+var (
+	_ Sink = (*SlackSink)(nil)
+	_ Sink = (*PagerDutySink)(nil)
+)
+
+// defaultTimeout bounds each request when no HTTP client is configured
+const defaultTimeout = 10 * time.Second
+
+// post sends body as JSON to url and fails unless the response is a 2xx
+func post(ctx context.Context, client *http.Client, service, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: HTTP %d: %s", service, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Slack
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// SlackConfig configures alerts posted to a Slack channel
+type SlackConfig struct {
+	WebhookURL string       // Incoming webhook of the channel (https://hooks.slack.com/services/…)
+	HTTPClient *http.Client // Nil for a client with a 10s timeout
+}
+
+// SlackSink posts alerts to a Slack channel through an incoming webhook
+type SlackSink struct {
+	cfg SlackConfig
+}
+
+// NewSlackSink creates a sink posting to cfg.WebhookURL
+func NewSlackSink(cfg SlackConfig) *SlackSink {
+	return &SlackSink{cfg: cfg}
+}
+
+// Send posts a as one message: the summary, then a line per sample
+func (s *SlackSink) Send(ctx context.Context, a Alert) error {
+	icon := ":rotating_light:"
+	if a.Resolved {
+		icon = ":white_check_mark:"
+	}
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s *%s*", icon, slackEscape(a.Summary))
+	if !a.Resolved {
+		fmt.Fprintf(&text, "\nRule `%s` (%s), breaching since %s", a.Rule, a.Severity, a.Since.UTC().Format(time.RFC3339))
+	}
+	for _, sample := range a.Samples {
+		fmt.Fprintf(&text, "\n• `%s` %s → %d in %s, request `%s`",
+			slackEscape(sample.Route), slackEscape(sample.Path), sample.Status, sample.Duration.Round(time.Millisecond), sample.RequestID)
+		if sample.Panic != "" {
+			fmt.Fprintf(&text, ": panic `%s`", slackEscape(truncate(sample.Panic, 200)))
+		}
+	}
+	return post(ctx, s.cfg.HTTPClient, "slack", s.cfg.WebhookURL, map[string]any{"text": text.String()})
+}
+
+// slackEscape escapes the characters Slack's message formatting reserves
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// PagerDuty
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// PagerDutyConfig configures alerts sent to a PagerDuty service
+type PagerDutyConfig struct {
+	RoutingKey string       // Integration key of an Events API v2 integration
+	URL        string       // Empty for https://events.pagerduty.com/v2/enqueue
+	HTTPClient *http.Client // Nil for a client with a 10s timeout
+}
+
+// PagerDutySink triggers and resolves PagerDuty incidents through the
+// Events API v2. Alerts of one rule and source share a deduplication key,
+// so reminders update the open incident and resolving closes it.
+type PagerDutySink struct {
+	cfg PagerDutyConfig
+}
+
+// NewPagerDutySink creates a sink sending to the service of cfg.RoutingKey
+func NewPagerDutySink(cfg PagerDutyConfig) *PagerDutySink {
+	if cfg.URL == "" {
+		cfg.URL = "https://events.pagerduty.com/v2/enqueue"
+	}
+	return &PagerDutySink{cfg: cfg}
+}
+
+// Send triggers an incident for a, or resolves it
+func (p *PagerDutySink) Send(ctx context.Context, a Alert) error {
+	event := map[string]any{
+		"routing_key":  p.cfg.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    a.Source + "/" + a.Rule,
+	}
+	if a.Resolved {
+		event["event_action"] = "resolve"
+	} else {
+		samples := make([]map[string]any, len(a.Samples))
+		for i, sample := range a.Samples {
+			samples[i] = map[string]any{
+				"time":        sample.Time.UTC().Format(time.RFC3339Nano),
+				"route":       sample.Route,
+				"path":        sample.Path,
+				"status":      sample.Status,
+				"duration_ms": sample.Duration.Milliseconds(),
+				"request_id":  sample.RequestID,
+			}
+			if sample.Panic != "" {
+				samples[i]["panic"] = sample.Panic
+			}
+		}
+		event["payload"] = map[string]any{
+			"summary":   truncate(a.Summary, 1024),
+			"source":    a.Source,
+			"severity":  a.Severity,
+			"timestamp": a.Since.UTC().Format(time.RFC3339),
+			"component": "api",
+			"class":     a.Rule,
+			"custom_details": map[string]any{
+				"window":   a.Window.String(),
+				"requests": a.Requests,
+				"errors":   a.Errors,
+				"panics":   a.Panics,
+				"samples":  samples,
+			},
+		}
+	}
+	return post(ctx, p.cfg.HTTPClient, "pagerduty", p.cfg.URL, event)
+}
@@ -0,0 +1,200 @@
+// Package anonymize copies users and orders from one store to another with
+// the personal data replaced, for refreshing staging from production (see
+// `api anonymize`).
+//
+// Names and emails are replaced with values derived from a keyed hash of the
+// originals: the same key maps the same person to the same fake identity on
+// every refresh, so staging bookmarks and test accounts survive, while
+// without the key the fakes cannot be traced back. Item prices are jittered
+// by a deterministic factor and order amounts recomputed from them, keeping
+// the distribution realistic without exposing real figures. IDs, statuses,
+// quantities, products and timestamps are copied as they are.
+package anonymize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// MinKeyLength is the shortest key accepted, in bytes
+const MinKeyLength = 16
+
+// EmailDomain is the domain of anonymized emails, reserved by RFC 2606 so
+// staging can never mail a real inbox
+const EmailDomain = "example.com"
+
+var firstNames = []string{
+	"Alex", "Bailey", "Casey", "Dana", "Eli", "Frankie", "Gale", "Harper",
+	"Indy", "Jordan", "Kai", "Logan", "Morgan", "Noel", "Oakley", "Parker",
+	"Quinn", "Riley", "Sage", "Taylor", "Umber", "Val", "Wren", "Yael",
+	"Avery", "Blair", "Cameron", "Drew", "Emery", "Finley", "Hayden", "Jesse",
+}
+
+var lastNames = []string{
+	"Abbott", "Brooks", "Carver", "Dalton", "Ellis", "Fletcher", "Grant", "Hollis",
+	"Irving", "Jensen", "Keller", "Lowell", "Mercer", "Nolan", "Osborne", "Pryor",
+	"Quade", "Ramsey", "Sutton", "Thorne", "Upton", "Vance", "Whitaker", "Yates",
+	"Archer", "Bishop", "Coleman", "Donovan", "Everett", "Foster", "Garner", "Hale",
+}
+
+// Anonymizer replaces the personal data of users and orders
+type Anonymizer struct {
+	key    []byte
+	jitter float64
+}
+
+// New creates an anonymizer. Prices are scaled by a factor within
+// 1 ± jitter (0.1 moves them by up to 10%).
+func New(key []byte, jitter float64) (*Anonymizer, error) {
+	if len(key) < MinKeyLength {
+		return nil, fmt.Errorf("anonymization key must be at least %d bytes", MinKeyLength)
+	}
+	if jitter < 0 || jitter >= 1 {
+		return nil, fmt.Errorf("price jitter must be at least 0 and below 1, got %g", jitter)
+	}
+	return &Anonymizer{key: slices.Clone(key), jitter: jitter}, nil
+}
+
+// sum returns the keyed hash of the parts
+func (a *Anonymizer) sum(parts ...string) []byte {
+	mac := hmac.New(sha256.New, a.key)
+	for _, part := range parts {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return mac.Sum(nil)
+}
+
+// User returns a copy of u with its name and email replaced. Equal emails
+// (ignoring case) always get the same replacement, so the copy keeps the
+// uniqueness of the originals.
+func (a *Anonymizer) User(u *domain.User) *domain.User {
+	c := *u
+	name := a.sum("name", strings.ToLower(strings.TrimSpace(u.Name)))
+	c.Name = firstNames[int(name[0])%len(firstNames)] + " " + lastNames[int(name[1])%len(lastNames)]
+	email := a.sum("email", strings.ToLower(strings.TrimSpace(u.Email)))
+	c.Email = "user-" + hex.EncodeToString(email[:8]) + "@" + EmailDomain
+	return &c
+}
+
+// Order returns a copy of o with every item price jittered and the amount
+// recomputed from them
+func (a *Anonymizer) Order(o *domain.Order) *domain.Order {
+	c := *o
+	c.Items = slices.Clone(o.Items)
+	if o.CancelledAt != nil {
+		cancelled := *o.CancelledAt
+		c.CancelledAt = &cancelled
+	}
+
+	var amount float64
+	for i := range c.Items {
+		item := &c.Items[i]
+		h := a.sum("price", o.ID, strconv.Itoa(i))
+		u := float64(binary.BigEndian.Uint64(h)>>11) / (1 << 53) // Uniform in [0, 1)
+		price := roundCents(item.Price * (1 + a.jitter*(2*u-1)))
+		if item.Price > 0 && price <= 0 {
+			price = 0.01 // Paid items stay paid
+		}
+		item.Price = price
+		amount += price * float64(item.Quantity)
+	}
+	c.Amount = roundCents(amount)
+	return &c
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Store is where users and orders are read from or written to
+type Store struct {
+	Users  domain.UserRepository
+	Orders domain.OrderRepository
+}
+
+// Result counts what Copy did
+type Result struct {
+	Users  int
+	Orders int
+}
+
+// Copy writes the anonymized users of src, each followed by its orders, to
+// dst, reading pageSize rows at a time. Rows already in dst (from an earlier
+// refresh) are overwritten, so Copy can be rerun; rows only in dst are left
+// alone. Users created in src while Copy pages through it may be missed or
+// copied twice without harm, so a copy from a replica or a quiet period is
+// the most consistent.
+func Copy(ctx context.Context, src, dst Store, a *Anonymizer, pageSize int, logg *logger.Logger) (Result, error) {
+	var res Result
+	for offset := 0; ; offset += pageSize {
+		users, err := src.Users.List(ctx, pageSize, offset)
+		if err != nil {
+			return res, fmt.Errorf("failed to read users: %w", err)
+		}
+		for _, user := range users {
+			if err := upsertUser(ctx, dst.Users, a.User(user)); err != nil {
+				return res, fmt.Errorf("failed to write user %s: %w", user.ID, err)
+			}
+			res.Users++
+
+			n, err := copyOrders(ctx, src, dst, a, user.ID, pageSize)
+			res.Orders += n
+			if err != nil {
+				return res, err
+			}
+		}
+		if len(users) < pageSize {
+			return res, nil
+		}
+		logg.Info("anonymized users copied", "users", res.Users, "orders", res.Orders)
+	}
+}
+
+// copyOrders copies the orders of one user, returning how many were written
+func copyOrders(ctx context.Context, src, dst Store, a *Anonymizer, userID string, pageSize int) (int, error) {
+	copied := 0
+	for offset := 0; ; offset += pageSize {
+		orders, err := src.Orders.GetByUserID(ctx, userID, pageSize, offset)
+		if err != nil {
+			return copied, fmt.Errorf("failed to read orders of user %s: %w", userID, err)
+		}
+		for _, order := range orders {
+			if err := upsertOrder(ctx, dst.Orders, a.Order(order)); err != nil {
+				return copied, fmt.Errorf("failed to write order %s: %w", order.ID, err)
+			}
+			copied++
+		}
+		if len(orders) < pageSize {
+			return copied, nil
+		}
+	}
+}
+
+func upsertUser(ctx context.Context, users domain.UserRepository, user *domain.User) error {
+	err := users.Update(ctx, user)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return users.Create(ctx, user)
+	}
+	return err
+}
+
+func upsertOrder(ctx context.Context, orders domain.OrderRepository, order *domain.Order) error {
+	err := orders.Update(ctx, order)
+	if errors.Is(err, domain.ErrOrderNotFound) {
+		return orders.Create(ctx, order)
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package anonymize
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestAnonymizer(t *testing.T) *Anonymizer {
+	t.Helper()
+	a, err := New(testKey, 0.1)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return a
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New([]byte("short"), 0.1); err == nil {
+		t.Error("expected an error for a short key")
+	}
+	if _, err := New(testKey, 1); err == nil {
+		t.Error("expected an error for a jitter of 1")
+	}
+	if _, err := New(testKey, -0.1); err == nil {
+		t.Error("expected an error for a negative jitter")
+	}
+}
+
+func TestUserIsDeterministic(t *testing.T) {
+	a := newTestAnonymizer(t)
+	u := &domain.User{ID: "u1", Name: "Ada Lovelace", Email: "ada@corp.test"}
+
+	first := a.User(u)
+	if first.Name == u.Name || first.Email == u.Email {
+		t.Fatalf("personal data kept: %+v", first)
+	}
+	if !strings.HasSuffix(first.Email, "@"+EmailDomain) {
+		t.Errorf("email %q is not in the reserved domain", first.Email)
+	}
+	if first.ID != u.ID {
+		t.Errorf("ID changed to %q", first.ID)
+	}
+	if u.Name != "Ada Lovelace" {
+		t.Error("the original user was modified")
+	}
+
+	again := a.User(&domain.User{ID: "u1", Name: "ada lovelace", Email: "ADA@corp.test"})
+	if again.Name != first.Name || again.Email != first.Email {
+		t.Errorf("same person anonymized differently: %+v and %+v", first, again)
+	}
+
+	other, err := New([]byte("fedcba9876543210fedcba9876543210"), 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.User(u).Email == first.Email {
+		t.Error("a different key produced the same email")
+	}
+}
+
+func TestUserEmailsStayUnique(t *testing.T) {
+	a := newTestAnonymizer(t)
+	seen := make(map[string]bool)
+	for i := range 5000 {
+		email := a.User(&domain.User{Email: fmt.Sprintf("person%d@corp.test", i)}).Email
+		if seen[email] {
+			t.Fatalf("duplicate anonymized email %s", email)
+		}
+		seen[email] = true
+	}
+}
+
+func TestOrderJittersPrices(t *testing.T) {
+	a := newTestAnonymizer(t)
+	cancelled := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	o := &domain.Order{
+		ID:     "o1",
+		UserID: "u1",
+		Status: domain.OrderStatusCancelled,
+		Items: []domain.OrderItem{
+			{ProductID: "pen", Quantity: 3, Price: 10},
+			{ProductID: "ink", Quantity: 1, Price: 0.01},
+			{ProductID: "gift", Quantity: 1, Price: 0},
+		},
+		Amount:      30.01,
+		CancelledAt: &cancelled,
+	}
+
+	c := a.Order(o)
+	var amount float64
+	for i, item := range c.Items {
+		orig := o.Items[i]
+		if item.ProductID != orig.ProductID || item.Quantity != orig.Quantity {
+			t.Errorf("item %d changed beyond its price: %+v", i, item)
+		}
+		if math.Abs(item.Price-orig.Price) > orig.Price*0.1+0.005 {
+			t.Errorf("item %d price %v is not within 10%% of %v", i, item.Price, orig.Price)
+		}
+		if orig.Price > 0 && item.Price <= 0 {
+			t.Errorf("item %d became free", i)
+		}
+		amount += item.Price * float64(item.Quantity)
+	}
+	if c.Items[2].Price != 0 {
+		t.Errorf("free item got price %v", c.Items[2].Price)
+	}
+	if c.Amount != roundCents(amount) {
+		t.Errorf("amount %v does not match the items (%v)", c.Amount, amount)
+	}
+	if o.Items[0].Price != 10 {
+		t.Error("the original order was modified")
+	}
+	if c.CancelledAt == o.CancelledAt || !c.CancelledAt.Equal(cancelled) {
+		t.Error("cancelled_at not copied")
+	}
+	if again := a.Order(o); again.Amount != c.Amount {
+		t.Errorf("same order jittered differently: %v and %v", c.Amount, again.Amount)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAnonymizer(t)
+	src := Store{Users: memory.NewUserRepository(), Orders: memory.NewOrderRepository()}
+	dst := Store{Users: memory.NewUserRepository(), Orders: memory.NewOrderRepository()}
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		id := fmt.Sprintf("u%d", i)
+		if err := src.Users.Create(ctx, &domain.User{ID: id, Name: "Real Name", Email: id + "@corp.test", CreatedAt: base}); err != nil {
+			t.Fatal(err)
+		}
+		for j := range 3 {
+			order := &domain.Order{
+				ID: fmt.Sprintf("%s-o%d", id, j), UserID: id, Status: domain.OrderStatusPending,
+				Items: []domain.OrderItem{{ProductID: "pen", Quantity: 1, Price: 5}}, Amount: 5,
+				CreatedAt: base.Add(time.Duration(j) * time.Hour),
+			}
+			if err := src.Orders.Create(ctx, order); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	logg := logger.New("error")
+	for run := range 2 { // A rerun overwrites the earlier copy
+		res, err := Copy(ctx, src, dst, a, 2, logg)
+		if err != nil {
+			t.Fatalf("run %d: Copy() error = %v", run, err)
+		}
+		if res.Users != 5 || res.Orders != 15 {
+			t.Errorf("run %d: Copy() = %+v, want 5 users and 15 orders", run, res)
+		}
+	}
+
+	users, _ := dst.Users.List(ctx, 100, 0)
+	if len(users) != 5 {
+		t.Fatalf("destination has %d users, want 5", len(users))
+	}
+	for _, u := range users {
+		if u.Name == "Real Name" || strings.HasSuffix(u.Email, "@corp.test") {
+			t.Errorf("user %s copied with personal data: %+v", u.ID, u)
+		}
+		orders, _ := dst.Orders.GetByUserID(ctx, u.ID, 100, 0)
+		if len(orders) != 3 {
+			t.Errorf("user %s has %d orders, want 3", u.ID, len(orders))
+		}
+	}
+}
@@ -0,0 +1,941 @@
+// Package app wires the application together: it builds infrastructure,
+// repositories, services, handlers and listeners from a *config.Config, so the
+// binary stays a thin shell and the whole API can be embedded or driven
+// end-to-end in tests with swapped implementations (see Option).
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/alerting"
+	"github.com/TopThisHat/stdlib-golang-api/internal/awsmsg"
+	"github.com/TopThisHat/stdlib-golang-api/internal/broadcast"
+	"github.com/TopThisHat/stdlib-golang-api/internal/chaos"
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	"github.com/TopThisHat/stdlib-golang-api/internal/diagnostics"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/email"
+	"github.com/TopThisHat/stdlib-golang-api/internal/featureflag"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/notify"
+	"github.com/TopThisHat/stdlib-golang-api/internal/payment"
+	"github.com/TopThisHat/stdlib-golang-api/internal/resilience"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/internal/server"
+	transporthttp "github.com/TopThisHat/stdlib-golang-api/internal/transport/http"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// App is a fully wired API instance
+type App struct {
+	cfg       *config.Config
+	logg      *logger.Logger
+	lifecycle *server.Lifecycle
+
+	semaphores  *usecase.Semaphores
+	jobs        *usecase.JobRunner
+	reports     *usecase.ReportService     // Nil unless REPORTS_ENABLED
+	status      *usecase.StatusService     // Nil unless STATUS_ENABLED
+	alerts      *alerting.Monitor          // Nil unless ALERTS_ENABLED
+	sagas       *usecase.SagaCoordinator   // Nil unless CHECKOUT_ENABLED
+	deadLetters *usecase.DeadLetterService // Nil unless DEAD_LETTERS_ENABLED
+
+	events        domain.EventBus
+	eventConsumer eventConsumer        // Of the event bus with EVENT_BUS=nats or redis, nil otherwise
+	outboxRelay   *usecase.OutboxRelay // Nil unless OUTBOX_ENABLED
+	queueEvents   domain.EventBus      // Fed by queueConsumer
+	queueConsumer *awsmsg.Consumer     // Nil unless SQS_QUEUE_URL
+	orderEvents   domain.OrderEventBus
+	orderEventLog domain.OrderEventLog              // Nil unless SSE_ENABLED
+	hub           *broadcast.Hub                    // Nil unless WS_ENABLED or SSE_ENABLED
+	orderStream   *transporthttp.OrderStreamHandler // Nil unless WS_ENABLED
+	eventStream   *transporthttp.EventHandler       // Nil unless SSE_ENABLED
+
+	diagnostics     *diagnostics.Collector
+	diagnosticsSink diagnostics.Sink
+
+	router      *transporthttp.Router
+	srv         *http.Server
+	redirectSrv *http.Server
+	adminSrv    *http.Server
+	configStore *config.Store
+}
+
+// eventConsumer runs the subscribed handlers of an event bus whose events
+// are consumed apart from their publishing, until ctx ends
+type eventConsumer interface {
+	Run(ctx context.Context) error
+}
+
+// New builds the application from cfg. Nothing listens until Run.
+// On error, resources acquired so far are released.
+func New(cfg *config.Config, opts ...Option) (app *App, err error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// ═══════════════════════════════════════════════
+	// Observability
+	// ═══════════════════════════════════════════════
+	logg := o.logger
+	if logg == nil {
+		logg = logger.New(cfg.LogLevel)
+	}
+
+	// Lifecycle: components register ordered shutdown hooks as they're created
+	lifecycle := server.NewLifecycle(logg, server.WithDrainDelay(cfg.ShutdownDrainDelay))
+	defer func() {
+		if err != nil {
+			lifecycle.Shutdown(context.Background())
+		}
+	}()
+
+	// Security events go to their own stream so a SIEM can collect them
+	if o.securityEvents == nil {
+		emitter, closeEvents, err := openSecurityEvents(cfg.SecurityEventsOutput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open security event stream: %w", err)
+		}
+		lifecycle.OnShutdown("security-events", server.PhasePublishers, 0, func(context.Context) error {
+			return closeEvents()
+		})
+		o.securityEvents = emitter
+		logg.Info("✓ security event stream configured", "output", cfg.SecurityEventsOutput)
+	}
+
+	// Diagnostics dumps (SIGQUIT, POST /api/admin/diagnostics); components register their stats below
+	collector := diagnostics.NewCollector(cfg.Version)
+
+	// Fault injection for resilience testing (never in production, see config)
+	var injector *chaos.Injector
+	if cfg.Chaos.Enabled {
+		injector = chaos.New(chaos.NewFaults(cfg.Chaos.ErrorPercent, cfg.Chaos.LatencyMS))
+		logg.Warn("⚠ chaos fault injection enabled",
+			"error_percent", cfg.Chaos.ErrorPercent,
+			"latency_ms", cfg.Chaos.LatencyMS,
+			"headers", cfg.Chaos.AllowHeaders)
+	}
+
+	w := &wiring{
+		cfg:       cfg,
+		o:         o,
+		logg:      logg,
+		lifecycle: lifecycle,
+		collector: collector,
+		injector:  injector,
+		replayers: map[string]usecase.DeadLetterReplayer{},
+		app: &App{
+			cfg:         cfg,
+			logg:        logg,
+			lifecycle:   lifecycle,
+			configStore: config.NewStore(cfg),
+			diagnostics: collector,
+		},
+	}
+
+	// Infrastructure (databases, caches, blob store), then what is built on it.
+	// Each step fills in w.o and w.app for the next.
+	if err := w.openStores(); err != nil {
+		return nil, err
+	}
+	authn, err := w.newAuth()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.connectMessaging(); err != nil {
+		return nil, err
+	}
+	handlers, err := w.newServices(authn)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.newRouter(handlers, authn); err != nil {
+		return nil, err
+	}
+	return w.app, nil
+}
+
+// wiring is the state New's steps share while assembling an App: the
+// options they fill in, and what one subsystem hands to the next
+type wiring struct {
+	cfg       *config.Config
+	o         *options
+	logg      *logger.Logger
+	lifecycle *server.Lifecycle
+	collector *diagnostics.Collector
+	injector  *chaos.Injector // Nil unless CHAOS_ENABLED
+
+	redisClient *goredis.Client // Nil unless something is kept in Redis
+	flags       *featureflag.Client
+
+	// Messages consumers give up on are kept for replay, by source, rather than dropped
+	deadLetters domain.DeadLetterQueue // Nil unless DEAD_LETTERS_ENABLED
+	replayers   map[string]usecase.DeadLetterReplayer
+
+	slo *usecase.SLOService // Nil unless SLO_ENABLED
+
+	app *App
+}
+
+// openSecurityEvents opens the security event stream named by output:
+// "stdout", "stderr", "none" (or empty) to disable, or a file path appended to
+func openSecurityEvents(output string) (security.Emitter, func() error, error) {
+	noClose := func() error { return nil }
+	switch strings.ToLower(strings.TrimSpace(output)) {
+	case "", "none":
+		return security.Nop{}, noClose, nil
+	case "stdout":
+		return security.NewJSONEmitter(os.Stdout), noClose, nil
+	case "stderr":
+		return security.NewJSONEmitter(os.Stderr), noClose, nil
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return security.NewJSONEmitter(f), f.Close, nil
+}
+
+// retryPolicy returns the retry policy for calls to dependency; the store it
+// is given decides which errors are transient
+func retryPolicy(cfg config.RetryConfig, dependency string) resilience.RetryPolicy {
+	return resilience.RetryPolicy{
+		MaxAttempts: cfg.Attempts[dependency],
+		BaseDelay:   cfg.BaseDelay,
+		MaxDelay:    cfg.MaxDelay,
+	}
+}
+
+// awsConfig loads the AWS SDK configuration for the AWS settings: static
+// keys when set, otherwise the default credential chain, as for S3
+func awsConfig(ctx context.Context, cfg config.AWSConfig) (aws.Config, error) {
+	return awsconfig.LoadDefaultConfig(ctx, blob.AWSLoadOptions(s3Config(cfg))...)
+}
+
+// s3Config maps the AWS settings onto the blob package's S3 configuration
+func s3Config(cfg config.AWSConfig) blob.S3Config {
+	return blob.S3Config{
+		Region:          cfg.Region,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		Bucket:          cfg.S3Bucket,
+	}
+}
+
+// routeRateLimits maps the per-route rate limits onto the router's
+func routeRateLimits(limits []config.RouteRateLimit) []transporthttp.RouteRateLimit {
+	mapped := make([]transporthttp.RouteRateLimit, len(limits))
+	for i, limit := range limits {
+		mapped[i] = transporthttp.RouteRateLimit{Route: limit.Route, PerMinute: limit.PerMinute, Key: limit.Key}
+	}
+	return mapped
+}
+
+// corsRoutes maps the configured CORS route policies onto the router's
+func corsRoutes(routes []config.CORSRoute) []transporthttp.CORSRoute {
+	mapped := make([]transporthttp.CORSRoute, len(routes))
+	for i, route := range routes {
+		mapped[i] = transporthttp.CORSRoute{
+			Route:            route.Route,
+			Origins:          route.Origins,
+			AllowCredentials: route.AllowCredentials,
+			MaxAge:           route.MaxAge,
+		}
+	}
+	return mapped
+}
+
+// rateLimitTiers maps the rate limit tiers onto the router's tier policy
+func rateLimitTiers(cfg config.HTTPConfig) transporthttp.RateLimitTierPolicy {
+	return transporthttp.RateLimitTierPolicy{
+		Multipliers: cfg.RateLimitTiers,
+		Members:     cfg.RateLimitTierMembers,
+	}
+}
+
+// apiVersionPolicy maps the API settings onto the router's version policy
+func apiVersionPolicy(cfg config.APIConfig) transporthttp.APIVersionPolicy {
+	policy := transporthttp.APIVersionPolicy{
+		Default:    transporthttp.APIVersion(cfg.DefaultVersion),
+		Deprecated: make(map[transporthttp.APIVersion]time.Time, len(cfg.Deprecations)),
+		Sunset:     make(map[transporthttp.APIVersion]time.Time, len(cfg.Sunsets)),
+	}
+	// Versions were validated by config.APIConfig
+	for version, at := range cfg.Deprecations {
+		n, _ := strconv.Atoi(version)
+		policy.Deprecated[transporthttp.APIVersion(n)] = at
+	}
+	for version, at := range cfg.Sunsets {
+		n, _ := strconv.Atoi(version)
+		policy.Sunset[transporthttp.APIVersion(n)] = at
+	}
+	return policy
+}
+
+// sloPolicy maps the SLO settings onto the SLO service's policy
+func sloPolicy(cfg config.SLOConfig) usecase.SLOPolicy {
+	policy := usecase.SLOPolicy{
+		Objectives:   make(map[string]usecase.SLOObjective, len(cfg.Targets)),
+		FastWindow:   cfg.FastWindow,
+		SlowWindow:   cfg.SlowWindow,
+		FastBurnRate: cfg.FastBurnRate,
+		SlowBurnRate: cfg.SlowBurnRate,
+		MinRequests:  int64(cfg.MinRequests),
+	}
+	for route, target := range cfg.Targets {
+		// Round so 99.9 becomes 0.999 rather than 0.9990000000000001
+		share := math.Round(target.Objective*1e4) / 1e6
+		policy.Objectives[route] = usecase.SLOObjective{Target: share, Latency: target.Latency}
+	}
+	return policy
+}
+
+// newAlertMonitor returns the alert monitor of cfg, posting to the Slack
+// channel and PagerDuty service it names
+func newAlertMonitor(cfg config.AlertsConfig, logg *logger.Logger) *alerting.Monitor {
+	var sinks []alerting.Sink
+	if cfg.SlackWebhookURL != "" {
+		sinks = append(sinks, alerting.NewSlackSink(alerting.SlackConfig{WebhookURL: cfg.SlackWebhookURL}))
+	}
+	if cfg.PagerDutyRoutingKey != "" {
+		sinks = append(sinks, alerting.NewPagerDutySink(alerting.PagerDutyConfig{RoutingKey: cfg.PagerDutyRoutingKey}))
+	}
+	return alerting.NewMonitor(alerting.Config{
+		Window:        cfg.Window,
+		ErrorRate:     cfg.ErrorRate,
+		MinRequests:   int64(cfg.MinRequests),
+		Panics:        int64(cfg.Panics),
+		Cooldown:      cfg.Cooldown,
+		CheckInterval: cfg.CheckInterval,
+		Samples:       cfg.Samples,
+	}, logg, sinks...)
+}
+
+// newGuard returns the guard for calls to dependency, logging its breaker's
+// transitions. Calls failing with one of expected do not count against it.
+func newGuard(dependency string, cfg config.ResilienceConfig, logg *logger.Logger, expected ...error) *resilience.Guard {
+	return resilience.NewGuard(dependency, resilience.GuardConfig{
+		MaxConcurrent: cfg.MaxConcurrent[dependency],
+		BulkheadWait:  cfg.BulkheadWait,
+		Timeout:       cfg.Timeouts[dependency],
+		Breaker: resilience.BreakerConfig{
+			FailureThreshold: cfg.FailureThreshold,
+			OpenTimeout:      cfg.OpenTimeout,
+			HalfOpenProbes:   cfg.HalfOpenProbes,
+			OnStateChange: func(name string, from, to resilience.State) {
+				if to == resilience.StateOpen {
+					logg.Warn("circuit breaker opened", "dependency", name, "from", from, "retry_in", cfg.OpenTimeout)
+					return
+				}
+				logg.Info("circuit breaker state changed", "dependency", name, "from", from, "to", to)
+			},
+		},
+		IsFailure: resilience.IgnoreErrors(expected...),
+	})
+}
+
+// jobRunnerPolicy maps the job settings onto the runner's policy
+func jobRunnerPolicy(cfg config.JobsConfig) usecase.JobRunnerPolicy {
+	policy := usecase.JobRunnerPolicy{
+		Priorities:    make(map[domain.JobPriority]usecase.JobPriorityPolicy, len(domain.JobPriorities)),
+		PollInterval:  cfg.PollInterval,
+		MaxAttempts:   cfg.MaxAttempts,
+		RetryDelay:    cfg.RetryDelay,
+		MaxRetryDelay: cfg.MaxRetryDelay,
+	}
+	for _, p := range domain.JobPriorities {
+		policy.Priorities[p] = usecase.JobPriorityPolicy{
+			Weight:      cfg.Weights[string(p)],
+			Concurrency: cfg.Concurrency[string(p)],
+		}
+	}
+	return policy
+}
+
+// newEmailSender returns an SMTP sender, or a logging one when no SMTP host is configured
+// newNotifiers returns the notifiers of the configured channels: email
+// through mailer, sms through SNS or Twilio, push through FCM and slack
+// through a Slack app
+func newNotifiers(cfg config.NotificationsConfig, awsSettings config.AWSConfig, mailer domain.EmailSender) (map[domain.NotificationChannel]domain.Notifier, error) {
+	notifiers := map[domain.NotificationChannel]domain.Notifier{
+		domain.NotificationEmail: notify.NewEmailNotifier(mailer),
+	}
+	switch cfg.SMSProvider {
+	case "sns":
+		awsCfg, err := awsConfig(context.Background(), awsSettings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		notifiers[domain.NotificationSMS] = awsmsg.NewSMSNotifier(awsCfg, cfg.SNSSenderID)
+	case "twilio":
+		notifiers[domain.NotificationSMS] = notify.NewTwilioNotifier(notify.TwilioConfig{
+			AccountSID: cfg.TwilioAccountSID,
+			AuthToken:  cfg.TwilioAuthToken,
+			From:       cfg.TwilioFrom,
+		})
+	}
+	if cfg.FCMCredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FCM_CREDENTIALS_FILE: %w", err)
+		}
+		fcm, err := notify.NewFCMNotifier(notify.FCMConfig{Credentials: credentials, ProjectID: cfg.FCMProjectID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create FCM notifier: %w", err)
+		}
+		notifiers[domain.NotificationPush] = fcm
+	}
+	if cfg.SlackBotToken != "" {
+		notifiers[domain.NotificationSlack] = notify.NewSlackNotifier(notify.SlackConfig{BotToken: cfg.SlackBotToken})
+	}
+	return notifiers, nil
+}
+
+func newEmailSender(cfg config.EmailConfig, logg *logger.Logger) domain.EmailSender {
+	if cfg.SMTPHost == "" {
+		return email.NewLogSender(logg)
+	}
+	return email.NewSMTPSender(email.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.From,
+	})
+}
+
+// newFlagProvider builds the configured feature flag provider. The redis
+// provider needs client.
+func newFlagProvider(cfg config.FeatureFlagsConfig, client *goredis.Client, logg *logger.Logger) (featureflag.Provider, error) {
+	switch cfg.Provider {
+	case "file":
+		return featureflag.NewFileProvider(cfg.File, cfg.RefreshInterval, logg)
+	case "redis":
+		return featureflag.NewRedisProvider(client, cfg.RefreshInterval, logg), nil
+	default:
+		return featureflag.NewEnvProvider(cfg.Rollouts)
+	}
+}
+
+// NewBlobStore creates the BLOB_BACKEND store, and returns it with where it
+// keeps blobs (bucket, account/container or directory) for logs and reports
+func NewBlobStore(ctx context.Context, cfg *config.Config, logg *logger.Logger) (blob.Store, string, error) {
+	switch cfg.Blob.Backend {
+	case config.BlobBackendAzure:
+		store, err := blob.NewAzureStore(blob.AzureConfig{
+			AccountName: cfg.Blob.AzureAccount,
+			AccountKey:  cfg.Blob.AzureKey,
+			Container:   cfg.Blob.AzureContainer,
+			Endpoint:    cfg.Blob.AzureEndpoint,
+		}, logg,
+			blob.WithAzureRetry(cfg.Retry.Attempts["blob"], cfg.Retry.MaxDelay),
+			blob.WithAzureRequestID(middleware.GetRequestID))
+		return store, cfg.Blob.AzureAccount + "/" + cfg.Blob.AzureContainer, err
+	case config.BlobBackendGCS:
+		store, err := blob.NewGCSStore(ctx, blob.GCSConfig{
+			Bucket:          cfg.Blob.GCSBucket,
+			CredentialsFile: cfg.Blob.GCSCredentialsFile,
+			Endpoint:        cfg.Blob.GCSEndpoint,
+		}, logg,
+			blob.WithGCSRetry(cfg.Retry.Attempts["blob"], cfg.Retry.MaxDelay))
+		return store, cfg.Blob.GCSBucket, err
+	case config.BlobBackendFilesystem:
+		store, err := blob.NewFileSystemStore(cfg.Blob.FilesystemDir, logg, blob.WithCreateBasePath(true))
+		return store, cfg.Blob.FilesystemDir, err
+	case config.BlobBackendMemory:
+		logg.Warn("⚠️  BLOB_BACKEND=memory: blobs are lost on restart and not shared between instances")
+		return blob.NewMemoryStore(), "memory", nil
+	default:
+		store, err := blob.NewS3Store(ctx, s3Config(cfg.AWS), logg,
+			blob.WithRetry(cfg.Retry.Attempts["blob"], cfg.Retry.MaxDelay),
+			blob.WithRequestID(middleware.GetRequestID),
+			blob.WithServerSideEncryption(cfg.AWS.S3Encryption, cfg.AWS.S3KMSKeyID),
+			blob.WithRequireEncryption(cfg.AWS.S3RequireEncryption))
+		return store, cfg.AWS.S3Bucket, err
+	}
+}
+
+// Lifecycle rules the app manages (BLOB_MANAGE_LIFECYCLE)
+const (
+	lifecycleRuleReports          = "api-reports-retention"
+	lifecycleRuleUserData         = "api-user-data-retention"
+	lifecycleRuleIncompleteUpload = "api-abort-incomplete-uploads"
+	lifecycleRuleAttachmentParts  = "api-attachment-parts"
+)
+
+// applyBlobLifecycle sets the bucket lifecycle rules of the configured
+// retentions, and deletes those of retentions no longer configured.
+// Retentions are rounded up to whole days.
+func applyBlobLifecycle(ctx context.Context, manager blob.LifecycleManager, cfg *config.Config, logg *logger.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	days := func(d time.Duration) int {
+		return int((d + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+	var rules []blob.LifecycleRule
+	var unused []string
+	if cfg.Reports.Enabled && cfg.Reports.Retention > 0 {
+		rules = append(rules, blob.LifecycleRule{ID: lifecycleRuleReports, Prefix: cfg.Reports.BlobPrefix, ExpirationDays: days(cfg.Reports.Retention)})
+	} else {
+		unused = append(unused, lifecycleRuleReports)
+	}
+	if cfg.UserData.Enabled && cfg.UserData.Retention > 0 {
+		rules = append(rules, blob.LifecycleRule{ID: lifecycleRuleUserData, Prefix: cfg.UserData.BlobPrefix, ExpirationDays: days(cfg.UserData.Retention)})
+	} else {
+		unused = append(unused, lifecycleRuleUserData)
+	}
+	if cfg.Blob.AbortIncompleteUploadsAfter > 0 {
+		rules = append(rules, blob.LifecycleRule{ID: lifecycleRuleIncompleteUpload, AbortIncompleteUploadDays: days(cfg.Blob.AbortIncompleteUploadsAfter)})
+	} else {
+		unused = append(unused, lifecycleRuleIncompleteUpload)
+	}
+	// The unfinished tails of resumable uploads go with their multipart uploads
+	if cfg.Attachments.Enabled && cfg.Attachments.Resumable && cfg.Blob.AbortIncompleteUploadsAfter > 0 {
+		rules = append(rules, blob.LifecycleRule{ID: lifecycleRuleAttachmentParts, Prefix: cfg.Attachments.PartPrefix, ExpirationDays: days(cfg.Blob.AbortIncompleteUploadsAfter)})
+	} else {
+		unused = append(unused, lifecycleRuleAttachmentParts)
+	}
+
+	if err := manager.DeleteLifecycleRules(ctx, unused...); err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		if err := manager.PutLifecycleRules(ctx, rules); err != nil {
+			return err
+		}
+	}
+	logg.Info("✓ blob lifecycle rules set", "rules", len(rules))
+	return nil
+}
+
+// newPaymentGateway returns the gateway checkout captures payments with:
+// the one supplied as an option, else the CHECKOUT_PAYMENTS_URL API, else,
+// in memory only, a stand-in approving every payment
+func newPaymentGateway(o *options, cfg *config.Config, logg *logger.Logger) (domain.PaymentGateway, error) {
+	switch {
+	case o.payments != nil:
+		return o.payments, nil
+	case cfg.Checkout.PaymentsURL != "":
+		return payment.NewClient(payment.Config{
+			BaseURL: cfg.Checkout.PaymentsURL,
+			APIKey:  cfg.Checkout.PaymentsAPIKey,
+		}), nil
+	case cfg.InMemory():
+		logg.Warn("⚠️  CHECKOUT_PAYMENTS_URL not set: every payment is approved without charging anyone")
+		return memory.NewPaymentGateway(), nil
+	}
+	return nil, fmt.Errorf("CHECKOUT_ENABLED requires CHECKOUT_PAYMENTS_URL to capture payments")
+}
+
+// buildServers creates the public listener and the optional HTTPS redirect and admin listeners
+func (a *App) buildServers(o *options) error {
+	cfg := a.cfg
+
+	a.srv = &http.Server{
+		Addr:         ":" + cfg.HTTP.Port,
+		Handler:      a.router,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		Protocols:         transporthttp.ServerProtocols(cfg.HTTP.EnableH2C),
+		HTTP2:             &http.HTTP2Config{MaxConcurrentStreams: cfg.HTTP.HTTP2MaxConcurrentStreams},
+	}
+	a.srv.SetKeepAlivesEnabled(cfg.HTTP.KeepAlives)
+	if cfg.HTTP.TLSEnabled() {
+		a.srv.TLSConfig = transporthttp.ModernTLSConfig()
+		if err := transporthttp.ConfigureClientAuth(a.srv.TLSConfig, cfg.HTTP.TLSClientCAFile, cfg.HTTP.TLSClientAuth); err != nil {
+			return fmt.Errorf("failed to configure client certificate auth: %w", err)
+		}
+	}
+
+	// ACME mode: certificates are issued on first use and renewed automatically,
+	// cached in the blob store (S3 by default) so all instances share them
+	var acmeManager *autocert.Manager
+	if cfg.HTTP.ACMEEnabled() {
+		acmeManager = transporthttp.NewACMEManager(
+			cfg.HTTP.ACMEHosts,
+			cfg.HTTP.ACMEEmail,
+			cfg.HTTP.ACMEDirectoryURL,
+			blob.NewAutocertCache(o.blobStore, cfg.HTTP.ACMECachePrefix),
+		)
+		transporthttp.ApplyACME(a.srv.TLSConfig, acmeManager)
+		a.logg.Info("✓ ACME certificate management enabled", "hosts", cfg.HTTP.ACMEHosts)
+	}
+
+	// Optional plain HTTP listener that only redirects to HTTPS
+	if cfg.HTTP.RedirectPort != "" {
+		redirectHandler := transporthttp.RedirectToHTTPS(cfg.HTTP.Port)
+		if acmeManager != nil {
+			// Answer HTTP-01 challenges before redirecting everything else
+			redirectHandler = acmeManager.HTTPHandler(redirectHandler)
+		}
+		a.redirectSrv = &http.Server{
+			Addr:              ":" + cfg.HTTP.RedirectPort,
+			Handler:           redirectHandler,
+			ReadTimeout:       5 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      5 * time.Second,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+		}
+	}
+
+	// Optional internal listener for health, metrics, profiling and admin routes.
+	// With TLS it serves the same certificates and client certificate policy
+	// as the public listener, so admin tokens never cross the network in clear.
+	if a.router.Admin != nil {
+		a.adminSrv = &http.Server{
+			Addr:              cfg.HTTP.AdminAddr,
+			Handler:           a.router.Admin,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			WriteTimeout:      cfg.HTTP.AdminWriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+			MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		}
+		if a.srv.TLSConfig != nil {
+			a.adminSrv.TLSConfig = a.srv.TLSConfig.Clone()
+		}
+	}
+
+	return nil
+}
+
+// Handler returns the API handler with the full middleware stack, for
+// embedding or driving the app with httptest without opening a listener
+func (a *App) Handler() http.Handler {
+	return a.router
+}
+
+// AdminHandler returns the internal listener's handler (health, metrics,
+// profiling and admin routes), or nil when ADMIN_ADDR is not set
+func (a *App) AdminHandler() http.Handler {
+	return a.router.Admin
+}
+
+// Semaphores returns the cluster-wide concurrency limits for expensive operations
+func (a *App) Semaphores() *usecase.Semaphores {
+	return a.semaphores
+}
+
+// Jobs returns the background job runner. Register handlers before Run.
+func (a *App) Jobs() *usecase.JobRunner {
+	return a.jobs
+}
+
+// Events returns the domain event bus, for subscribing to order lifecycle
+// events. Subscribe before Run. With EVENT_BUS=nats or redis, handlers run
+// after the event was published, on one of the instances, and a handler
+// error redelivers the event.
+func (a *App) Events() domain.EventBus {
+	return a.events
+}
+
+// QueueEvents returns the bus of domain events received from SQS_QUEUE_URL.
+// A handler error leaves the message on the queue, to be delivered again
+// and eventually dead-lettered, so subscribe with work that must happen
+// but need not happen during the request. Subscribe before Run.
+func (a *App) QueueEvents() domain.EventBus {
+	return a.queueEvents
+}
+
+// Lifecycle returns the lifecycle manager so embedders can register their own
+// shutdown hooks
+func (a *App) Lifecycle() *server.Lifecycle {
+	return a.lifecycle
+}
+
+// Run starts the listeners and the config watcher, then blocks until ctx is
+// cancelled (e.g. by SIGTERM) or a listener fails, and shuts down gracefully
+func (a *App) Run(ctx context.Context) error {
+	cfg := a.cfg
+	errCh := make(chan error, 3)
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	a.lifecycle.OnClose("config-watcher", server.PhaseWorkers, stopWatching)
+	go a.configStore.Watch(watchCtx, func(ignored []string, err error) {
+		if err != nil {
+			a.logg.Error("config reload rejected, keeping current settings", "errors", config.Violations(err))
+			return
+		}
+		if len(ignored) > 0 {
+			a.logg.Warn("config changes require a restart and were ignored", "fields", ignored)
+		}
+	})
+
+	// SIGQUIT dumps diagnostics instead of killing the process (until shutdown)
+	if cfg.Diagnostics.OnSIGQUIT {
+		diagCtx, stopDiagnostics := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("diagnostics", server.PhaseWorkers, stopDiagnostics)
+		go a.diagnostics.Watch(diagCtx, a.diagnosticsSink, a.logg)
+	}
+
+	// Job workers stop taking new jobs at shutdown; running jobs get until the hook times out
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		a.jobs.Run(jobsCtx)
+	}()
+	a.lifecycle.OnShutdown("jobs", server.PhaseWorkers, 0, func(ctx context.Context) error {
+		stopJobs()
+		select {
+		case <-jobsDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	// Request stats are flushed once more at shutdown so the last interval is kept
+	if a.status != nil {
+		statusCtx, stopStatus := context.WithCancel(context.Background())
+		statusDone := make(chan struct{})
+		go func() {
+			defer close(statusDone)
+			a.status.Run(statusCtx)
+		}()
+		a.lifecycle.OnShutdown("status-stats", server.PhaseWorkers, 0, func(ctx context.Context) error {
+			stopStatus()
+			<-statusDone
+			return a.status.Flush(ctx)
+		})
+	}
+
+	// The event being handled is handled to the end, so it is not redelivered
+	if a.eventConsumer != nil {
+		consumerCtx, stopConsumer := context.WithCancel(context.Background())
+		consumerDone := make(chan struct{})
+		go func() {
+			defer close(consumerDone)
+			a.eventConsumer.Run(consumerCtx)
+		}()
+		a.lifecycle.OnShutdown("event-consumer", server.PhaseWorkers, 0, func(ctx context.Context) error {
+			stopConsumer()
+			select {
+			case <-consumerDone:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+
+	// In-flight messages are handled to the end, so they are not redelivered
+	if a.queueConsumer != nil {
+		consumerCtx, stopConsumer := context.WithCancel(context.Background())
+		consumerDone := make(chan struct{})
+		go func() {
+			defer close(consumerDone)
+			a.queueConsumer.Run(consumerCtx, awsmsg.EventHandler(a.queueEvents.Publish))
+		}()
+		a.lifecycle.OnShutdown("sqs-consumer", server.PhaseWorkers, 0, func(ctx context.Context) error {
+			stopConsumer()
+			select {
+			case <-consumerDone:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+
+	if a.outboxRelay != nil {
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("outbox-relay", server.PhaseWorkers, stopRelay)
+		go a.outboxRelay.Run(relayCtx)
+	}
+
+	if a.alerts != nil {
+		alertsCtx, stopAlerts := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("alerting", server.PhaseWorkers, stopAlerts)
+		go a.alerts.Run(alertsCtx)
+	}
+
+	// Checkouts left unfinished by a crash, on any instance, are finished here
+	if a.sagas != nil {
+		recoveryCtx, stopRecovery := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("saga-recovery", server.PhaseWorkers, stopRecovery)
+		go a.sagas.Run(recoveryCtx)
+	}
+
+	// Dead letters past the retention are purged hourly by every instance
+	if a.deadLetters != nil {
+		purgeCtx, stopPurge := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("dead-letter-purge", server.PhaseWorkers, stopPurge)
+		go a.deadLetters.Run(purgeCtx)
+	}
+
+	if a.reports != nil {
+		schedCtx, stopScheduler := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("report-scheduler", server.PhaseWorkers, stopScheduler)
+		go a.reports.RunScheduler(schedCtx, cfg.Reports.CheckInterval)
+	}
+
+	// Order events from every instance reach this instance's WebSocket and SSE
+	// clients. The hub closes with the listeners, so streams end promptly.
+	if a.orderStream != nil {
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("order-events", server.PhaseWorkers, stopRelay)
+		go a.relayOrderEvents(relayCtx)
+	}
+	if a.eventStream != nil {
+		tailCtx, stopTail := context.WithCancel(context.Background())
+		a.lifecycle.OnClose("order-event-log", server.PhaseWorkers, stopTail)
+		go a.tailOrderEventLog(tailCtx)
+	}
+	if a.hub != nil {
+		a.lifecycle.OnShutdown("event-streams", server.PhaseListeners, 0, func(context.Context) error {
+			a.hub.Close()
+			return nil
+		})
+	}
+
+	go func() {
+		a.logg.Info("🚀 server starting", "addr", a.srv.Addr, "env", cfg.Environment, "tls", cfg.HTTP.TLSEnabled())
+		var err error
+		if cfg.HTTP.TLSEnabled() {
+			// Cert and key files are empty in ACME mode; GetCertificate supplies them
+			err = a.srv.ListenAndServeTLS(cfg.HTTP.TLSCertFile, cfg.HTTP.TLSKeyFile)
+		} else {
+			err = a.srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("server failed to start: %w", err)
+		}
+	}()
+	a.lifecycle.OnShutdown("http", server.PhaseListeners, cfg.ShutdownTimeout, a.srv.Shutdown)
+
+	if a.redirectSrv != nil {
+		go func() {
+			a.logg.Info("↪ https redirect listener starting", "addr", a.redirectSrv.Addr)
+			if err := a.redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("redirect listener failed to start: %w", err)
+			}
+		}()
+		a.lifecycle.OnShutdown("https-redirect", server.PhaseListeners, 0, a.redirectSrv.Shutdown)
+	}
+
+	if a.adminSrv != nil {
+		go func() {
+			a.logg.Info("🔧 admin listener starting", "addr", a.adminSrv.Addr, "tls", a.adminSrv.TLSConfig != nil)
+			var err error
+			if a.adminSrv.TLSConfig != nil {
+				err = a.adminSrv.ListenAndServeTLS(cfg.HTTP.TLSCertFile, cfg.HTTP.TLSKeyFile)
+			} else {
+				err = a.adminSrv.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("admin listener failed to start: %w", err)
+			}
+		}()
+		a.lifecycle.OnShutdown("admin", server.PhaseListeners, 0, a.adminSrv.Shutdown)
+	}
+
+	a.lifecycle.MarkReady()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		a.logg.Info("🛑 shutdown signal received, draining connections...")
+	case runErr = <-errCh:
+		a.logg.Error("listener failed, shutting down", "error", runErr)
+	}
+
+	return errors.Join(runErr, a.Shutdown(context.Background()))
+}
+
+// relayOrderEvents feeds the order event bus to the WebSocket clients until
+// ctx ends, resubscribing after failures (e.g. Redis restarting)
+func (a *App) relayOrderEvents(ctx context.Context) {
+	const retryDelay = 5 * time.Second
+	for {
+		err := a.orderEvents.Subscribe(ctx, a.orderStream.Publish)
+		if ctx.Err() != nil {
+			return
+		}
+		a.logg.Warn("order event subscription ended, retrying", "error", err, "retry_in", retryDelay)
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// tailOrderEventLog feeds events appended to the order event log by any
+// instance to the SSE clients until ctx ends, in log order
+func (a *App) tailOrderEventLog(ctx context.Context) {
+	const (
+		batch      = 100
+		wait       = 5 * time.Second
+		retryDelay = 5 * time.Second
+	)
+	// Start at the newest event; older ones are replayed per client
+	lastID, started := "", false
+	for ctx.Err() == nil {
+		var events []*domain.OrderEvent
+		var err error
+		if started {
+			events, err = a.orderEventLog.After(ctx, lastID, batch, wait)
+		} else if lastID, err = a.orderEventLog.LastID(ctx); err == nil {
+			started = true
+		}
+
+		switch {
+		case err == nil:
+			for _, e := range events {
+				a.eventStream.Publish(e)
+				lastID = e.ID
+			}
+		case errors.Is(err, domain.ErrOrderEventsExpired):
+			a.logg.Warn("order event log tail fell behind, skipping to the newest event")
+			started = false
+		case ctx.Err() == nil:
+			a.logg.Warn("order event log read failed, retrying", "error", err, "retry_in", retryDelay)
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// Shutdown withdraws readiness, drains listeners, stops workers, then closes
+// pools, bounded overall by SHUTDOWN_TIMEOUT (0 leaves only each step's own
+// bound). It is also safe to call on an App that never ran, to release its
+// connections.
+func (a *App) Shutdown(ctx context.Context) error {
+	cancel := context.CancelFunc(func() {})
+	if a.cfg.ShutdownTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, a.cfg.ShutdownTimeout)
+	}
+	defer cancel()
+
+	if err := a.lifecycle.Shutdown(ctx); err != nil {
+		return fmt.Errorf("graceful shutdown incomplete: %w", err)
+	}
+
+	a.logg.Info("✓ server stopped gracefully")
+	return nil
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+const testJWTSecret = "app-test-secret-with-at-least-32-characters"
+
+// standaloneConfig loads the configuration of an instance touching nothing
+// outside the process, listening on a free port
+func standaloneConfig(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("RUN_MODE", config.RunModeStandalone)
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	t.Setenv("SECURITY_EVENTS_OUTPUT", "none")
+	t.Setenv("SHUTDOWN_TIMEOUT", "0")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.HTTP.Port = "0" // Not a valid PORT, but it is to net.Listen
+	return cfg
+}
+
+func bearerToken(t *testing.T, subject string) string {
+	t.Helper()
+	keys, err := auth.LoadKeySet(testJWTSecret, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	tokens, err := auth.NewTokenManager(keys, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	token, err := tokens.Issue(auth.Claims{Subject: subject})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
+
+func get(h http.Handler, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestAppLifecycle(t *testing.T) {
+	cfg := standaloneConfig(t)
+	if cfg.ShutdownTimeout != 0 {
+		t.Fatalf("ShutdownTimeout = %v, want 0", cfg.ShutdownTimeout)
+	}
+	a, err := New(cfg, WithLogger(logger.New("error")))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	h := a.Handler()
+
+	if rec := get(h, "/ready", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ready before Run = %d, want 503", rec.Code)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for get(h, "/ready", "").Code != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("app never became ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if rec := get(h, "/health", ""); rec.Code != http.StatusOK {
+		t.Errorf("health = %d, want 200", rec.Code)
+	}
+	if rec := get(h, "/api/users", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("users without a token = %d, want 401", rec.Code)
+	}
+	// Served by the seeded in-memory repositories
+	if rec := get(h, "/api/users", bearerToken(t, "user-1")); rec.Code != http.StatusOK {
+		t.Errorf("users = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	// SHUTDOWN_TIMEOUT=0 leaves each hook its own bound rather than none at all
+	stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after its context ended")
+	}
+
+	if rec := get(h, "/ready", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ready after shutdown = %d, want 503", rec.Code)
+	}
+}
+
+func TestShutdownWithoutRun(t *testing.T) {
+	a, err := New(standaloneConfig(t), WithLogger(logger.New("error")))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+}
+
+func TestAdminListenerSharesTLS(t *testing.T) {
+	cfg := standaloneConfig(t)
+	cfg.HTTP.AdminAddr = "127.0.0.1:0"
+	a, err := New(cfg, WithLogger(logger.New("error")))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer a.Shutdown(context.Background())
+	if a.adminSrv.TLSConfig != nil {
+		t.Error("admin listener has a TLS config without TLS")
+	}
+
+	cfg = standaloneConfig(t)
+	cfg.HTTP.AdminAddr = "127.0.0.1:0"
+	cfg.HTTP.TLSCertFile, cfg.HTTP.TLSKeyFile = "cert.pem", "key.pem" // Only read by Run
+	a, err = New(cfg, WithLogger(logger.New("error")))
+	if err != nil {
+		t.Fatalf("New with TLS: %v", err)
+	}
+	defer a.Shutdown(context.Background())
+	admin, public := a.adminSrv.TLSConfig, a.srv.TLSConfig
+	if admin == nil {
+		t.Fatal("admin listener serves plain HTTP alongside a TLS listener")
+	}
+	if admin == public {
+		t.Error("admin listener shares the public TLS config instead of a copy")
+	}
+	if admin.MinVersion != public.MinVersion || len(admin.CipherSuites) != len(public.CipherSuites) {
+		t.Errorf("admin TLS = min %x with %d suites, want min %x with %d",
+			admin.MinVersion, len(admin.CipherSuites), public.MinVersion, len(public.CipherSuites))
+	}
+}
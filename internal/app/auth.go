@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Authentication (JWTs, Sessions, Personal Access Tokens)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// authServices are what issues and checks credentials, shared by the session
+// and access token handlers and the router's authentication middleware
+type authServices struct {
+	tokens       *auth.TokenManager
+	sessions     *usecase.SessionService
+	accessTokens *usecase.AccessTokenService
+	bruteForce   *usecase.BruteForceGuard
+}
+
+// newAuth loads the JWT keys and builds the services on top of the
+// revocation, login attempt and access token stores
+func (w *wiring) newAuth() (*authServices, error) {
+	cfg, o, logg := w.cfg, w.o, w.logg
+
+	// JWT keys: JWT_SECRET (legacy kid "default") plus rotating keys from JWT_KEYS_FILE
+	jwtKeys, err := auth.LoadKeySet(cfg.Auth.JWTSecret, cfg.Auth.JWTKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwt keys: %w", err)
+	}
+	tokens, err := auth.NewTokenManager(jwtKeys,
+		cfg.Auth.TokenTTL(),
+		auth.WithPIIEncryption(cfg.Auth.JWTEncryptPII))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure jwt: %w", err)
+	}
+	logg.Info("✓ jwt keys loaded", "keys_file", cfg.Auth.JWTKeysFile != "", "encrypt_pii", cfg.Auth.JWTEncryptPII)
+
+	return &authServices{
+		tokens:   tokens,
+		sessions: usecase.NewSessionService(o.revocations, o.accessTokenRepo, cfg.Auth.RevocationRetention, logg),
+		accessTokens: usecase.NewAccessTokenService(o.accessTokenRepo, usecase.AccessTokenPolicy{
+			DefaultLifetime: cfg.Auth.PATDefaultLifetime,
+			MaxLifetime:     cfg.Auth.PATMaxLifetime,
+		}, logg),
+		bruteForce: usecase.NewBruteForceGuard(o.loginAttempts, usecase.BruteForcePolicy{
+			Window:       cfg.Auth.FailureWindow,
+			CaptchaAfter: cfg.Auth.CaptchaAfter,
+			LockAfter:    cfg.Auth.LockoutAfter,
+			BaseLockout:  cfg.Auth.LockoutBase,
+			MaxLockout:   cfg.Auth.LockoutMax,
+		}, logg),
+	}, nil
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// poolStats is the connection pool section of a diagnostics dump. Waits that
+// keep growing while idle stays at zero mean the pool is exhausted.
+type poolStats struct {
+	Total       int64 `json:"total_conns"`
+	Idle        int64 `json:"idle_conns"`
+	InUse       int64 `json:"in_use_conns"`
+	Max         int64 `json:"max_conns,omitempty"`
+	Waits       int64 `json:"waits"` // Acquisitions that had to wait for a connection
+	WaitTotalMS int64 `json:"wait_total_ms"`
+	Timeouts    int64 `json:"timeouts,omitempty"` // Redis: waits that gave up
+	Canceled    int64 `json:"canceled,omitempty"` // Postgres: acquisitions cancelled by their context
+}
+
+// postgresPoolStats reports the pgx pool's connections and waits
+func postgresPoolStats(pool *pgxpool.Pool) poolStats {
+	s := pool.Stat()
+	return poolStats{
+		Total:       int64(s.TotalConns()),
+		Idle:        int64(s.IdleConns()),
+		InUse:       int64(s.AcquiredConns()),
+		Max:         int64(s.MaxConns()),
+		Waits:       s.EmptyAcquireCount(),
+		WaitTotalMS: s.EmptyAcquireWaitTime().Milliseconds(),
+		Canceled:    s.CanceledAcquireCount(),
+	}
+}
+
+// redisPoolStats reports the Redis client's connections and waits
+func redisPoolStats(client *goredis.Client) poolStats {
+	s := client.PoolStats()
+	return poolStats{
+		Total:       int64(s.TotalConns),
+		Idle:        int64(s.IdleConns),
+		InUse:       int64(s.TotalConns) - int64(s.IdleConns),
+		Max:         int64(client.Options().PoolSize),
+		Waits:       int64(s.WaitCount),
+		WaitTotalMS: s.WaitDurationNs / 1e6,
+		Timeouts:    int64(s.Timeouts),
+	}
+}
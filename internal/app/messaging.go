@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/awsmsg"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/kafka"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/nats"
+	"github.com/TopThisHat/stdlib-golang-api/internal/redis"
+	"github.com/TopThisHat/stdlib-golang-api/internal/server"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Messaging (Domain Events, Kafka, SNS/SQS)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// connectMessaging sets up the domain event bus and where its events are
+// forwarded to, and the SQS consumer feeding the App's queue events.
+// Each source messages are dead-lettered from registers its replayer.
+func (w *wiring) connectMessaging() error {
+	cfg, o, logg := w.cfg, w.o, w.logg
+
+	if cfg.DeadLetters.Enabled {
+		w.deadLetters = o.deadLetters
+	}
+
+	// Domain events, for subscribers reacting to business operations: in
+	// process, or through a JetStream or Redis stream every instance consumes
+	if o.eventBus == nil && cfg.EventBus.Backend == "nats" {
+		natsEvents, err := nats.NewEventBus(nats.Config{
+			URL:             cfg.EventBus.NATSURL,
+			User:            cfg.EventBus.NATSUser,
+			Password:        cfg.EventBus.NATSPassword,
+			Token:           cfg.EventBus.NATSToken,
+			Source:          cfg.EventBus.Source,
+			Stream:          cfg.EventBus.NATSStream,
+			Subject:         cfg.EventBus.NATSSubject,
+			Durable:         cfg.EventBus.NATSDurable,
+			AckWait:         cfg.EventBus.NATSAckWait,
+			MaxDeliver:      cfg.EventBus.NATSMaxDeliver,
+			MaxAge:          cfg.EventBus.NATSMaxAge,
+			DuplicateWindow: cfg.EventBus.NATSDuplicateWindow,
+			Timeout:         cfg.EventBus.NATSTimeout,
+			DeadLetters:     w.deadLetters,
+		}, logg)
+		if err != nil {
+			return fmt.Errorf("failed to create NATS event bus: %w", err)
+		}
+		o.eventBus, w.app.eventConsumer = natsEvents, natsEvents
+		w.replayers[domain.DeadLetterNATS] = usecase.EventReplayer(natsEvents.Redeliver)
+		w.lifecycle.OnClose("nats", server.PhasePublishers, func() { natsEvents.Close() })
+		logg.Info("✓ domain events on nats jetstream", "stream", cfg.EventBus.NATSStream, "consumer", cfg.EventBus.NATSDurable)
+	}
+	if o.eventBus == nil && cfg.EventBus.Backend == "redis" {
+		redisEvents := redis.NewEventBus(w.redisClient, redis.EventBusConfig{
+			Stream:      cfg.EventBus.RedisStream,
+			Group:       cfg.EventBus.RedisGroup,
+			Consumer:    cfg.EventBus.RedisConsumer,
+			Source:      cfg.EventBus.Source,
+			MaxLen:      cfg.EventBus.RedisMaxLen,
+			MaxAge:      cfg.EventBus.RedisMaxAge,
+			ClaimIdle:   cfg.EventBus.RedisClaimIdle,
+			MaxDeliver:  cfg.EventBus.RedisMaxDeliver,
+			DeadLetters: w.deadLetters,
+		}, logg)
+		o.eventBus, w.app.eventConsumer = redisEvents, redisEvents
+		w.replayers[domain.DeadLetterRedis] = usecase.EventReplayer(redisEvents.Redeliver)
+		logg.Info("✓ domain events on redis streams", "stream", cfg.EventBus.RedisStream, "group", cfg.EventBus.RedisGroup)
+	}
+	if o.eventBus == nil {
+		o.eventBus = memory.NewEventBus()
+	}
+	w.app.events = o.eventBus
+	w.app.orderEvents = o.orderEvents
+	w.app.orderEventLog = o.orderEventLog
+
+	if err := w.publishToKafka(); err != nil {
+		return err
+	}
+	return w.connectAWSMessaging()
+}
+
+// publishToKafka forwards domain events to other services through Kafka,
+// with KAFKA_BROKERS
+func (w *wiring) publishToKafka() error {
+	cfg := w.cfg
+	if len(cfg.Kafka.Brokers) == 0 {
+		return nil
+	}
+
+	kafkaCfg := kafka.Config{
+		Brokers:  cfg.Kafka.Brokers,
+		ClientID: cfg.Kafka.ClientID,
+		Timeout:  cfg.Kafka.Timeout,
+		SASL: kafka.SASL{
+			Mechanism: cfg.Kafka.SASLMechanism,
+			Username:  cfg.Kafka.SASLUsername,
+			Password:  cfg.Kafka.SASLPassword,
+		},
+	}
+	if cfg.Kafka.TLS {
+		tlsCfg, err := kafka.NewTLSConfig(cfg.Kafka.TLSCAFile, cfg.Kafka.TLSCertFile, cfg.Kafka.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		kafkaCfg.TLS = tlsCfg
+	}
+	producer, err := kafka.NewProducer(kafka.ProducerConfig{
+		Config: kafkaCfg,
+		Topic:  cfg.Kafka.Topic,
+		Source: cfg.EventBus.Source,
+	}, w.logg)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka producer: %w", err)
+	}
+	w.o.eventBus.Subscribe(producer.Publish)
+	w.lifecycle.OnClose("kafka", server.PhasePublishers, func() { producer.Close() })
+	w.logg.Info("✓ publishing domain events to kafka", "topic", cfg.Kafka.Topic)
+	return nil
+}
+
+// connectAWSMessaging forwards domain events through SNS, while an SQS queue
+// (typically subscribed to that topic) feeds the queue events, for work
+// retried until it succeeds
+func (w *wiring) connectAWSMessaging() error {
+	cfg, logg := w.cfg, w.logg
+
+	queueEvents := memory.NewEventBus()
+	w.app.queueEvents = queueEvents
+	if cfg.SNS.TopicARN == "" && cfg.SQS.QueueURL == "" {
+		return nil
+	}
+
+	awsCfg, err := awsConfig(context.Background(), cfg.AWS)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if cfg.SNS.TopicARN != "" {
+		w.o.eventBus.Subscribe(awsmsg.NewPublisher(awsCfg, cfg.SNS.TopicARN, cfg.EventBus.Source).Publish)
+		logg.Info("✓ publishing domain events to sns", "topic", cfg.SNS.TopicARN)
+	}
+	if cfg.SQS.QueueURL != "" {
+		w.app.queueConsumer = awsmsg.NewConsumer(awsCfg, awsmsg.ConsumerConfig{
+			QueueURL:           cfg.SQS.QueueURL,
+			DeadLetterQueueURL: cfg.SQS.DeadLetterQueueURL,
+			MaxReceives:        cfg.SQS.MaxReceives,
+			WaitTime:           cfg.SQS.WaitTime,
+			VisibilityTimeout:  cfg.SQS.VisibilityTimeout,
+			MaxMessages:        cfg.SQS.MaxMessages,
+			Concurrency:        cfg.SQS.Concurrency,
+			DeadLetters:        w.deadLetters,
+		}, logg)
+		// Replayed as if received again
+		w.replayers[domain.DeadLetterSQS] = func(ctx context.Context, d *domain.DeadLetter) error {
+			event, err := awsmsg.DecodeEvent(awsmsg.Message{ID: d.MessageID, Body: string(d.Payload), Attributes: d.Attributes})
+			if err != nil {
+				return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+			}
+			return queueEvents.Publish(ctx, event)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/featureflag"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Option swaps a default implementation, e.g. in-memory repositories for
+// end-to-end tests or a filesystem blob store for local development.
+// Postgres and Redis are only connected when some component still needs them.
+type Option func(*options)
+
+type options struct {
+	logger *logger.Logger
+
+	userRepo        domain.UserRepository
+	orderRepo       domain.OrderRepository
+	accessTokenRepo domain.AccessTokenRepository
+	jobRecordRepo   domain.JobRecordRepository
+	reportRepo      domain.ReportScheduleRepository
+	attachmentRepo  domain.AttachmentRepository
+	// Only used with NOTIFICATIONS_ENABLED
+	notificationPrefs domain.NotificationPreferencesRepository
+	// Only used with CHECKOUT_ENABLED
+	sagaRepo  domain.SagaRepository
+	inventory domain.Inventory
+	payments  domain.PaymentGateway
+	// Only used with DEAD_LETTERS_ENABLED
+	deadLetters domain.DeadLetterQueue
+
+	userCache     domain.UserCache
+	orderCache    domain.OrderCache
+	revocations   domain.TokenRevocationStore
+	loginAttempts domain.LoginAttemptStore
+	semaphores    domain.SemaphoreStore
+	jobQueue      domain.JobQueue
+	requestStats  domain.RequestStatsStore
+	orderEvents   domain.OrderEventBus
+	orderEventLog domain.OrderEventLog
+	eventBus      domain.EventBus
+	transactor    domain.Transactor
+	outbox        domain.Outbox
+	idempotency   domain.IdempotencyStore
+	responseCache domain.ResponseCache
+
+	blobStore    blob.Store
+	mailer       domain.EmailSender
+	flagProvider featureflag.Provider
+
+	securityEvents security.Emitter
+}
+
+// WithLogger uses logg instead of a logger built from the configured level
+func WithLogger(logg *logger.Logger) Option {
+	return func(o *options) {
+		o.logger = logg
+	}
+}
+
+// WithUserRepository replaces the Postgres user repository
+func WithUserRepository(repo domain.UserRepository) Option {
+	return func(o *options) {
+		o.userRepo = repo
+	}
+}
+
+// WithOrderRepository replaces the Postgres order repository
+func WithOrderRepository(repo domain.OrderRepository) Option {
+	return func(o *options) {
+		o.orderRepo = repo
+	}
+}
+
+// WithAccessTokenRepository replaces the Postgres personal access token repository
+func WithAccessTokenRepository(repo domain.AccessTokenRepository) Option {
+	return func(o *options) {
+		o.accessTokenRepo = repo
+	}
+}
+
+// WithJobRecordRepository replaces the Postgres background job status repository
+func WithJobRecordRepository(repo domain.JobRecordRepository) Option {
+	return func(o *options) {
+		o.jobRecordRepo = repo
+	}
+}
+
+// WithReportScheduleRepository replaces the Postgres scheduled report repository
+func WithReportScheduleRepository(repo domain.ReportScheduleRepository) Option {
+	return func(o *options) {
+		o.reportRepo = repo
+	}
+}
+
+// WithAttachmentRepository replaces the Postgres attachment metadata repository
+func WithAttachmentRepository(repo domain.AttachmentRepository) Option {
+	return func(o *options) {
+		o.attachmentRepo = repo
+	}
+}
+
+// WithNotificationPreferencesRepository replaces the Postgres notification
+// preference repository used with NOTIFICATIONS_ENABLED
+func WithNotificationPreferencesRepository(repo domain.NotificationPreferencesRepository) Option {
+	return func(o *options) {
+		o.notificationPrefs = repo
+	}
+}
+
+// WithSagaRepository replaces the Postgres saga repository used with
+// CHECKOUT_ENABLED
+func WithSagaRepository(repo domain.SagaRepository) Option {
+	return func(o *options) {
+		o.sagaRepo = repo
+	}
+}
+
+// WithInventory replaces the Postgres inventory used with CHECKOUT_ENABLED
+func WithInventory(inventory domain.Inventory) Option {
+	return func(o *options) {
+		o.inventory = inventory
+	}
+}
+
+// WithPaymentGateway replaces the CHECKOUT_PAYMENTS_URL payments API (e.g.
+// with a memory.PaymentGateway approving every payment)
+func WithPaymentGateway(gateway domain.PaymentGateway) Option {
+	return func(o *options) {
+		o.payments = gateway
+	}
+}
+
+// WithDeadLetterQueue replaces the Postgres dead-letter queue used with
+// DEAD_LETTERS_ENABLED
+func WithDeadLetterQueue(queue domain.DeadLetterQueue) Option {
+	return func(o *options) {
+		o.deadLetters = queue
+	}
+}
+
+// WithUserCache replaces the Redis user cache
+func WithUserCache(cache domain.UserCache) Option {
+	return func(o *options) {
+		o.userCache = cache
+	}
+}
+
+// WithOrderCache replaces the Redis order cache
+func WithOrderCache(cache domain.OrderCache) Option {
+	return func(o *options) {
+		o.orderCache = cache
+	}
+}
+
+// WithRevocationStore replaces the Redis token denylist
+func WithRevocationStore(store domain.TokenRevocationStore) Option {
+	return func(o *options) {
+		o.revocations = store
+	}
+}
+
+// WithLoginAttemptStore replaces the Redis failed-attempt tracker
+func WithLoginAttemptStore(store domain.LoginAttemptStore) Option {
+	return func(o *options) {
+		o.loginAttempts = store
+	}
+}
+
+// WithSemaphoreStore replaces the Redis store behind cluster-wide semaphores
+func WithSemaphoreStore(store domain.SemaphoreStore) Option {
+	return func(o *options) {
+		o.semaphores = store
+	}
+}
+
+// WithJobQueue replaces the Redis background job queues
+func WithJobQueue(queue domain.JobQueue) Option {
+	return func(o *options) {
+		o.jobQueue = queue
+	}
+}
+
+// WithIdempotencyStore replaces the Redis store behind Idempotency-Key replays
+func WithIdempotencyStore(store domain.IdempotencyStore) Option {
+	return func(o *options) {
+		o.idempotency = store
+	}
+}
+
+// WithResponseCache replaces the Redis cache behind RESPONSE_CACHE_ROUTES
+func WithResponseCache(cache domain.ResponseCache) Option {
+	return func(o *options) {
+		o.responseCache = cache
+	}
+}
+
+// WithRequestStatsStore replaces the Redis hourly request stats behind GET /status
+func WithRequestStatsStore(store domain.RequestStatsStore) Option {
+	return func(o *options) {
+		o.requestStats = store
+	}
+}
+
+// WithOrderEventBus replaces the Redis pub/sub carrying order status changes
+// to the WebSocket clients of every instance
+func WithOrderEventBus(bus domain.OrderEventBus) Option {
+	return func(o *options) {
+		o.orderEvents = bus
+	}
+}
+
+// WithOrderEventLog replaces the Redis Stream of recent order events that
+// GET /api/events streams resume from
+func WithOrderEventLog(log domain.OrderEventLog) Option {
+	return func(o *options) {
+		o.orderEventLog = log
+	}
+}
+
+// WithEventBus replaces the in-process bus carrying domain events (order
+// lifecycle events) to their subscribers
+func WithEventBus(bus domain.EventBus) Option {
+	return func(o *options) {
+		o.eventBus = bus
+	}
+}
+
+// WithOutbox replaces the Postgres outbox used with OUTBOX_ENABLED; tx must
+// span the order repository's writes and outbox's
+func WithOutbox(tx domain.Transactor, outbox domain.Outbox) Option {
+	return func(o *options) {
+		o.transactor = tx
+		o.outbox = outbox
+	}
+}
+
+// WithBlobStore replaces the S3 blob store (e.g. with a FileSystemStore)
+func WithBlobStore(store blob.Store) Option {
+	return func(o *options) {
+		o.blobStore = store
+	}
+}
+
+// WithEmailSender replaces the SMTP (or, without SMTP_HOST, logging) email sender
+func WithEmailSender(sender domain.EmailSender) Option {
+	return func(o *options) {
+		o.mailer = sender
+	}
+}
+
+// WithFeatureFlagProvider replaces the FEATURE_FLAGS_PROVIDER backend (e.g.
+// with a featureflag.StaticProvider pinning flags in tests)
+func WithFeatureFlagProvider(provider featureflag.Provider) Option {
+	return func(o *options) {
+		o.flagProvider = provider
+	}
+}
+
+// WithSecurityEmitter replaces the SECURITY_EVENTS_OUTPUT stream (e.g. to
+// forward events to a SIEM API or capture them in tests)
+func WithSecurityEmitter(emitter security.Emitter) Option {
+	return func(o *options) {
+		o.securityEvents = emitter
+	}
+}
+
+// needsPostgres reports whether any Postgres-backed default is still in use
+func (o *options) needsPostgres() bool {
+	return o.userRepo == nil || o.orderRepo == nil || o.accessTokenRepo == nil || o.jobRecordRepo == nil ||
+		o.reportRepo == nil || o.attachmentRepo == nil
+}
+
+// needsRedis reports whether any Redis-backed default is still in use
+func (o *options) needsRedis() bool {
+	return o.userCache == nil || o.orderCache == nil || o.revocations == nil || o.loginAttempts == nil || o.semaphores == nil ||
+		o.jobQueue == nil || o.orderEvents == nil
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/chaos"
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	transporthttp "github.com/TopThisHat/stdlib-golang-api/internal/transport/http"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// HTTP Transport with Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// newRouter mounts h behind the middleware stack, creates the listeners
+// serving it and subscribes the router to runtime configuration changes
+func (w *wiring) newRouter(h *handlers, authn *authServices) error {
+	cfg, o, logg, a := w.cfg, w.o, w.logg, w.app
+
+	// Requests being served, listed in diagnostics dumps (longest-running first)
+	inFlight := middleware.NewInFlight()
+	w.collector.Register("http_in_flight", func() any { return inFlight.Snapshot(20) })
+
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.HTTP.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	// Configure router with middleware stack
+	routerConfig := transporthttp.RouterConfig{
+		Logger:               logg,
+		Ready:                w.lifecycle.Ready,
+		EnableCORS:           cfg.HTTP.EnableCORS,
+		CORSAllowCredentials: cfg.HTTP.CORSAllowCredentials,
+		CORSMaxAge:           cfg.HTTP.CORSMaxAge,
+		CORSRoutes:           corsRoutes(cfg.HTTP.CORSRoutes),
+		AllowedOrigins:       cfg.HTTP.AllowedOrigins,
+		TrustedProxies:       trustedProxies,
+		RateLimitPerMinute:   cfg.HTTP.RateLimitPerMinute,
+		RouteClassLimits:     cfg.HTTP.RateLimitClasses,
+		RouteLimits:          routeRateLimits(cfg.HTTP.RateLimitRoutes),
+		RateLimitTiers:       rateLimitTiers(cfg.HTTP),
+		RequestTimeout:       cfg.HTTP.RequestTimeout,
+		RouteTimeouts:        cfg.HTTP.RouteTimeouts,
+		Concurrency: transporthttp.ConcurrencyConfig{
+			Max:          cfg.HTTP.MaxConcurrentRequests,
+			Routes:       cfg.HTTP.ConcurrencyRouteLimits,
+			QueueTimeout: cfg.HTTP.ConcurrencyQueueTimeout,
+		},
+		MaxBodySize:             cfg.HTTP.MaxBodyBytes,
+		MaxDecompressedBodySize: cfg.HTTP.MaxDecompressedBodyBytes,
+
+		ResponseWarnBytes:      cfg.HTTP.ResponseWarnBytes,
+		ResponseMaxBytes:       cfg.HTTP.ResponseMaxBytes,
+		TruncateLargeResponses: cfg.HTTP.TruncateLargeResponses,
+
+		SecurityEvents: o.securityEvents,
+		FeatureFlags:   w.flags,
+		InFlight:       inFlight,
+		APIVersions:    apiVersionPolicy(cfg.API),
+		SeparateAdmin:  cfg.HTTP.AdminAddr != "",
+	}
+	if cfg.API.IdempotencyKeyTTL > 0 {
+		routerConfig.Idempotency = o.idempotency
+		routerConfig.IdempotencyTTL = cfg.API.IdempotencyKeyTTL
+	}
+	if len(cfg.HTTP.ResponseCacheRoutes) > 0 {
+		routerConfig.ResponseCache = o.responseCache
+		routerConfig.ResponseCacheRoutes = cfg.HTTP.ResponseCacheRoutes
+	}
+	if a.status != nil {
+		routerConfig.RequestStats = a.status
+	}
+	if w.slo != nil {
+		routerConfig.RouteStats = w.slo
+	}
+	if a.alerts != nil {
+		routerConfig.Alerts = a.alerts
+	}
+	if w.injector != nil {
+		routerConfig.Chaos = w.injector
+		routerConfig.ChaosHeaders = cfg.Chaos.AllowHeaders
+	}
+	if h.upload != nil {
+		routerConfig.UploadChunkSize = cfg.Attachments.ResumableChunkBytes
+	}
+	if cfg.Auth.EnableAuthentication {
+		routerConfig.Tokens = authn.tokens
+		routerConfig.SessionCookie = cfg.Auth.SessionCookie
+		routerConfig.CSRFCookie = cfg.Auth.CSRFCookie
+		routerConfig.CSRFHeader = cfg.Auth.CSRFHeader
+		routerConfig.AccessTokens = authn.accessTokens
+		routerConfig.Revocations = o.revocations
+		routerConfig.BruteForce = authn.bruteForce
+	}
+
+	// Create router with all middleware applied
+	a.router = transporthttp.NewRouter(routerConfig, transporthttp.Handlers{
+		User:            h.user,
+		Order:           h.order,
+		Session:         h.session,
+		AccessToken:     h.accessToken,
+		Job:             h.job,
+		Report:          h.report,
+		TemplatePreview: h.templatePreview,
+		Attachment:      h.attachment,
+		Upload:          h.upload,
+		Feature:         h.feature,
+		Diagnostics:     h.diagnostics,
+		Status:          h.status,
+		GraphQL:         h.graphql,
+		OrderStream:     h.orderStream,
+		Event:           h.event,
+		SLO:             h.slo,
+		UserData:        h.userData,
+		Notification:    h.notification,
+		Checkout:        h.checkout,
+		DeadLetter:      h.deadLetter,
+	})
+	if err := a.buildServers(o); err != nil {
+		return err
+	}
+
+	logg.Info("✓ middleware stack configured",
+		"cors", cfg.HTTP.EnableCORS,
+		"rate_limit", cfg.HTTP.RateLimitPerMinute,
+		"rate_limit_classes", cfg.HTTP.RateLimitClasses,
+		"rate_limit_routes", len(cfg.HTTP.RateLimitRoutes),
+		"rate_limit_tiers", cfg.HTTP.RateLimitTiers,
+		"max_concurrent_requests", cfg.HTTP.MaxConcurrentRequests,
+		"request_timeout", cfg.HTTP.RequestTimeout,
+		"authentication", cfg.Auth.EnableAuthentication,
+		"tls", cfg.HTTP.TLSEnabled(),
+		"client_auth", cfg.HTTP.TLSClientAuth,
+		"h2c", cfg.HTTP.EnableH2C,
+		"admin_addr", cfg.HTTP.AdminAddr,
+	)
+
+	w.watchRuntimeConfig()
+	return nil
+}
+
+// watchRuntimeConfig applies what SIGHUP (or a CONFIG_FILE change) reloads:
+// log level, rate limits, CORS origins and fault injection rates
+func (w *wiring) watchRuntimeConfig() {
+	logg, router, injector := w.logg, w.app.router, w.injector
+
+	w.app.configStore.Subscribe(func(old, new *config.Config) {
+		logg.SetLevel(new.LogLevel)
+		router.SetRateLimit(new.HTTP.RateLimitPerMinute)
+		router.SetRouteClassLimits(new.HTTP.RateLimitClasses)
+		router.SetRouteLimits(routeRateLimits(new.HTTP.RateLimitRoutes))
+		router.SetRateLimitTiers(rateLimitTiers(new.HTTP))
+		router.SetAllowedOrigins(new.HTTP.AllowedOrigins)
+		if injector != nil {
+			injector.SetFaults(chaos.NewFaults(new.Chaos.ErrorPercent, new.Chaos.LatencyMS))
+		}
+		logg.Info("✓ runtime configuration applied",
+			"log_level", new.LogLevel,
+			"rate_limit", new.HTTP.RateLimitPerMinute,
+			"rate_limit_classes", new.HTTP.RateLimitClasses,
+			"rate_limit_routes", len(new.HTTP.RateLimitRoutes),
+			"rate_limit_tiers", new.HTTP.RateLimitTiers,
+			"allowed_origins", new.HTTP.AllowedOrigins,
+		)
+	})
+}
@@ -0,0 +1,285 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/broadcast"
+	"github.com/TopThisHat/stdlib-golang-api/internal/diagnostics"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/templates"
+	transporthttp "github.com/TopThisHat/stdlib-golang-api/internal/transport/http"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Dependency Graph (Repositories → Caches → Services → Handlers)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// handlers are the HTTP handlers newRouter mounts; those of disabled
+// features are nil
+type handlers struct {
+	user            *transporthttp.UserHandler
+	order           *transporthttp.OrderHandler
+	session         *transporthttp.SessionHandler
+	accessToken     *transporthttp.AccessTokenHandler
+	job             *transporthttp.JobHandler
+	report          *transporthttp.ReportHandler
+	templatePreview *transporthttp.TemplatePreviewHandler
+	attachment      *transporthttp.AttachmentHandler
+	upload          *transporthttp.UploadHandler
+	feature         *transporthttp.FeatureHandler
+	diagnostics     *transporthttp.DiagnosticsHandler
+	status          *transporthttp.StatusHandler
+	graphql         *transporthttp.GraphQLHandler
+	orderStream     *transporthttp.OrderStreamHandler
+	event           *transporthttp.EventHandler
+	slo             *transporthttp.SLOHandler
+	userData        *transporthttp.UserDataHandler
+	notification    *transporthttp.NotificationHandler
+	checkout        *transporthttp.CheckoutHandler
+	deadLetter      *transporthttp.DeadLetterHandler
+}
+
+// services are the use-cases the optional features build on
+type services struct {
+	users     *usecase.UserService
+	orders    *usecase.OrderService
+	templates *templates.Engine
+}
+
+// newServices builds the use-cases (business logic orchestrators with cache
+// integration), the background job runner and the handlers serving them
+func (w *wiring) newServices(authn *authServices) (*handlers, error) {
+	cfg, o, logg, a := w.cfg, w.o, w.logg, w.app
+
+	svc := services{users: usecase.NewUserService(o.userRepo, o.userCache, logg)}
+	orderOpts := []usecase.OrderServiceOption{
+		usecase.WithOrderFeatureFlags(w.flags),
+		usecase.WithOrderEvents(o.orderEvents),
+		usecase.WithOrderEventLog(o.orderEventLog),
+		usecase.WithDomainEvents(o.eventBus),
+	}
+	// Lifecycle events written with the order changes, relayed by outboxRelay
+	if cfg.Outbox.Enabled {
+		orderOpts = append(orderOpts, usecase.WithOutbox(o.transactor, o.outbox))
+		var relayOpts []usecase.OutboxRelayOption
+		if w.deadLetters != nil {
+			relayOpts = append(relayOpts, usecase.WithOutboxDeadLetters(w.deadLetters))
+			w.replayers[domain.DeadLetterOutbox] = usecase.OutboxReplayer(o.eventBus)
+		}
+		a.outboxRelay = usecase.NewOutboxRelay(o.outbox, o.eventBus, usecase.OutboxRelayPolicy{
+			PollInterval: cfg.Outbox.PollInterval,
+			BatchSize:    cfg.Outbox.BatchSize,
+			Retention:    cfg.Outbox.Retention,
+			MaxAttempts:  cfg.Outbox.MaxAttempts,
+		}, logg, relayOpts...)
+	}
+	svc.orders = usecase.NewOrderService(o.orderRepo, o.userRepo, o.orderCache, logg, orderOpts...)
+
+	// Cluster-wide concurrency limits for expensive operations (reports, exports, imports)
+	a.semaphores = usecase.NewSemaphores(o.semaphores, usecase.SemaphorePolicy{
+		Limit:       cfg.Semaphores.DefaultLimit,
+		LeaseTTL:    cfg.Semaphores.LeaseTTL,
+		WaitTimeout: cfg.Semaphores.WaitTimeout,
+	}, cfg.Semaphores.Limits, logg)
+
+	// Templates for notification emails and invoices (TEMPLATES_DIR overrides the embedded defaults)
+	tmpl, err := templates.New(cfg.TemplatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+	svc.templates = tmpl
+
+	// Background jobs: per-priority queues with weighted polling and worker limits
+	var jobOpts []usecase.JobRunnerOption
+	if w.deadLetters != nil {
+		jobOpts = append(jobOpts, usecase.WithJobDeadLetters(w.deadLetters))
+	}
+	a.jobs = usecase.NewJobRunner(o.jobQueue, o.jobRecordRepo, jobRunnerPolicy(cfg.Jobs), logg, jobOpts...)
+	w.replayers[domain.DeadLetterJobs] = usecase.JobReplayer(a.jobs)
+
+	a.diagnosticsSink = diagnostics.NewLogSink(logg)
+	if cfg.Diagnostics.Output == "blob" {
+		a.diagnosticsSink = diagnostics.NewBlobSink(o.blobStore, cfg.Diagnostics.BlobPrefix)
+	}
+
+	// HTTP handlers (transport layer)
+	var sessionCookies transporthttp.SessionCookies
+	if cfg.Auth.EnableAuthentication {
+		sessionCookies = transporthttp.SessionCookies{Session: cfg.Auth.SessionCookie, CSRF: cfg.Auth.CSRFCookie}
+	}
+	h := &handlers{
+		user: transporthttp.NewUserHandler(svc.users, logg),
+		order: transporthttp.NewOrderHandler(svc.orders, logg,
+			transporthttp.WithDisplayCurrency(cfg.DisplayCurrency),
+			transporthttp.WithInvoiceTemplates(tmpl)),
+		session:     transporthttp.NewSessionHandler(authn.sessions, sessionCookies, logg),
+		accessToken: transporthttp.NewAccessTokenHandler(authn.accessTokens, logg),
+		job:         transporthttp.NewJobHandler(a.jobs, logg),
+		feature:     transporthttp.NewFeatureHandler(w.flags, logg),
+		diagnostics: transporthttp.NewDiagnosticsHandler(w.collector, a.diagnosticsSink, logg),
+	}
+
+	if err := w.newBackgroundFeatures(h, svc); err != nil {
+		return nil, err
+	}
+	w.newReadFeatures(h, svc)
+
+	logg.Info("✓ services initialized",
+		"user_service", "ready",
+		"order_service", "ready")
+	return h, nil
+}
+
+// newBackgroundFeatures builds the optional features that do their work in
+// background jobs or sagas: user data, notifications, checkout, reports, and
+// the replay of whatever they dead-letter
+func (w *wiring) newBackgroundFeatures(h *handlers, svc services) error {
+	cfg, o, logg, a := w.cfg, w.o, w.logg, w.app
+
+	// User imports and data exports: run as background jobs, output stored in the blob store
+	if cfg.UserData.Enabled {
+		userData := usecase.NewUserDataService(svc.users, o.orderRepo, o.blobStore, a.jobs, usecase.UserDataPolicy{
+			BlobPrefix:  cfg.UserData.BlobPrefix,
+			ImportChunk: cfg.UserData.ImportChunk,
+		}, logg)
+		userData.Register()
+		h.userData = transporthttp.NewUserDataHandler(userData, a.jobs, logg)
+		logg.Info("✓ user imports and data exports enabled", "blob_prefix", cfg.UserData.BlobPrefix)
+	}
+
+	// Notifications of order events, on the channels each user chose, sent as background jobs
+	if cfg.Notifications.Enabled {
+		if o.mailer == nil {
+			o.mailer = newEmailSender(cfg.Email, logg)
+		}
+		notifiers, err := newNotifiers(cfg.Notifications, cfg.AWS, o.mailer)
+		if err != nil {
+			return err
+		}
+		defaults := make([]domain.NotificationChannel, len(cfg.Notifications.DefaultChannels))
+		for i, channel := range cfg.Notifications.DefaultChannels {
+			defaults[i] = domain.NotificationChannel(channel)
+		}
+		notifications := usecase.NewNotificationService(o.notificationPrefs, o.userRepo, notifiers, a.jobs,
+			usecase.NotificationPolicy{DefaultChannels: defaults}, logg)
+		notifications.Register(o.eventBus)
+		h.notification = transporthttp.NewNotificationHandler(notifications, logg)
+		logg.Info("✓ notifications enabled", "channels", notifications.Channels(), "defaults", defaults)
+	}
+
+	// Checkout of orders as a saga: payment captured, stock reserved, order confirmed, or all undone
+	if cfg.Checkout.Enabled {
+		payments, err := newPaymentGateway(o, cfg, logg)
+		if err != nil {
+			return err
+		}
+		a.sagas = usecase.NewSagaCoordinator(o.sagaRepo, usecase.SagaPolicy{
+			StepAttempts:     cfg.Checkout.StepAttempts,
+			RetryDelay:       cfg.Checkout.RetryDelay,
+			StepTimeout:      cfg.Checkout.StepTimeout,
+			RecoveryInterval: cfg.Checkout.RecoveryInterval,
+			StaleAfter:       cfg.Checkout.StaleAfter,
+		}, logg)
+		checkout := usecase.NewCheckoutService(a.sagas, svc.orders, payments, o.inventory, cfg.Checkout.Currency, logg)
+		h.checkout = transporthttp.NewCheckoutHandler(checkout, logg)
+		logg.Info("✓ checkout enabled", "currency", cfg.Checkout.Currency, "payments_url", cfg.Checkout.PaymentsURL)
+	}
+
+	// Inspection and replay of dead letters, once whatever failed them is fixed
+	if w.deadLetters != nil {
+		a.deadLetters = usecase.NewDeadLetterService(w.deadLetters, cfg.DeadLetters.Retention, logg)
+		for source, replay := range w.replayers {
+			a.deadLetters.Register(source, replay)
+		}
+		h.deadLetter = transporthttp.NewDeadLetterHandler(a.deadLetters, logg)
+		logg.Info("✓ dead letters kept for replay", "retention", cfg.DeadLetters.Retention)
+	}
+
+	// Scheduled reports: rendered by a background job, stored in the blob store and emailed as a link
+	if cfg.Reports.Enabled {
+		if o.mailer == nil {
+			o.mailer = newEmailSender(cfg.Email, logg)
+		}
+		a.reports = usecase.NewReportService(o.reportRepo, o.orderRepo, o.blobStore, o.mailer, a.jobs, usecase.ReportPolicy{
+			BlobPrefix: cfg.Reports.BlobPrefix,
+			LinkTTL:    cfg.Reports.LinkTTL,
+		}, logg, usecase.WithReportTemplates(svc.templates))
+		a.jobs.Handle(usecase.JobTypeReportEmail, a.reports.EmailReport)
+		h.report = transporthttp.NewReportHandler(a.reports, logg)
+		logg.Info("✓ scheduled reports enabled", "check_interval", cfg.Reports.CheckInterval, "smtp", cfg.Email.SMTPHost != "")
+	}
+	return nil
+}
+
+// newReadFeatures builds the optional features serving requests directly:
+// status and SLO reporting, alerting, GraphQL, live streams, attachments and
+// template previews
+func (w *wiring) newReadFeatures(h *handlers, svc services) {
+	cfg, o, logg, a := w.cfg, w.o, w.logg, w.app
+
+	// Public status page data: hourly request counts merged across instances
+	if cfg.Status.Enabled {
+		policy := usecase.DefaultStatusPolicy()
+		policy.FlushInterval = cfg.Status.FlushInterval
+		policy.CacheTTL = cfg.Status.CacheTTL
+		a.status = usecase.NewStatusService(o.requestStats, policy, logg)
+		h.status = transporthttp.NewStatusHandler(a.status, cfg.Status.CacheTTL, logg)
+	}
+
+	// Per-route SLO burn rates for alerting, counted per instance in memory
+	if cfg.SLO.Enabled {
+		slo := usecase.NewSLOService(sloPolicy(cfg.SLO))
+		w.slo = slo
+		h.slo = transporthttp.NewSLOHandler(slo)
+		w.collector.Register("slo", func() any { return transporthttp.SLOStatusReport(slo.Status()) })
+	}
+
+	// Paging on error rate and panics, counted per instance in memory
+	if cfg.Alerts.Enabled {
+		a.alerts = newAlertMonitor(cfg.Alerts, logg)
+	}
+
+	// GraphQL over the same services, with batched loading of nested fields
+	if cfg.GraphQL.Enabled {
+		h.graphql = transporthttp.NewGraphQLHandler(svc.users, svc.orders, cfg.GraphQL.MaxDepth, logg)
+	}
+
+	// Live order status over WebSocket (fed by the order event bus in Run) and
+	// order events over SSE (fed by the order event log), sharing one hub
+	if cfg.WebSocket.Enabled || cfg.SSE.Enabled {
+		hub := broadcast.NewHub(logg)
+		a.hub = hub
+		w.collector.Register("broadcast_hub", func() any { return hub.Stats() })
+	}
+	if cfg.WebSocket.Enabled {
+		h.orderStream = transporthttp.NewOrderStreamHandler(svc.orders, a.hub, cfg.WebSocket.PingInterval, cfg.WebSocket.WriteTimeout, logg)
+		a.orderStream = h.orderStream
+	}
+	if cfg.SSE.Enabled {
+		h.event = transporthttp.NewEventHandler(o.orderEventLog, a.hub, cfg.SSE.HeartbeatInterval, logg)
+		a.eventStream = h.event
+	}
+
+	// Attachments: direct-to-store uploads, downloadable once the malware
+	// scanner reports them clean, and tus resumable uploads through the API
+	if cfg.Attachments.Enabled {
+		attachmentSvc := usecase.NewAttachmentService(o.attachmentRepo, o.blobStore, o.semaphores, usecase.AttachmentPolicy{
+			KeyPrefix:           cfg.Attachments.KeyPrefix,
+			MaxSize:             cfg.Attachments.MaxBytes,
+			UploadURLTTL:        cfg.Attachments.UploadURLTTL,
+			DownloadURLTTL:      cfg.Attachments.DownloadURLTTL,
+			AllowedContentTypes: cfg.Attachments.AllowedTypes,
+			PartPrefix:          cfg.Attachments.PartPrefix,
+		}, logg)
+		h.attachment = transporthttp.NewAttachmentHandler(attachmentSvc, logg)
+		if cfg.Attachments.Resumable {
+			h.upload = transporthttp.NewUploadHandler(attachmentSvc, logg)
+		}
+	}
+
+	// Template previews with sample data, for editing TEMPLATES_DIR locally
+	if cfg.IsDevelopment() {
+		h.templatePreview = transporthttp.NewTemplatePreviewHandler(svc.templates, logg)
+	}
+}
@@ -0,0 +1,398 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	"github.com/TopThisHat/stdlib-golang-api/internal/diagnostics"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/featureflag"
+	"github.com/TopThisHat/stdlib-golang-api/internal/fixtures"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres"
+	"github.com/TopThisHat/stdlib-golang-api/internal/postgres/migrations"
+	"github.com/TopThisHat/stdlib-golang-api/internal/redis"
+	"github.com/TopThisHat/stdlib-golang-api/internal/repository"
+	"github.com/TopThisHat/stdlib-golang-api/internal/resilience"
+	"github.com/TopThisHat/stdlib-golang-api/internal/server"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Storage (Databases, Caches, Blob Store)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// openStores fills every repository, cache and store not supplied as an
+// option: in process with DEV_INMEMORY, otherwise by connecting Postgres and
+// Redis, but only when something still needs them. Caches and the blob store
+// are then wrapped in circuit breakers and instrumented for diagnostics.
+func (w *wiring) openStores() error {
+	cfg, o, logg := w.cfg, w.o, w.logg
+
+	// DEV_INMEMORY: whatever wasn't supplied as an option lives in process,
+	// so neither Postgres nor Redis is connected below. RUN_MODE=standalone
+	// also keeps blobs in memory and disables caching, touching nothing
+	// outside the process.
+	if cfg.InMemory() {
+		if cfg.IsStandalone() {
+			logg.Warn("⚠️  RUN_MODE=standalone: running without external services; data is lost on exit")
+			setStandaloneDefaults(o)
+		} else {
+			logg.Warn("⚠️  DEV_INMEMORY: running without Postgres or Redis; data is lost on exit",
+				"blob_dir", cfg.DevBlobDir)
+		}
+		if err := setInMemoryDefaults(o, cfg.DevBlobDir, logg); err != nil {
+			return err
+		}
+	}
+
+	if err := w.openPostgres(); err != nil {
+		return err
+	}
+	w.openRedis()
+
+	// Recent order events, so GET /api/events streams can resume (a Redis
+	// Stream shared by all instances, in process with DEV_INMEMORY)
+	if o.orderEventLog == nil && cfg.SSE.Enabled {
+		if w.redisClient != nil && !cfg.InMemory() {
+			o.orderEventLog = redis.NewOrderEventLog(w.redisClient, cfg.SSE.RetainEvents)
+		} else {
+			o.orderEventLog = memory.NewOrderEventLog(cfg.SSE.RetainEvents)
+		}
+	}
+
+	// Feature flags for gradual rollouts (FEATURE_FLAGS_PROVIDER)
+	if o.flagProvider == nil {
+		provider, err := newFlagProvider(cfg.FeatureFlags, w.redisClient, logg)
+		if err != nil {
+			return fmt.Errorf("failed to load feature flags: %w", err)
+		}
+		o.flagProvider = provider
+		logg.Info("✓ feature flags configured", "provider", cfg.FeatureFlags.Provider)
+	}
+	w.flags = featureflag.NewClient(o.flagProvider, logg)
+
+	// Blob store (BLOB_BACKEND) shared by the ACME certificate cache, scheduled reports, attachments, user data jobs and diagnostics dumps
+	if o.blobStore == nil && (cfg.HTTP.ACMEEnabled() || cfg.Reports.Enabled || cfg.Attachments.Enabled || cfg.UserData.Enabled || cfg.Diagnostics.Output == "blob") {
+		store, location, err := NewBlobStore(context.Background(), cfg, logg)
+		if err != nil {
+			return fmt.Errorf("failed to create blob store: %w", err)
+		}
+		o.blobStore = store
+		logg.Info("✓ blob store configured", "backend", cfg.Blob.Backend, "location", location)
+	}
+
+	// Bucket lifecycle rules expiring old reports, exports and unfinished uploads (BLOB_MANAGE_LIFECYCLE)
+	if manager, ok := o.blobStore.(blob.LifecycleManager); ok && cfg.Blob.ManageLifecycle && !cfg.InMemory() {
+		if err := applyBlobLifecycle(context.Background(), manager, cfg, logg); err != nil {
+			return fmt.Errorf("failed to set blob lifecycle rules: %w", err)
+		}
+	}
+
+	w.guardStores()
+	return nil
+}
+
+// openPostgres connects the pool and fills the Postgres-backed repositories
+// still missing, if any are
+func (w *wiring) openPostgres() error {
+	cfg, o, logg := w.cfg, w.o, w.logg
+
+	outboxInPostgres := o.outbox == nil && cfg.Outbox.Enabled
+	notificationsInPostgres := o.notificationPrefs == nil && cfg.Notifications.Enabled
+	sagasInPostgres := o.sagaRepo == nil && cfg.Checkout.Enabled
+	inventoryInPostgres := o.inventory == nil && cfg.Checkout.Enabled
+	deadLettersInPostgres := o.deadLetters == nil && cfg.DeadLetters.Enabled
+	if !o.needsPostgres() && !outboxInPostgres && !notificationsInPostgres && !sagasInPostgres && !inventoryInPostgres && !deadLettersInPostgres {
+		return nil
+	}
+
+	// PostgreSQL connection pool (pgx v5), logging queries under their request ID
+	poolOpts := []postgres.PoolOption{postgres.WithRequestTracing(middleware.GetRequestID, logg)}
+	if w.injector != nil {
+		poolOpts = append(poolOpts, postgres.WithFaultInjection(w.injector))
+	}
+	pgPool, err := postgres.NewPgxPool(cfg.Postgres, logg, poolOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	w.lifecycle.OnClose("postgres", server.PhaseStores, pgPool.Close)
+	w.collector.Register("postgres_pool", func() any { return postgresPoolStats(pgPool) })
+	logg.Info("✓ postgres connection pool established")
+
+	// Development convenience; deployed environments run `api migrate` instead
+	if cfg.AutoMigrate {
+		migrator, err := migrations.New(pgPool, logg)
+		if err != nil {
+			return fmt.Errorf("failed to load migrations: %w", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		logg.Info("✓ database migrated", "applied", applied)
+	}
+
+	// Repositories (adapters implementing our interfaces)
+	repoRetry := repository.WithRetry(retryPolicy(cfg.Retry, "postgres"))
+	if o.userRepo == nil {
+		o.userRepo = repository.NewUserRepo(pgPool, logg, repoRetry)
+	}
+	if o.orderRepo == nil {
+		o.orderRepo = repository.NewOrderRepo(pgPool, logg, repoRetry)
+	}
+	if o.accessTokenRepo == nil {
+		o.accessTokenRepo = repository.NewAccessTokenRepo(pgPool, logg, repoRetry)
+	}
+	if o.jobRecordRepo == nil {
+		o.jobRecordRepo = repository.NewJobRecordRepo(pgPool, logg, repoRetry)
+	}
+	if o.reportRepo == nil {
+		o.reportRepo = repository.NewReportScheduleRepo(pgPool, logg, repoRetry)
+	}
+	if o.attachmentRepo == nil {
+		o.attachmentRepo = repository.NewAttachmentRepo(pgPool, logg, repoRetry)
+	}
+	if outboxInPostgres {
+		o.transactor = repository.NewTransactor(pgPool, repoRetry)
+		o.outbox = repository.NewOutboxRepo(pgPool, logg, repoRetry)
+	}
+	if notificationsInPostgres {
+		o.notificationPrefs = repository.NewNotificationPreferencesRepo(pgPool, logg, repoRetry)
+	}
+	if sagasInPostgres {
+		o.sagaRepo = repository.NewSagaRepo(pgPool, logg, repoRetry)
+	}
+	if inventoryInPostgres {
+		o.inventory = repository.NewInventoryRepo(pgPool, logg, repoRetry)
+	}
+	if deadLettersInPostgres {
+		o.deadLetters = repository.NewDeadLetterRepo(pgPool, logg, repoRetry)
+	}
+	return nil
+}
+
+// openRedis creates the client and fills the Redis-backed stores still
+// missing, if any are. The event bus and order event log are left to their
+// own steps, which reuse w.redisClient.
+func (w *wiring) openRedis() {
+	cfg, o, logg := w.cfg, w.o, w.logg
+
+	flagsInRedis := o.flagProvider == nil && cfg.FeatureFlags.Provider == "redis"
+	statsInRedis := o.requestStats == nil && cfg.Status.Enabled
+	eventLogInRedis := o.orderEventLog == nil && cfg.SSE.Enabled && !cfg.InMemory()
+	eventsInRedis := o.eventBus == nil && cfg.EventBus.Backend == "redis"
+	idempotencyInRedis := o.idempotency == nil && cfg.API.IdempotencyKeyTTL > 0
+	responseCacheInRedis := o.responseCache == nil && len(cfg.HTTP.ResponseCacheRoutes) > 0
+	if !o.needsRedis() && !flagsInRedis && !statsInRedis && !eventLogInRedis && !eventsInRedis && !idempotencyInRedis && !responseCacheInRedis {
+		return
+	}
+
+	// Redis client for caching
+	client := redis.NewRedisClient(cfg.Redis)
+	client.AddHook(redis.NewTracingHook(middleware.GetRequestID, logg))
+	if w.injector != nil {
+		client.AddHook(redis.NewChaosHook(w.injector))
+	}
+	w.lifecycle.OnShutdown("redis", server.PhaseStores, 0, func(context.Context) error {
+		return client.Close()
+	})
+	w.collector.Register("redis_pool", func() any { return redisPoolStats(client) })
+	logg.Info("✓ redis client initialized", "addr", cfg.Redis.Addr)
+	setRedisDefaults(o, client, cfg.Retry, logg)
+	w.redisClient = client
+}
+
+// guardStores puts caches and the blob store behind circuit breakers and
+// bulkheads, so a slow Redis or S3 degrades requests (cache misses, missing
+// download links) instead of stalling them, and counts cache hit rates for
+// diagnostics dumps
+func (w *wiring) guardStores() {
+	cfg, o, logg := w.cfg, w.o, w.logg
+
+	if cfg.Resilience.Enabled {
+		guards := make(map[string]*resilience.Guard)
+		if o.userCache != nil || o.orderCache != nil || o.responseCache != nil {
+			guards["cache"] = newGuard("cache", cfg.Resilience, logg, domain.ErrCacheMiss)
+		}
+		if o.userCache != nil {
+			o.userCache = resilience.GuardUserCache(o.userCache, guards["cache"])
+		}
+		if o.orderCache != nil {
+			o.orderCache = resilience.GuardOrderCache(o.orderCache, guards["cache"])
+		}
+		if o.responseCache != nil {
+			o.responseCache = resilience.GuardResponseCache(o.responseCache, guards["cache"])
+		}
+		if o.blobStore != nil {
+			guards["blob"] = newGuard("blob", cfg.Resilience, logg, resilience.BlobErrors...)
+			o.blobStore = resilience.GuardBlobStore(o.blobStore, guards["blob"])
+		}
+		w.collector.Register("dependencies", func() any {
+			stats := make(map[string]resilience.GuardStats, len(guards))
+			for name, guard := range guards {
+				stats[name] = guard.Stats()
+			}
+			return stats
+		})
+	}
+
+	if o.userCache != nil {
+		var counters *diagnostics.CacheCounters
+		o.userCache, counters = diagnostics.InstrumentUserCache(o.userCache)
+		w.collector.Register("user_cache", func() any { return counters.Stats() })
+	}
+	if o.orderCache != nil {
+		var counters *diagnostics.CacheCounters
+		o.orderCache, counters = diagnostics.InstrumentOrderCache(o.orderCache)
+		w.collector.Register("order_cache", func() any { return counters.Stats() })
+	}
+}
+
+// setStandaloneDefaults keeps blobs in memory and disables caching, unless
+// supplied as options, ahead of setInMemoryDefaults
+func setStandaloneDefaults(o *options) {
+	if o.blobStore == nil {
+		o.blobStore = blob.NewMemoryStore()
+	}
+	if o.userCache == nil {
+		o.userCache = memory.NopUserCache{}
+	}
+	if o.orderCache == nil {
+		o.orderCache = memory.NopOrderCache{}
+	}
+}
+
+// setInMemoryDefaults fills every Postgres-, Redis- and S3-backed dependency
+// not supplied as an option with an in-process one, keeping blobs under
+// blobDir. In-memory user and order repositories are seeded with the default
+// fixtures so there is something to browse.
+func setInMemoryDefaults(o *options, blobDir string, logg *logger.Logger) error {
+	seed := o.userRepo == nil && o.orderRepo == nil
+
+	if o.userRepo == nil {
+		o.userRepo = memory.NewUserRepository()
+	}
+	if o.orderRepo == nil {
+		o.orderRepo = memory.NewOrderRepository()
+	}
+	if o.accessTokenRepo == nil {
+		o.accessTokenRepo = memory.NewAccessTokenRepository()
+	}
+	if o.jobRecordRepo == nil {
+		o.jobRecordRepo = memory.NewJobRecordRepository()
+	}
+	if o.reportRepo == nil {
+		o.reportRepo = memory.NewReportScheduleRepository()
+	}
+	if o.attachmentRepo == nil {
+		o.attachmentRepo = memory.NewAttachmentRepository()
+	}
+
+	if o.userCache == nil {
+		o.userCache = memory.NewUserCache()
+	}
+	if o.orderCache == nil {
+		o.orderCache = memory.NewOrderCache()
+	}
+	if o.revocations == nil {
+		o.revocations = memory.NewRevocationStore()
+	}
+	if o.loginAttempts == nil {
+		o.loginAttempts = memory.NewLoginAttemptStore()
+	}
+	if o.semaphores == nil {
+		o.semaphores = memory.NewSemaphoreStore()
+	}
+	if o.jobQueue == nil {
+		o.jobQueue = memory.NewJobQueue()
+	}
+	if o.requestStats == nil {
+		o.requestStats = memory.NewRequestStatsStore()
+	}
+	if o.idempotency == nil {
+		o.idempotency = memory.NewIdempotencyStore()
+	}
+	if o.responseCache == nil {
+		o.responseCache = memory.NewResponseCache()
+	}
+	if o.orderEvents == nil {
+		o.orderEvents = memory.NewOrderEventBus()
+	}
+	if o.outbox == nil {
+		o.transactor = memory.NewTransactor()
+		o.outbox = memory.NewOutbox()
+	}
+	if o.notificationPrefs == nil {
+		o.notificationPrefs = memory.NewNotificationPreferencesRepository()
+	}
+	if o.sagaRepo == nil {
+		o.sagaRepo = memory.NewSagaRepository()
+	}
+	if o.inventory == nil {
+		o.inventory = memory.NewInventory()
+	}
+	if o.deadLetters == nil {
+		o.deadLetters = memory.NewDeadLetterQueue()
+	}
+
+	if o.blobStore == nil {
+		fsStore, err := blob.NewFileSystemStore(blobDir, logg, blob.WithCreateBasePath(true))
+		if err != nil {
+			return fmt.Errorf("failed to create filesystem blob store: %w", err)
+		}
+		o.blobStore = fsStore
+	}
+
+	if seed {
+		f, err := fixtures.Default()
+		if err != nil {
+			return fmt.Errorf("failed to load default fixtures: %w", err)
+		}
+		res, err := fixtures.Apply(context.Background(), f, fixtures.Target{Users: o.userRepo, Orders: o.orderRepo}, logg)
+		if err != nil {
+			return fmt.Errorf("failed to seed in-memory repositories: %w", err)
+		}
+		logg.Info("✓ in-memory repositories seeded", "users", res.UsersCreated, "orders", res.OrdersCreated)
+	}
+	return nil
+}
+
+// setRedisDefaults fills every Redis-backed dependency not supplied as an option
+func setRedisDefaults(o *options, client *goredis.Client, retry config.RetryConfig, logg *logger.Logger) {
+	cacheRetry := redis.WithCacheRetry(retryPolicy(retry, "cache"))
+	if o.userCache == nil {
+		o.userCache = redis.NewUserCache(client, cacheRetry)
+	}
+	if o.orderCache == nil {
+		o.orderCache = redis.NewOrderCache(client, cacheRetry)
+	}
+	if o.revocations == nil {
+		o.revocations = redis.NewRevocationStore(client)
+	}
+	if o.loginAttempts == nil {
+		o.loginAttempts = redis.NewLoginAttemptStore(client)
+	}
+	if o.semaphores == nil {
+		o.semaphores = redis.NewSemaphoreStore(client)
+	}
+	if o.jobQueue == nil {
+		o.jobQueue = redis.NewJobQueue(client)
+	}
+	if o.requestStats == nil {
+		o.requestStats = redis.NewRequestStatsStore(client)
+	}
+	if o.idempotency == nil {
+		o.idempotency = redis.NewIdempotencyStore(client)
+	}
+	if o.responseCache == nil {
+		o.responseCache = redis.NewResponseCache(client)
+	}
+	if o.orderEvents == nil {
+		o.orderEvents = redis.NewOrderEventBus(client, logg)
+	}
+}
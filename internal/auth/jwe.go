@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// JWE (dir + A256GCM)
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// Signed tokens carrying PII are wrapped in a compact JWE:
+//
+//	BASE64URL(header) . "" . BASE64URL(iv) . BASE64URL(ciphertext) . BASE64URL(tag)
+//
+// using direct encryption: the content key is SHA-256 of the "enc" key secret,
+// and the protected header is the additional authenticated data.
+
+// encrypt wraps plaintext (a signed JWT) in a compact JWE
+func encrypt(key Key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	h, err := json.Marshal(header{Alg: "dir", Enc: "A256GCM", Kid: key.ID, Cty: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode header: %w", err)
+	}
+	protected := b64.EncodeToString(h)
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("failed to generate iv: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		protected,
+		"",
+		b64.EncodeToString(iv),
+		b64.EncodeToString(ciphertext),
+		b64.EncodeToString(tag),
+	}, "."), nil
+}
+
+// decrypt unwraps a compact JWE and returns the nested token
+func (m *TokenManager) decrypt(token string, now time.Time) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return nil, ErrTokenMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	if h.Alg != "dir" || h.Enc != "A256GCM" {
+		return nil, fmt.Errorf("%w: unsupported alg/enc %q/%q", ErrTokenMalformed, h.Alg, h.Enc)
+	}
+
+	key, ok := m.keys.lookup(KeyUseEncryption, h.Kid, now, m.ttl)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, h.Kid)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := b64.DecodeString(parts[2])
+	if err != nil || len(iv) != gcm.NonceSize() {
+		return nil, ErrTokenMalformed
+	}
+	ciphertext, err := b64.DecodeString(parts[3])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	tag, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, ErrTokenSignature
+	}
+	return plaintext, nil
+}
+
+func newGCM(key Key) (cipher.AEAD, error) {
+	cek := sha256.Sum256([]byte(key.Secret))
+	block, err := aes.NewCipher(cek[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KeyUse identifies what a key is used for
+type KeyUse string
+
+const (
+	KeyUseSignature  KeyUse = "sig" // HMAC-SHA256 signing of JWTs (JWS)
+	KeyUseEncryption KeyUse = "enc" // AES-256-GCM encryption of JWTs (JWE)
+)
+
+// MinSecretLength is the minimum length of any signing or encryption secret
+const MinSecretLength = 32
+
+// Key is a single signing or encryption key with an optional rotation schedule.
+//
+// A key is used to issue new tokens between NotBefore and NotAfter. After
+// NotAfter it is retired but still accepted for verification until every token
+// it could have issued has expired, so rotation never invalidates live sessions.
+type Key struct {
+	ID        string    `json:"kid"`
+	Secret    string    `json:"secret"`
+	Use       KeyUse    `json:"use"`
+	NotBefore time.Time `json:"not_before,omitempty"` // Zero means active immediately
+	NotAfter  time.Time `json:"not_after,omitempty"`  // Zero means never retired
+}
+
+// activeAt reports whether the key may issue new tokens at t
+func (k Key) activeAt(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	return k.NotAfter.IsZero() || t.Before(k.NotAfter)
+}
+
+// acceptableAt reports whether tokens from this key are still accepted at t,
+// given the maximum lifetime of a token
+func (k Key) acceptableAt(t time.Time, maxLifetime time.Duration) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	return k.NotAfter.IsZero() || t.Before(k.NotAfter.Add(maxLifetime))
+}
+
+// KeySet holds all configured keys
+type KeySet struct {
+	keys []Key
+}
+
+// NewKeySet validates keys and builds a key set
+func NewKeySet(keys ...Key) (*KeySet, error) {
+	seen := make(map[string]bool)
+	for i, k := range keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("key %d: kid is required", i)
+		}
+		if k.Use == "" {
+			k.Use = KeyUseSignature
+			keys[i].Use = KeyUseSignature
+		} else if k.Use != KeyUseSignature && k.Use != KeyUseEncryption {
+			return nil, fmt.Errorf("key %s: unknown use %q (must be sig or enc)", k.ID, k.Use)
+		}
+		if seen[string(k.Use)+":"+k.ID] {
+			return nil, fmt.Errorf("key %s: duplicate kid", k.ID)
+		}
+		seen[string(k.Use)+":"+k.ID] = true
+
+		if len(k.Secret) < MinSecretLength {
+			return nil, fmt.Errorf("key %s: secret must be at least %d characters long", k.ID, MinSecretLength)
+		}
+		if !k.NotAfter.IsZero() && !k.NotBefore.IsZero() && !k.NotAfter.After(k.NotBefore) {
+			return nil, fmt.Errorf("key %s: not_after must be after not_before", k.ID)
+		}
+	}
+
+	return &KeySet{keys: keys}, nil
+}
+
+// LoadKeysFile reads a JSON array of keys, e.g. a secret mounted from a secrets manager:
+//
+//	[
+//	  {"kid": "2025-01", "secret": "...", "use": "sig", "not_after": "2025-07-01T00:00:00Z"},
+//	  {"kid": "2025-07", "secret": "...", "use": "sig", "not_before": "2025-06-24T00:00:00Z"},
+//	  {"kid": "enc-1",   "secret": "...", "use": "enc"}
+//	]
+func LoadKeysFile(path string) (*KeySet, error) {
+	return LoadKeySet("", path)
+}
+
+// LoadKeySet builds the key set from the legacy single secret and/or a keys file.
+// The legacy secret becomes a signing key with kid LegacyKeyID so tokens issued
+// before rotation was introduced keep verifying.
+func LoadKeySet(legacySecret, keysFile string) (*KeySet, error) {
+	var keys []Key
+	if keysFile != "" {
+		data, err := os.ReadFile(keysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keys file: %w", err)
+		}
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse keys file: %w", err)
+		}
+	}
+
+	if legacySecret != "" {
+		// A keys file entry may already claim the legacy kid; don't clash with it
+		hasLegacy := false
+		for _, k := range keys {
+			if k.ID == LegacyKeyID && (k.Use == "" || k.Use == KeyUseSignature) {
+				hasLegacy = true
+			}
+		}
+		if !hasLegacy {
+			keys = append(keys, Key{ID: LegacyKeyID, Secret: legacySecret, Use: KeyUseSignature})
+		}
+	}
+
+	return NewKeySet(keys...)
+}
+
+// errNoActiveKey is returned when no key can currently issue tokens
+var errNoActiveKey = errors.New("no active key")
+
+// primary returns the newest key of the given use that is active at t
+func (s *KeySet) primary(use KeyUse, t time.Time) (Key, error) {
+	var active []Key
+	for _, k := range s.keys {
+		if k.Use == use && k.activeAt(t) {
+			active = append(active, k)
+		}
+	}
+	if len(active) == 0 {
+		return Key{}, fmt.Errorf("%w for use %q", errNoActiveKey, use)
+	}
+
+	// The most recently activated key wins, so a new key takes over as soon as
+	// its schedule starts while the previous one keeps verifying
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].NotBefore.After(active[j].NotBefore)
+	})
+	return active[0], nil
+}
+
+// lookup returns the key with the given id and use if it is still acceptable at t
+func (s *KeySet) lookup(use KeyUse, kid string, t time.Time, maxLifetime time.Duration) (Key, bool) {
+	for _, k := range s.keys {
+		if k.Use == use && k.ID == kid {
+			return k, k.acceptableAt(t, maxLifetime)
+		}
+	}
+	return Key{}, false
+}
+
+// hasUse reports whether any key of the given use is configured
+func (s *KeySet) hasUse(use KeyUse) bool {
+	for _, k := range s.keys {
+		if k.Use == use {
+			return true
+		}
+	}
+	return false
+}
+
+// minSecretEntropyBits is the least estimated entropy a secret must carry.
+// The estimate (character frequencies) understates random secrets, so this
+// sits below the 128 bits a random 32-character hex secret really has.
+const minSecretEntropyBits = 96
+
+// placeholderMarkers appear in example secrets that are never meant to be deployed
+var placeholderMarkers = []string{"your-super-secret", "changeme", "change-me", "example", "placeholder"}
+
+// CheckSecretStrength returns why secret is too weak to sign or encrypt
+// tokens, or nil: it must be long enough, not a placeholder copied from the
+// docs, and not made of a few repeated characters.
+func CheckSecretStrength(secret string) error {
+	if len(secret) < MinSecretLength {
+		return fmt.Errorf("secret is shorter than %d characters", MinSecretLength)
+	}
+	lower := strings.ToLower(secret)
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(lower, marker) {
+			return fmt.Errorf("secret looks like a placeholder (contains %q)", marker)
+		}
+	}
+	if bits := entropyBits(secret); bits < minSecretEntropyBits {
+		return fmt.Errorf("secret has about %.0f bits of entropy, want at least %d", bits, minSecretEntropyBits)
+	}
+	return nil
+}
+
+// entropyBits estimates a string's entropy from its character frequencies
+func entropyBits(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var perChar float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * float64(n)
+}
+
+// Audit reports problems that don't stop the key set from loading but should
+// block a deploy: weak secrets and no signing key able to issue tokens at t
+func (s *KeySet) Audit(t time.Time) []error {
+	var problems []error
+	for _, k := range s.keys {
+		if err := CheckSecretStrength(k.Secret); err != nil {
+			problems = append(problems, fmt.Errorf("key %s (%s): %w", k.ID, k.Use, err))
+		}
+	}
+	if _, err := s.primary(KeyUseSignature, t); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
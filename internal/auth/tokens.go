@@ -0,0 +1,302 @@
+// Package auth issues and verifies JWT access tokens.
+//
+// Tokens are signed with HMAC-SHA256 (JWS) using one of several configured keys,
+// each identified by a key ID (kid) in the token header. New tokens are signed
+// with the newest active key while older keys keep verifying until their tokens
+// expire, so keys can be rotated without logging every user out. Tokens that
+// carry PII claims can additionally be encrypted (JWE, dir + A256GCM).
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTokenMalformed = errors.New("malformed token")
+	ErrTokenSignature = errors.New("invalid token signature")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokenNotYet    = errors.New("token not valid yet")
+	ErrUnknownKey     = errors.New("unknown or retired key")
+)
+
+// LegacyKeyID is the kid assigned to JWT_SECRET. Tokens without a kid header
+// (issued before key rotation was introduced) are verified with this key.
+const LegacyKeyID = "default"
+
+// ScopeAdmin grants access to administrative endpoints
+const ScopeAdmin = "admin"
+
+// clockSkew is the tolerance applied to exp and nbf checks
+const clockSkew = 30 * time.Second
+
+// Claims are the JWT claims understood by the API
+type Claims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss,omitempty"`
+	ID        string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+	Scope     string `json:"scope,omitempty"` // Space-separated scopes
+
+	// PII claims; tokens carrying any of these are encrypted when PII encryption is enabled
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Scopes returns the token's scopes as a slice
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the token grants scope
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsPII reports whether any personally identifiable claim is set
+func (c *Claims) ContainsPII() bool {
+	return c.Email != "" || c.Name != ""
+}
+
+// ExpiresAtTime returns the expiry as a time.Time
+func (c *Claims) ExpiresAtTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// IssuedAtTime returns the issue time as a time.Time
+func (c *Claims) IssuedAtTime() time.Time {
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// TokenManager issues and verifies tokens against a KeySet
+type TokenManager struct {
+	keys       *KeySet
+	ttl        time.Duration
+	issuer     string
+	encryptPII bool
+	now        func() time.Time
+}
+
+// Option configures a TokenManager
+type Option func(*TokenManager)
+
+// WithIssuer sets the iss claim on issued tokens and requires it on verified ones
+func WithIssuer(issuer string) Option {
+	return func(m *TokenManager) {
+		m.issuer = issuer
+	}
+}
+
+// WithPIIEncryption encrypts tokens carrying PII claims (requires an "enc" key)
+func WithPIIEncryption(enabled bool) Option {
+	return func(m *TokenManager) {
+		m.encryptPII = enabled
+	}
+}
+
+// WithClock overrides the time source (used in tests)
+func WithClock(now func() time.Time) Option {
+	return func(m *TokenManager) {
+		m.now = now
+	}
+}
+
+// NewTokenManager creates a token manager issuing tokens valid for ttl
+func NewTokenManager(keys *KeySet, ttl time.Duration, opts ...Option) (*TokenManager, error) {
+	m := &TokenManager{
+		keys: keys,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("token lifetime must be positive")
+	}
+	if !keys.hasUse(KeyUseSignature) {
+		return nil, fmt.Errorf("at least one signing key is required")
+	}
+	if m.encryptPII && !keys.hasUse(KeyUseEncryption) {
+		return nil, fmt.Errorf("PII encryption requires at least one encryption key")
+	}
+
+	return m, nil
+}
+
+// TTL returns the lifetime of issued tokens
+func (m *TokenManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// Issue signs claims with the current primary key, filling in iat, exp and jti.
+// Tokens carrying PII are encrypted when PII encryption is enabled.
+func (m *TokenManager) Issue(claims Claims) (string, error) {
+	now := m.now()
+
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(m.ttl).Unix()
+	if claims.ID == "" {
+		claims.ID = uuid.New().String()
+	}
+	if m.issuer != "" {
+		claims.Issuer = m.issuer
+	}
+
+	key, err := m.keys.primary(KeyUseSignature, now)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := sign(key, claims)
+	if err != nil {
+		return "", err
+	}
+
+	if m.encryptPII && claims.ContainsPII() {
+		encKey, err := m.keys.primary(KeyUseEncryption, now)
+		if err != nil {
+			return "", err
+		}
+		return encrypt(encKey, []byte(token))
+	}
+
+	return token, nil
+}
+
+// Verify checks a token's signature (decrypting it first if it is a JWE) and
+// its time-based claims, returning the claims on success
+func (m *TokenManager) Verify(token string) (*Claims, error) {
+	now := m.now()
+
+	if strings.Count(token, ".") == 4 {
+		plaintext, err := m.decrypt(token, now)
+		if err != nil {
+			return nil, err
+		}
+		token = string(plaintext)
+	}
+
+	claims, err := m.verifySignature(token, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return nil, ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, ErrTokenNotYet
+	}
+	if m.issuer != "" && claims.Issuer != m.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrTokenMalformed)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrTokenMalformed)
+	}
+
+	return claims, nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// JWS (HS256)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Enc string `json:"enc,omitempty"`
+	Cty string `json:"cty,omitempty"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// sign produces a compact JWS for claims
+func sign(key Key, claims Claims) (string, error) {
+	h, err := json.Marshal(header{Alg: "HS256", Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	signingInput := b64.EncodeToString(h) + "." + b64.EncodeToString(payload)
+	return signingInput + "." + b64.EncodeToString(hmacSHA256(key.Secret, signingInput)), nil
+}
+
+// verifySignature validates a compact JWS and decodes its claims
+func (m *TokenManager) verifySignature(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	// Only HS256 is accepted; never trust the header to pick the algorithm
+	if h.Alg != "HS256" {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrTokenMalformed, h.Alg)
+	}
+
+	kid := h.Kid
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+	key, ok := m.keys.lookup(KeyUseSignature, kid, now, m.ttl)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !hmac.Equal(sig, hmacSHA256(key.Secret, parts[0]+"."+parts[1])) {
+		return nil, ErrTokenSignature
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func hmacSHA256(secret, input string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return mac.Sum(nil)
+}
+
+// decodeSegment base64url-decodes and unmarshals a token segment
+func decodeSegment(segment string, dst any) error {
+	data, err := b64.DecodeString(segment)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return ErrTokenMalformed
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	secretA = "key-a-secret-with-at-least-32-characters"
+	secretB = "key-b-secret-with-at-least-32-characters"
+	secretE = "enc-key-secret-with-at-least-32-characters"
+)
+
+func newTestManager(t *testing.T, now *time.Time, keys []Key, opts ...Option) *TokenManager {
+	t.Helper()
+	set, err := NewKeySet(keys...)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	opts = append(opts, WithClock(func() time.Time { return *now }))
+	m, err := NewTokenManager(set, time.Hour, opts...)
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	return m
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestManager(t, &now, []Key{{ID: "a", Secret: secretA}})
+
+	token, err := m.Issue(Claims{Subject: "user-1", Scope: "orders:read orders:write"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	claims, err := m.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject != "user-1" || !claims.HasScope("orders:write") {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// Tampering with the payload breaks the signature
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + b64.EncodeToString([]byte(`{"sub":"admin","exp":9999999999}`)) + "." + parts[2]
+	if _, err := m.Verify(forged); !errors.Is(err, ErrTokenSignature) {
+		t.Errorf("expected ErrTokenSignature for forged token, got %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := m.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	cutover := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	now := cutover.Add(-10 * time.Minute)
+	m := newTestManager(t, &now, []Key{
+		{ID: "a", Secret: secretA, NotAfter: cutover},
+		{ID: "b", Secret: secretB, NotBefore: cutover},
+	})
+
+	oldToken, err := m.Issue(Claims{Subject: "user-1"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if kidOf(t, oldToken) != "a" {
+		t.Fatalf("expected token signed with key a before cutover")
+	}
+
+	now = cutover.Add(10 * time.Minute)
+	newToken, err := m.Issue(Claims{Subject: "user-2"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if kidOf(t, newToken) != "b" {
+		t.Fatalf("expected token signed with key b after cutover")
+	}
+
+	// Both tokens verify during the overlap window
+	for _, tok := range []string{oldToken, newToken} {
+		if _, err := m.Verify(tok); err != nil {
+			t.Errorf("Verify during overlap: %v", err)
+		}
+	}
+
+	// Once every token from key a has expired, the key is no longer accepted
+	now = cutover.Add(2 * time.Hour)
+	if _, err := m.Verify(oldToken); !errors.Is(err, ErrUnknownKey) && !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected retired key to be rejected, got %v", err)
+	}
+}
+
+func TestLegacyTokenWithoutKid(t *testing.T) {
+	now := time.Now()
+	set, err := LoadKeySet(secretA, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	m, err := NewTokenManager(set, time.Hour, WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+
+	// Simulate a token issued before kids existed
+	legacy, err := sign(Key{Secret: secretA}, Claims{Subject: "user-1", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := m.Verify(legacy); err != nil {
+		t.Errorf("expected legacy token to verify, got %v", err)
+	}
+}
+
+func TestPIITokensAreEncrypted(t *testing.T) {
+	now := time.Now()
+	m := newTestManager(t, &now, []Key{
+		{ID: "a", Secret: secretA},
+		{ID: "e", Secret: secretE, Use: KeyUseEncryption},
+	}, WithPIIEncryption(true))
+
+	token, err := m.Issue(Claims{Subject: "user-1", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if strings.Count(token, ".") != 4 {
+		t.Fatalf("expected compact JWE with 5 segments, got %q", token)
+	}
+	if strings.Contains(token, b64.EncodeToString([]byte("jane"))) {
+		t.Error("PII visible in encrypted token")
+	}
+
+	claims, err := m.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Email != "jane@example.com" {
+		t.Errorf("expected email claim to round-trip, got %q", claims.Email)
+	}
+
+	// Tokens without PII stay plain JWS
+	plain, err := m.Issue(Claims{Subject: "user-2"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if strings.Count(plain, ".") != 2 {
+		t.Errorf("expected JWS for token without PII, got %q", plain)
+	}
+}
+
+func TestRejectsUnsupportedAlg(t *testing.T) {
+	now := time.Now()
+	m := newTestManager(t, &now, []Key{{ID: "a", Secret: secretA}})
+
+	h := b64.EncodeToString([]byte(`{"alg":"none","kid":"a"}`))
+	p := b64.EncodeToString([]byte(`{"sub":"admin","exp":9999999999}`))
+	if _, err := m.Verify(h + "." + p + "."); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("expected ErrTokenMalformed for alg none, got %v", err)
+	}
+}
+
+func TestCheckSecretStrength(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"random hex", "3f9a1c7e5b2d8f4a6c0e9b3d7f1a5c8e", false},
+		{"test secret", secretA, false},
+		{"too short", "short", true},
+		{"placeholder", "your-super-secret-jwt-key-must-be-at-least-32-characters-long", true},
+		{"repeated characters", strings.Repeat("ab", 20), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckSecretStrength(tt.secret); (err != nil) != tt.wantErr {
+				t.Errorf("CheckSecretStrength() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeySetAudit(t *testing.T) {
+	now := time.Now()
+	retired := Key{ID: "old", Secret: secretA, NotAfter: now.Add(-time.Hour)}
+	weak := Key{ID: "weak", Secret: strings.Repeat("ab", 20)}
+
+	ks, err := NewKeySet(retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := ks.Audit(now); len(problems) != 1 {
+		t.Errorf("Audit() = %v, want only the missing active signing key", problems)
+	}
+
+	ks, err = NewKeySet(weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := ks.Audit(now); len(problems) != 1 {
+		t.Errorf("Audit() = %v, want only the weak secret", problems)
+	}
+}
+
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	var h header
+	if err := decodeSegment(strings.Split(token, ".")[0], &h); err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+	return h.Kid
+}
@@ -0,0 +1,53 @@
+// Package awsmsg publishes domain events to Amazon SNS and consumes them
+// from Amazon SQS, and sends notifications as SNS text messages:
+//
+//   - a Publisher sends each event to an SNS topic as a CloudEvent
+//     (see domain.MarshalCloudEvent), with the event type and schema
+//     version as message attributes for subscription filter policies; on a
+//     FIFO topic, the events of one order share a message group;
+//   - a Consumer long-polls an SQS queue, extends the visibility timeout of
+//     messages while their handler runs, deletes them once handled and
+//     moves those that keep failing to a dead-letter queue;
+//   - an SMSNotifier sends notifications as transactional SMS to a phone
+//     number.
+//
+// Both services are called through the AWS SDK clients, built from the same
+// aws.Config as the S3 blob store, so they share its credentials, region,
+// endpoint override (AWS_ENDPOINT_URL, for LocalStack and the like) and
+// retry policy. Errors the services return are smithy.APIError values.
+//
+// Example:
+//
+//	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, blob.AWSLoadOptions(s3cfg)...)
+//	publisher := awsmsg.NewPublisher(awsCfg, "arn:aws:sns:us-east-1:123456789012:orders", "/orders-api")
+//	unsubscribe := bus.Subscribe(publisher.Publish)
+package awsmsg
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+)
+
+// truncate shortens s to at most n bytes on a rune boundary
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// sleep waits for d or until ctx ends
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
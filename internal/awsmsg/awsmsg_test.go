@@ -0,0 +1,386 @@
+package awsmsg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/smithy-go"
+)
+
+// testMaxAttempts is how often the SDK clients make a call that keeps failing
+const testMaxAttempts = 3
+
+func testAWSConfig(endpoint string) aws.Config {
+	return aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+		BaseEndpoint: aws.String(endpoint),
+		HTTPClient:   http.DefaultClient,
+		// The standard retryer, without waiting between attempts
+		Retryer: func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = testMaxAttempts
+				o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+			})
+		},
+	}
+}
+
+// formAttributes returns the string values of the message attributes in a
+// query protocol form, by name
+func formAttributes(form url.Values) map[string]string {
+	attrs := make(map[string]string)
+	for i := 1; form.Has(fmt.Sprintf("MessageAttributes.entry.%d.Name", i)); i++ {
+		prefix := fmt.Sprintf("MessageAttributes.entry.%d.", i)
+		attrs[form.Get(prefix+"Name")] = form.Get(prefix + "Value.StringValue")
+	}
+	return attrs
+}
+
+// checkSigned fails unless r carries a SigV4 signature for service
+func checkSigned(t *testing.T, r *http.Request, service string) {
+	t.Helper()
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+		!strings.Contains(auth, "/us-east-1/"+service+"/aws4_request") || r.Header.Get("X-Amz-Date") == "" {
+		t.Errorf("request not signed for %s: %q", service, auth)
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		forms []url.Values
+	)
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkSigned(t, r, "sns")
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		mu.Lock()
+		forms = append(forms, form)
+		mu.Unlock()
+		switch status {
+		case http.StatusOK:
+			io.WriteString(w, `<PublishResponse><PublishResult><MessageId>m-1</MessageId></PublishResult></PublishResponse>`)
+		case http.StatusNotFound:
+			w.WriteHeader(status)
+			io.WriteString(w, `<ErrorResponse><Error><Type>Sender</Type><Code>NotFound</Code><Message>Topic does not exist</Message></Error></ErrorResponse>`)
+		default:
+			w.WriteHeader(status)
+		}
+	}))
+	defer srv.Close()
+
+	publisher := NewPublisher(testAWSConfig(srv.URL), "arn:aws:sns:us-east-1:123456789012:orders.fifo", "/orders-api")
+	event := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1", UserID: "user-1"}
+	if err := publisher.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	form := forms[0]
+	if form.Get("Action") != "Publish" || form.Get("TopicArn") != "arn:aws:sns:us-east-1:123456789012:orders.fifo" ||
+		form.Get("MessageGroupId") != "order-1" || form.Get("MessageDeduplicationId") != "evt-1" {
+		t.Errorf("publish form = %v", form)
+	}
+	if attrs := formAttributes(form); attrs[AttributeEventType] != domain.EventOrderShipped || attrs[AttributeSchemaVersion] != "1" {
+		t.Errorf("message attributes = %v", attrs)
+	}
+	decoded, err := domain.UnmarshalCloudEvent([]byte(form.Get("Message")))
+	if err != nil || decoded != event {
+		t.Errorf("published message decodes to %#v, %v", decoded, err)
+	}
+
+	// Client errors are returned straight away, server errors after retrying
+	for _, tc := range []struct {
+		status   int
+		attempts int
+	}{
+		{http.StatusNotFound, 1},
+		{http.StatusServiceUnavailable, testMaxAttempts},
+	} {
+		status = tc.status
+		forms = nil
+		err := publisher.Publish(context.Background(), event)
+		if err == nil {
+			t.Errorf("HTTP %d: Publish succeeded", tc.status)
+		}
+		var apiErr smithy.APIError
+		if tc.status == http.StatusNotFound && (!errors.As(err, &apiErr) || apiErr.ErrorCode() != "NotFound") {
+			t.Errorf("HTTP %d error = %v, want code NotFound", tc.status, err)
+		}
+		if len(forms) != tc.attempts {
+			t.Errorf("HTTP %d attempted %d times, want %d", tc.status, len(forms), tc.attempts)
+		}
+	}
+}
+
+func TestSMSNotifier(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkSigned(t, r, "sns")
+		body, _ := io.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(body))
+		if form.Get("PhoneNumber") == "+15550000000" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `<ErrorResponse><Error><Type>Sender</Type><Code>InvalidParameter</Code><Message>Invalid phone number</Message></Error></ErrorResponse>`)
+			return
+		}
+		io.WriteString(w, `<PublishResponse><PublishResult><MessageId>m-1</MessageId></PublishResult></PublishResponse>`)
+	}))
+	defer srv.Close()
+
+	notifier := NewSMSNotifier(testAWSConfig(srv.URL), "OrdersAPI")
+	n := domain.Notification{UserID: "user-1", EventType: domain.EventOrderShipped, EventID: "evt-1", Subject: "Shipped", Body: "Your order shipped"}
+	if err := notifier.Notify(context.Background(), "+14155550100", n); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if form.Get("Action") != "Publish" || form.Get("PhoneNumber") != "+14155550100" || form.Get("Message") != n.Body || form.Has("TopicArn") {
+		t.Errorf("sms form = %v", form)
+	}
+	if attrs := formAttributes(form); attrs["AWS.SNS.SMS.SMSType"] != "Transactional" || attrs["AWS.SNS.SMS.SenderID"] != "OrdersAPI" {
+		t.Errorf("sms attributes = %v", attrs)
+	}
+
+	err := notifier.Notify(context.Background(), "+15550000000", n)
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "InvalidParameter" {
+		t.Errorf("invalid number error = %v, want InvalidParameter", err)
+	}
+}
+
+// fakeQueue is an SQS endpoint serving a main queue and a dead-letter queue
+type fakeQueue struct {
+	t *testing.T
+
+	mu         sync.Mutex
+	messages   map[string]*queuedMessage // By receipt handle
+	deleted    []string                  // Message IDs
+	deadLetter []map[string]any          // SendMessage requests
+	extended   int
+}
+
+type fakeAttribute struct {
+	DataType    string `json:"DataType"`
+	StringValue string `json:"StringValue,omitempty"`
+}
+
+type queuedMessage struct {
+	id       string
+	body     string
+	attrs    map[string]fakeAttribute
+	receives int
+	visible  time.Time
+}
+
+func (q *fakeQueue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	checkSigned(q.t, r, "sqs")
+	if ct := r.Header.Get("Content-Type"); ct != "application/x-amz-json-1.0" {
+		q.t.Errorf("Content-Type = %q", ct)
+	}
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		q.t.Errorf("decode request: %v", err)
+	}
+	handle, _ := req["ReceiptHandle"].(string)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var resp any = map[string]any{}
+	switch action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS."); action {
+	case "ReceiveMessage":
+		if req["QueueUrl"] != "https://sqs.example/queue" {
+			q.t.Errorf("receive from %v", req["QueueUrl"])
+		}
+		visibility := time.Duration(req["VisibilityTimeout"].(float64)) * time.Second
+		var msgs []map[string]any
+		for handle, m := range q.messages {
+			if time.Now().Before(m.visible) {
+				continue
+			}
+			m.receives++
+			m.visible = time.Now().Add(visibility)
+			msgs = append(msgs, map[string]any{
+				"MessageId": m.id, "ReceiptHandle": handle, "Body": m.body, "MessageAttributes": m.attrs,
+				"Attributes": map[string]string{"ApproximateReceiveCount": strconv.Itoa(m.receives), "SentTimestamp": "1700000000000"},
+			})
+		}
+		if len(msgs) == 0 {
+			// A short long poll, so the test runs quickly
+			q.mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			q.mu.Lock()
+		}
+		resp = map[string]any{"Messages": msgs}
+	case "DeleteMessage":
+		m, ok := q.messages[handle]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.sqs#ReceiptHandleIsInvalid", "message": "gone"})
+			return
+		}
+		delete(q.messages, handle)
+		q.deleted = append(q.deleted, m.id)
+	case "ChangeMessageVisibility":
+		if m, ok := q.messages[handle]; ok {
+			timeout := time.Duration(req["VisibilityTimeout"].(float64)) * time.Second
+			m.visible = time.Now().Add(timeout)
+			if timeout > 0 {
+				q.extended++
+			}
+		}
+	case "SendMessage":
+		q.deadLetter = append(q.deadLetter, req)
+		resp = map[string]string{"MessageId": "dlq-1"}
+	default:
+		q.t.Errorf("unexpected action %q", action)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestConsumer(t *testing.T) {
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1", UserID: "user-1"}
+	cloudEvent, _ := domain.MarshalCloudEvent(shipped, "/orders-api")
+	notification, _ := json.Marshal(map[string]string{"Type": "Notification", "Message": string(cloudEvent)})
+
+	queue := &fakeQueue{t: t, messages: map[string]*queuedMessage{
+		"rh-event":  {id: "event", body: string(notification)},
+		"rh-poison": {id: "poison", body: "not an event", attrs: map[string]fakeAttribute{"source": {DataType: "String", StringValue: "test"}}},
+		"rh-slow":   {id: "slow", body: string(cloudEvent)},
+	}}
+	srv := httptest.NewServer(queue)
+	defer srv.Close()
+
+	consumer := NewConsumer(testAWSConfig(srv.URL), ConsumerConfig{
+		QueueURL:           "https://sqs.example/queue",
+		DeadLetterQueueURL: "https://sqs.example/queue-dlq",
+		MaxReceives:        2,
+		WaitTime:           time.Second,
+		VisibilityTimeout:  time.Second,
+		Concurrency:        3,
+	}, logger.NewWithOptions("error", io.Discard, false))
+
+	var (
+		mu       sync.Mutex
+		received []domain.Event
+	)
+	handle := EventHandler(func(ctx context.Context, event domain.Event) error {
+		mu.Lock()
+		received = append(received, event)
+		first := len(received) == 1
+		mu.Unlock()
+		if first {
+			// Outlasts the visibility timeout, so it must be extended
+			time.Sleep(1500 * time.Millisecond)
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx, handle)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		queue.mu.Lock()
+		finished := len(queue.messages) == 0
+		queue.mu.Unlock()
+		if finished {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue not drained: %d messages left", len(queue.messages))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if len(received) != 2 || received[0] != shipped || received[1] != shipped {
+		t.Errorf("received %v, want the shipped event twice", received)
+	}
+	if queue.extended == 0 {
+		t.Error("visibility of the slow message was never extended")
+	}
+	if len(queue.deadLetter) != 1 {
+		t.Fatalf("dead-lettered %d messages, want 1", len(queue.deadLetter))
+	}
+	dlq := queue.deadLetter[0]
+	attrs, _ := dlq["MessageAttributes"].(map[string]any)
+	if dlq["QueueUrl"] != "https://sqs.example/queue-dlq" || dlq["MessageBody"] != "not an event" ||
+		attrs["source"] == nil || attrs["dead_letter_reason"] == nil {
+		t.Errorf("dead-letter request = %v", dlq)
+	}
+}
+
+func TestConsumerDeadLetters(t *testing.T) {
+	queue := &fakeQueue{t: t, messages: map[string]*queuedMessage{
+		"rh-poison": {id: "poison", body: "not an event", attrs: map[string]fakeAttribute{"event_type": {DataType: "String", StringValue: "order.shipped"}}},
+	}}
+	srv := httptest.NewServer(queue)
+	defer srv.Close()
+
+	deadLetters := memory.NewDeadLetterQueue()
+	consumer := NewConsumer(testAWSConfig(srv.URL), ConsumerConfig{
+		QueueURL:          "https://sqs.example/queue",
+		DeadLetters:       deadLetters,
+		MaxReceives:       2,
+		WaitTime:          time.Second,
+		VisibilityTimeout: time.Second,
+	}, logger.NewWithOptions("error", io.Discard, false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx, EventHandler(func(context.Context, domain.Event) error { return nil }))
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		queue.mu.Lock()
+		finished := len(queue.messages) == 0
+		queue.mu.Unlock()
+		if finished {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("poison message not deleted")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if len(queue.deadLetter) != 0 {
+		t.Errorf("sent %d messages to a dead-letter queue, want none", len(queue.deadLetter))
+	}
+	letters, _ := deadLetters.List(context.Background(), domain.DeadLetterFilter{})
+	if len(letters) != 1 {
+		t.Fatalf("stored %d dead letters, want 1", len(letters))
+	}
+	d := letters[0]
+	if d.Source != domain.DeadLetterSQS || d.Type != "order.shipped" || d.MessageID != "poison" ||
+		string(d.Payload) != "not an event" || d.Attempts != 2 || d.Error == "" {
+		t.Errorf("dead letter = %+v", d)
+	}
+}
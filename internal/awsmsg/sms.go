@@ -0,0 +1,51 @@
+package awsmsg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// Ensure SMSNotifier implements domain.Notifier at compile time
+var _ domain.Notifier = (*SMSNotifier)(nil)
+
+// SMSNotifier sends notifications as text messages through SNS, straight
+// to a phone number rather than through a topic. It is safe for concurrent
+// use.
+type SMSNotifier struct {
+	client   *sns.Client
+	senderID string
+}
+
+// NewSMSNotifier creates a notifier sending transactional SMS, from
+// senderID where the destination country supports sender IDs (empty for
+// the account default)
+func NewSMSNotifier(awsCfg aws.Config, senderID string) *SMSNotifier {
+	return &SMSNotifier{client: sns.NewFromConfig(awsCfg), senderID: senderID}
+}
+
+// Notify sends the body of n to the E.164 phone number address
+func (s *SMSNotifier) Notify(ctx context.Context, address string, n domain.Notification) error {
+	attributes := map[string]types.MessageAttributeValue{
+		// Transactional messages are delivered ahead of promotional ones,
+		// and also to numbers opted out of marketing
+		"AWS.SNS.SMS.SMSType": stringAttribute("Transactional"),
+	}
+	if s.senderID != "" {
+		attributes["AWS.SNS.SMS.SenderID"] = stringAttribute(s.senderID)
+	}
+
+	_, err := s.client.Publish(ctx, &sns.PublishInput{
+		PhoneNumber:       aws.String(address),
+		Message:           aws.String(n.Body),
+		MessageAttributes: attributes,
+	})
+	if err != nil {
+		return fmt.Errorf("sending %s notification by sms: %w", n.EventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package awsmsg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// Message attributes set on published events, so SNS subscriptions can
+// filter on them and SQS consumers route without decoding the body
+const (
+	AttributeEventType     = "event_type"
+	AttributeSchemaVersion = "schema_version"
+)
+
+// Publisher publishes domain events to an SNS topic. It is safe for
+// concurrent use.
+type Publisher struct {
+	client   *sns.Client
+	topicARN string
+	source   string
+	fifo     bool
+}
+
+// NewPublisher creates a publisher to topicARN of events from source (the
+// CloudEvents source; domain.DefaultEventSource when empty). Events to a
+// FIFO topic (".fifo") are grouped by aggregate ID and deduplicated by
+// event ID.
+func NewPublisher(awsCfg aws.Config, topicARN, source string) *Publisher {
+	return &Publisher{client: sns.NewFromConfig(awsCfg), topicARN: topicARN, source: source, fifo: strings.HasSuffix(topicARN, ".fifo")}
+}
+
+// Publish sends event to the topic and returns once SNS accepted it. It is
+// a domain.EventHandler, so a publisher subscribes to an event bus as is.
+func (p *Publisher) Publish(ctx context.Context, event domain.Event) error {
+	body, err := domain.MarshalCloudEvent(event, p.source)
+	if err != nil {
+		return fmt.Errorf("sns: %w", err)
+	}
+	input := &sns.PublishInput{
+		TopicArn: aws.String(p.topicARN),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			AttributeEventType:     stringAttribute(event.EventType()),
+			AttributeSchemaVersion: {DataType: aws.String("Number"), StringValue: aws.String(strconv.Itoa(domain.EventSchemaVersion(event.EventType())))},
+		},
+	}
+	if p.fifo {
+		input.MessageGroupId = aws.String(event.AggregateID())
+		input.MessageDeduplicationId = aws.String(event.Metadata().ID)
+	}
+
+	if _, err := p.client.Publish(ctx, input); err != nil {
+		return fmt.Errorf("publishing %s event %s: %w", event.EventType(), event.Metadata().ID, err)
+	}
+	return nil
+}
+
+func stringAttribute(value string) types.MessageAttributeValue {
+	return types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+}
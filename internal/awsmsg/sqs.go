@@ -0,0 +1,321 @@
+package awsmsg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Message is a message received from an SQS queue
+type Message struct {
+	ID            string
+	ReceiptHandle string
+	Body          string
+	Attributes    map[string]string // Message attributes with string values
+	ReceiveCount  int               // Including this delivery
+	SentAt        time.Time
+}
+
+// ConsumerConfig configures a Consumer
+type ConsumerConfig struct {
+	QueueURL           string
+	DeadLetterQueueURL string        // Where messages go after MaxReceives failed deliveries; empty leaves them to the queue's redrive policy
+	MaxReceives        int           // Deliveries before a message is dead-lettered
+	WaitTime           time.Duration // Long polling wait, at most 20s
+	VisibilityTimeout  time.Duration // How long a received message stays hidden, renewed while its handler runs
+	MaxMessages        int           // Received per poll, 1 to 10
+	Concurrency        int           // Messages handled at once
+	// DeadLetters keeps the messages that failed MaxReceives deliveries
+	// when there is no DeadLetterQueueURL, for replay
+	DeadLetters domain.DeadLetterQueue
+}
+
+// withDefaults fills in the zero fields of cfg
+func (cfg ConsumerConfig) withDefaults() ConsumerConfig {
+	if cfg.MaxReceives <= 0 {
+		cfg.MaxReceives = 5
+	}
+	if cfg.WaitTime <= 0 || cfg.WaitTime > 20*time.Second {
+		cfg.WaitTime = 20 * time.Second
+	}
+	if cfg.VisibilityTimeout < time.Second {
+		cfg.VisibilityTimeout = 30 * time.Second
+	}
+	if cfg.MaxMessages <= 0 || cfg.MaxMessages > 10 {
+		cfg.MaxMessages = 10
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return cfg
+}
+
+// Handler processes a message. A message whose handler fails is delivered
+// again once its visibility timeout lapses, and dead-lettered after
+// MaxReceives deliveries.
+type Handler func(ctx context.Context, msg Message) error
+
+// Consumer receives messages from an SQS queue and hands each to a handler,
+// deleting it once handled. Delivery is at least once, and in no particular
+// order unless the queue is FIFO.
+type Consumer struct {
+	client *sqs.Client
+	cfg    ConsumerConfig
+	logg   *logger.Logger
+}
+
+// NewConsumer creates a consumer of cfg.QueueURL
+func NewConsumer(awsCfg aws.Config, cfg ConsumerConfig, logg *logger.Logger) *Consumer {
+	return &Consumer{client: sqs.NewFromConfig(awsCfg), cfg: cfg.withDefaults(), logg: logg}
+}
+
+// Run receives and handles messages until ctx ends, then waits for the
+// handlers in flight, whose context stays live so they finish and their
+// messages are deleted, and returns
+func (c *Consumer) Run(ctx context.Context, handle Handler) {
+	work := context.WithoutCancel(ctx)
+	slots := make(chan struct{}, c.cfg.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	failures := 0
+	for ctx.Err() == nil {
+		msgs, err := c.receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			c.logg.Warn("sqs receive failed", "error", err, "queue", c.cfg.QueueURL, "attempt", failures)
+			if sleep(ctx, min(time.Duration(failures)*time.Second, 30*time.Second)) != nil {
+				return
+			}
+			continue
+		}
+		failures = 0
+
+		for i, msg := range msgs {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				// Hand back what was received but not started
+				for _, m := range msgs[i:] {
+					if err := c.changeVisibility(work, m.ReceiptHandle, 0); err != nil {
+						c.logg.Debug("sqs message not released", "error", err, "message_id", m.ID)
+					}
+				}
+				return
+			}
+			wg.Go(func() {
+				defer func() { <-slots }()
+				c.process(work, handle, msg)
+			})
+		}
+	}
+}
+
+// process handles msg while keeping it hidden, then deletes it, or
+// dead-letters it when it has failed too often
+func (c *Consumer) process(ctx context.Context, handle Handler, msg Message) {
+	stop := c.extendVisibility(ctx, msg)
+	err := runHandler(ctx, handle, msg)
+	stop()
+
+	if err == nil {
+		if err := c.deleteMessage(ctx, msg.ReceiptHandle); err != nil {
+			c.logg.Warn("sqs message handled but not deleted, it will be delivered again", "error", err, "message_id", msg.ID)
+		}
+		return
+	}
+	if (c.cfg.DeadLetterQueueURL == "" && c.cfg.DeadLetters == nil) || msg.ReceiveCount < c.cfg.MaxReceives {
+		c.logg.Warn("sqs message handler failed, will retry", "error", err, "message_id", msg.ID, "receive_count", msg.ReceiveCount)
+		return
+	}
+	if dlqErr := c.deadLetter(ctx, msg, err); dlqErr != nil {
+		c.logg.Error("sqs message not dead-lettered", "error", dlqErr, "message_id", msg.ID)
+		return
+	}
+	if c.cfg.DeadLetterQueueURL == "" {
+		c.logg.Error("sqs message dead-lettered", "error", err, "message_id", msg.ID, "receive_count", msg.ReceiveCount)
+		return
+	}
+	c.logg.Error("sqs message dead-lettered", "error", err, "message_id", msg.ID, "receive_count", msg.ReceiveCount,
+		"dead_letter_queue", c.cfg.DeadLetterQueueURL)
+}
+
+// runHandler calls handle, turning a panic into an error
+func runHandler(ctx context.Context, handle Handler, msg Message) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+	return handle(ctx, msg)
+}
+
+// extendVisibility renews the visibility timeout of msg at half its length
+// until the returned stop is called
+func (c *Consumer) extendVisibility(ctx context.Context, msg Message) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.cfg.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := c.changeVisibility(ctx, msg.ReceiptHandle, c.cfg.VisibilityTimeout); err != nil && ctx.Err() == nil {
+				c.logg.Warn("sqs visibility not extended, the message may be delivered twice", "error", err, "message_id", msg.ID)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// deadLetter moves msg to the dead-letter queue, or to DeadLetters
+func (c *Consumer) deadLetter(ctx context.Context, msg Message, cause error) error {
+	if c.cfg.DeadLetterQueueURL == "" {
+		err := c.cfg.DeadLetters.Add(ctx, &domain.DeadLetter{
+			Source:     domain.DeadLetterSQS,
+			Type:       msg.Attributes[AttributeEventType],
+			MessageID:  msg.ID,
+			Payload:    []byte(msg.Body),
+			Attributes: msg.Attributes,
+			Error:      cause.Error(),
+			Attempts:   msg.ReceiveCount,
+			FailedAt:   time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		return c.deleteMessage(ctx, msg.ReceiptHandle)
+	}
+
+	attributes := make(map[string]types.MessageAttributeValue, len(msg.Attributes)+1)
+	for name, value := range msg.Attributes {
+		attributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	if len(attributes) < maxMessageAttributes {
+		attributes["dead_letter_reason"] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(truncate(cause.Error(), 1024))}
+	}
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(c.cfg.DeadLetterQueueURL),
+		MessageBody:       aws.String(msg.Body),
+		MessageAttributes: attributes,
+	}
+	if strings.HasSuffix(c.cfg.DeadLetterQueueURL, ".fifo") {
+		input.MessageGroupId = aws.String("dead-letter")
+		input.MessageDeduplicationId = aws.String(msg.ID)
+	}
+	if _, err := c.client.SendMessage(ctx, input); err != nil {
+		return err
+	}
+	return c.deleteMessage(ctx, msg.ReceiptHandle)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// SQS API
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// maxMessageAttributes is how many attributes SQS accepts on a message
+const maxMessageAttributes = 10
+
+// receive long-polls the queue for messages
+func (c *Consumer) receive(ctx context.Context) ([]Message, error) {
+	resp, err := c.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(c.cfg.QueueURL),
+		MaxNumberOfMessages: int32(c.cfg.MaxMessages),
+		WaitTimeSeconds:     int32(c.cfg.WaitTime.Seconds()),
+		VisibilityTimeout:   int32(c.cfg.VisibilityTimeout.Seconds()),
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+			types.MessageSystemAttributeNameSentTimestamp,
+		},
+		MessageAttributeNames: []string{"All"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, len(resp.Messages))
+	for i, m := range resp.Messages {
+		msgs[i] = Message{ID: aws.ToString(m.MessageId), ReceiptHandle: aws.ToString(m.ReceiptHandle), Body: aws.ToString(m.Body)}
+		msgs[i].ReceiveCount, _ = strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+		if ms, err := strconv.ParseInt(m.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64); err == nil {
+			msgs[i].SentAt = time.UnixMilli(ms)
+		}
+		if len(m.MessageAttributes) > 0 {
+			msgs[i].Attributes = make(map[string]string, len(m.MessageAttributes))
+			for name, attr := range m.MessageAttributes {
+				if value := aws.ToString(attr.StringValue); value != "" {
+					msgs[i].Attributes[name] = value
+				}
+			}
+		}
+	}
+	return msgs, nil
+}
+
+func (c *Consumer) deleteMessage(ctx context.Context, receiptHandle string) error {
+	_, err := c.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(c.cfg.QueueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	})
+	return err
+}
+
+func (c *Consumer) changeVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error {
+	_, err := c.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(c.cfg.QueueURL),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: int32(timeout.Seconds()),
+	})
+	return err
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Domain events
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// DecodeEvent decodes the domain event in msg, as a Publisher sent it to
+// SNS: raw, or wrapped in the notification SNS delivers to a queue
+// subscribed without raw message delivery
+func DecodeEvent(msg Message) (domain.Event, error) {
+	body := msg.Body
+	var notification struct {
+		Type    string `json:"Type"`
+		Message string `json:"Message"`
+	}
+	if err := json.Unmarshal([]byte(body), &notification); err == nil && notification.Type == "Notification" {
+		body = notification.Message
+	}
+	return domain.UnmarshalCloudEvent([]byte(body))
+}
+
+// EventHandler adapts handle to a Handler of messages carrying domain
+// events. Messages that do not decode fail, to be dead-lettered.
+func EventHandler(handle domain.EventHandler) Handler {
+	return func(ctx context.Context, msg Message) error {
+		event, err := DecodeEvent(msg)
+		if err != nil {
+			return err
+		}
+		return handle(ctx, event)
+	}
+}
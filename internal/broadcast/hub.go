@@ -0,0 +1,256 @@
+// Package broadcast fans events out to connected clients (SSE streams and
+// WebSocket connections) without letting a slow client hold up the rest.
+//
+// Every subscription has its own bounded buffer. Publish never blocks: when a
+// buffer is full the event is dropped for that subscriber only, and a
+// subscriber that keeps falling behind is disconnected so it can reconnect
+// and resynchronise instead of silently missing events forever.
+package broadcast
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+var (
+	// ErrSlowConsumer closes a subscription that dropped too many events in a row
+	ErrSlowConsumer = errors.New("subscriber is too slow")
+	// ErrHubClosed closes every subscription when the hub shuts down
+	ErrHubClosed = errors.New("broadcast hub closed")
+)
+
+// Event is one message fanned out to subscribers
+type Event struct {
+	ID     string // Optional; sent as the SSE id so clients can resume
+	Topic  string // e.g. "orders"; also the SSE event name
+	UserID string // Deliver only to this user's subscriptions; empty broadcasts to everyone
+	Data   []byte // Encoded payload, usually JSON
+}
+
+// Stats is a snapshot of the hub's counters
+type Stats struct {
+	Connected       int64  // Current subscriptions
+	Published       uint64 // Events passed to Publish
+	Delivered       uint64 // Events queued to a subscriber
+	Dropped         uint64 // Events dropped because a subscriber's buffer was full
+	SlowDisconnects uint64 // Subscriptions closed with ErrSlowConsumer
+}
+
+// Option configures a Hub
+type Option func(*Hub)
+
+// WithBufferSize sets how many events each subscription buffers (default 64)
+func WithBufferSize(n int) Option {
+	return func(h *Hub) {
+		if n > 0 {
+			h.bufferSize = n
+		}
+	}
+}
+
+// WithMaxDropped sets how many consecutive events a subscriber may miss
+// before it is disconnected (default 16)
+func WithMaxDropped(n int) Option {
+	return func(h *Hub) {
+		if n > 0 {
+			h.maxDropped = n
+		}
+	}
+}
+
+// Hub fans published events out to subscriptions. It is safe for concurrent use.
+//
+// Example:
+//
+//	sub := hub.Subscribe(claims.Subject, "orders")
+//	defer sub.Close()
+//	for {
+//		select {
+//		case e := <-sub.Events():
+//			write(e)
+//		case <-sub.Done():
+//			return // sub.Err() says why
+//		case <-r.Context().Done():
+//			return
+//		}
+//	}
+type Hub struct {
+	bufferSize int
+	maxDropped int
+	logg       *logger.Logger
+
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+
+	connected       atomic.Int64
+	published       atomic.Uint64
+	delivered       atomic.Uint64
+	dropped         atomic.Uint64
+	slowDisconnects atomic.Uint64
+}
+
+// NewHub creates a hub
+func NewHub(logg *logger.Logger, opts ...Option) *Hub {
+	h := &Hub{
+		bufferSize: 64,
+		maxDropped: 16,
+		logg:       logg,
+		subs:       make(map[*Subscription]struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Subscribe registers a connection for userID (empty for anonymous clients)
+// receiving events on topics (none means every topic). The caller must Close
+// the subscription when the connection ends. Subscribing to a closed hub
+// returns a subscription that is already done with ErrHubClosed.
+func (h *Hub) Subscribe(userID string, topics ...string) *Subscription {
+	s := &Subscription{
+		hub:    h,
+		userID: userID,
+		topics: topics,
+		events: make(chan Event, h.bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		s.err = ErrHubClosed
+		close(s.done)
+		return s
+	}
+	h.subs[s] = struct{}{}
+	h.connected.Add(1)
+	return s
+}
+
+// Publish queues e for every matching subscription without blocking.
+// Subscribers whose buffer is full miss the event; those that miss too many
+// in a row are disconnected with ErrSlowConsumer.
+func (h *Hub) Publish(e Event) {
+	h.published.Add(1)
+
+	var slow []*Subscription
+	h.mu.RLock()
+	for s := range h.subs {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+			s.missed.Store(0)
+			h.delivered.Add(1)
+		default:
+			h.dropped.Add(1)
+			if int(s.missed.Add(1)) >= h.maxDropped {
+				slow = append(slow, s)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		if h.remove(s, ErrSlowConsumer) {
+			h.slowDisconnects.Add(1)
+			h.logg.Warn("disconnected slow broadcast subscriber", "user_id", s.userID, "topics", s.topics)
+		}
+	}
+}
+
+// Stats returns the hub's counters
+func (h *Hub) Stats() Stats {
+	return Stats{
+		Connected:       h.connected.Load(),
+		Published:       h.published.Load(),
+		Delivered:       h.delivered.Load(),
+		Dropped:         h.dropped.Load(),
+		SlowDisconnects: h.slowDisconnects.Load(),
+	}
+}
+
+// Close disconnects every subscription with ErrHubClosed and refuses new ones,
+// so streaming handlers return during shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for s := range h.subs {
+		h.closeLocked(s, ErrHubClosed)
+	}
+}
+
+// remove closes s with err unless it is already closed
+func (h *Hub) remove(s *Subscription, err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; !ok {
+		return false
+	}
+	h.closeLocked(s, err)
+	return true
+}
+
+func (h *Hub) closeLocked(s *Subscription, err error) {
+	delete(h.subs, s)
+	h.connected.Add(-1)
+	s.err = err
+	close(s.done)
+}
+
+// Subscription is one connection's view of the hub. Events is never closed;
+// select on Done as well to learn when the subscription ends.
+type Subscription struct {
+	hub    *Hub
+	userID string
+	topics []string
+	events chan Event
+	done   chan struct{}
+	missed atomic.Int64 // Consecutive events dropped
+	err    error        // Why the subscription ended; written before done is closed
+}
+
+// Events delivers the subscription's events in publish order
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Done is closed when the subscription ends
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err reports why the subscription ended: ErrSlowConsumer, ErrHubClosed, or
+// nil if it is still open or was closed by its owner
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the subscription; closing twice is a no-op
+func (s *Subscription) Close() {
+	s.hub.remove(s, nil)
+}
+
+// wants reports whether e is addressed to this subscription
+func (s *Subscription) wants(e Event) bool {
+	if e.UserID != "" && e.UserID != s.userID {
+		return false
+	}
+	return len(s.topics) == 0 || slices.Contains(s.topics, e.Topic)
+}
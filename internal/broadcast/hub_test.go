@@ -0,0 +1,101 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func newTestHub(opts ...Option) *Hub {
+	return NewHub(logger.New("error"), opts...)
+}
+
+func TestPublishRoutesByTopicAndUser(t *testing.T) {
+	h := newTestHub()
+	orders := h.Subscribe("u1", "orders")
+	everything := h.Subscribe("u2")
+	defer orders.Close()
+	defer everything.Close()
+
+	h.Publish(Event{Topic: "orders", Data: []byte("broadcast")})
+	h.Publish(Event{Topic: "orders", UserID: "u2", Data: []byte("for u2")})
+	h.Publish(Event{Topic: "users", Data: []byte("other topic")})
+
+	if got := len(orders.Events()); got != 1 {
+		t.Errorf("orders subscriber got %d events, want 1", got)
+	}
+	if got := len(everything.Events()); got != 3 {
+		t.Errorf("unfiltered subscriber got %d events, want 3", got)
+	}
+	if e := <-orders.Events(); string(e.Data) != "broadcast" {
+		t.Errorf("orders subscriber got %q", e.Data)
+	}
+}
+
+func TestSlowConsumerIsDisconnected(t *testing.T) {
+	h := newTestHub(WithBufferSize(2), WithMaxDropped(3))
+	slow := h.Subscribe("u1")
+	fast := h.Subscribe("u2")
+	defer fast.Close()
+
+	for range 5 {
+		h.Publish(Event{Topic: "orders"})
+		<-fast.Events()
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("slow subscriber still connected")
+	}
+	if !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Errorf("Err() = %v, want ErrSlowConsumer", slow.Err())
+	}
+	if fast.Err() != nil {
+		t.Errorf("fast subscriber closed: %v", fast.Err())
+	}
+
+	stats := h.Stats()
+	if stats.Connected != 1 || stats.Dropped != 3 || stats.SlowDisconnects != 1 || stats.Published != 5 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestCatchingUpResetsDrops(t *testing.T) {
+	h := newTestHub(WithBufferSize(1), WithMaxDropped(2))
+	s := h.Subscribe("u1")
+	defer s.Close()
+
+	for range 5 {
+		h.Publish(Event{}) // Delivered
+		h.Publish(Event{}) // Dropped: buffer full
+		<-s.Events()
+	}
+	if s.Err() != nil {
+		t.Errorf("subscriber that keeps catching up was disconnected: %v", s.Err())
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	h := newTestHub()
+	s := h.Subscribe("u1")
+	s.Close()
+	s.Close()
+	if s.Err() != nil {
+		t.Errorf("Err() after Close = %v, want nil", s.Err())
+	}
+
+	open := h.Subscribe("u2")
+	h.Close()
+	<-open.Done()
+	if !errors.Is(open.Err(), ErrHubClosed) {
+		t.Errorf("Err() = %v, want ErrHubClosed", open.Err())
+	}
+	if late := h.Subscribe("u3"); !errors.Is(late.Err(), ErrHubClosed) {
+		t.Errorf("Subscribe after Close: Err() = %v, want ErrHubClosed", late.Err())
+	}
+	if n := h.Stats().Connected; n != 0 {
+		t.Errorf("Connected = %d after Close", n)
+	}
+}
@@ -0,0 +1,161 @@
+// Package chaos injects faults (added latency, failed calls) into HTTP
+// requests and dependency adapters, so retries, timeouts and circuit breakers
+// can be exercised in development and staging. It is never enabled in
+// production (see config.ChaosConfig).
+//
+// Faults are set per target from config and may be replaced for a single
+// request (see WithFaults); adapters call Inject with the request context
+// before talking to their dependency.
+//
+// Example:
+//
+//	inj := chaos.New(chaos.NewFaults(map[string]int{"redis": 5}, nil))
+//	client.AddHook(redis.NewChaosHook(inj)) // 5% of Redis commands fail
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Injection targets
+const (
+	TargetHTTP     = "http"     // Incoming requests, before routing
+	TargetPostgres = "postgres" // Postgres connection acquires
+	TargetRedis    = "redis"    // Redis commands and pipelines
+)
+
+// Targets lists every injection target
+var Targets = []string{TargetHTTP, TargetPostgres, TargetRedis}
+
+// ErrInjected is returned (wrapped) by calls failed on purpose
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Fault is what happens to calls to one target
+type Fault struct {
+	ErrorPercent int           // Share of calls that fail, 0-100
+	Latency      time.Duration // Added before every call
+}
+
+// Faults maps targets to their fault; targets without one are untouched
+type Faults map[string]Fault
+
+// NewFaults builds faults from per-target error percentages and latencies in
+// milliseconds, as configured by CHAOS_ERROR_PERCENT and CHAOS_LATENCY_MS
+func NewFaults(errorPercent, latencyMS map[string]int) Faults {
+	faults := make(Faults)
+	for target, pct := range errorPercent {
+		f := faults[target]
+		f.ErrorPercent = pct
+		faults[target] = f
+	}
+	for target, ms := range latencyMS {
+		f := faults[target]
+		f.Latency = time.Duration(ms) * time.Millisecond
+		faults[target] = f
+	}
+	return faults
+}
+
+// ParseFaults parses "target=value" lists like "redis=5,http=1" for error
+// percentages and latencies in milliseconds, e.g. from request headers.
+// Unknown targets and out-of-range values are rejected.
+func ParseFaults(errorPercent, latencyMS string) (Faults, error) {
+	errs, err := parseTargetInts(errorPercent, 100)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error percentages: %w", err)
+	}
+	lat, err := parseTargetInts(latencyMS, -1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latencies: %w", err)
+	}
+	return NewFaults(errs, lat), nil
+}
+
+// parseTargetInts parses a "target=n" list with 0 <= n <= limit (no upper
+// bound when limit is negative)
+func parseTargetInts(spec string, limit int) (map[string]int, error) {
+	values := make(map[string]int)
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, value, ok := strings.Cut(entry, "=")
+		target = strings.TrimSpace(target)
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil || n < 0 || (limit >= 0 && n > limit) {
+			return nil, fmt.Errorf("entry %q (want target=n)", entry)
+		}
+		if !slices.Contains(Targets, target) {
+			return nil, fmt.Errorf("unknown target %q (must be one of %s)", target, strings.Join(Targets, ", "))
+		}
+		values[target] = n
+	}
+	return values, nil
+}
+
+type faultsKey struct{}
+
+// WithFaults returns a context whose calls are subject to faults instead of
+// the configured ones, e.g. for a request asking for specific faults
+func WithFaults(ctx context.Context, faults Faults) context.Context {
+	return context.WithValue(ctx, faultsKey{}, faults)
+}
+
+// Injector applies faults to calls. The zero value is not usable; a nil
+// *Injector injects nothing.
+type Injector struct {
+	faults atomic.Pointer[Faults]
+	roll   func() int // 0-99
+}
+
+// New creates an injector with the configured faults
+func New(faults Faults) *Injector {
+	inj := &Injector{roll: func() int { return rand.IntN(100) }}
+	inj.SetFaults(faults)
+	return inj
+}
+
+// SetFaults replaces the configured faults (safe for concurrent use)
+func (i *Injector) SetFaults(faults Faults) {
+	i.faults.Store(&faults)
+}
+
+// Inject delays a call to target by its fault's latency and then fails it
+// with probability ErrorPercent. It returns ctx's error if ctx ends during
+// the delay.
+func (i *Injector) Inject(ctx context.Context, target string) error {
+	if i == nil {
+		return nil
+	}
+	faults, ok := ctx.Value(faultsKey{}).(Faults)
+	if !ok {
+		faults = *i.faults.Load()
+	}
+	fault, ok := faults[target]
+	if !ok {
+		return nil
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	if fault.ErrorPercent > 0 && i.roll() < fault.ErrorPercent {
+		return fmt.Errorf("%w in %s", ErrInjected, target)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInject(t *testing.T) {
+	inj := New(NewFaults(map[string]int{TargetRedis: 30}, map[string]int{TargetPostgres: 20}))
+	rolls := []int{29, 30}
+	inj.roll = func() int {
+		r := rolls[0]
+		rolls = rolls[1:]
+		return r
+	}
+	ctx := context.Background()
+
+	if err := inj.Inject(ctx, TargetRedis); !errors.Is(err, ErrInjected) {
+		t.Errorf("Inject(redis) with roll 29 of 30%% error = %v, want ErrInjected", err)
+	}
+	if err := inj.Inject(ctx, TargetRedis); err != nil {
+		t.Errorf("Inject(redis) with roll 30 of 30%% error = %v, want nil", err)
+	}
+	if err := inj.Inject(ctx, TargetHTTP); err != nil {
+		t.Errorf("Inject(http) without a fault error = %v", err)
+	}
+
+	start := time.Now()
+	if err := inj.Inject(ctx, TargetPostgres); err != nil {
+		t.Errorf("Inject(postgres) error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Inject(postgres) took %s, want at least the 20ms latency", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := inj.Inject(cancelled, TargetPostgres); !errors.Is(err, context.Canceled) {
+		t.Errorf("Inject() with cancelled context error = %v, want context.Canceled", err)
+	}
+
+	var none *Injector
+	if err := none.Inject(ctx, TargetRedis); err != nil {
+		t.Errorf("nil Injector error = %v", err)
+	}
+}
+
+func TestWithFaultsReplacesConfigured(t *testing.T) {
+	inj := New(NewFaults(map[string]int{TargetRedis: 100}, nil))
+	ctx := WithFaults(context.Background(), NewFaults(map[string]int{TargetHTTP: 100}, nil))
+
+	if err := inj.Inject(ctx, TargetRedis); err != nil {
+		t.Errorf("Inject(redis) with request faults error = %v, want the configured fault replaced", err)
+	}
+	if err := inj.Inject(ctx, TargetHTTP); !errors.Is(err, ErrInjected) {
+		t.Errorf("Inject(http) with request faults error = %v, want ErrInjected", err)
+	}
+
+	inj.SetFaults(nil)
+	if err := inj.Inject(context.Background(), TargetRedis); err != nil {
+		t.Errorf("Inject() after SetFaults(nil) error = %v", err)
+	}
+}
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults("redis=5, http=100", "postgres=250")
+	if err != nil {
+		t.Fatalf("ParseFaults() error = %v", err)
+	}
+	want := Faults{
+		TargetRedis:    {ErrorPercent: 5},
+		TargetHTTP:     {ErrorPercent: 100},
+		TargetPostgres: {Latency: 250 * time.Millisecond},
+	}
+	for target, f := range want {
+		if faults[target] != f {
+			t.Errorf("faults[%s] = %+v, want %+v", target, faults[target], f)
+		}
+	}
+
+	for _, tt := range []struct{ errs, latency string }{
+		{"s3=5", ""},
+		{"redis=101", ""},
+		{"redis", ""},
+		{"", "http=-1"},
+		{"", "http=fast"},
+	} {
+		if _, err := ParseFaults(tt.errs, tt.latency); err == nil {
+			t.Errorf("ParseFaults(%q, %q) error = nil, want error", tt.errs, tt.latency)
+		}
+	}
+}
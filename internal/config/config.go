@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -8,43 +9,88 @@ import (
 	"time"
 )
 
+// Run modes (RUN_MODE)
+const (
+	RunModeStandard   = "standard"   // Postgres, Redis and the configured blob store
+	RunModeStandalone = "standalone" // No external services; see Config.RunMode
+)
+
 // Config holds all application configuration loaded from environment variables
+// Infrastructure settings are grouped into per-subsystem sections (see sections.go)
 type Config struct {
 	// Application
 	Environment string // "development", "staging", "production"
 	Version     string
-	Port        string
 	LogLevel    string // "debug", "info", "warn", "error"
 
-	// Database
-	PostgresDSN         string
-	PostgresMaxConns    int
-	PostgresMinConns    int
-	PostgresMaxIdleTime time.Duration
-
-	// Redis
-	RedisAddr     string
-	RedisPassword string
-	RedisDB       int
-
-	// AWS
-	AWSRegion          string
-	AWSAccessKeyID     string
-	AWSSecretAccessKey string
-	S3Bucket           string
-
-	// HTTP Server
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
-
-	// Security
-	JWTSecret            string
-	JWTExpirationHours   int
-	AllowedOrigins       []string
-	RateLimitPerMinute   int
-	EnableCORS           bool
-	EnableAuthentication bool
+	// Runtime reload (see Store)
+	ConfigFile          string        // Optional dotenv-format file with overrides, re-read on reload
+	ConfigWatchInterval time.Duration // Poll CONFIG_FILE for changes at this interval (0 disables)
+
+	// Shutdown
+	ShutdownTimeout    time.Duration // Upper bound for the whole graceful shutdown (0 bounds each step only)
+	ShutdownDrainDelay time.Duration // Report not-ready this long before draining listeners
+
+	// Apply pending database migrations at startup (development and test only;
+	// elsewhere run `api migrate` as a deploy step)
+	AutoMigrate bool
+
+	// Run without Postgres or Redis: in-memory stores seeded with the default
+	// fixtures and a filesystem blob store under DevBlobDir (development and
+	// test only; everything but the blobs is lost on exit)
+	DevInMemory bool
+	DevBlobDir  string
+
+	// RunModeStandalone goes further for demos and quick starts: no external
+	// services at all, with blobs kept in memory too and caching disabled
+	// (development and test only)
+	RunMode string // RunModeStandard or RunModeStandalone
+
+	// Security event stream, kept apart from application logs (stdout)
+	SecurityEventsOutput string // "stderr", "stdout", a file path, or "none"/"" to disable
+
+	// Subsystems
+	Postgres      PostgresConfig
+	Redis         RedisConfig
+	AWS           AWSConfig
+	Blob          BlobConfig
+	HTTP          HTTPConfig
+	API           APIConfig
+	Auth          AuthConfig
+	Semaphores    SemaphoreConfig
+	Resilience    ResilienceConfig
+	Retry         RetryConfig
+	Jobs          JobsConfig
+	Reports       ReportsConfig
+	Attachments   AttachmentsConfig
+	UserData      UserDataConfig
+	Notifications NotificationsConfig
+	Checkout      CheckoutConfig
+	DeadLetters   DeadLettersConfig
+	Email         EmailConfig
+	Diagnostics   DiagnosticsConfig
+	Status        StatusConfig
+	SLO           SLOConfig
+	Alerts        AlertsConfig
+	GraphQL       GraphQLConfig
+	WebSocket     WebSocketConfig
+	SSE           SSEConfig
+	Outbox        OutboxConfig
+	Kafka         KafkaConfig
+	SNS           SNSConfig
+	SQS           SQSConfig
+	EventBus      EventBusConfig
+	Telemetry     TelemetryConfig
+	Chaos         ChaosConfig
+
+	// Gradual rollouts of new behaviour (see internal/featureflag)
+	FeatureFlags FeatureFlagsConfig
+
+	// Localization
+	DisplayCurrency string // ISO 4217 code used for formatted amount display fields
+
+	// Templates for emails and invoices; files here override the embedded defaults
+	TemplatesDir string
 
 	// Feature Flags
 	EnableMetrics      bool
@@ -53,61 +99,140 @@ type Config struct {
 }
 
 // LoadFromEnv loads configuration from environment variables with validation
-// Fails fast if required variables are missing or invalid
+// Fails fast (panics) if required variables are missing or invalid
+// Prefer Load in binaries so every violation can be reported at once
 func LoadFromEnv() *Config {
+	cfg, err := Load()
+	if err != nil {
+		panic(fmt.Sprintf("invalid configuration: %v", err))
+	}
+	return cfg
+}
+
+// Load loads configuration from environment variables and validates it
+// Every malformed or invalid setting is collected and returned together as a
+// *ValidationError, so operators can fix all misconfigurations in one pass
+// In development and test, variables from a .env file (or ENV_FILE) are loaded first
+// Profiles from CONFIG_PROFILE_DIR (see profile.go) sit below the environment.
+func Load() (*Config, error) {
+	cfg, _, err := load()
+	return cfg, err
+}
+
+// load resolves and validates the configuration, returning the reader too so
+// callers can inspect every setting it resolved
+func load() (*Config, *envReader, error) {
+	env := &envReader{}
+	if err := loadDotEnvForEnvironment(); err != nil {
+		env.fail(err)
+	}
+
+	// Profiles supply per-environment defaults below the process environment
+	profileDir := env.bootstrap("CONFIG_PROFILE_DIR", defaultProfileDir)
+	profiles, err := loadProfiles(profileDir)
+	if err != nil {
+		env.fail(err)
+	}
+	env.profile = profiles
+
+	// Runtime overrides from CONFIG_FILE win over the process environment,
+	// which lets a reload pick up changes without restarting the process
+	configFile := env.bootstrap("CONFIG_FILE", "")
+	if configFile != "" {
+		overrides, err := readDotEnvFile(configFile)
+		if err != nil {
+			env.fail(fmt.Errorf("failed to read CONFIG_FILE %s: %w", configFile, err))
+		}
+		env.overrides = overrides
+	}
+
 	cfg := &Config{
 		// Application defaults
-		Environment: getEnv("ENVIRONMENT", "development"),
-		Version:     getEnv("VERSION", "0.0.0-dev"),
-		Port:        getEnv("PORT", "8080"),
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
-
-		// Database
-		PostgresDSN:         requireEnv("POSTGRES_DSN"),
-		PostgresMaxConns:    getEnvAsInt("POSTGRES_MAX_CONNS", 25),
-		PostgresMinConns:    getEnvAsInt("POSTGRES_MIN_CONNS", 5),
-		PostgresMaxIdleTime: getEnvAsDuration("POSTGRES_MAX_IDLE_TIME", 15*time.Minute),
-
-		// Redis
-		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisPassword: getEnv("REDIS_PASSWORD", ""),
-		RedisDB:       getEnvAsInt("REDIS_DB", 0),
-
-		// AWS
-		AWSRegion:          getEnv("AWS_REGION", "us-east-1"),
-		AWSAccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
-		AWSSecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
-		S3Bucket:           getEnv("S3_BUCKET", ""),
-
-		// HTTP Server
-		ReadTimeout:  getEnvAsDuration("HTTP_READ_TIMEOUT", 15*time.Second),
-		WriteTimeout: getEnvAsDuration("HTTP_WRITE_TIMEOUT", 15*time.Second),
-		IdleTimeout:  getEnvAsDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
-
-		// Security
-		JWTSecret:            requireEnv("JWT_SECRET"),
-		JWTExpirationHours:   getEnvAsInt("JWT_EXPIRATION_HOURS", 24),
-		AllowedOrigins:       getEnvAsSlice("ALLOWED_ORIGINS", []string{"*"}),
-		RateLimitPerMinute:   getEnvAsInt("RATE_LIMIT_PER_MINUTE", 100),
-		EnableCORS:           getEnvAsBool("ENABLE_CORS", true),
-		EnableAuthentication: getEnvAsBool("ENABLE_AUTHENTICATION", true),
+		Environment: env.String("ENVIRONMENT", "development"),
+		Version:     env.String("VERSION", "0.0.0-dev"),
+		LogLevel:    env.String("LOG_LEVEL", "info"),
+
+		// Runtime reload
+		ConfigFile:          configFile,
+		ConfigWatchInterval: env.Duration("CONFIG_WATCH_INTERVAL", 0),
+
+		// Shutdown
+		ShutdownTimeout:    env.Duration("SHUTDOWN_TIMEOUT", 10*time.Second),
+		ShutdownDrainDelay: env.Duration("SHUTDOWN_DRAIN_DELAY", 0),
+
+		// Schema
+		AutoMigrate: env.Bool("AUTO_MIGRATE", false),
+
+		// Infrastructure-free development mode
+		DevInMemory: env.Bool("DEV_INMEMORY", false),
+		DevBlobDir:  env.String("DEV_BLOB_DIR", "./data/blobs"),
+		RunMode:     env.String("RUN_MODE", RunModeStandard),
+
+		// Security events
+		SecurityEventsOutput: env.String("SECURITY_EVENTS_OUTPUT", "stderr"),
+
+		// Subsystems
+		Postgres:      loadPostgresConfig(env),
+		Redis:         loadRedisConfig(env),
+		AWS:           loadAWSConfig(env),
+		Blob:          loadBlobConfig(env),
+		HTTP:          loadHTTPConfig(env),
+		API:           loadAPIConfig(env),
+		Auth:          loadAuthConfig(env),
+		Semaphores:    loadSemaphoreConfig(env),
+		Resilience:    loadResilienceConfig(env),
+		Retry:         loadRetryConfig(env),
+		Jobs:          loadJobsConfig(env),
+		Reports:       loadReportsConfig(env),
+		Attachments:   loadAttachmentsConfig(env),
+		UserData:      loadUserDataConfig(env),
+		Notifications: loadNotificationsConfig(env),
+		Checkout:      loadCheckoutConfig(env),
+		DeadLetters:   loadDeadLettersConfig(env),
+		Email:         loadEmailConfig(env),
+		Diagnostics:   loadDiagnosticsConfig(env),
+		Status:        loadStatusConfig(env),
+		SLO:           loadSLOConfig(env),
+		Alerts:        loadAlertsConfig(env),
+		GraphQL:       loadGraphQLConfig(env),
+		WebSocket:     loadWebSocketConfig(env),
+		SSE:           loadSSEConfig(env),
+		Outbox:        loadOutboxConfig(env),
+		Kafka:         loadKafkaConfig(env),
+		SNS:           loadSNSConfig(env),
+		SQS:           loadSQSConfig(env),
+		EventBus:      loadEventBusConfig(env),
+		Telemetry:     loadTelemetryConfig(env),
+		Chaos:         loadChaosConfig(env),
+
+		FeatureFlags: loadFeatureFlagsConfig(env),
+
+		// Localization
+		DisplayCurrency: env.String("DISPLAY_CURRENCY", "USD"),
+
+		// Templates
+		TemplatesDir: env.String("TEMPLATES_DIR", ""),
 
 		// Feature Flags
-		EnableMetrics:      getEnvAsBool("ENABLE_METRICS", true),
-		EnableHealthChecks: getEnvAsBool("ENABLE_HEALTH_CHECKS", true),
-		EnableSwagger:      getEnvAsBool("ENABLE_SWAGGER", false),
+		EnableMetrics:      env.Bool("ENABLE_METRICS", true),
+		EnableHealthChecks: env.Bool("ENABLE_HEALTH_CHECKS", true),
+		EnableSwagger:      env.Bool("ENABLE_SWAGGER", false),
 	}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		panic(fmt.Sprintf("invalid configuration: %v", err))
+	// Validate configuration, reporting parse failures and violations together
+	errs := appendViolations(env.errs, cfg.Validate())
+	if len(errs) > 0 {
+		return nil, env, &ValidationError{Errors: errs}
 	}
 
-	return cfg
+	return cfg, env, nil
 }
 
 // Validate checks that the configuration is valid
+// All violations are collected and returned as a *ValidationError
 func (c *Config) Validate() error {
+	var errs []error
+
 	// Validate environment
 	validEnvs := map[string]bool{
 		"development": true,
@@ -116,7 +241,7 @@ func (c *Config) Validate() error {
 		"test":        true,
 	}
 	if !validEnvs[c.Environment] {
-		return fmt.Errorf("invalid environment: %s (must be development, staging, production, or test)", c.Environment)
+		errs = append(errs, fmt.Errorf("invalid environment: %s (must be development, staging, production, or test)", c.Environment))
 	}
 
 	// Validate log level
@@ -127,49 +252,248 @@ func (c *Config) Validate() error {
 		"error": true,
 	}
 	if !validLogLevels[c.LogLevel] {
-		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.LogLevel)
+		errs = append(errs, fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.LogLevel))
+	}
+
+	if c.AutoMigrate && c.Environment != "development" && c.Environment != "test" {
+		errs = append(errs, fmt.Errorf("AUTO_MIGRATE is only allowed in development and test (run `api migrate` as a deploy step instead)"))
 	}
 
-	// Validate port
-	if c.Port == "" {
-		return fmt.Errorf("PORT cannot be empty")
+	if c.DevInMemory && c.Environment != "development" && c.Environment != "test" {
+		errs = append(errs, fmt.Errorf("DEV_INMEMORY is only allowed in development and test (data would be lost on restart)"))
+	}
+	if c.DevInMemory && c.DevBlobDir == "" && !c.IsStandalone() {
+		errs = append(errs, fmt.Errorf("DEV_INMEMORY requires DEV_BLOB_DIR for the filesystem blob store"))
+	}
+	if c.RunMode != "" && c.RunMode != RunModeStandard && c.RunMode != RunModeStandalone {
+		errs = append(errs, fmt.Errorf("invalid RUN_MODE: %s (must be %s or %s)", c.RunMode, RunModeStandard, RunModeStandalone))
 	}
-	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
-		return fmt.Errorf("invalid PORT: %s (must be 1-65535)", c.Port)
+	if c.IsStandalone() && c.Environment != "development" && c.Environment != "test" {
+		errs = append(errs, fmt.Errorf("RUN_MODE=standalone is only allowed in development and test (data would be lost on restart)"))
 	}
 
-	// Validate database config
-	if c.PostgresDSN == "" {
-		return fmt.Errorf("POSTGRES_DSN is required")
+	if c.ShutdownTimeout < 0 || c.ShutdownDrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT and SHUTDOWN_DRAIN_DELAY must not be negative"))
 	}
-	if c.PostgresMaxConns < c.PostgresMinConns {
-		return fmt.Errorf("POSTGRES_MAX_CONNS (%d) must be >= POSTGRES_MIN_CONNS (%d)", c.PostgresMaxConns, c.PostgresMinConns)
+	if c.ShutdownTimeout > 0 && c.ShutdownDrainDelay >= c.ShutdownTimeout {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_DRAIN_DELAY (%s) must be shorter than SHUTDOWN_TIMEOUT (%s)", c.ShutdownDrainDelay, c.ShutdownTimeout))
 	}
 
-	// Validate JWT config
-	if c.JWTSecret == "" {
-		return fmt.Errorf("JWT_SECRET is required")
+	// Validate subsystems
+	errs = appendViolations(errs, c.HTTP.Validate())
+	errs = appendViolations(errs, c.API.Validate())
+	if !c.InMemory() {
+		// In-memory mode connects to neither
+		errs = appendViolations(errs, c.Postgres.Validate())
+		errs = appendViolations(errs, c.Redis.Validate())
 	}
-	if len(c.JWTSecret) < 32 {
-		return fmt.Errorf("JWT_SECRET must be at least 32 characters long for security")
+	errs = appendViolations(errs, c.AWS.Validate())
+	if !c.InMemory() {
+		// In-memory mode keeps blobs locally whatever the backend
+		errs = appendViolations(errs, c.Blob.Validate())
+	}
+	if c.Blob.Backend == BlobBackendMemory && c.Environment != "development" && c.Environment != "test" {
+		errs = append(errs, fmt.Errorf("BLOB_BACKEND=memory is only allowed in development and test (blobs would be lost on restart)"))
+	}
+	if c.HTTP.ACMEEnabled() && !c.Blob.Shared() {
+		// Certificates live in the shared store so every instance serves the same ones
+		errs = append(errs, fmt.Errorf("ACME_HOSTS requires BLOB_BACKEND=s3, azure or gcs for the shared certificate cache"))
+	} else if missing := c.blobStoreMissing(); c.HTTP.ACMEEnabled() && missing != "" {
+		errs = append(errs, fmt.Errorf("ACME_HOSTS requires %s for the shared certificate cache", missing))
+	}
+
+	if c.HTTP.AdminAddr != "" && !c.Auth.EnableAuthentication {
+		errs = append(errs, fmt.Errorf("ADMIN_ADDR requires ENABLE_AUTHENTICATION (every admin listener route but the health checks needs an admin token)"))
+	}
+
+	// Validate localization
+	if c.DisplayCurrency != "" && (len(c.DisplayCurrency) != 3 || strings.ToUpper(c.DisplayCurrency) != c.DisplayCurrency) {
+		errs = append(errs, fmt.Errorf("invalid DISPLAY_CURRENCY: %q (must be a 3-letter ISO 4217 code like USD)", c.DisplayCurrency))
+	}
+
+	errs = appendViolations(errs, c.Auth.Validate())
+	errs = appendViolations(errs, c.Semaphores.Validate())
+	errs = appendViolations(errs, c.Resilience.Validate())
+	errs = appendViolations(errs, c.Retry.Validate())
+	errs = appendViolations(errs, c.Jobs.Validate())
+	errs = appendViolations(errs, c.Reports.Validate())
+	errs = appendViolations(errs, c.Email.Validate())
+	errs = appendViolations(errs, c.Diagnostics.Validate())
+	if missing := c.blobStoreMissing(); c.Diagnostics.Output == "blob" && missing != "" {
+		errs = append(errs, fmt.Errorf("DIAGNOSTICS_OUTPUT=blob requires %s to store dumps", missing))
+	}
+	errs = appendViolations(errs, c.Status.Validate())
+	errs = appendViolations(errs, c.SLO.Validate())
+	errs = appendViolations(errs, c.Alerts.Validate())
+	errs = appendViolations(errs, c.GraphQL.Validate())
+	errs = appendViolations(errs, c.WebSocket.Validate())
+	errs = appendViolations(errs, c.SSE.Validate())
+	errs = appendViolations(errs, c.Outbox.Validate())
+	errs = appendViolations(errs, c.Kafka.Validate())
+	errs = appendViolations(errs, c.SNS.Validate())
+	errs = appendViolations(errs, c.SQS.Validate())
+	errs = appendViolations(errs, c.EventBus.Validate())
+	errs = appendViolations(errs, c.Telemetry.Validate())
+	errs = append(errs, c.telemetryPortConflicts()...)
+	errs = appendViolations(errs, c.Chaos.Validate())
+	if c.Chaos.Enabled && c.Environment == "production" {
+		errs = append(errs, fmt.Errorf("CHAOS_ENABLED is not allowed in production (use development or staging)"))
+	}
+	errs = appendViolations(errs, c.FeatureFlags.Validate())
+	if c.InMemory() && c.FeatureFlags.Provider == "redis" {
+		errs = append(errs, fmt.Errorf("FEATURE_FLAGS_PROVIDER=redis is not supported with DEV_INMEMORY or RUN_MODE=standalone (use env or file)"))
+	}
+	errs = appendViolations(errs, c.Attachments.Validate())
+	if missing := c.blobStoreMissing(); c.Reports.Enabled && missing != "" {
+		errs = append(errs, fmt.Errorf("REPORTS_ENABLED requires %s to store rendered reports", missing))
+	}
+	errs = appendViolations(errs, c.UserData.Validate())
+	if missing := c.blobStoreMissing(); c.UserData.Enabled && missing != "" {
+		errs = append(errs, fmt.Errorf("USER_DATA_JOBS_ENABLED requires %s to store imports and exports", missing))
+	}
+	if !c.Blob.ManageLifecycle && !c.InMemory() && ((c.Reports.Enabled && c.Reports.Retention > 0) || (c.UserData.Enabled && c.UserData.Retention > 0)) {
+		errs = append(errs, fmt.Errorf("REPORTS_RETENTION and USER_DATA_RETENTION require BLOB_MANAGE_LIFECYCLE (the bucket's lifecycle rules delete old objects)"))
+	}
+	errs = appendViolations(errs, c.Notifications.Validate())
+	errs = appendViolations(errs, c.Checkout.Validate())
+	if c.Checkout.Enabled && c.Checkout.PaymentsURL == "" && !c.InMemory() {
+		errs = append(errs, fmt.Errorf("CHECKOUT_ENABLED requires CHECKOUT_PAYMENTS_URL to capture payments"))
+	}
+	errs = appendViolations(errs, c.DeadLetters.Validate())
+	if c.Attachments.Enabled && c.InMemory() {
+		// Uploads go straight to the store with presigned URLs, which local stores can't issue
+		errs = append(errs, fmt.Errorf("ATTACHMENTS_ENABLED is not supported with DEV_INMEMORY or RUN_MODE=standalone (local blob stores cannot presign uploads)"))
+	} else if c.Attachments.Enabled && !c.Blob.Shared() {
+		errs = append(errs, fmt.Errorf("ATTACHMENTS_ENABLED requires BLOB_BACKEND=s3, azure or gcs (local blob stores cannot presign uploads)"))
+	} else if missing := c.blobStoreMissing(); c.Attachments.Enabled && missing != "" {
+		errs = append(errs, fmt.Errorf("ATTACHMENTS_ENABLED requires %s to store uploads", missing))
+	}
+	if c.Attachments.Enabled && c.Attachments.Resumable && c.Blob.Shared() && c.Blob.Backend != "" && c.Blob.Backend != BlobBackendS3 {
+		errs = append(errs, fmt.Errorf("ATTACHMENTS_RESUMABLE requires BLOB_BACKEND=s3 (resumable uploads are stored as multipart uploads)"))
 	}
 
 	// Production-specific validations
 	if c.Environment == "production" {
 		if c.LogLevel == "debug" {
-			return fmt.Errorf("debug log level should not be used in production")
+			errs = append(errs, fmt.Errorf("debug log level should not be used in production"))
 		}
 		if c.EnableSwagger {
-			return fmt.Errorf("swagger should be disabled in production")
+			errs = append(errs, fmt.Errorf("swagger should be disabled in production"))
 		}
-		if contains(c.AllowedOrigins, "*") {
-			return fmt.Errorf("wildcard CORS origins (*) should not be used in production")
+		if c.Reports.Enabled && c.Email.SMTPHost == "" {
+			errs = append(errs, fmt.Errorf("REPORTS_ENABLED requires SMTP_HOST in production (reports would only be logged)"))
+		}
+		if contains(c.HTTP.AllowedOrigins, "*") {
+			errs = append(errs, fmt.Errorf("wildcard CORS origins (*) should not be used in production"))
+		}
+		for _, l := range c.Telemetry.listeners() {
+			if l.cfg.AuthToken == "" && !isLoopbackAddr(l.cfg.Addr) {
+				errs = append(errs, fmt.Errorf("%s_AUTH_TOKEN is required in production when %s_ADDR is not a loopback address", l.prefix, l.prefix))
+			}
 		}
 	}
 
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
 	return nil
 }
 
+// telemetryPortConflicts reports enabled telemetry listeners sharing a port
+// with the API, the HTTPS redirect or the admin listener
+func (c *Config) telemetryPortConflicts() []error {
+	taken := map[string]string{c.HTTP.Port: "PORT"}
+	if c.HTTP.RedirectPort != "" {
+		taken[c.HTTP.RedirectPort] = "HTTP_REDIRECT_PORT"
+	}
+	if c.HTTP.AdminAddr != "" {
+		if port, err := listenerPort(c.HTTP.AdminAddr); err == nil {
+			taken[port] = "ADMIN_ADDR"
+		}
+	}
+
+	var errs []error
+	for _, l := range c.Telemetry.listeners() {
+		// Malformed addresses are reported by TelemetryConfig.Validate
+		port, err := listenerPort(l.cfg.Addr)
+		if err != nil {
+			continue
+		}
+		if other, ok := taken[port]; ok {
+			errs = append(errs, fmt.Errorf("%s_ADDR port %s collides with %s", l.prefix, port, other))
+		}
+	}
+	return errs
+}
+
+// ValidationError aggregates every configuration problem found while loading
+type ValidationError struct {
+	Errors []error
+}
+
+// Error joins all violations into a single message
+func (e *ValidationError) Error() string {
+	msgs := Violations(e)
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	return fmt.Sprintf("%d configuration errors: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Unwrap exposes the individual violations to errors.Is and errors.As
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// appendViolations flattens err (a *ValidationError or any other error) into errs
+func appendViolations(errs []error, err error) []error {
+	if err == nil {
+		return errs
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return append(errs, verr.Errors...)
+	}
+	return append(errs, err)
+}
+
+// Violations returns the individual violation messages contained in err
+// Useful for structured logging of configuration failures
+func Violations(err error) []string {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return []string{err.Error()}
+	}
+	msgs := make([]string, len(verr.Errors))
+	for i, e := range verr.Errors {
+		msgs[i] = e.Error()
+	}
+	return msgs
+}
+
+// IsStandalone returns true if running with no external services (RUN_MODE=standalone)
+func (c *Config) IsStandalone() bool {
+	return c.RunMode == RunModeStandalone
+}
+
+// InMemory returns true if Postgres and Redis are replaced by in-process
+// stores (DEV_INMEMORY or RUN_MODE=standalone)
+func (c *Config) InMemory() bool {
+	return c.DevInMemory || c.IsStandalone()
+}
+
+// blobStoreMissing returns the setting the blob store still needs, empty
+// when the features storing blobs can have one
+func (c *Config) blobStoreMissing() string {
+	if c.InMemory() {
+		return ""
+	}
+	if (c.Blob.Backend == "" || c.Blob.Backend == BlobBackendS3) && c.AWS.S3Bucket == "" {
+		return "S3_BUCKET"
+	}
+	return ""
+}
+
 // IsDevelopment returns true if running in development mode
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
@@ -187,70 +511,260 @@ func (c *Config) IsTest() bool {
 
 // DatabaseURL returns the formatted database URL for display (with password masked)
 func (c *Config) DatabaseURL() string {
-	return maskPassword(c.PostgresDSN)
+	return c.Postgres.DatabaseURL()
 }
 
 // Helper functions for environment variable parsing
 
-// getEnv reads an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
+// envReader reads typed environment variables, collecting parse errors
+// instead of failing on the first one. Values in overrides (from CONFIG_FILE)
+// take precedence over the process environment, which takes precedence over
+// the profile. Every variable read is recorded in settings.
+type envReader struct {
+	overrides map[string]string
+	profile   *profile
+	settings  map[string]Setting
+	errs      []error
+}
+
+// fail records a loading error
+func (e *envReader) fail(err error) {
+	e.errs = append(e.errs, err)
+}
+
+// lookup returns the raw value for key, recording it (or defaultValue, the
+// formatted default, when it is unset) as a setting
+func (e *envReader) lookup(key, defaultValue string) string {
+	value, source := e.resolve(key)
+	if value == "" {
+		e.record(key, defaultValue, SourceDefault)
+		return ""
+	}
+	e.record(key, value, source)
+	return value
+}
+
+// record stores the resolved value of key
+func (e *envReader) record(key, value, source string) {
+	if e.settings == nil {
+		e.settings = make(map[string]Setting)
+	}
+	e.settings[key] = Setting{Key: key, Value: value, Source: source}
+}
+
+// resolve finds key's value in precedence order
+func (e *envReader) resolve(key string) (value, source string) {
+	if value, ok := e.overrides[key]; ok {
+		return value, SourceConfigFile
+	}
 	if value := os.Getenv(key); value != "" {
+		return value, SourceEnv
+	}
+	if e.profile != nil {
+		if value, ok := e.profile.vars[key]; ok {
+			return value, e.profile.sources[key]
+		}
+	}
+	return "", ""
+}
+
+// bootstrap reads a variable that selects where the rest of the configuration
+// comes from, so it is only taken from the process environment
+func (e *envReader) bootstrap(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		e.record(key, value, SourceEnv)
 		return value
 	}
+	e.record(key, defaultValue, SourceDefault)
 	return defaultValue
 }
 
-// requireEnv reads an environment variable and panics if it's not set
-func requireEnv(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		panic(fmt.Sprintf("required environment variable %s is not set", key))
+// String reads an environment variable or returns a default value
+func (e *envReader) String(key, defaultValue string) string {
+	if value := e.lookup(key, defaultValue); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Int reads an environment variable as an integer or returns a default
+func (e *envReader) Int(key string, defaultValue int) int {
+	value, err := parseInt(key, e.lookup(key, strconv.Itoa(defaultValue)), defaultValue)
+	if err != nil {
+		e.fail(err)
 	}
 	return value
 }
 
-// getEnvAsInt reads an environment variable as an integer or returns a default
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := os.Getenv(key)
+// Float reads an environment variable as a decimal number or returns a default
+func (e *envReader) Float(key string, defaultValue float64) float64 {
+	value, err := parseFloat(key, e.lookup(key, strconv.FormatFloat(defaultValue, 'f', -1, 64)), defaultValue)
+	if err != nil {
+		e.fail(err)
+	}
+	return value
+}
+
+// Bool reads an environment variable as a boolean or returns a default
+func (e *envReader) Bool(key string, defaultValue bool) bool {
+	value, err := parseBool(key, e.lookup(key, strconv.FormatBool(defaultValue)), defaultValue)
+	if err != nil {
+		e.fail(err)
+	}
+	return value
+}
+
+// Duration reads an environment variable as a duration or returns a default
+func (e *envReader) Duration(key string, defaultValue time.Duration) time.Duration {
+	value, err := parseDuration(key, e.lookup(key, defaultValue.String()), defaultValue)
+	if err != nil {
+		e.fail(err)
+	}
+	return value
+}
+
+// Slice reads an environment variable as a comma-separated slice or returns a default
+func (e *envReader) Slice(key string, defaultValue []string) []string {
+	return parseSlice(e.lookup(key, strings.Join(defaultValue, ",")), defaultValue)
+}
+
+// IntMap reads a comma-separated list of name=integer pairs, e.g.
+// "reports=2,imports=1". Malformed entries are recorded and skipped.
+func (e *envReader) IntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for _, entry := range e.Slice(key, nil) {
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || strings.TrimSpace(name) == "" || err != nil {
+			e.fail(fmt.Errorf("invalid %s entry %q (want name=value)", key, entry))
+			continue
+		}
+		values[strings.TrimSpace(name)] = n
+	}
+	return values
+}
+
+// StringMap reads a comma-separated list of name=value pairs, e.g.
+// "key:abc=premium". Malformed entries are recorded and skipped.
+func (e *envReader) StringMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, entry := range e.Slice(key, nil) {
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
+			e.fail(fmt.Errorf("invalid %s entry %q (want name=value)", key, entry))
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// DurationMap reads a comma-separated list of name=duration pairs, e.g.
+// "POST /api/reports=60s". Malformed entries are recorded and skipped.
+func (e *envReader) DurationMap(key string) map[string]time.Duration {
+	values := make(map[string]time.Duration)
+	for _, entry := range e.Slice(key, nil) {
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || strings.TrimSpace(name) == "" || err != nil {
+			e.fail(fmt.Errorf("invalid %s entry %q (want name=duration)", key, entry))
+			continue
+		}
+		values[strings.TrimSpace(name)] = d
+	}
+	return values
+}
+
+// DateMap reads a comma-separated list of name=date pairs (YYYY-MM-DD or
+// RFC 3339), e.g. "1=2026-12-01". Malformed entries are recorded and skipped.
+func (e *envReader) DateMap(key string) map[string]time.Time {
+	values := make(map[string]time.Time)
+	for _, entry := range e.Slice(key, nil) {
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		value = strings.TrimSpace(value)
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, value)
+		}
+		if !ok || strings.TrimSpace(name) == "" || err != nil {
+			e.fail(fmt.Errorf("invalid %s entry %q (want name=YYYY-MM-DD)", key, entry))
+			continue
+		}
+		values[strings.TrimSpace(name)] = t
+	}
+	return values
+}
+
+// getEnv reads an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// parseInt parses the integer value of variable key
+func parseInt(key, valueStr string, defaultValue int) (int, error) {
 	if valueStr == "" {
-		return defaultValue
+		return defaultValue, nil
 	}
 	value, err := strconv.Atoi(valueStr)
 	if err != nil {
-		panic(fmt.Sprintf("invalid integer value for %s: %s", key, valueStr))
+		return defaultValue, fmt.Errorf("invalid integer value for %s: %s", key, valueStr)
 	}
-	return value
+	return value, nil
 }
 
-// getEnvAsBool reads an environment variable as a boolean or returns a default
-func getEnvAsBool(key string, defaultValue bool) bool {
-	valueStr := os.Getenv(key)
+// parseFloat parses the decimal value of variable key
+func parseFloat(key, valueStr string, defaultValue float64) (float64, error) {
 	if valueStr == "" {
-		return defaultValue
+		return defaultValue, nil
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue, fmt.Errorf("invalid number value for %s: %s", key, valueStr)
+	}
+	return value, nil
+}
+
+// parseBool parses the boolean value of variable key
+func parseBool(key, valueStr string, defaultValue bool) (bool, error) {
+	if valueStr == "" {
+		return defaultValue, nil
 	}
 	value, err := strconv.ParseBool(valueStr)
 	if err != nil {
-		panic(fmt.Sprintf("invalid boolean value for %s: %s (use true/false, 1/0, yes/no)", key, valueStr))
+		return defaultValue, fmt.Errorf("invalid boolean value for %s: %s (use true/false, 1/0, yes/no)", key, valueStr)
 	}
-	return value
+	return value, nil
 }
 
-// getEnvAsDuration reads an environment variable as a duration or returns a default
-func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	valueStr := os.Getenv(key)
+// parseDuration parses the duration value of variable key
+func parseDuration(key, valueStr string, defaultValue time.Duration) (time.Duration, error) {
 	if valueStr == "" {
-		return defaultValue
+		return defaultValue, nil
 	}
 	value, err := time.ParseDuration(valueStr)
 	if err != nil {
-		panic(fmt.Sprintf("invalid duration value for %s: %s (use format like '30s', '5m', '1h')", key, valueStr))
+		return defaultValue, fmt.Errorf("invalid duration value for %s: %s (use format like '30s', '5m', '1h')", key, valueStr)
 	}
-	return value
+	return value, nil
 }
 
-// getEnvAsSlice reads an environment variable as a comma-separated slice or returns a default
-func getEnvAsSlice(key string, defaultValue []string) []string {
-	valueStr := os.Getenv(key)
+// parseSlice splits a comma-separated value, trimming whitespace
+func parseSlice(valueStr string, defaultValue []string) []string {
 	if valueStr == "" {
 		return defaultValue
 	}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// dotEnvEnvironments lists the environments where a .env file is honored.
+// Staging and production must get their configuration from the real environment.
+var dotEnvEnvironments = map[string]bool{
+	"development": true,
+	"test":        true,
+}
+
+// loadDotEnvForEnvironment loads the .env file (or ENV_FILE) when running in
+// development or test. A missing file is not an error.
+func loadDotEnvForEnvironment() error {
+	if !dotEnvEnvironments[getEnv("ENVIRONMENT", "development")] {
+		return nil
+	}
+
+	path := getEnv("ENV_FILE", ".env")
+	if err := LoadDotEnv(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to load env file %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDotEnv reads KEY=VALUE pairs from a dotenv file into the process environment.
+// Variables that are already set are never overridden, so exported values always win.
+//
+// Supported syntax:
+//
+//	# comments and blank lines are ignored
+//	export KEY=value       # "export" prefix and trailing comments are allowed
+//	KEY="quoted\nvalue"    # double quotes support \n, \t, \" and \\ escapes
+//	KEY='literal value'    # single quotes are taken verbatim
+func LoadDotEnv(path string) error {
+	vars, err := readDotEnvFile(path)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range vars {
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// readDotEnvFile parses a dotenv file without touching the process environment
+func readDotEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseDotEnv(file.Name(), bufio.NewScanner(file))
+}
+
+// parseDotEnv parses dotenv lines into a map of variables
+func parseDotEnv(name string, scanner *bufio.Scanner) (map[string]string, error) {
+	vars := make(map[string]string)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", name, lineNum)
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: invalid key %q", name, lineNum, key)
+		}
+
+		parsed, err := parseDotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, lineNum, err)
+		}
+		vars[key] = parsed
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return vars, nil
+}
+
+// parseDotEnvValue unquotes a value and strips trailing comments from unquoted values
+func parseDotEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch value[0] {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return value[1 : end+1], nil
+
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(value); i++ {
+			c := value[i]
+			if c == '"' {
+				return sb.String(), nil
+			}
+			if c == '\\' && i+1 < len(value) {
+				i++
+				switch value[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					sb.WriteByte(value[i])
+				}
+				continue
+			}
+			sb.WriteByte(c)
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	}
+
+	// Unquoted: a " #" starts a comment
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = value[:idx]
+	}
+	return strings.TrimSpace(value), nil
+}
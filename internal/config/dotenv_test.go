@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDotEnv(t *testing.T) {
+	content := `# local overrides
+PORT=9090
+export LOG_LEVEL=debug
+QUOTED="hello\nworld"
+SINGLE='raw \n value'
+WITH_COMMENT=value # trailing comment
+EMPTY=
+`
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	// Already-exported variables must win over the file
+	os.Setenv("PORT", "3000")
+	defer func() {
+		for _, key := range []string{"PORT", "LOG_LEVEL", "QUOTED", "SINGLE", "WITH_COMMENT", "EMPTY"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	if err := LoadDotEnv(path); err != nil {
+		t.Fatalf("LoadDotEnv() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"PORT":         "3000",
+		"LOG_LEVEL":    "debug",
+		"QUOTED":       "hello\nworld",
+		"SINGLE":       `raw \n value`,
+		"WITH_COMMENT": "value",
+		"EMPTY":        "",
+	}
+	for key, want := range expected {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadDotEnvMissingFile(t *testing.T) {
+	err := LoadDotEnv(filepath.Join(t.TempDir(), "missing.env"))
+	if !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestLoadDotEnvInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("NOT_A_PAIR\n"), 0600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	if err := LoadDotEnv(path); err == nil {
+		t.Error("expected error for line without '='")
+	}
+}
+
+func TestLoadFromEnvSkipsDotEnvInProduction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("VERSION=from-dotenv\n"), 0600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	os.Setenv("ENVIRONMENT", "production")
+	os.Setenv("ENV_FILE", path)
+	defer func() {
+		os.Unsetenv("ENVIRONMENT")
+		os.Unsetenv("ENV_FILE")
+		os.Unsetenv("VERSION")
+	}()
+
+	loadDotEnvForEnvironment()
+
+	if v := os.Getenv("VERSION"); v != "" {
+		t.Errorf("expected .env to be ignored in production, VERSION=%q", v)
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Configuration profiles are dotenv-format files with per-environment
+// settings, kept in CONFIG_PROFILE_DIR (default ./config) and committed with
+// the code. base.env applies everywhere and <ENVIRONMENT>.env overrides it,
+// e.g. config/base.env then config/staging.env. Missing files are skipped.
+//
+// Precedence, lowest first:
+//
+//	built-in defaults < base.env < <environment>.env < environment variables < CONFIG_FILE
+//
+// Secrets belong in the environment, not in profiles.
+const defaultProfileDir = "config"
+
+// profile holds the merged profile variables and the file each one came from
+type profile struct {
+	vars    map[string]string
+	sources map[string]string
+	files   []string // Files applied, in order
+}
+
+// loadProfiles reads base.env and the environment's profile from dir. The
+// environment is taken from ENVIRONMENT, falling back to base.env.
+func loadProfiles(dir string) (*profile, error) {
+	p := &profile{vars: map[string]string{}, sources: map[string]string{}}
+
+	base := filepath.Join(dir, "base.env")
+	if err := p.apply(base); err != nil {
+		return nil, err
+	}
+
+	environment := getEnv("ENVIRONMENT", p.vars["ENVIRONMENT"])
+	if environment == "" {
+		environment = "development"
+	}
+	if strings.ContainsAny(environment, `/\.`) {
+		return nil, fmt.Errorf("invalid ENVIRONMENT %q for a profile name", environment)
+	}
+
+	overlay := filepath.Join(dir, environment+".env")
+	vars, err := readDotEnvFile(overlay)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile %s: %w", overlay, err)
+	}
+	if _, ok := vars["ENVIRONMENT"]; ok {
+		// The environment selects the profile, so the profile can't change it
+		return nil, fmt.Errorf("profile %s must not set ENVIRONMENT", overlay)
+	}
+	p.merge(overlay, vars)
+	return p, nil
+}
+
+// apply merges the profile file at path, skipping it if it doesn't exist
+func (p *profile) apply(path string) error {
+	vars, err := readDotEnvFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read profile %s: %w", path, err)
+	}
+	p.merge(path, vars)
+	return nil
+}
+
+func (p *profile) merge(path string, vars map[string]string) {
+	for key, value := range vars {
+		p.vars[key] = value
+		p.sources[key] = path
+	}
+	p.files = append(p.files, path)
+}
+
+// Setting sources other than profile file paths
+const (
+	SourceDefault    = "default"
+	SourceEnv        = "env"
+	SourceConfigFile = "CONFIG_FILE"
+)
+
+// Setting is one resolved configuration variable and where its value came from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string // SourceDefault, SourceEnv, SourceConfigFile or a profile file path
+}
+
+// secretMarkers identify variables whose values must never be printed
+var secretMarkers = []string{"SECRET", "PASSWORD", "TOKEN", "ACCESS_KEY_ID", "WEBHOOK_URL", "ROUTING_KEY", "API_KEY"}
+
+// Secret reports whether the setting holds a credential
+func (s Setting) Secret() bool {
+	for _, marker := range secretMarkers {
+		if strings.Contains(s.Key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the value safe for display: secrets are masked and
+// passwords in connection strings replaced
+func (s Setting) Redacted() string {
+	switch {
+	case s.Value == "":
+		return ""
+	case s.Secret():
+		return "****"
+	case strings.HasSuffix(s.Key, "_DSN"):
+		return maskPassword(s.Value)
+	}
+	return s.Value
+}
+
+// Effective resolves the configuration the way Load does and returns every
+// setting read, sorted by key, with the profile files that were applied.
+// Settings are returned even when the configuration is invalid (err then
+// reports the violations), so a broken configuration can be inspected.
+func Effective() (settings []Setting, profiles []string, err error) {
+	_, env, err := load()
+	for _, s := range env.settings {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	if env.profile != nil {
+		profiles = env.profile.files
+	}
+	return settings, profiles, err
+}
@@ -0,0 +1,2664 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Subsystem Configuration
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// Each subsystem owns a typed config section with its defaults, env loading and
+// validation, so infrastructure packages can accept exactly what they need:
+//
+//	pool, err := postgres.NewPgxPool(cfg.Postgres, logg)
+//	client := redis.NewRedisClient(cfg.Redis)
+//
+// Validate methods treat zero values as "use the library default" so partially
+// filled sections (e.g. in tests) stay valid.
+
+// PostgresConfig configures the PostgreSQL connection pool
+type PostgresConfig struct {
+	DSN               string
+	MaxConns          int
+	MinConns          int
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+}
+
+// DefaultPostgresConfig returns the pool defaults used when no env vars are set
+func DefaultPostgresConfig() PostgresConfig {
+	return PostgresConfig{
+		MaxConns:          25,
+		MinConns:          5,
+		MaxConnLifetime:   time.Hour,
+		MaxConnIdleTime:   15 * time.Minute,
+		HealthCheckPeriod: time.Minute,
+		ConnectTimeout:    5 * time.Second,
+	}
+}
+
+func loadPostgresConfig(env *envReader) PostgresConfig {
+	def := DefaultPostgresConfig()
+	return PostgresConfig{
+		DSN:               env.String("POSTGRES_DSN", ""),
+		MaxConns:          env.Int("POSTGRES_MAX_CONNS", def.MaxConns),
+		MinConns:          env.Int("POSTGRES_MIN_CONNS", def.MinConns),
+		MaxConnLifetime:   env.Duration("POSTGRES_MAX_CONN_LIFETIME", def.MaxConnLifetime),
+		MaxConnIdleTime:   env.Duration("POSTGRES_MAX_IDLE_TIME", def.MaxConnIdleTime),
+		HealthCheckPeriod: env.Duration("POSTGRES_HEALTH_CHECK_PERIOD", def.HealthCheckPeriod),
+		ConnectTimeout:    env.Duration("POSTGRES_CONNECT_TIMEOUT", def.ConnectTimeout),
+	}
+}
+
+// Validate checks the PostgreSQL settings
+func (c PostgresConfig) Validate() error {
+	var errs []error
+	if c.DSN == "" {
+		errs = append(errs, fmt.Errorf("POSTGRES_DSN is required"))
+	}
+	if c.MaxConns < 0 || c.MinConns < 0 {
+		errs = append(errs, fmt.Errorf("POSTGRES_MAX_CONNS and POSTGRES_MIN_CONNS must not be negative"))
+	}
+	if c.MaxConns < c.MinConns {
+		errs = append(errs, fmt.Errorf("POSTGRES_MAX_CONNS (%d) must be >= POSTGRES_MIN_CONNS (%d)", c.MaxConns, c.MinConns))
+	}
+	return validationErrors(errs)
+}
+
+// DatabaseURL returns the DSN for display (with password masked)
+func (c PostgresConfig) DatabaseURL() string {
+	return maskPassword(c.DSN)
+}
+
+// RedisConfig configures the Redis client
+type RedisConfig struct {
+	Addr         string
+	Password     string
+	DB           int
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// DefaultRedisConfig returns the client defaults used when no env vars are set
+func DefaultRedisConfig() RedisConfig {
+	return RedisConfig{
+		Addr:         "localhost:6379",
+		PoolSize:     10,
+		MinIdleConns: 5,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+	}
+}
+
+func loadRedisConfig(env *envReader) RedisConfig {
+	def := DefaultRedisConfig()
+	return RedisConfig{
+		Addr:         env.String("REDIS_ADDR", def.Addr),
+		Password:     env.String("REDIS_PASSWORD", ""),
+		DB:           env.Int("REDIS_DB", def.DB),
+		PoolSize:     env.Int("REDIS_POOL_SIZE", def.PoolSize),
+		MinIdleConns: env.Int("REDIS_MIN_IDLE_CONNS", def.MinIdleConns),
+		DialTimeout:  env.Duration("REDIS_DIAL_TIMEOUT", def.DialTimeout),
+		ReadTimeout:  env.Duration("REDIS_READ_TIMEOUT", def.ReadTimeout),
+		WriteTimeout: env.Duration("REDIS_WRITE_TIMEOUT", def.WriteTimeout),
+	}
+}
+
+// Validate checks the Redis settings
+func (c RedisConfig) Validate() error {
+	var errs []error
+	if c.DB < 0 || c.DB > 15 {
+		errs = append(errs, fmt.Errorf("invalid REDIS_DB: %d (must be 0-15)", c.DB))
+	}
+	if c.PoolSize < 0 || c.MinIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_POOL_SIZE and REDIS_MIN_IDLE_CONNS must not be negative"))
+	}
+	if c.PoolSize > 0 && c.MinIdleConns > c.PoolSize {
+		errs = append(errs, fmt.Errorf("REDIS_MIN_IDLE_CONNS (%d) must be <= REDIS_POOL_SIZE (%d)", c.MinIdleConns, c.PoolSize))
+	}
+	return validationErrors(errs)
+}
+
+// AWSConfig configures AWS clients (S3, SNS, SQS)
+type AWSConfig struct {
+	Region          string
+	AccessKeyID     string // Optional; the default credential chain is used when empty
+	SecretAccessKey string
+	S3Bucket        string
+
+	// Server-side encryption of S3 uploads: AES256 (SSE-S3), aws:kms
+	// (SSE-KMS) or empty for the bucket's default
+	S3Encryption string
+	S3KMSKeyID   string // For aws:kms; empty for the account's aws/s3 key
+	// S3RequireEncryption rejects uploads asking for another encryption and
+	// checks at startup that the bucket's default encryption matches
+	S3RequireEncryption bool
+}
+
+func loadAWSConfig(env *envReader) AWSConfig {
+	return AWSConfig{
+		Region:              env.String("AWS_REGION", "us-east-1"),
+		AccessKeyID:         env.String("AWS_ACCESS_KEY_ID", ""),
+		SecretAccessKey:     env.String("AWS_SECRET_ACCESS_KEY", ""),
+		S3Bucket:            env.String("S3_BUCKET", ""),
+		S3Encryption:        env.String("S3_ENCRYPTION", ""),
+		S3KMSKeyID:          env.String("S3_KMS_KEY_ID", ""),
+		S3RequireEncryption: env.Bool("S3_REQUIRE_ENCRYPTION", false),
+	}
+}
+
+// Validate checks the AWS settings
+func (c AWSConfig) Validate() error {
+	var errs []error
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		errs = append(errs, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set together"))
+	}
+	if c.S3Encryption != "" && c.S3Encryption != "AES256" && c.S3Encryption != "aws:kms" {
+		errs = append(errs, fmt.Errorf("invalid S3_ENCRYPTION: %s (must be AES256, aws:kms or empty)", c.S3Encryption))
+	}
+	if c.S3KMSKeyID != "" && c.S3Encryption != "aws:kms" {
+		errs = append(errs, fmt.Errorf("S3_KMS_KEY_ID requires S3_ENCRYPTION=aws:kms"))
+	}
+	if c.S3RequireEncryption && c.S3Encryption == "" {
+		errs = append(errs, fmt.Errorf("S3_REQUIRE_ENCRYPTION requires S3_ENCRYPTION"))
+	}
+	return validationErrors(errs)
+}
+
+// HasStaticCredentials reports whether explicit access keys are configured
+func (c AWSConfig) HasStaticCredentials() bool {
+	return c.AccessKeyID != "" && c.SecretAccessKey != ""
+}
+
+// Blob store backends (BLOB_BACKEND)
+const (
+	BlobBackendS3         = "s3"
+	BlobBackendAzure      = "azure"
+	BlobBackendGCS        = "gcs"
+	BlobBackendFilesystem = "filesystem"
+	BlobBackendMemory     = "memory"
+)
+
+// BlobConfig selects the blob store reports, attachments, user data jobs,
+// diagnostics dumps and the ACME certificate cache share. DEV_INMEMORY and
+// RUN_MODE=standalone ignore it and keep blobs locally.
+type BlobConfig struct {
+	Backend       string // s3 (S3_BUCKET, also when empty), azure, gcs, filesystem or memory
+	FilesystemDir string // Root of the filesystem backend, created when missing
+
+	// Azure Blob Storage, for the azure backend
+	AzureAccount   string
+	AzureKey       string // Base64 account key
+	AzureContainer string
+	AzureEndpoint  string // Empty for https://<account>.blob.core.windows.net
+
+	// Google Cloud Storage, for the gcs backend
+	GCSBucket          string
+	GCSCredentialsFile string // Service account key file, empty for Application Default Credentials
+	GCSEndpoint        string // Empty for https://storage.googleapis.com/storage/v1/
+
+	// ManageLifecycle sets the bucket lifecycle rules applying
+	// REPORTS_RETENTION, USER_DATA_RETENTION and AbortIncompleteUploadsAfter
+	// at startup (s3 only); rules of other IDs are left alone
+	ManageLifecycle             bool
+	AbortIncompleteUploadsAfter time.Duration // 0 keeps unfinished multipart uploads
+}
+
+// DefaultBlobConfig returns the settings used when no env vars are set
+func DefaultBlobConfig() BlobConfig {
+	return BlobConfig{
+		Backend:                     BlobBackendS3,
+		FilesystemDir:               "./data/blobs",
+		AbortIncompleteUploadsAfter: 7 * 24 * time.Hour,
+	}
+}
+
+func loadBlobConfig(env *envReader) BlobConfig {
+	def := DefaultBlobConfig()
+	return BlobConfig{
+		Backend:        strings.ToLower(env.String("BLOB_BACKEND", def.Backend)),
+		FilesystemDir:  env.String("BLOB_FILESYSTEM_DIR", def.FilesystemDir),
+		AzureAccount:   env.String("AZURE_STORAGE_ACCOUNT", ""),
+		AzureKey:       env.String("AZURE_STORAGE_KEY", ""),
+		AzureContainer: env.String("AZURE_STORAGE_CONTAINER", ""),
+		AzureEndpoint:  env.String("AZURE_STORAGE_ENDPOINT", ""),
+
+		GCSBucket:          env.String("GCS_BUCKET", ""),
+		GCSCredentialsFile: env.String("GCS_CREDENTIALS_FILE", ""),
+		GCSEndpoint:        env.String("GCS_ENDPOINT", ""),
+
+		ManageLifecycle:             env.Bool("BLOB_MANAGE_LIFECYCLE", def.ManageLifecycle),
+		AbortIncompleteUploadsAfter: env.Duration("BLOB_ABORT_INCOMPLETE_UPLOADS_AFTER", def.AbortIncompleteUploadsAfter),
+	}
+}
+
+// Validate checks the blob store settings; S3_BUCKET is checked by the
+// features needing a store
+func (c BlobConfig) Validate() error {
+	var errs []error
+	switch c.Backend {
+	case "", BlobBackendS3, BlobBackendMemory:
+	case BlobBackendFilesystem:
+		if c.FilesystemDir == "" {
+			errs = append(errs, fmt.Errorf("BLOB_BACKEND=filesystem requires BLOB_FILESYSTEM_DIR"))
+		}
+	case BlobBackendAzure:
+		if c.AzureAccount == "" || c.AzureContainer == "" {
+			errs = append(errs, fmt.Errorf("BLOB_BACKEND=azure requires AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_CONTAINER"))
+		}
+		if key, err := base64.StdEncoding.DecodeString(c.AzureKey); err != nil || len(key) == 0 {
+			errs = append(errs, fmt.Errorf("BLOB_BACKEND=azure requires AZURE_STORAGE_KEY, the base64 account key"))
+		}
+		if c.AzureEndpoint != "" {
+			if u, err := url.Parse(c.AzureEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("invalid AZURE_STORAGE_ENDPOINT: %q (must be an http or https URL)", c.AzureEndpoint))
+			}
+		}
+	case BlobBackendGCS:
+		if c.GCSBucket == "" {
+			errs = append(errs, fmt.Errorf("BLOB_BACKEND=gcs requires GCS_BUCKET"))
+		}
+		if c.GCSEndpoint != "" {
+			if u, err := url.Parse(c.GCSEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("invalid GCS_ENDPOINT: %q (must be an http or https URL)", c.GCSEndpoint))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid BLOB_BACKEND: %s (must be s3, azure, gcs, filesystem or memory)", c.Backend))
+	}
+	if c.ManageLifecycle && c.Backend != "" && c.Backend != BlobBackendS3 {
+		errs = append(errs, fmt.Errorf("BLOB_MANAGE_LIFECYCLE requires BLOB_BACKEND=s3"))
+	}
+	if err := validateLifecycleDays("BLOB_ABORT_INCOMPLETE_UPLOADS_AFTER", c.AbortIncompleteUploadsAfter); err != nil {
+		errs = append(errs, err)
+	}
+	return validationErrors(errs)
+}
+
+// validateLifecycleDays checks a duration lifecycle rules apply, which count
+// in whole days
+func validateLifecycleDays(name string, d time.Duration) error {
+	if d < 0 || (d > 0 && d < 24*time.Hour) {
+		return fmt.Errorf("%s must be 0 or at least 24h (lifecycle rules count in days), got %s", name, d)
+	}
+	return nil
+}
+
+// Shared reports whether every instance sees the same blobs
+func (c BlobConfig) Shared() bool {
+	return c.Backend == "" || c.Backend == BlobBackendS3 || c.Backend == BlobBackendAzure || c.Backend == BlobBackendGCS
+}
+
+// HTTPConfig configures the HTTP server and transport middleware
+type HTTPConfig struct {
+	Port         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	MaxBodyBytes int64
+	// MaxDecompressedBodyBytes bounds gzip request bodies once decompressed
+	// (0 for MaxBodyBytes); MaxBodyBytes still bounds them as sent
+	MaxDecompressedBodyBytes int64
+
+	// Connection tuning
+	ReadHeaderTimeout         time.Duration // Bounds slow-loris style header dribbling
+	MaxHeaderBytes            int
+	KeepAlives                bool // Disable to close connections after each response
+	EnableH2C                 bool // HTTP/2 over cleartext (prior knowledge) for proxies behind TLS termination
+	HTTP2MaxConcurrentStreams int  // 0 uses the Go default (250)
+
+	// TLS termination (both files enable HTTPS on Port)
+	TLSCertFile  string
+	TLSKeyFile   string
+	RedirectPort string // Plain HTTP port redirecting to HTTPS (empty disables)
+
+	// Automatic certificates via ACME (e.g. Let's Encrypt) instead of cert files
+	ACMEHosts        []string // Hostnames certificates may be issued for (enables ACME)
+	ACMEEmail        string   // Contact for expiry and account notices
+	ACMEDirectoryURL string   // Empty uses Let's Encrypt production
+	ACMECachePrefix  string   // Blob store prefix for shared certificates and account key
+
+	// Internal listener for /health, /metrics, /debug/pprof/ and /api/admin/
+	// (which then leave the public listener); host:port, empty disables
+	AdminAddr         string
+	AdminWriteTimeout time.Duration // Longer than WriteTimeout so CPU profiles and traces can finish
+
+	// Mutual TLS: verify client certificates against this CA bundle
+	TLSClientCAFile string
+	TLSClientAuth   string // "none", "optional" or "require"
+
+	// Response size budgets
+	ResponseWarnBytes      int64
+	ResponseMaxBytes       int64
+	TruncateLargeResponses bool
+
+	// Concurrency limits protecting the database from overload: a request
+	// beyond them waits up to ConcurrencyQueueTimeout for a slot, then gets 503
+	MaxConcurrentRequests   int            // Across every /api route; 0 is unlimited
+	ConcurrencyRouteLimits  map[string]int // Per route, written as in RATE_LIMIT_ROUTES; missing or 0 is unlimited
+	ConcurrencyQueueTimeout time.Duration
+
+	// Request timeouts: a handler still running at its deadline is cancelled,
+	// database queries included, and the client gets 503. RouteTimeouts
+	// override RequestTimeout per route, written as in RATE_LIMIT_ROUTES;
+	// 0 is unlimited.
+	RequestTimeout time.Duration
+	RouteTimeouts  map[string]time.Duration
+
+	// ResponseCacheRoutes caches whole 200 responses of GET routes for a TTL
+	// per route group, written as in RATE_LIMIT_ROUTES; empty disables
+	ResponseCacheRoutes map[string]time.Duration
+
+	// TrustedProxies lists the CIDRs (or single IPs) of the load balancers and
+	// reverse proxies in front of the server. X-Forwarded-For is only believed
+	// from these; empty ignores it and uses the connection's peer address.
+	TrustedProxies []string
+
+	AllowedOrigins     []string
+	RateLimitPerMinute int            // Per client IP, across every route
+	RateLimitClasses   map[string]int // Per principal and route class (see RouteClasses); missing or 0 is unlimited
+	RateLimitRoutes    []RouteRateLimit
+	// RateLimitTiers multiplies the per-identity budgets (class limits and
+	// route limits counted per principal) of the identities in each tier;
+	// 0 exempts them. RateLimitTierMembers puts identities in tiers:
+	// "user:<id>" for a user and every key they own, "key:<id>" for one
+	// personal access token.
+	RateLimitTiers       map[string]int
+	RateLimitTierMembers map[string]string
+	EnableCORS           bool
+	// The default CORS policy: AllowedOrigins, plus credentials and how long
+	// browsers cache a preflight. CORSRoutes overrides it per route group.
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	CORSRoutes           []CORSRoute
+}
+
+// RouteClasses lists the route classes with independent rate limit budgets
+// (see transport/http.ClassifyRoute)
+var RouteClasses = []string{"read", "write", "auth", "admin"}
+
+// RouteRateLimit is a per-minute budget for a group of routes: one route, one
+// method on every route, or one path on every method. A request is held to
+// the most specific limit that matches it, on top of the class budgets.
+type RouteRateLimit struct {
+	Route     string // "POST /api/orders", "GET" or "/api/orders/{id}", as routes are registered
+	PerMinute int
+	Key       string // What the budget is counted per (see RateLimitKeys); empty is "principal"
+}
+
+// RateLimitKeys lists what a route limit can be counted per: the
+// authenticated user or else the client IP, the client IP only, or one
+// budget shared by every client. "header:<name>" counts per value of a
+// request header (e.g. a tenant ID), falling back to the principal.
+var RateLimitKeys = []string{"principal", "ip", "global"}
+
+// CORSRoute is the CORS policy of a group of routes, overriding the default
+// policy's origins, credentials and preflight max age
+type CORSRoute struct {
+	Route            string // As in RouteRateLimit
+	Origins          []string
+	AllowCredentials bool
+	MaxAge           time.Duration // 0 keeps CORS_MAX_AGE
+}
+
+// loadCORSRoutes reads CORS_ROUTES, a comma-separated list of
+// route=origins entries: space-separated origins, optionally followed by
+// "credentials" and "max-age=<duration>", e.g.
+// "/api/features=*,/api/orders=https://*.example.com credentials max-age=10m".
+func loadCORSRoutes(env *envReader) []CORSRoute {
+	var routes []CORSRoute
+	for _, entry := range env.Slice("CORS_ROUTES", nil) {
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(route) == "" {
+			env.fail(fmt.Errorf("invalid CORS_ROUTES entry %q (want route=origins)", entry))
+			continue
+		}
+		policy := CORSRoute{Route: strings.Join(strings.Fields(route), " ")}
+		for _, field := range strings.Fields(spec) {
+			switch {
+			case field == "credentials":
+				policy.AllowCredentials = true
+			case strings.HasPrefix(field, "max-age="):
+				d, err := time.ParseDuration(strings.TrimPrefix(field, "max-age="))
+				if err != nil {
+					env.fail(fmt.Errorf("invalid CORS_ROUTES max-age in %q: %w", entry, err))
+				}
+				policy.MaxAge = d
+			default:
+				policy.Origins = append(policy.Origins, field)
+			}
+		}
+		routes = append(routes, policy)
+	}
+	return routes
+}
+
+// validateCORSOrigins checks allowed origins: "*", exact origins, or a
+// scheme and wildcard host ("https://*.example.com"). Browsers refuse
+// credentials with "*", so it can't be combined with them.
+func validateCORSOrigins(name string, origins []string, credentials bool) []error {
+	var errs []error
+	for _, origin := range origins {
+		switch {
+		case origin == "*":
+			if credentials {
+				errs = append(errs, fmt.Errorf("%s: \"*\" can't be used with credentials; list the origins", name))
+			}
+		case strings.Contains(origin, "*"):
+			scheme, host, ok := strings.Cut(origin, "://*.")
+			if !ok || scheme == "" || host == "" || strings.ContainsAny(scheme+host, "*/") {
+				errs = append(errs, fmt.Errorf("%s: invalid origin %q (a wildcard must be a whole leading label, as in https://*.example.com)", name, origin))
+			}
+		}
+	}
+	return errs
+}
+
+// maxResponseCacheTTL bounds RESPONSE_CACHE_ROUTES: changes made outside a
+// request are only seen once a cached response expires
+const maxResponseCacheTTL = time.Hour
+
+// loadRouteRateLimits reads RATE_LIMIT_ROUTES, a comma-separated list of
+// route=limit[:key] entries, e.g. "POST /api/orders=10,GET=300:ip".
+// Malformed entries are recorded and skipped.
+func loadRouteRateLimits(env *envReader) []RouteRateLimit {
+	var limits []RouteRateLimit
+	for _, entry := range env.Slice("RATE_LIMIT_ROUTES", nil) {
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		value, key, _ := strings.Cut(spec, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || strings.TrimSpace(route) == "" || err != nil {
+			env.fail(fmt.Errorf("invalid RATE_LIMIT_ROUTES entry %q (want route=limit or route=limit:key)", entry))
+			continue
+		}
+		limits = append(limits, RouteRateLimit{
+			Route:     strings.Join(strings.Fields(route), " "),
+			PerMinute: n,
+			Key:       strings.TrimSpace(key),
+		})
+	}
+	return limits
+}
+
+// validRouteGroup reports whether route names a group of routes: "METHOD
+// /path", "METHOD" or "/path"
+func validRouteGroup(route string) bool {
+	fields := strings.Fields(route)
+	isMethod := func(s string) bool { return s != "" && strings.ToUpper(s) == s && !strings.HasPrefix(s, "/") }
+	switch {
+	case len(fields) == 1:
+		return isMethod(fields[0]) || strings.HasPrefix(fields[0], "/")
+	case len(fields) == 2:
+		return isMethod(fields[0]) && strings.HasPrefix(fields[1], "/")
+	default:
+		return false
+	}
+}
+
+// validate checks the route pattern and key of a route limit
+func (l RouteRateLimit) validate() error {
+	if !validRouteGroup(l.Route) {
+		return fmt.Errorf("RATE_LIMIT_ROUTES: invalid route %q (want \"METHOD /path\", \"METHOD\" or \"/path\")", l.Route)
+	}
+	if l.PerMinute < 0 {
+		return fmt.Errorf("RATE_LIMIT_ROUTES: %s limit must not be negative", l.Route)
+	}
+	if header, ok := strings.CutPrefix(l.Key, "header:"); ok {
+		if header == "" {
+			return fmt.Errorf("RATE_LIMIT_ROUTES: %s key header: needs a header name", l.Route)
+		}
+	} else if l.Key != "" && !contains(RateLimitKeys, l.Key) {
+		return fmt.Errorf("RATE_LIMIT_ROUTES: %s has unknown key %q (want one of %v or header:<name>)", l.Route, l.Key, RateLimitKeys)
+	}
+	return nil
+}
+
+func loadHTTPConfig(env *envReader) HTTPConfig {
+	return HTTPConfig{
+		Port:         env.String("PORT", "8080"),
+		ReadTimeout:  env.Duration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout: env.Duration("HTTP_WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:  env.Duration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		MaxBodyBytes: int64(env.Int("HTTP_MAX_BODY_BYTES", 1<<20)),
+
+		MaxDecompressedBodyBytes: int64(env.Int("HTTP_MAX_DECOMPRESSED_BODY_BYTES", 0)),
+
+		ReadHeaderTimeout:         env.Duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		MaxHeaderBytes:            env.Int("HTTP_MAX_HEADER_BYTES", 1<<20),
+		KeepAlives:                env.Bool("HTTP_KEEP_ALIVES", true),
+		EnableH2C:                 env.Bool("HTTP_H2C", false),
+		HTTP2MaxConcurrentStreams: env.Int("HTTP2_MAX_CONCURRENT_STREAMS", 0),
+
+		TLSCertFile:  env.String("TLS_CERT_FILE", ""),
+		TLSKeyFile:   env.String("TLS_KEY_FILE", ""),
+		RedirectPort: env.String("HTTP_REDIRECT_PORT", ""),
+
+		ACMEHosts:        env.Slice("ACME_HOSTS", nil),
+		ACMEEmail:        env.String("ACME_EMAIL", ""),
+		ACMEDirectoryURL: env.String("ACME_DIRECTORY_URL", ""),
+		ACMECachePrefix:  env.String("ACME_CACHE_PREFIX", "acme/"),
+
+		AdminAddr:         env.String("ADMIN_ADDR", ""),
+		AdminWriteTimeout: env.Duration("ADMIN_WRITE_TIMEOUT", 60*time.Second),
+
+		TLSClientCAFile: env.String("TLS_CLIENT_CA_FILE", ""),
+		TLSClientAuth:   env.String("TLS_CLIENT_AUTH", "none"),
+
+		ResponseWarnBytes:      int64(env.Int("RESPONSE_WARN_BYTES", 1<<20)),
+		ResponseMaxBytes:       int64(env.Int("RESPONSE_MAX_BYTES", 5<<20)),
+		TruncateLargeResponses: env.Bool("RESPONSE_TRUNCATE_LISTS", false),
+
+		MaxConcurrentRequests:   env.Int("MAX_CONCURRENT_REQUESTS", 0),
+		ConcurrencyRouteLimits:  env.IntMap("CONCURRENCY_ROUTE_LIMITS"),
+		ConcurrencyQueueTimeout: env.Duration("CONCURRENCY_QUEUE_TIMEOUT", 500*time.Millisecond),
+
+		RequestTimeout: env.Duration("REQUEST_TIMEOUT", 10*time.Second),
+		RouteTimeouts:  env.DurationMap("ROUTE_TIMEOUTS"),
+
+		ResponseCacheRoutes: env.DurationMap("RESPONSE_CACHE_ROUTES"),
+
+		TrustedProxies: env.Slice("TRUSTED_PROXIES", nil),
+
+		AllowedOrigins:       env.Slice("ALLOWED_ORIGINS", []string{"*"}),
+		RateLimitPerMinute:   env.Int("RATE_LIMIT_PER_MINUTE", 100),
+		RateLimitClasses:     env.IntMap("RATE_LIMIT_CLASSES"),
+		RateLimitRoutes:      loadRouteRateLimits(env),
+		RateLimitTiers:       env.IntMap("RATE_LIMIT_TIERS"),
+		RateLimitTierMembers: env.StringMap("RATE_LIMIT_TIER_MEMBERS"),
+		EnableCORS:           env.Bool("ENABLE_CORS", true),
+		CORSAllowCredentials: env.Bool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           env.Duration("CORS_MAX_AGE", 24*time.Hour),
+		CORSRoutes:           loadCORSRoutes(env),
+	}
+}
+
+// Validate checks the HTTP settings
+func (c HTTPConfig) Validate() error {
+	var errs []error
+	if c.MaxDecompressedBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("HTTP_MAX_DECOMPRESSED_BODY_BYTES must not be negative"))
+	}
+	if c.Port == "" {
+		errs = append(errs, fmt.Errorf("PORT cannot be empty"))
+	} else if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid PORT: %s (must be 1-65535)", c.Port))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	if c.ACMEEnabled() && c.TLSCertFile != "" {
+		errs = append(errs, fmt.Errorf("ACME_HOSTS cannot be combined with TLS_CERT_FILE/TLS_KEY_FILE"))
+	}
+	if c.RedirectPort != "" {
+		if !c.TLSEnabled() {
+			errs = append(errs, fmt.Errorf("HTTP_REDIRECT_PORT requires TLS (TLS_CERT_FILE/TLS_KEY_FILE or ACME_HOSTS)"))
+		} else if port, err := strconv.Atoi(c.RedirectPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("invalid HTTP_REDIRECT_PORT: %s (must be 1-65535)", c.RedirectPort))
+		} else if c.RedirectPort == c.Port {
+			errs = append(errs, fmt.Errorf("HTTP_REDIRECT_PORT must differ from PORT"))
+		}
+	}
+	if c.AdminAddr != "" {
+		if _, port, err := net.SplitHostPort(c.AdminAddr); err != nil || port == "" {
+			errs = append(errs, fmt.Errorf("invalid ADMIN_ADDR: %q (want host:port, e.g. 127.0.0.1:9090)", c.AdminAddr))
+		} else if port == c.Port || port == c.RedirectPort {
+			errs = append(errs, fmt.Errorf("ADMIN_ADDR port must differ from PORT and HTTP_REDIRECT_PORT"))
+		}
+		if c.AdminWriteTimeout < 0 {
+			errs = append(errs, fmt.Errorf("ADMIN_WRITE_TIMEOUT must not be negative"))
+		}
+	}
+	switch c.TLSClientAuth {
+	case "", "none":
+	case "optional", "require":
+		if !c.TLSEnabled() {
+			errs = append(errs, fmt.Errorf("TLS_CLIENT_AUTH=%s requires TLS (TLS_CERT_FILE/TLS_KEY_FILE or ACME_HOSTS)", c.TLSClientAuth))
+		}
+		if c.TLSClientCAFile == "" {
+			errs = append(errs, fmt.Errorf("TLS_CLIENT_AUTH=%s requires TLS_CLIENT_CA_FILE", c.TLSClientAuth))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid TLS_CLIENT_AUTH: %s (must be none, optional, or require)", c.TLSClientAuth))
+	}
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ReadHeaderTimeout < 0 {
+		errs = append(errs, fmt.Errorf("HTTP timeouts must not be negative"))
+	}
+	if c.ReadTimeout > 0 && c.ReadHeaderTimeout > c.ReadTimeout {
+		errs = append(errs, fmt.Errorf("HTTP_READ_HEADER_TIMEOUT (%s) must be <= HTTP_READ_TIMEOUT (%s)", c.ReadHeaderTimeout, c.ReadTimeout))
+	}
+	if c.MaxHeaderBytes < 0 {
+		errs = append(errs, fmt.Errorf("HTTP_MAX_HEADER_BYTES must not be negative"))
+	}
+	if c.HTTP2MaxConcurrentStreams < 0 {
+		errs = append(errs, fmt.Errorf("HTTP2_MAX_CONCURRENT_STREAMS must not be negative"))
+	}
+	if c.EnableH2C && c.TLSEnabled() {
+		errs = append(errs, fmt.Errorf("HTTP_H2C cannot be combined with TLS (HTTP/2 is negotiated over TLS automatically)"))
+	}
+	if c.ResponseMaxBytes > 0 && c.ResponseWarnBytes > c.ResponseMaxBytes {
+		errs = append(errs, fmt.Errorf("RESPONSE_WARN_BYTES (%d) must be <= RESPONSE_MAX_BYTES (%d)", c.ResponseWarnBytes, c.ResponseMaxBytes))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("invalid TRUSTED_PROXIES entry %q (want a CIDR or IP, e.g. 10.0.0.0/8)", proxy))
+		}
+	}
+	if c.RateLimitPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_PER_MINUTE must not be negative"))
+	}
+	for class, limit := range c.RateLimitClasses {
+		if !contains(RouteClasses, class) {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_CLASSES: unknown route class %q (want one of %v)", class, RouteClasses))
+		} else if limit < 0 {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_CLASSES: %s limit must not be negative", class))
+		}
+	}
+	routes := make(map[string]bool, len(c.RateLimitRoutes))
+	for _, limit := range c.RateLimitRoutes {
+		if err := limit.validate(); err != nil {
+			errs = append(errs, err)
+		} else if routes[limit.Route] {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_ROUTES: %s is listed twice", limit.Route))
+		}
+		routes[limit.Route] = true
+	}
+	if c.MaxConcurrentRequests < 0 {
+		errs = append(errs, fmt.Errorf("MAX_CONCURRENT_REQUESTS must not be negative"))
+	}
+	for route, limit := range c.ConcurrencyRouteLimits {
+		if !validRouteGroup(route) {
+			errs = append(errs, fmt.Errorf("CONCURRENCY_ROUTE_LIMITS: invalid route %q (want \"METHOD /path\", \"METHOD\" or \"/path\")", route))
+		} else if limit < 0 {
+			errs = append(errs, fmt.Errorf("CONCURRENCY_ROUTE_LIMITS: %s limit must not be negative", route))
+		}
+	}
+	errs = append(errs, validateCORSOrigins("ALLOWED_ORIGINS", c.AllowedOrigins, c.CORSAllowCredentials)...)
+	if c.CORSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("CORS_MAX_AGE must not be negative"))
+	}
+	corsRoutes := make(map[string]bool, len(c.CORSRoutes))
+	for _, route := range c.CORSRoutes {
+		switch {
+		case !validRouteGroup(route.Route):
+			errs = append(errs, fmt.Errorf("CORS_ROUTES: invalid route %q (want \"METHOD /path\", \"METHOD\" or \"/path\")", route.Route))
+		case corsRoutes[route.Route]:
+			errs = append(errs, fmt.Errorf("CORS_ROUTES: %s is listed twice", route.Route))
+		case len(route.Origins) == 0:
+			errs = append(errs, fmt.Errorf("CORS_ROUTES: %s allows no origins", route.Route))
+		case route.MaxAge < 0:
+			errs = append(errs, fmt.Errorf("CORS_ROUTES: %s max-age must not be negative", route.Route))
+		}
+		corsRoutes[route.Route] = true
+		errs = append(errs, validateCORSOrigins("CORS_ROUTES: "+route.Route, route.Origins, route.AllowCredentials)...)
+	}
+	if c.ConcurrencyQueueTimeout < 0 {
+		errs = append(errs, fmt.Errorf("CONCURRENCY_QUEUE_TIMEOUT must not be negative"))
+	}
+	if c.RequestTimeout < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_TIMEOUT must not be negative"))
+	}
+	for route, timeout := range c.RouteTimeouts {
+		if !validRouteGroup(route) {
+			errs = append(errs, fmt.Errorf("ROUTE_TIMEOUTS: invalid route %q (want \"METHOD /path\", \"METHOD\" or \"/path\")", route))
+		} else if timeout < 0 {
+			errs = append(errs, fmt.Errorf("ROUTE_TIMEOUTS: %s timeout must not be negative", route))
+		}
+	}
+	for route, ttl := range c.ResponseCacheRoutes {
+		method, _, _ := strings.Cut(route, " ")
+		switch {
+		case !validRouteGroup(route):
+			errs = append(errs, fmt.Errorf("RESPONSE_CACHE_ROUTES: invalid route %q (want \"GET /path\", \"GET\" or \"/path\")", route))
+		case !strings.HasPrefix(method, "/") && method != "GET":
+			errs = append(errs, fmt.Errorf("RESPONSE_CACHE_ROUTES: %s: only GET responses are cached", route))
+		case ttl <= 0 || ttl > maxResponseCacheTTL:
+			errs = append(errs, fmt.Errorf("RESPONSE_CACHE_ROUTES: %s ttl must be between 0 and %v", route, maxResponseCacheTTL))
+		}
+	}
+	for tier, multiplier := range c.RateLimitTiers {
+		if multiplier < 0 {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_TIERS: %s multiplier must not be negative", tier))
+		}
+	}
+	for identity, tier := range c.RateLimitTierMembers {
+		if !strings.HasPrefix(identity, "user:") && !strings.HasPrefix(identity, "key:") {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_TIER_MEMBERS: invalid identity %q (want user:<id> or key:<token id>)", identity))
+		}
+		if _, ok := c.RateLimitTiers[tier]; !ok {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_TIER_MEMBERS: %s is in undefined tier %q (see RATE_LIMIT_TIERS)", identity, tier))
+		}
+	}
+	return validationErrors(errs)
+}
+
+// TLSEnabled reports whether the server terminates TLS itself
+func (c HTTPConfig) TLSEnabled() bool {
+	return (c.TLSCertFile != "" && c.TLSKeyFile != "") || c.ACMEEnabled()
+}
+
+// ACMEEnabled reports whether certificates are provisioned automatically
+func (c HTTPConfig) ACMEEnabled() bool {
+	return len(c.ACMEHosts) > 0
+}
+
+// APIConfig configures REST API versioning: the version served to requests
+// naming none, and the retirement schedule of old versions
+type APIConfig struct {
+	DefaultVersion int                  // 0 serves version 1
+	Deprecations   map[string]time.Time // Per version, when it is deprecated (Deprecation header)
+	Sunsets        map[string]time.Time // Per version, when it stops being served (Sunset header, then 410)
+
+	// How long the response to a POST with an Idempotency-Key is replayed to
+	// retries with the same key (0 disables idempotency keys)
+	IdempotencyKeyTTL time.Duration
+}
+
+// APIVersions lists the API versions served (see transport/http.APIVersion)
+var APIVersions = []string{"1", "2"}
+
+func loadAPIConfig(env *envReader) APIConfig {
+	return APIConfig{
+		DefaultVersion: env.Int("API_DEFAULT_VERSION", 1),
+		Deprecations:   env.DateMap("API_VERSION_DEPRECATIONS"),
+		Sunsets:        env.DateMap("API_VERSION_SUNSETS"),
+
+		IdempotencyKeyTTL: env.Duration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+	}
+}
+
+// Validate checks the API versioning settings
+func (c APIConfig) Validate() error {
+	var errs []error
+	latest := APIVersions[len(APIVersions)-1]
+	if c.DefaultVersion != 0 && !contains(APIVersions, strconv.Itoa(c.DefaultVersion)) {
+		errs = append(errs, fmt.Errorf("invalid API_DEFAULT_VERSION: %d (want one of %v)", c.DefaultVersion, APIVersions))
+	}
+	for version := range c.Deprecations {
+		if !contains(APIVersions, version) {
+			errs = append(errs, fmt.Errorf("API_VERSION_DEPRECATIONS: unknown version %q (want one of %v)", version, APIVersions))
+		} else if version == latest {
+			errs = append(errs, fmt.Errorf("API_VERSION_DEPRECATIONS: the latest version (%s) has no successor to move to", version))
+		}
+	}
+	for version, sunset := range c.Sunsets {
+		switch {
+		case !contains(APIVersions, version):
+			errs = append(errs, fmt.Errorf("API_VERSION_SUNSETS: unknown version %q (want one of %v)", version, APIVersions))
+		case version == latest:
+			errs = append(errs, fmt.Errorf("API_VERSION_SUNSETS: the latest version (%s) has no successor to move to", version))
+		case version == strconv.Itoa(max(c.DefaultVersion, 1)):
+			errs = append(errs, fmt.Errorf("API_VERSION_SUNSETS: version %s is API_DEFAULT_VERSION; move the default first", version))
+		}
+		if deprecated, ok := c.Deprecations[version]; ok && !sunset.After(deprecated) {
+			errs = append(errs, fmt.Errorf("API_VERSION_SUNSETS: version %s must be sunset after it is deprecated", version))
+		}
+	}
+	if c.IdempotencyKeyTTL < 0 {
+		errs = append(errs, fmt.Errorf("invalid IDEMPOTENCY_KEY_TTL: %v (must not be negative)", c.IdempotencyKeyTTL))
+	}
+	return validationErrors(errs)
+}
+
+// AuthConfig configures token authentication
+type AuthConfig struct {
+	JWTSecret            string // Legacy single signing key (kid "default")
+	JWTKeysFile          string // JSON file of rotating signing/encryption keys
+	JWTEncryptPII        bool   // Encrypt tokens that carry PII claims (JWE)
+	JWTExpirationHours   int
+	EnableAuthentication bool
+
+	// Brute-force protection for failed authentication attempts
+	FailureWindow time.Duration // How long failures are remembered
+	CaptchaAfter  int           // Failures before responses request a CAPTCHA (0 disables)
+	LockoutAfter  int           // Failures before the first lockout (0 disables)
+	LockoutBase   time.Duration // First lockout; doubles with each further failure
+	LockoutMax    time.Duration // Longest lockout (0 means no cap)
+
+	// Cookie-based sessions (browser clients); empty SessionCookie disables them
+	SessionCookie string // Cookie carrying the access token
+	CSRFCookie    string // Double-submit CSRF cookie
+	CSRFHeader    string // Header that must echo the CSRF cookie on unsafe requests
+
+	// Personal access tokens
+	PATDefaultLifetime time.Duration // Lifetime when the user doesn't choose one
+	PATMaxLifetime     time.Duration // Longest lifetime a user may choose (0 means unbounded)
+
+	// How long revoking every token of a user is remembered. It must cover the
+	// longest-lived token still valid, including ones issued before
+	// JWT_EXPIRATION_HOURS was lowered, so it is not derived from the current lifetimes.
+	RevocationRetention time.Duration
+}
+
+func loadAuthConfig(env *envReader) AuthConfig {
+	return AuthConfig{
+		JWTSecret:            env.String("JWT_SECRET", ""),
+		JWTKeysFile:          env.String("JWT_KEYS_FILE", ""),
+		JWTEncryptPII:        env.Bool("JWT_ENCRYPT_PII", false),
+		JWTExpirationHours:   env.Int("JWT_EXPIRATION_HOURS", 24),
+		EnableAuthentication: env.Bool("ENABLE_AUTHENTICATION", true),
+
+		FailureWindow: env.Duration("AUTH_FAILURE_WINDOW", 15*time.Minute),
+		CaptchaAfter:  env.Int("AUTH_CAPTCHA_AFTER", 3),
+		LockoutAfter:  env.Int("AUTH_LOCKOUT_AFTER", 5),
+		LockoutBase:   env.Duration("AUTH_LOCKOUT_BASE", time.Minute),
+		LockoutMax:    env.Duration("AUTH_LOCKOUT_MAX", time.Hour),
+
+		SessionCookie: env.String("AUTH_SESSION_COOKIE", ""),
+		CSRFCookie:    env.String("AUTH_CSRF_COOKIE", "csrf_token"),
+		CSRFHeader:    env.String("AUTH_CSRF_HEADER", "X-CSRF-Token"),
+
+		PATDefaultLifetime: env.Duration("AUTH_PAT_DEFAULT_LIFETIME", 90*24*time.Hour),
+		PATMaxLifetime:     env.Duration("AUTH_PAT_MAX_LIFETIME", 365*24*time.Hour),
+
+		RevocationRetention: env.Duration("AUTH_REVOCATION_RETENTION", 365*24*time.Hour),
+	}
+}
+
+// Validate checks the authentication settings
+func (c AuthConfig) Validate() error {
+	var errs []error
+	if c.JWTSecret == "" && c.JWTKeysFile == "" {
+		errs = append(errs, fmt.Errorf("JWT_SECRET or JWT_KEYS_FILE is required"))
+	} else if c.JWTSecret != "" && len(c.JWTSecret) < 32 {
+		errs = append(errs, fmt.Errorf("JWT_SECRET must be at least 32 characters long for security"))
+	}
+	if c.JWTExpirationHours <= 0 {
+		errs = append(errs, fmt.Errorf("JWT_EXPIRATION_HOURS must be positive"))
+	}
+	if c.CaptchaAfter < 0 || c.LockoutAfter < 0 {
+		errs = append(errs, fmt.Errorf("AUTH_CAPTCHA_AFTER and AUTH_LOCKOUT_AFTER must not be negative"))
+	}
+	if c.LockoutAfter > 0 && (c.FailureWindow <= 0 || c.LockoutBase <= 0) {
+		errs = append(errs, fmt.Errorf("AUTH_FAILURE_WINDOW and AUTH_LOCKOUT_BASE must be positive when AUTH_LOCKOUT_AFTER is set"))
+	}
+	if c.LockoutMax > 0 && c.LockoutMax < c.LockoutBase {
+		errs = append(errs, fmt.Errorf("AUTH_LOCKOUT_MAX (%s) must be >= AUTH_LOCKOUT_BASE (%s)", c.LockoutMax, c.LockoutBase))
+	}
+	if c.SessionCookie != "" && c.SessionCookie == c.CSRFCookie {
+		errs = append(errs, fmt.Errorf("AUTH_SESSION_COOKIE and AUTH_CSRF_COOKIE must differ"))
+	}
+	if c.PATDefaultLifetime < 0 || c.PATMaxLifetime < 0 {
+		errs = append(errs, fmt.Errorf("AUTH_PAT_DEFAULT_LIFETIME and AUTH_PAT_MAX_LIFETIME must not be negative"))
+	}
+	if c.PATMaxLifetime > 0 && c.PATDefaultLifetime > c.PATMaxLifetime {
+		errs = append(errs, fmt.Errorf("AUTH_PAT_DEFAULT_LIFETIME (%s) must be <= AUTH_PAT_MAX_LIFETIME (%s)", c.PATDefaultLifetime, c.PATMaxLifetime))
+	}
+	if longest := max(c.TokenTTL(), c.PATMaxLifetime); c.RevocationRetention < longest {
+		errs = append(errs, fmt.Errorf("AUTH_REVOCATION_RETENTION (%s) must be >= JWT_EXPIRATION_HOURS and AUTH_PAT_MAX_LIFETIME (%s)", c.RevocationRetention, longest))
+	}
+	return validationErrors(errs)
+}
+
+// TokenTTL returns the lifetime of issued access tokens
+func (c AuthConfig) TokenTTL() time.Duration {
+	return time.Duration(c.JWTExpirationHours) * time.Hour
+}
+
+// SemaphoreConfig bounds cluster-wide concurrency of expensive operations
+// (report generation, exports, bulk imports) so replicas can't collectively
+// overwhelm Postgres
+type SemaphoreConfig struct {
+	Limits       map[string]int // Per-operation holders across all replicas, e.g. "reports=2,imports=1"
+	DefaultLimit int            // For operations not listed in Limits
+	LeaseTTL     time.Duration  // A crashed holder's slot is reclaimed after this long
+	WaitTimeout  time.Duration  // How long callers queue for a slot before giving up (0 fails immediately)
+}
+
+// DefaultSemaphoreConfig returns the limits used when no env vars are set
+func DefaultSemaphoreConfig() SemaphoreConfig {
+	return SemaphoreConfig{
+		DefaultLimit: 2,
+		LeaseTTL:     30 * time.Second,
+		WaitTimeout:  5 * time.Second,
+	}
+}
+
+func loadSemaphoreConfig(env *envReader) SemaphoreConfig {
+	def := DefaultSemaphoreConfig()
+	return SemaphoreConfig{
+		Limits:       env.IntMap("SEMAPHORE_LIMITS"),
+		DefaultLimit: env.Int("SEMAPHORE_DEFAULT_LIMIT", def.DefaultLimit),
+		LeaseTTL:     env.Duration("SEMAPHORE_LEASE_TTL", def.LeaseTTL),
+		WaitTimeout:  env.Duration("SEMAPHORE_WAIT_TIMEOUT", def.WaitTimeout),
+	}
+}
+
+// Validate checks the semaphore settings
+func (c SemaphoreConfig) Validate() error {
+	var errs []error
+	if c.DefaultLimit < 0 {
+		errs = append(errs, fmt.Errorf("SEMAPHORE_DEFAULT_LIMIT must not be negative"))
+	}
+	for name, limit := range c.Limits {
+		if limit <= 0 {
+			errs = append(errs, fmt.Errorf("SEMAPHORE_LIMITS: limit for %s must be positive", name))
+		}
+	}
+	if c.LeaseTTL < 0 || c.WaitTimeout < 0 {
+		errs = append(errs, fmt.Errorf("SEMAPHORE_LEASE_TTL and SEMAPHORE_WAIT_TIMEOUT must not be negative"))
+	}
+	return validationErrors(errs)
+}
+
+// Limit returns the concurrency limit for operation name
+func (c SemaphoreConfig) Limit(name string) int {
+	if limit, ok := c.Limits[name]; ok {
+		return limit
+	}
+	return c.DefaultLimit
+}
+
+// ResilienceDependencies lists the dependencies calls to are guarded by a
+// circuit breaker and bulkhead (see internal/resilience)
+var ResilienceDependencies = []string{"cache", "blob"}
+
+// ResilienceConfig configures the guards around calls to dependencies. Each
+// dependency gets its own circuit breaker, which opens after FailureThreshold
+// consecutive failures and fails calls fast for OpenTimeout, and its own
+// bulkhead, which caps the calls in flight so a hanging dependency ties up a
+// bounded number of requests.
+type ResilienceConfig struct {
+	Enabled          bool
+	FailureThreshold int                      // Consecutive failures that open a breaker
+	OpenTimeout      time.Duration            // How long an open breaker fails fast before probing
+	HalfOpenProbes   int                      // Probe calls that must succeed to close it again
+	Timeouts         map[string]time.Duration // Per-call timeout per dependency, e.g. "cache=250ms,blob=10s" (0 for none)
+	MaxConcurrent    map[string]int           // Calls in flight per dependency (0 is unlimited)
+	BulkheadWait     time.Duration            // How long a call waits for a free slot (0 fails immediately)
+}
+
+// DefaultResilienceConfig returns the settings used when no env vars are set
+func DefaultResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Enabled:          true,
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenProbes:   1,
+		Timeouts:         map[string]time.Duration{"cache": 250 * time.Millisecond, "blob": 10 * time.Second},
+		MaxConcurrent:    map[string]int{"cache": 100, "blob": 32},
+		BulkheadWait:     50 * time.Millisecond,
+	}
+}
+
+func loadResilienceConfig(env *envReader) ResilienceConfig {
+	def := DefaultResilienceConfig()
+	cfg := ResilienceConfig{
+		Enabled:          env.Bool("RESILIENCE_ENABLED", def.Enabled),
+		FailureThreshold: env.Int("BREAKER_FAILURE_THRESHOLD", def.FailureThreshold),
+		OpenTimeout:      env.Duration("BREAKER_OPEN_TIMEOUT", def.OpenTimeout),
+		HalfOpenProbes:   env.Int("BREAKER_HALF_OPEN_PROBES", def.HalfOpenProbes),
+		Timeouts:         def.Timeouts,
+		MaxConcurrent:    def.MaxConcurrent,
+		BulkheadWait:     env.Duration("BULKHEAD_WAIT", def.BulkheadWait),
+	}
+	// Listed dependencies override the defaults; unlisted ones keep them
+	for name, timeout := range env.DurationMap("DEPENDENCY_TIMEOUTS") {
+		cfg.Timeouts[name] = timeout
+	}
+	for name, n := range env.IntMap("DEPENDENCY_MAX_CONCURRENT") {
+		cfg.MaxConcurrent[name] = n
+	}
+	return cfg
+}
+
+// Validate checks the resilience settings
+func (c ResilienceConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.FailureThreshold <= 0 || c.HalfOpenProbes <= 0 {
+		errs = append(errs, fmt.Errorf("BREAKER_FAILURE_THRESHOLD and BREAKER_HALF_OPEN_PROBES must be positive"))
+	}
+	if c.OpenTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("BREAKER_OPEN_TIMEOUT must be positive"))
+	}
+	known := make(map[string]bool, len(ResilienceDependencies))
+	for _, d := range ResilienceDependencies {
+		known[d] = true
+	}
+	for name, timeout := range c.Timeouts {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("DEPENDENCY_TIMEOUTS: unknown dependency %q (want one of %v)", name, ResilienceDependencies))
+		} else if timeout < 0 {
+			errs = append(errs, fmt.Errorf("DEPENDENCY_TIMEOUTS: timeout for %s must not be negative", name))
+		}
+	}
+	for name, n := range c.MaxConcurrent {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("DEPENDENCY_MAX_CONCURRENT: unknown dependency %q (want one of %v)", name, ResilienceDependencies))
+		} else if n < 0 {
+			errs = append(errs, fmt.Errorf("DEPENDENCY_MAX_CONCURRENT: limit for %s must not be negative", name))
+		}
+	}
+	if c.BulkheadWait < 0 {
+		errs = append(errs, fmt.Errorf("BULKHEAD_WAIT must not be negative"))
+	}
+	return validationErrors(errs)
+}
+
+// RetryDependencies lists the dependencies whose transient failures are
+// retried: Postgres statements, Redis cache commands and blob store requests
+var RetryDependencies = []string{"postgres", "cache", "blob"}
+
+// RetryConfig configures retries of calls failing with transient errors
+// (serialization failures, timeouts, dropped connections, throttling), with
+// a jittered exponential backoff from BaseDelay up to MaxDelay
+type RetryConfig struct {
+	Attempts  map[string]int // Tries per dependency, the first included, e.g. "postgres=3,cache=2" (1 disables retries)
+	BaseDelay time.Duration  // Backoff before the first retry, doubling with every attempt
+	MaxDelay  time.Duration  // Longest backoff between attempts
+}
+
+// DefaultRetryConfig returns the settings used when no env vars are set
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		Attempts:  map[string]int{"postgres": 3, "cache": 2, "blob": 3},
+		BaseDelay: 50 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+}
+
+func loadRetryConfig(env *envReader) RetryConfig {
+	def := DefaultRetryConfig()
+	cfg := RetryConfig{
+		Attempts:  def.Attempts,
+		BaseDelay: env.Duration("RETRY_BASE_DELAY", def.BaseDelay),
+		MaxDelay:  env.Duration("RETRY_MAX_DELAY", def.MaxDelay),
+	}
+	// Listed dependencies override the defaults; unlisted ones keep them
+	for name, n := range env.IntMap("RETRY_ATTEMPTS") {
+		cfg.Attempts[name] = n
+	}
+	return cfg
+}
+
+// Validate checks the retry settings
+func (c RetryConfig) Validate() error {
+	var errs []error
+	known := make(map[string]bool, len(RetryDependencies))
+	for _, d := range RetryDependencies {
+		known[d] = true
+	}
+	for name, n := range c.Attempts {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("RETRY_ATTEMPTS: unknown dependency %q (want one of %v)", name, RetryDependencies))
+		} else if n <= 0 {
+			errs = append(errs, fmt.Errorf("RETRY_ATTEMPTS: attempts for %s must be positive", name))
+		}
+	}
+	if c.BaseDelay < 0 || c.MaxDelay < 0 {
+		errs = append(errs, fmt.Errorf("RETRY_BASE_DELAY and RETRY_MAX_DELAY must not be negative"))
+	} else if c.MaxDelay < c.BaseDelay {
+		errs = append(errs, fmt.Errorf("RETRY_MAX_DELAY must not be shorter than RETRY_BASE_DELAY"))
+	}
+	return validationErrors(errs)
+}
+
+// JobPriorities lists the background job priority levels, highest first
+var JobPriorities = []string{"critical", "default", "bulk"}
+
+// JobsConfig configures the background job workers. Each priority has its
+// own concurrency limit, so bulk work can only ever occupy its own workers
+// and never starve time-sensitive jobs; weights set how often each priority
+// is polled when several have free workers.
+type JobsConfig struct {
+	Weights       map[string]int // Relative dequeue weight per priority, e.g. "critical=6,default=3,bulk=1"
+	Concurrency   map[string]int // Jobs of each priority running at once on this replica
+	PollInterval  time.Duration  // How often empty queues are polled
+	MaxAttempts   int            // A failing job is retried until it has run this many times
+	RetryDelay    time.Duration  // Backoff before a failed job's first retry, doubling with every further failure
+	MaxRetryDelay time.Duration  // Longest backoff between a job's attempts
+}
+
+// DefaultJobsConfig returns the settings used when no env vars are set
+func DefaultJobsConfig() JobsConfig {
+	return JobsConfig{
+		Weights:       map[string]int{"critical": 6, "default": 3, "bulk": 1},
+		Concurrency:   map[string]int{"critical": 4, "default": 4, "bulk": 1},
+		PollInterval:  time.Second,
+		MaxAttempts:   3,
+		RetryDelay:    time.Second,
+		MaxRetryDelay: time.Minute,
+	}
+}
+
+func loadJobsConfig(env *envReader) JobsConfig {
+	def := DefaultJobsConfig()
+	cfg := JobsConfig{
+		Weights:       def.Weights,
+		Concurrency:   def.Concurrency,
+		PollInterval:  env.Duration("JOB_POLL_INTERVAL", def.PollInterval),
+		MaxAttempts:   env.Int("JOB_MAX_ATTEMPTS", def.MaxAttempts),
+		RetryDelay:    env.Duration("JOB_RETRY_DELAY", def.RetryDelay),
+		MaxRetryDelay: env.Duration("JOB_MAX_RETRY_DELAY", def.MaxRetryDelay),
+	}
+	// Listed priorities override the defaults; unlisted ones keep them
+	for name, weight := range env.IntMap("JOB_PRIORITY_WEIGHTS") {
+		cfg.Weights[name] = weight
+	}
+	for name, n := range env.IntMap("JOB_PRIORITY_CONCURRENCY") {
+		cfg.Concurrency[name] = n
+	}
+	return cfg
+}
+
+// Validate checks the job worker settings
+func (c JobsConfig) Validate() error {
+	var errs []error
+	known := make(map[string]bool, len(JobPriorities))
+	for _, p := range JobPriorities {
+		known[p] = true
+	}
+	for name, weight := range c.Weights {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("JOB_PRIORITY_WEIGHTS: unknown priority %q (want one of %v)", name, JobPriorities))
+		} else if weight < 0 {
+			errs = append(errs, fmt.Errorf("JOB_PRIORITY_WEIGHTS: weight for %s must not be negative", name))
+		}
+	}
+	for name, n := range c.Concurrency {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("JOB_PRIORITY_CONCURRENCY: unknown priority %q (want one of %v)", name, JobPriorities))
+		} else if n < 0 {
+			errs = append(errs, fmt.Errorf("JOB_PRIORITY_CONCURRENCY: concurrency for %s must not be negative", name))
+		}
+	}
+	if c.PollInterval < 0 {
+		errs = append(errs, fmt.Errorf("JOB_POLL_INTERVAL must not be negative"))
+	}
+	if c.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("JOB_MAX_ATTEMPTS must not be negative"))
+	}
+	if c.RetryDelay < 0 || c.MaxRetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("JOB_RETRY_DELAY and JOB_MAX_RETRY_DELAY must not be negative"))
+	} else if c.MaxRetryDelay < c.RetryDelay {
+		errs = append(errs, fmt.Errorf("JOB_MAX_RETRY_DELAY must not be shorter than JOB_RETRY_DELAY"))
+	}
+	return validationErrors(errs)
+}
+
+// ReportsConfig configures scheduled report emails. Rendered reports are
+// stored in the blob store (S3_BUCKET) and recipients are emailed a link.
+type ReportsConfig struct {
+	Enabled       bool
+	CheckInterval time.Duration // How often due schedules are looked for
+	LinkTTL       time.Duration // Lifetime of emailed download links (at most 7 days on S3)
+	BlobPrefix    string        // Key prefix for stored reports
+	// Retention deletes stored reports this long after they were rendered,
+	// with BLOB_MANAGE_LIFECYCLE (0 keeps them)
+	Retention time.Duration
+}
+
+// DefaultReportsConfig returns the settings used when no env vars are set
+func DefaultReportsConfig() ReportsConfig {
+	return ReportsConfig{
+		CheckInterval: time.Minute,
+		LinkTTL:       7 * 24 * time.Hour,
+		BlobPrefix:    "reports/",
+	}
+}
+
+func loadReportsConfig(env *envReader) ReportsConfig {
+	def := DefaultReportsConfig()
+	return ReportsConfig{
+		Enabled:       env.Bool("REPORTS_ENABLED", def.Enabled),
+		CheckInterval: env.Duration("REPORTS_CHECK_INTERVAL", def.CheckInterval),
+		LinkTTL:       env.Duration("REPORTS_LINK_TTL", def.LinkTTL),
+		BlobPrefix:    env.String("REPORTS_BLOB_PREFIX", def.BlobPrefix),
+		Retention:     env.Duration("REPORTS_RETENTION", def.Retention),
+	}
+}
+
+// Validate checks the scheduled report settings
+func (c ReportsConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.CheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("REPORTS_CHECK_INTERVAL must be positive"))
+	}
+	if c.LinkTTL <= 0 || c.LinkTTL > 7*24*time.Hour {
+		errs = append(errs, fmt.Errorf("REPORTS_LINK_TTL must be between 0 and 168h (the S3 presigned URL limit)"))
+	}
+	if err := validateLifecycleDays("REPORTS_RETENTION", c.Retention); err != nil {
+		errs = append(errs, err)
+	} else if c.Retention > 0 && c.BlobPrefix == "" {
+		// The rule would expire every object in the bucket
+		errs = append(errs, fmt.Errorf("REPORTS_RETENTION requires REPORTS_BLOB_PREFIX"))
+	} else if c.Retention > 0 && c.Retention < c.LinkTTL {
+		// Emailed links would outlive the reports they point to
+		errs = append(errs, fmt.Errorf("REPORTS_RETENTION (%s) must not be shorter than REPORTS_LINK_TTL (%s)", c.Retention, c.LinkTTL))
+	}
+	return validationErrors(errs)
+}
+
+// AttachmentsConfig configures user uploads. Files go straight to the blob
+// store (S3_BUCKET) through presigned URLs; downloads are only allowed after
+// the malware scanner reports them clean.
+type AttachmentsConfig struct {
+	Enabled        bool
+	KeyPrefix      string        // Blob key prefix for attachments
+	MaxBytes       int64         // Largest accepted upload
+	UploadURLTTL   time.Duration // Lifetime of presigned upload URLs
+	DownloadURLTTL time.Duration // Lifetime of presigned download URLs
+	AllowedTypes   []string      // Accepted content types; entries ending in "/" are prefixes, empty allows all
+	// Resumable serves tus resumable uploads on /api/uploads, stored as
+	// multipart uploads (BLOB_BACKEND=s3)
+	Resumable           bool
+	ResumableChunkBytes int64  // Largest PATCH body of a resumable upload
+	PartPrefix          string // Blob key prefix for the unfinished tail of resumable uploads
+}
+
+// DefaultAttachmentsConfig returns the settings used when no env vars are set
+func DefaultAttachmentsConfig() AttachmentsConfig {
+	return AttachmentsConfig{
+		KeyPrefix:      "attachments/",
+		MaxBytes:       25 << 20, // 25 MB
+		UploadURLTTL:   15 * time.Minute,
+		DownloadURLTTL: 5 * time.Minute,
+
+		ResumableChunkBytes: 32 << 20, // 32 MB
+		PartPrefix:          "attachment-parts/",
+	}
+}
+
+func loadAttachmentsConfig(env *envReader) AttachmentsConfig {
+	def := DefaultAttachmentsConfig()
+	return AttachmentsConfig{
+		Enabled:        env.Bool("ATTACHMENTS_ENABLED", def.Enabled),
+		KeyPrefix:      env.String("ATTACHMENTS_KEY_PREFIX", def.KeyPrefix),
+		MaxBytes:       int64(env.Int("ATTACHMENTS_MAX_BYTES", int(def.MaxBytes))),
+		UploadURLTTL:   env.Duration("ATTACHMENTS_UPLOAD_URL_TTL", def.UploadURLTTL),
+		DownloadURLTTL: env.Duration("ATTACHMENTS_DOWNLOAD_URL_TTL", def.DownloadURLTTL),
+		AllowedTypes:   env.Slice("ATTACHMENTS_ALLOWED_CONTENT_TYPES", def.AllowedTypes),
+
+		Resumable:           env.Bool("ATTACHMENTS_RESUMABLE", def.Resumable),
+		ResumableChunkBytes: int64(env.Int("ATTACHMENTS_RESUMABLE_CHUNK_BYTES", int(def.ResumableChunkBytes))),
+		PartPrefix:          env.String("ATTACHMENTS_PART_PREFIX", def.PartPrefix),
+	}
+}
+
+// Validate checks the attachment settings
+func (c AttachmentsConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.MaxBytes <= 0 {
+		errs = append(errs, fmt.Errorf("ATTACHMENTS_MAX_BYTES must be positive"))
+	}
+	if c.UploadURLTTL <= 0 || c.DownloadURLTTL <= 0 {
+		errs = append(errs, fmt.Errorf("ATTACHMENTS_UPLOAD_URL_TTL and ATTACHMENTS_DOWNLOAD_URL_TTL must be positive"))
+	}
+	for _, t := range c.AllowedTypes {
+		if !strings.Contains(t, "/") {
+			errs = append(errs, fmt.Errorf("ATTACHMENTS_ALLOWED_CONTENT_TYPES entry %q must be a type/subtype or a type/ prefix", t))
+		}
+	}
+	if c.Resumable {
+		if c.ResumableChunkBytes <= 0 {
+			errs = append(errs, fmt.Errorf("ATTACHMENTS_RESUMABLE_CHUNK_BYTES must be positive"))
+		}
+		// Part tails are expired by the lifecycle rule, which must not reach
+		// finished attachments
+		switch {
+		case c.PartPrefix == "":
+			errs = append(errs, fmt.Errorf("ATTACHMENTS_PART_PREFIX is required with ATTACHMENTS_RESUMABLE"))
+		case strings.HasPrefix(c.PartPrefix, c.KeyPrefix) || strings.HasPrefix(c.KeyPrefix, c.PartPrefix):
+			errs = append(errs, fmt.Errorf("ATTACHMENTS_PART_PREFIX %q must not overlap ATTACHMENTS_KEY_PREFIX %q", c.PartPrefix, c.KeyPrefix))
+		}
+	}
+	return validationErrors(errs)
+}
+
+// UserDataConfig configures user imports (POST /api/users/import) and
+// personal data exports (POST /api/users/{id}/data-export), which run as
+// background jobs and store their output in the blob store (S3_BUCKET)
+type UserDataConfig struct {
+	Enabled     bool
+	BlobPrefix  string // Key prefix for import reports and exports
+	ImportChunk int    // Users inserted per transaction during an import
+	// Retention deletes import reports and exports this long after they
+	// were written, with BLOB_MANAGE_LIFECYCLE (0 keeps them)
+	Retention time.Duration
+}
+
+// DefaultUserDataConfig returns the settings used when no env vars are set
+func DefaultUserDataConfig() UserDataConfig {
+	return UserDataConfig{
+		BlobPrefix:  "user-data/",
+		ImportChunk: 100,
+	}
+}
+
+func loadUserDataConfig(env *envReader) UserDataConfig {
+	def := DefaultUserDataConfig()
+	return UserDataConfig{
+		Enabled:     env.Bool("USER_DATA_JOBS_ENABLED", def.Enabled),
+		BlobPrefix:  env.String("USER_DATA_BLOB_PREFIX", def.BlobPrefix),
+		ImportChunk: env.Int("USER_DATA_IMPORT_CHUNK", def.ImportChunk),
+		Retention:   env.Duration("USER_DATA_RETENTION", def.Retention),
+	}
+}
+
+// Validate checks the user data job settings
+func (c UserDataConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.BlobPrefix == "" {
+		errs = append(errs, fmt.Errorf("USER_DATA_BLOB_PREFIX must not be empty"))
+	}
+	if c.ImportChunk <= 0 {
+		errs = append(errs, fmt.Errorf("USER_DATA_IMPORT_CHUNK must be positive"))
+	}
+	if err := validateLifecycleDays("USER_DATA_RETENTION", c.Retention); err != nil {
+		errs = append(errs, err)
+	}
+	return validationErrors(errs)
+}
+
+// NotificationsConfig configures notifying users of their order events
+// (GET/PUT /api/users/{id}/notification-preferences), sent as background
+// jobs on the channels each user chose. Email is always available (logged
+// without SMTP_HOST); sms needs NOTIFICATIONS_SMS_PROVIDER, push
+// FCM_CREDENTIALS_FILE and slack SLACK_BOT_TOKEN.
+type NotificationsConfig struct {
+	Enabled bool
+	// DefaultChannels are the channels of event types users have no
+	// preference for
+	DefaultChannels []string
+	SMSProvider     string // "" (no SMS), "sns" or "twilio"
+	SNSSenderID     string // Alphanumeric sender ID, in the countries that support one, with sns
+
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFrom       string // E.164 number, or a Messaging Service SID (MG…)
+
+	SlackBotToken string // xoxb-…, with the chat:write scope
+
+	FCMCredentialsFile string // Firebase service account key (JSON)
+	FCMProjectID       string // Empty for the project of the service account
+}
+
+// DefaultNotificationsConfig returns the settings used when no env vars are set
+func DefaultNotificationsConfig() NotificationsConfig {
+	return NotificationsConfig{
+		DefaultChannels: []string{"email"},
+	}
+}
+
+func loadNotificationsConfig(env *envReader) NotificationsConfig {
+	def := DefaultNotificationsConfig()
+	return NotificationsConfig{
+		Enabled:            env.Bool("NOTIFICATIONS_ENABLED", def.Enabled),
+		DefaultChannels:    env.Slice("NOTIFICATIONS_DEFAULT_CHANNELS", def.DefaultChannels),
+		SMSProvider:        env.String("NOTIFICATIONS_SMS_PROVIDER", def.SMSProvider),
+		SNSSenderID:        env.String("NOTIFICATIONS_SNS_SENDER_ID", def.SNSSenderID),
+		TwilioAccountSID:   env.String("TWILIO_ACCOUNT_SID", def.TwilioAccountSID),
+		TwilioAuthToken:    env.String("TWILIO_AUTH_TOKEN", def.TwilioAuthToken),
+		TwilioFrom:         env.String("TWILIO_FROM", def.TwilioFrom),
+		SlackBotToken:      env.String("SLACK_BOT_TOKEN", def.SlackBotToken),
+		FCMCredentialsFile: env.String("FCM_CREDENTIALS_FILE", def.FCMCredentialsFile),
+		FCMProjectID:       env.String("FCM_PROJECT_ID", def.FCMProjectID),
+	}
+}
+
+var (
+	// e164 matches phone numbers in international E.164 form
+	e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	// snsSenderID matches SMS sender IDs: up to 11 letters and digits, not all digits
+	snsSenderID = regexp.MustCompile(`^[A-Za-z0-9]{0,10}[A-Za-z][A-Za-z0-9]{0,10}$`)
+)
+
+// Channel reports whether channel is configured
+func (c NotificationsConfig) Channel(channel string) bool {
+	switch channel {
+	case "email":
+		return true
+	case "sms":
+		return c.SMSProvider != ""
+	case "push":
+		return c.FCMCredentialsFile != ""
+	case "slack":
+		return c.SlackBotToken != ""
+	}
+	return false
+}
+
+// Validate checks the notification settings
+func (c NotificationsConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	for _, channel := range c.DefaultChannels {
+		switch {
+		case !slices.Contains([]string{"email", "sms", "push", "slack"}, channel):
+			errs = append(errs, fmt.Errorf("NOTIFICATIONS_DEFAULT_CHANNELS entry %q must be email, sms, push or slack", channel))
+		case !c.Channel(channel):
+			errs = append(errs, fmt.Errorf("NOTIFICATIONS_DEFAULT_CHANNELS entry %q is not configured", channel))
+		}
+	}
+	switch c.SMSProvider {
+	case "":
+	case "sns":
+		if c.SNSSenderID != "" && (len(c.SNSSenderID) > 11 || !snsSenderID.MatchString(c.SNSSenderID)) {
+			errs = append(errs, fmt.Errorf("invalid NOTIFICATIONS_SNS_SENDER_ID: %q (must be up to 11 letters and digits, not only digits)", c.SNSSenderID))
+		}
+	case "twilio":
+		if !strings.HasPrefix(c.TwilioAccountSID, "AC") || c.TwilioAuthToken == "" {
+			errs = append(errs, fmt.Errorf("NOTIFICATIONS_SMS_PROVIDER=twilio requires TWILIO_ACCOUNT_SID (AC…) and TWILIO_AUTH_TOKEN"))
+		}
+		if !e164.MatchString(c.TwilioFrom) && !strings.HasPrefix(c.TwilioFrom, "MG") {
+			errs = append(errs, fmt.Errorf("invalid TWILIO_FROM: %q (must be an E.164 phone number or a Messaging Service SID)", c.TwilioFrom))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid NOTIFICATIONS_SMS_PROVIDER: %q (must be sns or twilio)", c.SMSProvider))
+	}
+	if c.SlackBotToken != "" && !strings.HasPrefix(c.SlackBotToken, "xoxb-") {
+		errs = append(errs, fmt.Errorf("SLACK_BOT_TOKEN must be a bot token (xoxb-…)"))
+	}
+	return validationErrors(errs)
+}
+
+// DiagnosticsConfig configures diagnostics dumps (goroutine stacks, pool
+// stats, in-flight requests, cache stats) taken on SIGQUIT or from
+// POST /api/admin/diagnostics, for debugging stuck instances.
+type DiagnosticsConfig struct {
+	OnSIGQUIT  bool   // Dump on SIGQUIT and keep running, instead of Go's stack trace and exit
+	Output     string // "log" (or empty) or "blob"
+	BlobPrefix string // Blob key prefix with Output "blob"
+}
+
+// DefaultDiagnosticsConfig returns the settings used when no env vars are set
+func DefaultDiagnosticsConfig() DiagnosticsConfig {
+	return DiagnosticsConfig{
+		OnSIGQUIT:  true,
+		Output:     "log",
+		BlobPrefix: "diagnostics/",
+	}
+}
+
+func loadDiagnosticsConfig(env *envReader) DiagnosticsConfig {
+	def := DefaultDiagnosticsConfig()
+	return DiagnosticsConfig{
+		OnSIGQUIT:  env.Bool("DIAGNOSTICS_ON_SIGQUIT", def.OnSIGQUIT),
+		Output:     env.String("DIAGNOSTICS_OUTPUT", def.Output),
+		BlobPrefix: env.String("DIAGNOSTICS_BLOB_PREFIX", def.BlobPrefix),
+	}
+}
+
+// Validate checks the diagnostics settings
+func (c DiagnosticsConfig) Validate() error {
+	var errs []error
+	if c.Output != "" && c.Output != "log" && c.Output != "blob" {
+		errs = append(errs, fmt.Errorf("invalid DIAGNOSTICS_OUTPUT: %q (must be log or blob)", c.Output))
+	}
+	if c.Output == "blob" && c.BlobPrefix == "" {
+		errs = append(errs, fmt.Errorf("DIAGNOSTICS_BLOB_PREFIX must be set with DIAGNOSTICS_OUTPUT=blob"))
+	}
+	return validationErrors(errs)
+}
+
+// StatusConfig configures the public status endpoint (GET /status) and the
+// hourly request stats behind it
+type StatusConfig struct {
+	Enabled       bool
+	FlushInterval time.Duration // How often each instance merges its counts into the shared store
+	CacheTTL      time.Duration // How long a computed summary is served (also its Cache-Control max-age)
+}
+
+// DefaultStatusConfig returns the settings used when no env vars are set
+func DefaultStatusConfig() StatusConfig {
+	return StatusConfig{
+		Enabled:       true,
+		FlushInterval: 10 * time.Second,
+		CacheTTL:      30 * time.Second,
+	}
+}
+
+func loadStatusConfig(env *envReader) StatusConfig {
+	def := DefaultStatusConfig()
+	return StatusConfig{
+		Enabled:       env.Bool("STATUS_ENABLED", def.Enabled),
+		FlushInterval: env.Duration("STATUS_FLUSH_INTERVAL", def.FlushInterval),
+		CacheTTL:      env.Duration("STATUS_CACHE_TTL", def.CacheTTL),
+	}
+}
+
+// Validate checks the status endpoint settings
+func (c StatusConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("STATUS_FLUSH_INTERVAL must be positive, got %s", c.FlushInterval))
+	}
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("STATUS_CACHE_TTL must not be negative, got %s", c.CacheTTL))
+	}
+	return validationErrors(errs)
+}
+
+// SLOConfig configures per-route service level objectives and the burn rate
+// metrics computed for them (GET /api/admin/slo and the admin /metrics)
+type SLOConfig struct {
+	Enabled bool
+	// Targets by route ("GET /api/orders/{id}", as registered on the router);
+	// "*" applies to every other API route
+	Targets      map[string]SLOTarget
+	FastWindow   time.Duration // Short burn rate window, for paging on sudden breaches
+	SlowWindow   time.Duration // Long burn rate window, for slow leaks; also the heatmap history
+	FastBurnRate float64       // Fast window burn rate that pages
+	SlowBurnRate float64       // Slow window burn rate that opens a ticket
+	MinRequests  int           // Windows with fewer requests never breach
+}
+
+// SLOTarget is one route's objective: Objective percent of requests must be
+// answered without a server error, and within Latency when it is set
+type SLOTarget struct {
+	Objective float64 // Percent, e.g. 99.9
+	Latency   time.Duration
+}
+
+// DefaultSLOConfig returns the settings used when no env vars are set: the
+// multiwindow alerting thresholds of the Google SRE workbook for a 30 day budget
+func DefaultSLOConfig() SLOConfig {
+	return SLOConfig{
+		Enabled:      true,
+		Targets:      map[string]SLOTarget{"*": {Objective: 99.9, Latency: time.Second}},
+		FastWindow:   time.Hour,
+		SlowWindow:   6 * time.Hour,
+		FastBurnRate: 14.4,
+		SlowBurnRate: 6,
+		MinRequests:  100,
+	}
+}
+
+func loadSLOConfig(env *envReader) SLOConfig {
+	def := DefaultSLOConfig()
+	cfg := SLOConfig{
+		Enabled:      env.Bool("SLO_ENABLED", def.Enabled),
+		Targets:      def.Targets,
+		FastWindow:   env.Duration("SLO_FAST_WINDOW", def.FastWindow),
+		SlowWindow:   env.Duration("SLO_SLOW_WINDOW", def.SlowWindow),
+		FastBurnRate: env.Float("SLO_FAST_BURN_RATE", def.FastBurnRate),
+		SlowBurnRate: env.Float("SLO_SLOW_BURN_RATE", def.SlowBurnRate),
+		MinRequests:  env.Int("SLO_MIN_REQUESTS", def.MinRequests),
+	}
+	if entries := env.Slice("SLO_TARGETS", nil); len(entries) > 0 {
+		cfg.Targets = make(map[string]SLOTarget, len(entries))
+		for _, entry := range entries {
+			if entry == "" {
+				continue
+			}
+			route, target, err := parseSLOTarget(entry)
+			if err != nil {
+				env.fail(fmt.Errorf("invalid SLO_TARGETS entry %q (%v)", entry, err))
+				continue
+			}
+			cfg.Targets[route] = target
+		}
+	}
+	return cfg
+}
+
+// parseSLOTarget parses "route=objective" or "route=objective@latency", e.g.
+// "GET /api/orders/{id}=99.9@250ms"
+func parseSLOTarget(entry string) (string, SLOTarget, error) {
+	route, spec, ok := strings.Cut(entry, "=")
+	route = strings.TrimSpace(route)
+	if !ok || route == "" {
+		return "", SLOTarget{}, fmt.Errorf("want route=objective@latency")
+	}
+	objective, latency, hasLatency := strings.Cut(strings.TrimSpace(spec), "@")
+	var target SLOTarget
+	var err error
+	if target.Objective, err = strconv.ParseFloat(objective, 64); err != nil {
+		return "", SLOTarget{}, fmt.Errorf("objective must be a percentage")
+	}
+	if hasLatency {
+		if target.Latency, err = time.ParseDuration(latency); err != nil {
+			return "", SLOTarget{}, fmt.Errorf("latency must be a duration like 250ms")
+		}
+	}
+	return route, target, nil
+}
+
+// Validate checks the SLO settings
+func (c SLOConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	for route, target := range c.Targets {
+		if route != "*" {
+			method, path, ok := strings.Cut(route, " ")
+			if !ok || method == "" || strings.ToUpper(method) != method || !strings.HasPrefix(path, "/api/") {
+				errs = append(errs, fmt.Errorf("SLO_TARGETS: route %q must be * or a method and /api/ path, e.g. GET /api/orders/{id}", route))
+			}
+		}
+		if target.Objective <= 0 || target.Objective >= 100 {
+			errs = append(errs, fmt.Errorf("SLO_TARGETS: %s objective must be between 0 and 100 (exclusive), got %g", route, target.Objective))
+		}
+		if target.Latency < 0 {
+			errs = append(errs, fmt.Errorf("SLO_TARGETS: %s latency must not be negative", route))
+		}
+	}
+	if c.FastWindow < time.Minute {
+		errs = append(errs, fmt.Errorf("SLO_FAST_WINDOW must be at least 1m, got %s", c.FastWindow))
+	}
+	if c.SlowWindow <= c.FastWindow {
+		errs = append(errs, fmt.Errorf("SLO_SLOW_WINDOW (%s) must be longer than SLO_FAST_WINDOW (%s)", c.SlowWindow, c.FastWindow))
+	} else if c.SlowWindow > 24*time.Hour {
+		// Windows are kept in memory at minute resolution
+		errs = append(errs, fmt.Errorf("SLO_SLOW_WINDOW must be at most 24h, got %s", c.SlowWindow))
+	}
+	if c.FastBurnRate <= 0 || c.SlowBurnRate <= 0 {
+		errs = append(errs, fmt.Errorf("SLO_FAST_BURN_RATE and SLO_SLOW_BURN_RATE must be positive"))
+	}
+	if c.MinRequests < 0 {
+		errs = append(errs, fmt.Errorf("SLO_MIN_REQUESTS must not be negative"))
+	}
+	return validationErrors(errs)
+}
+
+// AlertsConfig configures paging operators when the instance starts
+// failing: alerts with samples of the failed requests are posted to a
+// Slack channel, a PagerDuty service, or both (only logged with neither)
+type AlertsConfig struct {
+	Enabled             bool
+	SlackWebhookURL     string // Incoming webhook (https://hooks.slack.com/services/…)
+	PagerDutyRoutingKey string // Integration key of an Events API v2 integration
+	Window              time.Duration
+	ErrorRate           float64 // Share of server errors (0..1) that alerts; 0 disables
+	MinRequests         int     // Windows with fewer requests never alert on the error rate
+	Panics              int     // Panics in a window that alert; 0 disables
+	Cooldown            time.Duration
+	CheckInterval       time.Duration
+	Samples             int // Failed requests sent with each alert
+}
+
+// DefaultAlertsConfig returns the settings used when no env vars are set
+func DefaultAlertsConfig() AlertsConfig {
+	return AlertsConfig{
+		Window:        5 * time.Minute,
+		ErrorRate:     0.05,
+		MinRequests:   50,
+		Panics:        1,
+		Cooldown:      15 * time.Minute,
+		CheckInterval: 15 * time.Second,
+		Samples:       5,
+	}
+}
+
+func loadAlertsConfig(env *envReader) AlertsConfig {
+	def := DefaultAlertsConfig()
+	return AlertsConfig{
+		Enabled:             env.Bool("ALERTS_ENABLED", def.Enabled),
+		SlackWebhookURL:     env.String("ALERTS_SLACK_WEBHOOK_URL", def.SlackWebhookURL),
+		PagerDutyRoutingKey: env.String("ALERTS_PAGERDUTY_ROUTING_KEY", def.PagerDutyRoutingKey),
+		Window:              env.Duration("ALERTS_WINDOW", def.Window),
+		ErrorRate:           env.Float("ALERTS_ERROR_RATE", def.ErrorRate),
+		MinRequests:         env.Int("ALERTS_MIN_REQUESTS", def.MinRequests),
+		Panics:              env.Int("ALERTS_PANICS", def.Panics),
+		Cooldown:            env.Duration("ALERTS_COOLDOWN", def.Cooldown),
+		CheckInterval:       env.Duration("ALERTS_CHECK_INTERVAL", def.CheckInterval),
+		Samples:             env.Int("ALERTS_SAMPLES", def.Samples),
+	}
+}
+
+// Validate checks the alerting settings
+func (c AlertsConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.SlackWebhookURL != "" {
+		if u, err := url.Parse(c.SlackWebhookURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("ALERTS_SLACK_WEBHOOK_URL must be an https URL"))
+		}
+	}
+	if c.Window < time.Minute {
+		errs = append(errs, fmt.Errorf("ALERTS_WINDOW must be at least 1m, got %s", c.Window))
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		errs = append(errs, fmt.Errorf("ALERTS_ERROR_RATE must be between 0 and 1, got %g", c.ErrorRate))
+	}
+	if c.MinRequests < 0 || c.Panics < 0 || c.Samples < 0 {
+		errs = append(errs, fmt.Errorf("ALERTS_MIN_REQUESTS, ALERTS_PANICS and ALERTS_SAMPLES must not be negative"))
+	}
+	if c.ErrorRate == 0 && c.Panics == 0 {
+		errs = append(errs, fmt.Errorf("ALERTS_ENABLED requires ALERTS_ERROR_RATE or ALERTS_PANICS"))
+	}
+	if c.Cooldown < 0 {
+		errs = append(errs, fmt.Errorf("ALERTS_COOLDOWN must not be negative"))
+	}
+	if c.CheckInterval <= 0 || c.CheckInterval > c.Window {
+		errs = append(errs, fmt.Errorf("ALERTS_CHECK_INTERVAL must be positive and at most ALERTS_WINDOW (%s), got %s", c.Window, c.CheckInterval))
+	}
+	return validationErrors(errs)
+}
+
+// CheckoutConfig configures order checkout (POST /api/orders/{id}/checkout):
+// a saga capturing the payment through the payments API, reserving stock,
+// then confirming the order, undone step by step when one fails
+type CheckoutConfig struct {
+	Enabled          bool
+	Currency         string // ISO 4217 code payments are captured in
+	PaymentsURL      string // Base URL of the payments API
+	PaymentsAPIKey   string
+	StepAttempts     int           // Tries of each step and compensation, the first included
+	RetryDelay       time.Duration // Before the second try; doubles after every failure
+	StepTimeout      time.Duration // Bounds each try
+	RecoveryInterval time.Duration // How often checkouts left unfinished are looked for
+	StaleAfter       time.Duration // Without progress before recovery takes a checkout over
+}
+
+// DefaultCheckoutConfig returns the settings used when no env vars are set
+func DefaultCheckoutConfig() CheckoutConfig {
+	return CheckoutConfig{
+		Currency:         "USD",
+		StepAttempts:     3,
+		RetryDelay:       200 * time.Millisecond,
+		StepTimeout:      30 * time.Second,
+		RecoveryInterval: time.Minute,
+		StaleAfter:       5 * time.Minute,
+	}
+}
+
+func loadCheckoutConfig(env *envReader) CheckoutConfig {
+	def := DefaultCheckoutConfig()
+	return CheckoutConfig{
+		Enabled:          env.Bool("CHECKOUT_ENABLED", def.Enabled),
+		Currency:         env.String("CHECKOUT_CURRENCY", def.Currency),
+		PaymentsURL:      env.String("CHECKOUT_PAYMENTS_URL", def.PaymentsURL),
+		PaymentsAPIKey:   env.String("CHECKOUT_PAYMENTS_API_KEY", def.PaymentsAPIKey),
+		StepAttempts:     env.Int("CHECKOUT_STEP_ATTEMPTS", def.StepAttempts),
+		RetryDelay:       env.Duration("CHECKOUT_RETRY_DELAY", def.RetryDelay),
+		StepTimeout:      env.Duration("CHECKOUT_STEP_TIMEOUT", def.StepTimeout),
+		RecoveryInterval: env.Duration("CHECKOUT_RECOVERY_INTERVAL", def.RecoveryInterval),
+		StaleAfter:       env.Duration("CHECKOUT_STALE_AFTER", def.StaleAfter),
+	}
+}
+
+// Validate checks the checkout settings
+func (c CheckoutConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if len(c.Currency) != 3 || strings.ToUpper(c.Currency) != c.Currency {
+		errs = append(errs, fmt.Errorf("CHECKOUT_CURRENCY must be a 3-letter ISO 4217 code like USD, got %q", c.Currency))
+	}
+	if c.PaymentsURL != "" {
+		if u, err := url.Parse(c.PaymentsURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("CHECKOUT_PAYMENTS_URL must be an http or https URL"))
+		}
+	}
+	if c.StepAttempts < 1 {
+		errs = append(errs, fmt.Errorf("CHECKOUT_STEP_ATTEMPTS must be at least 1, got %d", c.StepAttempts))
+	}
+	if c.RetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("CHECKOUT_RETRY_DELAY must not be negative"))
+	}
+	if c.StepTimeout <= 0 || c.RecoveryInterval <= 0 {
+		errs = append(errs, fmt.Errorf("CHECKOUT_STEP_TIMEOUT and CHECKOUT_RECOVERY_INTERVAL must be positive"))
+	}
+	// A checkout still retrying a step must not be taken over by recovery
+	if busy := c.StepTimeout * time.Duration(max(c.StepAttempts, 1)); c.StaleAfter <= busy {
+		errs = append(errs, fmt.Errorf("CHECKOUT_STALE_AFTER (%s) must exceed CHECKOUT_STEP_TIMEOUT times CHECKOUT_STEP_ATTEMPTS (%s)", c.StaleAfter, busy))
+	}
+	return validationErrors(errs)
+}
+
+// DeadLettersConfig configures the dead-letter queue: jobs out of attempts
+// and events out of deliveries are kept for inspection and replay
+// (/api/admin/dead-letters) instead of being dropped
+type DeadLettersConfig struct {
+	Enabled   bool
+	Retention time.Duration // How long dead letters are kept; 0 keeps them until deleted
+}
+
+// DefaultDeadLettersConfig returns the settings used when no env vars are set
+func DefaultDeadLettersConfig() DeadLettersConfig {
+	return DeadLettersConfig{
+		Retention: 30 * 24 * time.Hour,
+	}
+}
+
+func loadDeadLettersConfig(env *envReader) DeadLettersConfig {
+	def := DefaultDeadLettersConfig()
+	return DeadLettersConfig{
+		Enabled:   env.Bool("DEAD_LETTERS_ENABLED", def.Enabled),
+		Retention: env.Duration("DEAD_LETTERS_RETENTION", def.Retention),
+	}
+}
+
+// Validate checks the dead-letter settings
+func (c DeadLettersConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.Retention < 0 {
+		errs = append(errs, fmt.Errorf("DEAD_LETTERS_RETENTION must not be negative"))
+	}
+	if c.Retention > 0 && c.Retention < time.Hour {
+		// Purged hourly: a shorter retention would drop letters before anyone looks
+		errs = append(errs, fmt.Errorf("DEAD_LETTERS_RETENTION must be 0 or at least 1h, got %s", c.Retention))
+	}
+	return validationErrors(errs)
+}
+
+// GraphQLConfig configures the GraphQL endpoint (POST /api/graphql)
+type GraphQLConfig struct {
+	Enabled  bool
+	MaxDepth int // Deepest field nesting a query may select
+}
+
+// DefaultGraphQLConfig returns the settings used when no env vars are set
+func DefaultGraphQLConfig() GraphQLConfig {
+	return GraphQLConfig{
+		Enabled:  true,
+		MaxDepth: 8,
+	}
+}
+
+func loadGraphQLConfig(env *envReader) GraphQLConfig {
+	def := DefaultGraphQLConfig()
+	return GraphQLConfig{
+		Enabled:  env.Bool("GRAPHQL_ENABLED", def.Enabled),
+		MaxDepth: env.Int("GRAPHQL_MAX_DEPTH", def.MaxDepth),
+	}
+}
+
+// Validate checks the GraphQL settings
+func (c GraphQLConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxDepth < 1 {
+		return fmt.Errorf("GRAPHQL_MAX_DEPTH must be at least 1, got %d", c.MaxDepth)
+	}
+	return nil
+}
+
+// WebSocketConfig configures live order status (GET /api/orders/{id}/ws)
+type WebSocketConfig struct {
+	Enabled      bool
+	PingInterval time.Duration // Clients not answering a ping within the next interval are dropped
+	WriteTimeout time.Duration // Clients not reading a message within this time are dropped
+}
+
+// DefaultWebSocketConfig returns the settings used when no env vars are set
+func DefaultWebSocketConfig() WebSocketConfig {
+	return WebSocketConfig{
+		Enabled:      true,
+		PingInterval: 30 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+}
+
+func loadWebSocketConfig(env *envReader) WebSocketConfig {
+	def := DefaultWebSocketConfig()
+	return WebSocketConfig{
+		Enabled:      env.Bool("WS_ENABLED", def.Enabled),
+		PingInterval: env.Duration("WS_PING_INTERVAL", def.PingInterval),
+		WriteTimeout: env.Duration("WS_WRITE_TIMEOUT", def.WriteTimeout),
+	}
+}
+
+// Validate checks the WebSocket settings
+func (c WebSocketConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.PingInterval <= 0 {
+		errs = append(errs, fmt.Errorf("WS_PING_INTERVAL must be positive, got %s", c.PingInterval))
+	}
+	if c.WriteTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("WS_WRITE_TIMEOUT must be positive, got %s", c.WriteTimeout))
+	}
+	return validationErrors(errs)
+}
+
+// SSEConfig configures the order event stream (GET /api/events)
+type SSEConfig struct {
+	Enabled           bool
+	HeartbeatInterval time.Duration // Comment lines keeping idle connections (and proxies) alive
+	RetainEvents      int           // Recent events kept for clients resuming with Last-Event-ID
+}
+
+// DefaultSSEConfig returns the settings used when no env vars are set
+func DefaultSSEConfig() SSEConfig {
+	return SSEConfig{
+		Enabled:           true,
+		HeartbeatInterval: 15 * time.Second,
+		RetainEvents:      10000,
+	}
+}
+
+func loadSSEConfig(env *envReader) SSEConfig {
+	def := DefaultSSEConfig()
+	return SSEConfig{
+		Enabled:           env.Bool("SSE_ENABLED", def.Enabled),
+		HeartbeatInterval: env.Duration("SSE_HEARTBEAT_INTERVAL", def.HeartbeatInterval),
+		RetainEvents:      env.Int("SSE_RETAIN_EVENTS", def.RetainEvents),
+	}
+}
+
+// Validate checks the event stream settings
+func (c SSEConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.HeartbeatInterval <= 0 {
+		errs = append(errs, fmt.Errorf("SSE_HEARTBEAT_INTERVAL must be positive, got %s", c.HeartbeatInterval))
+	}
+	if c.RetainEvents < 1 {
+		errs = append(errs, fmt.Errorf("SSE_RETAIN_EVENTS must be at least 1, got %d", c.RetainEvents))
+	}
+	return validationErrors(errs)
+}
+
+// OutboxConfig configures the transactional outbox: order lifecycle events
+// stored with the writes they announce and relayed to the domain event bus
+type OutboxConfig struct {
+	Enabled      bool
+	PollInterval time.Duration // How often the relay checks for new events
+	BatchSize    int           // Events relayed per transaction
+	Retention    time.Duration // How long published events are kept
+	// MaxAttempts moves events failing this many times to the dead-letter
+	// queue (DEAD_LETTERS_ENABLED); 0 retries them forever
+	MaxAttempts int
+}
+
+// DefaultOutboxConfig returns the settings used when no env vars are set
+func DefaultOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Retention:    24 * time.Hour,
+		MaxAttempts:  20,
+	}
+}
+
+func loadOutboxConfig(env *envReader) OutboxConfig {
+	def := DefaultOutboxConfig()
+	return OutboxConfig{
+		Enabled:      env.Bool("OUTBOX_ENABLED", def.Enabled),
+		PollInterval: env.Duration("OUTBOX_POLL_INTERVAL", def.PollInterval),
+		BatchSize:    env.Int("OUTBOX_BATCH_SIZE", def.BatchSize),
+		Retention:    env.Duration("OUTBOX_RETENTION", def.Retention),
+		MaxAttempts:  env.Int("OUTBOX_MAX_ATTEMPTS", def.MaxAttempts),
+	}
+}
+
+// Validate checks the outbox settings
+func (c OutboxConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("OUTBOX_POLL_INTERVAL must be positive, got %s", c.PollInterval))
+	}
+	if c.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("OUTBOX_BATCH_SIZE must be at least 1, got %d", c.BatchSize))
+	}
+	if c.Retention < 0 {
+		errs = append(errs, fmt.Errorf("OUTBOX_RETENTION must not be negative, got %s", c.Retention))
+	}
+	if c.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("OUTBOX_MAX_ATTEMPTS must not be negative, got %d", c.MaxAttempts))
+	}
+	return validationErrors(errs)
+}
+
+// KafkaConfig configures publishing domain events to Kafka
+type KafkaConfig struct {
+	Brokers  []string      // Bootstrap brokers, host:port; empty disables
+	Topic    string        // Topic every domain event is published to
+	ClientID string        // Reported to the brokers
+	Timeout  time.Duration // Per request, including replication of each event
+
+	TLS         bool   // Encrypt broker connections
+	TLSCAFile   string // CA bundle broker certificates are checked against; the system pool when empty
+	TLSCertFile string // Client certificate, with TLSKeyFile, for brokers requiring mutual TLS
+	TLSKeyFile  string
+
+	SASLMechanism string // PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; empty disables
+	SASLUsername  string
+	SASLPassword  string
+}
+
+// DefaultKafkaConfig returns the settings used when no env vars are set
+func DefaultKafkaConfig() KafkaConfig {
+	return KafkaConfig{
+		Topic:    "domain-events",
+		ClientID: "stdlib-golang-api",
+		Timeout:  10 * time.Second,
+	}
+}
+
+func loadKafkaConfig(env *envReader) KafkaConfig {
+	def := DefaultKafkaConfig()
+	return KafkaConfig{
+		Brokers:  env.Slice("KAFKA_BROKERS", def.Brokers),
+		Topic:    env.String("KAFKA_TOPIC", def.Topic),
+		ClientID: env.String("KAFKA_CLIENT_ID", def.ClientID),
+		Timeout:  env.Duration("KAFKA_TIMEOUT", def.Timeout),
+
+		TLS:         env.Bool("KAFKA_TLS", false),
+		TLSCAFile:   env.String("KAFKA_TLS_CA_FILE", ""),
+		TLSCertFile: env.String("KAFKA_TLS_CERT_FILE", ""),
+		TLSKeyFile:  env.String("KAFKA_TLS_KEY_FILE", ""),
+
+		SASLMechanism: env.String("KAFKA_SASL_MECHANISM", ""),
+		SASLUsername:  env.String("KAFKA_SASL_USERNAME", ""),
+		SASLPassword:  env.String("KAFKA_SASL_PASSWORD", ""),
+	}
+}
+
+// kafkaTopicName matches the names Kafka accepts for topics
+var kafkaTopicName = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// Validate checks the Kafka settings
+func (c KafkaConfig) Validate() error {
+	if len(c.Brokers) == 0 {
+		return nil
+	}
+	var errs []error
+	for _, broker := range c.Brokers {
+		if host, port, err := net.SplitHostPort(broker); err != nil || host == "" || port == "" {
+			errs = append(errs, fmt.Errorf("invalid KAFKA_BROKERS entry: %q (want host:port, e.g. localhost:9092)", broker))
+		}
+	}
+	if !kafkaTopicName.MatchString(c.Topic) || c.Topic == "." || c.Topic == ".." {
+		errs = append(errs, fmt.Errorf("invalid KAFKA_TOPIC: %q (letters, digits, '.', '_' and '-', at most 249)", c.Topic))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("KAFKA_TIMEOUT must be positive, got %s", c.Timeout))
+	}
+	if !c.TLS && (c.TLSCAFile != "" || c.TLSCertFile != "" || c.TLSKeyFile != "") {
+		errs = append(errs, fmt.Errorf("KAFKA_TLS_CA_FILE, KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE require KAFKA_TLS=true"))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE must be set together"))
+	}
+	switch c.SASLMechanism {
+	case "":
+		if c.SASLUsername != "" || c.SASLPassword != "" {
+			errs = append(errs, fmt.Errorf("KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD require KAFKA_SASL_MECHANISM"))
+		}
+	case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		if c.SASLUsername == "" || c.SASLPassword == "" {
+			errs = append(errs, fmt.Errorf("KAFKA_SASL_MECHANISM=%s requires KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD", c.SASLMechanism))
+		}
+		if c.SASLMechanism == "PLAIN" && !c.TLS {
+			errs = append(errs, fmt.Errorf("KAFKA_SASL_MECHANISM=PLAIN sends the password in the clear and requires KAFKA_TLS=true"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid KAFKA_SASL_MECHANISM: %s (must be PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512)", c.SASLMechanism))
+	}
+	return validationErrors(errs)
+}
+
+// SNSConfig configures publishing domain events to Amazon SNS, with the
+// AWS settings
+type SNSConfig struct {
+	TopicARN string // Empty disables
+}
+
+func loadSNSConfig(env *envReader) SNSConfig {
+	return SNSConfig{TopicARN: env.String("SNS_TOPIC_ARN", "")}
+}
+
+// Validate checks the SNS settings
+func (c SNSConfig) Validate() error {
+	if c.TopicARN == "" {
+		return nil
+	}
+	var errs []error
+	if parts := strings.Split(c.TopicARN, ":"); len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[5] == "" {
+		errs = append(errs, fmt.Errorf("invalid SNS_TOPIC_ARN: %q (want arn:aws:sns:<region>:<account>:<topic>)", c.TopicARN))
+	}
+	return validationErrors(errs)
+}
+
+// SQSConfig configures the worker consuming domain events from an Amazon
+// SQS queue, with the AWS settings
+type SQSConfig struct {
+	QueueURL           string        // Empty disables
+	DeadLetterQueueURL string        // Where messages go after MaxReceives failed deliveries; empty leaves them to the queue's redrive policy
+	MaxReceives        int           // Deliveries before a message is dead-lettered
+	WaitTime           time.Duration // Long polling wait
+	VisibilityTimeout  time.Duration // Renewed while a message is handled
+	MaxMessages        int           // Received per poll
+	Concurrency        int           // Messages handled at once
+}
+
+// DefaultSQSConfig returns the settings used when no env vars are set
+func DefaultSQSConfig() SQSConfig {
+	return SQSConfig{
+		MaxReceives:       5,
+		WaitTime:          20 * time.Second,
+		VisibilityTimeout: 30 * time.Second,
+		MaxMessages:       10,
+		Concurrency:       4,
+	}
+}
+
+func loadSQSConfig(env *envReader) SQSConfig {
+	def := DefaultSQSConfig()
+	return SQSConfig{
+		QueueURL:           env.String("SQS_QUEUE_URL", def.QueueURL),
+		DeadLetterQueueURL: env.String("SQS_DEAD_LETTER_QUEUE_URL", def.DeadLetterQueueURL),
+		MaxReceives:        env.Int("SQS_MAX_RECEIVES", def.MaxReceives),
+		WaitTime:           env.Duration("SQS_WAIT_TIME", def.WaitTime),
+		VisibilityTimeout:  env.Duration("SQS_VISIBILITY_TIMEOUT", def.VisibilityTimeout),
+		MaxMessages:        env.Int("SQS_MAX_MESSAGES", def.MaxMessages),
+		Concurrency:        env.Int("SQS_CONCURRENCY", def.Concurrency),
+	}
+}
+
+// Validate checks the SQS settings
+func (c SQSConfig) Validate() error {
+	if c.QueueURL == "" {
+		return nil
+	}
+	var errs []error
+	for _, queue := range []struct{ key, url string }{
+		{"SQS_QUEUE_URL", c.QueueURL},
+		{"SQS_DEAD_LETTER_QUEUE_URL", c.DeadLetterQueueURL},
+	} {
+		if queue.url == "" {
+			continue
+		}
+		if u, err := url.Parse(queue.url); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid %s: %q (want the queue URL, https://sqs.<region>.amazonaws.com/<account>/<queue>)", queue.key, queue.url))
+		}
+	}
+	if c.DeadLetterQueueURL == c.QueueURL {
+		errs = append(errs, fmt.Errorf("SQS_DEAD_LETTER_QUEUE_URL must differ from SQS_QUEUE_URL"))
+	}
+	if c.MaxReceives < 1 {
+		errs = append(errs, fmt.Errorf("SQS_MAX_RECEIVES must be at least 1, got %d", c.MaxReceives))
+	}
+	if c.WaitTime < 0 || c.WaitTime > 20*time.Second {
+		errs = append(errs, fmt.Errorf("SQS_WAIT_TIME must be between 0s and 20s, got %s", c.WaitTime))
+	}
+	if c.VisibilityTimeout < 2*time.Second || c.VisibilityTimeout > 12*time.Hour {
+		errs = append(errs, fmt.Errorf("SQS_VISIBILITY_TIMEOUT must be between 2s and 12h, got %s", c.VisibilityTimeout))
+	}
+	if c.MaxMessages < 1 || c.MaxMessages > 10 {
+		errs = append(errs, fmt.Errorf("SQS_MAX_MESSAGES must be between 1 and 10, got %d", c.MaxMessages))
+	}
+	if c.Concurrency < 1 {
+		errs = append(errs, fmt.Errorf("SQS_CONCURRENCY must be at least 1, got %d", c.Concurrency))
+	}
+	return validationErrors(errs)
+}
+
+// EventBusConfig selects the backend of the domain event bus and configures
+// the NATS JetStream and Redis Streams ones
+type EventBusConfig struct {
+	Backend string // "memory" (or empty): in process; "nats": a JetStream stream, "redis": a Redis Stream, shared by every instance
+	Source  string // CloudEvents source of the events published to other services, a URI reference; empty for the default
+
+	NATSURL             string        // Comma-separated nats:// or tls:// URLs
+	NATSUser            string        // With NATSPassword, or NATSToken, to authenticate
+	NATSPassword        string        // Of NATSUser
+	NATSToken           string        // Instead of a user and password
+	NATSStream          string        // Stream holding the events, created when missing
+	NATSSubject         string        // Subject prefix: events go to <prefix>.<event type>
+	NATSDurable         string        // Durable consumer shared by every instance
+	NATSAckWait         time.Duration // Renewed while handlers run
+	NATSMaxDeliver      int           // Deliveries before an event whose handlers keep failing is dropped
+	NATSMaxAge          time.Duration // Stream retention, when it is created
+	NATSDuplicateWindow time.Duration // How long a republished event is recognized, when the stream is created
+	NATSTimeout         time.Duration // Per request
+
+	RedisStream     string        // Stream key
+	RedisGroup      string        // Consumer group shared by every instance
+	RedisConsumer   string        // This instance in the group; hostname-pid when empty
+	RedisMaxLen     int64         // About how many entries the stream keeps; 0 for no limit
+	RedisMaxAge     time.Duration // How long the stream keeps entries; 0 for no limit
+	RedisClaimIdle  time.Duration // How long an entry may go unacknowledged before another instance claims it
+	RedisMaxDeliver int           // Deliveries before an event whose handlers keep failing is dropped
+}
+
+// DefaultEventBusConfig returns the settings used when no env vars are set
+func DefaultEventBusConfig() EventBusConfig {
+	return EventBusConfig{
+		Backend:             "memory",
+		Source:              "/stdlib-golang-api",
+		NATSURL:             "nats://localhost:4222",
+		NATSStream:          "DOMAIN_EVENTS",
+		NATSSubject:         "events",
+		NATSDurable:         "stdlib-golang-api",
+		NATSAckWait:         30 * time.Second,
+		NATSMaxDeliver:      5,
+		NATSMaxAge:          7 * 24 * time.Hour,
+		NATSDuplicateWindow: 10 * time.Minute,
+		NATSTimeout:         5 * time.Second,
+		RedisStream:         "events:domain",
+		RedisGroup:          "stdlib-golang-api",
+		RedisMaxLen:         100_000,
+		RedisMaxAge:         7 * 24 * time.Hour,
+		RedisClaimIdle:      30 * time.Second,
+		RedisMaxDeliver:     5,
+	}
+}
+
+func loadEventBusConfig(env *envReader) EventBusConfig {
+	def := DefaultEventBusConfig()
+	return EventBusConfig{
+		Backend:             env.String("EVENT_BUS", def.Backend),
+		Source:              env.String("EVENT_SOURCE", def.Source),
+		NATSURL:             env.String("NATS_URL", def.NATSURL),
+		NATSUser:            env.String("NATS_USER", ""),
+		NATSPassword:        env.String("NATS_PASSWORD", ""),
+		NATSToken:           env.String("NATS_TOKEN", ""),
+		NATSStream:          env.String("NATS_STREAM", def.NATSStream),
+		NATSSubject:         env.String("NATS_SUBJECT", def.NATSSubject),
+		NATSDurable:         env.String("NATS_DURABLE", def.NATSDurable),
+		NATSAckWait:         env.Duration("NATS_ACK_WAIT", def.NATSAckWait),
+		NATSMaxDeliver:      env.Int("NATS_MAX_DELIVER", def.NATSMaxDeliver),
+		NATSMaxAge:          env.Duration("NATS_MAX_AGE", def.NATSMaxAge),
+		NATSDuplicateWindow: env.Duration("NATS_DUPLICATE_WINDOW", def.NATSDuplicateWindow),
+		NATSTimeout:         env.Duration("NATS_TIMEOUT", def.NATSTimeout),
+		RedisStream:         env.String("REDIS_EVENTS_STREAM", def.RedisStream),
+		RedisGroup:          env.String("REDIS_EVENTS_GROUP", def.RedisGroup),
+		RedisConsumer:       env.String("REDIS_EVENTS_CONSUMER", ""),
+		RedisMaxLen:         int64(env.Int("REDIS_EVENTS_MAX_LEN", int(def.RedisMaxLen))),
+		RedisMaxAge:         env.Duration("REDIS_EVENTS_MAX_AGE", def.RedisMaxAge),
+		RedisClaimIdle:      env.Duration("REDIS_EVENTS_CLAIM_IDLE", def.RedisClaimIdle),
+		RedisMaxDeliver:     env.Int("REDIS_EVENTS_MAX_DELIVER", def.RedisMaxDeliver),
+	}
+}
+
+var (
+	// natsName matches the stream and consumer names JetStream accepts
+	natsName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
+	// natsSubject matches a subject without wildcards
+	natsSubject = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*$`)
+)
+
+// Validate checks the event bus settings
+func (c EventBusConfig) Validate() error {
+	var errs []error
+	if _, err := url.Parse(c.Source); err != nil || strings.ContainsAny(c.Source, " \t") {
+		errs = append(errs, fmt.Errorf("invalid EVENT_SOURCE: %q (must be a URI reference, such as /orders-api)", c.Source))
+	}
+	switch c.Backend {
+	case "", "memory":
+	case "nats":
+		errs = append(errs, c.validateNATS()...)
+	case "redis":
+		errs = append(errs, c.validateRedis()...)
+	default:
+		errs = append(errs, fmt.Errorf("invalid EVENT_BUS: %q (must be memory, nats or redis)", c.Backend))
+	}
+	return validationErrors(errs)
+}
+
+// validateNATS checks the settings of the nats backend
+func (c EventBusConfig) validateNATS() []error {
+	var errs []error
+	servers := 0
+	for _, server := range strings.Split(c.NATSURL, ",") {
+		if server = strings.TrimSpace(server); server == "" {
+			continue
+		}
+		servers++
+		if !strings.Contains(server, "://") {
+			server = "nats://" + server
+		}
+		u, err := url.Parse(server)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid NATS_URL entry %d: %w", servers, err))
+		} else if (u.Scheme != "nats" && u.Scheme != "tls") || u.Hostname() == "" {
+			errs = append(errs, fmt.Errorf("invalid NATS_URL entry: %q (want nats://host:port or tls://host:port)", u.Redacted()))
+		}
+	}
+	if servers == 0 {
+		errs = append(errs, fmt.Errorf("NATS_URL is required with EVENT_BUS=nats"))
+	}
+	if c.NATSUser != "" && c.NATSToken != "" {
+		errs = append(errs, fmt.Errorf("NATS_USER and NATS_TOKEN are mutually exclusive"))
+	}
+	if (c.NATSUser == "") != (c.NATSPassword == "") {
+		errs = append(errs, fmt.Errorf("NATS_USER and NATS_PASSWORD must be set together"))
+	}
+	if !natsName.MatchString(c.NATSStream) {
+		errs = append(errs, fmt.Errorf("invalid NATS_STREAM: %q (letters, digits, '_' and '-')", c.NATSStream))
+	}
+	if !natsName.MatchString(c.NATSDurable) {
+		errs = append(errs, fmt.Errorf("invalid NATS_DURABLE: %q (letters, digits, '_' and '-')", c.NATSDurable))
+	}
+	if !natsSubject.MatchString(c.NATSSubject) {
+		errs = append(errs, fmt.Errorf("invalid NATS_SUBJECT: %q (dot-separated tokens of letters, digits, '_' and '-')", c.NATSSubject))
+	}
+	if c.NATSAckWait < time.Second {
+		errs = append(errs, fmt.Errorf("NATS_ACK_WAIT must be at least 1s, got %s", c.NATSAckWait))
+	}
+	if c.NATSMaxDeliver < 1 {
+		errs = append(errs, fmt.Errorf("NATS_MAX_DELIVER must be at least 1, got %d", c.NATSMaxDeliver))
+	}
+	if c.NATSMaxAge <= 0 {
+		errs = append(errs, fmt.Errorf("NATS_MAX_AGE must be positive, got %s", c.NATSMaxAge))
+	}
+	if c.NATSDuplicateWindow <= 0 || c.NATSDuplicateWindow > c.NATSMaxAge {
+		errs = append(errs, fmt.Errorf("NATS_DUPLICATE_WINDOW must be positive and at most NATS_MAX_AGE, got %s", c.NATSDuplicateWindow))
+	}
+	if c.NATSTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("NATS_TIMEOUT must be positive, got %s", c.NATSTimeout))
+	}
+	return errs
+}
+
+// validateRedis checks the settings of the redis backend
+func (c EventBusConfig) validateRedis() []error {
+	var errs []error
+	if c.RedisStream == "" {
+		errs = append(errs, fmt.Errorf("REDIS_EVENTS_STREAM is required with EVENT_BUS=redis"))
+	}
+	if c.RedisGroup == "" {
+		errs = append(errs, fmt.Errorf("REDIS_EVENTS_GROUP is required with EVENT_BUS=redis"))
+	}
+	if c.RedisMaxLen < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_EVENTS_MAX_LEN must not be negative, got %d", c.RedisMaxLen))
+	}
+	if c.RedisMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("REDIS_EVENTS_MAX_AGE must not be negative, got %s", c.RedisMaxAge))
+	}
+	if c.RedisClaimIdle < time.Second {
+		errs = append(errs, fmt.Errorf("REDIS_EVENTS_CLAIM_IDLE must be at least 1s, got %s", c.RedisClaimIdle))
+	}
+	if c.RedisMaxAge > 0 && c.RedisMaxAge < time.Duration(c.RedisMaxDeliver)*c.RedisClaimIdle {
+		// Entries would be trimmed before their last redelivery
+		errs = append(errs, fmt.Errorf("REDIS_EVENTS_MAX_AGE must be at least REDIS_EVENTS_MAX_DELIVER times REDIS_EVENTS_CLAIM_IDLE, got %s", c.RedisMaxAge))
+	}
+	if c.RedisMaxDeliver < 1 {
+		errs = append(errs, fmt.Errorf("REDIS_EVENTS_MAX_DELIVER must be at least 1, got %d", c.RedisMaxDeliver))
+	}
+	return errs
+}
+
+// TelemetryListenerConfig configures one dedicated observability listener
+type TelemetryListenerConfig struct {
+	Enabled   bool
+	Addr      string // host:port
+	AuthToken string // Bearer token callers must present; empty serves without auth
+}
+
+// TelemetryConfig configures the listeners of the observability surface, each
+// on its own port apart from the API. They are validated together, and
+// Config.Validate keeps them off the API, redirect and admin ports.
+type TelemetryConfig struct {
+	Metrics   TelemetryListenerConfig // Prometheus scrape endpoint
+	Tracing   TelemetryListenerConfig // Trace inspection (zPages)
+	Profiling TelemetryListenerConfig // pprof
+}
+
+// minTelemetryTokenLength keeps listener tokens from being guessable
+const minTelemetryTokenLength = 16
+
+// DefaultTelemetryConfig returns the settings used when no env vars are set:
+// every listener disabled, bound to loopback on its conventional port
+func DefaultTelemetryConfig() TelemetryConfig {
+	return TelemetryConfig{
+		Metrics:   TelemetryListenerConfig{Addr: "127.0.0.1:9464"},
+		Tracing:   TelemetryListenerConfig{Addr: "127.0.0.1:55679"},
+		Profiling: TelemetryListenerConfig{Addr: "127.0.0.1:6060"},
+	}
+}
+
+func loadTelemetryConfig(env *envReader) TelemetryConfig {
+	def := DefaultTelemetryConfig()
+	listener := func(prefix string, def TelemetryListenerConfig) TelemetryListenerConfig {
+		return TelemetryListenerConfig{
+			Enabled:   env.Bool(prefix+"_ENABLED", def.Enabled),
+			Addr:      env.String(prefix+"_ADDR", def.Addr),
+			AuthToken: env.String(prefix+"_AUTH_TOKEN", def.AuthToken),
+		}
+	}
+	return TelemetryConfig{
+		Metrics:   listener("TELEMETRY_METRICS", def.Metrics),
+		Tracing:   listener("TELEMETRY_TRACING", def.Tracing),
+		Profiling: listener("TELEMETRY_PROFILING", def.Profiling),
+	}
+}
+
+// telemetryListener names a listener by its env var prefix
+type telemetryListener struct {
+	prefix string
+	cfg    TelemetryListenerConfig
+}
+
+// listeners returns the enabled listeners in a fixed order
+func (c TelemetryConfig) listeners() []telemetryListener {
+	var out []telemetryListener
+	for _, l := range []telemetryListener{
+		{"TELEMETRY_METRICS", c.Metrics},
+		{"TELEMETRY_TRACING", c.Tracing},
+		{"TELEMETRY_PROFILING", c.Profiling},
+	} {
+		if l.cfg.Enabled {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Validate checks the telemetry listener settings, including that no two
+// enabled listeners share a port
+func (c TelemetryConfig) Validate() error {
+	var errs []error
+	ports := make(map[string]string) // Port to the listener that claimed it
+	for _, l := range c.listeners() {
+		port, err := listenerPort(l.cfg.Addr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s_ADDR: %q (%v)", l.prefix, l.cfg.Addr, err))
+		} else if other, taken := ports[port]; taken {
+			errs = append(errs, fmt.Errorf("%s_ADDR port %s is already used by %s_ADDR", l.prefix, port, other))
+		} else {
+			ports[port] = l.prefix
+		}
+		if l.cfg.AuthToken != "" && len(l.cfg.AuthToken) < minTelemetryTokenLength {
+			errs = append(errs, fmt.Errorf("%s_AUTH_TOKEN must be at least %d characters", l.prefix, minTelemetryTokenLength))
+		}
+	}
+	return validationErrors(errs)
+}
+
+// listenerPort returns the port of a host:port listen address, which must be
+// a number between 1 and 65535
+func listenerPort(addr string) (string, error) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("want host:port, e.g. 127.0.0.1:9464")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("port must be 1-65535")
+	}
+	return port, nil
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ChaosConfig configures fault injection for resilience testing. It is
+// rejected in production.
+type ChaosConfig struct {
+	Enabled      bool
+	ErrorPercent map[string]int // Per target (see ChaosTargets), the share of calls failing, 0-100
+	LatencyMS    map[string]int // Per target, milliseconds added to every call
+	AllowHeaders bool           // Requests may pick their own faults with X-Chaos-Error / X-Chaos-Latency-Ms
+}
+
+// ChaosTargets lists the fault injection targets (see internal/chaos)
+var ChaosTargets = []string{"http", "postgres", "redis"}
+
+func loadChaosConfig(env *envReader) ChaosConfig {
+	return ChaosConfig{
+		Enabled:      env.Bool("CHAOS_ENABLED", false),
+		ErrorPercent: env.IntMap("CHAOS_ERROR_PERCENT"),
+		LatencyMS:    env.IntMap("CHAOS_LATENCY_MS"),
+		AllowHeaders: env.Bool("CHAOS_HEADERS", false),
+	}
+}
+
+// Validate checks the fault injection settings
+func (c ChaosConfig) Validate() error {
+	var errs []error
+	for target, pct := range c.ErrorPercent {
+		if !contains(ChaosTargets, target) {
+			errs = append(errs, fmt.Errorf("CHAOS_ERROR_PERCENT: unknown target %q (want one of %v)", target, ChaosTargets))
+		} else if pct < 0 || pct > 100 {
+			errs = append(errs, fmt.Errorf("CHAOS_ERROR_PERCENT: %s must be between 0 and 100, got %d", target, pct))
+		}
+	}
+	for target, ms := range c.LatencyMS {
+		if !contains(ChaosTargets, target) {
+			errs = append(errs, fmt.Errorf("CHAOS_LATENCY_MS: unknown target %q (want one of %v)", target, ChaosTargets))
+		} else if ms < 0 {
+			errs = append(errs, fmt.Errorf("CHAOS_LATENCY_MS: %s must not be negative, got %d", target, ms))
+		}
+	}
+	return validationErrors(errs)
+}
+
+// EmailConfig configures outgoing email. Without an SMTP host, messages are
+// only logged (development).
+type EmailConfig struct {
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string // Optional; PLAIN auth is used when set
+	SMTPPassword string
+	From         string
+}
+
+func loadEmailConfig(env *envReader) EmailConfig {
+	return EmailConfig{
+		SMTPHost:     env.String("SMTP_HOST", ""),
+		SMTPPort:     env.String("SMTP_PORT", "587"),
+		SMTPUsername: env.String("SMTP_USERNAME", ""),
+		SMTPPassword: env.String("SMTP_PASSWORD", ""),
+		From:         env.String("EMAIL_FROM", "noreply@localhost"),
+	}
+}
+
+// Validate checks the email settings
+func (c EmailConfig) Validate() error {
+	var errs []error
+	if c.SMTPHost != "" {
+		if port, err := strconv.Atoi(c.SMTPPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("invalid SMTP_PORT: %s (must be 1-65535)", c.SMTPPort))
+		}
+	}
+	if (c.SMTPUsername == "") != (c.SMTPPassword == "") {
+		errs = append(errs, fmt.Errorf("SMTP_USERNAME and SMTP_PASSWORD must be set together"))
+	}
+	if c.From != "" && !strings.Contains(c.From, "@") {
+		errs = append(errs, fmt.Errorf("invalid EMAIL_FROM: %q (must be an email address)", c.From))
+	}
+	return validationErrors(errs)
+}
+
+// FeatureFlagsConfig selects where feature flag definitions come from
+type FeatureFlagsConfig struct {
+	Provider        string         // "env", "file" or "redis"
+	Rollouts        map[string]int // env provider: percent of users per flag, e.g. "new_order_flow=25,beta_ui=100"
+	File            string         // file provider: JSON or YAML flag definitions
+	RefreshInterval time.Duration  // file and redis providers: how often definitions are re-read
+}
+
+// DefaultFeatureFlagsConfig returns the settings used when no env vars are set
+func DefaultFeatureFlagsConfig() FeatureFlagsConfig {
+	return FeatureFlagsConfig{
+		Provider:        "env",
+		RefreshInterval: 10 * time.Second,
+	}
+}
+
+func loadFeatureFlagsConfig(env *envReader) FeatureFlagsConfig {
+	def := DefaultFeatureFlagsConfig()
+	return FeatureFlagsConfig{
+		Provider:        env.String("FEATURE_FLAGS_PROVIDER", def.Provider),
+		Rollouts:        env.IntMap("FEATURE_FLAGS"),
+		File:            env.String("FEATURE_FLAGS_FILE", ""),
+		RefreshInterval: env.Duration("FEATURE_FLAGS_REFRESH_INTERVAL", def.RefreshInterval),
+	}
+}
+
+// Validate checks the feature flag settings
+func (c FeatureFlagsConfig) Validate() error {
+	var errs []error
+	switch c.Provider {
+	case "", "env", "redis":
+	case "file":
+		if c.File == "" {
+			errs = append(errs, fmt.Errorf("FEATURE_FLAGS_PROVIDER=file requires FEATURE_FLAGS_FILE"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid FEATURE_FLAGS_PROVIDER: %s (must be env, file or redis)", c.Provider))
+	}
+	for key, rollout := range c.Rollouts {
+		if rollout < 0 || rollout > 100 {
+			errs = append(errs, fmt.Errorf("FEATURE_FLAGS: rollout for %s must be between 0 and 100", key))
+		}
+	}
+	if c.RefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("FEATURE_FLAGS_REFRESH_INTERVAL must not be negative"))
+	}
+	return validationErrors(errs)
+}
+
+// validationErrors returns a *ValidationError for errs, or nil if empty
+func validationErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
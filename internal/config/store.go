@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// runtimeTunableFields lists the Config fields that can change without a restart,
+// using dotted paths for fields inside subsystem sections. Everything else
+// (ports, DSNs, pool sizes, secrets) is structural and is only picked up on
+// the next process start.
+var runtimeTunableFields = map[string]bool{
+	"LogLevel":                  true,
+	"HTTP.RateLimitPerMinute":   true,
+	"HTTP.RateLimitClasses":     true,
+	"HTTP.RateLimitRoutes":      true,
+	"HTTP.RateLimitTiers":       true,
+	"HTTP.RateLimitTierMembers": true,
+	"HTTP.AllowedOrigins":       true,
+	"Chaos.ErrorPercent":        true,
+	"Chaos.LatencyMS":           true,
+}
+
+// ChangeFunc is notified after a reload applied new runtime settings
+type ChangeFunc func(old, new *Config)
+
+// Store holds the current configuration and reloads runtime-tunable settings
+// on demand (SIGHUP or CONFIG_FILE changes), notifying subscribers of changes.
+//
+// Example:
+//
+//	store := config.NewStore(cfg)
+//	store.Subscribe(func(old, new *config.Config) {
+//		logg.SetLevel(new.LogLevel)
+//	})
+//	go store.Watch(ctx, onReload)
+type Store struct {
+	current atomic.Pointer[Config]
+	load    func() (*Config, error)
+
+	mu          sync.Mutex // Serializes reloads and protects subscribers
+	subscribers []ChangeFunc
+}
+
+// NewStore creates a store seeded with the initial configuration
+func NewStore(cfg *Config) *Store {
+	s := &Store{load: Load}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get returns the current configuration
+// The returned value must be treated as read-only
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Subscribe registers fn to be called after every reload that changes a runtime setting
+func (s *Store) Subscribe(fn ChangeFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Reload re-reads the environment and CONFIG_FILE and applies runtime-tunable settings.
+// Invalid configuration leaves the current settings untouched.
+// Returns the names of structural fields that changed but require a restart.
+func (s *Store) Reload() (ignored []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	old := s.Get()
+	merged, changed, ignored := mergeRuntime(old, next)
+	if !changed {
+		return ignored, nil
+	}
+
+	s.current.Store(merged)
+	for _, fn := range s.subscribers {
+		fn(old, merged)
+	}
+
+	return ignored, nil
+}
+
+// Watch reloads on SIGHUP and, if CONFIG_FILE and CONFIG_WATCH_INTERVAL are set,
+// whenever the file's modification time changes. onReload receives the outcome
+// of every reload attempt. Blocks until ctx is cancelled.
+func (s *Store) Watch(ctx context.Context, onReload func(ignored []string, err error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	cfg := s.Get()
+	var poll <-chan time.Time
+	var lastMod time.Time
+	if cfg.ConfigFile != "" && cfg.ConfigWatchInterval > 0 {
+		ticker := time.NewTicker(cfg.ConfigWatchInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+		lastMod = modTime(cfg.ConfigFile)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			onReload(s.Reload())
+		case <-poll:
+			if mod := modTime(cfg.ConfigFile); !mod.Equal(lastMod) {
+				lastMod = mod
+				onReload(s.Reload())
+			}
+		}
+	}
+}
+
+// mergeRuntime copies runtime-tunable fields from next onto a copy of old.
+// It reports whether any runtime field changed and which structural fields
+// differ (and were therefore ignored), recursing into subsystem sections.
+func mergeRuntime(old, next *Config) (*Config, bool, []string) {
+	merged := *old
+	changed, ignored := mergeFields(
+		reflect.ValueOf(&merged).Elem(),
+		reflect.ValueOf(old).Elem(),
+		reflect.ValueOf(next).Elem(),
+		"",
+	)
+	return &merged, changed, ignored
+}
+
+// mergeFields applies runtime-tunable differences between ov and nv onto mv
+func mergeFields(mv, ov, nv reflect.Value, prefix string) (bool, []string) {
+	changed := false
+	var ignored []string
+	for i := 0; i < mv.NumField(); i++ {
+		name := prefix + mv.Type().Field(i).Name
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		if mv.Field(i).Kind() == reflect.Struct {
+			c, ign := mergeFields(mv.Field(i), ov.Field(i), nv.Field(i), name+".")
+			changed = changed || c
+			ignored = append(ignored, ign...)
+			continue
+		}
+		if runtimeTunableFields[name] {
+			mv.Field(i).Set(nv.Field(i))
+			changed = true
+		} else {
+			ignored = append(ignored, name)
+		}
+	}
+	return changed, ignored
+}
+
+// modTime returns the file's modification time, or the zero time if it can't be read
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,82 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// CacheStats is a snapshot of a cache's lookup counters
+type CacheStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Errors   uint64  `json:"errors"` // Lookups that failed, e.g. Redis unreachable
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// CacheCounters counts lookups on an instrumented cache
+type CacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+// Stats returns the counters and the hit ratio of successful lookups
+func (c *CacheCounters) Stats() CacheStats {
+	s := CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+func (c *CacheCounters) observe(err error) {
+	switch {
+	case err == nil:
+		c.hits.Add(1)
+	case errors.Is(err, domain.ErrCacheMiss):
+		c.misses.Add(1)
+	default:
+		c.errors.Add(1)
+	}
+}
+
+// InstrumentUserCache wraps cache so its lookups are counted
+func InstrumentUserCache(cache domain.UserCache) (domain.UserCache, *CacheCounters) {
+	counters := &CacheCounters{}
+	return &userCache{UserCache: cache, counters: counters}, counters
+}
+
+type userCache struct {
+	domain.UserCache
+	counters *CacheCounters
+}
+
+func (c *userCache) Get(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := c.UserCache.Get(ctx, userID)
+	c.counters.observe(err)
+	return user, err
+}
+
+// InstrumentOrderCache wraps cache so its lookups are counted
+func InstrumentOrderCache(cache domain.OrderCache) (domain.OrderCache, *CacheCounters) {
+	counters := &CacheCounters{}
+	return &orderCache{OrderCache: cache, counters: counters}, counters
+}
+
+type orderCache struct {
+	domain.OrderCache
+	counters *CacheCounters
+}
+
+func (c *orderCache) Get(ctx context.Context, orderID string) (*domain.Order, error) {
+	order, err := c.OrderCache.Get(ctx, orderID)
+	c.counters.observe(err)
+	return order, err
+}
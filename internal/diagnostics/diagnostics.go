@@ -0,0 +1,181 @@
+// Package diagnostics captures a point-in-time report of a running instance:
+// goroutine stacks, memory, connection pools, in-flight requests and cache
+// hit rates. It exists to debug an instance that is stuck or degraded without
+// restarting it (which would destroy the evidence).
+//
+// A dump is taken on SIGQUIT (see Watch) or from the admin endpoint and
+// written to a Sink: the application log, or the blob store when dumps are
+// too large to log or must outlive the instance.
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Dump reasons
+const (
+	ReasonSignal = "signal" // SIGQUIT
+	ReasonAdmin  = "admin"  // POST /api/admin/diagnostics
+	ReasonStats  = "stats"  // Summary without stacks (see Stats)
+)
+
+// MemoryStats is the subset of runtime.MemStats useful when debugging
+type MemoryStats struct {
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	HeapObjects    uint64 `json:"heap_objects"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	GCPauseTotalMS int64  `json:"gc_pause_total_ms"`
+}
+
+// Dump is one diagnostics snapshot
+type Dump struct {
+	CapturedAt time.Time      `json:"captured_at"`
+	Reason     string         `json:"reason"`
+	Hostname   string         `json:"hostname"`
+	Version    string         `json:"version"`
+	Goroutines int            `json:"goroutines"`
+	Memory     MemoryStats    `json:"memory"`
+	Sections   map[string]any `json:"sections"`         // Registered sections by name
+	Stacks     string         `json:"stacks,omitempty"` // Every goroutine's stack, as printed by a crash
+}
+
+type section struct {
+	name string
+	fn   func() any
+}
+
+// Collector assembles dumps from the runtime and registered sections.
+// It is safe for concurrent use.
+type Collector struct {
+	hostname string
+	version  string
+	now      func() time.Time
+
+	mu       sync.Mutex
+	sections []section
+}
+
+// NewCollector creates a collector reporting version (the release being run)
+func NewCollector(version string) *Collector {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return &Collector{
+		hostname: hostname,
+		version:  version,
+		now:      time.Now,
+	}
+}
+
+// Register adds a section, e.g. a connection pool's stats. fn is called on
+// every capture, possibly while the instance is in trouble, so it must be
+// quick and must not block on the network. Registering a name again
+// replaces the earlier section.
+func (c *Collector) Register(name string, fn func() any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.sections {
+		if c.sections[i].name == name {
+			c.sections[i].fn = fn
+			return
+		}
+	}
+	c.sections = append(c.sections, section{name: name, fn: fn})
+}
+
+// Capture takes a dump. A section that panics is reported as an error
+// instead of taking the rest of the dump down with it.
+func (c *Collector) Capture(reason string) *Dump {
+	d := c.summary(reason)
+	var stacks bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&stacks, 2); err != nil {
+		fmt.Fprintf(&stacks, "failed to write goroutine stacks: %v", err)
+	}
+	d.Stacks = stacks.String()
+	return d
+}
+
+// Stats is a dump without goroutine stacks, cheap enough to poll
+func (c *Collector) Stats() *Dump {
+	return c.summary(ReasonStats)
+}
+
+func (c *Collector) summary(reason string) *Dump {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	d := &Dump{
+		CapturedAt: c.now().UTC(),
+		Reason:     reason,
+		Hostname:   c.hostname,
+		Version:    c.version,
+		Goroutines: runtime.NumGoroutine(),
+		Memory: MemoryStats{
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapInuseBytes: mem.HeapInuse,
+			HeapObjects:    mem.HeapObjects,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+			GCPauseTotalMS: time.Duration(mem.PauseTotalNs).Milliseconds(),
+		},
+		Sections: make(map[string]any),
+	}
+
+	c.mu.Lock()
+	sections := append([]section(nil), c.sections...)
+	c.mu.Unlock()
+	for _, s := range sections {
+		d.Sections[s.name] = collect(s.fn)
+	}
+	return d
+}
+
+// collect calls fn, converting a panic into an error entry
+func collect(fn func() any) (v any) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = map[string]string{"error": fmt.Sprint(r)}
+		}
+	}()
+	return fn()
+}
+
+// Watch takes a dump and writes it to sink on every SIGQUIT until ctx is
+// cancelled. While it runs, SIGQUIT no longer kills the process with a stack
+// trace (Go's default): the instance keeps serving, so it can be dumped
+// repeatedly to see what changes. Once Watch returns the default is restored.
+func (c *Collector) Watch(ctx context.Context, sink Sink, logg *logger.Logger) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGQUIT)
+	defer signal.Stop(quit)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-quit:
+			writeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			location, err := sink.Write(writeCtx, c.Capture(ReasonSignal))
+			cancel()
+			if err != nil {
+				logg.Error("failed to write diagnostics dump", "error", err)
+				continue
+			}
+			logg.Info("diagnostics dump written", "location", location)
+		}
+	}
+}
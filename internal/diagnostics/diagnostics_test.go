@@ -0,0 +1,53 @@
+package diagnostics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+)
+
+func TestCapture(t *testing.T) {
+	c := NewCollector("1.2.3")
+	c.Register("pool", func() any { return map[string]int{"idle": 1} })
+	c.Register("pool", func() any { return map[string]int{"idle": 2} })
+	c.Register("broken", func() any { panic("boom") })
+
+	d := c.Capture(ReasonAdmin)
+	if d.Reason != ReasonAdmin || d.Version != "1.2.3" || d.Hostname == "" {
+		t.Errorf("dump header = %q %q %q", d.Reason, d.Version, d.Hostname)
+	}
+	if d.Goroutines < 1 || !strings.Contains(d.Stacks, "TestCapture") {
+		t.Errorf("dump has %d goroutines and stacks without the test goroutine", d.Goroutines)
+	}
+	if got := d.Sections["pool"].(map[string]int)["idle"]; got != 2 {
+		t.Errorf("re-registered section idle = %d, want 2", got)
+	}
+	if got, ok := d.Sections["broken"].(map[string]string); !ok || got["error"] != "boom" {
+		t.Errorf("panicking section = %v, want an error entry", d.Sections["broken"])
+	}
+}
+
+func TestInstrumentUserCache(t *testing.T) {
+	ctx := context.Background()
+	cache, counters := InstrumentUserCache(memory.NewUserCache())
+
+	if _, err := cache.Get(ctx, "u1"); err != domain.ErrCacheMiss {
+		t.Fatalf("Get() on empty cache error = %v, want ErrCacheMiss", err)
+	}
+	if err := cache.Set(ctx, &domain.User{ID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := cache.Get(ctx, "u1"); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	s := counters.Stats()
+	if s.Hits != 3 || s.Misses != 1 || s.Errors != 0 || s.HitRatio != 0.75 {
+		t.Errorf("Stats() = %+v, want 3 hits, 1 miss, ratio 0.75", s)
+	}
+}
@@ -0,0 +1,70 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Sink stores a dump and returns where it went (for logs and API responses)
+type Sink interface {
+	Write(ctx context.Context, d *Dump) (string, error)
+}
+
+// LogSink writes dumps to the application log at warn level, so they reach
+// wherever logs are shipped without extra infrastructure
+type LogSink struct {
+	logg *logger.Logger
+}
+
+// NewLogSink creates a sink writing to logg
+func NewLogSink(logg *logger.Logger) *LogSink {
+	return &LogSink{logg: logg}
+}
+
+// Write logs the dump as one entry
+func (s *LogSink) Write(_ context.Context, d *Dump) (string, error) {
+	s.logg.Warn("diagnostics dump",
+		"reason", d.Reason,
+		"hostname", d.Hostname,
+		"version", d.Version,
+		"goroutines", d.Goroutines,
+		"memory", d.Memory,
+		"sections", d.Sections,
+		"stacks", d.Stacks,
+	)
+	return "log", nil
+}
+
+// BlobSink uploads dumps as JSON under prefix/<hostname>/<timestamp>.json
+type BlobSink struct {
+	store  blob.Store
+	prefix string
+}
+
+// NewBlobSink creates a sink uploading to store under prefix (e.g. "diagnostics/")
+func NewBlobSink(store blob.Store, prefix string) *BlobSink {
+	return &BlobSink{store: store, prefix: prefix}
+}
+
+// Write uploads the dump and returns its key
+func (s *BlobSink) Write(ctx context.Context, d *Dump) (string, error) {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode diagnostics dump: %w", err)
+	}
+	key := path.Join(s.prefix, d.Hostname, d.CapturedAt.Format("20060102T150405.000Z")+".json")
+	if _, err := s.store.Upload(ctx, &blob.UploadInput{
+		Key:         key,
+		Body:        bytes.NewReader(body),
+		ContentType: "application/json",
+	}); err != nil {
+		return "", fmt.Errorf("failed to upload diagnostics dump: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Personal access token scopes. Scopes are deliberately narrow: a token only
+// ever reaches the owner's own data, never administrative endpoints.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeOrdersRead = "orders:read"
+)
+
+// AccessTokenScopes lists the scopes a personal access token may be granted
+var AccessTokenScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeOrdersRead}
+
+// PersonalAccessToken is a long-lived, user-minted credential with restricted
+// scopes for integrations and scripting. Only a hash of the secret is stored.
+type PersonalAccessToken struct {
+	ID         string
+	UserID     string
+	Name       string
+	Scopes     []string
+	TokenHash  string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// AccessTokenRepository defines the contract for personal access token persistence
+// The domain defines the interface, infrastructure implements it
+type AccessTokenRepository interface {
+	Create(ctx context.Context, token *PersonalAccessToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*PersonalAccessToken, error)
+	ListByUser(ctx context.Context, userID string) ([]*PersonalAccessToken, error)
+	// Revoke marks the user's token revoked; returns ErrAccessTokenNotFound if it doesn't exist
+	Revoke(ctx context.Context, userID, tokenID string, at time.Time) error
+	// RevokeAllForUser marks every token of the user created at or before at
+	// revoked, keeping the revocation time of tokens already revoked
+	RevokeAllForUser(ctx context.Context, userID string, at time.Time) error
+	TouchLastUsed(ctx context.Context, tokenID string, at time.Time) error
+}
+
+// NewPersonalAccessToken creates a token with validation
+// Business rules: name required, at least one known scope, expiry in the future
+func NewPersonalAccessToken(id, userID, name string, scopes []string, tokenHash string, expiresAt time.Time) (*PersonalAccessToken, error) {
+	t := &PersonalAccessToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Scopes:    normalizeScopes(scopes),
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt.UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Validate ensures the token entity is in a valid state
+func (t *PersonalAccessToken) Validate() error {
+	if strings.TrimSpace(t.UserID) == "" {
+		return ErrInvalidUserID
+	}
+	if t.Name == "" || len(t.Name) > 100 {
+		return ErrInvalidInput
+	}
+	if len(t.Scopes) == 0 {
+		return ErrInvalidScope
+	}
+	for _, s := range t.Scopes {
+		if !isAccessTokenScope(s) {
+			return ErrInvalidScope
+		}
+	}
+	if !t.ExpiresAt.After(t.CreatedAt) {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// HasScope reports whether the token grants scope
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the token can authenticate at now
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+func isAccessTokenScope(scope string) bool {
+	for _, s := range AccessTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScopes trims and de-duplicates scopes, preserving order
+func normalizeScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		result = append(result, s)
+	}
+	return result
+}
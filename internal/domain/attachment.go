@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ScanStatus is the result of the malware scan of an uploaded attachment
+type ScanStatus string
+
+const (
+	ScanStatusPending  ScanStatus = "pending"  // Uploaded (or awaiting upload), not yet scanned
+	ScanStatusClean    ScanStatus = "clean"    // No threats found
+	ScanStatusInfected ScanStatus = "infected" // Threats found; downloads stay blocked
+)
+
+// Valid reports whether s is a known scan status
+func (s ScanStatus) Valid() bool {
+	switch s {
+	case ScanStatusPending, ScanStatusClean, ScanStatusInfected:
+		return true
+	default:
+		return false
+	}
+}
+
+// Attachment is a user-uploaded file stored in the blob store.
+// Uploads go straight to the store and the client then reports completion,
+// which is verified against the stored object (see CompleteUpload), or go
+// through the API resumably, a multipart upload at a time; a malware
+// scanner reports back (see RecordScan) and only completed, clean attachments
+// can be downloaded.
+type Attachment struct {
+	ID          string
+	OwnerID     string
+	Key         string // Blob store key
+	Filename    string
+	ContentType string
+	Size        int64 // Declared size in bytes, verified on completion
+	ScanStatus  ScanStatus
+	ScanDetail  string // Scanner verdict, e.g. the threat name
+	ScannedAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	// Set once the upload is verified against the stored object
+	UploadedAt     *time.Time
+	ETag           string // Blob store entity tag of the verified object
+	ChecksumSHA256 string // Hex SHA-256 the client reported and the server verified, if any
+
+	// UploadID is the blob store multipart upload of a resumable upload
+	// still in progress, empty otherwise
+	UploadID string
+}
+
+// AttachmentRepository defines the contract for attachment metadata persistence
+// The domain defines the interface, infrastructure implements it
+type AttachmentRepository interface {
+	Create(ctx context.Context, a *Attachment) error
+	GetByID(ctx context.Context, id string) (*Attachment, error)
+	GetByKey(ctx context.Context, key string) (*Attachment, error)
+	ListByOwner(ctx context.Context, ownerID string, limit, offset int) ([]*Attachment, error)
+	Update(ctx context.Context, a *Attachment) error
+	Delete(ctx context.Context, id string) error
+}
+
+var attachmentExtRegex = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// NewAttachment creates a pending attachment stored under keyPrefix
+// Business rule: attachments need an owner, a plain file name and a positive size
+func NewAttachment(id, ownerID, filename, contentType string, size int64, keyPrefix string) (*Attachment, error) {
+	filename = strings.TrimSpace(filename)
+	if ownerID == "" || filename == "" || size <= 0 {
+		return nil, ErrInvalidInput
+	}
+	// The name is shown to users and sent in Content-Disposition; it never
+	// becomes part of a path
+	if strings.ContainsAny(filename, "/\\\x00\r\n") || filename == "." || filename == ".." {
+		return nil, ErrInvalidInput
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Keys keep a plain extension (for content sniffing tools) but nothing else from the name
+	ext := strings.ToLower(path.Ext(filename))
+	if !attachmentExtRegex.MatchString(ext) {
+		ext = ""
+	}
+
+	now := time.Now().UTC()
+	return &Attachment{
+		ID:          id,
+		OwnerID:     ownerID,
+		Key:         keyPrefix + ownerID + "/" + id + ext,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		ScanStatus:  ScanStatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// RecordScan stores the scanner's verdict. An infected verdict is final: a
+// later "clean" report (e.g. a replayed callback) does not unblock the file.
+func (a *Attachment) RecordScan(status ScanStatus, detail string, at time.Time) error {
+	if !status.Valid() || status == ScanStatusPending {
+		return ErrInvalidScanStatus
+	}
+	if a.ScanStatus == ScanStatusInfected {
+		return nil
+	}
+	a.ScanStatus = status
+	a.ScanDetail = detail
+	a.ScannedAt = &at
+	a.UpdatedAt = at
+	return nil
+}
+
+// Resumable reports whether a resumable upload of the attachment is in progress
+func (a *Attachment) Resumable() bool {
+	return a.UploadID != "" && !a.Uploaded()
+}
+
+// Uploaded reports whether the upload was completed and verified
+func (a *Attachment) Uploaded() bool {
+	return a.UploadedAt != nil
+}
+
+// CompleteUpload records the stored object's details once the upload is
+// verified. The stored size must equal the declared size. Completing again
+// is a no-op so clients can retry the callback.
+func (a *Attachment) CompleteUpload(size int64, etag, checksum string, at time.Time) error {
+	if a.Uploaded() {
+		return nil
+	}
+	if size != a.Size {
+		return ErrAttachmentMismatch
+	}
+	a.ETag = etag
+	a.ChecksumSHA256 = checksum
+	a.UploadID = ""
+	a.UploadedAt = &at
+	a.UpdatedAt = at
+	return nil
+}
+
+// Downloadable returns nil if the attachment was uploaded and passed its
+// scan, or the reason it can't be downloaded
+func (a *Attachment) Downloadable() error {
+	if !a.Uploaded() {
+		return ErrAttachmentNotUploaded
+	}
+	switch a.ScanStatus {
+	case ScanStatusClean:
+		return nil
+	case ScanStatusInfected:
+		return ErrAttachmentInfected
+	default:
+		return ErrAttachmentScanPending
+	}
+}
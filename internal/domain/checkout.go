@@ -0,0 +1,43 @@
+package domain
+
+import "context"
+
+// PaymentCapture is a payment to collect for an order
+type PaymentCapture struct {
+	// IdempotencyKey identifies the capture: capturing again with the same
+	// key returns the first capture instead of charging twice
+	IdempotencyKey string
+	OrderID        string
+	UserID         string
+	Amount         int64  // In minor units (cents)
+	Currency       string // ISO 4217, such as "USD"
+}
+
+// PaymentGateway collects and refunds payments
+// The domain defines the interface, infrastructure implements it
+type PaymentGateway interface {
+	// Capture collects c and returns the payment ID; ErrPaymentDeclined when
+	// the payment is refused
+	Capture(ctx context.Context, c PaymentCapture) (paymentID string, err error)
+	// Refund refunds in full the capture made with idempotencyKey. Refunding
+	// twice, or a capture that never happened, does nothing.
+	Refund(ctx context.Context, idempotencyKey string) error
+}
+
+// Inventory holds stock for orders. Only products given a stock level are
+// tracked: items of other products are always available.
+// The domain defines the interface, infrastructure implements it
+type Inventory interface {
+	// Reserve takes the quantities of items out of stock, all or none;
+	// ErrInsufficientStock when a product has too few. Reserving again under
+	// the same reservationID does nothing.
+	Reserve(ctx context.Context, reservationID string, items []OrderItem) error
+	// Release puts the stock of a reservation back. Releasing twice, or a
+	// reservation that never happened, does nothing.
+	Release(ctx context.Context, reservationID string) error
+	// Stock returns the available quantity of productID; false when the
+	// product is not tracked
+	Stock(ctx context.Context, productID string) (int, bool, error)
+	// SetStock sets the available quantity of productID, tracking it
+	SetStock(ctx context.Context, productID string, available int) error
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// CloudEvents constants for events published to other services
+const (
+	CloudEventsSpecVersion = "1.0"
+	CloudEventsContentType = "application/cloudevents+json" // Structured mode
+	DefaultEventSource     = "/stdlib-golang-api"
+)
+
+// CloudEvent is how an event travels between services: a CloudEvents 1.0
+// event (https://cloudevents.io) in structured JSON mode. The event type is
+// the CloudEvents type, its aggregate ID the subject and its payload schema
+// version the schemaversion extension attribute.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"` // URI reference of the publishing service
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time,omitzero"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	SchemaVersion   int             `json:"schemaversion"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// MarshalCloudEvent encodes event as a CloudEvent published by source, or
+// by DefaultEventSource when it is empty
+func MarshalCloudEvent(event Event, source string) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s event: %w", event.EventType(), err)
+	}
+	if source == "" {
+		source = DefaultEventSource
+	}
+	meta := event.Metadata()
+	return json.Marshal(CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              meta.ID,
+		Source:          source,
+		Type:            event.EventType(),
+		Subject:         event.AggregateID(),
+		Time:            meta.OccurredAt.UTC(),
+		DataContentType: "application/json",
+		SchemaVersion:   EventSchemaVersion(event.EventType()),
+		Data:            data,
+	})
+}
+
+// UnmarshalCloudEvent decodes an event encoded by MarshalCloudEvent, or by
+// releases before CloudEvents ({"type", "schema_version", "data"}), so
+// messages still queued from them are read. Schema versions newer than this
+// build knows are rejected rather than half-read.
+func UnmarshalCloudEvent(b []byte) (Event, error) {
+	var in struct {
+		CloudEvent
+		LegacySchemaVersion int             `json:"schema_version"`
+		DataBase64          json.RawMessage `json:"data_base64"`
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return nil, fmt.Errorf("%w: malformed event: %v", ErrInvalidInput, err)
+	}
+	version := in.SchemaVersion
+	if in.SpecVersion == "" {
+		version = in.LegacySchemaVersion
+	} else {
+		if in.SpecVersion != CloudEventsSpecVersion {
+			return nil, fmt.Errorf("%w: unsupported CloudEvents specversion %q", ErrInvalidInput, in.SpecVersion)
+		}
+		if in.Data == nil && in.DataBase64 != nil {
+			return nil, fmt.Errorf("%w: %s event with binary data", ErrInvalidInput, in.Type)
+		}
+		if !isJSONContentType(in.DataContentType) {
+			return nil, fmt.Errorf("%w: %s event with %s data", ErrInvalidInput, in.Type, in.DataContentType)
+		}
+	}
+	if known := EventSchemaVersion(in.Type); known > 0 && version > known {
+		return nil, fmt.Errorf("%w: %s schema version %d, this build reads up to %d",
+			ErrInvalidInput, in.Type, version, known)
+	}
+	return DecodeEvent(in.Type, in.Data)
+}
+
+// isJSONContentType reports whether data of contentType is JSON; CloudEvents
+// data without a content type is JSON in structured mode
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
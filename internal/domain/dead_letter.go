@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Dead letter sources: the job runner, the outbox relay and the event consumers
+const (
+	DeadLetterJobs   = "jobs"   // Type is the job type, Payload the job's payload
+	DeadLetterOutbox = "outbox" // Type is the event type, Payload the event as stored in the outbox
+	DeadLetterNATS   = "nats"   // Type is the event type, Payload the CloudEvent
+	DeadLetterRedis  = "redis"  // Type is the event type, Payload the CloudEvent
+	DeadLetterSQS    = "sqs"    // Type is the event type when known, Payload the message body
+)
+
+// DeadLetter is a message that failed for good: a job out of attempts, or an
+// event out of deliveries. It is kept with what it takes to run it again,
+// so it can be inspected and replayed once the cause is fixed.
+type DeadLetter struct {
+	ID         int64 // Set when added
+	Source     string
+	Type       string
+	MessageID  string            // Job ID, event ID or SQS message ID
+	Payload    []byte            // As it was delivered
+	Attributes map[string]string // What else a replay needs, such as a job's priority and owner
+	Error      string            // Of the last attempt
+	Attempts   int
+	FailedAt   time.Time
+
+	Replays     int        // Successful replays
+	ReplayedAt  *time.Time // Of the last successful replay
+	ReplayError string     // Of the last replay, when it failed
+}
+
+// Replayed reports whether the dead letter was replayed successfully
+func (d *DeadLetter) Replayed() bool {
+	return d.ReplayedAt != nil
+}
+
+// Clone returns a deep copy of d
+func (d *DeadLetter) Clone() *DeadLetter {
+	c := *d
+	c.Payload = slices.Clone(d.Payload)
+	c.Attributes = maps.Clone(d.Attributes)
+	if d.ReplayedAt != nil {
+		at := *d.ReplayedAt
+		c.ReplayedAt = &at
+	}
+	return &c
+}
+
+// DeadLetterFilter selects dead letters; zero fields match every one
+type DeadLetterFilter struct {
+	Source   string
+	Type     string
+	Replayed *bool
+	Limit    int // 0 for no limit
+	Offset   int
+}
+
+// DeadLetterQueue stores the messages consumers gave up on
+// The domain defines the interface, infrastructure implements it
+type DeadLetterQueue interface {
+	// Add stores d and sets its ID
+	Add(ctx context.Context, d *DeadLetter) error
+	Get(ctx context.Context, id int64) (*DeadLetter, error)
+	// List returns the dead letters matching filter, newest first
+	List(ctx context.Context, filter DeadLetterFilter) ([]*DeadLetter, error)
+	// Update stores the outcome of a replay of d
+	Update(ctx context.Context, d *DeadLetter) error
+	Delete(ctx context.Context, id int64) error
+	// Purge deletes the dead letters that failed before cutoff and returns how many
+	Purge(ctx context.Context, cutoff time.Time) (int, error)
+}
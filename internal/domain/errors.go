@@ -17,6 +17,10 @@ var (
 	ErrInvalidOrderAmount     = errors.New("invalid order amount")
 	ErrOrderCannotBeCancelled = errors.New("order cannot be cancelled")
 
+	// Personal access token errors
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrInvalidScope        = errors.New("invalid scope")
+
 	// Generic errors
 	ErrInvalidInput  = errors.New("invalid input")
 	ErrUnauthorized  = errors.New("unauthorized")
@@ -25,14 +29,53 @@ var (
 	ErrDatabaseError = errors.New("database error")
 	ErrConflict      = errors.New("resource conflict")
 
+	// Optimistic concurrency errors
+	ErrVersionMismatch = errors.New("resource changed since the version it was read at")
+
 	// Cache errors
 	ErrCacheMiss = errors.New("cache miss")
 
-	// Blob storage errors
-	ErrBlobNotFound       = errors.New("blob not found")
-	ErrBlobAlreadyExists  = errors.New("blob already exists")
-	ErrBlobUploadFailed   = errors.New("blob upload failed")
-	ErrBlobDownloadFailed = errors.New("blob download failed")
-	ErrBlobDeleteFailed   = errors.New("blob delete failed")
-	ErrInvalidBlobKey     = errors.New("invalid blob key")
+	// Report errors
+	ErrReportScheduleNotFound = errors.New("report schedule not found")
+	ErrUnknownReport          = errors.New("unknown report")
+	ErrInvalidSchedule        = errors.New("invalid schedule")
+
+	// Attachment errors
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrAttachmentScanPending = errors.New("attachment has not been scanned yet")
+	ErrAttachmentInfected    = errors.New("attachment failed the malware scan")
+	ErrInvalidScanStatus     = errors.New("invalid scan status")
+	ErrAttachmentNotUploaded = errors.New("attachment upload has not been completed")
+	ErrAttachmentMismatch    = errors.New("uploaded object does not match the attachment")
+	ErrUploadOffsetMismatch  = errors.New("upload offset does not match the bytes received")
+	ErrUploadLocked          = errors.New("upload is receiving another request")
+
+	// Concurrency errors
+	ErrSemaphoreFull = errors.New("too many concurrent operations")
+
+	// Job errors
+	ErrJobNotFound        = errors.New("job not found")
+	ErrInvalidJobPriority = errors.New("invalid job priority")
+	ErrUnknownJobType     = errors.New("unknown job type")
+	ErrJobNotFinished     = errors.New("job has not finished successfully")
+
+	// Order event errors
+	ErrInvalidEventID     = errors.New("invalid event id")
+	ErrOrderEventsExpired = errors.New("order events are no longer retained")
+
+	// Notification errors
+	ErrNotificationPreferencesNotFound = errors.New("notification preferences not found")
+	ErrInvalidNotificationPreferences  = errors.New("invalid notification preferences")
+	ErrNotificationChannelUnavailable  = errors.New("notification channel unavailable")
+
+	// Saga and checkout errors
+	ErrSagaNotFound      = errors.New("saga not found")
+	ErrSagaAlreadyExists = errors.New("saga already exists")
+	ErrCheckoutFailed    = errors.New("checkout failed")
+	ErrPaymentDeclined   = errors.New("payment declined")
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// Dead letter errors
+	ErrDeadLetterNotFound      = errors.New("dead letter not found")
+	ErrDeadLetterNotReplayable = errors.New("dead letter cannot be replayed on this instance")
 )
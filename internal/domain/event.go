@@ -0,0 +1,225 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event is something that happened in the domain, named in the past tense.
+// Each event type is its own struct, so subscribers get typed fields
+// rather than a status to decode. Its JSON encoding is its payload schema,
+// registered with its version in eventSchemas.
+type Event interface {
+	// EventType names the kind of event ("order.created")
+	EventType() string
+	// AggregateID identifies the entity the event happened to; events of
+	// one aggregate are published in the order they happened
+	AggregateID() string
+	// Metadata returns what every event carries
+	Metadata() EventMetadata
+}
+
+// EventMetadata identifies one occurrence of an event; event types embed it
+type EventMetadata struct {
+	ID         string    `json:"id"` // Unique per event
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Metadata returns m, so event types embedding it implement Event.Metadata
+func (m EventMetadata) Metadata() EventMetadata {
+	return m
+}
+
+// EventHandler reacts to a published event
+type EventHandler func(ctx context.Context, event Event) error
+
+// EventBus delivers domain events to the handlers subscribed to them, so
+// side effects such as webhooks, emails and analytics follow business
+// operations without the use cases knowing about them
+// The domain defines the interface, infrastructure implements it
+type EventBus interface {
+	// Publish delivers event to every handler subscribed to its type. Every
+	// handler runs even when some fail; their errors are returned joined.
+	Publish(ctx context.Context, event Event) error
+	// Subscribe calls handler for events of the given types, or of every
+	// type when none are given, until unsubscribe is called
+	Subscribe(handler EventHandler, eventTypes ...string) (unsubscribe func())
+}
+
+// Order lifecycle event types
+const (
+	EventOrderCreated   = "order.created"
+	EventOrderConfirmed = "order.confirmed"
+	EventOrderShipped   = "order.shipped"
+	EventOrderDelivered = "order.delivered"
+	EventOrderCancelled = "order.cancelled"
+)
+
+// eventSchema is the registered payload schema of an event type
+type eventSchema struct {
+	eventType string // Also the CloudEvents type
+	version   int    // Adding a field keeps it; renaming or removing one, or changing its meaning, bumps it
+	decode    func(payload []byte) (Event, error)
+}
+
+// eventSchemas is the registry of event types, by type. Consumers read the
+// version from each published event (see MarshalCloudEvent) and refuse
+// versions newer than they know.
+var eventSchemas = registerEventSchemas(
+	schemaOf[OrderCreated](EventOrderCreated, 1),
+	schemaOf[OrderConfirmed](EventOrderConfirmed, 1),
+	schemaOf[OrderShipped](EventOrderShipped, 1),
+	schemaOf[OrderDelivered](EventOrderDelivered, 1),
+	schemaOf[OrderCancelled](EventOrderCancelled, 1),
+)
+
+func schemaOf[E Event](eventType string, version int) eventSchema {
+	return eventSchema{eventType: eventType, version: version, decode: func(payload []byte) (Event, error) {
+		var event E
+		err := json.Unmarshal(payload, &event)
+		return event, err
+	}}
+}
+
+func registerEventSchemas(schemas ...eventSchema) map[string]eventSchema {
+	registry := make(map[string]eventSchema, len(schemas))
+	for _, schema := range schemas {
+		registry[schema.eventType] = schema
+	}
+	return registry
+}
+
+// EventSchemaVersion returns the payload schema version of eventType, 0
+// for unknown types
+func EventSchemaVersion(eventType string) int {
+	return eventSchemas[eventType].version
+}
+
+// OrderCreated is emitted when an order is placed
+type OrderCreated struct {
+	EventMetadata
+	OrderID string           `json:"order_id"`
+	UserID  string           `json:"user_id"`
+	Amount  float64          `json:"amount"`
+	Items   []OrderEventItem `json:"items"`
+}
+
+// OrderEventItem is an order line as events carry it
+type OrderEventItem struct {
+	ProductID string  `json:"product_id"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+func (e OrderCreated) EventType() string {
+	return EventOrderCreated
+}
+
+func (e OrderCreated) AggregateID() string {
+	return e.OrderID
+}
+
+// OrderConfirmed is emitted when a pending order is confirmed
+type OrderConfirmed struct {
+	EventMetadata
+	OrderID string `json:"order_id"`
+	UserID  string `json:"user_id"`
+}
+
+func (e OrderConfirmed) EventType() string {
+	return EventOrderConfirmed
+}
+
+func (e OrderConfirmed) AggregateID() string {
+	return e.OrderID
+}
+
+// OrderShipped is emitted when a confirmed order ships
+type OrderShipped struct {
+	EventMetadata
+	OrderID string `json:"order_id"`
+	UserID  string `json:"user_id"`
+}
+
+func (e OrderShipped) EventType() string {
+	return EventOrderShipped
+}
+
+func (e OrderShipped) AggregateID() string {
+	return e.OrderID
+}
+
+// OrderDelivered is emitted when a shipped order is delivered
+type OrderDelivered struct {
+	EventMetadata
+	OrderID string `json:"order_id"`
+	UserID  string `json:"user_id"`
+}
+
+func (e OrderDelivered) EventType() string {
+	return EventOrderDelivered
+}
+
+func (e OrderDelivered) AggregateID() string {
+	return e.OrderID
+}
+
+// OrderCancelled is emitted when an order is cancelled
+type OrderCancelled struct {
+	EventMetadata
+	OrderID        string      `json:"order_id"`
+	UserID         string      `json:"user_id"`
+	PreviousStatus OrderStatus `json:"previous_status"` // The status it was cancelled from
+	Amount         float64     `json:"amount"`          // To refund, if it was paid
+}
+
+func (e OrderCancelled) EventType() string {
+	return EventOrderCancelled
+}
+
+func (e OrderCancelled) AggregateID() string {
+	return e.OrderID
+}
+
+// NewOrderLifecycleEvent returns the event announcing that order, stored
+// just now, was created (previous is empty) or moved from previous to its
+// current status. Reports false for statuses no event announces.
+func NewOrderLifecycleEvent(id string, order *Order, previous OrderStatus) (Event, bool) {
+	meta := EventMetadata{ID: id, OccurredAt: order.UpdatedAt}
+	if previous == "" {
+		items := make([]OrderEventItem, len(order.Items))
+		for i, item := range order.Items {
+			items[i] = OrderEventItem{ProductID: item.ProductID, Quantity: item.Quantity, Price: item.Price}
+		}
+		return OrderCreated{EventMetadata: meta, OrderID: order.ID, UserID: order.UserID,
+			Amount: order.Amount, Items: items}, true
+	}
+	switch order.Status {
+	case OrderStatusConfirmed:
+		return OrderConfirmed{EventMetadata: meta, OrderID: order.ID, UserID: order.UserID}, true
+	case OrderStatusShipped:
+		return OrderShipped{EventMetadata: meta, OrderID: order.ID, UserID: order.UserID}, true
+	case OrderStatusDelivered:
+		return OrderDelivered{EventMetadata: meta, OrderID: order.ID, UserID: order.UserID}, true
+	case OrderStatusCancelled:
+		return OrderCancelled{EventMetadata: meta, OrderID: order.ID, UserID: order.UserID,
+			PreviousStatus: previous, Amount: order.Amount}, true
+	}
+	return nil, false
+}
+
+// DecodeEvent rebuilds an event of eventType from its JSON encoding, for
+// events read back from storage such as the outbox
+func DecodeEvent(eventType string, payload []byte) (Event, error) {
+	schema, ok := eventSchemas[eventType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidInput, eventType)
+	}
+	event, err := schema.decode(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed %s event: %v", ErrInvalidInput, eventType, err)
+	}
+	return event, nil
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRecord is what is remembered of a request sent with an
+// idempotency key: the request's fingerprint and, once it is done, its
+// response, replayed to retries of the same request
+type IdempotencyRecord struct {
+	Fingerprint string              // Hash of the request, to catch a key reused for another request
+	Completed   bool                // False while the first request is still being served
+	Status      int                 // Response status code
+	Header      map[string][]string // Response headers the handler set
+	Body        []byte              // Response body
+}
+
+// IdempotencyStore defines the contract for remembering requests by idempotency key
+// The domain defines the interface, infrastructure implements it
+//
+// A key is reserved while its first request runs and holds the response
+// afterwards. Reservations expire, so a crashed replica can't hold a key
+// forever.
+type IdempotencyStore interface {
+	// Reserve claims key for a request with fingerprint for lockTTL. Returns
+	// nil once claimed, or the record already stored under key.
+	Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*IdempotencyRecord, error)
+	// Complete stores the response of the request holding key for ttl
+	Complete(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Release forgets key, so the request can be tried again
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// JobPriority orders background jobs. Each priority is queued separately.
+type JobPriority string
+
+const (
+	JobPriorityCritical JobPriority = "critical" // Time-sensitive, e.g. notifications
+	JobPriorityDefault  JobPriority = "default"
+	JobPriorityBulk     JobPriority = "bulk" // Large, deferrable work, e.g. exports
+)
+
+// JobPriorities lists every priority, highest first
+var JobPriorities = []JobPriority{JobPriorityCritical, JobPriorityDefault, JobPriorityBulk}
+
+// Valid reports whether p is a known priority
+func (p JobPriority) Valid() bool {
+	switch p {
+	case JobPriorityCritical, JobPriorityDefault, JobPriorityBulk:
+		return true
+	}
+	return false
+}
+
+// Job is a unit of background work. Type selects the handler; Payload is
+// the handler's JSON-encoded input.
+type Job struct {
+	ID         string
+	Type       string
+	Priority   JobPriority
+	Payload    json.RawMessage
+	UserID     string // Who enqueued the job (empty for system jobs)
+	Attempts   int    // Times the job has already run and failed
+	EnqueuedAt time.Time
+}
+
+// NewJob creates a job. An empty priority means JobPriorityDefault.
+func NewJob(id, jobType string, priority JobPriority, payload json.RawMessage) (*Job, error) {
+	if id == "" || jobType == "" {
+		return nil, ErrInvalidInput
+	}
+	if priority == "" {
+		priority = JobPriorityDefault
+	}
+	if !priority.Valid() {
+		return nil, ErrInvalidJobPriority
+	}
+
+	return &Job{
+		ID:         id,
+		Type:       jobType,
+		Priority:   priority,
+		Payload:    payload,
+		EnqueuedAt: time.Now(),
+	}, nil
+}
+
+// JobState is the lifecycle state of a job
+type JobState string
+
+const (
+	JobQueued    JobState = "queued" // Waiting for a worker (including between retries)
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed" // Gave up after the last attempt
+)
+
+// JobRecord is the persisted status of a job, kept after the job leaves the queue
+type JobRecord struct {
+	ID         string
+	Type       string
+	Priority   JobPriority
+	UserID     string
+	State      JobState
+	Progress   int    // Percent complete, 0-100
+	Result     string // Pointer to the job's output, e.g. a blob key (set on success)
+	Error      string // Last failure message
+	Attempts   int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	StartedAt  *time.Time // Latest attempt
+	FinishedAt *time.Time
+}
+
+// NewJobRecord creates the queued status record for job
+func NewJobRecord(job *Job) *JobRecord {
+	return &JobRecord{
+		ID:        job.ID,
+		Type:      job.Type,
+		Priority:  job.Priority,
+		UserID:    job.UserID,
+		State:     JobQueued,
+		CreatedAt: job.EnqueuedAt,
+		UpdatedAt: job.EnqueuedAt,
+	}
+}
+
+// Start marks an attempt as running
+func (r *JobRecord) Start(at time.Time) {
+	r.State = JobRunning
+	r.Progress = 0
+	r.StartedAt = &at
+	r.UpdatedAt = at
+}
+
+// SetProgress records percent complete, clamped to 0-100
+func (r *JobRecord) SetProgress(percent int, at time.Time) {
+	r.Progress = min(max(percent, 0), 100)
+	r.UpdatedAt = at
+}
+
+// Succeed marks the job done with a pointer to its output
+func (r *JobRecord) Succeed(result string, at time.Time) {
+	r.State = JobSucceeded
+	r.Progress = 100
+	r.Result = result
+	r.Error = ""
+	r.FinishedAt = &at
+	r.UpdatedAt = at
+}
+
+// Fail records a failed attempt. The job is queued again unless final.
+func (r *JobRecord) Fail(message string, attempts int, final bool, at time.Time) {
+	r.Error = message
+	r.Attempts = attempts
+	r.State = JobQueued
+	if final {
+		r.State = JobFailed
+		r.FinishedAt = &at
+	}
+	r.UpdatedAt = at
+}
+
+// JobRecordRepository defines the contract for job status persistence
+// The domain defines the interface, infrastructure implements it
+type JobRecordRepository interface {
+	Create(ctx context.Context, record *JobRecord) error
+	GetByID(ctx context.Context, id string) (*JobRecord, error)
+	Update(ctx context.Context, record *JobRecord) error
+}
+
+// JobQueue defines the contract for the background job queues, one FIFO per priority
+// The domain defines the interface, infrastructure implements it
+type JobQueue interface {
+	// Enqueue appends job to the queue for its priority
+	Enqueue(ctx context.Context, job *Job) error
+	// Dequeue removes and returns the oldest job of priority, or nil if there is none
+	Dequeue(ctx context.Context, priority JobPriority) (*Job, error)
+}
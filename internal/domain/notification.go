@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// NotificationChannel is a way of reaching a user
+type NotificationChannel string
+
+// Notification channels
+const (
+	NotificationEmail NotificationChannel = "email"
+	NotificationSMS   NotificationChannel = "sms"
+	NotificationPush  NotificationChannel = "push"
+	NotificationSlack NotificationChannel = "slack"
+)
+
+// NotificationChannels lists every channel
+var NotificationChannels = []NotificationChannel{NotificationEmail, NotificationSMS, NotificationPush, NotificationSlack}
+
+// Notification is a message to one user about one event, rendered for
+// every channel: email uses the subject, body and HTML, push the subject
+// as its title and the body, SMS and Slack the body alone
+type Notification struct {
+	UserID    string `json:"user_id"`
+	EventType string `json:"event_type"`
+	EventID   string `json:"event_id"` // Delivery services that deduplicate use it as the message ID
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`           // Plain text
+	HTML      string `json:"html,omitempty"` // Email alternative to Body; empty for plain text only
+}
+
+// Notifier delivers notifications on one channel, to an address in that
+// channel's form (see NotificationPreferences.Addresses)
+// The domain defines the interface, infrastructure implements it
+type Notifier interface {
+	Notify(ctx context.Context, address string, n Notification) error
+}
+
+// NotificationPreferences is how a user wants to be notified: where, and
+// on which channels for each event type
+type NotificationPreferences struct {
+	UserID string
+	// Addresses by channel: a phone number in E.164 form for sms, a device
+	// registration token for push, a Slack member ID for slack. Email goes
+	// to the user's email address unless one is set here.
+	Addresses map[NotificationChannel]string
+	// Events lists the channels of each event type; types missing use the
+	// service defaults, and an empty list mutes the type
+	Events    map[string][]NotificationChannel
+	UpdatedAt time.Time
+}
+
+var (
+	// e164 matches phone numbers in international E.164 form
+	e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	// slackMemberID matches Slack user and channel IDs
+	slackMemberID = regexp.MustCompile(`^[UWC][A-Z0-9]{6,}$`)
+)
+
+// Validate checks the channels, event types and addresses of p
+// Business rule: known channels and event types, addresses in their
+// channel's form, and an address for every channel an event uses other
+// than email
+func (p *NotificationPreferences) Validate() error {
+	for channel, address := range p.Addresses {
+		if err := validateNotificationAddress(channel, address); err != nil {
+			return err
+		}
+	}
+	for eventType, channels := range p.Events {
+		if EventSchemaVersion(eventType) == 0 {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidNotificationPreferences, eventType)
+		}
+		for _, channel := range channels {
+			if !slices.Contains(NotificationChannels, channel) {
+				return fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationPreferences, channel)
+			}
+			if channel != NotificationEmail && p.Addresses[channel] == "" {
+				return fmt.Errorf("%w: %s notifications need a %s address", ErrInvalidNotificationPreferences, eventType, channel)
+			}
+		}
+	}
+	return nil
+}
+
+func validateNotificationAddress(channel NotificationChannel, address string) error {
+	var ok bool
+	switch channel {
+	case NotificationEmail:
+		ok = IsValidEmail(address)
+	case NotificationSMS:
+		ok = e164.MatchString(address)
+	case NotificationPush:
+		ok = address != "" && len(address) <= 4096
+	case NotificationSlack:
+		ok = slackMemberID.MatchString(address)
+	default:
+		return fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationPreferences, channel)
+	}
+	if !ok {
+		return fmt.Errorf("%w: invalid %s address", ErrInvalidNotificationPreferences, channel)
+	}
+	return nil
+}
+
+// ChannelsFor returns the channels of eventType, or defaults when p does
+// not list it
+func (p *NotificationPreferences) ChannelsFor(eventType string, defaults []NotificationChannel) []NotificationChannel {
+	if channels, ok := p.Events[eventType]; ok {
+		return channels
+	}
+	return defaults
+}
+
+// Clone returns a deep copy of p
+func (p *NotificationPreferences) Clone() *NotificationPreferences {
+	c := *p
+	c.Addresses = maps.Clone(p.Addresses)
+	c.Events = make(map[string][]NotificationChannel, len(p.Events))
+	for eventType, channels := range p.Events {
+		c.Events[eventType] = slices.Clone(channels)
+	}
+	return &c
+}
+
+// NotificationPreferencesRepository defines the contract for notification
+// preference persistence
+// The domain defines the interface, infrastructure implements it
+type NotificationPreferencesRepository interface {
+	// Get returns ErrNotificationPreferencesNotFound for users who saved none
+	Get(ctx context.Context, userID string) (*NotificationPreferences, error)
+	// Save creates or replaces the preferences of p.UserID
+	Save(ctx context.Context, p *NotificationPreferences) error
+}
@@ -27,6 +27,7 @@ type Order struct {
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	CancelledAt *time.Time
+	Version     int64 // Starts at 1; every stored update bumps it
 }
 
 // OrderItem represents a single item in an order
@@ -36,15 +37,40 @@ type OrderItem struct {
 	Price     float64
 }
 
+// OrderPatch is a partial update of an order: nil fields are left unchanged
+type OrderPatch struct {
+	Status *OrderStatus // Reached through the status transition rules
+	Items  []OrderItem  // Replaces every item
+}
+
 // OrderRepository defines the contract for order persistence
 // The domain defines the interface, infrastructure implements it
 type OrderRepository interface {
 	GetByID(ctx context.Context, id string) (*Order, error)
 	GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*Order, error)
+	// GetByUserIDs fetches the newest limit orders of each user in one round
+	// trip, keyed by user ID; users without orders are absent
+	GetByUserIDs(ctx context.Context, userIDs []string, limit int) (map[string][]*Order, error)
 	Create(ctx context.Context, order *Order) error
 	Update(ctx context.Context, order *Order) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context, limit, offset int) ([]*Order, error)
+	// Search is List narrowed and ordered by q, whose fields are among the
+	// OrderQueryFields; other fields fail with ErrInvalidInput
+	Search(ctx context.Context, q ListQuery, limit, offset int) ([]*Order, error)
+	// Count returns how many orders match every filter; all of them without
+	// filters
+	Count(ctx context.Context, filters []Filter) (int, error)
+	// ListKeyset returns one keyset page of orders, newest first, for
+	// pagination that stays fast however deep it goes
+	ListKeyset(ctx context.Context, page KeysetPage) ([]*Order, error)
+	// Stream passes the orders List would return to fn one at a time, as
+	// they are read, so exports need not hold a whole result in memory; a
+	// limit of 0 streams every order. Stops at the first error from fn and
+	// returns it.
+	Stream(ctx context.Context, limit, offset int, fn func(*Order) error) error
+	// Summarize aggregates the orders created in [from, to)
+	Summarize(ctx context.Context, from, to time.Time) (*OrderSummary, error)
 }
 
 // OrderCache defines the contract for order caching
@@ -90,6 +116,7 @@ func NewOrder(id, userID string, items []OrderItem) (*Order, error) {
 		Items:     items,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
+		Version:   1,
 	}
 
 	if err := o.Validate(); err != nil {
@@ -127,7 +154,12 @@ func (o *Order) Validate() error {
 
 // IsValidStatus checks if the current status is valid
 func (o *Order) IsValidStatus() bool {
-	switch o.Status {
+	return o.Status.IsValid()
+}
+
+// IsValid reports whether s is one of the defined order statuses
+func (s OrderStatus) IsValid() bool {
+	switch s {
 	case OrderStatusPending, OrderStatusConfirmed, OrderStatusShipped,
 		OrderStatusDelivered, OrderStatusCancelled:
 		return true
@@ -182,6 +214,49 @@ func (o *Order) Cancel() error {
 	return nil
 }
 
+// TransitionTo moves the order to status through the transition rules of
+// Confirm, Ship, Deliver and Cancel; the current status is a no-op
+// Business rule: Orders never go back to pending
+func (o *Order) TransitionTo(status OrderStatus) error {
+	if status == o.Status {
+		return nil
+	}
+	switch status {
+	case OrderStatusConfirmed:
+		return o.Confirm()
+	case OrderStatusShipped:
+		return o.Ship()
+	case OrderStatusDelivered:
+		return o.Deliver()
+	case OrderStatusCancelled:
+		return o.Cancel()
+	default:
+		return ErrInvalidOrderStatus
+	}
+}
+
+// ReplaceItems replaces the order's items and recalculates its amount
+// Business rule: Only pending orders can change, and keep at least one item
+func (o *Order) ReplaceItems(items []OrderItem) error {
+	if o.Status != OrderStatusPending {
+		return ErrInvalidOrderStatus
+	}
+	if len(items) == 0 {
+		return ErrInvalidInput
+	}
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return ErrInvalidInput
+		}
+		if item.Price < 0 {
+			return ErrInvalidOrderAmount
+		}
+	}
+	o.Items = items
+	o.RecalculateAmount()
+	return nil
+}
+
 // IsCancellable returns whether the order can be cancelled
 func (o *Order) IsCancellable() bool {
 	return o.Status == OrderStatusPending || o.Status == OrderStatusConfirmed
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OrderEvent records an order entering a status
+type OrderEvent struct {
+	ID             string // Unique per event
+	OrderID        string
+	UserID         string
+	Status         OrderStatus
+	PreviousStatus OrderStatus // Empty when the order was created
+	OccurredAt     time.Time
+}
+
+// NewOrderEvent records order having just moved from previous to its current status
+func NewOrderEvent(id string, order *Order, previous OrderStatus) *OrderEvent {
+	return &OrderEvent{
+		ID:             id,
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		Status:         order.Status,
+		PreviousStatus: previous,
+		OccurredAt:     order.UpdatedAt,
+	}
+}
+
+// IsFinal reports whether no further transitions can follow the status
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderStatusDelivered || s == OrderStatusCancelled
+}
+
+// OrderEventBus carries order events to every API instance, so clients
+// connected to one instance see transitions made on another
+// The domain defines the interface, infrastructure implements it
+type OrderEventBus interface {
+	Publish(ctx context.Context, event *OrderEvent) error
+	// Subscribe calls handle for every event published on any instance until
+	// ctx ends; events published while nobody is subscribed are not kept
+	Subscribe(ctx context.Context, handle func(*OrderEvent)) error
+}
+
+// OrderEventLog keeps the most recent order events in order, so event
+// streams can resume where a client disconnected
+// The domain defines the interface, infrastructure implements it
+type OrderEventLog interface {
+	// Append stores event and returns the ID assigned to it
+	Append(ctx context.Context, event *OrderEvent) (string, error)
+	// After returns up to limit events appended after afterID ("" for the
+	// oldest retained), oldest first, waiting up to wait for one when there
+	// are none yet. It fails with ErrOrderEventsExpired when events after
+	// afterID were already discarded, and ErrInvalidEventID for malformed IDs.
+	After(ctx context.Context, afterID string, limit int, wait time.Duration) ([]*OrderEvent, error)
+	// LastID returns the ID of the newest event, or "" when there is none
+	LastID(ctx context.Context) (string, error)
+}
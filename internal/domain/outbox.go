@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Transactor runs work atomically: repositories called with the context fn
+// receives take part in one transaction, committed when fn returns nil and
+// rolled back when it fails. Calls nested in fn join the same transaction.
+// The domain defines the interface, infrastructure implements it
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxEntry is a stored event awaiting publication
+type OutboxEntry struct {
+	Seq         int64 // Position in the outbox; events are relayed in this order
+	Event       Event // Nil when Payload cannot be decoded, such as an event type this build does not know
+	DecodeError error // Why Event is nil
+	EventID     string
+	EventType   string
+	AggregateID string
+	Payload     []byte // The event as stored
+	Attempts    int    // Failed publish attempts so far
+}
+
+// Outbox stores domain events in the transaction of the writes they
+// announce, so an event is kept exactly when its write is, and holds them
+// until a relay has published them. Publication is at least once: a relay
+// stopping between publishing and marking an event publishes it again, so
+// subscribers should skip event IDs they have seen.
+// The domain defines the interface, infrastructure implements it
+type Outbox interface {
+	// Add stores events, in the transaction of ctx if it carries one
+	Add(ctx context.Context, events ...Event) error
+	// Relay passes up to limit unpublished events to publish, oldest first,
+	// and marks each published once publish returns nil, whether publish
+	// published it or gave up on it. After a failure
+	// the aggregate's later events wait for the next call, so the events of
+	// one aggregate are published in order. Returns how many were published.
+	Relay(ctx context.Context, limit int, publish func(ctx context.Context, entry OutboxEntry) error) (int, error)
+	// Purge deletes the events published before cutoff and returns how many
+	Purge(ctx context.Context, cutoff time.Time) (int, error)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Cursor is a keyset pagination position: the sort key of a row in the
+// newest-first order lists use (created_at descending, ties by ID ascending)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// KeysetPage selects up to Limit rows of a newest-first list: the first
+// ones, the ones following After, or the ones just preceding Before. Rows
+// are always returned newest first. At most one of After and Before is set;
+// only rows matching every filter are listed.
+type KeysetPage struct {
+	Limit   int
+	After   *Cursor
+	Before  *Cursor
+	Filters []Filter
+	Fields  []string // The fields the caller reads, as in ListQuery
+}
+
+// Page is one keyset page of a list. Next and Prev are the cursors of the
+// adjacent pages, nil at either end of the list.
+type Page[T any] struct {
+	Items []T
+	Next  *Cursor // Pass as KeysetPage.After
+	Prev  *Cursor // Pass as KeysetPage.Before
+}
+
+// Follows reports whether a row with key (createdAt, id) comes after c in
+// the newest-first order
+func (c Cursor) Follows(createdAt time.Time, id string) bool {
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.Before(c.CreatedAt)
+	}
+	return id > c.ID
+}
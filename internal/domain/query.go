@@ -0,0 +1,97 @@
+package domain
+
+// FilterOp is a comparison a list filter applies to a field
+type FilterOp string
+
+const (
+	OpEq       FilterOp = "eq"
+	OpNe       FilterOp = "ne"
+	OpGt       FilterOp = "gt"
+	OpGte      FilterOp = "gte"
+	OpLt       FilterOp = "lt"
+	OpLte      FilterOp = "lte"
+	OpIn       FilterOp = "in"       // Any of several values
+	OpContains FilterOp = "contains" // Substring, ignoring case
+)
+
+// FieldKind is the type of the values of a queryable field
+type FieldKind int
+
+const (
+	StringField FieldKind = iota // Filter values are strings
+	NumberField                  // Filter values are float64
+	TimeField                    // Filter values are time.Time
+)
+
+// QueryField describes how a list may be filtered and sorted by one field
+type QueryField struct {
+	Kind     FieldKind
+	Ops      []FilterOp
+	Sortable bool
+	Values   []string // The allowed values of an enumerated string field
+}
+
+// Allows reports whether the field can be filtered with op
+func (f QueryField) Allows(op FilterOp) bool {
+	for _, allowed := range f.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter keeps the rows whose Field compares to Value with Op. Value is a
+// string, float64 or time.Time by the field's kind, or a []string for OpIn.
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value any
+}
+
+// SortKey orders a list by one field
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ListQuery narrows and orders a list. Every filter must match. Rows are
+// sorted by the keys in turn, then by ID; without keys a list is newest
+// first (created_at descending, ties by ID).
+type ListQuery struct {
+	Filters []Filter
+	Sort    []SortKey
+	// Fields names the fields of the rows the caller reads, by their query
+	// field names (plus "items" and "cancelled_at" for orders), so
+	// repositories can skip reading the rest; nil reads them all. Fields not
+	// named may be left zero; the ID and creation time are always filled in.
+	Fields []string
+}
+
+var (
+	equality   = []FilterOp{OpEq, OpNe, OpIn}
+	comparison = []FilterOp{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte}
+	text       = []FilterOp{OpEq, OpNe, OpIn, OpContains}
+)
+
+// UserQueryFields are the fields the user list can be filtered and sorted by
+var UserQueryFields = map[string]QueryField{
+	"id":         {Kind: StringField, Ops: equality},
+	"name":       {Kind: StringField, Ops: text, Sortable: true},
+	"email":      {Kind: StringField, Ops: text, Sortable: true},
+	"created_at": {Kind: TimeField, Ops: comparison, Sortable: true},
+	"updated_at": {Kind: TimeField, Ops: comparison, Sortable: true},
+}
+
+// OrderQueryFields are the fields the order list can be filtered and sorted by
+var OrderQueryFields = map[string]QueryField{
+	"id":      {Kind: StringField, Ops: equality},
+	"user_id": {Kind: StringField, Ops: equality},
+	"status": {Kind: StringField, Ops: equality, Sortable: true, Values: []string{
+		string(OrderStatusPending), string(OrderStatusConfirmed), string(OrderStatusShipped),
+		string(OrderStatusDelivered), string(OrderStatusCancelled),
+	}},
+	"amount":     {Kind: NumberField, Ops: comparison, Sortable: true},
+	"created_at": {Kind: TimeField, Ops: comparison, Sortable: true},
+	"updated_at": {Kind: TimeField, Ops: comparison, Sortable: true},
+}
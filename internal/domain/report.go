@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Report kinds that can be scheduled
+const (
+	ReportDailyOrderSummary = "daily_order_summary" // Orders placed in the 24 hours before the run
+)
+
+// ReportSchedule is a recurring report emailed to a list of recipients
+type ReportSchedule struct {
+	ID         string
+	Name       string
+	Report     string // One of the Report* kinds
+	Schedule   string // Standard 5-field cron expression, evaluated in UTC
+	Recipients []string
+	CreatedBy  string
+	NextRunAt  time.Time
+	LastRunAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// NewReportSchedule creates a schedule with validation
+// Business rule: a known report, a valid cron expression and at least one valid recipient
+func NewReportSchedule(id, name, report, schedule string, recipients []string, createdBy string) (*ReportSchedule, error) {
+	if strings.TrimSpace(id) == "" || strings.TrimSpace(name) == "" {
+		return nil, ErrInvalidInput
+	}
+	if report != ReportDailyOrderSummary {
+		return nil, ErrUnknownReport
+	}
+	if len(recipients) == 0 {
+		return nil, ErrInvalidUserEmail
+	}
+	for _, email := range recipients {
+		if !IsValidEmail(email) {
+			return nil, ErrInvalidUserEmail
+		}
+	}
+
+	now := time.Now().UTC()
+	s := &ReportSchedule{
+		ID:         id,
+		Name:       strings.TrimSpace(name),
+		Report:     report,
+		Schedule:   strings.TrimSpace(schedule),
+		Recipients: recipients,
+		CreatedBy:  createdBy,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	next, err := s.NextRun(now)
+	if err != nil {
+		return nil, err
+	}
+	s.NextRunAt = next
+	return s, nil
+}
+
+// NextRun returns the first run after t
+func (s *ReportSchedule) NextRun(t time.Time) (time.Time, error) {
+	sched, err := cron.ParseStandard(s.Schedule)
+	if err != nil {
+		return time.Time{}, ErrInvalidSchedule
+	}
+	return sched.Next(t.UTC()), nil
+}
+
+// ReportScheduleRepository defines the contract for report schedule persistence
+// The domain defines the interface, infrastructure implements it
+type ReportScheduleRepository interface {
+	Create(ctx context.Context, schedule *ReportSchedule) error
+	GetByID(ctx context.Context, id string) (*ReportSchedule, error)
+	List(ctx context.Context) ([]*ReportSchedule, error)
+	Delete(ctx context.Context, id string) error
+	// ListDue returns up to limit schedules whose next run is at or before now
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*ReportSchedule, error)
+	// Advance moves a schedule's next run from expected to next, recording
+	// ranAt. It reports false if the next run is no longer expected (another
+	// replica claimed this run first).
+	Advance(ctx context.Context, id string, expected, next, ranAt time.Time) (bool, error)
+}
+
+// OrderSummary aggregates the orders created in [From, To)
+type OrderSummary struct {
+	From     time.Time
+	To       time.Time
+	Count    int
+	Total    float64
+	ByStatus map[OrderStatus]int
+}
+
+// EmailMessage is an email with a plain-text body and an optional HTML
+// alternative
+type EmailMessage struct {
+	To      []string
+	Subject string
+	Body    string
+	HTML    string // Empty sends plain text only
+}
+
+// EmailSender defines the contract for outgoing email
+// The domain defines the interface, infrastructure implements it
+type EmailSender interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// LatencyBoundsMS are the upper bounds (inclusive, in milliseconds) of the
+// request latency histogram; a final overflow bucket counts everything slower.
+// Changing them invalidates stored histograms.
+var LatencyBoundsMS = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// RequestStats aggregates API requests over one hour. Counters are additive,
+// so stats from every instance (and every flush) merge by summing.
+type RequestStats struct {
+	Hour     time.Time // Start of the hour, UTC
+	Requests int64
+	Errors   int64   // Requests answered with a 5xx status
+	Latency  []int64 // Request counts per LatencyBoundsMS bucket, plus the overflow bucket
+}
+
+// NewRequestStats creates empty stats for the hour containing t
+func NewRequestStats(t time.Time) *RequestStats {
+	return &RequestStats{
+		Hour:    t.UTC().Truncate(time.Hour),
+		Latency: make([]int64, len(LatencyBoundsMS)+1),
+	}
+}
+
+// Observe counts one request
+func (s *RequestStats) Observe(d time.Duration, failed bool) {
+	s.Requests++
+	if failed {
+		s.Errors++
+	}
+	ms := d.Milliseconds()
+	i := 0
+	for i < len(LatencyBoundsMS) && ms > LatencyBoundsMS[i] {
+		i++
+	}
+	s.Latency[i]++
+}
+
+// Add merges other into s
+func (s *RequestStats) Add(other *RequestStats) {
+	s.Requests += other.Requests
+	s.Errors += other.Errors
+	for i := range s.Latency {
+		if i < len(other.Latency) {
+			s.Latency[i] += other.Latency[i]
+		}
+	}
+}
+
+// Percentile returns the upper bound in milliseconds of the histogram bucket
+// holding the q-th quantile (0 < q <= 1), or 0 without requests. Requests in
+// the overflow bucket report the largest bound.
+func (s *RequestStats) Percentile(q float64) int64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(q*float64(s.Requests))), 1)
+	var seen int64
+	for i, n := range s.Latency {
+		seen += n
+		if seen >= rank && i < len(LatencyBoundsMS) {
+			return LatencyBoundsMS[i]
+		}
+	}
+	return LatencyBoundsMS[len(LatencyBoundsMS)-1]
+}
+
+// RequestStatsStore defines the contract for cluster-wide hourly request stats
+// The domain defines the interface, infrastructure implements it
+//
+// Implementations should keep at least eight days of history.
+type RequestStatsStore interface {
+	// Add merges stats into the stored hour
+	Add(ctx context.Context, stats *RequestStats) error
+	// Range returns the stored hours from from to to (inclusive), oldest
+	// first, skipping hours without requests
+	Range(ctx context.Context, from, to time.Time) ([]*RequestStats, error)
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// CachedResponse is a whole response to a GET request, replayed to requests
+// with the same cache key while it is fresh
+type CachedResponse struct {
+	// Version is the version of the response's tags it was served under; it
+	// goes stale as soon as a write bumps one of them
+	Version string
+	Status  int                 // Response status code
+	Header  map[string][]string // Response headers the handler set
+	Body    []byte              // Response body
+}
+
+// ResponseCache defines the contract for caching whole HTTP responses
+// The domain defines the interface, infrastructure implements it
+//
+// Responses are tagged with the resources they show. Invalidating a tag bumps
+// its version instead of deleting entries, so a response computed before a
+// write and stored after it is never served: it carries the older version.
+type ResponseCache interface {
+	// Get returns the response stored under key if it is still current for
+	// tags, or ErrCacheMiss. Either way it returns the tags' current version,
+	// to store a fresh response under.
+	Get(ctx context.Context, key string, tags []string) (*CachedResponse, string, error)
+	// Set stores resp under key for ttl
+	Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+	// Invalidate makes every response tagged with one of tags stale
+	Invalidate(ctx context.Context, tags ...string) error
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// SagaStatus represents where a saga stands
+type SagaStatus string
+
+const (
+	SagaRunning      SagaStatus = "running"      // Steps are being performed
+	SagaCompensating SagaStatus = "compensating" // A step failed; completed steps are being undone
+	SagaCompleted    SagaStatus = "completed"    // Every step succeeded
+	SagaCompensated  SagaStatus = "compensated"  // A step failed and every completed step was undone
+)
+
+// Finished reports whether a saga in status s has nothing left to do
+func (s SagaStatus) Finished() bool {
+	return s == SagaCompleted || s == SagaCompensated
+}
+
+// Saga is the stored state of a long-running transaction spanning several
+// services: the steps performed so far, so a saga interrupted by a crash
+// can be resumed, or undone, by any instance
+type Saga struct {
+	ID     string
+	Type   string // Names its definition, such as "order.checkout"
+	Key    string // The entity it runs for; one unfinished or completed saga per type and key
+	Status SagaStatus
+	// Step is the index of the step being performed while running, of the
+	// next step to undo while compensating
+	Step int
+	// Data holds what steps produced, such as a payment ID, for later steps
+	// and compensations
+	Data      map[string]string
+	Error     string // Why the saga is compensating
+	Attempts  int    // Failed attempts at the current compensation
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   int64 // Starts at 1; every stored update bumps it
+}
+
+// Clone returns a deep copy of s
+func (s *Saga) Clone() *Saga {
+	c := *s
+	c.Data = maps.Clone(s.Data)
+	return &c
+}
+
+// SagaRepository defines the contract for saga persistence
+// The domain defines the interface, infrastructure implements it
+type SagaRepository interface {
+	// Create stores a new saga; ErrSagaAlreadyExists when a saga of its type
+	// and key is unfinished or completed
+	Create(ctx context.Context, saga *Saga) error
+	Get(ctx context.Context, id string) (*Saga, error)
+	// GetByKey returns the newest saga of sagaType for key
+	GetByKey(ctx context.Context, sagaType, key string) (*Saga, error)
+	// Update stores saga if it is still at saga.Version, and bumps the
+	// version; ErrVersionMismatch when another update came first
+	Update(ctx context.Context, saga *Saga) error
+	// ListUnfinished returns up to limit running or compensating sagas last
+	// updated before cutoff, oldest first
+	ListUnfinished(ctx context.Context, cutoff time.Time, limit int) ([]*Saga, error)
+}
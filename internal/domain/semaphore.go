@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SemaphoreStore defines the contract for cluster-wide counting semaphores
+// The domain defines the interface, infrastructure implements it
+//
+// Each holder's slot is a lease: it is freed automatically if not refreshed
+// within ttl, so a crashed replica can't leak capacity forever.
+type SemaphoreStore interface {
+	// TryAcquire takes a slot of name for holder if fewer than limit are held
+	TryAcquire(ctx context.Context, name, holder string, limit int, ttl time.Duration) (bool, error)
+	// Refresh extends holder's lease by ttl; it reports false if the lease already expired
+	Refresh(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// Release frees holder's slot (a no-op if it already expired)
+	Release(ctx context.Context, name, holder string) error
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// TokenRevocationStore defines the contract for the access token denylist
+// The domain defines the interface, infrastructure implements it
+//
+// Entries only need to live as long as the tokens they revoke, so
+// implementations should expire them after the given TTL.
+type TokenRevocationStore interface {
+	// RevokeToken denylists a single token by its ID (jti)
+	RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	// RevokeAllForUser denylists every token of a user issued at or before before
+	RevokeAllForUser(ctx context.Context, userID string, before time.Time, ttl time.Duration) error
+	// IsRevoked reports whether a token was revoked individually or by a user-wide revocation
+	IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error)
+}
+
+// LoginAttemptStore defines the contract for tracking failed authentication attempts
+// Keys identify what is being tracked, e.g. "ip:203.0.113.7"
+// The domain defines the interface, infrastructure implements it
+type LoginAttemptStore interface {
+	// RecordFailure increments the failure count for key, starting a new window if needed
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	// Failures returns the current failure count and remaining lockout for key
+	Failures(ctx context.Context, key string) (count int, lockedFor time.Duration, err error)
+	// Lock blocks key for d
+	Lock(ctx context.Context, key string, d time.Duration) error
+}
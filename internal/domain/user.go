@@ -15,17 +15,47 @@ type User struct {
 	Email     string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int64 // Starts at 1; every stored update bumps it
+}
+
+// UserPatch is a partial update of a user: nil fields are left unchanged
+type UserPatch struct {
+	Name  *string
+	Email *string
 }
 
 // UserRepository defines the contract for user persistence
 // The domain defines the interface, infrastructure implements it
 type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*User, error)
+	// GetByIDs fetches several users in one round trip, in no particular
+	// order; unknown IDs are skipped
+	GetByIDs(ctx context.Context, ids []string) ([]*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	Create(ctx context.Context, user *User) error
+	// CreateMany inserts users in one transaction. A user whose email (or ID)
+	// is taken, by a stored user or one earlier in users, is skipped with
+	// ErrUserAlreadyExists at its index in the returned errors; the others
+	// are inserted. The error fails the whole batch: no user is inserted.
+	CreateMany(ctx context.Context, users []*User) ([]error, error)
 	Update(ctx context.Context, user *User) error
-	Delete(ctx context.Context, id string) error
+	// Delete removes a user while its stored version is still version, and
+	// fails with ErrVersionMismatch otherwise
+	Delete(ctx context.Context, id string, version int64) error
 	List(ctx context.Context, limit, offset int) ([]*User, error)
+	// Search is List narrowed and ordered by q, whose fields are among the
+	// UserQueryFields; other fields fail with ErrInvalidInput
+	Search(ctx context.Context, q ListQuery, limit, offset int) ([]*User, error)
+	// Count returns how many users match every filter; all of them without
+	// filters
+	Count(ctx context.Context, filters []Filter) (int, error)
+	// ListKeyset returns one keyset page of users, newest first, for
+	// pagination that stays fast however deep it goes
+	ListKeyset(ctx context.Context, page KeysetPage) ([]*User, error)
+	// Stream passes the users List would return to fn one at a time, as they
+	// are read; a limit of 0 streams every user. Stops at the first error
+	// from fn and returns it.
+	Stream(ctx context.Context, limit, offset int, fn func(*User) error) error
 }
 
 // UserCache defines the contract for user caching
@@ -47,6 +77,7 @@ func NewUser(id, name, email string) (*User, error) {
 		Email:     email,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
+		Version:   1,
 	}
 
 	if err := u.Validate(); err != nil {
@@ -77,7 +108,12 @@ func (u *User) Validate() error {
 // IsValidEmail checks if the email format is valid
 // Business rule: Email must match standard email pattern
 func (u *User) IsValidEmail() bool {
-	email := strings.TrimSpace(strings.ToLower(u.Email))
+	return IsValidEmail(u.Email)
+}
+
+// IsValidEmail checks an email address against the standard pattern
+func IsValidEmail(email string) bool {
+	email = strings.TrimSpace(strings.ToLower(email))
 	if email == "" {
 		return false
 	}
@@ -0,0 +1,168 @@
+// Package email sends outgoing mail through SMTP, or logs it when no SMTP
+// server is configured (development).
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Ensure senders implement domain.EmailSender at compile time
+var (
+	_ domain.EmailSender = (*SMTPSender)(nil)
+	_ domain.EmailSender = (*LogSender)(nil)
+)
+
+// SMTPConfig configures an SMTP relay
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string // Empty skips authentication
+	Password string
+	From     string
+}
+
+// SMTPSender sends mail through an SMTP relay, upgrading to TLS with
+// STARTTLS when the server offers it
+type SMTPSender struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPSender creates an SMTP-backed sender
+func NewSMTPSender(cfg SMTPConfig) *SMTPSender {
+	return &SMTPSender{cfg: cfg}
+}
+
+// Send delivers msg, honouring ctx's deadline for the whole SMTP exchange
+func (s *SMTPSender) Send(ctx context.Context, msg domain.EmailMessage) error {
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("smtp dial failed: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("smtp starttls failed: %w", err)
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.cfg.From); err != nil {
+		return fmt.Errorf("smtp mail from failed: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp rcpt %s failed: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data failed: %w", err)
+	}
+	if _, err := w.Write(render(s.cfg.From, msg)); err != nil {
+		w.Close()
+		return fmt.Errorf("smtp write failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp send failed: %w", err)
+	}
+	return c.Quit()
+}
+
+// render builds the RFC 5322 message, multipart/alternative when msg has an
+// HTML part. Header values have CR and LF stripped so recipients and
+// subjects can't inject headers.
+func render(from string, msg domain.EmailMessage) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", clean.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", clean.Replace(strings.Join(msg.To, ", ")))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", clean.Replace(msg.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		writeQuotedPrintable(&b, msg.Body)
+		return b.Bytes()
+	}
+
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	// Least preferred first: clients show the last part they support
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Body},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		writeQuotedPrintable(w, part.body)
+	}
+	mw.Close()
+	return b.Bytes()
+}
+
+// writeQuotedPrintable encodes body with CRLF line endings, keeping lines
+// within the SMTP length limit
+func writeQuotedPrintable(w io.Writer, body string) {
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")))
+	qp.Close()
+}
+
+// LogSender logs messages instead of sending them, for development
+type LogSender struct {
+	logg *logger.Logger
+}
+
+// NewLogSender creates a sender that only logs
+func NewLogSender(logg *logger.Logger) *LogSender {
+	return &LogSender{logg: logg}
+}
+
+// Send logs msg
+func (s *LogSender) Send(_ context.Context, msg domain.EmailMessage) error {
+	s.logg.Info("email not sent (no SMTP server configured)",
+		"to", msg.To,
+		"subject", msg.Subject,
+		"body", msg.Body,
+		"html", msg.HTML != "",
+	)
+	return nil
+}
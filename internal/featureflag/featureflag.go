@@ -0,0 +1,154 @@
+// Package featureflag gates new behaviour so it can be rolled out gradually:
+// switched on for named users first, then for a growing percentage of
+// everyone, and off again without a deploy.
+//
+// Flag definitions come from a Provider (environment, file or Redis). A
+// Client evaluates them per request for the user bound to the context (see
+// WithUser and Middleware). Percentage rollouts are sticky: each user falls
+// in a fixed bucket per flag, so a user who got a feature at 10% keeps it at
+// 20%. Unknown flags and provider failures evaluate to off.
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Flag is the rollout rule for one feature
+type Flag struct {
+	Key         string   `json:"key" yaml:"key"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Enabled     bool     `json:"enabled" yaml:"enabled"`                 // Off for everyone when false
+	Rollout     int      `json:"rollout" yaml:"rollout"`                 // Percent of users (0-100) who get the feature
+	Users       []string `json:"users,omitempty" yaml:"users,omitempty"` // User IDs who get it regardless of Rollout
+}
+
+var keyRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Validate checks the flag definition
+func (f Flag) Validate() error {
+	var errs []error
+	if !keyRegex.MatchString(f.Key) {
+		errs = append(errs, fmt.Errorf("invalid flag key %q (lowercase letters, digits, '_', '.' and '-')", f.Key))
+	}
+	if f.Rollout < 0 || f.Rollout > 100 {
+		errs = append(errs, fmt.Errorf("flag %s: rollout must be between 0 and 100, got %d", f.Key, f.Rollout))
+	}
+	return errors.Join(errs...)
+}
+
+// EnabledFor reports whether userID gets the feature. Anonymous callers
+// (empty userID) only get fully rolled-out features.
+func (f Flag) EnabledFor(userID string) bool {
+	switch {
+	case !f.Enabled:
+		return false
+	case f.Rollout >= 100:
+		return true
+	case userID == "":
+		return false
+	case slices.Contains(f.Users, userID):
+		return true
+	default:
+		return bucket(f.Key, userID) < f.Rollout
+	}
+}
+
+// bucket places userID in one of 100 buckets for flag key. Hashing the key
+// too means the same users aren't always the first to get every feature.
+func bucket(key, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(userID))
+	return int(h.Sum32() % 100)
+}
+
+// Provider supplies flag definitions
+type Provider interface {
+	// Flags returns every defined flag by key
+	Flags(ctx context.Context) (map[string]Flag, error)
+}
+
+// Client evaluates flags. A nil *Client evaluates every flag to off, so
+// components can take one as an optional dependency.
+type Client struct {
+	provider Provider
+	logg     *logger.Logger
+}
+
+// NewClient creates a client evaluating the flags of provider
+func NewClient(provider Provider, logg *logger.Logger) *Client {
+	return &Client{provider: provider, logg: logg}
+}
+
+// Enabled reports whether the feature is on for the user bound to ctx
+func (c *Client) Enabled(ctx context.Context, key string) bool {
+	return c.EnabledFor(ctx, key, User(ctx))
+}
+
+// EnabledFor reports whether the feature is on for userID, e.g. the owner of
+// the resource being acted on rather than the caller
+func (c *Client) EnabledFor(ctx context.Context, key, userID string) bool {
+	if c == nil {
+		return false
+	}
+	flags, err := c.provider.Flags(ctx)
+	if err != nil {
+		c.logg.Warn("feature flags unavailable, treating flag as off", "error", err, "flag", key)
+		return false
+	}
+	f, ok := flags[key]
+	return ok && f.EnabledFor(userID)
+}
+
+// Evaluate returns every flag's state for the user bound to ctx
+func (c *Client) Evaluate(ctx context.Context) (map[string]bool, error) {
+	states := make(map[string]bool)
+	if c == nil {
+		return states, nil
+	}
+	flags, err := c.provider.Flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := User(ctx)
+	for key, f := range flags {
+		states[key] = f.EnabledFor(userID)
+	}
+	return states, nil
+}
+
+type contextKey struct{}
+
+// WithUser binds the user flags are evaluated for to ctx
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// User returns the user bound to ctx, or "" for anonymous requests
+func User(ctx context.Context) string {
+	userID, _ := ctx.Value(contextKey{}).(string)
+	return userID
+}
+
+// Middleware binds the user returned by userID (e.g. the authenticated
+// subject) to every request, so Client.Enabled evaluates flags for them.
+// Install it after authentication.
+func Middleware(userID func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := userID(r); id != "" {
+				r = r.WithContext(WithUser(r.Context(), id))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
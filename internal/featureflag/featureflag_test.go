@@ -0,0 +1,206 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestFlagEnabledFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   Flag
+		userID string
+		want   bool
+	}{
+		{"disabled", Flag{Key: "f", Enabled: false, Rollout: 100}, "u1", false},
+		{"fully rolled out", Flag{Key: "f", Enabled: true, Rollout: 100}, "u1", true},
+		{"fully rolled out anonymous", Flag{Key: "f", Enabled: true, Rollout: 100}, "", true},
+		{"partial rollout anonymous", Flag{Key: "f", Enabled: true, Rollout: 99}, "", false},
+		{"targeted user", Flag{Key: "f", Enabled: true, Users: []string{"u1"}}, "u1", true},
+		{"untargeted user at zero", Flag{Key: "f", Enabled: true, Users: []string{"u1"}}, "u2", false},
+		{"targeted user while disabled", Flag{Key: "f", Enabled: false, Users: []string{"u1"}}, "u1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.EnabledFor(tt.userID); got != tt.want {
+				t.Errorf("EnabledFor(%q) = %v, want %v", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutIsStickyAndProportional(t *testing.T) {
+	const users = 10000
+	at10 := Flag{Key: "new_order_flow", Enabled: true, Rollout: 10}
+	at20 := Flag{Key: "new_order_flow", Enabled: true, Rollout: 20}
+
+	enabled := 0
+	for i := range users {
+		id := fmt.Sprintf("user-%d", i)
+		if at10.EnabledFor(id) {
+			enabled++
+			if !at20.EnabledFor(id) {
+				t.Fatalf("user %s lost the feature when the rollout grew", id)
+			}
+		}
+	}
+
+	if enabled < users*8/100 || enabled > users*12/100 {
+		t.Errorf("10%% rollout enabled %d of %d users", enabled, users)
+	}
+}
+
+func TestFlagValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    Flag
+		wantErr bool
+	}{
+		{"valid", Flag{Key: "orders.new_flow", Rollout: 50}, false},
+		{"empty key", Flag{Rollout: 50}, true},
+		{"uppercase key", Flag{Key: "NewFlow"}, true},
+		{"rollout above 100", Flag{Key: "f", Rollout: 101}, true},
+		{"negative rollout", Flag{Key: "f", Rollout: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.flag.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	provider, err := NewEnvProvider(map[string]int{"on": 100, "off": 0})
+	if err != nil {
+		t.Fatalf("NewEnvProvider() error = %v", err)
+	}
+	client := NewClient(provider, logger.New("error"))
+	ctx := WithUser(context.Background(), "u1")
+
+	if !client.Enabled(ctx, "on") {
+		t.Error("expected flag at 100% to be on")
+	}
+	if client.Enabled(ctx, "off") || client.Enabled(ctx, "undefined") {
+		t.Error("expected flags at 0% and undefined flags to be off")
+	}
+
+	states, err := client.Evaluate(ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(states) != 2 || !states["on"] || states["off"] {
+		t.Errorf("Evaluate() = %v", states)
+	}
+
+	var nilClient *Client
+	if nilClient.Enabled(ctx, "on") {
+		t.Error("nil client should evaluate every flag to off")
+	}
+}
+
+func TestMiddlewareBindsUser(t *testing.T) {
+	var got string
+	handler := Middleware(func(r *http.Request) string { return r.Header.Get("X-User") })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = User(r.Context())
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User", "u1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "u1" {
+		t.Errorf("User() = %q, want u1", got)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	const yamlDoc = `
+flags:
+  - key: new_order_flow
+    enabled: true
+    rollout: 10
+    users: [u1]
+`
+	const jsonDoc = `{"flags": [{"key": "new_order_flow", "enabled": true, "rollout": 10}]}`
+
+	tests := []struct {
+		name    string
+		data    string
+		format  string
+		wantErr bool
+	}{
+		{"yaml", yamlDoc, "yaml", false},
+		{"json", jsonDoc, "json", false},
+		{"empty", "", "yaml", false},
+		{"unknown field", `{"flags": [{"key": "f", "percent": 10}]}`, "json", true},
+		{"duplicate key", `{"flags": [{"key": "f"}, {"key": "f"}]}`, "json", true},
+		{"invalid rollout", `{"flags": [{"key": "f", "rollout": 200}]}`, "json", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile([]byte(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileProviderReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	write := func(data string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now()
+	write(`{"flags": [{"key": "f", "enabled": false}]}`, start)
+
+	p, err := NewFileProvider(path, time.Second, logger.New("error"))
+	if err != nil {
+		t.Fatalf("NewFileProvider() error = %v", err)
+	}
+	now := start
+	p.now = func() time.Time { return now }
+	p.checkedAt = now
+
+	enabled := func() bool {
+		flags, err := p.Flags(context.Background())
+		if err != nil {
+			t.Fatalf("Flags() error = %v", err)
+		}
+		return flags["f"].Enabled
+	}
+
+	write(`{"flags": [{"key": "f", "enabled": true}]}`, start.Add(time.Minute))
+	if enabled() {
+		t.Error("file re-read before the refresh interval")
+	}
+	now = now.Add(time.Second)
+	if !enabled() {
+		t.Error("changed file not picked up after the refresh interval")
+	}
+
+	// A broken edit keeps the last good definitions
+	write(`{"flags": [`, start.Add(2*time.Minute))
+	now = now.Add(time.Second)
+	if !enabled() {
+		t.Error("invalid file replaced the current flags")
+	}
+}
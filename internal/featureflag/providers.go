@@ -0,0 +1,161 @@
+package featureflag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// StaticProvider serves a fixed set of flags
+type StaticProvider struct {
+	flags map[string]Flag
+}
+
+// NewStaticProvider creates a provider for flags
+func NewStaticProvider(flags ...Flag) (*StaticProvider, error) {
+	byKey, err := index(flags)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticProvider{flags: byKey}, nil
+}
+
+// NewEnvProvider creates a provider from per-flag rollout percentages, as
+// parsed from FEATURE_FLAGS=new_order_flow=25,beta_ui=100. Every listed flag
+// is enabled; 0 keeps a flag defined but off.
+func NewEnvProvider(rollouts map[string]int) (*StaticProvider, error) {
+	flags := make([]Flag, 0, len(rollouts))
+	for key, rollout := range rollouts {
+		flags = append(flags, Flag{Key: key, Enabled: true, Rollout: rollout})
+	}
+	return NewStaticProvider(flags...)
+}
+
+func (p *StaticProvider) Flags(ctx context.Context) (map[string]Flag, error) {
+	return p.flags, nil
+}
+
+// index validates flags and maps them by key
+func index(flags []Flag) (map[string]Flag, error) {
+	byKey := make(map[string]Flag, len(flags))
+	var errs []error
+	for _, f := range flags {
+		if err := f.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, dup := byKey[f.Key]; dup {
+			errs = append(errs, fmt.Errorf("flag %s is defined more than once", f.Key))
+			continue
+		}
+		byKey[f.Key] = f
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return byKey, nil
+}
+
+// fileFormat is the layout of a flag file
+type fileFormat struct {
+	Flags []Flag `json:"flags" yaml:"flags"`
+}
+
+// ParseFile decodes a flag file: JSON for .json, YAML otherwise. Unknown
+// fields are rejected so typos don't silently leave a flag off.
+func ParseFile(data []byte, format string) (map[string]Flag, error) {
+	var f fileFormat
+	var err error
+	if format == "json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid %s flag file: %w", format, err)
+	}
+	return index(f.Flags)
+}
+
+// FileProvider serves flags from a JSON or YAML file, re-reading it when it
+// changes so flags can be flipped without a restart. An edit that doesn't
+// parse is logged and the previous definitions stay in effect.
+type FileProvider struct {
+	path    string
+	refresh time.Duration // How often the file's modification time is checked (0 never re-reads)
+	logg    *logger.Logger
+	now     func() time.Time
+
+	mu        sync.Mutex
+	flags     map[string]Flag
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFileProvider loads the flags in path, failing if the file is missing or invalid
+func NewFileProvider(path string, refresh time.Duration, logg *logger.Logger) (*FileProvider, error) {
+	p := &FileProvider{path: path, refresh: refresh, logg: logg, now: time.Now}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flag file: %w", err)
+	}
+	if err := p.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+	p.checkedAt = p.now()
+	return p, nil
+}
+
+func (p *FileProvider) Flags(ctx context.Context) (map[string]Flag, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.refresh > 0 && p.now().Sub(p.checkedAt) >= p.refresh {
+		p.checkedAt = p.now()
+		info, err := os.Stat(p.path)
+		if err != nil {
+			p.logg.Warn("failed to check flag file, keeping current flags", "error", err, "path", p.path)
+		} else if !info.ModTime().Equal(p.modTime) {
+			if err := p.load(info.ModTime()); err != nil {
+				p.logg.Warn("invalid flag file, keeping current flags", "error", err, "path", p.path)
+			} else {
+				p.logg.Info("feature flags reloaded", "path", p.path, "flags", len(p.flags))
+			}
+		}
+	}
+	return p.flags, nil
+}
+
+// load replaces the flags with the file's contents
+func (p *FileProvider) load(modTime time.Time) error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read flag file: %w", err)
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(p.path), ".json") {
+		format = "json"
+	}
+	flags, err := ParseFile(data, format)
+	if err != nil {
+		return err
+	}
+	p.flags = flags
+	p.modTime = modTime
+	return nil
+}
@@ -0,0 +1,80 @@
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKey is the hash holding every flag definition
+const redisKey = "featureflags"
+
+// RedisProvider serves flags stored in Redis, shared by every replica, so a
+// flag flipped once takes effect everywhere within the refresh interval.
+//
+// Keys:
+//
+//	featureflags  hash of flag key to JSON-encoded Flag, e.g.
+//	              HSET featureflags new_order_flow '{"enabled":true,"rollout":10}'
+//
+// Definitions are cached for the refresh interval to keep Redis off the
+// request path. If Redis is unreachable the last definitions fetched stay in
+// effect; invalid entries are logged and skipped.
+type RedisProvider struct {
+	client  *redis.Client
+	refresh time.Duration
+	logg    *logger.Logger
+	now     func() time.Time
+
+	mu        sync.Mutex
+	flags     map[string]Flag
+	fetchedAt time.Time
+}
+
+// NewRedisProvider creates a provider reading flags from Redis at most once per refresh
+func NewRedisProvider(c *redis.Client, refresh time.Duration, logg *logger.Logger) *RedisProvider {
+	return &RedisProvider{client: c, refresh: refresh, logg: logg, now: time.Now}
+}
+
+func (p *RedisProvider) Flags(ctx context.Context) (map[string]Flag, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.flags != nil && p.now().Sub(p.fetchedAt) < p.refresh {
+		return p.flags, nil
+	}
+
+	raw, err := p.client.HGetAll(ctx, redisKey).Result()
+	if err != nil {
+		if p.flags != nil {
+			p.logg.Warn("failed to refresh feature flags, keeping current flags", "error", err)
+			// Retry after another interval rather than on every request
+			p.fetchedAt = p.now()
+			return p.flags, nil
+		}
+		return nil, fmt.Errorf("redis hgetall failed: %w", err)
+	}
+
+	flags := make(map[string]Flag, len(raw))
+	for key, data := range raw {
+		var f Flag
+		if err := json.Unmarshal([]byte(data), &f); err != nil {
+			p.logg.Warn("invalid feature flag in redis, skipping", "error", err, "flag", key)
+			continue
+		}
+		f.Key = key
+		if err := f.Validate(); err != nil {
+			p.logg.Warn("invalid feature flag in redis, skipping", "error", err, "flag", key)
+			continue
+		}
+		flags[key] = f
+	}
+	p.flags = flags
+	p.fetchedAt = p.now()
+	return flags, nil
+}
@@ -0,0 +1,257 @@
+// Package fixtures loads users and orders from YAML or JSON files into the
+// repositories, for demos and integration environments (see `api seed`).
+//
+// A fixture file looks like:
+//
+//	users:
+//	  - name: Ada Lovelace
+//	    email: ada@example.com
+//	    orders:
+//	      - status: shipped          # Optional, defaults to pending
+//	        items:
+//	          - {product_id: pen, quantity: 10, price: 1.25}
+//
+// Users may carry an explicit id so other fixtures or tests can refer to them;
+// otherwise one is generated. Loading is idempotent: a user whose email
+// already exists is skipped along with its orders.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default.yaml
+var defaultFixtures []byte
+
+// Fixtures is the content of a fixture file
+type Fixtures struct {
+	Users []User `json:"users" yaml:"users"`
+}
+
+// User is a fixture user and the orders created for it
+type User struct {
+	ID     string  `json:"id,omitempty" yaml:"id,omitempty"`
+	Name   string  `json:"name" yaml:"name"`
+	Email  string  `json:"email" yaml:"email"`
+	Orders []Order `json:"orders,omitempty" yaml:"orders,omitempty"`
+}
+
+// Order is a fixture order
+type Order struct {
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	Items  []Item `json:"items" yaml:"items"`
+}
+
+// Item is a fixture order line
+type Item struct {
+	ProductID string  `json:"product_id" yaml:"product_id"`
+	Quantity  int     `json:"quantity" yaml:"quantity"`
+	Price     float64 `json:"price" yaml:"price"`
+}
+
+// Default returns the demo fixtures embedded in the binary
+func Default() (*Fixtures, error) {
+	return Parse(defaultFixtures, "yaml")
+}
+
+// LoadFile reads a fixture file; .json files are parsed as JSON and
+// anything else as YAML
+func LoadFile(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+	f, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse decodes fixtures in format ("yaml" or "json"), rejecting unknown
+// fields so typos surface instead of silently seeding partial data
+func Parse(data []byte, format string) (*Fixtures, error) {
+	var f Fixtures
+	switch format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			return nil, fmt.Errorf("invalid fixtures: %w", err)
+		}
+	case "yaml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid fixtures: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported fixture format %q (want yaml or json)", format)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Validate checks every fixture against the domain rules, reporting all
+// problems at once
+func (f *Fixtures) Validate() error {
+	var errs []error
+	emails := make(map[string]bool, len(f.Users))
+	for i, u := range f.Users {
+		if _, err := u.user(); err != nil {
+			errs = append(errs, fmt.Errorf("users[%d] (%s): %w", i, u.Email, err))
+			continue
+		}
+		key := strings.ToLower(u.Email)
+		if emails[key] {
+			errs = append(errs, fmt.Errorf("users[%d]: duplicate email %s", i, u.Email))
+		}
+		emails[key] = true
+		for j, o := range u.Orders {
+			if _, err := o.order("fixture"); err != nil {
+				errs = append(errs, fmt.Errorf("users[%d].orders[%d] (%s): %w", i, j, u.Email, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (u User) user() (*domain.User, error) {
+	id := u.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return domain.NewUser(id, u.Name, u.Email)
+}
+
+func (o Order) order(userID string) (*domain.Order, error) {
+	items := make([]domain.OrderItem, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = domain.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity, Price: item.Price}
+	}
+	order, err := domain.NewOrder(uuid.New().String(), userID, items)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status != "" {
+		order.Status = domain.OrderStatus(o.Status)
+		if !order.IsValidStatus() {
+			return nil, fmt.Errorf("%w: %s", domain.ErrInvalidOrderStatus, o.Status)
+		}
+	}
+	return order, nil
+}
+
+// Target is where fixtures are loaded. The caches are optional; when set,
+// every fixture user and order (including ones that already existed) is
+// written to them so the first requests hit a warm cache.
+type Target struct {
+	Users      domain.UserRepository
+	Orders     domain.OrderRepository
+	UserCache  domain.UserCache
+	OrderCache domain.OrderCache
+}
+
+// warmOrderLimit bounds the orders of an existing user written to the cache
+const warmOrderLimit = 100
+
+// Result counts what Apply did
+type Result struct {
+	UsersCreated  int
+	UsersSkipped  int
+	OrdersCreated int
+	CacheWarmed   int // Users and orders written to the caches
+}
+
+// Apply loads f into t
+func Apply(ctx context.Context, f *Fixtures, t Target, logg *logger.Logger) (Result, error) {
+	var res Result
+	for _, fu := range f.Users {
+		user, err := fu.user()
+		if err != nil {
+			return res, fmt.Errorf("invalid fixture user %s: %w", fu.Email, err)
+		}
+
+		var orders []*domain.Order
+		if err := t.Users.Create(ctx, user); err != nil {
+			if !errors.Is(err, domain.ErrUserAlreadyExists) {
+				return res, fmt.Errorf("failed to seed user %s: %w", fu.Email, err)
+			}
+			res.UsersSkipped++
+			if t.UserCache == nil && t.OrderCache == nil {
+				continue
+			}
+			// Warm the caches with what is already stored instead
+			if user, err = t.Users.GetByEmail(ctx, fu.Email); err != nil {
+				return res, fmt.Errorf("failed to load existing user %s: %w", fu.Email, err)
+			}
+			if t.OrderCache != nil {
+				if orders, err = t.Orders.GetByUserID(ctx, user.ID, warmOrderLimit, 0); err != nil {
+					return res, fmt.Errorf("failed to load orders of %s: %w", fu.Email, err)
+				}
+			}
+		} else {
+			res.UsersCreated++
+			for _, fo := range fu.Orders {
+				order, err := fo.order(user.ID)
+				if err != nil {
+					return res, fmt.Errorf("invalid fixture order for %s: %w", fu.Email, err)
+				}
+				if err := t.Orders.Create(ctx, order); err != nil {
+					return res, fmt.Errorf("failed to seed order for %s: %w", fu.Email, err)
+				}
+				res.OrdersCreated++
+				orders = append(orders, order)
+			}
+		}
+
+		res.CacheWarmed += warm(ctx, t, user, orders, logg)
+	}
+	return res, nil
+}
+
+// warm writes user and its orders to the caches. Cache failures are logged,
+// not returned: the data is already stored.
+func warm(ctx context.Context, t Target, user *domain.User, orders []*domain.Order, logg *logger.Logger) int {
+	warmed := 0
+	if t.UserCache != nil {
+		if err := t.UserCache.Set(ctx, user); err != nil {
+			logg.Warn("failed to warm user cache", "error", err, "user_id", user.ID)
+		} else {
+			warmed++
+		}
+	}
+	if t.OrderCache != nil {
+		for _, order := range orders {
+			if err := t.OrderCache.Set(ctx, order); err != nil {
+				logg.Warn("failed to warm order cache", "error", err, "order_id", order.ID)
+				continue
+			}
+			if err := t.OrderCache.AddUserOrderIndex(ctx, user.ID, order.ID); err != nil {
+				logg.Warn("failed to index cached order", "error", err, "order_id", order.ID)
+			}
+			warmed++
+		}
+	}
+	return warmed
+}
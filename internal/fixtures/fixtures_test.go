@@ -0,0 +1,66 @@
+package fixtures
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+func TestDefault(t *testing.T) {
+	f, err := Default()
+	if err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	if len(f.Users) == 0 {
+		t.Fatal("expected embedded demo users")
+	}
+}
+
+func TestParse(t *testing.T) {
+	const yamlDoc = `
+users:
+  - id: 00000000-0000-0000-0000-000000000001
+    name: Ada Lovelace
+    email: ada@example.com
+    orders:
+      - status: shipped
+        items:
+          - {product_id: pen, quantity: 10, price: 1.25}
+`
+	const jsonDoc = `{"users": [{"name": "Ada Lovelace", "email": "ada@example.com",
+		"orders": [{"items": [{"product_id": "pen", "quantity": 10, "price": 1.25}]}]}]}`
+
+	tests := []struct {
+		name    string
+		data    string
+		format  string
+		wantErr bool
+	}{
+		{"yaml", yamlDoc, "yaml", false},
+		{"json", jsonDoc, "json", false},
+		{"empty yaml", "", "yaml", false},
+		{"unknown field", "users:\n  - name: Ada\n    emial: ada@example.com\n", "yaml", true},
+		{"invalid email", `{"users": [{"name": "Ada", "email": "ada"}]}`, "json", true},
+		{"duplicate email", `{"users": [{"name": "Ada", "email": "ada@example.com"}, {"name": "Ada", "email": "ADA@example.com"}]}`, "json", true},
+		{"order without items", `{"users": [{"name": "Ada", "email": "ada@example.com", "orders": [{}]}]}`, "json", true},
+		{"unsupported format", "users: []", "toml", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseInvalidStatus(t *testing.T) {
+	_, err := Parse([]byte(`{"users": [{"name": "Ada", "email": "ada@example.com",
+		"orders": [{"status": "lost", "items": [{"product_id": "pen", "quantity": 1, "price": 1}]}]}]}`), "json")
+	if !errors.Is(err, domain.ErrInvalidOrderStatus) {
+		t.Errorf("Parse() error = %v, want ErrInvalidOrderStatus", err)
+	}
+}
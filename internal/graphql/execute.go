@@ -0,0 +1,796 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Validation
+// ═══════════════════════════════════════════════════════════════════════════════
+
+type validator struct {
+	schema   *Schema
+	doc      *document
+	op       *operation
+	maxDepth int
+	errors   []*Error
+	tooDeep  bool
+}
+
+// validate checks op against the schema before anything is resolved
+func validate(s *Schema, doc *document, op *operation, maxDepth int) []*Error {
+	v := &validator{schema: s, doc: doc, op: op, maxDepth: maxDepth}
+	if op.kind != "query" {
+		v.errorf(op.loc, "Only query operations are supported; %s is not available.", op.kind)
+		return v.errors
+	}
+
+	seen := make(map[string]bool)
+	for _, def := range op.vars {
+		if seen[def.name] {
+			v.errorf(def.loc, "There can be only one variable named \"$%s\".", def.name)
+		}
+		seen[def.name] = true
+		if t := s.inputType(def.typ); t == nil {
+			v.errorf(def.loc, "Variable \"$%s\" cannot be of type %q (unknown or not an input type).", def.name, def.typ)
+		} else if def.def != nil {
+			v.value(*def.def, t)
+		}
+	}
+
+	v.selections(s.query, op.sel, 0, nil)
+	return v.errors
+}
+
+func (v *validator) errorf(loc Location, format string, args ...any) {
+	v.errors = append(v.errors, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+func (v *validator) selections(t *Type, sels []selection, depth int, fragments []string) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *fieldNode:
+			v.directives(sel.directives)
+			v.field(t, sel, depth+1, fragments)
+		case *fragmentSpread:
+			v.directives(sel.directives)
+			frag, ok := v.doc.fragments[sel.name]
+			if !ok {
+				v.errorf(sel.loc, "Unknown fragment %q.", sel.name)
+				continue
+			}
+			if contains(fragments, sel.name) {
+				v.errorf(sel.loc, "Cannot spread fragment %q within itself.", sel.name)
+				continue
+			}
+			if v.typeCondition(sel.loc, frag.typeCond, t) {
+				v.selections(t, frag.sel, depth, append(fragments, sel.name))
+			}
+		case *inlineFragment:
+			v.directives(sel.directives)
+			if sel.typeCond == "" || v.typeCondition(sel.loc, sel.typeCond, t) {
+				v.selections(t, sel.sel, depth, fragments)
+			}
+		}
+	}
+}
+
+// typeCondition reports whether a fragment on cond applies to objects of type t
+func (v *validator) typeCondition(loc Location, cond string, t *Type) bool {
+	if _, ok := v.schema.types[cond]; !ok {
+		v.errorf(loc, "Unknown type %q.", cond)
+		return false
+	}
+	if cond != t.Name {
+		v.errorf(loc, "Fragment cannot be spread here as objects of type %q can never be of type %q.", t.Name, cond)
+		return false
+	}
+	return true
+}
+
+func (v *validator) field(t *Type, node *fieldNode, depth int, fragments []string) {
+	if v.maxDepth > 0 && depth > v.maxDepth {
+		if !v.tooDeep {
+			v.tooDeep = true
+			v.errorf(node.loc, "Query is nested too deeply (maximum depth is %d).", v.maxDepth)
+		}
+		return
+	}
+
+	if node.name == "__typename" {
+		if len(node.args) > 0 || len(node.sel) > 0 {
+			v.errorf(node.loc, "Field \"__typename\" takes no arguments or subfields.")
+		}
+		return
+	}
+	f := t.Field(node.name)
+	if f == nil {
+		v.errorf(node.loc, "Cannot query field %q on type %q.", node.name, t.Name)
+		return
+	}
+
+	given := make(map[string]bool)
+	for _, arg := range node.args {
+		if given[arg.name] {
+			v.errorf(arg.loc, "There can be only one argument named %q.", arg.name)
+		}
+		given[arg.name] = true
+		def := findArgument(f.Args, arg.name)
+		if def == nil {
+			v.errorf(arg.loc, "Unknown argument %q on field \"%s.%s\".", arg.name, t.Name, node.name)
+			continue
+		}
+		v.value(arg.val, def.Type)
+	}
+	for _, def := range f.Args {
+		if def.Type.Kind == KindNonNull && def.Default == nil && !given[def.Name] {
+			v.errorf(node.loc, "Field %q argument %q of type %q is required, but it was not provided.", node.name, def.Name, def.Type)
+		}
+	}
+
+	switch {
+	case f.Type.isLeaf() && len(node.sel) > 0:
+		v.errorf(node.loc, "Field %q must not have a selection since type %q has no subfields.", node.name, f.Type)
+	case !f.Type.isLeaf() && len(node.sel) == 0:
+		v.errorf(node.loc, "Field %q of type %q must have a selection of subfields.", node.name, f.Type)
+	case !f.Type.isLeaf():
+		v.selections(f.Type.named(), node.sel, depth, fragments)
+	}
+}
+
+// directives accepts @skip(if:) and @include(if:)
+func (v *validator) directives(dirs []*directive) {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			v.errorf(d.loc, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			v.errorf(d.loc, "Directive \"@%s\" takes exactly one argument \"if\".", d.name)
+			continue
+		}
+		v.value(d.args[0].val, NonNull(Boolean))
+	}
+}
+
+// value checks a literal or variable against an input type
+func (v *validator) value(val value, t *Type) {
+	if val.kind == valueVariable {
+		def := v.variable(val.raw)
+		if def == nil {
+			v.errorf(val.loc, "Variable \"$%s\" is not defined.", val.raw)
+		} else if !variableFits(def.typ, def.def != nil, t) {
+			v.errorf(val.loc, "Variable \"$%s\" of type %q used in position expecting type %q.", val.raw, def.typ, t)
+		}
+		return
+	}
+	if val.kind == valueNull {
+		if t.Kind == KindNonNull {
+			v.errorf(val.loc, "Expected value of type %q, found null.", t)
+		}
+		return
+	}
+	if t.Kind == KindNonNull {
+		t = t.Of
+	}
+	if t.Kind == KindList {
+		if val.kind != valueList {
+			v.value(val, t.Of) // A single value is coerced to a list of one
+			return
+		}
+		for _, item := range val.list {
+			v.value(item, t.Of)
+		}
+		return
+	}
+
+	ok := false
+	switch t {
+	case Int:
+		_, err := strconv.ParseInt(val.raw, 10, 32)
+		ok = val.kind == valueInt && err == nil
+	case Float:
+		ok = val.kind == valueInt || val.kind == valueFloat
+	case String:
+		ok = val.kind == valueString
+	case ID:
+		ok = val.kind == valueString || val.kind == valueInt
+	case Boolean:
+		ok = val.kind == valueBoolean
+	default:
+		ok = t.Kind == KindEnum && val.kind == valueEnum && contains(t.Values, val.raw)
+	}
+	if !ok {
+		v.errorf(val.loc, "Expected value of type %q, found %s.", t, describeValue(val))
+	}
+}
+
+func (v *validator) variable(name string) *varDef {
+	for _, def := range v.op.vars {
+		if def.name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// variableFits reports whether a variable of type ref may be used where t is expected
+func variableFits(ref *typeRef, hasDefault bool, t *Type) bool {
+	if t.Kind == KindNonNull {
+		if !ref.nonNull && !hasDefault {
+			return false
+		}
+		t = t.Of
+	}
+	if t.Kind == KindList {
+		return ref.list != nil && variableFits(&typeRef{name: ref.list.name, list: ref.list.list, nonNull: ref.list.nonNull}, false, t.Of)
+	}
+	return ref.list == nil && ref.name == t.Name
+}
+
+func describeValue(val value) string {
+	switch val.kind {
+	case valueString:
+		return strconv.Quote(val.raw)
+	case valueList:
+		return "a list"
+	case valueObject:
+		return "an object"
+	}
+	return val.raw
+}
+
+func findArgument(args []*Argument, name string) *Argument {
+	for _, a := range args {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// inputType resolves a variable's declared type; nil if unknown or an object
+func (s *Schema) inputType(ref *typeRef) *Type {
+	var t *Type
+	if ref.list != nil {
+		inner := s.inputType(ref.list)
+		if inner == nil {
+			return nil
+		}
+		t = ListOf(inner)
+	} else {
+		t = s.types[ref.name]
+		if t == nil || t.Kind == KindObject {
+			return nil
+		}
+	}
+	if ref.nonNull {
+		t = NonNull(t)
+	}
+	return t
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Input Coercion
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// coerceVariables converts the request's JSON variables to argument values
+func coerceVariables(s *Schema, op *operation, provided map[string]any) (map[string]any, error) {
+	vars := make(map[string]any)
+	for _, def := range op.vars {
+		t := s.inputType(def.typ)
+		raw, ok := provided[def.name]
+		switch {
+		case ok:
+			v, err := coerceJSON(raw, t)
+			if err != nil {
+				return nil, &Error{Message: fmt.Sprintf("Variable \"$%s\" got invalid value: %v.", def.name, err), Locations: []Location{def.loc}}
+			}
+			vars[def.name] = v
+		case def.def != nil:
+			v, _ := valueFromAST(*def.def, t, nil)
+			vars[def.name] = v
+		case def.typ.nonNull:
+			return nil, &Error{Message: fmt.Sprintf("Variable \"$%s\" of required type %q was not provided.", def.name, def.typ), Locations: []Location{def.loc}}
+		}
+	}
+	return vars, nil
+}
+
+// coerceJSON converts a decoded JSON variable value to an input type
+func coerceJSON(raw any, t *Type) (any, error) {
+	if raw == nil {
+		if t.Kind == KindNonNull {
+			return nil, fmt.Errorf("expected non-null %s", t)
+		}
+		return nil, nil
+	}
+	if t.Kind == KindNonNull {
+		t = t.Of
+	}
+	if t.Kind == KindList {
+		items, ok := raw.([]any)
+		if !ok {
+			items = []any{raw}
+		}
+		list := make([]any, len(items))
+		for i, item := range items {
+			v, err := coerceJSON(item, t.Of)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	}
+
+	switch t {
+	case Int:
+		if n, ok := raw.(float64); ok && n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+			return int(n), nil
+		}
+	case Float:
+		if n, ok := raw.(float64); ok {
+			return n, nil
+		}
+	case String:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+	case ID:
+		switch v := raw.(type) {
+		case string:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) {
+				return strconv.FormatInt(int64(v), 10), nil
+			}
+		}
+	case Boolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+	default:
+		if s, ok := raw.(string); ok && t.Kind == KindEnum && contains(t.Values, s) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, found %v", t, raw)
+}
+
+// valueFromAST converts a validated literal or variable to an argument value
+func valueFromAST(val value, t *Type, vars map[string]any) (any, error) {
+	if val.kind == valueVariable {
+		v, ok := vars[val.raw]
+		if !ok || v == nil {
+			if t.Kind == KindNonNull {
+				return nil, fmt.Errorf("variable \"$%s\" of non-null type %s has no value", val.raw, t)
+			}
+			return nil, nil
+		}
+		return v, nil
+	}
+	if val.kind == valueNull {
+		return nil, nil
+	}
+	if t.Kind == KindNonNull {
+		t = t.Of
+	}
+	if t.Kind == KindList {
+		items := val.list
+		if val.kind != valueList {
+			items = []value{val}
+		}
+		list := make([]any, len(items))
+		for i, item := range items {
+			v, err := valueFromAST(item, t.Of, vars)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	}
+
+	switch t {
+	case Int:
+		return strconv.Atoi(val.raw)
+	case Float:
+		return strconv.ParseFloat(val.raw, 64)
+	case Boolean:
+		return val.raw == "true", nil
+	}
+	return val.raw, nil // IDs, strings and enums
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Execution
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// nullMarker stands for a null caused by an error already reported, which
+// nulls the nearest nullable parent when it reaches a non-null position
+type nullMarker struct{}
+
+var nullPropagated any = nullMarker{}
+
+// object is a result object with fields in selection order
+type object []resultField
+
+type resultField struct {
+	key   string
+	value any
+}
+
+// MarshalJSON writes the fields in order
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+type executor struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]any
+	opts   execOptions
+	errors []*Error
+}
+
+// fieldGroup is the fields selected under one response key
+type fieldGroup struct {
+	key   string
+	nodes []*fieldNode
+}
+
+// executeObject resolves sels for every parent object of type t, returning
+// one result object (or nullPropagated) per parent
+func (e *executor) executeObject(ctx context.Context, t *Type, parents []any, paths [][]any, sels []selection) []any {
+	results := make([]any, len(parents))
+	if len(parents) == 0 {
+		return results
+	}
+
+	objects := make([]object, len(parents))
+	nulled := make([]bool, len(parents))
+	for _, g := range e.collectFields(sels, nil, nil) {
+		node := g.nodes[0]
+		if node.name == "__typename" {
+			for i := range objects {
+				objects[i] = append(objects[i], resultField{g.key, t.Name})
+			}
+			continue
+		}
+
+		f := t.fields[node.name]
+		fieldPaths := make([][]any, len(parents))
+		for i := range parents {
+			fieldPaths[i] = appendPath(paths[i], g.key)
+		}
+		values := e.resolve(ctx, f, node, parents, fieldPaths)
+
+		var sub []selection
+		for _, n := range g.nodes {
+			sub = append(sub, n.sel...)
+		}
+		completed := e.complete(ctx, f.Type, t.Name+"."+node.name, node, values, fieldPaths, sub)
+		for i, v := range completed {
+			if v == nullPropagated {
+				nulled[i] = nulled[i] || f.Type.Kind == KindNonNull
+				v = nil
+			}
+			objects[i] = append(objects[i], resultField{g.key, v})
+		}
+	}
+
+	for i := range results {
+		if nulled[i] {
+			results[i] = nullPropagated
+		} else {
+			results[i] = objects[i]
+		}
+	}
+	return results
+}
+
+// collectFields flattens fragments and applies @skip/@include, grouping
+// fields by response key in selection order
+func (e *executor) collectFields(sels []selection, groups []*fieldGroup, visited map[string]bool) []*fieldGroup {
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *fieldNode:
+			if !e.included(sel.directives) {
+				continue
+			}
+			found := false
+			for _, g := range groups {
+				if g.key == sel.key() {
+					g.nodes = append(g.nodes, sel)
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, &fieldGroup{key: sel.key(), nodes: []*fieldNode{sel}})
+			}
+		case *fragmentSpread:
+			if visited[sel.name] || !e.included(sel.directives) {
+				continue
+			}
+			visited[sel.name] = true
+			frag := e.doc.fragments[sel.name]
+			if e.included(frag.directives) {
+				groups = e.collectFields(frag.sel, groups, visited)
+			}
+		case *inlineFragment:
+			if e.included(sel.directives) {
+				groups = e.collectFields(sel.sel, groups, visited)
+			}
+		}
+	}
+	return groups
+}
+
+// included evaluates @skip and @include
+func (e *executor) included(dirs []*directive) bool {
+	for _, d := range dirs {
+		v, _ := valueFromAST(d.args[0].val, Boolean, e.vars)
+		cond, _ := v.(bool)
+		if (d.name == "skip" && cond) || (d.name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve calls the field's resolver for every parent. When the resolver
+// fails the field is null for the whole batch, reported once at the first
+// parent's path; errors returned as values fail their parent alone.
+func (e *executor) resolve(ctx context.Context, f *Field, node *fieldNode, parents []any, paths [][]any) []any {
+	fail := func(err error) []any {
+		e.fieldError(err, node, paths[0])
+		values := make([]any, len(parents))
+		for i := range values {
+			values[i] = nullPropagated
+		}
+		return values
+	}
+
+	args := make(Args, len(f.Args))
+	for _, def := range f.Args {
+		args[def.Name] = def.Default
+		for _, arg := range node.args {
+			if arg.name != def.Name {
+				continue
+			}
+			if arg.val.kind == valueVariable {
+				if _, ok := e.vars[arg.val.raw]; !ok {
+					break // Unset variables leave the default
+				}
+			}
+			v, err := valueFromAST(arg.val, def.Type, e.vars)
+			if err != nil {
+				return fail(&Error{Message: fmt.Sprintf("Argument %q: %v.", def.Name, err)})
+			}
+			args[def.Name] = v
+		}
+	}
+
+	if f.Resolve == nil {
+		return fail(fmt.Errorf("field %q has no resolver", node.name))
+	}
+	values, err := f.Resolve(ctx, parents, args)
+	if err != nil {
+		return fail(err)
+	}
+	if len(values) != len(parents) {
+		return fail(fmt.Errorf("field %q resolved %d values for %d parents", node.name, len(values), len(parents)))
+	}
+	for i, v := range values {
+		if err, ok := v.(error); ok {
+			e.fieldError(err, node, paths[i])
+			values[i] = nullPropagated
+		}
+	}
+	return values
+}
+
+// fieldError records a resolver error at path
+func (e *executor) fieldError(err error, node *fieldNode, path []any) {
+	gqlErr := e.opts.formatError(err)
+	gqlErr.Path = path
+	gqlErr.Locations = []Location{node.loc}
+	e.errors = append(e.errors, gqlErr)
+}
+
+// complete turns resolved values into result values of type t
+func (e *executor) complete(ctx context.Context, t *Type, field string, node *fieldNode, values []any, paths [][]any, sels []selection) []any {
+	switch t.Kind {
+	case KindNonNull:
+		inner := e.complete(ctx, t.Of, field, node, values, paths, sels)
+		for i, v := range inner {
+			if v == nil {
+				e.errors = append(e.errors, &Error{
+					Message:   fmt.Sprintf("Cannot return null for non-null field %s.", field),
+					Locations: []Location{node.loc},
+					Path:      paths[i],
+				})
+				inner[i] = nullPropagated
+			}
+		}
+		return inner
+
+	case KindList:
+		results := make([]any, len(values))
+		var items []any
+		var itemPaths [][]any
+		lengths := make([]int, len(values))
+		for i, v := range values {
+			if v == nil || v == nullPropagated {
+				results[i] = v
+				lengths[i] = -1
+				continue
+			}
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				e.errors = append(e.errors, &Error{
+					Message:   fmt.Sprintf("Expected a list for field %s, got %T.", field, v),
+					Locations: []Location{node.loc},
+					Path:      paths[i],
+				})
+				results[i] = nullPropagated
+				lengths[i] = -1
+				continue
+			}
+			lengths[i] = rv.Len()
+			for j := 0; j < rv.Len(); j++ {
+				items = append(items, rv.Index(j).Interface())
+				itemPaths = append(itemPaths, appendPath(paths[i], j))
+			}
+		}
+
+		// Every item at this level is completed together, keeping nested fields batched
+		completed := e.complete(ctx, t.Of, field, node, items, itemPaths, sels)
+		next := 0
+		for i, n := range lengths {
+			if n < 0 {
+				continue
+			}
+			list := make([]any, n)
+			for j := range list {
+				item := completed[next+j]
+				if item == nullPropagated {
+					if t.Of.Kind == KindNonNull {
+						list = nil
+						break
+					}
+					item = nil
+				}
+				list[j] = item
+			}
+			next += n
+			if list == nil && n > 0 {
+				results[i] = nullPropagated
+			} else {
+				results[i] = list
+			}
+		}
+		return results
+
+	case KindObject:
+		results := make([]any, len(values))
+		var parents []any
+		var parentPaths [][]any
+		var index []int
+		for i, v := range values {
+			if v == nil || v == nullPropagated || isNilPointer(v) {
+				if !isNilPointer(v) {
+					results[i] = v
+				}
+				continue
+			}
+			parents = append(parents, v)
+			parentPaths = append(parentPaths, paths[i])
+			index = append(index, i)
+		}
+		for j, obj := range e.executeObject(ctx, t, parents, parentPaths, sels) {
+			results[index[j]] = obj
+		}
+		return results
+
+	default: // Scalars and enums
+		results := make([]any, len(values))
+		for i, v := range values {
+			if v == nil || v == nullPropagated || isNilPointer(v) {
+				if !isNilPointer(v) {
+					results[i] = v
+				}
+				continue
+			}
+			out, err := serialize(t, v)
+			if err != nil {
+				e.errors = append(e.errors, &Error{
+					Message:   fmt.Sprintf("Cannot serialize field %s: %v.", field, err),
+					Locations: []Location{node.loc},
+					Path:      paths[i],
+				})
+				out = nullPropagated
+			}
+			results[i] = out
+		}
+		return results
+	}
+}
+
+// serialize converts a resolved Go value to a scalar or enum result
+func serialize(t *Type, v any) (any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch {
+	case t == Int && rv.CanInt():
+		return rv.Int(), nil
+	case t == Int && rv.CanUint():
+		return rv.Uint(), nil
+	case t == Float && rv.CanFloat():
+		return rv.Float(), nil
+	case t == Float && rv.CanInt():
+		return float64(rv.Int()), nil
+	case t == Boolean && rv.Kind() == reflect.Bool:
+		return rv.Bool(), nil
+	case (t == String || t == ID) && rv.Kind() == reflect.String:
+		return rv.String(), nil
+	case t == ID && rv.CanInt():
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case t.Kind == KindEnum && rv.Kind() == reflect.String:
+		if !contains(t.Values, rv.String()) {
+			return nil, fmt.Errorf("%q is not a %s value", rv.String(), t.Name)
+		}
+		return rv.String(), nil
+	}
+	if s, ok := v.(fmt.Stringer); ok && t == String {
+		return s.String(), nil
+	}
+	return nil, fmt.Errorf("%T is not a %s", v, t)
+}
+
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+func appendPath(path []any, segment any) []any {
+	p := make([]any, len(path)+1)
+	copy(p, path)
+	p[len(path)] = segment
+	return p
+}
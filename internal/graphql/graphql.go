@@ -0,0 +1,382 @@
+// Package graphql is a small GraphQL query executor for schemas defined in Go.
+//
+// Queries are parsed, validated against the schema and executed level by
+// level: a field is resolved once for every parent object at its depth, so
+// orders { user { name } } loads the users of all orders in one call rather
+// than one call per order. Combined with a per-request Loader (batching and
+// caching by key), nested queries never issue N+1 repository calls.
+//
+// Only query operations are supported; mutations and subscriptions are
+// rejected, as is introspection beyond __typename (Schema.SDL renders the
+// schema instead).
+//
+// The package exists instead of a GraphQL library because the API needs so
+// little of the language: a read-only schema over the usecase layer, with
+// batching that has to go through the repositories' GetByIDs anyway. A
+// library would bring a schema language, introspection and a resolver model
+// of its own for that. The parser accepts only executable documents and
+// bounds how deeply they nest, so untrusted queries cannot exhaust the stack
+// before validation applies WithMaxDepth.
+//
+// Example:
+//
+//	user := graphql.NewObject("User", "A registered user")
+//	user.AddField("name", &graphql.Field{Type: graphql.NonNull(graphql.String),
+//		Resolve: graphql.Each(func(ctx context.Context, u *domain.User, args graphql.Args) (any, error) {
+//			return u.Name, nil
+//		})})
+//	schema := graphql.NewSchema(query)
+//	resp := schema.Execute(ctx, graphql.Request{Query: `{ users { name } }`})
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kind distinguishes the types of the type system
+type Kind int
+
+// Type kinds
+const (
+	KindScalar Kind = iota + 1
+	KindEnum
+	KindObject
+	KindList
+	KindNonNull
+)
+
+// Type is a scalar, enum, object, list or non-null type
+type Type struct {
+	Kind        Kind
+	Name        string // Scalars, enums and objects
+	Description string
+	Of          *Type    // Lists and non-null types
+	Values      []string // Enums
+
+	fields     map[string]*Field // Objects
+	fieldOrder []string
+}
+
+// Built-in scalars. IDs and strings serialize Go strings, ints serialize Go
+// integers and floats serialize Go floats or integers.
+var (
+	ID      = &Type{Kind: KindScalar, Name: "ID"}
+	String  = &Type{Kind: KindScalar, Name: "String"}
+	Int     = &Type{Kind: KindScalar, Name: "Int"}
+	Float   = &Type{Kind: KindScalar, Name: "Float"}
+	Boolean = &Type{Kind: KindScalar, Name: "Boolean"}
+)
+
+var builtinScalars = []*Type{ID, String, Int, Float, Boolean}
+
+// NewObject creates an object type; add its fields with AddField (fields
+// may refer back to the object, e.g. User.orders and Order.user)
+func NewObject(name, description string) *Type {
+	return &Type{Kind: KindObject, Name: name, Description: description, fields: make(map[string]*Field)}
+}
+
+// NewEnum creates an enum type serializing Go strings (or string-kinded types)
+func NewEnum(name, description string, values ...string) *Type {
+	return &Type{Kind: KindEnum, Name: name, Description: description, Values: values}
+}
+
+// ListOf returns the list type of t
+func ListOf(t *Type) *Type { return &Type{Kind: KindList, Of: t} }
+
+// NonNull returns the non-null type of t
+func NonNull(t *Type) *Type { return &Type{Kind: KindNonNull, Of: t} }
+
+// AddField adds a field to an object type
+func (t *Type) AddField(name string, f *Field) {
+	if _, dup := t.fields[name]; !dup {
+		t.fieldOrder = append(t.fieldOrder, name)
+	}
+	t.fields[name] = f
+}
+
+// Field returns the object type's field called name, or nil
+func (t *Type) Field(name string) *Field {
+	return t.fields[name]
+}
+
+// String renders the type as in the schema language, e.g. [Order!]!
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindList:
+		return "[" + t.Of.String() + "]"
+	case KindNonNull:
+		return t.Of.String() + "!"
+	}
+	return t.Name
+}
+
+// named returns the scalar, enum or object type under list and non-null wrappers
+func (t *Type) named() *Type {
+	for t.Kind == KindList || t.Kind == KindNonNull {
+		t = t.Of
+	}
+	return t
+}
+
+// isLeaf reports whether values of t have no selection set
+func (t *Type) isLeaf() bool {
+	k := t.named().Kind
+	return k == KindScalar || k == KindEnum
+}
+
+// Field is a field of an object type
+type Field struct {
+	Type        *Type
+	Description string
+	Args        []*Argument
+	// Resolve returns the field's value for every parent at once, in the
+	// same order (see Each for fields resolved per parent)
+	Resolve ResolveFunc
+}
+
+// Argument is a field argument. Arguments must be scalars, enums or lists of them.
+type Argument struct {
+	Name        string
+	Type        *Type
+	Default     any // Used when the argument is omitted; nil for none
+	Description string
+}
+
+// Args are a field's coerced arguments: every declared argument is present,
+// holding its value, default or nil. Ints are int, floats float64, IDs,
+// strings and enums string, booleans bool and lists []any.
+type Args map[string]any
+
+// Int returns an Int argument, or 0 when it is null
+func (a Args) Int(name string) int {
+	n, _ := a[name].(int)
+	return n
+}
+
+// String returns an ID, String or enum argument, or "" when it is null
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// ResolveFunc resolves a field for a batch of parents (nil for root
+// fields), returning one value per parent: a Go value for scalars and enums,
+// an object for object types (handed to that type's resolvers), a slice for
+// list types, nil for null, or an error failing that parent alone. A returned
+// error fails the field for the whole batch.
+type ResolveFunc func(ctx context.Context, parents []any, args Args) ([]any, error)
+
+// Each adapts a per-parent resolver; an error fails only its parent. It suits
+// fields read straight off the parent; fields that load data should batch
+// (see Loader).
+func Each[P any](fn func(ctx context.Context, parent P, args Args) (any, error)) ResolveFunc {
+	return func(ctx context.Context, parents []any, args Args) ([]any, error) {
+		values := make([]any, len(parents))
+		for i, p := range parents {
+			parent, _ := p.(P)
+			v, err := fn(ctx, parent, args)
+			if err != nil {
+				v = err
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+}
+
+// Schema is an executable schema rooted at a query type
+type Schema struct {
+	query *Type
+	types map[string]*Type // Named types reachable from the query type
+	order []*Type          // Object and enum types in discovery order, for SDL
+}
+
+// NewSchema creates a schema rooted at query, which must be an object type
+func NewSchema(query *Type) *Schema {
+	s := &Schema{query: query, types: make(map[string]*Type)}
+	for _, t := range builtinScalars {
+		s.types[t.Name] = t
+	}
+
+	queue := []*Type{query}
+	for len(queue) > 0 {
+		t := queue[0].named()
+		queue = queue[1:]
+		if _, seen := s.types[t.Name]; seen {
+			continue
+		}
+		s.types[t.Name] = t
+		s.order = append(s.order, t)
+		for _, name := range t.fieldOrder {
+			f := t.fields[name]
+			queue = append(queue, f.Type)
+			for _, arg := range f.Args {
+				queue = append(queue, arg.Type)
+			}
+		}
+	}
+	return s
+}
+
+// SDL renders the schema in the schema definition language, for clients
+// and documentation in lieu of introspection
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	for i, t := range s.order {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeDescription(&b, "", t.Description)
+		if t.Kind == KindEnum {
+			fmt.Fprintf(&b, "enum %s {\n", t.Name)
+			for _, v := range t.Values {
+				fmt.Fprintf(&b, "  %s\n", v)
+			}
+			b.WriteString("}\n")
+			continue
+		}
+
+		fmt.Fprintf(&b, "type %s {\n", t.Name)
+		for _, name := range t.fieldOrder {
+			f := t.fields[name]
+			writeDescription(&b, "  ", f.Description)
+			fmt.Fprintf(&b, "  %s", name)
+			if len(f.Args) > 0 {
+				args := make([]string, len(f.Args))
+				for j, a := range f.Args {
+					args[j] = a.Name + ": " + a.Type.String()
+					if a.Default != nil {
+						def, _ := json.Marshal(a.Default)
+						args[j] += " = " + string(def)
+					}
+				}
+				fmt.Fprintf(&b, "(%s)", strings.Join(args, ", "))
+			}
+			fmt.Fprintf(&b, ": %s\n", f.Type)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description != "" {
+		fmt.Fprintf(b, "%s%q\n", indent, description)
+	}
+}
+
+// Request is a GraphQL request as posted by clients
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response. Data is absent when the request failed
+// before execution (syntax, validation or variable errors).
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Executed reports whether the request got as far as execution; requests
+// that did not are the client's fault (HTTP 400)
+func (r *Response) Executed() bool {
+	return r.Data != nil
+}
+
+// Error is a GraphQL error
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Option configures Execute
+type Option func(*execOptions)
+
+type execOptions struct {
+	maxDepth    int
+	formatError func(err error) *Error
+}
+
+// WithMaxDepth rejects queries nesting fields deeper than depth (0 allows any depth)
+func WithMaxDepth(depth int) Option {
+	return func(o *execOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// WithErrorFormatter turns resolver errors into GraphQL errors (path and
+// locations are filled in afterwards), e.g. to hide internal details
+func WithErrorFormatter(fn func(err error) *Error) Option {
+	return func(o *execOptions) {
+		o.formatError = fn
+	}
+}
+
+// Execute parses, validates and runs req against the schema
+func (s *Schema) Execute(ctx context.Context, req Request, opts ...Option) *Response {
+	o := execOptions{formatError: func(err error) *Error { return &Error{Message: err.Error()} }}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	doc, err := parse(req.Query)
+	if err != nil {
+		return requestError(err)
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return requestError(err)
+	}
+	if errs := validate(s, doc, op, o.maxDepth); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	vars, err := coerceVariables(s, op, req.Variables)
+	if err != nil {
+		return requestError(err)
+	}
+
+	e := &executor{schema: s, doc: doc, vars: vars, opts: o}
+	data := e.executeObject(ctx, s.query, []any{nil}, [][]any{nil}, op.sel)[0]
+	resp := &Response{Data: data, Errors: e.errors}
+	if data == nullPropagated {
+		resp.Data = json.RawMessage("null")
+	}
+	return resp
+}
+
+// requestError turns a failure before execution into a response
+func requestError(err error) *Response {
+	gqlErr := &Error{Message: err.Error()}
+	if se, ok := err.(*syntaxError); ok {
+		gqlErr.Locations = []Location{se.loc}
+	}
+	if ge, ok := err.(*Error); ok {
+		gqlErr = ge
+	}
+	return &Response{Errors: []*Error{gqlErr}}
+}
+
+// selectOperation picks the operation to run
+func selectOperation(doc *document, name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations."}
+		}
+		return doc.operations[0], nil
+	}
+	i := slices.IndexFunc(doc.operations, func(op *operation) bool { return op.name == name })
+	if i < 0 {
+		return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q.", name)}
+	}
+	return doc.operations[i], nil
+}
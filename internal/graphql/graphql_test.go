@@ -0,0 +1,355 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testAuthor struct {
+	ID   string
+	Name string
+}
+
+type testPost struct {
+	ID       string
+	AuthorID string
+	Title    string
+	Status   string
+}
+
+// testSchema is Query{author, authors, posts} with Author.posts and Post.author
+// resolved per batch, counting the batch calls
+type testSchema struct {
+	schema      *Schema
+	authors     []*testAuthor
+	posts       []*testPost
+	postBatches int
+}
+
+func newTestSchema() *testSchema {
+	ts := &testSchema{
+		authors: []*testAuthor{{ID: "a1", Name: "Ada"}, {ID: "a2", Name: "Grace"}, {ID: "a3", Name: ""}},
+		posts: []*testPost{
+			{ID: "p1", AuthorID: "a1", Title: "Engines", Status: "PUBLISHED"},
+			{ID: "p2", AuthorID: "a1", Title: "Notes", Status: "DRAFT"},
+			{ID: "p3", AuthorID: "a2", Title: "Compilers", Status: "PUBLISHED"},
+		},
+	}
+
+	status := NewEnum("PostStatus", "", "DRAFT", "PUBLISHED")
+	author := NewObject("Author", "Writes posts")
+	post := NewObject("Post", "")
+
+	author.AddField("id", &Field{Type: NonNull(ID), Resolve: Each(func(_ context.Context, a *testAuthor, _ Args) (any, error) {
+		return a.ID, nil
+	})})
+	author.AddField("name", &Field{Type: NonNull(String), Resolve: Each(func(_ context.Context, a *testAuthor, _ Args) (any, error) {
+		if a.Name == "" {
+			return nil, nil // Breaks the non-null contract on purpose
+		}
+		return a.Name, nil
+	})})
+	author.AddField("posts", &Field{
+		Type: NonNull(ListOf(NonNull(post))),
+		Args: []*Argument{{Name: "limit", Type: Int, Default: 10}},
+		Resolve: func(_ context.Context, parents []any, args Args) ([]any, error) {
+			ts.postBatches++
+			values := make([]any, len(parents))
+			for i, p := range parents {
+				var posts []*testPost
+				for _, post := range ts.posts {
+					if post.AuthorID == p.(*testAuthor).ID && len(posts) < args.Int("limit") {
+						posts = append(posts, post)
+					}
+				}
+				values[i] = posts
+			}
+			return values, nil
+		},
+	})
+
+	post.AddField("id", &Field{Type: NonNull(ID), Resolve: Each(func(_ context.Context, p *testPost, _ Args) (any, error) {
+		return p.ID, nil
+	})})
+	post.AddField("title", &Field{Type: String, Resolve: Each(func(_ context.Context, p *testPost, _ Args) (any, error) {
+		if p.Title == "Notes" {
+			return nil, errors.New("notes are private")
+		}
+		return p.Title, nil
+	})})
+	post.AddField("status", &Field{Type: NonNull(status), Resolve: Each(func(_ context.Context, p *testPost, _ Args) (any, error) {
+		return p.Status, nil
+	})})
+	post.AddField("author", &Field{Type: author, Resolve: Each(func(_ context.Context, p *testPost, _ Args) (any, error) {
+		return ts.author(p.AuthorID), nil
+	})})
+
+	query := NewObject("Query", "")
+	query.AddField("author", &Field{
+		Type: author,
+		Args: []*Argument{{Name: "id", Type: NonNull(ID)}},
+		Resolve: Each(func(_ context.Context, _ any, args Args) (any, error) {
+			return ts.author(args.String("id")), nil
+		}),
+	})
+	query.AddField("authors", &Field{Type: NonNull(ListOf(NonNull(author))), Resolve: Each(func(context.Context, any, Args) (any, error) {
+		return ts.authors[:2], nil
+	})})
+	query.AddField("everyone", &Field{Type: ListOf(NonNull(author)), Resolve: Each(func(context.Context, any, Args) (any, error) {
+		return ts.authors, nil
+	})})
+
+	ts.schema = NewSchema(query)
+	return ts
+}
+
+func (ts *testSchema) author(id string) *testAuthor {
+	for _, a := range ts.authors {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func execute(t *testing.T, ts *testSchema, req Request, opts ...Option) (string, []*Error) {
+	t.Helper()
+	resp := ts.schema.Execute(context.Background(), req, opts...)
+	if !resp.Executed() {
+		return "", resp.Errors
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("marshal data: %v", err)
+	}
+	return string(data), resp.Errors
+}
+
+func TestExecuteNestedQuery(t *testing.T) {
+	ts := newTestSchema()
+	data, errs := execute(t, ts, Request{Query: `{
+		authors { name posts(limit: 1) { id status author { name } } }
+	}`})
+	if len(errs) > 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	want := `{"authors":[` +
+		`{"name":"Ada","posts":[{"id":"p1","status":"PUBLISHED","author":{"name":"Ada"}}]},` +
+		`{"name":"Grace","posts":[{"id":"p3","status":"PUBLISHED","author":{"name":"Grace"}}]}]}`
+	if data != want {
+		t.Errorf("data = %s\nwant %s", data, want)
+	}
+	if ts.postBatches != 1 {
+		t.Errorf("Author.posts resolved in %d batches, want 1 for all authors", ts.postBatches)
+	}
+}
+
+func TestExecuteVariablesFragmentsAndAliases(t *testing.T) {
+	ts := newTestSchema()
+	data, errs := execute(t, ts, Request{
+		Query: `query Find($id: ID!, $withPosts: Boolean = false) {
+			first: author(id: $id) { ...names posts @include(if: $withPosts) { id } }
+			second: author(id: "a2") { ... on Author { id __typename } }
+		}
+		fragment names on Author { id name }`,
+		Variables: map[string]any{"id": "a1", "withPosts": true},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	want := `{"first":{"id":"a1","name":"Ada","posts":[{"id":"p1"},{"id":"p2"}]},"second":{"id":"a2","__typename":"Author"}}`
+	if data != want {
+		t.Errorf("data = %s\nwant %s", data, want)
+	}
+}
+
+func TestExecuteNullPropagation(t *testing.T) {
+	ts := newTestSchema()
+
+	// A resolver error nulls a nullable field and is reported with its path
+	data, errs := execute(t, ts, Request{Query: `{ author(id: "a1") { posts { title } } }`})
+	if want := `{"author":{"posts":[{"title":"Engines"},{"title":null}]}}`; data != want {
+		t.Errorf("data = %s\nwant %s", data, want)
+	}
+	if len(errs) != 1 || errs[0].Message != "notes are private" {
+		t.Fatalf("errors = %v, want the resolver error", errs)
+	}
+	if path, _ := json.Marshal(errs[0].Path); string(path) != `["author","posts",1,"title"]` {
+		t.Errorf("error path = %s", path)
+	}
+
+	// Null in a non-null field nulls the nearest nullable parent
+	data, errs = execute(t, ts, Request{Query: `{ everyone { name } }`})
+	if want := `{"everyone":null}`; data != want {
+		t.Errorf("data = %s\nwant %s", data, want)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "Author.name") {
+		t.Errorf("errors = %v, want a non-null violation", errs)
+	}
+
+	// Unknown IDs resolve to null without an error
+	data, errs = execute(t, ts, Request{Query: `{ author(id: "missing") { id } }`})
+	if want := `{"author":null}`; data != want || len(errs) > 0 {
+		t.Errorf("data = %s, errors = %v; want %s without errors", data, errs, want)
+	}
+}
+
+func TestExecuteRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     Request
+		wantErr string
+	}{
+		{"syntax", Request{Query: `{ authors { name }`}, "Syntax Error"},
+		{"unknown field", Request{Query: `{ authors { age } }`}, `Cannot query field "age" on type "Author"`},
+		{"missing selection", Request{Query: `{ authors }`}, "must have a selection of subfields"},
+		{"selection on leaf", Request{Query: `{ authors { name { x } } }`}, "must not have a selection"},
+		{"missing argument", Request{Query: `{ author { id } }`}, `argument "id" of type "ID!" is required`},
+		{"unknown argument", Request{Query: `{ authors(first: 1) { id } }`}, `Unknown argument "first"`},
+		{"bad literal", Request{Query: `{ author(id: "a1") { posts(limit: "two") { id } } }`}, `Expected value of type "Int"`},
+		{"undefined variable", Request{Query: `{ author(id: $id) { id } }`}, `Variable "$id" is not defined`},
+		{"variable type", Request{Query: `query($id: String!) { author(id: $id) { id } }`}, "used in position expecting type"},
+		{"missing variable", Request{Query: `query($id: ID!) { author(id: $id) { id } }`}, "was not provided"},
+		{"invalid variable", Request{Query: `query($n: Int) { authors { posts(limit: $n) { id } } }`, Variables: map[string]any{"n": "x"}}, "got invalid value"},
+		{"fragment cycle", Request{Query: `{ authors { ...a } } fragment a on Author { posts { author { ...a } } }`}, "within itself"},
+		{"unknown fragment", Request{Query: `{ authors { ...b } }`}, `Unknown fragment "b"`},
+		{"wrong type condition", Request{Query: `{ authors { ... on Post { id } } }`}, "can never be of type"},
+		{"mutation", Request{Query: `mutation { authors { id } }`}, "Only query operations are supported"},
+		{"unknown directive", Request{Query: `{ authors @cached { id } }`}, `Unknown directive "@cached"`},
+		{"ambiguous operation", Request{Query: `query A { authors { id } } query B { authors { id } }`}, "Must provide operation name"},
+		{"too deep", Request{Query: `{ authors { posts { author { posts { id } } } } }`}, "nested too deeply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestSchema()
+			data, errs := execute(t, ts, tt.req, WithMaxDepth(3))
+			if data != "" {
+				t.Errorf("data = %s, want none", data)
+			}
+			if len(errs) == 0 || !strings.Contains(errs[0].Message, tt.wantErr) {
+				t.Errorf("errors = %v, want %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteIntVariableFromJSON(t *testing.T) {
+	ts := newTestSchema()
+	var req Request
+	if err := json.Unmarshal([]byte(`{"query":"query($n: Int) { author(id: \"a1\") { posts(limit: $n) { id } } }","variables":{"n":1}}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	data, errs := execute(t, ts, req)
+	if want := `{"author":{"posts":[{"id":"p1"}]}}`; data != want || len(errs) > 0 {
+		t.Errorf("data = %s, errors = %v; want %s", data, errs, want)
+	}
+}
+
+func TestErrorFormatter(t *testing.T) {
+	ts := newTestSchema()
+	_, errs := execute(t, ts, Request{Query: `{ author(id: "a1") { posts { title } } }`},
+		WithErrorFormatter(func(err error) *Error {
+			return &Error{Message: "hidden", Extensions: map[string]any{"code": "FORBIDDEN"}}
+		}))
+	if len(errs) != 1 || errs[0].Message != "hidden" || errs[0].Extensions["code"] != "FORBIDDEN" || errs[0].Path == nil {
+		t.Errorf("errors = %+v, want the formatted error with its path", errs)
+	}
+}
+
+func TestLoader(t *testing.T) {
+	var calls [][]string
+	l := NewLoader(func(_ context.Context, keys []string) (map[string]int, error) {
+		calls = append(calls, keys)
+		values := make(map[string]int)
+		for _, k := range keys {
+			if k != "missing" {
+				values[k] = len(k)
+			}
+		}
+		return values, nil
+	})
+	ctx := context.Background()
+
+	got, err := l.LoadMany(ctx, []string{"a", "bb", "a", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["a"] != 1 || got["bb"] != 2 {
+		t.Errorf("LoadMany() = %v", got)
+	}
+
+	l.Prime("primed", 42)
+	if got, _ := l.LoadMany(ctx, []string{"bb", "missing", "primed"}); got["primed"] != 42 {
+		t.Errorf("LoadMany() = %v, want the primed value", got)
+	}
+	if len(calls) != 1 || len(calls[0]) != 3 {
+		t.Errorf("fetch calls = %v, want one call with the unique keys", calls)
+	}
+
+	failing := NewLoader(func(context.Context, []string) (map[string]int, error) {
+		return nil, errors.New("down")
+	})
+	if _, err := failing.LoadMany(ctx, []string{"a"}); err == nil {
+		t.Error("LoadMany() with failing fetch error = nil")
+	}
+}
+
+func TestParseLiterals(t *testing.T) {
+	doc, err := parse("\uFEFF# comment\nquery Q($a: [Int!]! = [1, 2], $s: String = \"tab\\t\\u00e9\") {\n  f(b: \"\"\"\n    block\n      indented\n  \"\"\", e: ENUM, n: null, o: {k: 1.5e3})\n}")
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	op := doc.operations[0]
+	if op.name != "Q" || len(op.vars) != 2 || op.vars[0].typ.String() != "[Int!]!" {
+		t.Fatalf("operation = %+v", op)
+	}
+	if got := op.vars[1].def.raw; got != "tab\té" {
+		t.Errorf("string default = %q", got)
+	}
+	field := op.sel[0].(*fieldNode)
+	if got := field.args[0].val.raw; got != "block\n  indented" {
+		t.Errorf("block string = %q", got)
+	}
+	if field.args[3].val.kind != valueObject || field.args[3].val.fields[0].val.kind != valueFloat {
+		t.Errorf("object argument = %+v", field.args[3].val)
+	}
+
+	_, err = parse("{\n  f(a: 1 }")
+	var se *syntaxError
+	if !errors.As(err, &se) || se.loc.Line != 2 {
+		t.Errorf("parse() error = %v, want a syntax error on line 2", err)
+	}
+
+	// Nesting is bounded while parsing, before validation sees the depth
+	deep := map[string]string{
+		"selections": strings.Repeat("{ f ", maxNesting+1) + strings.Repeat("}", maxNesting+1),
+		"lists":      "{ f(a: " + strings.Repeat("[", maxNesting+1) + strings.Repeat("]", maxNesting+1) + ") }",
+		"objects":    "{ f(a: " + strings.Repeat("{k: ", maxNesting+1) + "1" + strings.Repeat("}", maxNesting+1) + ") }",
+		"types":      "query($a: " + strings.Repeat("[", maxNesting+1) + "Int" + strings.Repeat("]", maxNesting+1) + ") { f }",
+	}
+	for name, src := range deep {
+		if _, err := parse(src); !errors.As(err, &se) || !strings.Contains(se.msg, "nests deeper") {
+			t.Errorf("%s nested %d deep: error = %v, want the nesting limit", name, maxNesting+1, err)
+		}
+	}
+	if _, err := parse(strings.Repeat("{ f ", maxNesting-1) + strings.Repeat("}", maxNesting-1)); err != nil {
+		t.Errorf("selections nested %d deep: error = %v", maxNesting-1, err)
+	}
+}
+
+func TestSchemaSDL(t *testing.T) {
+	sdl := newTestSchema().schema.SDL()
+	for _, want := range []string{
+		"type Query {\n  author(id: ID!): Author\n",
+		"\"Writes posts\"\ntype Author {",
+		"  posts(limit: Int = 10): [Post!]!\n",
+		"enum PostStatus {\n  DRAFT\n  PUBLISHED\n}",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("SDL() missing %q in\n%s", want, sdl)
+		}
+	}
+}
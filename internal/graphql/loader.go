@@ -0,0 +1,73 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// Loader batches and caches loads by key for the duration of one request.
+// Resolvers of a batch of parents collect their keys and call LoadMany once;
+// keys already loaded (or primed) are served from the cache.
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu     sync.Mutex
+	cache  map[K]V
+	missed map[K]bool // Keys fetched but not found
+}
+
+// NewLoader creates a loader; fetch receives unique, uncached keys and
+// returns the values found (keys without a value are cached as missing)
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:  fetch,
+		cache:  make(map[K]V),
+		missed: make(map[K]bool),
+	}
+}
+
+// LoadMany returns the values for keys, fetching the uncached ones in a
+// single call. Keys not found are absent from the result.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) (map[K]V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var pending []K
+	queued := make(map[K]bool)
+	for _, k := range keys {
+		_, cached := l.cache[k]
+		if !cached && !l.missed[k] && !queued[k] {
+			queued[k] = true
+			pending = append(pending, k)
+		}
+	}
+	if len(pending) > 0 {
+		fetched, err := l.fetch(ctx, pending)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range pending {
+			if v, ok := fetched[k]; ok {
+				l.cache[k] = v
+			} else {
+				l.missed[k] = true
+			}
+		}
+	}
+
+	values := make(map[K]V, len(keys))
+	for _, k := range keys {
+		if v, ok := l.cache[k]; ok {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// Prime caches a value loaded elsewhere, e.g. users from a list query
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache[key] = value
+	delete(l.missed, key)
+}
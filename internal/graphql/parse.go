@@ -0,0 +1,886 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Query Documents
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// The parser covers executable documents: operations with variables,
+// fields with aliases and arguments, fragments (named and inline) and
+// directives. Type system definitions are not accepted.
+
+// Location is a 1-based line and column in the query text
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind string // "query", "mutation" or "subscription"
+	name string
+	vars []*varDef
+	sel  []selection
+	loc  Location
+}
+
+type varDef struct {
+	name string
+	typ  *typeRef
+	def  *value // Nil without a default
+	loc  Location
+}
+
+// typeRef is a type as written in a variable definition, e.g. [ID!]!
+type typeRef struct {
+	name    string   // Named types
+	list    *typeRef // List types
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.list != nil {
+		s = "[" + t.list.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type fragment struct {
+	name       string
+	typeCond   string
+	directives []*directive
+	sel        []selection
+	loc        Location
+}
+
+// selection is a *fieldNode, *fragmentSpread or *inlineFragment
+type selection interface{ location() Location }
+
+type fieldNode struct {
+	alias, name string
+	args        []*argNode
+	directives  []*directive
+	sel         []selection
+	loc         Location
+}
+
+// key is the field's name in the response
+func (f *fieldNode) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	typeCond   string // Empty without a type condition
+	directives []*directive
+	sel        []selection
+	loc        Location
+}
+
+func (f *fieldNode) location() Location      { return f.loc }
+func (f *fragmentSpread) location() Location { return f.loc }
+func (f *inlineFragment) location() Location { return f.loc }
+
+type argNode struct {
+	name string
+	val  value
+	loc  Location
+}
+
+type directive struct {
+	name string
+	args []*argNode
+	loc  Location
+}
+
+// value is a literal or variable in the query text
+type value struct {
+	kind   valueKind
+	raw    string // Ints, floats, strings, enums and variable names
+	list   []value
+	fields []objectField
+	loc    Location
+}
+
+type objectField struct {
+	name string
+	val  value
+}
+
+type valueKind int
+
+const (
+	valueInt valueKind = iota + 1
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueVariable
+	valueList
+	valueObject
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Lexer
+// ═══════════════════════════════════════════════════════════════════════════════
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	loc  Location
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+}
+
+// syntaxError is a parse failure at a location
+type syntaxError struct {
+	msg string
+	loc Location
+}
+
+func (e *syntaxError) Error() string { return "Syntax Error: " + e.msg }
+
+func (l *lexer) location() Location {
+	return Location{Line: l.line, Column: l.pos - l.lineStart + 1}
+}
+
+func (l *lexer) errorf(format string, args ...any) error {
+	return &syntaxError{msg: fmt.Sprintf(format, args...), loc: l.location()}
+}
+
+// skipIgnored skips whitespace, commas, comments and the byte order mark
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			l.pos++
+			l.line++
+			l.lineStart = l.pos
+		case c == '\r':
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.line++
+			l.lineStart = l.pos
+		case c == ' ' || c == '\t' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	loc := l.location()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, loc: loc}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$&()/:=@[]{|}", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunct, val: string(c), loc: loc}, nil
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return token{}, l.errorf("unexpected %q", ".")
+		}
+		l.pos += 3
+		return token{kind: tokenPunct, val: "...", loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, val: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.number(loc)
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString(loc)
+		}
+		return l.string(loc)
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf("unexpected character %q", r)
+}
+
+func (l *lexer) number(loc Location) (token, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	if !l.digits() {
+		return token{}, l.errorf("invalid number, expected digit")
+	}
+	kind := tokenInt
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if !l.digits() {
+			return token{}, l.errorf("invalid number, expected digit after \".\"")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if !l.digits() {
+			return token{}, l.errorf("invalid number, expected digit in exponent")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || l.src[l.pos] == '.') {
+		return token{}, l.errorf("invalid number, unexpected %q", l.src[l.pos])
+	}
+	return token{kind: kind, val: l.src[start:l.pos], loc: loc}, nil
+}
+
+// digits consumes a run of digits, reporting whether there was one
+func (l *lexer) digits() bool {
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+func (l *lexer) string(loc Location) (token, error) {
+	l.pos++ // Opening quote
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' || l.src[l.pos] == '\r' {
+			return token{}, l.errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{kind: tokenString, val: b.String(), loc: loc}, nil
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf("unterminated string")
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, l.errorf("invalid unicode escape")
+				}
+				n, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, l.errorf("invalid unicode escape %q", l.src[l.pos:l.pos+4])
+				}
+				b.WriteRune(rune(n))
+				l.pos += 4
+			default:
+				return token{}, l.errorf("invalid escape sequence \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+func (l *lexer) blockString(loc Location) (token, error) {
+	l.pos += 3
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return token{}, l.errorf("unterminated block string")
+		}
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			return token{kind: tokenString, val: dedentBlockString(b.String()), loc: loc}, nil
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			b.WriteString(`"""`)
+			l.pos += 4
+		default:
+			c := l.src[l.pos]
+			b.WriteByte(c)
+			l.pos++
+			if c == '\n' {
+				l.line++
+				l.lineStart = l.pos
+			}
+		}
+	}
+}
+
+// dedentBlockString removes the common indentation and leading and trailing
+// blank lines of a block string, as the spec's BlockStringValue does
+func dedentBlockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	common := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if indent := len(line) - len(trimmed); common < 0 || indent < common {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= common {
+				lines[i] = lines[i][common:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Parser
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// maxNesting bounds how deeply selection sets, list and object values and
+// list types may nest. The parser recurses on each level, so without it a
+// short document of brackets would grow the stack without limit, before
+// WithMaxDepth ever sees the query.
+const maxNesting = 128
+
+type parser struct {
+	lex   *lexer
+	tok   token
+	depth int // Levels of nesting open at the current token
+}
+
+// parse parses an executable document
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"), p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			frag, err := p.fragmentDefinition()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.fragments[frag.name]; dup {
+				return nil, &syntaxError{msg: fmt.Sprintf("there can be only one fragment named %q", frag.name), loc: frag.loc}
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &syntaxError{msg: "document has no operations", loc: Location{Line: 1, Column: 1}}
+	}
+	return doc, nil
+}
+
+// nest enters a level of nesting, failing past maxNesting; every successful
+// call is paired with a deferred p.depth--
+func (p *parser) nest() error {
+	if p.depth >= maxNesting {
+		return &syntaxError{msg: fmt.Sprintf("document nests deeper than %d levels", maxNesting), loc: p.tok.loc}
+	}
+	p.depth++
+	return nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.val == punct
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokenName && p.tok.val == name
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return &syntaxError{msg: "unexpected end of document", loc: p.tok.loc}
+	}
+	return &syntaxError{msg: fmt.Sprintf("unexpected %q", p.tok.val), loc: p.tok.loc}
+}
+
+// skip consumes punct if it is next, reporting whether it was
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return &syntaxError{msg: fmt.Sprintf("expected %q, found %s", punct, p.describe()), loc: p.tok.loc}
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", &syntaxError{msg: fmt.Sprintf("expected name, found %s", p.describe()), loc: p.tok.loc}
+	}
+	name := p.tok.val
+	return name, p.advance()
+}
+
+func (p *parser) describe() string {
+	if p.tok.kind == tokenEOF {
+		return "end of document"
+	}
+	return strconv.Quote(p.tok.val)
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: "query", loc: p.tok.loc}
+	if p.tok.kind == tokenName {
+		op.kind = p.tok.val
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName {
+			op.name = p.tok.val
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek("(") {
+			vars, err := p.variableDefinitions()
+			if err != nil {
+				return nil, err
+			}
+			op.vars = vars
+		}
+		if p.peek("@") {
+			if _, err := p.directives(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.sel = sel
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*varDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []*varDef
+	for !p.peek(")") {
+		def := &varDef{loc: p.tok.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		def.name = name
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if def.typ, err = p.typeReference(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			v, err := p.value(true)
+			if err != nil {
+				return nil, err
+			}
+			def.def = &v
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+func (p *parser) typeReference() (*typeRef, error) {
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		inner, err := p.typeReference()
+		if err != nil {
+			return nil, err
+		}
+		t.list = inner
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		t.name = name
+	}
+	ok, err := p.skip("!")
+	t.nonNull = ok
+	return t, err
+}
+
+func (p *parser) fragmentDefinition() (*fragment, error) {
+	frag := &fragment{loc: p.tok.loc}
+	if err := p.advance(); err != nil { // "fragment"
+		return nil, err
+	}
+	if p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	frag.name = name
+	if !p.peekName("on") {
+		return nil, &syntaxError{msg: fmt.Sprintf("expected \"on\", found %s", p.describe()), loc: p.tok.loc}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if frag.typeCond, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.sel, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sel []selection
+	for !p.peek("}") {
+		if p.tok.kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, s)
+	}
+	if len(sel) == 0 {
+		return nil, &syntaxError{msg: "selection set is empty", loc: p.tok.loc}
+	}
+	return sel, p.advance()
+}
+
+func (p *parser) selection() (selection, error) {
+	loc := p.tok.loc
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragmentSelection(loc)
+	}
+
+	f := &fieldNode{loc: loc}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f.name = name
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if f.args, err = p.arguments(); err != nil {
+			return nil, err
+		}
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.sel, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) fragmentSelection(loc Location) (selection, error) {
+	if p.tok.kind == tokenName && !p.peekName("on") {
+		spread := &fragmentSpread{name: p.tok.val, loc: loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		spread.directives, err = p.directives()
+		return spread, err
+	}
+
+	inline := &inlineFragment{loc: loc}
+	if p.peekName("on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		inline.typeCond = name
+	}
+	var err error
+	if inline.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if inline.sel, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+func (p *parser) arguments() ([]*argNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []*argNode
+	for !p.peek(")") {
+		arg := &argNode{loc: p.tok.loc}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		arg.name = name
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.val, err = p.value(false); err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, &syntaxError{msg: "argument list is empty", loc: p.tok.loc}
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var dirs []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d.name = name
+		if p.peek("(") {
+			if d.args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// value parses a value; constant values (variable defaults) may not
+// reference variables
+func (p *parser) value(constant bool) (value, error) {
+	v := value{loc: p.tok.loc, raw: p.tok.val}
+	switch p.tok.kind {
+	case tokenInt:
+		v.kind = valueInt
+	case tokenFloat:
+		v.kind = valueFloat
+	case tokenString:
+		v.kind = valueString
+	case tokenName:
+		switch p.tok.val {
+		case "true", "false":
+			v.kind = valueBoolean
+		case "null":
+			v.kind = valueNull
+		default:
+			v.kind = valueEnum
+		}
+	case tokenPunct:
+		switch p.tok.val {
+		case "$":
+			if constant {
+				return v, &syntaxError{msg: "unexpected variable in constant value", loc: v.loc}
+			}
+			if err := p.advance(); err != nil {
+				return v, err
+			}
+			name, err := p.name()
+			v.kind, v.raw = valueVariable, name
+			return v, err
+		case "[":
+			return p.listValue(v, constant)
+		case "{":
+			return p.objectValue(v, constant)
+		}
+		return v, p.unexpected()
+	default:
+		return v, p.unexpected()
+	}
+	return v, p.advance()
+}
+
+func (p *parser) listValue(v value, constant bool) (value, error) {
+	if err := p.nest(); err != nil {
+		return v, err
+	}
+	defer func() { p.depth-- }()
+
+	v.kind = valueList
+	if err := p.advance(); err != nil {
+		return v, err
+	}
+	for !p.peek("]") {
+		item, err := p.value(constant)
+		if err != nil {
+			return v, err
+		}
+		v.list = append(v.list, item)
+	}
+	return v, p.advance()
+}
+
+func (p *parser) objectValue(v value, constant bool) (value, error) {
+	if err := p.nest(); err != nil {
+		return v, err
+	}
+	defer func() { p.depth-- }()
+
+	v.kind = valueObject
+	if err := p.advance(); err != nil {
+		return v, err
+	}
+	for !p.peek("}") {
+		name, err := p.name()
+		if err != nil {
+			return v, err
+		}
+		if err := p.expect(":"); err != nil {
+			return v, err
+		}
+		item, err := p.value(constant)
+		if err != nil {
+			return v, err
+		}
+		v.fields = append(v.fields, objectField{name: name, val: item})
+	}
+	return v, p.advance()
+}
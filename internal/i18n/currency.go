@@ -0,0 +1,180 @@
+// Package i18n provides locale negotiation and locale-aware number and
+// currency formatting for API responses.
+package i18n
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale describes how numbers and currency amounts are written in a locale
+type Locale struct {
+	Tag          string // BCP 47 tag, e.g. "en-US"
+	Decimal      string // Decimal separator
+	Group        string // Thousands separator
+	SymbolAfter  bool   // Place the currency symbol after the number
+	SymbolSpaced bool   // Separate symbol and number with a space
+}
+
+// Currency describes an ISO 4217 currency
+type Currency struct {
+	Code   string
+	Symbol string
+	Digits int // Number of minor unit digits
+}
+
+// DefaultLocale is used when no supported locale matches the request
+var DefaultLocale = locales["en-US"]
+
+var locales = map[string]Locale{
+	"en-US": {Tag: "en-US", Decimal: ".", Group: ","},
+	"en-GB": {Tag: "en-GB", Decimal: ".", Group: ","},
+	"de-DE": {Tag: "de-DE", Decimal: ",", Group: ".", SymbolAfter: true, SymbolSpaced: true},
+	"fr-FR": {Tag: "fr-FR", Decimal: ",", Group: "\u202f", SymbolAfter: true, SymbolSpaced: true},
+	"es-ES": {Tag: "es-ES", Decimal: ",", Group: ".", SymbolAfter: true, SymbolSpaced: true},
+	"it-IT": {Tag: "it-IT", Decimal: ",", Group: ".", SymbolAfter: true, SymbolSpaced: true},
+	"pt-BR": {Tag: "pt-BR", Decimal: ",", Group: ".", SymbolSpaced: true},
+	"ja-JP": {Tag: "ja-JP", Decimal: ".", Group: ","},
+}
+
+// languageDefaults maps a bare language to its most common regional locale
+var languageDefaults = map[string]string{
+	"en": "en-US",
+	"de": "de-DE",
+	"fr": "fr-FR",
+	"es": "es-ES",
+	"it": "it-IT",
+	"pt": "pt-BR",
+	"ja": "ja-JP",
+}
+
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", Symbol: "$", Digits: 2},
+	"EUR": {Code: "EUR", Symbol: "€", Digits: 2},
+	"GBP": {Code: "GBP", Symbol: "£", Digits: 2},
+	"JPY": {Code: "JPY", Symbol: "¥", Digits: 0},
+	"BRL": {Code: "BRL", Symbol: "R$", Digits: 2},
+	"CAD": {Code: "CAD", Symbol: "CA$", Digits: 2},
+	"AUD": {Code: "AUD", Symbol: "A$", Digits: 2},
+}
+
+// LookupLocale returns the supported locale for a tag (case-insensitive),
+// falling back to the language's default region
+func LookupLocale(tag string) (Locale, bool) {
+	tag = canonicalTag(tag)
+	if loc, ok := locales[tag]; ok {
+		return loc, true
+	}
+	lang, _, _ := strings.Cut(tag, "-")
+	if def, ok := languageDefaults[lang]; ok {
+		return locales[def], true
+	}
+	return Locale{}, false
+}
+
+// LookupCurrency returns the currency for an ISO 4217 code.
+// Unknown codes format with the code as the symbol and two minor digits.
+func LookupCurrency(code string) Currency {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if c, ok := currencies[code]; ok {
+		return c
+	}
+	return Currency{Code: code, Symbol: code, Digits: 2}
+}
+
+// Negotiate picks the best supported locale from an Accept-Language header value,
+// honoring q-values. Returns DefaultLocale if nothing matches.
+func Negotiate(acceptLanguage string) Locale {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if loc, ok := LookupLocale(c.tag); ok {
+			return loc
+		}
+	}
+	return DefaultLocale
+}
+
+// FormatNumber formats n with the locale's separators and a fixed number of decimals
+func FormatNumber(n float64, decimals int, loc Locale) string {
+	scale := math.Pow10(decimals)
+	n = math.Round(n*scale) / scale
+
+	negative := n < 0
+	digits := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+
+	var sb strings.Builder
+	if negative {
+		sb.WriteByte('-')
+	}
+	for i, d := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteString(loc.Group)
+		}
+		sb.WriteRune(d)
+	}
+	if fracPart != "" {
+		sb.WriteString(loc.Decimal)
+		sb.WriteString(fracPart)
+	}
+	return sb.String()
+}
+
+// FormatCurrency formats a major-unit amount (e.g. 12.5 dollars) for display
+//
+// Example:
+//
+//	i18n.FormatCurrency(1234.5, "EUR", de) // "1.234,50 €"
+//	i18n.FormatCurrency(1234.5, "USD", us) // "$1,234.50"
+func FormatCurrency(amount float64, code string, loc Locale) string {
+	cur := LookupCurrency(code)
+	number := FormatNumber(amount, cur.Digits, loc)
+
+	sep := ""
+	if loc.SymbolSpaced || cur.Symbol == cur.Code {
+		sep = " "
+	}
+
+	if loc.SymbolAfter {
+		return number + sep + cur.Symbol
+	}
+	if sign, rest, ok := strings.Cut(number, "-"); ok && sign == "" {
+		return "-" + cur.Symbol + sep + rest
+	}
+	return cur.Symbol + sep + number
+}
+
+// canonicalTag normalizes a language tag to "ll-RR" casing
+func canonicalTag(tag string) string {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	lang, region, ok := strings.Cut(tag, "-")
+	if !ok {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "-" + strings.ToUpper(region)
+}
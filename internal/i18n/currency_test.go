@@ -0,0 +1,57 @@
+package i18n
+
+import "testing"
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		locale   string
+		expected string
+	}{
+		{"us dollars", 1234.5, "USD", "en-US", "$1,234.50"},
+		{"german euros", 1234.5, "EUR", "de-DE", "1.234,50 €"},
+		{"french euros", 1234567.891, "EUR", "fr-FR", "1\u202f234\u202f567,89 €"},
+		{"yen has no minor units", 1234.5, "JPY", "ja-JP", "¥1,235"},
+		{"brazilian real", 99.9, "BRL", "pt-BR", "R$ 99,90"},
+		{"negative amount", -5, "GBP", "en-GB", "-£5.00"},
+		{"unknown currency", 10, "CHF", "en-US", "CHF 10.00"},
+		{"small amount", 0.5, "USD", "en-US", "$0.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, ok := LookupLocale(tt.locale)
+			if !ok {
+				t.Fatalf("locale %s not supported", tt.locale)
+			}
+			if got := FormatCurrency(tt.amount, tt.currency, loc); got != tt.expected {
+				t.Errorf("FormatCurrency(%v, %s, %s) = %q, want %q", tt.amount, tt.currency, tt.locale, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en-US"},
+		{"de-DE", "de-DE"},
+		{"fr", "fr-FR"},
+		{"en-gb,en;q=0.8", "en-GB"},
+		{"zz-ZZ, de;q=0.5", "de-DE"},
+		{"fr;q=0.2, ja-JP;q=0.9", "ja-JP"},
+		{"xx", "en-US"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := Negotiate(tt.header); got.Tag != tt.expected {
+				t.Errorf("Negotiate(%q) = %s, want %s", tt.header, got.Tag, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// ConsumerConfig configures a Consumer. Every member of a group must
+// consume the same topic.
+type ConsumerConfig struct {
+	Config
+	Group             string
+	Topic             string
+	SessionTimeout    time.Duration // Without a heartbeat for this long, a member is dropped from the group
+	RebalanceTimeout  time.Duration // How long members have to rejoin during a rebalance
+	HeartbeatInterval time.Duration
+	CommitInterval    time.Duration // How often processed offsets are committed
+	MaxWait           time.Duration // How long a fetch waits for new messages
+	MaxPartitionBytes int32         // Fetched per partition per request
+	StartAtLatest     bool          // Partitions without a committed offset start at new messages, not the oldest
+}
+
+// withDefaults fills in the zero fields of cfg
+func (cfg ConsumerConfig) withDefaults() ConsumerConfig {
+	cfg.Config = cfg.Config.withDefaults()
+	if cfg.SessionTimeout <= 0 {
+		cfg.SessionTimeout = 30 * time.Second
+	}
+	if cfg.RebalanceTimeout <= 0 {
+		cfg.RebalanceTimeout = time.Minute
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 3 * time.Second
+	}
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = 5 * time.Second
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = 500 * time.Millisecond
+	}
+	if cfg.MaxPartitionBytes <= 0 {
+		cfg.MaxPartitionBytes = 1 << 20
+	}
+	return cfg
+}
+
+// Handler processes a message. A message whose handler fails is retried,
+// with backoff, until it succeeds or the partition moves to another member;
+// a message that can never be processed must be set aside by the handler,
+// which then returns nil, or it holds up the rest of its partition.
+type Handler func(ctx context.Context, msg Message) error
+
+// errPartitionRevoked ends the handling of a partition assigned elsewhere
+var errPartitionRevoked = errors.New("kafka: partition revoked")
+
+// Consumer reads a topic as a member of a consumer group. The partitions it
+// is assigned are read from the offsets the group committed, and their
+// messages handled one at a time, in order.
+type Consumer struct {
+	cfg    ConsumerConfig
+	client *kgo.Client
+	logg   *logger.Logger
+
+	// Offsets are committed by the consumer rather than the client, so only
+	// messages the handler is done with are ever committed
+	mu         sync.Mutex
+	runCtx     context.Context
+	partitions map[int32]context.CancelCauseFunc // Assigned partitions, ended when revoked
+	contexts   map[int32]context.Context
+	positions  map[int32]int64 // Offset after the last handled message, by partition
+	committed  map[int32]int64
+}
+
+// NewConsumer creates a consumer; it joins its group when Run is called
+func NewConsumer(cfg ConsumerConfig, logg *logger.Logger) (*Consumer, error) {
+	cfg = cfg.withDefaults()
+	c := &Consumer{
+		cfg:        cfg,
+		logg:       logg,
+		runCtx:     context.Background(),
+		partitions: make(map[int32]context.CancelCauseFunc),
+		contexts:   make(map[int32]context.Context),
+		positions:  make(map[int32]int64),
+		committed:  make(map[int32]int64),
+	}
+
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	reset := kgo.NewOffset().AtStart()
+	if cfg.StartAtLatest {
+		reset = kgo.NewOffset().AtEnd()
+	}
+	opts = append(opts,
+		kgo.ConsumerGroup(cfg.Group),
+		kgo.ConsumeTopics(cfg.Topic),
+		kgo.ConsumeResetOffset(reset),
+		kgo.SessionTimeout(cfg.SessionTimeout),
+		kgo.RebalanceTimeout(cfg.RebalanceTimeout),
+		kgo.HeartbeatInterval(cfg.HeartbeatInterval),
+		kgo.FetchMaxWait(cfg.MaxWait),
+		kgo.FetchMaxPartitionBytes(cfg.MaxPartitionBytes),
+		kgo.DisableAutoCommit(),
+		kgo.OnPartitionsAssigned(c.assigned),
+		kgo.OnPartitionsRevoked(c.revoked),
+		kgo.OnPartitionsLost(c.lost),
+	)
+	if c.client, err = kgo.NewClient(opts...); err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	return c, nil
+}
+
+// Run consumes until ctx ends, then commits the offsets it processed,
+// leaves the group so its partitions are reassigned straight away, and
+// returns nil. It returns early only for errors retrying cannot fix, such
+// as a denied topic. A consumer runs once.
+func (c *Consumer) Run(ctx context.Context, handle Handler) error {
+	c.mu.Lock()
+	c.runCtx = ctx
+	c.mu.Unlock()
+	defer c.client.Close()
+
+	lastCommit := time.Now()
+	for ctx.Err() == nil {
+		fetches := c.client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			break
+		}
+		for _, f := range fetches.Errors() {
+			if errors.Is(f.Err, kerr.TopicAuthorizationFailed) || errors.Is(f.Err, kerr.GroupAuthorizationFailed) {
+				return f.Err
+			}
+			c.logg.Warn("kafka fetch failed", "error", f.Err, "topic", f.Topic, "partition", f.Partition)
+		}
+
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			for _, rec := range p.Records {
+				partCtx := c.partitionContext(p.Partition)
+				if partCtx == nil || c.process(partCtx, handle, messageOf(rec)) != nil {
+					// Revoked, or ctx ended: the rest is read again from
+					// the committed offset
+					return
+				}
+				c.advance(p.Partition, rec.Offset+1)
+			}
+		})
+
+		if time.Since(lastCommit) >= c.cfg.CommitInterval {
+			if err := c.commit(ctx); err != nil {
+				c.logg.Warn("kafka offset commit failed", "error", err, "group", c.cfg.Group)
+			}
+			lastCommit = time.Now()
+		}
+	}
+
+	final, done := context.WithTimeout(context.WithoutCancel(ctx), c.cfg.Timeout)
+	defer done()
+	if err := c.commit(final); err != nil {
+		c.logg.Warn("kafka offset commit failed", "error", err, "group", c.cfg.Group)
+	}
+	return nil
+}
+
+// process handles msg, retrying until it succeeds or ctx ends
+func (c *Consumer) process(ctx context.Context, handle Handler, msg Message) error {
+	for attempt := 1; ; attempt++ {
+		err := handle(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.logg.Warn("kafka message handler failed, retrying", "error", err,
+			"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "attempt", attempt)
+		if err := sleep(ctx, backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Assignments and Offsets
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// partitionContext returns the context handlers of partition run in, nil
+// once it is no longer assigned
+func (c *Consumer) partitionContext(partition int32) context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.contexts[partition]
+}
+
+// advance records that the messages of partition before offset are handled,
+// unless the partition moved meanwhile
+func (c *Consumer) advance(partition int32, offset int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.partitions[partition]; ok {
+		c.positions[partition] = offset
+	}
+}
+
+// commit commits the positions of the assigned partitions that moved since
+// the last commit
+func (c *Consumer) commit(ctx context.Context) error {
+	c.mu.Lock()
+	offsets := make(map[int32]kgo.EpochOffset)
+	for p := range c.partitions {
+		if offset, ok := c.positions[p]; ok && c.committed[p] != offset {
+			offsets[p] = kgo.EpochOffset{Epoch: -1, Offset: offset}
+		}
+	}
+	c.mu.Unlock()
+	return c.commitOffsets(ctx, offsets)
+}
+
+func (c *Consumer) commitOffsets(ctx context.Context, offsets map[int32]kgo.EpochOffset) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	var errs []error
+	c.client.CommitOffsetsSync(ctx, map[string]map[int32]kgo.EpochOffset{c.cfg.Topic: offsets},
+		func(_ *kgo.Client, _ *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			for _, topic := range resp.Topics {
+				for _, p := range topic.Partitions {
+					if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+						errs = append(errs, err)
+					} else if offset, ok := offsets[p.Partition]; ok {
+						c.committed[p.Partition] = offset.Offset
+					}
+				}
+			}
+		})
+	return errors.Join(errs...)
+}
+
+// assigned starts handling the partitions newly assigned to the consumer,
+// from the offsets the group committed
+func (c *Consumer) assigned(_ context.Context, _ *kgo.Client, assigned map[string][]int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range assigned[c.cfg.Topic] {
+		ctx, cancel := context.WithCancelCause(c.runCtx)
+		c.contexts[p], c.partitions[p] = ctx, cancel
+		delete(c.positions, p)
+		delete(c.committed, p)
+	}
+	c.logg.Info("kafka consumer assigned partitions", "group", c.cfg.Group, "partitions", assigned[c.cfg.Topic])
+}
+
+// revoked commits what was handled of the partitions moving to another
+// member and stops handling them
+func (c *Consumer) revoked(ctx context.Context, _ *kgo.Client, revoked map[string][]int32) {
+	c.mu.Lock()
+	offsets := make(map[int32]kgo.EpochOffset)
+	for _, p := range revoked[c.cfg.Topic] {
+		if offset, ok := c.positions[p]; ok && c.committed[p] != offset {
+			offsets[p] = kgo.EpochOffset{Epoch: -1, Offset: offset}
+		}
+	}
+	c.mu.Unlock()
+	if err := c.commitOffsets(ctx, offsets); err != nil {
+		c.logg.Warn("kafka offset commit failed", "error", err, "group", c.cfg.Group)
+	}
+	c.lost(ctx, nil, revoked)
+}
+
+// lost stops handling partitions without committing them, as the group no
+// longer accepts this member's commits
+func (c *Consumer) lost(_ context.Context, _ *kgo.Client, lost map[string][]int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range lost[c.cfg.Topic] {
+		if cancel, ok := c.partitions[p]; ok {
+			cancel(errPartitionRevoked)
+		}
+		delete(c.partitions, p)
+		delete(c.contexts, p)
+	}
+}
+
+// backoff returns the wait before a retry: 100ms doubling to at most 2s
+func backoff(attempt int) time.Duration {
+	return min(100*time.Millisecond<<min(attempt-1, 5), 2*time.Second)
+}
+
+// sleep waits for d or until ctx ends
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
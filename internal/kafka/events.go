@@ -0,0 +1,41 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Headers set on event messages, so consumers can route or skip a message
+// without decoding its value
+const (
+	HeaderEventType     = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderContentType   = "content-type"
+)
+
+// EventMessage encodes event as a structured-mode CloudEvent from source
+// (see domain.MarshalCloudEvent), keyed by its aggregate ID
+func EventMessage(event domain.Event, source string) (Message, error) {
+	value, err := domain.MarshalCloudEvent(event, source)
+	if err != nil {
+		return Message{}, fmt.Errorf("kafka: %w", err)
+	}
+	version := domain.EventSchemaVersion(event.EventType())
+	return Message{
+		Key:   []byte(event.AggregateID()),
+		Value: value,
+		Headers: []Header{
+			{Key: HeaderEventType, Value: []byte(event.EventType())},
+			{Key: HeaderSchemaVersion, Value: []byte(strconv.Itoa(version))},
+			{Key: HeaderContentType, Value: []byte(domain.CloudEventsContentType)},
+		},
+		Time: event.Metadata().OccurredAt,
+	}, nil
+}
+
+// DecodeEvent decodes an event message (see domain.UnmarshalCloudEvent)
+func DecodeEvent(msg Message) (domain.Event, error) {
+	return domain.UnmarshalCloudEvent(msg.Value)
+}
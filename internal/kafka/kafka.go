@@ -0,0 +1,149 @@
+// Package kafka publishes domain events to Apache Kafka and consumes them,
+// through the franz-go client:
+//
+//   - a Producer writes each event to the partition of its aggregate ID, so
+//     the events of one order stay in order, as a CloudEvent carrying the
+//     event type and its schema version;
+//   - a Consumer joins a consumer group, reads the partitions assigned to it
+//     and commits the offsets of the messages its handler has processed,
+//     leaving the group cleanly when its context ends.
+//
+// Delivery is at least once: a rebalance before an offset was committed
+// delivers a message again, so handlers must be idempotent (the event ID
+// identifies a redelivery). Producers are idempotent, so their own retries
+// do not duplicate messages.
+//
+// Connections are plaintext, or TLS when Config.TLS is set, and
+// authenticate with SASL PLAIN or SCRAM-SHA-256/512 when Config.SASL names a
+// mechanism. Record batches compressed with any codec Kafka supports are
+// read; keys are partitioned like the Java client does, so every client puts
+// a key in the same place.
+//
+// Example:
+//
+//	producer, err := kafka.NewProducer(kafka.ProducerConfig{
+//		Config: kafka.Config{Brokers: []string{"localhost:9092"}},
+//		Topic:  "domain-events",
+//	}, logg)
+//	if err != nil {
+//		return err
+//	}
+//	defer producer.Close()
+//	unsubscribe := bus.Subscribe(producer.Publish)
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// Config configures the connection to a cluster
+type Config struct {
+	Brokers  []string      // Bootstrap brokers (host:port); the rest are discovered
+	ClientID string        // Reported to brokers, for their logs and quotas
+	Timeout  time.Duration // Per request, on top of any time the broker is asked to wait
+	// TLS encrypts every broker connection (nil for plaintext); an empty
+	// ServerName verifies each broker's certificate against its host
+	TLS *tls.Config
+	// SASL authenticates every connection, after the TLS handshake
+	SASL SASL
+}
+
+// SASL mechanisms a connection can authenticate with
+const (
+	// SASLPlain sends the password itself, so only use it over TLS
+	SASLPlain = "PLAIN"
+	// SASLScramSHA256 and SASLScramSHA512 prove the password without sending
+	// it (RFC 5802) and check that the broker knows it too
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SASL configures the authentication of broker connections
+type SASL struct {
+	Mechanism string // SASLPlain, SASLScramSHA256 or SASLScramSHA512; empty disables
+	Username  string
+	Password  string
+}
+
+// DefaultConfig returns the connection defaults for brokers
+func DefaultConfig(brokers ...string) Config {
+	return Config{Brokers: brokers, ClientID: "stdlib-golang-api", Timeout: 10 * time.Second}
+}
+
+// NewTLSConfig returns TLS settings checking broker certificates against the
+// CA bundle in caFile (the system roots when empty) and presenting the
+// client certificate in certFile and keyFile when given
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("kafka: reading CA bundle: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("kafka: no certificates in CA bundle %s", caFile)
+		}
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("kafka: loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// withDefaults fills in the zero fields of c
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	if c.ClientID == "" {
+		c.ClientID = defaults.ClientID
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaults.Timeout
+	}
+	return c
+}
+
+// options returns the client options connecting to the cluster as c says
+func (c Config) options() ([]kgo.Opt, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(c.Brokers...),
+		kgo.ClientID(c.ClientID),
+		kgo.RequestTimeoutOverhead(c.Timeout),
+	}
+	if c.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(c.TLS))
+	}
+	if c.SASL.Mechanism != "" {
+		mechanism, err := c.SASL.mechanism()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	return opts, nil
+}
+
+func (s SASL) mechanism() (sasl.Mechanism, error) {
+	switch s.Mechanism {
+	case SASLPlain:
+		return plain.Auth{User: s.Username, Pass: s.Password}.AsMechanism(), nil
+	case SASLScramSHA256:
+		return scram.Auth{User: s.Username, Pass: s.Password}.AsSha256Mechanism(), nil
+	case SASLScramSHA512:
+		return scram.Auth{User: s.Username, Pass: s.Password}.AsSha512Mechanism(), nil
+	}
+	return nil, fmt.Errorf("kafka: unsupported SASL mechanism %q", s.Mechanism)
+}
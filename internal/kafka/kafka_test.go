@@ -0,0 +1,634 @@
+package kafka
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestEventMessages(t *testing.T) {
+	event := domain.OrderCancelled{
+		EventMetadata:  domain.EventMetadata{ID: "evt-1", OccurredAt: time.UnixMilli(1700000000000).UTC()},
+		OrderID:        "order-1",
+		UserID:         "user-1",
+		PreviousStatus: domain.OrderStatusConfirmed,
+		Amount:         12.5,
+	}
+	msg, err := EventMessage(event, "/orders-api")
+	if err != nil {
+		t.Fatalf("EventMessage: %v", err)
+	}
+	if string(msg.Key) != "order-1" || string(msg.Header(HeaderEventType)) != domain.EventOrderCancelled ||
+		string(msg.Header(HeaderSchemaVersion)) != "1" || string(msg.Header(HeaderContentType)) != domain.CloudEventsContentType {
+		t.Fatalf("message = key %q, headers %v", msg.Key, msg.Headers)
+	}
+	var ce domain.CloudEvent
+	if err := json.Unmarshal(msg.Value, &ce); err != nil {
+		t.Fatalf("value is not JSON: %v", err)
+	}
+	if ce.SpecVersion != "1.0" || ce.ID != "evt-1" || ce.Source != "/orders-api" || ce.Type != domain.EventOrderCancelled ||
+		ce.Subject != "order-1" || !ce.Time.Equal(event.OccurredAt) || ce.DataContentType != "application/json" || ce.SchemaVersion != 1 {
+		t.Errorf("CloudEvent attributes = %+v", ce)
+	}
+	decoded, err := DecodeEvent(msg)
+	if err != nil {
+		t.Fatalf("DecodeEvent: %v", err)
+	}
+	if got, ok := decoded.(domain.OrderCancelled); !ok || got != event {
+		t.Fatalf("decoded %#v, want %#v", decoded, event)
+	}
+
+	// Messages from before CloudEvents still decode
+	legacy := Message{Value: []byte(`{"type":"order.shipped","schema_version":1,"data":{"id":"evt-2","order_id":"order-2"}}`)}
+	if decoded, err := DecodeEvent(legacy); err != nil || decoded.AggregateID() != "order-2" {
+		t.Errorf("legacy envelope decodes to %v, %v", decoded, err)
+	}
+	for name, value := range map[string]string{
+		"newer schema":        `{"specversion":"1.0","id":"e","source":"/x","type":"order.cancelled","schemaversion":2,"data":{}}`,
+		"newer legacy schema": `{"type":"order.cancelled","schema_version":2,"data":{}}`,
+		"unknown type":        `{"specversion":"1.0","id":"e","source":"/x","type":"invoice.paid","schemaversion":1,"data":{}}`,
+		"unknown specversion": `{"specversion":"2.0","id":"e","source":"/x","type":"order.cancelled","data":{}}`,
+		"binary data":         `{"specversion":"1.0","id":"e","source":"/x","type":"order.cancelled","data_base64":"AAAA"}`,
+		"xml data":            `{"specversion":"1.0","id":"e","source":"/x","type":"order.cancelled","datacontenttype":"application/xml","data":"<a/>"}`,
+	} {
+		if _, err := DecodeEvent(Message{Value: []byte(value)}); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%s: error = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Fake broker
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// fakeBroker is a single-node cluster serving one topic and one consumer
+// group whose every join makes the joiner its only member. Requests are
+// decoded and responses encoded by kmsg.
+type fakeBroker struct {
+	t          *testing.T
+	ln         net.Listener
+	topic      string
+	partitions int
+
+	// password makes connections authenticate as "api" with SASL PLAIN
+	// before anything else (empty skips authentication)
+	password string
+
+	mu        sync.Mutex
+	logins    int
+	logs      [][][]byte // Batches by partition
+	ends      []int64    // Log end offset by partition
+	committed map[int32]int64
+	gen       int32
+	members   int
+	member    string
+	assigned  []byte
+	left      []string
+}
+
+// fakeVersions are the request versions the fake broker answers, all from
+// v0; none but ApiVersions v3+ is flexible
+var fakeVersions = map[int16]int16{
+	0: 7, 1: 11, 2: 4, 3: 7, 8: 7, 9: 5, 10: 2, 11: 5, 12: 3, 13: 3, 14: 3, 17: 1, 18: 4, 22: 1, 36: 1,
+}
+
+func newFakeBroker(t *testing.T, topic string, partitions int) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	return serveFakeBroker(t, ln, topic, partitions, "")
+}
+
+// serveFakeBroker serves a fake broker on ln
+func serveFakeBroker(t *testing.T, ln net.Listener, topic string, partitions int, password string) *fakeBroker {
+	b := &fakeBroker{password: password, t: t, ln: ln, topic: topic, partitions: partitions,
+		logs: make([][][]byte, partitions), ends: make([]int64, partitions), committed: make(map[int32]int64)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	authenticated := b.password == ""
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		frame := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, frame); err != nil {
+			return
+		}
+		req, correlation, err := readRequest(frame)
+		if err != nil {
+			b.t.Errorf("malformed request: %v", err)
+			return
+		}
+
+		var resp kmsg.Response
+		switch key := req.Key(); {
+		case key == 17 || key == 36:
+			var ok bool
+			resp, ok = b.authenticate(req)
+			authenticated = authenticated || ok
+		case key == 18:
+			resp = b.handle(req)
+		case !authenticated:
+			b.t.Errorf("api %d sent before authenticating", key)
+			return
+		default:
+			if resp = b.handle(req); resp == nil {
+				b.t.Errorf("unexpected api %d", key)
+				return
+			}
+		}
+
+		out := binary.BigEndian.AppendUint32(make([]byte, 4), uint32(correlation))
+		if resp.IsFlexible() && resp.Key() != 18 {
+			out = append(out, 0) // Response header tags; never sent for ApiVersions
+		}
+		out = resp.AppendTo(out)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+// readRequest decodes a request frame, less its size
+func readRequest(frame []byte) (kmsg.Request, int32, error) {
+	if len(frame) < 10 {
+		return nil, 0, errors.New("short header")
+	}
+	key, version := int16(binary.BigEndian.Uint16(frame)), int16(binary.BigEndian.Uint16(frame[2:]))
+	correlation := int32(binary.BigEndian.Uint32(frame[4:]))
+	req := kmsg.RequestForKey(key)
+	if max, ok := fakeVersions[key]; !ok || version > max {
+		return nil, 0, errors.New("api " + strconv.Itoa(int(key)) + " v" + strconv.Itoa(int(version)) + " not advertised")
+	}
+	req.SetVersion(version)
+
+	body := frame[8:]
+	if clientID := int16(binary.BigEndian.Uint16(body)); clientID > 0 {
+		body = body[2+int(clientID):]
+	} else {
+		body = body[2:]
+	}
+	if req.IsFlexible() {
+		tags, n := binary.Uvarint(body)
+		body = body[n:]
+		for range tags {
+			_, n := binary.Uvarint(body)
+			size, m := binary.Uvarint(body[n:])
+			body = body[n+m+int(size):]
+		}
+	}
+	return req, correlation, req.ReadFrom(body)
+}
+
+// authenticate answers the SASL requests of a PLAIN login, reporting
+// whether the credentials were right
+func (b *fakeBroker) authenticate(req kmsg.Request) (kmsg.Response, bool) {
+	if handshake, ok := req.(*kmsg.SASLHandshakeRequest); ok {
+		resp := handshake.ResponseKind().(*kmsg.SASLHandshakeResponse)
+		if handshake.Mechanism != SASLPlain {
+			resp.ErrorCode = kerr.UnsupportedSaslMechanism.Code
+		}
+		resp.SupportedMechanisms = []string{SASLPlain}
+		return resp, false
+	}
+
+	auth := req.(*kmsg.SASLAuthenticateRequest)
+	resp := auth.ResponseKind().(*kmsg.SASLAuthenticateResponse)
+	ok := string(auth.SASLAuthBytes) == "\x00api\x00"+b.password
+	if ok {
+		b.mu.Lock()
+		b.logins++
+		b.mu.Unlock()
+	} else {
+		resp.ErrorCode = kerr.SaslAuthenticationFailed.Code
+		msg := "Authentication failed: Invalid username or password"
+		resp.ErrorMessage = &msg
+	}
+	return resp, ok
+}
+
+// handle answers any other request, nil for one the broker does not serve
+func (b *fakeBroker) handle(req kmsg.Request) kmsg.Response {
+	host, portText, _ := net.SplitHostPort(b.addr())
+	port, _ := strconv.Atoi(portText)
+
+	switch req := req.(type) {
+	case *kmsg.ApiVersionsRequest:
+		resp := req.ResponseKind().(*kmsg.ApiVersionsResponse)
+		for key, max := range fakeVersions {
+			resp.ApiKeys = append(resp.ApiKeys, kmsg.ApiVersionsResponseApiKey{ApiKey: key, MaxVersion: max})
+		}
+		return resp
+
+	case *kmsg.MetadataRequest:
+		resp := req.ResponseKind().(*kmsg.MetadataResponse)
+		resp.Brokers = []kmsg.MetadataResponseBroker{{NodeID: 0, Host: host, Port: int32(port)}}
+		topic := kmsg.NewMetadataResponseTopic()
+		topic.Topic = &b.topic
+		for p := range b.partitions {
+			partition := kmsg.NewMetadataResponseTopicPartition()
+			partition.Partition, partition.LeaderEpoch = int32(p), -1
+			partition.Replicas, partition.ISR = []int32{0}, []int32{0}
+			topic.Partitions = append(topic.Partitions, partition)
+		}
+		resp.Topics = []kmsg.MetadataResponseTopic{topic}
+		return resp
+
+	case *kmsg.FindCoordinatorRequest:
+		resp := req.ResponseKind().(*kmsg.FindCoordinatorResponse)
+		resp.Host, resp.Port = host, int32(port)
+		return resp
+
+	case *kmsg.InitProducerIDRequest:
+		resp := req.ResponseKind().(*kmsg.InitProducerIDResponse)
+		resp.ProducerID = 1
+		return resp
+
+	case *kmsg.ProduceRequest:
+		resp := req.ResponseKind().(*kmsg.ProduceResponse)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for _, topic := range req.Topics {
+			rt := kmsg.NewProduceResponseTopic()
+			rt.Topic = topic.Topic
+			for _, p := range topic.Partitions {
+				rp := kmsg.NewProduceResponseTopicPartition()
+				rp.Partition, rp.BaseOffset = p.Partition, b.ends[p.Partition]
+				// The base offset is outside the batch CRC, so it is set
+				// in place; the record count follows the batch header
+				batch := append([]byte(nil), p.Records...)
+				binary.BigEndian.PutUint64(batch, uint64(b.ends[p.Partition]))
+				b.logs[p.Partition] = append(b.logs[p.Partition], batch)
+				b.ends[p.Partition] += int64(binary.BigEndian.Uint32(batch[57:]))
+				rt.Partitions = append(rt.Partitions, rp)
+			}
+			resp.Topics = append(resp.Topics, rt)
+		}
+		return resp
+
+	case *kmsg.FetchRequest:
+		resp := req.ResponseKind().(*kmsg.FetchResponse)
+		b.mu.Lock()
+		for _, topic := range req.Topics {
+			rt := kmsg.NewFetchResponseTopic()
+			rt.Topic = topic.Topic
+			for _, p := range topic.Partitions {
+				rp := kmsg.NewFetchResponseTopicPartition()
+				rp.Partition, rp.HighWatermark, rp.LastStableOffset = p.Partition, b.ends[p.Partition], b.ends[p.Partition]
+				for _, batch := range b.logs[p.Partition] {
+					last := int64(binary.BigEndian.Uint64(batch)) + int64(binary.BigEndian.Uint32(batch[23:]))
+					if last >= p.FetchOffset {
+						rp.RecordBatches = append(rp.RecordBatches, batch...)
+					}
+				}
+				rt.Partitions = append(rt.Partitions, rp)
+			}
+			resp.Topics = append(resp.Topics, rt)
+		}
+		b.mu.Unlock()
+		empty := true
+		for _, rt := range resp.Topics {
+			for _, rp := range rt.Partitions {
+				empty = empty && len(rp.RecordBatches) == 0
+			}
+		}
+		if empty {
+			time.Sleep(min(time.Duration(req.MaxWaitMillis)*time.Millisecond, 50*time.Millisecond))
+		}
+		return resp
+
+	case *kmsg.ListOffsetsRequest:
+		resp := req.ResponseKind().(*kmsg.ListOffsetsResponse)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for _, topic := range req.Topics {
+			rt := kmsg.NewListOffsetsResponseTopic()
+			rt.Topic = topic.Topic
+			for _, p := range topic.Partitions {
+				rp := kmsg.NewListOffsetsResponseTopicPartition()
+				rp.Partition, rp.LeaderEpoch = p.Partition, -1
+				if p.Timestamp == -1 { // Latest; anything else is treated as earliest
+					rp.Offset = b.ends[p.Partition]
+				}
+				rt.Partitions = append(rt.Partitions, rp)
+			}
+			resp.Topics = append(resp.Topics, rt)
+		}
+		return resp
+
+	case *kmsg.JoinGroupRequest:
+		resp := req.ResponseKind().(*kmsg.JoinGroupResponse)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.gen++
+		b.members++
+		b.member = "member-" + strconv.Itoa(b.members)
+		resp.Generation, resp.LeaderID, resp.MemberID = b.gen, b.member, b.member
+		resp.Protocol = &req.Protocols[0].Name
+		resp.Members = []kmsg.JoinGroupResponseMember{{MemberID: b.member, ProtocolMetadata: req.Protocols[0].Metadata}}
+		return resp
+
+	case *kmsg.SyncGroupRequest:
+		resp := req.ResponseKind().(*kmsg.SyncGroupResponse)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for _, a := range req.GroupAssignment {
+			if a.MemberID == req.MemberID {
+				b.assigned = a.MemberAssignment
+			}
+		}
+		resp.MemberAssignment = b.assigned
+		return resp
+
+	case *kmsg.HeartbeatRequest:
+		return req.ResponseKind()
+
+	case *kmsg.LeaveGroupRequest:
+		resp := req.ResponseKind().(*kmsg.LeaveGroupResponse)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for _, m := range req.Members {
+			b.left = append(b.left, m.MemberID)
+			resp.Members = append(resp.Members, kmsg.LeaveGroupResponseMember{MemberID: m.MemberID, InstanceID: m.InstanceID})
+		}
+		return resp
+
+	case *kmsg.OffsetCommitRequest:
+		resp := req.ResponseKind().(*kmsg.OffsetCommitResponse)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for _, topic := range req.Topics {
+			rt := kmsg.NewOffsetCommitResponseTopic()
+			rt.Topic = topic.Topic
+			for _, p := range topic.Partitions {
+				b.committed[p.Partition] = p.Offset
+				rt.Partitions = append(rt.Partitions, kmsg.OffsetCommitResponseTopicPartition{Partition: p.Partition})
+			}
+			resp.Topics = append(resp.Topics, rt)
+		}
+		return resp
+
+	case *kmsg.OffsetFetchRequest:
+		resp := req.ResponseKind().(*kmsg.OffsetFetchResponse)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for _, topic := range req.Topics {
+			rt := kmsg.NewOffsetFetchResponseTopic()
+			rt.Topic = topic.Topic
+			for _, p := range topic.Partitions {
+				rp := kmsg.NewOffsetFetchResponseTopicPartition()
+				rp.Partition, rp.Offset, rp.LeaderEpoch = p, -1, -1
+				if offset, ok := b.committed[p]; ok {
+					rp.Offset = offset
+				}
+				rt.Partitions = append(rt.Partitions, rp)
+			}
+			resp.Topics = append(resp.Topics, rt)
+		}
+		return resp
+	}
+	return nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// End to end
+// ═══════════════════════════════════════════════════════════════════════════════
+
+func TestProduceConsumeAndResume(t *testing.T) {
+	const topic = "domain-events"
+	broker := newFakeBroker(t, topic, 3)
+	logg := logger.NewWithOptions("error", io.Discard, false)
+	cfg := Config{Brokers: []string{broker.addr()}, Timeout: 5 * time.Second}
+
+	producer, err := NewProducer(ProducerConfig{Config: cfg, Topic: topic}, logg)
+	if err != nil {
+		t.Fatalf("NewProducer: %v", err)
+	}
+	defer producer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var published []domain.Event
+	for i, orderID := range []string{"order-1", "order-2", "order-3", "order-4"} {
+		meta := domain.EventMetadata{ID: "created-" + orderID, OccurredAt: time.UnixMilli(int64(1700000000000 + i)).UTC()}
+		published = append(published,
+			domain.OrderCreated{EventMetadata: meta, OrderID: orderID, UserID: "user-1", Amount: 10,
+				Items: []domain.OrderEventItem{{ProductID: "p", Quantity: 1, Price: 10}}},
+			domain.OrderConfirmed{EventMetadata: domain.EventMetadata{ID: "confirmed-" + orderID, OccurredAt: meta.OccurredAt},
+				OrderID: orderID, UserID: "user-1"})
+	}
+	for _, event := range published {
+		if err := producer.Publish(ctx, event); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	consumerCfg := ConsumerConfig{Config: cfg, Group: "orders", Topic: topic,
+		HeartbeatInterval: 20 * time.Millisecond, CommitInterval: 20 * time.Millisecond, MaxWait: 20 * time.Millisecond}
+
+	// consume runs a consumer until it has handled want messages
+	consume := func(want int) []Message {
+		t.Helper()
+		consumer, err := NewConsumer(consumerCfg, logg)
+		if err != nil {
+			t.Fatalf("NewConsumer: %v", err)
+		}
+		runCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		var (
+			mu  sync.Mutex
+			got []Message
+		)
+		done := make(chan error, 1)
+		failed := false
+		go func() {
+			done <- consumer.Run(runCtx, func(ctx context.Context, msg Message) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if !failed {
+					failed = true // The first attempt fails; the message is retried, not skipped
+					return errors.New("transient")
+				}
+				got = append(got, msg)
+				if len(got) == want {
+					stop()
+				}
+				return nil
+			})
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+		case <-ctx.Done():
+			t.Fatalf("consumed %d of %d messages", len(got), want)
+		}
+		return got
+	}
+
+	got := consume(len(published))
+	byOrder := make(map[string][]domain.Event)
+	partitions := make(map[string]int32)
+	for _, msg := range got {
+		if p, ok := partitions[string(msg.Key)]; ok && p != msg.Partition {
+			t.Errorf("%s on partitions %d and %d", msg.Key, p, msg.Partition)
+		}
+		partitions[string(msg.Key)] = msg.Partition
+		event, err := DecodeEvent(msg)
+		if err != nil {
+			t.Fatalf("DecodeEvent: %v", err)
+		}
+		byOrder[event.AggregateID()] = append(byOrder[event.AggregateID()], event)
+	}
+	for orderID, events := range byOrder {
+		if len(events) != 2 || events[0].EventType() != domain.EventOrderCreated || events[1].EventType() != domain.EventOrderConfirmed {
+			t.Errorf("%s events out of order: %v", orderID, events)
+		}
+	}
+
+	broker.mu.Lock()
+	for p, end := range broker.ends {
+		if broker.committed[int32(p)] != end && end > 0 {
+			t.Errorf("partition %d committed %d, want %d", p, broker.committed[int32(p)], end)
+		}
+	}
+	if len(broker.left) != 1 {
+		t.Errorf("left group %d times, want 1", len(broker.left))
+	}
+	broker.mu.Unlock()
+
+	// A new member resumes after the committed offsets
+	late := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "shipped-order-1"}, OrderID: "order-1", UserID: "user-1"}
+	if err := producer.Publish(ctx, late); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	got = consume(1)
+	if len(got) != 1 || string(got[0].Header(HeaderEventType)) != domain.EventOrderShipped {
+		t.Fatalf("resumed with %v, want only the shipped event", got)
+	}
+}
+
+func TestProduceOverTLSWithSASL(t *testing.T) {
+	const topic = "domain-events"
+	cert, roots := testCertificate(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	broker := serveFakeBroker(t, ln, topic, 1, "secret")
+	logg := logger.NewWithOptions("error", io.Discard, false)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	send := func(tlsCfg *tls.Config, password string) error {
+		producer, err := NewProducer(ProducerConfig{Config: Config{
+			Brokers: []string{broker.addr()},
+			Timeout: 5 * time.Second,
+			TLS:     tlsCfg,
+			SASL:    SASL{Mechanism: SASLPlain, Username: "api", Password: password},
+		}, Topic: topic, MaxAttempts: 1}, logg)
+		if err != nil {
+			t.Fatalf("NewProducer: %v", err)
+		}
+		defer producer.Close()
+		return producer.Send(ctx, Message{Key: []byte("order-1"), Value: []byte("{}")})
+	}
+
+	if err := send(&tls.Config{RootCAs: roots}, "secret"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	broker.mu.Lock()
+	logins, end := broker.logins, broker.ends[0]
+	broker.mu.Unlock()
+	if logins == 0 || end != 1 {
+		t.Errorf("logins = %d, log end = %d; want an authenticated produce", logins, end)
+	}
+
+	if err := send(&tls.Config{RootCAs: roots}, "wrong"); !errors.Is(err, kerr.SaslAuthenticationFailed) {
+		t.Errorf("wrong password error = %v, want SaslAuthenticationFailed", err)
+	}
+	var certErr *tls.CertificateVerificationError
+	if err := send(&tls.Config{}, "secret"); !errors.As(err, &certErr) {
+		t.Errorf("untrusted certificate error = %v, want a verification error", err)
+	}
+}
+
+func TestUnsupportedSASLMechanism(t *testing.T) {
+	_, err := NewProducer(ProducerConfig{Config: Config{Brokers: []string{"127.0.0.1:9092"},
+		SASL: SASL{Mechanism: "GSSAPI"}}}, logger.NewWithOptions("error", io.Discard, false))
+	if err == nil {
+		t.Error("NewProducer accepted SASL mechanism GSSAPI")
+	}
+}
+
+// testCertificate returns a self-signed certificate for 127.0.0.1 and a
+// pool trusting it
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-broker"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, roots
+}
@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// ProducerConfig configures a Producer
+type ProducerConfig struct {
+	Config
+	Topic       string // Topic messages without one are sent to
+	Source      string // CloudEvents source of published events; domain.DefaultEventSource when empty
+	MaxAttempts int    // Per message, across leader changes and broker failures
+}
+
+// Producer sends messages to the leader of their partition and waits until
+// every in-sync replica has them (acks=all). It is safe for concurrent use.
+type Producer struct {
+	cfg    ProducerConfig
+	client *kgo.Client
+	logg   *logger.Logger
+}
+
+// NewProducer creates a producer; brokers are contacted on the first send
+func NewProducer(cfg ProducerConfig, logg *logger.Logger) (*Producer, error) {
+	cfg.Config = cfg.Config.withDefaults()
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts,
+		kgo.DefaultProduceTopic(cfg.Topic),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordRetries(cfg.MaxAttempts-1),
+		// Each send waits for its own message; lingering would only delay it
+		kgo.ProducerLinger(0),
+	)
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	return &Producer{cfg: cfg, client: client, logg: logg}, nil
+}
+
+// Publish sends event to the producer's topic. It is a domain.EventHandler,
+// so a producer subscribes to an event bus as is.
+func (p *Producer) Publish(ctx context.Context, event domain.Event) error {
+	msg, err := EventMessage(event, p.cfg.Source)
+	if err != nil {
+		return err
+	}
+	if err := p.Send(ctx, msg); err != nil {
+		return fmt.Errorf("publishing %s event %s: %w", event.EventType(), event.Metadata().ID, err)
+	}
+	return nil
+}
+
+// Send writes msg to its topic, or the producer's, on the partition of its
+// key; msg.Partition and msg.Offset are ignored. Messages without a key are
+// spread over the partitions.
+func (p *Producer) Send(ctx context.Context, msg Message) error {
+	if msg.Topic == "" {
+		msg.Topic = p.cfg.Topic
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if err := p.client.ProduceSync(ctx, msg.record()).FirstErr(); err != nil {
+		p.logg.Debug("kafka produce failed", "error", err, "topic", msg.Topic)
+		return err
+	}
+	return nil
+}
+
+// Close waits for the messages in flight and closes the producer's broker
+// connections
+func (p *Producer) Close() error {
+	p.client.Close()
+	return nil
+}
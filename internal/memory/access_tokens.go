@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure AccessTokenRepository implements domain.AccessTokenRepository at compile time
+var _ domain.AccessTokenRepository = (*AccessTokenRepository)(nil)
+
+// AccessTokenRepository is an in-memory implementation of domain.AccessTokenRepository
+type AccessTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]*domain.PersonalAccessToken
+}
+
+// NewAccessTokenRepository creates an empty in-memory personal access token repository
+func NewAccessTokenRepository() domain.AccessTokenRepository {
+	return &AccessTokenRepository{tokens: make(map[string]*domain.PersonalAccessToken)}
+}
+
+func copyAccessToken(t *domain.PersonalAccessToken) *domain.PersonalAccessToken {
+	c := *t
+	c.Scopes = slices.Clone(t.Scopes)
+	c.LastUsedAt = copyTime(t.LastUsedAt)
+	c.RevokedAt = copyTime(t.RevokedAt)
+	return &c
+}
+
+func (r *AccessTokenRepository) Create(ctx context.Context, token *domain.PersonalAccessToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tokens[token.ID]; ok {
+		return domain.ErrConflict
+	}
+	for _, t := range r.tokens {
+		if t.TokenHash == token.TokenHash {
+			return domain.ErrConflict
+		}
+	}
+	r.tokens[token.ID] = copyAccessToken(token)
+	return nil
+}
+
+func (r *AccessTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			return copyAccessToken(t), nil
+		}
+	}
+	return nil, domain.ErrAccessTokenNotFound
+}
+
+func (r *AccessTokenRepository) ListByUser(ctx context.Context, userID string) ([]*domain.PersonalAccessToken, error) {
+	r.mu.RLock()
+	var tokens []*domain.PersonalAccessToken
+	for _, t := range r.tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, copyAccessToken(t))
+		}
+	}
+	r.mu.RUnlock()
+
+	sortNewestFirst(tokens,
+		func(t *domain.PersonalAccessToken) time.Time { return t.CreatedAt },
+		func(t *domain.PersonalAccessToken) string { return t.ID })
+	return tokens, nil
+}
+
+func (r *AccessTokenRepository) Revoke(ctx context.Context, userID, tokenID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tokens[tokenID]
+	if !ok || t.UserID != userID {
+		return domain.ErrAccessTokenNotFound
+	}
+	// Idempotent: the first revocation time is kept
+	if t.RevokedAt == nil {
+		t.RevokedAt = &at
+	}
+	return nil
+}
+
+func (r *AccessTokenRepository) RevokeAllForUser(ctx context.Context, userID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.RevokedAt == nil && !t.CreatedAt.After(at) {
+			t.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *AccessTokenRepository) TouchLastUsed(ctx context.Context, tokenID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.tokens[tokenID]; ok {
+		t.LastUsedAt = &at
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure AttachmentRepository implements domain.AttachmentRepository at compile time
+var _ domain.AttachmentRepository = (*AttachmentRepository)(nil)
+
+// AttachmentRepository is an in-memory implementation of domain.AttachmentRepository
+type AttachmentRepository struct {
+	mu          sync.RWMutex
+	attachments map[string]*domain.Attachment
+}
+
+// NewAttachmentRepository creates an empty in-memory attachment metadata repository
+func NewAttachmentRepository() domain.AttachmentRepository {
+	return &AttachmentRepository{attachments: make(map[string]*domain.Attachment)}
+}
+
+func copyAttachment(a *domain.Attachment) *domain.Attachment {
+	c := *a
+	c.ScannedAt = copyTime(a.ScannedAt)
+	c.UploadedAt = copyTime(a.UploadedAt)
+	return &c
+}
+
+func (r *AttachmentRepository) Create(ctx context.Context, a *domain.Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.attachments[a.ID]; ok {
+		return domain.ErrConflict
+	}
+	for _, existing := range r.attachments {
+		if existing.Key == a.Key {
+			return domain.ErrConflict
+		}
+	}
+	r.attachments[a.ID] = copyAttachment(a)
+	return nil
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, id string) (*domain.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.attachments[id]
+	if !ok {
+		return nil, domain.ErrAttachmentNotFound
+	}
+	return copyAttachment(a), nil
+}
+
+func (r *AttachmentRepository) GetByKey(ctx context.Context, key string) (*domain.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, a := range r.attachments {
+		if a.Key == key {
+			return copyAttachment(a), nil
+		}
+	}
+	return nil, domain.ErrAttachmentNotFound
+}
+
+func (r *AttachmentRepository) ListByOwner(ctx context.Context, ownerID string, limit, offset int) ([]*domain.Attachment, error) {
+	r.mu.RLock()
+	var attachments []*domain.Attachment
+	for _, a := range r.attachments {
+		if a.OwnerID == ownerID {
+			attachments = append(attachments, copyAttachment(a))
+		}
+	}
+	r.mu.RUnlock()
+
+	sortNewestFirst(attachments,
+		func(a *domain.Attachment) time.Time { return a.CreatedAt },
+		func(a *domain.Attachment) string { return a.ID })
+	return page(attachments, limit, offset), nil
+}
+
+// Update saves the upload and scan fields; the owner, key and file name are fixed at creation
+func (r *AttachmentRepository) Update(ctx context.Context, a *domain.Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.attachments[a.ID]
+	if !ok {
+		return domain.ErrAttachmentNotFound
+	}
+	existing.ContentType = a.ContentType
+	existing.Size = a.Size
+	existing.ScanStatus = a.ScanStatus
+	existing.ScanDetail = a.ScanDetail
+	existing.ScannedAt = copyTime(a.ScannedAt)
+	existing.UploadedAt = copyTime(a.UploadedAt)
+	existing.ETag = a.ETag
+	existing.ChecksumSHA256 = a.ChecksumSHA256
+	existing.UploadID = a.UploadID
+	existing.UpdatedAt = a.UpdatedAt
+	return nil
+}
+
+func (r *AttachmentRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.attachments[id]; !ok {
+		return domain.ErrAttachmentNotFound
+	}
+	delete(r.attachments, id)
+	return nil
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure UserCache implements domain.UserCache at compile time
+var _ domain.UserCache = (*UserCache)(nil)
+
+// UserCache is an in-memory implementation of domain.UserCache
+type UserCache struct {
+	users *expiring[domain.User]
+	ttl   time.Duration
+}
+
+// NewUserCache creates an in-memory user cache with the same TTL as the Redis one
+func NewUserCache() domain.UserCache {
+	return &UserCache{users: newExpiring[domain.User](), ttl: 5 * time.Minute}
+}
+
+func (c *UserCache) Get(ctx context.Context, userID string) (*domain.User, error) {
+	u, ok := c.users.get(userID)
+	if !ok {
+		return nil, domain.ErrCacheMiss
+	}
+	return &u, nil
+}
+
+func (c *UserCache) Set(ctx context.Context, user *domain.User) error {
+	c.users.set(user.ID, *user, c.ttl)
+	return nil
+}
+
+func (c *UserCache) Invalidate(ctx context.Context, userID string) error {
+	c.users.delete(userID)
+	return nil
+}
+
+// Ensure OrderCache implements domain.OrderCache at compile time
+var _ domain.OrderCache = (*OrderCache)(nil)
+
+// OrderCache is an in-memory implementation of domain.OrderCache
+type OrderCache struct {
+	orders *expiring[*domain.Order]
+	index  *expiring[map[string]struct{}] // User ID to cached order IDs
+	ttl    time.Duration
+}
+
+// NewOrderCache creates an in-memory order cache with the same TTL as the Redis one
+func NewOrderCache() domain.OrderCache {
+	return &OrderCache{
+		orders: newExpiring[*domain.Order](),
+		index:  newExpiring[map[string]struct{}](),
+		ttl:    10 * time.Minute,
+	}
+}
+
+func (c *OrderCache) Get(ctx context.Context, orderID string) (*domain.Order, error) {
+	o, ok := c.orders.get(orderID)
+	if !ok {
+		return nil, domain.ErrCacheMiss
+	}
+	return copyOrder(o), nil
+}
+
+func (c *OrderCache) Set(ctx context.Context, order *domain.Order) error {
+	c.orders.set(order.ID, copyOrder(order), c.ttl)
+	return nil
+}
+
+func (c *OrderCache) Invalidate(ctx context.Context, orderID string) error {
+	c.orders.delete(orderID)
+	return nil
+}
+
+// InvalidateByUserID drops every cached order of the user and its index
+func (c *OrderCache) InvalidateByUserID(ctx context.Context, userID string) error {
+	c.orders.mu.Lock()
+	for id, e := range c.orders.items {
+		if e.value.UserID == userID {
+			delete(c.orders.items, id)
+		}
+	}
+	c.orders.mu.Unlock()
+
+	c.index.delete(userID)
+	return nil
+}
+
+// AddUserOrderIndex records orderID under userID, renewing the index TTL
+func (c *OrderCache) AddUserOrderIndex(ctx context.Context, userID, orderID string) error {
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
+
+	e, ok := c.index.getLocked(userID)
+	ids := e.value
+	if !ok {
+		ids = make(map[string]struct{})
+	}
+	ids[orderID] = struct{}{}
+	c.index.setLocked(userID, ids, c.ttl)
+	return nil
+}
+
+func (c *OrderCache) RemoveUserOrderIndex(ctx context.Context, userID, orderID string) error {
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
+
+	if e, ok := c.index.getLocked(userID); ok {
+		delete(e.value, orderID)
+	}
+	return nil
+}
+
+// Ensure NopUserCache and NopOrderCache implement the domain caches at compile time
+var (
+	_ domain.UserCache  = NopUserCache{}
+	_ domain.OrderCache = NopOrderCache{}
+)
+
+// NopUserCache caches nothing: every Get misses, so reads go straight to the
+// repository (RUN_MODE=standalone, where the repository is in memory anyway)
+type NopUserCache struct{}
+
+func (NopUserCache) Get(ctx context.Context, userID string) (*domain.User, error) {
+	return nil, domain.ErrCacheMiss
+}
+
+func (NopUserCache) Set(ctx context.Context, user *domain.User) error { return nil }
+
+func (NopUserCache) Invalidate(ctx context.Context, userID string) error { return nil }
+
+// NopOrderCache caches nothing, like NopUserCache
+type NopOrderCache struct{}
+
+func (NopOrderCache) Get(ctx context.Context, orderID string) (*domain.Order, error) {
+	return nil, domain.ErrCacheMiss
+}
+
+func (NopOrderCache) Set(ctx context.Context, order *domain.Order) error { return nil }
+
+func (NopOrderCache) Invalidate(ctx context.Context, orderID string) error { return nil }
+
+func (NopOrderCache) InvalidateByUserID(ctx context.Context, userID string) error { return nil }
+
+func (NopOrderCache) AddUserOrderIndex(ctx context.Context, userID, orderID string) error {
+	return nil
+}
+
+func (NopOrderCache) RemoveUserOrderIndex(ctx context.Context, userID, orderID string) error {
+	return nil
+}
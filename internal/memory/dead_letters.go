@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure DeadLetterQueue implements domain.DeadLetterQueue at compile time
+var _ domain.DeadLetterQueue = (*DeadLetterQueue)(nil)
+
+// DeadLetterQueue is an in-memory implementation of domain.DeadLetterQueue
+type DeadLetterQueue struct {
+	mu      sync.RWMutex
+	letters map[int64]*domain.DeadLetter
+	nextID  int64
+}
+
+// NewDeadLetterQueue creates an empty in-memory dead letter queue
+func NewDeadLetterQueue() *DeadLetterQueue {
+	return &DeadLetterQueue{letters: make(map[int64]*domain.DeadLetter)}
+}
+
+func (q *DeadLetterQueue) Add(ctx context.Context, d *domain.DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	d.ID = q.nextID
+	q.letters[d.ID] = d.Clone()
+	return nil
+}
+
+func (q *DeadLetterQueue) Get(ctx context.Context, id int64) (*domain.DeadLetter, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	d, ok := q.letters[id]
+	if !ok {
+		return nil, domain.ErrDeadLetterNotFound
+	}
+	return d.Clone(), nil
+}
+
+func (q *DeadLetterQueue) List(ctx context.Context, filter domain.DeadLetterFilter) ([]*domain.DeadLetter, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	var matched []*domain.DeadLetter
+	for _, d := range q.letters {
+		if (filter.Source == "" || d.Source == filter.Source) && (filter.Type == "" || d.Type == filter.Type) &&
+			(filter.Replayed == nil || d.Replayed() == *filter.Replayed) {
+			matched = append(matched, d)
+		}
+	}
+	// Newest first; IDs break ties between letters failed at the same time
+	slices.SortFunc(matched, func(a, b *domain.DeadLetter) int {
+		if c := b.FailedAt.Compare(a.FailedAt); c != 0 {
+			return c
+		}
+		return int(b.ID - a.ID)
+	})
+
+	matched = matched[min(filter.Offset, len(matched)):]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	letters := make([]*domain.DeadLetter, len(matched))
+	for i, d := range matched {
+		letters[i] = d.Clone()
+	}
+	return letters, nil
+}
+
+func (q *DeadLetterQueue) Update(ctx context.Context, d *domain.DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.letters[d.ID]; !ok {
+		return domain.ErrDeadLetterNotFound
+	}
+	q.letters[d.ID] = d.Clone()
+	return nil
+}
+
+func (q *DeadLetterQueue) Delete(ctx context.Context, id int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.letters[id]; !ok {
+		return domain.ErrDeadLetterNotFound
+	}
+	delete(q.letters, id)
+	return nil
+}
+
+func (q *DeadLetterQueue) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	purged := 0
+	for id, d := range q.letters {
+		if d.FailedAt.Before(cutoff) {
+			delete(q.letters, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure EventBus implements domain.EventBus at compile time
+var _ domain.EventBus = (*EventBus)(nil)
+
+// EventBus is an in-process implementation of domain.EventBus. Handlers run
+// on the publisher's goroutine in the order they subscribed, so slow work
+// (sending mail, calling webhooks) belongs in a job the handler enqueues.
+// Events only reach subscribers of the same instance.
+type EventBus struct {
+	mu            sync.RWMutex
+	nextID        int
+	subscriptions []eventSubscription // In the order they subscribed
+}
+
+type eventSubscription struct {
+	id         int
+	handler    domain.EventHandler
+	eventTypes map[string]bool // Nil for every type
+}
+
+// NewEventBus creates an in-process domain event bus
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+func (b *EventBus) Publish(ctx context.Context, event domain.Event) error {
+	b.mu.RLock()
+	var handlers []domain.EventHandler
+	for _, sub := range b.subscriptions {
+		if sub.eventTypes == nil || sub.eventTypes[event.EventType()] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handle := range handlers {
+		if err := runEventHandler(ctx, handle, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runEventHandler calls handle, turning a panic into an error so the other
+// handlers and the publisher carry on
+func runEventHandler(ctx context.Context, handle domain.EventHandler, event domain.Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%s handler panicked: %v", event.EventType(), p)
+		}
+	}()
+	return handle(ctx, event)
+}
+
+func (b *EventBus) Subscribe(handler domain.EventHandler, eventTypes ...string) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub := eventSubscription{id: b.nextID, handler: handler}
+	b.nextID++
+	if len(eventTypes) > 0 {
+		sub.eventTypes = make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			sub.eventTypes[t] = true
+		}
+	}
+	b.subscriptions = append(b.subscriptions, sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			b.subscriptions = slices.DeleteFunc(b.subscriptions, func(s eventSubscription) bool { return s.id == sub.id })
+			b.mu.Unlock()
+		})
+	}
+}
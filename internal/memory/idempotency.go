@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure IdempotencyStore implements domain.IdempotencyStore at compile time
+var _ domain.IdempotencyStore = (*IdempotencyStore)(nil)
+
+// IdempotencyStore is an in-memory implementation of domain.IdempotencyStore.
+// Keys are only remembered by this process.
+type IdempotencyStore struct {
+	records *expiring[domain.IdempotencyRecord]
+}
+
+// NewIdempotencyStore creates an in-memory idempotency key store
+func NewIdempotencyStore() domain.IdempotencyStore {
+	return &IdempotencyStore{records: newExpiring[domain.IdempotencyRecord]()}
+}
+
+func (s *IdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*domain.IdempotencyRecord, error) {
+	s.records.mu.Lock()
+	defer s.records.mu.Unlock()
+
+	// As with SET NX: only a missing key is claimed
+	if e, ok := s.records.getLocked(key); ok {
+		return copyIdempotencyRecord(e.value), nil
+	}
+	s.records.setLocked(key, domain.IdempotencyRecord{Fingerprint: fingerprint}, lockTTL)
+	return nil, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, record *domain.IdempotencyRecord, ttl time.Duration) error {
+	s.records.set(key, *copyIdempotencyRecord(*record), ttl)
+	return nil
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	s.records.delete(key)
+	return nil
+}
+
+func copyIdempotencyRecord(r domain.IdempotencyRecord) *domain.IdempotencyRecord {
+	r.Header = maps.Clone(r.Header)
+	for k, v := range r.Header {
+		r.Header[k] = slices.Clone(v)
+	}
+	r.Body = slices.Clone(r.Body)
+	return &r
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure Inventory implements domain.Inventory at compile time
+var _ domain.Inventory = (*Inventory)(nil)
+
+// Inventory is an in-memory implementation of domain.Inventory
+type Inventory struct {
+	mu           sync.Mutex
+	stock        map[string]int            // Available quantity by tracked product ID
+	reservations map[string]map[string]int // Reserved quantities by reservation ID, then product ID
+	released     map[string]bool           // Reservation IDs released
+}
+
+// NewInventory creates an inventory tracking no product
+func NewInventory() *Inventory {
+	return &Inventory{
+		stock:        make(map[string]int),
+		reservations: make(map[string]map[string]int),
+		released:     make(map[string]bool),
+	}
+}
+
+func (inv *Inventory) Reserve(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if _, ok := inv.reservations[reservationID]; ok || inv.released[reservationID] {
+		return nil
+	}
+	wanted := make(map[string]int)
+	for _, item := range items {
+		if _, tracked := inv.stock[item.ProductID]; tracked {
+			wanted[item.ProductID] += item.Quantity
+		}
+	}
+	for productID, quantity := range wanted {
+		if inv.stock[productID] < quantity {
+			return fmt.Errorf("%w: %s has %d, %d wanted", domain.ErrInsufficientStock, productID, inv.stock[productID], quantity)
+		}
+	}
+	for productID, quantity := range wanted {
+		inv.stock[productID] -= quantity
+	}
+	inv.reservations[reservationID] = wanted
+	return nil
+}
+
+func (inv *Inventory) Release(ctx context.Context, reservationID string) error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.released[reservationID] {
+		return nil
+	}
+	for productID, quantity := range inv.reservations[reservationID] {
+		if _, tracked := inv.stock[productID]; tracked {
+			inv.stock[productID] += quantity
+		}
+	}
+	delete(inv.reservations, reservationID)
+	inv.released[reservationID] = true
+	return nil
+}
+
+func (inv *Inventory) Stock(ctx context.Context, productID string) (int, bool, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	available, tracked := inv.stock[productID]
+	return available, tracked, nil
+}
+
+func (inv *Inventory) SetStock(ctx context.Context, productID string, available int) error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.stock[productID] = available
+	return nil
+}
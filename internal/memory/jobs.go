@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure JobRecordRepository implements domain.JobRecordRepository at compile time
+var _ domain.JobRecordRepository = (*JobRecordRepository)(nil)
+
+// JobRecordRepository is an in-memory implementation of domain.JobRecordRepository
+type JobRecordRepository struct {
+	mu      sync.RWMutex
+	records map[string]*domain.JobRecord
+}
+
+// NewJobRecordRepository creates an empty in-memory job status repository
+func NewJobRecordRepository() domain.JobRecordRepository {
+	return &JobRecordRepository{records: make(map[string]*domain.JobRecord)}
+}
+
+func copyJobRecord(r *domain.JobRecord) *domain.JobRecord {
+	c := *r
+	c.StartedAt = copyTime(r.StartedAt)
+	c.FinishedAt = copyTime(r.FinishedAt)
+	return &c
+}
+
+func (r *JobRecordRepository) Create(ctx context.Context, record *domain.JobRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.records[record.ID]; ok {
+		return domain.ErrConflict
+	}
+	r.records[record.ID] = copyJobRecord(record)
+	return nil
+}
+
+func (r *JobRecordRepository) GetByID(ctx context.Context, id string) (*domain.JobRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.records[id]
+	if !ok {
+		return nil, domain.ErrJobNotFound
+	}
+	return copyJobRecord(record), nil
+}
+
+func (r *JobRecordRepository) Update(ctx context.Context, record *domain.JobRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.records[record.ID]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	// Type, priority, owner and creation time are fixed at enqueue
+	c := copyJobRecord(record)
+	c.Type = existing.Type
+	c.Priority = existing.Priority
+	c.UserID = existing.UserID
+	c.CreatedAt = existing.CreatedAt
+	r.records[record.ID] = c
+	return nil
+}
+
+// Ensure JobQueue implements domain.JobQueue at compile time
+var _ domain.JobQueue = (*JobQueue)(nil)
+
+// JobQueue is an in-memory implementation of domain.JobQueue. Like the
+// Redis queue, a job is removed when dequeued (at-most-once delivery), and
+// queued jobs are lost when the process exits.
+type JobQueue struct {
+	mu     sync.Mutex
+	queues map[domain.JobPriority][]*domain.Job
+}
+
+// NewJobQueue creates empty in-memory job queues
+func NewJobQueue() domain.JobQueue {
+	return &JobQueue{queues: make(map[domain.JobPriority][]*domain.Job)}
+}
+
+func copyJob(j *domain.Job) *domain.Job {
+	c := *j
+	c.Payload = slices.Clone(j.Payload)
+	return &c
+}
+
+func (q *JobQueue) Enqueue(ctx context.Context, job *domain.Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queues[job.Priority] = append(q.queues[job.Priority], copyJob(job))
+	return nil
+}
+
+func (q *JobQueue) Dequeue(ctx context.Context, priority domain.JobPriority) (*domain.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queues[priority]
+	if len(queue) == 0 {
+		return nil, nil
+	}
+	job := queue[0]
+	queue[0] = nil
+	q.queues[priority] = queue[1:]
+	return job, nil
+}
@@ -0,0 +1,141 @@
+// Package memory provides in-process implementations of the domain
+// repositories, caches and stores, so the API can run without Postgres or
+// Redis (DEV_INMEMORY) and end-to-end tests can swap them in with app options.
+//
+// They honour the same contracts as the Postgres and Redis adapters: the same
+// sentinel errors, orderings and expiry rules. Values are copied on the way in
+// and out, so callers never share state with the store. Data lives only as
+// long as the process and is not shared between replicas, and there are no
+// foreign keys: deleting a user leaves its orders in place.
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// page returns the items a LIMIT/OFFSET query would
+func page[T any](items []T, limit, offset int) []T {
+	if limit <= 0 || offset >= len(items) {
+		return nil
+	}
+	offset = max(offset, 0)
+	return items[offset : offset+min(limit, len(items)-offset)]
+}
+
+// keysetPage returns one keyset page of items already sorted newest first
+func keysetPage[T any](items []T, kp domain.KeysetPage, created func(T) time.Time, id func(T) string) []T {
+	start, end := 0, len(items)
+	switch {
+	case kp.After != nil:
+		start = slices.IndexFunc(items, func(item T) bool { return kp.After.Follows(created(item), id(item)) })
+		if start < 0 {
+			return nil
+		}
+		end = min(start+kp.Limit, len(items))
+	case kp.Before != nil:
+		// The rows before the cursor end at the first one at or after it
+		end = slices.IndexFunc(items, func(item T) bool {
+			return kp.Before.Follows(created(item), id(item)) ||
+				(created(item).Equal(kp.Before.CreatedAt) && id(item) == kp.Before.ID)
+		})
+		if end < 0 {
+			end = len(items)
+		}
+		start = max(end-kp.Limit, 0)
+	default:
+		end = min(kp.Limit, len(items))
+	}
+	return items[start:end]
+}
+
+// sortNewestFirst orders items by created descending, breaking ties by ID so
+// pages are stable
+func sortNewestFirst[T any](items []T, created func(T) time.Time, id func(T) string) {
+	slices.SortFunc(items, func(a, b T) int {
+		if c := created(b).Compare(created(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(id(a), id(b))
+	})
+}
+
+// copyTime returns a copy of an optional timestamp
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// expiring is a map whose entries expire like Redis keys with a TTL
+type expiring[V any] struct {
+	mu    sync.Mutex
+	items map[string]expiringEntry[V]
+	now   func() time.Time
+}
+
+type expiringEntry[V any] struct {
+	value     V
+	expiresAt time.Time // Zero never expires
+}
+
+func newExpiring[V any]() *expiring[V] {
+	return &expiring[V]{items: make(map[string]expiringEntry[V]), now: time.Now}
+}
+
+// getLocked returns the live entry for key, dropping it if it has expired
+func (m *expiring[V]) getLocked(key string) (expiringEntry[V], bool) {
+	e, ok := m.items[key]
+	if ok && !e.expiresAt.IsZero() && !m.now().Before(e.expiresAt) {
+		delete(m.items, key)
+		return expiringEntry[V]{}, false
+	}
+	return e, ok
+}
+
+func (m *expiring[V]) get(key string) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.getLocked(key)
+	return e.value, ok
+}
+
+// set stores value under key for ttl (0 or less never expires)
+func (m *expiring[V]) set(key string, value V, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocked(key, value, ttl)
+}
+
+func (m *expiring[V]) setLocked(key string, value V, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = m.now().Add(ttl)
+	}
+	m.items[key] = expiringEntry[V]{value: value, expiresAt: expiresAt}
+}
+
+func (m *expiring[V]) delete(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+}
+
+// ttl returns how long key has left, or 0 if it is missing or never expires
+func (m *expiring[V]) ttl(key string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.getLocked(key)
+	if !ok || e.expiresAt.IsZero() {
+		return 0
+	}
+	return e.expiresAt.Sub(m.now())
+}
@@ -0,0 +1,931 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, email := range []string{"ada@example.com", "grace@example.com", "alan@example.com"} {
+		u := &domain.User{ID: email, Name: "n", Email: email, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create(%s) error = %v", email, err)
+		}
+	}
+
+	if err := repo.Create(ctx, &domain.User{ID: "other", Email: "ADA@example.com"}); !errors.Is(err, domain.ErrUserAlreadyExists) {
+		t.Errorf("Create() with taken email error = %v, want ErrUserAlreadyExists", err)
+	}
+	if err := repo.Update(ctx, &domain.User{ID: "alan@example.com", Email: "grace@example.com"}); !errors.Is(err, domain.ErrUserAlreadyExists) {
+		t.Errorf("Update() to taken email error = %v, want ErrUserAlreadyExists", err)
+	}
+	if err := repo.Delete(ctx, "missing", 0); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Delete() missing error = %v, want ErrUserNotFound", err)
+	}
+	ada, _ := repo.GetByID(ctx, "ada@example.com")
+	if err := repo.Delete(ctx, ada.ID, ada.Version+1); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("Delete() at a stale version error = %v, want ErrVersionMismatch", err)
+	}
+
+	u, err := repo.GetByEmail(ctx, "Grace@Example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() error = %v", err)
+	}
+	u.Name = "changed"
+	if stored, _ := repo.GetByID(ctx, u.ID); stored.Name == "changed" {
+		t.Error("returned user shares state with the repository")
+	}
+
+	users, _ := repo.List(ctx, 2, 0)
+	if len(users) != 2 || users[0].Email != "alan@example.com" || users[1].Email != "grace@example.com" {
+		t.Errorf("List(2, 0) = %v, want newest first", users)
+	}
+	if users, _ := repo.List(ctx, 2, 2); len(users) != 1 || users[0].Email != "ada@example.com" {
+		t.Errorf("List(2, 2) = %v, want the oldest user", users)
+	}
+	if users, _ := repo.GetByIDs(ctx, []string{"ada@example.com", "missing", "ada@example.com"}); len(users) != 1 {
+		t.Errorf("GetByIDs() = %v, want the known user once", users)
+	}
+}
+
+func TestOrderRepositorySummarize(t *testing.T) {
+	ctx := context.Background()
+	repo := NewOrderRepository()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	orders := []*domain.Order{
+		{ID: "1", UserID: "u", Amount: 10, Status: domain.OrderStatusPending, CreatedAt: from},
+		{ID: "2", UserID: "u", Amount: 5, Status: domain.OrderStatusPending, CreatedAt: from.Add(time.Hour)},
+		{ID: "3", UserID: "u", Amount: 7, Status: domain.OrderStatusPending, CreatedAt: from.Add(24 * time.Hour)},
+	}
+	for _, o := range orders {
+		if err := repo.Create(ctx, o); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	summary, err := repo.Summarize(ctx, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if summary.Count != 2 || summary.Total != 15 || summary.ByStatus[domain.OrderStatusPending] != 2 {
+		t.Errorf("Summarize() = %+v, want the two orders in [from, to)", summary)
+	}
+}
+
+func TestOrderRepositoryGetByUserIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := NewOrderRepository()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, userID := range []string{"a", "a", "a", "b"} {
+		order := &domain.Order{ID: string(rune('1' + i)), UserID: userID, CreatedAt: from.Add(time.Duration(i) * time.Hour)}
+		if err := repo.Create(ctx, order); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	byUser, err := repo.GetByUserIDs(ctx, []string{"a", "b", "c"}, 2)
+	if err != nil {
+		t.Fatalf("GetByUserIDs() error = %v", err)
+	}
+	if a := byUser["a"]; len(a) != 2 || a[0].ID != "3" || a[1].ID != "2" {
+		t.Errorf("GetByUserIDs()[a] = %v, want the two newest orders", a)
+	}
+	if len(byUser["b"]) != 1 {
+		t.Errorf("GetByUserIDs()[b] = %v, want one order", byUser["b"])
+	}
+	if _, ok := byUser["c"]; ok {
+		t.Error("GetByUserIDs() includes a user without orders")
+	}
+}
+
+func TestReportScheduleRepositoryAdvance(t *testing.T) {
+	ctx := context.Background()
+	repo := NewReportScheduleRepository()
+	due := time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC)
+	next := due.Add(24 * time.Hour)
+
+	if err := repo.Create(ctx, &domain.ReportSchedule{ID: "s", NextRunAt: due}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if list, _ := repo.ListDue(ctx, due.Add(-time.Minute), 10); len(list) != 0 {
+		t.Errorf("ListDue() before the run = %d schedules, want 0", len(list))
+	}
+	if list, _ := repo.ListDue(ctx, due, 10); len(list) != 1 {
+		t.Errorf("ListDue() at the run = %d schedules, want 1", len(list))
+	}
+
+	if ok, _ := repo.Advance(ctx, "s", due, next, due); !ok {
+		t.Error("first Advance() should claim the run")
+	}
+	if ok, _ := repo.Advance(ctx, "s", due, next, due); ok {
+		t.Error("second Advance() of the same run should not claim it")
+	}
+}
+
+func TestJobQueueFIFO(t *testing.T) {
+	ctx := context.Background()
+	q := NewJobQueue()
+
+	for _, id := range []string{"a", "b"} {
+		if err := q.Enqueue(ctx, &domain.Job{ID: id, Priority: domain.JobPriorityDefault}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	if job, _ := q.Dequeue(ctx, domain.JobPriorityCritical); job != nil {
+		t.Errorf("Dequeue(critical) = %v, want nil", job)
+	}
+	for _, want := range []string{"a", "b"} {
+		job, err := q.Dequeue(ctx, domain.JobPriorityDefault)
+		if err != nil || job == nil || job.ID != want {
+			t.Fatalf("Dequeue() = %v, %v, want job %s", job, err, want)
+		}
+	}
+	if job, _ := q.Dequeue(ctx, domain.JobPriorityDefault); job != nil {
+		t.Errorf("Dequeue() on empty queue = %v, want nil", job)
+	}
+}
+
+func TestUserCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	cache := NewUserCache().(*UserCache)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.users.now = func() time.Time { return now }
+
+	cache.Set(ctx, &domain.User{ID: "u"})
+	if _, err := cache.Get(ctx, "u"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	now = now.Add(cache.ttl)
+	if _, err := cache.Get(ctx, "u"); !errors.Is(err, domain.ErrCacheMiss) {
+		t.Errorf("Get() after ttl error = %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestLoginAttemptStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLoginAttemptStore().(*LoginAttemptStore)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store.failures.now, store.locks.now = clock, clock
+
+	store.RecordFailure(ctx, "ip:1", time.Minute)
+	now = now.Add(30 * time.Second)
+	if n, _ := store.RecordFailure(ctx, "ip:1", time.Minute); n != 2 {
+		t.Errorf("RecordFailure() = %d, want 2", n)
+	}
+
+	// The window is anchored at the first failure, not extended by the second
+	now = now.Add(30 * time.Second)
+	if n, _, _ := store.Failures(ctx, "ip:1"); n != 0 {
+		t.Errorf("Failures() after the window = %d, want 0", n)
+	}
+
+	store.Lock(ctx, "ip:1", time.Minute)
+	if _, lockedFor, _ := store.Failures(ctx, "ip:1"); lockedFor != time.Minute {
+		t.Errorf("lockedFor = %s, want 1m", lockedFor)
+	}
+	now = now.Add(time.Minute)
+	if _, lockedFor, _ := store.Failures(ctx, "ip:1"); lockedFor != 0 {
+		t.Errorf("lockedFor after the lockout = %s, want 0", lockedFor)
+	}
+}
+
+func TestSemaphoreStoreLeases(t *testing.T) {
+	ctx := context.Background()
+	store := NewSemaphoreStore().(*SemaphoreStore)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	acquire := func(holder string) bool {
+		ok, err := store.TryAcquire(ctx, "reports", holder, 1, time.Minute)
+		if err != nil {
+			t.Fatalf("TryAcquire() error = %v", err)
+		}
+		return ok
+	}
+
+	if !acquire("a") {
+		t.Fatal("first holder should get the slot")
+	}
+	if !acquire("a") {
+		t.Error("re-acquiring should renew the holder's own lease")
+	}
+	if acquire("b") {
+		t.Error("second holder should be refused at the limit")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, _ := store.Refresh(ctx, "reports", "a", time.Minute); ok {
+		t.Error("Refresh() of an expired lease should report false")
+	}
+	if !acquire("b") {
+		t.Error("an expired lease should free its slot")
+	}
+}
+
+func TestRequestStatsStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewRequestStatsStore()
+	now := time.Now().UTC().Truncate(time.Hour)
+
+	// Two instances flushing the same hour, one earlier hour, one expired hour
+	for _, s := range []struct {
+		hour    time.Time
+		latency time.Duration
+		failed  bool
+	}{
+		{now, 3 * time.Millisecond, false},
+		{now.Add(30 * time.Minute), 40 * time.Millisecond, true},
+		{now.Add(-2 * time.Hour), 900 * time.Millisecond, false},
+		{now.Add(-9 * 24 * time.Hour), time.Millisecond, false},
+	} {
+		stats := domain.NewRequestStats(s.hour)
+		stats.Observe(s.latency, s.failed)
+		if err := store.Add(ctx, stats); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	hours, err := store.Range(ctx, now.Add(-10*24*time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+	if len(hours) != 2 {
+		t.Fatalf("Range() returned %d hours, want 2 (expired hour dropped)", len(hours))
+	}
+	if !hours[0].Hour.Equal(now.Add(-2 * time.Hour)) {
+		t.Errorf("first hour = %v, want oldest first", hours[0].Hour)
+	}
+	if got := hours[1]; got.Requests != 2 || got.Errors != 1 {
+		t.Errorf("merged hour = %d requests, %d errors, want 2 and 1", got.Requests, got.Errors)
+	}
+	if got := hours[1].Percentile(0.5); got != 5 {
+		t.Errorf("Percentile(0.5) = %d, want 5", got)
+	}
+	if got := hours[1].Percentile(0.99); got != 50 {
+		t.Errorf("Percentile(0.99) = %d, want 50", got)
+	}
+
+	hours[1].Requests = 100
+	if again, _ := store.Range(ctx, now, now); again[0].Requests != 2 {
+		t.Error("returned stats share state with the store")
+	}
+}
+
+func TestOrderEventBus(t *testing.T) {
+	bus := NewOrderEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	received := make(chan *domain.OrderEvent, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Subscribe(ctx, func(e *domain.OrderEvent) { received <- e })
+	}()
+
+	// Publish until the subscriber is registered
+	event := &domain.OrderEvent{ID: "e1", OrderID: "o1", Status: domain.OrderStatusShipped}
+	var got *domain.OrderEvent
+	for got == nil {
+		bus.Publish(ctx, event)
+		select {
+		case got = <-received:
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if got.OrderID != "o1" || got.Status != domain.OrderStatusShipped {
+		t.Errorf("received %+v, want the published event", got)
+	}
+	if got == event {
+		t.Error("subscribers should receive a copy of the event")
+	}
+
+	cancel()
+	<-done
+	bus.Publish(context.Background(), event)
+	select {
+	case e := <-received:
+		t.Errorf("received %+v after Subscribe returned", e)
+	default:
+	}
+}
+
+func TestOrderEventLog(t *testing.T) {
+	ctx := context.Background()
+	log := NewOrderEventLog(3)
+
+	var ids []string
+	for _, status := range []domain.OrderStatus{domain.OrderStatusPending, domain.OrderStatusConfirmed, domain.OrderStatusShipped, domain.OrderStatusDelivered} {
+		id, err := log.Append(ctx, &domain.OrderEvent{OrderID: "o1", Status: status})
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if last, _ := log.LastID(ctx); last != ids[3] {
+		t.Errorf("LastID() = %q, want %q", last, ids[3])
+	}
+	events, err := log.After(ctx, ids[1], 10, 0)
+	if err != nil {
+		t.Fatalf("After() error = %v", err)
+	}
+	if len(events) != 2 || events[0].ID != ids[2] || events[1].Status != domain.OrderStatusDelivered {
+		t.Errorf("After(%s) = %+v, want the last two events", ids[1], events)
+	}
+	if events, _ := log.After(ctx, "", 1, 0); len(events) != 1 || events[0].ID != ids[1] {
+		t.Errorf("After(\"\") = %+v, want the oldest retained event", events)
+	}
+
+	// The first event was trimmed, so resuming from before it loses events
+	if _, err := log.After(ctx, "0", 10, 0); !errors.Is(err, domain.ErrOrderEventsExpired) {
+		t.Errorf("After(trimmed) error = %v, want ErrOrderEventsExpired", err)
+	}
+	if _, err := log.After(ctx, ids[0], 10, 0); err != nil {
+		t.Errorf("After(newest trimmed) error = %v, nothing after it was lost", err)
+	}
+	if _, err := log.After(ctx, "abc", 10, 0); !errors.Is(err, domain.ErrInvalidEventID) {
+		t.Errorf("After(abc) error = %v, want ErrInvalidEventID", err)
+	}
+
+	// Waiting returns as soon as an event is appended
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		log.Append(ctx, &domain.OrderEvent{OrderID: "o2"})
+	}()
+	events, err = log.After(ctx, ids[3], 10, time.Second)
+	if err != nil || len(events) != 1 || events[0].OrderID != "o2" {
+		t.Errorf("After(wait) = %+v, %v, want the appended event", events, err)
+	}
+	if events, _ := log.After(ctx, events[0].ID, 10, 10*time.Millisecond); len(events) != 0 {
+		t.Errorf("After(wait) = %+v, want none after the timeout", events)
+	}
+}
+
+func TestStream(t *testing.T) {
+	ctx := context.Background()
+	users := NewUserRepository()
+	orders := NewOrderRepository()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c"} {
+		created := base.Add(time.Duration(i) * time.Hour)
+		if err := users.Create(ctx, &domain.User{ID: id, Email: id + "@example.com", CreatedAt: created}); err != nil {
+			t.Fatal(err)
+		}
+		order := &domain.Order{ID: id, UserID: id, Status: domain.OrderStatusPending, CreatedAt: created,
+			Items: []domain.OrderItem{{ProductID: "pen", Quantity: 1, Price: 1}}}
+		if err := orders.Create(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	collect := func(u *domain.User) error { got = append(got, u.ID); return nil }
+	if err := users.Stream(ctx, 0, 1, collect); err != nil || len(got) != 2 || got[0] != "b" {
+		t.Errorf("Stream(0, 1) = %v, %v; want [b a]", got, err)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err := orders.Stream(ctx, 2, 0, func(*domain.Order) error { n++; return stop })
+	if !errors.Is(err, stop) || n != 1 {
+		t.Errorf("Stream() returned %v after %d orders, want the callback's error after 1", err, n)
+	}
+}
+
+func TestListKeyset(t *testing.T) {
+	ctx := context.Background()
+	users := NewUserRepository()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Newest first with ties by ID: d, b, c, a
+	created := map[string]time.Time{"a": base, "b": base.Add(time.Hour), "c": base.Add(time.Hour), "d": base.Add(2 * time.Hour)}
+	for id, at := range created {
+		if err := users.Create(ctx, &domain.User{ID: id, Email: id + "@example.com", CreatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cursor := func(id string) *domain.Cursor { return &domain.Cursor{CreatedAt: created[id], ID: id} }
+	ids := func(page domain.KeysetPage) string {
+		got, err := users.ListKeyset(ctx, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s string
+		for _, u := range got {
+			s += u.ID
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		page domain.KeysetPage
+		want string
+	}{
+		{"first", domain.KeysetPage{Limit: 2}, "db"},
+		{"after a tie", domain.KeysetPage{Limit: 2, After: cursor("b")}, "ca"},
+		{"after the last", domain.KeysetPage{Limit: 2, After: cursor("a")}, ""},
+		{"before", domain.KeysetPage{Limit: 2, Before: cursor("a")}, "bc"},
+		{"before a tie", domain.KeysetPage{Limit: 5, Before: cursor("c")}, "db"},
+		{"before the first", domain.KeysetPage{Limit: 2, Before: cursor("d")}, ""},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.page); got != tt.want {
+			t.Errorf("%s: ListKeyset() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	orders := NewOrderRepository()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, o := range []struct {
+		id     string
+		status domain.OrderStatus
+		amount float64
+	}{
+		{"a", domain.OrderStatusPending, 50},
+		{"b", domain.OrderStatusShipped, 150},
+		{"c", domain.OrderStatusShipped, 100},
+		{"d", domain.OrderStatusDelivered, 100},
+	} {
+		order := &domain.Order{ID: o.id, UserID: "u1", Status: o.status, Amount: o.amount,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := orders.Create(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		q    domain.ListQuery
+		want string
+	}{
+		{"newest first", domain.ListQuery{}, "dcba"},
+		{"eq", domain.ListQuery{Filters: []domain.Filter{{Field: "status", Op: domain.OpEq, Value: "shipped"}}}, "cb"},
+		{"gte and ne", domain.ListQuery{Filters: []domain.Filter{
+			{Field: "amount", Op: domain.OpGte, Value: 100.0},
+			{Field: "status", Op: domain.OpNe, Value: "delivered"},
+		}}, "cb"},
+		{"in", domain.ListQuery{Filters: []domain.Filter{{Field: "id", Op: domain.OpIn, Value: []string{"a", "d"}}}}, "da"},
+		{"time", domain.ListQuery{Filters: []domain.Filter{{Field: "created_at", Op: domain.OpLt, Value: base.Add(time.Hour)}}}, "a"},
+		{"sort ties by ID", domain.ListQuery{Sort: []domain.SortKey{{Field: "amount", Desc: true}}}, "bcda"},
+		{"sort by two keys", domain.ListQuery{Sort: []domain.SortKey{{Field: "status"}, {Field: "amount", Desc: true}}}, "dabc"},
+	}
+	for _, tt := range tests {
+		got, err := orders.Search(ctx, tt.q, 10, 0)
+		if err != nil {
+			t.Fatalf("%s: Search() error = %v", tt.name, err)
+		}
+		var ids string
+		for _, o := range got {
+			ids += o.ID
+		}
+		if ids != tt.want {
+			t.Errorf("%s: Search() = %q, want %q", tt.name, ids, tt.want)
+		}
+	}
+
+	shipped := []domain.Filter{{Field: "status", Op: domain.OpEq, Value: "shipped"}}
+	if n, err := orders.Count(ctx, shipped); err != nil || n != 2 {
+		t.Errorf("Count(shipped) = %d, %v; want 2", n, err)
+	}
+	if n, _ := orders.Count(ctx, nil); n != 4 {
+		t.Errorf("Count() = %d, want 4", n)
+	}
+
+	users := NewUserRepository()
+	if err := users.Create(ctx, &domain.User{ID: "u1", Name: "Ada Lovelace", Email: "ada@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	found, _ := users.Search(ctx, domain.ListQuery{Filters: []domain.Filter{{Field: "name", Op: domain.OpContains, Value: "LOVE"}}}, 10, 0)
+	if len(found) != 1 {
+		t.Errorf("contains filter found %d users, want 1", len(found))
+	}
+
+	_, err := orders.Search(ctx, domain.ListQuery{Filters: []domain.Filter{{Field: "items", Op: domain.OpEq, Value: "x"}}}, 10, 0)
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("filter on an unknown field: error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	ctx := context.Background()
+	orders := NewOrderRepository()
+	order, _ := domain.NewOrder("o1", "u1", []domain.OrderItem{{ProductID: "p", Quantity: 1, Price: 10}})
+	if err := orders.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := orders.GetByID(ctx, "o1")
+	second, _ := orders.GetByID(ctx, "o1")
+	if err := first.Confirm(); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.Update(ctx, first); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Version after Update() = %d, want 2", first.Version)
+	}
+	if err := second.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.Update(ctx, second); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("Update() of a stale order error = %v, want ErrVersionMismatch", err)
+	}
+	if stored, _ := orders.GetByID(ctx, "o1"); stored.Status != domain.OrderStatusConfirmed || stored.Version != 2 {
+		t.Errorf("stored order = %s at version %d, want confirmed at 2", stored.Status, stored.Version)
+	}
+
+	users := NewUserRepository()
+	user, _ := domain.NewUser("u1", "Ada", "ada@example.com")
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	stale := *user
+	if err := users.Update(ctx, user); err != nil || user.Version != 2 {
+		t.Fatalf("Update() = %v at version %d, want nil at 2", err, user.Version)
+	}
+	if err := users.Update(ctx, &stale); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("Update() of a stale user error = %v, want ErrVersionMismatch", err)
+	}
+}
+
+func TestUserCreateMany(t *testing.T) {
+	ctx := context.Background()
+	users := NewUserRepository()
+	existing, _ := domain.NewUser("u1", "Ada", "ada@example.com")
+	if err := users.Create(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := make([]*domain.User, 3)
+	batch[0], _ = domain.NewUser("u2", "Grace", "grace@example.com")
+	batch[1], _ = domain.NewUser("u3", "Ada Again", "ADA@example.com")
+	batch[2], _ = domain.NewUser("u4", "Grace Again", "grace@example.com")
+	errs, err := users.CreateMany(ctx, batch)
+	if err != nil {
+		t.Fatalf("CreateMany() error = %v", err)
+	}
+	if errs[0] != nil {
+		t.Errorf("CreateMany() error for a new email = %v, want nil", errs[0])
+	}
+	for _, i := range []int{1, 2} {
+		if !errors.Is(errs[i], domain.ErrUserAlreadyExists) {
+			t.Errorf("CreateMany() error for user %d = %v, want ErrUserAlreadyExists", i, errs[i])
+		}
+	}
+	if count, _ := users.Count(ctx, nil); count != 2 {
+		t.Errorf("Count() after CreateMany() = %d, want 2", count)
+	}
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewIdempotencyStore().(*IdempotencyStore)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.records.now = func() time.Time { return now }
+
+	if record, err := store.Reserve(ctx, "k", "fp", time.Minute); err != nil || record != nil {
+		t.Fatalf("Reserve() of a new key = %v, %v; want nil, nil", record, err)
+	}
+	if record, _ := store.Reserve(ctx, "k", "other", time.Minute); record == nil || record.Completed || record.Fingerprint != "fp" {
+		t.Errorf("Reserve() of a reserved key = %+v, want the pending reservation", record)
+	}
+
+	// A reservation left behind by a crash expires
+	now = now.Add(time.Minute)
+	if record, _ := store.Reserve(ctx, "k", "fp", time.Minute); record != nil {
+		t.Errorf("Reserve() after the lock ttl = %+v, want nil", record)
+	}
+
+	body := []byte(`{"id":"o1"}`)
+	store.Complete(ctx, "k", &domain.IdempotencyRecord{Fingerprint: "fp", Completed: true, Status: 201, Body: body}, time.Hour)
+	body[0] = 'x'
+	record, _ := store.Reserve(ctx, "k", "fp", time.Minute)
+	if record == nil || !record.Completed || record.Status != 201 || string(record.Body) != `{"id":"o1"}` {
+		t.Errorf("Reserve() of a completed key = %+v, want the stored response", record)
+	}
+
+	store.Release(ctx, "k")
+	if record, _ := store.Reserve(ctx, "k", "fp", time.Minute); record != nil {
+		t.Errorf("Reserve() after Release() = %+v, want nil", record)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewResponseCache().(*ResponseCache)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.entries.now = func() time.Time { return now }
+	tags := []string{"users", "orders"}
+
+	_, version, err := cache.Get(ctx, "k", tags)
+	if !errors.Is(err, domain.ErrCacheMiss) {
+		t.Fatalf("Get() of a new key error = %v, want ErrCacheMiss", err)
+	}
+	body := []byte(`{"id":"u1"}`)
+	cache.Set(ctx, "k", &domain.CachedResponse{Version: version, Status: 200, Body: body}, time.Minute)
+	body[0] = 'x'
+	if resp, _, err := cache.Get(ctx, "k", tags); err != nil || string(resp.Body) != `{"id":"u1"}` {
+		t.Errorf("Get() = %+v, %v; want the stored response", resp, err)
+	}
+
+	// A write to any tag makes the entry stale, as does its TTL
+	cache.Invalidate(ctx, "orders")
+	_, bumped, err := cache.Get(ctx, "k", tags)
+	if !errors.Is(err, domain.ErrCacheMiss) || bumped == version {
+		t.Errorf("Get() after Invalidate() = %q, %v; want ErrCacheMiss and a new version", bumped, err)
+	}
+	if _, _, err := cache.Get(ctx, "k", []string{"users"}); !errors.Is(err, domain.ErrCacheMiss) {
+		t.Errorf("Get() under other tags error = %v, want ErrCacheMiss", err)
+	}
+	cache.Set(ctx, "k", &domain.CachedResponse{Version: bumped, Status: 200}, time.Minute)
+	now = now.Add(time.Minute)
+	if _, _, err := cache.Get(ctx, "k", tags); !errors.Is(err, domain.ErrCacheMiss) {
+		t.Errorf("Get() after ttl error = %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewEventBus()
+
+	var all, shipped []string
+	unsubscribe := bus.Subscribe(func(_ context.Context, e domain.Event) error {
+		all = append(all, e.EventType())
+		return nil
+	})
+	bus.Subscribe(func(_ context.Context, e domain.Event) error {
+		shipped = append(shipped, e.AggregateID())
+		return errors.New("webhook down")
+	}, domain.EventOrderShipped)
+	bus.Subscribe(func(context.Context, domain.Event) error {
+		panic("boom")
+	}, domain.EventOrderCancelled)
+
+	order := &domain.Order{ID: "o1", UserID: "u1", Status: domain.OrderStatusShipped}
+	event, ok := domain.NewOrderLifecycleEvent("e1", order, domain.OrderStatusConfirmed)
+	if _, typed := event.(domain.OrderShipped); !ok || !typed {
+		t.Fatalf("NewOrderLifecycleEvent() = %T, want OrderShipped", event)
+	}
+	if err := bus.Publish(ctx, event); err == nil {
+		t.Error("Publish() error = nil, want the failing handler's error")
+	}
+	if err := bus.Publish(ctx, domain.OrderCreated{OrderID: "o2"}); err != nil {
+		t.Errorf("Publish(OrderCreated) error = %v", err)
+	}
+	if err := bus.Publish(ctx, domain.OrderCancelled{OrderID: "o1"}); err == nil {
+		t.Error("Publish() error = nil, want the panicking handler's error")
+	}
+	if want := []string{domain.EventOrderShipped, domain.EventOrderCreated, domain.EventOrderCancelled}; !slices.Equal(all, want) {
+		t.Errorf("catch-all handler got %v, want %v", all, want)
+	}
+	if !slices.Equal(shipped, []string{"o1"}) {
+		t.Errorf("order.shipped handler got %v, want [o1]", shipped)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(ctx, domain.OrderConfirmed{OrderID: "o3"})
+	if len(all) != 3 {
+		t.Errorf("catch-all handler got %v after unsubscribing", all)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewOutbox()
+	outbox.Add(ctx,
+		domain.OrderCreated{EventMetadata: domain.EventMetadata{ID: "e1"}, OrderID: "o1"},
+		domain.OrderCreated{EventMetadata: domain.EventMetadata{ID: "e2"}, OrderID: "o2"},
+		domain.OrderConfirmed{EventMetadata: domain.EventMetadata{ID: "e3"}, OrderID: "o1"},
+		domain.OrderConfirmed{EventMetadata: domain.EventMetadata{ID: "e4"}, OrderID: "o2"},
+	)
+
+	// e1 fails, so e3 of the same order waits; the other order carries on
+	var relayed []string
+	publish := func(_ context.Context, entry domain.OutboxEntry) error {
+		id := entry.Event.Metadata().ID
+		if id == "e1" && entry.Attempts == 0 {
+			return errors.New("bus down")
+		}
+		relayed = append(relayed, id)
+		return nil
+	}
+	if n, err := outbox.Relay(ctx, 10, publish); err != nil || n != 2 {
+		t.Fatalf("Relay() = %d, %v, want 2 published", n, err)
+	}
+	if n, err := outbox.Relay(ctx, 10, publish); err != nil || n != 2 {
+		t.Fatalf("second Relay() = %d, %v, want the 2 held back", n, err)
+	}
+	if want := []string{"e2", "e4", "e1", "e3"}; !slices.Equal(relayed, want) {
+		t.Errorf("relayed %v, want %v", relayed, want)
+	}
+	if n, _ := outbox.Relay(ctx, 10, publish); n != 0 {
+		t.Errorf("Relay() republished %d events", n)
+	}
+
+	if n, _ := outbox.Purge(ctx, time.Now().Add(-time.Hour)); n != 0 {
+		t.Errorf("Purge() before publication = %d, want 0", n)
+	}
+	if n, _ := outbox.Purge(ctx, time.Now().Add(time.Second)); n != 4 {
+		t.Errorf("Purge() = %d, want 4", n)
+	}
+}
+
+func TestNotificationPreferencesRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewNotificationPreferencesRepository()
+
+	if _, err := repo.Get(ctx, "u1"); !errors.Is(err, domain.ErrNotificationPreferencesNotFound) {
+		t.Errorf("Get() before Save() error = %v, want ErrNotificationPreferencesNotFound", err)
+	}
+	p := &domain.NotificationPreferences{
+		UserID:    "u1",
+		Addresses: map[domain.NotificationChannel]string{domain.NotificationSMS: "+14155550100"},
+		Events:    map[string][]domain.NotificationChannel{domain.EventOrderShipped: {domain.NotificationSMS}},
+	}
+	if err := repo.Save(ctx, p); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	p.Events[domain.EventOrderShipped][0] = domain.NotificationSlack
+
+	got, err := repo.Get(ctx, "u1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if channels := got.Events[domain.EventOrderShipped]; !slices.Equal(channels, []domain.NotificationChannel{domain.NotificationSMS}) {
+		t.Errorf("Get() channels = %v, want the saved ones unshared", channels)
+	}
+	got.Addresses[domain.NotificationSMS] = "+1"
+	if stored, _ := repo.Get(ctx, "u1"); stored.Addresses[domain.NotificationSMS] != "+14155550100" {
+		t.Error("returned preferences share state with the repository")
+	}
+}
+
+func TestSagaRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSagaRepository()
+	start := time.Now().UTC()
+
+	saga := &domain.Saga{ID: "s1", Type: "order.checkout", Key: "o1", Status: domain.SagaRunning,
+		Data: map[string]string{}, CreatedAt: start, UpdatedAt: start, Version: 1}
+	if err := repo.Create(ctx, saga); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	second := saga.Clone()
+	second.ID = "s2"
+	if err := repo.Create(ctx, second); !errors.Is(err, domain.ErrSagaAlreadyExists) {
+		t.Errorf("Create() of a second saga for the key error = %v, want ErrSagaAlreadyExists", err)
+	}
+
+	stale := saga.Clone()
+	saga.Data["payment_id"] = "pay_1"
+	saga.Step = 1
+	if err := repo.Update(ctx, saga); err != nil || saga.Version != 2 {
+		t.Fatalf("Update() error = %v, version %d", err, saga.Version)
+	}
+	if err := repo.Update(ctx, stale); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("Update() at a stale version error = %v, want ErrVersionMismatch", err)
+	}
+	saga.Data["payment_id"] = "changed"
+	if got, _ := repo.Get(ctx, "s1"); got.Data["payment_id"] != "pay_1" || got.Step != 1 {
+		t.Errorf("Get() = %+v, want the stored saga unshared", got)
+	}
+
+	if unfinished, _ := repo.ListUnfinished(ctx, start.Add(time.Minute), 10); len(unfinished) != 1 {
+		t.Errorf("ListUnfinished() = %d sagas, want 1", len(unfinished))
+	}
+	if unfinished, _ := repo.ListUnfinished(ctx, start, 10); len(unfinished) != 0 {
+		t.Errorf("ListUnfinished() before the update = %d sagas, want 0", len(unfinished))
+	}
+
+	// Once compensated, another saga may run for the key
+	saga, _ = repo.Get(ctx, "s1")
+	saga.Status = domain.SagaCompensated
+	if err := repo.Update(ctx, saga); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	second.CreatedAt = start.Add(time.Second)
+	if err := repo.Create(ctx, second); err != nil {
+		t.Fatalf("Create() after compensation error = %v", err)
+	}
+	if got, _ := repo.GetByKey(ctx, "order.checkout", "o1"); got == nil || got.ID != "s2" {
+		t.Errorf("GetByKey() = %+v, want the newest saga", got)
+	}
+	if _, err := repo.GetByKey(ctx, "order.checkout", "o2"); !errors.Is(err, domain.ErrSagaNotFound) {
+		t.Errorf("GetByKey() of an unknown key error = %v, want ErrSagaNotFound", err)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	ctx := context.Background()
+	inv := NewInventory()
+	inv.SetStock(ctx, "p1", 5)
+	items := []domain.OrderItem{{ProductID: "p1", Quantity: 2}, {ProductID: "p1", Quantity: 1}, {ProductID: "untracked", Quantity: 100}}
+
+	if err := inv.Reserve(ctx, "r1", items); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := inv.Reserve(ctx, "r1", items); err != nil {
+		t.Fatalf("Reserve() again error = %v", err)
+	}
+	if available, tracked, _ := inv.Stock(ctx, "p1"); available != 2 || !tracked {
+		t.Errorf("Stock() = %d, %v, want 2 reserved once", available, tracked)
+	}
+	if err := inv.Reserve(ctx, "r2", items); !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Errorf("Reserve() beyond stock error = %v, want ErrInsufficientStock", err)
+	}
+	if available, _, _ := inv.Stock(ctx, "p1"); available != 2 {
+		t.Errorf("Stock() after a failed reservation = %d, want 2", available)
+	}
+
+	for range 2 {
+		if err := inv.Release(ctx, "r1"); err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+	}
+	if available, _, _ := inv.Stock(ctx, "p1"); available != 5 {
+		t.Errorf("Stock() after release = %d, want 5", available)
+	}
+	if err := inv.Release(ctx, "never-reserved"); err != nil {
+		t.Errorf("Release() of an unknown reservation error = %v", err)
+	}
+	if _, tracked, _ := inv.Stock(ctx, "untracked"); tracked {
+		t.Error("Stock() tracks a product never given a stock level")
+	}
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	ctx := context.Background()
+	q := NewDeadLetterQueue()
+	now := time.Now().UTC()
+	old := &domain.DeadLetter{Source: domain.DeadLetterJobs, Type: "report.email", Payload: []byte(`{}`), FailedAt: now.Add(-48 * time.Hour)}
+	recent := &domain.DeadLetter{Source: domain.DeadLetterNATS, Type: "order.shipped", Attributes: map[string]string{"stream": "EVENTS"}, FailedAt: now}
+	for _, d := range []*domain.DeadLetter{old, recent} {
+		if err := q.Add(ctx, d); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if old.ID == 0 || recent.ID == old.ID {
+		t.Fatalf("Add() set IDs %d and %d, want distinct ones", old.ID, recent.ID)
+	}
+	recent.Attributes["stream"] = "changed"
+
+	got, err := q.Get(ctx, recent.ID)
+	if err != nil || got.Attributes["stream"] != "EVENTS" {
+		t.Errorf("Get() = %+v, %v, want the dead letter as added", got, err)
+	}
+	if _, err := q.Get(ctx, 999); !errors.Is(err, domain.ErrDeadLetterNotFound) {
+		t.Errorf("Get() of an unknown ID error = %v, want ErrDeadLetterNotFound", err)
+	}
+
+	all, _ := q.List(ctx, domain.DeadLetterFilter{})
+	if len(all) != 2 || all[0].ID != recent.ID {
+		t.Errorf("List() = %d dead letters, want 2 newest first", len(all))
+	}
+	jobs, _ := q.List(ctx, domain.DeadLetterFilter{Source: domain.DeadLetterJobs})
+	if len(jobs) != 1 || jobs[0].ID != old.ID {
+		t.Errorf("List() of jobs = %v, want the job only", jobs)
+	}
+
+	got.ReplayedAt = &now
+	got.Replays = 1
+	if err := q.Update(ctx, got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	pending := false
+	if list, _ := q.List(ctx, domain.DeadLetterFilter{Replayed: &pending}); len(list) != 1 || list[0].ID != old.ID {
+		t.Errorf("List() of pending = %v, want the one not replayed", list)
+	}
+	if list, _ := q.List(ctx, domain.DeadLetterFilter{Limit: 1, Offset: 1}); len(list) != 1 || list[0].ID != old.ID {
+		t.Errorf("List() second page = %v, want the oldest", list)
+	}
+
+	if purged, err := q.Purge(ctx, now.Add(-time.Hour)); err != nil || purged != 1 {
+		t.Errorf("Purge() = %d, %v, want 1", purged, err)
+	}
+	if err := q.Delete(ctx, recent.ID); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := q.Delete(ctx, recent.ID); !errors.Is(err, domain.ErrDeadLetterNotFound) {
+		t.Errorf("Delete() again error = %v, want ErrDeadLetterNotFound", err)
+	}
+}
@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure NotificationPreferencesRepository implements domain.NotificationPreferencesRepository at compile time
+var _ domain.NotificationPreferencesRepository = (*NotificationPreferencesRepository)(nil)
+
+// NotificationPreferencesRepository is an in-memory implementation of
+// domain.NotificationPreferencesRepository
+type NotificationPreferencesRepository struct {
+	mu          sync.RWMutex
+	preferences map[string]*domain.NotificationPreferences // By user ID
+}
+
+// NewNotificationPreferencesRepository creates an empty in-memory notification preference repository
+func NewNotificationPreferencesRepository() domain.NotificationPreferencesRepository {
+	return &NotificationPreferencesRepository{preferences: make(map[string]*domain.NotificationPreferences)}
+}
+
+func (r *NotificationPreferencesRepository) Get(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.preferences[userID]
+	if !ok {
+		return nil, domain.ErrNotificationPreferencesNotFound
+	}
+	return p.Clone(), nil
+}
+
+func (r *NotificationPreferencesRepository) Save(ctx context.Context, p *domain.NotificationPreferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.preferences[p.UserID] = p.Clone()
+	return nil
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure OrderEventLog implements domain.OrderEventLog at compile time
+var _ domain.OrderEventLog = (*OrderEventLog)(nil)
+
+// OrderEventLog is an in-memory implementation of domain.OrderEventLog.
+// Event IDs are sequence numbers starting at 1.
+type OrderEventLog struct {
+	mu      sync.Mutex
+	events  []*domain.OrderEvent // Oldest first
+	lastSeq uint64
+	maxLen  int
+	changed chan struct{} // Closed and replaced on every append
+}
+
+// NewOrderEventLog creates an in-memory order event log keeping the newest maxLen events
+func NewOrderEventLog(maxLen int) domain.OrderEventLog {
+	return &OrderEventLog{maxLen: maxLen, changed: make(chan struct{})}
+}
+
+func (l *OrderEventLog) Append(ctx context.Context, event *domain.OrderEvent) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastSeq++
+	stored := *event
+	stored.ID = strconv.FormatUint(l.lastSeq, 10)
+	l.events = append(l.events, &stored)
+	if l.maxLen > 0 && len(l.events) > l.maxLen {
+		l.events = l.events[len(l.events)-l.maxLen:]
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return stored.ID, nil
+}
+
+func (l *OrderEventLog) After(ctx context.Context, afterID string, limit int, wait time.Duration) ([]*domain.OrderEvent, error) {
+	var after uint64
+	if afterID != "" {
+		var err error
+		if after, err = strconv.ParseUint(afterID, 10, 64); err != nil {
+			return nil, domain.ErrInvalidEventID
+		}
+	}
+
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		l.mu.Lock()
+		if afterID != "" && len(l.events) > 0 && l.seq(0) > after+1 {
+			l.mu.Unlock()
+			return nil, domain.ErrOrderEventsExpired
+		}
+		var events []*domain.OrderEvent
+		for i, e := range l.events {
+			if l.seq(i) > after && len(events) < limit {
+				copied := *e
+				events = append(events, &copied)
+			}
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		if len(events) > 0 || timeout == nil {
+			return events, nil
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *OrderEventLog) LastID(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lastSeq == 0 {
+		return "", nil
+	}
+	return strconv.FormatUint(l.lastSeq, 10), nil
+}
+
+// seq returns the sequence number of the i-th retained event; l.mu must be held
+func (l *OrderEventLog) seq(i int) uint64 {
+	return l.lastSeq - uint64(len(l.events)-1-i)
+}
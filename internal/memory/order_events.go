@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure OrderEventBus implements domain.OrderEventBus at compile time
+var _ domain.OrderEventBus = (*OrderEventBus)(nil)
+
+// OrderEventBus is an in-process implementation of domain.OrderEventBus;
+// events only reach subscribers of the same instance
+type OrderEventBus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]func(*domain.OrderEvent)
+}
+
+// NewOrderEventBus creates an in-memory order event bus
+func NewOrderEventBus() domain.OrderEventBus {
+	return &OrderEventBus{handlers: make(map[int]func(*domain.OrderEvent))}
+}
+
+func (b *OrderEventBus) Publish(ctx context.Context, event *domain.OrderEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handle := range b.handlers {
+		e := *event
+		handle(&e)
+	}
+	return nil
+}
+
+func (b *OrderEventBus) Subscribe(ctx context.Context, handle func(*domain.OrderEvent)) error {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handle
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.handlers, id)
+	b.mu.Unlock()
+	return nil
+}
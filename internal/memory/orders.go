@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure OrderRepository implements domain.OrderRepository at compile time
+var _ domain.OrderRepository = (*OrderRepository)(nil)
+
+// OrderRepository is an in-memory implementation of domain.OrderRepository
+type OrderRepository struct {
+	mu     sync.RWMutex
+	orders map[string]*domain.Order
+}
+
+// NewOrderRepository creates an empty in-memory order repository
+func NewOrderRepository() domain.OrderRepository {
+	return &OrderRepository{orders: make(map[string]*domain.Order)}
+}
+
+func copyOrder(o *domain.Order) *domain.Order {
+	c := *o
+	c.Items = slices.Clone(o.Items)
+	c.CancelledAt = copyTime(o.CancelledAt)
+	return &c
+}
+
+func (r *OrderRepository) GetByID(ctx context.Context, id string) (*domain.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, domain.ErrOrderNotFound
+	}
+	return copyOrder(o), nil
+}
+
+func (r *OrderRepository) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*domain.Order, error) {
+	return r.list(limit, offset, func(o *domain.Order) bool { return o.UserID == userID }), nil
+}
+
+func (r *OrderRepository) GetByUserIDs(ctx context.Context, userIDs []string, limit int) (map[string][]*domain.Order, error) {
+	byUser := make(map[string][]*domain.Order)
+	for _, userID := range userIDs {
+		if orders := r.list(limit, 0, func(o *domain.Order) bool { return o.UserID == userID }); len(orders) > 0 {
+			byUser[userID] = orders
+		}
+	}
+	return byUser, nil
+}
+
+func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[order.ID]; ok {
+		return domain.ErrOrderAlreadyExists
+	}
+	c := copyOrder(order)
+	// Like the INSERT, a new order is never stored cancelled
+	c.CancelledAt = nil
+	r.orders[order.ID] = c
+	return nil
+}
+
+func (r *OrderRepository) Update(ctx context.Context, order *domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.orders[order.ID]
+	if !ok {
+		return domain.ErrOrderNotFound
+	}
+	if existing.Version != order.Version {
+		return domain.ErrVersionMismatch
+	}
+	// The owner and creation time are immutable, as in the UPDATE statement
+	c := copyOrder(order)
+	c.UserID = existing.UserID
+	c.CreatedAt = existing.CreatedAt
+	c.Version++
+	r.orders[order.ID] = c
+	order.Version = c.Version
+	return nil
+}
+
+func (r *OrderRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[id]; !ok {
+		return domain.ErrOrderNotFound
+	}
+	delete(r.orders, id)
+	return nil
+}
+
+func (r *OrderRepository) List(ctx context.Context, limit, offset int) ([]*domain.Order, error) {
+	return r.list(limit, offset, func(*domain.Order) bool { return true }), nil
+}
+
+func (r *OrderRepository) Search(ctx context.Context, q domain.ListQuery, limit, offset int) ([]*domain.Order, error) {
+	if err := checkQuery(q.Filters, q.Sort, domain.OrderQueryFields); err != nil {
+		return nil, err
+	}
+	orders := r.list(math.MaxInt, 0, func(*domain.Order) bool { return true })
+	orders = filterRows(orders, q.Filters, orderField)
+	sortRows(orders, q.Sort, orderField, func(o *domain.Order) string { return o.ID })
+	return page(orders, limit, offset), nil
+}
+
+func (r *OrderRepository) Count(ctx context.Context, filters []domain.Filter) (int, error) {
+	if err := checkQuery(filters, nil, domain.OrderQueryFields); err != nil {
+		return 0, err
+	}
+	orders := r.list(math.MaxInt, 0, func(*domain.Order) bool { return true })
+	return len(filterRows(orders, filters, orderField)), nil
+}
+
+func (r *OrderRepository) ListKeyset(ctx context.Context, page domain.KeysetPage) ([]*domain.Order, error) {
+	if err := checkQuery(page.Filters, nil, domain.OrderQueryFields); err != nil {
+		return nil, err
+	}
+	orders := r.list(math.MaxInt, 0, func(*domain.Order) bool { return true })
+	orders = filterRows(orders, page.Filters, orderField)
+	return keysetPage(orders, page,
+		func(o *domain.Order) time.Time { return o.CreatedAt },
+		func(o *domain.Order) string { return o.ID }), nil
+}
+
+func (r *OrderRepository) Stream(ctx context.Context, limit, offset int, fn func(*domain.Order) error) error {
+	if limit == 0 {
+		limit = math.MaxInt
+	}
+	for _, o := range r.list(limit, offset, func(*domain.Order) bool { return true }) {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OrderRepository) Summarize(ctx context.Context, from, to time.Time) (*domain.OrderSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summary := &domain.OrderSummary{From: from, To: to, ByStatus: make(map[domain.OrderStatus]int)}
+	for _, o := range r.orders {
+		if o.CreatedAt.Before(from) || !o.CreatedAt.Before(to) {
+			continue
+		}
+		summary.ByStatus[o.Status]++
+		summary.Count++
+		summary.Total += o.Amount
+	}
+	return summary, nil
+}
+
+// list returns a page of the orders matching keep, newest first
+func (r *OrderRepository) list(limit, offset int, keep func(*domain.Order) bool) []*domain.Order {
+	r.mu.RLock()
+	var orders []*domain.Order
+	for _, o := range r.orders {
+		if keep(o) {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+	r.mu.RUnlock()
+
+	sortNewestFirst(orders,
+		func(o *domain.Order) time.Time { return o.CreatedAt },
+		func(o *domain.Order) string { return o.ID })
+	return page(orders, limit, offset)
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure the outbox types implement the domain interfaces at compile time
+var (
+	_ domain.Outbox     = (*Outbox)(nil)
+	_ domain.Transactor = Transactor{}
+)
+
+// Transactor is an in-process domain.Transactor. The memory stores have no
+// transactions, so fn simply runs: writes it made before failing are kept.
+type Transactor struct{}
+
+// NewTransactor creates an in-process transactor
+func NewTransactor() Transactor {
+	return Transactor{}
+}
+
+func (Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// outboxRecord is an event in the outbox
+type outboxRecord struct {
+	entry     domain.OutboxEntry
+	published time.Time // Zero until published
+}
+
+// Outbox is an in-process implementation of domain.Outbox
+type Outbox struct {
+	mu      sync.Mutex
+	relay   sync.Mutex // Held by the Relay call in progress
+	nextSeq int64
+	records []*outboxRecord // By sequence
+	now     func() time.Time
+}
+
+// NewOutbox creates an in-memory outbox
+func NewOutbox() *Outbox {
+	return &Outbox{nextSeq: 1, now: time.Now}
+}
+
+func (o *Outbox) Add(ctx context.Context, events ...domain.Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, event := range events {
+		// Stored as the Postgres outbox stores it
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		o.records = append(o.records, &outboxRecord{entry: domain.OutboxEntry{
+			Seq:         o.nextSeq,
+			Event:       event,
+			EventID:     event.Metadata().ID,
+			EventType:   event.EventType(),
+			AggregateID: event.AggregateID(),
+			Payload:     payload,
+		}})
+		o.nextSeq++
+	}
+	return nil
+}
+
+func (o *Outbox) Relay(ctx context.Context, limit int, publish func(ctx context.Context, entry domain.OutboxEntry) error) (int, error) {
+	// mu is not held while publishing, so handlers may write to the outbox
+	o.relay.Lock()
+	defer o.relay.Unlock()
+
+	o.mu.Lock()
+	var pending []*outboxRecord
+	for _, r := range o.records {
+		if len(pending) == limit {
+			break
+		}
+		if r.published.IsZero() {
+			pending = append(pending, r)
+		}
+	}
+	o.mu.Unlock()
+
+	published := 0
+	blocked := make(map[string]bool)
+	for _, r := range pending {
+		aggregate := r.entry.Event.AggregateID()
+		if blocked[aggregate] {
+			continue
+		}
+		o.mu.Lock()
+		entry := r.entry
+		o.mu.Unlock()
+
+		err := publish(ctx, entry)
+
+		o.mu.Lock()
+		if err != nil {
+			blocked[aggregate] = true
+			r.entry.Attempts++
+		} else {
+			r.published = o.now()
+			published++
+		}
+		o.mu.Unlock()
+	}
+	return published, nil
+}
+
+func (o *Outbox) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.records[:0]
+	for _, r := range o.records {
+		if r.published.IsZero() || !r.published.Before(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	purged := len(o.records) - len(kept)
+	clear(o.records[len(kept):])
+	o.records = kept
+	return purged, nil
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Ensure PaymentGateway implements domain.PaymentGateway at compile time
+var _ domain.PaymentGateway = (*PaymentGateway)(nil)
+
+// PaymentGateway is an in-memory domain.PaymentGateway approving every
+// payment, for development and tests
+type PaymentGateway struct {
+	mu       sync.Mutex
+	captures map[string]*Payment // By idempotency key
+}
+
+// Payment is a payment captured by a PaymentGateway
+type Payment struct {
+	ID       string
+	Capture  domain.PaymentCapture
+	Refunded bool
+}
+
+// NewPaymentGateway creates a payment gateway with no payments
+func NewPaymentGateway() *PaymentGateway {
+	return &PaymentGateway{captures: make(map[string]*Payment)}
+}
+
+func (g *PaymentGateway) Capture(ctx context.Context, c domain.PaymentCapture) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if p, ok := g.captures[c.IdempotencyKey]; ok {
+		return p.ID, nil
+	}
+	p := &Payment{ID: "pay_" + uuid.New().String(), Capture: c}
+	g.captures[c.IdempotencyKey] = p
+	return p.ID, nil
+}
+
+func (g *PaymentGateway) Refund(ctx context.Context, idempotencyKey string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if p, ok := g.captures[idempotencyKey]; ok {
+		p.Refunded = true
+	}
+	return nil
+}
+
+// Payment returns a copy of the payment captured with idempotencyKey
+func (g *PaymentGateway) Payment(idempotencyKey string) (Payment, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.captures[idempotencyKey]
+	if !ok {
+		return Payment{}, false
+	}
+	return *p, true
+}
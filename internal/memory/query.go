@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// userField returns the value of one of the domain.UserQueryFields of u
+func userField(u *domain.User, field string) any {
+	switch field {
+	case "id":
+		return u.ID
+	case "name":
+		return u.Name
+	case "email":
+		return u.Email
+	case "created_at":
+		return u.CreatedAt
+	case "updated_at":
+		return u.UpdatedAt
+	}
+	return nil
+}
+
+// orderField returns the value of one of the domain.OrderQueryFields of o
+func orderField(o *domain.Order, field string) any {
+	switch field {
+	case "id":
+		return o.ID
+	case "user_id":
+		return o.UserID
+	case "status":
+		return string(o.Status)
+	case "amount":
+		return o.Amount
+	case "created_at":
+		return o.CreatedAt
+	case "updated_at":
+		return o.UpdatedAt
+	}
+	return nil
+}
+
+// checkQuery rejects the filters and sort keys of q naming fields outside
+// fields, as the Postgres repositories do
+func checkQuery(filters []domain.Filter, keys []domain.SortKey, fields map[string]domain.QueryField) error {
+	for _, f := range filters {
+		if _, ok := fields[f.Field]; !ok {
+			return fmt.Errorf("%w: cannot filter by %q", domain.ErrInvalidInput, f.Field)
+		}
+	}
+	for _, key := range keys {
+		if _, ok := fields[key.Field]; !ok {
+			return fmt.Errorf("%w: cannot sort by %q", domain.ErrInvalidInput, key.Field)
+		}
+	}
+	return nil
+}
+
+// filterRows keeps the items matching every filter
+func filterRows[T any](items []T, filters []domain.Filter, field func(T, string) any) []T {
+	if len(filters) == 0 {
+		return items
+	}
+	return slices.DeleteFunc(items, func(item T) bool {
+		for _, f := range filters {
+			if !matches(field(item, f.Field), f) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// matches reports whether a field value passes a filter
+func matches(value any, f domain.Filter) bool {
+	switch f.Op {
+	case domain.OpIn:
+		values, _ := f.Value.([]string)
+		s, _ := value.(string)
+		return slices.Contains(values, s)
+	case domain.OpContains:
+		s, _ := value.(string)
+		sub, _ := f.Value.(string)
+		return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+	}
+	c, ok := compareValues(value, f.Value)
+	if !ok {
+		return false
+	}
+	switch f.Op {
+	case domain.OpEq:
+		return c == 0
+	case domain.OpNe:
+		return c != 0
+	case domain.OpGt:
+		return c > 0
+	case domain.OpGte:
+		return c >= 0
+	case domain.OpLt:
+		return c < 0
+	case domain.OpLte:
+		return c <= 0
+	}
+	return false
+}
+
+// compareValues compares two field values of the same kind
+func compareValues(a, b any) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return strings.Compare(a, b), ok
+	case float64:
+		b, ok := b.(float64)
+		return cmp.Compare(a, b), ok
+	case time.Time:
+		b, ok := b.(time.Time)
+		return a.Compare(b), ok
+	}
+	return 0, false
+}
+
+// sortRows orders items by the sort keys, then by ID; items already newest
+// first stay that way without keys
+func sortRows[T any](items []T, keys []domain.SortKey, field func(T, string) any, id func(T) string) {
+	if len(keys) == 0 {
+		return
+	}
+	slices.SortFunc(items, func(a, b T) int {
+		for _, key := range keys {
+			c, _ := compareValues(field(a, key.Field), field(b, key.Field))
+			if key.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(id(a), id(b))
+	})
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure ReportScheduleRepository implements domain.ReportScheduleRepository at compile time
+var _ domain.ReportScheduleRepository = (*ReportScheduleRepository)(nil)
+
+// ReportScheduleRepository is an in-memory implementation of domain.ReportScheduleRepository
+type ReportScheduleRepository struct {
+	mu        sync.RWMutex
+	schedules map[string]*domain.ReportSchedule
+}
+
+// NewReportScheduleRepository creates an empty in-memory scheduled report repository
+func NewReportScheduleRepository() domain.ReportScheduleRepository {
+	return &ReportScheduleRepository{schedules: make(map[string]*domain.ReportSchedule)}
+}
+
+func copyReportSchedule(s *domain.ReportSchedule) *domain.ReportSchedule {
+	c := *s
+	c.Recipients = slices.Clone(s.Recipients)
+	c.LastRunAt = copyTime(s.LastRunAt)
+	return &c
+}
+
+func (r *ReportScheduleRepository) Create(ctx context.Context, schedule *domain.ReportSchedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schedules[schedule.ID]; ok {
+		return domain.ErrConflict
+	}
+	r.schedules[schedule.ID] = copyReportSchedule(schedule)
+	return nil
+}
+
+func (r *ReportScheduleRepository) GetByID(ctx context.Context, id string) (*domain.ReportSchedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.schedules[id]
+	if !ok {
+		return nil, domain.ErrReportScheduleNotFound
+	}
+	return copyReportSchedule(s), nil
+}
+
+// List returns every schedule, oldest first
+func (r *ReportScheduleRepository) List(ctx context.Context) ([]*domain.ReportSchedule, error) {
+	schedules := r.matching(func(*domain.ReportSchedule) bool { return true })
+	slices.SortFunc(schedules, func(a, b *domain.ReportSchedule) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return schedules, nil
+}
+
+func (r *ReportScheduleRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schedules[id]; !ok {
+		return domain.ErrReportScheduleNotFound
+	}
+	delete(r.schedules, id)
+	return nil
+}
+
+// ListDue returns the schedules due at now, earliest first
+func (r *ReportScheduleRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*domain.ReportSchedule, error) {
+	due := r.matching(func(s *domain.ReportSchedule) bool { return !s.NextRunAt.After(now) })
+	slices.SortFunc(due, func(a, b *domain.ReportSchedule) int {
+		return cmp.Or(a.NextRunAt.Compare(b.NextRunAt), cmp.Compare(a.ID, b.ID))
+	})
+	return page(due, limit, 0), nil
+}
+
+func (r *ReportScheduleRepository) Advance(ctx context.Context, id string, expected, next, ranAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.schedules[id]
+	if !ok || !s.NextRunAt.Equal(expected) {
+		return false, nil
+	}
+	s.NextRunAt = next
+	s.LastRunAt = &ranAt
+	s.UpdatedAt = ranAt
+	return true, nil
+}
+
+// matching returns copies of the schedules for which keep is true
+func (r *ReportScheduleRepository) matching(keep func(*domain.ReportSchedule) bool) []*domain.ReportSchedule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var schedules []*domain.ReportSchedule
+	for _, s := range r.schedules {
+		if keep(s) {
+			schedules = append(schedules, copyReportSchedule(s))
+		}
+	}
+	return schedules
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure RequestStatsStore implements domain.RequestStatsStore at compile time
+var _ domain.RequestStatsStore = (*RequestStatsStore)(nil)
+
+// requestStatsRetention matches the Redis store's eight-day expiry
+const requestStatsRetention = 8 * 24 * time.Hour
+
+// RequestStatsStore is an in-memory implementation of domain.RequestStatsStore
+type RequestStatsStore struct {
+	mu    sync.Mutex
+	hours map[time.Time]*domain.RequestStats
+	now   func() time.Time
+}
+
+// NewRequestStatsStore creates an in-memory hourly request stats store
+func NewRequestStatsStore() domain.RequestStatsStore {
+	return &RequestStatsStore{hours: make(map[time.Time]*domain.RequestStats), now: time.Now}
+}
+
+func (s *RequestStatsStore) Add(ctx context.Context, stats *domain.RequestStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hour := stats.Hour.UTC().Truncate(time.Hour)
+	stored, ok := s.hours[hour]
+	if !ok {
+		stored = domain.NewRequestStats(hour)
+		s.hours[hour] = stored
+	}
+	stored.Add(stats)
+
+	// Drop hours the Redis store would have expired
+	cutoff := s.now().Add(-requestStatsRetention)
+	for h := range s.hours {
+		if h.Before(cutoff) {
+			delete(s.hours, h)
+		}
+	}
+	return nil
+}
+
+func (s *RequestStatsStore) Range(ctx context.Context, from, to time.Time) ([]*domain.RequestStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from = from.UTC().Truncate(time.Hour)
+	var result []*domain.RequestStats
+	for h, stats := range s.hours {
+		if h.Before(from) || h.After(to) || stats.Requests == 0 {
+			continue
+		}
+		c := domain.NewRequestStats(h)
+		c.Add(stats)
+		result = append(result, c)
+	}
+	slices.SortFunc(result, func(a, b *domain.RequestStats) int { return a.Hour.Compare(b.Hour) })
+	return result, nil
+}
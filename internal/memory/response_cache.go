@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure ResponseCache implements domain.ResponseCache at compile time
+var _ domain.ResponseCache = (*ResponseCache)(nil)
+
+// ResponseCache is an in-memory implementation of domain.ResponseCache.
+// Invalidations are only seen by this process.
+type ResponseCache struct {
+	entries *expiring[domain.CachedResponse]
+
+	mu       sync.Mutex
+	versions map[string]uint64 // By tag
+}
+
+// NewResponseCache creates an in-memory response cache
+func NewResponseCache() domain.ResponseCache {
+	return &ResponseCache{
+		entries:  newExpiring[domain.CachedResponse](),
+		versions: make(map[string]uint64),
+	}
+}
+
+func (c *ResponseCache) Get(ctx context.Context, key string, tags []string) (*domain.CachedResponse, string, error) {
+	c.mu.Lock()
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = strconv.FormatUint(c.versions[tag], 10)
+	}
+	c.mu.Unlock()
+	version := strings.Join(parts, ".")
+
+	resp, ok := c.entries.get(key)
+	if !ok || resp.Version != version {
+		return nil, version, domain.ErrCacheMiss
+	}
+	return copyCachedResponse(resp), version, nil
+}
+
+func (c *ResponseCache) Set(ctx context.Context, key string, resp *domain.CachedResponse, ttl time.Duration) error {
+	c.entries.set(key, *copyCachedResponse(*resp), ttl)
+	return nil
+}
+
+func (c *ResponseCache) Invalidate(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		c.versions[tag]++
+	}
+	return nil
+}
+
+func copyCachedResponse(r domain.CachedResponse) *domain.CachedResponse {
+	r.Header = maps.Clone(r.Header)
+	for k, v := range r.Header {
+		r.Header[k] = slices.Clone(v)
+	}
+	r.Body = slices.Clone(r.Body)
+	return &r
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure SagaRepository implements domain.SagaRepository at compile time
+var _ domain.SagaRepository = (*SagaRepository)(nil)
+
+// SagaRepository is an in-memory implementation of domain.SagaRepository
+type SagaRepository struct {
+	mu    sync.RWMutex
+	sagas map[string]*domain.Saga // By ID
+}
+
+// NewSagaRepository creates an empty in-memory saga repository
+func NewSagaRepository() domain.SagaRepository {
+	return &SagaRepository{sagas: make(map[string]*domain.Saga)}
+}
+
+func (r *SagaRepository) Create(ctx context.Context, saga *domain.Saga) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.sagas {
+		if s.ID == saga.ID || (s.Type == saga.Type && s.Key == saga.Key && s.Status != domain.SagaCompensated) {
+			return domain.ErrSagaAlreadyExists
+		}
+	}
+	r.sagas[saga.ID] = saga.Clone()
+	return nil
+}
+
+func (r *SagaRepository) Get(ctx context.Context, id string) (*domain.Saga, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.sagas[id]
+	if !ok {
+		return nil, domain.ErrSagaNotFound
+	}
+	return s.Clone(), nil
+}
+
+func (r *SagaRepository) GetByKey(ctx context.Context, sagaType, key string) (*domain.Saga, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var newest *domain.Saga
+	for _, s := range r.sagas {
+		if s.Type == sagaType && s.Key == key && (newest == nil || s.CreatedAt.After(newest.CreatedAt)) {
+			newest = s
+		}
+	}
+	if newest == nil {
+		return nil, domain.ErrSagaNotFound
+	}
+	return newest.Clone(), nil
+}
+
+func (r *SagaRepository) Update(ctx context.Context, saga *domain.Saga) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.sagas[saga.ID]
+	if !ok {
+		return domain.ErrSagaNotFound
+	}
+	if stored.Version != saga.Version {
+		return domain.ErrVersionMismatch
+	}
+	saga.Version++
+	r.sagas[saga.ID] = saga.Clone()
+	return nil
+}
+
+func (r *SagaRepository) ListUnfinished(ctx context.Context, cutoff time.Time, limit int) ([]*domain.Saga, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sagas []*domain.Saga
+	for _, s := range r.sagas {
+		if !s.Status.Finished() && s.UpdatedAt.Before(cutoff) {
+			sagas = append(sagas, s.Clone())
+		}
+	}
+	slices.SortFunc(sagas, func(a, b *domain.Saga) int { return a.UpdatedAt.Compare(b.UpdatedAt) })
+	return page(sagas, limit, 0), nil
+}
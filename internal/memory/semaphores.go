@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure SemaphoreStore implements domain.SemaphoreStore at compile time
+var _ domain.SemaphoreStore = (*SemaphoreStore)(nil)
+
+// SemaphoreStore is an in-memory implementation of domain.SemaphoreStore.
+// Each holder has a lease that expires unless refreshed, so a slot held by a
+// crashed holder frees itself. The limits only hold within this process.
+type SemaphoreStore struct {
+	mu     sync.Mutex
+	leases map[string]map[string]time.Time // Semaphore name to holder lease expiry
+	now    func() time.Time
+}
+
+// NewSemaphoreStore creates an in-memory semaphore store
+func NewSemaphoreStore() domain.SemaphoreStore {
+	return &SemaphoreStore{leases: make(map[string]map[string]time.Time), now: time.Now}
+}
+
+// holdersLocked returns name's unexpired leases, dropping expired ones
+func (s *SemaphoreStore) holdersLocked(name string, now time.Time) map[string]time.Time {
+	holders := s.leases[name]
+	for holder, expiresAt := range holders {
+		if !now.Before(expiresAt) {
+			delete(holders, holder)
+		}
+	}
+	return holders
+}
+
+func (s *SemaphoreStore) TryAcquire(ctx context.Context, name, holder string, limit int, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	holders := s.holdersLocked(name, now)
+	if holders == nil {
+		holders = make(map[string]time.Time)
+		s.leases[name] = holders
+	}
+	// A holder re-acquiring renews its lease rather than taking a second slot
+	if _, held := holders[holder]; !held && len(holders) >= limit {
+		return false, nil
+	}
+	holders[holder] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *SemaphoreStore) Refresh(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	holders := s.holdersLocked(name, now)
+	if _, held := holders[holder]; !held {
+		return false, nil
+	}
+	holders[holder] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *SemaphoreStore) Release(ctx context.Context, name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.leases[name], holder)
+	if len(s.leases[name]) == 0 {
+		delete(s.leases, name)
+	}
+	return nil
+}
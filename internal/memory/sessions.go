@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure RevocationStore implements domain.TokenRevocationStore at compile time
+var _ domain.TokenRevocationStore = (*RevocationStore)(nil)
+
+// RevocationStore is an in-memory implementation of domain.TokenRevocationStore
+type RevocationStore struct {
+	tokens *expiring[struct{}]
+	users  *expiring[int64] // Unix time of the user-wide revocation
+}
+
+// NewRevocationStore creates an in-memory token denylist
+func NewRevocationStore() domain.TokenRevocationStore {
+	return &RevocationStore{tokens: newExpiring[struct{}](), users: newExpiring[int64]()}
+}
+
+func (s *RevocationStore) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		// Already expired; nothing to denylist
+		return nil
+	}
+	s.tokens.set(tokenID, struct{}{}, ttl)
+	return nil
+}
+
+func (s *RevocationStore) RevokeAllForUser(ctx context.Context, userID string, before time.Time, ttl time.Duration) error {
+	s.users.set(userID, before.Unix(), ttl)
+	return nil
+}
+
+func (s *RevocationStore) IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error) {
+	if _, ok := s.tokens.get(tokenID); ok {
+		return true, nil
+	}
+	if before, ok := s.users.get(userID); ok {
+		// Second precision, as with the token's iat claim
+		return issuedAt.Unix() <= before, nil
+	}
+	return false, nil
+}
+
+// Ensure LoginAttemptStore implements domain.LoginAttemptStore at compile time
+var _ domain.LoginAttemptStore = (*LoginAttemptStore)(nil)
+
+// LoginAttemptStore is an in-memory implementation of domain.LoginAttemptStore
+type LoginAttemptStore struct {
+	failures *expiring[int]
+	locks    *expiring[struct{}]
+}
+
+// NewLoginAttemptStore creates an in-memory failed attempt tracker
+func NewLoginAttemptStore() domain.LoginAttemptStore {
+	return &LoginAttemptStore{failures: newExpiring[int](), locks: newExpiring[struct{}]()}
+}
+
+func (s *LoginAttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.failures.mu.Lock()
+	defer s.failures.mu.Unlock()
+
+	e, ok := s.failures.getLocked(key)
+	if !ok {
+		// The window is anchored at the first failure
+		s.failures.setLocked(key, 1, window)
+		return 1, nil
+	}
+	e.value++
+	s.failures.items[key] = e
+	return e.value, nil
+}
+
+func (s *LoginAttemptStore) Failures(ctx context.Context, key string) (int, time.Duration, error) {
+	count, _ := s.failures.get(key)
+	return count, max(s.locks.ttl(key), 0), nil
+}
+
+func (s *LoginAttemptStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	s.locks.set(key, struct{}{}, d)
+	return nil
+}
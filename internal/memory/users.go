@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure UserRepository implements domain.UserRepository at compile time
+var _ domain.UserRepository = (*UserRepository)(nil)
+
+// UserRepository is an in-memory implementation of domain.UserRepository.
+// Emails are unique case-insensitively, as with the Postgres unique index.
+type UserRepository struct {
+	mu    sync.RWMutex
+	users map[string]domain.User
+}
+
+// NewUserRepository creates an empty in-memory user repository
+func NewUserRepository() domain.UserRepository {
+	return &UserRepository{users: make(map[string]domain.User)}
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return &u, nil
+}
+
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Each user once, as with WHERE id = ANY($1)
+	var users []*domain.User
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok && !seen[id] {
+			seen[id] = true
+			users = append(users, &u)
+		}
+	}
+	return users, nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			return &u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok || r.emailTakenLocked(user.Email, "") {
+		return domain.ErrUserAlreadyExists
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepository) CreateMany(ctx context.Context, users []*domain.User) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := make([]error, len(users))
+	for i, user := range users {
+		if _, ok := r.users[user.ID]; ok || r.emailTakenLocked(user.Email, "") {
+			errs[i] = domain.ErrUserAlreadyExists
+			continue
+		}
+		r.users[user.ID] = *user
+	}
+	return errs, nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	// As with UPDATE ... WHERE version = $5, checked before the email index
+	if existing.Version != user.Version {
+		return domain.ErrVersionMismatch
+	}
+	if r.emailTakenLocked(user.Email, user.ID) {
+		return domain.ErrUserAlreadyExists
+	}
+	existing.Name = user.Name
+	existing.Email = user.Email
+	existing.UpdatedAt = user.UpdatedAt
+	existing.Version++
+	r.users[user.ID] = existing
+	user.Version = existing.Version
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[id]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	// As with DELETE ... WHERE version = $2
+	if existing.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*domain.User, error) {
+	r.mu.RLock()
+	users := make([]*domain.User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, &u)
+	}
+	r.mu.RUnlock()
+
+	sortNewestFirst(users,
+		func(u *domain.User) time.Time { return u.CreatedAt },
+		func(u *domain.User) string { return u.ID })
+	return page(users, limit, offset), nil
+}
+
+func (r *UserRepository) Search(ctx context.Context, q domain.ListQuery, limit, offset int) ([]*domain.User, error) {
+	if err := checkQuery(q.Filters, q.Sort, domain.UserQueryFields); err != nil {
+		return nil, err
+	}
+	users, _ := r.List(ctx, math.MaxInt, 0)
+	users = filterRows(users, q.Filters, userField)
+	sortRows(users, q.Sort, userField, func(u *domain.User) string { return u.ID })
+	return page(users, limit, offset), nil
+}
+
+func (r *UserRepository) Count(ctx context.Context, filters []domain.Filter) (int, error) {
+	if err := checkQuery(filters, nil, domain.UserQueryFields); err != nil {
+		return 0, err
+	}
+	users, _ := r.List(ctx, math.MaxInt, 0)
+	return len(filterRows(users, filters, userField)), nil
+}
+
+func (r *UserRepository) ListKeyset(ctx context.Context, page domain.KeysetPage) ([]*domain.User, error) {
+	if err := checkQuery(page.Filters, nil, domain.UserQueryFields); err != nil {
+		return nil, err
+	}
+	users, _ := r.List(ctx, math.MaxInt, 0)
+	users = filterRows(users, page.Filters, userField)
+	return keysetPage(users, page,
+		func(u *domain.User) time.Time { return u.CreatedAt },
+		func(u *domain.User) string { return u.ID }), nil
+}
+
+func (r *UserRepository) Stream(ctx context.Context, limit, offset int, fn func(*domain.User) error) error {
+	if limit == 0 {
+		limit = math.MaxInt
+	}
+	users, _ := r.List(ctx, limit, offset)
+	for _, u := range users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emailTakenLocked reports whether a user other than exceptID has email
+func (r *UserRepository) emailTakenLocked(email, exceptID string) bool {
+	for id, u := range r.users {
+		if id != exceptID && strings.EqualFold(u.Email, email) {
+			return true
+		}
+	}
+	return false
+}
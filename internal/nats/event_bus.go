@@ -0,0 +1,411 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Ensure EventBus implements domain.EventBus at compile time
+var _ domain.EventBus = (*EventBus)(nil)
+
+// Pull requests: events asked for at once, and how long the server may
+// hold a request open while the stream has none. The batch is small: the
+// events delivered behind the one being handled use up their ack wait.
+const (
+	fetchBatch = 8
+	fetchWait  = 5 * time.Second
+)
+
+// EventBus is a NATS JetStream implementation of domain.EventBus. It is
+// safe for concurrent use.
+type EventBus struct {
+	cfg     Config
+	servers string // Comma-separated URLs, as the client takes them
+	logg    *logger.Logger
+
+	mu          sync.Mutex // Guards conn, js and streamReady
+	conn        *natsgo.Conn
+	js          jetstream.JetStream
+	streamReady bool // Whether the stream is known to exist
+
+	subsMu        sync.RWMutex
+	nextID        int
+	subscriptions []subscription // In the order they subscribed
+}
+
+type subscription struct {
+	id         int
+	handler    domain.EventHandler
+	eventTypes map[string]bool // Nil for every type
+}
+
+// NewEventBus creates an event bus on the servers of cfg.URL; they are
+// contacted on the first publish or on Run
+func NewEventBus(cfg Config, logg *logger.Logger) (*EventBus, error) {
+	cfg = cfg.withDefaults()
+	servers, err := ParseServers(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, len(servers))
+	for i, server := range servers {
+		urls[i] = server.String()
+	}
+	return &EventBus{cfg: cfg, servers: strings.Join(urls, ","), logg: logg}, nil
+}
+
+// Publish stores event in the stream and returns once the server has it.
+// Handlers run later, through Run; their errors are not returned here.
+func (b *EventBus) Publish(ctx context.Context, event domain.Event) error {
+	if err := b.publish(ctx, event); err != nil {
+		return fmt.Errorf("publishing %s event %s: %w", event.EventType(), event.Metadata().ID, err)
+	}
+	return nil
+}
+
+func (b *EventBus) publish(ctx context.Context, event domain.Event) error {
+	data, err := domain.MarshalCloudEvent(event, b.cfg.Source)
+	if err != nil {
+		return err
+	}
+	js, err := b.connection(ctx)
+	if err != nil {
+		return err
+	}
+	msg := &natsgo.Msg{
+		Subject: b.cfg.Subject + "." + event.EventType(),
+		Header:  natsgo.Header{"Content-Type": {domain.CloudEventsContentType}},
+		Data:    data,
+	}
+	var opts []jetstream.PublishOpt
+	if id := event.Metadata().ID; id != "" {
+		// Deduplicated by the stream within its duplicate window
+		opts = append(opts, jetstream.WithMsgID(id))
+	}
+	ack, err := js.PublishMsg(ctx, msg, opts...)
+	if errors.Is(err, jetstream.ErrNoStreamResponse) {
+		// The stream was deleted, or no longer captures the subject
+		b.mu.Lock()
+		b.streamReady = false
+		b.mu.Unlock()
+		return fmt.Errorf("jetstream: no stream captures subject %s", msg.Subject)
+	}
+	if err != nil {
+		return err
+	}
+	if ack.Duplicate {
+		b.logg.Debug("nats event already in stream", "event", event.EventType(), "event_id", event.Metadata().ID, "seq", ack.Sequence)
+	}
+	return nil
+}
+
+func (b *EventBus) Subscribe(handler domain.EventHandler, eventTypes ...string) func() {
+	b.subsMu.Lock()
+	defer b.subsMu.Unlock()
+	sub := subscription{id: b.nextID, handler: handler}
+	b.nextID++
+	if len(eventTypes) > 0 {
+		sub.eventTypes = make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			sub.eventTypes[t] = true
+		}
+	}
+	b.subscriptions = append(b.subscriptions, sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.subsMu.Lock()
+			b.subscriptions = slices.DeleteFunc(b.subscriptions, func(s subscription) bool { return s.id == sub.id })
+			b.subsMu.Unlock()
+		})
+	}
+}
+
+// Close closes the connection; Publish and Run reconnect if called again
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn, b.js = nil, nil
+	}
+	return nil
+}
+
+// connection returns the JetStream context of the open connection,
+// connecting if there is none, once the stream exists
+func (b *EventBus) connection(ctx context.Context) (jetstream.JetStream, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil && b.conn.IsClosed() {
+		b.conn, b.js = nil, nil
+	}
+	if b.conn == nil {
+		nc, err := natsgo.Connect(b.servers, b.options()...)
+		if err != nil {
+			return nil, fmt.Errorf("nats: connecting: %w", err)
+		}
+		js, err := jetstream.New(nc, jetstream.WithDefaultTimeout(b.cfg.Timeout))
+		if err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("nats: %w", err)
+		}
+		b.conn, b.js = nc, js
+	}
+	if !b.streamReady {
+		if err := ensureStream(ctx, b.js, b.cfg); err != nil {
+			return nil, fmt.Errorf("creating stream %s: %w", b.cfg.Stream, err)
+		}
+		b.streamReady = true
+	}
+	return b.js, nil
+}
+
+// options returns the client options of the bus's connection, which
+// reconnects for as long as it is open
+func (b *EventBus) options() []natsgo.Option {
+	opts := []natsgo.Option{
+		natsgo.Name(b.cfg.Name),
+		natsgo.Timeout(b.cfg.Timeout),
+		natsgo.MaxReconnects(-1),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			if err != nil {
+				b.logg.Warn("nats connection lost, reconnecting", "error", err)
+			}
+		}),
+		natsgo.ReconnectHandler(func(nc *natsgo.Conn) {
+			b.logg.Info("nats reconnected", "server", nc.ConnectedUrlRedacted())
+		}),
+	}
+	if b.cfg.User != "" {
+		opts = append(opts, natsgo.UserInfo(b.cfg.User, b.cfg.Password))
+	}
+	if b.cfg.Token != "" {
+		opts = append(opts, natsgo.Token(b.cfg.Token))
+	}
+	return opts
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Consuming
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Run delivers the stream's events to the subscribed handlers, one at a
+// time in stream order (an event whose handlers failed comes back after a
+// delay), until ctx ends; the event being handled then is handled to the
+// end. Connection failures are retried with backoff.
+func (b *EventBus) Run(ctx context.Context) error {
+	failures := 0
+	for {
+		handled, err := b.consume(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if handled > 0 {
+			failures = 0
+		}
+		failures++
+		b.logg.Warn("nats consumer failed, retrying", "error", err, "stream", b.cfg.Stream, "consumer", b.cfg.Durable, "attempt", failures)
+		if sleep(ctx, backoff(failures)) != nil {
+			return nil
+		}
+	}
+}
+
+// consume pulls and handles events until ctx ends or the connection fails,
+// and returns how many it handled
+func (b *EventBus) consume(ctx context.Context) (int, error) {
+	js, err := b.connection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	consumer, err := ensureConsumer(ctx, js, b.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("creating consumer %s: %w", b.cfg.Durable, err)
+	}
+	handled := 0
+	for ctx.Err() == nil {
+		n, err := b.pull(ctx, consumer)
+		handled += n
+		if err != nil {
+			return handled, err
+		}
+	}
+	return handled, nil
+}
+
+// pull asks for the next batch of events and handles each as it arrives,
+// until the server ends the request; it returns how many it handled.
+// Events delivered once ctx ended are handed back straight away.
+func (b *EventBus) pull(ctx context.Context, consumer jetstream.Consumer) (int, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchWait)
+	defer cancel()
+	batch, err := consumer.Fetch(fetchBatch, jetstream.FetchContext(fetchCtx))
+	if err != nil {
+		return 0, err
+	}
+	handled := 0
+	for m := range batch.Messages() {
+		if ctx.Err() != nil {
+			b.acknowledge(m, "nak", m.Nak)
+			continue
+		}
+		b.process(ctx, m)
+		handled++
+	}
+	if err := batch.Error(); err != nil && fetchCtx.Err() == nil {
+		return handled, fmt.Errorf("jetstream: pull request ended: %w", err)
+	}
+	return handled, nil
+}
+
+// process hands m to the subscribed handlers and acknowledges it: once they
+// all succeeded, or with a delayed redelivery when one failed, or for good
+// when m is not an event or its deliveries ran out
+func (b *EventBus) process(ctx context.Context, m jetstream.Msg) {
+	meta, err := m.Metadata()
+	if err != nil {
+		b.logg.Error("nats message without delivery metadata, skipping", "error", err, "subject", m.Subject())
+		return
+	}
+	event, err := domain.UnmarshalCloudEvent(m.Data())
+	if err != nil {
+		b.logg.Error("nats message is not a domain event, dropping", "error", err, "subject", m.Subject(), "seq", meta.Sequence.Stream)
+		b.deadLetter(ctx, m, meta, "", "", err)
+		b.acknowledge(m, "term", m.Term)
+		return
+	}
+
+	// Handlers run to the end at shutdown, with the delivery kept alive
+	stop := b.keepAlive(m)
+	err = b.dispatch(context.WithoutCancel(ctx), event)
+	stop()
+	delivered := int(meta.NumDelivered)
+	switch {
+	case err == nil:
+		// A confirmed acknowledgement, so the event is not delivered again
+		ackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.cfg.Timeout)
+		defer cancel()
+		if err := m.DoubleAck(ackCtx); err != nil {
+			b.logg.Warn("nats acknowledgement not confirmed, event may be redelivered", "error", err,
+				"event", event.EventType(), "event_id", event.Metadata().ID, "seq", meta.Sequence.Stream)
+		}
+	case delivered >= b.cfg.MaxDeliver:
+		b.logg.Error("nats event handler failed, giving up", "error", err,
+			"event", event.EventType(), "event_id", event.Metadata().ID, "seq", meta.Sequence.Stream, "deliveries", delivered)
+		b.deadLetter(ctx, m, meta, event.EventType(), event.Metadata().ID, err)
+		b.acknowledge(m, "term", m.Term)
+	default:
+		delay := min(time.Second<<min(delivered-1, 10), b.cfg.AckWait)
+		b.logg.Warn("nats event handler failed, redelivering", "error", err,
+			"event", event.EventType(), "event_id", event.Metadata().ID, "seq", meta.Sequence.Stream, "deliveries", delivered, "delay", delay)
+		b.acknowledge(m, "nak", func() error { return m.NakWithDelay(delay) })
+	}
+}
+
+// deadLetter keeps m, given up on with cause, in the dead letter queue. The
+// server delivers it no more either way, so a failure to keep it is logged.
+func (b *EventBus) deadLetter(ctx context.Context, m jetstream.Msg, meta *jetstream.MsgMetadata, eventType, eventID string, cause error) {
+	if b.cfg.DeadLetters == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.cfg.Timeout)
+	defer cancel()
+	err := b.cfg.DeadLetters.Add(ctx, &domain.DeadLetter{
+		Source:     domain.DeadLetterNATS,
+		Type:       eventType,
+		MessageID:  eventID,
+		Payload:    m.Data(),
+		Attributes: map[string]string{"stream": b.cfg.Stream, "stream_seq": strconv.FormatUint(meta.Sequence.Stream, 10)},
+		Error:      cause.Error(),
+		Attempts:   int(meta.NumDelivered),
+		FailedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		b.logg.Error("nats event not dead-lettered, it is lost", "error", err, "event", eventType, "event_id", eventID, "seq", meta.Sequence.Stream)
+	}
+}
+
+// Redeliver hands event to the handlers subscribed on this instance, as a
+// delivery from the stream would, and returns their errors joined. It
+// replays dead letters: publishing the event again would be dropped as a
+// duplicate within the duplicate window.
+func (b *EventBus) Redeliver(ctx context.Context, event domain.Event) error {
+	return b.dispatch(ctx, event)
+}
+
+// acknowledge sends an acknowledgement of kind without waiting for a
+// confirmation; a lost one means a redelivery once the ack wait expired
+func (b *EventBus) acknowledge(m jetstream.Msg, kind string, send func() error) {
+	if err := send(); err != nil {
+		b.logg.Warn("nats acknowledgement failed", "error", err, "ack", kind, "subject", m.Subject())
+	}
+}
+
+// keepAlive resets the ack wait of a delivery every half of it, until stop
+// is called
+func (b *EventBus) keepAlive(m jetstream.Msg) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ticker := time.NewTicker(b.cfg.AckWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if m.InProgress() != nil {
+					return
+				}
+			}
+		}
+	})
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// dispatch calls the handlers subscribed to event's type, in the order they
+// subscribed, and returns their errors joined
+func (b *EventBus) dispatch(ctx context.Context, event domain.Event) error {
+	b.subsMu.RLock()
+	var handlers []domain.EventHandler
+	for _, sub := range b.subscriptions {
+		if sub.eventTypes == nil || sub.eventTypes[event.EventType()] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.subsMu.RUnlock()
+
+	var errs []error
+	for _, handle := range handlers {
+		if err := runHandler(ctx, handle, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runHandler calls handle, turning a panic into an error
+func runHandler(ctx context.Context, handle domain.EventHandler, event domain.Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%s handler panicked: %v", event.EventType(), p)
+		}
+	}()
+	return handle(ctx, event)
+}
@@ -0,0 +1,162 @@
+// Package nats implements domain.EventBus on NATS JetStream, through the
+// official client:
+//
+//   - Publish stores each event in a stream as a CloudEvent, on the subject
+//     <prefix>.<event type>, and returns once the server has it; the event
+//     ID is the message ID, so an event published twice (an outbox retry
+//     after a lost acknowledgement) is stored once;
+//   - Run reads the stream through a durable pull consumer shared by every
+//     instance, hands each event to the handlers subscribed on this
+//     instance and acknowledges it once they all succeeded, waiting for the
+//     server to confirm the acknowledgement. Events whose handlers fail are
+//     redelivered with a growing delay, up to a number of deliveries, then
+//     kept in Config.DeadLetters.
+//
+// Unlike the in-process bus, handlers run after Publish returned, on
+// whichever instance the consumer delivers the event to, and each event is
+// handled by one instance. An instance stopping between a handler's success
+// and the acknowledgement still delivers that event again, so handlers must
+// be idempotent (the event ID identifies a redelivery).
+//
+// The bus needs NATS 2.9+ with JetStream; it authenticates with a user and
+// password or a token, and speaks TLS to tls:// URLs and servers that
+// require it. A lost connection is reestablished by the client, to any of
+// the servers of Config.URL or those the cluster announces.
+//
+// Example:
+//
+//	bus, err := nats.NewEventBus(nats.DefaultConfig(), logg)
+//	unsubscribe := bus.Subscribe(handler, domain.EventOrderShipped)
+//	go bus.Run(ctx)
+//	defer bus.Close()
+package nats
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Config configures the connection and the stream and consumer of events
+type Config struct {
+	URL             string        // nats://[user:password@|token@]host:port, comma-separated for several servers
+	User            string        // With Password, instead of the URL's credentials
+	Password        string        // Of User
+	Token           string        // Instead of the URL's credentials
+	Name            string        // Client name, shown in the server's connection list
+	Source          string        // CloudEvents source of published events; domain.DefaultEventSource when empty
+	Stream          string        // Stream holding the events, created when missing
+	Subject         string        // Subject prefix: events are published to <Subject>.<event type>
+	Durable         string        // Durable consumer, shared by every instance
+	AckWait         time.Duration // How long a delivery may go unacknowledged; renewed while handlers run
+	MaxDeliver      int           // Deliveries before an event whose handlers keep failing is dropped
+	MaxAge          time.Duration // How long the stream keeps events, when it is created here
+	DuplicateWindow time.Duration // How long the stream recognizes a republished event, when it is created here
+	Timeout         time.Duration // Per request
+	// DeadLetters keeps the events that are not domain events or ran out of
+	// deliveries, for replay through Redeliver; nil only logs them
+	DeadLetters domain.DeadLetterQueue
+}
+
+// DefaultConfig returns the defaults for a local server
+func DefaultConfig() Config {
+	return Config{
+		URL:             "nats://localhost:4222",
+		Name:            "stdlib-golang-api",
+		Stream:          "DOMAIN_EVENTS",
+		Subject:         "events",
+		Durable:         "stdlib-golang-api",
+		AckWait:         30 * time.Second,
+		MaxDeliver:      5,
+		MaxAge:          7 * 24 * time.Hour,
+		DuplicateWindow: 10 * time.Minute,
+		Timeout:         5 * time.Second,
+	}
+}
+
+// withDefaults fills in the zero fields of c
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	if c.URL == "" {
+		c.URL = defaults.URL
+	}
+	if c.Name == "" {
+		c.Name = defaults.Name
+	}
+	if c.Stream == "" {
+		c.Stream = defaults.Stream
+	}
+	if c.Subject == "" {
+		c.Subject = defaults.Subject
+	}
+	if c.Durable == "" {
+		c.Durable = defaults.Durable
+	}
+	if c.AckWait <= 0 {
+		c.AckWait = defaults.AckWait
+	}
+	if c.MaxDeliver <= 0 {
+		c.MaxDeliver = defaults.MaxDeliver
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = defaults.MaxAge
+	}
+	if c.DuplicateWindow <= 0 {
+		c.DuplicateWindow = defaults.DuplicateWindow
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaults.Timeout
+	}
+	return c
+}
+
+// ParseServers parses a comma-separated list of server URLs. A missing
+// scheme means nats://, a missing port 4222.
+func ParseServers(s string) ([]*url.URL, error) {
+	var servers []*url.URL
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "://") {
+			raw = "nats://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NATS server URL: %w", err)
+		}
+		if (u.Scheme != "nats" && u.Scheme != "tls") || u.Hostname() == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("invalid NATS server URL %q (want nats://host:port or tls://host:port)", u.Redacted())
+		}
+		if u.Port() == "" {
+			u.Host += ":4222"
+		}
+		servers = append(servers, u)
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no NATS server URL")
+	}
+	return servers, nil
+}
+
+// backoff is the pause before retry number attempt (from 1)
+func backoff(attempt int) time.Duration {
+	return min(100*time.Millisecond<<min(attempt-1, 5), 2*time.Second)
+}
+
+// sleep waits for d or until ctx ends
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
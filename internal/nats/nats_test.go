@@ -0,0 +1,225 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/nats-io/nats-server/v2/server"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestParseServers(t *testing.T) {
+	servers, err := ParseServers("nats://user:pass@a:4223, b ,tls://c")
+	if err != nil {
+		t.Fatalf("ParseServers: %v", err)
+	}
+	var hosts []string
+	for _, s := range servers {
+		hosts = append(hosts, s.Scheme+"://"+s.Host)
+	}
+	if got := strings.Join(hosts, ","); got != "nats://a:4223,nats://b:4222,tls://c:4222" {
+		t.Errorf("servers = %s", got)
+	}
+	for _, bad := range []string{"", "http://a:4222", "nats://:4222", "nats://a:4222/path"} {
+		if _, err := ParseServers(bad); err == nil {
+			t.Errorf("ParseServers(%q) succeeded", bad)
+		}
+	}
+}
+
+// runServer starts a NATS server with JetStream on a free port and returns
+// its URL and a JetStream context on it, for inspecting what the bus did
+func runServer(t *testing.T, opts server.Options) (string, jetstream.JetStream) {
+	t.Helper()
+	opts.Host, opts.Port = "127.0.0.1", -1
+	opts.JetStream, opts.StoreDir = true, t.TempDir()
+	opts.NoLog, opts.NoSigs = true, true
+	srv, err := server.NewServer(&opts)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatal("server not ready")
+	}
+
+	connect := []natsgo.Option{natsgo.UserInfo(opts.Username, opts.Password)}
+	if opts.Authorization != "" {
+		connect = []natsgo.Option{natsgo.Token(opts.Authorization)}
+	}
+	nc, err := natsgo.Connect(srv.ClientURL(), connect...)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(nc.Close)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("jetstream: %v", err)
+	}
+	return srv.ClientURL(), js
+}
+
+func TestEventBusPublish(t *testing.T) {
+	url, js := runServer(t, server.Options{Username: "api", Password: "secret"})
+	bus, err := NewEventBus(Config{URL: url, User: "api", Password: "secret", Durable: "api"}, logger.NewWithOptions("error", io.Discard, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1", UserID: "user-1"}
+	for range 2 {
+		if err := bus.Publish(context.Background(), shipped); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	ctx := context.Background()
+	stream, err := js.Stream(ctx, "DOMAIN_EVENTS")
+	if err != nil {
+		t.Fatalf("stream not created: %v", err)
+	}
+	info := stream.CachedInfo()
+	if info.Config.Subjects[0] != "events.>" || info.Config.Duplicates != 10*time.Minute || info.State.Msgs != 1 {
+		t.Fatalf("stream = %+v holding %d messages, want the event once", info.Config, info.State.Msgs)
+	}
+	msg, err := stream.GetMsg(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetMsg: %v", err)
+	}
+	if msg.Subject != "events.order.shipped" || msg.Header.Get("Content-Type") != domain.CloudEventsContentType {
+		t.Errorf("stored on %s with headers %v", msg.Subject, msg.Header)
+	}
+	if decoded, err := domain.UnmarshalCloudEvent(msg.Data); err != nil || decoded != shipped {
+		t.Errorf("stored message decodes to %#v, %v", decoded, err)
+	}
+
+	// The stream is deleted behind the bus's back: Publish reports it, and
+	// the next one creates the stream again
+	if err := js.DeleteStream(ctx, "DOMAIN_EVENTS"); err != nil {
+		t.Fatalf("DeleteStream: %v", err)
+	}
+	if err := bus.Publish(ctx, shipped); err == nil || !strings.Contains(err.Error(), "no stream captures subject") {
+		t.Errorf("Publish without a stream: %v", err)
+	}
+	if err := bus.Publish(ctx, shipped); err != nil {
+		t.Errorf("Publish after the stream was recreated: %v", err)
+	}
+}
+
+func TestEventBusRejectsWrongCredentials(t *testing.T) {
+	url, _ := runServer(t, server.Options{Authorization: "token"})
+	bus, err := NewEventBus(Config{URL: url, Token: "wrong", Timeout: time.Second}, logger.NewWithOptions("error", io.Discard, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1"}
+	if err := bus.Publish(context.Background(), shipped); !errors.Is(err, natsgo.ErrAuthorization) {
+		t.Errorf("Publish error = %v, want ErrAuthorization", err)
+	}
+}
+
+func TestEventBusRun(t *testing.T) {
+	url, js := runServer(t, server.Options{})
+	deadLetters := memory.NewDeadLetterQueue()
+	bus, err := NewEventBus(Config{URL: url, Durable: "api", AckWait: time.Second, MaxDeliver: 3, DeadLetters: deadLetters},
+		logger.NewWithOptions("error", io.Discard, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1", UserID: "user-1"}
+	confirmed := domain.OrderConfirmed{EventMetadata: domain.EventMetadata{ID: "evt-2"}, OrderID: "order-1", UserID: "user-1"}
+	var (
+		mu       sync.Mutex
+		received []domain.Event
+		attempts int
+	)
+	// Slow enough that the delivery must be kept alive, or it is delivered
+	// twice
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+		time.Sleep(1200 * time.Millisecond)
+		return nil
+	}, domain.EventOrderShipped)
+	// Fails the first delivery, which is retried
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts++; attempts == 1 {
+			return errors.New("unavailable")
+		}
+		received = append(received, event)
+		return nil
+	}, domain.EventOrderConfirmed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := bus.Publish(ctx, shipped); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Run(ctx)
+	}()
+	// consumed waits until the consumer acknowledged the stream up to seq
+	var info *jetstream.ConsumerInfo
+	consumed := func(seq uint64) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			consumer, err := js.Consumer(ctx, "DOMAIN_EVENTS", "api")
+			if err == nil {
+				info, err = consumer.Info(ctx)
+			}
+			if err == nil && info.AckFloor.Stream == seq && info.NumAckPending == 0 && info.NumPending == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("stream not consumed: %+v, %v", info, err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	// Events published while the slow one is handled would wait for it
+	// past their own ack wait, and be delivered again
+	consumed(1)
+	if err := bus.Publish(ctx, confirmed); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if _, err := js.Publish(ctx, "events.order.unknown", []byte("not an event")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	consumed(3)
+	cancel()
+	<-done
+
+	if len(received) != 2 || received[0] != shipped || received[1] != confirmed {
+		t.Errorf("received %v, want the shipped then the confirmed event", received)
+	}
+	if info.Config.AckPolicy != jetstream.AckExplicitPolicy || info.Config.MaxDeliver != 3 || info.Config.FilterSubject != "events.>" {
+		t.Errorf("consumer created as %+v", info.Config)
+	}
+	if attempts != 2 {
+		t.Errorf("confirmed event handled %d times, want 2", attempts)
+	}
+	letters, _ := deadLetters.List(context.Background(), domain.DeadLetterFilter{})
+	if len(letters) != 1 || letters[0].Source != domain.DeadLetterNATS || string(letters[0].Payload) != "not an event" ||
+		letters[0].Attributes["stream_seq"] != "3" {
+		t.Errorf("dead letters = %+v, want the message that is not an event", letters)
+	}
+}
@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// fcmScope is the OAuth 2.0 scope of the FCM HTTP v1 API
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMConfig configures the Firebase project sending push notifications
+type FCMConfig struct {
+	// Credentials is the JSON key of a service account allowed to send
+	// messages (the Firebase Admin SDK service account, for instance)
+	Credentials []byte
+	ProjectID   string       // Empty for the project of the service account
+	BaseURL     string       // Empty for https://fcm.googleapis.com
+	HTTPClient  *http.Client // Nil for a client with a 10s timeout
+}
+
+// FCMNotifier sends notifications as push notifications through Firebase
+// Cloud Messaging, authenticating as a service account with short-lived
+// access tokens. It is safe for concurrent use.
+type FCMNotifier struct {
+	projectID   string
+	baseURL     string
+	clientEmail string
+	tokenURL    string
+	keyID       string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex // Guards accessToken and expiresAt
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMNotifier creates a notifier from the service account key of cfg
+func NewFCMNotifier(cfg FCMConfig) (*FCMNotifier, error) {
+	var account struct {
+		Type         string `json:"type"`
+		ProjectID    string `json:"project_id"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		TokenURI     string `json:"token_uri"`
+		PrivateKeyID string `json:"private_key_id"`
+	}
+	if err := json.Unmarshal(cfg.Credentials, &account); err != nil {
+		return nil, fmt.Errorf("fcm: malformed service account key: %w", err)
+	}
+	if account.Type != "service_account" || account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, errors.New("fcm: credentials are not a service account key")
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("fcm: service account key has no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("fcm: invalid service account private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("fcm: service account private key is not an RSA key")
+	}
+	if cfg.ProjectID == "" {
+		cfg.ProjectID = account.ProjectID
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.New("fcm: no project ID")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://fcm.googleapis.com"
+	}
+	return &FCMNotifier{
+		projectID:   cfg.ProjectID,
+		baseURL:     strings.TrimSuffix(cfg.BaseURL, "/"),
+		clientEmail: account.ClientEmail,
+		tokenURL:    account.TokenURI,
+		keyID:       account.PrivateKeyID,
+		key:         key,
+		client:      httpClient(cfg.HTTPClient),
+	}, nil
+}
+
+// Notify sends n to the device registration token address: the subject as
+// the title, and the event in the data payload for the app to act on
+func (f *FCMNotifier) Notify(ctx context.Context, address string, n domain.Notification) error {
+	token, err := f.token(ctx)
+	if err != nil {
+		return fmt.Errorf("sending %s notification by push: %w", n.EventType, err)
+	}
+	body, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"token":        address,
+			"notification": map[string]string{"title": n.Subject, "body": n.Body},
+			"data":         map[string]string{"event_type": n.EventType, "event_id": n.EventID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	header := http.Header{
+		"Content-Type":  {"application/json; charset=utf-8"},
+		"Authorization": {"Bearer " + token},
+	}
+
+	endpoint := f.baseURL + "/v1/projects/" + url.PathEscape(f.projectID) + "/messages:send"
+	status, resp, err := do(ctx, f.client, http.MethodPost, endpoint, header, body)
+	if err != nil {
+		return fmt.Errorf("sending %s notification by push: fcm: %w", n.EventType, err)
+	}
+	if status == http.StatusUnauthorized {
+		// Revoked or expired early: the next attempt gets a new one
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("sending %s notification by push: %w", n.EventType, decodeGoogleError("fcm", status, resp))
+	}
+	return nil
+}
+
+// decodeGoogleError decodes the error of a Google API: its FCM error code
+// (such as UNREGISTERED for a device that uninstalled the app) when it has
+// one, its status otherwise
+func decodeGoogleError(service string, status int, body []byte) error {
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	apiErr := &APIError{Service: service, StatusCode: status, Code: http.StatusText(status), Message: truncate(string(body), 200)}
+	if json.Unmarshal(body, &resp) == nil && resp.Error.Status != "" {
+		apiErr.Code, apiErr.Message = resp.Error.Status, resp.Error.Message
+		for _, d := range resp.Error.Details {
+			if d.ErrorCode != "" {
+				apiErr.Code = d.ErrorCode
+			}
+		}
+	}
+	return apiErr
+}
+
+// token returns an access token valid for at least another minute,
+// exchanging a signed assertion for a new one when needed
+func (f *FCMNotifier) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Until(f.expiresAt) > time.Minute {
+		return f.accessToken, nil
+	}
+
+	assertion, err := f.assertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	header := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	status, body, err := do(ctx, f.client, http.MethodPost, f.tokenURL, header, []byte(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("fcm: getting an access token: %w", err)
+	}
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"` // Seconds
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if json.Unmarshal(body, &resp) != nil || status != http.StatusOK || resp.AccessToken == "" {
+		code := resp.Error
+		if code == "" {
+			code = http.StatusText(status)
+		}
+		return "", fmt.Errorf("fcm: getting an access token: %w", &APIError{Service: "oauth2", StatusCode: status, Code: code, Message: resp.Description})
+	}
+	f.accessToken = resp.AccessToken
+	f.expiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+// assertion returns the JWT, signed with the service account key, that
+// token exchanges for an access token
+func (f *FCMNotifier) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": f.keyID})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   f.clientEmail,
+		"scope": fcmScope,
+		"aud":   f.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("fcm: signing the token request: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,127 @@
+// Package notify delivers notifications to users on their channels:
+//
+//   - EmailNotifier sends them through a domain.EmailSender;
+//   - TwilioNotifier sends them as SMS through the Twilio Messages API (see
+//     also awsmsg.SMSNotifier, through SNS);
+//   - FCMNotifier sends them as push notifications through Firebase Cloud
+//     Messaging (HTTP v1 API), to Android, iOS and web devices;
+//   - SlackNotifier sends them as direct messages from a Slack app.
+//
+// The HTTP APIs are called directly. Requests that fail because the service
+// is unavailable or rate limiting return an error the caller may retry;
+// the notification service retries them as jobs.
+//
+// Example:
+//
+//	notifiers := map[domain.NotificationChannel]domain.Notifier{
+//		domain.NotificationEmail: notify.NewEmailNotifier(mailer),
+//		domain.NotificationSlack: notify.NewSlackNotifier(notify.SlackConfig{BotToken: token}),
+//	}
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure notifiers implement domain.Notifier at compile time
+var (
+	_ domain.Notifier = (*EmailNotifier)(nil)
+	_ domain.Notifier = (*TwilioNotifier)(nil)
+	_ domain.Notifier = (*FCMNotifier)(nil)
+	_ domain.Notifier = (*SlackNotifier)(nil)
+)
+
+// defaultTimeout bounds each request when no HTTP client is configured
+const defaultTimeout = 10 * time.Second
+
+// maxResponseSize bounds the responses read from the services
+const maxResponseSize = 1 << 20
+
+// APIError is an error returned by a notification service
+type APIError struct {
+	Service    string // "twilio", "fcm" or "slack"
+	StatusCode int
+	Code       string // The service's error code, such as "UNREGISTERED" or "channel_not_found"
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: %s (HTTP %d)", e.Service, e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s: %s (HTTP %d)", e.Service, e.Code, e.Message, e.StatusCode)
+}
+
+// Retriable reports whether the request may succeed if sent again
+func (e *APIError) Retriable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// httpClient returns c, or a client with the default timeout when c is nil
+func httpClient(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return &http.Client{Timeout: defaultTimeout}
+}
+
+// do sends a request with body and returns the status and body of the
+// response
+func do(ctx context.Context, client *http.Client, method, url string, header http.Header, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
+
+// truncate shortens s to at most n bytes, for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Email
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// EmailNotifier sends notifications as email
+type EmailNotifier struct {
+	sender domain.EmailSender
+}
+
+// NewEmailNotifier creates a notifier sending through sender
+func NewEmailNotifier(sender domain.EmailSender) *EmailNotifier {
+	return &EmailNotifier{sender: sender}
+}
+
+// Notify mails n to the email address
+func (e *EmailNotifier) Notify(ctx context.Context, address string, n domain.Notification) error {
+	return e.sender.Send(ctx, domain.EmailMessage{
+		To:      []string{address},
+		Subject: n.Subject,
+		Body:    n.Body,
+		HTML:    n.HTML,
+	})
+}
@@ -0,0 +1,281 @@
+package notify
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+var testNotification = domain.Notification{
+	UserID:    "u-1",
+	EventType: domain.EventOrderShipped,
+	EventID:   "e-1",
+	Subject:   "Order shipped",
+	Body:      "Order o-1 <is> on its way",
+}
+
+type fakeSender struct {
+	sent []domain.EmailMessage
+}
+
+func (f *fakeSender) Send(_ context.Context, msg domain.EmailMessage) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestEmailNotifier(t *testing.T) {
+	sender := &fakeSender{}
+	n := testNotification
+	n.HTML = "<p>Order o-1 is on its way</p>"
+	if err := NewEmailNotifier(sender).Notify(context.Background(), "ada@example.com", n); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("got %d messages, want 1", len(sender.sent))
+	}
+	msg := sender.sent[0]
+	if len(msg.To) != 1 || msg.To[0] != "ada@example.com" || msg.Subject != n.Subject || msg.Body != n.Body || msg.HTML != n.HTML {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestTwilioNotifier(t *testing.T) {
+	var form url.Values
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "AC123" || pass != "token" {
+			t.Errorf("basic auth = %q %q %v", user, pass, ok)
+		}
+		body, _ := io.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(body))
+		w.WriteHeader(status)
+		if status == http.StatusCreated {
+			io.WriteString(w, `{"sid":"SM1","status":"queued"}`)
+		} else {
+			io.WriteString(w, `{"code":21211,"message":"Invalid 'To' Phone Number","status":400}`)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	twilio := NewTwilioNotifier(TwilioConfig{AccountSID: "AC123", AuthToken: "token", From: "+15005550006", BaseURL: srv.URL})
+	if err := twilio.Notify(ctx, "+14155550100", testNotification); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if form.Get("To") != "+14155550100" || form.Get("From") != "+15005550006" || form.Get("Body") != testNotification.Body {
+		t.Errorf("form = %v", form)
+	}
+
+	// A Messaging Service sends instead of a number
+	service := NewTwilioNotifier(TwilioConfig{AccountSID: "AC123", AuthToken: "token", From: "MG456", BaseURL: srv.URL})
+	if err := service.Notify(ctx, "+14155550100", testNotification); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if form.Get("MessagingServiceSid") != "MG456" || form.Has("From") {
+		t.Errorf("form = %v", form)
+	}
+
+	status = http.StatusBadRequest
+	err := twilio.Notify(ctx, "+1", testNotification)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "21211" || apiErr.StatusCode != http.StatusBadRequest || apiErr.Retriable() {
+		t.Errorf("error = %v, want Twilio error 21211", err)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var payload map[string]any
+	response := `{"ok":true,"ts":"1.2"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-1" {
+			t.Errorf("request = %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		payload = nil
+		json.NewDecoder(r.Body).Decode(&payload)
+		io.WriteString(w, response)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	slack := NewSlackNotifier(SlackConfig{BotToken: "xoxb-1", BaseURL: srv.URL})
+	if err := slack.Notify(ctx, "U0123456", testNotification); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if payload["channel"] != "U0123456" || payload["text"] != "*Order shipped*\nOrder o-1 &lt;is&gt; on its way" {
+		t.Errorf("payload = %v", payload)
+	}
+
+	response = `{"ok":false,"error":"channel_not_found"}`
+	err := slack.Notify(ctx, "C0000000", testNotification)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "channel_not_found" || apiErr.Retriable() {
+		t.Errorf("error = %v, want channel_not_found", err)
+	}
+}
+
+// fakeGoogle serves the OAuth 2.0 token endpoint and the FCM API, checking
+// the assertions against the service account key
+type fakeGoogle struct {
+	t        *testing.T
+	key      *rsa.PublicKey
+	tokens   atomic.Int32
+	mu       sync.Mutex
+	messages []map[string]any
+	status   int // Of the FCM API
+}
+
+func (g *fakeGoogle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/token":
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			g.t.Errorf("grant_type = %q", r.Form.Get("grant_type"))
+		}
+		g.checkAssertion(r.Form.Get("assertion"))
+		g.tokens.Add(1)
+		io.WriteString(w, `{"access_token":"ya29.test","expires_in":3599,"token_type":"Bearer"}`)
+	case "/v1/projects/demo/messages:send":
+		if r.Header.Get("Authorization") != "Bearer ya29.test" {
+			g.t.Errorf("authorization = %q", r.Header.Get("Authorization"))
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		g.mu.Lock()
+		g.messages = append(g.messages, body)
+		status := g.status
+		g.mu.Unlock()
+		if status != 0 {
+			w.WriteHeader(status)
+			io.WriteString(w, `{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND",
+				"details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`)
+			return
+		}
+		io.WriteString(w, `{"name":"projects/demo/messages/1"}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *fakeGoogle) checkAssertion(jwt string) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		g.t.Errorf("assertion %q is not a JWT", jwt)
+		return
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(g.key, crypto.SHA256, digest[:], signature); err != nil {
+		g.t.Errorf("assertion signature: %v", err)
+	}
+	var claims map[string]any
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(payload, &claims)
+	if claims["iss"] != "push@demo.iam.gserviceaccount.com" || claims["scope"] != fcmScope || !strings.HasSuffix(claims["aud"].(string), "/token") {
+		g.t.Errorf("claims = %v", claims)
+	}
+}
+
+func serviceAccountKey(t *testing.T, tokenURI string) ([]byte, *rsa.PublicKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	credentials, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "demo",
+		"private_key_id": "k1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "push@demo.iam.gserviceaccount.com",
+		"token_uri":      tokenURI,
+	})
+	return credentials, &key.PublicKey
+}
+
+func TestFCMNotifier(t *testing.T) {
+	google := &fakeGoogle{t: t}
+	srv := httptest.NewServer(google)
+	defer srv.Close()
+	credentials, public := serviceAccountKey(t, srv.URL+"/token")
+	google.key = public
+
+	fcm, err := NewFCMNotifier(FCMConfig{Credentials: credentials, BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewFCMNotifier: %v", err)
+	}
+	ctx := context.Background()
+	for range 2 {
+		if err := fcm.Notify(ctx, "device-token", testNotification); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+	if n := google.tokens.Load(); n != 1 {
+		t.Errorf("got %d access tokens, want 1 reused", n)
+	}
+	message, _ := google.messages[0]["message"].(map[string]any)
+	notification, _ := message["notification"].(map[string]any)
+	data, _ := message["data"].(map[string]any)
+	if message["token"] != "device-token" || notification["title"] != testNotification.Subject ||
+		notification["body"] != testNotification.Body || data["event_type"] != domain.EventOrderShipped || data["event_id"] != "e-1" {
+		t.Errorf("message = %v", message)
+	}
+
+	google.status = http.StatusNotFound
+	err = fcm.Notify(ctx, "stale-token", testNotification)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "UNREGISTERED" || apiErr.Retriable() {
+		t.Errorf("error = %v, want UNREGISTERED", err)
+	}
+}
+
+func TestNewFCMNotifierRejectsCredentials(t *testing.T) {
+	credentials, _ := serviceAccountKey(t, "https://oauth2.googleapis.com/token")
+	var account map[string]string
+	json.Unmarshal(credentials, &account)
+
+	tests := []struct {
+		name   string
+		modify func(map[string]string)
+	}{
+		{"not a service account", func(a map[string]string) { a["type"] = "authorized_user" }},
+		{"no private key", func(a map[string]string) { a["private_key"] = "" }},
+		{"no project", func(a map[string]string) { a["project_id"] = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := map[string]string{}
+			for k, v := range account {
+				modified[k] = v
+			}
+			tt.modify(modified)
+			b, _ := json.Marshal(modified)
+			if _, err := NewFCMNotifier(FCMConfig{Credentials: b}); err == nil {
+				t.Error("NewFCMNotifier succeeded")
+			}
+		})
+	}
+	if _, err := NewFCMNotifier(FCMConfig{Credentials: []byte("{")}); err == nil {
+		t.Error("NewFCMNotifier accepted malformed JSON")
+	}
+}
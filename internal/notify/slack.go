@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// SlackConfig configures the Slack app sending notifications
+type SlackConfig struct {
+	BotToken   string       // xoxb-…, with the chat:write scope
+	BaseURL    string       // Empty for https://slack.com/api
+	HTTPClient *http.Client // Nil for a client with a 10s timeout
+}
+
+// SlackNotifier sends notifications as Slack messages from an app, to a
+// member ID (a direct message from the app) or a channel ID the app is in.
+// It is safe for concurrent use.
+type SlackNotifier struct {
+	cfg    SlackConfig
+	client *http.Client
+}
+
+// NewSlackNotifier creates a notifier posting as the app of cfg.BotToken
+func NewSlackNotifier(cfg SlackConfig) *SlackNotifier {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://slack.com/api"
+	}
+	return &SlackNotifier{cfg: cfg, client: httpClient(cfg.HTTPClient)}
+}
+
+// Notify posts n to the member or channel ID address: the subject in bold
+// above the body
+func (s *SlackNotifier) Notify(ctx context.Context, address string, n domain.Notification) error {
+	text := slackEscape(n.Body)
+	if n.Subject != "" {
+		text = "*" + slackEscape(n.Subject) + "*\n" + text
+	}
+	body, err := json.Marshal(map[string]any{
+		"channel": address,
+		"text":    text,
+		// Order IDs and links stay as they are
+		"unfurl_links": false,
+		"unfurl_media": false,
+	})
+	if err != nil {
+		return err
+	}
+	header := http.Header{
+		"Content-Type":  {"application/json; charset=utf-8"},
+		"Authorization": {"Bearer " + s.cfg.BotToken},
+	}
+
+	status, resp, err := do(ctx, s.client, http.MethodPost, strings.TrimSuffix(s.cfg.BaseURL, "/")+"/chat.postMessage", header, body)
+	if err != nil {
+		return fmt.Errorf("sending %s notification to slack: %w", n.EventType, err)
+	}
+	// Slack answers 200 with ok false for most errors, 429 when rate limiting
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if status != http.StatusOK || json.Unmarshal(resp, &result) != nil || !result.OK {
+		code := result.Error
+		if code == "" {
+			code = http.StatusText(status)
+		}
+		return fmt.Errorf("sending %s notification to slack: %w", n.EventType, &APIError{Service: "slack", StatusCode: status, Code: code})
+	}
+	return nil
+}
+
+// slackEscape escapes the characters Slack's message formatting reserves
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// TwilioConfig configures a Twilio account sending SMS
+type TwilioConfig struct {
+	AccountSID string // AC…
+	AuthToken  string
+	// From is the sending phone number in E.164 form, or a Messaging
+	// Service SID (MG…) letting Twilio pick the number
+	From       string
+	BaseURL    string       // Empty for https://api.twilio.com
+	HTTPClient *http.Client // Nil for a client with a 10s timeout
+}
+
+// TwilioNotifier sends notifications as SMS through Twilio. It is safe for
+// concurrent use.
+type TwilioNotifier struct {
+	cfg    TwilioConfig
+	client *http.Client
+}
+
+// NewTwilioNotifier creates a notifier sending from the account of cfg
+func NewTwilioNotifier(cfg TwilioConfig) *TwilioNotifier {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.twilio.com"
+	}
+	return &TwilioNotifier{cfg: cfg, client: httpClient(cfg.HTTPClient)}
+}
+
+// Notify sends the body of n to the E.164 phone number address
+func (t *TwilioNotifier) Notify(ctx context.Context, address string, n domain.Notification) error {
+	form := url.Values{"To": {address}, "Body": {n.Body}}
+	if strings.HasPrefix(t.cfg.From, "MG") {
+		form.Set("MessagingServiceSid", t.cfg.From)
+	} else {
+		form.Set("From", t.cfg.From)
+	}
+	endpoint := strings.TrimSuffix(t.cfg.BaseURL, "/") + "/2010-04-01/Accounts/" + url.PathEscape(t.cfg.AccountSID) + "/Messages.json"
+	header := http.Header{
+		"Content-Type":  {"application/x-www-form-urlencoded"},
+		"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(t.cfg.AccountSID+":"+t.cfg.AuthToken))},
+	}
+
+	status, body, err := do(ctx, t.client, http.MethodPost, endpoint, header, []byte(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("sending %s notification by sms: twilio: %w", n.EventType, err)
+	}
+	if status/100 != 2 {
+		var resp struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		apiErr := &APIError{Service: "twilio", StatusCode: status, Code: http.StatusText(status), Message: truncate(string(body), 200)}
+		if json.Unmarshal(body, &resp) == nil && resp.Code != 0 {
+			apiErr.Code, apiErr.Message = strconv.Itoa(resp.Code), resp.Message
+		}
+		return fmt.Errorf("sending %s notification by sms: %w", n.EventType, apiErr)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Package payment is a client of the payments service, implementing
+// domain.PaymentGateway over its HTTP API:
+//
+//	POST /v1/captures                    Capture, with an Idempotency-Key header
+//	  {"order_id": "…", "user_id": "…", "amount": 1999, "currency": "USD"}
+//	  → 200 or 201 {"id": "…"}; 402 {"error": {"code": "…", "message": "…"}} when declined
+//	POST /v1/captures/{idempotency_key}/refund
+//	  → 200 once refunded, again included; 404 when no capture has the key
+//
+// Requests carry the API key as a bearer token. Amounts are in minor units.
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Ensure Client implements domain.PaymentGateway at compile time
+var _ domain.PaymentGateway = (*Client)(nil)
+
+// Config configures the payments service client
+type Config struct {
+	BaseURL    string // Such as https://payments.internal
+	APIKey     string
+	HTTPClient *http.Client // Nil for a client with a 10s timeout
+}
+
+// Client calls the payments service
+type Client struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewClient creates a payments service client
+func NewClient(cfg Config) *Client {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{baseURL: strings.TrimSuffix(cfg.BaseURL, "/"), apiKey: cfg.APIKey, client: client}
+}
+
+// Capture collects c, returning the payment ID
+func (c *Client) Capture(ctx context.Context, capture domain.PaymentCapture) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"order_id": capture.OrderID,
+		"user_id":  capture.UserID,
+		"amount":   capture.Amount,
+		"currency": capture.Currency,
+	})
+	if err != nil {
+		return "", err
+	}
+	status, resp, err := c.post(ctx, "/v1/captures", capture.IdempotencyKey, body)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case status == http.StatusPaymentRequired:
+		code, message := decodeError(resp)
+		return "", fmt.Errorf("%w: %s: %s", domain.ErrPaymentDeclined, code, message)
+	case status/100 != 2:
+		code, message := decodeError(resp)
+		return "", fmt.Errorf("payments: capture: HTTP %d: %s: %s", status, code, message)
+	}
+	var payment struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &payment); err != nil || payment.ID == "" {
+		return "", fmt.Errorf("payments: capture: malformed response: %s", truncate(string(resp), 200))
+	}
+	return payment.ID, nil
+}
+
+// Refund refunds the capture made with idempotencyKey in full
+func (c *Client) Refund(ctx context.Context, idempotencyKey string) error {
+	status, resp, err := c.post(ctx, "/v1/captures/"+url.PathEscape(idempotencyKey)+"/refund", "refund:"+idempotencyKey, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound {
+		// Never captured: nothing to refund
+		return nil
+	}
+	if status/100 != 2 {
+		code, message := decodeError(resp)
+		return fmt.Errorf("payments: refund: HTTP %d: %s: %s", status, code, message)
+	}
+	return nil
+}
+
+// post sends body to path and returns the response status and body
+func (c *Client) post(ctx context.Context, path, idempotencyKey string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("payments: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, nil, fmt.Errorf("payments: reading response: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// decodeError returns the code and message of an error response
+func decodeError(body []byte) (code, message string) {
+	var resp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error.Code == "" {
+		return "unknown", truncate(strings.TrimSpace(string(body)), 200)
+	}
+	return resp.Error.Code, resp.Error.Message
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
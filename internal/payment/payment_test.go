@@ -0,0 +1,121 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// fakePayments serves the payments API, declining amounts over 1000
+type fakePayments struct {
+	t        *testing.T
+	captures map[string]string // Payment ID by idempotency key
+	refunded map[string]bool
+}
+
+func (f *fakePayments) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer sk_test" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	key := r.Header.Get("Idempotency-Key")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/captures":
+		var body struct {
+			OrderID  string `json:"order_id"`
+			Amount   int64  `json:"amount"`
+			Currency string `json:"currency"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.OrderID == "" || body.Currency != "USD" {
+			f.t.Errorf("capture body = %+v", body)
+		}
+		if body.Amount > 1000 {
+			w.WriteHeader(http.StatusPaymentRequired)
+			io.WriteString(w, `{"error":{"code":"card_declined","message":"Your card was declined."}}`)
+			return
+		}
+		if _, ok := f.captures[key]; !ok {
+			f.captures[key] = "pay_" + key
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"id": f.captures[key]})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/captures/"+r.PathValue("key")+"/refund":
+		captureKey := r.PathValue("key")
+		if key != "refund:"+captureKey {
+			f.t.Errorf("refund idempotency key = %q", key)
+		}
+		if _, ok := f.captures[captureKey]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.refunded[captureKey] = true
+		io.WriteString(w, `{"status":"refunded"}`)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "boom")
+	}
+}
+
+func newTestClient(t *testing.T) (*Client, *fakePayments) {
+	t.Helper()
+	fake := &fakePayments{t: t, captures: map[string]string{}, refunded: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.Handle("/v1/captures", fake)
+	mux.Handle("/v1/captures/{key}/refund", fake)
+	mux.Handle("/", fake)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return NewClient(Config{BaseURL: srv.URL + "/", APIKey: "sk_test"}), fake
+}
+
+func TestCaptureAndRefund(t *testing.T) {
+	client, fake := newTestClient(t)
+	ctx := context.Background()
+	capture := domain.PaymentCapture{IdempotencyKey: "saga-1", OrderID: "o-1", UserID: "u-1", Amount: 999, Currency: "USD"}
+
+	id, err := client.Capture(ctx, capture)
+	if err != nil || id != "pay_saga-1" {
+		t.Fatalf("Capture() = %q, %v", id, err)
+	}
+	// The same key returns the same payment
+	if again, err := client.Capture(ctx, capture); err != nil || again != id {
+		t.Errorf("Capture() again = %q, %v, want %q", again, err, id)
+	}
+
+	if err := client.Refund(ctx, "saga-1"); err != nil || !fake.refunded["saga-1"] {
+		t.Errorf("Refund() = %v, refunded %v", err, fake.refunded["saga-1"])
+	}
+	if err := client.Refund(ctx, "never-captured"); err != nil {
+		t.Errorf("Refund() of an unknown capture = %v, want nil", err)
+	}
+}
+
+func TestCaptureDeclined(t *testing.T) {
+	client, _ := newTestClient(t)
+	_, err := client.Capture(context.Background(), domain.PaymentCapture{IdempotencyKey: "saga-2", OrderID: "o-2", Amount: 5000, Currency: "USD"})
+	if !errors.Is(err, domain.ErrPaymentDeclined) {
+		t.Fatalf("Capture() error = %v, want ErrPaymentDeclined", err)
+	}
+	if err.Error() != "payment declined: card_declined: Your card was declined." {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	client, _ := newTestClient(t)
+	client.apiKey = "wrong"
+	_, err := client.Capture(context.Background(), domain.PaymentCapture{IdempotencyKey: "k", OrderID: "o", Currency: "USD"})
+	if err == nil || errors.Is(err, domain.ErrPaymentDeclined) {
+		t.Errorf("Capture() with a bad key = %v, want a plain error", err)
+	}
+	if err := client.Refund(context.Background(), "k"); err == nil {
+		t.Error("Refund() with a bad key succeeded")
+	}
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS orders (
+	id           TEXT PRIMARY KEY,
+	user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	amount       NUMERIC(12, 2) NOT NULL,
+	status       TEXT NOT NULL,
+	items        JSONB NOT NULL DEFAULT '[]',
+	created_at   TIMESTAMPTZ NOT NULL,
+	updated_at   TIMESTAMPTZ NOT NULL,
+	cancelled_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_created_at_idx ON orders (user_id, created_at DESC);
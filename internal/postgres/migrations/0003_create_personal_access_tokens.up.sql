@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+	id           TEXT PRIMARY KEY,
+	user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name         TEXT NOT NULL,
+	scopes       TEXT[] NOT NULL,
+	token_hash   TEXT NOT NULL UNIQUE,
+	expires_at   TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	created_at   TIMESTAMPTZ NOT NULL,
+	revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_id_idx ON personal_access_tokens (user_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS jobs (
+	id          TEXT PRIMARY KEY,
+	type        TEXT NOT NULL,
+	priority    TEXT NOT NULL,
+	user_id     TEXT REFERENCES users(id) ON DELETE SET NULL,
+	state       TEXT NOT NULL,
+	progress    INTEGER NOT NULL DEFAULT 0,
+	result      TEXT NOT NULL DEFAULT '',
+	error       TEXT NOT NULL DEFAULT '',
+	attempts    INTEGER NOT NULL DEFAULT 0,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL,
+	started_at  TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS jobs_user_id_created_at_idx ON jobs (user_id, created_at DESC);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS report_schedules (
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	report      TEXT NOT NULL,
+	schedule    TEXT NOT NULL,
+	recipients  TEXT[] NOT NULL,
+	created_by  TEXT NOT NULL DEFAULT '',
+	next_run_at TIMESTAMPTZ NOT NULL,
+	last_run_at TIMESTAMPTZ,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS report_schedules_next_run_at_idx ON report_schedules (next_run_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS attachments (
+	id           TEXT PRIMARY KEY,
+	owner_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	key          TEXT NOT NULL UNIQUE,
+	filename     TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size         BIGINT NOT NULL,
+	scan_status  TEXT NOT NULL DEFAULT 'pending',
+	scan_detail  TEXT NOT NULL DEFAULT '',
+	scanned_at   TIMESTAMPTZ,
+	created_at   TIMESTAMPTZ NOT NULL,
+	updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS attachments_owner_id_idx ON attachments (owner_id, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE attachments
+	DROP COLUMN IF EXISTS checksum_sha256,
+	DROP COLUMN IF EXISTS etag,
+	DROP COLUMN IF EXISTS uploaded_at;
@@ -0,0 +1,7 @@
+ALTER TABLE attachments
+	ADD COLUMN IF NOT EXISTS uploaded_at     TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS etag            TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS checksum_sha256 TEXT NOT NULL DEFAULT '';
+
+-- Attachments the scanner has already seen were uploaded before completion existed
+UPDATE attachments SET uploaded_at = updated_at WHERE uploaded_at IS NULL AND scan_status <> 'pending';
@@ -0,0 +1,3 @@
+-- Keyset pagination walks users and orders newest first, ties broken by ID
+CREATE INDEX IF NOT EXISTS users_created_at_id_idx ON users (created_at DESC, id);
+CREATE INDEX IF NOT EXISTS orders_created_at_id_idx ON orders (created_at DESC, id);
@@ -0,0 +1,3 @@
+-- Every update bumps the row version; clients send it back in If-Match
+ALTER TABLE users  ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+-- Domain events written with the changes they announce, until relayed
+CREATE TABLE IF NOT EXISTS outbox (
+	seq          BIGSERIAL PRIMARY KEY,
+	event_id     TEXT NOT NULL UNIQUE,
+	event_type   TEXT NOT NULL,
+	aggregate_id TEXT NOT NULL,
+	payload      JSONB NOT NULL,
+	occurred_at  TIMESTAMPTZ NOT NULL,
+	published_at TIMESTAMPTZ,
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	last_error   TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox (seq) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_published_at_idx ON outbox (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Where and on which channels each user wants to be notified
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	user_id    TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	addresses  JSONB NOT NULL DEFAULT '{}',
+	events     JSONB NOT NULL DEFAULT '{}',
+	updated_at TIMESTAMPTZ NOT NULL
+);
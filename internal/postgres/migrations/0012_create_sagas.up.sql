@@ -0,0 +1,18 @@
+-- Progress of long-running transactions, resumed after a crash
+CREATE TABLE IF NOT EXISTS sagas (
+	id         TEXT PRIMARY KEY,
+	saga_type  TEXT NOT NULL,
+	saga_key   TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	step       INTEGER NOT NULL DEFAULT 0,
+	data       JSONB NOT NULL DEFAULT '{}',
+	error      TEXT NOT NULL DEFAULT '',
+	attempts   INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	version    BIGINT NOT NULL DEFAULT 1
+);
+
+-- One saga per entity at a time; a compensated one may be tried again
+CREATE UNIQUE INDEX IF NOT EXISTS sagas_key_idx ON sagas (saga_type, saga_key) WHERE status <> 'compensated';
+CREATE INDEX IF NOT EXISTS sagas_unfinished_idx ON sagas (updated_at) WHERE status IN ('running', 'compensating');
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS inventory_reservations;
+DROP TABLE IF EXISTS inventory;
@@ -0,0 +1,16 @@
+-- Stock of the tracked products; products without a row are not tracked
+CREATE TABLE IF NOT EXISTS inventory (
+	product_id TEXT PRIMARY KEY,
+	available  INTEGER NOT NULL CHECK (available >= 0),
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Stock taken out by each reservation, put back when it is released
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+	reservation_id TEXT NOT NULL,
+	product_id     TEXT NOT NULL,
+	quantity       INTEGER NOT NULL,
+	created_at     TIMESTAMPTZ NOT NULL,
+	released_at    TIMESTAMPTZ,
+	PRIMARY KEY (reservation_id, product_id)
+);
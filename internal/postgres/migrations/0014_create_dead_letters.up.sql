@@ -0,0 +1,18 @@
+-- Jobs and events that failed for good, kept for inspection and replay
+CREATE TABLE IF NOT EXISTS dead_letters (
+	id           BIGSERIAL PRIMARY KEY,
+	source       TEXT NOT NULL,
+	message_type TEXT NOT NULL DEFAULT '',
+	message_id   TEXT NOT NULL DEFAULT '',
+	payload      BYTEA NOT NULL,
+	attributes   JSONB NOT NULL DEFAULT '{}',
+	error        TEXT NOT NULL DEFAULT '',
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	failed_at    TIMESTAMPTZ NOT NULL,
+	replays      INTEGER NOT NULL DEFAULT 0,
+	replayed_at  TIMESTAMPTZ,
+	replay_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS dead_letters_failed_at_idx ON dead_letters (failed_at);
+CREATE INDEX IF NOT EXISTS dead_letters_source_idx ON dead_letters (source, message_type, failed_at);
@@ -0,0 +1 @@
+ALTER TABLE attachments DROP COLUMN IF EXISTS upload_id;
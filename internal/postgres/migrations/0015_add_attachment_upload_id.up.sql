@@ -0,0 +1,2 @@
+-- Multipart upload of a resumable upload in progress
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS upload_id TEXT NOT NULL DEFAULT '';
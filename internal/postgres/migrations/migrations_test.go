@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := Load(files)
+	if err != nil {
+		t.Fatalf("embedded migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range migrations {
+		if want := int64(i + 1); m.Version != want {
+			t.Errorf("migration %d_%s: want version %d (versions must be contiguous)", m.Version, m.Name, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	sql := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr bool
+	}{
+		{"sorted by version", fstest.MapFS{
+			"0010_b.up.sql": sql, "0010_b.down.sql": sql,
+			"0002_a.up.sql": sql, "0002_a.down.sql": sql,
+		}, false},
+		{"missing down", fstest.MapFS{"0001_a.up.sql": sql}, true},
+		{"bad name", fstest.MapFS{"create_users.sql": sql}, true},
+		{"conflicting names", fstest.MapFS{"0001_a.up.sql": sql, "0001_b.down.sql": sql}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for i := 1; i < len(migrations); i++ {
+				if migrations[i-1].Version >= migrations[i].Version {
+					t.Errorf("migrations not sorted: %v", migrations)
+				}
+			}
+		})
+	}
+}
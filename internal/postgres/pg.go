@@ -5,31 +5,90 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/internal/chaos"
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// NewPgxPool creates a connection pool with sensible defaults
-func NewPgxPool(dsn string, logg *logger.Logger) (*pgxpool.Pool, error) {
-	// Give ourselves 5 seconds to connect—if it takes longer, something’s wrong
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// defaultConnectTimeout bounds the initial connect and ping when cfg.ConnectTimeout is unset
+const defaultConnectTimeout = 5 * time.Second
+
+// cancelDeadlineDelay is how long a query whose context is done may take to
+// acknowledge its cancel request before the connection is closed instead
+const cancelDeadlineDelay = 3 * time.Second
 
-	// Parse the DSN and configure the pool
-	cfg, err := pgxpool.ParseConfig(dsn)
+// PoolConfig builds a pgxpool configuration from cfg
+// Zero-valued settings keep pgx's own defaults
+func PoolConfig(cfg config.PostgresConfig) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("invalid postgres DSN: %w", err)
 	}
 
-	// Tune these for your workload (these are sensible starting points)
-	cfg.MaxConns = 25               // Don’t overwhelm your DB
-	cfg.MinConns = 5                // Keep some warm connections
-	cfg.MaxConnLifetime = time.Hour // Recycle connections periodically
-	cfg.MaxConnIdleTime = 30 * time.Minute
-	cfg.HealthCheckPeriod = time.Minute
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	// When a request times out, ask Postgres to cancel its query rather than
+	// dropping the connection (pgx's default), which leaves the query running
+	// and the pool a connection short
+	poolCfg.ConnConfig.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: cancelDeadlineDelay}
+	}
+
+	return poolCfg, nil
+}
+
+// PoolOption adjusts the pgxpool configuration built by NewPgxPool
+type PoolOption func(*pgxpool.Config)
+
+// WithFaultInjection injects the chaos.TargetPostgres fault into every
+// connection acquire, failing the query that needed the connection. The
+// startup ping is subject to it too.
+func WithFaultInjection(injector *chaos.Injector) PoolOption {
+	return func(poolCfg *pgxpool.Config) {
+		poolCfg.PrepareConn = func(ctx context.Context, _ *pgx.Conn) (bool, error) {
+			return true, injector.Inject(ctx, chaos.TargetPostgres)
+		}
+	}
+}
+
+// NewPgxPool creates a connection pool from a typed configuration
+func NewPgxPool(cfg config.PostgresConfig, logg *logger.Logger, opts ...PoolOption) (*pgxpool.Pool, error) {
+	timeout := cfg.ConnectTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+	// If connecting takes longer than this, something’s wrong
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	poolCfg, err := PoolConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(poolCfg)
+	}
 
 	// Actually connect
-	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
@@ -41,8 +100,9 @@ func NewPgxPool(dsn string, logg *logger.Logger) (*pgxpool.Pool, error) {
 	}
 
 	logg.Info("postgres connection pool ready",
-		"max_conns", cfg.MaxConns,
-		"min_conns", cfg.MinConns)
+		"max_conns", poolCfg.MaxConns,
+		"min_conns", poolCfg.MinConns,
+		"max_conn_idle_time", poolCfg.MaxConnIdleTime)
 
 	return pool, nil
 }
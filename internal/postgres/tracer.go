@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxTracedSQLLength bounds the statement text logged with a query
+const maxTracedSQLLength = 200
+
+// WithRequestTracing logs queries under the ID of the request that issued
+// them, read from the query's context by requestID, so a query can be
+// matched to its line in the access log and to the Redis and S3 calls the
+// request made. Failed queries are logged at warn with or without an ID;
+// the others at debug, and only when they carry one. Arguments are never
+// logged.
+func WithRequestTracing(requestID func(context.Context) string, logg *logger.Logger) PoolOption {
+	return func(poolCfg *pgxpool.Config) {
+		poolCfg.ConnConfig.Tracer = &queryTracer{requestID: requestID, logger: logg}
+	}
+}
+
+// queryTracer is a pgx.QueryTracer logging queries with their request ID
+type queryTracer struct {
+	requestID func(context.Context) string
+	logger    *logger.Logger
+}
+
+type queryTraceKey struct{}
+
+// queryTrace is what TraceQueryStart hands to TraceQueryEnd
+type queryTrace struct {
+	sql   string
+	start time.Time
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	sql := data.SQL
+	if len(sql) > maxTracedSQLLength {
+		sql = sql[:maxTracedSQLLength] + "…"
+	}
+	return context.WithValue(ctx, queryTraceKey{}, queryTrace{sql: sql, start: time.Now()})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(queryTrace)
+	if !ok {
+		return
+	}
+	requestID := t.requestID(ctx)
+	args := []any{
+		"sql", trace.sql,
+		"duration_ms", time.Since(trace.start).Milliseconds(),
+		"request_id", requestID,
+	}
+
+	// A caller giving up is not the query failing
+	if data.Err != nil && !errors.Is(data.Err, context.Canceled) {
+		t.logger.Warn("postgres query failed", append(args, "error", data.Err.Error())...)
+		return
+	}
+	if requestID != "" {
+		t.logger.Debug("postgres query", append(args, "rows", data.CommandTag.RowsAffected())...)
+	}
+}
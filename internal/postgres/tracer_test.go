@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type requestIDKey struct{}
+
+// testTracer returns a tracer reading request IDs from requestIDKey and
+// logging JSON at debug into the returned buffer
+func testTracer() (*queryTracer, *bytes.Buffer) {
+	var buf bytes.Buffer
+	requestID := func(ctx context.Context) string {
+		id, _ := ctx.Value(requestIDKey{}).(string)
+		return id
+	}
+	return &queryTracer{requestID: requestID, logger: logger.NewWithOptions("debug", &buf, true)}, &buf
+}
+
+// logRecords decodes the JSON log lines in buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decoding log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestQueryTracer(t *testing.T) {
+	failure := &pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint"}
+	tests := []struct {
+		name      string
+		requestID string
+		sql       string
+		tag       string
+		err       error
+		wantLevel string // "" for no record
+		wantMsg   string
+	}{
+		{"query of a request", "req-1", "UPDATE orders SET status = $1", "UPDATE 3", nil, "DEBUG", "postgres query"},
+		{"query outside a request", "", "SELECT 1", "SELECT 1", nil, "", ""},
+		{"failed query of a request", "req-1", "INSERT INTO users VALUES ($1)", "", failure, "WARN", "postgres query failed"},
+		{"failed query outside a request", "", "INSERT INTO users VALUES ($1)", "", failure, "WARN", "postgres query failed"},
+		// A caller giving up is not the query failing
+		{"cancelled query of a request", "req-1", "SELECT pg_sleep(10)", "", context.Canceled, "DEBUG", "postgres query"},
+		{"cancelled query outside a request", "", "SELECT pg_sleep(10)", "", context.Canceled, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, buf := testTracer()
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = context.WithValue(ctx, requestIDKey{}, tt.requestID)
+			}
+			ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: tt.sql, Args: []any{"secret-arg"}})
+			tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag(tt.tag), Err: tt.err})
+
+			records := logRecords(t, buf)
+			if tt.wantLevel == "" {
+				if len(records) != 0 {
+					t.Errorf("records = %v, want none", records)
+				}
+				return
+			}
+			if len(records) != 1 {
+				t.Fatalf("records = %v, want one", records)
+			}
+			record := records[0]
+			if record["level"] != tt.wantLevel || record["msg"] != tt.wantMsg {
+				t.Errorf("record = %s %q, want %s %q", record["level"], record["msg"], tt.wantLevel, tt.wantMsg)
+			}
+			if record["sql"] != tt.sql || record["request_id"] != tt.requestID {
+				t.Errorf("record = %v, want the statement and request ID", record)
+			}
+			if _, ok := record["duration_ms"].(float64); !ok {
+				t.Errorf("duration_ms = %v, want a number", record["duration_ms"])
+			}
+			if strings.Contains(buf.String(), "secret-arg") {
+				t.Errorf("record %v logs the query's arguments", record)
+			}
+
+			// Failures carry the error, the others the rows affected
+			if tt.wantLevel == "WARN" {
+				if errMsg, _ := record["error"].(string); !strings.Contains(errMsg, failure.Message) {
+					t.Errorf("error = %v, want the query's error", record["error"])
+				}
+			} else if _, ok := record["error"]; ok {
+				t.Errorf("error = %v on a query that did not fail", record["error"])
+			}
+			if tt.wantLevel == "DEBUG" && record["rows"] != float64(pgconn.NewCommandTag(tt.tag).RowsAffected()) {
+				t.Errorf("rows = %v, want those of %q", record["rows"], tt.tag)
+			}
+		})
+	}
+}
+
+func TestQueryTracerTruncatesSQL(t *testing.T) {
+	tracer, buf := testTracer()
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	sql := "SELECT " + strings.Repeat("a, ", 100) + "b FROM t"
+
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	records := logRecords(t, buf)
+	if len(records) != 1 {
+		t.Fatalf("records = %v, want one", records)
+	}
+	if want := sql[:maxTracedSQLLength] + "…"; records[0]["sql"] != want {
+		t.Errorf("sql = %q, want the first %d bytes", records[0]["sql"], maxTracedSQLLength)
+	}
+}
+
+func TestQueryTracerWithoutStart(t *testing.T) {
+	tracer, buf := testTracer()
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+
+	// An end without its start (another tracer's context) is ignored
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+	if buf.Len() != 0 {
+		t.Errorf("logged %q for a query that was never started", buf)
+	}
+}
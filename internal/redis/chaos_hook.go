@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/chaos"
+	"github.com/redis/go-redis/v9"
+)
+
+// chaosHook fails or delays Redis commands for resilience testing
+type chaosHook struct {
+	injector *chaos.Injector
+}
+
+// NewChaosHook returns a client hook injecting the chaos.TargetRedis fault
+// before every command and pipeline (install with client.AddHook)
+func NewChaosHook(injector *chaos.Injector) redis.Hook {
+	return chaosHook{injector: injector}
+}
+
+func (h chaosHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h chaosHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.injector.Inject(ctx, chaos.TargetRedis); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h chaosHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := h.injector.Inject(ctx, chaos.TargetRedis); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
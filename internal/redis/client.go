@@ -4,23 +4,28 @@ import (
 	"context"
 	"time"
 
+	"github.com/TopThisHat/stdlib-golang-api/internal/config"
 	"github.com/redis/go-redis/v9"
 )
 
-// NewRedisClient creates a Redis client with sensible defaults
-func NewRedisClient(addr, password string) *redis.Client {
-	opt := &redis.Options{
-		Addr:         addr,
-		Password:     password,
-		DB:           0, // Use default DB
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolSize:     10, // Connection pool size
-		MinIdleConns: 5,  // Keep some connections warm
+// Options builds go-redis client options from cfg
+// Zero-valued settings keep go-redis's own defaults
+func Options(cfg config.RedisConfig) *redis.Options {
+	return &redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
 	}
+}
 
-	client := redis.NewClient(opt)
+// NewRedisClient creates a Redis client from a typed configuration
+func NewRedisClient(cfg config.RedisConfig) *redis.Client {
+	client := redis.NewClient(Options(cfg))
 
 	// Test the connection immediately
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
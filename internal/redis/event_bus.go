@@ -0,0 +1,471 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure EventBus implements domain.EventBus at compile time
+var _ domain.EventBus = (*EventBus)(nil)
+
+// Reads: entries asked for at once, and how long XREADGROUP blocks at most
+// while the stream has none (so idle entries are looked for at least that
+// often, and every half of ClaimIdle when that is shorter)
+const (
+	readCount = 8
+	readBlock = 5 * time.Second
+)
+
+// EventBusConfig configures the stream and consumer group of an EventBus
+type EventBusConfig struct {
+	Stream     string        // Stream key
+	Group      string        // Consumer group, shared by every instance
+	Consumer   string        // This instance in the group; hostname-pid when empty
+	Source     string        // CloudEvents source of published events; domain.DefaultEventSource when empty
+	MaxLen     int64         // About how many entries the stream keeps; 0 for no limit
+	MaxAge     time.Duration // How long the stream keeps entries; 0 for no limit
+	ClaimIdle  time.Duration // How long an entry may go unacknowledged before another consumer claims it
+	MaxDeliver int           // Deliveries before an entry whose handlers keep failing is dropped
+	// DeadLetters keeps the entries that are not domain events or ran out
+	// of deliveries, for replay through Redeliver; nil only logs them
+	DeadLetters domain.DeadLetterQueue
+}
+
+// DefaultEventBusConfig returns the defaults
+func DefaultEventBusConfig() EventBusConfig {
+	return EventBusConfig{
+		Stream:     "events:domain",
+		Group:      "stdlib-golang-api",
+		MaxLen:     100_000,
+		MaxAge:     7 * 24 * time.Hour,
+		ClaimIdle:  30 * time.Second,
+		MaxDeliver: 5,
+	}
+}
+
+// EventBus is a Redis Streams implementation of domain.EventBus, for
+// asynchronous handlers without infrastructure beyond Redis (5.0+; trimming
+// by age and claiming idle entries need 6.2):
+//
+//   - Publish appends each event to the stream as a CloudEvent and returns
+//     once Redis has it, trimming the stream to about MaxLen entries;
+//   - Run reads the stream through a consumer group shared by every
+//     instance, hands each entry to the handlers subscribed on this
+//     instance and acknowledges it once they all succeeded. Entries left
+//     unacknowledged for ClaimIdle (their handlers failed, or their
+//     consumer stopped) are claimed and handled again, up to MaxDeliver
+//     deliveries, then kept in DeadLetters. Run also trims entries older
+//     than MaxAge.
+//
+// As with the NATS bus, handlers run after Publish returned, each event on
+// one instance, and must be idempotent: an entry is delivered again when
+// its consumer stops before acknowledging it, and republishing an event
+// (an outbox retry) appends it twice. Trimming drops entries whether or not
+// they were handled, so MaxLen and MaxAge must leave room for a backlog.
+//
+// Keys:
+//
+//	<stream>  stream of entries {type, event}, event being the CloudEvent JSON
+type EventBus struct {
+	client *redis.Client
+	cfg    EventBusConfig
+	logg   *logger.Logger
+
+	subsMu        sync.RWMutex
+	nextID        int
+	subscriptions []subscription // In the order they subscribed
+}
+
+type subscription struct {
+	id         int
+	handler    domain.EventHandler
+	eventTypes map[string]bool // Nil for every type
+}
+
+// NewEventBus creates an event bus on the stream of cfg; zero settings
+// take their defaults, except MaxLen and MaxAge
+func NewEventBus(c *redis.Client, cfg EventBusConfig, logg *logger.Logger) *EventBus {
+	defaults := DefaultEventBusConfig()
+	if cfg.Stream == "" {
+		cfg.Stream = defaults.Stream
+	}
+	if cfg.Group == "" {
+		cfg.Group = defaults.Group
+	}
+	if cfg.Consumer == "" {
+		host, _ := os.Hostname()
+		cfg.Consumer = host + "-" + strconv.Itoa(os.Getpid())
+	}
+	if cfg.ClaimIdle <= 0 {
+		cfg.ClaimIdle = defaults.ClaimIdle
+	}
+	if cfg.MaxDeliver <= 0 {
+		cfg.MaxDeliver = defaults.MaxDeliver
+	}
+	return &EventBus{client: c, cfg: cfg, logg: logg}
+}
+
+// Publish appends event to the stream and returns once Redis has it.
+// Handlers run later, through Run; their errors are not returned here.
+func (b *EventBus) Publish(ctx context.Context, event domain.Event) error {
+	data, err := domain.MarshalCloudEvent(event, b.cfg.Source)
+	if err != nil {
+		return err
+	}
+	args := &redis.XAddArgs{
+		Stream: b.cfg.Stream,
+		Values: map[string]any{"type": event.EventType(), "event": data},
+	}
+	if b.cfg.MaxLen > 0 {
+		args.MaxLen, args.Approx = b.cfg.MaxLen, true
+	}
+	if err := b.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("redis xadd of %s event %s failed: %w", event.EventType(), event.Metadata().ID, err)
+	}
+	return nil
+}
+
+func (b *EventBus) Subscribe(handler domain.EventHandler, eventTypes ...string) func() {
+	b.subsMu.Lock()
+	defer b.subsMu.Unlock()
+	sub := subscription{id: b.nextID, handler: handler}
+	b.nextID++
+	if len(eventTypes) > 0 {
+		sub.eventTypes = make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			sub.eventTypes[t] = true
+		}
+	}
+	b.subscriptions = append(b.subscriptions, sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.subsMu.Lock()
+			b.subscriptions = slices.DeleteFunc(b.subscriptions, func(s subscription) bool { return s.id == sub.id })
+			b.subsMu.Unlock()
+		})
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Consuming
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Run delivers the stream's entries to the subscribed handlers, one at a
+// time, until ctx ends; the entry being handled then is handled to the end.
+// New entries come in stream order, claimed ones once they were idle for
+// ClaimIdle. Redis failures are retried with backoff. On return, this
+// consumer leaves the group if it holds no pending entries.
+func (b *EventBus) Run(ctx context.Context) error {
+	defer b.leaveGroup()
+	failures := 0
+	for {
+		handled, err := b.consume(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if handled > 0 {
+			failures = 0
+		}
+		failures++
+		b.logg.Warn("redis stream consumer failed, retrying", "error", err, "stream", b.cfg.Stream, "group", b.cfg.Group, "attempt", failures)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(min(100*time.Millisecond<<min(failures-1, 5), 2*time.Second)):
+		}
+	}
+}
+
+// consume reads, claims and handles entries until ctx ends or a command
+// fails, and returns how many it handled
+func (b *EventBus) consume(ctx context.Context) (int, error) {
+	// From the start of the stream, like a JetStream consumer delivering
+	// all: events published before the first Run are handled too
+	err := b.client.XGroupCreateMkStream(ctx, b.cfg.Stream, b.cfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return 0, fmt.Errorf("redis xgroup create failed: %w", err)
+	}
+	handled := 0
+	var lastMaintenance time.Time
+	for ctx.Err() == nil {
+		if time.Since(lastMaintenance) >= min(b.cfg.ClaimIdle/2, time.Minute) {
+			n, err := b.claimIdle(ctx)
+			handled += n
+			if err != nil {
+				return handled, err
+			}
+			if err := b.trim(ctx); err != nil {
+				return handled, err
+			}
+			lastMaintenance = time.Now()
+		}
+		n, err := b.read(ctx)
+		handled += n
+		if err != nil {
+			return handled, err
+		}
+	}
+	return handled, nil
+}
+
+// read waits for new entries and handles them
+func (b *EventBus) read(ctx context.Context) (int, error) {
+	streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    b.cfg.Group,
+		Consumer: b.cfg.Consumer,
+		Streams:  []string{b.cfg.Stream, ">"},
+		Count:    readCount,
+		Block:    min(readBlock, b.cfg.ClaimIdle/2),
+	}).Result()
+	if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+		// Entries delivered by a cancelled read are pending on this
+		// consumer, and claimed by another once idle
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redis xreadgroup failed: %w", err)
+	}
+	handled := 0
+	for _, s := range streams {
+		for _, entry := range s.Messages {
+			b.process(ctx, entry, 1)
+			handled++
+		}
+	}
+	return handled, nil
+}
+
+// claimIdle takes over the entries left unacknowledged for ClaimIdle, by
+// any consumer, and handles them
+func (b *EventBus) claimIdle(ctx context.Context) (int, error) {
+	pending, err := b.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: b.cfg.Stream,
+		Group:  b.cfg.Group,
+		Idle:   b.cfg.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  readCount,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis xpending failed: %w", err)
+	}
+	handled := 0
+	for _, p := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		// Claims nothing when another consumer claimed the entry meanwhile
+		// (it is no longer idle)
+		claimed, err := b.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   b.cfg.Stream,
+			Group:    b.cfg.Group,
+			Consumer: b.cfg.Consumer,
+			MinIdle:  b.cfg.ClaimIdle,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			return handled, fmt.Errorf("redis xclaim failed: %w", err)
+		}
+		if len(claimed) == 0 {
+			continue
+		}
+		if claimed[0].Values == nil {
+			// Trimmed away: Redis 7 drops such entries from the pending
+			// list itself, earlier versions return them empty
+			b.ack(ctx, p.ID)
+			continue
+		}
+		b.process(ctx, claimed[0], int(p.RetryCount)+1)
+		handled++
+	}
+	return handled, nil
+}
+
+// trim drops the entries older than MaxAge
+func (b *EventBus) trim(ctx context.Context) error {
+	if b.cfg.MaxAge <= 0 {
+		return nil
+	}
+	minID := strconv.FormatInt(time.Now().Add(-b.cfg.MaxAge).UnixMilli(), 10)
+	if err := b.client.XTrimMinIDApprox(ctx, b.cfg.Stream, minID, 0).Err(); err != nil {
+		return fmt.Errorf("redis xtrim failed: %w", err)
+	}
+	return nil
+}
+
+// process hands entry, delivered for the given time, to the subscribed
+// handlers and acknowledges it once they all succeeded, or for good when it
+// is not an event or its deliveries ran out. A failed entry stays pending,
+// to be claimed after ClaimIdle.
+func (b *EventBus) process(ctx context.Context, entry redis.XMessage, delivered int) {
+	data, _ := entry.Values["event"].(string)
+	event, err := domain.UnmarshalCloudEvent([]byte(data))
+	if err != nil {
+		b.logg.Error("redis stream entry is not a domain event, dropping", "error", err, "stream", b.cfg.Stream, "entry", entry.ID)
+		if b.deadLetter(ctx, entry, delivered, "", "", []byte(data), err) {
+			b.ack(ctx, entry.ID)
+		}
+		return
+	}
+
+	// Handlers run to the end at shutdown, with the entry kept claimed
+	stop := b.keepClaimed(entry.ID)
+	err = b.dispatch(context.WithoutCancel(ctx), event)
+	stop()
+	switch {
+	case err == nil:
+		b.ack(ctx, entry.ID)
+	case delivered >= b.cfg.MaxDeliver:
+		b.logg.Error("redis stream event handler failed, giving up", "error", err,
+			"event", event.EventType(), "event_id", event.Metadata().ID, "entry", entry.ID, "deliveries", delivered)
+		if b.deadLetter(ctx, entry, delivered, event.EventType(), event.Metadata().ID, []byte(data), err) {
+			b.ack(ctx, entry.ID)
+		}
+	default:
+		b.logg.Warn("redis stream event handler failed, redelivering", "error", err,
+			"event", event.EventType(), "event_id", event.Metadata().ID, "entry", entry.ID, "deliveries", delivered, "after", b.cfg.ClaimIdle)
+	}
+}
+
+// deadLetter keeps entry, given up on with cause, in the dead letter queue,
+// and reports whether it may be acknowledged: an entry that could not be
+// kept stays pending, to be given up on again once claimed
+func (b *EventBus) deadLetter(ctx context.Context, entry redis.XMessage, delivered int, eventType, eventID string, data []byte, cause error) bool {
+	if b.cfg.DeadLetters == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	err := b.cfg.DeadLetters.Add(ctx, &domain.DeadLetter{
+		Source:     domain.DeadLetterRedis,
+		Type:       eventType,
+		MessageID:  eventID,
+		Payload:    data,
+		Attributes: map[string]string{"stream": b.cfg.Stream, "entry": entry.ID},
+		Error:      cause.Error(),
+		Attempts:   delivered,
+		FailedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		b.logg.Error("redis stream event not dead-lettered, leaving it pending", "error", err, "entry", entry.ID)
+		return false
+	}
+	return true
+}
+
+// Redeliver hands event to the handlers subscribed on this instance, as a
+// delivery from the stream would, and returns their errors joined. It
+// replays dead letters.
+func (b *EventBus) Redeliver(ctx context.Context, event domain.Event) error {
+	return b.dispatch(ctx, event)
+}
+
+// ack acknowledges an entry; a lost acknowledgement means a redelivery
+// once the entry was idle for ClaimIdle
+func (b *EventBus) ack(ctx context.Context, id string) {
+	ackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := b.client.XAck(ackCtx, b.cfg.Stream, b.cfg.Group, id).Err(); err != nil {
+		b.logg.Warn("redis stream acknowledgement failed, event may be redelivered", "error", err, "entry", id)
+	}
+}
+
+// keepClaimed resets the idle time of an entry every half of ClaimIdle, so
+// no other consumer claims it while its handlers run, until stop is called.
+// Claiming by ID only leaves the delivery count as it is.
+func (b *EventBus) keepClaimed(id string) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ticker := time.NewTicker(b.cfg.ClaimIdle / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), b.cfg.ClaimIdle/2)
+				err := b.client.XClaimJustID(ctx, &redis.XClaimArgs{
+					Stream:   b.cfg.Stream,
+					Group:    b.cfg.Group,
+					Consumer: b.cfg.Consumer,
+					Messages: []string{id},
+				}).Err()
+				cancel()
+				if err != nil {
+					b.logg.Warn("redis stream entry not kept claimed", "error", err, "entry", id)
+				}
+			}
+		}
+	})
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// leaveGroup deletes this consumer from the group unless entries are
+// pending on it (deleting it would drop them), so consumers named after
+// stopped instances do not pile up
+func (b *EventBus) leaveGroup() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pending, err := b.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   b.cfg.Stream,
+		Group:    b.cfg.Group,
+		Start:    "-",
+		End:      "+",
+		Count:    1,
+		Consumer: b.cfg.Consumer,
+	}).Result()
+	if err != nil || len(pending) > 0 {
+		return
+	}
+	if err := b.client.XGroupDelConsumer(ctx, b.cfg.Stream, b.cfg.Group, b.cfg.Consumer).Err(); err != nil {
+		b.logg.Debug("redis stream consumer not deleted", "error", err, "consumer", b.cfg.Consumer)
+	}
+}
+
+// dispatch calls the handlers subscribed to event's type, in the order they
+// subscribed, and returns their errors joined
+func (b *EventBus) dispatch(ctx context.Context, event domain.Event) error {
+	b.subsMu.RLock()
+	var handlers []domain.EventHandler
+	for _, sub := range b.subscriptions {
+		if sub.eventTypes == nil || sub.eventTypes[event.EventType()] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.subsMu.RUnlock()
+
+	var errs []error
+	for _, handle := range handlers {
+		if err := runHandler(ctx, handle, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runHandler calls handle, turning a panic into an error
+func runHandler(ctx context.Context, handle domain.EventHandler, event domain.Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%s handler panicked: %v", event.EventType(), p)
+		}
+	}()
+	return handle(ctx, event)
+}
@@ -0,0 +1,349 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testClaimIdle is short enough for redeliveries to come within a test
+const testClaimIdle = 200 * time.Millisecond
+
+// testEventBusConfig returns the defaults, with redeliveries after
+// testClaimIdle
+func testEventBusConfig() EventBusConfig {
+	cfg := DefaultEventBusConfig()
+	cfg.Consumer = "api-1"
+	cfg.ClaimIdle = testClaimIdle
+	return cfg
+}
+
+// newTestEventBus returns a bus on a fresh miniredis, and a client on the
+// same server for inspecting what the bus did
+func newTestEventBus(t *testing.T, cfg EventBusConfig) (*EventBus, *redis.Client) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewEventBus(client, cfg, logger.NewWithOptions("error", io.Discard, false)), client
+}
+
+// runBus runs bus until the returned stop is called, which waits for Run
+// to return
+func runBus(bus *EventBus) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// eventually fails the test unless cond holds within a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// consumed reports whether the group read the whole stream and holds no
+// pending entries
+func consumed(client *redis.Client, cfg EventBusConfig) bool {
+	ctx := context.Background()
+	groups, err := client.XInfoGroups(ctx, cfg.Stream).Result()
+	if err != nil || len(groups) != 1 {
+		return false
+	}
+	last, err := client.XRevRangeN(ctx, cfg.Stream, "+", "-", 1).Result()
+	if err != nil || len(last) == 0 {
+		return false
+	}
+	pending, err := client.XPending(ctx, cfg.Stream, cfg.Group).Result()
+	return err == nil && groups[0].LastDeliveredID == last[0].ID && pending.Count == 0
+}
+
+// recorder records the events a handler received
+type recorder struct {
+	mu     sync.Mutex
+	events []domain.Event
+}
+
+func (r *recorder) record(event domain.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) received() []domain.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.Event(nil), r.events...)
+}
+
+func TestEventBusPublish(t *testing.T) {
+	cfg := testEventBusConfig()
+	cfg.MaxLen = 3
+	cfg.Source = "/test"
+	bus, client := newTestEventBus(t, cfg)
+	ctx := context.Background()
+
+	for i := range 10 {
+		shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-" + string(rune('a'+i))}, OrderID: "order-1"}
+		if err := bus.Publish(ctx, shipped); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	// Redis trims to about MaxLen, by whole nodes; miniredis trims exactly
+	entries, err := client.XRange(ctx, cfg.Stream, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("stream has %d entries, want it trimmed to 3", len(entries))
+	}
+	last := entries[2]
+	if last.Values["type"] != domain.EventOrderShipped {
+		t.Errorf("entry type = %v, want %s", last.Values["type"], domain.EventOrderShipped)
+	}
+	data, _ := last.Values["event"].(string)
+	event, err := domain.UnmarshalCloudEvent([]byte(data))
+	if err != nil {
+		t.Fatalf("entry event: %v", err)
+	}
+	if shipped, ok := event.(domain.OrderShipped); !ok || shipped.ID != "evt-j" || shipped.OrderID != "order-1" {
+		t.Errorf("last entry = %#v, want the last event published", event)
+	}
+	if !strings.Contains(data, `"source":"/test"`) {
+		t.Errorf("cloud event = %s, want the configured source", data)
+	}
+}
+
+func TestEventBusRun(t *testing.T) {
+	cfg := testEventBusConfig()
+	bus, client := newTestEventBus(t, cfg)
+
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1", UserID: "user-1"}
+	confirmed := domain.OrderConfirmed{EventMetadata: domain.EventMetadata{ID: "evt-2"}, OrderID: "order-1", UserID: "user-1"}
+	var onlyShipped, every recorder
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		onlyShipped.record(event)
+		return nil
+	}, domain.EventOrderShipped)
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		every.record(event)
+		return nil
+	})
+
+	// Published before the group exists, and handled all the same
+	ctx := context.Background()
+	if err := bus.Publish(ctx, shipped); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	stop := runBus(bus)
+	eventually(t, "the shipped event", func() bool { return len(every.received()) == 1 })
+	if err := bus.Publish(ctx, confirmed); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	eventually(t, "the stream to be acknowledged", func() bool { return consumed(client, cfg) })
+	stop()
+
+	if got := onlyShipped.received(); len(got) != 1 || got[0] != shipped {
+		t.Errorf("shipped handler received %v, want the shipped event alone", got)
+	}
+	if got := every.received(); len(got) != 2 || got[0] != shipped || got[1] != confirmed {
+		t.Errorf("handler of every type received %v, want the shipped then the confirmed event", got)
+	}
+	// The consumer left the group, holding nothing
+	consumers, err := client.XInfoConsumers(ctx, cfg.Stream, cfg.Group).Result()
+	if err != nil || len(consumers) != 0 {
+		t.Errorf("consumers after Run = %+v, %v; want none", consumers, err)
+	}
+}
+
+func TestEventBusRedeliversFailures(t *testing.T) {
+	cfg := testEventBusConfig()
+	bus, client := newTestEventBus(t, cfg)
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts++; attempts == 1 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1"}
+	if err := bus.Publish(context.Background(), shipped); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	started := time.Now()
+	stop := runBus(bus)
+	defer stop()
+	eventually(t, "the redelivery to be acknowledged", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts == 2 && consumed(client, cfg)
+	})
+	// The failed entry stays pending until it was idle for ClaimIdle
+	if elapsed := time.Since(started); elapsed < testClaimIdle {
+		t.Errorf("redelivered after %v, want at least ClaimIdle", elapsed)
+	}
+}
+
+func TestEventBusClaimsFromStoppedConsumer(t *testing.T) {
+	cfg := testEventBusConfig()
+	bus, client := newTestEventBus(t, cfg)
+	var got recorder
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		got.record(event)
+		return nil
+	})
+
+	// Another instance read the entry and stopped before acknowledging it
+	ctx := context.Background()
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1"}
+	if err := bus.Publish(ctx, shipped); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := client.XGroupCreate(ctx, cfg.Stream, cfg.Group, "0").Err(); err != nil {
+		t.Fatalf("XGroupCreate: %v", err)
+	}
+	read, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    cfg.Group,
+		Consumer: "api-stopped",
+		Streams:  []string{cfg.Stream, ">"},
+		Count:    1,
+	}).Result()
+	if err != nil || len(read) != 1 || len(read[0].Messages) != 1 {
+		t.Fatalf("XReadGroup = %v, %v", read, err)
+	}
+
+	stop := runBus(bus)
+	defer stop()
+	eventually(t, "the entry to be claimed and acknowledged", func() bool { return consumed(client, cfg) })
+	if events := got.received(); len(events) != 1 || events[0] != shipped {
+		t.Errorf("received %v, want the event of the stopped consumer", events)
+	}
+}
+
+func TestEventBusDeadLetters(t *testing.T) {
+	cfg := testEventBusConfig()
+	cfg.MaxDeliver = 2
+	cfg.DeadLetters = memory.NewDeadLetterQueue()
+	bus, client := newTestEventBus(t, cfg)
+
+	// The first handler panics on every delivery; those after it run
+	// regardless
+	var after recorder
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		panic("boom")
+	}, domain.EventOrderShipped)
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		after.record(event)
+		return nil
+	})
+
+	ctx := context.Background()
+	shipped := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-1"}, OrderID: "order-1"}
+	if err := bus.Publish(ctx, shipped); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := client.XAdd(ctx, &redis.XAddArgs{
+		Stream: cfg.Stream,
+		Values: map[string]any{"type": "order.unknown", "event": "not an event"},
+	}).Err(); err != nil {
+		t.Fatalf("XAdd: %v", err)
+	}
+
+	stop := runBus(bus)
+	defer stop()
+	eventually(t, "both entries to be given up on", func() bool { return consumed(client, cfg) })
+
+	if got := after.received(); len(got) != 2 {
+		t.Errorf("handler after the panicking one received %d deliveries, want MaxDeliver", len(got))
+	}
+	letters, err := cfg.DeadLetters.List(ctx, domain.DeadLetterFilter{})
+	if err != nil || len(letters) != 2 {
+		t.Fatalf("dead letters = %+v, %v; want the panicking event and the entry that is not one", letters, err)
+	}
+	byType := map[string]*domain.DeadLetter{}
+	for _, letter := range letters {
+		if letter.Source != domain.DeadLetterRedis || letter.Attributes["stream"] != cfg.Stream || letter.Attributes["entry"] == "" {
+			t.Errorf("dead letter = %+v, want it sourced from the stream entry", letter)
+		}
+		byType[letter.Type] = letter
+	}
+	if letter := byType[domain.EventOrderShipped]; letter == nil || letter.MessageID != "evt-1" || letter.Attempts != 2 ||
+		!strings.Contains(letter.Error, "handler panicked: boom") {
+		t.Errorf("dead letter of the shipped event = %+v, want the recovered panic after 2 deliveries", letter)
+	}
+	if letter := byType[""]; letter == nil || string(letter.Payload) != "not an event" || letter.Attempts != 1 {
+		t.Errorf("dead letter of the bad entry = %+v, want it after 1 delivery", letter)
+	}
+}
+
+func TestEventBusTrimsOldEntries(t *testing.T) {
+	cfg := testEventBusConfig()
+	cfg.MaxAge = time.Hour
+	bus, client := newTestEventBus(t, cfg)
+	var got recorder
+	bus.Subscribe(func(ctx context.Context, event domain.Event) error {
+		got.record(event)
+		return nil
+	})
+
+	// An entry from long before MaxAge, left unread, is trimmed away
+	// before the consumer reads the stream
+	ctx := context.Background()
+	old := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-old"}, OrderID: "order-1"}
+	data, err := domain.MarshalCloudEvent(old, "")
+	if err != nil {
+		t.Fatalf("MarshalCloudEvent: %v", err)
+	}
+	if err := client.XAdd(ctx, &redis.XAddArgs{
+		Stream: cfg.Stream,
+		ID:     "1-1",
+		Values: map[string]any{"type": old.EventType(), "event": data},
+	}).Err(); err != nil {
+		t.Fatalf("XAdd: %v", err)
+	}
+	fresh := domain.OrderShipped{EventMetadata: domain.EventMetadata{ID: "evt-fresh"}, OrderID: "order-2"}
+	if err := bus.Publish(ctx, fresh); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	stop := runBus(bus)
+	defer stop()
+	eventually(t, "the fresh event", func() bool { return consumed(client, cfg) })
+	if events := got.received(); len(events) != 1 || events[0] != fresh {
+		t.Errorf("received %v, want the fresh event alone", events)
+	}
+	if n, err := client.XLen(ctx, cfg.Stream).Result(); err != nil || n != 1 {
+		t.Errorf("stream length = %d, %v; want the old entry trimmed", n, err)
+	}
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure IdempotencyStore implements domain.IdempotencyStore at compile time
+var _ domain.IdempotencyStore = (*IdempotencyStore)(nil)
+
+// IdempotencyStore is a Redis implementation of domain.IdempotencyStore
+//
+// Keys:
+//
+//	idempotency:<key>  JSON domain.IdempotencyRecord, expiring after the lock
+//	                   TTL while reserved and the record TTL once completed
+type IdempotencyStore struct {
+	client *redis.Client
+}
+
+// NewIdempotencyStore creates a Redis-backed idempotency key store
+func NewIdempotencyStore(c *redis.Client) domain.IdempotencyStore {
+	return &IdempotencyStore{client: c}
+}
+
+func idempotencyKey(key string) string {
+	return "idempotency:" + key
+}
+
+// reserveScript sets the reservation unless the key exists, returning the
+// existing record otherwise. ARGV = reservation, lock ttl ms
+var reserveScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return false
+end
+return redis.call('GET', KEYS[1])
+`)
+
+func (s *IdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*domain.IdempotencyRecord, error) {
+	reservation, err := json.Marshal(domain.IdempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	data, err := reserveScript.Run(ctx, s.client, []string{idempotencyKey(key)}, reservation, lockTTL.Milliseconds()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis reserve failed: %w", err)
+	}
+
+	var record domain.IdempotencyRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+	return &record, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, record *domain.IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+	if err := s.client.Set(ctx, idempotencyKey(key), data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, idempotencyKey(key)).Err(); err != nil {
+		return fmt.Errorf("redis del failed: %w", err)
+	}
+	return nil
+}
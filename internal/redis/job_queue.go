@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure JobQueue implements domain.JobQueue at compile time
+var _ domain.JobQueue = (*JobQueue)(nil)
+
+// JobQueue is a Redis implementation of domain.JobQueue
+//
+// Keys:
+//
+//	jobs:<priority>  list of JSON-encoded jobs; pushed on the left, popped on the right
+//
+// A job is removed when dequeued, so one that is running when its replica
+// crashes is lost (at-most-once delivery).
+type JobQueue struct {
+	client *redis.Client
+}
+
+// NewJobQueue creates a Redis-backed job queue
+func NewJobQueue(c *redis.Client) domain.JobQueue {
+	return &JobQueue{client: c}
+}
+
+func jobQueueKey(priority domain.JobPriority) string {
+	return fmt.Sprintf("jobs:%s", priority)
+}
+
+func (q *JobQueue) Enqueue(ctx context.Context, job *domain.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	if err := q.client.LPush(ctx, jobQueueKey(job.Priority), data).Err(); err != nil {
+		return fmt.Errorf("redis lpush failed: %w", err)
+	}
+	return nil
+}
+
+func (q *JobQueue) Dequeue(ctx context.Context, priority domain.JobPriority) (*domain.Job, error) {
+	data, err := q.client.RPop(ctx, jobQueueKey(priority)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redis rpop failed: %w", err)
+	}
+
+	var job domain.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	return &job, nil
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure LoginAttemptStore implements domain.LoginAttemptStore at compile time
+var _ domain.LoginAttemptStore = (*LoginAttemptStore)(nil)
+
+// LoginAttemptStore is a Redis implementation of domain.LoginAttemptStore
+//
+// Keys:
+//
+//	bruteforce:fail:<key>  failure counter, expires at the end of the window
+//	bruteforce:lock:<key>  present while the key is locked out
+type LoginAttemptStore struct {
+	client *redis.Client
+}
+
+// NewLoginAttemptStore creates a Redis-backed failed attempt tracker
+func NewLoginAttemptStore(c *redis.Client) domain.LoginAttemptStore {
+	return &LoginAttemptStore{client: c}
+}
+
+func (s *LoginAttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	failKey := fmt.Sprintf("bruteforce:fail:%s", key)
+
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, failKey)
+	// NX keeps the window anchored at the first failure
+	pipe.ExpireNX(ctx, failKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis incr failed: %w", err)
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s *LoginAttemptStore) Failures(ctx context.Context, key string) (int, time.Duration, error) {
+	pipe := s.client.Pipeline()
+	count := pipe.Get(ctx, fmt.Sprintf("bruteforce:fail:%s", key))
+	lock := pipe.PTTL(ctx, fmt.Sprintf("bruteforce:lock:%s", key))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, fmt.Errorf("redis get failed: %w", err)
+	}
+
+	n, err := count.Int()
+	if err != nil && err != redis.Nil {
+		return 0, 0, fmt.Errorf("invalid failure counter: %w", err)
+	}
+
+	// PTTL is negative when the key is missing or has no expiry
+	lockedFor := lock.Val()
+	if lockedFor < 0 {
+		lockedFor = 0
+	}
+
+	return n, lockedFor, nil
+}
+
+func (s *LoginAttemptStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	if err := s.client.Set(ctx, fmt.Sprintf("bruteforce:lock:%s", key), "1", d).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
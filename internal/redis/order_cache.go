@@ -7,31 +7,40 @@ import (
 	"time"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/resilience"
 	"github.com/redis/go-redis/v9"
 )
 
 // Ensure OrderCache implements domain.OrderCache at compile time
 var _ domain.OrderCache = (*OrderCache)(nil)
 
+// orderKeyPrefix namespaces cached orders by their encoding, as
+// userKeyPrefix does users
+const orderKeyPrefix = "orders:v2:"
+
 // OrderCache is a Redis implementation of domain.OrderCache
 type OrderCache struct {
 	client *redis.Client
 	ttl    time.Duration // How long to cache entries
+	retry  resilience.RetryPolicy
 }
 
 // NewOrderCache creates a Redis-backed order cache
-func NewOrderCache(c *redis.Client) domain.OrderCache {
+func NewOrderCache(c *redis.Client, opts ...CacheOption) domain.OrderCache {
 	return &OrderCache{
 		client: c,
 		ttl:    10 * time.Minute, // Cache orders for 10 minutes
+		retry:  newCacheOptions(opts).retry,
 	}
 }
 
 // Get retrieves a cached order by ID
 func (c *OrderCache) Get(ctx context.Context, orderID string) (*domain.Order, error) {
-	key := fmt.Sprintf("order:%s", orderID)
+	key := orderKeyPrefix + orderID
 
-	data, err := c.client.Get(ctx, key).Result()
+	data, err := resilience.Retry(ctx, c.retry, func(ctx context.Context) (string, error) {
+		return c.client.Get(ctx, key).Result()
+	})
 	if err != nil {
 		if err == redis.Nil {
 			return nil, domain.ErrCacheMiss // Cache miss, not an error
@@ -49,14 +58,17 @@ func (c *OrderCache) Get(ctx context.Context, orderID string) (*domain.Order, er
 
 // Set caches an order
 func (c *OrderCache) Set(ctx context.Context, order *domain.Order) error {
-	key := fmt.Sprintf("order:%s", order.ID)
+	key := orderKeyPrefix + order.ID
 
 	data, err := json.Marshal(order)
 	if err != nil {
 		return fmt.Errorf("failed to marshal order: %w", err)
 	}
 
-	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+	err = c.retry.Do(ctx, func(ctx context.Context) error {
+		return c.client.Set(ctx, key, data, c.ttl).Err()
+	})
+	if err != nil {
 		return fmt.Errorf("redis set failed: %w", err)
 	}
 
@@ -65,8 +77,10 @@ func (c *OrderCache) Set(ctx context.Context, order *domain.Order) error {
 
 // Invalidate removes an order from cache (call this when updating/deleting)
 func (c *OrderCache) Invalidate(ctx context.Context, orderID string) error {
-	key := fmt.Sprintf("order:%s", orderID)
-	return c.client.Del(ctx, key).Err()
+	key := orderKeyPrefix + orderID
+	return c.retry.Do(ctx, func(ctx context.Context) error {
+		return c.client.Del(ctx, key).Err()
+	})
 }
 
 // InvalidateByUserID removes all cached orders for a specific user
@@ -75,14 +89,17 @@ func (c *OrderCache) InvalidateByUserID(ctx context.Context, userID string) erro
 	// Use Redis SCAN to find all order keys for this user
 	// Note: This requires scanning all order keys and checking userID
 	// For better performance, consider maintaining a separate index set
-	pattern := "order:*"
+	pattern := orderKeyPrefix + "*"
 	var cursor uint64
 	var keysToDelete []string
 
 	for {
 		var keys []string
-		var err error
-		keys, cursor, err = c.client.Scan(ctx, cursor, pattern, 100).Result()
+		err := c.retry.Do(ctx, func(ctx context.Context) error {
+			var err error
+			keys, cursor, err = c.client.Scan(ctx, cursor, pattern, 100).Result()
+			return err
+		})
 		if err != nil {
 			return fmt.Errorf("failed to scan keys: %w", err)
 		}
@@ -111,7 +128,10 @@ func (c *OrderCache) InvalidateByUserID(ctx context.Context, userID string) erro
 
 	// Delete all matching keys
 	if len(keysToDelete) > 0 {
-		if err := c.client.Del(ctx, keysToDelete...).Err(); err != nil {
+		err := c.retry.Do(ctx, func(ctx context.Context) error {
+			return c.client.Del(ctx, keysToDelete...).Err()
+		})
+		if err != nil {
 			return fmt.Errorf("failed to delete keys: %w", err)
 		}
 	}
@@ -125,7 +145,9 @@ func (c *OrderCache) InvalidateByUserID(ctx context.Context, userID string) erro
 func (c *OrderCache) GetUserOrderIDs(ctx context.Context, userID string) ([]string, error) {
 	key := fmt.Sprintf("user:%s:orders", userID)
 
-	orderIDs, err := c.client.SMembers(ctx, key).Result()
+	orderIDs, err := resilience.Retry(ctx, c.retry, func(ctx context.Context) ([]string, error) {
+		return c.client.SMembers(ctx, key).Result()
+	})
 	if err != nil {
 		if err == redis.Nil {
 			return []string{}, nil
@@ -141,12 +163,18 @@ func (c *OrderCache) GetUserOrderIDs(ctx context.Context, userID string) ([]stri
 func (c *OrderCache) AddUserOrderIndex(ctx context.Context, userID, orderID string) error {
 	key := fmt.Sprintf("user:%s:orders", userID)
 
-	if err := c.client.SAdd(ctx, key, orderID).Err(); err != nil {
+	err := c.retry.Do(ctx, func(ctx context.Context) error {
+		return c.client.SAdd(ctx, key, orderID).Err()
+	})
+	if err != nil {
 		return fmt.Errorf("failed to add order to user index: %w", err)
 	}
 
 	// Set TTL on the index set (same as order TTL)
-	if err := c.client.Expire(ctx, key, c.ttl).Err(); err != nil {
+	err = c.retry.Do(ctx, func(ctx context.Context) error {
+		return c.client.Expire(ctx, key, c.ttl).Err()
+	})
+	if err != nil {
 		return fmt.Errorf("failed to set TTL on user order index: %w", err)
 	}
 
@@ -157,5 +185,7 @@ func (c *OrderCache) AddUserOrderIndex(ctx context.Context, userID, orderID stri
 // Call this when invalidating an order
 func (c *OrderCache) RemoveUserOrderIndex(ctx context.Context, userID, orderID string) error {
 	key := fmt.Sprintf("user:%s:orders", userID)
-	return c.client.SRem(ctx, key, orderID).Err()
+	return c.retry.Do(ctx, func(ctx context.Context) error {
+		return c.client.SRem(ctx, key, orderID).Err()
+	})
 }
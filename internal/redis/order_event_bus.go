@@ -0,0 +1,119 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure OrderEventBus implements domain.OrderEventBus at compile time
+var _ domain.OrderEventBus = (*OrderEventBus)(nil)
+
+// orderEventsChannel carries every order event as JSON
+const orderEventsChannel = "events:orders"
+
+// OrderEventBus is a Redis pub/sub implementation of domain.OrderEventBus.
+// Pub/sub is fire-and-forget: instances that are not subscribed (e.g. while
+// reconnecting) miss the events published meanwhile.
+//
+// Channels:
+//
+//	events:orders  JSON-encoded order events
+type OrderEventBus struct {
+	client *redis.Client
+	logg   *logger.Logger
+}
+
+// NewOrderEventBus creates a Redis-backed order event bus
+func NewOrderEventBus(c *redis.Client, logg *logger.Logger) domain.OrderEventBus {
+	return &OrderEventBus{client: c, logg: logg}
+}
+
+func (b *OrderEventBus) Publish(ctx context.Context, event *domain.OrderEvent) error {
+	data, err := encodeOrderEvent(event)
+	if err != nil {
+		return err
+	}
+	if err := b.client.Publish(ctx, orderEventsChannel, data).Err(); err != nil {
+		return fmt.Errorf("redis publish failed: %w", err)
+	}
+	return nil
+}
+
+// Subscribe relays the channel until ctx ends. The client resubscribes by
+// itself after connection failures.
+func (b *OrderEventBus) Subscribe(ctx context.Context, handle func(*domain.OrderEvent)) error {
+	sub := b.client.Subscribe(ctx, orderEventsChannel)
+	defer sub.Close()
+
+	// Wait for the subscription to be confirmed before relaying
+	if _, err := sub.Receive(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("redis subscribe failed: %w", err)
+	}
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			event, err := decodeOrderEvent([]byte(msg.Payload))
+			if err != nil {
+				b.logg.Warn("ignoring malformed order event", "error", err)
+				continue
+			}
+			handle(event)
+		}
+	}
+}
+
+// orderEventMessage is the stored and published form of an order event
+type orderEventMessage struct {
+	ID             string    `json:"id"`
+	OrderID        string    `json:"order_id"`
+	UserID         string    `json:"user_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+func encodeOrderEvent(event *domain.OrderEvent) ([]byte, error) {
+	data, err := json.Marshal(orderEventMessage{
+		ID:             event.ID,
+		OrderID:        event.OrderID,
+		UserID:         event.UserID,
+		Status:         string(event.Status),
+		PreviousStatus: string(event.PreviousStatus),
+		OccurredAt:     event.OccurredAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal order event: %w", err)
+	}
+	return data, nil
+}
+
+func decodeOrderEvent(data []byte) (*domain.OrderEvent, error) {
+	var m orderEventMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order event: %w", err)
+	}
+	return &domain.OrderEvent{
+		ID:             m.ID,
+		OrderID:        m.OrderID,
+		UserID:         m.UserID,
+		Status:         domain.OrderStatus(m.Status),
+		PreviousStatus: domain.OrderStatus(m.PreviousStatus),
+		OccurredAt:     m.OccurredAt,
+	}, nil
+}
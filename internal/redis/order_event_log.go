@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure OrderEventLog implements domain.OrderEventLog at compile time
+var _ domain.OrderEventLog = (*OrderEventLog)(nil)
+
+// orderEventLogKey holds the retained order events
+const orderEventLogKey = "events:orders:log"
+
+// OrderEventLog is a Redis Stream implementation of domain.OrderEventLog.
+// Event IDs are stream entry IDs ("<ms>-<seq>"), so they sort in append
+// order across instances.
+//
+// Keys:
+//
+//	events:orders:log  stream of JSON-encoded order events, trimmed to about maxLen entries
+type OrderEventLog struct {
+	client *redis.Client
+	maxLen int64
+}
+
+// NewOrderEventLog creates a Redis-backed order event log keeping about maxLen events
+func NewOrderEventLog(c *redis.Client, maxLen int) domain.OrderEventLog {
+	return &OrderEventLog{client: c, maxLen: int64(maxLen)}
+}
+
+func (l *OrderEventLog) Append(ctx context.Context, event *domain.OrderEvent) (string, error) {
+	data, err := encodeOrderEvent(event)
+	if err != nil {
+		return "", err
+	}
+	id, err := l.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: orderEventLogKey,
+		MaxLen: l.maxLen,
+		Approx: true,
+		Values: map[string]any{"event": data},
+	}).Result()
+	if err != nil {
+		return "", fmt.Errorf("redis xadd failed: %w", err)
+	}
+	return id, nil
+}
+
+func (l *OrderEventLog) After(ctx context.Context, afterID string, limit int, wait time.Duration) ([]*domain.OrderEvent, error) {
+	if afterID != "" {
+		after, ok := parseStreamID(afterID)
+		if !ok {
+			return nil, domain.ErrInvalidEventID
+		}
+		// Trimming drops the oldest entries; if the oldest kept one is newer
+		// than afterID, events the caller has not seen may be gone
+		oldest, err := l.client.XRangeN(ctx, orderEventLogKey, "-", "+", 1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis xrange failed: %w", err)
+		}
+		if len(oldest) > 0 {
+			if first, _ := parseStreamID(oldest[0].ID); first.after(after) {
+				return nil, domain.ErrOrderEventsExpired
+			}
+		}
+	}
+
+	var entries []redis.XMessage
+	if wait > 0 {
+		start := afterID
+		if start == "" {
+			start = "0"
+		}
+		streams, err := l.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{orderEventLogKey, start},
+			Count:   int64(limit),
+			Block:   wait,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("redis xread failed: %w", err)
+		}
+		for _, s := range streams {
+			entries = append(entries, s.Messages...)
+		}
+	} else {
+		start := "-"
+		if afterID != "" {
+			start = "(" + afterID
+		}
+		var err error
+		entries, err = l.client.XRangeN(ctx, orderEventLogKey, start, "+", int64(limit)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis xrange failed: %w", err)
+		}
+	}
+
+	events := make([]*domain.OrderEvent, 0, len(entries))
+	for _, entry := range entries {
+		data, _ := entry.Values["event"].(string)
+		event, err := decodeOrderEvent([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		event.ID = entry.ID
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (l *OrderEventLog) LastID(ctx context.Context) (string, error) {
+	newest, err := l.client.XRevRangeN(ctx, orderEventLogKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("redis xrevrange failed: %w", err)
+	}
+	if len(newest) == 0 {
+		return "", nil
+	}
+	return newest[0].ID, nil
+}
+
+// streamID is a parsed stream entry ID
+type streamID struct {
+	ms, seq uint64
+}
+
+func parseStreamID(id string) (streamID, bool) {
+	msPart, seqPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return streamID{}, false
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+	return streamID{ms: ms, seq: seq}, true
+}
+
+func (a streamID) after(b streamID) bool {
+	return a.ms > b.ms || (a.ms == b.ms && a.seq > b.seq)
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure RequestStatsStore implements domain.RequestStatsStore at compile time
+var _ domain.RequestStatsStore = (*RequestStatsStore)(nil)
+
+// requestStatsTTL keeps a week of history plus a day of margin
+const requestStatsTTL = 8 * 24 * time.Hour
+
+// RequestStatsStore is a Redis implementation of domain.RequestStatsStore
+//
+// Keys:
+//
+//	status:requests:<yyyymmddhh>  hash of requests, errors and latency bucket
+//	                              counts l0..lN, expiring after eight days
+type RequestStatsStore struct {
+	client *redis.Client
+}
+
+// NewRequestStatsStore creates a Redis-backed hourly request stats store
+func NewRequestStatsStore(c *redis.Client) domain.RequestStatsStore {
+	return &RequestStatsStore{client: c}
+}
+
+func requestStatsKey(hour time.Time) string {
+	return "status:requests:" + hour.UTC().Format("2006010215")
+}
+
+func (s *RequestStatsStore) Add(ctx context.Context, stats *domain.RequestStats) error {
+	key := requestStatsKey(stats.Hour)
+
+	pipe := s.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, "requests", stats.Requests)
+	pipe.HIncrBy(ctx, key, "errors", stats.Errors)
+	for i, n := range stats.Latency {
+		if n != 0 {
+			pipe.HIncrBy(ctx, key, "l"+strconv.Itoa(i), n)
+		}
+	}
+	pipe.Expire(ctx, key, requestStatsTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis hincrby failed: %w", err)
+	}
+	return nil
+}
+
+func (s *RequestStatsStore) Range(ctx context.Context, from, to time.Time) ([]*domain.RequestStats, error) {
+	var hours []time.Time
+	for h := from.UTC().Truncate(time.Hour); !h.After(to); h = h.Add(time.Hour) {
+		hours = append(hours, h)
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(hours))
+	for i, h := range hours {
+		cmds[i] = pipe.HGetAll(ctx, requestStatsKey(h))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("redis hgetall failed: %w", err)
+	}
+
+	var result []*domain.RequestStats
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		stats := domain.NewRequestStats(hours[i])
+		stats.Requests, _ = strconv.ParseInt(fields["requests"], 10, 64)
+		stats.Errors, _ = strconv.ParseInt(fields["errors"], 10, 64)
+		for j := range stats.Latency {
+			stats.Latency[j], _ = strconv.ParseInt(fields["l"+strconv.Itoa(j)], 10, 64)
+		}
+		if stats.Requests > 0 {
+			result = append(result, stats)
+		}
+	}
+	return result, nil
+}
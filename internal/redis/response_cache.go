@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure ResponseCache implements domain.ResponseCache at compile time
+var _ domain.ResponseCache = (*ResponseCache)(nil)
+
+// ResponseCache is a Redis implementation of domain.ResponseCache
+//
+// Keys:
+//
+//	respcache:entry:<key>  JSON domain.CachedResponse, expiring after its TTL
+//	respcache:tag:<tag>    version counter of a tag, bumped by INCR on writes
+type ResponseCache struct {
+	client *redis.Client
+}
+
+// NewResponseCache creates a Redis-backed response cache
+func NewResponseCache(c *redis.Client) domain.ResponseCache {
+	return &ResponseCache{client: c}
+}
+
+func responseCacheEntryKey(key string) string {
+	return "respcache:entry:" + key
+}
+
+func responseCacheTagKey(tag string) string {
+	return "respcache:tag:" + tag
+}
+
+// Get reads the entry and its tags' versions in one round trip
+func (c *ResponseCache) Get(ctx context.Context, key string, tags []string) (*domain.CachedResponse, string, error) {
+	pipe := c.client.Pipeline()
+	entry := pipe.Get(ctx, responseCacheEntryKey(key))
+	var versions *redis.SliceCmd
+	if len(tags) > 0 {
+		tagKeys := make([]string, len(tags))
+		for i, tag := range tags {
+			tagKeys[i] = responseCacheTagKey(tag)
+		}
+		versions = pipe.MGet(ctx, tagKeys...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, "", fmt.Errorf("redis pipeline failed: %w", err)
+	}
+
+	var version string
+	if versions != nil {
+		parts := make([]string, len(tags))
+		for i, v := range versions.Val() {
+			if s, ok := v.(string); ok {
+				parts[i] = s
+			} else {
+				parts[i] = "0"
+			}
+		}
+		version = strings.Join(parts, ".")
+	}
+
+	data, err := entry.Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, version, domain.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, version, fmt.Errorf("redis get failed: %w", err)
+	}
+	var resp domain.CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, version, fmt.Errorf("failed to unmarshal cached response: %w", err)
+	}
+	if resp.Version != version {
+		return nil, version, domain.ErrCacheMiss
+	}
+	return &resp, version, nil
+}
+
+func (c *ResponseCache) Set(ctx context.Context, key string, resp *domain.CachedResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached response: %w", err)
+	}
+	if err := c.client.Set(ctx, responseCacheEntryKey(key), data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+func (c *ResponseCache) Invalidate(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	pipe := c.client.Pipeline()
+	for _, tag := range tags {
+		pipe.Incr(ctx, responseCacheTagKey(tag))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis incr failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/resilience"
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheOption configures a Redis cache
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	retry resilience.RetryPolicy
+}
+
+func newCacheOptions(opts []CacheOption) cacheOptions {
+	var o cacheOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCacheRetry retries cache commands failing with transient errors under
+// policy: timeouts, dropped connections and a server still loading or
+// failing over. Cache commands are idempotent, so a timed out command that
+// did run is harmless to repeat. A nil policy.Retryable is replaced by
+// IsTransient.
+func WithCacheRetry(policy resilience.RetryPolicy) CacheOption {
+	return func(o *cacheOptions) {
+		if policy.Retryable == nil {
+			policy.Retryable = IsTransient
+		}
+		o.retry = policy
+	}
+}
+
+// transientReplies are the error replies of a server that will answer again
+// shortly
+var transientReplies = []string{"LOADING", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN"}
+
+// IsTransient reports whether err is a Redis failure worth retrying
+func IsTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, redis.ErrPoolTimeout) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var reply redis.Error
+	if errors.As(err, &reply) && !errors.Is(err, redis.Nil) {
+		for _, prefix := range transientReplies {
+			if strings.HasPrefix(reply.Error(), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
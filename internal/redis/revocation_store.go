@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure RevocationStore implements domain.TokenRevocationStore at compile time
+var _ domain.TokenRevocationStore = (*RevocationStore)(nil)
+
+// RevocationStore is a Redis implementation of domain.TokenRevocationStore
+//
+// Keys:
+//
+//	revoked:token:<jti>    "1"                      expires with the token
+//	revoked:user:<userID>  unix time of revocation  expires after the max token lifetime
+type RevocationStore struct {
+	client *redis.Client
+}
+
+// NewRevocationStore creates a Redis-backed token denylist
+func NewRevocationStore(c *redis.Client) domain.TokenRevocationStore {
+	return &RevocationStore{client: c}
+}
+
+func (s *RevocationStore) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		// Already expired; nothing to denylist
+		return nil
+	}
+
+	key := fmt.Sprintf("revoked:token:%s", tokenID)
+	if err := s.client.Set(ctx, key, "1", ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+func (s *RevocationStore) RevokeAllForUser(ctx context.Context, userID string, before time.Time, ttl time.Duration) error {
+	key := fmt.Sprintf("revoked:user:%s", userID)
+	if err := s.client.Set(ctx, key, before.Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+func (s *RevocationStore) IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error) {
+	// One round trip for both the token and the user-wide entry
+	values, err := s.client.MGet(ctx,
+		fmt.Sprintf("revoked:token:%s", tokenID),
+		fmt.Sprintf("revoked:user:%s", userID),
+	).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis mget failed: %w", err)
+	}
+
+	if values[0] != nil {
+		return true, nil
+	}
+
+	if raw, ok := values[1].(string); ok {
+		before, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid user revocation entry: %w", err)
+		}
+		// Token iat has second precision, so tokens issued within the
+		// revocation's second are revoked too
+		return issuedAt.Unix() <= before, nil
+	}
+
+	return false, nil
+}
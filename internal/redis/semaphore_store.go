@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Ensure SemaphoreStore implements domain.SemaphoreStore at compile time
+var _ domain.SemaphoreStore = (*SemaphoreStore)(nil)
+
+// SemaphoreStore is a Redis implementation of domain.SemaphoreStore
+//
+// Keys:
+//
+//	semaphore:<name>  sorted set of holders scored by lease expiry (unix ms)
+//
+// Scripts read the clock with TIME so leases don't depend on replica clocks.
+type SemaphoreStore struct {
+	client *redis.Client
+}
+
+// NewSemaphoreStore creates a Redis-backed semaphore store
+func NewSemaphoreStore(c *redis.Client) domain.SemaphoreStore {
+	return &SemaphoreStore{client: c}
+}
+
+// semaphorePrelude is shared by both scripts: it reads the server time in ms,
+// drops expired leases and defines lease(), which (re)records the holder's
+// lease and keeps the key alive at least as long
+const semaphorePrelude = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local ttl = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+local function lease()
+	redis.call('ZADD', KEYS[1], now + ttl, ARGV[1])
+	if redis.call('PTTL', KEYS[1]) < ttl then
+		redis.call('PEXPIRE', KEYS[1], ttl)
+	end
+end
+`
+
+// acquireScript: ARGV = holder, ttl ms, limit
+var acquireScript = redis.NewScript(semaphorePrelude + `
+if redis.call('ZSCORE', KEYS[1], ARGV[1]) or redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[3]) then
+	lease()
+	return 1
+end
+return 0
+`)
+
+// refreshScript: ARGV = holder, ttl ms
+var refreshScript = redis.NewScript(semaphorePrelude + `
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+lease()
+return 1
+`)
+
+func semaphoreKey(name string) string {
+	return fmt.Sprintf("semaphore:%s", name)
+}
+
+func (s *SemaphoreStore) TryAcquire(ctx context.Context, name, holder string, limit int, ttl time.Duration) (bool, error) {
+	ok, err := acquireScript.Run(ctx, s.client, []string{semaphoreKey(name)},
+		holder, ttl.Milliseconds(), limit).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis semaphore acquire failed: %w", err)
+	}
+	return ok == 1, nil
+}
+
+func (s *SemaphoreStore) Refresh(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	ok, err := refreshScript.Run(ctx, s.client, []string{semaphoreKey(name)},
+		holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis semaphore refresh failed: %w", err)
+	}
+	return ok == 1, nil
+}
+
+func (s *SemaphoreStore) Release(ctx context.Context, name, holder string) error {
+	if err := s.client.ZRem(ctx, semaphoreKey(name), holder).Err(); err != nil {
+		return fmt.Errorf("redis zrem failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// tracingHook logs Redis commands under the request that issued them
+type tracingHook struct {
+	requestID func(context.Context) string
+	logger    *logger.Logger
+}
+
+// NewTracingHook returns a client hook logging commands and pipelines with
+// the ID of the request that issued them, read from the command's context
+// by requestID, so a command can be matched to its line in the access log
+// and to the queries the request made (install with client.AddHook, before
+// any hook whose failures should be logged). Failed commands are logged at
+// warn with or without an ID; the others at debug, and only when they carry
+// one. Command names are logged, never their arguments.
+func NewTracingHook(requestID func(context.Context) string, logg *logger.Logger) redis.Hook {
+	return tracingHook{requestID: requestID, logger: logg}
+}
+
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.log(ctx, "redis command", start, err, "command", cmd.Name())
+		return err
+	}
+}
+
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		names := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			names[i] = cmd.Name()
+		}
+		h.log(ctx, "redis pipeline", start, err, "commands", names)
+		return err
+	}
+}
+
+// log records a command that took since start and returned err
+func (h tracingHook) log(ctx context.Context, msg string, start time.Time, err error, args ...any) {
+	requestID := h.requestID(ctx)
+	args = append(args,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"request_id", requestID)
+
+	// A missing key is an answer, and a caller giving up is not Redis failing
+	if err != nil && !errors.Is(err, redis.Nil) && !errors.Is(err, context.Canceled) {
+		h.logger.Warn(msg+" failed", append(args, "error", err.Error())...)
+		return
+	}
+	if requestID != "" {
+		h.logger.Debug(msg, args...)
+	}
+}
@@ -0,0 +1,173 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type requestIDKey struct{}
+
+// newTracedClient returns a client on a fresh miniredis with the tracing
+// hook installed, reading request IDs from requestIDKey and logging JSON at
+// debug into the returned buffer
+func newTracedClient(t *testing.T) (*redis.Client, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr(), PoolSize: 1, MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	// Connect first, so the handshake commands miniredis refuses aren't traced
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	client.AddHook(NewTracingHook(func(ctx context.Context) string {
+		id, _ := ctx.Value(requestIDKey{}).(string)
+		return id
+	}, logger.NewWithOptions("debug", &buf, true)))
+	return client, &buf
+}
+
+// logRecords decodes the JSON log lines in buf, emptying it
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decoding log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	buf.Reset()
+	return records
+}
+
+func TestTracingHookCommands(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		requestID string
+		ctx       context.Context
+		run       func(ctx context.Context, client *redis.Client) error
+		wantLevel string // "" for no record
+		wantMsg   string
+		wantCmd   string
+		wantError string // Contained in the record's error
+	}{
+		{"command of a request", "req-1", nil, func(ctx context.Context, c *redis.Client) error {
+			return c.Set(ctx, "k", "secret-value", 0).Err()
+		}, "DEBUG", "redis command", "set", ""},
+		{"command outside a request", "", nil, func(ctx context.Context, c *redis.Client) error {
+			return c.Set(ctx, "k", "v", 0).Err()
+		}, "", "", "", ""},
+		// A missing key is an answer
+		{"missing key", "req-1", nil, func(ctx context.Context, c *redis.Client) error {
+			return c.Get(ctx, "missing").Err()
+		}, "DEBUG", "redis command", "get", ""},
+		{"missing key outside a request", "", nil, func(ctx context.Context, c *redis.Client) error {
+			return c.Get(ctx, "missing").Err()
+		}, "", "", "", ""},
+		{"failed command of a request", "req-1", nil, func(ctx context.Context, c *redis.Client) error {
+			c.Set(context.Background(), "k", "v", 0)
+			return c.LPush(ctx, "k", "x").Err()
+		}, "WARN", "redis command failed", "lpush", "WRONGTYPE"},
+		{"failed command outside a request", "", nil, func(ctx context.Context, c *redis.Client) error {
+			c.Set(context.Background(), "k", "v", 0)
+			return c.Incr(ctx, "k").Err()
+		}, "WARN", "redis command failed", "incr", "not an integer"},
+		// A caller giving up is not Redis failing
+		{"cancelled command outside a request", "", cancelled, func(ctx context.Context, c *redis.Client) error {
+			return c.Get(ctx, "k").Err()
+		}, "", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, buf := newTracedClient(t)
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if tt.requestID != "" {
+				ctx = context.WithValue(ctx, requestIDKey{}, tt.requestID)
+			}
+			// Setup commands run outside the request, untraced
+			if err := tt.run(ctx, client); err != nil && err != redis.Nil && tt.wantError == "" && tt.ctx == nil {
+				t.Fatalf("command failed: %v", err)
+			}
+
+			records := logRecords(t, buf)
+			if tt.wantLevel == "" {
+				if len(records) != 0 {
+					t.Errorf("records = %v, want none", records)
+				}
+				return
+			}
+			if len(records) != 1 {
+				t.Fatalf("records = %v, want one for %s", records, tt.wantCmd)
+			}
+			record := records[0]
+			if record["level"] != tt.wantLevel || record["msg"] != tt.wantMsg || record["command"] != tt.wantCmd ||
+				record["request_id"] != tt.requestID {
+				t.Errorf("record = %v, want %s %q of %s for request %q", record, tt.wantLevel, tt.wantMsg, tt.wantCmd, tt.requestID)
+			}
+			if _, ok := record["duration_ms"].(float64); !ok {
+				t.Errorf("duration_ms = %v, want a number", record["duration_ms"])
+			}
+			if errMsg, _ := record["error"].(string); !strings.Contains(errMsg, tt.wantError) || (tt.wantError == "") != (errMsg == "") {
+				t.Errorf("error = %q, want %q", errMsg, tt.wantError)
+			}
+			if strings.Contains(buf.String(), "secret-value") {
+				t.Errorf("record %v logs the command's arguments", record)
+			}
+		})
+	}
+}
+
+func TestTracingHookPipelines(t *testing.T) {
+	client, buf := newTracedClient(t)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "n", 1, 0)
+		pipe.Incr(ctx, "n")
+		pipe.Get(ctx, "missing")
+		return nil
+	})
+	if err != redis.Nil {
+		t.Fatalf("Pipelined = %v, want the missing key's redis.Nil", err)
+	}
+	records := logRecords(t, buf)
+	if len(records) != 1 {
+		t.Fatalf("records = %v, want one for the pipeline", records)
+	}
+	names, _ := records[0]["commands"].([]any)
+	if records[0]["level"] != "DEBUG" || records[0]["msg"] != "redis pipeline" || records[0]["request_id"] != "req-1" ||
+		!slices.Equal(names, []any{"set", "incr", "get"}) {
+		t.Errorf("record = %v, want the pipeline's commands under req-1", records[0])
+	}
+
+	// A failing command fails the pipeline, logged even outside a request
+	background := context.Background()
+	_, err = client.Pipelined(background, func(pipe redis.Pipeliner) error {
+		pipe.Set(background, "s", "text", 0)
+		pipe.Incr(background, "s")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Pipelined succeeded incrementing a string")
+	}
+	records = logRecords(t, buf)
+	if len(records) != 1 || records[0]["level"] != "WARN" || records[0]["msg"] != "redis pipeline failed" ||
+		records[0]["request_id"] != "" || !strings.Contains(records[0]["error"].(string), "not an integer") {
+		t.Errorf("records = %v, want the failed pipeline at warn", records)
+	}
+}
@@ -7,30 +7,41 @@ import (
 	"time"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/resilience"
 	"github.com/redis/go-redis/v9"
 )
 
 // Ensure UserCache implements domain.UserCache at compile time
 var _ domain.UserCache = (*UserCache)(nil)
 
+// userKeyPrefix namespaces cached users by their encoding. Bump it whenever
+// domain.User gains a field: entries left by older replicas would decode
+// with it zeroed (v2 came with Version, which read back as 0 and failed
+// every If-Match until the entry expired).
+const userKeyPrefix = "users:v2:"
+
 // UserCache is a Redis implementation of domain.UserCache
 type UserCache struct {
 	client *redis.Client
 	ttl    time.Duration
+	retry  resilience.RetryPolicy
 }
 
 // NewUserCache creates a Redis-backed user cache
-func NewUserCache(c *redis.Client) domain.UserCache {
+func NewUserCache(c *redis.Client, opts ...CacheOption) domain.UserCache {
 	return &UserCache{
 		client: c,
 		ttl:    5 * time.Minute,
+		retry:  newCacheOptions(opts).retry,
 	}
 }
 
 func (c *UserCache) Get(ctx context.Context, userID string) (*domain.User, error) {
-	key := fmt.Sprintf("user:%s", userID)
+	key := userKeyPrefix + userID
 
-	data, err := c.client.Get(ctx, key).Result()
+	data, err := resilience.Retry(ctx, c.retry, func(ctx context.Context) (string, error) {
+		return c.client.Get(ctx, key).Result()
+	})
 	if err != nil {
 		if err == redis.Nil {
 			return nil, domain.ErrCacheMiss
@@ -47,14 +58,17 @@ func (c *UserCache) Get(ctx context.Context, userID string) (*domain.User, error
 }
 
 func (c *UserCache) Set(ctx context.Context, user *domain.User) error {
-	key := fmt.Sprintf("user:%s", user.ID)
+	key := userKeyPrefix + user.ID
 
 	data, err := json.Marshal(user)
 	if err != nil {
 		return fmt.Errorf("failed to marshal user: %w", err)
 	}
 
-	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+	err = c.retry.Do(ctx, func(ctx context.Context) error {
+		return c.client.Set(ctx, key, data, c.ttl).Err()
+	})
+	if err != nil {
 		return fmt.Errorf("redis set failed: %w", err)
 	}
 
@@ -62,6 +76,8 @@ func (c *UserCache) Set(ctx context.Context, user *domain.User) error {
 }
 
 func (c *UserCache) Invalidate(ctx context.Context, userID string) error {
-	key := fmt.Sprintf("user:%s", userID)
-	return c.client.Del(ctx, key).Err()
+	key := userKeyPrefix + userID
+	return c.retry.Do(ctx, func(ctx context.Context) error {
+		return c.client.Del(ctx, key).Err()
+	})
 }
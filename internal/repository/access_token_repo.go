@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// accessTokenRepo is the PostgreSQL implementation of domain.AccessTokenRepository
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE personal_access_tokens (
+//	    id           TEXT PRIMARY KEY,
+//	    user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+//	    name         TEXT NOT NULL,
+//	    scopes       TEXT[] NOT NULL,
+//	    token_hash   TEXT NOT NULL UNIQUE,
+//	    expires_at   TIMESTAMPTZ NOT NULL,
+//	    last_used_at TIMESTAMPTZ,
+//	    created_at   TIMESTAMPTZ NOT NULL,
+//	    revoked_at   TIMESTAMPTZ
+//	);
+//	CREATE INDEX personal_access_tokens_user_id_idx ON personal_access_tokens (user_id);
+type accessTokenRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewAccessTokenRepo creates a Postgres-backed personal access token repository
+func NewAccessTokenRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.AccessTokenRepository {
+	return &accessTokenRepo{db: newPool(db, opts), logg: logg}
+}
+
+const accessTokenColumns = "id, user_id, name, scopes, token_hash, expires_at, last_used_at, created_at, revoked_at"
+
+// Create inserts a new token
+func (r *accessTokenRepo) Create(ctx context.Context, t *domain.PersonalAccessToken) error {
+	query := "INSERT INTO personal_access_tokens (" + accessTokenColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+
+	_, err := r.db.Exec(ctx, query,
+		t.ID,
+		t.UserID,
+		t.Name,
+		t.Scopes,
+		t.TokenHash,
+		t.ExpiresAt,
+		t.LastUsedAt,
+		t.CreatedAt,
+		t.RevokedAt,
+	)
+	if err != nil {
+		r.logg.Error("failed to create access token", "error", err, "token_id", t.ID, "user_id", t.UserID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// GetByHash fetches a token by the hash of its secret
+func (r *accessTokenRepo) GetByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	query := "SELECT " + accessTokenColumns + " FROM personal_access_tokens WHERE token_hash = $1"
+
+	t, err := scanAccessToken(r.db.QueryRow(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrAccessTokenNotFound
+		}
+		r.logg.Error("failed to get access token", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return t, nil
+}
+
+// ListByUser retrieves all of a user's tokens, newest first
+func (r *accessTokenRepo) ListByUser(ctx context.Context, userID string) ([]*domain.PersonalAccessToken, error) {
+	query := "SELECT " + accessTokenColumns + " FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC"
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		r.logg.Error("failed to list access tokens", "error", err, "user_id", userID)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var tokens []*domain.PersonalAccessToken
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			r.logg.Error("failed to scan access token row", "error", err)
+			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logg.Error("error iterating access token rows", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return tokens, nil
+}
+
+// Revoke marks a token revoked (idempotent for already revoked tokens)
+func (r *accessTokenRepo) Revoke(ctx context.Context, userID, tokenID string, at time.Time) error {
+	query := "UPDATE personal_access_tokens SET revoked_at = COALESCE(revoked_at, $3) WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.Exec(ctx, query, tokenID, userID, at)
+	if err != nil {
+		r.logg.Error("failed to revoke access token", "error", err, "token_id", tokenID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrAccessTokenNotFound
+	}
+
+	return nil
+}
+
+// RevokeAllForUser marks every token the user created up to at revoked
+func (r *accessTokenRepo) RevokeAllForUser(ctx context.Context, userID string, at time.Time) error {
+	query := "UPDATE personal_access_tokens SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL AND created_at <= $2"
+
+	if _, err := r.db.Exec(ctx, query, userID, at); err != nil {
+		r.logg.Error("failed to revoke user access tokens", "error", err, "user_id", userID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// TouchLastUsed records when a token was last used
+func (r *accessTokenRepo) TouchLastUsed(ctx context.Context, tokenID string, at time.Time) error {
+	query := "UPDATE personal_access_tokens SET last_used_at = $2 WHERE id = $1"
+
+	if _, err := r.db.Exec(ctx, query, tokenID, at); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// scanAccessToken scans a row selected with accessTokenColumns
+func scanAccessToken(row pgx.Row) (*domain.PersonalAccessToken, error) {
+	var t domain.PersonalAccessToken
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.Scopes,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.CreatedAt,
+		&t.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
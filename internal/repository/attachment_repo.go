@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// attachmentRepo is the PostgreSQL implementation of domain.AttachmentRepository
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE attachments (
+//	    id           TEXT PRIMARY KEY,
+//	    owner_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+//	    key          TEXT NOT NULL UNIQUE,
+//	    filename     TEXT NOT NULL,
+//	    content_type TEXT NOT NULL,
+//	    size         BIGINT NOT NULL,
+//	    scan_status  TEXT NOT NULL DEFAULT 'pending',
+//	    scan_detail  TEXT NOT NULL DEFAULT '',
+//	    scanned_at   TIMESTAMPTZ,
+//	    created_at   TIMESTAMPTZ NOT NULL,
+//	    updated_at   TIMESTAMPTZ NOT NULL,
+//	    uploaded_at     TIMESTAMPTZ,
+//	    etag            TEXT NOT NULL DEFAULT '',
+//	    checksum_sha256 TEXT NOT NULL DEFAULT '',
+//	    upload_id       TEXT NOT NULL DEFAULT ''
+//	);
+//	CREATE INDEX attachments_owner_id_idx ON attachments (owner_id, created_at DESC);
+type attachmentRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewAttachmentRepo creates a Postgres-backed attachment repository
+func NewAttachmentRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.AttachmentRepository {
+	return &attachmentRepo{db: newPool(db, opts), logg: logg}
+}
+
+const attachmentColumns = "id, owner_id, key, filename, content_type, size, scan_status, scan_detail, scanned_at, created_at, updated_at, uploaded_at, etag, checksum_sha256, upload_id"
+
+// Create inserts a new attachment
+func (r *attachmentRepo) Create(ctx context.Context, a *domain.Attachment) error {
+	query := "INSERT INTO attachments (" + attachmentColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)"
+
+	_, err := r.db.Exec(ctx, query,
+		a.ID,
+		a.OwnerID,
+		a.Key,
+		a.Filename,
+		a.ContentType,
+		a.Size,
+		a.ScanStatus,
+		a.ScanDetail,
+		a.ScannedAt,
+		a.CreatedAt,
+		a.UpdatedAt,
+		a.UploadedAt,
+		a.ETag,
+		a.ChecksumSHA256,
+		a.UploadID,
+	)
+	if err != nil {
+		r.logg.Error("failed to create attachment", "error", err, "attachment_id", a.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// GetByID fetches an attachment by ID
+func (r *attachmentRepo) GetByID(ctx context.Context, id string) (*domain.Attachment, error) {
+	return r.get(ctx, "id", id)
+}
+
+// GetByKey fetches an attachment by its blob store key
+func (r *attachmentRepo) GetByKey(ctx context.Context, key string) (*domain.Attachment, error) {
+	return r.get(ctx, "key", key)
+}
+
+func (r *attachmentRepo) get(ctx context.Context, column, value string) (*domain.Attachment, error) {
+	query := "SELECT " + attachmentColumns + " FROM attachments WHERE " + column + " = $1"
+
+	a, err := scanAttachment(r.db.QueryRow(ctx, query, value))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrAttachmentNotFound
+		}
+		r.logg.Error("failed to get attachment", "error", err, column, value)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return a, nil
+}
+
+// ListByOwner retrieves a user's attachments, newest first
+func (r *attachmentRepo) ListByOwner(ctx context.Context, ownerID string, limit, offset int) ([]*domain.Attachment, error) {
+	query := "SELECT " + attachmentColumns + " FROM attachments WHERE owner_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
+
+	rows, err := r.db.Query(ctx, query, ownerID, limit, offset)
+	if err != nil {
+		r.logg.Error("failed to list attachments", "error", err, "owner_id", ownerID)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var attachments []*domain.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			r.logg.Error("failed to scan attachment row", "error", err)
+			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logg.Error("error iterating attachment rows", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return attachments, nil
+}
+
+// Update saves an attachment's mutable fields
+func (r *attachmentRepo) Update(ctx context.Context, a *domain.Attachment) error {
+	query := "UPDATE attachments SET content_type = $2, size = $3, scan_status = $4, scan_detail = $5, scanned_at = $6, updated_at = $7, uploaded_at = $8, etag = $9, checksum_sha256 = $10, upload_id = $11 WHERE id = $1"
+
+	result, err := r.db.Exec(ctx, query,
+		a.ID,
+		a.ContentType,
+		a.Size,
+		a.ScanStatus,
+		a.ScanDetail,
+		a.ScannedAt,
+		a.UpdatedAt,
+		a.UploadedAt,
+		a.ETag,
+		a.ChecksumSHA256,
+		a.UploadID,
+	)
+	if err != nil {
+		r.logg.Error("failed to update attachment", "error", err, "attachment_id", a.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+// Delete removes an attachment's metadata; its object is the caller's to delete
+func (r *attachmentRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM attachments WHERE id = $1", id)
+	if err != nil {
+		r.logg.Error("failed to delete attachment", "error", err, "attachment_id", id)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+// scanAttachment scans a row selected with attachmentColumns
+func scanAttachment(row pgx.Row) (*domain.Attachment, error) {
+	var a domain.Attachment
+	err := row.Scan(
+		&a.ID,
+		&a.OwnerID,
+		&a.Key,
+		&a.Filename,
+		&a.ContentType,
+		&a.Size,
+		&a.ScanStatus,
+		&a.ScanDetail,
+		&a.ScannedAt,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		&a.UploadedAt,
+		&a.ETag,
+		&a.ChecksumSHA256,
+		&a.UploadID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// deadLetterRepo is the PostgreSQL implementation of domain.DeadLetterQueue
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE dead_letters (
+//	    id           BIGSERIAL PRIMARY KEY,
+//	    source       TEXT NOT NULL,
+//	    message_type TEXT NOT NULL DEFAULT '',
+//	    message_id   TEXT NOT NULL DEFAULT '',
+//	    payload      BYTEA NOT NULL,
+//	    attributes   JSONB NOT NULL DEFAULT '{}',
+//	    error        TEXT NOT NULL DEFAULT '',
+//	    attempts     INTEGER NOT NULL DEFAULT 0,
+//	    failed_at    TIMESTAMPTZ NOT NULL,
+//	    replays      INTEGER NOT NULL DEFAULT 0,
+//	    replayed_at  TIMESTAMPTZ,
+//	    replay_error TEXT NOT NULL DEFAULT ''
+//	);
+type deadLetterRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewDeadLetterRepo creates a Postgres-backed dead letter queue
+func NewDeadLetterRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.DeadLetterQueue {
+	return &deadLetterRepo{db: newPool(db, opts), logg: logg}
+}
+
+const deadLetterColumns = "id, source, message_type, message_id, payload, attributes, error, attempts, failed_at, replays, replayed_at, replay_error"
+
+// Add inserts a dead letter and sets its ID
+func (r *deadLetterRepo) Add(ctx context.Context, d *domain.DeadLetter) error {
+	query := `INSERT INTO dead_letters (source, message_type, message_id, payload, attributes, error, attempts, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	attributes, err := json.Marshal(d.Attributes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	if d.Attributes == nil {
+		attributes = []byte("{}")
+	}
+	err = r.db.QueryRow(ctx, query, d.Source, d.Type, d.MessageID, d.Payload, attributes, d.Error, d.Attempts, d.FailedAt).Scan(&d.ID)
+	if err != nil {
+		r.logg.Error("failed to add dead letter", "error", err, "source", d.Source, "message_id", d.MessageID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// Get fetches a dead letter by ID
+func (r *deadLetterRepo) Get(ctx context.Context, id int64) (*domain.DeadLetter, error) {
+	d, err := scanDeadLetter(r.db.QueryRow(ctx, "SELECT "+deadLetterColumns+" FROM dead_letters WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrDeadLetterNotFound
+		}
+		r.logg.Error("failed to get dead letter", "error", err, "id", id)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return d, nil
+}
+
+// List fetches the dead letters matching filter, newest first
+func (r *deadLetterRepo) List(ctx context.Context, filter domain.DeadLetterFilter) ([]*domain.DeadLetter, error) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.Source != "" {
+		where = append(where, "source = "+arg(filter.Source))
+	}
+	if filter.Type != "" {
+		where = append(where, "message_type = "+arg(filter.Type))
+	}
+	if filter.Replayed != nil {
+		if *filter.Replayed {
+			where = append(where, "replayed_at IS NOT NULL")
+		} else {
+			where = append(where, "replayed_at IS NULL")
+		}
+	}
+
+	query := "SELECT " + deadLetterColumns + " FROM dead_letters"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY failed_at DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + arg(filter.Offset)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logg.Error("failed to list dead letters", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	letters, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.DeadLetter, error) {
+		return scanDeadLetter(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return letters, nil
+}
+
+// Update stores the outcome of a replay
+func (r *deadLetterRepo) Update(ctx context.Context, d *domain.DeadLetter) error {
+	query := "UPDATE dead_letters SET replays = $2, replayed_at = $3, replay_error = $4 WHERE id = $1"
+
+	result, err := r.db.Exec(ctx, query, d.ID, d.Replays, d.ReplayedAt, d.ReplayError)
+	if err != nil {
+		r.logg.Error("failed to update dead letter", "error", err, "id", d.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// Delete removes a dead letter
+func (r *deadLetterRepo) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM dead_letters WHERE id = $1", id)
+	if err != nil {
+		r.logg.Error("failed to delete dead letter", "error", err, "id", id)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// Purge deletes the dead letters that failed before cutoff
+func (r *deadLetterRepo) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	result, err := r.db.Exec(ctx, "DELETE FROM dead_letters WHERE failed_at < $1", cutoff)
+	if err != nil {
+		r.logg.Error("failed to purge dead letters", "error", err)
+		return 0, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// scanDeadLetter reads a row of deadLetterColumns
+func scanDeadLetter(row pgx.Row) (*domain.DeadLetter, error) {
+	var (
+		d          domain.DeadLetter
+		attributes []byte
+	)
+	if err := row.Scan(&d.ID, &d.Source, &d.Type, &d.MessageID, &d.Payload, &attributes, &d.Error, &d.Attempts,
+		&d.FailedAt, &d.Replays, &d.ReplayedAt, &d.ReplayError); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(attributes, &d.Attributes); err != nil {
+		return nil, fmt.Errorf("decoding dead letter attributes: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// inventoryRepo is the PostgreSQL implementation of domain.Inventory
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE inventory (
+//	    product_id TEXT PRIMARY KEY,
+//	    available  INTEGER NOT NULL CHECK (available >= 0),
+//	    updated_at TIMESTAMPTZ NOT NULL
+//	);
+//	CREATE TABLE inventory_reservations (
+//	    reservation_id TEXT NOT NULL,
+//	    product_id     TEXT NOT NULL,
+//	    quantity       INTEGER NOT NULL,
+//	    created_at     TIMESTAMPTZ NOT NULL,
+//	    released_at    TIMESTAMPTZ,
+//	    PRIMARY KEY (reservation_id, product_id)
+//	);
+type inventoryRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewInventoryRepo creates a Postgres-backed inventory
+func NewInventoryRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.Inventory {
+	return &inventoryRepo{db: newPool(db, opts), logg: logg}
+}
+
+// Reserve takes the reserved quantities out of stock and records them, in
+// one transaction; a product short of stock rolls it all back
+func (r *inventoryRepo) Reserve(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	wanted := make(map[string]int)
+	var products []string
+	for _, item := range items {
+		if _, ok := wanted[item.ProductID]; !ok {
+			products = append(products, item.ProductID)
+		}
+		wanted[item.ProductID] += item.Quantity
+	}
+
+	// Products in a fixed order, so concurrent reservations lock rows alike
+	slices.Sort(products)
+
+	var short error
+	err := r.db.inTx(ctx, func(tx pgx.Tx) error {
+		short = nil
+		var reserved bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM inventory_reservations WHERE reservation_id = $1)",
+			reservationID).Scan(&reserved); err != nil {
+			return err
+		}
+		if reserved {
+			return nil
+		}
+		for _, productID := range products {
+			quantity := wanted[productID]
+			var available int
+			err := tx.QueryRow(ctx, "SELECT available FROM inventory WHERE product_id = $1 FOR UPDATE", productID).Scan(&available)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue // Not tracked
+			}
+			if err != nil {
+				return err
+			}
+			if available < quantity {
+				short = fmt.Errorf("%w: %s has %d, %d wanted", domain.ErrInsufficientStock, productID, available, quantity)
+				return short
+			}
+			if _, err := tx.Exec(ctx, "UPDATE inventory SET available = available - $2, updated_at = now() WHERE product_id = $1",
+				productID, quantity); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `INSERT INTO inventory_reservations (reservation_id, product_id, quantity, created_at)
+				VALUES ($1, $2, $3, now())`, reservationID, productID, quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if short != nil {
+		return short
+	}
+	if err != nil {
+		r.logg.Error("failed to reserve stock", "error", err, "reservation_id", reservationID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// Release marks a reservation released and puts its quantities back, in
+// one transaction
+func (r *inventoryRepo) Release(ctx context.Context, reservationID string) error {
+	err := r.db.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `UPDATE inventory_reservations SET released_at = now()
+			WHERE reservation_id = $1 AND released_at IS NULL RETURNING product_id, quantity`, reservationID)
+		if err != nil {
+			return err
+		}
+		type line struct {
+			productID string
+			quantity  int
+		}
+		lines, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (line, error) {
+			var l line
+			err := row.Scan(&l.productID, &l.quantity)
+			return l, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, l := range lines {
+			if _, err := tx.Exec(ctx, "UPDATE inventory SET available = available + $2, updated_at = now() WHERE product_id = $1",
+				l.productID, l.quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.logg.Error("failed to release stock", "error", err, "reservation_id", reservationID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// Stock fetches the available quantity of a product
+func (r *inventoryRepo) Stock(ctx context.Context, productID string) (int, bool, error) {
+	var available int
+	err := r.db.QueryRow(ctx, "SELECT available FROM inventory WHERE product_id = $1", productID).Scan(&available)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		r.logg.Error("failed to get stock", "error", err, "product_id", productID)
+		return 0, false, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return available, true, nil
+}
+
+// SetStock inserts or replaces the available quantity of a product
+func (r *inventoryRepo) SetStock(ctx context.Context, productID string, available int) error {
+	query := `INSERT INTO inventory (product_id, available, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (product_id) DO UPDATE SET available = EXCLUDED.available, updated_at = EXCLUDED.updated_at`
+
+	if _, err := r.db.Exec(ctx, query, productID, available, time.Now().UTC()); err != nil {
+		r.logg.Error("failed to set stock", "error", err, "product_id", productID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return nil
+}
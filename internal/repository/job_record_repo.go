@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// jobRecordRepo is the PostgreSQL implementation of domain.JobRecordRepository
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE jobs (
+//	    id          TEXT PRIMARY KEY,
+//	    type        TEXT NOT NULL,
+//	    priority    TEXT NOT NULL,
+//	    user_id     TEXT REFERENCES users(id) ON DELETE SET NULL,
+//	    state       TEXT NOT NULL,
+//	    progress    INTEGER NOT NULL DEFAULT 0,
+//	    result      TEXT NOT NULL DEFAULT '',
+//	    error       TEXT NOT NULL DEFAULT '',
+//	    attempts    INTEGER NOT NULL DEFAULT 0,
+//	    created_at  TIMESTAMPTZ NOT NULL,
+//	    updated_at  TIMESTAMPTZ NOT NULL,
+//	    started_at  TIMESTAMPTZ,
+//	    finished_at TIMESTAMPTZ
+//	);
+type jobRecordRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewJobRecordRepo creates a Postgres-backed job status repository
+func NewJobRecordRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.JobRecordRepository {
+	return &jobRecordRepo{db: newPool(db, opts), logg: logg}
+}
+
+// System jobs have no owner; user_id is NULL rather than ”
+const jobRecordColumns = "id, type, priority, COALESCE(user_id, ''), state, progress, result, error, attempts, created_at, updated_at, started_at, finished_at"
+
+// Create inserts a new job record
+func (r *jobRecordRepo) Create(ctx context.Context, j *domain.JobRecord) error {
+	query := `INSERT INTO jobs (id, type, priority, user_id, state, progress, result, error, attempts, created_at, updated_at, started_at, finished_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err := r.db.Exec(ctx, query,
+		j.ID,
+		j.Type,
+		j.Priority,
+		j.UserID,
+		j.State,
+		j.Progress,
+		j.Result,
+		j.Error,
+		j.Attempts,
+		j.CreatedAt,
+		j.UpdatedAt,
+		j.StartedAt,
+		j.FinishedAt,
+	)
+	if err != nil {
+		r.logg.Error("failed to create job record", "error", err, "job_id", j.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// GetByID fetches a job record by ID
+func (r *jobRecordRepo) GetByID(ctx context.Context, id string) (*domain.JobRecord, error) {
+	query := "SELECT " + jobRecordColumns + " FROM jobs WHERE id = $1"
+
+	j, err := scanJobRecord(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrJobNotFound
+		}
+		r.logg.Error("failed to get job record", "error", err, "job_id", id)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return j, nil
+}
+
+// Update saves the mutable status fields of a job record
+func (r *jobRecordRepo) Update(ctx context.Context, j *domain.JobRecord) error {
+	query := `UPDATE jobs SET state = $2, progress = $3, result = $4, error = $5, attempts = $6,
+		updated_at = $7, started_at = $8, finished_at = $9
+		WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query,
+		j.ID,
+		j.State,
+		j.Progress,
+		j.Result,
+		j.Error,
+		j.Attempts,
+		j.UpdatedAt,
+		j.StartedAt,
+		j.FinishedAt,
+	)
+	if err != nil {
+		r.logg.Error("failed to update job record", "error", err, "job_id", j.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrJobNotFound
+	}
+
+	return nil
+}
+
+// scanJobRecord scans a row selected with jobRecordColumns
+func scanJobRecord(row pgx.Row) (*domain.JobRecord, error) {
+	var j domain.JobRecord
+	err := row.Scan(
+		&j.ID,
+		&j.Type,
+		&j.Priority,
+		&j.UserID,
+		&j.State,
+		&j.Progress,
+		&j.Result,
+		&j.Error,
+		&j.Attempts,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+		&j.StartedAt,
+		&j.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// notificationPreferencesRepo is the PostgreSQL implementation of domain.NotificationPreferencesRepository
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE notification_preferences (
+//	    user_id    TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+//	    addresses  JSONB NOT NULL DEFAULT '{}',
+//	    events     JSONB NOT NULL DEFAULT '{}',
+//	    updated_at TIMESTAMPTZ NOT NULL
+//	);
+type notificationPreferencesRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewNotificationPreferencesRepo creates a Postgres-backed notification preference repository
+func NewNotificationPreferencesRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.NotificationPreferencesRepository {
+	return &notificationPreferencesRepo{db: newPool(db, opts), logg: logg}
+}
+
+// Get fetches the preferences of a user
+func (r *notificationPreferencesRepo) Get(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	query := "SELECT user_id, addresses, events, updated_at FROM notification_preferences WHERE user_id = $1"
+
+	var (
+		p                 domain.NotificationPreferences
+		addresses, events []byte
+	)
+	err := r.db.QueryRow(ctx, query, userID).Scan(&p.UserID, &addresses, &events, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotificationPreferencesNotFound
+		}
+		r.logg.Error("failed to get notification preferences", "error", err, "user_id", userID)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	if err := json.Unmarshal(addresses, &p.Addresses); err != nil {
+		return nil, fmt.Errorf("%w: decoding notification addresses: %v", domain.ErrDatabaseError, err)
+	}
+	if err := json.Unmarshal(events, &p.Events); err != nil {
+		return nil, fmt.Errorf("%w: decoding notification events: %v", domain.ErrDatabaseError, err)
+	}
+
+	return &p, nil
+}
+
+// Save inserts or replaces the preferences of p.UserID
+func (r *notificationPreferencesRepo) Save(ctx context.Context, p *domain.NotificationPreferences) error {
+	query := `INSERT INTO notification_preferences (user_id, addresses, events, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET addresses = EXCLUDED.addresses, events = EXCLUDED.events, updated_at = EXCLUDED.updated_at`
+
+	addresses, err := json.Marshal(p.Addresses)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	events, err := json.Marshal(p.Events)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, p.UserID, addresses, events, p.UpdatedAt); err != nil {
+		r.logg.Error("failed to save notification preferences", "error", err, "user_id", p.UserID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return nil
+}
@@ -6,9 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -16,19 +17,22 @@ import (
 // orderRepo is the PostgreSQL implementation of domain.OrderRepository
 // It contains NO business logic - only data persistence
 type orderRepo struct {
-	db   *pgxpool.Pool
+	db   *pool
 	logg *logger.Logger
 }
 
+// orderSelectColumns are the columns of an order row, in order
+const orderSelectColumns = "id, user_id, amount, status, items, created_at, updated_at, cancelled_at, version"
+
 // NewOrderRepo creates a Postgres-backed order repository
-func NewOrderRepo(db *pgxpool.Pool, logg *logger.Logger) domain.OrderRepository {
-	return &orderRepo{db: db, logg: logg}
+func NewOrderRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.OrderRepository {
+	return &orderRepo{db: newPool(db, opts), logg: logg}
 }
 
 // GetByID fetches an order by ID
 // Responsibility: Query database and translate errors to domain errors
 func (r *orderRepo) GetByID(ctx context.Context, id string) (*domain.Order, error) {
-	query := "SELECT id, user_id, amount, status, items, created_at, updated_at, cancelled_at FROM orders WHERE id = $1"
+	query := "SELECT id, user_id, amount, status, items, created_at, updated_at, cancelled_at, version FROM orders WHERE id = $1"
 
 	var o domain.Order
 	var itemsJSON []byte
@@ -43,6 +47,7 @@ func (r *orderRepo) GetByID(ctx context.Context, id string) (*domain.Order, erro
 		&o.CreatedAt,
 		&o.UpdatedAt,
 		&cancelledAt,
+		&o.Version,
 	)
 
 	if err != nil {
@@ -69,7 +74,7 @@ func (r *orderRepo) GetByID(ctx context.Context, id string) (*domain.Order, erro
 // GetByUserID fetches orders for a specific user with pagination
 // Responsibility: Query database and translate errors to domain errors
 func (r *orderRepo) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*domain.Order, error) {
-	query := "SELECT id, user_id, amount, status, items, created_at, updated_at, cancelled_at FROM orders WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
+	query := "SELECT id, user_id, amount, status, items, created_at, updated_at, cancelled_at, version FROM orders WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
 
 	rows, err := r.db.Query(ctx, query, userID, limit, offset)
 	if err != nil {
@@ -81,10 +86,37 @@ func (r *orderRepo) GetByUserID(ctx context.Context, userID string, limit, offse
 	return r.scanOrders(rows)
 }
 
+// GetByUserIDs fetches the newest limit orders of each user in one query
+// Responsibility: Query database and translate errors to domain errors
+func (r *orderRepo) GetByUserIDs(ctx context.Context, userIDs []string, limit int) (map[string][]*domain.Order, error) {
+	query := `SELECT id, user_id, amount, status, items, created_at, updated_at, cancelled_at, version FROM (
+		SELECT *, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id) AS n
+		FROM orders WHERE user_id = ANY($1)
+	) ranked WHERE n <= $2 ORDER BY user_id, created_at DESC, id`
+
+	rows, err := r.db.Query(ctx, query, userIDs, limit)
+	if err != nil {
+		r.logg.Error("failed to get orders by user ids", "error", err, "count", len(userIDs))
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	orders, err := r.scanOrders(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[string][]*domain.Order)
+	for _, o := range orders {
+		byUser[o.UserID] = append(byUser[o.UserID], o)
+	}
+	return byUser, nil
+}
+
 // Create inserts a new order
 // Responsibility: Execute INSERT and handle database constraints
 func (r *orderRepo) Create(ctx context.Context, order *domain.Order) error {
-	query := "INSERT INTO orders (id, user_id, amount, status, items, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+	query := "INSERT INTO orders (id, user_id, amount, status, items, created_at, updated_at, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
 
 	// Serialize items to JSON
 	itemsJSON, err := json.Marshal(order.Items)
@@ -101,6 +133,7 @@ func (r *orderRepo) Create(ctx context.Context, order *domain.Order) error {
 		itemsJSON,
 		order.CreatedAt,
 		order.UpdatedAt,
+		order.Version,
 	)
 
 	if err != nil {
@@ -111,10 +144,11 @@ func (r *orderRepo) Create(ctx context.Context, order *domain.Order) error {
 	return nil
 }
 
-// Update updates an existing order
+// Update updates an existing order if its stored version is still order's,
+// then bumps the version
 // Responsibility: Execute UPDATE and handle database errors
 func (r *orderRepo) Update(ctx context.Context, order *domain.Order) error {
-	query := "UPDATE orders SET amount = $2, status = $3, items = $4, updated_at = $5, cancelled_at = $6 WHERE id = $1"
+	query := "UPDATE orders SET amount = $2, status = $3, items = $4, updated_at = $5, cancelled_at = $6, version = version + 1 WHERE id = $1 AND version = $7"
 
 	// Serialize items to JSON
 	itemsJSON, err := json.Marshal(order.Items)
@@ -130,6 +164,7 @@ func (r *orderRepo) Update(ctx context.Context, order *domain.Order) error {
 		itemsJSON,
 		order.UpdatedAt,
 		order.CancelledAt,
+		order.Version,
 	)
 
 	if err != nil {
@@ -137,14 +172,28 @@ func (r *orderRepo) Update(ctx context.Context, order *domain.Order) error {
 		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
 	}
 
-	// Check if any rows were affected
+	// No row was updated: either it is gone or someone else updated it first
 	if result.RowsAffected() == 0 {
-		return domain.ErrOrderNotFound
+		return r.missingOrStale(ctx, order.ID)
 	}
 
+	order.Version++
 	return nil
 }
 
+// missingOrStale explains an update that matched no row
+func (r *orderRepo) missingOrStale(ctx context.Context, id string) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)", id).Scan(&exists); err != nil {
+		r.logg.Error("failed to check order existence", "error", err, "order_id", id)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	if !exists {
+		return domain.ErrOrderNotFound
+	}
+	return domain.ErrVersionMismatch
+}
+
 // Delete removes an order by ID
 // Responsibility: Execute DELETE and handle database errors
 func (r *orderRepo) Delete(ctx context.Context, id string) error {
@@ -167,9 +216,18 @@ func (r *orderRepo) Delete(ctx context.Context, id string) error {
 // List retrieves a paginated list of orders
 // Responsibility: Query database with pagination
 func (r *orderRepo) List(ctx context.Context, limit, offset int) ([]*domain.Order, error) {
-	query := "SELECT id, user_id, amount, status, items, created_at, updated_at, cancelled_at FROM orders ORDER BY created_at DESC LIMIT $1 OFFSET $2"
+	return r.Search(ctx, domain.ListQuery{}, limit, offset)
+}
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+// Search retrieves a filtered and sorted page of orders
+// Responsibility: Translate the list query into parameterized SQL
+func (r *orderRepo) Search(ctx context.Context, q domain.ListQuery, limit, offset int) ([]*domain.Order, error) {
+	query, args, err := searchQuery(selectColumns(orderSelectColumns, q.Fields), "orders", orderColumns, q, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		r.logg.Error("failed to list orders", "error", err)
 		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
@@ -179,42 +237,120 @@ func (r *orderRepo) List(ctx context.Context, limit, offset int) ([]*domain.Orde
 	return r.scanOrders(rows)
 }
 
-// scanOrders is a helper method to scan multiple order rows
-// Responsibility: Convert database rows to domain entities
-func (r *orderRepo) scanOrders(rows pgx.Rows) ([]*domain.Order, error) {
-	var orders []*domain.Order
+// Count counts the orders matching the filters
+// Responsibility: Translate the filters into a parameterized COUNT query
+func (r *orderRepo) Count(ctx context.Context, filters []domain.Filter) (int, error) {
+	query, args, err := countQuery("orders", orderColumns, filters)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&n); err != nil {
+		r.logg.Error("failed to count orders", "error", err)
+		return 0, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return n, nil
+}
+
+// ListKeyset retrieves one keyset page of orders
+// Responsibility: Query database by cursor position
+func (r *orderRepo) ListKeyset(ctx context.Context, page domain.KeysetPage) ([]*domain.Order, error) {
+	query, args, err := keysetQuery(selectColumns(orderSelectColumns, page.Fields), "orders", orderColumns, page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logg.Error("failed to list orders", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	return r.scanOrders(rows)
+}
+
+// Stream passes orders to fn as rows arrive from the database, without
+// buffering the result
+// Responsibility: Query database and hand over each row as it is scanned
+func (r *orderRepo) Stream(ctx context.Context, limit, offset int, fn func(*domain.Order) error) error {
+	// LIMIT NULL is no limit
+	query := "SELECT id, user_id, amount, status, items, created_at, updated_at, cancelled_at, version FROM orders ORDER BY created_at DESC, id LIMIT $1 OFFSET $2"
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	rows, err := r.db.Query(ctx, query, limitArg, offset)
+	if err != nil {
+		r.logg.Error("failed to stream orders", "error", err)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var o domain.Order
-		var itemsJSON []byte
-		var cancelledAt sql.NullTime
-
-		err := rows.Scan(
-			&o.ID,
-			&o.UserID,
-			&o.Amount,
-			&o.Status,
-			&itemsJSON,
-			&o.CreatedAt,
-			&o.UpdatedAt,
-			&cancelledAt,
-		)
+		o, err := r.scanOrder(rows)
 		if err != nil {
-			r.logg.Error("failed to scan order row", "error", err)
-			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+			return err
 		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
 
-		// Deserialize items from JSON
-		if err := json.Unmarshal(itemsJSON, &o.Items); err != nil {
-			r.logg.Error("failed to unmarshal order items", "error", err, "order_id", o.ID)
+	if err := rows.Err(); err != nil {
+		r.logg.Error("error iterating order rows", "error", err)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// Summarize aggregates the orders created in [from, to) by status
+// Responsibility: Let the database do the aggregation
+func (r *orderRepo) Summarize(ctx context.Context, from, to time.Time) (*domain.OrderSummary, error) {
+	query := "SELECT status, COUNT(*), COALESCE(SUM(amount), 0)::float8 FROM orders WHERE created_at >= $1 AND created_at < $2 GROUP BY status"
+
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		r.logg.Error("failed to summarize orders", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	summary := &domain.OrderSummary{From: from, To: to, ByStatus: make(map[domain.OrderStatus]int)}
+	for rows.Next() {
+		var status domain.OrderStatus
+		var count int
+		var total float64
+		if err := rows.Scan(&status, &count, &total); err != nil {
+			r.logg.Error("failed to scan order summary row", "error", err)
 			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
 		}
+		summary.ByStatus[status] = count
+		summary.Count += count
+		summary.Total += total
+	}
 
-		if cancelledAt.Valid {
-			o.CancelledAt = &cancelledAt.Time
-		}
+	if err := rows.Err(); err != nil {
+		r.logg.Error("error iterating order summary rows", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return summary, nil
+}
+
+// scanOrders is a helper method to scan multiple order rows
+// Responsibility: Convert database rows to domain entities
+func (r *orderRepo) scanOrders(rows pgx.Rows) ([]*domain.Order, error) {
+	var orders []*domain.Order
 
-		orders = append(orders, &o)
+	for rows.Next() {
+		o, err := r.scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -224,3 +360,53 @@ func (r *orderRepo) scanOrders(rows pgx.Rows) ([]*domain.Order, error) {
 
 	return orders, nil
 }
+
+// scanOrder scans the current row of an order query, which selects the
+// orderSelectColumns or, for a narrowed list, some of them
+func (r *orderRepo) scanOrder(rows pgx.Rows) (*domain.Order, error) {
+	var o domain.Order
+	var itemsJSON []byte
+	var cancelledAt sql.NullTime
+
+	columns := rows.FieldDescriptions()
+	targets := make([]any, len(columns))
+	for i, column := range columns {
+		switch column.Name {
+		case "id":
+			targets[i] = &o.ID
+		case "user_id":
+			targets[i] = &o.UserID
+		case "amount":
+			targets[i] = &o.Amount
+		case "status":
+			targets[i] = &o.Status
+		case "items":
+			targets[i] = &itemsJSON
+		case "created_at":
+			targets[i] = &o.CreatedAt
+		case "updated_at":
+			targets[i] = &o.UpdatedAt
+		case "cancelled_at":
+			targets[i] = &cancelledAt
+		case "version":
+			targets[i] = &o.Version
+		}
+	}
+	if err := rows.Scan(targets...); err != nil {
+		r.logg.Error("failed to scan order row", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	// Deserialize items from JSON, when selected
+	if itemsJSON != nil {
+		if err := json.Unmarshal(itemsJSON, &o.Items); err != nil {
+			r.logg.Error("failed to unmarshal order items", "error", err, "order_id", o.ID)
+			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+	}
+
+	if cancelledAt.Valid {
+		o.CancelledAt = &cancelledAt.Time
+	}
+	return &o, nil
+}
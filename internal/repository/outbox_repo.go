@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// outboxRelayLock is the advisory lock key held by the instance relaying
+// the outbox, so events are relayed by one instance at a time and in order
+const outboxRelayLock = 0x6f7574626f78 // "outbox"
+
+// outboxRepo is the PostgreSQL implementation of domain.Outbox
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE outbox (
+//	    seq          BIGSERIAL PRIMARY KEY,
+//	    event_id     TEXT NOT NULL UNIQUE,
+//	    event_type   TEXT NOT NULL,
+//	    aggregate_id TEXT NOT NULL,
+//	    payload      JSONB NOT NULL,
+//	    occurred_at  TIMESTAMPTZ NOT NULL,
+//	    published_at TIMESTAMPTZ,
+//	    attempts     INTEGER NOT NULL DEFAULT 0,
+//	    last_error   TEXT NOT NULL DEFAULT ''
+//	);
+type outboxRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewOutboxRepo creates a Postgres-backed outbox. Add joins the transaction
+// of a Transactor over the same database.
+func NewOutboxRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.Outbox {
+	return &outboxRepo{db: newPool(db, opts), logg: logg}
+}
+
+// Add inserts events, in the transaction of ctx if it carries one
+func (r *outboxRepo) Add(ctx context.Context, events ...domain.Event) error {
+	query := `INSERT INTO outbox (event_id, event_type, aggregate_id, payload, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			r.logg.Error("failed to marshal outbox event", "error", err, "event", event.EventType())
+			return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		meta := event.Metadata()
+		if _, err := r.db.Exec(ctx, query, meta.ID, event.EventType(), event.AggregateID(), payload, meta.OccurredAt); err != nil {
+			r.logg.Error("failed to add outbox event", "error", err, "event", event.EventType(), "event_id", meta.ID)
+			return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+	}
+	return nil
+}
+
+// outboxRow is an unpublished outbox row
+type outboxRow struct {
+	seq         int64
+	eventID     string
+	eventType   string
+	aggregateID string
+	payload     []byte
+	attempts    int
+}
+
+// Relay publishes unpublished events in a transaction holding the relay
+// lock and the rows it marks; while another instance holds the lock it
+// publishes nothing
+func (r *outboxRepo) Relay(ctx context.Context, limit int, publish func(ctx context.Context, entry domain.OutboxEntry) error) (int, error) {
+	var published int
+	err := r.db.inTx(ctx, func(tx pgx.Tx) error {
+		published = 0
+
+		var locked bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxRelayLock).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		rows, err := tx.Query(ctx, `SELECT seq, event_id, event_type, aggregate_id, payload, attempts FROM outbox
+			WHERE published_at IS NULL ORDER BY seq LIMIT $1`, limit)
+		if err != nil {
+			return err
+		}
+		pending, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (outboxRow, error) {
+			var o outboxRow
+			err := row.Scan(&o.seq, &o.eventID, &o.eventType, &o.aggregateID, &o.payload, &o.attempts)
+			return o, err
+		})
+		if err != nil {
+			return err
+		}
+
+		blocked := make(map[string]bool)
+		for _, row := range pending {
+			if blocked[row.aggregateID] {
+				continue
+			}
+			entry := domain.OutboxEntry{
+				Seq:         row.seq,
+				EventID:     row.eventID,
+				EventType:   row.eventType,
+				AggregateID: row.aggregateID,
+				Payload:     row.payload,
+				Attempts:    row.attempts,
+			}
+			entry.Event, entry.DecodeError = domain.DecodeEvent(row.eventType, row.payload)
+			if failure := publish(ctx, entry); failure != nil {
+				blocked[row.aggregateID] = true
+				if _, err := tx.Exec(ctx, "UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE seq = $1",
+					row.seq, failure.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := tx.Exec(ctx, "UPDATE outbox SET published_at = now() WHERE seq = $1", row.seq); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		r.logg.Error("failed to relay outbox", "error", err)
+		return 0, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return published, nil
+}
+
+// Purge deletes the events published before cutoff
+func (r *outboxRepo) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	result, err := r.db.Exec(ctx, "DELETE FROM outbox WHERE published_at < $1", cutoff)
+	if err != nil {
+		r.logg.Error("failed to purge outbox", "error", err)
+		return 0, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import "github.com/TopThisHat/stdlib-golang-api/internal/domain"
+
+// keysetQuery builds the query of one keyset page of table in newest-first
+// order (created_at descending, ties by ID ascending), served by the
+// (created_at DESC, id) index. Pages before a cursor are read backwards from
+// it and put back in newest-first order.
+func keysetQuery(columns, table string, fields map[string]string, page domain.KeysetPage) (string, []any, error) {
+	var args sqlArgs
+	limit := args.add(page.Limit)
+	conditions, err := filterConditions(page.Filters, fields, &args)
+	if err != nil {
+		return "", nil, err
+	}
+	selectFrom := "SELECT " + columns + " FROM " + table
+	const newestFirst = " ORDER BY created_at DESC, id"
+
+	switch {
+	case page.After != nil:
+		at, id := args.add(page.After.CreatedAt), args.add(page.After.ID)
+		conditions = append(conditions, "(created_at < "+at+" OR (created_at = "+at+" AND id > "+id+"))")
+		return selectFrom + where(conditions) + newestFirst + " LIMIT " + limit, args, nil
+	case page.Before != nil:
+		at, id := args.add(page.Before.CreatedAt), args.add(page.Before.ID)
+		conditions = append(conditions, "(created_at > "+at+" OR (created_at = "+at+" AND id < "+id+"))")
+		return "SELECT * FROM (" + selectFrom + where(conditions) +
+			" ORDER BY created_at, id DESC LIMIT " + limit + ") AS page" + newestFirst, args, nil
+	default:
+		return selectFrom + where(conditions) + newestFirst + " LIMIT " + limit, args, nil
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Query fields and the columns they read. Only these columns are ever named
+// in filter and sort clauses; values always travel as parameters.
+var (
+	userColumns = map[string]string{
+		"id": "id", "name": "name", "email": "email",
+		"created_at": "created_at", "updated_at": "updated_at",
+	}
+	orderColumns = map[string]string{
+		"id": "id", "user_id": "user_id", "status": "status", "amount": "amount",
+		"created_at": "created_at", "updated_at": "updated_at",
+	}
+)
+
+// selectColumns narrows a column list to the columns a list query needs:
+// those named in fields, plus the ID and creation time that cursors and the
+// default order need. Nil fields keep every column.
+func selectColumns(all string, fields []string) string {
+	if fields == nil {
+		return all
+	}
+	var picked []string
+	for _, column := range strings.Split(all, ", ") {
+		if column == "id" || column == "created_at" || slices.Contains(fields, column) {
+			picked = append(picked, column)
+		}
+	}
+	return strings.Join(picked, ", ")
+}
+
+// sqlArgs collects the parameters of a query as it is built
+type sqlArgs []any
+
+// add appends a parameter and returns its placeholder
+func (a *sqlArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+var comparisons = map[domain.FilterOp]string{
+	domain.OpEq: "=", domain.OpNe: "<>",
+	domain.OpGt: ">", domain.OpGte: ">=",
+	domain.OpLt: "<", domain.OpLte: "<=",
+}
+
+// likeEscaper escapes the LIKE wildcards of a contains filter
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filterConditions translates filters into SQL conditions, one per filter,
+// with their values added to args
+func filterConditions(filters []domain.Filter, columns map[string]string, args *sqlArgs) ([]string, error) {
+	conditions := make([]string, 0, len(filters))
+	for _, f := range filters {
+		column, ok := columns[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot filter by %q", domain.ErrInvalidInput, f.Field)
+		}
+		switch f.Op {
+		case domain.OpIn:
+			values, ok := f.Value.([]string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s[in] needs a list of strings", domain.ErrInvalidInput, f.Field)
+			}
+			conditions = append(conditions, column+" = ANY("+args.add(values)+")")
+		case domain.OpContains:
+			value, ok := f.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s[contains] needs a string", domain.ErrInvalidInput, f.Field)
+			}
+			conditions = append(conditions, column+" ILIKE "+args.add("%"+likeEscaper.Replace(value)+"%"))
+		default:
+			op, ok := comparisons[f.Op]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown filter operator %q", domain.ErrInvalidInput, f.Op)
+			}
+			conditions = append(conditions, column+" "+op+" "+args.add(f.Value))
+		}
+	}
+	return conditions, nil
+}
+
+// orderBy translates sort keys into an ORDER BY list, ending with the ID so
+// the order is total; no keys give the newest-first order
+func orderBy(keys []domain.SortKey, columns map[string]string) (string, error) {
+	if len(keys) == 0 {
+		return "created_at DESC, id", nil
+	}
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		column, ok := columns[key.Field]
+		if !ok {
+			return "", fmt.Errorf("%w: cannot sort by %q", domain.ErrInvalidInput, key.Field)
+		}
+		if key.Desc {
+			column += " DESC"
+		}
+		terms = append(terms, column)
+	}
+	return strings.Join(append(terms, "id"), ", "), nil
+}
+
+// searchQuery builds the query of one offset page of table narrowed and
+// ordered by q
+func searchQuery(columns, table string, fields map[string]string, q domain.ListQuery, limit, offset int) (string, []any, error) {
+	var args sqlArgs
+	conditions, err := filterConditions(q.Filters, fields, &args)
+	if err != nil {
+		return "", nil, err
+	}
+	order, err := orderBy(q.Sort, fields)
+	if err != nil {
+		return "", nil, err
+	}
+	return "SELECT " + columns + " FROM " + table + where(conditions) +
+		" ORDER BY " + order + " LIMIT " + args.add(limit) + " OFFSET " + args.add(offset), args, nil
+}
+
+// countQuery builds the query counting the rows of table matching filters
+func countQuery(table string, fields map[string]string, filters []domain.Filter) (string, []any, error) {
+	var args sqlArgs
+	conditions, err := filterConditions(filters, fields, &args)
+	if err != nil {
+		return "", nil, err
+	}
+	return "SELECT COUNT(*) FROM " + table + where(conditions), args, nil
+}
+
+// where joins conditions into a WHERE clause, empty without conditions
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
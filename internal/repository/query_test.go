@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		all    string
+		fields []string
+		want   string
+	}{
+		{"every field", orderSelectColumns, nil, orderSelectColumns},
+		{"narrowed", orderSelectColumns, []string{"status", "amount"}, "id, amount, status, created_at"},
+		{"items", orderSelectColumns, []string{"id", "items"}, "id, items, created_at"},
+		{"no fields", orderSelectColumns, []string{}, "id, created_at"},
+		{"unknown names are never selected", userSelectColumns, []string{"name", "password_hash", "email; DROP TABLE users"}, "id, name, created_at"},
+		{"users", userSelectColumns, []string{"email", "updated_at"}, "id, email, created_at, updated_at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectColumns(tt.all, tt.fields); got != tt.want {
+				t.Errorf("selectColumns(%v) = %q, want %q", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListQueriesSelectTheRequestedColumns(t *testing.T) {
+	columns := selectColumns(orderSelectColumns, []string{"amount", "id", "status"})
+
+	query, _, err := searchQuery(columns, "orders", orderColumns, domain.ListQuery{Fields: []string{"amount", "id", "status"}}, 20, 0)
+	if err != nil {
+		t.Fatalf("searchQuery: %v", err)
+	}
+	if want := "SELECT id, amount, status, created_at FROM orders ORDER BY created_at DESC, id LIMIT $1 OFFSET $2"; query != want {
+		t.Errorf("search query = %q, want %q", query, want)
+	}
+
+	query, _, err = keysetQuery(columns, "orders", orderColumns, domain.KeysetPage{Limit: 20, Fields: []string{"amount", "id", "status"}})
+	if err != nil {
+		t.Fatalf("keysetQuery: %v", err)
+	}
+	if want := "SELECT id, amount, status, created_at FROM orders ORDER BY created_at DESC, id LIMIT $1"; query != want {
+		t.Errorf("keyset query = %q, want %q", query, want)
+	}
+}
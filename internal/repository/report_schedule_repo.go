@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// reportScheduleRepo is the PostgreSQL implementation of domain.ReportScheduleRepository
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE report_schedules (
+//	    id          TEXT PRIMARY KEY,
+//	    name        TEXT NOT NULL,
+//	    report      TEXT NOT NULL,
+//	    schedule    TEXT NOT NULL,
+//	    recipients  TEXT[] NOT NULL,
+//	    created_by  TEXT NOT NULL DEFAULT '',
+//	    next_run_at TIMESTAMPTZ NOT NULL,
+//	    last_run_at TIMESTAMPTZ,
+//	    created_at  TIMESTAMPTZ NOT NULL,
+//	    updated_at  TIMESTAMPTZ NOT NULL
+//	);
+//	CREATE INDEX report_schedules_next_run_at_idx ON report_schedules (next_run_at);
+type reportScheduleRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewReportScheduleRepo creates a Postgres-backed report schedule repository
+func NewReportScheduleRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.ReportScheduleRepository {
+	return &reportScheduleRepo{db: newPool(db, opts), logg: logg}
+}
+
+const reportScheduleColumns = "id, name, report, schedule, recipients, created_by, next_run_at, last_run_at, created_at, updated_at"
+
+// Create inserts a new schedule
+func (r *reportScheduleRepo) Create(ctx context.Context, s *domain.ReportSchedule) error {
+	query := "INSERT INTO report_schedules (" + reportScheduleColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"
+
+	_, err := r.db.Exec(ctx, query,
+		s.ID,
+		s.Name,
+		s.Report,
+		s.Schedule,
+		s.Recipients,
+		s.CreatedBy,
+		s.NextRunAt,
+		s.LastRunAt,
+		s.CreatedAt,
+		s.UpdatedAt,
+	)
+	if err != nil {
+		r.logg.Error("failed to create report schedule", "error", err, "schedule_id", s.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// GetByID fetches a schedule by ID
+func (r *reportScheduleRepo) GetByID(ctx context.Context, id string) (*domain.ReportSchedule, error) {
+	query := "SELECT " + reportScheduleColumns + " FROM report_schedules WHERE id = $1"
+
+	s, err := scanReportSchedule(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrReportScheduleNotFound
+		}
+		r.logg.Error("failed to get report schedule", "error", err, "schedule_id", id)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return s, nil
+}
+
+// List retrieves every schedule, oldest first
+func (r *reportScheduleRepo) List(ctx context.Context) ([]*domain.ReportSchedule, error) {
+	query := "SELECT " + reportScheduleColumns + " FROM report_schedules ORDER BY created_at"
+	return r.query(ctx, query)
+}
+
+// ListDue retrieves schedules whose next run is at or before now, most overdue first
+func (r *reportScheduleRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]*domain.ReportSchedule, error) {
+	query := "SELECT " + reportScheduleColumns + " FROM report_schedules WHERE next_run_at <= $1 ORDER BY next_run_at LIMIT $2"
+	return r.query(ctx, query, now, limit)
+}
+
+// Advance moves next_run_at forward only if it still holds the expected value,
+// so exactly one replica claims each run
+func (r *reportScheduleRepo) Advance(ctx context.Context, id string, expected, next, ranAt time.Time) (bool, error) {
+	query := "UPDATE report_schedules SET next_run_at = $3, last_run_at = $4, updated_at = $4 WHERE id = $1 AND next_run_at = $2"
+
+	result, err := r.db.Exec(ctx, query, id, expected, next, ranAt)
+	if err != nil {
+		r.logg.Error("failed to advance report schedule", "error", err, "schedule_id", id)
+		return false, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// Delete removes a schedule
+func (r *reportScheduleRepo) Delete(ctx context.Context, id string) error {
+	query := "DELETE FROM report_schedules WHERE id = $1"
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		r.logg.Error("failed to delete report schedule", "error", err, "schedule_id", id)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrReportScheduleNotFound
+	}
+
+	return nil
+}
+
+func (r *reportScheduleRepo) query(ctx context.Context, query string, args ...any) ([]*domain.ReportSchedule, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logg.Error("failed to list report schedules", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var schedules []*domain.ReportSchedule
+	for rows.Next() {
+		s, err := scanReportSchedule(rows)
+		if err != nil {
+			r.logg.Error("failed to scan report schedule row", "error", err)
+			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		schedules = append(schedules, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logg.Error("error iterating report schedule rows", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return schedules, nil
+}
+
+// scanReportSchedule scans a row selected with reportScheduleColumns
+func scanReportSchedule(row pgx.Row) (*domain.ReportSchedule, error) {
+	var s domain.ReportSchedule
+	err := row.Scan(
+		&s.ID,
+		&s.Name,
+		&s.Report,
+		&s.Schedule,
+		&s.Recipients,
+		&s.CreatedBy,
+		&s.NextRunAt,
+		&s.LastRunAt,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/resilience"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Option configures a repository
+type Option func(*pool)
+
+// WithRetry retries statements failing with transient errors under policy:
+// serialization failures and deadlocks, which Postgres rolled back, and
+// connection failures before the statement was sent. A nil
+// policy.Retryable is replaced by that check; anything else could have
+// applied a write already and is never retried.
+func WithRetry(policy resilience.RetryPolicy) Option {
+	return func(p *pool) {
+		if policy.Retryable == nil {
+			policy.Retryable = IsTransient
+		}
+		p.retry = policy
+	}
+}
+
+// pool is the connection pool of a repository, running statements under its
+// retry policy. Query retries failing to start the query; errors met while
+// reading rows are left to the caller. Statements run with a context from
+// a Transactor run in its transaction instead, and are not retried: a
+// failed statement aborts the transaction, which is retried as a whole.
+type pool struct {
+	*pgxpool.Pool
+	retry resilience.RetryPolicy
+}
+
+func newPool(db *pgxpool.Pool, opts []Option) *pool {
+	p := &pool{Pool: db}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.Exec(ctx, sql, args...)
+	}
+	return resilience.Retry(ctx, p.retry, func(ctx context.Context) (pgconn.CommandTag, error) {
+		return p.Pool.Exec(ctx, sql, args...)
+	})
+}
+
+func (p *pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.Query(ctx, sql, args...)
+	}
+	return resilience.Retry(ctx, p.retry, func(ctx context.Context) (pgx.Rows, error) {
+		return p.Pool.Query(ctx, sql, args...)
+	})
+}
+
+func (p *pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.QueryRow(ctx, sql, args...)
+	}
+	return &retryRow{pool: p, ctx: ctx, sql: sql, args: args}
+}
+
+// inTx runs fn in a transaction, running it again from the start if the
+// transaction fails with a transient error. Inside a Transactor's
+// transaction, fn runs in a savepoint of it instead.
+func (p *pool) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	if tx := txFromContext(ctx); tx != nil {
+		return pgx.BeginFunc(ctx, tx, fn)
+	}
+	return p.retry.Do(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, p.Pool, fn)
+	})
+}
+
+// retryRow runs its query when scanned, so the whole round trip is retried
+type retryRow struct {
+	pool *pool
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+func (r *retryRow) Scan(dest ...any) error {
+	return r.pool.retry.Do(r.ctx, func(ctx context.Context) error {
+		return r.pool.Pool.QueryRow(ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+// IsTransient reports whether err is a Postgres failure a statement can be
+// retried after without risk of applying it twice
+func IsTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"53300", // too_many_connections
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Class 08, connection exceptions, raised before the statement ran
+		return len(pgErr.Code) == 5 && pgErr.Code[:2] == "08"
+	}
+	return pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sagaRepo is the PostgreSQL implementation of domain.SagaRepository
+// It contains NO business logic - only data persistence
+//
+// Expected schema (see internal/postgres/migrations):
+//
+//	CREATE TABLE sagas (
+//	    id         TEXT PRIMARY KEY,
+//	    saga_type  TEXT NOT NULL,
+//	    saga_key   TEXT NOT NULL,
+//	    status     TEXT NOT NULL,
+//	    step       INTEGER NOT NULL DEFAULT 0,
+//	    data       JSONB NOT NULL DEFAULT '{}',
+//	    error      TEXT NOT NULL DEFAULT '',
+//	    attempts   INTEGER NOT NULL DEFAULT 0,
+//	    created_at TIMESTAMPTZ NOT NULL,
+//	    updated_at TIMESTAMPTZ NOT NULL,
+//	    version    BIGINT NOT NULL DEFAULT 1
+//	);
+//	CREATE UNIQUE INDEX sagas_key_idx ON sagas (saga_type, saga_key) WHERE status <> 'compensated';
+type sagaRepo struct {
+	db   *pool
+	logg *logger.Logger
+}
+
+// NewSagaRepo creates a Postgres-backed saga repository
+func NewSagaRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.SagaRepository {
+	return &sagaRepo{db: newPool(db, opts), logg: logg}
+}
+
+const sagaColumns = "id, saga_type, saga_key, status, step, data, error, attempts, created_at, updated_at, version"
+
+// Create inserts a new saga
+func (r *sagaRepo) Create(ctx context.Context, saga *domain.Saga) error {
+	query := "INSERT INTO sagas (" + sagaColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)"
+
+	data, err := json.Marshal(saga.Data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	_, err = r.db.Exec(ctx, query, saga.ID, saga.Type, saga.Key, saga.Status, saga.Step, data, saga.Error,
+		saga.Attempts, saga.CreatedAt, saga.UpdatedAt, saga.Version)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domain.ErrSagaAlreadyExists
+		}
+		r.logg.Error("failed to create saga", "error", err, "saga_id", saga.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// Get fetches a saga by ID
+func (r *sagaRepo) Get(ctx context.Context, id string) (*domain.Saga, error) {
+	return r.get(ctx, "SELECT "+sagaColumns+" FROM sagas WHERE id = $1", id)
+}
+
+// GetByKey fetches the newest saga of a type for a key
+func (r *sagaRepo) GetByKey(ctx context.Context, sagaType, key string) (*domain.Saga, error) {
+	return r.get(ctx, "SELECT "+sagaColumns+" FROM sagas WHERE saga_type = $1 AND saga_key = $2 ORDER BY created_at DESC LIMIT 1", sagaType, key)
+}
+
+func (r *sagaRepo) get(ctx context.Context, query string, args ...any) (*domain.Saga, error) {
+	saga, err := scanSaga(r.db.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrSagaNotFound
+		}
+		r.logg.Error("failed to get saga", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return saga, nil
+}
+
+// Update stores a saga at the version it was read at
+func (r *sagaRepo) Update(ctx context.Context, saga *domain.Saga) error {
+	query := `UPDATE sagas SET status = $2, step = $3, data = $4, error = $5, attempts = $6, updated_at = $7, version = version + 1
+		WHERE id = $1 AND version = $8`
+
+	data, err := json.Marshal(saga.Data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	result, err := r.db.Exec(ctx, query, saga.ID, saga.Status, saga.Step, data, saga.Error, saga.Attempts, saga.UpdatedAt, saga.Version)
+	if err != nil {
+		r.logg.Error("failed to update saga", "error", err, "saga_id", saga.ID)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	// No row was updated: either it is gone or someone else updated it first
+	if result.RowsAffected() == 0 {
+		var exists bool
+		if err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM sagas WHERE id = $1)", saga.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		if !exists {
+			return domain.ErrSagaNotFound
+		}
+		return domain.ErrVersionMismatch
+	}
+
+	saga.Version++
+	return nil
+}
+
+// ListUnfinished fetches the running and compensating sagas last updated before cutoff
+func (r *sagaRepo) ListUnfinished(ctx context.Context, cutoff time.Time, limit int) ([]*domain.Saga, error) {
+	query := "SELECT " + sagaColumns + ` FROM sagas
+		WHERE status IN ('running', 'compensating') AND updated_at < $1 ORDER BY updated_at LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, cutoff, limit)
+	if err != nil {
+		r.logg.Error("failed to list unfinished sagas", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	sagas, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.Saga, error) {
+		return scanSaga(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return sagas, nil
+}
+
+// scanSaga reads a row of sagaColumns
+func scanSaga(row pgx.Row) (*domain.Saga, error) {
+	var (
+		s    domain.Saga
+		data []byte
+	)
+	if err := row.Scan(&s.ID, &s.Type, &s.Key, &s.Status, &s.Step, &data, &s.Error, &s.Attempts,
+		&s.CreatedAt, &s.UpdatedAt, &s.Version); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.Data); err != nil {
+		return nil, fmt.Errorf("decoding saga data: %w", err)
+	}
+	if s.Data == nil {
+		s.Data = map[string]string{}
+	}
+	return &s, nil
+}
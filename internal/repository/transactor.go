@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// txKey is the context key of the transaction repositories join
+type txKey struct{}
+
+// txFromContext returns the transaction ctx carries, or nil
+func txFromContext(ctx context.Context) pgx.Tx {
+	tx, _ := ctx.Value(txKey{}).(pgx.Tx)
+	return tx
+}
+
+// transactor is the PostgreSQL implementation of domain.Transactor: the
+// repositories of this package called with the context it hands out run
+// their statements in its transaction
+type transactor struct {
+	db *pool
+}
+
+// NewTransactor creates a Transactor over db. With WithRetry, a transaction
+// failing with a transient error runs again from the start.
+func NewTransactor(db *pgxpool.Pool, opts ...Option) domain.Transactor {
+	return &transactor{db: newPool(db, opts)}
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	return t.db.inTx(ctx, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
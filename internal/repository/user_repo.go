@@ -7,7 +7,7 @@ import (
 	"strings"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -16,19 +16,22 @@ import (
 // userRepo is the PostgreSQL implementation of domain.UserRepository
 // It contains NO business logic - only data persistence
 type userRepo struct {
-	db   *pgxpool.Pool
+	db   *pool
 	logg *logger.Logger
 }
 
+// userSelectColumns are the columns of a user row, in order
+const userSelectColumns = "id, name, email, created_at, updated_at, version"
+
 // NewUserRepo creates a Postgres-backed user repository
-func NewUserRepo(db *pgxpool.Pool, logg *logger.Logger) domain.UserRepository {
-	return &userRepo{db: db, logg: logg}
+func NewUserRepo(db *pgxpool.Pool, logg *logger.Logger, opts ...Option) domain.UserRepository {
+	return &userRepo{db: newPool(db, opts), logg: logg}
 }
 
 // GetByID fetches a user by ID
 // Responsibility: Query database and translate errors to domain errors
 func (r *userRepo) GetByID(ctx context.Context, id string) (*domain.User, error) {
-	query := "SELECT id, name, email, created_at, updated_at FROM users WHERE id = $1"
+	query := "SELECT id, name, email, created_at, updated_at, version FROM users WHERE id = $1"
 
 	var u domain.User
 	err := r.db.QueryRow(ctx, query, id).Scan(
@@ -37,6 +40,7 @@ func (r *userRepo) GetByID(ctx context.Context, id string) (*domain.User, error)
 		&u.Email,
 		&u.CreatedAt,
 		&u.UpdatedAt,
+		&u.Version,
 	)
 
 	if err != nil {
@@ -50,10 +54,40 @@ func (r *userRepo) GetByID(ctx context.Context, id string) (*domain.User, error)
 	return &u, nil
 }
 
+// GetByIDs fetches the users with the given IDs in one query
+// Responsibility: Query database and translate errors to domain errors
+func (r *userRepo) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	query := "SELECT id, name, email, created_at, updated_at, version FROM users WHERE id = ANY($1)"
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		r.logg.Error("failed to get users by ids", "error", err, "count", len(ids))
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt, &u.Version); err != nil {
+			r.logg.Error("failed to scan user row", "error", err)
+			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		users = append(users, &u)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logg.Error("error iterating user rows", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return users, nil
+}
+
 // GetByEmail fetches a user by email address
 // Responsibility: Query database and translate errors to domain errors
 func (r *userRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := "SELECT id, name, email, created_at, updated_at FROM users WHERE LOWER(email) = LOWER($1)"
+	query := "SELECT id, name, email, created_at, updated_at, version FROM users WHERE LOWER(email) = LOWER($1)"
 
 	var u domain.User
 	err := r.db.QueryRow(ctx, query, email).Scan(
@@ -62,6 +96,7 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (*domain.User,
 		&u.Email,
 		&u.CreatedAt,
 		&u.UpdatedAt,
+		&u.Version,
 	)
 
 	if err != nil {
@@ -78,7 +113,7 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (*domain.User,
 // Create inserts a new user
 // Responsibility: Execute INSERT and handle database constraints
 func (r *userRepo) Create(ctx context.Context, user *domain.User) error {
-	query := "INSERT INTO users (id, name, email, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)"
+	query := "INSERT INTO users (id, name, email, created_at, updated_at, version) VALUES ($1, $2, $3, $4, $5, $6)"
 
 	_, err := r.db.Exec(ctx, query,
 		user.ID,
@@ -86,6 +121,7 @@ func (r *userRepo) Create(ctx context.Context, user *domain.User) error {
 		user.Email,
 		user.CreatedAt,
 		user.UpdatedAt,
+		user.Version,
 	)
 
 	if err != nil {
@@ -105,16 +141,57 @@ func (r *userRepo) Create(ctx context.Context, user *domain.User) error {
 	return nil
 }
 
-// Update updates an existing user
+// CreateMany inserts users with one batch of statements in a transaction
+// Responsibility: Execute the INSERTs and report conflicts per user
+func (r *userRepo) CreateMany(ctx context.Context, users []*domain.User) ([]error, error) {
+	// ON CONFLICT DO NOTHING skips a taken email without aborting the
+	// transaction; returning no row tells that user apart. (COPY is faster
+	// but fails the whole batch on the first conflict.)
+	query := "INSERT INTO users (id, name, email, created_at, updated_at, version) VALUES ($1, $2, $3, $4, $5, $6) " +
+		"ON CONFLICT DO NOTHING RETURNING id"
+
+	errs := make([]error, len(users))
+	err := r.db.inTx(ctx, func(tx pgx.Tx) error {
+		clear(errs) // Left over from an attempt that was retried
+		batch := &pgx.Batch{}
+		for _, user := range users {
+			batch.Queue(query, user.ID, user.Name, user.Email, user.CreatedAt, user.UpdatedAt, user.Version)
+		}
+		results := tx.SendBatch(ctx, batch)
+		defer results.Close()
+
+		for i := range users {
+			var id string
+			if err := results.QueryRow().Scan(&id); err != nil {
+				if !errors.Is(err, pgx.ErrNoRows) {
+					return err
+				}
+				errs[i] = domain.ErrUserAlreadyExists
+			}
+		}
+		return results.Close()
+	})
+
+	if err != nil {
+		r.logg.Error("failed to create users", "error", err, "count", len(users))
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+
+	return errs, nil
+}
+
+// Update updates an existing user if its stored version is still user's,
+// then bumps the version
 // Responsibility: Execute UPDATE and handle database errors
 func (r *userRepo) Update(ctx context.Context, user *domain.User) error {
-	query := "UPDATE users SET name = $2, email = $3, updated_at = $4 WHERE id = $1"
+	query := "UPDATE users SET name = $2, email = $3, updated_at = $4, version = version + 1 WHERE id = $1 AND version = $5"
 
 	result, err := r.db.Exec(ctx, query,
 		user.ID,
 		user.Name,
 		user.Email,
 		user.UpdatedAt,
+		user.Version,
 	)
 
 	if err != nil {
@@ -130,28 +207,42 @@ func (r *userRepo) Update(ctx context.Context, user *domain.User) error {
 		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
 	}
 
-	// Check if any rows were affected
+	// No row was updated: either it is gone or someone else updated it first
 	if result.RowsAffected() == 0 {
-		return domain.ErrUserNotFound
+		return r.missingOrStale(ctx, user.ID)
 	}
 
+	user.Version++
 	return nil
 }
 
-// Delete removes a user by ID
+// missingOrStale explains an update that matched no row
+func (r *userRepo) missingOrStale(ctx context.Context, id string) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", id).Scan(&exists); err != nil {
+		r.logg.Error("failed to check user existence", "error", err, "user_id", id)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	if !exists {
+		return domain.ErrUserNotFound
+	}
+	return domain.ErrVersionMismatch
+}
+
+// Delete removes a user by ID if its stored version is still version
 // Responsibility: Execute DELETE and handle database errors
-func (r *userRepo) Delete(ctx context.Context, id string) error {
-	query := "DELETE FROM users WHERE id = $1"
+func (r *userRepo) Delete(ctx context.Context, id string, version int64) error {
+	query := "DELETE FROM users WHERE id = $1 AND version = $2"
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := r.db.Exec(ctx, query, id, version)
 	if err != nil {
 		r.logg.Error("failed to delete user", "error", err, "user_id", id)
 		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
 	}
 
-	// Check if any rows were affected
+	// No row was deleted: either it is gone or someone else updated it first
 	if result.RowsAffected() == 0 {
-		return domain.ErrUserNotFound
+		return r.missingOrStale(ctx, id)
 	}
 
 	return nil
@@ -160,26 +251,88 @@ func (r *userRepo) Delete(ctx context.Context, id string) error {
 // List retrieves a paginated list of users
 // Responsibility: Query database with pagination
 func (r *userRepo) List(ctx context.Context, limit, offset int) ([]*domain.User, error) {
-	query := "SELECT id, name, email, created_at, updated_at FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2"
+	return r.Search(ctx, domain.ListQuery{}, limit, offset)
+}
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+// Search retrieves a filtered and sorted page of users
+// Responsibility: Translate the list query into parameterized SQL
+func (r *userRepo) Search(ctx context.Context, q domain.ListQuery, limit, offset int) ([]*domain.User, error) {
+	query, args, err := searchQuery(selectColumns(userSelectColumns, q.Fields), "users", userColumns, q, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logg.Error("failed to list users", "error", err)
+		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	return r.scanUsers(rows)
+}
+
+// Count counts the users matching the filters
+// Responsibility: Translate the filters into a parameterized COUNT query
+func (r *userRepo) Count(ctx context.Context, filters []domain.Filter) (int, error) {
+	query, args, err := countQuery("users", userColumns, filters)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&n); err != nil {
+		r.logg.Error("failed to count users", "error", err)
+		return 0, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return n, nil
+}
+
+// ListKeyset retrieves one keyset page of users
+// Responsibility: Query database by cursor position
+func (r *userRepo) ListKeyset(ctx context.Context, page domain.KeysetPage) ([]*domain.User, error) {
+	query, args, err := keysetQuery(selectColumns(userSelectColumns, page.Fields), "users", userColumns, page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		r.logg.Error("failed to list users", "error", err)
 		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
 	}
 	defer rows.Close()
 
+	return r.scanUsers(rows)
+}
+
+// scanUsers scans every row of a user query, which selects the
+// userSelectColumns or, for a narrowed list, some of them
+// Responsibility: Convert database rows to domain entities
+func (r *userRepo) scanUsers(rows pgx.Rows) ([]*domain.User, error) {
+	columns := rows.FieldDescriptions()
+	targets := make([]any, len(columns))
+
 	var users []*domain.User
 	for rows.Next() {
 		var u domain.User
-		err := rows.Scan(
-			&u.ID,
-			&u.Name,
-			&u.Email,
-			&u.CreatedAt,
-			&u.UpdatedAt,
-		)
-		if err != nil {
+		for i, column := range columns {
+			switch column.Name {
+			case "id":
+				targets[i] = &u.ID
+			case "name":
+				targets[i] = &u.Name
+			case "email":
+				targets[i] = &u.Email
+			case "created_at":
+				targets[i] = &u.CreatedAt
+			case "updated_at":
+				targets[i] = &u.UpdatedAt
+			case "version":
+				targets[i] = &u.Version
+			}
+		}
+		if err := rows.Scan(targets...); err != nil {
 			r.logg.Error("failed to scan user row", "error", err)
 			return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
 		}
@@ -190,6 +343,41 @@ func (r *userRepo) List(ctx context.Context, limit, offset int) ([]*domain.User,
 		r.logg.Error("error iterating user rows", "error", err)
 		return nil, fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
 	}
-
 	return users, nil
 }
+
+// Stream passes users to fn as rows arrive from the database, without
+// buffering the result
+// Responsibility: Query database and hand over each row as it is scanned
+func (r *userRepo) Stream(ctx context.Context, limit, offset int, fn func(*domain.User) error) error {
+	// LIMIT NULL is no limit
+	query := "SELECT id, name, email, created_at, updated_at, version FROM users ORDER BY created_at DESC, id LIMIT $1 OFFSET $2"
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	rows, err := r.db.Query(ctx, query, limitArg, offset)
+	if err != nil {
+		r.logg.Error("failed to stream users", "error", err)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt, &u.Version); err != nil {
+			r.logg.Error("failed to scan user row", "error", err)
+			return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+		}
+		if err := fn(&u); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logg.Error("error iterating user rows", "error", err)
+		return fmt.Errorf("%w: %v", domain.ErrDatabaseError, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package resilience
+
+import (
+	"context"
+	"io"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+)
+
+// BlobErrors are the blob store errors that say nothing about its health;
+// pass them to IgnoreErrors for a blob store guard
+var BlobErrors = []error{blob.ErrNotFound, blob.ErrAlreadyExists, blob.ErrInvalidKey, blob.ErrInvalidInput}
+
+// GuardBlobStore wraps store so every call goes through guard. Transfers
+// (Upload, Download, GetObject, UploadPart) take as long as their content
+// needs and are not held to the guard's timeout; the other calls are.
+//
+// Presigning is only local signing and is not guarded, but the wrapper keeps
+// the PresignedURLGenerator and PresignedPostGenerator interfaces of store,
+// so callers discovering them by type assertion still find them. It keeps
+// MultipartUploader too, guarded as the Store calls are.
+func GuardBlobStore(store blob.Store, guard *Guard) blob.Store {
+	guarded := &blobStore{store: store, guard: guard}
+	urls, hasURLs := store.(blob.PresignedURLGenerator)
+	posts, hasPosts := store.(blob.PresignedPostGenerator)
+	if uploader, ok := store.(blob.MultipartUploader); ok {
+		uploads := &multipartUploader{uploader: uploader, guard: guard}
+		switch {
+		case hasURLs && hasPosts:
+			return &struct {
+				*blobStore
+				*multipartUploader
+				blob.PresignedURLGenerator
+				blob.PresignedPostGenerator
+			}{guarded, uploads, urls, posts}
+		case hasURLs:
+			return &struct {
+				*blobStore
+				*multipartUploader
+				blob.PresignedURLGenerator
+			}{guarded, uploads, urls}
+		case hasPosts:
+			return &struct {
+				*blobStore
+				*multipartUploader
+				blob.PresignedPostGenerator
+			}{guarded, uploads, posts}
+		}
+		return &struct {
+			*blobStore
+			*multipartUploader
+		}{guarded, uploads}
+	}
+	switch {
+	case hasURLs && hasPosts:
+		return &struct {
+			*blobStore
+			blob.PresignedURLGenerator
+			blob.PresignedPostGenerator
+		}{guarded, urls, posts}
+	case hasURLs:
+		return &struct {
+			*blobStore
+			blob.PresignedURLGenerator
+		}{guarded, urls}
+	case hasPosts:
+		return &struct {
+			*blobStore
+			blob.PresignedPostGenerator
+		}{guarded, posts}
+	}
+	return guarded
+}
+
+type blobStore struct {
+	store blob.Store
+	guard *Guard
+}
+
+func (s *blobStore) Upload(ctx context.Context, input *blob.UploadInput) (*blob.UploadOutput, error) {
+	var out *blob.UploadOutput
+	err := s.guard.DoStreaming(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.store.Upload(ctx, input)
+		return err
+	})
+	return out, err
+}
+
+func (s *blobStore) Download(ctx context.Context, key string, w io.WriterAt) (int64, error) {
+	var n int64
+	err := s.guard.DoStreaming(ctx, func(ctx context.Context) error {
+		var err error
+		n, err = s.store.Download(ctx, key, w)
+		return err
+	})
+	return n, err
+}
+
+// GetObject guards opening the object; reading it is up to the caller
+func (s *blobStore) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := s.guard.DoStreaming(ctx, func(ctx context.Context) error {
+		var err error
+		body, err = s.store.GetObject(ctx, key)
+		return err
+	})
+	return body, err
+}
+
+func (s *blobStore) HeadObject(ctx context.Context, key string) (*blob.ObjectInfo, error) {
+	return Call(ctx, s.guard, func(ctx context.Context) (*blob.ObjectInfo, error) {
+		return s.store.HeadObject(ctx, key)
+	})
+}
+
+func (s *blobStore) Delete(ctx context.Context, key string) error {
+	return s.guard.Do(ctx, func(ctx context.Context) error {
+		return s.store.Delete(ctx, key)
+	})
+}
+
+func (s *blobStore) DeleteMultiple(ctx context.Context, keys []string) ([]string, error) {
+	return Call(ctx, s.guard, func(ctx context.Context) ([]string, error) {
+		return s.store.DeleteMultiple(ctx, keys)
+	})
+}
+
+func (s *blobStore) List(ctx context.Context, input *blob.ListInput) (*blob.ListOutput, error) {
+	return Call(ctx, s.guard, func(ctx context.Context) (*blob.ListOutput, error) {
+		return s.store.List(ctx, input)
+	})
+}
+
+func (s *blobStore) Exists(ctx context.Context, key string) (bool, error) {
+	return Call(ctx, s.guard, func(ctx context.Context) (bool, error) {
+		return s.store.Exists(ctx, key)
+	})
+}
+
+func (s *blobStore) Copy(ctx context.Context, sourceKey, destKey string) error {
+	return s.guard.Do(ctx, func(ctx context.Context) error {
+		return s.store.Copy(ctx, sourceKey, destKey)
+	})
+}
+
+// multipartUploader guards the calls of a blob.MultipartUploader
+type multipartUploader struct {
+	uploader blob.MultipartUploader
+	guard    *Guard
+}
+
+func (u *multipartUploader) CreateMultipartUpload(ctx context.Context, input *blob.UploadInput) (string, error) {
+	return Call(ctx, u.guard, func(ctx context.Context) (string, error) {
+		return u.uploader.CreateMultipartUpload(ctx, input)
+	})
+}
+
+func (u *multipartUploader) UploadPart(ctx context.Context, key, uploadID string, number int32, body io.Reader, size int64) (*blob.Part, error) {
+	var part *blob.Part
+	err := u.guard.DoStreaming(ctx, func(ctx context.Context) error {
+		var err error
+		part, err = u.uploader.UploadPart(ctx, key, uploadID, number, body, size)
+		return err
+	})
+	return part, err
+}
+
+func (u *multipartUploader) ListParts(ctx context.Context, key, uploadID string) ([]blob.Part, error) {
+	return Call(ctx, u.guard, func(ctx context.Context) ([]blob.Part, error) {
+		return u.uploader.ListParts(ctx, key, uploadID)
+	})
+}
+
+func (u *multipartUploader) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []blob.Part) (*blob.UploadOutput, error) {
+	return Call(ctx, u.guard, func(ctx context.Context) (*blob.UploadOutput, error) {
+		return u.uploader.CompleteMultipartUpload(ctx, key, uploadID, parts)
+	})
+}
+
+func (u *multipartUploader) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return u.guard.Do(ctx, func(ctx context.Context) error {
+		return u.uploader.AbortMultipartUpload(ctx, key, uploadID)
+	})
+}
@@ -0,0 +1,119 @@
+package resilience
+
+import (
+	"context"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// Callers treat any cache error as a miss and go to Postgres, so a refused
+// call costs a database read instead of a stalled request.
+
+// GuardUserCache wraps cache so every call goes through guard
+func GuardUserCache(cache domain.UserCache, guard *Guard) domain.UserCache {
+	return &userCache{cache: cache, guard: guard}
+}
+
+type userCache struct {
+	cache domain.UserCache
+	guard *Guard
+}
+
+func (c *userCache) Get(ctx context.Context, userID string) (*domain.User, error) {
+	return Call(ctx, c.guard, func(ctx context.Context) (*domain.User, error) {
+		return c.cache.Get(ctx, userID)
+	})
+}
+
+func (c *userCache) Set(ctx context.Context, user *domain.User) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Set(ctx, user)
+	})
+}
+
+func (c *userCache) Invalidate(ctx context.Context, userID string) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Invalidate(ctx, userID)
+	})
+}
+
+// GuardOrderCache wraps cache so every call goes through guard
+func GuardOrderCache(cache domain.OrderCache, guard *Guard) domain.OrderCache {
+	return &orderCache{cache: cache, guard: guard}
+}
+
+type orderCache struct {
+	cache domain.OrderCache
+	guard *Guard
+}
+
+func (c *orderCache) Get(ctx context.Context, orderID string) (*domain.Order, error) {
+	return Call(ctx, c.guard, func(ctx context.Context) (*domain.Order, error) {
+		return c.cache.Get(ctx, orderID)
+	})
+}
+
+func (c *orderCache) Set(ctx context.Context, order *domain.Order) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Set(ctx, order)
+	})
+}
+
+func (c *orderCache) Invalidate(ctx context.Context, orderID string) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Invalidate(ctx, orderID)
+	})
+}
+
+func (c *orderCache) InvalidateByUserID(ctx context.Context, userID string) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.InvalidateByUserID(ctx, userID)
+	})
+}
+
+func (c *orderCache) AddUserOrderIndex(ctx context.Context, userID, orderID string) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.AddUserOrderIndex(ctx, userID, orderID)
+	})
+}
+
+func (c *orderCache) RemoveUserOrderIndex(ctx context.Context, userID, orderID string) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.RemoveUserOrderIndex(ctx, userID, orderID)
+	})
+}
+
+// GuardResponseCache wraps cache so every call goes through guard. A refused
+// Get is served uncached, never from a copy the guard let go stale.
+func GuardResponseCache(cache domain.ResponseCache, guard *Guard) domain.ResponseCache {
+	return &responseCache{cache: cache, guard: guard}
+}
+
+type responseCache struct {
+	cache domain.ResponseCache
+	guard *Guard
+}
+
+func (c *responseCache) Get(ctx context.Context, key string, tags []string) (*domain.CachedResponse, string, error) {
+	var resp *domain.CachedResponse
+	var version string
+	err := c.guard.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, version, err = c.cache.Get(ctx, key, tags)
+		return err
+	})
+	return resp, version, err
+}
+
+func (c *responseCache) Set(ctx context.Context, key string, resp *domain.CachedResponse, ttl time.Duration) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Set(ctx, key, resp, ttl)
+	})
+}
+
+func (c *responseCache) Invalidate(ctx context.Context, tags ...string) error {
+	return c.guard.Do(ctx, func(ctx context.Context) error {
+		return c.cache.Invalidate(ctx, tags...)
+	})
+}
@@ -0,0 +1,383 @@
+// Package resilience keeps a slow or failing dependency from stalling every
+// request that touches it. A Guard combines three protections around calls
+// to one dependency:
+//
+//   - a bulkhead caps the calls in flight, so a dependency that hangs ties
+//     up a bounded number of goroutines instead of all of them;
+//   - a per-call timeout bounds how long each call may take;
+//   - a circuit breaker stops calling a dependency that keeps failing,
+//     failing fast with ErrOpen until a probe call succeeds again.
+//
+// Calls refused by the guard return ErrOpen or ErrBulkheadFull; callers
+// degrade as they would for any failure of the dependency (a cache miss
+// served from Postgres, a report without its download link).
+//
+// A RetryPolicy repeats calls that failed with transient errors, with
+// jittered exponential backoff. Retries run inside the guard of a guarded
+// dependency, so a call the policy gave up on counts against its breaker once.
+//
+// Example:
+//
+//	guard := resilience.NewGuard("redis", resilience.GuardConfig{
+//		MaxConcurrent: 100,
+//		Timeout:       250 * time.Millisecond,
+//		Breaker:       resilience.DefaultBreakerConfig(),
+//	})
+//	err := guard.Do(ctx, func(ctx context.Context) error {
+//		return client.Ping(ctx).Err()
+//	})
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOpen is returned for calls refused by an open circuit breaker
+	ErrOpen = errors.New("resilience: circuit breaker open")
+	// ErrBulkheadFull is returned for calls refused because too many were in flight
+	ErrBulkheadFull = errors.New("resilience: too many concurrent calls")
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Circuit Breaker
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// State is the state of a circuit breaker
+type State string
+
+// Breaker states
+const (
+	StateClosed   State = "closed"    // Calls go through; failures are counted
+	StateOpen     State = "open"      // Calls fail fast with ErrOpen
+	StateHalfOpen State = "half_open" // A limited number of probe calls go through
+)
+
+// BreakerConfig configures a circuit breaker
+type BreakerConfig struct {
+	// FailureThreshold is how many consecutive failures open the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing
+	OpenTimeout time.Duration
+	// HalfOpenProbes is how many probe calls must succeed to close it
+	// again; any failed probe reopens it
+	HalfOpenProbes int
+	// OnStateChange is called after every transition (nil for none)
+	OnStateChange func(name string, from, to State)
+}
+
+// DefaultBreakerConfig opens after 5 consecutive failures and probes every 30s
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// Breaker is a consecutive-failure circuit breaker, safe for concurrent use
+type Breaker struct {
+	name   string
+	config BreakerConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int       // Consecutive failures while closed
+	openedAt  time.Time // When the breaker last opened
+	probing   int       // Probes in flight while half-open
+	successes int       // Successful probes while half-open
+	opens     uint64    // Times the breaker has opened
+	changes   []State   // Transitions not yet passed to OnStateChange, from and to in pairs
+}
+
+// NewBreaker creates a closed breaker. Zero config fields use the defaults.
+func NewBreaker(name string, config BreakerConfig) *Breaker {
+	defaults := DefaultBreakerConfig()
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaults.FailureThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaults.OpenTimeout
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = defaults.HalfOpenProbes
+	}
+	return &Breaker{name: name, config: config, now: time.Now, state: StateClosed}
+}
+
+// Allow reports whether a call may go ahead, returning ErrOpen if not. A
+// caller that is allowed must report the outcome with Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return ErrOpen
+		}
+		b.transition(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probing+b.successes >= b.config.HalfOpenProbes {
+			return ErrOpen
+		}
+		b.probing++
+	}
+	return nil
+}
+
+// Record reports the outcome of a call Allow let through
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.transition(StateOpen)
+		}
+	case StateHalfOpen:
+		b.probing--
+		if failed {
+			b.transition(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.transition(StateClosed)
+		}
+	case StateOpen:
+		// A call let through before the breaker opened; it changes nothing
+	}
+}
+
+// transition moves the breaker to state. Called with mu held.
+func (b *Breaker) transition(state State) {
+	from := b.state
+	b.state = state
+	b.failures, b.probing, b.successes = 0, 0, 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+		b.opens++
+	}
+	if b.config.OnStateChange != nil {
+		b.changes = append(b.changes, from, state)
+	}
+}
+
+// unlock releases mu, then reports the transitions made while it was held,
+// so OnStateChange may read the breaker. Callbacks run on the calling
+// goroutine; keep them short.
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	for i := 0; i+1 < len(changes); i += 2 {
+		b.config.OnStateChange(b.name, changes[i], changes[i+1])
+	}
+}
+
+// State returns the breaker's current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		return StateHalfOpen // Will probe on the next call
+	}
+	return b.state
+}
+
+// Opens returns how many times the breaker has opened
+func (b *Breaker) Opens() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.opens
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Bulkhead
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Bulkhead caps the calls in flight to a dependency
+type Bulkhead struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewBulkhead creates a bulkhead letting max calls run at once; a call
+// finding them all busy waits up to wait for one (0 fails at once)
+func NewBulkhead(max int, wait time.Duration) *Bulkhead {
+	return &Bulkhead{slots: make(chan struct{}, max), wait: wait}
+}
+
+// Acquire takes a slot, returning ErrBulkheadFull if none frees up in time.
+// The caller releases the slot with Release.
+func (b *Bulkhead) Acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if b.wait <= 0 {
+		return ErrBulkheadFull
+	}
+	timer := time.NewTimer(b.wait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (b *Bulkhead) Release() {
+	<-b.slots
+}
+
+// InFlight returns the number of calls holding a slot
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Guard
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// GuardConfig configures the protections of a Guard
+type GuardConfig struct {
+	// MaxConcurrent caps the calls in flight (0 is unlimited); BulkheadWait
+	// is how long a call waits for a free slot
+	MaxConcurrent int
+	BulkheadWait  time.Duration
+	// Timeout bounds each call (0 leaves calls to their caller's deadline)
+	Timeout time.Duration
+	Breaker BreakerConfig
+	// IsFailure reports whether an error returned by a call is the
+	// dependency's fault and counts against the breaker. Nil counts every
+	// error except the caller's own cancellation.
+	IsFailure func(error) bool
+}
+
+// Guard protects calls to one dependency with a bulkhead, a timeout and a
+// circuit breaker
+type Guard struct {
+	name      string
+	bulkhead  *Bulkhead // nil when unlimited
+	timeout   time.Duration
+	breaker   *Breaker
+	isFailure func(error) bool
+}
+
+// NewGuard creates a guard for the dependency name
+func NewGuard(name string, config GuardConfig) *Guard {
+	g := &Guard{
+		name:      name,
+		timeout:   config.Timeout,
+		breaker:   NewBreaker(name, config.Breaker),
+		isFailure: config.IsFailure,
+	}
+	if config.MaxConcurrent > 0 {
+		g.bulkhead = NewBulkhead(config.MaxConcurrent, config.BulkheadWait)
+	}
+	if g.isFailure == nil {
+		g.isFailure = func(error) bool { return true }
+	}
+	return g
+}
+
+// Name returns the name of the guarded dependency
+func (g *Guard) Name() string {
+	return g.name
+}
+
+// Do runs fn under the guard's protections, with a context bounded by the
+// guard's timeout. It returns ErrOpen or ErrBulkheadFull without calling fn
+// when the dependency is failing or saturated.
+func (g *Guard) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return g.do(ctx, true, fn)
+}
+
+// DoStreaming is Do for calls whose result outlives them, such as a reader
+// of a download: the timeout is not applied, since cancelling the context
+// when fn returns would cut the stream off
+func (g *Guard) DoStreaming(ctx context.Context, fn func(ctx context.Context) error) error {
+	return g.do(ctx, false, fn)
+}
+
+func (g *Guard) do(ctx context.Context, timed bool, fn func(ctx context.Context) error) error {
+	if g.bulkhead != nil {
+		if err := g.bulkhead.Acquire(ctx); err != nil {
+			return err
+		}
+		defer g.bulkhead.Release()
+	}
+	if err := g.breaker.Allow(); err != nil {
+		return err
+	}
+
+	callCtx := ctx
+	if timed && g.timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	err := fn(callCtx)
+	// The caller giving up says nothing about the dependency
+	callerGone := ctx.Err() != nil && errors.Is(err, ctx.Err())
+	g.breaker.Record(err != nil && !callerGone && g.isFailure(err))
+	return err
+}
+
+// GuardStats is a snapshot of a guard, for diagnostics
+type GuardStats struct {
+	State    State  `json:"state"`
+	Opens    uint64 `json:"opens"`
+	InFlight int    `json:"in_flight"`
+}
+
+// Stats returns a snapshot of the guard
+func (g *Guard) Stats() GuardStats {
+	stats := GuardStats{State: g.breaker.State(), Opens: g.breaker.Opens()}
+	if g.bulkhead != nil {
+		stats.InFlight = g.bulkhead.InFlight()
+	}
+	return stats
+}
+
+// Call is Do for calls returning a value
+func Call[T any](ctx context.Context, g *Guard, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := g.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// IgnoreErrors returns an IsFailure counting every error but errs, which are
+// expected answers from a healthy dependency (a cache miss, a missing object)
+func IgnoreErrors(errs ...error) func(error) bool {
+	return func(err error) bool {
+		for _, expected := range errs {
+			if errors.Is(err, expected) {
+				return false
+			}
+		}
+		return true
+	}
+}
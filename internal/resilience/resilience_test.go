@@ -0,0 +1,374 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+)
+
+var errDown = errors.New("dependency down")
+
+// fakeClock is a settable time source for breakers
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var transitions []State
+	var b *Breaker
+	b = NewBreaker("redis", BreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+		HalfOpenProbes:   1,
+		OnStateChange: func(name string, from, to State) {
+			// Called without the lock held, so reading the breaker is fine
+			if got := b.State(); got != to {
+				t.Errorf("State() in callback = %s, want %s", got, to)
+			}
+			transitions = append(transitions, to)
+		},
+	})
+	b.now = clock.Now
+
+	fail := func() {
+		t.Helper()
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		b.Record(true)
+	}
+
+	fail()
+	fail()
+	// A success resets the count of consecutive failures
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.Record(false)
+	fail()
+	fail()
+	if b.State() != StateClosed {
+		t.Fatalf("State() = %s after 2 consecutive failures, want closed", b.State())
+	}
+	fail()
+	if b.State() != StateOpen {
+		t.Fatalf("State() = %s after 3 consecutive failures, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() on open breaker error = %v, want ErrOpen", err)
+	}
+
+	// After the open timeout one probe goes through; a failed probe reopens
+	clock.Advance(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after open timeout error = %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second Allow() while probing error = %v, want ErrOpen", err)
+	}
+	b.Record(true)
+	if b.State() != StateOpen {
+		t.Fatalf("State() = %s after failed probe, want open", b.State())
+	}
+
+	// A successful probe closes it
+	clock.Advance(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.Record(false)
+	if b.State() != StateClosed {
+		t.Fatalf("State() = %s after successful probe, want closed", b.State())
+	}
+
+	want := []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", transitions, want)
+		}
+	}
+	if b.Opens() != 2 {
+		t.Errorf("Opens() = %d, want 2", b.Opens())
+	}
+}
+
+func TestBulkheadFull(t *testing.T) {
+	b := NewBulkhead(1, 10*time.Millisecond)
+	ctx := context.Background()
+	if err := b.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(ctx); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Acquire() on full bulkhead error = %v, want ErrBulkheadFull", err)
+	}
+	b.Release()
+	if err := b.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire() after Release() error = %v", err)
+	}
+	if b.InFlight() != 1 {
+		t.Errorf("InFlight() = %d, want 1", b.InFlight())
+	}
+}
+
+func TestGuardTimeoutCountsAsFailure(t *testing.T) {
+	g := NewGuard("s3", GuardConfig{
+		Timeout: 10 * time.Millisecond,
+		Breaker: BreakerConfig{FailureThreshold: 1},
+	})
+	err := g.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do() error = %v, want DeadlineExceeded", err)
+	}
+
+	called := false
+	err = g.Do(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("Do() after timeout error = %v (called %v), want ErrOpen without a call", err, called)
+	}
+	if s := g.Stats(); s.State != StateOpen || s.Opens != 1 {
+		t.Errorf("Stats() = %+v, want open once", s)
+	}
+}
+
+func TestGuardIgnoresCallerCancellation(t *testing.T) {
+	g := NewGuard("redis", GuardConfig{Breaker: BreakerConfig{FailureThreshold: 1}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := g.Do(ctx, func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do() error = %v, want Canceled", err)
+	}
+	if g.Stats().State != StateClosed {
+		t.Errorf("State = %s after caller cancellation, want closed", g.Stats().State)
+	}
+}
+
+func TestGuardBulkhead(t *testing.T) {
+	g := NewGuard("redis", GuardConfig{MaxConcurrent: 1})
+	started, release := make(chan struct{}), make(chan struct{})
+	go g.Do(context.Background(), func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	if err := g.Do(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Do() with the bulkhead full error = %v, want ErrBulkheadFull", err)
+	}
+	if g.Stats().InFlight != 1 {
+		t.Errorf("InFlight = %d, want 1", g.Stats().InFlight)
+	}
+}
+
+func TestGuardUserCache(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard("cache", GuardConfig{
+		Breaker:   BreakerConfig{FailureThreshold: 1},
+		IsFailure: IgnoreErrors(domain.ErrCacheMiss),
+	})
+	cache := GuardUserCache(memory.NewUserCache(), g)
+
+	// Misses are the cache working, not failing
+	for range 3 {
+		if _, err := cache.Get(ctx, "u1"); !errors.Is(err, domain.ErrCacheMiss) {
+			t.Fatalf("Get() error = %v, want ErrCacheMiss", err)
+		}
+	}
+	if err := cache.Set(ctx, &domain.User{ID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := cache.Get(ctx, "u1"); err != nil || user.ID != "u1" {
+		t.Fatalf("Get() = %v, %v, want u1", user, err)
+	}
+	if g.Stats().State != StateClosed {
+		t.Errorf("State = %s, want closed", g.Stats().State)
+	}
+}
+
+func TestGuardBlobStore(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard("blob", GuardConfig{
+		Breaker:   BreakerConfig{FailureThreshold: 1},
+		IsFailure: IgnoreErrors(BlobErrors...),
+	})
+	store := GuardBlobStore(blob.NewMemoryStore(), g)
+
+	if _, err := store.GetObject(ctx, "missing"); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("GetObject() error = %v, want ErrNotFound", err)
+	}
+	if ok, err := store.Exists(ctx, "missing"); err != nil || ok {
+		t.Fatalf("Exists() = %v, %v, want false", ok, err)
+	}
+	if g.Stats().State != StateClosed {
+		t.Errorf("State = %s after ErrNotFound, want closed", g.Stats().State)
+	}
+}
+
+func TestGuardBlobStoreKeepsPresigners(t *testing.T) {
+	store := GuardBlobStore(presigningStore{blob.NewMemoryStore()}, NewGuard("blob", GuardConfig{}))
+	if _, ok := store.(blob.PresignedURLGenerator); !ok {
+		t.Error("guarded store lost PresignedURLGenerator")
+	}
+	if _, ok := store.(blob.PresignedPostGenerator); ok {
+		t.Error("guarded store gained PresignedPostGenerator")
+	}
+	if _, ok := GuardBlobStore(blob.NewMemoryStore(), NewGuard("blob", GuardConfig{})).(blob.PresignedURLGenerator); ok {
+		t.Error("guarded memory store gained PresignedURLGenerator")
+	}
+	if _, ok := store.(blob.MultipartUploader); ok {
+		t.Error("guarded store gained MultipartUploader")
+	}
+
+	store = GuardBlobStore(multipartStore{presigningStore{blob.NewMemoryStore()}}, NewGuard("blob", GuardConfig{}))
+	uploader, ok := store.(blob.MultipartUploader)
+	if !ok {
+		t.Fatal("guarded store lost MultipartUploader")
+	}
+	if _, ok := store.(blob.PresignedURLGenerator); !ok {
+		t.Error("guarded multipart store lost PresignedURLGenerator")
+	}
+	if id, err := uploader.CreateMultipartUpload(context.Background(), &blob.UploadInput{Key: "a"}); err != nil || id != "upload-1" {
+		t.Errorf("CreateMultipartUpload() = %q, %v, want upload-1", id, err)
+	}
+}
+
+// multipartStore adds a stub MultipartUploader to a presigning store
+type multipartStore struct {
+	presigningStore
+}
+
+func (multipartStore) CreateMultipartUpload(context.Context, *blob.UploadInput) (string, error) {
+	return "upload-1", nil
+}
+
+func (multipartStore) UploadPart(_ context.Context, _, _ string, number int32, _ io.Reader, size int64) (*blob.Part, error) {
+	return &blob.Part{Number: number, Size: size}, nil
+}
+
+func (multipartStore) ListParts(context.Context, string, string) ([]blob.Part, error) {
+	return nil, nil
+}
+
+func (multipartStore) CompleteMultipartUpload(context.Context, string, string, []blob.Part) (*blob.UploadOutput, error) {
+	return &blob.UploadOutput{}, nil
+}
+
+func (multipartStore) AbortMultipartUpload(context.Context, string, string) error {
+	return nil
+}
+
+// presigningStore is a store with presigned URLs but no presigned POSTs
+type presigningStore struct {
+	blob.Store
+}
+
+func (presigningStore) GeneratePresignedURL(context.Context, string, time.Duration) (string, error) {
+	return "https://example.com/get", nil
+}
+
+func (presigningStore) GeneratePresignedUploadURL(context.Context, string, string, time.Duration) (string, error) {
+	return "https://example.com/put", nil
+}
+
+func TestCall(t *testing.T) {
+	g := NewGuard("payments", GuardConfig{})
+	got, err := Call(context.Background(), g, func(context.Context) (int, error) { return 42, nil })
+	if err != nil || got != 42 {
+		t.Fatalf("Call() = %d, %v, want 42", got, err)
+	}
+	if _, err := Call(context.Background(), g, func(context.Context) (int, error) { return 0, errDown }); !errors.Is(err, errDown) {
+		t.Fatalf("Call() error = %v, want errDown", err)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	ctx := context.Background()
+
+	calls := 0
+	err := p.Do(ctx, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errDown
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("Do() = %v after %d calls, want success on the third", err, calls)
+	}
+
+	calls = 0
+	if err := p.Do(ctx, func(context.Context) error { calls++; return errDown }); !errors.Is(err, errDown) || calls != 3 {
+		t.Fatalf("Do() = %v after %d calls, want errDown after 3", err, calls)
+	}
+
+	// Errors the policy does not retry, and guard refusals, are returned at once
+	p.Retryable = func(err error) bool { return errors.Is(err, errDown) }
+	for _, want := range []error{errors.New("conflict"), ErrOpen, ErrBulkheadFull} {
+		calls = 0
+		if err := p.Do(ctx, func(context.Context) error { calls++; return want }); !errors.Is(err, want) || calls != 1 {
+			t.Errorf("Do() = %v after %d calls, want %v after 1", err, calls, want)
+		}
+	}
+}
+
+func TestRetryPolicyStopsWhenCallerGivesUp(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := p.Do(ctx, func(context.Context) error { calls++; return errDown })
+	if !errors.Is(err, errDown) || calls != 1 {
+		t.Fatalf("Do() = %v after %d calls, want errDown after 1", err, calls)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Do() waited out its backoff after the caller gave up")
+	}
+}
+
+func TestRetryZeroPolicyCallsOnce(t *testing.T) {
+	calls := 0
+	got, err := Retry(context.Background(), RetryPolicy{}, func(context.Context) (int, error) {
+		calls++
+		return 0, errDown
+	})
+	if !errors.Is(err, errDown) || calls != 1 || got != 0 {
+		t.Fatalf("Retry() = %d, %v after %d calls, want errDown after 1", got, err, calls)
+	}
+}
@@ -0,0 +1,85 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Retry
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// A retry policy repeats calls failing with transient errors (a serialization
+// failure, a dropped connection, a throttled request) after an exponential,
+// jittered backoff: BaseDelay before the first retry, doubling up to MaxDelay,
+// each wait drawn from its upper half so replicas retrying together spread out.
+//
+// Which errors are transient depends on the dependency; each store defines
+// its own check and applies it to the policies it is given (see
+// repository.WithRetry and redis.WithCacheRetry).
+
+// RetryPolicy configures how calls are retried
+type RetryPolicy struct {
+	// MaxAttempts is how many times a call is tried, the first included;
+	// 1 or less disables retries
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Retryable reports whether a failed call may be tried again. Nil retries
+	// every error but the caller's cancellation and calls refused by a Guard.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy tries calls three times, waiting up to 50ms then 100ms
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+}
+
+// Do runs fn until it succeeds, fails with an error the policy does not
+// retry, runs out of attempts or ctx is done. It returns fn's last error.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(ctx, err) {
+			return err
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		if p.MaxDelay > 0 {
+			backoff = min(backoff, p.MaxDelay)
+		}
+	}
+}
+
+func (p RetryPolicy) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrOpen) || errors.Is(err, ErrBulkheadFull) {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// Retry is RetryPolicy.Do for calls returning a value
+func Retry[T any](ctx context.Context, p RetryPolicy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := p.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,142 @@
+// Package security defines the security event stream: structured records of
+// authentication failures, lockouts, permission denials, rate-limit trips and
+// administrative actions, emitted on a channel separate from application logs
+// so they can be shipped to a SIEM with a stable schema.
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchemaVersion identifies the event schema. Fields are only ever added;
+// renaming or removing one requires a new version.
+const SchemaVersion = "security.v1"
+
+// EventType classifies a security event
+type EventType string
+
+const (
+	EventAuthFailure      EventType = "auth.failure"      // Invalid, revoked or unknown credential
+	EventAuthLockout      EventType = "auth.lockout"      // Client locked out after repeated failures
+	EventPermissionDenied EventType = "authz.denied"      // Authenticated but not allowed
+	EventRateLimited      EventType = "ratelimit.tripped" // Request rejected by a rate limiter
+	EventAdminAction      EventType = "admin.action"      // Privileged operation performed
+	EventTokenCreated     EventType = "token.created"     // Personal access token minted or session started
+	EventTokenRevoked     EventType = "token.revoked"     // Token or session revoked
+)
+
+// Outcome of the attempted action
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeDenied  Outcome = "denied"
+)
+
+// Severity of an event, for SIEM triage
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// Actor is whoever triggered the event. UserID is empty for unauthenticated actors.
+type Actor struct {
+	UserID    string `json:"user_id,omitempty"`
+	TokenID   string `json:"token_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// Target is the resource acted on
+type Target struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+// Event is a single security event. The JSON encoding is the stable schema.
+type Event struct {
+	Schema    string            `json:"schema"`
+	ID        string            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Type      EventType         `json:"type"`
+	Outcome   Outcome           `json:"outcome"`
+	Severity  Severity          `json:"severity"`
+	Action    string            `json:"action,omitempty"` // e.g. "revoke_user_tokens"
+	Reason    string            `json:"reason,omitempty"` // Machine-readable, e.g. "insufficient_scope"
+	Actor     Actor             `json:"actor"`
+	Target    *Target           `json:"target,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Method    string            `json:"method,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// defaultSeverity is used when an event doesn't set one
+func (t EventType) defaultSeverity() Severity {
+	switch t {
+	case EventAuthLockout, EventAdminAction:
+		return SeverityHigh
+	case EventAuthFailure, EventPermissionDenied, EventTokenCreated, EventTokenRevoked:
+		return SeverityMedium
+	default:
+		return SeverityLow
+	}
+}
+
+// normalize fills the envelope fields
+func (e *Event) normalize() {
+	e.Schema = SchemaVersion
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Severity == "" {
+		e.Severity = e.Type.defaultSeverity()
+	}
+}
+
+// Emitter publishes security events. Implementations must be safe for
+// concurrent use and must not block request handling for long.
+type Emitter interface {
+	Emit(ctx context.Context, event Event)
+}
+
+// Nop discards every event
+type Nop struct{}
+
+// Emit implements Emitter
+func (Nop) Emit(context.Context, Event) {}
+
+// JSONEmitter writes one JSON object per line (JSON Lines), suitable for a
+// dedicated file or stream picked up by a SIEM agent
+type JSONEmitter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONEmitter creates an emitter writing to w
+func NewJSONEmitter(w io.Writer) *JSONEmitter {
+	return &JSONEmitter{enc: json.NewEncoder(w)}
+}
+
+// Emit implements Emitter. Write errors are dropped: security events must
+// never fail the request that produced them.
+func (e *JSONEmitter) Emit(_ context.Context, event Event) {
+	event.normalize()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(event)
+}
@@ -0,0 +1,45 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONEmitterSchema(t *testing.T) {
+	var buf bytes.Buffer
+	emitter := NewJSONEmitter(&buf)
+
+	emitter.Emit(context.Background(), Event{
+		Type:    EventAuthLockout,
+		Outcome: OutcomeDenied,
+		Reason:  "too_many_failed_attempts",
+		Actor:   Actor{IP: "203.0.113.7"},
+	})
+	emitter.Emit(context.Background(), Event{Type: EventRateLimited, Outcome: OutcomeDenied})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected one JSON line per event, got %d: %s", len(lines), buf.String())
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(lines[0], &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, field := range []string{"schema", "id", "time", "type", "outcome", "severity", "actor"} {
+		if _, ok := got[field]; !ok {
+			t.Errorf("missing field %q in %s", field, lines[0])
+		}
+	}
+	if got["schema"] != SchemaVersion {
+		t.Errorf("schema = %v, want %s", got["schema"], SchemaVersion)
+	}
+	if got["severity"] != string(SeverityHigh) {
+		t.Errorf("lockout severity = %v, want %s", got["severity"], SeverityHigh)
+	}
+	if actor, _ := got["actor"].(map[string]any); actor["ip"] != "203.0.113.7" {
+		t.Errorf("actor = %v", got["actor"])
+	}
+}
@@ -1 +1,186 @@
+// Package server manages the application lifecycle: readiness and the ordered
+// shutdown of every component (listeners, background workers, publishers,
+// connection pools).
 package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Phase orders shutdown hooks: all hooks of a phase finish before the next
+// phase starts. Hooks within a phase run concurrently.
+type Phase int
+
+const (
+	// PhaseListeners stops accepting connections and drains in-flight requests
+	PhaseListeners Phase = iota * 10
+	// PhaseWorkers stops background workers and config watchers
+	PhaseWorkers
+	// PhasePublishers flushes event publishers and outboxes
+	PhasePublishers
+	// PhaseStores closes connection pools and clients (Postgres, Redis)
+	PhaseStores
+)
+
+// DefaultHookTimeout bounds a hook registered without its own timeout
+const DefaultHookTimeout = 5 * time.Second
+
+// Hook is a named shutdown step
+type Hook struct {
+	Name    string
+	Phase   Phase
+	Timeout time.Duration // 0 uses DefaultHookTimeout
+	Fn      func(ctx context.Context) error
+}
+
+// Lifecycle tracks readiness and runs shutdown hooks in phase order
+type Lifecycle struct {
+	mu         sync.Mutex
+	hooks      []Hook
+	ready      atomic.Bool
+	drainDelay time.Duration
+	logg       *logger.Logger
+}
+
+// Option configures a Lifecycle
+type Option func(*Lifecycle)
+
+// WithDrainDelay keeps serving for d after readiness flips to not-ready, giving
+// load balancers time to stop routing new requests before listeners close
+func WithDrainDelay(d time.Duration) Option {
+	return func(l *Lifecycle) {
+		l.drainDelay = d
+	}
+}
+
+// NewLifecycle creates a lifecycle manager; it starts not ready
+func NewLifecycle(logg *logger.Logger, opts ...Option) *Lifecycle {
+	l := &Lifecycle{logg: logg}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// OnShutdown registers fn to run during shutdown in the given phase
+func (l *Lifecycle) OnShutdown(name string, phase Phase, timeout time.Duration, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, Hook{Name: name, Phase: phase, Timeout: timeout, Fn: fn})
+}
+
+// OnClose registers a close function that can't fail or be cancelled, such as
+// pgxpool.Pool.Close, as a shutdown hook
+func (l *Lifecycle) OnClose(name string, phase Phase, closeFn func()) {
+	l.OnShutdown(name, phase, 0, func(context.Context) error {
+		closeFn()
+		return nil
+	})
+}
+
+// MarkReady reports the application ready to receive traffic
+func (l *Lifecycle) MarkReady() {
+	l.ready.Store(true)
+}
+
+// Ready reports whether the application should receive traffic
+// It is false before MarkReady and as soon as shutdown begins
+func (l *Lifecycle) Ready() bool {
+	return l.ready.Load()
+}
+
+// Shutdown flips readiness, waits the drain delay, then runs every hook in
+// phase order. Each hook gets its own timeout (bounded by ctx); failures are
+// logged and returned together, and never stop later hooks from running.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.ready.Store(false)
+
+	if l.drainDelay > 0 {
+		l.logg.Info("readiness withdrawn, waiting before draining", "delay", l.drainDelay)
+		select {
+		case <-time.After(l.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	l.mu.Lock()
+	hooks := append([]Hook(nil), l.hooks...)
+	l.mu.Unlock()
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].Phase < hooks[j].Phase
+	})
+
+	var errs []error
+	for start := 0; start < len(hooks); {
+		end := start
+		for end < len(hooks) && hooks[end].Phase == hooks[start].Phase {
+			end++
+		}
+		errs = append(errs, l.runPhase(ctx, hooks[start:end])...)
+		start = end
+	}
+
+	return errors.Join(errs...)
+}
+
+// runPhase runs hooks concurrently and waits for all of them
+func (l *Lifecycle) runPhase(ctx context.Context, hooks []Hook) []error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, h := range hooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.runHook(ctx, h); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// runHook runs a single hook with its timeout, giving up (but not waiting) on
+// hooks that ignore their context
+func (l *Lifecycle) runHook(ctx context.Context, h Hook) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Fn(hookCtx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-hookCtx.Done():
+		err = hookCtx.Err()
+	}
+
+	if err != nil {
+		l.logg.Error("shutdown hook failed", "hook", h.Name, "error", err, "duration", time.Since(started))
+		return fmt.Errorf("%s: %w", h.Name, err)
+	}
+	l.logg.Info("✓ shutdown hook completed", "hook", h.Name, "duration", time.Since(started))
+	return nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestShutdownRunsPhasesInOrder(t *testing.T) {
+	l := NewLifecycle(logger.NewWithOptions("error", io.Discard, true))
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	// Registered out of order on purpose
+	l.OnShutdown("postgres", PhaseStores, 0, record("postgres"))
+	l.OnShutdown("worker", PhaseWorkers, 0, record("worker"))
+	l.OnShutdown("http", PhaseListeners, 0, record("http"))
+
+	l.MarkReady()
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if l.Ready() {
+		t.Error("expected readiness withdrawn after shutdown")
+	}
+
+	want := []string{"http", "worker", "postgres"}
+	if len(order) != len(want) {
+		t.Fatalf("got hooks %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("got hooks %v, want %v", order, want)
+			break
+		}
+	}
+}
+
+func TestShutdownHookTimeout(t *testing.T) {
+	l := NewLifecycle(logger.NewWithOptions("error", io.Discard, true))
+
+	ranLater := false
+	l.OnShutdown("stuck", PhaseWorkers, 20*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	l.OnShutdown("broken", PhaseWorkers, 0, func(context.Context) error {
+		return errors.New("boom")
+	})
+	l.OnClose("later", PhaseStores, func() { ranLater = true })
+
+	err := l.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected timed out hook to be reported, got %v", err)
+	}
+	if err == nil || !ranLater {
+		t.Error("expected failing hooks not to stop later phases")
+	}
+}
@@ -0,0 +1,54 @@
+package templates
+
+// ReportReady is the data for EmailReportReady
+type ReportReady struct {
+	Name    string // Schedule name
+	RunDate string // e.g. "2025-01-31"
+	Link    string // Download URL
+	Expires string // How long Link stays valid, e.g. "168h0m0s"
+}
+
+// Invoice is the data for InvoiceOrder. Amounts are preformatted for the
+// reader's locale.
+type Invoice struct {
+	Locale     string // BCP 47 tag for the lang attribute
+	Number     string
+	IssuedAt   string
+	Status     string
+	CustomerID string
+	Lines      []InvoiceLine
+	Total      string
+}
+
+// InvoiceLine is one row of an Invoice
+type InvoiceLine struct {
+	Product   string
+	Quantity  int
+	UnitPrice string
+	Amount    string
+}
+
+// Samples returns example data for each built-in template, used by the
+// development preview endpoint
+func Samples() map[string]any {
+	return map[string]any{
+		EmailReportReady: ReportReady{
+			Name:    "Daily order summary",
+			RunDate: "2025-01-31",
+			Link:    "https://example.com/reports/sample.csv",
+			Expires: "168h0m0s",
+		},
+		InvoiceOrder: Invoice{
+			Locale:     "en-US",
+			Number:     "INV-1F2E3D4C",
+			IssuedAt:   "2025-01-31",
+			Status:     "confirmed",
+			CustomerID: "6f1c2a4e-0000-4000-8000-000000000001",
+			Lines: []InvoiceLine{
+				{Product: "notebook", Quantity: 2, UnitPrice: "$4.50", Amount: "$9.00"},
+				{Product: "pen", Quantity: 10, UnitPrice: "$1.25", Amount: "$12.50"},
+			},
+			Total: "$21.50",
+		},
+	}
+}
@@ -0,0 +1,184 @@
+// Package templates renders HTML documents (email notifications, invoices)
+// from html/template files with layout support.
+//
+// Templates live in two layers: defaults embedded in the binary and an
+// optional override directory (TEMPLATES_DIR) whose files replace the
+// embedded ones with the same path or add new ones. A page at
+// <kind>/<name>.html is rendered inside layouts/<kind>.html when that layout
+// exists: the layout calls {{template "content" .}} and pages define
+// "content" (and optionally "title"). Pages without a layout render as is.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//go:embed defaults
+var embedded embed.FS
+
+// Names of the built-in templates
+const (
+	EmailReportReady = "email/report_ready"
+	InvoiceOrder     = "invoice/order"
+)
+
+// ErrNotFound is returned when rendering a template that does not exist
+var ErrNotFound = errors.New("template not found")
+
+const layoutDir = "layouts"
+
+// Engine renders named templates. It is safe for concurrent use.
+type Engine struct {
+	overrideDir string
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// New parses the embedded defaults, overlaid with the templates in
+// overrideDir when it is not empty. Every template is parsed up front so
+// syntax errors fail startup rather than the first render.
+func New(overrideDir string) (*Engine, error) {
+	e := &Engine{overrideDir: overrideDir}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload re-reads the templates, e.g. after editing the override directory.
+// On error the previous templates are kept.
+func (e *Engine) Reload() error {
+	defaults, err := fs.Sub(embedded, "defaults")
+	if err != nil {
+		return err
+	}
+	layers := []fs.FS{defaults}
+	if e.overrideDir != "" {
+		info, err := os.Stat(e.overrideDir)
+		if err != nil {
+			return fmt.Errorf("template override directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("template override directory: %s is not a directory", e.overrideDir)
+		}
+		// Later layers win
+		layers = append(layers, os.DirFS(e.overrideDir))
+	}
+
+	pages, err := parse(layers)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.pages = pages
+	e.mu.Unlock()
+	return nil
+}
+
+// Names lists the renderable templates, sorted
+func (e *Engine) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.pages))
+	for name := range e.pages {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Render executes template name (e.g. "invoice/order") with data into w.
+// Output is buffered, so w receives nothing if execution fails.
+func (e *Engine) Render(w io.Writer, name string, data any) error {
+	e.mu.RLock()
+	t, ok := e.pages[name]
+	e.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// RenderString is Render into a string
+func (e *Engine) RenderString(name string, data any) (string, error) {
+	var b strings.Builder
+	if err := e.Render(&b, name, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parse builds one template set per page from the merged layers
+func parse(layers []fs.FS) (map[string]*template.Template, error) {
+	files := make(map[string]fs.FS) // Path -> layer that provides it
+	for _, layer := range layers {
+		err := fs.WalkDir(layer, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && path.Ext(p) == ".html" {
+				files[p] = layer
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read templates: %w", err)
+		}
+	}
+
+	read := func(p string) (string, error) {
+		b, err := fs.ReadFile(files[p], p)
+		return string(b), err
+	}
+
+	pages := make(map[string]*template.Template)
+	for p := range files {
+		if strings.HasPrefix(p, layoutDir+"/") {
+			continue
+		}
+		name := strings.TrimSuffix(p, ".html")
+		t := template.New(name)
+
+		if kind, _, ok := strings.Cut(name, "/"); ok {
+			layout := path.Join(layoutDir, kind+".html")
+			if _, ok := files[layout]; ok {
+				text, err := read(layout)
+				if err != nil {
+					return nil, err
+				}
+				if _, err := t.Parse(text); err != nil {
+					return nil, fmt.Errorf("failed to parse %s: %w", layout, err)
+				}
+			}
+		}
+
+		text, err := read(p)
+		if err != nil {
+			return nil, err
+		}
+		// The page only defines blocks, so the layout stays the body
+		if _, err := t.Parse(text); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+		pages[name] = t
+	}
+	return pages, nil
+}
@@ -0,0 +1,115 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultsRenderSamples(t *testing.T) {
+	e, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for name, data := range Samples() {
+		out, err := e.RenderString(name, data)
+		if err != nil {
+			t.Errorf("Render(%s) error = %v", name, err)
+			continue
+		}
+		if !strings.Contains(out, "<!DOCTYPE html>") {
+			t.Errorf("Render(%s) did not apply the layout", name)
+		}
+	}
+}
+
+func TestRenderEscapes(t *testing.T) {
+	e, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	out, err := e.RenderString(EmailReportReady, ReportReady{Name: "<script>alert(1)</script>", Link: "javascript:alert(1)"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(out, "<script>") || strings.Contains(out, `href="javascript:`) {
+		t.Errorf("unescaped content in output:\n%s", out)
+	}
+}
+
+func TestOverrideDirectory(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("email/report_ready.html", `{{define "content"}}custom {{.Name}}{{end}}`)
+	write("email/welcome.html", `{{define "content"}}welcome{{end}}`)
+	write("plain.html", `standalone {{.}}`)
+
+	e, err := New(dir)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		data   any
+		want   string
+		layout bool
+	}{
+		{EmailReportReady, ReportReady{Name: "daily"}, "custom daily", true},
+		{"email/welcome", nil, "welcome", true},
+		{InvoiceOrder, Samples()[InvoiceOrder], "Invoice INV-", true},
+		{"plain", "page", "standalone page", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := e.RenderString(tt.name, tt.data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("output missing %q:\n%s", tt.want, out)
+			}
+			if got := strings.Contains(out, "<!DOCTYPE html>"); got != tt.layout {
+				t.Errorf("layout applied = %v, want %v", got, tt.layout)
+			}
+		})
+	}
+}
+
+func TestRenderUnknown(t *testing.T) {
+	e, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := e.Render(&strings.Builder{}, "email/missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Render() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReloadKeepsTemplatesOnError(t *testing.T) {
+	dir := t.TempDir()
+	e, err := New(dir)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.html"), []byte("{{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reload(); err == nil {
+		t.Fatal("Reload() succeeded with a broken template")
+	}
+	if _, err := e.RenderString(InvoiceOrder, Samples()[InvoiceOrder]); err != nil {
+		t.Errorf("previous templates lost after failed reload: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// AccessTokenHandler handles HTTP requests for personal access token management
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type AccessTokenHandler struct {
+	accessTokenService *usecase.AccessTokenService
+	logg               *logger.Logger
+}
+
+// NewAccessTokenHandler creates a new access token handler
+func NewAccessTokenHandler(accessTokenService *usecase.AccessTokenService, logg *logger.Logger) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		accessTokenService: accessTokenService,
+		logg:               logg,
+	}
+}
+
+// CreateAccessTokenRequest represents the request body for minting a token
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"` // 0 uses the server default
+}
+
+// AccessTokenResponse represents a token's metadata (never its secret)
+type AccessTokenResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+	RevokedAt  *string  `json:"revoked_at,omitempty"`
+}
+
+// CreateAccessTokenResponse includes the token secret, returned only once
+type CreateAccessTokenResponse struct {
+	*AccessTokenResponse
+	Token string `json:"token"`
+}
+
+// toAccessTokenResponse converts a domain token to a response DTO
+func toAccessTokenResponse(t *domain.PersonalAccessToken) *AccessTokenResponse {
+	return &AccessTokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt.UTC().Format(time.RFC3339),
+		LastUsedAt: formatOptionalTime(t.LastUsedAt),
+		CreatedAt:  t.CreatedAt.UTC().Format(time.RFC3339),
+		RevokedAt:  formatOptionalTime(t.RevokedAt),
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format(time.RFC3339)
+	return &s
+}
+
+// authorizeTokenOwner resolves the {id} path user and checks the caller may
+// manage their tokens: the user themselves or an admin, using a session token.
+// Tokens can't mint tokens, so a leaked token can't outlive its own revocation.
+func (h *AccessTokenHandler) authorizeTokenOwner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := strings.TrimSpace(r.PathValue("id"))
+	if userID == "" {
+		handleError(w, domain.ErrInvalidUserID)
+		return "", false
+	}
+
+	claims := GetClaims(r.Context())
+	if claims == nil || GetAccessToken(r.Context()) != nil ||
+		(claims.Subject != userID && !claims.HasScope(auth.ScopeAdmin)) {
+		emitPermissionDenied(r, "token_management_not_allowed", nil)
+		handleError(w, domain.ErrForbidden)
+		return "", false
+	}
+
+	return userID, true
+}
+
+// Create handles POST /api/users/{id}/tokens
+func (h *AccessTokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizeTokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAccessTokenRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		respondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Name is required")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		respondErrorWithDetails(w, http.StatusBadRequest, "VALIDATION_ERROR", "At least one scope is required",
+			map[string]string{"allowed_scopes": strings.Join(domain.AccessTokenScopes, " ")})
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		respondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "expires_in_days must not be negative")
+		return
+	}
+
+	lifetime := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, secret, err := h.accessTokenService.CreateToken(r.Context(), userID, req.Name, req.Scopes, lifetime)
+	if err != nil {
+		h.logg.Error("failed to create access token", "error", err, "user_id", userID)
+		handleError(w, err)
+		return
+	}
+
+	emitTokenEvent(r, security.EventTokenCreated, userID, token.ID, map[string]string{
+		"scopes":     strings.Join(token.Scopes, " "),
+		"expires_at": token.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+	respondJSON(w, http.StatusCreated, &CreateAccessTokenResponse{
+		AccessTokenResponse: toAccessTokenResponse(token),
+		Token:               secret,
+	})
+}
+
+// List handles GET /api/users/{id}/tokens
+func (h *AccessTokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizeTokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.accessTokenService.ListTokens(r.Context(), userID)
+	if err != nil {
+		h.logg.Error("failed to list access tokens", "error", err, "user_id", userID)
+		handleError(w, err)
+		return
+	}
+
+	result := make([]*AccessTokenResponse, len(tokens))
+	for i, t := range tokens {
+		result[i] = toAccessTokenResponse(t)
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// Revoke handles DELETE /api/users/{id}/tokens/{token_id}
+func (h *AccessTokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizeTokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID := strings.TrimSpace(r.PathValue("token_id"))
+	if err := h.accessTokenService.RevokeToken(r.Context(), userID, tokenID); err != nil {
+		h.logg.Error("failed to revoke access token", "error", err, "user_id", userID, "token_id", tokenID)
+		handleError(w, err)
+		return
+	}
+	emitTokenEvent(r, security.EventTokenRevoked, userID, tokenID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// emitTokenEvent records a personal access token lifecycle change, flagged as
+// an admin action when the caller manages another user's tokens
+func emitTokenEvent(r *http.Request, eventType security.EventType, userID, tokenID string, details map[string]string) {
+	action := "manage_own_tokens"
+	if GetUserID(r.Context()) != userID {
+		action = "manage_user_tokens"
+	}
+	emitSecurityEvent(r, security.Event{
+		Type:    eventType,
+		Outcome: security.OutcomeSuccess,
+		Action:  action,
+		Target:  &security.Target{Type: "access_token", ID: tokenID},
+		Details: details,
+	})
+}
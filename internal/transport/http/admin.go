@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Internal Admin Listener
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// adminRouteSplitter sends /api/admin/ routes to the admin mux and everything
+// else to the public one, so handlers register their routes once
+type adminRouteSplitter struct {
+	public routeRegistrar
+	admin  routeRegistrar
+}
+
+// HandleFunc registers handler on the mux that serves pattern
+func (s adminRouteSplitter) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	if isAdminPattern(pattern) {
+		s.admin.HandleFunc(pattern, handler)
+		return
+	}
+	s.public.HandleFunc(pattern, handler)
+}
+
+// isAdminPattern reports whether a mux pattern ("POST /api/admin/...") is an admin route
+func isAdminPattern(pattern string) bool {
+	_, path, found := strings.Cut(pattern, " ")
+	if !found {
+		path = pattern
+	}
+	return strings.HasPrefix(path, "/api/admin/")
+}
+
+// registerInternalRoutes sets up the routes only served on the admin listener
+func registerInternalRoutes(mux routeRegistrar, diagnosticsHandler *DiagnosticsHandler) {
+	if diagnosticsHandler != nil {
+		mux.HandleFunc("GET /metrics", diagnosticsHandler.Stats)
+	}
+
+	// Profiling; see https://pkg.go.dev/net/http/pprof
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+}
+
+// newAdminHandler wraps the admin mux with the internal listener's stack:
+// no CORS or rate limits, and every route but the health checks requires a
+// JWT with the admin scope (no cookies, no personal access tokens)
+func newAdminHandler(config RouterConfig, mux *http.ServeMux) http.Handler {
+	return middleware.Chain(mux,
+		middleware.RequestID(),
+		middleware.ForwardedFor(config.TrustedProxies),
+		APIVersioning(config.APIVersions),
+		SecurityEvents(config.SecurityEvents),
+		middleware.Recover(config.Logger),
+		middleware.Logging(config.Logger),
+		middleware.SecureHeaders(),
+		middleware.MaxBodySize(config.MaxBodySize),
+		RouteMethods(mux),
+		Authenticate(AuthenticateConfig{
+			Tokens:      config.Tokens,
+			Revocations: config.Revocations,
+			Guard:       config.BruteForce,
+			Logger:      config.Logger,
+		}),
+		RequireScope(auth.ScopeAdmin),
+		middleware.ContentType(codecMediaTypes()...),
+	)
+}
+
+// RequireScope rejects authenticated requests to non-public routes whose
+// token lacks scope
+func RequireScope(scope string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if claims := GetClaims(r.Context()); claims == nil || !claims.HasScope(scope) {
+				emitPermissionDenied(r, scope+"_scope_required", map[string]string{"path": r.URL.Path})
+				handleError(w, domain.ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestAdminRouteSplitter(t *testing.T) {
+	public, admin := http.NewServeMux(), http.NewServeMux()
+	splitter := adminRouteSplitter{public: public, admin: admin}
+	noContent := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+
+	tests := []struct {
+		pattern string
+		method  string
+		target  string
+		admin   bool
+	}{
+		{"GET /api/admin/reports", http.MethodGet, "/api/admin/reports", true},
+		{"POST /api/admin/users/{id}/revoke-tokens", http.MethodPost, "/api/admin/users/u1/revoke-tokens", true},
+		{"/api/admin/slo", http.MethodGet, "/api/admin/slo", true},
+		{"GET /api/users/{id}", http.MethodGet, "/api/users/u1", false},
+		{"GET /api/administrators", http.MethodGet, "/api/administrators", false},
+		{"GET /internal/api/admin/", http.MethodGet, "/internal/api/admin/", false},
+	}
+	for _, tt := range tests {
+		splitter.HandleFunc(tt.pattern, noContent)
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			r := limitRequest(tt.method, tt.target, "", "10.0.0.1")
+			_, onAdmin := admin.Handler(r)
+			_, onPublic := public.Handler(r)
+			if (onAdmin == tt.pattern) != tt.admin || (onPublic == tt.pattern) == tt.admin {
+				t.Errorf("admin mux matched %q, public mux %q, want admin = %v", onAdmin, onPublic, tt.admin)
+			}
+		})
+	}
+}
+
+// adminRouter is the full router over the users of orderHandler and an empty
+// dead letter queue, with admin routes on their own listener when separate
+func adminRouter(t *testing.T, separate bool) (*Router, *auth.TokenManager) {
+	t.Helper()
+	logg := logger.New("error")
+	tokens := newTestTokens(t)
+	_, users, _ := orderHandler(t)
+	router := NewRouter(RouterConfig{
+		Logger:        logg,
+		Tokens:        tokens,
+		SeparateAdmin: separate,
+	}, Handlers{
+		User:       NewUserHandler(usecase.NewUserService(users, memory.NewUserCache(), logg), logg),
+		Order:      &OrderHandler{},
+		DeadLetter: NewDeadLetterHandler(usecase.NewDeadLetterService(memory.NewDeadLetterQueue(), time.Hour, logg), logg),
+	})
+	return router, tokens
+}
+
+func TestRouterSeparateAdmin(t *testing.T) {
+	router, tokens := adminRouter(t, true)
+	if router.Admin == nil {
+		t.Fatal("Admin handler is nil with SeparateAdmin set")
+	}
+	adminToken := issueTestToken(t, tokens, auth.Claims{Subject: "admin-1", Scope: auth.ScopeAdmin})
+	userToken := issueTestToken(t, tokens, auth.Claims{Subject: "u1"})
+
+	tests := []struct {
+		name       string
+		target     string
+		token      string
+		wantPublic int
+		wantAdmin  int
+	}{
+		{"admin route", "/api/admin/dead-letters", adminToken, http.StatusNotFound, http.StatusOK},
+		{"public route", "/api/users/u1", adminToken, http.StatusOK, http.StatusNotFound},
+		{"profiling", "/debug/pprof/cmdline", adminToken, http.StatusNotFound, http.StatusOK},
+		{"health check without a token", "/health", "", http.StatusOK, http.StatusOK},
+		// The admin listener wants an admin token for everything else
+		{"admin route without the admin scope", "/api/admin/dead-letters", userToken, http.StatusNotFound, http.StatusForbidden},
+		{"admin route without a token", "/api/admin/dead-letters", "", http.StatusUnauthorized, http.StatusUnauthorized},
+		{"public route without the admin scope", "/api/users/u1", userToken, http.StatusOK, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(router, authRequest(http.MethodGet, tt.target, tt.token)); rec.Code != tt.wantPublic {
+				t.Errorf("public listener = %d %q, want %d", rec.Code, rec.Body, tt.wantPublic)
+			}
+			if rec := serve(router.Admin, authRequest(http.MethodGet, tt.target, tt.token)); rec.Code != tt.wantAdmin {
+				t.Errorf("admin listener = %d %q, want %d", rec.Code, rec.Body, tt.wantAdmin)
+			}
+		})
+	}
+}
+
+func TestRouterWithoutSeparateAdmin(t *testing.T) {
+	router, tokens := adminRouter(t, false)
+	if router.Admin != nil {
+		t.Error("Admin handler is set without SeparateAdmin")
+	}
+	adminToken := issueTestToken(t, tokens, auth.Claims{Subject: "admin-1", Scope: auth.ScopeAdmin})
+
+	// Admin routes stay on the public listener; the internal routes are not served
+	for target, want := range map[string]int{
+		"/api/admin/dead-letters": http.StatusOK,
+		"/api/users/u1":           http.StatusOK,
+		"/debug/pprof/cmdline":    http.StatusNotFound,
+	} {
+		if rec := serve(router, authRequest(http.MethodGet, target, adminToken)); rec.Code != want {
+			t.Errorf("GET %s = %d %q, want %d", target, rec.Code, rec.Body, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/alerting"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ReportAlerts reports every /api request to monitor under its route
+// pattern (from resolve), with the panic value of handlers that panicked.
+// It must run inside middleware.Recover: panics are recorded, then passed
+// on for Recover to log and answer. Event streams are not counted.
+func ReportAlerts(monitor *alerting.Monitor, resolve func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") || isEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			sample := alerting.Sample{
+				Time:      time.Now(),
+				Method:    r.Method,
+				Route:     resolve(r),
+				Path:      r.URL.Path,
+				RequestID: middleware.GetRequestID(r.Context()),
+			}
+			rw := middleware.NewResponseWriter(w)
+			defer func() {
+				sample.Duration = time.Since(sample.Time)
+				sample.Status = rw.Status()
+				if err := recover(); err != nil {
+					// An aborted handler is a client gone away, not a bug
+					if e, ok := err.(error); !ok || !errors.Is(e, http.ErrAbortHandler) {
+						sample.Status, sample.Panic = http.StatusInternalServerError, logger.Scrub(fmt.Sprint(err))
+						monitor.Observe(sample)
+					}
+					panic(err)
+				}
+				monitor.Observe(sample)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// AttachmentHandler handles HTTP requests for attachments
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type AttachmentHandler struct {
+	attachmentService *usecase.AttachmentService
+	logg              *logger.Logger
+}
+
+// NewAttachmentHandler creates a new attachment handler
+func NewAttachmentHandler(attachmentService *usecase.AttachmentService, logg *logger.Logger) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		logg:              logg,
+	}
+}
+
+// CreateAttachmentRequest represents the request body for starting an upload
+type CreateAttachmentRequest struct {
+	Filename     string `json:"filename"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`          // Bytes
+	UploadMethod string `json:"upload_method"` // "put" (default) or "post" for browser form uploads
+}
+
+// CompleteUploadRequest optionally carries the client's digest of the file
+type CompleteUploadRequest struct {
+	ChecksumSHA256 string `json:"checksum_sha256"` // Hex; the stored object is read back and compared
+}
+
+// ScanResultRequest represents a malware scanner's verdict
+type ScanResultRequest struct {
+	Status string `json:"status"` // "clean" or "infected"
+	Detail string `json:"detail"` // e.g. the threat name
+}
+
+// AttachmentResponse represents attachment metadata
+type AttachmentResponse struct {
+	ID          string  `json:"id"`
+	Filename    string  `json:"filename"`
+	ContentType string  `json:"content_type"`
+	Size        int64   `json:"size"`
+	Uploaded    bool    `json:"uploaded"`    // Completion verified against the stored object
+	ScanStatus  string  `json:"scan_status"` // pending, clean or infected
+	ScannedAt   *string `json:"scanned_at"`
+	UploadedAt  *string `json:"uploaded_at"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+// CreateAttachmentResponse is returned when an upload starts. For "put",
+// PUT the file to upload_url with the same Content-Type; for "post", submit a
+// multipart form to upload_url with every upload_fields entry followed by the
+// file as the last field. Either way, before upload_expires_at, then call
+// POST /api/blobs/{key}/complete.
+type CreateAttachmentResponse struct {
+	Attachment      *AttachmentResponse `json:"attachment"`
+	UploadMethod    string              `json:"upload_method"`
+	UploadURL       string              `json:"upload_url"`
+	UploadFields    map[string]string   `json:"upload_fields,omitempty"`
+	UploadExpiresAt string              `json:"upload_expires_at"`
+}
+
+// DownloadResponse carries a presigned download URL
+type DownloadResponse struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// toAttachmentResponse converts a domain attachment to a response DTO
+func toAttachmentResponse(a *domain.Attachment) *AttachmentResponse {
+	return &AttachmentResponse{
+		ID:          a.ID,
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		Uploaded:    a.Uploaded(),
+		ScanStatus:  string(a.ScanStatus),
+		ScannedAt:   formatOptionalTime(a.ScannedAt),
+		UploadedAt:  formatOptionalTime(a.UploadedAt),
+		CreatedAt:   a.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// Create handles POST /api/attachments
+func (h *AttachmentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return
+	}
+
+	var req CreateAttachmentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	switch strings.ToLower(req.UploadMethod) {
+	case "", "put":
+		attachment, upload, err := h.attachmentService.CreateUpload(r.Context(), claims.Subject, req.Filename, req.ContentType, req.Size)
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusCreated, &CreateAttachmentResponse{
+			Attachment:      toAttachmentResponse(attachment),
+			UploadMethod:    "put",
+			UploadURL:       upload.URL,
+			UploadExpiresAt: upload.ExpiresAt.UTC().Format(time.RFC3339),
+		})
+	case "post":
+		attachment, upload, err := h.attachmentService.CreatePostUpload(r.Context(), claims.Subject, req.Filename, req.ContentType, req.Size)
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusCreated, &CreateAttachmentResponse{
+			Attachment:      toAttachmentResponse(attachment),
+			UploadMethod:    "post",
+			UploadURL:       upload.URL,
+			UploadFields:    upload.Fields,
+			UploadExpiresAt: upload.ExpiresAt.UTC().Format(time.RFC3339),
+		})
+	default:
+		respondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "upload_method must be put or post")
+	}
+}
+
+// List handles GET /api/attachments, listing the caller's attachments
+func (h *AttachmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return
+	}
+
+	var params PaginationParams
+	if !bindQueryOrRespond(w, r, &params) {
+		return
+	}
+
+	attachments, err := h.attachmentService.ListByOwner(r.Context(), claims.Subject, params.Limit, params.Offset)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := make([]*AttachmentResponse, len(attachments))
+	for i, a := range attachments {
+		response[i] = toAttachmentResponse(a)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"attachments": response,
+		"limit":       params.Limit,
+		"offset":      params.Offset,
+	})
+}
+
+// GetByID handles GET /api/attachments/{id}
+func (h *AttachmentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, toAttachmentResponse(attachment))
+}
+
+// Download handles GET /api/attachments/{id}/download
+// URLs are only issued once the attachment has scanned clean
+func (h *AttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	download, err := h.attachmentService.DownloadURL(r.Context(), attachment)
+	if err != nil {
+		if attachment.ScanStatus == domain.ScanStatusInfected {
+			emitPermissionDenied(r, "attachment_infected", map[string]string{"attachment_id": attachment.ID})
+		}
+		handleError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &DownloadResponse{
+		URL:       download.URL,
+		ExpiresAt: download.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// CompleteUpload handles POST /api/blobs/{key}/complete, called by the client
+// once its upload finished. The stored object is checked against the upload
+// (existence, size, content type and optional checksum) before the attachment
+// counts as uploaded. Only the owner can complete an upload.
+func (h *AttachmentHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return
+	}
+
+	var req CompleteUploadRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+	}
+
+	key := r.PathValue("key")
+	if key == "" {
+		handleError(w, domain.ErrInvalidInput)
+		return
+	}
+	attachment, err := h.attachmentService.GetByKey(r.Context(), key)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if attachment.OwnerID != claims.Subject {
+		// Reported as missing so keys can't be probed
+		handleError(w, domain.ErrAttachmentNotFound)
+		return
+	}
+
+	attachment, err = h.attachmentService.CompleteUpload(r.Context(), attachment, req.ChecksumSHA256)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, toAttachmentResponse(attachment))
+}
+
+// RecordScanResult handles POST /api/admin/attachments/{id}/scan-result,
+// called by the malware scanner (requires the admin scope)
+func (h *AttachmentHandler) RecordScanResult(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil || !claims.HasScope(auth.ScopeAdmin) {
+		emitPermissionDenied(r, "admin_scope_required", nil)
+		handleError(w, domain.ErrForbidden)
+		return
+	}
+
+	var req ScanResultRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	id := strings.TrimSpace(r.PathValue("id"))
+	attachment, err := h.attachmentService.RecordScanResult(r.Context(), id, domain.ScanStatus(req.Status), req.Detail)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventAdminAction,
+		Outcome: security.OutcomeSuccess,
+		Action:  "record_scan_result",
+		Target:  &security.Target{Type: "attachment", ID: attachment.ID},
+		Details: map[string]string{"scan_status": string(attachment.ScanStatus)},
+	})
+	respondJSON(w, http.StatusOK, toAttachmentResponse(attachment))
+}
+
+// load fetches the attachment named by the path. Another user's attachment
+// is reported as missing so IDs can't be probed; admins see every attachment.
+func (h *AttachmentHandler) load(w http.ResponseWriter, r *http.Request) (*domain.Attachment, bool) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		handleError(w, domain.ErrInvalidInput)
+		return nil, false
+	}
+
+	attachment, err := h.attachmentService.Get(r.Context(), id)
+	if err != nil {
+		handleError(w, err)
+		return nil, false
+	}
+
+	if claims := GetClaims(r.Context()); claims != nil &&
+		attachment.OwnerID != claims.Subject && !claims.HasScope(auth.ScopeAdmin) {
+		handleError(w, domain.ErrAttachmentNotFound)
+		return nil, false
+	}
+	return attachment, true
+}
@@ -0,0 +1,362 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Authentication Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// publicPaths are reachable without a token
+var publicPaths = map[string]bool{
+	"/health": true,
+	"/ready":  true,
+	"/status": true,
+}
+
+// GetUserID retrieves the authenticated user ID (the token subject) from context
+func GetUserID(ctx context.Context) string {
+	if id, ok := ctx.Value(UserIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// GetAccessToken retrieves the personal access token the request authenticated
+// with, or nil for session (JWT) authentication
+func GetAccessToken(ctx context.Context) *domain.PersonalAccessToken {
+	if token, ok := ctx.Value(AccessTokenKey).(*domain.PersonalAccessToken); ok {
+		return token
+	}
+	return nil
+}
+
+// CredentialSource is where an authenticated request presented its token
+type CredentialSource string
+
+const (
+	// CredentialBearer is an Authorization: Bearer header, which browsers never send on their own
+	CredentialBearer CredentialSource = "bearer"
+	// CredentialCookie is the session cookie, which browsers attach to cross-site requests
+	CredentialCookie CredentialSource = "cookie"
+)
+
+// GetCredentialSource retrieves where the request's token came from, or ""
+// for unauthenticated requests
+func GetCredentialSource(ctx context.Context) CredentialSource {
+	source, _ := ctx.Value(CredentialSourceKey).(CredentialSource)
+	return source
+}
+
+// GetClaims retrieves the verified token claims from context
+func GetClaims(ctx context.Context) *auth.Claims {
+	if claims, ok := ctx.Value(ClaimsKey).(*auth.Claims); ok {
+		return claims
+	}
+	return nil
+}
+
+// AuthenticateConfig configures the authentication middleware
+type AuthenticateConfig struct {
+	Tokens *auth.TokenManager
+	// SessionCookie also accepts the token from this cookie when no Authorization
+	// header is sent (empty disables); pair it with CSRF protection
+	SessionCookie string
+	// AccessTokens accepts personal access tokens (pat_...) alongside JWTs (nil disables)
+	AccessTokens *usecase.AccessTokenService
+	// Revocations rejects revoked tokens (nil disables); fails closed so a
+	// denylist outage never lets a revoked token through
+	Revocations domain.TokenRevocationStore
+	// Guard locks out IPs that repeatedly present invalid tokens (nil disables)
+	Guard  *usecase.BruteForceGuard
+	Logger *logger.Logger
+}
+
+// Authenticate requires a valid bearer token on every non-public route and
+// stores the verified claims and user ID in the request context
+func Authenticate(config AuthenticateConfig) Middleware {
+	logg := config.Logger
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ipKey := usecase.IPKey(middleware.ClientIP(r))
+			if config.Guard != nil {
+				if decision := config.Guard.Check(r.Context(), ipKey); decision.Locked {
+					respondLockedOut(w, decision)
+					return
+				}
+			}
+
+			source := CredentialBearer
+			token, ok := bearerToken(r)
+			if !ok && config.SessionCookie != "" {
+				source = CredentialCookie
+				token, ok = cookieToken(r, config.SessionCookie)
+			}
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				respondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing bearer token")
+				return
+			}
+
+			if config.AccessTokens != nil && usecase.IsAccessToken(token) {
+				authenticateAccessToken(w, r, next, config, token, source, ipKey)
+				return
+			}
+
+			claims, err := config.Tokens.Verify(token)
+			if err != nil {
+				logg.Debug("token rejected",
+					"request_id", GetRequestID(r.Context()),
+					"error", err,
+				)
+				message := "Invalid token"
+				if errors.Is(err, auth.ErrTokenExpired) {
+					message = "Token expired"
+				}
+
+				// Expired tokens are normal client behaviour, not an attack
+				var decision usecase.AttemptDecision
+				if !errors.Is(err, auth.ErrTokenExpired) {
+					if config.Guard != nil {
+						decision = config.Guard.RecordFailure(r.Context(), ipKey)
+					}
+					emitAuthFailure(r, "invalid_token", decision.Locked)
+					if decision.Locked {
+						respondLockedOut(w, decision)
+						return
+					}
+				}
+
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				respondAuthFailure(w, message, decision)
+				return
+			}
+
+			if rejectRevoked(w, r, config, claims.ID, claims.Subject, claims.IssuedAtTime()) {
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ClaimsKey, claims)
+			ctx = context.WithValue(ctx, UserIDKey, claims.Subject)
+			ctx = context.WithValue(ctx, CredentialSourceKey, source)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticateAccessToken authenticates a request presenting a personal access
+// token. Route-level scope and ownership checks happen in restrictAccessTokens.
+func authenticateAccessToken(w http.ResponseWriter, r *http.Request, next http.Handler, config AuthenticateConfig, token string, source CredentialSource, ipKey string) {
+	pat, err := config.AccessTokens.Authenticate(r.Context(), token)
+	if err != nil {
+		if !errors.Is(err, domain.ErrUnauthorized) {
+			config.Logger.Error("access token lookup failed",
+				"request_id", GetRequestID(r.Context()),
+				"error", err,
+			)
+			respondError(w, http.StatusServiceUnavailable,
+				"SERVICE_UNAVAILABLE", "Authentication temporarily unavailable")
+			return
+		}
+
+		var decision usecase.AttemptDecision
+		if config.Guard != nil {
+			decision = config.Guard.RecordFailure(r.Context(), ipKey)
+		}
+		emitAuthFailure(r, "invalid_access_token", decision.Locked)
+		if decision.Locked {
+			respondLockedOut(w, decision)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		respondAuthFailure(w, "Invalid token", decision)
+		return
+	}
+
+	// Revoking every token of the user (logout-all, the admin compromise
+	// response) covers access tokens as well as sessions
+	if rejectRevoked(w, r, config, pat.ID, pat.UserID, pat.CreatedAt) {
+		return
+	}
+
+	// Expose the token as claims too, so handlers see one shape of identity
+	claims := &auth.Claims{
+		Subject:   pat.UserID,
+		ID:        pat.ID,
+		IssuedAt:  pat.CreatedAt.Unix(),
+		ExpiresAt: pat.ExpiresAt.Unix(),
+		Scope:     strings.Join(pat.Scopes, " "),
+	}
+	ctx := context.WithValue(r.Context(), ClaimsKey, claims)
+	ctx = context.WithValue(ctx, UserIDKey, pat.UserID)
+	ctx = context.WithValue(ctx, AccessTokenKey, pat)
+	ctx = context.WithValue(ctx, CredentialSourceKey, source)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// rejectRevoked answers the request itself when the token tokenID of userID,
+// issued at issuedAt, was revoked or the revocation store cannot be reached.
+// It reports whether it did.
+func rejectRevoked(w http.ResponseWriter, r *http.Request, config AuthenticateConfig, tokenID, userID string, issuedAt time.Time) bool {
+	if config.Revocations == nil {
+		return false
+	}
+	revoked, err := config.Revocations.IsRevoked(r.Context(), tokenID, userID, issuedAt)
+	if err != nil {
+		config.Logger.Error("token revocation check failed",
+			"request_id", GetRequestID(r.Context()),
+			"error", err,
+		)
+		respondError(w, http.StatusServiceUnavailable,
+			"SERVICE_UNAVAILABLE", "Authentication temporarily unavailable")
+		return true
+	}
+	if !revoked {
+		return false
+	}
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventAuthFailure,
+		Outcome: security.OutcomeFailure,
+		Reason:  "token_revoked",
+		Actor:   security.Actor{UserID: userID, TokenID: tokenID},
+	})
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	respondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Token revoked")
+	return true
+}
+
+// respondAuthFailure writes a 401, signaling when the client must solve a CAPTCHA
+// before its next attempt (X-Captcha-Required header and captcha_required detail)
+func respondAuthFailure(w http.ResponseWriter, message string, decision usecase.AttemptDecision) {
+	if !decision.CaptchaRequired {
+		respondError(w, http.StatusUnauthorized, "UNAUTHORIZED", message)
+		return
+	}
+	w.Header().Set("X-Captcha-Required", "true")
+	respondErrorWithDetails(w, http.StatusUnauthorized, "UNAUTHORIZED", message,
+		map[string]string{"captcha_required": "true"})
+}
+
+// respondLockedOut writes a 429 for a client locked out after repeated failures
+func respondLockedOut(w http.ResponseWriter, decision usecase.AttemptDecision) {
+	retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	if decision.CaptchaRequired {
+		w.Header().Set("X-Captcha-Required", "true")
+	}
+	respondErrorWithDetails(w, http.StatusTooManyRequests, "TOO_MANY_FAILED_ATTEMPTS",
+		"Too many failed authentication attempts, please try again later",
+		map[string]string{
+			"retry_after_seconds": strconv.Itoa(retryAfter),
+			"captcha_required":    strconv.FormatBool(decision.CaptchaRequired),
+		})
+}
+
+// cookieToken extracts the token from the session cookie
+func cookieToken(r *http.Request, name string) (string, bool) {
+	c, err := r.Cookie(name)
+	if err != nil || c.Value == "" {
+		return "", false
+	}
+	return c.Value, true
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Personal Access Token Restrictions
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// accessTokenRule is the scope a route requires from a personal access token,
+// and the path parameter that must equal the token owner's user ID
+type accessTokenRule struct {
+	Scope     string
+	UserParam string
+}
+
+// accessTokenRoutes lists the only routes reachable with a personal access
+// token, keyed by mux pattern. Everything else (listing all users, token
+// management, admin endpoints) requires a session token.
+var accessTokenRoutes = map[string]accessTokenRule{
+	"GET /api/users/{id}":             {Scope: domain.ScopeUsersRead, UserParam: "id"},
+	"PUT /api/users/{id}":             {Scope: domain.ScopeUsersWrite, UserParam: "id"},
+	"PATCH /api/users/{id}":           {Scope: domain.ScopeUsersWrite, UserParam: "id"},
+	"GET /api/users/{user_id}/orders": {Scope: domain.ScopeOrdersRead, UserParam: "user_id"},
+}
+
+// accessTokenMux registers handlers wrapped with restrictAccessTokens, so
+// restrictions apply to every route without each handler opting in
+type accessTokenMux struct {
+	*http.ServeMux
+}
+
+// HandleFunc registers handler for pattern with personal access token restrictions
+func (m accessTokenMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.ServeMux.Handle(pattern, restrictAccessTokens(pattern, http.HandlerFunc(handler)))
+}
+
+// restrictAccessTokens rejects personal access tokens on routes not listed in
+// accessTokenRoutes, lacking the route's scope, or targeting another user.
+// Session-authenticated requests pass through unchanged.
+func restrictAccessTokens(pattern string, next http.Handler) http.Handler {
+	rule, allowed := accessTokenRoutes[pattern]
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pat := GetAccessToken(r.Context())
+		if pat == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !allowed {
+			emitPermissionDenied(r, "access_token_route_not_allowed", map[string]string{"route": pattern})
+			respondError(w, http.StatusForbidden, "FORBIDDEN",
+				"This endpoint is not available to personal access tokens")
+			return
+		}
+		if !pat.HasScope(rule.Scope) {
+			emitPermissionDenied(r, "insufficient_scope", map[string]string{"required_scope": rule.Scope})
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+rule.Scope+`"`)
+			respondErrorWithDetails(w, http.StatusForbidden, "INSUFFICIENT_SCOPE",
+				"Token lacks the required scope",
+				map[string]string{"required_scope": rule.Scope})
+			return
+		}
+		if rule.UserParam != "" && r.PathValue(rule.UserParam) != pat.UserID {
+			emitPermissionDenied(r, "access_token_not_owner", nil)
+			handleError(w, domain.ErrForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
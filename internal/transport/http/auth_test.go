@@ -0,0 +1,282 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// authFixture is the Authenticate middleware in front of a mux restricting
+// personal access tokens, with session routes and stand-ins for user routes
+type authFixture struct {
+	handler      http.Handler
+	tokens       *auth.TokenManager
+	accessTokens *usecase.AccessTokenService
+	revocations  domain.TokenRevocationStore
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+	logg := logger.New("error")
+	accessTokenRepo := memory.NewAccessTokenRepository()
+	f := &authFixture{
+		tokens: newTestTokens(t),
+		accessTokens: usecase.NewAccessTokenService(accessTokenRepo, usecase.AccessTokenPolicy{
+			DefaultLifetime: time.Hour,
+			MaxLifetime:     24 * time.Hour,
+		}, logg),
+		revocations: memory.NewRevocationStore(),
+	}
+
+	// Answers with the authenticated user, so tests see who got through
+	whoami := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-User-ID", GetUserID(r.Context()))
+		w.Header().Set("X-Credential-Source", string(GetCredentialSource(r.Context())))
+		w.WriteHeader(http.StatusOK)
+	}
+	mux := accessTokenMux{http.NewServeMux()}
+	mux.HandleFunc("GET /health", whoami)
+	mux.HandleFunc("GET /api/users", whoami)
+	mux.HandleFunc("GET /api/users/{id}", whoami)
+	mux.HandleFunc("PUT /api/users/{id}", whoami)
+	mux.HandleFunc("GET /api/users/{user_id}/orders", whoami)
+	sessions := NewSessionHandler(usecase.NewSessionService(f.revocations, accessTokenRepo, time.Hour, logg),
+		SessionCookies{Session: "session"}, logg)
+	registerSessionRoutes(mux, sessions)
+
+	f.handler = Authenticate(AuthenticateConfig{
+		Tokens:        f.tokens,
+		SessionCookie: "session",
+		AccessTokens:  f.accessTokens,
+		Revocations:   f.revocations,
+		Logger:        logg,
+	})(mux)
+	return f
+}
+
+// accessToken creates a personal access token for userID with scopes and returns its secret
+func (f *authFixture) accessToken(t *testing.T, userID string, scopes ...string) (*domain.PersonalAccessToken, string) {
+	t.Helper()
+	pat, secret, err := f.accessTokens.CreateToken(context.Background(), userID, "test", scopes, 0)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	return pat, secret
+}
+
+func authRequest(method, target, token string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	f := newAuthFixture(t)
+	session := issueTestToken(t, f.tokens, auth.Claims{Subject: "user-1"})
+
+	revoked := issueTestToken(t, f.tokens, auth.Claims{Subject: "user-1", ID: "revoked-jti"})
+	f.revocations.RevokeToken(context.Background(), "revoked-jti", time.Hour)
+
+	keys, err := auth.LoadKeySet(testJWTSecret, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	past, err := auth.NewTokenManager(keys, time.Hour,
+		auth.WithClock(func() time.Time { return time.Now().Add(-2 * time.Hour) }))
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	expired := issueTestToken(t, past, auth.Claims{Subject: "user-1"})
+
+	_, pat := f.accessToken(t, "user-1", domain.ScopeUsersRead)
+	revokedPAT, revokedSecret := f.accessToken(t, "user-1", domain.ScopeUsersRead)
+	if err := f.accessTokens.RevokeToken(context.Background(), "user-1", revokedPAT.ID); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		request     *http.Request
+		wantStatus  int
+		wantMessage string
+		wantSource  CredentialSource
+	}{
+		{"public path", authRequest(http.MethodGet, "/health", ""), http.StatusOK, "", ""},
+		{"missing token", authRequest(http.MethodGet, "/api/users/user-1", ""), http.StatusUnauthorized, "Missing bearer token", ""},
+		{"malformed token", authRequest(http.MethodGet, "/api/users/user-1", "not-a-jwt"), http.StatusUnauthorized, "Invalid token", ""},
+		{"expired token", authRequest(http.MethodGet, "/api/users/user-1", expired), http.StatusUnauthorized, "Token expired", ""},
+		{"revoked token", authRequest(http.MethodGet, "/api/users/user-1", revoked), http.StatusUnauthorized, "Token revoked", ""},
+		{"session token", authRequest(http.MethodGet, "/api/users/user-1", session), http.StatusOK, "", CredentialBearer},
+		{
+			name: "session cookie",
+			request: func() *http.Request {
+				r := authRequest(http.MethodGet, "/api/users/user-1", "")
+				r.AddCookie(&http.Cookie{Name: "session", Value: session})
+				return r
+			}(),
+			wantStatus: http.StatusOK,
+			wantSource: CredentialCookie,
+		},
+		{"access token", authRequest(http.MethodGet, "/api/users/user-1", pat), http.StatusOK, "", CredentialBearer},
+		{"unknown access token", authRequest(http.MethodGet, "/api/users/user-1", "pat_unknown"), http.StatusUnauthorized, "Invalid token", ""},
+		{"revoked access token", authRequest(http.MethodGet, "/api/users/user-1", revokedSecret), http.StatusUnauthorized, "Invalid token", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(f.handler, tt.request)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantMessage != "" && !strings.Contains(rec.Body.String(), tt.wantMessage) {
+				t.Errorf("body = %s, want %q", rec.Body, tt.wantMessage)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if got := CredentialSource(rec.Header().Get("X-Credential-Source")); got != tt.wantSource {
+				t.Errorf("credential source = %q, want %q", got, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestAccessTokenRestrictions(t *testing.T) {
+	f := newAuthFixture(t)
+	session := issueTestToken(t, f.tokens, auth.Claims{Subject: "user-1"})
+	_, reader := f.accessToken(t, "user-1", domain.ScopeUsersRead)
+	_, writer := f.accessToken(t, "user-1", domain.ScopeUsersWrite)
+	_, orders := f.accessToken(t, "user-1", domain.ScopeOrdersRead)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"own user with users:read", http.MethodGet, "/api/users/user-1", reader, http.StatusOK, ""},
+		{"own user update with users:write", http.MethodPut, "/api/users/user-1", writer, http.StatusOK, ""},
+		{"own orders with orders:read", http.MethodGet, "/api/users/user-1/orders", orders, http.StatusOK, ""},
+		{"another user", http.MethodGet, "/api/users/user-2", reader, http.StatusForbidden, "FORBIDDEN"},
+		{"another user's orders", http.MethodGet, "/api/users/user-2/orders", orders, http.StatusForbidden, "FORBIDDEN"},
+		{"update without users:write", http.MethodPut, "/api/users/user-1", reader, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"orders without orders:read", http.MethodGet, "/api/users/user-1/orders", reader, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"read with only users:write", http.MethodGet, "/api/users/user-1", writer, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"route not listed", http.MethodGet, "/api/users", reader, http.StatusForbidden, "FORBIDDEN"},
+		{"logout not listed", http.MethodPost, "/api/auth/logout", reader, http.StatusForbidden, "FORBIDDEN"},
+		{"session token on an unlisted route", http.MethodGet, "/api/users", session, http.StatusOK, ""},
+		{"session token on another user", http.MethodGet, "/api/users/user-2", session, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(f.handler, authRequest(tt.method, tt.target, tt.token))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), `"`+tt.wantCode+`"`) {
+				t.Errorf("body = %s, want code %s", rec.Body, tt.wantCode)
+			}
+			if tt.wantCode == "INSUFFICIENT_SCOPE" && !strings.Contains(rec.Header().Get("WWW-Authenticate"), "insufficient_scope") {
+				t.Errorf("WWW-Authenticate = %q, want insufficient_scope", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestLogoutRevokesToken(t *testing.T) {
+	f := newAuthFixture(t)
+	session := issueTestToken(t, f.tokens, auth.Claims{Subject: "user-1"})
+
+	if rec := serve(f.handler, authRequest(http.MethodPost, "/api/auth/logout", session)); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout status = %d, want 204: %s", rec.Code, rec.Body)
+	}
+	rec := serve(f.handler, authRequest(http.MethodGet, "/api/users/user-1", session))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "Token revoked") {
+		t.Errorf("after logout = %d %s, want 401 Token revoked", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateSession(t *testing.T) {
+	f := newAuthFixture(t)
+	session := issueTestToken(t, f.tokens, auth.Claims{Subject: "user-1"})
+
+	rec := serve(f.handler, authRequest(http.MethodPost, "/api/auth/session", session))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != session || !cookie.HttpOnly || !cookie.Secure {
+		t.Fatalf("session cookie = %+v, want the token, HttpOnly and Secure", cookie)
+	}
+
+	// The cookie authenticates on its own afterwards
+	r := authRequest(http.MethodGet, "/api/users/user-1", "")
+	r.AddCookie(cookie)
+	if rec := serve(f.handler, r); rec.Code != http.StatusOK {
+		t.Errorf("with the session cookie = %d, want 200", rec.Code)
+	}
+}
+
+func TestRevokingEveryTokenCoversAccessTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(f *authFixture) *http.Request
+	}{
+		{"logout-all", func(f *authFixture) *http.Request {
+			return authRequest(http.MethodPost, "/api/auth/logout-all",
+				issueTestToken(t, f.tokens, auth.Claims{Subject: "user-1"}))
+		}},
+		{"admin revoke-tokens", func(f *authFixture) *http.Request {
+			return authRequest(http.MethodPost, "/api/admin/users/user-1/revoke-tokens",
+				issueTestToken(t, f.tokens, auth.Claims{Subject: "admin-1", Scope: auth.ScopeAdmin}))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthFixture(t)
+			_, pat := f.accessToken(t, "user-1", domain.ScopeUsersRead)
+			_, other := f.accessToken(t, "user-2", domain.ScopeUsersRead)
+			if rec := serve(f.handler, authRequest(http.MethodGet, "/api/users/user-1", pat)); rec.Code != http.StatusOK {
+				t.Fatalf("before revoking = %d, want 200", rec.Code)
+			}
+
+			if rec := serve(f.handler, tt.revoke(f)); rec.Code != http.StatusOK {
+				t.Fatalf("revoke status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			// Rejected like any revoked access token
+			rec := serve(f.handler, authRequest(http.MethodGet, "/api/users/user-1", pat))
+			if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "Invalid token") {
+				t.Errorf("access token after revoking = %d %s, want 401 Invalid token", rec.Code, rec.Body)
+			}
+			// The token itself is revoked, so it stays dead once the
+			// user-wide revocation has been forgotten
+			if _, err := f.accessTokens.Authenticate(context.Background(), pat); !errors.Is(err, domain.ErrUnauthorized) {
+				t.Errorf("Authenticate after revoking = %v, want ErrUnauthorized", err)
+			}
+			if rec := serve(f.handler, authRequest(http.MethodGet, "/api/users/user-2", other)); rec.Code != http.StatusOK {
+				t.Errorf("another user's access token = %d, want 200", rec.Code)
+			}
+		})
+	}
+}
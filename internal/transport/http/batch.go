@@ -0,0 +1,257 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Batch Requests
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// POST /api/batch runs several API requests in one round trip, for clients on
+// slow networks that would otherwise pay the latency of each:
+//
+//	{"operations": [
+//	  {"method": "GET",   "path": "/api/users/u1"},
+//	  {"method": "PATCH", "path": "/api/users/u1", "headers": {"If-Match": "\"3\""},
+//	   "body": {"name": "Ada"}}
+//	]}
+//
+// Operations run one after another, in order, each through the whole router
+// as if sent on its own: authenticated with the batch's credentials, rate
+// limited, and logged under the batch's request ID. The response lists the
+// status, headers and body of every operation (less the headers the batch
+// response has too, such as the request ID); the batch itself succeeds even
+// when operations fail. Operations are not a transaction: a failed one does
+// not undo those before it.
+//
+// A batch cannot contain another batch or a streaming endpoint, and its
+// operations cannot set the headers that say who the client is or which
+// request they belong to: those come from the batch alone.
+
+// maxBatchOperations is the most operations one batch may carry
+const maxBatchOperations = 20
+
+// batchMethods are the methods an operation may use
+var batchMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// batchDroppedHeaders are the batch's own headers that do not describe its
+// operations; an operation sends them only if it sets them itself
+var batchDroppedHeaders = []string{
+	"Accept", "Content-Type", "Content-Length", "Content-Encoding",
+	"If-Match", "If-None-Match", IdempotencyKeyHeader,
+}
+
+// batchReservedHeaders are the headers an operation may not set: the
+// forwarding headers would let each operation claim a client IP of its own,
+// getting past per-IP rate limits and lockouts, and the request ID ties it to
+// the batch
+var batchReservedHeaders = []string{
+	"X-Forwarded-For", "Forwarded", "X-Real-Ip", "X-Request-Id",
+}
+
+// BatchRequest represents the request body of a batch
+type BatchRequest struct {
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationRequest is one request of a batch
+type BatchOperationRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"` // Including the query, e.g. /api/orders?status=pending
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse represents the response body of a batch
+type BatchResponse struct {
+	Operations []BatchOperationResponse `json:"operations"`
+}
+
+// BatchOperationResponse is the response to one operation of a batch. The
+// body is the operation's JSON response, or a string for other formats.
+type BatchOperationResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchHandler runs the operations of a batch through the router
+// Transport layer - operations reach the other handlers exactly as requests
+// of their own would
+type BatchHandler struct {
+	router http.Handler
+	mux    *http.ServeMux
+}
+
+// newBatchHandler creates the batch handler; router serves the operations and
+// mux resolves their routes
+func newBatchHandler(router http.Handler, mux *http.ServeMux) *BatchHandler {
+	return &BatchHandler{router: router, mux: mux}
+}
+
+// Run handles POST /api/batch
+func (h *BatchHandler) Run(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		respondError(w, http.StatusBadRequest, "VALIDATION_ERROR",
+			fmt.Sprintf("Between 1 and %d operations are required", maxBatchOperations))
+		return
+	}
+
+	// Every operation is checked before any runs
+	details := make(map[string]string)
+	targets := make([]*url.URL, len(req.Operations))
+	for i, op := range req.Operations {
+		target, problem := h.checkOperation(op)
+		if problem != "" {
+			details["operations["+strconv.Itoa(i)+"]"] = problem
+			continue
+		}
+		targets[i] = target
+	}
+	if len(details) > 0 {
+		respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_BATCH",
+			"One or more operations are invalid", details)
+		return
+	}
+
+	resp := BatchResponse{Operations: make([]BatchOperationResponse, len(req.Operations))}
+	for i, op := range req.Operations {
+		if r.Context().Err() != nil {
+			// The client is gone; the rest would run for nobody
+			return
+		}
+		resp.Operations[i] = h.run(w, r, op, targets[i])
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// checkOperation returns the URL op targets, or why it cannot be run
+func (h *BatchHandler) checkOperation(op BatchOperationRequest) (*url.URL, string) {
+	if !slices.Contains(batchMethods, op.Method) {
+		return nil, "method must be one of: " + strings.Join(batchMethods, ", ")
+	}
+	for name := range op.Headers {
+		if slices.Contains(batchReservedHeaders, http.CanonicalHeaderKey(name)) {
+			return nil, "header " + name + " cannot be set on an operation"
+		}
+	}
+	target, err := url.ParseRequestURI(op.Path)
+	if err != nil || target.Host != "" || !strings.HasPrefix(target.Path, "/api/") {
+		return nil, "path must be an API path such as /api/users"
+	}
+
+	// Routes are registered without the version segment
+	_, unversioned, _ := splitVersionedPath(target.Path)
+	probe := &http.Request{Method: op.Method, URL: &url.URL{Path: unversioned}}
+	if _, pattern := h.mux.Handler(probe); pattern == "POST /api/batch" || streamingRoutes[pattern] {
+		return nil, pattern + " cannot be batched"
+	}
+	return target, ""
+}
+
+// run serves one operation of the batch r and records its response, leaving
+// out the headers the batch response to w has as well
+func (h *BatchHandler) run(w http.ResponseWriter, r *http.Request, op BatchOperationRequest, target *url.URL) BatchOperationResponse {
+	// A fresh context, so nothing the batch's middleware stored leaks into the
+	// operation, that still ends with the batch
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := context.AfterFunc(r.Context(), cancel)
+	defer stop()
+
+	sub := (&http.Request{
+		Method:     op.Method,
+		URL:        target,
+		RequestURI: op.Path,
+		Proto:      r.Proto,
+		ProtoMajor: r.ProtoMajor,
+		ProtoMinor: r.ProtoMinor,
+		Header:     r.Header.Clone(),
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		TLS:        r.TLS,
+		Body:       http.NoBody,
+	}).WithContext(ctx)
+	for _, name := range batchDroppedHeaders {
+		sub.Header.Del(name)
+	}
+	sub.Header.Set("Accept", "application/json")
+	sub.Header.Set("X-Request-ID", GetRequestID(r.Context()))
+	if len(op.Body) > 0 && !bytes.Equal(op.Body, []byte("null")) {
+		sub.Body = io.NopCloser(bytes.NewReader(op.Body))
+		sub.ContentLength = int64(len(op.Body))
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range op.Headers {
+		sub.Header.Set(name, value)
+	}
+
+	rec := &batchRecorder{header: make(http.Header), status: http.StatusOK}
+	h.router.ServeHTTP(rec, sub)
+	return rec.response(w.Header())
+}
+
+// batchRecorder captures the response to one operation of a batch
+type batchRecorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = code
+}
+
+func (rec *batchRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	return rec.body.Write(b)
+}
+
+// response returns the recorded response without the headers it shares with
+// batch, and with its body embedded as is when it is JSON
+func (rec *batchRecorder) response(batch http.Header) BatchOperationResponse {
+	resp := BatchOperationResponse{Status: rec.status}
+	for name, values := range rec.header {
+		if slices.Equal(batch[name], values) {
+			continue
+		}
+		if resp.Headers == nil {
+			resp.Headers = make(map[string]string)
+		}
+		resp.Headers[name] = strings.Join(values, ", ")
+	}
+	switch body := bytes.TrimSpace(rec.body.Bytes()); {
+	case len(body) == 0:
+	case json.Valid(body):
+		resp.Body = json.RawMessage(body)
+	default:
+		resp.Body, _ = json.Marshal(string(body))
+	}
+	return resp
+}
@@ -0,0 +1,285 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// batchFixture is a batch handler in front of a few stand-in routes, behind
+// the request ID middleware, which sets a header on every response
+func batchFixture(t *testing.T) (http.Handler, *[]string) {
+	t.Helper()
+	var served []string
+	mux := http.NewServeMux()
+	router := middleware.RequestID()(mux)
+
+	// Echoes what the operation arrived with
+	mux.HandleFunc("/api/echo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, r.Method+" "+r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Item", r.PathValue("id"))
+		respondJSON(w, http.StatusCreated, map[string]string{
+			"method":        r.Method,
+			"query":         r.URL.RawQuery,
+			"body":          string(body),
+			"authorization": r.Header.Get("Authorization"),
+			"content_type":  r.Header.Get("Content-Type"),
+			"if_match":      r.Header.Get("If-Match"),
+			"idempotency":   r.Header.Get(IdempotencyKeyHeader),
+			"request_id":    r.Header.Get("X-Request-ID"),
+		})
+	})
+	mux.HandleFunc("GET /api/text", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, "GET /api/text")
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "plain text\n")
+	})
+	mux.HandleFunc("GET /api/missing", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, "GET /api/missing")
+		respondError(w, http.StatusNotFound, "NOT_FOUND", "Not found")
+	})
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/orders/{id}/ws", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /api/batch", newBatchHandler(router, mux).Run)
+	return router, &served
+}
+
+func batchRequest(t *testing.T, ops ...BatchOperationRequest) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(BatchRequest{Operations: ops})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer batch-token")
+	r.Header.Set("X-Request-ID", "req-1")
+	return r
+}
+
+func decodeBatch(t *testing.T, rec *httptest.ResponseRecorder) []BatchOperationResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var envelope struct {
+		Data BatchResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("batch response: %v: %s", err, rec.Body)
+	}
+	return envelope.Data.Operations
+}
+
+func TestBatchRunsOperationsInOrder(t *testing.T) {
+	h, served := batchFixture(t)
+	r := batchRequest(t,
+		BatchOperationRequest{Method: http.MethodGet, Path: "/api/echo/1?expand=user"},
+		BatchOperationRequest{Method: http.MethodPatch, Path: "/api/echo/2", Headers: map[string]string{"If-Match": `"3"`}, Body: json.RawMessage(`{"name":"Ada"}`)},
+		BatchOperationRequest{Method: http.MethodGet, Path: "/api/missing"},
+		BatchOperationRequest{Method: http.MethodGet, Path: "/api/text"},
+	)
+	r.Header.Set("If-Match", `"9"`)
+	r.Header.Set(IdempotencyKeyHeader, "batch-key")
+	rec := serve(h, r)
+	ops := decodeBatch(t, rec)
+
+	want := []string{"GET /api/echo/1", "PATCH /api/echo/2", "GET /api/missing", "GET /api/text"}
+	if strings.Join(*served, ",") != strings.Join(want, ",") {
+		t.Errorf("served %v, want %v", *served, want)
+	}
+	if len(ops) != 4 {
+		t.Fatalf("got %d operation responses, want 4", len(ops))
+	}
+
+	var echo struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(ops[0].Body, &echo); err != nil {
+		t.Fatalf("operation 0 body: %v: %s", err, ops[0].Body)
+	}
+	if ops[0].Status != http.StatusCreated || ops[0].Headers["X-Item"] != "1" {
+		t.Errorf("operation 0 = %d %v, want 201 with X-Item 1", ops[0].Status, ops[0].Headers)
+	}
+	for name, want := range map[string]string{
+		"method":        http.MethodGet,
+		"query":         "expand=user",
+		"body":          "",
+		"authorization": "Bearer batch-token",
+		"content_type":  "",
+		"if_match":      "",
+		"idempotency":   "",
+		"request_id":    "req-1",
+	} {
+		if echo.Data[name] != want {
+			t.Errorf("operation 0 saw %s = %q, want %q", name, echo.Data[name], want)
+		}
+	}
+
+	if err := json.Unmarshal(ops[1].Body, &echo); err != nil {
+		t.Fatalf("operation 1 body: %v", err)
+	}
+	if echo.Data["body"] != `{"name":"Ada"}` || echo.Data["content_type"] != "application/json" || echo.Data["if_match"] != `"3"` {
+		t.Errorf("operation 1 saw %v, want its own body, Content-Type and If-Match", echo.Data)
+	}
+
+	if ops[2].Status != http.StatusNotFound || !strings.Contains(string(ops[2].Body), "NOT_FOUND") {
+		t.Errorf("operation 2 = %d %s, want the 404 error", ops[2].Status, ops[2].Body)
+	}
+	if ops[3].Status != http.StatusOK || string(ops[3].Body) != `"plain text"` || ops[3].Headers["Content-Type"] != "text/plain" {
+		t.Errorf("operation 3 = %d %v %s, want the text as a JSON string", ops[3].Status, ops[3].Headers, ops[3].Body)
+	}
+
+	// Headers the batch response carries as well are left out
+	for i, op := range ops {
+		if _, ok := op.Headers["X-Request-ID"]; ok {
+			t.Errorf("operation %d repeats the batch's X-Request-ID", i)
+		}
+	}
+	if rec.Header().Get("X-Request-ID") != "req-1" {
+		t.Errorf("batch X-Request-ID = %q, want req-1", rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestBatchRejects(t *testing.T) {
+	get := func(path string) BatchOperationRequest {
+		return BatchOperationRequest{Method: http.MethodGet, Path: path}
+	}
+	tooMany := make([]BatchOperationRequest, maxBatchOperations+1)
+	for i := range tooMany {
+		tooMany[i] = get(fmt.Sprintf("/api/echo/%d", i))
+	}
+
+	tests := []struct {
+		name     string
+		ops      []BatchOperationRequest
+		wantCode string
+		wantBody string
+	}{
+		{"no operations", nil, "VALIDATION_ERROR", "Between 1 and 20"},
+		{"over the cap", tooMany, "VALIDATION_ERROR", "Between 1 and 20"},
+		{"nested batch", []BatchOperationRequest{get("/api/echo/1"), {Method: http.MethodPost, Path: "/api/batch"}}, "INVALID_BATCH", `"operations[1]":"POST /api/batch cannot be batched"`},
+		{"versioned nested batch", []BatchOperationRequest{{Method: http.MethodPost, Path: "/api/v1/batch"}}, "INVALID_BATCH", "cannot be batched"},
+		{"event stream", []BatchOperationRequest{get("/api/events")}, "INVALID_BATCH", "GET /api/events cannot be batched"},
+		{"websocket", []BatchOperationRequest{get("/api/orders/o1/ws")}, "INVALID_BATCH", "cannot be batched"},
+		{"unsupported method", []BatchOperationRequest{{Method: http.MethodOptions, Path: "/api/echo/1"}}, "INVALID_BATCH", "method must be one of"},
+		{"absolute URL", []BatchOperationRequest{get("https://evil.example/api/echo/1")}, "INVALID_BATCH", "must be an API path"},
+		{"outside the API", []BatchOperationRequest{get("/health")}, "INVALID_BATCH", "must be an API path"},
+		{"own request ID", []BatchOperationRequest{{Method: http.MethodGet, Path: "/api/echo/1", Headers: map[string]string{"X-Request-ID": "mine"}}}, "INVALID_BATCH", "header X-Request-ID cannot be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, served := batchFixture(t)
+			rec := serve(h, batchRequest(t, tt.ops...))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), `"`+tt.wantCode+`"`) || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s with %s", rec.Body, tt.wantCode, tt.wantBody)
+			}
+			// Checked before any runs
+			if len(*served) != 0 {
+				t.Errorf("served %v before rejecting the batch", *served)
+			}
+		})
+	}
+}
+
+func TestBatchKeepsTheClientIP(t *testing.T) {
+	trusted, err := middleware.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	mux := http.NewServeMux()
+	router := middleware.ForwardedFor(trusted)(mux)
+	mux.HandleFunc("GET /api/ip", func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, middleware.ClientIP(r))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /api/batch", newBatchHandler(router, mux).Run)
+
+	// Each spelling an operation might use to pick its own client IP
+	for _, header := range []string{"X-Forwarded-For", "x-forwarded-for", "Forwarded", "X-Real-IP"} {
+		t.Run(header, func(t *testing.T) {
+			seen = nil
+			r := batchRequest(t,
+				BatchOperationRequest{Method: http.MethodGet, Path: "/api/ip"},
+				BatchOperationRequest{Method: http.MethodGet, Path: "/api/ip", Headers: map[string]string{header: "198.51.100.7"}},
+			)
+			r.RemoteAddr = "10.0.0.2:5000"
+			r.Header.Set("X-Forwarded-For", "203.0.113.9")
+			rec := serve(router, r)
+
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "cannot be set on an operation") {
+				t.Errorf("status = %d %s, want the operation rejected", rec.Code, rec.Body)
+			}
+			if len(seen) != 0 {
+				t.Errorf("served operations as %v before rejecting the batch", seen)
+			}
+		})
+	}
+
+	// Operations that leave the headers alone are the batch's client
+	seen = nil
+	r := batchRequest(t, BatchOperationRequest{Method: http.MethodGet, Path: "/api/ip"})
+	r.RemoteAddr = "10.0.0.2:5000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+	decodeBatch(t, serve(router, r))
+	if len(seen) != 1 || seen[0] != "203.0.113.9" {
+		t.Errorf("operation client IP = %v, want the batch's 203.0.113.9", seen)
+	}
+}
+
+func TestBatchAcceptsTheCap(t *testing.T) {
+	h, served := batchFixture(t)
+	ops := make([]BatchOperationRequest, maxBatchOperations)
+	for i := range ops {
+		ops[i] = BatchOperationRequest{Method: http.MethodGet, Path: fmt.Sprintf("/api/echo/%d", i)}
+	}
+	if got := decodeBatch(t, serve(h, batchRequest(t, ops...))); len(got) != maxBatchOperations || len(*served) != maxBatchOperations {
+		t.Errorf("ran %d of %d operations", len(*served), maxBatchOperations)
+	}
+}
+
+func TestBatchStopsWhenTheClientGoesAway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var served []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/step/{n}", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, r.PathValue("n"))
+		if r.PathValue("n") == "2" {
+			cancel() // The client hangs up during the second operation
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+				t.Error("operation context outlived the batch")
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /api/batch", newBatchHandler(mux, mux).Run)
+
+	ops := make([]BatchOperationRequest, 4)
+	for i := range ops {
+		ops[i] = BatchOperationRequest{Method: http.MethodGet, Path: fmt.Sprintf("/api/step/%d", i+1)}
+	}
+	rec := serve(mux, batchRequest(t, ops...).WithContext(ctx))
+
+	if strings.Join(served, ",") != "1,2" {
+		t.Errorf("served %v, want only the operations before the client left", served)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("wrote %s to a client that is gone", rec.Body)
+	}
+}
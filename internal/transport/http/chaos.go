@@ -0,0 +1,44 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/chaos"
+)
+
+// Request headers replacing the configured faults for one request, in the
+// CHAOS_ERROR_PERCENT / CHAOS_LATENCY_MS format (e.g. "redis=100")
+const (
+	ChaosErrorHeader   = "X-Chaos-Error"
+	ChaosLatencyHeader = "X-Chaos-Latency-Ms"
+)
+
+// FaultInjection injects the chaos.TargetHTTP fault into every request,
+// answering failed ones with 503 so clients' retries can be exercised. With
+// allowHeaders, a request carrying ChaosErrorHeader or ChaosLatencyHeader is
+// subject to those faults only, down to the Postgres and Redis adapters.
+func FaultInjection(injector *chaos.Injector, allowHeaders bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			errSpec, latencySpec := r.Header.Get(ChaosErrorHeader), r.Header.Get(ChaosLatencyHeader)
+			if allowHeaders && (errSpec != "" || latencySpec != "") {
+				faults, err := chaos.ParseFaults(errSpec, latencySpec)
+				if err != nil {
+					respondError(w, http.StatusBadRequest, "INVALID_CHAOS_HEADER", err.Error())
+					return
+				}
+				r = r.WithContext(chaos.WithFaults(r.Context(), faults))
+			}
+
+			if err := injector.Inject(r.Context(), chaos.TargetHTTP); err != nil {
+				if errors.Is(err, chaos.ErrInjected) {
+					respondError(w, http.StatusServiceUnavailable, "CHAOS_FAULT_INJECTED", "Fault injected for resilience testing")
+				}
+				// Otherwise the client went away during the injected delay
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
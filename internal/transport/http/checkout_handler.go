@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// CheckoutHandler handles HTTP requests for order checkout and stock levels
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type CheckoutHandler struct {
+	checkout *usecase.CheckoutService
+	logg     *logger.Logger
+}
+
+// NewCheckoutHandler creates a new checkout handler
+func NewCheckoutHandler(checkout *usecase.CheckoutService, logg *logger.Logger) *CheckoutHandler {
+	return &CheckoutHandler{
+		checkout: checkout,
+		logg:     logg,
+	}
+}
+
+// CheckoutResponse represents the checkout of an order
+type CheckoutResponse struct {
+	ID        string            `json:"id"`
+	OrderID   string            `json:"order_id"`
+	Status    domain.SagaStatus `json:"status"`         // running, compensating, completed or compensated
+	Step      string            `json:"step,omitempty"` // Being performed or undone, while unfinished
+	Error     string            `json:"error,omitempty"`
+	PaymentID string            `json:"payment_id,omitempty"`
+	CreatedAt string            `json:"created_at"`
+	UpdatedAt string            `json:"updated_at"`
+}
+
+func (h *CheckoutHandler) toResponse(saga *domain.Saga) *CheckoutResponse {
+	return &CheckoutResponse{
+		ID:        saga.ID,
+		OrderID:   saga.Key,
+		Status:    saga.Status,
+		Step:      h.checkout.StepName(saga),
+		Error:     saga.Error,
+		PaymentID: saga.Data["payment_id"],
+		CreatedAt: saga.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: saga.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// StockRequest represents the request body setting a product's stock
+type StockRequest struct {
+	Available *int `json:"available"`
+}
+
+// StockResponse represents the stock of a product
+type StockResponse struct {
+	ProductID string `json:"product_id"`
+	Available int    `json:"available"`
+	Tracked   bool   `json:"tracked"` // Untracked products are never out of stock
+}
+
+// Checkout handles POST /api/orders/{id}/checkout
+// It answers 200 once the order is confirmed, and 202 when the checkout was
+// interrupted and is being finished in the background
+func (h *CheckoutHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Order ID is required")
+		return
+	}
+
+	saga, err := h.checkout.Checkout(r.Context(), id)
+	if err != nil {
+		// The declined payment or short product is worth telling the client
+		if errors.Is(err, domain.ErrPaymentDeclined) || errors.Is(err, domain.ErrInsufficientStock) {
+			status, code, _ := mapDomainErrorToHTTP(err)
+			respondError(w, status, code, err.Error())
+			return
+		}
+		h.logg.Error("failed to check out order", "error", err, "order_id", id)
+		handleError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if saga.Status != domain.SagaCompleted {
+		status = http.StatusAccepted
+	}
+	respondJSON(w, status, h.toResponse(saga))
+}
+
+// GetCheckout handles GET /api/orders/{id}/checkout
+func (h *CheckoutHandler) GetCheckout(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Order ID is required")
+		return
+	}
+
+	saga, err := h.checkout.GetCheckout(r.Context(), id)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, h.toResponse(saga))
+}
+
+// GetStock handles GET /api/admin/inventory/{product_id}
+func (h *CheckoutHandler) GetStock(w http.ResponseWriter, r *http.Request) {
+	productID := r.PathValue("product_id")
+
+	available, tracked, err := h.checkout.GetStock(r.Context(), productID)
+	if err != nil {
+		h.logg.Error("failed to get stock", "error", err, "product_id", productID)
+		handleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, &StockResponse{ProductID: productID, Available: available, Tracked: tracked})
+}
+
+// SetStock handles PUT /api/admin/inventory/{product_id}
+func (h *CheckoutHandler) SetStock(w http.ResponseWriter, r *http.Request) {
+	productID := r.PathValue("product_id")
+
+	var req StockRequest
+	if err := decodeJSON(r, &req); err != nil || req.Available == nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	if err := h.checkout.SetStock(r.Context(), productID, *req.Available); err != nil {
+		h.logg.Error("failed to set stock", "error", err, "product_id", productID)
+		handleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, &StockResponse{ProductID: productID, Available: *req.Available, Tracked: true})
+}
@@ -0,0 +1,480 @@
+package http
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/msgpack"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Content Negotiation
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Codec encodes response bodies and decodes request bodies in one media type.
+// Every codec works from the JSON form of a value, so the json struct tags of
+// the DTOs name the fields in every format.
+type Codec interface {
+	// ContentType is the Content-Type of encoded responses
+	ContentType() string
+	Encode(w io.Writer, v any) error
+	// Decode rejects unknown fields, like JSON requests always have
+	Decode(r io.Reader, v any) error
+}
+
+// codecs maps the accepted media types to their codec
+var codecs = map[string]Codec{
+	"application/json":      jsonCodec{},
+	"application/msgpack":   msgpackCodec{},
+	"application/x-msgpack": msgpackCodec{},
+	"application/xml":       xmlCodec{},
+	"text/xml":              xmlCodec{},
+}
+
+// defaultCodec serves requests accepting anything, or nothing we support
+var defaultCodec Codec = jsonCodec{}
+
+// codecMediaTypes lists the media types request bodies may be sent in, JSON first
+func codecMediaTypes() []string {
+	types := make([]string, 0, len(codecs))
+	for mediaType := range codecs {
+		if mediaType != defaultCodec.ContentType() {
+			types = append(types, mediaType)
+		}
+	}
+	sort.Strings(types)
+	return append([]string{defaultCodec.ContentType()}, types...)
+}
+
+// acceptRange is one media range of an Accept header
+type acceptRange struct {
+	mediaType string
+	params    map[string]string
+	q         float64
+}
+
+// parseAccept reads the media ranges of the Accept headers, skipping
+// malformed ones
+func parseAccept(accept []string) []acceptRange {
+	var ranges []acceptRange
+	for _, header := range accept {
+		for _, mediaRange := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if raw, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(raw, 64); err != nil {
+					continue
+				}
+			}
+			ranges = append(ranges, acceptRange{mediaType: mediaType, params: params, q: q})
+		}
+	}
+	return ranges
+}
+
+// negotiateCodec picks the codec of the most preferred media range in the
+// Accept headers. Wildcards, and requests without an Accept header, get the
+// default codec; acceptable is false when the Accept headers name only
+// formats no codec encodes (respondJSON then answers 406).
+func negotiateCodec(accept []string) (codec Codec, acceptable bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return defaultCodec, true
+	}
+	var best Codec
+	bestQ := 0.0
+	for _, ar := range ranges {
+		codec, ok := codecs[ar.mediaType]
+		if !ok && (ar.mediaType == "*/*" || ar.mediaType == "application/*") {
+			codec, ok = defaultCodec, true
+		}
+		if ok && ar.q > bestQ {
+			best, bestQ = codec, ar.q
+		}
+	}
+	if best == nil {
+		return defaultCodec, false
+	}
+	return best, true
+}
+
+// requestCodec returns the codec of the request body's Content-Type, JSON
+// when it has none; ok is false for a Content-Type no codec decodes
+func requestCodec(r *http.Request) (codec Codec, ok bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return defaultCodec, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codec, ok = codecs[mediaType]
+	return codec, ok
+}
+
+// negotiatedRoutes hands every handler a writer carrying the negotiated API
+// version and response codec, so respondJSON can reshape and encode what it
+// writes
+type negotiatedRoutes struct {
+	routeRegistrar
+}
+
+// HandleFunc registers handler for pattern with negotiated responses
+func (m negotiatedRoutes) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.routeRegistrar.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(w.Header().Values("Vary"), "Accept") {
+			w.Header().Add("Vary", "Accept")
+		}
+		codec, acceptable := negotiateCodec(r.Header.Values("Accept"))
+		handler(&negotiatedWriter{
+			ResponseWriter: w,
+			version:        GetAPIVersion(r.Context()),
+			codec:          codec,
+			unacceptable:   !acceptable,
+		}, r)
+	})
+}
+
+// negotiatedWriter remembers the version and codec a response is encoded with
+type negotiatedWriter struct {
+	http.ResponseWriter
+	version      APIVersion
+	codec        Codec
+	unacceptable bool // The request accepts no format a codec encodes
+}
+
+// Unwrap returns the underlying writer (used by http.ResponseController)
+func (nw *negotiatedWriter) Unwrap() http.ResponseWriter {
+	return nw.ResponseWriter
+}
+
+// responseCodec returns the codec a response written to w is encoded with
+func responseCodec(w http.ResponseWriter) Codec {
+	if nw, ok := w.(*negotiatedWriter); ok {
+		return nw.codec
+	}
+	return defaultCodec
+}
+
+// genericValue converts v to the generic values encoding/json decodes into,
+// keeping numbers exact
+func genericValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	err = decoder.Decode(&generic)
+	return generic, err
+}
+
+// decodeGeneric decodes a generic value into target through its JSON form
+func decodeGeneric(generic any, target any) error {
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// JSON and MessagePack
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// jsonCodec encodes application/json
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// msgpackCodec encodes application/msgpack, for high-throughput clients
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	generic, err := genericValue(v)
+	if err != nil {
+		return err
+	}
+	data, err := msgpack.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Decode accepts bin values for []byte fields, as well as their base64 string
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	generic, err := msgpack.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	return decodeGeneric(generic, v)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// XML
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// xmlCodec encodes application/xml, for legacy clients. Responses are a
+// <response> element holding an element per JSON field; list items are <item>
+// elements, and map keys that are not valid element names become
+// <entry key="...">. Requests use the same layout under any root element.
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string { return "application/xml" }
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	generic, err := genericValue(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	writeXMLElement(&buf, "response", generic)
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// writeXMLElement writes v as an element named name
+func writeXMLElement(buf *bytes.Buffer, name string, v any) {
+	open, end := "<"+name, "</"+name+">"
+	if !isXMLName(name) {
+		var key bytes.Buffer
+		xml.EscapeText(&key, []byte(name))
+		open, end = `<entry key="`+key.String()+`"`, "</entry>"
+	}
+
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString(open + "/>")
+	case []any:
+		buf.WriteString(open + ">")
+		for _, item := range v {
+			writeXMLElement(buf, "item", item)
+		}
+		buf.WriteString(end)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString(open + ">")
+		for _, k := range keys {
+			writeXMLElement(buf, k, v[k])
+		}
+		buf.WriteString(end)
+	default:
+		buf.WriteString(open + ">")
+		xml.EscapeText(buf, []byte(fmt.Sprint(v)))
+		buf.WriteString(end)
+	}
+}
+
+// isXMLName reports whether s can be used as an element name as is
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case i > 0 && (c >= '0' && c <= '9' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// xmlNode is a parsed request element
+type xmlNode struct {
+	name     string
+	text     string
+	children []*xmlNode
+}
+
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	root, err := parseXML(r)
+	if err != nil {
+		return err
+	}
+	generic, err := xmlValue(root, reflect.TypeOf(v))
+	if err != nil {
+		return err
+	}
+	return decodeGeneric(generic, v)
+}
+
+// parseXML reads the root element of a request body
+func parseXML(r io.Reader) (*xmlNode, error) {
+	decoder := xml.NewDecoder(r)
+	var stack []*xmlNode
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) >= 100 {
+				return nil, fmt.Errorf("xml nesting too deep")
+			}
+			node := &xmlNode{name: t.Name.Local}
+			if t.Name.Local == "entry" {
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "key" {
+						node.name = attr.Value
+					}
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			if stack = stack[:len(stack)-1]; len(stack) == 0 {
+				return node, nil
+			}
+		}
+	}
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// xmlValue converts n to the generic value the JSON form of type t has, since
+// XML text alone does not say whether it is a number, a list or a string
+func xmlValue(n *xmlNode, t reflect.Type) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return n.text, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return n.text, nil
+	case reflect.Bool:
+		return strconv.ParseBool(strings.TrimSpace(n.text))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		number := strings.TrimSpace(n.text)
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number", n.name, number)
+		}
+		return json.Number(number), nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return strings.TrimSpace(n.text), nil // Base64, like JSON
+		}
+		items := make([]any, len(n.children))
+		for i, child := range n.children {
+			item, err := xmlValue(child, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		m := make(map[string]any, len(n.children))
+		for _, child := range n.children {
+			value, err := xmlValue(child, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			m[child.name] = value
+		}
+		return m, nil
+	case reflect.Struct:
+		fields := jsonFieldTypes(t)
+		m := make(map[string]any, len(n.children))
+		for _, child := range n.children {
+			fieldType, ok := fields[child.name]
+			if !ok {
+				m[child.name] = child.text // Rejected as an unknown field
+				continue
+			}
+			value, err := xmlValue(child, fieldType)
+			if err != nil {
+				return nil, err
+			}
+			m[child.name] = value
+		}
+		return m, nil
+	case reflect.Interface:
+		if len(n.children) == 0 {
+			return n.text, nil
+		}
+		if n.children[0].name == "item" {
+			return xmlValue(n, reflect.TypeFor[[]any]())
+		}
+		return xmlValue(n, reflect.TypeFor[map[string]any]())
+	default:
+		return nil, fmt.Errorf("%s: unsupported field type %s", n.name, t)
+	}
+}
+
+// jsonFieldTypes maps the JSON names of a struct's fields to their types
+func jsonFieldTypes(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// codecHandler serves user creation and reads, and the order reads of
+// orderHandler, with negotiated responses
+func codecHandler(t *testing.T) http.Handler {
+	t.Helper()
+	logg := logger.New("error")
+	orders, users, _ := orderHandler(t)
+	h := NewUserHandler(usecase.NewUserService(users, memory.NewUserCache(), logg), logg)
+	mux := http.NewServeMux()
+	routes := negotiatedRoutes{mux}
+	routes.HandleFunc("POST /api/users", h.Create)
+	routes.HandleFunc("GET /api/users/{id}", h.GetByID)
+	routes.HandleFunc("GET /api/orders/{id}", orders.ServeHTTP)
+	return mux
+}
+
+// codecRequest sends a request with the given Content-Type and Accept
+// headers, either left out when empty
+func codecRequest(h http.Handler, method, target, contentType, accept string, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	return serve(h, r)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, mediaType := range []string{"application/json", "application/msgpack", "application/x-msgpack", "application/xml", "text/xml"} {
+		t.Run(mediaType, func(t *testing.T) {
+			h := codecHandler(t)
+			codec := codecs[mediaType]
+
+			// The codec encodes the request as it does responses
+			var body bytes.Buffer
+			if err := codec.Encode(&body, CreateUserRequest{Name: "Ada <Lovelace>", Email: "ada@example.com"}); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			rec := codecRequest(h, http.MethodPost, "/api/users", mediaType, mediaType, body.Bytes())
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("POST = %d %q, want 201", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != codec.ContentType() {
+				t.Errorf("Content-Type = %q, want %q", got, codec.ContentType())
+			}
+			var created struct {
+				Success bool          `json:"success"`
+				Data    *UserResponse `json:"data"`
+			}
+			if err := codec.Decode(rec.Body, &created); err != nil {
+				t.Fatalf("decoding the response: %v", err)
+			}
+			if !created.Success || created.Data == nil || created.Data.Name != "Ada <Lovelace>" || created.Data.ID == "" {
+				t.Fatalf("created = %+v, want the user", created.Data)
+			}
+
+			// Numbers, lists and nested objects survive the format
+			rec = codecRequest(h, http.MethodGet, "/api/orders/o1", "", mediaType, nil)
+			var order struct {
+				Success bool           `json:"success"`
+				Data    *OrderResponse `json:"data"`
+			}
+			if err := codec.Decode(rec.Body, &order); err != nil || rec.Code != http.StatusOK {
+				t.Fatalf("GET o1 = %d, decoding: %v", rec.Code, err)
+			}
+			if o := order.Data; o.ID != "o1" || o.Amount != 10 || len(o.Items) != 1 || o.Items[0].Quantity != 2 || o.Items[0].Price != 5 {
+				t.Errorf("order = %+v, want o1 with its item", o)
+			}
+		})
+	}
+}
+
+func TestCodecNegotiation(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{"no Accept", "", "application/json"},
+		{"wildcard", "*/*", "application/json"},
+		{"application wildcard", "application/*", "application/json"},
+		{"browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "application/xml"},
+		{"preferred", "application/xml;q=0.5, application/msgpack", "application/msgpack"},
+		{"unsupported with a wildcard", "text/csv, */*;q=0.1", "application/json"},
+	}
+	h := codecHandler(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := codecRequest(h, http.MethodGet, "/api/users/u1", "", tt.accept, nil)
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != tt.wantContentType {
+				t.Errorf("GET = %d %q, want 200 %s", rec.Code, rec.Header().Get("Content-Type"), tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestCodecNotAcceptable(t *testing.T) {
+	h := codecHandler(t)
+
+	for _, accept := range []string{"text/csv", "application/json;q=0", "text/csv, application/pdf"} {
+		rec := codecRequest(h, http.MethodGet, "/api/users/u1", "", accept, nil)
+		if rec.Code != http.StatusNotAcceptable || errorCode(t, rec) != "NOT_ACCEPTABLE" {
+			t.Errorf("Accept %q = %d %q, want 406 NOT_ACCEPTABLE", accept, rec.Code, rec.Body)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Accept %q: 406 Content-Type = %q, want JSON", accept, ct)
+		}
+	}
+
+	// Errors are sent as JSON rather than replaced
+	rec := codecRequest(h, http.MethodGet, "/api/users/nobody", "", "text/csv", nil)
+	if rec.Code != http.StatusNotFound || errorCode(t, rec) != "USER_NOT_FOUND" {
+		t.Errorf("missing user accepting CSV = %d %q, want 404 USER_NOT_FOUND", rec.Code, rec.Body)
+	}
+}
+
+func TestCodecRequestErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"unsupported Content-Type", "text/csv", "", "name,email\nAda,ada@example.com", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"malformed Content-Type", "application/", "", `{"name":"Ada","email":"ada@example.com"}`, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"no Content-Type is JSON", "", "", `{"name":"Ada","email":"ada@example.com"}`, http.StatusCreated, ""},
+		{"charset parameter", "application/json; charset=utf-8", "", `{"name":"Ada","email":"ada@example.com"}`, http.StatusCreated, ""},
+		{"malformed XML", "application/xml", "", "<user><name>Ada</name>", http.StatusBadRequest, "INVALID_REQUEST"},
+		{"unknown XML field", "application/xml", "", "<user><name>Ada</name><email>ada@example.com</email><admin>true</admin></user>", http.StatusBadRequest, "INVALID_REQUEST"},
+		{"malformed MessagePack", "application/msgpack", "", "\x82\xa4name", http.StatusBadRequest, "INVALID_REQUEST"},
+		{"JSON sent as MessagePack", "application/msgpack", "", `{"name":"Ada","email":"ada@example.com"}`, http.StatusBadRequest, "INVALID_REQUEST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := codecRequest(codecHandler(t), http.MethodPost, "/api/users", tt.contentType, tt.accept, []byte(tt.body))
+			if rec.Code != tt.wantStatus || errorCode(t, rec) != tt.wantCode {
+				t.Errorf("POST = %d %q, want %d %s", rec.Code, rec.Body, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+
+	// The error is encoded in the format the request accepts
+	rec := codecRequest(codecHandler(t), http.MethodPost, "/api/users", "application/xml", "application/xml", []byte("<user>"))
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/xml" ||
+		!strings.Contains(rec.Body.String(), "<code>INVALID_REQUEST</code>") {
+		t.Errorf("malformed XML accepting XML = %d %q, want an XML error", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Concurrency Limits
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// Rate limits bound how often clients may call; concurrency limits bound how
+// much work runs at once, whoever asked for it. Every /api request takes a
+// slot of the global limit and one of its route's limit, if it has one,
+// waiting up to the queue timeout when they are all taken:
+//
+//	MAX_CONCURRENT_REQUESTS=200
+//	CONCURRENCY_ROUTE_LIMITS=POST /api/reports=4,/api/orders=50
+//
+// A request still waiting when the timeout runs out gets 503 Service
+// Unavailable with Retry-After, so a burst sheds load at the edge instead of
+// piling up behind the connection pool.
+//
+// Streaming endpoints hold their request for as long as the client listens
+// and take no slot; neither does a batch, whose operations each take their own.
+
+// streamingRoutes are the routes whose responses never end, or that take over
+// the connection
+var streamingRoutes = map[string]bool{
+	"GET /api/events":         true,
+	"GET /api/orders/{id}/ws": true,
+}
+
+// ConcurrencyConfig configures concurrency limits
+type ConcurrencyConfig struct {
+	// Max bounds the /api requests served at once (0 is unlimited)
+	Max int
+	// Routes bounds the requests served at once per route group: a
+	// registered pattern ("POST /api/orders"), a path on every method
+	// ("/api/orders/{id}") or a method on every route ("POST"). The most
+	// specific group that matches applies; 0 is unlimited.
+	Routes map[string]int
+	// QueueTimeout is how long a request waits for a slot (0 fails at once)
+	QueueTimeout time.Duration
+	Logger       *logger.Logger
+}
+
+// concurrencySlots is a counting semaphore
+type concurrencySlots chan struct{}
+
+// acquire takes a slot, waiting until ctx is done
+func (s concurrencySlots) acquire(ctx context.Context) bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+	}
+	select {
+	case s <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s concurrencySlots) release() {
+	<-s
+}
+
+// ConcurrencyLimit caps the /api requests in flight, globally and per route
+// group, resolving routes through mux
+func ConcurrencyLimit(config ConcurrencyConfig, mux *http.ServeMux) Middleware {
+	var global concurrencySlots
+	if config.Max > 0 {
+		global = make(concurrencySlots, config.Max)
+	}
+	routes := make(map[string]concurrencySlots, len(config.Routes))
+	for route, limit := range config.Routes {
+		if limit > 0 {
+			routes[strings.Join(strings.Fields(route), " ")] = make(concurrencySlots, limit)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if global == nil && len(routes) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			_, pattern := mux.Handler(r)
+			if streamingRoutes[pattern] || pattern == "POST /api/batch" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), config.QueueTimeout)
+			defer cancel()
+
+			group, route, _ := routeGroup(routes, r.Method, pattern)
+			limits := []struct {
+				name  string
+				slots concurrencySlots
+			}{{"global", global}, {group, route}}
+			for _, limit := range limits {
+				if limit.slots == nil {
+					continue
+				}
+				if !limit.slots.acquire(ctx) {
+					if r.Context().Err() == nil {
+						config.Logger.Warn("request shed: concurrency limit reached",
+							"route", pattern, "limit", limit.name, "request_id", GetRequestID(r.Context()))
+						w.Header().Set("Retry-After", "1")
+						middleware.WriteError(w, http.StatusServiceUnavailable,
+							"SERVER_BUSY", "The server is busy, please try again shortly")
+					}
+					return
+				}
+				defer limit.slots.release()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeGroup returns the most specific of groups covering a request with
+// method to the route registered as pattern: the pattern itself, its path on
+// every method, then its method on every route. Reports false for none.
+func routeGroup[T any](groups map[string]T, method, pattern string) (string, T, bool) {
+	_, path, found := strings.Cut(pattern, " ")
+	if !found {
+		path = pattern
+	}
+	for _, group := range []string{pattern, path, method} {
+		if value, ok := groups[group]; ok {
+			return group, value, true
+		}
+	}
+	var zero T
+	return "", zero, false
+}
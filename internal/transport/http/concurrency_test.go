@@ -0,0 +1,170 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// concurrencyFixture limits a mux whose routes under /api/ block until
+// release is closed; entered receives each request they start serving
+func concurrencyFixture(t *testing.T, config ConcurrencyConfig) (h http.Handler, entered chan string, release chan struct{}) {
+	t.Helper()
+	entered = make(chan string, 10)
+	release = make(chan struct{})
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+	block := func(w http.ResponseWriter, r *http.Request) {
+		entered <- r.Method + " " + r.URL.Path
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/orders/{id}", block)
+	mux.HandleFunc("POST /api/reports", block)
+	mux.HandleFunc("GET /api/users/{id}", ok)
+	mux.HandleFunc("GET /api/events", ok)
+	mux.HandleFunc("GET /api/orders/{id}/ws", ok)
+	mux.HandleFunc("POST /api/batch", ok)
+	mux.HandleFunc("GET /health", ok)
+
+	config.Logger = logger.New("error")
+	return ConcurrencyLimit(config, mux)(mux), entered, release
+}
+
+// hold starts serving a blocking request and waits until it holds its slots
+func hold(t *testing.T, h http.Handler, entered chan string, method, path string) <-chan *httptest.ResponseRecorder {
+	t.Helper()
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- serve(h, httptest.NewRequest(method, path, nil)) }()
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		t.Fatalf("%s %s never started", method, path)
+	}
+	return done
+}
+
+func assertShed(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503: %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", rec.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(rec.Body.String(), "SERVER_BUSY") {
+		t.Errorf("body = %s, want SERVER_BUSY", rec.Body)
+	}
+}
+
+func TestConcurrencyLimitGlobal(t *testing.T) {
+	h, entered, release := concurrencyFixture(t, ConcurrencyConfig{Max: 1, QueueTimeout: 20 * time.Millisecond})
+	held := hold(t, h, entered, http.MethodGet, "/api/orders/o1")
+
+	start := time.Now()
+	assertShed(t, serve(h, httptest.NewRequest(http.MethodGet, "/api/users/user-1", nil)))
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("shed after %v, want after the queue timeout", waited)
+	}
+
+	// Paths outside /api/ take no slot
+	if rec := serve(h, httptest.NewRequest(http.MethodGet, "/health", nil)); rec.Code != http.StatusNoContent {
+		t.Errorf("GET /health = %d, want 204", rec.Code)
+	}
+
+	close(release)
+	if rec := <-held; rec.Code != http.StatusNoContent {
+		t.Errorf("held request = %d, want 204", rec.Code)
+	}
+	// and its slot is free again
+	if rec := serve(h, httptest.NewRequest(http.MethodGet, "/api/users/user-1", nil)); rec.Code != http.StatusNoContent {
+		t.Errorf("after release = %d, want 204", rec.Code)
+	}
+}
+
+func TestConcurrencyLimitQueues(t *testing.T) {
+	h, entered, release := concurrencyFixture(t, ConcurrencyConfig{Max: 1, QueueTimeout: time.Second})
+	held := hold(t, h, entered, http.MethodGet, "/api/orders/o1")
+
+	queued := make(chan *httptest.ResponseRecorder, 1)
+	go func() { queued <- serve(h, httptest.NewRequest(http.MethodGet, "/api/users/user-1", nil)) }()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if rec := <-queued; rec.Code != http.StatusNoContent {
+		t.Errorf("queued request = %d, want 204 once the slot was released", rec.Code)
+	}
+	<-held
+}
+
+func TestConcurrencyLimitPerRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    string
+		method   string
+		path     string
+		wantShed bool
+	}{
+		{"same route", "POST /api/reports", http.MethodPost, "/api/reports", true},
+		{"other method", "POST /api/reports", http.MethodGet, "/api/users/user-1", false},
+		{"path on every method", "/api/orders/{id}", http.MethodGet, "/api/orders/o2", true},
+		{"method on every route", "GET", http.MethodGet, "/api/users/user-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, entered, _ := concurrencyFixture(t, ConcurrencyConfig{
+				Max:          10,
+				Routes:       map[string]int{tt.route: 1},
+				QueueTimeout: 10 * time.Millisecond,
+			})
+			method, path := http.MethodPost, "/api/reports"
+			if tt.route != "POST /api/reports" {
+				method, path = http.MethodGet, "/api/orders/o1"
+			}
+			hold(t, h, entered, method, path)
+
+			rec := serve(h, httptest.NewRequest(tt.method, tt.path, nil))
+			if tt.wantShed {
+				assertShed(t, rec)
+			} else if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want 204: another route's limit applied", rec.Code)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitExemptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"event stream", http.MethodGet, "/api/events"},
+		{"websocket", http.MethodGet, "/api/orders/o1/ws"},
+		{"batch", http.MethodPost, "/api/batch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, entered, _ := concurrencyFixture(t, ConcurrencyConfig{Max: 1, QueueTimeout: 10 * time.Millisecond})
+			hold(t, h, entered, http.MethodGet, "/api/orders/o1")
+
+			if rec := serve(h, httptest.NewRequest(tt.method, tt.path, nil)); rec.Code != http.StatusNoContent {
+				t.Errorf("%s %s with every slot taken = %d, want 204", tt.method, tt.path, rec.Code)
+			}
+		})
+	}
+}
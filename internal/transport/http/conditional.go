@@ -0,0 +1,120 @@
+package http
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Optimistic Concurrency (ETag / If-Match)
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// Users and orders carry a version that every update bumps. Reads return it
+// in a strong ETag; updates must send it back in If-Match, so a client never
+// overwrites a change it has not seen:
+//
+//	GET   /api/orders/{id}          -> ETag: "3-5e2f0c1a"
+//	PATCH /api/orders/{id}          If-Match: "3-5e2f0c1a" -> 200, ETag: "4-5e2f0c1a"
+//	PATCH /api/orders/{id}          If-Match: "3-5e2f0c1a" -> 412 Precondition Failed
+//
+// One version has many representations (JSON, MessagePack or XML, API
+// version 1 or 2, amounts formatted per Content-Language, trimmed by
+// ?fields=), so the tag carries a hash of the variant after the version: a
+// strong ETag must differ between representations that differ in bytes, or a
+// cache holding one would answer 304 for another. If-Match compares the
+// version alone, since any representation of the current version proves the
+// client has seen it. If-Match: * matches any version. A request without If-Match is refused
+// with 428 Precondition Required.
+//
+// The same ETags make reads conditional: a GET whose If-None-Match lists the
+// current ETag gets 304 Not Modified with no body, so polling clients only
+// download what changed. Responses embedding related resources (?expand=)
+// carry a weak ETag made of every version involved, which If-None-Match
+// matches but If-Match does not.
+
+// etag formats a resource version as the strong entity tag of the
+// representation w carries for r. Handlers that localize must set
+// Content-Language before calling it.
+func etag(w http.ResponseWriter, r *http.Request, version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + "-" + representationVariant(w, r) + `"`
+}
+
+// weakETag formats the versions of a resource and those embedded in it as a
+// weak entity tag of the representation w carries for r
+func weakETag(w http.ResponseWriter, r *http.Request, versions ...int64) string {
+	parts := make([]string, len(versions))
+	for i, version := range versions {
+		parts[i] = strconv.FormatInt(version, 10)
+	}
+	return `W/"` + strings.Join(parts, ".") + "-" + representationVariant(w, r) + `"`
+}
+
+// setETag sets the ETag of the resource at version on the response
+func setETag(w http.ResponseWriter, r *http.Request, version int64) {
+	w.Header().Set("ETag", etag(w, r, version))
+}
+
+// representationVariant hashes everything besides the resource versions that
+// shapes the bytes of a response: its codec, API version, language and
+// field selection
+func representationVariant(w http.ResponseWriter, r *http.Request) string {
+	h := fnv.New32a()
+	h.Write([]byte(responseCodec(w).ContentType() + "\n" +
+		strconv.Itoa(int(GetAPIVersion(r.Context()))) + "\n" +
+		w.Header().Get("Content-Language") + "\n" +
+		r.URL.Query().Get("fields")))
+	return strconv.FormatUint(uint64(h.Sum32()), 16)
+}
+
+// requireIfMatch makes the update r asks for conditional on its If-Match
+// header, returning r with the expected versions in its context. Writes a
+// 428 response and returns false when the header is missing.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		respondError(w, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED",
+			"If-Match is required: send the ETag of the resource being changed")
+		return nil, false
+	}
+	if header == "*" {
+		return r, true
+	}
+
+	// Strong comparison: weak or malformed tags match no version
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		opaque, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		if version, err := strconv.ParseInt(opaque, 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return r.WithContext(usecase.WithExpectedVersions(r.Context(), versions...)), true
+}
+
+// notModified sets tag as the ETag of the response to the read r, and writes
+// a 304 response and returns true when r's If-None-Match already lists it.
+// Tags are compared weakly, as RFC 9110 has for If-None-Match.
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
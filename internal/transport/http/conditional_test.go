@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETagVariesWithRepresentation(t *testing.T) {
+	variant := func(codec Codec, version APIVersion, language, target string) string {
+		w := &negotiatedWriter{ResponseWriter: httptest.NewRecorder(), version: version, codec: codec}
+		if language != "" {
+			w.Header().Set("Content-Language", language)
+		}
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r = r.WithContext(context.WithValue(r.Context(), APIVersionKey, version))
+		return etag(w, r, 3)
+	}
+
+	base := variant(jsonCodec{}, APIVersion1, "en-US", "/api/orders/o1")
+	if base != variant(jsonCodec{}, APIVersion1, "en-US", "/api/orders/o1") {
+		t.Fatal("etag() differs for the same representation")
+	}
+	if !strings.HasPrefix(base, `"3-`) {
+		t.Errorf("etag() = %s, want the version first", base)
+	}
+
+	others := map[string]string{
+		"msgpack":  variant(msgpackCodec{}, APIVersion1, "en-US", "/api/orders/o1"),
+		"xml":      variant(xmlCodec{}, APIVersion1, "en-US", "/api/orders/o1"),
+		"v2":       variant(jsonCodec{}, APIVersion2, "en-US", "/api/orders/o1"),
+		"language": variant(jsonCodec{}, APIVersion1, "de-DE", "/api/orders/o1"),
+		"fields":   variant(jsonCodec{}, APIVersion1, "en-US", "/api/orders/o1?fields=id"),
+	}
+	for name, tag := range others {
+		if tag == base {
+			t.Errorf("%s representation shares the ETag %s", name, tag)
+		}
+	}
+}
+
+func TestNotModifiedMatchesVariant(t *testing.T) {
+	tag := `"3-abc"`
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{"same tag", `"3-abc"`, true},
+		{"weak form", `W/"3-abc"`, true},
+		{"listed", `"2-abc", "3-abc"`, true},
+		{"any", `*`, true},
+		{"other variant", `"3-def"`, false},
+		{"bare version", `"3"`, false},
+		{"none", ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/users/u1", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			if got := notModified(rec, r, tag); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+			if rec.Header().Get("ETag") != tag {
+				t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), tag)
+			}
+		})
+	}
+}
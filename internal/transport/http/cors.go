@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// CORSRoute is the CORS policy of a group of routes, as declared in
+// CORS_ROUTES (see config.CORSRoute). Allowed methods and headers are the
+// default policy's.
+type CORSRoute struct {
+	// Route is a registered pattern ("GET /api/features"), a path on every
+	// method ("/api/orders/{id}") or a method on every route ("GET")
+	Route            string
+	Origins          []string
+	AllowCredentials bool
+	MaxAge           time.Duration // 0 keeps the default policy's
+}
+
+// corsRoutePolicies returns the policies of routes by route group, each
+// base with the route's origins, credentials and max age
+func corsRoutePolicies(base middleware.CORSConfig, routes []CORSRoute) map[string]*middleware.CORSPolicy {
+	policies := make(map[string]*middleware.CORSPolicy, len(routes))
+	for _, route := range routes {
+		config := base
+		config.AllowedOrigins = route.Origins
+		config.AllowCredentials = route.AllowCredentials
+		if route.MaxAge > 0 {
+			config.MaxAge = int(route.MaxAge.Seconds())
+		}
+		policies[strings.Join(strings.Fields(route.Route), " ")] = middleware.NewCORSPolicy(config)
+	}
+	return policies
+}
+
+// routeCORS applies to each request the CORS policy of the most specific
+// route group in routes that covers it, or base. A preflight is resolved to
+// the route it asks about, through mux and its Access-Control-Request-Method.
+func routeCORS(base *middleware.CORSPolicy, routes map[string]*middleware.CORSPolicy, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		fallback := base.Middleware()(next)
+		if len(routes) == 0 {
+			return fallback
+		}
+		handlers := make(map[string]http.Handler, len(routes))
+		for group, policy := range routes {
+			handlers[group] = policy.Middleware()(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lookup := r
+			if method := r.Header.Get("Access-Control-Request-Method"); r.Method == http.MethodOptions && method != "" {
+				lookup = r.WithContext(r.Context())
+				lookup.Method = method
+			}
+			_, pattern := mux.Handler(lookup)
+			if _, handler, ok := routeGroup(handlers, lookup.Method, pattern); ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			fallback.ServeHTTP(w, r)
+		})
+	}
+}
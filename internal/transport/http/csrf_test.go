@@ -0,0 +1,139 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+const testJWTSecret = "test-secret-with-at-least-32-characters"
+
+func newTestTokens(t *testing.T) *auth.TokenManager {
+	t.Helper()
+	keys, err := auth.LoadKeySet(testJWTSecret, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	tokens, err := auth.NewTokenManager(keys, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	return tokens
+}
+
+func issueTestToken(t *testing.T, tokens *auth.TokenManager, claims auth.Claims) string {
+	t.Helper()
+	token, err := tokens.Issue(claims)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
+
+func TestCSRF(t *testing.T) {
+	tokens := newTestTokens(t)
+	session := issueTestToken(t, tokens, auth.Claims{Subject: "user-1"})
+
+	h := Authenticate(AuthenticateConfig{
+		Tokens:        tokens,
+		SessionCookie: "session",
+		Logger:        logger.New("error"),
+	})(CSRF(CSRFConfig{SessionCookie: "session"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		csrfCookie    string
+		csrfHeader    string
+		sessionCookie bool
+		wantStatus    int
+		wantIssued    bool
+	}{
+		{
+			name:          "bearer token with the session cookie",
+			method:        http.MethodPost,
+			authorization: "Bearer " + session,
+			sessionCookie: true,
+			wantStatus:    http.StatusNoContent,
+		},
+		{
+			name:          "bearer token alone",
+			method:        http.MethodPost,
+			authorization: "Bearer " + session,
+			wantStatus:    http.StatusNoContent,
+		},
+		{
+			name:          "other Authorization scheme falls back to the cookie",
+			method:        http.MethodPost,
+			authorization: "Basic dXNlcjpwYXNz",
+			sessionCookie: true,
+			wantStatus:    http.StatusForbidden,
+			wantIssued:    true,
+		},
+		{
+			name:          "cookie without the CSRF header",
+			method:        http.MethodPost,
+			csrfCookie:    "abc",
+			sessionCookie: true,
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "cookie with a wrong CSRF header",
+			method:        http.MethodDelete,
+			csrfCookie:    "abc",
+			csrfHeader:    "abd",
+			sessionCookie: true,
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "cookie with the CSRF header",
+			method:        http.MethodPost,
+			csrfCookie:    "abc",
+			csrfHeader:    "abc",
+			sessionCookie: true,
+			wantStatus:    http.StatusNoContent,
+		},
+		{
+			name:          "safe method issues the CSRF cookie",
+			method:        http.MethodGet,
+			sessionCookie: true,
+			wantStatus:    http.StatusNoContent,
+			wantIssued:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/orders", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.sessionCookie {
+				r.AddCookie(&http.Cookie{Name: "session", Value: session})
+			}
+			if tt.csrfCookie != "" {
+				r.AddCookie(&http.Cookie{Name: DefaultCSRFCookieName, Value: tt.csrfCookie})
+			}
+			if tt.csrfHeader != "" {
+				r.Header.Set(DefaultCSRFHeaderName, tt.csrfHeader)
+			}
+
+			rec := serve(h, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			issued := strings.Contains(rec.Header().Get("Set-Cookie"), DefaultCSRFCookieName+"=")
+			if issued != tt.wantIssued {
+				t.Errorf("CSRF cookie issued = %v, want %v", issued, tt.wantIssued)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// DeadLetterHandler handles HTTP requests inspecting and replaying dead letters
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type DeadLetterHandler struct {
+	deadLetters *usecase.DeadLetterService
+	logg        *logger.Logger
+}
+
+// NewDeadLetterHandler creates a new dead-letter handler
+func NewDeadLetterHandler(deadLetters *usecase.DeadLetterService, logg *logger.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetters: deadLetters,
+		logg:        logg,
+	}
+}
+
+// DeadLetterResponse represents a dead letter
+type DeadLetterResponse struct {
+	ID          int64             `json:"id"`
+	Source      string            `json:"source"` // jobs, outbox, nats, redis or sqs
+	Type        string            `json:"type,omitempty"`
+	MessageID   string            `json:"message_id,omitempty"`
+	Payload     any               `json:"payload"` // JSON payloads as they are, others as a string
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Error       string            `json:"error"`
+	Attempts    int               `json:"attempts"`
+	FailedAt    string            `json:"failed_at"`
+	Replays     int               `json:"replays"`
+	ReplayedAt  string            `json:"replayed_at,omitempty"`
+	ReplayError string            `json:"replay_error,omitempty"`
+}
+
+func toDeadLetterResponse(d *domain.DeadLetter) *DeadLetterResponse {
+	resp := &DeadLetterResponse{
+		ID:          d.ID,
+		Source:      d.Source,
+		Type:        d.Type,
+		MessageID:   d.MessageID,
+		Payload:     string(d.Payload),
+		Attributes:  d.Attributes,
+		Error:       d.Error,
+		Attempts:    d.Attempts,
+		FailedAt:    d.FailedAt.UTC().Format(time.RFC3339),
+		Replays:     d.Replays,
+		ReplayError: d.ReplayError,
+	}
+	if json.Valid(d.Payload) {
+		resp.Payload = json.RawMessage(d.Payload)
+	}
+	if d.ReplayedAt != nil {
+		resp.ReplayedAt = d.ReplayedAt.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+// ListDeadLettersParams holds the query parameters of GET /api/admin/dead-letters
+type ListDeadLettersParams struct {
+	PaginationParams
+	Source string `query:"source" enum:"jobs,outbox,nats,redis,sqs"`
+	Type   string `query:"type"`
+	Status string `query:"status" enum:"pending,replayed"`
+}
+
+// ReplayDeadLettersRequest represents the request body of a bulk replay
+type ReplayDeadLettersRequest struct {
+	Source string `json:"source"`
+	Type   string `json:"type"`
+	Limit  int    `json:"limit"` // At most 100, the default
+}
+
+// ReplayDeadLettersResponse represents the outcome of a bulk replay
+type ReplayDeadLettersResponse struct {
+	Replayed int                   `json:"replayed"`
+	Failed   []*DeadLetterResponse `json:"failed"`
+}
+
+// List handles GET /api/admin/dead-letters, newest first
+func (h *DeadLetterHandler) List(w http.ResponseWriter, r *http.Request) {
+	var params ListDeadLettersParams
+	if !bindQueryOrRespond(w, r, &params) {
+		return
+	}
+	filter := domain.DeadLetterFilter{
+		Source: params.Source,
+		Type:   params.Type,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+	if params.Status != "" {
+		replayed := params.Status == "replayed"
+		filter.Replayed = &replayed
+	}
+
+	letters, err := h.deadLetters.List(r.Context(), filter)
+	if err != nil {
+		h.logg.Error("failed to list dead letters", "error", err)
+		handleError(w, err)
+		return
+	}
+
+	response := make([]*DeadLetterResponse, len(letters))
+	for i, d := range letters {
+		response[i] = toDeadLetterResponse(d)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"dead_letters": response,
+		"limit":        params.Limit,
+		"offset":       params.Offset,
+	})
+}
+
+// Get handles GET /api/admin/dead-letters/{id}
+func (h *DeadLetterHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.deadLetters.Get(r.Context(), id)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, toDeadLetterResponse(d))
+}
+
+// Replay handles POST /api/admin/dead-letters/{id}/replay
+// A replay that fails answers 502 with the replay's error, also recorded on
+// the dead letter
+func (h *DeadLetterHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.deadLetters.Replay(r.Context(), id)
+	switch {
+	case err == nil:
+		respondJSON(w, http.StatusOK, toDeadLetterResponse(d))
+	case d == nil:
+		handleError(w, err)
+	case errors.Is(err, domain.ErrDeadLetterNotReplayable):
+		status, code, _ := mapDomainErrorToHTTP(err)
+		respondError(w, status, code, err.Error())
+	default:
+		respondError(w, http.StatusBadGateway, "REPLAY_FAILED", err.Error())
+	}
+}
+
+// ReplayAll handles POST /api/admin/dead-letters/replay, replaying the
+// pending dead letters of a source and type, oldest first. It is repeated
+// until replayed and failed are both zero.
+func (h *DeadLetterHandler) ReplayAll(w http.ResponseWriter, r *http.Request) {
+	var req ReplayDeadLettersRequest
+	if err := decodeJSON(r, &req); err != nil || req.Limit < 0 {
+		respondDecodeError(w, err)
+		return
+	}
+
+	report, err := h.deadLetters.ReplayAll(r.Context(), domain.DeadLetterFilter{
+		Source: req.Source,
+		Type:   req.Type,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		h.logg.Error("failed to replay dead letters", "error", err, "source", req.Source, "type", req.Type)
+		handleError(w, err)
+		return
+	}
+
+	response := &ReplayDeadLettersResponse{Replayed: report.Replayed, Failed: make([]*DeadLetterResponse, len(report.Failed))}
+	for i, d := range report.Failed {
+		response.Failed[i] = toDeadLetterResponse(d)
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// Delete handles DELETE /api/admin/dead-letters/{id}
+func (h *DeadLetterHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.deadLetters.Delete(r.Context(), id); err != nil {
+		handleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deadLetterID parses the {id} path value, responding 400 when it is not one
+func deadLetterID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Dead letter ID must be a positive integer")
+		return 0, false
+	}
+	return id, true
+}
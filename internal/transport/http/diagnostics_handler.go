@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/diagnostics"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// DiagnosticsHandler handles on-demand diagnostics dumps
+// Transport layer - handles HTTP concerns only, delegates capture to the collector
+type DiagnosticsHandler struct {
+	collector *diagnostics.Collector
+	sink      diagnostics.Sink
+	logg      *logger.Logger
+}
+
+// NewDiagnosticsHandler creates a handler writing dumps to sink
+func NewDiagnosticsHandler(collector *diagnostics.Collector, sink diagnostics.Sink, logg *logger.Logger) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		collector: collector,
+		sink:      sink,
+		logg:      logg,
+	}
+}
+
+// DiagnosticsResponse is a dump and where it was stored
+type DiagnosticsResponse struct {
+	Location string `json:"location"` // "log" or the blob key
+	*diagnostics.Dump
+}
+
+// Dump handles POST /api/admin/diagnostics: it takes a dump, stores it like
+// a SIGQUIT dump and returns it (requires the admin scope)
+func (h *DiagnosticsHandler) Dump(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil || !claims.HasScope(auth.ScopeAdmin) {
+		emitPermissionDenied(r, "admin_scope_required", nil)
+		handleError(w, domain.ErrForbidden)
+		return
+	}
+
+	dump := h.collector.Capture(diagnostics.ReasonAdmin)
+	location, err := h.sink.Write(r.Context(), dump)
+	if err != nil {
+		h.logg.Error("failed to write diagnostics dump", "error", err)
+		handleError(w, domain.ErrInternalError)
+		return
+	}
+
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventAdminAction,
+		Outcome: security.OutcomeSuccess,
+		Action:  "diagnostics_dump",
+		Details: map[string]string{"location": location},
+	})
+	respondJSON(w, http.StatusOK, DiagnosticsResponse{Location: location, Dump: dump})
+}
+
+// Stats handles GET /metrics on the admin listener: the diagnostics sections
+// (pools, in-flight requests, caches) and runtime counters, without stacks
+func (h *DiagnosticsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.collector.Stats())
+}
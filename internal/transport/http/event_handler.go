@@ -0,0 +1,251 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/broadcast"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// EventHandler streams order lifecycle events as Server-Sent Events
+// Transport layer - live events arrive through Publish (fed from the order
+// event log), missed ones are replayed from the log itself
+type EventHandler struct {
+	eventLog  domain.OrderEventLog
+	hub       *broadcast.Hub
+	heartbeat time.Duration
+	logg      *logger.Logger
+}
+
+// NewEventHandler creates the order event stream handler; idle streams get a
+// comment line every heartbeat, and a client that cannot take one within a
+// heartbeat is disconnected
+func NewEventHandler(eventLog domain.OrderEventLog, hub *broadcast.Hub, heartbeat time.Duration, logg *logger.Logger) *EventHandler {
+	return &EventHandler{
+		eventLog:  eventLog,
+		hub:       hub,
+		heartbeat: heartbeat,
+		logg:      logg,
+	}
+}
+
+// OrderEventResponse is the data of an "order" event
+type OrderEventResponse struct {
+	ID             string `json:"id"`
+	OrderID        string `json:"order_id"`
+	UserID         string `json:"user_id"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"` // Empty when the order was created
+	OccurredAt     string `json:"occurred_at"`
+}
+
+const (
+	// orderEventsTopic is the hub topic and the SSE event name of order events
+	orderEventsTopic = "order"
+
+	// eventReplayPage is how many missed events are read from the log at once
+	eventReplayPage = 500
+
+	// sseRetryMS tells clients how long to wait before reconnecting
+	sseRetryMS = 3000
+)
+
+func toOrderEventResponse(e *domain.OrderEvent) OrderEventResponse {
+	return OrderEventResponse{
+		ID:             e.ID,
+		OrderID:        e.OrderID,
+		UserID:         e.UserID,
+		Status:         string(e.Status),
+		PreviousStatus: string(e.PreviousStatus),
+		OccurredAt:     e.OccurredAt.Format(graphQLTimeFormat),
+	}
+}
+
+// Publish forwards an event read from the order event log to the open streams
+func (h *EventHandler) Publish(event *domain.OrderEvent) {
+	data, err := json.Marshal(toOrderEventResponse(event))
+	if err != nil {
+		h.logg.Error("failed to encode order event", "error", err, "order_id", event.OrderID)
+		return
+	}
+	h.hub.Publish(broadcast.Event{ID: event.ID, Topic: orderEventsTopic, Data: data})
+}
+
+// eventFilter selects the events a stream receives
+type eventFilter struct {
+	userID   string               // Empty for every user
+	statuses []domain.OrderStatus // Empty for every status
+}
+
+func (f eventFilter) matches(userID string, status domain.OrderStatus) bool {
+	return (f.userID == "" || f.userID == userID) &&
+		(len(f.statuses) == 0 || slices.Contains(f.statuses, status))
+}
+
+// Stream handles GET /api/events?user_id=&status=, streaming order events as
+// Server-Sent Events. Users only see events of their own orders; admins see
+// every user's unless user_id narrows it down. A client reconnecting with the
+// Last-Event-ID header (or last_event_id parameter) first receives the events
+// it missed, or a "reset" event when they are no longer retained.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+
+	// Subscribe before replaying, so no event falls between the two; events
+	// that arrive both ways are sent once
+	var subject string
+	if claims := GetClaims(r.Context()); claims != nil {
+		subject = claims.Subject
+	}
+	sub := h.hub.Subscribe(subject, orderEventsTopic)
+	defer sub.Close()
+
+	// Read the first missed page before the response starts, so a bad ID is a 400
+	var missed []*domain.OrderEvent
+	expired := false
+	if lastID != "" {
+		var err error
+		missed, err = h.eventLog.After(r.Context(), lastID, eventReplayPage, 0)
+		if errors.Is(err, domain.ErrOrderEventsExpired) {
+			expired = true
+		} else if err != nil {
+			handleError(w, err)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	s := &sseWriter{w: w, rc: rc, timeout: h.heartbeat}
+	s.printf("retry: %d\n\n", sseRetryMS)
+	if expired {
+		s.printf("event: reset\ndata: {\"reason\":\"events since Last-Event-ID are no longer retained\"}\n\n")
+	}
+
+	replayed := make(map[string]bool)
+	for len(missed) > 0 {
+		for _, e := range missed {
+			replayed[e.ID] = true
+			lastID = e.ID
+			if filter.matches(e.UserID, e.Status) {
+				data, _ := json.Marshal(toOrderEventResponse(e))
+				s.event(e.ID, data)
+			}
+		}
+		if len(missed) < eventReplayPage {
+			break
+		}
+		var err error
+		if missed, err = h.eventLog.After(r.Context(), lastID, eventReplayPage, 0); err != nil {
+			// The client reconnects and resumes from the last event it got
+			h.logg.Warn("order event replay failed", "error", err, "last_event_id", lastID)
+			return
+		}
+	}
+	if s.flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e := <-sub.Events():
+			if replayed[e.ID] {
+				delete(replayed, e.ID)
+				continue
+			}
+			var event OrderEventResponse
+			if json.Unmarshal(e.Data, &event) != nil || !filter.matches(event.UserID, domain.OrderStatus(event.Status)) {
+				continue
+			}
+			s.event(e.ID, e.Data)
+		case <-heartbeat.C:
+			s.printf(": heartbeat\n\n")
+		case <-sub.Done():
+			// Too slow or shutting down: the client reconnects with Last-Event-ID
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if s.flush() != nil {
+			return
+		}
+	}
+}
+
+// parseFilter reads user_id and status, writing an error response if they
+// are invalid or name another user's orders
+func (h *EventHandler) parseFilter(w http.ResponseWriter, r *http.Request) (eventFilter, bool) {
+	query := r.URL.Query()
+	filter := eventFilter{userID: query.Get("user_id")}
+
+	if claims := GetClaims(r.Context()); claims != nil && !claims.HasScope(auth.ScopeAdmin) {
+		if filter.userID != "" && filter.userID != claims.Subject {
+			emitPermissionDenied(r, "events_of_other_user", map[string]string{"user_id": filter.userID})
+			handleError(w, domain.ErrForbidden)
+			return eventFilter{}, false
+		}
+		filter.userID = claims.Subject
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			status := domain.OrderStatus(strings.TrimSpace(part))
+			if !status.IsValid() {
+				respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid query parameters",
+					map[string]string{"status": fmt.Sprintf("unknown order status %q", status)})
+				return eventFilter{}, false
+			}
+			filter.statuses = append(filter.statuses, status)
+		}
+	}
+	return filter, true
+}
+
+// sseWriter writes Server-Sent Events, remembering the first write error
+type sseWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+	err     error
+}
+
+func (s *sseWriter) printf(format string, args ...any) {
+	if s.err != nil {
+		return
+	}
+	// Bound every write, so a client that stopped reading is dropped
+	s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+	_, s.err = fmt.Fprintf(s.w, format, args...)
+}
+
+func (s *sseWriter) event(id string, data []byte) {
+	s.printf("id: %s\nevent: %s\ndata: %s\n\n", id, orderEventsTopic, data)
+}
+
+func (s *sseWriter) flush() error {
+	if s.err == nil {
+		s.err = s.rc.Flush()
+	}
+	return s.err
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/broadcast"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// eventsFixture serves GET /api/events over an in-memory event log,
+// authenticating requests as the user named by the X-Test-User header
+type eventsFixture struct {
+	h      http.Handler
+	log    domain.OrderEventLog
+	events *EventHandler
+}
+
+// newEventsFixture keeps the newest retain events in the log
+func newEventsFixture(t *testing.T, retain int) *eventsFixture {
+	t.Helper()
+	logg := logger.New("error")
+	hub := broadcast.NewHub(logg)
+	t.Cleanup(hub.Close)
+	f := &eventsFixture{log: memory.NewOrderEventLog(retain)}
+	f.events = NewEventHandler(f.log, hub, time.Minute, logg)
+	f.h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := r.Header.Get("X-Test-User"); user != "" {
+			r = r.WithContext(context.WithValue(r.Context(), ClaimsKey, &auth.Claims{Subject: user}))
+		}
+		f.events.Stream(w, r)
+	})
+	return f
+}
+
+// record appends an event of user's order to the log, returning its ID;
+// publish also sends it to the open streams, as the log reader would
+func (f *eventsFixture) record(t *testing.T, user string, status domain.OrderStatus, publish bool) string {
+	t.Helper()
+	event := &domain.OrderEvent{OrderID: "o-" + user, UserID: user, Status: status, OccurredAt: time.Now()}
+	id, err := f.log.Append(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if publish {
+		event.ID = id
+		f.events.Publish(event)
+	}
+	return id
+}
+
+// sseEvent is an event read from a stream
+type sseEvent struct {
+	id, name, data string
+}
+
+// sseStream reads the events of an open stream
+type sseStream struct {
+	events chan sseEvent
+}
+
+// open starts a stream on a test server for user, sending lastID, if any, as
+// the Last-Event-ID header
+func (f *eventsFixture) open(t *testing.T, target, user, lastID string) *sseStream {
+	t.Helper()
+	server := httptest.NewServer(f.h)
+	t.Cleanup(server.Close)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+target, nil)
+	req.Header.Set("X-Test-User", user)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	// Closed before the server, which waits for the stream to end
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET %s = %d %s, want an event stream", target, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	s := &sseStream{events: make(chan sseEvent, 16)}
+	go func() {
+		defer close(s.events)
+		var e sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				e.id = value
+			case "event":
+				e.name = value
+			case "data":
+				e.data = value
+			case "":
+				// A blank line ends an event; retry and comments are not events
+				if e.name != "" {
+					s.events <- e
+				}
+				e = sseEvent{}
+			}
+		}
+	}()
+	return s
+}
+
+// next returns the next event, failing the test if none arrives
+func (s *sseStream) next(t *testing.T) sseEvent {
+	t.Helper()
+	select {
+	case e, ok := <-s.events:
+		if !ok {
+			t.Fatal("stream ended")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return sseEvent{}
+	}
+}
+
+// orderEvent reads the next event, failing the test unless it is the order
+// event with ID id
+func (s *sseStream) orderEvent(t *testing.T, id string) OrderEventResponse {
+	t.Helper()
+	e := s.next(t)
+	var data OrderEventResponse
+	if e.name != orderEventsTopic || e.id != id || json.Unmarshal([]byte(e.data), &data) != nil || data.ID != id {
+		t.Fatalf("event = %+v, want order event %s", e, id)
+	}
+	return data
+}
+
+func TestEventStreamResume(t *testing.T) {
+	tests := []struct {
+		name      string
+		useHeader bool
+	}{
+		{"Last-Event-ID header", true},
+		{"last_event_id parameter", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newEventsFixture(t, 100)
+			seen := f.record(t, "u1", domain.OrderStatusPending, true)
+			missed := f.record(t, "u1", domain.OrderStatusConfirmed, true)
+			f.record(t, "u2", domain.OrderStatusConfirmed, true)
+			// Appended while the client was away, and not yet published
+			unpublished := f.record(t, "u1", domain.OrderStatusShipped, false)
+
+			var stream *sseStream
+			if tt.useHeader {
+				stream = f.open(t, "/api/events", "u1", seen)
+			} else {
+				stream = f.open(t, "/api/events?last_event_id="+seen, "u1", "")
+			}
+
+			// Only the user's newer events are replayed, oldest first
+			if e := stream.orderEvent(t, missed); e.Status != string(domain.OrderStatusConfirmed) || e.UserID != "u1" {
+				t.Errorf("first replayed event = %+v, want u1's confirmed", e)
+			}
+			stream.orderEvent(t, unpublished)
+
+			// An event already replayed is not sent again when it is published
+			f.events.Publish(&domain.OrderEvent{ID: unpublished, OrderID: "o-u1", UserID: "u1", Status: domain.OrderStatusShipped})
+			live := f.record(t, "u1", domain.OrderStatusDelivered, true)
+			stream.orderEvent(t, live)
+		})
+	}
+}
+
+func TestEventStreamUnknownLastEventID(t *testing.T) {
+	f := newEventsFixture(t, 2)
+	for range 4 {
+		f.record(t, "u1", domain.OrderStatusPending, false)
+	}
+
+	// An ID from another log ahead of this one replays nothing
+	stream := f.open(t, "/api/events", "u1", "99")
+	live := f.record(t, "u1", domain.OrderStatusConfirmed, true)
+	stream.orderEvent(t, live)
+
+	// Events after an ID no longer retained can't be replayed: the client is
+	// told to start over, then gets live events
+	stream = f.open(t, "/api/events", "u1", "1")
+	if e := stream.next(t); e.name != "reset" || !strings.Contains(e.data, "no longer retained") {
+		t.Fatalf("first event = %+v, want a reset", e)
+	}
+	live = f.record(t, "u1", domain.OrderStatusShipped, true)
+	stream.orderEvent(t, live)
+
+	// An ID this log could never have issued is rejected before streaming
+	for _, lastID := range []string{"not-an-id", "-1"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+		r.Header.Set("X-Test-User", "u1")
+		r.Header.Set("Last-Event-ID", lastID)
+		rec := serve(f.h, r)
+		if rec.Code != http.StatusBadRequest || errorCode(t, rec) != "INVALID_EVENT_ID" {
+			t.Errorf("Last-Event-ID %q = %d %q, want 400 INVALID_EVENT_ID", lastID, rec.Code, rec.Body)
+		}
+	}
+}
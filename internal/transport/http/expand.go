@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Relationship Expansion
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// ?expand=user embeds related resources in a response instead of leaving the
+// client to fetch each one by ID. Lists load the related resources of a whole
+// page in one batch.
+
+// expansions is the set of relations a client asked to embed
+type expansions map[string]bool
+
+// bindExpandOrRespond reads ?expand= for a resource with the given
+// relations. Writes a 400 response and returns false for any other relation.
+func bindExpandOrRespond(w http.ResponseWriter, r *http.Request, relations ...string) (expansions, bool) {
+	raw := r.URL.Query().Get("expand")
+	if raw == "" {
+		return nil, true
+	}
+	expand := make(expansions)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(relations, name) {
+			respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_QUERY_PARAMS",
+				"One or more query parameters are invalid",
+				map[string]string{"expand": "must be a comma-separated list of: " + strings.Join(relations, ", ")})
+			return nil, false
+		}
+		expand[name] = true
+	}
+	return expand, true
+}
+
+// keep adds the expanded relations to a sparse fieldset, so ?fields= never
+// trims away what ?expand= asked for
+func (e expansions) keep(fields fieldset) {
+	if fields == nil {
+		return
+	}
+	for name := range e {
+		fields[name] = true
+	}
+}
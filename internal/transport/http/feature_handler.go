@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/featureflag"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// FeatureHandler handles HTTP requests for feature flag states
+// Transport layer - handles HTTP concerns only, delegates evaluation to the flag client
+type FeatureHandler struct {
+	flags *featureflag.Client
+	logg  *logger.Logger
+}
+
+// NewFeatureHandler creates a new feature flag handler
+func NewFeatureHandler(flags *featureflag.Client, logg *logger.Logger) *FeatureHandler {
+	return &FeatureHandler{
+		flags: flags,
+		logg:  logg,
+	}
+}
+
+// FeaturesResponse lists every flag's state for the caller
+type FeaturesResponse struct {
+	Features map[string]bool `json:"features"`
+}
+
+// List handles GET /api/features, so clients can switch UI on the same flags
+// the API evaluates for them
+func (h *FeatureHandler) List(w http.ResponseWriter, r *http.Request) {
+	states, err := h.flags.Evaluate(r.Context())
+	if err != nil {
+		h.logg.Error("failed to evaluate feature flags", "error", err)
+		handleError(w, domain.ErrInternalError)
+		return
+	}
+	respondJSON(w, http.StatusOK, FeaturesResponse{Features: states})
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Sparse Fieldsets
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// ?fields=id,status,amount trims every resource in a response to the listed
+// fields; the id is always kept. The names are those of the resource's JSON
+// representation, checked against its response DTO. List endpoints also pass
+// them to the repository so unneeded columns are not read.
+
+// fieldset is the set of response fields a client asked for; nil keeps all
+type fieldset map[string]bool
+
+// bindFieldsOrRespond reads ?fields= for resources shaped like dto (a
+// response struct). Writes a 400 response and returns false for a field dto
+// does not have.
+func bindFieldsOrRespond(w http.ResponseWriter, r *http.Request, dto reflect.Type) (fieldset, bool) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, true
+	}
+	known := jsonFieldTypes(dto)
+	fields := fieldset{"id": true}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := known[name]; !ok {
+			names := make([]string, 0, len(known))
+			for name := range known {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_QUERY_PARAMS",
+				"One or more query parameters are invalid",
+				map[string]string{"fields": "must be a comma-separated list of: " + strings.Join(names, ", ")})
+			return nil, false
+		}
+		fields[name] = true
+	}
+	return fields, true
+}
+
+// sources returns the domain fields the requested response fields are built
+// from, for narrowing a query; derived maps response fields to their source
+// where the names differ. Returns nil, meaning every field, for a nil set.
+func (f fieldset) sources(derived map[string]string) []string {
+	if f == nil {
+		return nil
+	}
+	names := make([]string, 0, len(f))
+	for name := range f {
+		if source, ok := derived[name]; ok {
+			name = source
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// respondFields sends a success response like respondJSON with every
+// resource in data trimmed to fields
+func respondFields(w http.ResponseWriter, status int, data interface{}, fields fieldset) {
+	writeResponse(w, status, APIResponse{
+		Success: status >= 200 && status < 300,
+		Data:    fields.trim(adaptResponse(responseVersion(w), data)),
+	})
+}
+
+// trim keeps the requested fields of the response structs in data, looking
+// inside the maps and slices that list endpoints wrap their items in
+func (f fieldset) trim(data any) any {
+	if f == nil || data == nil {
+		return data
+	}
+	if m, ok := data.(map[string]interface{}); ok {
+		trimmed := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			trimmed[k] = f.trim(v)
+		}
+		return trimmed
+	}
+
+	v := reflect.ValueOf(data)
+	switch {
+	case v.Kind() == reflect.Slice:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = f.trim(v.Index(i).Interface())
+		}
+		return items
+	case v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			return data
+		}
+		v = v.Elem()
+	case v.Kind() != reflect.Struct:
+		return data
+	}
+
+	t := v.Type()
+	trimmed := make(map[string]interface{}, len(f))
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || !f[name] {
+			continue
+		}
+		value := v.Field(i)
+		if strings.Contains(opts, "omitempty") && value.IsZero() {
+			continue
+		}
+		trimmed[name] = value.Interface()
+	}
+	return trimmed
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// keysOf returns the sorted member names of a JSON object
+func keysOf(object any) []string {
+	m, _ := object.(map[string]any)
+	return slices.Sorted(maps.Keys(m))
+}
+
+func TestFieldsTrimOrders(t *testing.T) {
+	handler, _, _ := orderHandler(t)
+
+	status, body, apiErr := getJSON(t, handler, "/api/orders/o1?fields=status,amount_display")
+	if status != http.StatusOK {
+		t.Fatalf("GET o1 = %d, want 200: %+v", status, apiErr)
+	}
+	if got, want := keysOf(body), []string{"amount_display", "id", "status"}; !slices.Equal(got, want) {
+		t.Errorf("order fields = %v, want %v (the id is always kept)", got, want)
+	}
+	if body["status"] != "pending" || body["amount_display"] != "$10.00" {
+		t.Errorf("order = %v, want its status and display amount", body)
+	}
+
+	// Every item of a list is trimmed; the list's own members are kept
+	status, body, apiErr = getJSON(t, handler, "/api/orders?fields=user_id&limit=10")
+	if status != http.StatusOK {
+		t.Fatalf("GET orders = %d, want 200: %+v", status, apiErr)
+	}
+	if _, ok := body["has_more"]; !ok {
+		t.Errorf("list = %v, want its paging members", keysOf(body))
+	}
+	orders, _ := body["orders"].([]any)
+	if len(orders) != 4 {
+		t.Fatalf("orders = %v, want 4", body["orders"])
+	}
+	for _, order := range orders {
+		if got, want := keysOf(order), []string{"id", "user_id"}; !slices.Equal(got, want) {
+			t.Errorf("listed order fields = %v, want %v", got, want)
+		}
+	}
+
+	// Expanded relations survive the projection
+	status, body, _ = getJSON(t, handler, "/api/users/u1/orders?fields=status&expand=user")
+	if status != http.StatusOK {
+		t.Fatalf("GET orders of u1 = %d, want 200", status)
+	}
+	for _, order := range body["orders"].([]any) {
+		if got, want := keysOf(order), []string{"id", "status", "user"}; !slices.Equal(got, want) {
+			t.Errorf("expanded order fields = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFieldsTrimUsers(t *testing.T) {
+	logg := logger.New("error")
+	_, users, _ := orderHandler(t)
+	h := NewUserHandler(usecase.NewUserService(users, memory.NewUserCache(), logg), logg)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", h.List)
+	mux.HandleFunc("GET /api/users/{id}", h.GetByID)
+
+	status, body, apiErr := getJSON(t, mux, "/api/users/u1?fields=email")
+	if status != http.StatusOK {
+		t.Fatalf("GET u1 = %d, want 200: %+v", status, apiErr)
+	}
+	if got, want := keysOf(body), []string{"email", "id"}; !slices.Equal(got, want) || body["email"] != "u1@example.com" {
+		t.Errorf("user = %v, want only its id and email", body)
+	}
+
+	status, body, _ = getJSON(t, mux, "/api/users?fields=name")
+	if status != http.StatusOK {
+		t.Fatalf("GET users = %d, want 200", status)
+	}
+	for _, user := range body["users"].([]any) {
+		if got, want := keysOf(user), []string{"id", "name"}; !slices.Equal(got, want) {
+			t.Errorf("listed user fields = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFieldsRejectUnknownFields(t *testing.T) {
+	handler, _, orders := orderHandler(t)
+
+	for _, target := range []string{
+		"/api/orders?fields=status,password",
+		"/api/orders/o1?fields=Status",
+		"/api/users/u1/orders?fields=amount%3BDROP",
+	} {
+		status, _, apiErr := getJSON(t, handler, target)
+		if status != http.StatusBadRequest || apiErr == nil || apiErr.Code != "INVALID_QUERY_PARAMS" {
+			t.Errorf("GET %s = %d, %+v; want 400 INVALID_QUERY_PARAMS", target, status, apiErr)
+			continue
+		}
+		if _, ok := apiErr.Details["fields"]; !ok {
+			t.Errorf("GET %s error details = %v, want fields", target, apiErr.Details)
+		}
+	}
+	if len(orders.fields) != 0 {
+		t.Errorf("rejected requests reached the repository with fields %v", orders.fields)
+	}
+}
+
+func TestFieldsNarrowListQueries(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		want   []string // Fields passed to the repository, nil for all
+	}{
+		{"no projection", "/api/orders", nil},
+		{"projection", "/api/orders?fields=status,amount", []string{"amount", "id", "status"}},
+		{"derived fields read their source", "/api/orders?fields=amount_display,user&expand=user", []string{"amount", "id", "user_id"}},
+		{"items", "/api/orders?fields=items", []string{"id", "items"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _, orders := orderHandler(t)
+
+			if status, _, apiErr := getJSON(t, handler, tt.target); status != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %+v", status, apiErr)
+			}
+			if len(orders.fields) != 1 {
+				t.Fatalf("repository list reads = %d, want 1", len(orders.fields))
+			}
+			if got := orders.fields[0]; !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("fields = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
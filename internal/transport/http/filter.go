@@ -0,0 +1,177 @@
+package http
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Filter and Sort Parameters
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// List endpoints accept filters on a whitelist of fields per resource
+// (domain.UserQueryFields, domain.OrderQueryFields):
+//
+//	?status=shipped                 equal to
+//	?amount[gte]=100&amount[lt]=500 eq, ne, gt, gte, lt, lte
+//	?status[in]=pending,confirmed   any of
+//	?name[contains]=ada             substring, ignoring case
+//	?sort=-created_at,amount        ascending, or descending with a leading -
+//
+// Every filter must match. The repositories turn them into parameterized SQL;
+// values never become part of a statement.
+
+const (
+	// maxSortKeys is the most fields a list can be sorted by
+	maxSortKeys = 3
+	// maxInValues is the most values an [in] filter can list
+	maxInValues = 100
+)
+
+// parseListQuery reads the filters and sort order of a list from its query
+// parameters. Parameters that are neither a field of fields nor bracketed are
+// left to other binders. Every invalid parameter is reported.
+func parseListQuery(values url.Values, fields map[string]domain.QueryField) (domain.ListQuery, map[string]string) {
+	var q domain.ListQuery
+	bindErr := &QueryBindingError{}
+
+	// Sorted so filters reach the database in a stable order
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		fieldName, op := name, domain.OpEq
+		if i := strings.IndexByte(name, '['); i >= 0 && strings.HasSuffix(name, "]") {
+			fieldName, op = name[:i], domain.FilterOp(name[i+1:len(name)-1])
+		}
+		field, known := fields[fieldName]
+		if !known {
+			if fieldName != name {
+				bindErr.add(name, "is not a filterable field")
+			}
+			continue
+		}
+		if !field.Allows(op) {
+			bindErr.add(name, "operator must be one of: "+joinOps(field.Ops))
+			continue
+		}
+		for _, raw := range values[name] {
+			value, msg := filterValue(field, op, raw)
+			if msg != "" {
+				bindErr.add(name, msg)
+				break
+			}
+			q.Filters = append(q.Filters, domain.Filter{Field: fieldName, Op: op, Value: value})
+		}
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		keys, msg := parseSort(sort, fields)
+		if msg != "" {
+			bindErr.add("sort", msg)
+		}
+		q.Sort = keys
+	}
+	return q, bindErr.Errors
+}
+
+// filterValue parses the value of one filter by the kind of its field
+func filterValue(field domain.QueryField, op domain.FilterOp, raw string) (any, string) {
+	if op == domain.OpIn {
+		var values []string
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				if msg := checkFieldValue(field, v); msg != "" {
+					return nil, msg
+				}
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 || len(values) > maxInValues {
+			return nil, "must list between 1 and " + strconv.Itoa(maxInValues) + " comma-separated values"
+		}
+		return values, ""
+	}
+
+	switch field.Kind {
+	case domain.NumberField:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, "must be a number"
+		}
+		return n, ""
+	case domain.TimeField:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, "must be an RFC 3339 timestamp"
+		}
+		return t, ""
+	}
+	if raw == "" && op == domain.OpContains {
+		return nil, "must not be empty"
+	}
+	if op != domain.OpContains {
+		if msg := checkFieldValue(field, raw); msg != "" {
+			return nil, msg
+		}
+	}
+	return raw, ""
+}
+
+// checkFieldValue validates a value of an enumerated string field
+func checkFieldValue(field domain.QueryField, value string) string {
+	if len(field.Values) == 0 || slices.Contains(field.Values, value) {
+		return ""
+	}
+	return "must be one of: " + strings.Join(field.Values, ", ")
+}
+
+// parseSort reads a comma-separated list of sortable fields, each descending
+// with a leading -
+func parseSort(sort string, fields map[string]domain.QueryField) ([]domain.SortKey, string) {
+	parts := strings.Split(sort, ",")
+	if len(parts) > maxSortKeys {
+		return nil, "must name at most " + strconv.Itoa(maxSortKeys) + " fields"
+	}
+	keys := make([]domain.SortKey, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		key := domain.SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if field, ok := fields[key.Field]; !ok || !field.Sortable {
+			return nil, "must be a comma-separated list of: " + strings.Join(sortableFields(fields), ", ")
+		}
+		if slices.ContainsFunc(keys, func(k domain.SortKey) bool { return k.Field == key.Field }) {
+			return nil, "must not name " + key.Field + " twice"
+		}
+		keys = append(keys, key)
+	}
+	return keys, ""
+}
+
+// sortableFields lists the sortable fields in alphabetical order
+func sortableFields(fields map[string]domain.QueryField) []string {
+	var names []string
+	for name, field := range fields {
+		if field.Sortable {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func joinOps(ops []domain.FilterOp) string {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = string(op)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/graphql"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// GraphQLHandler serves GraphQL queries over users and orders
+// Transport layer - handles HTTP concerns only, resolvers delegate to the usecase services
+type GraphQLHandler struct {
+	schema       *graphql.Schema
+	userService  *usecase.UserService
+	orderService *usecase.OrderService
+	maxDepth     int
+	logg         *logger.Logger
+}
+
+// NewGraphQLHandler creates a GraphQL handler; queries nesting fields deeper
+// than maxDepth are rejected (0 allows any depth)
+func NewGraphQLHandler(userService *usecase.UserService, orderService *usecase.OrderService, maxDepth int, logg *logger.Logger) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema:       newGraphQLSchema(userService, orderService),
+		userService:  userService,
+		orderService: orderService,
+		maxDepth:     maxDepth,
+		logg:         logg,
+	}
+}
+
+// Query handles POST /api/graphql with a {query, operationName, variables}
+// body. Responses follow the GraphQL spec rather than the API envelope:
+// 200 with data (and errors for failed fields) once the query executed, 400
+// with errors only when it could not be parsed or validated.
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	// Loaders live for one request, so cached users and orders are never stale
+	ctx := context.WithValue(r.Context(), graphQLLoadersKey{}, newGraphQLLoaders(h.userService, h.orderService))
+	resp := h.schema.Execute(ctx, req,
+		graphql.WithMaxDepth(h.maxDepth),
+		graphql.WithErrorFormatter(h.formatError),
+	)
+
+	status := http.StatusOK
+	if !resp.Executed() {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// formatError maps resolver errors like the REST handlers do, exposing the
+// error code under extensions.code and hiding internal details
+func (h *GraphQLHandler) formatError(err error) *graphql.Error {
+	status, code, message := mapDomainErrorToHTTP(err)
+	if status >= http.StatusInternalServerError {
+		h.logg.Error("graphql resolver failed", "error", err)
+	}
+	return &graphql.Error{Message: message, Extensions: map[string]any{"code": code}}
+}
+
+// Schema handles GET /api/graphql/schema, returning the schema in the schema
+// definition language (introspection queries are not supported)
+func (h *GraphQLHandler) Schema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(h.schema.SDL()))
+}
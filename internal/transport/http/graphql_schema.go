@@ -0,0 +1,257 @@
+package http
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/graphql"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+)
+
+// graphQLTimeFormat matches the timestamps of the REST responses
+const graphQLTimeFormat = "2006-01-02T15:04:05Z"
+
+// userOrdersKey identifies a user's newest orders; User.orders(limit:) may be
+// selected with different limits in one query
+type userOrdersKey struct {
+	UserID string
+	Limit  int
+}
+
+// graphQLLoaders batch and cache the loads of one GraphQL request, so nested
+// fields cost one usecase call per level instead of one per parent
+type graphQLLoaders struct {
+	users  *graphql.Loader[string, *domain.User]
+	orders *graphql.Loader[userOrdersKey, []*domain.Order]
+}
+
+type graphQLLoadersKey struct{}
+
+func newGraphQLLoaders(users *usecase.UserService, orders *usecase.OrderService) *graphQLLoaders {
+	return &graphQLLoaders{
+		users: graphql.NewLoader(func(ctx context.Context, ids []string) (map[string]*domain.User, error) {
+			found, err := users.GetUsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[string]*domain.User, len(found))
+			for _, u := range found {
+				byID[u.ID] = u
+			}
+			return byID, nil
+		}),
+		orders: graphql.NewLoader(func(ctx context.Context, keys []userOrdersKey) (map[userOrdersKey][]*domain.Order, error) {
+			// One usecase call per distinct limit, usually just one
+			userIDs := make(map[int][]string)
+			for _, k := range keys {
+				userIDs[k.Limit] = append(userIDs[k.Limit], k.UserID)
+			}
+			byKey := make(map[userOrdersKey][]*domain.Order, len(keys))
+			for limit, ids := range userIDs {
+				found, err := orders.GetOrdersByUserIDs(ctx, ids, limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, id := range ids {
+					byKey[userOrdersKey{UserID: id, Limit: limit}] = found[id]
+				}
+			}
+			return byKey, nil
+		}),
+	}
+}
+
+// loadersFrom returns the request's loaders (installed by GraphQLHandler)
+func loadersFrom(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey{}).(*graphQLLoaders)
+}
+
+// newGraphQLSchema builds the schema over the user and order services:
+//
+//	type Query {
+//	  user(id: ID!): User
+//	  users(limit: Int = 20, offset: Int = 0): [User!]!
+//	  order(id: ID!): Order
+//	  orders(limit: Int = 20, offset: Int = 0): [Order!]!
+//	}
+//
+// with User.orders and Order.user loaded in batches
+func newGraphQLSchema(users *usecase.UserService, orders *usecase.OrderService) *graphql.Schema {
+	userType := graphql.NewObject("User", "A registered user")
+	orderType := graphql.NewObject("Order", "An order placed by a user")
+	itemType := graphql.NewObject("OrderItem", "A line item of an order")
+	statusType := graphql.NewEnum("OrderStatus", "The state of an order",
+		"pending", "confirmed", "shipped", "delivered", "cancelled")
+
+	pageArgs := func() []*graphql.Argument {
+		return []*graphql.Argument{
+			{Name: "limit", Type: graphql.Int, Default: 20, Description: "Page size, at most 100"},
+			{Name: "offset", Type: graphql.Int, Default: 0},
+		}
+	}
+
+	// User
+	userType.AddField("id", &graphql.Field{Type: graphql.NonNull(graphql.ID), Resolve: graphql.Each(func(_ context.Context, u *domain.User, _ graphql.Args) (any, error) {
+		return u.ID, nil
+	})})
+	userType.AddField("name", &graphql.Field{Type: graphql.NonNull(graphql.String), Resolve: graphql.Each(func(_ context.Context, u *domain.User, _ graphql.Args) (any, error) {
+		return u.Name, nil
+	})})
+	userType.AddField("email", &graphql.Field{Type: graphql.NonNull(graphql.String), Resolve: graphql.Each(func(_ context.Context, u *domain.User, _ graphql.Args) (any, error) {
+		return u.Email, nil
+	})})
+	userType.AddField("createdAt", &graphql.Field{Type: graphql.NonNull(graphql.String), Resolve: graphql.Each(func(_ context.Context, u *domain.User, _ graphql.Args) (any, error) {
+		return u.CreatedAt.Format(graphQLTimeFormat), nil
+	})})
+	userType.AddField("updatedAt", &graphql.Field{Type: graphql.NonNull(graphql.String), Resolve: graphql.Each(func(_ context.Context, u *domain.User, _ graphql.Args) (any, error) {
+		return u.UpdatedAt.Format(graphQLTimeFormat), nil
+	})})
+	userType.AddField("orders", &graphql.Field{
+		Type:        graphql.NonNull(graphql.ListOf(graphql.NonNull(orderType))),
+		Description: "The user's newest orders",
+		Args:        []*graphql.Argument{{Name: "limit", Type: graphql.Int, Default: 20, Description: "Orders per user, at most 100"}},
+		Resolve: func(ctx context.Context, parents []any, args graphql.Args) ([]any, error) {
+			keys := make([]userOrdersKey, len(parents))
+			for i, p := range parents {
+				keys[i] = userOrdersKey{UserID: p.(*domain.User).ID, Limit: args.Int("limit")}
+			}
+			found, err := loadersFrom(ctx).orders.LoadMany(ctx, keys)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]any, len(parents))
+			for i, k := range keys {
+				userOrders := found[k]
+				if userOrders == nil {
+					userOrders = []*domain.Order{}
+				}
+				values[i] = userOrders
+			}
+			return values, nil
+		},
+	})
+
+	// Order
+	orderType.AddField("id", &graphql.Field{Type: graphql.NonNull(graphql.ID), Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		return o.ID, nil
+	})})
+	orderType.AddField("userId", &graphql.Field{Type: graphql.NonNull(graphql.ID), Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		return o.UserID, nil
+	})})
+	orderType.AddField("status", &graphql.Field{Type: graphql.NonNull(statusType), Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		return o.Status, nil
+	})})
+	orderType.AddField("amount", &graphql.Field{Type: graphql.NonNull(graphql.Float), Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		return o.Amount, nil
+	})})
+	orderType.AddField("items", &graphql.Field{Type: graphql.NonNull(graphql.ListOf(graphql.NonNull(itemType))), Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		items := make([]*domain.OrderItem, len(o.Items))
+		for i := range o.Items {
+			items[i] = &o.Items[i]
+		}
+		return items, nil
+	})})
+	orderType.AddField("createdAt", &graphql.Field{Type: graphql.NonNull(graphql.String), Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		return o.CreatedAt.Format(graphQLTimeFormat), nil
+	})})
+	orderType.AddField("updatedAt", &graphql.Field{Type: graphql.NonNull(graphql.String), Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		return o.UpdatedAt.Format(graphQLTimeFormat), nil
+	})})
+	orderType.AddField("cancelledAt", &graphql.Field{Type: graphql.String, Resolve: graphql.Each(func(_ context.Context, o *domain.Order, _ graphql.Args) (any, error) {
+		if o.CancelledAt == nil {
+			return nil, nil
+		}
+		return o.CancelledAt.Format(graphQLTimeFormat), nil
+	})})
+	orderType.AddField("user", &graphql.Field{
+		Type:        userType,
+		Description: "The user who placed the order",
+		Resolve: func(ctx context.Context, parents []any, _ graphql.Args) ([]any, error) {
+			ids := make([]string, len(parents))
+			for i, p := range parents {
+				ids[i] = p.(*domain.Order).UserID
+			}
+			found, err := loadersFrom(ctx).users.LoadMany(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]any, len(parents))
+			for i, id := range ids {
+				if u, ok := found[id]; ok {
+					values[i] = u
+				}
+			}
+			return values, nil
+		},
+	})
+
+	// OrderItem
+	itemType.AddField("productId", &graphql.Field{Type: graphql.NonNull(graphql.ID), Resolve: graphql.Each(func(_ context.Context, item *domain.OrderItem, _ graphql.Args) (any, error) {
+		return item.ProductID, nil
+	})})
+	itemType.AddField("quantity", &graphql.Field{Type: graphql.NonNull(graphql.Int), Resolve: graphql.Each(func(_ context.Context, item *domain.OrderItem, _ graphql.Args) (any, error) {
+		return item.Quantity, nil
+	})})
+	itemType.AddField("price", &graphql.Field{Type: graphql.NonNull(graphql.Float), Resolve: graphql.Each(func(_ context.Context, item *domain.OrderItem, _ graphql.Args) (any, error) {
+		return item.Price, nil
+	})})
+
+	// Query
+	query := graphql.NewObject("Query", "")
+	query.AddField("user", &graphql.Field{
+		Type:        userType,
+		Description: "A user by ID, or null if there is none",
+		Args:        []*graphql.Argument{{Name: "id", Type: graphql.NonNull(graphql.ID)}},
+		Resolve: graphql.Each(func(ctx context.Context, _ any, args graphql.Args) (any, error) {
+			u, err := users.GetUserByID(ctx, args.String("id"))
+			if errors.Is(err, domain.ErrUserNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			loadersFrom(ctx).users.Prime(u.ID, u)
+			return u, nil
+		}),
+	})
+	query.AddField("users", &graphql.Field{
+		Type: graphql.NonNull(graphql.ListOf(graphql.NonNull(userType))),
+		Args: pageArgs(),
+		Resolve: graphql.Each(func(ctx context.Context, _ any, args graphql.Args) (any, error) {
+			list, err := users.ListUsers(ctx, args.Int("limit"), args.Int("offset"))
+			if err != nil {
+				return nil, err
+			}
+			loaders := loadersFrom(ctx)
+			for _, u := range list {
+				loaders.users.Prime(u.ID, u)
+			}
+			return list, nil
+		}),
+	})
+	query.AddField("order", &graphql.Field{
+		Type:        orderType,
+		Description: "An order by ID, or null if there is none",
+		Args:        []*graphql.Argument{{Name: "id", Type: graphql.NonNull(graphql.ID)}},
+		Resolve: graphql.Each(func(ctx context.Context, _ any, args graphql.Args) (any, error) {
+			o, err := orders.GetOrderByID(ctx, args.String("id"))
+			if errors.Is(err, domain.ErrOrderNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return o, nil
+		}),
+	})
+	query.AddField("orders", &graphql.Field{
+		Type: graphql.NonNull(graphql.ListOf(graphql.NonNull(orderType))),
+		Args: pageArgs(),
+		Resolve: graphql.Each(func(ctx context.Context, _ any, args graphql.Args) (any, error) {
+			return orders.ListOrders(ctx, args.Int("limit"), args.Int("offset"))
+		}),
+	})
+
+	return graphql.NewSchema(query)
+}
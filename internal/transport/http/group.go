@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Route Groups
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// The global stack in NewRouter runs for every request. Middleware only some
+// routes need belongs to a route group instead: a registrar that wraps each
+// route registered through it with the group's chain, so the register
+// functions stay unaware of it. Groups derive from each other:
+//
+//	api := newGroup(routes, middleware.ContentType(...))
+//	admin := api.With(RequireScope(auth.ScopeAdmin))
+//	registerReportRoutes(admin, reportHandler)
+//
+// A group's chain runs inside the global stack, once the mux has matched the
+// route, so r.Pattern is set and unmatched paths never reach it.
+
+// group registers routes with a chain of middleware applied
+type group struct {
+	routes      routeRegistrar
+	middlewares []Middleware
+}
+
+// newGroup returns a group registering on routes with middlewares,
+// first applied outermost
+func newGroup(routes routeRegistrar, middlewares ...Middleware) group {
+	return group{routes: routes, middlewares: middlewares}
+}
+
+// With returns a group running the group's chain and then middlewares
+func (g group) With(middlewares ...Middleware) group {
+	return group{routes: g.routes, middlewares: append(slices.Clip(g.middlewares), middlewares...)}
+}
+
+// HandleFunc registers handler for pattern behind the group's chain
+func (g group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	if len(g.middlewares) == 0 {
+		g.routes.HandleFunc(pattern, handler)
+		return
+	}
+	g.routes.HandleFunc(pattern, middleware.Chain(http.HandlerFunc(handler), g.middlewares...).ServeHTTP)
+}
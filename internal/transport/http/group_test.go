@@ -0,0 +1,113 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// tagging appends name to the X-Chain header on the way in
+func tagging(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestGroupChain(t *testing.T) {
+	mux := http.NewServeMux()
+	base := newGroup(mux, tagging("a"), tagging("b"))
+	// Both derive from a chain with spare capacity, so appending in place
+	// would let one overwrite the other's middleware
+	base.middlewares = append(make([]Middleware, 0, 4), base.middlewares...)
+	left := base.With(tagging("left"))
+	right := base.With(tagging("right"))
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	newGroup(mux).HandleFunc("GET /bare", ok)
+	base.HandleFunc("GET /base", ok)
+	left.HandleFunc("GET /left", ok)
+	right.HandleFunc("GET /right", ok)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/bare", ""},
+		{"/base", "a,b"},
+		{"/left", "a,b,left"},
+		{"/right", "a,b,right"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(mux, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want 204", rec.Code)
+			}
+			if got := strings.Join(rec.Header().Values("X-Chain"), ","); got != tt.want {
+				t.Errorf("chain = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// groupRouter is the full router over handlers that are never reached: each
+// request it is sent must be answered by a group's middleware
+func groupRouter(t *testing.T) (*Router, *auth.TokenManager) {
+	t.Helper()
+	tokens := newTestTokens(t)
+	router := NewRouter(RouterConfig{
+		Logger:      logger.New("error"),
+		Tokens:      tokens,
+		Idempotency: memory.NewIdempotencyStore(),
+	}, Handlers{User: &UserHandler{}, Order: &OrderHandler{}, Session: &SessionHandler{}})
+	return router, tokens
+}
+
+func TestRouterAdminGroupRequiresTheAdminScope(t *testing.T) {
+	router, tokens := groupRouter(t)
+	token := issueTestToken(t, tokens, auth.Claims{Subject: "user-1"})
+
+	r := authRequest(http.MethodPost, "/api/admin/users/user-2/revoke-tokens", token)
+	rec := serve(router, r)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "FORBIDDEN") {
+		t.Errorf("body = %s, want FORBIDDEN", rec.Body)
+	}
+}
+
+func TestRouterGroupsBodyChecks(t *testing.T) {
+	router, tokens := groupRouter(t)
+	token := issueTestToken(t, tokens, auth.Claims{Subject: "user-1"})
+
+	// API routes refuse bodies no codec reads
+	r := authRequest(http.MethodPost, "/api/users", token)
+	r.Body = http.NoBody
+	r.Header.Set("Content-Type", "text/plain")
+	if rec := serve(router, r); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("POST /api/users as text/plain = %d, want 415: %s", rec.Code, rec.Body)
+	}
+
+	// Health checks run neither check, whatever the request carries
+	for range 2 {
+		r := httptest.NewRequest(http.MethodGet, "/health", nil)
+		r.Header.Set("Content-Type", "text/plain")
+		r.Header.Set(IdempotencyKeyHeader, "health-key")
+		rec := serve(router, r)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /health = %d, want 200: %s", rec.Code, rec.Body)
+		}
+		if rec.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("GET /health was replayed from the idempotency store")
+		}
+	}
+}
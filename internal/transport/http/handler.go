@@ -1,12 +1,12 @@
 package http
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
-	"strconv"
+	"strings"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/i18n"
 )
 
 // APIResponse represents a standard API response
@@ -18,42 +18,69 @@ type APIResponse struct {
 
 // APIError represents an error response
 type APIError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"` // Per-field validation errors
 }
 
-// respondJSON sends a JSON response with the given status code
+// respondJSON sends a response with the given status code, in the shape of
+// the request's API version and the format it accepts (JSON unless it asks
+// for MessagePack or XML)
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	response := APIResponse{
+	writeResponse(w, status, APIResponse{
 		Success: status >= 200 && status < 300,
-		Data:    data,
-	}
-
-	json.NewEncoder(w).Encode(response)
+		Data:    adaptResponse(responseVersion(w), data),
+	})
 }
 
 // respondError sends an error response with the given status code
 func respondError(w http.ResponseWriter, status int, code, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	writeResponse(w, status, APIResponse{
+		Success: false,
+		Error: &APIError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
 
-	response := APIResponse{
+// respondErrorWithDetails sends an error response including per-field details
+func respondErrorWithDetails(w http.ResponseWriter, status int, code, message string, details map[string]string) {
+	writeResponse(w, status, APIResponse{
 		Success: false,
 		Error: &APIError{
 			Code:    code,
 			Message: message,
+			Details: details,
 		},
+	})
+}
+
+// writeResponse encodes response with the codec negotiated for w. A
+// successful response to a request accepting none of the codecs' formats
+// is replaced by a 406; error responses are sent as JSON then.
+func writeResponse(w http.ResponseWriter, status int, response APIResponse) {
+	if nw, ok := w.(*negotiatedWriter); ok && nw.unacceptable && response.Success {
+		status, response = http.StatusNotAcceptable, APIResponse{Error: &APIError{
+			Code:    "NOT_ACCEPTABLE",
+			Message: "Accept must allow one of " + strings.Join(codecMediaTypes(), ", "),
+		}}
 	}
+	codec := responseCodec(w)
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.WriteHeader(status)
 
-	json.NewEncoder(w).Encode(response)
+	codec.Encode(w, response)
 }
 
 // mapDomainErrorToHTTP maps domain errors to appropriate HTTP status codes
 func mapDomainErrorToHTTP(err error) (int, string, string) {
 	switch {
+	// Before the order errors: a failed checkout wraps the cause
+	case errors.Is(err, domain.ErrPaymentDeclined):
+		return http.StatusPaymentRequired, "PAYMENT_DECLINED", "Payment was declined"
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return http.StatusConflict, "INSUFFICIENT_STOCK", "Not enough stock for the order"
 	case errors.Is(err, domain.ErrUserNotFound):
 		return http.StatusNotFound, "USER_NOT_FOUND", "User not found"
 	case errors.Is(err, domain.ErrOrderNotFound):
@@ -74,12 +101,62 @@ func mapDomainErrorToHTTP(err error) (int, string, string) {
 		return http.StatusBadRequest, "INVALID_ORDER_AMOUNT", "Invalid order amount"
 	case errors.Is(err, domain.ErrOrderCannotBeCancelled):
 		return http.StatusBadRequest, "ORDER_CANNOT_BE_CANCELLED", "Order cannot be cancelled in current state"
+	case errors.Is(err, domain.ErrAccessTokenNotFound):
+		return http.StatusNotFound, "ACCESS_TOKEN_NOT_FOUND", "Access token not found"
+	case errors.Is(err, domain.ErrInvalidScope):
+		return http.StatusBadRequest, "INVALID_SCOPE", "Invalid or unsupported scope"
 	case errors.Is(err, domain.ErrUnauthorized):
 		return http.StatusUnauthorized, "UNAUTHORIZED", "Unauthorized access"
 	case errors.Is(err, domain.ErrForbidden):
 		return http.StatusForbidden, "FORBIDDEN", "Access forbidden"
 	case errors.Is(err, domain.ErrConflict):
 		return http.StatusConflict, "CONFLICT", "Resource conflict"
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return http.StatusPreconditionFailed, "PRECONDITION_FAILED", "Resource has changed since the If-Match version"
+	case errors.Is(err, domain.ErrJobNotFound):
+		return http.StatusNotFound, "JOB_NOT_FOUND", "Job not found"
+	case errors.Is(err, domain.ErrJobNotFinished):
+		return http.StatusConflict, "JOB_NOT_FINISHED", "Job has no result until it succeeds"
+	case errors.Is(err, domain.ErrInvalidJobPriority):
+		return http.StatusBadRequest, "INVALID_JOB_PRIORITY", "Invalid job priority"
+	case errors.Is(err, domain.ErrReportScheduleNotFound):
+		return http.StatusNotFound, "REPORT_SCHEDULE_NOT_FOUND", "Report schedule not found"
+	case errors.Is(err, domain.ErrUnknownReport):
+		return http.StatusBadRequest, "UNKNOWN_REPORT", "Unknown report"
+	case errors.Is(err, domain.ErrInvalidSchedule):
+		return http.StatusBadRequest, "INVALID_SCHEDULE", "Invalid cron schedule"
+	case errors.Is(err, domain.ErrAttachmentNotFound):
+		return http.StatusNotFound, "ATTACHMENT_NOT_FOUND", "Attachment not found"
+	case errors.Is(err, domain.ErrAttachmentScanPending):
+		return http.StatusConflict, "ATTACHMENT_SCAN_PENDING", "Attachment is awaiting its malware scan"
+	case errors.Is(err, domain.ErrAttachmentInfected):
+		return http.StatusForbidden, "ATTACHMENT_INFECTED", "Attachment failed its malware scan and cannot be downloaded"
+	case errors.Is(err, domain.ErrAttachmentNotUploaded):
+		return http.StatusConflict, "ATTACHMENT_NOT_UPLOADED", "Attachment upload has not been completed"
+	case errors.Is(err, domain.ErrAttachmentMismatch):
+		return http.StatusUnprocessableEntity, "ATTACHMENT_MISMATCH", "Uploaded object does not match the attachment"
+	case errors.Is(err, domain.ErrUploadOffsetMismatch):
+		return http.StatusConflict, "UPLOAD_OFFSET_MISMATCH", "Upload-Offset does not match the bytes received"
+	case errors.Is(err, domain.ErrUploadLocked):
+		return http.StatusLocked, "UPLOAD_LOCKED", "The upload is receiving another request"
+	case errors.Is(err, domain.ErrInvalidScanStatus):
+		return http.StatusBadRequest, "INVALID_SCAN_STATUS", "Scan status must be clean or infected"
+	case errors.Is(err, domain.ErrSemaphoreFull):
+		return http.StatusServiceUnavailable, "TOO_BUSY", "Too many similar operations in progress, please retry shortly"
+	case errors.Is(err, domain.ErrInvalidEventID):
+		return http.StatusBadRequest, "INVALID_EVENT_ID", "Invalid event ID"
+	case errors.Is(err, domain.ErrInvalidNotificationPreferences):
+		return http.StatusBadRequest, "INVALID_NOTIFICATION_PREFERENCES", "Invalid notification preferences"
+	case errors.Is(err, domain.ErrNotificationChannelUnavailable):
+		return http.StatusUnprocessableEntity, "CHANNEL_UNAVAILABLE", "Notification channel not available on this server"
+	case errors.Is(err, domain.ErrSagaNotFound):
+		return http.StatusNotFound, "CHECKOUT_NOT_FOUND", "Order has not been checked out"
+	case errors.Is(err, domain.ErrCheckoutFailed):
+		return http.StatusBadGateway, "CHECKOUT_FAILED", "Checkout failed and was rolled back, please retry"
+	case errors.Is(err, domain.ErrDeadLetterNotFound):
+		return http.StatusNotFound, "DEAD_LETTER_NOT_FOUND", "Dead letter not found"
+	case errors.Is(err, domain.ErrDeadLetterNotReplayable):
+		return http.StatusConflict, "DEAD_LETTER_NOT_REPLAYABLE", "Dead letter cannot be replayed on this instance"
 	default:
 		return http.StatusInternalServerError, "INTERNAL_ERROR", "An internal error occurred"
 	}
@@ -91,34 +168,64 @@ func handleError(w http.ResponseWriter, err error) {
 	respondError(w, status, code, message)
 }
 
-// parseIntQueryParam parses an integer query parameter with a default value
-func parseIntQueryParam(r *http.Request, name string, defaultVal int) int {
-	val := r.URL.Query().Get(name)
-	if val == "" {
-		return defaultVal
+// bindQueryOrRespond binds query parameters into dst and writes a 400 response
+// with per-parameter details on failure. Returns false if the request was rejected.
+func bindQueryOrRespond(w http.ResponseWriter, r *http.Request, dst any) bool {
+	err := bindQuery(r, dst)
+	if err == nil {
+		return true
 	}
 
-	parsed, err := strconv.Atoi(val)
-	if err != nil {
-		return defaultVal
+	var bindErr *QueryBindingError
+	if errors.As(err, &bindErr) {
+		respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_QUERY_PARAMS",
+			"One or more query parameters are invalid", bindErr.Errors)
+		return false
 	}
 
-	return parsed
+	respondError(w, http.StatusBadRequest, "INVALID_QUERY_PARAMS", "Invalid query parameters")
+	return false
+}
+
+// requestLocale picks the response locale from the ?locale= parameter or Accept-Language
+func requestLocale(r *http.Request) i18n.Locale {
+	if tag := r.URL.Query().Get("locale"); tag != "" {
+		if loc, ok := i18n.LookupLocale(tag); ok {
+			return loc
+		}
+	}
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
 }
 
-// decodeJSON decodes JSON from request body into the target struct
+// errUnsupportedMediaType is returned by decodeJSON for a body in a format
+// no codec decodes
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// decodeJSON decodes the request body into the target struct, as JSON or in
+// the format its Content-Type names (see codecs)
 func decodeJSON(r *http.Request, target interface{}) error {
 	if r.Body == nil {
 		return domain.ErrInvalidInput
 	}
 	defer r.Body.Close()
 
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-
-	if err := decoder.Decode(target); err != nil {
+	codec, ok := requestCodec(r)
+	if !ok {
+		return errUnsupportedMediaType
+	}
+	if err := codec.Decode(r.Body, target); err != nil {
 		return domain.ErrInvalidInput
 	}
 
 	return nil
 }
+
+// respondDecodeError answers a request whose body decodeJSON rejected
+func respondDecodeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		respondError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE",
+			"Content-Type must be one of "+strings.Join(codecMediaTypes(), ", "))
+		return
+	}
+	respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+}
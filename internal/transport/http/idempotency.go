@@ -0,0 +1,223 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Idempotency Keys
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// A POST carrying an Idempotency-Key header runs at most once per key and
+// principal. The first request reserves the key; its response is stored and
+// replayed, marked Idempotent-Replayed: true, to every retry with the same key
+// until the TTL runs out. A client that lost the response to a network error
+// can resend the request without creating a second order.
+//
+//	same key while the first request runs   409 IDEMPOTENCY_KEY_IN_USE
+//	same key with another method, URL/body  422 IDEMPOTENCY_KEY_REUSED
+//
+// Responses with a 5xx status are not stored, and neither are handlers that
+// panic: the key is released so a retry runs again. Requests without the header behave as before.
+
+const (
+	// IdempotencyKeyHeader names the client-chosen key of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength bounds keys; a UUID needs 36 characters
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyConfig configures idempotency keys
+type IdempotencyConfig struct {
+	Store domain.IdempotencyStore
+	// TTL is how long a completed response is replayed
+	TTL time.Duration
+	// LockTTL bounds how long a request holds its key; a reservation left by
+	// a crashed replica frees itself after it
+	LockTTL time.Duration
+	Logger  *logger.Logger
+}
+
+// Idempotency replays the stored response to POST requests repeating an
+// earlier Idempotency-Key
+func Idempotency(config IdempotencyConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !validIdempotencyKey(key) {
+				respondError(w, http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY",
+					"Idempotency-Key must be 1 to 255 printable ASCII characters")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					respondError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request body is too large")
+					return
+				}
+				respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Keys are per principal, so one client can never replay another's response
+			storeKey := rateLimitPrincipal(r) + ":" + key
+			fingerprint := requestFingerprint(r, body)
+
+			record, err := config.Store.Reserve(r.Context(), storeKey, fingerprint, config.LockTTL)
+			if err != nil {
+				// The client asked for at most once; running without the store can't promise it
+				config.Logger.Error("idempotency key reservation failed", "error", err,
+					"request_id", GetRequestID(r.Context()))
+				respondError(w, http.StatusServiceUnavailable, "IDEMPOTENCY_UNAVAILABLE",
+					"Idempotency keys are temporarily unavailable, please retry")
+				return
+			}
+			switch {
+			case record == nil:
+				// Claimed: serve the request below
+			case record.Fingerprint != fingerprint:
+				respondError(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+					"Idempotency-Key was already used for a different request")
+				return
+			case !record.Completed:
+				w.Header().Set("Retry-After", "1")
+				respondError(w, http.StatusConflict, "IDEMPOTENCY_KEY_IN_USE",
+					"A request with this Idempotency-Key is still being processed")
+				return
+			default:
+				replayResponse(w, record)
+				return
+			}
+
+			// Stored even if the client has gone away, so its retry finds the result
+			ctx := context.WithoutCancel(r.Context())
+			release := func() {
+				if err := config.Store.Release(ctx, storeKey); err != nil {
+					config.Logger.Warn("idempotency key release failed", "error", err,
+						"request_id", GetRequestID(r.Context()))
+				}
+			}
+			// Recovery answers a panic with a 500 further out; without the release
+			// every retry would get 409 until the lock TTL ran out
+			defer func() {
+				if p := recover(); p != nil {
+					release()
+					panic(p)
+				}
+			}()
+
+			rec := &responseRecorder{ResponseWriter: w, before: w.Header().Clone(), status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				release()
+				return
+			}
+			err = config.Store.Complete(ctx, storeKey, &domain.IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      rec.status,
+				Header:      rec.header,
+				Body:        rec.body.Bytes(),
+			}, config.TTL)
+			if err != nil {
+				config.Logger.Warn("idempotency response not stored", "error", err,
+					"request_id", GetRequestID(r.Context()))
+			}
+		})
+	}
+}
+
+// validIdempotencyKey reports whether key is printable ASCII of a sensible length
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFingerprint hashes what makes a request the same request; the URL
+// is the one sent, with its version prefix
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.RequestURI+"\n"+r.Header.Get("Content-Type")+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayResponse writes a stored response again
+func replayResponse(w http.ResponseWriter, record *domain.IdempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = slices.Clone(values)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status, body and the headers set after the middleware ran (the request ID
+// and other per-request headers are set anew on a replay). Shared by
+// idempotency keys and the response cache.
+type responseRecorder struct {
+	http.ResponseWriter
+	before      http.Header
+	header      map[string][]string
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = code
+	rec.header = make(map[string][]string)
+	for name, values := range rec.Header() {
+		if !slices.Equal(rec.before[name], values) {
+			rec.header[name] = slices.Clone(values)
+		}
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer (used by http.ResponseController)
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
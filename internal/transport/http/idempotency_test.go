@@ -0,0 +1,284 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// idempotentHandler counts its calls and answers with respond
+type idempotentHandler struct {
+	calls   atomic.Int32
+	respond func(w http.ResponseWriter, r *http.Request)
+}
+
+func (h *idempotentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls.Add(1)
+	h.respond(w, r)
+}
+
+func newIdempotencyFixture(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) (http.Handler, *idempotentHandler, domain.IdempotencyStore) {
+	t.Helper()
+	store := memory.NewIdempotencyStore()
+	inner := &idempotentHandler{respond: respond}
+	mw := Idempotency(IdempotencyConfig{
+		Store:   store,
+		TTL:     time.Hour,
+		LockTTL: time.Minute,
+		Logger:  logger.New("error"),
+	})
+	return mw(inner), inner, store
+}
+
+func created(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Location", "/api/orders/order-1")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return r
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	h, inner, _ := newIdempotencyFixture(t, created)
+
+	first := serve(h, idempotentRequest("key-1", `{"item":"a"}`))
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", first.Code)
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("first response is marked as replayed")
+	}
+
+	retry := serve(h, idempotentRequest("key-1", `{"item":"a"}`))
+	if retry.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want 201", retry.Code)
+	}
+	if got := retry.Header().Get(IdempotentReplayedHeader); got != "true" {
+		t.Errorf("%s = %q, want true", IdempotentReplayedHeader, got)
+	}
+	if got := retry.Header().Get("Location"); got != "/api/orders/order-1" {
+		t.Errorf("replayed Location = %q", got)
+	}
+	if retry.Body.String() != `{"item":"a"}` {
+		t.Errorf("replayed body = %q", retry.Body.String())
+	}
+	if n := inner.calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyPassesThrough(t *testing.T) {
+	h, inner, _ := newIdempotencyFixture(t, created)
+
+	serve(h, idempotentRequest("", `{}`))
+	serve(h, idempotentRequest("", `{}`))
+
+	get := idempotentRequest("key-1", "")
+	get.Method = http.MethodGet
+	serve(h, get)
+	serve(h, get)
+
+	if n := inner.calls.Load(); n != 4 {
+		t.Errorf("handler ran %d times, want 4", n)
+	}
+}
+
+func TestIdempotencyRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(h http.Handler, store domain.IdempotencyStore)
+		request    *http.Request
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "invalid key",
+			request:    idempotentRequest("key\x01", `{}`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_IDEMPOTENCY_KEY",
+		},
+		{
+			name:       "key too long",
+			request:    idempotentRequest(strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_IDEMPOTENCY_KEY",
+		},
+		{
+			name: "different body",
+			setup: func(h http.Handler, store domain.IdempotencyStore) {
+				serve(h, idempotentRequest("key-1", `{"item":"a"}`))
+			},
+			request:    idempotentRequest("key-1", `{"item":"b"}`),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "IDEMPOTENCY_KEY_REUSED",
+		},
+		{
+			name: "different URL",
+			setup: func(h http.Handler, store domain.IdempotencyStore) {
+				serve(h, idempotentRequest("key-1", `{}`))
+			},
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{}`))
+				r.Header.Set("Content-Type", "application/json")
+				r.Header.Set(IdempotencyKeyHeader, "key-1")
+				return r
+			}(),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "IDEMPOTENCY_KEY_REUSED",
+		},
+		{
+			name: "in flight",
+			setup: func(h http.Handler, store domain.IdempotencyStore) {
+				// Another replica holds the key for the same request
+				fp := requestFingerprint(idempotentRequest("key-1", `{}`), []byte(`{}`))
+				store.Reserve(context.Background(), "ip:192.0.2.1:key-1", fp, time.Minute)
+			},
+			request:    idempotentRequest("key-1", `{}`),
+			wantStatus: http.StatusConflict,
+			wantCode:   "IDEMPOTENCY_KEY_IN_USE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, inner, store := newIdempotencyFixture(t, created)
+			if tt.setup != nil {
+				tt.setup(h, store)
+			}
+			calls := inner.calls.Load()
+
+			rec := serve(h, tt.request)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("body = %s, want code %s", rec.Body, tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusConflict && rec.Header().Get("Retry-After") == "" {
+				t.Error("409 without Retry-After")
+			}
+			if inner.calls.Load() != calls {
+				t.Error("handler ran for a rejected request")
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeysArePerPrincipal(t *testing.T) {
+	h, inner, _ := newIdempotencyFixture(t, created)
+
+	serve(h, idempotentRequest("key-1", `{}`))
+	other := idempotentRequest("key-1", `{}`)
+	other.RemoteAddr = "198.51.100.7:1234"
+	rec := serve(h, other)
+
+	if rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("response replayed to another client")
+	}
+	if n := inner.calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyReleasesServerErrors(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	h, inner, _ := newIdempotencyFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		created(w, r)
+	})
+
+	if rec := serve(h, idempotentRequest("key-1", `{}`)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	fail.Store(false)
+	rec := serve(h, idempotentRequest("key-1", `{}`))
+	if rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("retry = %d replayed=%q, want a fresh 201", rec.Code, rec.Header().Get(IdempotentReplayedHeader))
+	}
+	if n := inner.calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyReleasesAfterPanic(t *testing.T) {
+	var panicking atomic.Bool
+	panicking.Store(true)
+	h, inner, _ := newIdempotencyFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		if panicking.Load() {
+			panic("boom")
+		}
+		created(w, r)
+	})
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the handler's panic", p)
+			}
+		}()
+		serve(h, idempotentRequest("key-1", `{}`))
+	}()
+
+	panicking.Store(false)
+	rec := serve(h, idempotentRequest("key-1", `{}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want 201 (key still held): %s", rec.Code, rec.Body)
+	}
+	if n := inner.calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyStoreUnavailable(t *testing.T) {
+	inner := &idempotentHandler{respond: created}
+	h := Idempotency(IdempotencyConfig{
+		Store:   unavailableIdempotencyStore{},
+		TTL:     time.Hour,
+		LockTTL: time.Minute,
+		Logger:  logger.New("error"),
+	})(inner)
+
+	rec := serve(h, idempotentRequest("key-1", `{}`))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "IDEMPOTENCY_UNAVAILABLE") {
+		t.Errorf("status = %d body = %s, want 503 IDEMPOTENCY_UNAVAILABLE", rec.Code, rec.Body)
+	}
+	if inner.calls.Load() != 0 {
+		t.Error("handler ran without a reservation")
+	}
+}
+
+type unavailableIdempotencyStore struct{}
+
+func (unavailableIdempotencyStore) Reserve(context.Context, string, string, time.Duration) (*domain.IdempotencyRecord, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unavailableIdempotencyStore) Complete(context.Context, string, *domain.IdempotencyRecord, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (unavailableIdempotencyStore) Release(context.Context, string) error {
+	return errors.New("connection refused")
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// JobHandler handles HTTP requests for background job status
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type JobHandler struct {
+	jobs *usecase.JobRunner
+	logg *logger.Logger
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobs *usecase.JobRunner, logg *logger.Logger) *JobHandler {
+	return &JobHandler{
+		jobs: jobs,
+		logg: logg,
+	}
+}
+
+// JobResponse represents the status of a background job
+type JobResponse struct {
+	ID         string  `json:"id"`
+	Type       string  `json:"type"`
+	Priority   string  `json:"priority"`
+	State      string  `json:"state"`
+	Progress   int     `json:"progress"`
+	Result     string  `json:"result,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	Attempts   int     `json:"attempts"`
+	CreatedAt  string  `json:"created_at"`
+	UpdatedAt  string  `json:"updated_at"`
+	StartedAt  *string `json:"started_at,omitempty"`
+	FinishedAt *string `json:"finished_at,omitempty"`
+}
+
+// toJobResponse converts a domain job record to a response DTO
+func toJobResponse(j *domain.JobRecord) *JobResponse {
+	return &JobResponse{
+		ID:         j.ID,
+		Type:       j.Type,
+		Priority:   string(j.Priority),
+		State:      string(j.State),
+		Progress:   j.Progress,
+		Result:     j.Result,
+		Error:      j.Error,
+		Attempts:   j.Attempts,
+		CreatedAt:  j.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:  j.UpdatedAt.UTC().Format(time.RFC3339),
+		StartedAt:  formatOptionalTime(j.StartedAt),
+		FinishedAt: formatOptionalTime(j.FinishedAt),
+	}
+}
+
+// GetByID handles GET /api/jobs/{id}
+// Callers see their own jobs; admins see every job
+func (h *JobHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		handleError(w, domain.ErrInvalidInput)
+		return
+	}
+
+	job, err := h.jobs.Get(r.Context(), id)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	if !canSeeJob(r, job) {
+		handleError(w, domain.ErrJobNotFound)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, toJobResponse(job))
+}
+
+// canSeeJob reports whether the caller of r may see job: its owner or an
+// admin. Other callers are told the job is missing, so IDs can't be probed.
+func canSeeJob(r *http.Request, job *domain.JobRecord) bool {
+	claims := GetClaims(r.Context())
+	return claims == nil || job.UserID == claims.Subject || claims.HasScope(auth.ScopeAdmin)
+}
+
+// respondJobAccepted answers a request whose work was queued as job with 202
+// Accepted, pointing the client at the job's status
+func respondJobAccepted(w http.ResponseWriter, job *domain.Job) {
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	w.Header().Set("Retry-After", "1")
+	respondJSON(w, http.StatusAccepted, toJobResponse(domain.NewJobRecord(job)))
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+)
+
+func TestJobHandlerRendersStatus(t *testing.T) {
+	f := newJobsFixture(t)
+	ctx := context.Background()
+	started, release := make(chan struct{}), make(chan error)
+	f.jobs.Handle("test.step", func(ctx context.Context, run *usecase.JobRun) error {
+		run.Progress(ctx, 40)
+		run.SetResult("reports/" + run.ID)
+		close(started)
+		return <-release
+	})
+	job, err := f.jobs.Enqueue(ctx, "test.step", domain.JobPriorityDefault, nil, usecase.WithJobOwner("u1"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	get := func() *JobResponse {
+		t.Helper()
+		rec := f.do(http.MethodGet, "/api/jobs/"+job.ID, "u1", "", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET = %d %q, want 200", rec.Code, rec.Body)
+		}
+		return decodeJob(t, rec)
+	}
+
+	got := get()
+	if got.ID != job.ID || got.Type != "test.step" || got.Priority != string(domain.JobPriorityDefault) ||
+		got.State != string(domain.JobQueued) || got.Progress != 0 || got.Attempts != 0 {
+		t.Errorf("queued job = %+v", got)
+	}
+	if _, err := time.Parse(time.RFC3339, got.CreatedAt); err != nil || got.UpdatedAt != got.CreatedAt {
+		t.Errorf("created_at = %q, updated_at = %q, want the same RFC 3339 time", got.CreatedAt, got.UpdatedAt)
+	}
+	if got.StartedAt != nil || got.FinishedAt != nil || got.Result != "" {
+		t.Errorf("queued job = %+v, want neither started nor finished", got)
+	}
+
+	f.start(t)
+	// Runs before the runner stops, should the test end early
+	t.Cleanup(func() { close(release) })
+	<-started
+	got = get()
+	if got.State != string(domain.JobRunning) || got.Progress != 40 || got.StartedAt == nil || got.FinishedAt != nil {
+		t.Errorf("running job = %+v, want running at 40%%", got)
+	}
+	// The result is saved only once the job succeeds
+	if got.Result != "" {
+		t.Errorf("running job result = %q, want none", got.Result)
+	}
+
+	release <- nil
+	got = f.waitForJob(t, job.ID, "u1")
+	if got.State != string(domain.JobSucceeded) || got.Progress != 100 || got.Result != "reports/"+job.ID ||
+		got.FinishedAt == nil || got.Error != "" {
+		t.Errorf("finished job = %+v, want succeeded with its result", got)
+	}
+	if _, err := time.Parse(time.RFC3339, *got.FinishedAt); err != nil {
+		t.Errorf("finished_at = %q: %v", *got.FinishedAt, err)
+	}
+}
+
+func TestJobHandlerRendersFailure(t *testing.T) {
+	f := newJobsFixture(t)
+	f.jobs.Handle("test.fail", func(ctx context.Context, run *usecase.JobRun) error {
+		return errors.New("upstream unavailable")
+	})
+	job, err := f.jobs.Enqueue(context.Background(), "test.fail", "", nil, usecase.WithJobOwner("u1"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	f.start(t)
+
+	// The fixture's runner gives up after the first attempt
+	got := f.waitForJob(t, job.ID, "u1")
+	if got.State != string(domain.JobFailed) || got.Error != "upstream unavailable" || got.Attempts != 1 ||
+		got.FinishedAt == nil || got.Result != "" {
+		t.Errorf("failed job = %+v, want failed after one attempt", got)
+	}
+}
+
+func TestJobHandlerVisibility(t *testing.T) {
+	f := newJobsFixture(t)
+	f.jobs.Handle("test.noop", func(context.Context, *usecase.JobRun) error { return nil })
+	job, err := f.jobs.Enqueue(context.Background(), "test.noop", "", nil, usecase.WithJobOwner("u1"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		user       string
+		scope      string
+		id         string
+		wantStatus int
+		wantCode   string
+	}{
+		{"owner", "u1", "", job.ID, http.StatusOK, ""},
+		{"admin", "admin-1", auth.ScopeAdmin, job.ID, http.StatusOK, ""},
+		// Other users can't tell the job from a missing one
+		{"another user", "u2", "", job.ID, http.StatusNotFound, "JOB_NOT_FOUND"},
+		{"another user with other scopes", "u2", "orders:read", job.ID, http.StatusNotFound, "JOB_NOT_FOUND"},
+		{"unknown job", "u1", "", "no-such-job", http.StatusNotFound, "JOB_NOT_FOUND"},
+		{"unknown job as admin", "admin-1", auth.ScopeAdmin, "no-such-job", http.StatusNotFound, "JOB_NOT_FOUND"},
+		{"blank ID", "u1", "", "%20", http.StatusBadRequest, "INVALID_INPUT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.do(http.MethodGet, "/api/jobs/"+tt.id, tt.user, tt.scope, "")
+			if rec.Code != tt.wantStatus || errorCode(t, rec) != tt.wantCode {
+				t.Fatalf("GET = %d %q, want %d %s", rec.Code, rec.Body, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusOK && decodeJob(t, rec).ID != job.ID {
+				t.Errorf("GET = %q, want the job", rec.Body)
+			}
+		})
+	}
+}
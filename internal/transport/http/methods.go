@@ -0,0 +1,114 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// OPTIONS and HEAD
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// The methods a path serves are read from the mux's route table, so they can
+// never drift from the registered routes:
+//
+//	OPTIONS /api/orders/{id}   204 No Content, Allow: GET, HEAD, PATCH, OPTIONS
+//	DELETE  /api/orders/{id}   405 METHOD_NOT_ALLOWED, with the same Allow
+//
+// HEAD runs the GET handler and sends its status and headers without the
+// body, with the Content-Length the body would have had. Streams have no
+// length to report and refuse HEAD. CORS preflights are answered before any
+// of this (see middleware.CORSPolicy).
+
+// routeMethods lists the methods routes are registered with, in Allow order
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// RouteMethods answers OPTIONS requests and requests with a method their path
+// has no route for from the routes registered on mux, and serves HEAD
+// requests with the GET route's headers
+func RouteMethods(mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, pattern := mux.Handler(r)
+			switch {
+			case pattern != "" && r.Method == http.MethodHead && !streamingRoutes[pattern]:
+				hw := &headWriter{ResponseWriter: w}
+				next.ServeHTTP(hw, r)
+				hw.finish()
+				return
+			case pattern != "" && r.Method != http.MethodHead:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed := allowedMethods(mux, r)
+			if len(allowed) == 0 {
+				next.ServeHTTP(w, r) // No route on this path: 404
+				return
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			respondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED",
+				r.Method+" is not allowed here; allowed: "+strings.Join(allowed, ", "))
+		})
+	}
+}
+
+// allowedMethods returns the methods routes on mux serve for r's path, with
+// OPTIONS, or nil when no route matches the path. HEAD comes with GET, but
+// for streams.
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		probe := r.WithContext(r.Context())
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern != "" && !(method == http.MethodHead && streamingRoutes[pattern]) {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return append(allowed, http.MethodOptions)
+}
+
+// headWriter discards the body of a response to a HEAD request, holding its
+// status back until the handler is done so Content-Length can report the
+// body's size
+type headWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *headWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.size += len(b)
+	return len(b), nil
+}
+
+// finish sends the held status and headers
+func (w *headWriter) finish() {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if w.size > 0 && w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
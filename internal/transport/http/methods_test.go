@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// routeMethodsFixture is RouteMethods in front of stand-in order routes, an
+// event stream and the real tus upload routes
+func routeMethodsFixture(t *testing.T) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	order := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		respondJSON(w, http.StatusOK, map[string]string{"id": r.PathValue("id")})
+	}
+	mux.HandleFunc("GET /api/orders/{id}", order)
+	mux.HandleFunc("PATCH /api/orders/{id}", order)
+	mux.HandleFunc("POST /api/orders", order)
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the event stream served a HEAD or OPTIONS request")
+	})
+
+	svc := usecase.NewAttachmentService(memory.NewAttachmentRepository(), newMultipartMemoryStore(), memory.NewSemaphoreStore(),
+		usecase.AttachmentPolicy{MaxSize: 1 << 20}, logger.New("error"))
+	registerUploadRoutes(mux, NewUploadHandler(svc, logger.New("error")))
+	return RouteMethods(mux)(mux)
+}
+
+func TestRouteMethodsAllow(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{"options on an item", http.MethodOptions, "/api/orders/o1", http.StatusNoContent, "GET, HEAD, PATCH, OPTIONS"},
+		{"options on a collection", http.MethodOptions, "/api/orders", http.StatusNoContent, "POST, OPTIONS"},
+		{"options on a stream leaves out HEAD", http.MethodOptions, "/api/events", http.StatusNoContent, "GET, OPTIONS"},
+		{"method without a route", http.MethodDelete, "/api/orders/o1", http.StatusMethodNotAllowed, "GET, HEAD, PATCH, OPTIONS"},
+		{"put on a collection", http.MethodPut, "/api/orders", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{"unknown path", http.MethodOptions, "/api/unknown", http.StatusNotFound, ""},
+		{"unknown path and method", http.MethodDelete, "/api/unknown", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(routeMethodsFixture(t), httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if tt.wantStatus == http.StatusMethodNotAllowed && !strings.Contains(rec.Body.String(), "METHOD_NOT_ALLOWED") {
+				t.Errorf("body = %s, want METHOD_NOT_ALLOWED", rec.Body)
+			}
+		})
+	}
+}
+
+func TestRouteMethodsHead(t *testing.T) {
+	h := routeMethodsFixture(t)
+	get := serve(h, httptest.NewRequest(http.MethodGet, "/api/orders/o1", nil))
+
+	rec := serve(h, httptest.NewRequest(http.MethodHead, "/api/orders/o1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("HEAD status = %d, want 200", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("HEAD sent a %d byte body", rec.Body.Len())
+	}
+	if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(get.Body.Len()); got != want {
+		t.Errorf("HEAD Content-Length = %q, want the GET body's %s", got, want)
+	}
+	if rec.Header().Get("ETag") != `"1"` || rec.Header().Get("Content-Type") != get.Header().Get("Content-Type") {
+		t.Errorf("HEAD headers = %v, want the GET route's", rec.Header())
+	}
+}
+
+func TestRouteMethodsHeadRefusedOnStreams(t *testing.T) {
+	rec := serve(routeMethodsFixture(t), httptest.NewRequest(http.MethodHead, "/api/events", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("HEAD on a stream = %d, want 405", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, OPTIONS" {
+		t.Errorf("Allow = %q, want GET, OPTIONS", got)
+	}
+}
+
+func TestRouteMethodsLeavesTusRoutesAlone(t *testing.T) {
+	h := routeMethodsFixture(t)
+
+	rec := serve(h, tusRequest(http.MethodOptions, "/api/uploads", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS /api/uploads = %d, want 204", rec.Code)
+	}
+	if rec.Header().Get("Tus-Version") == "" || rec.Header().Get("Tus-Max-Size") != "1048576" {
+		t.Errorf("OPTIONS /api/uploads headers = %v, want the tus handler's", rec.Header())
+	}
+	if rec.Header().Get("Allow") != "" {
+		t.Errorf("Allow = %q, want the tus handler's answer untouched", rec.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,227 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// flushRecorder is a response recorder noting how many lines had been
+// written at each flush, and accepting write deadlines as a connection does
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+
+	flushedLines []int
+}
+
+func newFlushRecorder() *flushRecorder {
+	return &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+}
+
+func (rec *flushRecorder) Flush() {
+	rec.flushedLines = append(rec.flushedLines, bytes.Count(rec.Body.Bytes(), []byte("\n")))
+	rec.ResponseRecorder.Flush()
+}
+
+func (rec *flushRecorder) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// ndjsonLines decodes every line of an NDJSON body
+func ndjsonLines(t *testing.T, body string) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for line := range strings.Lines(body) {
+		var v map[string]any
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("decoding line %q: %v", line, err)
+		}
+		lines = append(lines, v)
+	}
+	return lines
+}
+
+func TestAcceptsNDJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"*/*", false},
+		{NDJSONContentType, true},
+		{"application/ndjson", true},
+		{"application/jsonl", true},
+		{"application/jsonlines", true},
+		{"application/x-ndjson, application/json;q=0.9", true},
+		{"application/json, application/x-ndjson;q=0.5", false},
+		{"application/json;q=0.5, application/x-ndjson;q=0.5", true}, // Ties go to the stream
+		{"application/x-ndjson;q=0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := acceptsNDJSON(r); got != tt.want {
+				t.Errorf("acceptsNDJSON(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderExportNegotiation(t *testing.T) {
+	handler, _, _ := orderHandler(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+	r.Header.Set("Accept", "application/x-ndjson")
+	rec := serve(handler, r)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != NDJSONContentType {
+		t.Fatalf("export = %d %q, want 200 %s", rec.Code, rec.Header().Get("Content-Type"), NDJSONContentType)
+	}
+	var ids []string
+	for _, line := range ndjsonLines(t, rec.Body.String()) {
+		id, _ := line["id"].(string)
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	if want := []string{"o1", "o2", "o3", "o4"}; !slices.Equal(ids, want) {
+		t.Errorf("exported orders = %v, want %v, one per line", ids, want)
+	}
+
+	// Preferring JSON gets the page
+	r = httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+	r.Header.Set("Accept", "application/json, application/x-ndjson;q=0.5")
+	rec = serve(handler, r)
+	if ct := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("page = %d %q, want 200 JSON", rec.Code, ct)
+	}
+
+	// The export's parameters are validated before the stream starts
+	r = httptest.NewRequest(http.MethodGet, "/api/orders?limit=-1", nil)
+	r.Header.Set("Accept", "application/x-ndjson")
+	rec = serve(handler, r)
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") == NDJSONContentType {
+		t.Errorf("export with a negative limit = %d %q, want an ordinary 400", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestNDJSONWriterFlushes(t *testing.T) {
+	rec := newFlushRecorder()
+	stream := newNDJSONWriter(rec)
+	rows := 2*ndjsonFlushRows + ndjsonFlushRows/2
+	for i := range rows {
+		if err := stream.Write(map[string]int{"n": i}); err != nil {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+	stream.Close(nil)
+
+	if want := []int{ndjsonFlushRows, 2 * ndjsonFlushRows, rows}; !slices.Equal(rec.flushedLines, want) {
+		t.Errorf("lines written at each flush = %v, want %v", rec.flushedLines, want)
+	}
+	if got := rec.Header().Get("X-Accel-Buffering"); got != "no" {
+		t.Errorf("X-Accel-Buffering = %q, want no", got)
+	}
+	if lines := ndjsonLines(t, rec.Body.String()); len(lines) != rows || lines[rows-1]["n"] != float64(rows-1) {
+		t.Errorf("lines = %d, want %d ending with the last row", len(lines), rows)
+	}
+}
+
+func TestNDJSONWriterErrors(t *testing.T) {
+	t.Run("after the first row", func(t *testing.T) {
+		rec := newFlushRecorder()
+		stream := newNDJSONWriter(rec)
+		stream.Write(map[string]string{"id": "o1"})
+		stream.Close(errors.New("connection reset"))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want the 200 already sent", rec.Code)
+		}
+		lines := ndjsonLines(t, rec.Body.String())
+		if len(lines) != 2 {
+			t.Fatalf("lines = %v, want the row and an error", lines)
+		}
+		apiErr, _ := lines[1]["error"].(map[string]any)
+		if apiErr["code"] != "INTERNAL_ERROR" || lines[1]["success"] != false {
+			t.Errorf("last line = %v, want an INTERNAL_ERROR error", lines[1])
+		}
+		if len(rec.flushedLines) == 0 || rec.flushedLines[len(rec.flushedLines)-1] != 2 {
+			t.Errorf("lines written at each flush = %v, want the error line flushed", rec.flushedLines)
+		}
+	})
+
+	t.Run("before the first row", func(t *testing.T) {
+		rec := newFlushRecorder()
+		stream := newNDJSONWriter(rec)
+		stream.Close(domain.ErrUserNotFound)
+
+		if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") == NDJSONContentType {
+			t.Errorf("response = %d %q, want an ordinary 404", rec.Code, rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		rec := newFlushRecorder()
+		newNDJSONWriter(rec).Close(nil)
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != NDJSONContentType || rec.Body.Len() != 0 {
+			t.Errorf("response = %d %q %q, want an empty stream", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+	})
+}
+
+func TestRequestTimeoutSkipsExports(t *testing.T) {
+	// A list whose rows take longer to come than the request timeout
+	slowList := func(w http.ResponseWriter, r *http.Request) {
+		stream := newNDJSONWriter(w)
+		for i := range 3 {
+			select {
+			case <-r.Context().Done():
+				stream.Close(r.Context().Err())
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			stream.Write(map[string]int{"n": i})
+		}
+		stream.Close(nil)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/orders", slowList)
+	mux.HandleFunc("GET /api/orders/{id}", slowList)
+	h := RequestTimeout(RequestTimeoutConfig{Default: 10 * time.Millisecond}, mux)(mux)
+
+	get := func(target, accept string) *flushRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("Accept", accept)
+		rec := newFlushRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := get("/api/orders", NDJSONContentType)
+	if lines := ndjsonLines(t, rec.Body.String()); rec.Code != http.StatusOK || len(lines) != 3 || lines[2]["n"] != float64(2) {
+		t.Errorf("export = %d %q, want every row past the timeout", rec.Code, rec.Body)
+	}
+
+	// A page of the same list is still timed out, and so is a route without
+	// exports, whatever it accepts
+	for _, tt := range []struct{ target, accept string }{
+		{"/api/orders", "application/json"},
+		{"/api/orders/o1", NDJSONContentType},
+	} {
+		rec := get(tt.target, tt.accept)
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "REQUEST_TIMEOUT") {
+			t.Errorf("GET %s accepting %s = %d %q, want 503 REQUEST_TIMEOUT", tt.target, tt.accept, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// NotificationHandler handles HTTP requests for notification preferences
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type NotificationHandler struct {
+	notifications *usecase.NotificationService
+	logg          *logger.Logger
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notifications *usecase.NotificationService, logg *logger.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		notifications: notifications,
+		logg:          logg,
+	}
+}
+
+// NotificationPreferencesRequest represents the request body replacing a
+// user's notification preferences
+type NotificationPreferencesRequest struct {
+	// Addresses by channel: "sms" (E.164), "push" (device token), "slack"
+	// (member ID), and "email" to use another address than the account's
+	Addresses map[domain.NotificationChannel]string `json:"addresses"`
+	// Events lists the channels of each event type; types left out use
+	// the defaults, and an empty list mutes the type
+	Events map[string][]domain.NotificationChannel `json:"events"`
+}
+
+// NotificationPreferencesResponse represents a user's notification preferences
+type NotificationPreferencesResponse struct {
+	UserID            string                                  `json:"user_id"`
+	Addresses         map[domain.NotificationChannel]string   `json:"addresses"`
+	Events            map[string][]domain.NotificationChannel `json:"events"`
+	DefaultChannels   []domain.NotificationChannel            `json:"default_channels"`   // Of the event types left out
+	AvailableChannels []domain.NotificationChannel            `json:"available_channels"` // Those configured on this server
+	UpdatedAt         *string                                 `json:"updated_at"`         // Null until first saved
+}
+
+func (h *NotificationHandler) toResponse(p *domain.NotificationPreferences) *NotificationPreferencesResponse {
+	resp := &NotificationPreferencesResponse{
+		UserID:            p.UserID,
+		Addresses:         p.Addresses,
+		Events:            p.Events,
+		DefaultChannels:   h.notifications.DefaultChannels(),
+		AvailableChannels: h.notifications.Channels(),
+	}
+	if !p.UpdatedAt.IsZero() {
+		updated := p.UpdatedAt.UTC().Format(time.RFC3339)
+		resp.UpdatedAt = &updated
+	}
+	return resp
+}
+
+// authorizeUser resolves the {id} path user and checks the caller may manage
+// their preferences: the user themselves or an admin
+func (h *NotificationHandler) authorizeUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := strings.TrimSpace(r.PathValue("id"))
+	if userID == "" {
+		handleError(w, domain.ErrInvalidUserID)
+		return "", false
+	}
+	if claims := GetClaims(r.Context()); claims != nil &&
+		claims.Subject != userID && !claims.HasScope(auth.ScopeAdmin) {
+		emitPermissionDenied(r, "notification_preferences_not_allowed", nil)
+		handleError(w, domain.ErrForbidden)
+		return "", false
+	}
+	return userID, true
+}
+
+// GetPreferences handles GET /api/users/{id}/notification-preferences
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := h.notifications.GetPreferences(r.Context(), userID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, h.toResponse(p))
+}
+
+// UpdatePreferences handles PUT /api/users/{id}/notification-preferences
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req NotificationPreferencesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	p, err := h.notifications.UpdatePreferences(r.Context(), userID, req.Addresses, req.Events)
+	if err != nil {
+		// Validation errors name the address, event type or channel at fault
+		if errors.Is(err, domain.ErrInvalidNotificationPreferences) || errors.Is(err, domain.ErrNotificationChannelUnavailable) {
+			status, code, _ := mapDomainErrorToHTTP(err)
+			respondError(w, status, code, err.Error())
+			return
+		}
+		h.logg.Error("failed to update notification preferences", "error", err, "user_id", userID)
+		handleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, h.toResponse(p))
+}
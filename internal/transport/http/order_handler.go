@@ -1,26 +1,81 @@
 package http
 
 import (
+	"bytes"
 	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/internal/i18n"
+	"github.com/TopThisHat/stdlib-golang-api/internal/templates"
 	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
 )
 
 // OrderHandler handles HTTP requests for order operations
 // Transport layer - handles HTTP concerns only, delegates business logic to service
 type OrderHandler struct {
-	orderService *usecase.OrderService
-	logg         *logger.Logger
+	orderService    *usecase.OrderService
+	logg            *logger.Logger
+	displayCurrency string            // ISO 4217 code used for amount_display fields
+	templates       *templates.Engine // Renders invoices (nil disables them)
+}
+
+// OrderHandlerOption configures an OrderHandler
+type OrderHandlerOption func(*OrderHandler)
+
+// WithDisplayCurrency sets the currency used to render amount_display fields
+func WithDisplayCurrency(code string) OrderHandlerOption {
+	return func(h *OrderHandler) {
+		if code != "" {
+			h.displayCurrency = code
+		}
+	}
+}
+
+// WithInvoiceTemplates enables GET /api/orders/{id}/invoice, rendered from templates.InvoiceOrder
+func WithInvoiceTemplates(engine *templates.Engine) OrderHandlerOption {
+	return func(h *OrderHandler) {
+		h.templates = engine
+	}
 }
 
 // NewOrderHandler creates a new order handler
-func NewOrderHandler(orderService *usecase.OrderService, logg *logger.Logger) *OrderHandler {
-	return &OrderHandler{
-		orderService: orderService,
-		logg:         logg,
+func NewOrderHandler(orderService *usecase.OrderService, logg *logger.Logger, opts ...OrderHandlerOption) *OrderHandler {
+	h := &OrderHandler{
+		orderService:    orderService,
+		logg:            logg,
+		displayCurrency: "USD",
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// amountFormatter renders amounts for display in the request's locale
+type amountFormatter struct {
+	locale   i18n.Locale
+	currency string
+}
+
+func (f amountFormatter) format(amount float64) string {
+	return i18n.FormatCurrency(amount, f.currency, f.locale)
+}
+
+// formatter negotiates the response locale and announces it via Content-Language.
+// Caches must key the response on Accept-Language too; Vary names it once
+// however many times a handler calls this.
+func (h *OrderHandler) formatter(w http.ResponseWriter, r *http.Request) amountFormatter {
+	locale := requestLocale(r)
+	w.Header().Set("Content-Language", locale.Tag)
+	if !slices.Contains(w.Header().Values("Vary"), "Accept-Language") {
+		w.Header().Add("Vary", "Accept-Language")
 	}
+	return amountFormatter{locale: locale, currency: h.displayCurrency}
 }
 
 // CreateOrderRequest represents the request body for creating an order
@@ -38,42 +93,51 @@ type OrderItemRequest struct {
 
 // OrderResponse represents the response body for order operations
 type OrderResponse struct {
-	ID          string              `json:"id"`
-	UserID      string              `json:"user_id"`
-	Amount      float64             `json:"amount"`
-	Status      string              `json:"status"`
-	Items       []OrderItemResponse `json:"items"`
-	CreatedAt   string              `json:"created_at"`
-	UpdatedAt   string              `json:"updated_at"`
-	CancelledAt *string             `json:"cancelled_at,omitempty"`
+	ID            string              `json:"id"`
+	UserID        string              `json:"user_id"`
+	Amount        float64             `json:"amount"`
+	AmountDisplay string              `json:"amount_display"` // Locale-formatted amount, e.g. "1.234,50 €"
+	Status        string              `json:"status"`
+	Items         []OrderItemResponse `json:"items"`
+	CreatedAt     string              `json:"created_at"`
+	UpdatedAt     string              `json:"updated_at"`
+	CancelledAt   *string             `json:"cancelled_at,omitempty"`
+	User          *UserResponse       `json:"user,omitempty"` // The owner, with ?expand=user
 }
 
+// orderFieldSources names the order fields the OrderResponse fields derived
+// from another one are built from, for ?fields=
+var orderFieldSources = map[string]string{"amount_display": "amount", "user": "user_id"}
+
 // OrderItemResponse represents an order item in the response
 type OrderItemResponse struct {
-	ProductID string  `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID    string  `json:"product_id"`
+	Quantity     int     `json:"quantity"`
+	Price        float64 `json:"price"`
+	PriceDisplay string  `json:"price_display"`
 }
 
 // toOrderResponse converts a domain order to a response DTO
-func toOrderResponse(o *domain.Order) *OrderResponse {
+func toOrderResponse(o *domain.Order, f amountFormatter) *OrderResponse {
 	items := make([]OrderItemResponse, len(o.Items))
 	for i, item := range o.Items {
 		items[i] = OrderItemResponse{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
+			ProductID:    item.ProductID,
+			Quantity:     item.Quantity,
+			Price:        item.Price,
+			PriceDisplay: f.format(item.Price),
 		}
 	}
 
 	resp := &OrderResponse{
-		ID:        o.ID,
-		UserID:    o.UserID,
-		Amount:    o.Amount,
-		Status:    string(o.Status),
-		Items:     items,
-		CreatedAt: o.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: o.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:            o.ID,
+		UserID:        o.UserID,
+		Amount:        o.Amount,
+		AmountDisplay: f.format(o.Amount),
+		Status:        string(o.Status),
+		Items:         items,
+		CreatedAt:     o.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:     o.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 
 	if o.CancelledAt != nil {
@@ -85,14 +149,98 @@ func toOrderResponse(o *domain.Order) *OrderResponse {
 }
 
 // toOrderListResponse converts a slice of domain orders to response DTOs
-func toOrderListResponse(orders []*domain.Order) []*OrderResponse {
+func toOrderListResponse(orders []*domain.Order, f amountFormatter) []*OrderResponse {
 	result := make([]*OrderResponse, len(orders))
 	for i, o := range orders {
-		result[i] = toOrderResponse(o)
+		result[i] = toOrderResponse(o, f)
 	}
 	return result
 }
 
+// bindOrderShapeOrRespond reads the ?fields= and ?expand= of an order
+// response. Writes a 400 response and returns false if either is invalid.
+func bindOrderShapeOrRespond(w http.ResponseWriter, r *http.Request) (fieldset, expansions, bool) {
+	fields, ok := bindFieldsOrRespond(w, r, reflect.TypeFor[OrderResponse]())
+	if !ok {
+		return nil, nil, false
+	}
+	expand, ok := bindExpandOrRespond(w, r, "user")
+	if !ok {
+		return nil, nil, false
+	}
+	expand.keep(fields)
+	return fields, expand, true
+}
+
+// orderOwners fetches the owners of all orders in one batch when ?expand=user
+// asks to embed them; nil otherwise
+func (h *OrderHandler) orderOwners(r *http.Request, orders []*domain.Order, expand expansions) (map[string]*domain.User, error) {
+	if !expand["user"] {
+		return nil, nil
+	}
+	return h.orderService.GetOrderOwners(r.Context(), orders)
+}
+
+// orderResponses converts orders to response DTOs, embedding their owners
+// when given
+func (h *OrderHandler) orderResponses(w http.ResponseWriter, r *http.Request, orders []*domain.Order, owners map[string]*domain.User) []*OrderResponse {
+	responses := toOrderListResponse(orders, h.formatter(w, r))
+	for i, o := range orders {
+		if owner, ok := owners[o.UserID]; ok {
+			responses[i].User = toUserResponse(owner)
+		}
+	}
+	return responses
+}
+
+// OrderResponseV2 is the order from API version 2 on: amounts and prices are
+// decimal strings, so clients need not parse money as binary floats
+type OrderResponseV2 struct {
+	ID            string                `json:"id"`
+	UserID        string                `json:"user_id"`
+	Amount        string                `json:"amount"`
+	AmountDisplay string                `json:"amount_display"`
+	Status        string                `json:"status"`
+	Items         []OrderItemResponseV2 `json:"items"`
+	CreatedAt     string                `json:"created_at"`
+	UpdatedAt     string                `json:"updated_at"`
+	CancelledAt   *string               `json:"cancelled_at,omitempty"`
+	User          *UserResponse         `json:"user,omitempty"`
+}
+
+// OrderItemResponseV2 is an order item from API version 2 on
+type OrderItemResponseV2 struct {
+	ProductID    string `json:"product_id"`
+	Quantity     int    `json:"quantity"`
+	Price        string `json:"price"`
+	PriceDisplay string `json:"price_display"`
+}
+
+// toOrderResponseV2 adapts a version 1 order response (see responseAdapters)
+func toOrderResponseV2(o *OrderResponse) *OrderResponseV2 {
+	items := make([]OrderItemResponseV2, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = OrderItemResponseV2{
+			ProductID:    item.ProductID,
+			Quantity:     item.Quantity,
+			Price:        strconv.FormatFloat(item.Price, 'f', -1, 64),
+			PriceDisplay: item.PriceDisplay,
+		}
+	}
+	return &OrderResponseV2{
+		ID:            o.ID,
+		UserID:        o.UserID,
+		Amount:        strconv.FormatFloat(o.Amount, 'f', -1, 64),
+		AmountDisplay: o.AmountDisplay,
+		Status:        o.Status,
+		Items:         items,
+		CreatedAt:     o.CreatedAt,
+		UpdatedAt:     o.UpdatedAt,
+		CancelledAt:   o.CancelledAt,
+		User:          o.User,
+	}
+}
+
 // toDomainOrderItems converts request items to domain order items
 func toDomainOrderItems(items []OrderItemRequest) []domain.OrderItem {
 	result := make([]domain.OrderItem, len(items))
@@ -110,7 +258,7 @@ func toDomainOrderItems(items []OrderItemRequest) []domain.OrderItem {
 func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateOrderRequest
 	if err := decodeJSON(r, &req); err != nil {
-		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		respondDecodeError(w, err)
 		return
 	}
 
@@ -149,10 +297,11 @@ func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, toOrderResponse(order))
+	respondJSON(w, http.StatusCreated, toOrderResponse(order, h.formatter(w, r)))
 }
 
-// GetByID handles GET /api/orders/{id}
+// GetByID handles GET /api/orders/{id}, trimmed to ?fields= and with the
+// owner embedded by ?expand=user when given
 func (h *OrderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -160,13 +309,88 @@ func (h *OrderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	fields, expand, ok := bindOrderShapeOrRespond(w, r)
+	if !ok {
+		return
+	}
+
 	order, err := h.orderService.GetOrderByID(r.Context(), id)
 	if err != nil {
 		handleError(w, err)
 		return
 	}
+	orders := []*domain.Order{order}
+	owners, err := h.orderOwners(r, orders, expand)
+	if err != nil {
+		h.logg.Error("failed to expand order", "error", err, "order_id", id)
+		handleError(w, err)
+		return
+	}
 
-	respondJSON(w, http.StatusOK, toOrderResponse(order))
+	// Built first: formatting sets the Content-Language the ETag depends on
+	response := h.orderResponses(w, r, orders, owners)[0]
+
+	// An embedded owner changes the representation without changing the order
+	tag := etag(w, r, order.Version)
+	if owner, ok := owners[order.UserID]; ok {
+		tag = weakETag(w, r, order.Version, owner.Version)
+	}
+	if notModified(w, r, tag) {
+		return
+	}
+	respondFields(w, http.StatusOK, response, fields)
+}
+
+// Invoice handles GET /api/orders/{id}/invoice, rendering the order as an
+// HTML invoice in the request's locale
+func (h *OrderHandler) Invoice(w http.ResponseWriter, r *http.Request) {
+	if h.templates == nil {
+		respondError(w, http.StatusNotFound, "NOT_FOUND", "Invoices are not enabled")
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Order ID is required")
+		return
+	}
+
+	order, err := h.orderService.GetOrderByID(r.Context(), id)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	f := h.formatter(w, r)
+	number := strings.ToUpper(strings.ReplaceAll(order.ID, "-", ""))
+	lines := make([]templates.InvoiceLine, len(order.Items))
+	for i, item := range order.Items {
+		lines[i] = templates.InvoiceLine{
+			Product:   item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: f.format(item.Price),
+			Amount:    f.format(item.Price * float64(item.Quantity)),
+		}
+	}
+	invoice := templates.Invoice{
+		Locale:     f.locale.Tag,
+		Number:     "INV-" + number[:min(8, len(number))],
+		IssuedAt:   order.CreatedAt.Format("2006-01-02"),
+		Status:     string(order.Status),
+		CustomerID: order.UserID,
+		Lines:      lines,
+		Total:      f.format(order.Amount),
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates.Render(&buf, templates.InvoiceOrder, invoice); err != nil {
+		h.logg.Error("failed to render invoice", "error", err, "order_id", order.ID)
+		respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to render invoice")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
 }
 
 // GetByUserID handles GET /api/users/{user_id}/orders
@@ -177,40 +401,191 @@ func (h *OrderHandler) GetByUserID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	limit := parseIntQueryParam(r, "limit", 20)
-	offset := parseIntQueryParam(r, "offset", 0)
+	var params PaginationParams
+	if !bindQueryOrRespond(w, r, &params) {
+		return
+	}
+	fields, expand, ok := bindOrderShapeOrRespond(w, r)
+	if !ok {
+		return
+	}
 
-	orders, err := h.orderService.GetOrdersByUserID(r.Context(), userID, limit, offset)
+	orders, err := h.orderService.GetOrdersByUserID(r.Context(), userID, params.Limit, params.Offset)
 	if err != nil {
 		h.logg.Error("failed to get orders by user", "error", err, "user_id", userID)
 		handleError(w, err)
 		return
 	}
+	owners, err := h.orderOwners(r, orders, expand)
+	if err != nil {
+		h.logg.Error("failed to expand orders", "error", err, "user_id", userID)
+		handleError(w, err)
+		return
+	}
+	responses := h.orderResponses(w, r, orders, owners)
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"orders": toOrderListResponse(orders),
-		"limit":  limit,
-		"offset": offset,
-	})
+	respondFields(w, http.StatusOK, map[string]interface{}{
+		"orders": responses,
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	}, fields)
 }
 
-// List handles GET /api/orders
+// List handles GET /api/orders?limit=&offset= or ?limit=&cursor= (keyset
+// pagination from the next_cursor or prev_cursor of an earlier page), with
+// optional filters, sort (see parseListQuery), fields and expand; pages carry
+// the total and Link headers. Streams every order as NDJSON instead when the
+// request accepts application/x-ndjson.
 func (h *OrderHandler) List(w http.ResponseWriter, r *http.Request) {
-	limit := parseIntQueryParam(r, "limit", 20)
-	offset := parseIntQueryParam(r, "offset", 0)
+	if acceptsNDJSON(r) {
+		h.export(w, r)
+		return
+	}
 
-	orders, err := h.orderService.ListOrders(r.Context(), limit, offset)
+	req, ok := bindListOrRespond(w, r, domain.OrderQueryFields)
+	if !ok {
+		return
+	}
+	fields, expand, ok := bindOrderShapeOrRespond(w, r)
+	if !ok {
+		return
+	}
+	req.Query.Fields = fields.sources(orderFieldSources)
+	req.Page.Fields = req.Query.Fields
+
+	total := -1
+	if req.Total {
+		n, err := h.orderService.CountOrders(r.Context(), req.Query.Filters)
+		if err != nil {
+			h.logg.Error("failed to count orders", "error", err)
+			handleError(w, err)
+			return
+		}
+		total = n
+	}
+
+	if req.Keyset {
+		result, err := h.orderService.ListOrdersPage(r.Context(), req.Page)
+		if err != nil {
+			h.logg.Error("failed to list orders", "error", err)
+			handleError(w, err)
+			return
+		}
+		owners, err := h.orderOwners(r, result.Items, expand)
+		if err != nil {
+			h.logg.Error("failed to expand orders", "error", err)
+			handleError(w, err)
+			return
+		}
+		responses := h.orderResponses(w, r, result.Items, owners)
+		req.respond(w, r, "orders", responses, listPage{
+			Rows:  len(result.Items),
+			Total: total,
+			Next:  encodeCursor(cursorAfter, result.Next),
+			Prev:  encodeCursor(cursorBefore, result.Prev),
+		}, fields)
+		return
+	}
+
+	orders, err := h.orderService.SearchOrders(r.Context(), req.Query, req.Limit, req.Offset)
 	if err != nil {
 		h.logg.Error("failed to list orders", "error", err)
 		handleError(w, err)
 		return
 	}
+	owners, err := h.orderOwners(r, orders, expand)
+	if err != nil {
+		h.logg.Error("failed to expand orders", "error", err)
+		handleError(w, err)
+		return
+	}
+	responses := h.orderResponses(w, r, orders, owners)
+
+	req.respond(w, r, "orders", responses, listPage{
+		Rows:  len(orders),
+		Total: total,
+		Last: func() domain.Cursor {
+			last := orders[len(orders)-1]
+			return domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		},
+	}, fields)
+}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"orders": toOrderListResponse(orders),
-		"limit":  limit,
-		"offset": offset,
+// export streams orders as NDJSON (Accept: application/x-ndjson), one
+// OrderResponse per line; limit and offset are optional and uncapped
+func (h *OrderHandler) export(w http.ResponseWriter, r *http.Request) {
+	var params StreamParams
+	if !bindQueryOrRespond(w, r, &params) {
+		return
+	}
+
+	f := h.formatter(w, r)
+	stream := newNDJSONWriter(w)
+	err := h.orderService.StreamOrders(r.Context(), params.Limit, params.Offset, func(o *domain.Order) error {
+		return stream.Write(toOrderResponse(o, f))
 	})
+	if err != nil && r.Context().Err() == nil {
+		h.logg.Error("failed to export orders", "error", err)
+	}
+	stream.Close(err)
+}
+
+// PatchOrderRequest is the merge patch body for partially updating an order
+type PatchOrderRequest struct {
+	Status PatchField[string]             `json:"status"`
+	Items  PatchField[[]OrderItemRequest] `json:"items"`
+}
+
+// Patch handles PATCH /api/orders/{id} with a JSON merge patch. A status
+// moves the order through the same transitions as the confirm, ship, deliver
+// and cancel routes; items replace every item of a pending order.
+func (h *OrderHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Order ID is required")
+		return
+	}
+
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var req PatchOrderRequest
+	if !decodeMergePatch(w, r, &req) {
+		return
+	}
+	errs := patchErrors{}
+	errs.required("status", req.Status.Null)
+	errs.required("items", req.Items.Null)
+	if status := optional(req.Status); status != nil {
+		if msg := checkFieldValue(domain.OrderQueryFields["status"], *status); msg != "" {
+			errs["status"] = msg
+		}
+	}
+	if !errs.respond(w) {
+		return
+	}
+
+	var patch domain.OrderPatch
+	if status := optional(req.Status); status != nil {
+		s := domain.OrderStatus(*status)
+		patch.Status = &s
+	}
+	if items := optional(req.Items); items != nil {
+		patch.Items = toDomainOrderItems(*items)
+	}
+
+	order, err := h.orderService.PatchOrder(r.Context(), id, patch)
+	if err != nil {
+		h.logg.Error("failed to patch order", "error", err, "order_id", id)
+		handleError(w, err)
+		return
+	}
+
+	response := toOrderResponse(order, h.formatter(w, r))
+	setETag(w, r, order.Version)
+	respondJSON(w, http.StatusOK, response)
 }
 
 // Confirm handles POST /api/orders/{id}/confirm
@@ -221,6 +596,11 @@ func (h *OrderHandler) Confirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	order, err := h.orderService.ConfirmOrder(r.Context(), id)
 	if err != nil {
 		h.logg.Error("failed to confirm order", "error", err, "order_id", id)
@@ -228,7 +608,9 @@ func (h *OrderHandler) Confirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, toOrderResponse(order))
+	response := toOrderResponse(order, h.formatter(w, r))
+	setETag(w, r, order.Version)
+	respondJSON(w, http.StatusOK, response)
 }
 
 // Ship handles POST /api/orders/{id}/ship
@@ -239,6 +621,11 @@ func (h *OrderHandler) Ship(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	order, err := h.orderService.ShipOrder(r.Context(), id)
 	if err != nil {
 		h.logg.Error("failed to ship order", "error", err, "order_id", id)
@@ -246,7 +633,9 @@ func (h *OrderHandler) Ship(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, toOrderResponse(order))
+	response := toOrderResponse(order, h.formatter(w, r))
+	setETag(w, r, order.Version)
+	respondJSON(w, http.StatusOK, response)
 }
 
 // Deliver handles POST /api/orders/{id}/deliver
@@ -257,6 +646,11 @@ func (h *OrderHandler) Deliver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	order, err := h.orderService.DeliverOrder(r.Context(), id)
 	if err != nil {
 		h.logg.Error("failed to deliver order", "error", err, "order_id", id)
@@ -264,7 +658,9 @@ func (h *OrderHandler) Deliver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, toOrderResponse(order))
+	response := toOrderResponse(order, h.formatter(w, r))
+	setETag(w, r, order.Version)
+	respondJSON(w, http.StatusOK, response)
 }
 
 // Cancel handles POST /api/orders/{id}/cancel
@@ -275,6 +671,11 @@ func (h *OrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	order, err := h.orderService.CancelOrder(r.Context(), id)
 	if err != nil {
 		h.logg.Error("failed to cancel order", "error", err, "order_id", id)
@@ -282,5 +683,7 @@ func (h *OrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, toOrderResponse(order))
+	response := toOrderResponse(order, h.formatter(w, r))
+	setETag(w, r, order.Version)
+	respondJSON(w, http.StatusOK, response)
 }
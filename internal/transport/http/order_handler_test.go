@@ -0,0 +1,223 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestFormatterVariesOnAcceptLanguage(t *testing.T) {
+	h := &OrderHandler{displayCurrency: "EUR"}
+	r := httptest.NewRequest(http.MethodGet, "/api/orders/o1", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	rec := httptest.NewRecorder()
+	rec.Header().Add("Vary", "Accept")
+
+	// Calling it again, as a handler formatting several things would, adds nothing
+	h.formatter(rec, r)
+	f := h.formatter(rec, r)
+
+	vary := rec.Header().Values("Vary")
+	if !slices.Equal(vary, []string{"Accept", "Accept-Language"}) {
+		t.Errorf("Vary = %q, want Accept and Accept-Language once each", vary)
+	}
+	if got := rec.Header().Get("Content-Language"); got != f.locale.Tag {
+		t.Errorf("Content-Language = %q, want %q", got, f.locale.Tag)
+	}
+}
+
+// countingUsers is a user repository counting the lookups made through it
+type countingUsers struct {
+	domain.UserRepository
+
+	getByID  int
+	getByIDs int
+}
+
+func (r *countingUsers) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	r.getByID++
+	return r.UserRepository.GetByID(ctx, id)
+}
+
+func (r *countingUsers) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	r.getByIDs++
+	return r.UserRepository.GetByIDs(ctx, ids)
+}
+
+// recordingOrders is an order repository recording the fields list reads ask for
+type recordingOrders struct {
+	domain.OrderRepository
+
+	fields [][]string
+}
+
+func (r *recordingOrders) Search(ctx context.Context, q domain.ListQuery, limit, offset int) ([]*domain.Order, error) {
+	r.fields = append(r.fields, q.Fields)
+	return r.OrderRepository.Search(ctx, q, limit, offset)
+}
+
+func (r *recordingOrders) ListKeyset(ctx context.Context, page domain.KeysetPage) ([]*domain.Order, error) {
+	r.fields = append(r.fields, page.Fields)
+	return r.OrderRepository.ListKeyset(ctx, page)
+}
+
+// orderHandler serves the order reads over repositories holding users u1
+// and u2 and their orders o1 to o3, and o4 of a user that no longer exists
+func orderHandler(t *testing.T) (http.Handler, *countingUsers, *recordingOrders) {
+	t.Helper()
+	ctx := context.Background()
+	logg := logger.New("error")
+	users := &countingUsers{UserRepository: memory.NewUserRepository()}
+	for _, id := range []string{"u1", "u2"} {
+		user, err := domain.NewUser(id, "User "+id, id+"@example.com")
+		if err != nil {
+			t.Fatalf("NewUser: %v", err)
+		}
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+	}
+	orders := &recordingOrders{OrderRepository: memory.NewOrderRepository()}
+	for _, o := range []struct{ id, userID string }{{"o1", "u1"}, {"o2", "u2"}, {"o3", "u1"}, {"o4", "deleted"}} {
+		order, err := domain.NewOrder(o.id, o.userID, []domain.OrderItem{{ProductID: "p1", Quantity: 2, Price: 5}})
+		if err != nil {
+			t.Fatalf("NewOrder: %v", err)
+		}
+		if err := orders.Create(ctx, order); err != nil {
+			t.Fatalf("Create order: %v", err)
+		}
+	}
+
+	h := NewOrderHandler(usecase.NewOrderService(orders, users, memory.NewOrderCache(), logg), logg)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/orders", h.List)
+	mux.HandleFunc("GET /api/orders/{id}", h.GetByID)
+	mux.HandleFunc("GET /api/users/{user_id}/orders", h.GetByUserID)
+	return mux, users, orders
+}
+
+// getJSON sends a GET to handler and decodes the data or error of the
+// response
+func getJSON(t *testing.T, handler http.Handler, target string) (int, map[string]any, *APIError) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	var body struct {
+		Data  map[string]any `json:"data"`
+		Error *APIError      `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: decoding %q: %v", target, rec.Body, err)
+	}
+	return rec.Code, body.Data, body.Error
+}
+
+func TestOrderHandlerExpandsUsers(t *testing.T) {
+	handler, users, _ := orderHandler(t)
+
+	status, body, _ := getJSON(t, handler, "/api/orders?expand=user&limit=10")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %v", status, body)
+	}
+	if users.getByIDs != 1 || users.getByID != 0 {
+		t.Errorf("user lookups = %d batched and %d by ID, want one batch for the page", users.getByIDs, users.getByID)
+	}
+	orders, _ := body["orders"].([]any)
+	if len(orders) != 4 {
+		t.Fatalf("orders = %v, want 4", body["orders"])
+	}
+	for _, item := range orders {
+		order := item.(map[string]any)
+		user, expanded := order["user"].(map[string]any)
+		if order["user_id"] == "deleted" {
+			// A missing owner is left out rather than failing the page
+			if expanded {
+				t.Errorf("order %v embeds user %v, want none for a missing owner", order["id"], user)
+			}
+			continue
+		}
+		if !expanded || user["id"] != order["user_id"] || user["email"] != order["user_id"].(string)+"@example.com" {
+			t.Errorf("order %v embeds user %v, want its owner", order["id"], order["user"])
+		}
+	}
+
+	// The orders of one user share one owner, fetched once
+	users.getByIDs = 0
+	status, body, _ = getJSON(t, handler, "/api/users/u1/orders?expand=user")
+	if status != http.StatusOK || users.getByIDs != 1 {
+		t.Fatalf("status = %d with %d batched lookups, want 200 with one: %v", status, users.getByIDs, body)
+	}
+	for _, item := range body["orders"].([]any) {
+		if user, _ := item.(map[string]any)["user"].(map[string]any); user["id"] != "u1" {
+			t.Errorf("order %v embeds user %v, want u1", item.(map[string]any)["id"], user)
+		}
+	}
+
+	status, body, _ = getJSON(t, handler, "/api/orders/o2?expand=user")
+	if user, _ := body["user"].(map[string]any); status != http.StatusOK || user["id"] != "u2" {
+		t.Errorf("GET o2 = %d, user %v; want 200 embedding u2", status, body["user"])
+	}
+	status, body, _ = getJSON(t, handler, "/api/orders/o4?expand=user")
+	if _, ok := body["user"]; status != http.StatusOK || ok {
+		t.Errorf("GET o4 of a missing owner = %d, user %v; want 200 without a user", status, body["user"])
+	}
+}
+
+func TestOrderHandlerDoesNotExpandUnlessAsked(t *testing.T) {
+	handler, users, _ := orderHandler(t)
+
+	status, body, _ := getJSON(t, handler, "/api/orders?limit=10")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %v", status, body)
+	}
+	if users.getByIDs != 0 || users.getByID != 0 {
+		t.Errorf("user lookups = %d batched and %d by ID, want none", users.getByIDs, users.getByID)
+	}
+	for _, item := range body["orders"].([]any) {
+		if user, ok := item.(map[string]any)["user"]; ok {
+			t.Errorf("order embeds user %v without ?expand=user", user)
+		}
+	}
+}
+
+func TestOrderHandlerRejectsUnknownExpansions(t *testing.T) {
+	tests := []struct {
+		target     string
+		wantStatus int
+	}{
+		{"/api/orders?expand=owner", http.StatusBadRequest},
+		{"/api/orders?expand=user,items", http.StatusBadRequest},
+		{"/api/orders/o1?expand=payments", http.StatusBadRequest},
+		{"/api/users/u1/orders?expand=USER", http.StatusBadRequest},
+		{"/api/users/u1/orders?expand=user,", http.StatusOK}, // Empty names are skipped
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			handler, users, _ := orderHandler(t)
+
+			status, _, apiErr := getJSON(t, handler, tt.target)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %+v", status, tt.wantStatus, apiErr)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+			if apiErr == nil || apiErr.Code != "INVALID_QUERY_PARAMS" {
+				t.Errorf("error = %+v, want INVALID_QUERY_PARAMS", apiErr)
+			} else if _, ok := apiErr.Details["expand"]; !ok {
+				t.Errorf("error details = %v, want expand", apiErr.Details)
+			}
+			if users.getByIDs != 0 || users.getByID != 0 {
+				t.Errorf("rejected request looked up users")
+			}
+		})
+	}
+}
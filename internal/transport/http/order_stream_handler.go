@@ -0,0 +1,231 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/broadcast"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/websocket"
+)
+
+// OrderStreamHandler pushes live order status to WebSocket clients
+// Transport layer - order events arrive through Publish (fed by the order
+// event bus) and fan out to the connections watching each order
+type OrderStreamHandler struct {
+	orderService *usecase.OrderService
+	hub          *broadcast.Hub
+	pingInterval time.Duration
+	writeTimeout time.Duration
+	logg         *logger.Logger
+}
+
+// NewOrderStreamHandler creates the live order status handler. Clients are
+// pinged every pingInterval and dropped when a message or ping cannot be
+// written within writeTimeout, or when no pong arrives within two intervals.
+func NewOrderStreamHandler(orderService *usecase.OrderService, hub *broadcast.Hub, pingInterval, writeTimeout time.Duration, logg *logger.Logger) *OrderStreamHandler {
+	return &OrderStreamHandler{
+		orderService: orderService,
+		hub:          hub,
+		pingInterval: pingInterval,
+		writeTimeout: writeTimeout,
+		logg:         logg,
+	}
+}
+
+// OrderStatusMessage is sent as a text message: first a "snapshot" of the
+// current status, then a "status_changed" message for every transition. A
+// transition racing the snapshot may be repeated once.
+type OrderStatusMessage struct {
+	Type           string `json:"type"`
+	OrderID        string `json:"order_id"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	At             string `json:"at"`
+	EventID        string `json:"event_id,omitempty"`
+}
+
+const (
+	orderMessageSnapshot      = "snapshot"
+	orderMessageStatusChanged = "status_changed"
+
+	// orderStreamReadLimit caps client messages, which carry nothing but control frames
+	orderStreamReadLimit = 4 << 10
+)
+
+func orderStatusTopic(orderID string) string {
+	return "order:" + orderID
+}
+
+// Publish forwards an order event to the connections watching the order.
+// Ownership was checked when they connected, so events go to every watcher.
+func (h *OrderStreamHandler) Publish(event *domain.OrderEvent) {
+	data, err := json.Marshal(OrderStatusMessage{
+		Type:           orderMessageStatusChanged,
+		OrderID:        event.OrderID,
+		Status:         string(event.Status),
+		PreviousStatus: string(event.PreviousStatus),
+		At:             event.OccurredAt.Format(graphQLTimeFormat),
+		EventID:        event.ID,
+	})
+	if err != nil {
+		h.logg.Error("failed to encode order event", "error", err, "order_id", event.OrderID)
+		return
+	}
+	h.hub.Publish(broadcast.Event{ID: event.ID, Topic: orderStatusTopic(event.OrderID), Data: data})
+}
+
+// Stream handles GET /api/orders/{id}/ws. The connection closes with 1000
+// once the order is delivered or cancelled, 1013 when the client reads too
+// slowly to keep up and 1001 when the server shuts down.
+func (h *OrderStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsUpgrade(r) {
+		w.Header().Set("Upgrade", "websocket")
+		respondError(w, http.StatusUpgradeRequired, "UPGRADE_REQUIRED", "This endpoint only accepts WebSocket connections")
+		return
+	}
+
+	// Browsers attach the session cookie to cross-site WebSocket handshakes
+	// and CORS does not apply to them, so cookie sessions must be same-origin
+	if GetCredentialSource(r.Context()) != CredentialBearer && !sameOrigin(r) {
+		emitPermissionDenied(r, "websocket_cross_origin", map[string]string{"origin": r.Header.Get("Origin")})
+		respondError(w, http.StatusForbidden, "FORBIDDEN", "Cross-origin WebSocket connections are not allowed")
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Order ID is required")
+		return
+	}
+
+	// Subscribe before reading the order, so no transition falls between the
+	// snapshot and the first event
+	claims := GetClaims(r.Context())
+	var subject string
+	if claims != nil {
+		subject = claims.Subject
+	}
+	sub := h.hub.Subscribe(subject, orderStatusTopic(id))
+	defer sub.Close()
+
+	order, err := h.orderService.GetOrderByID(r.Context(), id)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	// Another user's order is reported as missing so IDs can't be probed
+	if claims != nil && order.UserID != claims.Subject && !claims.HasScope(auth.ScopeAdmin) {
+		handleError(w, domain.ErrOrderNotFound)
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r,
+		websocket.WithWriteTimeout(h.writeTimeout),
+		websocket.WithReadLimit(orderStreamReadLimit))
+	if errors.Is(err, websocket.ErrBadHandshake) {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid WebSocket handshake")
+		return
+	}
+	if err != nil {
+		h.logg.Warn("websocket upgrade failed", "error", err, "order_id", id)
+		return
+	}
+	defer conn.Close()
+
+	h.serve(conn, sub, order)
+}
+
+// serve writes the snapshot and then the order's events until the order is
+// final, the client goes away or the subscription ends
+func (h *OrderStreamHandler) serve(conn *websocket.Conn, sub *broadcast.Subscription, order *domain.Order) {
+	// The reader answers pings and notices the client leaving; every pong
+	// extends the read deadline by two ping intervals
+	pongWait := 2 * h.pingInterval
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	readDone := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				readDone <- err
+				return
+			}
+		}
+	}()
+
+	snapshot, err := json.Marshal(OrderStatusMessage{
+		Type:    orderMessageSnapshot,
+		OrderID: order.ID,
+		Status:  string(order.Status),
+		At:      order.UpdatedAt.Format(graphQLTimeFormat),
+	})
+	if err != nil || conn.WriteMessage(websocket.TextMessage, snapshot) != nil {
+		return
+	}
+	if order.Status.IsFinal() {
+		h.closeConn(conn, readDone, websocket.CloseNormalClosure, "order is "+string(order.Status))
+		return
+	}
+
+	ping := time.NewTicker(h.pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case e := <-sub.Events():
+			// A client too slow to take a message within the write timeout is dropped
+			if err := conn.WriteMessage(websocket.TextMessage, e.Data); err != nil {
+				return
+			}
+			var msg OrderStatusMessage
+			if json.Unmarshal(e.Data, &msg) == nil && domain.OrderStatus(msg.Status).IsFinal() {
+				h.closeConn(conn, readDone, websocket.CloseNormalClosure, "order is "+msg.Status)
+				return
+			}
+		case <-ping.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		case <-readDone:
+			// Closed by the client, a protocol error or a missed pong
+			return
+		case <-sub.Done():
+			if errors.Is(sub.Err(), broadcast.ErrSlowConsumer) {
+				h.closeConn(conn, readDone, websocket.CloseTryAgainLater, "too slow, reconnect")
+			} else {
+				h.closeConn(conn, readDone, websocket.CloseGoingAway, "server shutting down")
+			}
+			return
+		}
+	}
+}
+
+// closeConn runs the closing handshake, waiting up to the write timeout for
+// the client's reply; the caller closes the connection afterwards
+func (h *OrderStreamHandler) closeConn(conn *websocket.Conn, readDone <-chan error, code int, reason string) {
+	if err := conn.WriteClose(code, reason); err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Now().Add(h.writeTimeout))
+	<-readDone
+}
+
+// sameOrigin reports whether the request has no Origin header or one naming
+// the host it was sent to
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,238 @@
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Cursor Pagination
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ListParams are the parameters of the user and order lists: offset
+// pagination, or keyset pagination from the next_cursor or prev_cursor of an
+// earlier page, which stays fast however deep a client pages. Filters and
+// sort order come from parseListQuery.
+type ListParams struct {
+	PaginationParams
+	Cursor string `query:"cursor"`
+	// Total=false skips counting the matching rows, which costs a scan of
+	// them on large tables
+	Total bool `query:"total" default:"true"`
+}
+
+// Cursor directions, the first letter of an encoded cursor
+const (
+	cursorAfter  = "a"
+	cursorBefore = "b"
+)
+
+// encodeCursor makes an opaque cursor for the page after (or before) c
+func encodeCursor(direction string, c *domain.Cursor) string {
+	if c == nil {
+		return ""
+	}
+	raw := direction + "|" + strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor turns a cursor from a client into the keyset page it names
+func decodeCursor(cursor string, limit int) (domain.KeysetPage, bool) {
+	page := domain.KeysetPage{Limit: limit}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return page, false
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return page, false
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return page, false
+	}
+	c := &domain.Cursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: parts[2]}
+	switch parts[0] {
+	case cursorAfter:
+		page.After = c
+	case cursorBefore:
+		page.Before = c
+	default:
+		return page, false
+	}
+	return page, true
+}
+
+// listRequest is a bound list request: its parameters, filters and sort
+// order, and for keyset pagination the page its cursor names
+type listRequest struct {
+	ListParams
+	Query  domain.ListQuery
+	Page   domain.KeysetPage
+	Keyset bool // Paginated by cursor rather than offset
+}
+
+// bindListOrRespond binds the parameters of a list whose filterable and
+// sortable fields are fields. Writes a 400 response and returns false if the
+// parameters are invalid.
+func bindListOrRespond(w http.ResponseWriter, r *http.Request, fields map[string]domain.QueryField) (listRequest, bool) {
+	var req listRequest
+	if !bindQueryOrRespond(w, r, &req.ListParams) {
+		return req, false
+	}
+	query, errs := parseListQuery(r.URL.Query(), fields)
+	if errs == nil && req.Cursor != "" {
+		// Cursors hold a position in the newest-first order only
+		if r.URL.Query().Has("offset") {
+			errs = map[string]string{"offset": "cannot be combined with cursor"}
+		} else if len(query.Sort) > 0 {
+			errs = map[string]string{"sort": "cannot be combined with cursor"}
+		}
+	}
+	if errs != nil {
+		respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_QUERY_PARAMS",
+			"One or more query parameters are invalid", errs)
+		return req, false
+	}
+	req.Query = query
+	if req.Cursor == "" {
+		return req, true
+	}
+
+	page, valid := decodeCursor(req.Cursor, req.Limit)
+	if !valid {
+		respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_QUERY_PARAMS",
+			"One or more query parameters are invalid",
+			map[string]string{"cursor": "must be the next_cursor or prev_cursor of an earlier page"})
+		return req, false
+	}
+	page.Filters = query.Filters
+	req.Page, req.Keyset = page, true
+	return req, true
+}
+
+// offsetPageCursor returns the cursor continuing after a full offset page
+// from its last row, so clients can switch to cursors from any page; it may
+// lead to an empty page. Pages that are not full have no next page.
+func offsetPageCursor(rows, limit int, last func() domain.Cursor) string {
+	if rows == 0 || rows < limit {
+		return ""
+	}
+	c := last()
+	return encodeCursor(cursorAfter, &c)
+}
+
+// listPage is what a list handler found for a listRequest
+type listPage struct {
+	Rows  int // Items on the page
+	Total int // Rows matching the filters, -1 when not counted
+	// Keyset cursors of the pages after and before, empty at either end
+	Next, Prev string
+	// Last returns the cursor position of the last item, for the cursor
+	// continuing after an offset page
+	Last func() domain.Cursor
+}
+
+// respond sends a page of a list as key, with the pagination metadata
+// (limit, offset or cursors, total, has_more) and RFC 8288 Link headers for
+// the first, previous, next and, with a total, last pages
+func (req listRequest) respond(w http.ResponseWriter, r *http.Request, key string, items any, page listPage, fields fieldset) {
+	body := map[string]interface{}{
+		key:     items,
+		"limit": req.Limit,
+	}
+	if page.Total >= 0 {
+		body["total"] = page.Total
+	}
+
+	links := []pageLink{{"first", url.Values{"cursor": nil, "offset": nil}}}
+	if req.Keyset {
+		body["has_more"] = page.Next != ""
+		if page.Prev != "" {
+			links = append(links, pageLink{"prev", url.Values{"cursor": {page.Prev}, "offset": nil}})
+		}
+		if page.Next != "" {
+			links = append(links, pageLink{"next", url.Values{"cursor": {page.Next}, "offset": nil}})
+		}
+		setPageLinks(w, r, links)
+		respondFields(w, http.StatusOK, withCursors(body, page.Next, page.Prev), fields)
+		return
+	}
+
+	hasMore := page.Rows >= req.Limit
+	if page.Total >= 0 {
+		hasMore = req.Offset+page.Rows < page.Total
+	}
+	body["offset"] = req.Offset
+	body["has_more"] = hasMore
+	if req.Offset > 0 {
+		links = append(links, pageLink{"prev", offsetValues(max(req.Offset-req.Limit, 0))})
+	}
+	if hasMore {
+		links = append(links, pageLink{"next", offsetValues(req.Offset + req.Limit)})
+	}
+	if page.Total >= 0 {
+		links = append(links, pageLink{"last", offsetValues(max(page.Total-1, 0) / req.Limit * req.Limit)})
+	}
+	setPageLinks(w, r, links)
+
+	next := ""
+	if len(req.Query.Sort) == 0 {
+		// Cursors hold a position in the newest-first order only
+		next = offsetPageCursor(page.Rows, req.Limit, page.Last)
+	}
+	respondFields(w, http.StatusOK, withCursors(body, next, ""), fields)
+}
+
+// pageLink is a Link to another page of a list: the request's URL with the
+// parameters in set replaced (or removed, for nil values)
+type pageLink struct {
+	rel string
+	set url.Values
+}
+
+func offsetValues(offset int) url.Values {
+	return url.Values{"offset": {strconv.Itoa(offset)}, "cursor": nil}
+}
+
+// setPageLinks adds a Link header for each page. Links keep the path the
+// client asked for, before any /api/v{n} rewriting, so they stay on the same
+// API version.
+func setPageLinks(w http.ResponseWriter, r *http.Request, links []pageLink) {
+	path := r.URL.Path
+	if requested, err := url.ParseRequestURI(r.RequestURI); err == nil && requested.Path != "" {
+		path = requested.Path
+	}
+	for _, link := range links {
+		query := r.URL.Query()
+		for name, values := range link.set {
+			if values == nil {
+				query.Del(name)
+			} else {
+				query[name] = values
+			}
+		}
+		target := path
+		if encoded := query.Encode(); encoded != "" {
+			target += "?" + encoded
+		}
+		w.Header().Add("Link", "<"+target+`>; rel="`+link.rel+`"`)
+	}
+}
+
+// withCursors adds the non-empty cursors to a list response
+func withCursors(body map[string]interface{}, next, prev string) map[string]interface{} {
+	if next != "" {
+		body["next_cursor"] = next
+	}
+	if prev != "" {
+		body["prev_cursor"] = prev
+	}
+	return body
+}
@@ -0,0 +1,331 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	tests := []struct {
+		name      string
+		direction string
+		cursor    domain.Cursor
+	}{
+		{"after", cursorAfter, domain.Cursor{CreatedAt: at, ID: "o1"}},
+		{"before", cursorBefore, domain.Cursor{CreatedAt: at, ID: "o1"}},
+		{"ID with separators", cursorAfter, domain.Cursor{CreatedAt: at, ID: "tenant|o1"}},
+		{"before 1970", cursorAfter, domain.Cursor{CreatedAt: time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC), ID: "apollo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := encodeCursor(tt.direction, &tt.cursor)
+			if url.QueryEscape(encoded) != encoded {
+				t.Errorf("cursor %q needs escaping in a URL", encoded)
+			}
+			page, ok := decodeCursor(encoded, 10)
+			if !ok {
+				t.Fatalf("decodeCursor(%q) failed", encoded)
+			}
+			got, other := page.After, page.Before
+			if tt.direction == cursorBefore {
+				got, other = page.Before, page.After
+			}
+			if got == nil || other != nil || !got.CreatedAt.Equal(tt.cursor.CreatedAt) || got.ID != tt.cursor.ID || page.Limit != 10 {
+				t.Errorf("decoded = %+v, want %s %+v", page, tt.direction, tt.cursor)
+			}
+		})
+	}
+
+	if got := encodeCursor(cursorAfter, nil); got != "" {
+		t.Errorf("encodeCursor(nil) = %q, want none", got)
+	}
+}
+
+func TestDecodeCorruptCursor(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	for name, cursor := range map[string]string{
+		"not base64":          "not a cursor!",
+		"padded base64":       base64.URLEncoding.EncodeToString([]byte("a|1|o12")),
+		"unknown direction":   raw("x|1|o1"),
+		"missing ID":          raw("a|1|"),
+		"missing parts":       raw("a|1"),
+		"non-numeric time":    raw("a|yesterday|o1"),
+		"time out of range":   raw("a|99999999999999999999|o1"),
+		"empty":               raw(""),
+		"truncated by a copy": encodeCursor(cursorAfter, &domain.Cursor{CreatedAt: time.Now(), ID: "o1"})[:5],
+	} {
+		if page, ok := decodeCursor(cursor, 10); ok {
+			t.Errorf("%s: decodeCursor(%q) = %+v, want invalid", name, cursor, page)
+		}
+	}
+}
+
+// pagedOrders serves GET /api/orders over orders o1 to o<n>, each a minute
+// newer than the one before
+func pagedOrders(t *testing.T, n int) http.Handler {
+	t.Helper()
+	ctx := context.Background()
+	logg := logger.New("error")
+	orders := memory.NewOrderRepository()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 500, time.UTC)
+	for i := 1; i <= n; i++ {
+		order, err := domain.NewOrder(fmt.Sprintf("o%d", i), "u1", []domain.OrderItem{{ProductID: "p1", Quantity: 1, Price: 5}})
+		if err != nil {
+			t.Fatalf("NewOrder: %v", err)
+		}
+		order.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		if err := orders.Create(ctx, order); err != nil {
+			t.Fatalf("Create order: %v", err)
+		}
+	}
+	h := NewOrderHandler(usecase.NewOrderService(orders, memory.NewUserRepository(), memory.NewOrderCache(), logg), logg)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/orders", h.List)
+	return mux
+}
+
+// orderPage is a page of GET /api/orders
+type orderPage struct {
+	rec *httptest.ResponseRecorder
+	ids []string
+	// The pagination metadata
+	Total      *int   `json:"total"`
+	Offset     *int   `json:"offset"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor"`
+	PrevCursor string `json:"prev_cursor"`
+}
+
+// getOrderPage fetches target, failing the test unless it is a page
+func getOrderPage(t *testing.T, h http.Handler, target string) *orderPage {
+	t.Helper()
+	rec := serve(h, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d %q, want 200", target, rec.Code, rec.Body)
+	}
+	var body struct {
+		Data struct {
+			orderPage
+			Orders []struct {
+				ID string `json:"id"`
+			} `json:"orders"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: decoding %q: %v", target, rec.Body, err)
+	}
+	page := body.Data.orderPage
+	page.rec = rec
+	for _, o := range body.Data.Orders {
+		page.ids = append(page.ids, o.ID)
+	}
+	return &page
+}
+
+func TestOrderListKeysetPages(t *testing.T) {
+	h := pagedOrders(t, 7)
+
+	// An offset page hands over to cursors
+	page := getOrderPage(t, h, "/api/orders?limit=3")
+	if !slices.Equal(page.ids, []string{"o7", "o6", "o5"}) || page.NextCursor == "" || page.PrevCursor != "" {
+		t.Fatalf("first page = %v next %q prev %q, want o7-o5 with a next cursor", page.ids, page.NextCursor, page.PrevCursor)
+	}
+
+	// Forwards to the end
+	forward := []struct {
+		ids      []string
+		hasMore  bool
+		wantPrev bool
+	}{
+		{[]string{"o4", "o3", "o2"}, true, true},
+		{[]string{"o1"}, false, true},
+	}
+	var prevCursors []string
+	for i, want := range forward {
+		page = getOrderPage(t, h, "/api/orders?limit=3&cursor="+page.NextCursor)
+		if !slices.Equal(page.ids, want.ids) || page.HasMore != want.hasMore ||
+			(page.NextCursor != "") != want.hasMore || (page.PrevCursor != "") != want.wantPrev {
+			t.Fatalf("page %d forwards = %v has_more %v next %q prev %q, want %v", i+2, page.ids, page.HasMore,
+				page.NextCursor, page.PrevCursor, want.ids)
+		}
+		if page.Offset != nil {
+			t.Errorf("page %d forwards has offset %d", i+2, *page.Offset)
+		}
+		prevCursors = append(prevCursors, page.PrevCursor)
+	}
+
+	// And back to the start, where there is no page before
+	backward := []struct {
+		ids      []string
+		wantPrev bool
+	}{
+		{[]string{"o4", "o3", "o2"}, true},
+		{[]string{"o7", "o6", "o5"}, false},
+	}
+	for i, want := range backward {
+		page = getOrderPage(t, h, "/api/orders?limit=3&cursor="+page.PrevCursor)
+		if !slices.Equal(page.ids, want.ids) || !page.HasMore || page.NextCursor == "" || (page.PrevCursor != "") != want.wantPrev {
+			t.Fatalf("page %d backwards = %v has_more %v next %q prev %q, want %v", i+1, page.ids, page.HasMore,
+				page.NextCursor, page.PrevCursor, want.ids)
+		}
+	}
+
+	// A cursor is a position, not a page: following it again gives the same rows
+	again := getOrderPage(t, h, "/api/orders?limit=3&cursor="+prevCursors[1])
+	if !slices.Equal(again.ids, []string{"o4", "o3", "o2"}) {
+		t.Errorf("page before the last = %v, want o4-o2", again.ids)
+	}
+}
+
+func TestOrderListCursorErrors(t *testing.T) {
+	h := pagedOrders(t, 3)
+	valid := encodeCursor(cursorAfter, &domain.Cursor{CreatedAt: time.Now(), ID: "o2"})
+	tests := []struct {
+		name      string
+		query     string
+		wantField string
+	}{
+		{"corrupt cursor", "cursor=bm90LWEtY3Vyc29y", "cursor"},
+		{"not base64", "cursor=%25%25%25", "cursor"},
+		{"cursor and offset", "cursor=" + valid + "&offset=3", "offset"},
+		{"cursor and sort", "cursor=" + valid + "&sort=amount", "sort"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, apiErr := getJSON(t, h, "/api/orders?"+tt.query)
+			if status != http.StatusBadRequest || apiErr == nil || apiErr.Code != "INVALID_QUERY_PARAMS" {
+				t.Fatalf("GET = %d %+v, want 400 INVALID_QUERY_PARAMS", status, apiErr)
+			}
+			if _, ok := apiErr.Details[tt.wantField]; !ok {
+				t.Errorf("details = %v, want one for %s", apiErr.Details, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestOrderListOffsetLinks(t *testing.T) {
+	h := pagedOrders(t, 7)
+	// The cursor continuing after o5, the last row of the first page
+	afterO5 := encodeCursor(cursorAfter, &domain.Cursor{CreatedAt: time.Date(2026, 3, 1, 12, 5, 0, 500, time.UTC), ID: "o5"})
+	tests := []struct {
+		name       string
+		query      string
+		wantLinks  []string
+		wantMore   bool
+		wantCursor string
+	}{
+		{"first page", "limit=3&total=true", []string{
+			`</api/orders?limit=3&total=true>; rel="first"`,
+			`</api/orders?limit=3&offset=3&total=true>; rel="next"`,
+			`</api/orders?limit=3&offset=6&total=true>; rel="last"`,
+		}, true, afterO5},
+		{"middle page", "limit=3&offset=3&total=true", []string{
+			`</api/orders?limit=3&total=true>; rel="first"`,
+			`</api/orders?limit=3&offset=0&total=true>; rel="prev"`,
+			`</api/orders?limit=3&offset=6&total=true>; rel="next"`,
+			`</api/orders?limit=3&offset=6&total=true>; rel="last"`,
+		}, true, ""},
+		{"last page", "limit=3&offset=6&total=true", []string{
+			`</api/orders?limit=3&total=true>; rel="first"`,
+			`</api/orders?limit=3&offset=3&total=true>; rel="prev"`,
+			`</api/orders?limit=3&offset=6&total=true>; rel="last"`,
+		}, false, ""},
+		// A total is counted unless asked not to
+		{"total by default", "limit=3&offset=3", []string{
+			`</api/orders?limit=3>; rel="first"`,
+			`</api/orders?limit=3&offset=0>; rel="prev"`,
+			`</api/orders?limit=3&offset=6>; rel="next"`,
+			`</api/orders?limit=3&offset=6>; rel="last"`,
+		}, true, ""},
+		{"prev page clamped to the start", "limit=3&offset=1", []string{
+			`</api/orders?limit=3>; rel="first"`,
+			`</api/orders?limit=3&offset=0>; rel="prev"`,
+			`</api/orders?limit=3&offset=4>; rel="next"`,
+			`</api/orders?limit=3&offset=6>; rel="last"`,
+		}, true, ""},
+		// Without a total there is no last page, and a full page may have more
+		{"middle page without a total", "limit=3&offset=3&total=false", []string{
+			`</api/orders?limit=3&total=false>; rel="first"`,
+			`</api/orders?limit=3&offset=0&total=false>; rel="prev"`,
+			`</api/orders?limit=3&offset=6&total=false>; rel="next"`,
+		}, true, ""},
+		{"last page without a total", "limit=3&offset=6&total=false", []string{
+			`</api/orders?limit=3&total=false>; rel="first"`,
+			`</api/orders?limit=3&offset=3&total=false>; rel="prev"`,
+		}, false, ""},
+		{"empty list", "limit=3&offset=9&total=true", []string{
+			`</api/orders?limit=3&total=true>; rel="first"`,
+			`</api/orders?limit=3&offset=6&total=true>; rel="prev"`,
+			`</api/orders?limit=3&offset=6&total=true>; rel="last"`,
+		}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := getOrderPage(t, h, "/api/orders?"+tt.query)
+			if got := page.rec.Header().Values("Link"); !slices.Equal(got, tt.wantLinks) {
+				t.Errorf("Link =\n%q\nwant\n%q", got, tt.wantLinks)
+			}
+			if page.HasMore != tt.wantMore {
+				t.Errorf("has_more = %v, want %v", page.HasMore, tt.wantMore)
+			}
+			// Only full pages in the newest-first order continue with a cursor
+			if tt.wantCursor != "" && page.NextCursor != tt.wantCursor {
+				t.Errorf("next_cursor = %q, want %q", page.NextCursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestOrderListKeysetLinks(t *testing.T) {
+	h := pagedOrders(t, 7)
+	first := getOrderPage(t, h, "/api/orders?limit=3")
+	middle := getOrderPage(t, h, "/api/orders?limit=3&cursor="+first.NextCursor)
+	last := getOrderPage(t, h, "/api/orders?limit=3&cursor="+middle.NextCursor)
+
+	tests := []struct {
+		name      string
+		page      *orderPage
+		wantLinks []string
+	}{
+		{"middle page", middle, []string{
+			`</api/orders?limit=3>; rel="first"`,
+			`</api/orders?cursor=` + middle.PrevCursor + `&limit=3>; rel="prev"`,
+			`</api/orders?cursor=` + middle.NextCursor + `&limit=3>; rel="next"`,
+		}},
+		// Keyset pages are never counted, so never have a last link
+		{"last page", last, []string{
+			`</api/orders?limit=3>; rel="first"`,
+			`</api/orders?cursor=` + last.PrevCursor + `&limit=3>; rel="prev"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.page.rec.Header().Values("Link"); !slices.Equal(got, tt.wantLinks) {
+				t.Errorf("Link =\n%q\nwant\n%q", got, tt.wantLinks)
+			}
+		})
+	}
+
+	// Following the links walks the same pages
+	back := getOrderPage(t, h, "/api/orders?cursor="+last.PrevCursor+"&limit=3&total=true")
+	if !slices.Equal(back.ids, middle.ids) {
+		t.Errorf("prev of the last page = %v, want %v", back.ids, middle.ids)
+	}
+	if got := back.rec.Header().Values("Link")[0]; got != `</api/orders?limit=3&total=true>; rel="first"` {
+		t.Errorf("first Link = %q, want the other parameters kept", got)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// JSON Merge Patch (RFC 7396)
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// PATCH bodies are merge patches: a JSON object naming only the members to
+// change. A member that is absent is left alone, while a member set to null
+// removes the field. Unlike PUT, an empty string is a value like any other
+// and is validated as such.
+
+// MergePatchContentType is the media type of a JSON merge patch
+const MergePatchContentType = "application/merge-patch+json"
+
+// PatchField is one member of a merge patch, telling an absent member apart
+// from one set to null
+type PatchField[T any] struct {
+	Present bool // The member appeared in the patch
+	Null    bool // The member was null: remove the field
+	Value   T
+}
+
+// UnmarshalJSON records that the member was present, and whether it was null
+func (f *PatchField[T]) UnmarshalJSON(data []byte) error {
+	f.Present = true
+	if bytes.Equal(data, []byte("null")) {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+// decodeMergePatch decodes the merge patch in r's body into target, a struct
+// of PatchFields. Writes an error response and returns false for another
+// media type, a body that is not a JSON object, or an unknown member.
+func decodeMergePatch(w http.ResponseWriter, r *http.Request, target any) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != MergePatchContentType && mediaType != "application/json" {
+		respondError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE",
+			"Content-Type must be "+MergePatchContentType)
+		return false
+	}
+	if r.Body == nil {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return false
+	}
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return false
+	}
+	// Any other JSON value would replace the whole resource
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '{' {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Merge patch must be a JSON object")
+		return false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// patchErrors collects the members of a merge patch that cannot be applied
+type patchErrors map[string]string
+
+// required rejects the removal of a field the resource cannot go without
+func (e patchErrors) required(name string, null bool) {
+	if null {
+		e[name] = "cannot be removed"
+	}
+}
+
+// respond writes a 400 response listing the errors and returns false, or
+// returns true when there are none
+func (e patchErrors) respond(w http.ResponseWriter) bool {
+	if len(e) == 0 {
+		return true
+	}
+	respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_PATCH",
+		"One or more patch members are invalid", map[string]string(e))
+	return false
+}
+
+// optional returns a pointer to the value of a present, non-null member
+func optional[T any](f PatchField[T]) *T {
+	if !f.Present || f.Null {
+		return nil
+	}
+	return &f.Value
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestPatchFieldTellsAbsentFromNull(t *testing.T) {
+	var patch struct {
+		Name  PatchField[string] `json:"name"`
+		Email PatchField[string] `json:"email"`
+		Note  PatchField[string] `json:"note"`
+	}
+	if err := json.Unmarshal([]byte(`{"name":null,"email":"ada@example.com"}`), &patch); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !patch.Name.Present || !patch.Name.Null || optional(patch.Name) != nil {
+		t.Errorf("null member = %+v, want present and null", patch.Name)
+	}
+	if !patch.Email.Present || patch.Email.Null || *optional(patch.Email) != "ada@example.com" {
+		t.Errorf("set member = %+v, want present with its value", patch.Email)
+	}
+	if patch.Note.Present || patch.Note.Null || optional(patch.Note) != nil {
+		t.Errorf("absent member = %+v, want absent", patch.Note)
+	}
+}
+
+// patchUserHandler serves PATCH /api/users/{id} over a repository holding user-1
+func patchUserHandler(t *testing.T) (http.Handler, domain.UserRepository) {
+	t.Helper()
+	logg := logger.New("error")
+	repo := memory.NewUserRepository()
+	user, err := domain.NewUser("user-1", "Ada Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatalf("NewUser: %v", err)
+	}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /api/users/{id}", NewUserHandler(usecase.NewUserService(repo, memory.NewUserCache(), logg), logg).Patch)
+	return mux, repo
+}
+
+func patchRequest(contentType, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPatch, "/api/users/user-1", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	r.Header.Set("If-Match", "*")
+	return r
+}
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantName    string
+		wantEmail   string
+	}{
+		{"absent member unchanged", MergePatchContentType, `{"email":"ada@example.org"}`, http.StatusOK, "Ada Lovelace", "ada@example.org"},
+		{"both members", MergePatchContentType, `{"name":"Ada King","email":"ada@example.org"}`, http.StatusOK, "Ada King", "ada@example.org"},
+		{"empty patch", MergePatchContentType, `{}`, http.StatusOK, "Ada Lovelace", "ada@example.com"},
+		{"media type parameters", MergePatchContentType + "; charset=utf-8", `{"name":"Ada King"}`, http.StatusOK, "Ada King", "ada@example.com"},
+		{"plain JSON", "application/json", `{"name":"Ada King"}`, http.StatusOK, "Ada King", "ada@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, repo := patchUserHandler(t)
+			rec := serve(h, patchRequest(tt.contentType, tt.body))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			user, err := repo.GetByID(context.Background(), "user-1")
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if user.Name != tt.wantName || user.Email != tt.wantEmail {
+				t.Errorf("user = %q <%s>, want %q <%s>", user.Name, user.Email, tt.wantName, tt.wantEmail)
+			}
+			if rec.Header().Get("ETag") == "" {
+				t.Error("patched user has no ETag")
+			}
+		})
+	}
+}
+
+func TestPatchUserRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{"other media type", "text/plain", `{"name":"Ada King"}`, http.StatusUnsupportedMediaType, MergePatchContentType},
+		{"no media type", "", `{"name":"Ada King"}`, http.StatusUnsupportedMediaType, MergePatchContentType},
+		{"JSON patch", "application/json-patch+json", `[{"op":"remove","path":"/name"}]`, http.StatusUnsupportedMediaType, MergePatchContentType},
+		{"array body", MergePatchContentType, `[{"name":"Ada King"}]`, http.StatusBadRequest, "must be a JSON object"},
+		{"string body", MergePatchContentType, `"Ada King"`, http.StatusBadRequest, "must be a JSON object"},
+		{"null body", MergePatchContentType, `null`, http.StatusBadRequest, "must be a JSON object"},
+		{"empty body", MergePatchContentType, ``, http.StatusBadRequest, "must be a JSON object"},
+		{"malformed object", MergePatchContentType, `{"name":`, http.StatusBadRequest, "Invalid request body"},
+		{"unknown member", MergePatchContentType, `{"name":"Ada King","role":"admin"}`, http.StatusBadRequest, "Invalid request body"},
+		{"wrong member type", MergePatchContentType, `{"name":42}`, http.StatusBadRequest, "Invalid request body"},
+		{"removing the name", MergePatchContentType, `{"name":null}`, http.StatusBadRequest, `"name":"cannot be removed"`},
+		{"removing both", MergePatchContentType, `{"name":null,"email":null}`, http.StatusBadRequest, `"email":"cannot be removed"`},
+		{"empty name is validated", MergePatchContentType, `{"name":""}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, repo := patchUserHandler(t)
+			rec := serve(h, patchRequest(tt.contentType, tt.body))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s", rec.Body, tt.wantBody)
+			}
+			user, err := repo.GetByID(context.Background(), "user-1")
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if user.Name != "Ada Lovelace" || user.Email != "ada@example.com" {
+				t.Errorf("user = %q <%s>, want it unchanged", user.Name, user.Email)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Typed Query Parameter Binding
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// bindQuery maps query parameters onto a struct using field tags:
+//
+//	type ListOrdersParams struct {
+//		PaginationParams                                   // embedded structs are flattened
+//		Status  string    `query:"status" enum:"pending,confirmed,shipped"`
+//		Created TimeRange `query:"created"`                // reads created_from / created_to
+//		IDs     []string  `query:"id"`                     // repeated or comma-separated
+//		Verbose bool      `query:"verbose" default:"false"`
+//	}
+//
+// Supported tags: query (parameter name), default, required, min, max (numbers
+// and slice lengths) and enum (comma-separated allowed values). Every invalid
+// parameter is reported, not just the first one.
+
+// PaginationParams holds the standard offset pagination parameters shared by list endpoints
+type PaginationParams struct {
+	Limit  int `query:"limit" default:"20" min:"1" max:"100"`
+	Offset int `query:"offset" default:"0" min:"0"`
+}
+
+// TimeRange is an optional, inclusive time window bound from <name>_from and <name>_to
+// parameters in RFC 3339 format
+type TimeRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// IsZero reports whether neither bound was provided
+func (tr TimeRange) IsZero() bool {
+	return tr.From == nil && tr.To == nil
+}
+
+// QueryBindingError collects validation errors keyed by parameter name
+type QueryBindingError struct {
+	Errors map[string]string
+}
+
+// Error returns a deterministic summary of all parameter errors
+func (e *QueryBindingError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + e.Errors[name]
+	}
+	return "invalid query parameters: " + strings.Join(parts, "; ")
+}
+
+func (e *QueryBindingError) add(name, msg string) {
+	if e.Errors == nil {
+		e.Errors = make(map[string]string)
+	}
+	if _, exists := e.Errors[name]; !exists {
+		e.Errors[name] = msg
+	}
+}
+
+var timeRangeType = reflect.TypeOf(TimeRange{})
+
+// bindQuery binds r's query parameters into dst, which must be a pointer to a struct.
+// Returns a *QueryBindingError listing every invalid parameter.
+func bindQuery(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic("bindQuery: dst must be a pointer to a struct")
+	}
+
+	bindErr := &QueryBindingError{}
+	bindStruct(r.URL.Query(), v.Elem(), bindErr)
+
+	if len(bindErr.Errors) > 0 {
+		return bindErr
+	}
+	return nil
+}
+
+// bindStruct binds every tagged field of a struct, recursing into embedded structs
+func bindStruct(values url.Values, v reflect.Value, bindErr *QueryBindingError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			bindStruct(values, fv, bindErr)
+			continue
+		}
+
+		name := field.Tag.Get("query")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		if field.Type == timeRangeType {
+			bindTimeRange(values, name, fv, bindErr)
+			continue
+		}
+
+		raw := values[name]
+		if len(raw) == 0 || (len(raw) == 1 && raw[0] == "") {
+			if def, ok := field.Tag.Lookup("default"); ok {
+				raw = []string{def}
+			} else {
+				if field.Tag.Get("required") == "true" {
+					bindErr.add(name, "is required")
+				}
+				continue
+			}
+		}
+
+		if err := bindField(fv, field, raw); err != nil {
+			bindErr.add(name, err.Error())
+		}
+	}
+}
+
+// bindField parses raw values into a single field and applies its constraints
+func bindField(fv reflect.Value, field reflect.StructField, raw []string) error {
+	if fv.Kind() == reflect.Slice {
+		var parts []string
+		for _, r := range raw {
+			for _, p := range strings.Split(r, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					parts = append(parts, p)
+				}
+			}
+		}
+
+		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setScalar(slice.Index(i), p); err != nil {
+				return err
+			}
+			if err := checkEnum(field, p); err != nil {
+				return err
+			}
+		}
+		if err := checkBounds(field, float64(len(parts)), "must contain at %s %s values"); err != nil {
+			return err
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	value := raw[len(raw)-1]
+	if err := setScalar(fv, value); err != nil {
+		return err
+	}
+	if err := checkEnum(field, value); err != nil {
+		return err
+	}
+
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return checkBounds(field, float64(fv.Int()), "must be at %s %s")
+	case reflect.Float32, reflect.Float64:
+		return checkBounds(field, fv.Float(), "must be at %s %s")
+	}
+	return nil
+}
+
+// setScalar parses a single string value into a scalar field
+func setScalar(fv reflect.Value, value string) error {
+	if fv.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("must be an RFC 3339 timestamp")
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration like 30s or 5m")
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		fv.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		fv.SetFloat(f)
+	default:
+		panic(fmt.Sprintf("bindQuery: unsupported field type %s", fv.Type()))
+	}
+	return nil
+}
+
+// checkEnum validates a value against the field's enum tag
+func checkEnum(field reflect.StructField, value string) error {
+	enum, ok := field.Tag.Lookup("enum")
+	if !ok {
+		return nil
+	}
+	for _, allowed := range strings.Split(enum, ",") {
+		if value == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of: %s", strings.ReplaceAll(enum, ",", ", "))
+}
+
+// checkBounds validates n against the field's min and max tags
+func checkBounds(field reflect.StructField, n float64, format string) error {
+	if minStr, ok := field.Tag.Lookup("min"); ok {
+		if lo, err := strconv.ParseFloat(minStr, 64); err == nil && n < lo {
+			return fmt.Errorf(format, "least", minStr)
+		}
+	}
+	if maxStr, ok := field.Tag.Lookup("max"); ok {
+		if hi, err := strconv.ParseFloat(maxStr, 64); err == nil && n > hi {
+			return fmt.Errorf(format, "most", maxStr)
+		}
+	}
+	return nil
+}
+
+// bindTimeRange binds <name>_from and <name>_to into a TimeRange
+func bindTimeRange(values url.Values, name string, fv reflect.Value, bindErr *QueryBindingError) {
+	var tr TimeRange
+	for _, bound := range []struct {
+		param string
+		dst   **time.Time
+	}{
+		{name + "_from", &tr.From},
+		{name + "_to", &tr.To},
+	} {
+		raw := values.Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			bindErr.add(bound.param, "must be an RFC 3339 timestamp")
+			continue
+		}
+		*bound.dst = &t
+	}
+
+	if tr.From != nil && tr.To != nil && tr.From.After(*tr.To) {
+		bindErr.add(name+"_from", "must not be after "+name+"_to")
+		return
+	}
+	fv.Set(reflect.ValueOf(tr))
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testQueryParams struct {
+	PaginationParams
+	Status   string        `query:"status" enum:"pending,confirmed,shipped"`
+	Created  TimeRange     `query:"created"`
+	IDs      []string      `query:"id" max:"3"`
+	Verbose  bool          `query:"verbose" default:"false"`
+	MinTotal float64       `query:"min_total" min:"0"`
+	Since    time.Time     `query:"since"`
+	Wait     time.Duration `query:"wait" default:"5s"`
+	Owner    string        `query:"owner" required:"true"`
+	Internal string        `query:"-"`
+	hidden   string        `query:"hidden"`
+}
+
+func TestBindQuery(t *testing.T) {
+	at := func(s string) *time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &ts
+	}
+	defaults := testQueryParams{
+		PaginationParams: PaginationParams{Limit: 20},
+		Wait:             5 * time.Second,
+		Owner:            "user-1",
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  func(p *testQueryParams)
+	}{
+		{"defaults", "owner=user-1", func(p *testQueryParams) {}},
+		{"empty value falls back to the default", "owner=user-1&limit=", func(p *testQueryParams) {}},
+		{"pagination", "owner=user-1&limit=100&offset=40", func(p *testQueryParams) {
+			p.Limit, p.Offset = 100, 40
+		}},
+		{"enum", "owner=user-1&status=shipped", func(p *testQueryParams) { p.Status = "shipped" }},
+		{"bool", "owner=user-1&verbose=true", func(p *testQueryParams) { p.Verbose = true }},
+		{"float", "owner=user-1&min_total=12.5", func(p *testQueryParams) { p.MinTotal = 12.5 }},
+		{"time", "owner=user-1&since=2024-05-01T10:00:00Z", func(p *testQueryParams) {
+			p.Since = *at("2024-05-01T10:00:00Z")
+		}},
+		{"duration", "owner=user-1&wait=1m", func(p *testQueryParams) { p.Wait = time.Minute }},
+		{"repeated and comma-separated slice", "owner=user-1&id=a,b&id=+c+", func(p *testQueryParams) {
+			p.IDs = []string{"a", "b", "c"}
+		}},
+		{"last scalar wins", "owner=user-1&owner=user-2", func(p *testQueryParams) { p.Owner = "user-2" }},
+		{"time range", "owner=user-1&created_from=2024-01-01T00:00:00Z&created_to=2024-02-01T00:00:00Z", func(p *testQueryParams) {
+			p.Created = TimeRange{From: at("2024-01-01T00:00:00Z"), To: at("2024-02-01T00:00:00Z")}
+		}},
+		{"open time range", "owner=user-1&created_to=2024-02-01T00:00:00Z", func(p *testQueryParams) {
+			p.Created = TimeRange{To: at("2024-02-01T00:00:00Z")}
+		}},
+		{"untagged and unexported fields are left alone", "owner=user-1&Internal=x&hidden=y", func(p *testQueryParams) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := defaults
+			tt.want(&want)
+
+			var got testQueryParams
+			r := httptest.NewRequest(http.MethodGet, "/api/orders?"+tt.query, nil)
+			if err := bindQuery(r, &got); err != nil {
+				t.Fatalf("bindQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("bindQuery() =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestBindQueryRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{"missing required", "", map[string]string{"owner": "is required"}},
+		{"not an integer", "owner=u&limit=ten", map[string]string{"limit": "must be an integer"}},
+		{"below min", "owner=u&limit=0", map[string]string{"limit": "must be at least 1"}},
+		{"above max", "owner=u&limit=101", map[string]string{"limit": "must be at most 100"}},
+		{"negative float", "owner=u&min_total=-1", map[string]string{"min_total": "must be at least 0"}},
+		{"not a number", "owner=u&min_total=lots", map[string]string{"min_total": "must be a number"}},
+		{"not a bool", "owner=u&verbose=yes please", map[string]string{"verbose": "must be true or false"}},
+		{"not in enum", "owner=u&status=lost", map[string]string{"status": "must be one of: pending, confirmed, shipped"}},
+		{"bad timestamp", "owner=u&since=yesterday", map[string]string{"since": "must be an RFC 3339 timestamp"}},
+		{"bad duration", "owner=u&wait=soon", map[string]string{"wait": "must be a duration like 30s or 5m"}},
+		{"too many values", "owner=u&id=a,b,c,d", map[string]string{"id": "must contain at most 3 values"}},
+		{"bad range bound", "owner=u&created_from=jan", map[string]string{"created_from": "must be an RFC 3339 timestamp"}},
+		{
+			name:  "inverted range",
+			query: "owner=u&created_from=2024-02-01T00:00:00Z&created_to=2024-01-01T00:00:00Z",
+			want:  map[string]string{"created_from": "must not be after created_to"},
+		},
+		{
+			name:  "every invalid parameter is reported",
+			query: "limit=0&offset=-1&status=lost",
+			want: map[string]string{
+				"owner":  "is required",
+				"limit":  "must be at least 1",
+				"offset": "must be at least 0",
+				"status": "must be one of: pending, confirmed, shipped",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testQueryParams
+			r := httptest.NewRequest(http.MethodGet, "/api/orders?"+strings.ReplaceAll(tt.query, " ", "+"), nil)
+			err := bindQuery(r, &got)
+
+			var bindErr *QueryBindingError
+			if !errors.As(err, &bindErr) {
+				t.Fatalf("bindQuery() error = %v, want a *QueryBindingError", err)
+			}
+			if !reflect.DeepEqual(bindErr.Errors, tt.want) {
+				t.Errorf("errors = %v, want %v", bindErr.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBindingErrorIsDeterministic(t *testing.T) {
+	err := &QueryBindingError{Errors: map[string]string{"status": "bad", "limit": "too big", "offset": "negative"}}
+	want := "invalid query parameters: limit: too big; offset: negative; status: bad"
+	for range 5 {
+		if got := err.Error(); got != want {
+			t.Fatalf("Error() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestBindQueryPanicsOnInvalidTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  any
+	}{
+		{"not a pointer", testQueryParams{}},
+		{"pointer to a non-struct", new(string)},
+		{"unsupported field type", &struct {
+			Tags map[string]string `query:"tags"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("bindQuery() did not panic")
+				}
+			}()
+			bindQuery(httptest.NewRequest(http.MethodGet, "/?tags=a", nil), tt.dst)
+		})
+	}
+}
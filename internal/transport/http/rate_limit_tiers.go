@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// RateLimitTierPolicy gives some identities larger per-identity budgets:
+// the route class limits and the route limits counted per principal
+type RateLimitTierPolicy struct {
+	// Multipliers scales the budgets of each tier's members; 0 exempts them
+	Multipliers map[string]int
+	// Members maps identities to tiers: "user:<id>" covers a user and every
+	// access token they own, "key:<id>" a single personal access token
+	Members map[string]string
+}
+
+// RateLimitTiers holds the tier policy in force; it may be replaced at runtime
+type RateLimitTiers struct {
+	mu     sync.RWMutex
+	policy RateLimitTierPolicy
+}
+
+// NewRateLimitTiers creates rate limit tiers enforcing policy
+func NewRateLimitTiers(policy RateLimitTierPolicy) *RateLimitTiers {
+	return &RateLimitTiers{policy: policy}
+}
+
+// Set replaces the tier policy
+func (t *RateLimitTiers) Set(policy RateLimitTierPolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policy = policy
+}
+
+// multiplier returns the budget multiplier of the tier of the identity r
+// authenticated as, 1 for identities in no tier. A key's own tier wins over
+// its owner's.
+func (t *RateLimitTiers) multiplier(r *http.Request) int {
+	if t == nil {
+		return 1
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var identities []string
+	if token := GetAccessToken(r.Context()); token != nil {
+		identities = append(identities, "key:"+token.ID)
+	}
+	if claims := GetClaims(r.Context()); claims != nil && claims.Subject != "" {
+		identities = append(identities, "user:"+claims.Subject)
+	}
+	for _, identity := range identities {
+		if tier, ok := t.policy.Members[identity]; ok {
+			if multiplier, ok := t.policy.Multipliers[tier]; ok {
+				return multiplier
+			}
+		}
+	}
+	return 1
+}
+
+// allow consumes a token of limiter for the principal of r, scaled by the
+// tier of its identity. Identities in an exempt tier are always allowed.
+func (t *RateLimitTiers) allow(limiter *middleware.RateLimiter, r *http.Request) bool {
+	multiplier := t.multiplier(r)
+	if multiplier == 0 {
+		return true
+	}
+	return limiter.AllowScaled(rateLimitPrincipal(r), multiplier)
+}
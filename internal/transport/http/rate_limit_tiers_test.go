@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// testTierPolicy triples premium budgets and exempts internal ones
+func testTierPolicy() RateLimitTierPolicy {
+	return RateLimitTierPolicy{
+		Multipliers: map[string]int{"premium": 3, "internal": 0},
+		Members: map[string]string{
+			"user:u-premium": "premium",
+			"user:u-owner":   "premium",
+			"key:k-internal": "internal",
+		},
+	}
+}
+
+// tokenRequest builds a request authenticated with access token id of user,
+// as the access token middleware does
+func tokenRequest(method, target, id, user string) *http.Request {
+	r := limitRequest(method, target, user, "10.0.0.1")
+	ctx := context.WithValue(r.Context(), AccessTokenKey, &domain.PersonalAccessToken{ID: id, UserID: user})
+	return r.WithContext(context.WithValue(ctx, ClaimsKey, &auth.Claims{Subject: user, ID: id}))
+}
+
+// neverLimited is what allowedBefore429 returns when no request was limited
+const neverLimited = 20
+
+// allowedBefore429 sends requests built by newRequest until one is limited,
+// returning how many were allowed
+func allowedBefore429(t *testing.T, h http.Handler, newRequest func() *http.Request) int {
+	t.Helper()
+	for n := range neverLimited {
+		switch code := serve(h, newRequest()).Code; code {
+		case http.StatusNoContent:
+		case http.StatusTooManyRequests:
+			return n
+		default:
+			t.Fatalf("request %d = %d, want 204 or 429", n+1, code)
+		}
+	}
+	return neverLimited
+}
+
+func TestRouteClassRateLimitTiers(t *testing.T) {
+	tests := []struct {
+		name        string
+		newRequest  func() *http.Request
+		wantAllowed int // Under a write limit of 2 per minute
+	}{
+		{"free user", func() *http.Request {
+			return limitRequest(http.MethodPost, "/api/orders", "u-free", "10.0.0.1")
+		}, 2},
+		{"premium user", func() *http.Request {
+			return limitRequest(http.MethodPost, "/api/orders", "u-premium", "10.0.0.1")
+		}, 6},
+		{"anonymous", func() *http.Request {
+			return limitRequest(http.MethodPost, "/api/orders", "", "10.0.0.1")
+		}, 2},
+		// A key of a premium user shares its owner's tier, with a budget of its own
+		{"key of a premium user", func() *http.Request {
+			return tokenRequest(http.MethodPost, "/api/orders", "k1", "u-owner")
+		}, 6},
+		// The key's own tier wins over its owner's
+		{"exempt key", func() *http.Request {
+			return tokenRequest(http.MethodPost, "/api/orders", "k-internal", "u-free")
+		}, neverLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := limitedRoutes()
+			limiters := newRouteClassLimiters(map[string]int{"write": 2})
+			h := RouteClassRateLimit(limiters, NewRateLimitTiers(testTierPolicy()))(mux)
+			if got := allowedBefore429(t, h, tt.newRequest); got != tt.wantAllowed {
+				t.Errorf("allowed %d requests, want %d", got, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestRouteClassRateLimitTierKeys(t *testing.T) {
+	mux := limitedRoutes()
+	limiters := newRouteClassLimiters(map[string]int{"write": 1})
+	h := RouteClassRateLimit(limiters, NewRateLimitTiers(testTierPolicy()))(mux)
+	post := func(user, ip string) int {
+		return serve(h, limitRequest(http.MethodPost, "/api/orders", user, ip)).Code
+	}
+
+	// Anonymous requests count against their IP, not the users behind it
+	if code := post("", "10.0.0.1"); code != http.StatusNoContent {
+		t.Fatalf("anonymous POST = %d, want 204", code)
+	}
+	if code := post("", "10.0.0.1"); code != http.StatusTooManyRequests {
+		t.Errorf("second anonymous POST from the IP = %d, want 429", code)
+	}
+	if code := post("", "10.0.0.2"); code != http.StatusNoContent {
+		t.Errorf("anonymous POST from another IP = %d, want 204", code)
+	}
+	if code := post("u-free", "10.0.0.1"); code != http.StatusNoContent {
+		t.Errorf("POST by a user on the limited IP = %d, want 204", code)
+	}
+	if code := post("u-free", "10.0.0.2"); code != http.StatusTooManyRequests {
+		t.Errorf("second POST by the user from another IP = %d, want 429", code)
+	}
+}
+
+func TestRateLimitTiersReload(t *testing.T) {
+	mux := limitedRoutes()
+	policy := RateLimitTierPolicy{
+		Multipliers: map[string]int{"internal": 0},
+		Members:     map[string]string{"user:u1": "internal"},
+	}
+	tiers := NewRateLimitTiers(policy)
+	h := RouteClassRateLimit(newRouteClassLimiters(map[string]int{"write": 1}), tiers)(mux)
+	newRequest := func() *http.Request {
+		return limitRequest(http.MethodPost, "/api/orders", "u1", "10.0.0.1")
+	}
+
+	if got := allowedBefore429(t, h, newRequest); got != neverLimited {
+		t.Fatalf("exempt user limited after %d requests", got)
+	}
+	// Leaving the tier takes effect on the next request
+	tiers.Set(RateLimitTierPolicy{})
+	if got := allowedBefore429(t, h, newRequest); got != 1 {
+		t.Errorf("allowed %d requests after leaving the exempt tier, want 1", got)
+	}
+	tiers.Set(policy)
+	if got := allowedBefore429(t, h, newRequest); got != neverLimited {
+		t.Errorf("user limited after %d requests on rejoining the exempt tier", got)
+	}
+}
+
+func TestPerRouteRateLimitTiers(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		user        string
+		wantAllowed int // Under a route limit of 2 per minute
+	}{
+		{"free user", RouteLimitKeyPrincipal, "u-free", 2},
+		{"premium user", RouteLimitKeyPrincipal, "u-premium", 6},
+		{"anonymous", RouteLimitKeyPrincipal, "", 2},
+		// Limits not counted per principal are the same for everyone
+		{"premium user under an IP limit", RouteLimitKeyIP, "u-premium", 2},
+		{"premium user under a global limit", RouteLimitKeyGlobal, "u-premium", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := limitedRoutes()
+			limiters := NewRouteLimiters([]RouteRateLimit{{Route: "POST /api/orders", PerMinute: 2, Key: tt.key}})
+			h := PerRouteRateLimit(limiters, NewRateLimitTiers(testTierPolicy()), mux)(mux)
+			got := allowedBefore429(t, h, func() *http.Request {
+				return limitRequest(http.MethodPost, "/api/orders", tt.user, "10.0.0.1")
+			})
+			if got != tt.wantAllowed {
+				t.Errorf("allowed %d requests, want %d", got, tt.wantAllowed)
+			}
+		})
+	}
+}
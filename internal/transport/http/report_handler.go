@@ -0,0 +1,142 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// ReportHandler handles HTTP requests for scheduled report administration
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type ReportHandler struct {
+	reportService *usecase.ReportService
+	logg          *logger.Logger
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(reportService *usecase.ReportService, logg *logger.Logger) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		logg:          logg,
+	}
+}
+
+// CreateReportScheduleRequest represents the request body for scheduling a report
+type CreateReportScheduleRequest struct {
+	Name       string   `json:"name"`
+	Report     string   `json:"report"`   // e.g. "daily_order_summary"
+	Schedule   string   `json:"schedule"` // Cron expression in UTC, e.g. "0 7 * * *"
+	Recipients []string `json:"recipients"`
+}
+
+// ReportScheduleResponse represents a scheduled report
+type ReportScheduleResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Report     string   `json:"report"`
+	Schedule   string   `json:"schedule"`
+	Recipients []string `json:"recipients"`
+	NextRunAt  string   `json:"next_run_at"`
+	LastRunAt  *string  `json:"last_run_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// toReportScheduleResponse converts a domain schedule to a response DTO
+func toReportScheduleResponse(s *domain.ReportSchedule) *ReportScheduleResponse {
+	return &ReportScheduleResponse{
+		ID:         s.ID,
+		Name:       s.Name,
+		Report:     s.Report,
+		Schedule:   s.Schedule,
+		Recipients: s.Recipients,
+		NextRunAt:  s.NextRunAt.UTC().Format(time.RFC3339),
+		LastRunAt:  formatOptionalTime(s.LastRunAt),
+		CreatedAt:  s.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// requireAdmin rejects callers without the admin scope
+func (h *ReportHandler) requireAdmin(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	claims := GetClaims(r.Context())
+	if claims == nil || !claims.HasScope(auth.ScopeAdmin) {
+		emitPermissionDenied(r, "admin_scope_required", nil)
+		handleError(w, domain.ErrForbidden)
+		return nil, false
+	}
+	return claims, true
+}
+
+// Create handles POST /api/admin/reports
+func (h *ReportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateReportScheduleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+
+	sched, err := h.reportService.CreateSchedule(r.Context(), req.Name, req.Report, req.Schedule, req.Recipients, claims.Subject)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventAdminAction,
+		Outcome: security.OutcomeSuccess,
+		Action:  "schedule_report",
+		Target:  &security.Target{Type: "report_schedule", ID: sched.ID},
+		Details: map[string]string{"recipients": strings.Join(sched.Recipients, ",")},
+	})
+	respondJSON(w, http.StatusCreated, toReportScheduleResponse(sched))
+}
+
+// List handles GET /api/admin/reports
+func (h *ReportHandler) List(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+
+	schedules, err := h.reportService.ListSchedules(r.Context())
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := make([]*ReportScheduleResponse, len(schedules))
+	for i, s := range schedules {
+		response[i] = toReportScheduleResponse(s)
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// Delete handles DELETE /api/admin/reports/{id}
+func (h *ReportHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+
+	id := strings.TrimSpace(r.PathValue("id"))
+	if err := h.reportService.DeleteSchedule(r.Context(), id); err != nil {
+		handleError(w, err)
+		return
+	}
+
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventAdminAction,
+		Outcome: security.OutcomeSuccess,
+		Action:  "delete_report_schedule",
+		Target:  &security.Target{Type: "report_schedule", ID: id},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
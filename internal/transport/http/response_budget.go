@@ -0,0 +1,226 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Response Size Budget Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ResponseBudgetConfig holds response size budget configuration
+type ResponseBudgetConfig struct {
+	Logger *logger.Logger
+
+	// WarnBytes logs a warning when a response body exceeds this size (0 disables)
+	WarnBytes int64
+
+	// MaxBytes is the hard budget used for list truncation (0 disables truncation)
+	MaxBytes int64
+
+	// TruncateLists trims the largest list in an oversized JSON response until it
+	// fits MaxBytes, marking the payload as a partial result
+	TruncateLists bool
+}
+
+// ResponseBudget measures serialized response sizes and enforces the configured budgets.
+//
+// Without truncation the response is streamed through untouched and only measured.
+// With truncation enabled, JSON responses are buffered so an oversized list can be
+// trimmed. Truncated responses carry an X-Partial-Result header and extra
+// "partial", "returned" and "available" fields next to the trimmed list.
+func ResponseBudget(config ResponseBudgetConfig) Middleware {
+	buffering := config.TruncateLists && config.MaxBytes > 0
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !buffering {
+				wrapped := middleware.NewResponseWriter(w)
+				next.ServeHTTP(wrapped, r)
+				config.warnIfOverBudget(r, wrapped.BytesWritten(), false)
+				return
+			}
+
+			bw := &budgetWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(bw, r)
+
+			if bw.hijacked {
+				return
+			}
+			if bw.streaming {
+				config.warnIfOverBudget(r, bw.streamed, false)
+				return
+			}
+
+			body := bw.buf.Bytes()
+			truncated := false
+			if int64(len(body)) > config.MaxBytes && bw.isJSONSuccess() {
+				if trimmed, ok := truncateLargestList(body, config.MaxBytes); ok {
+					body = trimmed
+					truncated = true
+					w.Header().Set("X-Partial-Result", "true")
+				}
+			}
+
+			config.warnIfOverBudget(r, int64(bw.buf.Len()), truncated)
+
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(bw.status)
+			w.Write(body)
+		})
+	}
+}
+
+// warnIfOverBudget logs responses that exceed the warning budget
+func (c ResponseBudgetConfig) warnIfOverBudget(r *http.Request, size int64, truncated bool) {
+	if c.Logger == nil || c.WarnBytes <= 0 || size <= c.WarnBytes {
+		return
+	}
+
+	c.Logger.Warn("response exceeds size budget",
+		"request_id", GetRequestID(r.Context()),
+		"method", r.Method,
+		"path", r.URL.Path,
+		"bytes", size,
+		"warn_bytes", c.WarnBytes,
+		"max_bytes", c.MaxBytes,
+		"truncated", truncated,
+	)
+}
+
+// budgetWriter buffers a response so its size can be checked before sending.
+// If the handler flushes (e.g. a streaming endpoint), buffering stops and the
+// response passes straight through; a hijacked connection (WebSocket) is no
+// longer a response at all.
+type budgetWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	streaming   bool
+	streamed    int64
+	hijacked    bool
+}
+
+func (bw *budgetWriter) WriteHeader(code int) {
+	if bw.wroteHeader {
+		return
+	}
+	bw.status = code
+	bw.wroteHeader = true
+	if bw.streaming {
+		bw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (bw *budgetWriter) Write(b []byte) (int, error) {
+	if !bw.wroteHeader {
+		bw.WriteHeader(http.StatusOK)
+	}
+	if bw.streaming {
+		n, err := bw.ResponseWriter.Write(b)
+		bw.streamed += int64(n)
+		return n, err
+	}
+	return bw.buf.Write(b)
+}
+
+// Flush switches the writer to pass-through mode and flushes buffered data
+func (bw *budgetWriter) Flush() {
+	if !bw.streaming {
+		bw.streaming = true
+		bw.ResponseWriter.WriteHeader(bw.status)
+		n, _ := bw.ResponseWriter.Write(bw.buf.Bytes())
+		bw.streamed = int64(n)
+		bw.buf.Reset()
+	}
+	if f, ok := bw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection to the handler; nothing is buffered or sent afterwards
+func (bw *budgetWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(bw.ResponseWriter).Hijack()
+	if err == nil {
+		bw.hijacked = true
+	}
+	return conn, brw, err
+}
+
+// Unwrap returns the underlying writer (used by http.ResponseController)
+func (bw *budgetWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
+
+// isJSONSuccess reports whether the buffered response is a successful JSON payload
+func (bw *budgetWriter) isJSONSuccess() bool {
+	ct := bw.Header().Get("Content-Type")
+	return bw.status >= 200 && bw.status < 300 && strings.HasPrefix(ct, "application/json")
+}
+
+// truncateLargestList trims the largest array inside the response's data object
+// so the re-encoded response fits within maxBytes. It returns false if the
+// payload has no list to trim.
+func truncateLargestList(body []byte, maxBytes int64) ([]byte, bool) {
+	var envelope map[string]any
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, false
+	}
+
+	data, ok := envelope["data"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	// Pick the largest list in the payload (e.g. "users" or "orders")
+	var listKey string
+	var list []any
+	for key, value := range data {
+		if items, ok := value.([]any); ok && len(items) > len(list) {
+			listKey, list = key, items
+		}
+	}
+	if listKey == "" {
+		return nil, false
+	}
+
+	data["partial"] = true
+	data["available"] = len(list)
+
+	encode := func(n int) []byte {
+		data[listKey] = list[:n]
+		data["returned"] = n
+		out, err := json.Marshal(envelope)
+		if err != nil {
+			return nil
+		}
+		return append(out, '\n')
+	}
+
+	// Binary search for the largest prefix of the list that fits the budget
+	lo, hi := 0, len(list)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if out := encode(mid); out != nil && int64(len(out)) <= maxBytes {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	out := encode(lo)
+	if out == nil {
+		return nil, false
+	}
+	return out, true
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// listBody is a JSON envelope with n users and a couple of tags
+func listBody(n int) []byte {
+	users := make([]map[string]string, n)
+	for i := range users {
+		users[i] = map[string]string{"id": fmt.Sprintf("user-%03d", i), "name": "Name Surname"}
+	}
+	body, _ := json.Marshal(map[string]any{
+		"success": true,
+		"data":    map[string]any{"users": users, "tags": []string{"a", "b"}, "total": n},
+	})
+	return append(body, '\n')
+}
+
+// partialData decodes a truncated envelope's data object
+func partialData(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	var envelope struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("truncated body is not JSON: %v: %s", err, body)
+	}
+	return envelope.Data
+}
+
+func TestTruncateLargestList(t *testing.T) {
+	body := listBody(50)
+	out, ok := truncateLargestList(body, int64(len(body)/2))
+	if !ok {
+		t.Fatal("truncateLargestList() = false, want a trimmed list")
+	}
+	if len(out) > len(body)/2 {
+		t.Errorf("trimmed to %d bytes, over the %d budget", len(out), len(body)/2)
+	}
+
+	data := partialData(t, out)
+	returned := int(data["returned"].(float64))
+	if data["partial"] != true || data["available"] != float64(50) {
+		t.Errorf("partial = %v, available = %v, want true and 50", data["partial"], data["available"])
+	}
+	if users := data["users"].([]any); len(users) != returned || returned == 0 || returned >= 50 {
+		t.Errorf("returned = %d with %d users, want a matching count between 0 and 50", returned, len(users))
+	}
+	if tags := data["tags"].([]any); len(tags) != 2 {
+		t.Errorf("tags = %v, want the smaller list left alone", tags)
+	}
+	if data["total"] != float64(50) {
+		t.Errorf("total = %v, want other fields left alone", data["total"])
+	}
+}
+
+func TestTruncateLargestListKeepsAsManyAsFit(t *testing.T) {
+	body := listBody(20)
+	fit, ok := truncateLargestList(body, int64(len(body)-1))
+	if !ok {
+		t.Fatal("truncateLargestList() = false")
+	}
+	n := int(partialData(t, fit)["returned"].(float64))
+
+	// A budget of exactly that size keeps the same items; one byte less drops one
+	exact, _ := truncateLargestList(body, int64(len(fit)))
+	if got := int(partialData(t, exact)["returned"].(float64)); got != n {
+		t.Errorf("budget of %d bytes returned %d, want %d", len(fit), got, n)
+	}
+	under, _ := truncateLargestList(body, int64(len(fit)-1))
+	if got := int(partialData(t, under)["returned"].(float64)); got != n-1 {
+		t.Errorf("budget of %d bytes returned %d, want %d", len(fit)-1, got, n-1)
+	}
+
+	// A budget too small for any item still answers, with an empty list
+	none, ok := truncateLargestList(body, 10)
+	if !ok {
+		t.Fatal("truncateLargestList() = false for a tiny budget")
+	}
+	if got := partialData(t, none)["returned"]; got != float64(0) {
+		t.Errorf("tiny budget returned %v, want 0", got)
+	}
+}
+
+func TestTruncateLargestListNothingToTrim(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not JSON", `<users/>`},
+		{"no data envelope", `{"users":[1,2,3]}`},
+		{"data is a list", `{"data":[1,2,3]}`},
+		{"no list in data", `{"data":{"id":"user-1","name":"Name"}}`},
+		{"only empty lists", `{"data":{"users":[]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out, ok := truncateLargestList([]byte(tt.body), 5); ok {
+				t.Errorf("truncateLargestList() = %s, want false", out)
+			}
+		})
+	}
+}
+
+func TestResponseBudget(t *testing.T) {
+	large := listBody(100)
+	budget := int64(len(large) / 4)
+
+	tests := []struct {
+		name          string
+		truncate      bool
+		status        int
+		contentType   string
+		body          []byte
+		flush         bool
+		wantTruncated bool
+	}{
+		{"oversized JSON list", true, http.StatusOK, "application/json; charset=utf-8", large, false, true},
+		{"within budget", true, http.StatusOK, "application/json", listBody(2), false, false},
+		{"error response", true, http.StatusInternalServerError, "application/json", large, false, false},
+		{"not JSON", true, http.StatusOK, "application/x-ndjson", large, false, false},
+		{"streamed", true, http.StatusOK, "application/json", large, true, false},
+		{"truncation disabled", false, http.StatusOK, "application/json", large, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := ResponseBudget(ResponseBudgetConfig{
+				WarnBytes:     budget,
+				MaxBytes:      budget,
+				TruncateLists: tt.truncate,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				if tt.flush {
+					w.(http.Flusher).Flush()
+				}
+				w.Write(tt.body)
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+			partial := rec.Header().Get("X-Partial-Result") == "true"
+			if partial != tt.wantTruncated {
+				t.Fatalf("X-Partial-Result = %v, want %v", partial, tt.wantTruncated)
+			}
+			if !tt.wantTruncated {
+				if rec.Code != tt.status || rec.Body.String() != string(tt.body) {
+					t.Errorf("response = %d with %d bytes, want the original %d with %d", rec.Code, rec.Body.Len(), tt.status, len(tt.body))
+				}
+				return
+			}
+			if int64(rec.Body.Len()) > budget {
+				t.Errorf("body = %d bytes, over the %d budget", rec.Body.Len(), budget)
+			}
+			if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+				t.Errorf("Content-Length = %s, want %d", got, rec.Body.Len())
+			}
+			if data := partialData(t, rec.Body.Bytes()); data["partial"] != true {
+				t.Errorf("data.partial = %v, want true", data["partial"])
+			}
+		})
+	}
+}
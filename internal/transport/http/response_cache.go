@@ -0,0 +1,208 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Response Caching
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// GET routes named in RESPONSE_CACHE_ROUTES have their whole 200 responses
+// cached for the route's TTL, and replayed marked X-Cache: HIT:
+//
+//	RESPONSE_CACHE_ROUTES=GET /api/users/{id}=30s,/api/orders=10s
+//
+// Responses are cached per principal, credential kind (session or personal
+// access token) and scopes, API version, URL and Accept/Accept-Language, so
+// no caller is ever served what another was allowed to see. Each is tagged
+// with the resources in its route's path (GET /api/users/{user_id}/orders
+// reads users and orders); a successful POST, PUT, PATCH or DELETE
+// invalidates the resources in its own path, so PATCH /api/orders/{id} drops
+// every cached order listing at once.
+// Changes made outside a request, such as by a background job, are only seen
+// once the TTL runs out: keep TTLs short.
+//
+// Not cached: requests with ?expand=, which embed resources their route does
+// not name; streaming routes; requests sent with Cache-Control: no-store; and
+// responses setting cookies or Cache-Control: no-store. Cache-Control:
+// no-cache skips the cached copy but refreshes it.
+
+const (
+	// CacheStatusHeader tells whether a response was served from the cache
+	CacheStatusHeader = "X-Cache"
+
+	// maxCachedResponseBytes bounds the responses worth keeping
+	maxCachedResponseBytes = 1 << 20
+)
+
+// ResponseCacheConfig configures response caching
+type ResponseCacheConfig struct {
+	Cache domain.ResponseCache
+	// Routes sets how long responses are cached per route group: a
+	// registered pattern ("GET /api/orders"), a path ("/api/orders/{id}") or
+	// "GET" for every route. The most specific group that matches applies.
+	Routes map[string]time.Duration
+	Logger *logger.Logger
+}
+
+// ResponseCache serves GET requests to the configured routes from the cache
+// and invalidates it on writes, resolving routes through mux. Install it
+// after authentication, so responses are cached per verified principal.
+func ResponseCache(config ResponseCacheConfig, mux *http.ServeMux) Middleware {
+	routes := make(map[string]time.Duration, len(config.Routes))
+	for route, ttl := range config.Routes {
+		if ttl > 0 {
+			routes[strings.Join(strings.Fields(route), " ")] = ttl
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(routes) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			_, pattern := mux.Handler(r)
+			switch r.Method {
+			case http.MethodGet:
+				serveCached(config, routes, pattern, next, w, r)
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+				invalidateOnWrite(config, pattern, next, w, r)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// serveCached answers a GET from the cache when its route is cached, or
+// serves it and stores the response
+func serveCached(config ResponseCacheConfig, routes map[string]time.Duration, pattern string,
+	next http.Handler, w http.ResponseWriter, r *http.Request) {
+	_, ttl, ok := routeGroup(routes, r.Method, pattern)
+	directives := r.Header.Get("Cache-Control")
+	if !ok || pattern == "" || streamingRoutes[pattern] || r.URL.Query().Has("expand") || strings.Contains(directives, "no-store") {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	key := responseCacheKey(r)
+	tags := responseCacheTags(pattern)
+	resp, version, err := config.Cache.Get(r.Context(), key, tags)
+	switch {
+	case err == nil && !strings.Contains(directives, "no-cache"):
+		replayCachedResponse(w, r, resp)
+		return
+	case err != nil && !errors.Is(err, domain.ErrCacheMiss):
+		// Serve uncached: without the versions a stored copy could outlive a write
+		config.Logger.Warn("response cache unavailable", "error", err,
+			"request_id", GetRequestID(r.Context()))
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set(CacheStatusHeader, "MISS")
+	rec := &responseRecorder{ResponseWriter: w, before: w.Header().Clone(), status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+	if rec.status != http.StatusOK || rec.body.Len() > maxCachedResponseBytes ||
+		len(rec.header["Set-Cookie"]) > 0 || strings.Contains(w.Header().Get("Cache-Control"), "no-store") {
+		return
+	}
+	err = config.Cache.Set(context.WithoutCancel(r.Context()), key, &domain.CachedResponse{
+		Version: version,
+		Status:  rec.status,
+		Header:  rec.header,
+		Body:    rec.body.Bytes(),
+	}, ttl)
+	if err != nil {
+		config.Logger.Warn("response not cached", "error", err,
+			"request_id", GetRequestID(r.Context()))
+	}
+}
+
+// invalidateOnWrite serves a write and, if it succeeded, invalidates the
+// resources in its route's path
+func invalidateOnWrite(config ResponseCacheConfig, pattern string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	// GraphQL only runs queries; a batch's operations invalidate their own resources
+	if pattern == "" || pattern == "POST /api/graphql" || pattern == "POST /api/batch" {
+		next.ServeHTTP(w, r)
+		return
+	}
+	rw := middleware.NewResponseWriter(w)
+	next.ServeHTTP(rw, r)
+	if rw.Status() >= http.StatusBadRequest {
+		return
+	}
+	// Even if the client has gone away: the write happened
+	if err := config.Cache.Invalidate(context.WithoutCancel(r.Context()), responseCacheTags(pattern)...); err != nil {
+		config.Logger.Error("response cache invalidation failed; cached responses may be stale until they expire",
+			"error", err, "route", pattern, "request_id", GetRequestID(r.Context()))
+	}
+}
+
+// responseCacheKey hashes what a cached response may be replayed for: the
+// caller, the kind of credential they used and what they may see, and the
+// representation they asked for
+func responseCacheKey(r *http.Request) string {
+	scope := "public"
+	if claims := GetClaims(r.Context()); claims != nil {
+		scopes := claims.Scopes()
+		slices.Sort(scopes)
+		credential := "session"
+		if GetAccessToken(r.Context()) != nil {
+			credential = "token"
+		}
+		scope = "user:" + claims.Subject + " " + credential + " " + strings.Join(scopes, " ")
+	}
+	h := sha256.New()
+	io.WriteString(h, scope+"\n"+strconv.Itoa(int(GetAPIVersion(r.Context())))+"\n"+r.URL.RequestURI()+"\n"+
+		r.Header.Get("Accept")+"\n"+r.Header.Get("Accept-Language"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCacheTags returns the resources the route registered as pattern
+// reads or writes: the literal segments of its path after /api/, but admin
+func responseCacheTags(pattern string) []string {
+	_, path, found := strings.Cut(pattern, " ")
+	if !found {
+		path = pattern
+	}
+	var tags []string
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/api/"), "/") {
+		if segment != "" && segment != "admin" && !strings.HasPrefix(segment, "{") {
+			tags = append(tags, segment)
+		}
+	}
+	return tags
+}
+
+// replayCachedResponse writes a cached response again, or 304 Not Modified
+// when the request's If-None-Match lists its ETag
+func replayCachedResponse(w http.ResponseWriter, r *http.Request, resp *domain.CachedResponse) {
+	for name, values := range resp.Header {
+		w.Header()[name] = slices.Clone(values)
+	}
+	w.Header().Set(CacheStatusHeader, "HIT")
+	if tag := w.Header().Get("ETag"); tag != "" && notModified(w, r, tag) {
+		return
+	}
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// responseCacheFixture caches every GET route of a mux whose handlers answer
+// with how many times they have run
+func responseCacheFixture(t *testing.T) http.Handler {
+	t.Helper()
+	served := 0
+	count := func(w http.ResponseWriter, r *http.Request) {
+		served++
+		respondJSON(w, http.StatusOK, map[string]int{"served": served})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{id}", count)
+	mux.HandleFunc("PATCH /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fail") {
+			respondError(w, http.StatusConflict, "CONFLICT", "Conflict")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/orders", count)
+	mux.HandleFunc("GET /api/session", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s"})
+		count(w, r)
+	})
+	mux.HandleFunc("GET /api/private", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		count(w, r)
+	})
+	return ResponseCache(ResponseCacheConfig{
+		Cache:  memory.NewResponseCache(),
+		Routes: map[string]time.Duration{"GET": time.Minute},
+		Logger: logger.New("error"),
+	}, mux)(mux)
+}
+
+// cacheRequest is a request as authenticated by claims, with the personal
+// access token pat if set
+func cacheRequest(method, target string, claims *auth.Claims, pat *domain.PersonalAccessToken) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	ctx := r.Context()
+	if claims != nil {
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
+	}
+	if pat != nil {
+		ctx = context.WithValue(ctx, AccessTokenKey, pat)
+	}
+	return r.WithContext(ctx)
+}
+
+// fetch serves r and returns its X-Cache status and body
+func fetch(t *testing.T, h http.Handler, r *http.Request) (string, string) {
+	t.Helper()
+	rec := serve(h, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s %s = %d, want 200: %s", r.Method, r.URL, rec.Code, rec.Body)
+	}
+	return rec.Header().Get(CacheStatusHeader), rec.Body.String()
+}
+
+func TestResponseCacheReplays(t *testing.T) {
+	h := responseCacheFixture(t)
+	ada := &auth.Claims{Subject: "user-1"}
+
+	first, body := fetch(t, h, cacheRequest(http.MethodGet, "/api/users/user-1", ada, nil))
+	second, replayed := fetch(t, h, cacheRequest(http.MethodGet, "/api/users/user-1", ada, nil))
+	if first != "MISS" || second != "HIT" || replayed != body {
+		t.Errorf("got %s then %s %s, want MISS then HIT replaying %s", first, second, replayed, body)
+	}
+}
+
+func TestResponseCacheKeysPerPrincipal(t *testing.T) {
+	ada := &auth.Claims{Subject: "user-1"}
+	pat := &domain.PersonalAccessToken{ID: "pat-1", UserID: "user-1"}
+
+	tests := []struct {
+		name        string
+		claims      *auth.Claims
+		accessToken *domain.PersonalAccessToken
+	}{
+		{"anonymous", nil, nil},
+		{"another user", &auth.Claims{Subject: "user-2"}, nil},
+		{"other scopes", &auth.Claims{Subject: "user-1", Scope: auth.ScopeAdmin}, nil},
+		{"access token of the same user", ada, pat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := responseCacheFixture(t)
+			fetch(t, h, cacheRequest(http.MethodGet, "/api/users/user-1", ada, nil))
+			if status, _ := fetch(t, h, cacheRequest(http.MethodGet, "/api/users/user-1", tt.claims, tt.accessToken)); status != "MISS" {
+				t.Errorf("X-Cache = %s, want MISS: a response cached for a session of user-1 was replayed", status)
+			}
+		})
+	}
+}
+
+func TestResponseCacheInvalidatesOnWrites(t *testing.T) {
+	ada := &auth.Claims{Subject: "user-1"}
+
+	tests := []struct {
+		name   string
+		write  string
+		cached string
+		want   string
+	}{
+		{"successful write", "/api/users/user-1", "/api/users/user-1", "MISS"},
+		{"other item of the resource", "/api/users/user-2", "/api/users/user-1", "MISS"},
+		{"failed write", "/api/users/user-1?fail", "/api/users/user-1", "HIT"},
+		{"other resource", "/api/users/user-1", "/api/orders", "HIT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := responseCacheFixture(t)
+			fetch(t, h, cacheRequest(http.MethodGet, tt.cached, ada, nil))
+			serve(h, cacheRequest(http.MethodPatch, tt.write, ada, nil))
+			if status, _ := fetch(t, h, cacheRequest(http.MethodGet, tt.cached, ada, nil)); status != tt.want {
+				t.Errorf("X-Cache after PATCH %s = %s, want %s", tt.write, status, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseCacheSkips(t *testing.T) {
+	ada := &auth.Claims{Subject: "user-1"}
+	noStore := func(r *http.Request) *http.Request {
+		r.Header.Set("Cache-Control", "no-store")
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+	}{
+		{"expand", func() *http.Request { return cacheRequest(http.MethodGet, "/api/orders?expand=user", ada, nil) }},
+		{"request no-store", func() *http.Request { return noStore(cacheRequest(http.MethodGet, "/api/orders", ada, nil)) }},
+		{"response no-store", func() *http.Request { return cacheRequest(http.MethodGet, "/api/private", ada, nil) }},
+		{"response sets a cookie", func() *http.Request { return cacheRequest(http.MethodGet, "/api/session", ada, nil) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := responseCacheFixture(t)
+			for i := 1; i <= 2; i++ {
+				status, body := fetch(t, h, tt.request())
+				if status == "HIT" {
+					t.Fatalf("request %d was served from the cache", i)
+				}
+				if want := fmt.Sprintf(`"served":%d`, i); !strings.Contains(body, want) {
+					t.Errorf("request %d body = %s, want %s", i, body, want)
+				}
+			}
+		})
+	}
+}
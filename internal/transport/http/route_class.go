@@ -0,0 +1,118 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// RouteClass groups routes that share a rate limit budget, so one kind of
+// traffic (e.g. clients polling report status) can't use up the budget
+// another needs (e.g. order submissions)
+type RouteClass string
+
+// Route classes, see ClassifyRoute
+const (
+	RouteClassRead  RouteClass = "read"  // Safe methods
+	RouteClassWrite RouteClass = "write" // Everything else
+	RouteClassAuth  RouteClass = "auth"  // /api/auth/ and personal access token management
+	RouteClassAdmin RouteClass = "admin" // /api/admin/
+)
+
+// RouteClasses lists every route class
+var RouteClasses = []RouteClass{RouteClassRead, RouteClassWrite, RouteClassAuth, RouteClassAdmin}
+
+// ClassifyRoute returns the class of an API request, or "" for routes
+// outside /api (health checks, development pages) that have no class budget
+func ClassifyRoute(r *http.Request) RouteClass {
+	path := r.URL.Path
+	switch {
+	case !strings.HasPrefix(path, "/api/"):
+		return ""
+	case strings.HasPrefix(path, "/api/admin/"):
+		return RouteClassAdmin
+	case strings.HasPrefix(path, "/api/auth/"), isAccessTokenPath(path):
+		return RouteClassAuth
+	case path == "/api/graphql":
+		return RouteClassRead // Queries only; mutations are not supported
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return RouteClassRead
+	default:
+		return RouteClassWrite
+	}
+}
+
+// isAccessTokenPath matches /api/users/{id}/tokens and the routes below it
+func isAccessTokenPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/users/")
+	if !ok {
+		return false
+	}
+	_, sub, _ := strings.Cut(rest, "/")
+	return sub == "tokens" || strings.HasPrefix(sub, "tokens/")
+}
+
+// routeClassLimiters holds an independent limiter per route class
+type routeClassLimiters map[RouteClass]*middleware.RateLimiter
+
+// newRouteClassLimiters creates a limiter per class at perMinute[class]
+// requests per minute (missing or 0 leaves the class unlimited)
+func newRouteClassLimiters(perMinute map[string]int) routeClassLimiters {
+	limiters := make(routeClassLimiters, len(RouteClasses))
+	for _, class := range RouteClasses {
+		limiters[class] = middleware.NewRateLimiter(perMinute[string(class)], time.Minute)
+	}
+	return limiters
+}
+
+// setRates changes every class's limit; classes missing from perMinute become unlimited
+func (l routeClassLimiters) setRates(perMinute map[string]int) {
+	for class, limiter := range l {
+		limiter.SetRate(perMinute[string(class)])
+	}
+}
+
+// RouteClassRateLimit limits each principal's requests per route class,
+// scaled by the principal's tier. Install it after authentication:
+// authenticated requests are counted against the access token or user,
+// anonymous ones against the client IP.
+func RouteClassRateLimit(limiters map[RouteClass]*middleware.RateLimiter, tiers *RateLimitTiers) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class := ClassifyRoute(r)
+			limiter := limiters[class]
+			if limiter == nil || tiers.allow(limiter, r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			emitSecurityEvent(r, security.Event{
+				Type:    security.EventRateLimited,
+				Outcome: security.OutcomeDenied,
+				Reason:  "route_class_rate_limit",
+				Details: map[string]string{"class": string(class)},
+			})
+			w.Header().Set("Retry-After", "60")
+			w.Header().Set("X-RateLimit-Class", string(class))
+			middleware.WriteError(w, http.StatusTooManyRequests,
+				"RATE_LIMIT_EXCEEDED", "Too many "+string(class)+" requests, please try again later")
+		})
+	}
+}
+
+// rateLimitPrincipal identifies who a request counts against: the personal
+// access token it authenticated with, else its user, else its client IP. Each
+// of a user's keys has a budget of its own, so one busy integration cannot
+// use up the budget of the others.
+func rateLimitPrincipal(r *http.Request) string {
+	if token := GetAccessToken(r.Context()); token != nil {
+		return "key:" + token.ID
+	}
+	if claims := GetClaims(r.Context()); claims != nil && claims.Subject != "" {
+		return "user:" + claims.Subject
+	}
+	return "ip:" + middleware.ClientIP(r)
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifyRoute(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   RouteClass
+	}{
+		{http.MethodGet, "/api/orders", RouteClassRead},
+		{http.MethodHead, "/api/orders/o1", RouteClassRead},
+		{http.MethodOptions, "/api/orders", RouteClassRead},
+		{http.MethodPost, "/api/orders", RouteClassWrite},
+		{http.MethodDelete, "/api/orders/o1", RouteClassWrite},
+		{http.MethodPost, "/api/graphql", RouteClassRead},
+		{http.MethodPost, "/api/auth/login", RouteClassAuth},
+		{http.MethodGet, "/api/auth/sessions", RouteClassAuth},
+		{http.MethodPost, "/api/users/u1/tokens", RouteClassAuth},
+		{http.MethodDelete, "/api/users/u1/tokens/t1", RouteClassAuth},
+		{http.MethodGet, "/api/users/u1/tokensets", RouteClassRead},
+		{http.MethodGet, "/api/admin/stats", RouteClassAdmin},
+		{http.MethodPost, "/api/admin/jobs/replay", RouteClassAdmin},
+		{http.MethodGet, "/health", ""},
+		{http.MethodGet, "/api", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := ClassifyRoute(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+				t.Errorf("ClassifyRoute = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteClassRateLimitIndependentBudgets(t *testing.T) {
+	mux := limitedRoutes()
+	limiters := newRouteClassLimiters(map[string]int{"read": 2, "write": 2, "auth": 2, "admin": 2})
+	h := RouteClassRateLimit(limiters, nil)(mux)
+	requests := map[RouteClass]func() *http.Request{
+		RouteClassRead:  func() *http.Request { return limitRequest(http.MethodGet, "/api/orders", "u1", "10.0.0.1") },
+		RouteClassWrite: func() *http.Request { return limitRequest(http.MethodPost, "/api/orders", "u1", "10.0.0.1") },
+		RouteClassAuth:  func() *http.Request { return limitRequest(http.MethodPost, "/api/auth/login", "u1", "10.0.0.1") },
+		RouteClassAdmin: func() *http.Request { return limitRequest(http.MethodGet, "/api/admin/stats", "u1", "10.0.0.1") },
+	}
+
+	for _, exhausted := range RouteClasses {
+		t.Run(string(exhausted), func(t *testing.T) {
+			limiters := newRouteClassLimiters(map[string]int{"read": 2, "write": 2, "auth": 2, "admin": 2})
+			h := RouteClassRateLimit(limiters, nil)(mux)
+
+			if got := allowedBefore429(t, h, requests[exhausted]); got != 2 {
+				t.Fatalf("allowed %d %s requests, want 2", got, exhausted)
+			}
+			rec := serve(h, requests[exhausted]())
+			if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "RATE_LIMIT_EXCEEDED" {
+				t.Fatalf("%s request over the limit = %d %q, want 429", exhausted, rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("X-RateLimit-Class"); got != string(exhausted) {
+				t.Errorf("X-RateLimit-Class = %q, want %s", got, exhausted)
+			}
+
+			// Every other class still has its whole budget
+			for _, class := range RouteClasses {
+				if class == exhausted {
+					continue
+				}
+				if got := allowedBefore429(t, h, requests[class]); got != 2 {
+					t.Errorf("allowed %d %s requests after %s ran out, want 2", got, class, exhausted)
+				}
+			}
+		})
+	}
+
+	// Routes without a class, and classes without a limit, are not held
+	limiters.setRates(map[string]int{"write": 1})
+	for _, newRequest := range []func() *http.Request{
+		func() *http.Request { return limitRequest(http.MethodGet, "/health", "u1", "10.0.0.1") },
+		requests[RouteClassRead],
+	} {
+		if got := allowedBefore429(t, h, newRequest); got != neverLimited {
+			t.Errorf("%s limited after %d requests", newRequest().URL.Path, got)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// RouteRateLimit is a per-minute budget for a group of /api routes, as
+// declared in RATE_LIMIT_ROUTES (see config.RouteRateLimit)
+type RouteRateLimit struct {
+	// Route is a registered pattern ("POST /api/orders"), a method on every
+	// route ("GET") or a path on every method ("/api/orders/{id}")
+	Route     string
+	PerMinute int // 0 is unlimited
+	// Key is what the budget is counted per: "principal" (the default), "ip",
+	// "global" or "header:<name>"
+	Key string
+}
+
+// Route limit keys, see RouteRateLimit.Key
+const (
+	RouteLimitKeyPrincipal = "principal"
+	RouteLimitKeyIP        = "ip"
+	RouteLimitKeyGlobal    = "global"
+	routeLimitKeyHeader    = "header:"
+)
+
+// routeLimitRule is a route limit with the limiter counting it
+type routeLimitRule struct {
+	RouteRateLimit
+	method, path string // Either may be empty, matching anything
+	limiter      *middleware.RateLimiter
+}
+
+// specificity ranks rules: a method and path beat a path, which beats a method
+func (rule *routeLimitRule) specificity() int {
+	score := 0
+	if rule.path != "" {
+		score += 2
+	}
+	if rule.method != "" {
+		score++
+	}
+	return score
+}
+
+// matches reports whether the rule covers a request with method to the
+// route registered as pattern
+func (rule *routeLimitRule) matches(method, pattern string) bool {
+	patternMethod, patternPath, found := strings.Cut(pattern, " ")
+	if !found {
+		patternMethod, patternPath = "", pattern
+	}
+	if rule.path != "" && rule.path != patternPath {
+		return false
+	}
+	// GET routes also serve HEAD; the rule follows the route
+	return rule.method == "" || rule.method == method || rule.method == patternMethod
+}
+
+// allow consumes a token for r; limits counted per principal are scaled by
+// the principal's tier, the others are the same for everyone
+func (rule *routeLimitRule) allow(r *http.Request, tiers *RateLimitTiers) bool {
+	if rule.Key == "" || rule.Key == RouteLimitKeyPrincipal {
+		return tiers.allow(rule.limiter, r)
+	}
+	return rule.limiter.Allow(rule.key(r))
+}
+
+// key returns what r counts against under a rule not counted per principal
+func (rule *routeLimitRule) key(r *http.Request) string {
+	switch {
+	case rule.Key == RouteLimitKeyIP:
+		return "ip:" + middleware.ClientIP(r)
+	case rule.Key == RouteLimitKeyGlobal:
+		return "global"
+	case strings.HasPrefix(rule.Key, routeLimitKeyHeader):
+		if value := r.Header.Get(strings.TrimPrefix(rule.Key, routeLimitKeyHeader)); value != "" {
+			return "header:" + value
+		}
+	}
+	return rateLimitPrincipal(r)
+}
+
+// RouteLimiters holds the route limits in force. Limiters are kept across
+// reloads, so changing a limit does not hand every client a fresh budget.
+type RouteLimiters struct {
+	mu       sync.RWMutex
+	rules    []*routeLimitRule
+	limiters map[string]*middleware.RateLimiter // By route and key
+}
+
+// NewRouteLimiters creates route limiters enforcing limits
+func NewRouteLimiters(limits []RouteRateLimit) *RouteLimiters {
+	l := &RouteLimiters{limiters: make(map[string]*middleware.RateLimiter)}
+	l.Set(limits)
+	return l
+}
+
+// Set replaces the route limits; limiters of dropped limits are disabled
+func (l *RouteLimiters) Set(limits []RouteRateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	used := make(map[string]bool, len(limits))
+	rules := make([]*routeLimitRule, 0, len(limits))
+	for _, limit := range limits {
+		rule := &routeLimitRule{RouteRateLimit: limit}
+		if first, rest, found := strings.Cut(limit.Route, " "); found {
+			rule.method, rule.path = first, rest
+		} else if strings.HasPrefix(first, "/") {
+			rule.path = first
+		} else {
+			rule.method = first
+		}
+
+		id := limit.Route + "|" + limit.Key
+		limiter, ok := l.limiters[id]
+		if ok {
+			limiter.SetRate(limit.PerMinute)
+		} else {
+			limiter = middleware.NewRateLimiter(limit.PerMinute, time.Minute)
+			l.limiters[id] = limiter
+		}
+		rule.limiter = limiter
+		used[id] = true
+		rules = append(rules, rule)
+	}
+	for id, limiter := range l.limiters {
+		if !used[id] {
+			limiter.SetRate(0)
+		}
+	}
+	l.rules = rules
+}
+
+// match returns the most specific rule covering a request with method to the
+// route registered as pattern, or nil
+func (l *RouteLimiters) match(method, pattern string) *routeLimitRule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var best *routeLimitRule
+	for _, rule := range l.rules {
+		if rule.matches(method, pattern) && (best == nil || rule.specificity() > best.specificity()) {
+			best = rule
+		}
+	}
+	return best
+}
+
+// PerRouteRateLimit holds /api requests to the most specific route limit
+// that covers their route, resolved through mux. Install it after
+// authentication, so limits counted per principal are per user or key.
+func PerRouteRateLimit(limiters *RouteLimiters, tiers *RateLimitTiers, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			_, pattern := mux.Handler(r)
+			rule := limiters.match(r.Method, pattern)
+			if rule == nil || rule.allow(r, tiers) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			emitSecurityEvent(r, security.Event{
+				Type:    security.EventRateLimited,
+				Outcome: security.OutcomeDenied,
+				Reason:  "route_rate_limit",
+				Details: map[string]string{"route": rule.Route},
+			})
+			w.Header().Set("Retry-After", "60")
+			w.Header().Set("X-RateLimit-Route", rule.Route)
+			middleware.WriteError(w, http.StatusTooManyRequests,
+				"RATE_LIMIT_EXCEEDED", "Too many requests to "+rule.Route+", please try again later")
+		})
+	}
+}
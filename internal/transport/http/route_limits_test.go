@@ -0,0 +1,249 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+)
+
+// limitedRoutes registers a few /api routes answering 204, and a health
+// check outside /api
+func limitedRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	noContent := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	for _, pattern := range []string{
+		"GET /api/orders", "POST /api/orders", "GET /api/orders/{id}", "PUT /api/orders/{id}",
+		"GET /api/users/{id}", "POST /api/users", "POST /api/auth/login", "GET /api/admin/stats",
+		"GET /health",
+	} {
+		mux.HandleFunc(pattern, noContent)
+	}
+	return mux
+}
+
+// limitRequest builds a request from ip, authenticated as user unless empty
+func limitRequest(method, target, user, ip string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	r.RemoteAddr = ip + ":40000"
+	if user != "" {
+		r = r.WithContext(context.WithValue(r.Context(), ClaimsKey, &auth.Claims{Subject: user}))
+	}
+	return r
+}
+
+// routeLimited serves limitedRoutes behind PerRouteRateLimit without tiers
+func routeLimited(limits ...RouteRateLimit) (http.Handler, *RouteLimiters) {
+	mux := limitedRoutes()
+	limiters := NewRouteLimiters(limits)
+	return PerRouteRateLimit(limiters, nil, mux)(mux), limiters
+}
+
+func TestRouteLimitMatch(t *testing.T) {
+	limiters := NewRouteLimiters([]RouteRateLimit{
+		{Route: "GET", PerMinute: 100},
+		{Route: "/api/orders/{id}", PerMinute: 10},
+		{Route: "GET /api/orders/{id}", PerMinute: 1},
+		{Route: "POST /api/orders", PerMinute: 5},
+	})
+
+	tests := []struct {
+		method  string
+		pattern string
+		want    string // "" for no rule
+	}{
+		{"GET", "GET /api/orders/{id}", "GET /api/orders/{id}"},
+		// GET routes also serve HEAD
+		{"HEAD", "GET /api/orders/{id}", "GET /api/orders/{id}"},
+		{"PUT", "PUT /api/orders/{id}", "/api/orders/{id}"},
+		{"GET", "GET /api/users/{id}", "GET"},
+		{"POST", "POST /api/orders", "POST /api/orders"},
+		{"POST", "POST /api/users", ""},
+		// Patterns registered without a method match on the request's
+		{"DELETE", "/api/orders/{id}", "/api/orders/{id}"},
+		{"GET", "/api/orders/{id}", "GET /api/orders/{id}"},
+		// The path must match the pattern exactly, not as a prefix
+		{"GET", "GET /api/orders/{id}/items", "GET"},
+		{"GET", "", "GET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.pattern, func(t *testing.T) {
+			got := ""
+			if rule := limiters.match(tt.method, tt.pattern); rule != nil {
+				got = rule.Route
+			}
+			if got != tt.want {
+				t.Errorf("match = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerRouteRateLimitMostSpecificWins(t *testing.T) {
+	h, _ := routeLimited(
+		RouteRateLimit{Route: "GET", PerMinute: 100},
+		RouteRateLimit{Route: "GET /api/orders/{id}", PerMinute: 1},
+	)
+
+	// The route's own limit applies rather than the looser one for GET
+	if rec := serve(h, limitRequest(http.MethodGet, "/api/orders/o1", "u1", "10.0.0.1")); rec.Code != http.StatusNoContent {
+		t.Fatalf("first GET = %d, want 204", rec.Code)
+	}
+	rec := serve(h, limitRequest(http.MethodGet, "/api/orders/o2", "u1", "10.0.0.1"))
+	if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "RATE_LIMIT_EXCEEDED" {
+		t.Fatalf("second GET = %d %q, want 429 RATE_LIMIT_EXCEEDED", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("X-RateLimit-Route"); got != "GET /api/orders/{id}" {
+		t.Errorf("X-RateLimit-Route = %q, want the route's limit", got)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+
+	// Other GET routes count against the method's limit
+	if rec := serve(h, limitRequest(http.MethodGet, "/api/users/u1", "u1", "10.0.0.1")); rec.Code != http.StatusNoContent {
+		t.Errorf("GET user = %d, want 204", rec.Code)
+	}
+}
+
+func TestPerRouteRateLimitIndependentBudgets(t *testing.T) {
+	h, _ := routeLimited(
+		RouteRateLimit{Route: "POST /api/orders", PerMinute: 2},
+		RouteRateLimit{Route: "GET /api/orders", PerMinute: 3},
+	)
+	send := func(method, target string) int {
+		return serve(h, limitRequest(method, target, "u1", "10.0.0.1")).Code
+	}
+
+	for i := range 2 {
+		if code := send(http.MethodPost, "/api/orders"); code != http.StatusNoContent {
+			t.Fatalf("POST %d = %d, want 204", i+1, code)
+		}
+	}
+	if code := send(http.MethodPost, "/api/orders"); code != http.StatusTooManyRequests {
+		t.Fatalf("POST over the limit = %d, want 429", code)
+	}
+
+	// Using up one route's budget leaves the other's whole
+	for i := range 3 {
+		if code := send(http.MethodGet, "/api/orders"); code != http.StatusNoContent {
+			t.Fatalf("GET %d after POSTs ran out = %d, want 204", i+1, code)
+		}
+	}
+	if code := send(http.MethodGet, "/api/orders"); code != http.StatusTooManyRequests {
+		t.Errorf("GET over the limit = %d, want 429", code)
+	}
+
+	// Routes without a limit, and routes outside /api, are not held
+	for _, target := range []string{"/api/orders/o1", "/health"} {
+		for range 5 {
+			if code := send(http.MethodGet, target); code != http.StatusNoContent {
+				t.Fatalf("GET %s = %d, want 204", target, code)
+			}
+		}
+	}
+}
+
+func TestPerRouteRateLimitKeys(t *testing.T) {
+	// Each request is a user (empty for anonymous), a client IP and an
+	// X-Tenant header, under a limit of one request
+	type step struct {
+		user, ip, tenant string
+		want             int
+	}
+	tests := []struct {
+		name  string
+		key   string
+		steps []step
+	}{
+		{"principal by default", "", []step{
+			{"u1", "10.0.0.1", "", http.StatusNoContent},
+			{"u1", "10.0.0.2", "", http.StatusTooManyRequests},
+			{"u2", "10.0.0.1", "", http.StatusNoContent},
+		}},
+		{"principal falls back to the IP", RouteLimitKeyPrincipal, []step{
+			{"", "10.0.0.1", "", http.StatusNoContent},
+			{"", "10.0.0.1", "", http.StatusTooManyRequests},
+			{"", "10.0.0.2", "", http.StatusNoContent},
+			{"u1", "10.0.0.1", "", http.StatusNoContent},
+		}},
+		{"IP", RouteLimitKeyIP, []step{
+			{"u1", "10.0.0.1", "", http.StatusNoContent},
+			{"u2", "10.0.0.1", "", http.StatusTooManyRequests},
+			{"u1", "10.0.0.2", "", http.StatusNoContent},
+		}},
+		{"global", RouteLimitKeyGlobal, []step{
+			{"u1", "10.0.0.1", "", http.StatusNoContent},
+			{"u2", "10.0.0.2", "", http.StatusTooManyRequests},
+			{"", "10.0.0.3", "", http.StatusTooManyRequests},
+		}},
+		{"header", "header:X-Tenant", []step{
+			{"u1", "10.0.0.1", "acme", http.StatusNoContent},
+			{"u2", "10.0.0.2", "acme", http.StatusTooManyRequests},
+			{"u1", "10.0.0.1", "globex", http.StatusNoContent},
+			// Without the header the request counts against its principal
+			{"u1", "10.0.0.1", "", http.StatusNoContent},
+			{"u1", "10.0.0.2", "", http.StatusTooManyRequests},
+			{"u2", "10.0.0.1", "", http.StatusNoContent},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := routeLimited(RouteRateLimit{Route: "POST /api/orders", PerMinute: 1, Key: tt.key})
+			for i, s := range tt.steps {
+				r := limitRequest(http.MethodPost, "/api/orders", s.user, s.ip)
+				if s.tenant != "" {
+					r.Header.Set("X-Tenant", s.tenant)
+				}
+				if code := serve(h, r).Code; code != s.want {
+					t.Errorf("request %d (%+v) = %d, want %d", i+1, s, code, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPerRouteRateLimitReload(t *testing.T) {
+	limits := []RouteRateLimit{{Route: "POST /api/orders", PerMinute: 1}}
+	h, limiters := routeLimited(limits...)
+	send := func(method, target string) int {
+		return serve(h, limitRequest(method, target, "u1", "10.0.0.1")).Code
+	}
+
+	send(http.MethodPost, "/api/orders")
+	if code := send(http.MethodPost, "/api/orders"); code != http.StatusTooManyRequests {
+		t.Fatalf("POST over the limit = %d, want 429", code)
+	}
+
+	// Reloading the same limits does not hand out a fresh budget
+	limiters.Set([]RouteRateLimit{{Route: "POST /api/orders", PerMinute: 1}})
+	if code := send(http.MethodPost, "/api/orders"); code != http.StatusTooManyRequests {
+		t.Errorf("POST after reloading the same limit = %d, want 429", code)
+	}
+
+	// A limit counted per another key has a budget of its own
+	limiters.Set([]RouteRateLimit{{Route: "POST /api/orders", PerMinute: 1, Key: RouteLimitKeyIP}})
+	if code := send(http.MethodPost, "/api/orders"); code != http.StatusNoContent {
+		t.Errorf("POST after changing the key = %d, want 204", code)
+	}
+
+	// Dropped limits stop applying, and new ones start
+	limiters.Set([]RouteRateLimit{{Route: "GET /api/orders", PerMinute: 1}})
+	for range 3 {
+		if code := send(http.MethodPost, "/api/orders"); code != http.StatusNoContent {
+			t.Fatalf("POST after its limit was dropped = %d, want 204", code)
+		}
+	}
+	send(http.MethodGet, "/api/orders")
+	if code := send(http.MethodGet, "/api/orders"); code != http.StatusTooManyRequests {
+		t.Errorf("GET over a limit added by a reload = %d, want 429", code)
+	}
+
+	// A limit restored within the window resumes its count
+	limiters.Set(limits)
+	if code := send(http.MethodPost, "/api/orders"); code != http.StatusTooManyRequests {
+		t.Errorf("POST after its limit was restored = %d, want 429", code)
+	}
+}
@@ -1,20 +1,113 @@
 package http
 
 import (
+	"cmp"
 	"net/http"
+	"net/netip"
 	"time"
 
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/internal/alerting"
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/chaos"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/featureflag"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
 )
 
 // RouterConfig holds configuration for the HTTP router
 type RouterConfig struct {
-	Logger             *logger.Logger
-	EnableCORS         bool
-	AllowedOrigins     []string
+	Logger         *logger.Logger
+	EnableCORS     bool
+	AllowedOrigins []string
+	// CORSAllowCredentials and CORSMaxAge (0 for 24h) complete the default CORS
+	// policy; CORSRoutes overrides it per route group
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	CORSRoutes           []CORSRoute
+	// TrustedProxies are the only peers whose X-Forwarded-For is believed
+	// when resolving client IPs (empty ignores the header)
+	TrustedProxies     []netip.Prefix
 	RateLimitPerMinute int
-	RequestTimeout     time.Duration
-	MaxBodySize        int64 // in bytes
+	// RouteClassLimits are per-principal limits per minute for each route
+	// class (see ClassifyRoute); missing or 0 leaves a class unlimited
+	RouteClassLimits map[string]int
+	// RouteLimits are per-minute limits for groups of routes, each counted by
+	// its own key; a request is held to the most specific one that matches
+	RouteLimits []RouteRateLimit
+	// RateLimitTiers scales the class and per-principal route limits of
+	// chosen users and access tokens
+	RateLimitTiers RateLimitTierPolicy
+	// RequestTimeout bounds how long a request may run (0 is unlimited);
+	// RouteTimeouts overrides it per route group
+	RequestTimeout time.Duration
+	RouteTimeouts  map[string]time.Duration
+	// Concurrency caps the /api requests in flight, globally and per route
+	// (zero limits disable)
+	Concurrency ConcurrencyConfig
+	MaxBodySize int64 // in bytes
+	// MaxDecompressedBodySize bounds gzip request bodies once decompressed
+	// (0 for MaxBodySize)
+	MaxDecompressedBodySize int64
+	// UploadChunkSize bounds the chunks of resumable uploads instead of
+	// MaxBodySize (0 for MaxBodySize)
+	UploadChunkSize int64
+
+	// Response size budgets (0 disables)
+	ResponseWarnBytes      int64
+	ResponseMaxBytes       int64
+	TruncateLargeResponses bool
+
+	// Tokens enables bearer token authentication on all non-public routes (nil disables)
+	Tokens *auth.TokenManager
+	// SessionCookie accepts tokens from this cookie and enables CSRF protection (empty disables)
+	SessionCookie string
+	CSRFCookie    string
+	CSRFHeader    string
+	// AccessTokens accepts personal access tokens alongside JWTs (nil disables)
+	AccessTokens *usecase.AccessTokenService
+	// Revocations is the token denylist consulted by authentication (nil disables)
+	Revocations domain.TokenRevocationStore
+	// Ready reports readiness on /ready (nil means always ready); it flips to
+	// false during shutdown so load balancers stop routing before the drain
+	Ready func() bool
+	// BruteForce locks out clients presenting repeated invalid tokens (nil disables)
+	BruteForce *usecase.BruteForceGuard
+	// SecurityEvents receives auth failures, denials, rate-limit trips and
+	// admin actions, separately from application logs (nil disables)
+	SecurityEvents security.Emitter
+	// FeatureFlags are evaluated for the authenticated user of each request (nil disables)
+	FeatureFlags *featureflag.Client
+	// InFlight tracks requests being served, for diagnostics dumps (nil disables)
+	InFlight *middleware.InFlight
+	// RequestStats receives the duration and outcome of every /api request,
+	// for the public status page (nil disables)
+	RequestStats RequestObserver
+	// RouteStats receives the duration and outcome of every /api request by
+	// route pattern, for SLO burn rates (nil disables)
+	RouteStats RouteObserver
+	// Alerts counts the server errors and panics of /api requests, paging
+	// operators past its thresholds (nil disables)
+	Alerts *alerting.Monitor
+	// Chaos injects faults for resilience testing, never in production (nil disables)
+	Chaos *chaos.Injector
+	// ChaosHeaders lets requests pick their own faults (see FaultInjection)
+	ChaosHeaders bool
+	// APIVersions configures version negotiation and the retirement of old versions
+	APIVersions APIVersionPolicy
+	// Idempotency stores the responses of POST requests sent with an
+	// Idempotency-Key, replayed for IdempotencyTTL (nil disables)
+	Idempotency    domain.IdempotencyStore
+	IdempotencyTTL time.Duration
+	// ResponseCache stores whole GET responses of the routes in
+	// ResponseCacheRoutes for their TTL (nil disables)
+	ResponseCache       domain.ResponseCache
+	ResponseCacheRoutes map[string]time.Duration
+	// SeparateAdmin moves /api/admin/ routes off the public handler onto
+	// Router.Admin, which also serves /metrics and /debug/pprof/. Requires Tokens.
+	SeparateAdmin bool
 }
 
 // DefaultRouterConfig returns sensible defaults
@@ -26,66 +119,366 @@ func DefaultRouterConfig(logg *logger.Logger) RouterConfig {
 		RateLimitPerMinute: 100,
 		RequestTimeout:     30 * time.Second,
 		MaxBodySize:        1 << 20, // 1 MB
+		ResponseWarnBytes:  1 << 20, // 1 MB
+		ResponseMaxBytes:   5 << 20, // 5 MB
+	}
+}
+
+// Router is the application's HTTP handler with the middleware stack applied
+// It exposes setters for settings that may be tuned at runtime (see config.Store)
+type Router struct {
+	http.Handler
+	// Admin serves the internal listener when RouterConfig.SeparateAdmin is set (nil otherwise)
+	Admin http.Handler
+
+	limiter      *middleware.RateLimiter
+	classLimiter routeClassLimiters
+	routeLimiter *RouteLimiters
+	tiers        *RateLimitTiers
+	cors         *middleware.CORSPolicy
+}
+
+// SetRateLimit changes the per-client rate limit (0 disables limiting)
+func (rt *Router) SetRateLimit(perMinute int) {
+	rt.limiter.SetRate(perMinute)
+}
+
+// SetRouteClassLimits changes the per-principal limits for each route class
+// (missing or 0 leaves a class unlimited)
+func (rt *Router) SetRouteClassLimits(perMinute map[string]int) {
+	rt.classLimiter.setRates(perMinute)
+}
+
+// SetRouteLimits replaces the per-route limits
+func (rt *Router) SetRouteLimits(limits []RouteRateLimit) {
+	rt.routeLimiter.Set(limits)
+}
+
+// SetRateLimitTiers replaces the rate limit tiers
+func (rt *Router) SetRateLimitTiers(policy RateLimitTierPolicy) {
+	rt.tiers.Set(policy)
+}
+
+// SetAllowedOrigins changes the origins of the default CORS policy; route
+// group policies keep theirs (no-op if CORS is disabled)
+func (rt *Router) SetAllowedOrigins(origins []string) {
+	if rt.cors != nil {
+		rt.cors.SetAllowedOrigins(origins)
 	}
 }
 
+// Handlers are the handlers whose routes NewRouter registers. User and
+// Order are required; the routes of a nil handler are left out.
+type Handlers struct {
+	User            *UserHandler
+	Order           *OrderHandler
+	Session         *SessionHandler
+	AccessToken     *AccessTokenHandler
+	Job             *JobHandler
+	Report          *ReportHandler
+	TemplatePreview *TemplatePreviewHandler
+	Attachment      *AttachmentHandler
+	Upload          *UploadHandler
+	Feature         *FeatureHandler
+	Diagnostics     *DiagnosticsHandler
+	Status          *StatusHandler
+	GraphQL         *GraphQLHandler
+	OrderStream     *OrderStreamHandler
+	Event           *EventHandler
+	SLO             *SLOHandler
+	UserData        *UserDataHandler
+	Notification    *NotificationHandler
+	Checkout        *CheckoutHandler
+	DeadLetter      *DeadLetterHandler
+}
+
 // NewRouter creates a new HTTP router with middleware stack applied
-func NewRouter(config RouterConfig, userHandler *UserHandler, orderHandler *OrderHandler) http.Handler {
+func NewRouter(config RouterConfig, handlers Handlers) *Router {
+	router := &Router{}
+
 	mux := http.NewServeMux()
 
-	// Register routes
-	registerRoutes(mux, userHandler, orderHandler)
+	// Register routes; every route enforces personal access token restrictions
+	var routes routeRegistrar = accessTokenMux{mux}
+	var adminMux *http.ServeMux
+	if config.SeparateAdmin {
+		adminMux = http.NewServeMux()
+		routes = adminRouteSplitter{public: routes, admin: adminMux}
+		registerHealthRoutes(adminMux, config.Ready)
+		registerInternalRoutes(adminMux, handlers.Diagnostics)
+	}
+	// Responses are encoded in the shape of the negotiated API version and in
+	// the negotiated format
+	routes = negotiatedRoutes{routes}
+
+	// Route groups, each with its own middleware (see group.go): health
+	// checks, the status page and template previews take no request bodies;
+	// streams get nothing that buffers or replays a response; API routes
+	// validate request bodies and honour idempotency keys; admin routes also
+	// require the admin scope; resumable uploads take raw chunks that are not
+	// replayed
+	system := newGroup(routes)
+	streams := newGroup(routes)
+	uploads := newGroup(routes)
+	bodies := []Middleware{
+		// Request bodies in any format a codec reads, and JSON merge patches
+		middleware.ContentType(append(codecMediaTypes(), MergePatchContentType)...),
+	}
+	if config.Idempotency != nil {
+		// Innermost, so only requests that passed every check reserve their key
+		bodies = append(bodies, Idempotency(IdempotencyConfig{
+			Store:   config.Idempotency,
+			TTL:     config.IdempotencyTTL,
+			LockTTL: config.RequestTimeout + time.Minute,
+			Logger:  config.Logger,
+		}))
+	}
+	api := newGroup(routes, bodies...)
+	admin := newGroup(routes, RequireScope(auth.ScopeAdmin)).With(bodies...)
+	// Handlers register their routes once; /api/admin/ routes join the admin group
+	apiRoutes := adminRouteSplitter{public: api, admin: admin}
+
+	registerHealthRoutes(system, config.Ready)
+	if handlers.Status != nil {
+		registerStatusRoutes(system, handlers.Status)
+	}
+	if handlers.TemplatePreview != nil {
+		registerTemplatePreviewRoutes(system, handlers.TemplatePreview)
+	}
+	if handlers.OrderStream != nil {
+		registerOrderStreamRoutes(streams, handlers.OrderStream)
+	}
+	if handlers.Event != nil {
+		registerEventRoutes(streams, handlers.Event)
+	}
+	if handlers.Upload != nil {
+		registerUploadRoutes(uploads, handlers.Upload)
+	}
+
+	registerRoutes(apiRoutes, handlers.User, handlers.Order)
+	if handlers.Session != nil {
+		registerSessionRoutes(apiRoutes, handlers.Session)
+	}
+	if handlers.AccessToken != nil {
+		registerAccessTokenRoutes(apiRoutes, handlers.AccessToken)
+	}
+	if handlers.Job != nil {
+		registerJobRoutes(apiRoutes, handlers.Job)
+	}
+	if handlers.Report != nil {
+		registerReportRoutes(apiRoutes, handlers.Report)
+	}
+	if handlers.Attachment != nil {
+		registerAttachmentRoutes(apiRoutes, handlers.Attachment)
+	}
+	if handlers.Feature != nil {
+		registerFeatureRoutes(apiRoutes, handlers.Feature)
+	}
+	if handlers.Diagnostics != nil {
+		registerDiagnosticsRoutes(apiRoutes, handlers.Diagnostics)
+	}
+	if handlers.GraphQL != nil {
+		registerGraphQLRoutes(apiRoutes, handlers.GraphQL)
+	}
+	if handlers.SLO != nil {
+		registerSLORoutes(apiRoutes, handlers.SLO)
+	}
+	if handlers.UserData != nil {
+		registerUserDataRoutes(apiRoutes, handlers.UserData)
+	}
+	if handlers.Notification != nil {
+		registerNotificationRoutes(apiRoutes, handlers.Notification)
+	}
+	if handlers.Checkout != nil {
+		registerCheckoutRoutes(apiRoutes, handlers.Checkout)
+	}
+	if handlers.DeadLetter != nil {
+		registerDeadLetterRoutes(apiRoutes, handlers.DeadLetter)
+	}
+	// Operations go through the whole router, middleware included
+	registerBatchRoutes(apiRoutes, newBatchHandler(router, mux))
 
 	// Build middleware stack (order matters - first applied is outermost)
 	middlewares := []Middleware{
 		// Outermost: Request ID for tracing
-		RequestID(),
+		middleware.RequestID(),
+		// Client IP for rate limits, lockouts and security events
+		middleware.ForwardedFor(config.TrustedProxies),
+		// Version negotiation; everything after sees unversioned paths
+		APIVersioning(config.APIVersions),
+	}
+	if config.InFlight != nil {
+		// Ahead of everything else, so a request stuck anywhere is counted
+		middlewares = append(middlewares, config.InFlight.Middleware())
+	}
+	if config.RequestStats != nil {
+		// Outside recovery, so panics are counted as the 500s they become
+		middlewares = append(middlewares, RecordRequestStats(config.RequestStats))
+	}
+	if config.RouteStats != nil {
+		middlewares = append(middlewares, RecordRouteStats(config.RouteStats, func(r *http.Request) string {
+			_, pattern := mux.Handler(r)
+			return pattern
+		}))
+	}
+	middlewares = append(middlewares,
+		// Security event stream (tagged with the request ID)
+		SecurityEvents(config.SecurityEvents),
 		// Recovery from panics
-		Recover(config.Logger),
+		middleware.Recover(config.Logger),
+	)
+	if config.Alerts != nil {
+		// Inside recovery, to see the panic values before Recover swallows them
+		middlewares = append(middlewares, ReportAlerts(config.Alerts, func(r *http.Request) string {
+			_, pattern := mux.Handler(r)
+			return pattern
+		}))
+	}
+	middlewares = append(middlewares,
 		// Request logging
-		Logging(config.Logger),
+		middleware.Logging(config.Logger),
 		// Security headers
-		SecureHeaders(),
-		// Request body size limit
-		MaxBodySize(config.MaxBodySize),
-	}
+		middleware.SecureHeaders(),
+		// Client certificate identity (no-op without verified mTLS)
+		ClientCertIdentity(),
+		// Request body size limit, then the limit once gzip bodies are decompressed
+		middleware.MaxBodySizeFunc(func(r *http.Request) int64 {
+			if _, pattern := mux.Handler(r); pattern == uploadChunkRoute && config.UploadChunkSize > 0 {
+				return config.UploadChunkSize
+			}
+			return config.MaxBodySize
+		}),
+		middleware.DecompressBody(cmp.Or(config.MaxDecompressedBodySize, config.MaxBodySize)),
+		// Response size budgets
+		ResponseBudget(ResponseBudgetConfig{
+			Logger:        config.Logger,
+			WarnBytes:     config.ResponseWarnBytes,
+			MaxBytes:      config.ResponseMaxBytes,
+			TruncateLists: config.TruncateLargeResponses,
+		}),
+	)
 
 	// Conditional middlewares
 	if config.EnableCORS {
-		corsConfig := DefaultCORSConfig()
+		corsConfig := middleware.DefaultCORSConfig()
 		corsConfig.AllowedOrigins = config.AllowedOrigins
-		middlewares = append(middlewares, CORS(corsConfig))
+		corsConfig.AllowedHeaders = append(corsConfig.AllowedHeaders, DefaultCSRFHeaderName, "If-Match", IdempotencyKeyHeader, "Content-Encoding")
+		corsConfig.ExposedHeaders = append(corsConfig.ExposedHeaders, APIVersionHeader, "Deprecation", "Sunset", "Link", "ETag", IdempotentReplayedHeader, CacheStatusHeader)
+		if handlers.Upload != nil {
+			corsConfig.AllowedHeaders = append(corsConfig.AllowedHeaders, tusRequestHeaders...)
+			corsConfig.ExposedHeaders = append(corsConfig.ExposedHeaders, tusResponseHeaders...)
+		}
+		if config.SessionCookie != "" && config.CSRFHeader != "" && config.CSRFHeader != DefaultCSRFHeaderName {
+			corsConfig.AllowedHeaders = append(corsConfig.AllowedHeaders, config.CSRFHeader)
+		}
+		if config.Chaos != nil && config.ChaosHeaders {
+			corsConfig.AllowedHeaders = append(corsConfig.AllowedHeaders, ChaosErrorHeader, ChaosLatencyHeader)
+		}
+		corsConfig.AllowCredentials = config.CORSAllowCredentials
+		if config.CORSMaxAge > 0 {
+			corsConfig.MaxAge = int(config.CORSMaxAge.Seconds())
+		}
+		router.cors = middleware.NewCORSPolicy(corsConfig)
+		middlewares = append(middlewares, routeCORS(router.cors, corsRoutePolicies(corsConfig, config.CORSRoutes), mux))
 	}
 
-	if config.RateLimitPerMinute > 0 {
-		limiter := NewRateLimiter(config.RateLimitPerMinute, time.Minute)
-		middlewares = append(middlewares, RateLimit(limiter))
+	if config.Chaos != nil {
+		// After CORS, so browser clients can read injected failures
+		middlewares = append(middlewares, FaultInjection(config.Chaos, config.ChaosHeaders))
 	}
 
-	// Content-Type validation for API routes
-	middlewares = append(middlewares, ContentType("application/json"))
+	// Always installed so the limit can be enabled at runtime; a rate of 0 lets everything through
+	router.limiter = middleware.NewRateLimiter(config.RateLimitPerMinute, time.Minute)
+	middlewares = append(middlewares, middleware.RateLimit(router.limiter, middleware.OnRateLimited(emitRateLimited)))
 
-	// Apply middleware chain
-	return Chain(mux, middlewares...)
-}
+	// OPTIONS and wrong methods are answered from the route table without
+	// authentication; HEAD is served by GET routes without their bodies
+	middlewares = append(middlewares, RouteMethods(mux))
 
-// registerRoutes sets up all API routes on the mux
-func registerRoutes(mux *http.ServeMux, userHandler *UserHandler, orderHandler *OrderHandler) {
-	// Health check (no auth required)
-	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		respondJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
-	})
+	// After the rate limit, so rejected requests never hold a slot, and ahead
+	// of authentication, which may query the database itself
+	concurrency := config.Concurrency
+	if concurrency.Logger == nil {
+		concurrency.Logger = config.Logger
+	}
+	middlewares = append(middlewares, ConcurrencyLimit(concurrency, mux))
 
-	// Readiness check
-	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
-		respondJSON(w, http.StatusOK, map[string]string{"status": "ready"})
-	})
+	// Once a slot is held, so queueing does not eat into the deadline; the
+	// headers set so far survive a timeout response
+	middlewares = append(middlewares, RequestTimeout(RequestTimeoutConfig{
+		Default: config.RequestTimeout,
+		Routes:  config.RouteTimeouts,
+	}, mux))
+
+	if config.Tokens != nil {
+		middlewares = append(middlewares, Authenticate(AuthenticateConfig{
+			Tokens:        config.Tokens,
+			SessionCookie: config.SessionCookie,
+			AccessTokens:  config.AccessTokens,
+			Revocations:   config.Revocations,
+			Guard:         config.BruteForce,
+			Logger:        config.Logger,
+		}))
+		if config.SessionCookie != "" {
+			// After authentication, which records whether the session cookie was used
+			middlewares = append(middlewares, CSRF(CSRFConfig{
+				SessionCookie: config.SessionCookie,
+				CookieName:    config.CSRFCookie,
+				HeaderName:    config.CSRFHeader,
+			}))
+		}
+	}
+
+	// After authentication, so budgets are per user rather than per IP; always
+	// installed so class limits can be enabled at runtime
+	router.tiers = NewRateLimitTiers(config.RateLimitTiers)
+	router.classLimiter = newRouteClassLimiters(config.RouteClassLimits)
+	middlewares = append(middlewares, RouteClassRateLimit(router.classLimiter, router.tiers))
+	router.routeLimiter = NewRouteLimiters(config.RouteLimits)
+	middlewares = append(middlewares, PerRouteRateLimit(router.routeLimiter, router.tiers, mux))
 
+	if config.FeatureFlags != nil {
+		// After authentication, so flags are evaluated for the verified subject
+		middlewares = append(middlewares, featureflag.Middleware(func(r *http.Request) string {
+			if claims := GetClaims(r.Context()); claims != nil {
+				return claims.Subject
+			}
+			return ""
+		}))
+	}
+
+	if config.ResponseCache != nil {
+		// After authentication and rate limits, so cached responses are per
+		// verified principal and still count against the caller's budget
+		middlewares = append(middlewares, ResponseCache(ResponseCacheConfig{
+			Cache:  config.ResponseCache,
+			Routes: config.ResponseCacheRoutes,
+			Logger: config.Logger,
+		}, mux))
+	}
+
+	// Apply middleware chain; route groups run inside it
+	router.Handler = middleware.Chain(mux, middlewares...)
+	if adminMux != nil {
+		router.Admin = newAdminHandler(config, adminMux)
+	}
+	return router
+}
+
+// routeRegistrar is implemented by *http.ServeMux, accessTokenMux, adminRouteSplitter and group
+type routeRegistrar interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// registerRoutes sets up the user and order API routes on the mux
+func registerRoutes(mux routeRegistrar, userHandler *UserHandler, orderHandler *OrderHandler) {
 	// User routes
 	mux.HandleFunc("POST /api/users", userHandler.Create)
+	mux.HandleFunc("POST /api/users/bulk", userHandler.BulkCreate)
 	mux.HandleFunc("GET /api/users", userHandler.List)
 	mux.HandleFunc("GET /api/users/{id}", userHandler.GetByID)
 	mux.HandleFunc("PUT /api/users/{id}", userHandler.Update)
+	mux.HandleFunc("PATCH /api/users/{id}", userHandler.Patch)
 	mux.HandleFunc("DELETE /api/users/{id}", userHandler.Delete)
 
 	// User's orders route
@@ -95,6 +488,8 @@ func registerRoutes(mux *http.ServeMux, userHandler *UserHandler, orderHandler *
 	mux.HandleFunc("POST /api/orders", orderHandler.Create)
 	mux.HandleFunc("GET /api/orders", orderHandler.List)
 	mux.HandleFunc("GET /api/orders/{id}", orderHandler.GetByID)
+	mux.HandleFunc("PATCH /api/orders/{id}", orderHandler.Patch)
+	mux.HandleFunc("GET /api/orders/{id}/invoice", orderHandler.Invoice)
 
 	// Order status transition routes
 	mux.HandleFunc("POST /api/orders/{id}/confirm", orderHandler.Confirm)
@@ -103,8 +498,165 @@ func registerRoutes(mux *http.ServeMux, userHandler *UserHandler, orderHandler *
 	mux.HandleFunc("POST /api/orders/{id}/cancel", orderHandler.Cancel)
 }
 
+// registerHealthRoutes sets up the liveness and readiness checks (no auth required)
+func registerHealthRoutes(mux routeRegistrar, ready func() bool) {
+	// Health check
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+	})
+
+	// Readiness check
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
+		if ready != nil && !ready() {
+			respondJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready"})
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	})
+}
+
+// registerSessionRoutes sets up logout and token revocation routes
+func registerSessionRoutes(mux routeRegistrar, sessionHandler *SessionHandler) {
+	if sessionHandler.cookies.Session != "" {
+		mux.HandleFunc("POST /api/auth/session", sessionHandler.CreateSession)
+	}
+	mux.HandleFunc("POST /api/auth/logout", sessionHandler.Logout)
+	mux.HandleFunc("POST /api/auth/logout-all", sessionHandler.LogoutAll)
+
+	// Admin: compromise response (requires the admin scope)
+	mux.HandleFunc("POST /api/admin/users/{id}/revoke-tokens", sessionHandler.RevokeUserTokens)
+}
+
+// registerAccessTokenRoutes sets up personal access token management routes
+func registerAccessTokenRoutes(mux routeRegistrar, accessTokenHandler *AccessTokenHandler) {
+	mux.HandleFunc("POST /api/users/{id}/tokens", accessTokenHandler.Create)
+	mux.HandleFunc("GET /api/users/{id}/tokens", accessTokenHandler.List)
+	mux.HandleFunc("DELETE /api/users/{id}/tokens/{token_id}", accessTokenHandler.Revoke)
+}
+
+// registerJobRoutes sets up background job status routes
+func registerJobRoutes(mux routeRegistrar, jobHandler *JobHandler) {
+	mux.HandleFunc("GET /api/jobs/{id}", jobHandler.GetByID)
+}
+
+// registerReportRoutes sets up scheduled report administration (requires the admin scope)
+func registerReportRoutes(mux routeRegistrar, reportHandler *ReportHandler) {
+	mux.HandleFunc("POST /api/admin/reports", reportHandler.Create)
+	mux.HandleFunc("GET /api/admin/reports", reportHandler.List)
+	mux.HandleFunc("DELETE /api/admin/reports/{id}", reportHandler.Delete)
+}
+
+// registerAttachmentRoutes sets up attachment upload, metadata and download routes
+func registerAttachmentRoutes(mux routeRegistrar, attachmentHandler *AttachmentHandler) {
+	mux.HandleFunc("POST /api/attachments", attachmentHandler.Create)
+	mux.HandleFunc("GET /api/attachments", attachmentHandler.List)
+	mux.HandleFunc("GET /api/attachments/{id}", attachmentHandler.GetByID)
+	mux.HandleFunc("GET /api/attachments/{id}/download", attachmentHandler.Download)
+
+	// Upload completion callback; the blob key is URL-encoded into one segment
+	mux.HandleFunc("POST /api/blobs/{key}/complete", attachmentHandler.CompleteUpload)
+
+	// Malware scanner callback (requires the admin scope)
+	mux.HandleFunc("POST /api/admin/attachments/{id}/scan-result", attachmentHandler.RecordScanResult)
+}
+
+// uploadChunkRoute receives the chunks of resumable uploads, held to
+// RouterConfig.UploadChunkSize
+const uploadChunkRoute = "PATCH /api/uploads/{id}"
+
+// registerUploadRoutes sets up tus resumable attachment uploads
+func registerUploadRoutes(mux routeRegistrar, uploadHandler *UploadHandler) {
+	mux.HandleFunc("OPTIONS /api/uploads", uploadHandler.Options)
+	mux.HandleFunc("POST /api/uploads", uploadHandler.Create)
+	mux.HandleFunc("HEAD /api/uploads/{id}", uploadHandler.Head)
+	mux.HandleFunc(uploadChunkRoute, uploadHandler.Append)
+	mux.HandleFunc("DELETE /api/uploads/{id}", uploadHandler.Terminate)
+}
+
+// registerFeatureRoutes sets up the caller's feature flag states
+func registerFeatureRoutes(mux routeRegistrar, featureHandler *FeatureHandler) {
+	mux.HandleFunc("GET /api/features", featureHandler.List)
+}
+
+// registerDiagnosticsRoutes sets up on-demand diagnostics dumps (requires the admin scope)
+func registerDiagnosticsRoutes(mux routeRegistrar, diagnosticsHandler *DiagnosticsHandler) {
+	mux.HandleFunc("POST /api/admin/diagnostics", diagnosticsHandler.Dump)
+}
+
+// registerStatusRoutes sets up the public status page data (no auth required)
+func registerStatusRoutes(mux routeRegistrar, statusHandler *StatusHandler) {
+	mux.HandleFunc("GET /status", statusHandler.Get)
+}
+
+// registerGraphQLRoutes sets up the GraphQL endpoint and its schema
+func registerGraphQLRoutes(mux routeRegistrar, graphqlHandler *GraphQLHandler) {
+	mux.HandleFunc("POST /api/graphql", graphqlHandler.Query)
+	mux.HandleFunc("GET /api/graphql/schema", graphqlHandler.Schema)
+}
+
+// registerOrderStreamRoutes sets up live order status over WebSocket
+func registerOrderStreamRoutes(mux routeRegistrar, orderStreamHandler *OrderStreamHandler) {
+	mux.HandleFunc("GET /api/orders/{id}/ws", orderStreamHandler.Stream)
+}
+
+// registerEventRoutes sets up the Server-Sent Events stream of order events
+func registerEventRoutes(mux routeRegistrar, eventHandler *EventHandler) {
+	mux.HandleFunc("GET /api/events", eventHandler.Stream)
+}
+
+// registerUserDataRoutes sets up background user imports and data exports
+// and the download of their results
+func registerUserDataRoutes(mux routeRegistrar, userDataHandler *UserDataHandler) {
+	mux.HandleFunc("POST /api/users/import", userDataHandler.Import)
+	mux.HandleFunc("POST /api/users/{id}/data-export", userDataHandler.Export)
+	mux.HandleFunc("GET /api/jobs/{id}/result", userDataHandler.Result)
+}
+
+// registerNotificationRoutes sets up notification preferences
+func registerNotificationRoutes(mux routeRegistrar, notificationHandler *NotificationHandler) {
+	mux.HandleFunc("GET /api/users/{id}/notification-preferences", notificationHandler.GetPreferences)
+	mux.HandleFunc("PUT /api/users/{id}/notification-preferences", notificationHandler.UpdatePreferences)
+}
+
+// registerCheckoutRoutes sets up order checkout, and the stock levels it
+// reserves from (admin routes require the admin scope)
+func registerCheckoutRoutes(mux routeRegistrar, checkoutHandler *CheckoutHandler) {
+	mux.HandleFunc("POST /api/orders/{id}/checkout", checkoutHandler.Checkout)
+	mux.HandleFunc("GET /api/orders/{id}/checkout", checkoutHandler.GetCheckout)
+	mux.HandleFunc("GET /api/admin/inventory/{product_id}", checkoutHandler.GetStock)
+	mux.HandleFunc("PUT /api/admin/inventory/{product_id}", checkoutHandler.SetStock)
+}
+
+// registerDeadLetterRoutes sets up the inspection and replay of dead
+// letters (requires the admin scope)
+func registerDeadLetterRoutes(mux routeRegistrar, deadLetterHandler *DeadLetterHandler) {
+	mux.HandleFunc("GET /api/admin/dead-letters", deadLetterHandler.List)
+	mux.HandleFunc("POST /api/admin/dead-letters/replay", deadLetterHandler.ReplayAll)
+	mux.HandleFunc("GET /api/admin/dead-letters/{id}", deadLetterHandler.Get)
+	mux.HandleFunc("DELETE /api/admin/dead-letters/{id}", deadLetterHandler.Delete)
+	mux.HandleFunc("POST /api/admin/dead-letters/{id}/replay", deadLetterHandler.Replay)
+}
+
+// registerBatchRoutes sets up batches of API requests
+func registerBatchRoutes(mux routeRegistrar, batchHandler *BatchHandler) {
+	mux.HandleFunc("POST /api/batch", batchHandler.Run)
+}
+
+// registerSLORoutes sets up SLO burn rates and latency heatmaps (requires the admin scope)
+func registerSLORoutes(mux routeRegistrar, sloHandler *SLOHandler) {
+	mux.HandleFunc("GET /api/admin/slo", sloHandler.List)
+	mux.HandleFunc("GET /api/admin/slo/heatmap", sloHandler.Heatmap)
+}
+
+// registerTemplatePreviewRoutes sets up template previews (development only)
+func registerTemplatePreviewRoutes(mux routeRegistrar, templatePreviewHandler *TemplatePreviewHandler) {
+	mux.HandleFunc("GET /dev/templates", templatePreviewHandler.List)
+	mux.HandleFunc("GET /dev/templates/{name...}", templatePreviewHandler.Preview)
+}
+
 // RegisterRoutes is kept for backwards compatibility
 // Deprecated: Use NewRouter instead
 func RegisterRoutes(mux *http.ServeMux, userHandler *UserHandler, orderHandler *OrderHandler) {
+	registerHealthRoutes(mux, nil)
 	registerRoutes(mux, userHandler, orderHandler)
 }
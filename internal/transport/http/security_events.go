@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Security Event Stream
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// SecurityEventsKey holds the security.Emitter for the request
+const SecurityEventsKey contextKey = "security_events"
+
+// SecurityEvents makes emitter available to every later middleware and
+// handler, so any of them can record a security event for the request
+func SecurityEvents(emitter security.Emitter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), SecurityEventsKey, emitter)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// emitSecurityEvent records event, filling the request ID, method, path and
+// actor from the request. It's a no-op when SecurityEvents isn't installed.
+func emitSecurityEvent(r *http.Request, event security.Event) {
+	emitter, ok := r.Context().Value(SecurityEventsKey).(security.Emitter)
+	if !ok || emitter == nil {
+		return
+	}
+
+	ctx := r.Context()
+	event.RequestID = GetRequestID(ctx)
+	event.Method = r.Method
+	event.Path = r.URL.Path
+	event.Actor.IP = middleware.ClientIP(r)
+	event.Actor.UserAgent = r.UserAgent()
+	if event.Actor.UserID == "" {
+		event.Actor.UserID = GetUserID(ctx)
+	}
+	if pat := GetAccessToken(ctx); pat != nil && event.Actor.TokenID == "" {
+		event.Actor.TokenID = pat.ID
+	}
+
+	emitter.Emit(ctx, event)
+}
+
+// emitAuthFailure records a rejected credential, and a lockout when the
+// failure tripped the brute-force guard
+func emitAuthFailure(r *http.Request, reason string, locked bool) {
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventAuthFailure,
+		Outcome: security.OutcomeFailure,
+		Reason:  reason,
+	})
+	if locked {
+		emitSecurityEvent(r, security.Event{
+			Type:    security.EventAuthLockout,
+			Outcome: security.OutcomeDenied,
+			Reason:  "too_many_failed_attempts",
+		})
+	}
+}
+
+// emitRateLimited records a request rejected by the rate limiter
+func emitRateLimited(r *http.Request) {
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventRateLimited,
+		Outcome: security.OutcomeDenied,
+		Reason:  "ip_rate_limit",
+	})
+}
+
+// emitPermissionDenied records an authenticated request refused for reason
+func emitPermissionDenied(r *http.Request, reason string, details map[string]string) {
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventPermissionDenied,
+		Outcome: security.OutcomeDenied,
+		Reason:  reason,
+		Details: details,
+	})
+}
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Protocols
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ServerProtocols returns the protocols the server accepts: HTTP/1.1 always,
+// HTTP/2 over TLS, and with h2c also HTTP/2 over cleartext. h2c uses prior
+// knowledge (no Upgrade), which is what gRPC and TLS-terminating proxies such
+// as Envoy speak to their upstreams.
+func ServerProtocols(h2c bool) *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(h2c)
+	return p
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// TLS Termination
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ModernTLSConfig returns server TLS settings with modern defaults:
+// TLS 1.2 minimum, forward-secret AEAD cipher suites only (TLS 1.3 suites are
+// not configurable and always secure), and X25519/P-256 key exchange.
+func ModernTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+		},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+}
+
+// NewACMEManager creates an autocert manager that provisions and renews
+// certificates for hosts only, storing them in cache. Install it on the
+// server's TLS config with ApplyACME; wrapping the plain HTTP listener with
+// its HTTPHandler additionally answers HTTP-01 challenges.
+func NewACMEManager(hosts []string, email, directoryURL string, cache autocert.Cache) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      cache,
+		Email:      email,
+	}
+	if directoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: directoryURL}
+	}
+	return m
+}
+
+// ApplyACME makes cfg obtain certificates from m, including TLS-ALPN-01
+// challenge support
+func ApplyACME(cfg *tls.Config, m *autocert.Manager) {
+	acmeCfg := m.TLSConfig()
+	cfg.GetCertificate = acmeCfg.GetCertificate
+	cfg.NextProtos = acmeCfg.NextProtos
+}
+
+// RedirectToHTTPS permanently redirects every request to the same host and
+// path on the HTTPS listener. 308 is used so clients keep the request method.
+func RedirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Mutual TLS (Client Certificates)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Client certificate modes
+const (
+	ClientAuthNone     = "none"     // Client certificates are not requested
+	ClientAuthOptional = "optional" // Verified if presented
+	ClientAuthRequire  = "require"  // Handshake fails without a valid certificate
+)
+
+// ConfigureClientAuth enables client certificate verification on cfg against
+// the CA bundle at caFile (PEM). mode is one of ClientAuthNone,
+// ClientAuthOptional or ClientAuthRequire.
+func ConfigureClientAuth(cfg *tls.Config, caFile, mode string) error {
+	if mode == "" || mode == ClientAuthNone {
+		return nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in client CA file %s", caFile)
+	}
+	cfg.ClientCAs = pool
+
+	switch mode {
+	case ClientAuthOptional:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return fmt.Errorf("unknown client auth mode %q", mode)
+	}
+	return nil
+}
+
+// ClientIdentity is the identity asserted by a verified client certificate
+type ClientIdentity struct {
+	// Subject is the primary identity: the first URI SAN (e.g. a SPIFFE ID),
+	// else the first DNS SAN, else the first email SAN, else the Common Name
+	Subject     string
+	CommonName  string
+	DNSNames    []string
+	URIs        []string
+	Emails      []string
+	Fingerprint string // Hex SHA-256 of the leaf certificate
+}
+
+// GetClientIdentity retrieves the client certificate identity from context
+func GetClientIdentity(ctx context.Context) *ClientIdentity {
+	if id, ok := ctx.Value(ClientIdentityKey).(*ClientIdentity); ok {
+		return id
+	}
+	return nil
+}
+
+// ClientCertIdentity maps a verified client certificate to a ClientIdentity in
+// the request context. Requests without a verified certificate pass through
+// unchanged; use ClientAuthRequire to reject them during the handshake.
+func ClientCertIdentity() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// VerifiedChains is only populated when the chain was checked against ClientCAs
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity := identityFromCert(r.TLS.VerifiedChains[0][0])
+			ctx := context.WithValue(r.Context(), ClientIdentityKey, identity)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// identityFromCert extracts CN and SANs from a leaf certificate
+func identityFromCert(cert *x509.Certificate) *ClientIdentity {
+	id := &ClientIdentity{
+		CommonName: cert.Subject.CommonName,
+		DNSNames:   cert.DNSNames,
+		Emails:     cert.EmailAddresses,
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	sum := sha256.Sum256(cert.Raw)
+	id.Fingerprint = hex.EncodeToString(sum[:])
+
+	switch {
+	case len(id.URIs) > 0:
+		id.Subject = id.URIs[0]
+	case len(id.DNSNames) > 0:
+		id.Subject = id.DNSNames[0]
+	case len(id.Emails) > 0:
+		id.Subject = id.Emails[0]
+	default:
+		id.Subject = id.CommonName
+	}
+	return id
+}
@@ -0,0 +1,241 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate with its key
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issueTestCert creates a certificate from template, signed by parent or
+// self-signed when parent is nil
+func issueTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// testCA creates a certificate authority for client certificates
+func testCA(t *testing.T, name string) *testCert {
+	t.Helper()
+	return issueTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+// clientCertTemplate is a client certificate template with the given names
+func clientCertTemplate(commonName string, dnsNames, emails []string, uris ...string) *x509.Certificate {
+	template := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: commonName},
+		DNSNames:       dnsNames,
+		EmailAddresses: emails,
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, raw := range uris {
+		u, _ := url.Parse(raw)
+		template.URIs = append(template.URIs, u)
+	}
+	return template
+}
+
+// identityRecorder records the client identity of the last request it served
+type identityRecorder struct {
+	identity *ClientIdentity
+	served   bool
+}
+
+func (ir *identityRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ir.identity, ir.served = GetClientIdentity(r.Context()), true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestClientCertIdentitySubject(t *testing.T) {
+	ca := testCA(t, "Test CA")
+	tests := []struct {
+		name     string
+		template *x509.Certificate
+		want     string
+	}{
+		{"URI SAN first", clientCertTemplate("billing", []string{"billing.internal"}, []string{"billing@example.com"},
+			"spiffe://example.com/ns/prod/sa/billing", "spiffe://example.com/ns/prod/sa/other"),
+			"spiffe://example.com/ns/prod/sa/billing"},
+		{"DNS SAN without a URI", clientCertTemplate("billing", []string{"billing.internal", "billing.example.com"},
+			[]string{"billing@example.com"}), "billing.internal"},
+		{"email SAN without DNS", clientCertTemplate("billing", nil, []string{"billing@example.com"}), "billing@example.com"},
+		{"common name without SANs", clientCertTemplate("billing", nil, nil), "billing"},
+		{"nothing", clientCertTemplate("", nil, nil), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := issueTestCert(t, tt.template, ca)
+			next := &identityRecorder{}
+			r := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+			r.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{leaf.cert},
+				VerifiedChains:   [][]*x509.Certificate{{leaf.cert, ca.cert}},
+			}
+			serve(ClientCertIdentity()(next), r)
+
+			id := next.identity
+			if id == nil {
+				t.Fatal("no client identity for a verified certificate")
+			}
+			if id.Subject != tt.want {
+				t.Errorf("Subject = %q, want %q", id.Subject, tt.want)
+			}
+			sum := sha256.Sum256(leaf.cert.Raw)
+			if id.Fingerprint != hex.EncodeToString(sum[:]) {
+				t.Errorf("Fingerprint = %q, want the leaf's SHA-256", id.Fingerprint)
+			}
+			if id.CommonName != tt.template.Subject.CommonName || !slices.Equal(id.DNSNames, tt.template.DNSNames) ||
+				!slices.Equal(id.Emails, tt.template.EmailAddresses) || len(id.URIs) != len(tt.template.URIs) {
+				t.Errorf("identity = %+v, want the certificate's names", id)
+			}
+			for i, u := range tt.template.URIs {
+				if id.URIs[i] != u.String() {
+					t.Errorf("URIs[%d] = %q, want %q", i, id.URIs[i], u)
+				}
+			}
+		})
+	}
+}
+
+func TestClientCertIdentityWithoutVerifiedCert(t *testing.T) {
+	leaf := issueTestCert(t, clientCertTemplate("billing", []string{"billing.internal"}, nil), testCA(t, "Test CA"))
+	tests := []struct {
+		name  string
+		state *tls.ConnectionState
+	}{
+		{"plain HTTP", nil},
+		{"no client certificate", &tls.ConnectionState{}},
+		// Presented but not checked against the client CAs
+		{"unverified certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf.cert}}},
+		{"empty verified chain", &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{leaf.cert},
+			VerifiedChains:   [][]*x509.Certificate{{}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &identityRecorder{}
+			r := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+			r.TLS = tt.state
+			rec := serve(ClientCertIdentity()(next), r)
+			if !next.served || rec.Code != http.StatusNoContent {
+				t.Fatalf("request was not passed on: %d", rec.Code)
+			}
+			if next.identity != nil {
+				t.Errorf("identity = %+v, want none", next.identity)
+			}
+		})
+	}
+}
+
+func TestClientCertIdentityHandshake(t *testing.T) {
+	ca := testCA(t, "Test CA")
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	next := &identityRecorder{}
+	server := httptest.NewUnstartedServer(ClientCertIdentity()(next))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // The failed handshake
+	server.TLS = &tls.Config{}
+	if err := ConfigureClientAuth(server.TLS, caFile, ClientAuthOptional); err != nil {
+		t.Fatalf("ConfigureClientAuth: %v", err)
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	// get sends a request on a new connection, presenting cert if any, even
+	// when the server would not accept its issuer
+	base := server.Client().Transport.(*http.Transport)
+	get := func(cert *testCert) error {
+		transport := base.Clone()
+		defer transport.CloseIdleConnections()
+		transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return &tls.Certificate{Certificate: [][]byte{cert.cert.Raw}, PrivateKey: cert.key, Leaf: cert.cert}, nil
+		}
+		client := &http.Client{Transport: transport}
+		next.identity, next.served = nil, false
+		resp, err := client.Get(server.URL + "/api/orders")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	leaf := issueTestCert(t, clientCertTemplate("billing", nil, nil, "spiffe://example.com/billing"), ca)
+	if err := get(leaf); err != nil {
+		t.Fatalf("GET with a trusted certificate: %v", err)
+	}
+	if next.identity == nil || next.identity.Subject != "spiffe://example.com/billing" {
+		t.Errorf("identity = %+v, want the certificate's URI", next.identity)
+	}
+
+	// Optional client auth serves clients without a certificate, unidentified
+	if err := get(nil); err != nil {
+		t.Fatalf("GET without a certificate: %v", err)
+	}
+	if !next.served || next.identity != nil {
+		t.Errorf("served = %v with identity %+v, want served without one", next.served, next.identity)
+	}
+
+	// A certificate from another CA fails the handshake
+	untrusted := issueTestCert(t, clientCertTemplate("mallory", nil, nil, "spiffe://example.com/billing"), testCA(t, "Other CA"))
+	if err := get(untrusted); err == nil {
+		t.Error("GET with an untrusted certificate succeeded")
+	}
+	if next.served {
+		t.Error("request with an untrusted certificate reached the handler")
+	}
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/security"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// SessionHandler handles HTTP requests for session and token revocation
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type SessionHandler struct {
+	sessionService *usecase.SessionService
+	cookies        SessionCookies
+	logg           *logger.Logger
+}
+
+// SessionCookies names the cookies of browser sessions
+type SessionCookies struct {
+	Session string // Cookie carrying the access token; empty disables cookie sessions
+	CSRF    string // Double-submit CSRF cookie (default DefaultCSRFCookieName)
+}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler(sessionService *usecase.SessionService, cookies SessionCookies, logg *logger.Logger) *SessionHandler {
+	if cookies.CSRF == "" {
+		cookies.CSRF = DefaultCSRFCookieName
+	}
+	return &SessionHandler{
+		sessionService: sessionService,
+		cookies:        cookies,
+		logg:           logg,
+	}
+}
+
+// SessionResponse represents a browser session started from a token
+type SessionResponse struct {
+	ExpiresAt string `json:"expires_at"`
+	CSRFToken string `json:"csrf_token"` // Echo in the CSRF header on unsafe requests
+}
+
+// CreateSession handles POST /api/auth/session
+// Stores the bearer token of the request in the session cookie, so browsers
+// stop handling it; the session ends when the token expires or is revoked
+func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return
+	}
+	token, ok := bearerToken(r)
+	if !ok || GetAccessToken(r.Context()) != nil {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Send the session token (JWT) to store in the Authorization header")
+		return
+	}
+
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	expiresAt := claims.ExpiresAtTime()
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookies.Session,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookies.CSRF,
+		Value:    csrfToken,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   true,
+		HttpOnly: false, // Must be readable by the page to echo it
+		SameSite: http.SameSiteStrictMode,
+	})
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventTokenCreated,
+		Outcome: security.OutcomeSuccess,
+		Action:  "session",
+		Target:  &security.Target{Type: "session", ID: claims.ID},
+	})
+
+	respondJSON(w, http.StatusCreated, &SessionResponse{
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+		CSRFToken: csrfToken,
+	})
+}
+
+// RevocationResponse represents the response body for user-wide revocations
+type RevocationResponse struct {
+	UserID        string `json:"user_id"`
+	RevokedBefore string `json:"revoked_before"`
+}
+
+// Logout handles POST /api/auth/logout
+// Revokes the token used to make the request
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return
+	}
+
+	if err := h.sessionService.RevokeToken(r.Context(), claims.ID, claims.ExpiresAtTime()); err != nil {
+		h.logg.Error("failed to revoke token", "error", err)
+		handleError(w, err)
+		return
+	}
+	emitSecurityEvent(r, security.Event{
+		Type:    security.EventTokenRevoked,
+		Outcome: security.OutcomeSuccess,
+		Action:  "logout",
+		Target:  &security.Target{Type: "session", ID: claims.ID},
+	})
+
+	h.clearSessionCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clearSessionCookie tells browsers to drop the session cookie, if sessions are on
+func (h *SessionHandler) clearSessionCookie(w http.ResponseWriter) {
+	if h.cookies.Session == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookies.Session,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// LogoutAll handles POST /api/auth/logout-all
+// Revokes every token issued to the caller, including the current one
+func (h *SessionHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return
+	}
+
+	h.clearSessionCookie(w)
+	if h.revokeAll(w, r, claims.Subject) {
+		emitSecurityEvent(r, security.Event{
+			Type:    security.EventTokenRevoked,
+			Outcome: security.OutcomeSuccess,
+			Action:  "logout_all",
+			Target:  &security.Target{Type: "user", ID: claims.Subject},
+		})
+	}
+}
+
+// RevokeUserTokens handles POST /api/admin/users/{id}/revoke-tokens
+// Compromise response: revokes every token issued to the user (requires the admin scope)
+func (h *SessionHandler) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r.Context())
+	if claims == nil || !claims.HasScope(auth.ScopeAdmin) {
+		emitPermissionDenied(r, "admin_scope_required", nil)
+		handleError(w, domain.ErrForbidden)
+		return
+	}
+
+	userID := strings.TrimSpace(r.PathValue("id"))
+	if userID == "" {
+		handleError(w, domain.ErrInvalidUserID)
+		return
+	}
+
+	h.logg.Warn("admin revoked user tokens",
+		"request_id", GetRequestID(r.Context()),
+		"admin_id", claims.Subject,
+		"user_id", userID,
+	)
+	if h.revokeAll(w, r, userID) {
+		emitSecurityEvent(r, security.Event{
+			Type:    security.EventAdminAction,
+			Outcome: security.OutcomeSuccess,
+			Action:  "revoke_user_tokens",
+			Target:  &security.Target{Type: "user", ID: userID},
+		})
+	}
+}
+
+// revokeAll revokes every token issued to userID and reports whether it succeeded
+func (h *SessionHandler) revokeAll(w http.ResponseWriter, r *http.Request, userID string) bool {
+	revokedBefore, err := h.sessionService.RevokeAllForUser(r.Context(), userID)
+	if err != nil {
+		h.logg.Error("failed to revoke user tokens", "error", err, "user_id", userID)
+		handleError(w, err)
+		return false
+	}
+
+	respondJSON(w, http.StatusOK, &RevocationResponse{
+		UserID:        userID,
+		RevokedBefore: revokedBefore.UTC().Format(time.RFC3339),
+	})
+	return true
+}
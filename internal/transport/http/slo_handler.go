@@ -0,0 +1,187 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// SLOHandler serves per-route SLO burn rates and latency heatmaps
+// Transport layer - handles HTTP concerns only, delegates the counting to the SLO service
+type SLOHandler struct {
+	slo *usecase.SLOService
+}
+
+// NewSLOHandler creates an SLO handler
+func NewSLOHandler(slo *usecase.SLOService) *SLOHandler {
+	return &SLOHandler{slo: slo}
+}
+
+// SLOWindowResponse is the error budget burn over one window
+type SLOWindowResponse struct {
+	WindowSeconds int64   `json:"window_seconds"`
+	Requests      int64   `json:"requests"`
+	Bad           int64   `json:"bad"`
+	BurnRate      float64 `json:"burn_rate"`
+	Breaching     bool    `json:"breaching"`
+}
+
+// SLOStatusResponse is the burn of one route's objective
+type SLOStatusResponse struct {
+	Route     string            `json:"route"`
+	Target    float64           `json:"target"`               // Share of good requests, e.g. 0.999
+	LatencyMS int64             `json:"latency_ms,omitempty"` // Slower requests count as bad
+	Fast      SLOWindowResponse `json:"fast"`
+	Slow      SLOWindowResponse `json:"slow"`
+	Alert     string            `json:"alert,omitempty"` // "page" or "ticket"
+}
+
+// SLOListResponse lists every observed route's SLO status
+type SLOListResponse struct {
+	Routes      []SLOStatusResponse `json:"routes"`
+	Alerts      int                 `json:"alerts"` // Routes with an alert
+	GeneratedAt time.Time           `json:"generated_at"`
+}
+
+// LatencyHeatmapResponse is a route's latency histogram per time step
+type LatencyHeatmapResponse struct {
+	Route       string                      `json:"route"`
+	StepSeconds int64                       `json:"step_seconds"`
+	BoundsMS    []int64                     `json:"bounds_ms"` // Bucket upper bounds; a last bucket counts everything slower
+	Rows        []LatencyHeatmapRowResponse `json:"rows"`
+}
+
+// LatencyHeatmapRowResponse is the histogram of one step
+type LatencyHeatmapRowResponse struct {
+	Start  time.Time `json:"start"`
+	Counts []int64   `json:"counts"`
+}
+
+func toSLOWindowResponse(w usecase.SLOWindow) SLOWindowResponse {
+	return SLOWindowResponse{
+		WindowSeconds: int64(w.Window.Seconds()),
+		Requests:      w.Requests,
+		Bad:           w.Bad,
+		BurnRate:      w.BurnRate,
+		Breaching:     w.Breaching,
+	}
+}
+
+// SLOStatusReport converts SLO statuses to their response, also used for the
+// admin listener's /metrics
+func SLOStatusReport(statuses []usecase.SLOStatus) *SLOListResponse {
+	report := &SLOListResponse{Routes: make([]SLOStatusResponse, len(statuses)), GeneratedAt: time.Now().UTC()}
+	for i, s := range statuses {
+		report.Routes[i] = SLOStatusResponse{
+			Route:     s.Route,
+			Target:    s.Objective.Target,
+			LatencyMS: s.Objective.Latency.Milliseconds(),
+			Fast:      toSLOWindowResponse(s.Fast),
+			Slow:      toSLOWindowResponse(s.Slow),
+			Alert:     s.Alert,
+		}
+		if s.Alert != usecase.SLOAlertNone {
+			report.Alerts++
+		}
+	}
+	return report
+}
+
+// List handles GET /api/admin/slo: the fast and slow window burn rates of
+// every observed route, with "page" or "ticket" alerts (requires the admin scope)
+func (h *SLOHandler) List(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	respondJSON(w, http.StatusOK, SLOStatusReport(h.slo.Status()))
+}
+
+// Heatmap handles GET /api/admin/slo/heatmap?route=&window=1h&step=1m: a
+// route's latency histogram per step (requires the admin scope)
+func (h *SLOHandler) Heatmap(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	details := map[string]string{}
+	route := strings.TrimSpace(query.Get("route"))
+	if route == "" {
+		details["route"] = "required, e.g. GET /api/orders/{id}"
+	}
+	window, step := time.Hour, time.Minute
+	for name, d := range map[string]*time.Duration{"window": &window, "step": &step} {
+		if raw := query.Get(name); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				details[name] = "must be a duration like 30m or 1h"
+				continue
+			}
+			*d = parsed
+		}
+	}
+	if len(details) > 0 {
+		respondErrorWithDetails(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid query parameters", details)
+		return
+	}
+
+	heatmap, err := h.slo.Heatmap(route, window, step)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	resp := LatencyHeatmapResponse{
+		Route:       heatmap.Route,
+		StepSeconds: int64(heatmap.Step.Seconds()),
+		BoundsMS:    domain.LatencyBoundsMS,
+		Rows:        make([]LatencyHeatmapRowResponse, len(heatmap.Rows)),
+	}
+	for i, row := range heatmap.Rows {
+		resp.Rows[i] = LatencyHeatmapRowResponse{Start: row.Start, Counts: row.Counts}
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// requireAdmin writes a 403 unless the caller has the admin scope
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	claims := GetClaims(r.Context())
+	if claims == nil || !claims.HasScope(auth.ScopeAdmin) {
+		emitPermissionDenied(r, "admin_scope_required", nil)
+		handleError(w, domain.ErrForbidden)
+		return false
+	}
+	return true
+}
+
+// RouteObserver records the outcome of API requests per route (see usecase.SLOService)
+type RouteObserver interface {
+	ObserveRoute(route string, d time.Duration, failed bool)
+}
+
+// RecordRouteStats reports the duration of every /api request to observer
+// under its route pattern (from resolve), marking 5xx responses as failed.
+// Unmatched requests and event streams are not counted.
+func RecordRouteStats(observer RouteObserver, resolve func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") || isEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			route := resolve(r)
+			if route == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			rw := middleware.NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			observer.ObserveRoute(route, time.Since(start), rw.Status() >= http.StatusInternalServerError)
+		})
+	}
+}
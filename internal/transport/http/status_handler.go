@@ -0,0 +1,133 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/websocket"
+)
+
+// statusSchemaVersion is bumped only on breaking changes to StatusResponse
+const statusSchemaVersion = 1
+
+// StatusHandler serves the public status page data
+// Transport layer - handles HTTP concerns only, delegates the roll-up to the status service
+type StatusHandler struct {
+	status *usecase.StatusService
+	maxAge time.Duration
+	logg   *logger.Logger
+}
+
+// NewStatusHandler creates a status handler; responses may be cached by
+// clients and CDNs for maxAge
+func NewStatusHandler(status *usecase.StatusService, maxAge time.Duration, logg *logger.Logger) *StatusHandler {
+	return &StatusHandler{
+		status: status,
+		maxAge: maxAge,
+		logg:   logg,
+	}
+}
+
+// StatusWindowResponse is the availability and latency over one window
+type StatusWindowResponse struct {
+	Requests     int64                 `json:"requests"`
+	Errors       int64                 `json:"errors"`
+	Availability float64               `json:"availability"` // 0..1, 1 without requests
+	LatencyMS    StatusLatencyResponse `json:"latency_ms"`
+}
+
+// StatusLatencyResponse holds latency percentiles, as histogram bucket upper bounds
+type StatusLatencyResponse struct {
+	P50 int64 `json:"p50"`
+	P95 int64 `json:"p95"`
+	P99 int64 `json:"p99"`
+}
+
+// StatusWindows are the rolled-up windows, at hour granularity
+type StatusWindows struct {
+	LastHour StatusWindowResponse `json:"1h"`
+	Last24h  StatusWindowResponse `json:"24h"`
+	Last7d   StatusWindowResponse `json:"7d"`
+}
+
+// StatusResponse is the stable schema behind GET /status
+type StatusResponse struct {
+	SchemaVersion int           `json:"schema_version"`
+	Status        string        `json:"status"` // operational, degraded or major_outage
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Windows       StatusWindows `json:"windows"`
+}
+
+// Get handles GET /status (no auth required): availability and latency of
+// the /api routes over the last hour, 24 hours and 7 days
+func (h *StatusHandler) Get(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.status.Summary(r.Context())
+	if err != nil {
+		h.logg.Error("failed to compute status summary", "error", err)
+		handleError(w, domain.ErrInternalError)
+		return
+	}
+
+	if h.maxAge > 0 {
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(h.maxAge.Seconds())))
+	}
+	respondJSON(w, http.StatusOK, StatusResponse{
+		SchemaVersion: statusSchemaVersion,
+		Status:        summary.Status,
+		UpdatedAt:     summary.UpdatedAt,
+		Windows: StatusWindows{
+			LastHour: toStatusWindowResponse(summary.LastHour),
+			Last24h:  toStatusWindowResponse(summary.Last24h),
+			Last7d:   toStatusWindowResponse(summary.Last7d),
+		},
+	})
+}
+
+func toStatusWindowResponse(w usecase.StatusWindow) StatusWindowResponse {
+	return StatusWindowResponse{
+		Requests:     w.Requests,
+		Errors:       w.Errors,
+		Availability: w.Availability,
+		LatencyMS: StatusLatencyResponse{
+			P50: w.LatencyP50MS,
+			P95: w.LatencyP95MS,
+			P99: w.LatencyP99MS,
+		},
+	}
+}
+
+// RequestObserver records the outcome of API requests (see usecase.StatusService)
+type RequestObserver interface {
+	Observe(d time.Duration, failed bool)
+}
+
+// isEventStream reports whether r opens a WebSocket, Server-Sent Events or
+// NDJSON export stream, whose duration says nothing about latency
+func isEventStream(r *http.Request) bool {
+	return websocket.IsUpgrade(r) || r.URL.Path == "/api/events" || acceptsNDJSON(r)
+}
+
+// RecordRequestStats reports the duration of every /api request to observer,
+// marking 5xx responses as failed. Health checks, the status endpoint and
+// other non-API routes are not counted, and neither are event streams.
+func RecordRequestStats(observer RequestObserver) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Event streams last minutes, their duration is not latency
+			if !strings.HasPrefix(r.URL.Path, "/api/") || isEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			rw := middleware.NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			observer.Observe(time.Since(start), rw.Status() >= http.StatusInternalServerError)
+		})
+	}
+}
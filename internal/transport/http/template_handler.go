@@ -0,0 +1,58 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/templates"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// TemplatePreviewHandler renders templates with sample data so they can be
+// checked in a browser while editing. Register it in development only.
+type TemplatePreviewHandler struct {
+	templates *templates.Engine
+	logg      *logger.Logger
+}
+
+// NewTemplatePreviewHandler creates a template preview handler
+func NewTemplatePreviewHandler(engine *templates.Engine, logg *logger.Logger) *TemplatePreviewHandler {
+	return &TemplatePreviewHandler{
+		templates: engine,
+		logg:      logg,
+	}
+}
+
+// List handles GET /dev/templates
+func (h *TemplatePreviewHandler) List(w http.ResponseWriter, r *http.Request) {
+	if err := h.templates.Reload(); err != nil {
+		respondError(w, http.StatusInternalServerError, "TEMPLATE_ERROR", err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string][]string{"templates": h.templates.Names()})
+}
+
+// Preview handles GET /dev/templates/{name...}, e.g. /dev/templates/invoice/order.
+// Templates are re-read first, so edits in the override directory show on refresh.
+func (h *TemplatePreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	if err := h.templates.Reload(); err != nil {
+		respondError(w, http.StatusInternalServerError, "TEMPLATE_ERROR", err.Error())
+		return
+	}
+
+	name := r.PathValue("name")
+	var buf bytes.Buffer
+	// Templates without sample data render with nil, showing their static parts
+	if err := h.templates.Render(&buf, name, templates.Samples()[name]); err != nil {
+		if errors.Is(err, templates.ErrNotFound) {
+			respondError(w, http.StatusNotFound, "NOT_FOUND", "Template not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "TEMPLATE_ERROR", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/middleware"
+)
+
+// RequestTimeoutConfig configures how long requests may run
+type RequestTimeoutConfig struct {
+	// Default bounds every request without a route override (0 is unlimited)
+	Default time.Duration
+	// Routes overrides Default per route group: a registered pattern
+	// ("POST /api/reports"), a path on every method ("/api/users/import")
+	// or a method on every route ("GET"). The most specific group that
+	// matches applies; 0 is unlimited.
+	Routes map[string]time.Duration
+}
+
+// RequestTimeout cancels the context of a request still being handled at
+// its route's deadline and answers 503 REQUEST_TIMEOUT (see
+// middleware.Timeout). The cancellation reaches the database: pgx cancels
+// the running query. Streaming endpoints and NDJSON exports are never timed
+// out; an export is bounded by its write timeout instead, which a client
+// that stops reading runs into.
+func RequestTimeout(config RequestTimeoutConfig, mux *http.ServeMux) Middleware {
+	routes := make(map[string]time.Duration, len(config.Routes))
+	for route, timeout := range config.Routes {
+		routes[strings.Join(strings.Fields(route), " ")] = timeout
+	}
+
+	return middleware.TimeoutFunc(func(r *http.Request) time.Duration {
+		_, pattern := mux.Handler(r)
+		if streamingRoutes[pattern] || exportRoutes[pattern] && acceptsNDJSON(r) {
+			return 0
+		}
+		if _, timeout, ok := routeGroup(routes, r.Method, pattern); ok {
+			return timeout
+		}
+		return config.Default
+	})
+}
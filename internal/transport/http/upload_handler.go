@@ -0,0 +1,252 @@
+package http
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Resumable Uploads (tus)
+// ═══════════════════════════════════════════════════════════════════════════════
+//
+// Attachments too large or connections too flaky for a single PUT are sent
+// through the API with the tus 1.0.0 resumable upload protocol
+// (https://tus.io/protocols/resumable-upload) and its creation and
+// termination extensions:
+//
+//	POST   /api/uploads        Upload-Length, Upload-Metadata: filename and filetype
+//	                           201 Created, Location: /api/uploads/{id}
+//	HEAD   /api/uploads/{id}   200 OK, Upload-Offset: bytes received so far
+//	PATCH  /api/uploads/{id}   Upload-Offset, a chunk of the file as
+//	                           application/offset+octet-stream; 204 No Content
+//	                           with the new Upload-Offset
+//	DELETE /api/uploads/{id}   204 No Content, the upload is discarded
+//
+// Each upload is an attachment (the upload's ID is its ID) stored in a
+// blob store multipart upload; the PATCH bringing the last byte completes it,
+// and it is then scanned and downloaded as any other attachment. A chunk is
+// bounded by RouterConfig.UploadChunkSize rather than MaxBodySize, and a
+// client whose connection drops asks HEAD where to resume from.
+
+// tus protocol values
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,termination"
+	tusContentType = "application/offset+octet-stream"
+)
+
+// tusRequestHeaders and tusResponseHeaders are the headers browser clients
+// send and read, allowed and exposed by CORS
+var (
+	tusRequestHeaders  = []string{"Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"}
+	tusResponseHeaders = []string{"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length"}
+)
+
+// UploadHandler handles tus resumable upload requests
+// Transport layer - handles HTTP concerns only, delegates business logic to service
+type UploadHandler struct {
+	attachmentService *usecase.AttachmentService
+	logg              *logger.Logger
+}
+
+// NewUploadHandler creates a new resumable upload handler
+func NewUploadHandler(attachmentService *usecase.AttachmentService, logg *logger.Logger) *UploadHandler {
+	return &UploadHandler{
+		attachmentService: attachmentService,
+		logg:              logg,
+	}
+}
+
+// Options handles OPTIONS /api/uploads, tus discovery
+func (h *UploadHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.attachmentService.MaxSize(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Create handles POST /api/uploads, starting an upload of Upload-Length
+// bytes. Upload-Metadata must name the file (filename or name) and may give
+// its content type (filetype or type).
+func (h *UploadHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if !tusResumable(w, r) {
+		return
+	}
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return
+	}
+
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Upload-Length is required; deferred lengths are not supported")
+		return
+	}
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Upload-Length must be a number of bytes")
+		return
+	}
+	if maxSize := h.attachmentService.MaxSize(); size > maxSize {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+		respondError(w, http.StatusRequestEntityTooLarge, "UPLOAD_TOO_LARGE", "Upload-Length exceeds Tus-Max-Size")
+		return
+	}
+	if r.ContentLength > 0 {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Send the file with PATCH once the upload is created")
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Upload-Metadata must be comma-separated keys with base64 values")
+		return
+	}
+
+	attachment, err := h.attachmentService.CreateResumableUpload(r.Context(), claims.Subject,
+		cmp.Or(metadata["filename"], metadata["name"]),
+		cmp.Or(metadata["filetype"], metadata["type"]),
+		size)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/uploads/"+attachment.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Head handles HEAD /api/uploads/{id}, reporting the bytes received
+func (h *UploadHandler) Head(w http.ResponseWriter, r *http.Request) {
+	if !tusResumable(w, r) {
+		return
+	}
+	attachment, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	offset, err := h.attachmentService.UploadOffset(r.Context(), attachment)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(attachment.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Append handles PATCH /api/uploads/{id}, writing the body at Upload-Offset
+func (h *UploadHandler) Append(w http.ResponseWriter, r *http.Request) {
+	if !tusResumable(w, r) {
+		return
+	}
+	if mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); strings.TrimSpace(mediaType) != tusContentType {
+		respondError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Content-Type must be "+tusContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Upload-Offset must be a number of bytes")
+		return
+	}
+	attachment, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if r.ContentLength > 0 && offset+r.ContentLength > attachment.Size {
+		respondError(w, http.StatusBadRequest, "UPLOAD_LENGTH_EXCEEDED", "The chunk goes past Upload-Length")
+		return
+	}
+
+	offset, err = h.attachmentService.AppendUpload(r.Context(), attachment, offset, r.Body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		// The bytes up to the limit are kept; HEAD tells the client where to resume
+		respondError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Chunks are limited to "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes")
+	case err != nil:
+		handleError(w, err)
+	default:
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Terminate handles DELETE /api/uploads/{id}, discarding an unfinished upload
+func (h *UploadHandler) Terminate(w http.ResponseWriter, r *http.Request) {
+	if !tusResumable(w, r) {
+		return
+	}
+	attachment, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentService.AbortUpload(r.Context(), attachment); err != nil {
+		handleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// load fetches the caller's upload named by the path. Other users' uploads,
+// and attachments still waiting for a PUT or POST upload, are reported as
+// missing.
+func (h *UploadHandler) load(w http.ResponseWriter, r *http.Request) (*domain.Attachment, bool) {
+	claims := GetClaims(r.Context())
+	if claims == nil {
+		handleError(w, domain.ErrUnauthorized)
+		return nil, false
+	}
+
+	attachment, err := h.attachmentService.Get(r.Context(), strings.TrimSpace(r.PathValue("id")))
+	if err != nil {
+		handleError(w, err)
+		return nil, false
+	}
+	if attachment.OwnerID != claims.Subject || (!attachment.Resumable() && !attachment.Uploaded()) {
+		handleError(w, domain.ErrAttachmentNotFound)
+		return nil, false
+	}
+	return attachment, true
+}
+
+// tusResumable sets the protocol version on the response and checks the
+// request's, answering 412 to clients of another version
+func tusResumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		respondError(w, http.StatusPreconditionFailed, "UNSUPPORTED_TUS_VERSION", "Tus-Resumable must be "+tusVersion)
+		return false
+	}
+	return true
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma-separated
+// pairs of a key and its base64 value, which may be left out
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for pair := range strings.SplitSeq(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
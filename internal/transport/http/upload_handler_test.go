@@ -0,0 +1,325 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// multipartMemoryStore adds multipart uploads to the in-memory blob store
+type multipartMemoryStore struct {
+	*blob.MemoryStore
+
+	mu      sync.Mutex
+	uploads map[string]map[int32][]byte // Parts by upload ID
+	types   map[string]string           // Content types by upload ID
+}
+
+func newMultipartMemoryStore() *multipartMemoryStore {
+	return &multipartMemoryStore{
+		MemoryStore: blob.NewMemoryStore(),
+		uploads:     make(map[string]map[int32][]byte),
+		types:       make(map[string]string),
+	}
+}
+
+func (s *multipartMemoryStore) CreateMultipartUpload(ctx context.Context, input *blob.UploadInput) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := fmt.Sprintf("upload-%d", len(s.types)+1)
+	s.uploads[id] = make(map[int32][]byte)
+	s.types[id] = input.ContentType
+	return id, nil
+}
+
+func (s *multipartMemoryStore) UploadPart(ctx context.Context, key, uploadID string, number int32, body io.Reader, size int64) (*blob.Part, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+	parts[number] = data
+	return &blob.Part{Number: number, Size: int64(len(data))}, nil
+}
+
+func (s *multipartMemoryStore) ListParts(ctx context.Context, key, uploadID string) ([]blob.Part, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+	var list []blob.Part
+	for number := int32(1); parts[number] != nil; number++ {
+		list = append(list, blob.Part{Number: number, Size: int64(len(parts[number]))})
+	}
+	return list, nil
+}
+
+func (s *multipartMemoryStore) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []blob.Part) (*blob.UploadOutput, error) {
+	s.mu.Lock()
+	var data []byte
+	for _, part := range parts {
+		data = append(data, s.uploads[uploadID][part.Number]...)
+	}
+	delete(s.uploads, uploadID)
+	contentType := s.types[uploadID]
+	s.mu.Unlock()
+	return s.Upload(ctx, &blob.UploadInput{Key: key, Body: bytes.NewReader(data), ContentType: contentType})
+}
+
+func (s *multipartMemoryStore) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, uploadID)
+	return nil
+}
+
+// newUploadServer serves the tus routes, authenticating requests as the
+// user named by the X-Test-User header
+func newUploadServer(t *testing.T, maxSize int64) (http.Handler, *usecase.AttachmentService) {
+	t.Helper()
+	svc := usecase.NewAttachmentService(memory.NewAttachmentRepository(), newMultipartMemoryStore(), memory.NewSemaphoreStore(),
+		usecase.AttachmentPolicy{MaxSize: maxSize}, logger.New("error"))
+	mux := http.NewServeMux()
+	registerUploadRoutes(mux, NewUploadHandler(svc, logger.New("error")))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := r.Header.Get("X-Test-User"); user != "" {
+			r = r.WithContext(context.WithValue(r.Context(), ClaimsKey, &auth.Claims{Subject: user}))
+		}
+		mux.ServeHTTP(w, r)
+	}), svc
+}
+
+// tusRequest builds a tus request by user-1 with headers given as name, value pairs
+func tusRequest(method, path string, body []byte, headers ...string) *http.Request {
+	r := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if body == nil {
+		r = httptest.NewRequest(method, path, nil)
+	}
+	r.Header.Set("Tus-Resumable", tusVersion)
+	r.Header.Set("X-Test-User", "user-1")
+	for i := 0; i+1 < len(headers); i += 2 {
+		if headers[i+1] == "" {
+			r.Header.Del(headers[i])
+		} else {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+	}
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func uploadMetadata(pairs ...string) string {
+	var encoded []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		encoded = append(encoded, pairs[i]+" "+base64.StdEncoding.EncodeToString([]byte(pairs[i+1])))
+	}
+	return strings.Join(encoded, ",")
+}
+
+// createUpload starts an upload of size bytes and returns its path
+func createUpload(t *testing.T, h http.Handler, size int) string {
+	t.Helper()
+	rec := serve(h, tusRequest(http.MethodPost, "/api/uploads", nil,
+		"Upload-Length", strconv.Itoa(size),
+		"Upload-Metadata", uploadMetadata("filename", "notes.txt", "filetype", "text/plain")))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/uploads = %d %s, want 201", rec.Code, rec.Body)
+	}
+	return rec.Header().Get("Location")
+}
+
+func TestUploadHandlerFlow(t *testing.T) {
+	h, svc := newUploadServer(t, 1<<20)
+
+	rec := serve(h, tusRequest(http.MethodOptions, "/api/uploads", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Tus-Extension") != tusExtensions || rec.Header().Get("Tus-Max-Size") != "1048576" {
+		t.Errorf("OPTIONS = %d %v, want 204 with the tus capabilities", rec.Code, rec.Header())
+	}
+
+	location := createUpload(t, h, 11)
+	if !strings.HasPrefix(location, "/api/uploads/") {
+		t.Fatalf("Location = %q", location)
+	}
+
+	rec = serve(h, tusRequest(http.MethodHead, location, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != "0" || rec.Header().Get("Upload-Length") != "11" {
+		t.Errorf("HEAD = %d offset %q length %q, want 200 0 11", rec.Code, rec.Header().Get("Upload-Offset"), rec.Header().Get("Upload-Length"))
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("HEAD Cache-Control = %q, want no-store", rec.Header().Get("Cache-Control"))
+	}
+
+	for _, chunk := range []struct {
+		offset, body, want string
+	}{
+		{"0", "hello ", "6"},
+		{"6", "world", "11"},
+	} {
+		rec = serve(h, tusRequest(http.MethodPatch, location, []byte(chunk.body),
+			"Content-Type", tusContentType, "Upload-Offset", chunk.offset))
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != chunk.want {
+			t.Fatalf("PATCH at %s = %d %s offset %q, want 204 %s", chunk.offset, rec.Code, rec.Body, rec.Header().Get("Upload-Offset"), chunk.want)
+		}
+	}
+
+	attachment, err := svc.Get(context.Background(), strings.TrimPrefix(location, "/api/uploads/"))
+	if err != nil || !attachment.Uploaded() {
+		t.Fatalf("attachment = %+v, %v, want it uploaded", attachment, err)
+	}
+
+	// Completed uploads report their size and can't be terminated
+	rec = serve(h, tusRequest(http.MethodHead, location, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != "11" {
+		t.Errorf("HEAD after completion = %d offset %q, want 200 11", rec.Code, rec.Header().Get("Upload-Offset"))
+	}
+	rec = serve(h, tusRequest(http.MethodDelete, location, nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("DELETE after completion = %d, want 409", rec.Code)
+	}
+}
+
+func TestUploadHandlerTerminate(t *testing.T) {
+	h, _ := newUploadServer(t, 1<<20)
+	location := createUpload(t, h, 10)
+
+	if rec := serve(h, tusRequest(http.MethodDelete, location, nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d %s, want 204", rec.Code, rec.Body)
+	}
+	if rec := serve(h, tusRequest(http.MethodHead, location, nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("HEAD after DELETE = %d, want 404", rec.Code)
+	}
+}
+
+func TestUploadHandlerCreateRejects(t *testing.T) {
+	h, _ := newUploadServer(t, 100)
+	metadata := uploadMetadata("filename", "notes.txt")
+
+	tests := []struct {
+		name    string
+		body    []byte
+		headers []string
+		want    int
+	}{
+		{"wrong tus version", nil, []string{"Tus-Resumable", "0.2.2", "Upload-Length", "10", "Upload-Metadata", metadata}, http.StatusPreconditionFailed},
+		{"unauthenticated", nil, []string{"X-Test-User", "", "Upload-Length", "10", "Upload-Metadata", metadata}, http.StatusUnauthorized},
+		{"deferred length", nil, []string{"Upload-Defer-Length", "1", "Upload-Metadata", metadata}, http.StatusBadRequest},
+		{"missing length", nil, []string{"Upload-Metadata", metadata}, http.StatusBadRequest},
+		{"negative length", nil, []string{"Upload-Length", "-1", "Upload-Metadata", metadata}, http.StatusBadRequest},
+		{"too large", nil, []string{"Upload-Length", "101", "Upload-Metadata", metadata}, http.StatusRequestEntityTooLarge},
+		{"body with creation", []byte("data"), []string{"Upload-Length", "10", "Upload-Metadata", metadata}, http.StatusBadRequest},
+		{"bad metadata", nil, []string{"Upload-Length", "10", "Upload-Metadata", "filename !!!"}, http.StatusBadRequest},
+		{"no filename", nil, []string{"Upload-Length", "10"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, tusRequest(http.MethodPost, "/api/uploads", tt.body, tt.headers...))
+			if rec.Code != tt.want {
+				t.Errorf("POST = %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+			if rec.Header().Get("Tus-Resumable") != tusVersion {
+				t.Error("response lacks Tus-Resumable")
+			}
+		})
+	}
+}
+
+func TestUploadHandlerAppendRejects(t *testing.T) {
+	h, _ := newUploadServer(t, 100)
+	location := createUpload(t, h, 10)
+	if rec := serve(h, tusRequest(http.MethodPatch, location, []byte("abc"), "Content-Type", tusContentType, "Upload-Offset", "0")); rec.Code != http.StatusNoContent {
+		t.Fatalf("PATCH = %d %s, want 204", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		headers []string
+		want    int
+	}{
+		{"wrong tus version", location, "d", []string{"Tus-Resumable", "", "Content-Type", tusContentType, "Upload-Offset", "3"}, http.StatusPreconditionFailed},
+		{"wrong content type", location, "d", []string{"Content-Type", "application/octet-stream", "Upload-Offset", "3"}, http.StatusUnsupportedMediaType},
+		{"missing offset", location, "d", []string{"Content-Type", tusContentType}, http.StatusBadRequest},
+		{"stale offset", location, "d", []string{"Content-Type", tusContentType, "Upload-Offset", "0"}, http.StatusConflict},
+		{"past the length", location, "too many bytes", []string{"Content-Type", tusContentType, "Upload-Offset", "3"}, http.StatusBadRequest},
+		{"another user's upload", location, "d", []string{"X-Test-User", "user-2", "Content-Type", tusContentType, "Upload-Offset", "3"}, http.StatusNotFound},
+		{"unknown upload", "/api/uploads/missing", "d", []string{"Content-Type", tusContentType, "Upload-Offset", "0"}, http.StatusNotFound},
+		{"unauthenticated", location, "d", []string{"X-Test-User", "", "Content-Type", tusContentType, "Upload-Offset", "3"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, tusRequest(http.MethodPatch, tt.path, []byte(tt.body), tt.headers...))
+			if rec.Code != tt.want {
+				t.Errorf("PATCH = %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+
+	// None of them moved the offset
+	rec := serve(h, tusRequest(http.MethodHead, location, nil))
+	if got := rec.Header().Get("Upload-Offset"); got != "3" {
+		t.Errorf("Upload-Offset = %q after rejected chunks, want 3", got)
+	}
+}
+
+func TestUploadHandlerChunkTooLarge(t *testing.T) {
+	h, _ := newUploadServer(t, 100)
+	location := createUpload(t, h, 10)
+
+	// MaxBodySizeFunc bounds chunks; the bytes within the limit are kept
+	limited := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, 4)
+		h.ServeHTTP(w, r)
+	})
+	r := tusRequest(http.MethodPatch, location, []byte("0123456"), "Content-Type", tusContentType, "Upload-Offset", "0")
+	r.ContentLength = -1
+	if rec := serve(limited, r); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("PATCH = %d %s, want 413", rec.Code, rec.Body)
+	}
+	rec := serve(h, tusRequest(http.MethodHead, location, nil))
+	if got := rec.Header().Get("Upload-Offset"); got != "4" {
+		t.Errorf("Upload-Offset = %q, want the 4 bytes within the limit", got)
+	}
+}
+
+func TestParseUploadMetadata(t *testing.T) {
+	got, err := parseUploadMetadata(uploadMetadata("filename", "a b.txt", "filetype", "text/plain") + ", is_confidential")
+	if err != nil {
+		t.Fatalf("parseUploadMetadata() error = %v", err)
+	}
+	if got["filename"] != "a b.txt" || got["filetype"] != "text/plain" {
+		t.Errorf("metadata = %v", got)
+	}
+	if v, ok := got["is_confidential"]; !ok || v != "" {
+		t.Errorf("key without a value = %q, %v, want present and empty", v, ok)
+	}
+	if _, err := parseUploadMetadata("filename not-base64!"); err == nil {
+		t.Error("expected invalid base64 to be rejected")
+	}
+}
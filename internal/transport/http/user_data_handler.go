@@ -0,0 +1,125 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// maxImportUsers is the most users one import may carry; the request body
+// limit usually binds first
+const maxImportUsers = 10000
+
+// UserDataHandler handles HTTP requests for user imports and data exports
+// Transport layer - both run as background jobs: the request is answered
+// with 202 Accepted and the job's status URL, polled until the job is done,
+// and the output is then downloaded from GET /api/jobs/{id}/result
+type UserDataHandler struct {
+	data *usecase.UserDataService
+	jobs *usecase.JobRunner
+	logg *logger.Logger
+}
+
+// NewUserDataHandler creates a new user data handler
+func NewUserDataHandler(data *usecase.UserDataService, jobs *usecase.JobRunner, logg *logger.Logger) *UserDataHandler {
+	return &UserDataHandler{
+		data: data,
+		jobs: jobs,
+		logg: logg,
+	}
+}
+
+// Import handles POST /api/users/import
+// Takes the body of POST /api/users/bulk, with far more users
+func (h *UserDataHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var req BulkCreateUsersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	if len(req.Users) == 0 || len(req.Users) > maxImportUsers {
+		respondError(w, http.StatusBadRequest, "VALIDATION_ERROR",
+			fmt.Sprintf("Between 1 and %d users are required", maxImportUsers))
+		return
+	}
+
+	inputs := make([]usecase.UserInput, len(req.Users))
+	for i, u := range req.Users {
+		inputs[i] = usecase.UserInput{Name: u.Name, Email: u.Email}
+	}
+
+	job, err := h.data.StartImport(r.Context(), callerID(r), inputs)
+	if err != nil {
+		h.logg.Error("failed to queue user import", "error", err)
+		handleError(w, err)
+		return
+	}
+	respondJobAccepted(w, job)
+}
+
+// Export handles POST /api/users/{id}/data-export
+// Only the user themselves or an admin may export a user's data
+func (h *UserDataHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimSpace(r.PathValue("id"))
+	if userID == "" {
+		handleError(w, domain.ErrInvalidUserID)
+		return
+	}
+	if claims := GetClaims(r.Context()); claims != nil &&
+		claims.Subject != userID && !claims.HasScope(auth.ScopeAdmin) {
+		emitPermissionDenied(r, "data_export_not_allowed", nil)
+		handleError(w, domain.ErrForbidden)
+		return
+	}
+
+	job, err := h.data.StartDataExport(r.Context(), callerID(r), userID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	respondJobAccepted(w, job)
+}
+
+// Result handles GET /api/jobs/{id}/result, the output of a finished import
+// or export
+func (h *UserDataHandler) Result(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	job, err := h.jobs.Get(r.Context(), id)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+	if !canSeeJob(r, job) {
+		handleError(w, domain.ErrJobNotFound)
+		return
+	}
+
+	body, err := h.data.OpenResult(r.Context(), job)
+	if err != nil {
+		h.logg.Warn("job result unavailable", "error", err, "job_id", job.ID)
+		handleError(w, err)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+job.ID+`.json"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		h.logg.Warn("job result download interrupted", "error", err, "job_id", job.ID)
+	}
+}
+
+// callerID returns the authenticated subject of r, empty without authentication
+func callerID(r *http.Request) string {
+	if claims := GetClaims(r.Context()); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
@@ -0,0 +1,312 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/auth"
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// jobsFixture serves the user import and export routes and the job routes
+// over the users and orders of orderHandler, authenticating requests as the
+// user named by the X-Test-User header with the scopes of X-Test-Scope
+type jobsFixture struct {
+	h    http.Handler
+	jobs *usecase.JobRunner
+}
+
+func newJobsFixture(t *testing.T) *jobsFixture {
+	t.Helper()
+	logg := logger.New("error")
+	_, users, orders := orderHandler(t)
+	jobs := usecase.NewJobRunner(memory.NewJobQueue(), memory.NewJobRecordRepository(), usecase.JobRunnerPolicy{
+		Priorities: map[domain.JobPriority]usecase.JobPriorityPolicy{
+			domain.JobPriorityDefault: {Concurrency: 1},
+			domain.JobPriorityBulk:    {Concurrency: 1},
+		},
+		PollInterval: 5 * time.Millisecond,
+		MaxAttempts:  1,
+	}, logg)
+	data := usecase.NewUserDataService(usecase.NewUserService(users, memory.NewUserCache(), logg), orders,
+		blob.NewMemoryStore(), jobs, usecase.UserDataPolicy{}, logg)
+	data.Register()
+
+	dataHandler := NewUserDataHandler(data, jobs, logg)
+	jobHandler := NewJobHandler(jobs, logg)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users/import", dataHandler.Import)
+	mux.HandleFunc("POST /api/users/{id}/data-export", dataHandler.Export)
+	mux.HandleFunc("GET /api/jobs/{id}", jobHandler.GetByID)
+	mux.HandleFunc("GET /api/jobs/{id}/result", dataHandler.Result)
+	return &jobsFixture{
+		h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-Test-User"); user != "" {
+				claims := &auth.Claims{Subject: user, Scope: r.Header.Get("X-Test-Scope")}
+				r = r.WithContext(context.WithValue(r.Context(), ClaimsKey, claims))
+			}
+			mux.ServeHTTP(w, r)
+		}),
+		jobs: jobs,
+	}
+}
+
+// start runs the queued jobs until the test ends
+func (f *jobsFixture) start(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.jobs.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// do sends a request as user, with scope, and returns the response
+func (f *jobsFixture) do(method, target, user, scope, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if user != "" {
+		r.Header.Set("X-Test-User", user)
+		r.Header.Set("X-Test-Scope", scope)
+	}
+	return serve(f.h, r)
+}
+
+// decodeJob decodes the job of a job status response
+func decodeJob(t *testing.T, rec *httptest.ResponseRecorder) *JobResponse {
+	t.Helper()
+	var body struct {
+		Data *JobResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Data == nil {
+		t.Fatalf("decoding job %q: %v", rec.Body, err)
+	}
+	return body.Data
+}
+
+// accepted checks rec is a 202 pointing at a queued job of jobType and
+// returns the job's ID
+func accepted(t *testing.T, rec *httptest.ResponseRecorder, jobType string) string {
+	t.Helper()
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d %q, want 202", rec.Code, rec.Body)
+	}
+	job := decodeJob(t, rec)
+	if job.ID == "" || job.Type != jobType || job.State != string(domain.JobQueued) || job.Progress != 0 {
+		t.Errorf("job = %+v, want a queued %s job", job, jobType)
+	}
+	if got := rec.Header().Get("Location"); got != "/api/jobs/"+job.ID {
+		t.Errorf("Location = %q, want the job's status URL", got)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	return job.ID
+}
+
+// waitForJob polls the job's status as user until it has finished
+func (f *jobsFixture) waitForJob(t *testing.T, id, user string) *JobResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := f.do(http.MethodGet, "/api/jobs/"+id, user, "", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET job = %d %q, want 200", rec.Code, rec.Body)
+		}
+		job := decodeJob(t, rec)
+		if job.State == string(domain.JobSucceeded) || job.State == string(domain.JobFailed) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s", job.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestUserDataImport(t *testing.T) {
+	f := newJobsFixture(t)
+	f.start(t)
+
+	rec := f.do(http.MethodPost, "/api/users/import", "admin-1", "", `{"users":[
+		{"name":"Grace","email":"grace@example.com"},
+		{"name":"Taken","email":"u1@example.com"}
+	]}`)
+	id := accepted(t, rec, usecase.JobTypeUserImport)
+
+	job := f.waitForJob(t, id, "admin-1")
+	if job.State != string(domain.JobSucceeded) || job.Progress != 100 {
+		t.Fatalf("job = %+v, want succeeded", job)
+	}
+
+	rec = f.do(http.MethodGet, "/api/jobs/"+id+"/result", "admin-1", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET result = %d %q, want 200", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want JSON", got)
+	}
+	if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="`+id+`.json"`; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+	var result struct {
+		Created  int `json:"created"`
+		Failed   int `json:"failed"`
+		Outcomes []struct {
+			UserID string `json:"user_id"`
+			Error  string `json:"error"`
+		} `json:"outcomes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decoding result %q: %v", rec.Body, err)
+	}
+	if result.Created != 1 || result.Failed != 1 || len(result.Outcomes) != 2 ||
+		result.Outcomes[0].UserID == "" || result.Outcomes[1].Error != domain.ErrUserAlreadyExists.Error() {
+		t.Errorf("result = %+v, want Grace created and the taken email failed", result)
+	}
+}
+
+func TestUserDataImportValidation(t *testing.T) {
+	tooMany := `{"users":[` + strings.Repeat(`{"name":"A","email":"a@example.com"},`, maxImportUsers) +
+		`{"name":"A","email":"a@example.com"}]}`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"no users", "", `{"users":[]}`, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{"missing users", "", `{}`, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{"too many users", "", tooMany, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{"malformed body", "", `{"users":[`, http.StatusBadRequest, "INVALID_REQUEST"},
+		{"unknown field", "", `{"users":[{"name":"A","email":"a@example.com","admin":true}]}`, http.StatusBadRequest, "INVALID_REQUEST"},
+		{"unsupported Content-Type", "text/csv", "name,email\nA,a@example.com", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newJobsFixture(t)
+			r := httptest.NewRequest(http.MethodPost, "/api/users/import", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := serve(f.h, r)
+			if rec.Code != tt.wantStatus || errorCode(t, rec) != tt.wantCode {
+				t.Errorf("POST = %d %q, want %d %s", rec.Code, rec.Body, tt.wantStatus, tt.wantCode)
+			}
+			if rec.Header().Get("Location") != "" {
+				t.Errorf("Location = %q on a rejected import", rec.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestUserDataExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		scope      string
+		target     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"own data", "u1", "", "u1", http.StatusAccepted, ""},
+		{"admin", "admin-1", auth.ScopeAdmin, "u2", http.StatusAccepted, ""},
+		{"another user", "u2", "", "u1", http.StatusForbidden, "FORBIDDEN"},
+		{"another user with other scopes", "u2", "orders:read", "u1", http.StatusForbidden, "FORBIDDEN"},
+		{"missing user", "admin-1", auth.ScopeAdmin, "nobody", http.StatusNotFound, "USER_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newJobsFixture(t)
+			rec := f.do(http.MethodPost, "/api/users/"+tt.target+"/data-export", tt.user, tt.scope, "")
+			if tt.wantStatus == http.StatusAccepted {
+				accepted(t, rec, usecase.JobTypeUserDataExport)
+				return
+			}
+			if rec.Code != tt.wantStatus || errorCode(t, rec) != tt.wantCode {
+				t.Errorf("POST = %d %q, want %d %s", rec.Code, rec.Body, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUserDataExportResult(t *testing.T) {
+	f := newJobsFixture(t)
+
+	id := accepted(t, f.do(http.MethodPost, "/api/users/u1/data-export", "u1", "", ""), usecase.JobTypeUserDataExport)
+
+	// Nothing to download before the job has run
+	rec := f.do(http.MethodGet, "/api/jobs/"+id+"/result", "u1", "", "")
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != "JOB_NOT_FINISHED" {
+		t.Errorf("GET result of a queued job = %d %q, want 409 JOB_NOT_FINISHED", rec.Code, rec.Body)
+	}
+
+	f.start(t)
+	if job := f.waitForJob(t, id, "u1"); job.State != string(domain.JobSucceeded) {
+		t.Fatalf("job = %+v, want succeeded", job)
+	}
+
+	tests := []struct {
+		name       string
+		user       string
+		scope      string
+		id         string
+		wantStatus int
+		wantCode   string
+	}{
+		{"owner", "u1", "", id, http.StatusOK, ""},
+		{"admin", "admin-1", auth.ScopeAdmin, id, http.StatusOK, ""},
+		// Other users are told the job is missing, as for an unknown ID
+		{"another user", "u2", "", id, http.StatusNotFound, "JOB_NOT_FOUND"},
+		{"unknown job", "u1", "", "no-such-job", http.StatusNotFound, "JOB_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.do(http.MethodGet, "/api/jobs/"+tt.id+"/result", tt.user, tt.scope, "")
+			if rec.Code != tt.wantStatus || errorCode(t, rec) != tt.wantCode {
+				t.Fatalf("GET result = %d %q, want %d %s", rec.Code, rec.Body, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment;") {
+				t.Errorf("Content-Disposition = %q, want an attachment", rec.Header().Get("Content-Disposition"))
+			}
+			var export struct {
+				User struct {
+					ID    string `json:"id"`
+					Email string `json:"email"`
+				} `json:"user"`
+				Orders []struct {
+					ID     string  `json:"id"`
+					Amount float64 `json:"amount"`
+				} `json:"orders"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &export); err != nil {
+				t.Fatalf("decoding export %q: %v", rec.Body, err)
+			}
+			if export.User.ID != "u1" || export.User.Email != "u1@example.com" || len(export.Orders) != 2 {
+				t.Errorf("export = %+v, want u1 with o1 and o3", export)
+			}
+			for _, o := range export.Orders {
+				if (o.ID != "o1" && o.ID != "o3") || o.Amount != 10 {
+					t.Errorf("exported order = %+v, want one of u1's", o)
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
 	"github.com/TopThisHat/stdlib-golang-api/internal/usecase"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
 )
 
 // UserHandler handles HTTP requests for user operations
@@ -30,6 +32,30 @@ type CreateUserRequest struct {
 	Email string `json:"email"`
 }
 
+// maxBulkUsers is the most users one bulk creation request may carry
+const maxBulkUsers = 100
+
+// BulkCreateUsersRequest represents the request body for creating users in bulk
+type BulkCreateUsersRequest struct {
+	Users []CreateUserRequest `json:"users"`
+}
+
+// BulkUserResult is the outcome for one user of a bulk creation, at the
+// index it had in the request
+type BulkUserResult struct {
+	Index  int           `json:"index"`
+	Status int           `json:"status"`
+	User   *UserResponse `json:"user,omitempty"`
+	Error  *APIError     `json:"error,omitempty"`
+}
+
+// BulkCreateUsersResponse represents the response body for bulk user creation
+type BulkCreateUsersResponse struct {
+	Results []BulkUserResult `json:"results"`
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+}
+
 // UpdateUserRequest represents the request body for updating a user
 type UpdateUserRequest struct {
 	Name  string `json:"name,omitempty"`
@@ -69,7 +95,7 @@ func toUserListResponse(users []*domain.User) []*UserResponse {
 func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateUserRequest
 	if err := decodeJSON(r, &req); err != nil {
-		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		respondDecodeError(w, err)
 		return
 	}
 
@@ -94,7 +120,75 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusCreated, toUserResponse(user))
 }
 
-// GetByID handles GET /api/users/{id}
+// BulkCreate handles POST /api/users/bulk
+// Responds 201 when every user was created, else 207 with a result per user
+func (h *UserHandler) BulkCreate(w http.ResponseWriter, r *http.Request) {
+	var req BulkCreateUsersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	if len(req.Users) == 0 || len(req.Users) > maxBulkUsers {
+		respondError(w, http.StatusBadRequest, "VALIDATION_ERROR",
+			fmt.Sprintf("Between 1 and %d users are required", maxBulkUsers))
+		return
+	}
+
+	// Users missing a required field never reach the service
+	results := make([]BulkUserResult, len(req.Users))
+	var inputs []usecase.UserInput
+	var positions []int
+	for i, u := range req.Users {
+		results[i].Index = i
+		switch {
+		case strings.TrimSpace(u.Name) == "":
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = &APIError{Code: "VALIDATION_ERROR", Message: "Name is required"}
+		case strings.TrimSpace(u.Email) == "":
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = &APIError{Code: "VALIDATION_ERROR", Message: "Email is required"}
+		default:
+			inputs = append(inputs, usecase.UserInput{Name: u.Name, Email: u.Email})
+			positions = append(positions, i)
+		}
+	}
+
+	if len(inputs) > 0 {
+		creations, err := h.userService.CreateUsers(r.Context(), inputs)
+		if err != nil {
+			h.logg.Error("failed to create users", "error", err)
+			handleError(w, err)
+			return
+		}
+		for j, c := range creations {
+			result := &results[positions[j]]
+			if c.Err != nil {
+				status, code, message := mapDomainErrorToHTTP(c.Err)
+				result.Status = status
+				result.Error = &APIError{Code: code, Message: message}
+				continue
+			}
+			result.Status = http.StatusCreated
+			result.User = toUserResponse(c.User)
+		}
+	}
+
+	resp := BulkCreateUsersResponse{Results: results}
+	for _, result := range results {
+		if result.Error != nil {
+			resp.Failed++
+		} else {
+			resp.Created++
+		}
+	}
+	status := http.StatusCreated
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	respondJSON(w, status, resp)
+}
+
+// GetByID handles GET /api/users/{id}, trimmed to ?fields= when given
 func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -102,13 +196,21 @@ func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	fields, ok := bindFieldsOrRespond(w, r, reflect.TypeFor[UserResponse]())
+	if !ok {
+		return
+	}
+
 	user, err := h.userService.GetUserByID(r.Context(), id)
 	if err != nil {
 		handleError(w, err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, toUserResponse(user))
+	if notModified(w, r, etag(w, r, user.Version)) {
+		return
+	}
+	respondFields(w, http.StatusOK, toUserResponse(user), fields)
 }
 
 // Update handles PUT /api/users/{id}
@@ -119,9 +221,14 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	var req UpdateUserRequest
 	if err := decodeJSON(r, &req); err != nil {
-		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		respondDecodeError(w, err)
 		return
 	}
 
@@ -132,6 +239,52 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, r, user.Version)
+	respondJSON(w, http.StatusOK, toUserResponse(user))
+}
+
+// PatchUserRequest is the merge patch body for partially updating a user
+type PatchUserRequest struct {
+	Name  PatchField[string] `json:"name"`
+	Email PatchField[string] `json:"email"`
+}
+
+// Patch handles PATCH /api/users/{id} with a JSON merge patch; members left
+// out are unchanged, and neither field can be removed
+func (h *UserHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "User ID is required")
+		return
+	}
+
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var req PatchUserRequest
+	if !decodeMergePatch(w, r, &req) {
+		return
+	}
+	errs := patchErrors{}
+	errs.required("name", req.Name.Null)
+	errs.required("email", req.Email.Null)
+	if !errs.respond(w) {
+		return
+	}
+
+	user, err := h.userService.PatchUser(r.Context(), id, domain.UserPatch{
+		Name:  optional(req.Name),
+		Email: optional(req.Email),
+	})
+	if err != nil {
+		h.logg.Error("failed to patch user", "error", err, "user_id", id)
+		handleError(w, err)
+		return
+	}
+
+	setETag(w, r, user.Version)
 	respondJSON(w, http.StatusOK, toUserResponse(user))
 }
 
@@ -143,6 +296,11 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	if err := h.userService.DeleteUser(r.Context(), id); err != nil {
 		h.logg.Error("failed to delete user", "error", err, "user_id", id)
 		handleError(w, err)
@@ -152,21 +310,86 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"message": "User deleted successfully"})
 }
 
-// List handles GET /api/users
+// List handles GET /api/users?limit=&offset= or ?limit=&cursor= (keyset
+// pagination from the next_cursor or prev_cursor of an earlier page), with
+// optional filters, sort (see parseListQuery) and fields; pages carry the
+// total and Link headers. Streams every user as NDJSON instead when the
+// request accepts application/x-ndjson.
 func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
-	limit := parseIntQueryParam(r, "limit", 20)
-	offset := parseIntQueryParam(r, "offset", 0)
+	if acceptsNDJSON(r) {
+		h.export(w, r)
+		return
+	}
+
+	req, ok := bindListOrRespond(w, r, domain.UserQueryFields)
+	if !ok {
+		return
+	}
+	fields, ok := bindFieldsOrRespond(w, r, reflect.TypeFor[UserResponse]())
+	if !ok {
+		return
+	}
+	req.Query.Fields = fields.sources(nil)
+	req.Page.Fields = req.Query.Fields
+
+	total := -1
+	if req.Total {
+		n, err := h.userService.CountUsers(r.Context(), req.Query.Filters)
+		if err != nil {
+			h.logg.Error("failed to count users", "error", err)
+			handleError(w, err)
+			return
+		}
+		total = n
+	}
 
-	users, err := h.userService.ListUsers(r.Context(), limit, offset)
+	if req.Keyset {
+		result, err := h.userService.ListUsersPage(r.Context(), req.Page)
+		if err != nil {
+			h.logg.Error("failed to list users", "error", err)
+			handleError(w, err)
+			return
+		}
+		req.respond(w, r, "users", toUserListResponse(result.Items), listPage{
+			Rows:  len(result.Items),
+			Total: total,
+			Next:  encodeCursor(cursorAfter, result.Next),
+			Prev:  encodeCursor(cursorBefore, result.Prev),
+		}, fields)
+		return
+	}
+
+	users, err := h.userService.SearchUsers(r.Context(), req.Query, req.Limit, req.Offset)
 	if err != nil {
 		h.logg.Error("failed to list users", "error", err)
 		handleError(w, err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"users":  toUserListResponse(users),
-		"limit":  limit,
-		"offset": offset,
+	req.respond(w, r, "users", toUserListResponse(users), listPage{
+		Rows:  len(users),
+		Total: total,
+		Last: func() domain.Cursor {
+			last := users[len(users)-1]
+			return domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		},
+	}, fields)
+}
+
+// export streams users as NDJSON (Accept: application/x-ndjson), one
+// UserResponse per line; limit and offset are optional and uncapped
+func (h *UserHandler) export(w http.ResponseWriter, r *http.Request) {
+	var params StreamParams
+	if !bindQueryOrRespond(w, r, &params) {
+		return
+	}
+
+	stream := newNDJSONWriter(w)
+	err := h.userService.StreamUsers(r.Context(), params.Limit, params.Offset, func(u *domain.User) error {
+		return stream.Write(toUserResponse(u))
 	})
+	if err != nil && r.Context().Err() == nil {
+		h.logg.Error("failed to export users", "error", err)
+	}
+	stream.Close(err)
 }
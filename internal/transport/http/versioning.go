@@ -0,0 +1,269 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// API Versioning
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// APIVersion is a major version of the REST API. A breaking change to a
+// response shape ships in a new version, while older versions keep the old
+// shape; handlers build APIVersion1 DTOs and responseAdapters reshape them.
+type APIVersion int
+
+const (
+	APIVersion1 APIVersion = 1
+	// APIVersion2 returns order amounts and prices as decimal strings
+	APIVersion2 APIVersion = 2
+
+	// LatestAPIVersion is the newest version served
+	LatestAPIVersion = APIVersion2
+)
+
+// APIVersionKey is the context key of the negotiated version
+const APIVersionKey contextKey = "api_version"
+
+// APIVersionHeader names the version that served a response
+const APIVersionHeader = "API-Version"
+
+// APIVersionPolicy configures version negotiation
+type APIVersionPolicy struct {
+	// Default is served to requests naming no version (0 means APIVersion1)
+	Default APIVersion
+	// Deprecated versions are announced with the Deprecation header from the given time
+	Deprecated map[APIVersion]time.Time
+	// Sunset versions are announced with the Sunset header and answer 410 Gone after it
+	Sunset map[APIVersion]time.Time
+}
+
+// Supported reports whether v is a version this server serves
+func (v APIVersion) Supported() bool {
+	return v >= APIVersion1 && v <= LatestAPIVersion
+}
+
+// GetAPIVersion returns the version negotiated for the request (APIVersion1
+// outside /api/)
+func GetAPIVersion(ctx context.Context) APIVersion {
+	if v, ok := ctx.Value(APIVersionKey).(APIVersion); ok {
+		return v
+	}
+	return APIVersion1
+}
+
+// APIVersioning negotiates the version of every /api/ request, from a
+// /api/v{n}/ path prefix or a version parameter on the JSON media type
+// (Accept: application/json; version=2, or any other supported format's
+// media type), falling back to the policy default.
+// Versioned paths are rewritten to the unversioned route, so the mux,
+// authorization and rate limits see one path per route. Responses carry the
+// API-Version header, plus Deprecation, Sunset and a successor-version Link
+// for versions being retired.
+func APIVersioning(policy APIVersionPolicy) Middleware {
+	if policy.Default == 0 {
+		policy.Default = APIVersion1
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api" && !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			pathVersion, rest, inPath := splitVersionedPath(r.URL.Path)
+			if inPath && !pathVersion.Supported() {
+				respondError(w, http.StatusNotFound, "UNSUPPORTED_API_VERSION",
+					fmt.Sprintf("API version %d is not supported (latest is %d)", pathVersion, LatestAPIVersion))
+				return
+			}
+			headerVersion, inHeader, err := acceptedVersion(r.Header.Values("Accept"))
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "INVALID_API_VERSION", err.Error())
+				return
+			}
+			if inHeader && !headerVersion.Supported() {
+				respondError(w, http.StatusNotAcceptable, "UNSUPPORTED_API_VERSION",
+					fmt.Sprintf("API version %d is not supported (latest is %d)", headerVersion, LatestAPIVersion))
+				return
+			}
+			if inPath && inHeader && pathVersion != headerVersion {
+				respondError(w, http.StatusBadRequest, "API_VERSION_CONFLICT",
+					fmt.Sprintf("The path asks for API version %d but the Accept header for %d", pathVersion, headerVersion))
+				return
+			}
+
+			version := policy.Default
+			switch {
+			case inPath:
+				version = pathVersion
+			case inHeader:
+				version = headerVersion
+			}
+			if !inPath {
+				// The same URL serves different shapes depending on Accept
+				w.Header().Add("Vary", "Accept")
+			}
+
+			canonical := r.URL.Path
+			if inPath {
+				canonical = rest
+			}
+			w.Header().Set(APIVersionHeader, strconv.Itoa(int(version)))
+			if !policy.announceRetirement(w, version, canonical) {
+				respondError(w, http.StatusGone, "API_VERSION_SUNSET",
+					fmt.Sprintf("API version %d has been retired; use version %d", version, LatestAPIVersion))
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), APIVersionKey, version))
+			if inPath {
+				u := *r.URL
+				u.Path = rest
+				if u.RawPath != "" {
+					_, u.RawPath, _ = splitVersionedPath(u.RawPath)
+				}
+				r.URL = &u
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// announceRetirement sets the Deprecation, Sunset and Link headers for a
+// version being retired, returning false once its sunset has passed
+func (p APIVersionPolicy) announceRetirement(w http.ResponseWriter, version APIVersion, path string) bool {
+	deprecated, isDeprecated := p.Deprecated[version]
+	sunset, hasSunset := p.Sunset[version]
+	if !isDeprecated && !hasSunset {
+		return true
+	}
+
+	if isDeprecated {
+		// RFC 9745: the time the version was (or will be) deprecated
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecated.Unix(), 10))
+	}
+	if hasSunset {
+		// RFC 8594
+		w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	if version < LatestAPIVersion {
+		successor := fmt.Sprintf("/api/v%d%s", LatestAPIVersion, strings.TrimPrefix(path, "/api"))
+		w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+	}
+	return !hasSunset || time.Now().Before(sunset)
+}
+
+// splitVersionedPath splits "/api/v2/orders" into version 2 and "/api/orders";
+// found is false for paths without a version segment
+func splitVersionedPath(path string) (version APIVersion, rest string, found bool) {
+	tail, ok := strings.CutPrefix(path, "/api/v")
+	if !ok {
+		return 0, path, false
+	}
+	digits, rest, hasRest := strings.Cut(tail, "/")
+	if digits == "" || digits[0] < '1' || digits[0] > '9' {
+		return 0, path, false
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, path, false
+	}
+	if !hasRest {
+		return APIVersion(n), "/api", true
+	}
+	return APIVersion(n), "/api/" + rest, true
+}
+
+// acceptedVersion reads the version parameter of a supported media range in the
+// Accept headers; found is false when none names a version
+func acceptedVersion(accept []string) (version APIVersion, found bool, err error) {
+	for _, header := range accept {
+		for _, mediaRange := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			_, known := codecs[mediaType]
+			if err != nil || (!known && !ndjsonMediaTypes[mediaType] && mediaType != "*/*") {
+				continue
+			}
+			raw, ok := params["version"]
+			if !ok {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimPrefix(raw, "v"))
+			if err != nil || n < 1 {
+				return 0, false, fmt.Errorf("invalid API version %q in the Accept header", raw)
+			}
+			return APIVersion(n), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Response Shape Adapters
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// responseAdapters reshape response DTOs for the version that changed them,
+// keyed by that version and the type of the previous version's shape. A
+// response for version N passes through the adapters of versions 2 to N in
+// turn.
+var responseAdapters = map[APIVersion]map[reflect.Type]func(any) any{
+	APIVersion2: {
+		reflect.TypeFor[*OrderResponse](): func(v any) any { return toOrderResponseV2(v.(*OrderResponse)) },
+	},
+}
+
+// adaptResponse reshapes an APIVersion1 response body for version
+func adaptResponse(version APIVersion, data any) any {
+	for v := APIVersion1 + 1; v <= version; v++ {
+		if adapters := responseAdapters[v]; len(adapters) > 0 {
+			data = adaptValue(adapters, data)
+		}
+	}
+	return data
+}
+
+// adaptValue applies the adapter for data's type, looking inside the maps and
+// slices that list endpoints wrap their items in
+func adaptValue(adapters map[reflect.Type]func(any) any, data any) any {
+	if data == nil {
+		return nil
+	}
+	t := reflect.TypeOf(data)
+	if adapt, ok := adapters[t]; ok {
+		return adapt(data)
+	}
+	if m, ok := data.(map[string]interface{}); ok {
+		adapted := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			adapted[k] = adaptValue(adapters, v)
+		}
+		return adapted
+	}
+	if t.Kind() == reflect.Slice {
+		if _, ok := adapters[t.Elem()]; ok {
+			items := reflect.ValueOf(data)
+			adapted := make([]any, items.Len())
+			for i := range adapted {
+				adapted[i] = adaptValue(adapters, items.Index(i).Interface())
+			}
+			return adapted
+		}
+	}
+	return data
+}
+
+// responseVersion returns the version a response written to w is encoded for
+func responseVersion(w http.ResponseWriter) APIVersion {
+	if nw, ok := w.(*negotiatedWriter); ok {
+		return nw.version
+	}
+	return APIVersion1
+}
@@ -0,0 +1,302 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSplitVersionedPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantVersion APIVersion
+		wantRest    string
+		wantFound   bool
+	}{
+		{"/api/v2/orders", 2, "/api/orders", true},
+		{"/api/v1/orders/o1/items", 1, "/api/orders/o1/items", true},
+		{"/api/v1", 1, "/api", true},
+		{"/api/v10/orders", 10, "/api/orders", true},
+		{"/api/orders", 0, "/api/orders", false},
+		{"/api/v0/orders", 0, "/api/v0/orders", false},
+		{"/api/v02/orders", 0, "/api/v02/orders", false},
+		{"/api/v2x/orders", 0, "/api/v2x/orders", false},
+		{"/api/versions", 0, "/api/versions", false},
+		{"/api/v/orders", 0, "/api/v/orders", false},
+	}
+	for _, tt := range tests {
+		version, rest, found := splitVersionedPath(tt.path)
+		if version != tt.wantVersion || rest != tt.wantRest || found != tt.wantFound {
+			t.Errorf("splitVersionedPath(%q) = %d, %q, %v; want %d, %q, %v",
+				tt.path, version, rest, found, tt.wantVersion, tt.wantRest, tt.wantFound)
+		}
+	}
+}
+
+func TestAcceptedVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      []string
+		wantVersion APIVersion
+		wantFound   bool
+		wantErr     bool
+	}{
+		{"no Accept", nil, 0, false, false},
+		{"no version", []string{"application/json"}, 0, false, false},
+		{"json", []string{"application/json; version=2"}, 2, true, false},
+		{"v prefix", []string{"application/json;version=v2"}, 2, true, false},
+		{"other codec", []string{"application/xml; version=1"}, 1, true, false},
+		{"ndjson", []string{"application/x-ndjson; version=2"}, 2, true, false},
+		{"wildcard", []string{"*/*; version=2"}, 2, true, false},
+		{"unsupported media type", []string{"text/html; version=2"}, 0, false, false},
+		{"later range", []string{"text/html; version=1, application/json; version=2"}, 2, true, false},
+		{"later header", []string{"text/html", "application/json; version=2"}, 2, true, false},
+		{"not a number", []string{"application/json; version=two"}, 0, false, true},
+		{"zero", []string{"application/json; version=0"}, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, found, err := acceptedVersion(tt.accept)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if version != tt.wantVersion || found != tt.wantFound {
+				t.Errorf("acceptedVersion = %d, %v; want %d, %v", version, found, tt.wantVersion, tt.wantFound)
+			}
+		})
+	}
+}
+
+// versionRecorder is a handler recording the version and path it was
+// served
+type versionRecorder struct {
+	version APIVersion
+	path    string
+	rawPath string
+	reached bool
+}
+
+func (vr *versionRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vr.version, vr.path, vr.rawPath, vr.reached = GetAPIVersion(r.Context()), r.URL.Path, r.URL.RawPath, true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errorCode returns the error code of a JSON error response, or "" for
+// other responses
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	if rec.Code < 400 {
+		return ""
+	}
+	var body struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == nil {
+		t.Fatalf("decoding error %q: %v", rec.Body, err)
+	}
+	return body.Error.Code
+}
+
+func TestAPIVersioningNegotiation(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		defaultV    APIVersion
+		wantStatus  int
+		wantCode    string
+		wantVersion APIVersion
+		wantPath    string
+		wantVary    bool
+	}{
+		{name: "no version", target: "/api/orders", wantStatus: http.StatusNoContent, wantVersion: 1, wantPath: "/api/orders", wantVary: true},
+		{name: "policy default", target: "/api/orders", defaultV: 2, wantStatus: http.StatusNoContent, wantVersion: 2, wantPath: "/api/orders", wantVary: true},
+		{name: "path", target: "/api/v2/orders/o1", wantStatus: http.StatusNoContent, wantVersion: 2, wantPath: "/api/orders/o1"},
+		{name: "path over the default", target: "/api/v1/orders", defaultV: 2, wantStatus: http.StatusNoContent, wantVersion: 1, wantPath: "/api/orders"},
+		{name: "accept", target: "/api/orders", accept: "application/json; version=2", wantStatus: http.StatusNoContent, wantVersion: 2, wantPath: "/api/orders", wantVary: true},
+		{name: "path and accept agreeing", target: "/api/v2/orders", accept: "application/json; version=2", wantStatus: http.StatusNoContent, wantVersion: 2, wantPath: "/api/orders"},
+		{name: "path and accept conflicting", target: "/api/v1/orders", accept: "application/json; version=2", wantStatus: http.StatusBadRequest, wantCode: "API_VERSION_CONFLICT"},
+		{name: "unknown version in the path", target: "/api/v3/orders", wantStatus: http.StatusNotFound, wantCode: "UNSUPPORTED_API_VERSION"},
+		{name: "unknown version in accept", target: "/api/orders", accept: "application/json; version=3", wantStatus: http.StatusNotAcceptable, wantCode: "UNSUPPORTED_API_VERSION"},
+		{name: "malformed version in accept", target: "/api/orders", accept: "application/json; version=latest", wantStatus: http.StatusBadRequest, wantCode: "INVALID_API_VERSION"},
+		{name: "outside the API", target: "/health", accept: "application/json; version=3", wantStatus: http.StatusNoContent, wantVersion: 1, wantPath: "/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &versionRecorder{}
+			h := APIVersioning(APIVersionPolicy{Default: tt.defaultV})(next)
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := serve(h, r)
+
+			if rec.Code != tt.wantStatus || errorCode(t, rec) != tt.wantCode {
+				t.Fatalf("status = %d %s, want %d %s", rec.Code, errorCode(t, rec), tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantCode != "" {
+				if next.reached {
+					t.Error("rejected request reached the handler")
+				}
+				return
+			}
+			if next.version != tt.wantVersion || next.path != tt.wantPath {
+				t.Errorf("handler saw version %d at %s, want %d at %s", next.version, next.path, tt.wantVersion, tt.wantPath)
+			}
+			if tt.target == "/health" {
+				if got := rec.Header().Get(APIVersionHeader); got != "" {
+					t.Errorf("API-Version = %q outside the API, want none", got)
+				}
+				return
+			}
+			if got := rec.Header().Get(APIVersionHeader); got != strconv.Itoa(int(tt.wantVersion)) {
+				t.Errorf("API-Version = %q, want %d", got, tt.wantVersion)
+			}
+			if vary := rec.Header().Get("Vary") == "Accept"; vary != tt.wantVary {
+				t.Errorf("Vary = %q, want Accept %v", rec.Header().Get("Vary"), tt.wantVary)
+			}
+		})
+	}
+}
+
+func TestAPIVersioningKeepsEscapedPaths(t *testing.T) {
+	next := &versionRecorder{}
+	serve(APIVersioning(APIVersionPolicy{})(next), httptest.NewRequest(http.MethodGet, "/api/v2/files/a%2Fb", nil))
+	if next.path != "/api/files/a/b" || next.rawPath != "/api/files/a%2Fb" {
+		t.Errorf("handler saw %q (raw %q), want the escaped path without its version", next.path, next.rawPath)
+	}
+}
+
+func TestAPIVersioningRetirement(t *testing.T) {
+	deprecated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	policy := APIVersionPolicy{
+		Deprecated: map[APIVersion]time.Time{APIVersion1: deprecated},
+		Sunset:     map[APIVersion]time.Time{APIVersion1: sunset},
+	}
+	h := APIVersioning(policy)(&versionRecorder{})
+
+	rec := serve(h, httptest.NewRequest(http.MethodGet, "/api/v1/orders/o1", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want the deprecated version still served", rec.Code)
+	}
+	want := map[string]string{
+		"Deprecation": "@" + strconv.FormatInt(deprecated.Unix(), 10),
+		"Sunset":      sunset.UTC().Format(http.TimeFormat),
+		"Link":        `</api/v2/orders/o1>; rel="successor-version"`,
+	}
+	for header, value := range want {
+		if got := rec.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+
+	// The latest version announces nothing
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/v2/orders/o1", nil))
+	for header := range want {
+		if got := rec.Header().Get(header); got != "" {
+			t.Errorf("%s = %q on the latest version, want none", header, got)
+		}
+	}
+
+	// Deprecated without a sunset is served indefinitely
+	h = APIVersioning(APIVersionPolicy{Deprecated: policy.Deprecated})(&versionRecorder{})
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/orders", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Deprecation") == "" || rec.Header().Get("Sunset") != "" {
+		t.Errorf("deprecated version = %d %v, want it served with Deprecation alone", rec.Code, rec.Header())
+	}
+
+	// Past its sunset, a version is gone
+	next := &versionRecorder{}
+	h = APIVersioning(APIVersionPolicy{Sunset: map[APIVersion]time.Time{APIVersion1: time.Now().Add(-time.Minute)}})(next)
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil))
+	if rec.Code != http.StatusGone || errorCode(t, rec) != "API_VERSION_SUNSET" || next.reached {
+		t.Errorf("sunset version = %d %s, want 410 API_VERSION_SUNSET", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Sunset") == "" {
+		t.Error("410 without the Sunset header")
+	}
+}
+
+func TestAdaptResponse(t *testing.T) {
+	v1 := &OrderResponse{
+		ID:     "o1",
+		Amount: 10.5,
+		Items:  []OrderItemResponse{{ProductID: "p1", Quantity: 3, Price: 3.5, PriceDisplay: "$3.50"}},
+	}
+
+	if got := adaptResponse(APIVersion1, v1); got != v1 {
+		t.Errorf("version 1 response = %#v, want it unchanged", got)
+	}
+	v2, ok := adaptResponse(APIVersion2, v1).(*OrderResponseV2)
+	if !ok || v2.ID != "o1" || v2.Amount != "10.5" || len(v2.Items) != 1 || v2.Items[0].Price != "3.5" {
+		t.Fatalf("version 2 response = %#v, want decimal strings", v2)
+	}
+
+	// Inside the maps and slices lists wrap their items in
+	list := adaptResponse(APIVersion2, map[string]interface{}{"orders": []*OrderResponse{v1}, "has_more": true})
+	m, _ := list.(map[string]interface{})
+	items, _ := m["orders"].([]any)
+	if len(items) != 1 || m["has_more"] != true {
+		t.Fatalf("version 2 list = %#v, want its members kept", list)
+	}
+	if item, ok := items[0].(*OrderResponseV2); !ok || item.Amount != "10.5" {
+		t.Errorf("listed order = %#v, want it adapted", items[0])
+	}
+
+	// Other shapes pass through
+	user := &UserResponse{ID: "u1"}
+	if got := adaptResponse(APIVersion2, user); got != user {
+		t.Errorf("user response = %#v, want it unchanged", got)
+	}
+	if got := adaptResponse(APIVersion2, nil); got != nil {
+		t.Errorf("nil response = %#v, want nil", got)
+	}
+}
+
+func TestVersionedOrderResponses(t *testing.T) {
+	orders, _, _ := orderHandler(t)
+	routes := http.NewServeMux()
+	negotiatedRoutes{routes}.HandleFunc("/", orders.ServeHTTP)
+	h := APIVersioning(APIVersionPolicy{})(routes)
+
+	tests := []struct {
+		target string
+		accept string
+		want   any // Amount of o1
+	}{
+		{"/api/orders/o1", "", float64(10)},
+		{"/api/v1/orders/o1", "", float64(10)},
+		{"/api/v2/orders/o1", "", "10"},
+		{"/api/orders/o1", "application/json; version=2", "10"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		rec := serve(h, r)
+		var body struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %q", tt.target, rec.Code, rec.Body)
+		}
+		if body.Data["amount"] != tt.want {
+			t.Errorf("GET %s (Accept %q) amount = %#v, want %#v", tt.target, tt.accept, body.Data["amount"], tt.want)
+		}
+	}
+
+	status, body, _ := getJSON(t, h, "/api/v2/orders")
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v2/orders = %d", status)
+	}
+	for _, item := range body["orders"].([]any) {
+		if amount := item.(map[string]any)["amount"]; amount != "10" {
+			t.Errorf("listed version 2 amount = %#v, want a decimal string", amount)
+		}
+	}
+}
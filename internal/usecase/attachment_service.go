@@ -0,0 +1,651 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// AttachmentPolicy configures attachment uploads and downloads
+type AttachmentPolicy struct {
+	KeyPrefix      string        // Blob key prefix for attachments
+	MaxSize        int64         // Largest accepted upload in bytes
+	UploadURLTTL   time.Duration // Lifetime of presigned upload URLs
+	DownloadURLTTL time.Duration // Lifetime of presigned download URLs
+
+	// AllowedContentTypes limits uploads to these content types. Entries
+	// ending in "/" match a prefix (e.g. "image/"); empty allows any type.
+	AllowedContentTypes []string
+
+	// PartPrefix is the blob key prefix of the bytes of resumable uploads
+	// waiting to fill a part (see AppendUpload)
+	PartPrefix string
+}
+
+// DefaultAttachmentPolicy returns sensible defaults
+func DefaultAttachmentPolicy() AttachmentPolicy {
+	return AttachmentPolicy{
+		KeyPrefix:      "attachments/",
+		PartPrefix:     "attachment-parts/",
+		MaxSize:        25 << 20, // 25 MB
+		UploadURLTTL:   15 * time.Minute,
+		DownloadURLTTL: 5 * time.Minute,
+	}
+}
+
+// PresignedURL is a time-limited URL for direct blob store access
+type PresignedURL struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+// PresignedPost is a signed form for a browser upload: POST multipart/form-data
+// to URL with Fields, then the file, before ExpiresAt
+type PresignedPost struct {
+	URL       string
+	Fields    map[string]string
+	ExpiresAt time.Time
+}
+
+// AttachmentService manages user uploads. Clients upload and download
+// directly against the blob store with presigned URLs and report finished
+// uploads with CompleteUpload, which checks the stored object. An external
+// malware scanner reports each object's verdict with RecordScanResult, and
+// download URLs are only issued for completed attachments that scanned clean.
+// Resumable uploads go through the service instead, a part at a time.
+type AttachmentService struct {
+	repo   domain.AttachmentRepository
+	store  blob.Store
+	locks  domain.SemaphoreStore // Per resumable upload, across replicas
+	policy AttachmentPolicy
+	logg   *logger.Logger
+}
+
+// NewAttachmentService creates an attachment service. locks serializes the
+// requests writing to each resumable upload across replicas. Zero policy
+// fields use the defaults.
+func NewAttachmentService(repo domain.AttachmentRepository, store blob.Store, locks domain.SemaphoreStore, policy AttachmentPolicy, logg *logger.Logger) *AttachmentService {
+	defaults := DefaultAttachmentPolicy()
+	if policy.KeyPrefix == "" {
+		policy.KeyPrefix = defaults.KeyPrefix
+	}
+	if policy.PartPrefix == "" {
+		policy.PartPrefix = defaults.PartPrefix
+	}
+	if policy.MaxSize <= 0 {
+		policy.MaxSize = defaults.MaxSize
+	}
+	if policy.UploadURLTTL <= 0 {
+		policy.UploadURLTTL = defaults.UploadURLTTL
+	}
+	if policy.DownloadURLTTL <= 0 {
+		policy.DownloadURLTTL = defaults.DownloadURLTTL
+	}
+	return &AttachmentService{
+		repo:   repo,
+		store:  store,
+		locks:  locks,
+		policy: policy,
+		logg:   logg,
+	}
+}
+
+// presigner returns the store's presigned URL support
+func (s *AttachmentService) presigner() (blob.PresignedURLGenerator, error) {
+	p, ok := s.store.(blob.PresignedURLGenerator)
+	if !ok {
+		return nil, fmt.Errorf("%w: blob store does not support presigned URLs", domain.ErrInternalError)
+	}
+	return p, nil
+}
+
+// CreateUpload records a pending attachment and returns the URL the client
+// PUTs the file to
+func (s *AttachmentService) CreateUpload(ctx context.Context, ownerID, filename, contentType string, size int64) (*domain.Attachment, *PresignedURL, error) {
+	a, _, err := s.newAttachment(ownerID, filename, contentType, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	presigner, err := s.presigner()
+	if err != nil {
+		return nil, nil, err
+	}
+	url, err := presigner.GeneratePresignedUploadURL(ctx, a.Key, a.ContentType, s.policy.UploadURLTTL)
+	if err != nil {
+		s.logg.Error("failed to presign attachment upload", "error", err, "attachment_id", a.ID)
+		return nil, nil, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+
+	if err := s.repo.Create(ctx, a); err != nil {
+		return nil, nil, err
+	}
+
+	s.logg.Info("attachment upload started", "attachment_id", a.ID, "owner_id", ownerID, "size", size)
+	return a, &PresignedURL{URL: url, ExpiresAt: time.Now().Add(s.policy.UploadURLTTL)}, nil
+}
+
+// CreatePostUpload records a pending attachment and returns a presigned POST
+// form, so a browser can upload the file directly to the store. The store
+// enforces the policy: the attachment's key, at most the declared size, and
+// the declared content type (or any type under its allowed prefix).
+func (s *AttachmentService) CreatePostUpload(ctx context.Context, ownerID, filename, contentType string, size int64) (*domain.Attachment, *PresignedPost, error) {
+	a, typePrefix, err := s.newAttachment(ownerID, filename, contentType, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	presigner, ok := s.store.(blob.PresignedPostGenerator)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: blob store does not support presigned POST uploads", domain.ErrInternalError)
+	}
+	form, err := presigner.GeneratePresignedPost(ctx, &blob.PresignedPostInput{
+		Key:               a.Key,
+		ContentType:       a.ContentType,
+		ContentTypePrefix: typePrefix,
+		MinSize:           1,
+		MaxSize:           a.Size,
+		Expiration:        s.policy.UploadURLTTL,
+	})
+	if err != nil {
+		s.logg.Error("failed to presign attachment upload", "error", err, "attachment_id", a.ID)
+		return nil, nil, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+
+	if err := s.repo.Create(ctx, a); err != nil {
+		return nil, nil, err
+	}
+
+	s.logg.Info("attachment upload started", "attachment_id", a.ID, "owner_id", ownerID, "size", size, "method", "post")
+	return a, &PresignedPost{URL: form.URL, Fields: form.Fields, ExpiresAt: time.Now().Add(s.policy.UploadURLTTL)}, nil
+}
+
+// newAttachment validates an upload request against the policy and builds
+// its pending attachment. typePrefix is the allowed prefix the content type
+// matched, or empty when it must match exactly.
+func (s *AttachmentService) newAttachment(ownerID, filename, contentType string, size int64) (a *domain.Attachment, typePrefix string, err error) {
+	if size > s.policy.MaxSize {
+		return nil, "", fmt.Errorf("%w: attachment exceeds %d bytes", domain.ErrInvalidInput, s.policy.MaxSize)
+	}
+	a, err = domain.NewAttachment(uuid.New().String(), ownerID, filename, contentType, size, s.policy.KeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	typePrefix, ok := s.allowedContentType(a.ContentType)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: content type %s is not allowed", domain.ErrInvalidInput, a.ContentType)
+	}
+	return a, typePrefix, nil
+}
+
+// allowedContentType reports whether contentType is allowed by the policy,
+// returning the prefix entry it matched, if any
+func (s *AttachmentService) allowedContentType(contentType string) (prefix string, ok bool) {
+	if len(s.policy.AllowedContentTypes) == 0 {
+		return "", true
+	}
+	contentType = strings.ToLower(contentType)
+	for _, allowed := range s.policy.AllowedContentTypes {
+		allowed = strings.ToLower(allowed)
+		if strings.HasSuffix(allowed, "/") {
+			if strings.HasPrefix(contentType, allowed) {
+				return allowed, true
+			}
+		} else if contentType == allowed {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// sha256Regex matches a hex-encoded SHA-256 digest
+var sha256Regex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// CompleteUpload verifies the client's upload of a against the stored object
+// and marks it uploaded. The object must exist with the declared size and an
+// allowed content type; if checksum (hex SHA-256) is given, the object is read
+// back and must match it. Until completion the attachment can't be downloaded,
+// so a client can't claim an upload that never happened or went wrong.
+func (s *AttachmentService) CompleteUpload(ctx context.Context, a *domain.Attachment, checksum string) (*domain.Attachment, error) {
+	if a.Uploaded() {
+		return a, nil
+	}
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if checksum != "" && !sha256Regex.MatchString(checksum) {
+		return nil, fmt.Errorf("%w: checksum must be a hex SHA-256 digest", domain.ErrInvalidInput)
+	}
+
+	info, err := s.store.HeadObject(ctx, a.Key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, domain.ErrAttachmentNotUploaded
+		}
+		s.logg.Error("failed to inspect uploaded attachment", "error", err, "attachment_id", a.ID)
+		return nil, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+
+	if info.ContentType != "" && info.ContentType != a.ContentType {
+		// POST uploads may pick any type under an allowed prefix
+		if _, ok := s.allowedContentType(info.ContentType); !ok {
+			s.logg.Warn("uploaded attachment has a disallowed content type", "attachment_id", a.ID, "content_type", info.ContentType)
+			return nil, fmt.Errorf("%w: content type %s is not allowed", domain.ErrAttachmentMismatch, info.ContentType)
+		}
+		a.ContentType = info.ContentType
+	}
+
+	if checksum != "" {
+		actual, err := s.checksum(ctx, a.Key)
+		if err != nil {
+			s.logg.Error("failed to checksum uploaded attachment", "error", err, "attachment_id", a.ID)
+			return nil, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+		}
+		if actual != checksum {
+			s.logg.Warn("uploaded attachment checksum mismatch", "attachment_id", a.ID)
+			return nil, fmt.Errorf("%w: checksum does not match", domain.ErrAttachmentMismatch)
+		}
+	}
+
+	if err := a.CompleteUpload(info.Size, info.ETag, checksum, time.Now().UTC()); err != nil {
+		s.logg.Warn("uploaded attachment size mismatch", "attachment_id", a.ID, "declared", a.Size, "stored", info.Size)
+		return nil, fmt.Errorf("%w: stored %d bytes, declared %d", err, info.Size, a.Size)
+	}
+	if err := s.repo.Update(ctx, a); err != nil {
+		return nil, err
+	}
+
+	s.logg.Info("attachment upload completed", "attachment_id", a.ID, "owner_id", a.OwnerID, "size", a.Size)
+	return a, nil
+}
+
+// checksum reads the object at key and returns its hex SHA-256
+func (s *AttachmentService) checksum(ctx context.Context, key string) (string, error) {
+	body, err := s.store.GetObject(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MaxSize returns the largest attachment accepted, in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.policy.MaxSize
+}
+
+// resumablePartSize is the part size of resumable uploads, raised for files
+// that would need more than blob.MaxParts parts
+const resumablePartSize = 8 << 20
+
+// partSize returns the part size of a resumable upload of size bytes
+func partSize(size int64) int64 {
+	return max(resumablePartSize, (size+blob.MaxParts-1)/blob.MaxParts)
+}
+
+// multipart returns the store's multipart upload support
+func (s *AttachmentService) multipart() (blob.MultipartUploader, error) {
+	uploader, ok := s.store.(blob.MultipartUploader)
+	if !ok {
+		return nil, fmt.Errorf("%w: blob store does not support multipart uploads", domain.ErrInternalError)
+	}
+	return uploader, nil
+}
+
+// uploadLockTTL bounds how long a crashed replica keeps an upload locked; the
+// lease is refreshed while a request is being served
+const uploadLockTTL = 30 * time.Second
+
+// lockUpload takes a's upload lock, held across replicas until the permit is
+// released; its context is cancelled if the lease is lost. An upload already
+// locked is ErrUploadLocked. As with every semaphore, a lock store outage is
+// logged and the upload proceeds unlocked.
+func (s *AttachmentService) lockUpload(ctx context.Context, a *domain.Attachment) (*Permit, error) {
+	lock := NewSemaphore(s.locks, "upload:"+a.ID, SemaphorePolicy{Limit: 1, LeaseTTL: uploadLockTTL}, s.logg)
+	permit, err := lock.Acquire(ctx)
+	if errors.Is(err, domain.ErrSemaphoreFull) {
+		return nil, domain.ErrUploadLocked
+	}
+	return permit, err
+}
+
+// partKey is where the bytes of a's resumable upload short of a part wait
+func (s *AttachmentService) partKey(a *domain.Attachment) string {
+	return s.policy.PartPrefix + a.ID
+}
+
+// CreateResumableUpload records a pending attachment and starts the
+// multipart upload its file is then sent to with AppendUpload, in as many
+// requests as the client needs (the tus protocol)
+func (s *AttachmentService) CreateResumableUpload(ctx context.Context, ownerID, filename, contentType string, size int64) (*domain.Attachment, error) {
+	a, _, err := s.newAttachment(ownerID, filename, contentType, size)
+	if err != nil {
+		return nil, err
+	}
+
+	uploader, err := s.multipart()
+	if err != nil {
+		return nil, err
+	}
+	a.UploadID, err = uploader.CreateMultipartUpload(ctx, &blob.UploadInput{Key: a.Key, ContentType: a.ContentType})
+	if err != nil {
+		s.logg.Error("failed to start resumable attachment upload", "error", err, "attachment_id", a.ID)
+		return nil, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+
+	if err := s.repo.Create(ctx, a); err != nil {
+		if abortErr := uploader.AbortMultipartUpload(context.WithoutCancel(ctx), a.Key, a.UploadID); abortErr != nil {
+			s.logg.Warn("failed to abort unrecorded attachment upload", "error", abortErr, "attachment_id", a.ID)
+		}
+		return nil, err
+	}
+
+	s.logg.Info("attachment upload started", "attachment_id", a.ID, "owner_id", ownerID, "size", size, "method", "resumable")
+	return a, nil
+}
+
+// UploadOffset returns how many bytes of a the store received: its size
+// once uploaded
+func (s *AttachmentService) UploadOffset(ctx context.Context, a *domain.Attachment) (int64, error) {
+	if a.Uploaded() {
+		return a.Size, nil
+	}
+	if !a.Resumable() {
+		return 0, domain.ErrAttachmentNotFound
+	}
+	uploader, err := s.multipart()
+	if err != nil {
+		return 0, err
+	}
+	parts, pending, err := s.received(ctx, uploader, a)
+	if err != nil || a.Uploaded() {
+		return a.Size, err
+	}
+	return partsSize(parts) + pending, nil
+}
+
+// AppendUpload writes body to a's resumable upload at offset, which must be
+// the bytes received so far (ErrUploadOffsetMismatch otherwise), and returns
+// the new offset. Bytes short of a part wait in the store for the next
+// request; the request bringing the last byte completes the upload, checked
+// as CompleteUpload checks uploads. A body failing partway is kept up to the
+// failure and its error returned with the new offset: the client resumes
+// from there.
+//
+// One request per upload is served at a time, across replicas; AppendUpload,
+// AbortUpload included, returns ErrUploadLocked to the others.
+func (s *AttachmentService) AppendUpload(ctx context.Context, a *domain.Attachment, offset int64, body io.Reader) (int64, error) {
+	if a.Uploaded() {
+		return uploadedOffset(a, offset)
+	}
+	if !a.Resumable() {
+		return 0, domain.ErrAttachmentNotFound
+	}
+	permit, err := s.lockUpload(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+	defer permit.Release()
+	ctx = permit.Context()
+
+	uploader, err := s.multipart()
+	if err != nil {
+		return 0, err
+	}
+	parts, pending, err := s.received(ctx, uploader, a)
+	if err != nil {
+		return 0, err
+	}
+	if a.Uploaded() {
+		return uploadedOffset(a, offset)
+	}
+	received := partsSize(parts)
+	if offset != received+pending {
+		return received + pending, domain.ErrUploadOffsetMismatch
+	}
+
+	src := io.LimitReader(body, a.Size-offset)
+	if pending > 0 {
+		stored, err := s.store.GetObject(ctx, s.partKey(a))
+		if err != nil {
+			s.logg.Error("failed to read pending attachment bytes", "error", err, "attachment_id", a.ID)
+			return offset, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+		}
+		defer stored.Close()
+		src = io.MultiReader(io.LimitReader(stored, pending), src)
+	}
+
+	// Bytes are sent a part at a time, and what is left short of a part is
+	// stored for the next request. Stored bytes are deleted as soon as they
+	// are read into a part, so whatever fails, the offset is never ahead of
+	// the bytes kept: at worst the client sends some again.
+	buf := make([]byte, partSize(a.Size))
+	number := int32(1)
+	if len(parts) > 0 {
+		number = parts[len(parts)-1].Number + 1
+	}
+	for {
+		n, readErr := io.ReadFull(src, buf)
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			readErr = nil
+		}
+		last := received+int64(n) == a.Size
+		if n < len(buf) && !last {
+			return s.storePending(ctx, a, buf[:n], received, pending, readErr)
+		}
+
+		if pending > 0 {
+			if err := s.store.Delete(ctx, s.partKey(a)); err != nil {
+				s.logg.Error("failed to delete pending attachment bytes", "error", err, "attachment_id", a.ID)
+				return received + pending, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+			}
+			pending = 0
+		}
+		part, err := uploader.UploadPart(ctx, a.Key, a.UploadID, number, bytes.NewReader(buf[:n]), int64(n))
+		if err != nil {
+			s.logg.Error("failed to upload attachment part", "error", err, "attachment_id", a.ID, "part", number)
+			return received, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+		}
+		parts = append(parts, *part)
+		received += int64(n)
+		number++
+		if last {
+			return a.Size, s.completeResumable(ctx, uploader, a, parts)
+		}
+	}
+}
+
+// uploadedOffset answers an append at offset to the uploaded a: there is
+// nothing left to send
+func uploadedOffset(a *domain.Attachment, offset int64) (int64, error) {
+	if offset != a.Size {
+		return a.Size, domain.ErrUploadOffsetMismatch
+	}
+	return a.Size, nil
+}
+
+// storePending stores data, the bytes of a's upload after received short of
+// a part, for the next request, in place of the pending bytes stored so far,
+// and returns the new offset. readErr, the body's failure, is returned with it.
+func (s *AttachmentService) storePending(ctx context.Context, a *domain.Attachment, data []byte, received, pending int64, readErr error) (int64, error) {
+	if readErr != nil {
+		readErr = fmt.Errorf("%w: reading the upload: %w", domain.ErrInvalidInput, readErr)
+	}
+	if int64(len(data)) == pending {
+		return received + pending, readErr // Nothing new
+	}
+
+	// A client gone mid-request cancels ctx; what it sent is kept all the same
+	_, err := s.store.Upload(context.WithoutCancel(ctx), &blob.UploadInput{
+		Key:         s.partKey(a),
+		Body:        bytes.NewReader(data),
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		s.logg.Error("failed to store pending attachment bytes", "error", err, "attachment_id", a.ID)
+		return received + pending, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+	return received + int64(len(data)), readErr
+}
+
+// received returns the parts of a's multipart upload and the size of the
+// bytes waiting to fill the next one. An upload whose parts hold the whole
+// file is completed, as is one gone from the store with its object there (a
+// completion that failed to be recorded); one gone without its object, such
+// as one aborted by the bucket's lifecycle rules, is ErrAttachmentNotFound.
+func (s *AttachmentService) received(ctx context.Context, uploader blob.MultipartUploader, a *domain.Attachment) ([]blob.Part, int64, error) {
+	parts, err := uploader.ListParts(ctx, a.Key, a.UploadID)
+	switch {
+	case errors.Is(err, blob.ErrNotFound):
+		if _, err := s.CompleteUpload(ctx, a, ""); err != nil {
+			if errors.Is(err, domain.ErrAttachmentNotUploaded) {
+				return nil, 0, domain.ErrAttachmentNotFound
+			}
+			return nil, 0, err
+		}
+		return nil, 0, nil
+	case err != nil:
+		s.logg.Error("failed to list attachment parts", "error", err, "attachment_id", a.ID)
+		return nil, 0, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+	if partsSize(parts) == a.Size {
+		return parts, 0, s.completeResumable(ctx, uploader, a, parts)
+	}
+
+	info, err := s.store.HeadObject(ctx, s.partKey(a))
+	switch {
+	case errors.Is(err, blob.ErrNotFound):
+		return parts, 0, nil
+	case err != nil:
+		s.logg.Error("failed to inspect pending attachment bytes", "error", err, "attachment_id", a.ID)
+		return nil, 0, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+	return parts, info.Size, nil
+}
+
+// completeResumable creates a's object from parts and completes a
+func (s *AttachmentService) completeResumable(ctx context.Context, uploader blob.MultipartUploader, a *domain.Attachment, parts []blob.Part) error {
+	ctx = context.WithoutCancel(ctx)
+	if _, err := uploader.CompleteMultipartUpload(ctx, a.Key, a.UploadID, parts); err != nil {
+		s.logg.Error("failed to complete resumable attachment upload", "error", err, "attachment_id", a.ID)
+		return fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+	_, err := s.CompleteUpload(ctx, a, "")
+	return err
+}
+
+// AbortUpload discards a's resumable upload with the bytes received and the
+// attachment. Uploads already completed cannot be aborted (ErrConflict).
+func (s *AttachmentService) AbortUpload(ctx context.Context, a *domain.Attachment) error {
+	if !a.Resumable() {
+		if a.Uploaded() {
+			return fmt.Errorf("%w: the upload is complete", domain.ErrConflict)
+		}
+		return domain.ErrAttachmentNotFound
+	}
+	permit, err := s.lockUpload(ctx, a)
+	if err != nil {
+		return err
+	}
+	defer permit.Release()
+	ctx = permit.Context()
+
+	uploader, err := s.multipart()
+	if err != nil {
+		return err
+	}
+	if err := uploader.AbortMultipartUpload(ctx, a.Key, a.UploadID); err != nil {
+		s.logg.Error("failed to abort attachment upload", "error", err, "attachment_id", a.ID)
+		return fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+	if err := s.store.Delete(ctx, s.partKey(a)); err != nil && !errors.Is(err, blob.ErrNotFound) {
+		// The bucket's lifecycle rules expire them (BLOB_MANAGE_LIFECYCLE)
+		s.logg.Warn("failed to delete pending attachment bytes", "error", err, "attachment_id", a.ID)
+	}
+	if err := s.repo.Delete(ctx, a.ID); err != nil {
+		return err
+	}
+
+	s.logg.Info("attachment upload aborted", "attachment_id", a.ID, "owner_id", a.OwnerID)
+	return nil
+}
+
+// partsSize returns the bytes held by parts
+func partsSize(parts []blob.Part) int64 {
+	var size int64
+	for _, p := range parts {
+		size += p.Size
+	}
+	return size
+}
+
+// Get returns an attachment's metadata, including its scan status
+func (s *AttachmentService) Get(ctx context.Context, id string) (*domain.Attachment, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetByKey returns the attachment stored under a blob key
+func (s *AttachmentService) GetByKey(ctx context.Context, key string) (*domain.Attachment, error) {
+	return s.repo.GetByKey(ctx, key)
+}
+
+// ListByOwner returns a user's attachments, newest first
+func (s *AttachmentService) ListByOwner(ctx context.Context, ownerID string, limit, offset int) ([]*domain.Attachment, error) {
+	return s.repo.ListByOwner(ctx, ownerID, limit, offset)
+}
+
+// DownloadURL presigns a download of a. Attachments not yet completed, still
+// pending a scan or found infected are refused with ErrAttachmentNotUploaded,
+// ErrAttachmentScanPending or ErrAttachmentInfected.
+func (s *AttachmentService) DownloadURL(ctx context.Context, a *domain.Attachment) (*PresignedURL, error) {
+	if err := a.Downloadable(); err != nil {
+		return nil, err
+	}
+
+	presigner, err := s.presigner()
+	if err != nil {
+		return nil, err
+	}
+	url, err := presigner.GeneratePresignedURL(ctx, a.Key, s.policy.DownloadURLTTL)
+	if err != nil {
+		s.logg.Error("failed to presign attachment download", "error", err, "attachment_id", a.ID)
+		return nil, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+	return &PresignedURL{URL: url, ExpiresAt: time.Now().Add(s.policy.DownloadURLTTL)}, nil
+}
+
+// RecordScanResult stores the scanner's verdict for attachment id
+func (s *AttachmentService) RecordScanResult(ctx context.Context, id string, status domain.ScanStatus, detail string) (*domain.Attachment, error) {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.RecordScan(status, detail, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, a); err != nil {
+		return nil, err
+	}
+
+	if a.ScanStatus == domain.ScanStatusInfected {
+		s.logg.Warn("attachment failed malware scan", "attachment_id", a.ID, "owner_id", a.OwnerID, "detail", a.ScanDetail)
+	} else {
+		s.logg.Info("attachment scanned", "attachment_id", a.ID, "status", a.ScanStatus)
+	}
+	return a, nil
+}
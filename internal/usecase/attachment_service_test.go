@@ -0,0 +1,782 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+var errStore = errors.New("store unavailable")
+
+// multipartStore is an in-memory blob store with multipart uploads, failing
+// the calls named in fail
+type multipartStore struct {
+	*blob.MemoryStore
+
+	mu      sync.Mutex
+	uploads map[string]*multipartUpload // By upload ID
+	nextID  int
+	fail    map[string]error // By method name
+}
+
+type multipartUpload struct {
+	key         string
+	contentType string
+	parts       map[int32][]byte
+}
+
+func newMultipartStore() *multipartStore {
+	return &multipartStore{
+		MemoryStore: blob.NewMemoryStore(),
+		uploads:     make(map[string]*multipartUpload),
+		fail:        make(map[string]error),
+	}
+}
+
+func (s *multipartStore) failing(method string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fail[method]
+}
+
+func (s *multipartStore) CreateMultipartUpload(ctx context.Context, input *blob.UploadInput) (string, error) {
+	if err := s.failing("CreateMultipartUpload"); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("upload-%d", s.nextID)
+	s.uploads[id] = &multipartUpload{key: input.Key, contentType: input.ContentType, parts: make(map[int32][]byte)}
+	return id, nil
+}
+
+func (s *multipartStore) UploadPart(ctx context.Context, key, uploadID string, number int32, body io.Reader, size int64) (*blob.Part, error) {
+	if err := s.failing("UploadPart"); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok || upload.key != key {
+		return nil, blob.ErrNotFound
+	}
+	upload.parts[number] = data
+	return &blob.Part{Number: number, ETag: fmt.Sprintf("etag-%d", number), Size: int64(len(data))}, nil
+}
+
+func (s *multipartStore) ListParts(ctx context.Context, key, uploadID string) ([]blob.Part, error) {
+	if err := s.failing("ListParts"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok || upload.key != key {
+		return nil, blob.ErrNotFound
+	}
+	var parts []blob.Part
+	for _, number := range slices.Sorted(maps.Keys(upload.parts)) {
+		parts = append(parts, blob.Part{Number: number, ETag: fmt.Sprintf("etag-%d", number), Size: int64(len(upload.parts[number]))})
+	}
+	return parts, nil
+}
+
+func (s *multipartStore) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []blob.Part) (*blob.UploadOutput, error) {
+	if err := s.failing("CompleteMultipartUpload"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	upload, ok := s.uploads[uploadID]
+	if !ok || upload.key != key {
+		s.mu.Unlock()
+		return nil, blob.ErrNotFound
+	}
+	var data []byte
+	for _, part := range parts {
+		data = append(data, upload.parts[part.Number]...)
+	}
+	delete(s.uploads, uploadID)
+	s.mu.Unlock()
+
+	return s.MemoryStore.Upload(ctx, &blob.UploadInput{Key: key, Body: bytes.NewReader(data), ContentType: upload.contentType})
+}
+
+func (s *multipartStore) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	if err := s.failing("AbortMultipartUpload"); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, uploadID)
+	return nil
+}
+
+func (s *multipartStore) Upload(ctx context.Context, input *blob.UploadInput) (*blob.UploadOutput, error) {
+	if err := s.failing("Upload"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.Upload(ctx, input)
+}
+
+func (s *multipartStore) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := s.failing("GetObject"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetObject(ctx, key)
+}
+
+func (s *multipartStore) HeadObject(ctx context.Context, key string) (*blob.ObjectInfo, error) {
+	if err := s.failing("HeadObject"); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.HeadObject(ctx, key)
+}
+
+func (s *multipartStore) Delete(ctx context.Context, key string) error {
+	if err := s.failing("Delete"); err != nil {
+		return err
+	}
+	return s.MemoryStore.Delete(ctx, key)
+}
+
+// partCount returns how many parts upload holds
+func (s *multipartStore) partCount(uploadID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if upload, ok := s.uploads[uploadID]; ok {
+		return len(upload.parts)
+	}
+	return 0
+}
+
+// failingReader returns its bytes, then err
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// failingAttachments fails Create, as a database outage would
+type failingAttachments struct {
+	domain.AttachmentRepository
+}
+
+func (failingAttachments) Create(ctx context.Context, a *domain.Attachment) error {
+	return errStore
+}
+
+type uploadFixture struct {
+	svc   *AttachmentService
+	store *multipartStore
+	repo  domain.AttachmentRepository
+	locks domain.SemaphoreStore
+}
+
+func newUploadFixture(t *testing.T) *uploadFixture {
+	t.Helper()
+	f := &uploadFixture{
+		store: newMultipartStore(),
+		repo:  memory.NewAttachmentRepository(),
+		locks: memory.NewSemaphoreStore(),
+	}
+	f.svc = NewAttachmentService(f.repo, f.store, f.locks, AttachmentPolicy{MaxSize: 64 << 20}, logger.New("error"))
+	return f
+}
+
+// create starts a resumable upload of size bytes
+func (f *uploadFixture) create(t *testing.T, size int64) *domain.Attachment {
+	t.Helper()
+	a, err := f.svc.CreateResumableUpload(context.Background(), "user-1", "video.mp4", "video/mp4", size)
+	if err != nil {
+		t.Fatalf("CreateResumableUpload() error = %v", err)
+	}
+	return a
+}
+
+// pending returns the bytes waiting for a's next part
+func (f *uploadFixture) pending(t *testing.T, a *domain.Attachment) []byte {
+	t.Helper()
+	body, err := f.store.MemoryStore.GetObject(context.Background(), f.svc.partKey(a))
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	return data
+}
+
+// object returns a's completed object
+func (f *uploadFixture) object(t *testing.T, a *domain.Attachment) []byte {
+	t.Helper()
+	body, err := f.store.MemoryStore.GetObject(context.Background(), a.Key)
+	if err != nil {
+		t.Fatalf("GetObject(%s) error = %v", a.Key, err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	return data
+}
+
+// file returns size bytes of test content
+func file(size int64) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestCreateResumableUpload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("starts a multipart upload", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 1024)
+		if !a.Resumable() {
+			t.Fatalf("attachment is not resumable: %+v", a)
+		}
+		if _, err := f.repo.GetByID(ctx, a.ID); err != nil {
+			t.Errorf("attachment not recorded: %v", err)
+		}
+		if _, err := f.store.ListParts(ctx, a.Key, a.UploadID); err != nil {
+			t.Errorf("multipart upload not started: %v", err)
+		}
+	})
+
+	t.Run("invalid attachment", func(t *testing.T) {
+		f := newUploadFixture(t)
+		if _, err := f.svc.CreateResumableUpload(ctx, "user-1", "video.mp4", "video/mp4", 65<<20); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("too large: error = %v, want ErrInvalidInput", err)
+		}
+		if _, err := f.svc.CreateResumableUpload(ctx, "user-1", "", "video/mp4", 1024); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("no filename: error = %v, want ErrInvalidInput", err)
+		}
+	})
+
+	t.Run("store without multipart uploads", func(t *testing.T) {
+		svc := NewAttachmentService(memory.NewAttachmentRepository(), blob.NewMemoryStore(), memory.NewSemaphoreStore(), AttachmentPolicy{}, logger.New("error"))
+		if _, err := svc.CreateResumableUpload(ctx, "user-1", "video.mp4", "video/mp4", 1024); !errors.Is(err, domain.ErrInternalError) {
+			t.Errorf("error = %v, want ErrInternalError", err)
+		}
+	})
+
+	t.Run("multipart upload fails to start", func(t *testing.T) {
+		f := newUploadFixture(t)
+		f.store.fail["CreateMultipartUpload"] = errStore
+		if _, err := f.svc.CreateResumableUpload(ctx, "user-1", "video.mp4", "video/mp4", 1024); !errors.Is(err, domain.ErrInternalError) {
+			t.Errorf("error = %v, want ErrInternalError", err)
+		}
+	})
+
+	t.Run("unrecorded upload is aborted", func(t *testing.T) {
+		f := newUploadFixture(t)
+		svc := NewAttachmentService(failingAttachments{f.repo}, f.store, f.locks, AttachmentPolicy{}, logger.New("error"))
+		if _, err := svc.CreateResumableUpload(ctx, "user-1", "video.mp4", "video/mp4", 1024); !errors.Is(err, errStore) {
+			t.Errorf("error = %v, want the repository's", err)
+		}
+		if n := len(f.store.uploads); n != 0 {
+			t.Errorf("%d multipart uploads left behind, want the upload aborted", n)
+		}
+	})
+}
+
+func TestAppendUploadAcrossRequests(t *testing.T) {
+	ctx := context.Background()
+	f := newUploadFixture(t)
+	size := 2*int64(resumablePartSize) + 100
+	data := file(size)
+	a := f.create(t, size)
+
+	// Chunks smaller than a part, crossing part boundaries, then the rest
+	cuts := []int64{0, 1000, resumablePartSize - 10, resumablePartSize + 10, 2*resumablePartSize + 50, size}
+	for i := 1; i < len(cuts); i++ {
+		offset, err := f.svc.AppendUpload(ctx, a, cuts[i-1], bytes.NewReader(data[cuts[i-1]:cuts[i]]))
+		if err != nil {
+			t.Fatalf("chunk %d: AppendUpload() error = %v", i, err)
+		}
+		if offset != cuts[i] {
+			t.Fatalf("chunk %d: offset = %d, want %d", i, offset, cuts[i])
+		}
+		if i < len(cuts)-1 {
+			got, err := f.svc.UploadOffset(ctx, a)
+			if err != nil || got != cuts[i] {
+				t.Fatalf("chunk %d: UploadOffset() = %d, %v, want %d", i, got, err, cuts[i])
+			}
+		}
+	}
+
+	if !a.Uploaded() {
+		t.Fatal("upload not completed by its last byte")
+	}
+	if got := f.object(t, a); !bytes.Equal(got, data) {
+		t.Errorf("object holds %d bytes differing from the %d sent", len(got), len(data))
+	}
+	if pending := f.pending(t, a); pending != nil {
+		t.Errorf("%d pending bytes left behind", len(pending))
+	}
+	stored, err := f.repo.GetByID(ctx, a.ID)
+	if err != nil || !stored.Uploaded() {
+		t.Errorf("completion not recorded: %+v, %v", stored, err)
+	}
+
+	// A retried last request is answered, not re-applied
+	if offset, err := f.svc.AppendUpload(ctx, a, size, bytes.NewReader(nil)); err != nil || offset != size {
+		t.Errorf("retry after completion: AppendUpload() = %d, %v, want %d, nil", offset, err, size)
+	}
+	if offset, err := f.svc.AppendUpload(ctx, a, 10, bytes.NewReader(nil)); !errors.Is(err, domain.ErrUploadOffsetMismatch) || offset != size {
+		t.Errorf("stale offset after completion: AppendUpload() = %d, %v, want %d, ErrUploadOffsetMismatch", offset, err, size)
+	}
+	if offset, err := f.svc.UploadOffset(ctx, a); err != nil || offset != size {
+		t.Errorf("UploadOffset() after completion = %d, %v, want %d", offset, err, size)
+	}
+}
+
+func TestAppendUploadSinglePart(t *testing.T) {
+	ctx := context.Background()
+	f := newUploadFixture(t)
+	data := file(4096)
+	a := f.create(t, int64(len(data)))
+
+	// The last part may be short; a chunk past the declared size is cut off
+	body := io.MultiReader(bytes.NewReader(data), bytes.NewReader([]byte("trailing")))
+	offset, err := f.svc.AppendUpload(ctx, a, 0, body)
+	if err != nil || offset != int64(len(data)) {
+		t.Fatalf("AppendUpload() = %d, %v, want %d, nil", offset, err, len(data))
+	}
+	if got := f.object(t, a); !bytes.Equal(got, data) {
+		t.Errorf("object = %d bytes, want the %d declared", len(got), len(data))
+	}
+}
+
+func TestAppendUploadRejects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not resumable", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a, _, err := f.svc.newAttachment("user-1", "photo.png", "image/png", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(10))); !errors.Is(err, domain.ErrAttachmentNotFound) {
+			t.Errorf("error = %v, want ErrAttachmentNotFound", err)
+		}
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 10)
+		if ok, _ := f.locks.TryAcquire(ctx, "upload:"+a.ID, "other-replica", 1, uploadLockTTL); !ok {
+			t.Fatal("failed to take the lock")
+		}
+		if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(10))); !errors.Is(err, domain.ErrUploadLocked) {
+			t.Errorf("AppendUpload() error = %v, want ErrUploadLocked", err)
+		}
+		if err := f.svc.AbortUpload(ctx, a); !errors.Is(err, domain.ErrUploadLocked) {
+			t.Errorf("AbortUpload() error = %v, want ErrUploadLocked", err)
+		}
+
+		// Released when the request is done
+		f.locks.Release(ctx, "upload:"+a.ID, "other-replica")
+		if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(5))); err != nil {
+			t.Fatalf("AppendUpload() error = %v", err)
+		}
+		if _, err := f.svc.AppendUpload(ctx, a, 5, bytes.NewReader(file(5))); err != nil {
+			t.Errorf("second AppendUpload() error = %v, want the lock released", err)
+		}
+	})
+
+	t.Run("store without multipart uploads", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 10)
+		svc := NewAttachmentService(f.repo, blob.NewMemoryStore(), f.locks, AttachmentPolicy{}, logger.New("error"))
+		if _, err := svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(10))); !errors.Is(err, domain.ErrInternalError) {
+			t.Errorf("AppendUpload() error = %v, want ErrInternalError", err)
+		}
+		if _, err := svc.UploadOffset(ctx, a); !errors.Is(err, domain.ErrInternalError) {
+			t.Errorf("UploadOffset() error = %v, want ErrInternalError", err)
+		}
+	})
+
+	t.Run("offset mismatch", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 100)
+		if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(40))); err != nil {
+			t.Fatal(err)
+		}
+		offset, err := f.svc.AppendUpload(ctx, a, 10, bytes.NewReader(file(10)))
+		if !errors.Is(err, domain.ErrUploadOffsetMismatch) || offset != 40 {
+			t.Errorf("AppendUpload() = %d, %v, want 40, ErrUploadOffsetMismatch", offset, err)
+		}
+	})
+}
+
+func TestAppendUploadFailures(t *testing.T) {
+	ctx := context.Background()
+	part := int64(resumablePartSize)
+
+	tests := []struct {
+		name string
+		// sent before the failure, as one request
+		before int64
+		fail   string
+		// the failing request's chunk
+		chunk      int64
+		wantOffset int64
+		wantErr    error
+		// bytes that must be received afterwards
+		wantReceived int64
+	}{
+		{"listing parts", 0, "ListParts", 10, 0, domain.ErrInternalError, 0},
+		{"inspecting pending bytes", 10, "HeadObject", 10, 0, domain.ErrInternalError, 10},
+		{"reading pending bytes", 10, "GetObject", 10, 10, domain.ErrInternalError, 10},
+		{"storing pending bytes", 10, "Upload", 10, 10, domain.ErrInternalError, 10},
+		{"deleting pending bytes", 10, "Delete", part, 10, domain.ErrInternalError, 10},
+		{"uploading a part", 0, "UploadPart", part + 10, 0, domain.ErrInternalError, 0},
+		{"completing the upload", part, "CompleteMultipartUpload", 10, part + 10, domain.ErrInternalError, part + 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUploadFixture(t)
+			size := part + 10
+			data := file(size)
+			a := f.create(t, size)
+			if tt.before > 0 {
+				if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(data[:tt.before])); err != nil {
+					t.Fatalf("AppendUpload() before the failure: %v", err)
+				}
+			}
+
+			f.store.fail[tt.fail] = errStore
+			offset, err := f.svc.AppendUpload(ctx, a, tt.before, bytes.NewReader(data[tt.before:tt.before+tt.chunk]))
+			if !errors.Is(err, tt.wantErr) || offset != tt.wantOffset {
+				t.Errorf("AppendUpload() = %d, %v, want %d, %v", offset, err, tt.wantOffset, tt.wantErr)
+			}
+			delete(f.store.fail, tt.fail)
+
+			// Nothing reported received was lost: the client resumes from the offset
+			if a.Uploaded() {
+				t.Fatal("attachment completed despite the failure")
+			}
+			got, err := f.svc.UploadOffset(ctx, a)
+			if err != nil || got != tt.wantReceived {
+				t.Fatalf("UploadOffset() = %d, %v, want %d", got, err, tt.wantReceived)
+			}
+			if _, err := f.svc.AppendUpload(ctx, a, got, bytes.NewReader(data[got:])); err != nil {
+				t.Fatalf("resuming: AppendUpload() error = %v", err)
+			}
+			if !bytes.Equal(f.object(t, a), data) {
+				t.Error("resumed upload does not match the file")
+			}
+		})
+	}
+}
+
+func TestAppendUploadBodyFailure(t *testing.T) {
+	ctx := context.Background()
+	part := int64(resumablePartSize)
+	errReset := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		sent       int64 // Before the connection drops
+		wantOffset int64
+	}{
+		{"before any byte", 0, 0},
+		{"within a part", 100, 100},
+		{"after a part", part + 100, part + 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUploadFixture(t)
+			size := 2 * part
+			data := file(size)
+			a := f.create(t, size)
+
+			offset, err := f.svc.AppendUpload(ctx, a, 0, &failingReader{data: data[:tt.sent], err: errReset})
+			if !errors.Is(err, domain.ErrInvalidInput) || !errors.Is(err, errReset) {
+				t.Errorf("error = %v, want ErrInvalidInput wrapping the read error", err)
+			}
+			if offset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d", offset, tt.wantOffset)
+			}
+			if got := int64(len(f.pending(t, a))); got != tt.sent%part {
+				t.Errorf("pending bytes = %d, want %d", got, tt.sent%part)
+			}
+			if got := int64(f.store.partCount(a.UploadID)); got != tt.sent/part {
+				t.Errorf("parts = %d, want %d", got, tt.sent/part)
+			}
+		})
+	}
+}
+
+func TestAppendUploadEmptyChunk(t *testing.T) {
+	ctx := context.Background()
+	f := newUploadFixture(t)
+	a := f.create(t, 100)
+	if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(30))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing new keeps the pending bytes as they are, without a write
+	f.store.fail["Upload"] = errStore
+	offset, err := f.svc.AppendUpload(ctx, a, 30, bytes.NewReader(nil))
+	if err != nil || offset != 30 {
+		t.Errorf("AppendUpload() = %d, %v, want 30, nil", offset, err)
+	}
+	if got := len(f.pending(t, a)); got != 30 {
+		t.Errorf("pending bytes = %d, want 30", got)
+	}
+}
+
+func TestUploadOffset(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not resumable", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a, _, err := f.svc.newAttachment("user-1", "photo.png", "image/png", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.svc.UploadOffset(ctx, a); !errors.Is(err, domain.ErrAttachmentNotFound) {
+			t.Errorf("error = %v, want ErrAttachmentNotFound", err)
+		}
+	})
+
+	t.Run("parts holding the whole file", func(t *testing.T) {
+		// A request that uploaded the last part but crashed before completing
+		f := newUploadFixture(t)
+		data := file(10)
+		a := f.create(t, 10)
+		if _, err := f.store.UploadPart(ctx, a.Key, a.UploadID, 1, bytes.NewReader(data), 10); err != nil {
+			t.Fatal(err)
+		}
+		offset, err := f.svc.UploadOffset(ctx, a)
+		if err != nil || offset != 10 {
+			t.Fatalf("UploadOffset() = %d, %v, want 10, nil", offset, err)
+		}
+		if !a.Uploaded() || !bytes.Equal(f.object(t, a), data) {
+			t.Error("upload not completed")
+		}
+	})
+
+	t.Run("completed without being recorded", func(t *testing.T) {
+		f := newUploadFixture(t)
+		data := file(10)
+		a := f.create(t, 10)
+		f.store.UploadPart(ctx, a.Key, a.UploadID, 1, bytes.NewReader(data), 10)
+		if _, err := f.store.CompleteMultipartUpload(ctx, a.Key, a.UploadID, []blob.Part{{Number: 1}}); err != nil {
+			t.Fatal(err)
+		}
+		offset, err := f.svc.UploadOffset(ctx, a)
+		if err != nil || offset != 10 || !a.Uploaded() {
+			t.Errorf("UploadOffset() = %d, %v (uploaded %v), want 10 and the attachment completed", offset, err, a.Uploaded())
+		}
+	})
+
+	t.Run("aborted by the store", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 10)
+		f.store.AbortMultipartUpload(ctx, a.Key, a.UploadID)
+		if _, err := f.svc.UploadOffset(ctx, a); !errors.Is(err, domain.ErrAttachmentNotFound) {
+			t.Errorf("UploadOffset() error = %v, want ErrAttachmentNotFound", err)
+		}
+		if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(10))); !errors.Is(err, domain.ErrAttachmentNotFound) {
+			t.Errorf("AppendUpload() error = %v, want ErrAttachmentNotFound", err)
+		}
+	})
+
+	t.Run("gone and the object unreadable", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 10)
+		f.store.AbortMultipartUpload(ctx, a.Key, a.UploadID)
+		f.store.fail["HeadObject"] = errStore
+		if _, err := f.svc.UploadOffset(ctx, a); !errors.Is(err, domain.ErrInternalError) {
+			t.Errorf("UploadOffset() error = %v, want ErrInternalError", err)
+		}
+	})
+}
+
+func TestAbortUpload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("discards the upload", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 100)
+		if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(30))); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.svc.AbortUpload(ctx, a); err != nil {
+			t.Fatalf("AbortUpload() error = %v", err)
+		}
+		if _, err := f.repo.GetByID(ctx, a.ID); !errors.Is(err, domain.ErrAttachmentNotFound) {
+			t.Errorf("attachment still recorded: %v", err)
+		}
+		if f.pending(t, a) != nil {
+			t.Error("pending bytes left behind")
+		}
+		if _, err := f.store.ListParts(ctx, a.Key, a.UploadID); !errors.Is(err, blob.ErrNotFound) {
+			t.Errorf("multipart upload not aborted: %v", err)
+		}
+	})
+
+	t.Run("completed upload", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 10)
+		if _, err := f.svc.AppendUpload(ctx, a, 0, bytes.NewReader(file(10))); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.svc.AbortUpload(ctx, a); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("error = %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("store fails to abort", func(t *testing.T) {
+		f := newUploadFixture(t)
+		a := f.create(t, 10)
+		f.store.fail["AbortMultipartUpload"] = errStore
+		if err := f.svc.AbortUpload(ctx, a); !errors.Is(err, domain.ErrInternalError) {
+			t.Errorf("error = %v, want ErrInternalError", err)
+		}
+		if _, err := f.repo.GetByID(ctx, a.ID); err != nil {
+			t.Errorf("attachment deleted despite the failure: %v", err)
+		}
+	})
+}
+
+func TestCompleteUpload(t *testing.T) {
+	content := []byte("%PDF-1.7 quarterly report")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name       string
+		declared   string // Content type the attachment was created with
+		stored     []byte // Uploaded object, nil for none
+		storedType string
+		checksum   string
+		fail       string // Store method failing
+		wantErr    error
+		wantType   string
+		wantSummed string // Checksum recorded
+	}{
+		{name: "verified", declared: "application/pdf", stored: content, storedType: "application/pdf", wantType: "application/pdf"},
+		{name: "matching checksum", declared: "application/pdf", stored: content, storedType: "application/pdf",
+			checksum: " " + strings.ToUpper(checksum) + " ", wantType: "application/pdf", wantSummed: checksum},
+		{name: "other type under an allowed prefix", declared: "image/png", stored: content, storedType: "image/jpeg", wantType: "image/jpeg"},
+		{name: "object not uploaded", declared: "application/pdf", wantErr: domain.ErrAttachmentNotUploaded},
+		{name: "size mismatch", declared: "application/pdf", stored: content[:8], storedType: "application/pdf", wantErr: domain.ErrAttachmentMismatch},
+		{name: "checksum mismatch", declared: "application/pdf", stored: content, storedType: "application/pdf",
+			checksum: strings.Repeat("0", 64), wantErr: domain.ErrAttachmentMismatch},
+		{name: "malformed checksum", declared: "application/pdf", stored: content, storedType: "application/pdf",
+			checksum: "md5:abc", wantErr: domain.ErrInvalidInput},
+		{name: "content type mismatch", declared: "application/pdf", stored: content, storedType: "text/html", wantErr: domain.ErrAttachmentMismatch},
+		{name: "store unavailable", declared: "application/pdf", stored: content, storedType: "application/pdf",
+			fail: "HeadObject", wantErr: domain.ErrInternalError},
+		{name: "object unreadable for the checksum", declared: "application/pdf", stored: content, storedType: "application/pdf",
+			checksum: checksum, fail: "GetObject", wantErr: domain.ErrInternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newUploadFixture(t)
+			f.svc = NewAttachmentService(f.repo, f.store, f.locks, AttachmentPolicy{
+				MaxSize:             64 << 20,
+				AllowedContentTypes: []string{"application/pdf", "image/"},
+			}, logger.New("error"))
+
+			a, err := domain.NewAttachment("att-1", "user-1", "report.pdf", tt.declared, int64(len(content)), "attachments/")
+			if err != nil {
+				t.Fatalf("NewAttachment() error = %v", err)
+			}
+			if err := f.repo.Create(ctx, a); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if tt.stored != nil {
+				if _, err := f.store.Upload(ctx, &blob.UploadInput{Key: a.Key, Body: bytes.NewReader(tt.stored), ContentType: tt.storedType}); err != nil {
+					t.Fatalf("Upload() error = %v", err)
+				}
+			}
+			if tt.fail != "" {
+				f.store.fail[tt.fail] = errStore
+			}
+
+			got, err := f.svc.CompleteUpload(ctx, a, tt.checksum)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompleteUpload() error = %v, want %v", err, tt.wantErr)
+			}
+			stored, _ := f.repo.GetByID(ctx, a.ID)
+			if tt.wantErr != nil {
+				if stored.Uploaded() {
+					t.Error("attachment recorded as uploaded after a failed completion")
+				}
+				return
+			}
+			if !got.Uploaded() || !stored.Uploaded() {
+				t.Errorf("attachment not marked uploaded: %+v", stored)
+			}
+			if got.ContentType != tt.wantType || stored.ContentType != tt.wantType {
+				t.Errorf("content type = %q (stored %q), want %q", got.ContentType, stored.ContentType, tt.wantType)
+			}
+			if stored.ChecksumSHA256 != tt.wantSummed {
+				t.Errorf("checksum = %q, want %q", stored.ChecksumSHA256, tt.wantSummed)
+			}
+			if stored.ETag == "" {
+				t.Error("ETag of the stored object not recorded")
+			}
+		})
+	}
+}
+
+func TestCompleteUploadIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	f := newUploadFixture(t)
+	a, err := domain.NewAttachment("att-1", "user-1", "report.pdf", "application/pdf", 4, "attachments/")
+	if err != nil {
+		t.Fatalf("NewAttachment() error = %v", err)
+	}
+	if err := f.repo.Create(ctx, a); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := f.store.Upload(ctx, &blob.UploadInput{Key: a.Key, Body: strings.NewReader("%PDF"), ContentType: "application/pdf"}); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if _, err := f.svc.CompleteUpload(ctx, a, ""); err != nil {
+		t.Fatalf("CompleteUpload() error = %v", err)
+	}
+
+	// A retried callback succeeds without looking at the store again
+	f.store.fail["HeadObject"] = errStore
+	if _, err := f.svc.CompleteUpload(ctx, a, ""); err != nil {
+		t.Errorf("second CompleteUpload() error = %v, want nil", err)
+	}
+}
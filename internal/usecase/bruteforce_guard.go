@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// BruteForcePolicy configures when clients are challenged and locked out
+type BruteForcePolicy struct {
+	Window       time.Duration // Failures older than this are forgotten
+	CaptchaAfter int           // Failures before a CAPTCHA is requested (0 disables)
+	LockAfter    int           // Failures before the first lockout (0 disables)
+	BaseLockout  time.Duration // First lockout; doubles with every further failure
+	MaxLockout   time.Duration // Upper bound for a single lockout (0 means no cap)
+}
+
+// DefaultBruteForcePolicy returns conservative defaults
+func DefaultBruteForcePolicy() BruteForcePolicy {
+	return BruteForcePolicy{
+		Window:       15 * time.Minute,
+		CaptchaAfter: 3,
+		LockAfter:    5,
+		BaseLockout:  time.Minute,
+		MaxLockout:   time.Hour,
+	}
+}
+
+// lockoutFor returns the lockout earned by the given failure count (0 if none)
+// Lockouts grow exponentially: base, 2×base, 4×base, ... up to MaxLockout,
+// or without a cap short of the longest time.Duration when MaxLockout is 0
+func (p BruteForcePolicy) lockoutFor(failures int) time.Duration {
+	if p.LockAfter <= 0 || failures < p.LockAfter {
+		return 0
+	}
+	d := p.BaseLockout
+	for i := p.LockAfter; i < failures; i++ {
+		if p.MaxLockout > 0 && d >= p.MaxLockout {
+			break
+		}
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxLockout > 0 && d > p.MaxLockout {
+		d = p.MaxLockout
+	}
+	return d
+}
+
+// AttemptDecision tells the caller how to treat an authentication attempt
+type AttemptDecision struct {
+	Locked          bool
+	RetryAfter      time.Duration
+	CaptchaRequired bool
+}
+
+// BruteForceGuard tracks failed authentication attempts per client IP,
+// independently of the generic request rate limiter. Failures are never
+// tracked per account: a forged token names whatever subject it likes, and
+// counting those would let anyone lock a user out. Successes don't reset the
+// count either, or a client holding one valid token could interleave it with
+// its guesses to stay below the lockout.
+type BruteForceGuard struct {
+	store  domain.LoginAttemptStore
+	policy BruteForcePolicy
+	logg   *logger.Logger
+}
+
+// NewBruteForceGuard creates a new brute-force guard
+func NewBruteForceGuard(store domain.LoginAttemptStore, policy BruteForcePolicy, logg *logger.Logger) *BruteForceGuard {
+	return &BruteForceGuard{
+		store:  store,
+		policy: policy,
+		logg:   logg,
+	}
+}
+
+// IPKey identifies attempts from a client IP
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check reports whether any of keys is currently locked out or challenged.
+// Store failures are logged and treated as "allowed" so an outage of the
+// tracker never blocks legitimate users.
+func (g *BruteForceGuard) Check(ctx context.Context, keys ...string) AttemptDecision {
+	var decision AttemptDecision
+	for _, key := range keys {
+		failures, lockedFor, err := g.store.Failures(ctx, key)
+		if err != nil {
+			g.logg.Warn("brute-force check failed", "error", err, "key", key)
+			continue
+		}
+		g.merge(&decision, failures, lockedFor)
+	}
+	return decision
+}
+
+// RecordFailure counts a failed attempt against every key and applies lockouts
+func (g *BruteForceGuard) RecordFailure(ctx context.Context, keys ...string) AttemptDecision {
+	var decision AttemptDecision
+	for _, key := range keys {
+		failures, err := g.store.RecordFailure(ctx, key, g.policy.Window)
+		if err != nil {
+			g.logg.Warn("failed to record authentication failure", "error", err, "key", key)
+			continue
+		}
+
+		lockout := g.policy.lockoutFor(failures)
+		if lockout > 0 {
+			if err := g.store.Lock(ctx, key, lockout); err != nil {
+				g.logg.Warn("failed to apply lockout", "error", err, "key", key)
+				lockout = 0
+			} else {
+				g.logg.Warn("authentication locked out",
+					"key", key,
+					"failures", failures,
+					"lockout", lockout,
+				)
+			}
+		}
+		g.merge(&decision, failures, lockout)
+	}
+	return decision
+}
+
+func (g *BruteForceGuard) merge(d *AttemptDecision, failures int, lockedFor time.Duration) {
+	if lockedFor > 0 {
+		d.Locked = true
+		if lockedFor > d.RetryAfter {
+			d.RetryAfter = lockedFor
+		}
+	}
+	if g.policy.CaptchaAfter > 0 && failures >= g.policy.CaptchaAfter {
+		d.CaptchaRequired = true
+	}
+}
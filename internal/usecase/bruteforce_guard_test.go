@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestLockoutFor(t *testing.T) {
+	capped := BruteForcePolicy{LockAfter: 5, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	uncapped := BruteForcePolicy{LockAfter: 5, BaseLockout: time.Minute}
+
+	tests := []struct {
+		name     string
+		policy   BruteForcePolicy
+		failures int
+		want     time.Duration
+	}{
+		{"below the threshold", capped, 4, 0},
+		{"first lockout", capped, 5, time.Minute},
+		{"doubles", capped, 6, 2 * time.Minute},
+		{"keeps doubling", capped, 10, 32 * time.Minute},
+		{"capped", capped, 11, time.Hour},
+		{"stays capped", capped, 50, time.Hour},
+		{"cap below base", BruteForcePolicy{LockAfter: 1, BaseLockout: time.Hour, MaxLockout: time.Minute}, 1, time.Minute},
+		{"no cap first lockout", uncapped, 5, time.Minute},
+		{"no cap doubles", uncapped, 6, 2 * time.Minute},
+		{"no cap past an hour", uncapped, 12, 128 * time.Minute},
+		{"no cap saturates instead of overflowing", uncapped, 500, math.MaxInt64},
+		{"lockouts disabled", BruteForcePolicy{BaseLockout: time.Minute}, 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.lockoutFor(tt.failures); got != tt.want {
+				t.Errorf("lockoutFor(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBruteForceGuard(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		wantCaptcha bool
+		wantLocked  bool
+		wantRetry   time.Duration
+	}{
+		{"first failure", 1, false, false, 0},
+		{"below the CAPTCHA threshold", 2, false, false, 0},
+		{"CAPTCHA threshold", 3, true, false, 0},
+		{"first lockout", 5, true, true, time.Minute},
+		{"escalated lockout", 7, true, true, 4 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			guard := NewBruteForceGuard(memory.NewLoginAttemptStore(), DefaultBruteForcePolicy(), logger.New("error"))
+			key := IPKey("203.0.113.7")
+
+			var decision AttemptDecision
+			for range tt.failures {
+				decision = guard.RecordFailure(ctx, key)
+			}
+			if decision.CaptchaRequired != tt.wantCaptcha || decision.Locked != tt.wantLocked || decision.RetryAfter != tt.wantRetry {
+				t.Errorf("RecordFailure() = %+v, want captcha %v, locked %v for %v", decision, tt.wantCaptcha, tt.wantLocked, tt.wantRetry)
+			}
+
+			// Check sees the same state without counting another failure
+			check := guard.Check(ctx, key)
+			if check.CaptchaRequired != tt.wantCaptcha || check.Locked != tt.wantLocked {
+				t.Errorf("Check() = %+v, want captcha %v, locked %v", check, tt.wantCaptcha, tt.wantLocked)
+			}
+			if tt.wantLocked && (check.RetryAfter <= 0 || check.RetryAfter > tt.wantRetry) {
+				t.Errorf("Check() retry after %v, want up to %v", check.RetryAfter, tt.wantRetry)
+			}
+			if other := guard.Check(ctx, IPKey("198.51.100.1")); other != (AttemptDecision{}) {
+				t.Errorf("Check() of another client = %+v, want nothing", other)
+			}
+		})
+	}
+}
+
+func TestBruteForceGuardCaptchaDisabled(t *testing.T) {
+	ctx := context.Background()
+	policy := DefaultBruteForcePolicy()
+	policy.CaptchaAfter, policy.LockAfter = 0, 0
+	guard := NewBruteForceGuard(memory.NewLoginAttemptStore(), policy, logger.New("error"))
+
+	var decision AttemptDecision
+	for range 20 {
+		decision = guard.RecordFailure(ctx, IPKey("203.0.113.7"))
+	}
+	if decision != (AttemptDecision{}) {
+		t.Errorf("RecordFailure() with CAPTCHA and lockouts disabled = %+v, want nothing", decision)
+	}
+}
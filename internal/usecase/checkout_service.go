@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// SagaTypeCheckout is the saga checking out an order
+const SagaTypeCheckout = "order.checkout"
+
+// Checkout saga steps
+const (
+	StepCapturePayment   = "capture_payment"
+	StepReserveInventory = "reserve_inventory"
+	StepConfirmOrder     = "confirm_order"
+)
+
+// CheckoutService checks out pending orders with a saga: it captures the
+// payment, reserves the stock of the items, then confirms the order. When
+// a step fails, the payment is refunded and the stock released, and the
+// order stays pending so checkout can be tried again. The saga ID is the
+// idempotency key of the capture and the reservation ID, so steps
+// performed again after a crash neither charge nor reserve twice.
+type CheckoutService struct {
+	sagas     *SagaCoordinator
+	orders    *OrderService
+	payments  domain.PaymentGateway
+	inventory domain.Inventory
+	currency  string
+	logg      *logger.Logger
+}
+
+// NewCheckoutService creates a checkout service charging in currency, and
+// registers the checkout saga with sagas
+func NewCheckoutService(sagas *SagaCoordinator, orders *OrderService, payments domain.PaymentGateway, inventory domain.Inventory, currency string, logg *logger.Logger) *CheckoutService {
+	s := &CheckoutService{
+		sagas:     sagas,
+		orders:    orders,
+		payments:  payments,
+		inventory: inventory,
+		currency:  currency,
+		logg:      logg,
+	}
+	sagas.Register(SagaDefinition{
+		Type: SagaTypeCheckout,
+		Steps: []SagaStep{
+			{Name: StepCapturePayment, Action: s.capturePayment, Compensate: s.refundPayment},
+			{Name: StepReserveInventory, Action: s.reserveInventory, Compensate: s.releaseInventory},
+			{Name: StepConfirmOrder, Action: s.confirmOrder},
+		},
+		Permanent: func(err error) bool {
+			return errors.Is(err, domain.ErrPaymentDeclined) || errors.Is(err, domain.ErrInsufficientStock) ||
+				errors.Is(err, domain.ErrOrderNotFound) || errors.Is(err, domain.ErrInvalidOrderStatus)
+		},
+	})
+	return s
+}
+
+// Checkout checks out the order orderID and returns its saga. Checking out
+// an order again returns the saga already running or completed for it.
+// The error of a failed checkout wraps domain.ErrCheckoutFailed and the
+// cause, once rolled back; a checkout still running or rolling back (its
+// saga unfinished) is finished by recovery.
+func (s *CheckoutService) Checkout(ctx context.Context, orderID string) (*domain.Saga, error) {
+	order, err := s.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.OrderStatusPending {
+		if saga, err := s.sagas.GetByKey(ctx, SagaTypeCheckout, orderID); err == nil && saga.Status == domain.SagaCompleted {
+			return saga, nil
+		}
+		return nil, domain.ErrInvalidOrderStatus
+	}
+
+	saga, err := s.sagas.Start(ctx, SagaTypeCheckout, orderID, nil)
+	if errors.Is(err, domain.ErrSagaAlreadyExists) {
+		return s.sagas.GetByKey(ctx, SagaTypeCheckout, orderID)
+	}
+	if err != nil && saga != nil && saga.Status == domain.SagaCompensated {
+		return saga, fmt.Errorf("%w: %w", domain.ErrCheckoutFailed, err)
+	}
+	if err != nil && saga != nil {
+		s.logg.Warn("checkout unfinished, recovery will finish it", "error", err, "order_id", orderID, "saga_id", saga.ID)
+		return saga, nil
+	}
+	return saga, err
+}
+
+// GetCheckout returns the newest checkout saga of orderID
+func (s *CheckoutService) GetCheckout(ctx context.Context, orderID string) (*domain.Saga, error) {
+	return s.sagas.GetByKey(ctx, SagaTypeCheckout, orderID)
+}
+
+// StepName returns the step a checkout is at, empty once finished
+func (s *CheckoutService) StepName(saga *domain.Saga) string {
+	return s.sagas.StepName(saga)
+}
+
+// GetStock returns the available quantity of productID; false when the
+// product is not tracked
+func (s *CheckoutService) GetStock(ctx context.Context, productID string) (int, bool, error) {
+	return s.inventory.Stock(ctx, productID)
+}
+
+// SetStock sets the available quantity of productID
+// Business rule: stock cannot be negative
+func (s *CheckoutService) SetStock(ctx context.Context, productID string, available int) error {
+	if productID == "" || available < 0 {
+		return domain.ErrInvalidInput
+	}
+	if err := s.inventory.SetStock(ctx, productID, available); err != nil {
+		return err
+	}
+	s.logg.Info("stock set", "product_id", productID, "available", available)
+	return nil
+}
+
+// capturePayment is the action of StepCapturePayment
+func (s *CheckoutService) capturePayment(ctx context.Context, saga *domain.Saga) error {
+	order, err := s.orders.GetOrderByID(ctx, saga.Key)
+	if err != nil {
+		return err
+	}
+	if order.Status != domain.OrderStatusPending {
+		return domain.ErrInvalidOrderStatus
+	}
+	amount := int64(math.Round(order.Amount * 100))
+	paymentID, err := s.payments.Capture(ctx, domain.PaymentCapture{
+		IdempotencyKey: saga.ID,
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		Amount:         amount,
+		Currency:       s.currency,
+	})
+	if err != nil {
+		return err
+	}
+	saga.Data["payment_id"] = paymentID
+	saga.Data["amount"] = strconv.FormatInt(amount, 10)
+	return nil
+}
+
+// refundPayment compensates StepCapturePayment
+func (s *CheckoutService) refundPayment(ctx context.Context, saga *domain.Saga) error {
+	if err := s.payments.Refund(ctx, saga.ID); err != nil {
+		return err
+	}
+	s.logg.Info("checkout payment refunded", "order_id", saga.Key, "saga_id", saga.ID, "payment_id", saga.Data["payment_id"])
+	return nil
+}
+
+// reserveInventory is the action of StepReserveInventory
+func (s *CheckoutService) reserveInventory(ctx context.Context, saga *domain.Saga) error {
+	order, err := s.orders.GetOrderByID(ctx, saga.Key)
+	if err != nil {
+		return err
+	}
+	return s.inventory.Reserve(ctx, saga.ID, order.Items)
+}
+
+// releaseInventory compensates StepReserveInventory
+func (s *CheckoutService) releaseInventory(ctx context.Context, saga *domain.Saga) error {
+	return s.inventory.Release(ctx, saga.ID)
+}
+
+// confirmOrder is the action of StepConfirmOrder
+func (s *CheckoutService) confirmOrder(ctx context.Context, saga *domain.Saga) error {
+	order, err := s.orders.GetOrderByID(ctx, saga.Key)
+	if err != nil {
+		return err
+	}
+	if order.Status == domain.OrderStatusConfirmed {
+		// Confirmed before a crash kept the step from being stored
+		return nil
+	}
+	_, err = s.orders.ConfirmOrder(ctx, saga.Key)
+	return err
+}
@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// maxDeadLetterReplays bounds the dead letters one bulk replay runs
+const maxDeadLetterReplays = 100
+
+// DeadLetterReplayer runs a dead letter of one source again, as the consumer
+// that gave up on it would have
+type DeadLetterReplayer func(ctx context.Context, d *domain.DeadLetter) error
+
+// EventReplayer replays the dead letters holding a CloudEvent with
+// redeliver, such as the Redeliver of an event bus
+func EventReplayer(redeliver func(ctx context.Context, event domain.Event) error) DeadLetterReplayer {
+	return func(ctx context.Context, d *domain.DeadLetter) error {
+		event, err := domain.UnmarshalCloudEvent(d.Payload)
+		if err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+		}
+		return redeliver(ctx, event)
+	}
+}
+
+// DeadLetterReplayReport is the outcome of a bulk replay
+type DeadLetterReplayReport struct {
+	Replayed int
+	Failed   []*domain.DeadLetter // With their ReplayError
+}
+
+// DeadLetterService lets operators inspect the messages consumers gave up
+// on and replay them once the cause is fixed. Each source has its replayer
+// (see Register); a dead letter of a source without one, such as an
+// optional consumer disabled on this instance, cannot be replayed here.
+//
+// A replay runs the message once more: a dead letter replayed after its
+// cause was fixed elsewhere runs twice, so handlers must be idempotent.
+type DeadLetterService struct {
+	queue     domain.DeadLetterQueue
+	replayers map[string]DeadLetterReplayer
+	retention time.Duration
+	logg      *logger.Logger
+	now       func() time.Time
+}
+
+// NewDeadLetterService creates a dead-letter service keeping dead letters
+// for retention (0 keeps them until deleted)
+func NewDeadLetterService(queue domain.DeadLetterQueue, retention time.Duration, logg *logger.Logger) *DeadLetterService {
+	return &DeadLetterService{
+		queue:     queue,
+		replayers: make(map[string]DeadLetterReplayer),
+		retention: retention,
+		logg:      logg,
+		now:       time.Now,
+	}
+}
+
+// Register sets the replayer of source's dead letters. Register replayers
+// before serving requests.
+func (s *DeadLetterService) Register(source string, replay DeadLetterReplayer) {
+	s.replayers[source] = replay
+}
+
+// JobReplayer replays job dead letters by enqueueing the job again, with
+// its priority and owner and a fresh set of attempts
+func JobReplayer(jobs *JobRunner) DeadLetterReplayer {
+	return func(ctx context.Context, d *domain.DeadLetter) error {
+		var opts []EnqueueOption
+		if owner := d.Attributes["user_id"]; owner != "" {
+			opts = append(opts, WithJobOwner(owner))
+		}
+		_, err := jobs.Enqueue(ctx, d.Type, domain.JobPriority(d.Attributes["priority"]), json.RawMessage(d.Payload), opts...)
+		return err
+	}
+}
+
+// List returns the dead letters matching filter, newest first
+// Business rule: at most 100 per page, 20 by default
+func (s *DeadLetterService) List(ctx context.Context, filter domain.DeadLetterFilter) ([]*domain.DeadLetter, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.queue.List(ctx, filter)
+}
+
+// Get returns the dead letter id
+func (s *DeadLetterService) Get(ctx context.Context, id int64) (*domain.DeadLetter, error) {
+	return s.queue.Get(ctx, id)
+}
+
+// Delete discards the dead letter id
+func (s *DeadLetterService) Delete(ctx context.Context, id int64) error {
+	if err := s.queue.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.logg.Info("dead letter deleted", "dead_letter_id", id)
+	return nil
+}
+
+// Replay runs the dead letter id again and records the outcome on it. It
+// returns the dead letter and, when the replay failed, the replay's error.
+// A dead letter already replayed can be replayed again.
+func (s *DeadLetterService) Replay(ctx context.Context, id int64) (*domain.DeadLetter, error) {
+	d, err := s.queue.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return d, s.replay(ctx, d)
+}
+
+// ReplayAll replays the dead letters matching filter, oldest first, up to
+// 100 of them. Dead letters already replayed are skipped unless
+// filter.Replayed asks for them, so replaying a source after a fix can be
+// repeated until nothing is left; sources without a replayer fail.
+func (s *DeadLetterService) ReplayAll(ctx context.Context, filter domain.DeadLetterFilter) (*DeadLetterReplayReport, error) {
+	if filter.Replayed == nil {
+		pending := false
+		filter.Replayed = &pending
+	}
+	if filter.Limit <= 0 || filter.Limit > maxDeadLetterReplays {
+		filter.Limit = maxDeadLetterReplays
+	}
+	letters, err := s.queue.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DeadLetterReplayReport{Failed: []*domain.DeadLetter{}}
+	for i := len(letters) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		if err := s.replay(ctx, letters[i]); err != nil {
+			report.Failed = append(report.Failed, letters[i])
+			continue
+		}
+		report.Replayed++
+	}
+	s.logg.Info("dead letters replayed", "source", filter.Source, "type", filter.Type, "replayed", report.Replayed, "failed", len(report.Failed))
+	return report, nil
+}
+
+// replay runs d with its source's replayer and stores the outcome on d
+func (s *DeadLetterService) replay(ctx context.Context, d *domain.DeadLetter) error {
+	replayer, ok := s.replayers[d.Source]
+	if !ok {
+		return fmt.Errorf("%w: no %s consumer", domain.ErrDeadLetterNotReplayable, d.Source)
+	}
+	logg := s.logg.WithFields("dead_letter_id", d.ID, "source", d.Source, "type", d.Type, "message_id", d.MessageID)
+
+	replayErr := replayer(ctx, d)
+	if replayErr == nil {
+		now := s.now().UTC()
+		d.Replays++
+		d.ReplayedAt, d.ReplayError = &now, ""
+	} else {
+		d.ReplayError = replayErr.Error()
+	}
+	if err := s.queue.Update(context.WithoutCancel(ctx), d); err != nil {
+		// The replay ran: a failing write must not make it look undone
+		logg.Error("failed to record dead letter replay", "error", err, "replay_error", replayErr)
+	}
+	if replayErr != nil {
+		logg.Warn("dead letter replay failed", "error", replayErr)
+		return replayErr
+	}
+	logg.Info("dead letter replayed", "replays", d.Replays)
+	return nil
+}
+
+// Run deletes the dead letters older than the retention every hour until
+// ctx ends. It returns immediately when dead letters are kept forever.
+func (s *DeadLetterService) Run(ctx context.Context) {
+	if s.retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := s.PurgeOnce(ctx); err != nil && ctx.Err() == nil {
+			s.logg.Warn("dead letter purge failed, retrying next hour", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce deletes the dead letters older than the retention
+func (s *DeadLetterService) PurgeOnce(ctx context.Context) error {
+	purged, err := s.queue.Purge(ctx, s.now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		s.logg.Info("dead letters purged", "purged", purged, "retention", s.retention)
+	}
+	return nil
+}
@@ -0,0 +1,466 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// JobFunc runs one job. A returned error retries the job until
+// JobRunnerPolicy.MaxAttempts is reached.
+type JobFunc func(ctx context.Context, run *JobRun) error
+
+// JobPriorityPolicy configures one priority level
+type JobPriorityPolicy struct {
+	Weight      int // Relative share of polls while several priorities have free workers (0 polls only when the others are empty)
+	Concurrency int // Jobs of this priority running at once on this replica (0 pauses the priority)
+}
+
+// JobRunnerPolicy configures the job runner
+type JobRunnerPolicy struct {
+	Priorities    map[domain.JobPriority]JobPriorityPolicy
+	PollInterval  time.Duration // How long to wait after finding every queue empty
+	MaxAttempts   int           // Runs per job before it is dropped (0 or 1 never retries)
+	RetryDelay    time.Duration // Backoff before the first retry, doubling with every further failure
+	MaxRetryDelay time.Duration // Longest backoff between attempts
+}
+
+// DefaultJobRunnerPolicy returns conservative defaults: critical jobs are
+// polled six times as often as bulk ones, bulk work gets a single worker and
+// retries back off from a second up to a minute
+func DefaultJobRunnerPolicy() JobRunnerPolicy {
+	return JobRunnerPolicy{
+		Priorities: map[domain.JobPriority]JobPriorityPolicy{
+			domain.JobPriorityCritical: {Weight: 6, Concurrency: 4},
+			domain.JobPriorityDefault:  {Weight: 3, Concurrency: 4},
+			domain.JobPriorityBulk:     {Weight: 1, Concurrency: 1},
+		},
+		PollInterval:  time.Second,
+		MaxAttempts:   3,
+		RetryDelay:    time.Second,
+		MaxRetryDelay: time.Minute,
+	}
+}
+
+// JobRunner enqueues background jobs, runs them with registered handlers and
+// keeps a status record of each (see Get).
+//
+// Every priority has its own pool of worker slots, so a backlog of bulk jobs
+// can only ever occupy the bulk slots: critical jobs always find a free
+// worker of their own. Among priorities with free slots the next queue to
+// poll is picked at random in proportion to its weight.
+//
+// A failed job frees its slot and goes back on its queue after a jittered
+// exponential backoff. Until then it is held in memory only, so a job
+// waiting out its backoff when the process crashes is lost.
+type JobRunner struct {
+	queue   domain.JobQueue
+	records domain.JobRecordRepository
+	policy  JobRunnerPolicy
+	logg    *logger.Logger
+	dead    domain.DeadLetterQueue // Nil drops the jobs that gave up
+
+	mu       sync.RWMutex
+	handlers map[string]JobFunc
+
+	slots map[domain.JobPriority]chan struct{} // Buffered to the priority's concurrency
+	freed chan struct{}                        // Signalled when a running job finishes
+	wg    sync.WaitGroup
+}
+
+// JobRunnerOption configures a JobRunner
+type JobRunnerOption func(*JobRunner)
+
+// WithJobDeadLetters keeps the jobs that gave up in dead, to be replayed
+// once whatever failed them is fixed
+func WithJobDeadLetters(dead domain.DeadLetterQueue) JobRunnerOption {
+	return func(r *JobRunner) {
+		r.dead = dead
+	}
+}
+
+// NewJobRunner creates a job runner. Priorities missing from policy use the defaults.
+func NewJobRunner(queue domain.JobQueue, records domain.JobRecordRepository, policy JobRunnerPolicy, logg *logger.Logger, opts ...JobRunnerOption) *JobRunner {
+	defaults := DefaultJobRunnerPolicy()
+	if policy.PollInterval <= 0 {
+		policy.PollInterval = defaults.PollInterval
+	}
+	if policy.RetryDelay <= 0 {
+		policy.RetryDelay = defaults.RetryDelay
+	}
+	if policy.MaxRetryDelay <= 0 {
+		policy.MaxRetryDelay = defaults.MaxRetryDelay
+	}
+	policy.MaxRetryDelay = max(policy.MaxRetryDelay, policy.RetryDelay)
+
+	slots := make(map[domain.JobPriority]chan struct{}, len(domain.JobPriorities))
+	priorities := make(map[domain.JobPriority]JobPriorityPolicy, len(domain.JobPriorities))
+	for _, p := range domain.JobPriorities {
+		pp, ok := policy.Priorities[p]
+		if !ok {
+			pp = defaults.Priorities[p]
+		}
+		priorities[p] = pp
+		slots[p] = make(chan struct{}, max(pp.Concurrency, 0))
+	}
+	policy.Priorities = priorities
+
+	r := &JobRunner{
+		queue:    queue,
+		records:  records,
+		policy:   policy,
+		logg:     logg,
+		handlers: make(map[string]JobFunc),
+		slots:    slots,
+		freed:    make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Handle registers the handler for jobType. Register handlers before Run.
+func (r *JobRunner) Handle(jobType string, handler JobFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = handler
+}
+
+func (r *JobRunner) handler(jobType string) (JobFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[jobType]
+	return h, ok
+}
+
+// EnqueueOption customizes a job at enqueue time
+type EnqueueOption func(*domain.Job)
+
+// WithJobOwner records the user the job runs for, who may then query its status
+func WithJobOwner(userID string) EnqueueOption {
+	return func(j *domain.Job) {
+		j.UserID = userID
+	}
+}
+
+// Enqueue queues a job of jobType with payload encoded as JSON. An empty
+// priority means domain.JobPriorityDefault. The returned job's ID identifies
+// its status record.
+func (r *JobRunner) Enqueue(ctx context.Context, jobType string, priority domain.JobPriority, payload any, opts ...EnqueueOption) (*domain.Job, error) {
+	if _, ok := r.handler(jobType); !ok {
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnknownJobType, jobType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	job, err := domain.NewJob(uuid.New().String(), jobType, priority, data)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	// The record exists before the job can be picked up
+	record := domain.NewJobRecord(job)
+	if err := r.records.Create(ctx, record); err != nil {
+		return nil, err
+	}
+	if err := r.queue.Enqueue(ctx, job); err != nil {
+		r.logg.Error("failed to enqueue job", "error", err, "job_type", jobType, "priority", job.Priority)
+		record.Fail("enqueue failed", 0, true, time.Now())
+		r.saveRecord(context.WithoutCancel(ctx), record)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Get returns the status record of job id
+func (r *JobRunner) Get(ctx context.Context, id string) (*domain.JobRecord, error) {
+	return r.records.GetByID(ctx, id)
+}
+
+// saveRecord persists a status change. Tracking is best-effort: a failed
+// write is logged and the job carries on.
+func (r *JobRunner) saveRecord(ctx context.Context, record *domain.JobRecord) {
+	if err := r.records.Update(ctx, record); err != nil {
+		r.logg.Warn("failed to update job status", "error", err, "job_id", record.ID, "state", record.State)
+	}
+}
+
+// Run polls the queues and runs jobs until ctx is cancelled, then re-enqueues
+// the jobs waiting to be retried and waits for running jobs to finish. Running jobs are not cancelled with ctx, so bound
+// the wait by how long Run may take to return. It returns immediately when
+// no handlers are registered.
+func (r *JobRunner) Run(ctx context.Context) {
+	r.mu.RLock()
+	handlers := len(r.handlers)
+	r.mu.RUnlock()
+	if handlers == 0 {
+		r.logg.Debug("no job handlers registered, job runner idle")
+		return
+	}
+	defer r.wg.Wait()
+
+	for ctx.Err() == nil {
+		candidates := r.pollOrder()
+		if len(candidates) == 0 {
+			// Every priority is at its concurrency limit
+			r.wait(ctx, 0)
+			continue
+		}
+
+		dispatched, err := r.dispatchNext(ctx, candidates)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logg.Warn("job dequeue failed", "error", err)
+		}
+		if !dispatched {
+			r.wait(ctx, r.policy.PollInterval)
+		}
+	}
+}
+
+// wait blocks until a running job finishes, d elapses (0 waits indefinitely)
+// or ctx is done
+func (r *JobRunner) wait(ctx context.Context, d time.Duration) {
+	var timeout <-chan time.Time
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ctx.Done():
+	case <-r.freed:
+	case <-timeout:
+	}
+}
+
+// pollOrder returns the priorities with a free slot: those with a weight in
+// weighted-random order, then the zero-weight ones highest first
+func (r *JobRunner) pollOrder() []domain.JobPriority {
+	var weighted, fallback []domain.JobPriority
+	total := 0
+	for _, p := range domain.JobPriorities {
+		if len(r.slots[p]) >= cap(r.slots[p]) {
+			continue
+		}
+		if w := r.policy.Priorities[p].Weight; w > 0 {
+			weighted = append(weighted, p)
+			total += w
+		} else {
+			fallback = append(fallback, p)
+		}
+	}
+
+	order := make([]domain.JobPriority, 0, len(weighted)+len(fallback))
+	for len(weighted) > 0 {
+		n := rand.N(total)
+		for i, p := range weighted {
+			w := r.policy.Priorities[p].Weight
+			if n < w {
+				order = append(order, p)
+				weighted = append(weighted[:i], weighted[i+1:]...)
+				total -= w
+				break
+			}
+			n -= w
+		}
+	}
+	return append(order, fallback...)
+}
+
+// dispatchNext starts the first job found in candidates, in order
+func (r *JobRunner) dispatchNext(ctx context.Context, candidates []domain.JobPriority) (bool, error) {
+	for _, p := range candidates {
+		job, err := r.queue.Dequeue(ctx, p)
+		if err != nil {
+			return false, err
+		}
+		if job == nil {
+			continue
+		}
+
+		// Only Run takes slots, so the free slot seen by pollOrder is still free
+		r.slots[p] <- struct{}{}
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer r.release(p)
+			r.run(ctx, job)
+		}()
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *JobRunner) release(p domain.JobPriority) {
+	<-r.slots[p]
+	select {
+	case r.freed <- struct{}{}:
+	default:
+	}
+}
+
+// JobRun is a job being executed, passed to its JobFunc
+type JobRun struct {
+	*domain.Job
+
+	runner *JobRunner
+	mu     sync.Mutex
+	record *domain.JobRecord
+	result string
+}
+
+// Progress records percent complete on the job's status record
+func (j *JobRun) Progress(ctx context.Context, percent int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if percent == j.record.Progress {
+		return
+	}
+	j.record.SetProgress(percent, time.Now())
+	j.runner.saveRecord(ctx, j.record)
+}
+
+// SetResult sets the pointer to the job's output (e.g. a blob key), saved
+// when the job succeeds
+func (j *JobRun) SetResult(result string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.result = result
+}
+
+// run executes job, re-enqueueing it after a backoff on failure until
+// MaxAttempts. ctx is Run's: the job itself runs without its cancellation,
+// which only cuts short the wait before a retry.
+func (r *JobRunner) run(ctx context.Context, job *domain.Job) {
+	stop := ctx.Done()
+	ctx = context.WithoutCancel(ctx)
+	logg := r.logg.WithFields("job_id", job.ID, "job_type", job.Type, "priority", job.Priority)
+
+	record, err := r.records.GetByID(ctx, job.ID)
+	if err != nil {
+		// Keep running untracked rather than dropping the job
+		logg.Warn("job status record unavailable", "error", err)
+		record = domain.NewJobRecord(job)
+	}
+
+	handler, ok := r.handler(job.Type)
+	if !ok {
+		logg.Error("no handler for job type, dropping job")
+		record.Fail("no handler for job type", job.Attempts, true, time.Now())
+		r.saveRecord(ctx, record)
+		r.deadLetter(ctx, job, record.Error)
+		return
+	}
+
+	start := time.Now()
+	record.Start(start)
+	r.saveRecord(ctx, record)
+
+	jr := &JobRun{Job: job, runner: r, record: record}
+	err = safeRun(ctx, handler, jr)
+
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	if err == nil {
+		record.Succeed(jr.result, time.Now())
+		r.saveRecord(ctx, record)
+		logg.Debug("job completed", "duration", time.Since(start), "attempt", job.Attempts+1)
+		return
+	}
+
+	job.Attempts++
+	final := job.Attempts >= r.policy.MaxAttempts
+	record.Fail(err.Error(), job.Attempts, final, time.Now())
+	r.saveRecord(ctx, record)
+	if final {
+		logg.Error("job failed, giving up", "error", err, "attempts", job.Attempts)
+		r.deadLetter(ctx, job, err.Error())
+		return
+	}
+	delay := r.retryDelay(job.Attempts)
+	logg.Warn("job failed, retrying", "error", err, "attempts", job.Attempts, "retry_in", delay)
+	r.wg.Add(1)
+	go r.retryAfter(ctx, stop, job, record, delay)
+}
+
+// retryDelay returns the backoff before retrying a job that has failed
+// attempts times: RetryDelay doubled for every failure after the first, up
+// to MaxRetryDelay, with jitter so jobs failing together spread out
+func (r *JobRunner) retryDelay(attempts int) time.Duration {
+	d := r.policy.RetryDelay
+	for i := 1; i < attempts && d < r.policy.MaxRetryDelay; i++ {
+		d *= 2
+	}
+	d = min(d, r.policy.MaxRetryDelay)
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter puts job back on its queue once delay has passed, or at once
+// when stop is closed so shutting down neither waits for the backoff nor
+// forgets the job
+func (r *JobRunner) retryAfter(ctx context.Context, stop <-chan struct{}, job *domain.Job, record *domain.JobRecord, delay time.Duration) {
+	defer r.wg.Done()
+	timer := time.NewTimer(delay)
+	select {
+	case <-stop:
+		timer.Stop()
+	case <-timer.C:
+	}
+
+	if err := r.queue.Enqueue(ctx, job); err != nil {
+		r.logg.Error("failed to re-enqueue job, dropping it", "error", err, "job_id", job.ID, "job_type", job.Type)
+		record.Fail("re-enqueue failed: "+err.Error(), job.Attempts, true, time.Now())
+		r.saveRecord(ctx, record)
+		r.deadLetter(ctx, job, record.Error)
+	}
+}
+
+// deadLetter keeps job, which gave up with cause, for replay
+func (r *JobRunner) deadLetter(ctx context.Context, job *domain.Job, cause string) {
+	if r.dead == nil {
+		return
+	}
+	d := &domain.DeadLetter{
+		Source:     domain.DeadLetterJobs,
+		Type:       job.Type,
+		MessageID:  job.ID,
+		Payload:    job.Payload,
+		Attributes: map[string]string{"priority": string(job.Priority)},
+		Error:      cause,
+		Attempts:   job.Attempts,
+		FailedAt:   time.Now().UTC(),
+	}
+	if job.UserID != "" {
+		d.Attributes["user_id"] = job.UserID
+	}
+	if err := r.dead.Add(ctx, d); err != nil {
+		r.logg.Error("failed to dead-letter job, dropping it", "error", err, "job_id", job.ID, "job_type", job.Type)
+	}
+}
+
+// safeRun calls handler, turning a panic into an error so one bad job
+// cannot take down the process
+func safeRun(ctx context.Context, handler JobFunc, run *JobRun) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job handler panicked: %v", rec)
+		}
+	}()
+	return handler(ctx, run)
+}
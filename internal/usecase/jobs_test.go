@@ -0,0 +1,312 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+var errJobFailed = errors.New("boom")
+
+// testJobPolicy polls every few milliseconds and backs off briefly, giving
+// every priority one worker and only critical jobs a weight
+func testJobPolicy() JobRunnerPolicy {
+	return JobRunnerPolicy{
+		Priorities: map[domain.JobPriority]JobPriorityPolicy{
+			domain.JobPriorityCritical: {Weight: 1, Concurrency: 1},
+			domain.JobPriorityDefault:  {Weight: 0, Concurrency: 1},
+			domain.JobPriorityBulk:     {Weight: 0, Concurrency: 1},
+		},
+		PollInterval:  5 * time.Millisecond,
+		MaxAttempts:   3,
+		RetryDelay:    20 * time.Millisecond,
+		MaxRetryDelay: 40 * time.Millisecond,
+	}
+}
+
+// startRunner runs r and returns a function stopping it, which fails the
+// test if Run does not return promptly
+func startRunner(t *testing.T, r *JobRunner) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+	stop := func() {
+		t.Helper()
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after its context ended")
+		}
+	}
+	t.Cleanup(cancel)
+	return stop
+}
+
+// eventually polls cond until it holds, failing the test after a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobRunnerPollOrder(t *testing.T) {
+	critical, def, bulk := domain.JobPriorityCritical, domain.JobPriorityDefault, domain.JobPriorityBulk
+	tests := []struct {
+		name    string
+		weights [3]int // critical, default, bulk
+		paused  []domain.JobPriority
+		want    []domain.JobPriority
+	}{
+		{"no weights polls highest first", [3]int{0, 0, 0}, nil, []domain.JobPriority{critical, def, bulk}},
+		{"weighted before zero weight", [3]int{0, 0, 1}, nil, []domain.JobPriority{bulk, critical, def}},
+		{"only critical weighted", [3]int{1, 0, 0}, nil, []domain.JobPriority{critical, def, bulk}},
+		{"paused priority skipped", [3]int{0, 0, 0}, []domain.JobPriority{critical}, []domain.JobPriority{def, bulk}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := testJobPolicy()
+			for i, p := range domain.JobPriorities {
+				concurrency := 1
+				if slices.Contains(tt.paused, p) {
+					concurrency = 0
+				}
+				policy.Priorities[p] = JobPriorityPolicy{Weight: tt.weights[i], Concurrency: concurrency}
+			}
+			r := NewJobRunner(memory.NewJobQueue(), memory.NewJobRecordRepository(), policy, logger.New("error"))
+			if got := r.pollOrder(); !slices.Equal(got, tt.want) {
+				t.Errorf("pollOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobRunnerPollOrderSkipsBusyPriorities(t *testing.T) {
+	r := NewJobRunner(memory.NewJobQueue(), memory.NewJobRecordRepository(), testJobPolicy(), logger.New("error"))
+	r.slots[domain.JobPriorityCritical] <- struct{}{}
+
+	want := []domain.JobPriority{domain.JobPriorityDefault, domain.JobPriorityBulk}
+	if got := r.pollOrder(); !slices.Equal(got, want) {
+		t.Errorf("pollOrder() with critical at its limit = %v, want %v", got, want)
+	}
+}
+
+// recordingJobQueue records the priority of every job it hands out
+type recordingJobQueue struct {
+	domain.JobQueue
+	mu       sync.Mutex
+	dequeued []domain.JobPriority
+}
+
+func (q *recordingJobQueue) Dequeue(ctx context.Context, priority domain.JobPriority) (*domain.Job, error) {
+	job, err := q.JobQueue.Dequeue(ctx, priority)
+	if job != nil {
+		q.mu.Lock()
+		q.dequeued = append(q.dequeued, job.Priority)
+		q.mu.Unlock()
+	}
+	return job, err
+}
+
+func TestJobRunnerRunsHigherPriorityFirst(t *testing.T) {
+	ctx := context.Background()
+	queue := &recordingJobQueue{JobQueue: memory.NewJobQueue()}
+	records := memory.NewJobRecordRepository()
+	r := NewJobRunner(queue, records, testJobPolicy(), logger.New("error"))
+	r.Handle("work", func(ctx context.Context, run *JobRun) error { return nil })
+
+	// Queued lowest first, so FIFO order alone would run bulk first
+	var jobs []*domain.Job
+	for _, p := range []domain.JobPriority{domain.JobPriorityBulk, domain.JobPriorityDefault, domain.JobPriorityCritical} {
+		job, err := r.Enqueue(ctx, "work", p, nil)
+		if err != nil {
+			t.Fatalf("Enqueue(%s): %v", p, err)
+		}
+		jobs = append(jobs, job)
+	}
+	stop := startRunner(t, r)
+	eventually(t, "every job to succeed", func() bool {
+		for _, job := range jobs {
+			if record, err := records.GetByID(ctx, job.ID); err != nil || record.State != domain.JobSucceeded {
+				return false
+			}
+		}
+		return true
+	})
+	stop()
+
+	want := []domain.JobPriority{domain.JobPriorityCritical, domain.JobPriorityDefault, domain.JobPriorityBulk}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if !slices.Equal(queue.dequeued, want) {
+		t.Errorf("dequeue order = %v, want %v", queue.dequeued, want)
+	}
+}
+
+func TestJobRunnerRetriesWithBackoffThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	records := memory.NewJobRecordRepository()
+	dead := memory.NewDeadLetterQueue()
+	r := NewJobRunner(memory.NewJobQueue(), records, testJobPolicy(), logger.New("error"), WithJobDeadLetters(dead))
+
+	var mu sync.Mutex
+	var runs []time.Time
+	r.Handle("flaky", func(ctx context.Context, run *JobRun) error {
+		mu.Lock()
+		defer mu.Unlock()
+		runs = append(runs, time.Now())
+		return errJobFailed
+	})
+	job, err := r.Enqueue(ctx, "flaky", domain.JobPriorityDefault, map[string]string{"id": "42"}, WithJobOwner("user-1"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	stop := startRunner(t, r)
+
+	var letters []*domain.DeadLetter
+	eventually(t, "the job to be dead-lettered", func() bool {
+		letters, err = dead.List(ctx, domain.DeadLetterFilter{})
+		return err == nil && len(letters) > 0
+	})
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(runs) != 3 {
+		t.Fatalf("ran %d times, want MaxAttempts (3)", len(runs))
+	}
+	// Jittered down to half of 20ms before the first retry, then of 40ms
+	for i, least := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond} {
+		if gap := runs[i+1].Sub(runs[i]); gap < least {
+			t.Errorf("retry %d ran %v after the failure, want at least %v", i+1, gap, least)
+		}
+	}
+
+	record, err := records.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if record.State != domain.JobFailed || record.Attempts != 3 || record.Error != errJobFailed.Error() {
+		t.Errorf("record = %s after %d attempts (%q), want failed after 3 (%q)", record.State, record.Attempts, record.Error, errJobFailed)
+	}
+
+	if len(letters) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(letters))
+	}
+	d := letters[0]
+	if d.Source != domain.DeadLetterJobs || d.Type != "flaky" || d.MessageID != job.ID {
+		t.Errorf("dead letter = %s %s %s, want jobs flaky %s", d.Source, d.Type, d.MessageID, job.ID)
+	}
+	if d.Attempts != 3 || d.Error != errJobFailed.Error() || string(d.Payload) != `{"id":"42"}` {
+		t.Errorf("dead letter = %d attempts, error %q, payload %s", d.Attempts, d.Error, d.Payload)
+	}
+	if d.Attributes["priority"] != string(domain.JobPriorityDefault) || d.Attributes["user_id"] != "user-1" {
+		t.Errorf("dead letter attributes = %v", d.Attributes)
+	}
+}
+
+func TestJobRunnerSucceedsOnRetry(t *testing.T) {
+	ctx := context.Background()
+	records := memory.NewJobRecordRepository()
+	dead := memory.NewDeadLetterQueue()
+	r := NewJobRunner(memory.NewJobQueue(), records, testJobPolicy(), logger.New("error"), WithJobDeadLetters(dead))
+
+	var mu sync.Mutex
+	calls := 0
+	r.Handle("flaky", func(ctx context.Context, run *JobRun) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return errJobFailed
+		}
+		run.SetResult("reports/42.csv")
+		return nil
+	})
+	job, err := r.Enqueue(ctx, "flaky", domain.JobPriorityBulk, nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	stop := startRunner(t, r)
+
+	var record *domain.JobRecord
+	eventually(t, "the job to succeed", func() bool {
+		record, err = records.GetByID(ctx, job.ID)
+		return err == nil && record.State == domain.JobSucceeded
+	})
+	stop()
+
+	if record.Attempts != 1 || record.Result != "reports/42.csv" {
+		t.Errorf("record = %d failed attempts with result %q, want 1 with reports/42.csv", record.Attempts, record.Result)
+	}
+	if letters, _ := dead.List(ctx, domain.DeadLetterFilter{}); len(letters) != 0 {
+		t.Errorf("dead letters = %d, want none", len(letters))
+	}
+}
+
+func TestJobRunnerShutdownRequeuesPendingRetry(t *testing.T) {
+	ctx := context.Background()
+	queue := memory.NewJobQueue()
+	records := memory.NewJobRecordRepository()
+	policy := testJobPolicy()
+	policy.RetryDelay, policy.MaxRetryDelay = time.Hour, time.Hour
+	r := NewJobRunner(queue, records, policy, logger.New("error"))
+	r.Handle("flaky", func(ctx context.Context, run *JobRun) error { return errJobFailed })
+
+	job, err := r.Enqueue(ctx, "flaky", domain.JobPriorityCritical, nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	stop := startRunner(t, r)
+	eventually(t, "the first attempt to fail", func() bool {
+		record, err := records.GetByID(ctx, job.ID)
+		return err == nil && record.Attempts == 1
+	})
+	stop() // Well before the hour's backoff is up
+
+	requeued, err := queue.Dequeue(ctx, domain.JobPriorityCritical)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if requeued == nil || requeued.ID != job.ID || requeued.Attempts != 1 {
+		t.Fatalf("queued after shutdown = %+v, want job %s with 1 attempt", requeued, job.ID)
+	}
+}
+
+func TestJobRunnerRetryDelay(t *testing.T) {
+	policy := testJobPolicy()
+	policy.RetryDelay, policy.MaxRetryDelay = 100*time.Millisecond, time.Second
+	r := NewJobRunner(memory.NewJobQueue(), memory.NewJobRecordRepository(), policy, logger.New("error"))
+
+	ceilings := []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second,
+	}
+	for i, ceiling := range ceilings {
+		for range 20 {
+			if d := r.retryDelay(i + 1); d < ceiling/2 || d > ceiling {
+				t.Errorf("retryDelay(%d) = %v, want between %v and %v", i+1, d, ceiling/2, ceiling)
+			}
+		}
+	}
+	if d := r.retryDelay(100); d > time.Second {
+		t.Errorf("retryDelay(100) = %v, want at most MaxRetryDelay", d)
+	}
+}
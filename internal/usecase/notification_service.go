@@ -0,0 +1,276 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// JobTypeNotification delivers one notification on one channel
+const JobTypeNotification = "notification.send"
+
+// NotificationPolicy configures notifications
+type NotificationPolicy struct {
+	// DefaultChannels are the channels of event types a user has no
+	// preference for
+	DefaultChannels []domain.NotificationChannel
+}
+
+// DefaultNotificationPolicy returns sensible defaults: email only, the one
+// channel every user has an address for
+func DefaultNotificationPolicy() NotificationPolicy {
+	return NotificationPolicy{
+		DefaultChannels: []domain.NotificationChannel{domain.NotificationEmail},
+	}
+}
+
+// notifiedEvents are the event types users are notified of
+var notifiedEvents = []string{
+	domain.EventOrderCreated,
+	domain.EventOrderConfirmed,
+	domain.EventOrderShipped,
+	domain.EventOrderDelivered,
+	domain.EventOrderCancelled,
+}
+
+// NotificationService notifies users of the domain events that concern
+// them, on the channels they chose for each event type. Each event enqueues
+// a job per channel, so a channel that is down delays only its own
+// notifications and is retried with the other jobs. Addresses are looked up
+// when the job runs, so job payloads hold no contact details and a changed
+// address applies to notifications still queued.
+//
+// Event buses deliver at least once: an event delivered twice notifies
+// twice.
+type NotificationService struct {
+	preferences domain.NotificationPreferencesRepository
+	users       domain.UserRepository
+	notifiers   map[domain.NotificationChannel]domain.Notifier
+	jobs        *JobRunner
+	policy      NotificationPolicy
+	logg        *logger.Logger
+}
+
+// NewNotificationService creates a notification service delivering through
+// notifiers, by channel. Channels without a notifier are unavailable: users
+// cannot choose them and defaults using them are skipped.
+func NewNotificationService(preferences domain.NotificationPreferencesRepository, users domain.UserRepository, notifiers map[domain.NotificationChannel]domain.Notifier, jobs *JobRunner, policy NotificationPolicy, logg *logger.Logger) *NotificationService {
+	if policy.DefaultChannels == nil {
+		policy.DefaultChannels = DefaultNotificationPolicy().DefaultChannels
+	}
+	return &NotificationService{
+		preferences: preferences,
+		users:       users,
+		notifiers:   notifiers,
+		jobs:        jobs,
+		policy:      policy,
+		logg:        logg,
+	}
+}
+
+// Register adds the service's job handler to its job runner and subscribes
+// it to the events of bus it notifies of
+func (s *NotificationService) Register(bus domain.EventBus) (unsubscribe func()) {
+	s.jobs.Handle(JobTypeNotification, s.Send)
+	return bus.Subscribe(s.HandleEvent, notifiedEvents...)
+}
+
+// Channels returns the available channels
+func (s *NotificationService) Channels() []domain.NotificationChannel {
+	var channels []domain.NotificationChannel
+	for _, channel := range domain.NotificationChannels {
+		if s.notifiers[channel] != nil {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// DefaultChannels returns the channels of event types users have no
+// preference for
+func (s *NotificationService) DefaultChannels() []domain.NotificationChannel {
+	return slices.Clone(s.policy.DefaultChannels)
+}
+
+// GetPreferences returns the notification preferences of userID, empty
+// (every event type on the default channels) when the user saved none
+func (s *NotificationService) GetPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	p, err := s.preferences.Get(ctx, userID)
+	if errors.Is(err, domain.ErrNotificationPreferencesNotFound) {
+		if _, err := s.users.GetByID(ctx, userID); err != nil {
+			return nil, err
+		}
+		return &domain.NotificationPreferences{
+			UserID:    userID,
+			Addresses: map[domain.NotificationChannel]string{},
+			Events:    map[string][]domain.NotificationChannel{},
+		}, nil
+	}
+	return p, err
+}
+
+// UpdatePreferences replaces the notification preferences of userID
+// Business rule: only available channels may be chosen
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID string, addresses map[domain.NotificationChannel]string, events map[string][]domain.NotificationChannel) (*domain.NotificationPreferences, error) {
+	p := &domain.NotificationPreferences{
+		UserID:    userID,
+		Addresses: addresses,
+		Events:    events,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if p.Addresses == nil {
+		p.Addresses = map[domain.NotificationChannel]string{}
+	}
+	if p.Events == nil {
+		p.Events = map[string][]domain.NotificationChannel{}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	for eventType, channels := range p.Events {
+		for _, channel := range channels {
+			if s.notifiers[channel] == nil {
+				return nil, fmt.Errorf("%w: %s (for %s)", domain.ErrNotificationChannelUnavailable, channel, eventType)
+			}
+		}
+	}
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.preferences.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	s.logg.Info("notification preferences updated", "user_id", userID, "event_types", len(p.Events))
+	return p, nil
+}
+
+// notificationJob is the payload of a JobTypeNotification job
+type notificationJob struct {
+	Channel      domain.NotificationChannel `json:"channel"`
+	Notification domain.Notification        `json:"notification"`
+}
+
+// HandleEvent is the domain.EventHandler enqueueing the notifications of
+// event, one job per channel its user chose for its type
+func (s *NotificationService) HandleEvent(ctx context.Context, event domain.Event) error {
+	n, ok := s.render(event)
+	if !ok {
+		return nil
+	}
+
+	channels := s.policy.DefaultChannels
+	p, err := s.preferences.Get(ctx, n.UserID)
+	switch {
+	case err == nil:
+		channels = p.ChannelsFor(n.EventType, s.policy.DefaultChannels)
+	case !errors.Is(err, domain.ErrNotificationPreferencesNotFound):
+		return err
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		if s.notifiers[channel] == nil {
+			// Chosen while the channel was configured, or a default that is not
+			s.logg.Debug("notification channel unavailable, skipping", "channel", channel, "event_type", n.EventType)
+			continue
+		}
+		if _, err := s.jobs.Enqueue(ctx, JobTypeNotification, domain.JobPriorityDefault,
+			notificationJob{Channel: channel, Notification: n}, WithJobOwner(n.UserID)); err != nil {
+			errs = append(errs, fmt.Errorf("enqueueing %s notification: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Send is the JobFunc for JobTypeNotification
+func (s *NotificationService) Send(ctx context.Context, run *JobRun) error {
+	var payload notificationJob
+	if err := json.Unmarshal(run.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	n := payload.Notification
+	logg := s.logg.WithFields("channel", payload.Channel, "user_id", n.UserID, "event_type", n.EventType, "event_id", n.EventID)
+
+	notifier := s.notifiers[payload.Channel]
+	if notifier == nil {
+		logg.Warn("notification channel no longer available, dropping notification")
+		return nil
+	}
+	address, err := s.address(ctx, n.UserID, payload.Channel)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		logg.Info("user deleted, dropping notification")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if address == "" {
+		logg.Info("no address for channel, dropping notification")
+		return nil
+	}
+
+	if err := notifier.Notify(ctx, address, n); err != nil {
+		// Services tell which errors are worth retrying (see notify.APIError)
+		var retriable interface{ Retriable() bool }
+		if errors.As(err, &retriable) && !retriable.Retriable() {
+			logg.Warn("notification rejected, dropping it", "error", err)
+			return nil
+		}
+		return err
+	}
+	logg.Debug("notification sent")
+	return nil
+}
+
+// address returns the address of userID on channel, empty when the user
+// has none
+func (s *NotificationService) address(ctx context.Context, userID string, channel domain.NotificationChannel) (string, error) {
+	p, err := s.preferences.Get(ctx, userID)
+	if err != nil && !errors.Is(err, domain.ErrNotificationPreferencesNotFound) {
+		return "", err
+	}
+	if p != nil && p.Addresses[channel] != "" {
+		return p.Addresses[channel], nil
+	}
+	if channel != domain.NotificationEmail {
+		return "", nil
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return user.Email, nil
+}
+
+// render returns the notification of event, false for events users are not
+// notified of
+func (s *NotificationService) render(event domain.Event) (domain.Notification, bool) {
+	n := domain.Notification{EventType: event.EventType(), EventID: event.Metadata().ID}
+	switch e := event.(type) {
+	case domain.OrderCreated:
+		n.UserID, n.Subject = e.UserID, "Order received"
+		n.Body = fmt.Sprintf("We received your order %s of %.2f.", e.OrderID, e.Amount)
+	case domain.OrderConfirmed:
+		n.UserID, n.Subject = e.UserID, "Order confirmed"
+		n.Body = fmt.Sprintf("Your order %s is confirmed.", e.OrderID)
+	case domain.OrderShipped:
+		n.UserID, n.Subject = e.UserID, "Order shipped"
+		n.Body = fmt.Sprintf("Your order %s is on its way.", e.OrderID)
+	case domain.OrderDelivered:
+		n.UserID, n.Subject = e.UserID, "Order delivered"
+		n.Body = fmt.Sprintf("Your order %s was delivered.", e.OrderID)
+	case domain.OrderCancelled:
+		n.UserID, n.Subject = e.UserID, "Order cancelled"
+		n.Body = fmt.Sprintf("Your order %s was cancelled.", e.OrderID)
+	default:
+		return n, false
+	}
+	return n, n.UserID != ""
+}
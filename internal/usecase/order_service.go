@@ -6,7 +6,8 @@ import (
 	"fmt"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/internal/featureflag"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
 	"github.com/google/uuid"
 )
 
@@ -16,17 +17,76 @@ type OrderService struct {
 	orderRepo  domain.OrderRepository
 	userRepo   domain.UserRepository
 	orderCache domain.OrderCache
+	flags      *featureflag.Client  // Nil keeps every flagged behaviour off
+	events     domain.OrderEventBus // Nil publishes no order events
+	eventLog   domain.OrderEventLog // Nil keeps no order events
+	domainBus  domain.EventBus      // Nil emits no domain events
+	tx         domain.Transactor    // With outbox; nil outside the outbox
+	outbox     domain.Outbox        // Nil hands domain events to domainBus directly
 	logg       *logger.Logger
 }
 
+// FlagNewOrderFlow rolls out the new order flow: order lines for the same
+// product at the same price are merged into one line
+const FlagNewOrderFlow = "new_order_flow"
+
+// OrderServiceOption configures an OrderService
+type OrderServiceOption func(*OrderService)
+
+// WithOrderFeatureFlags evaluates FlagNewOrderFlow for each order's owner
+func WithOrderFeatureFlags(flags *featureflag.Client) OrderServiceOption {
+	return func(s *OrderService) {
+		s.flags = flags
+	}
+}
+
+// WithOrderEvents publishes an event for every order created or changing status
+func WithOrderEvents(bus domain.OrderEventBus) OrderServiceOption {
+	return func(s *OrderService) {
+		s.events = bus
+	}
+}
+
+// WithOrderEventLog records every order event in log before it is
+// published, so the event carries the log's ID
+func WithOrderEventLog(log domain.OrderEventLog) OrderServiceOption {
+	return func(s *OrderService) {
+		s.eventLog = log
+	}
+}
+
+// WithDomainEvents emits a typed lifecycle event (domain.OrderCreated,
+// OrderConfirmed, ...) on bus for every order created or changing status,
+// for subscribers such as webhooks, emails and analytics
+func WithDomainEvents(bus domain.EventBus) OrderServiceOption {
+	return func(s *OrderService) {
+		s.domainBus = bus
+	}
+}
+
+// WithOutbox stores each lifecycle event in outbox, in the transaction of
+// the order write it announces, instead of handing it to the domain event
+// bus; an OutboxRelay publishes it from there. Events are then never lost
+// to a crash between the write and the publish.
+func WithOutbox(tx domain.Transactor, outbox domain.Outbox) OrderServiceOption {
+	return func(s *OrderService) {
+		s.tx = tx
+		s.outbox = outbox
+	}
+}
+
 // NewOrderService creates a new order service
-func NewOrderService(orderRepo domain.OrderRepository, userRepo domain.UserRepository, orderCache domain.OrderCache, logg *logger.Logger) *OrderService {
-	return &OrderService{
+func NewOrderService(orderRepo domain.OrderRepository, userRepo domain.UserRepository, orderCache domain.OrderCache, logg *logger.Logger, opts ...OrderServiceOption) *OrderService {
+	s := &OrderService{
 		orderRepo:  orderRepo,
 		userRepo:   userRepo,
 		orderCache: orderCache,
 		logg:       logg,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // CreateOrder creates a new order with validation
@@ -43,6 +103,11 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []d
 		return nil, fmt.Errorf("%w: failed to verify user", domain.ErrInternalError)
 	}
 
+	// Flagged per owner, so an admin ordering for a user gets that user's flow
+	if s.flags.EnabledFor(ctx, FlagNewOrderFlow, userID) {
+		items = mergeOrderItems(items)
+	}
+
 	// Generate unique ID for the order
 	id := uuid.New().String()
 
@@ -54,7 +119,7 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []d
 	}
 
 	// Persist the order
-	if err := s.orderRepo.Create(ctx, order); err != nil {
+	if err := s.save(ctx, order, "", s.orderRepo.Create); err != nil {
 		s.logg.Error("failed to create order", "error", err, "order_id", order.ID)
 		return nil, err
 	}
@@ -69,10 +134,38 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []d
 		}
 	}
 
+	s.publishEvent(ctx, order, "")
+
 	s.logg.Info("order created successfully", "order_id", order.ID, "user_id", userID, "amount", order.Amount)
 	return order, nil
 }
 
+// mergeOrderItems combines lines for the same product at the same price,
+// keeping the first line's position. Lines with a non-positive quantity are
+// left alone so validation still rejects them.
+func mergeOrderItems(items []domain.OrderItem) []domain.OrderItem {
+	type line struct {
+		productID string
+		price     float64
+	}
+	merged := make([]domain.OrderItem, 0, len(items))
+	seen := make(map[line]int, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			merged = append(merged, item)
+			continue
+		}
+		k := line{item.ProductID, item.Price}
+		if i, ok := seen[k]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		seen[k] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged
+}
+
 // GetOrderByID retrieves an order by ID
 // Uses cache-aside pattern: check cache first, then database
 func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*domain.Order, error) {
@@ -129,6 +222,55 @@ func (s *OrderService) GetOrdersByUserID(ctx context.Context, userID string, lim
 	return orders, nil
 }
 
+// GetOrdersByUserIDs retrieves the newest orders of several users in one
+// repository call, for batch loading; users without orders are absent
+func (s *OrderService) GetOrdersByUserIDs(ctx context.Context, userIDs []string, limit int) (map[string][]*domain.Order, error) {
+	if len(userIDs) == 0 {
+		return map[string][]*domain.Order{}, nil
+	}
+
+	// Business rule: Same per-user limits as GetOrdersByUserID
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	orders, err := s.orderRepo.GetByUserIDs(ctx, userIDs, limit)
+	if err != nil {
+		s.logg.Error("failed to get orders by user ids", "error", err, "count", len(userIDs))
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetOrderOwners retrieves the users who placed orders in one repository
+// call, keyed by user ID, so a page of orders can embed its owners without a
+// query per order; owners that no longer exist are absent
+func (s *OrderService) GetOrderOwners(ctx context.Context, orders []*domain.Order) (map[string]*domain.User, error) {
+	owners := make(map[string]*domain.User)
+	var ids []string
+	seen := make(map[string]bool)
+	for _, o := range orders {
+		if !seen[o.UserID] {
+			seen[o.UserID] = true
+			ids = append(ids, o.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return owners, nil
+	}
+
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		s.logg.Error("failed to get order owners", "error", err, "count", len(ids))
+		return nil, err
+	}
+	for _, u := range users {
+		owners[u.ID] = u
+	}
+	return owners, nil
+}
+
 // ConfirmOrder confirms a pending order
 // Business logic: Uses domain method to enforce status transition rules
 func (s *OrderService) ConfirmOrder(ctx context.Context, id string) (*domain.Order, error) {
@@ -136,14 +278,18 @@ func (s *OrderService) ConfirmOrder(ctx context.Context, id string) (*domain.Ord
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(ctx, order.Version); err != nil {
+		return nil, err
+	}
 
 	// Domain enforces business rules for state transitions
+	previous := order.Status
 	if err := order.Confirm(); err != nil {
 		s.logg.Warn("cannot confirm order", "error", err, "order_id", id, "status", order.Status)
 		return nil, err
 	}
 
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	if err := s.save(ctx, order, previous, s.orderRepo.Update); err != nil {
 		s.logg.Error("failed to update order", "error", err, "order_id", id)
 		return nil, err
 	}
@@ -155,6 +301,8 @@ func (s *OrderService) ConfirmOrder(ctx context.Context, id string) (*domain.Ord
 		}
 	}
 
+	s.publishEvent(ctx, order, previous)
+
 	s.logg.Info("order confirmed", "order_id", id)
 	return order, nil
 }
@@ -166,14 +314,18 @@ func (s *OrderService) ShipOrder(ctx context.Context, id string) (*domain.Order,
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(ctx, order.Version); err != nil {
+		return nil, err
+	}
 
 	// Domain enforces business rules for state transitions
+	previous := order.Status
 	if err := order.Ship(); err != nil {
 		s.logg.Warn("cannot ship order", "error", err, "order_id", id, "status", order.Status)
 		return nil, err
 	}
 
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	if err := s.save(ctx, order, previous, s.orderRepo.Update); err != nil {
 		s.logg.Error("failed to update order", "error", err, "order_id", id)
 		return nil, err
 	}
@@ -185,6 +337,8 @@ func (s *OrderService) ShipOrder(ctx context.Context, id string) (*domain.Order,
 		}
 	}
 
+	s.publishEvent(ctx, order, previous)
+
 	s.logg.Info("order shipped", "order_id", id)
 	return order, nil
 }
@@ -196,14 +350,18 @@ func (s *OrderService) DeliverOrder(ctx context.Context, id string) (*domain.Ord
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(ctx, order.Version); err != nil {
+		return nil, err
+	}
 
 	// Domain enforces business rules for state transitions
+	previous := order.Status
 	if err := order.Deliver(); err != nil {
 		s.logg.Warn("cannot deliver order", "error", err, "order_id", id, "status", order.Status)
 		return nil, err
 	}
 
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	if err := s.save(ctx, order, previous, s.orderRepo.Update); err != nil {
 		s.logg.Error("failed to update order", "error", err, "order_id", id)
 		return nil, err
 	}
@@ -215,6 +373,8 @@ func (s *OrderService) DeliverOrder(ctx context.Context, id string) (*domain.Ord
 		}
 	}
 
+	s.publishEvent(ctx, order, previous)
+
 	s.logg.Info("order delivered", "order_id", id)
 	return order, nil
 }
@@ -226,8 +386,12 @@ func (s *OrderService) CancelOrder(ctx context.Context, id string) (*domain.Orde
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(ctx, order.Version); err != nil {
+		return nil, err
+	}
 
 	// Domain enforces business rules for cancellation
+	previous := order.Status
 	if err := order.Cancel(); err != nil {
 		s.logg.Warn("cannot cancel order", "error", err, "order_id", id, "status", order.Status)
 		return nil, err
@@ -236,7 +400,7 @@ func (s *OrderService) CancelOrder(ctx context.Context, id string) (*domain.Orde
 	// Business logic: Could add refund processing here
 	// e.g., s.paymentService.ProcessRefund(ctx, order)
 
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	if err := s.save(ctx, order, previous, s.orderRepo.Update); err != nil {
 		s.logg.Error("failed to update order", "error", err, "order_id", id)
 		return nil, err
 	}
@@ -251,10 +415,135 @@ func (s *OrderService) CancelOrder(ctx context.Context, id string) (*domain.Orde
 		}
 	}
 
+	s.publishEvent(ctx, order, previous)
+
 	s.logg.Info("order cancelled", "order_id", id)
 	return order, nil
 }
 
+// PatchOrder applies a partial update to an order: its items are replaced
+// first, then its status moves through the usual transitions
+// Business logic: Items only change while pending; a status change announces
+// an event like the dedicated transitions do
+func (s *OrderService) PatchOrder(ctx context.Context, id string, patch domain.OrderPatch) (*domain.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(ctx, order.Version); err != nil {
+		return nil, err
+	}
+
+	previous := order.Status
+	if patch.Items != nil {
+		items := patch.Items
+		if s.flags.EnabledFor(ctx, FlagNewOrderFlow, order.UserID) {
+			items = mergeOrderItems(items)
+		}
+		if err := order.ReplaceItems(items); err != nil {
+			s.logg.Warn("cannot replace order items", "error", err, "order_id", id, "status", order.Status)
+			return nil, err
+		}
+	}
+	if patch.Status != nil {
+		if err := order.TransitionTo(*patch.Status); err != nil {
+			s.logg.Warn("cannot change order status", "error", err, "order_id", id,
+				"status", order.Status, "target", *patch.Status)
+			return nil, err
+		}
+	}
+
+	if err := s.save(ctx, order, previous, s.orderRepo.Update); err != nil {
+		s.logg.Error("failed to update order", "error", err, "order_id", id)
+		return nil, err
+	}
+
+	if s.orderCache != nil {
+		if err := s.orderCache.Invalidate(ctx, id); err != nil {
+			s.logg.Warn("cache invalidate failed", "error", err, "order_id", id)
+		}
+		if order.Status == domain.OrderStatusCancelled && previous != domain.OrderStatusCancelled {
+			if err := s.orderCache.RemoveUserOrderIndex(ctx, order.UserID, id); err != nil {
+				s.logg.Warn("cache user index remove failed", "error", err, "order_id", id)
+			}
+		}
+	}
+
+	if order.Status != previous {
+		s.publishEvent(ctx, order, previous)
+	}
+
+	s.logg.Info("order patched", "order_id", id, "status", order.Status)
+	return order, nil
+}
+
+// publishEvent announces order's new status. Delivery is best effort: the
+// change is already stored, so a failed publish is logged, not returned.
+func (s *OrderService) publishEvent(ctx context.Context, order *domain.Order, previous domain.OrderStatus) {
+	s.emitDomainEvent(ctx, order, previous)
+	if s.events == nil && s.eventLog == nil {
+		return
+	}
+	event := domain.NewOrderEvent(uuid.New().String(), order, previous)
+	if s.eventLog != nil {
+		// A failed append only costs stream resumption, so publish regardless
+		if id, err := s.eventLog.Append(ctx, event); err != nil {
+			s.logg.Warn("order event append failed", "error", err, "order_id", order.ID, "status", order.Status)
+		} else {
+			event.ID = id
+		}
+	}
+	if s.events != nil {
+		if err := s.events.Publish(ctx, event); err != nil {
+			s.logg.Warn("order event publish failed", "error", err, "order_id", order.ID, "status", order.Status)
+		}
+	}
+}
+
+// save stores order with write. With an outbox, the lifecycle event
+// announcing its creation or new status is stored in the same transaction.
+func (s *OrderService) save(ctx context.Context, order *domain.Order, previous domain.OrderStatus,
+	write func(context.Context, *domain.Order) error) error {
+	if s.outbox == nil {
+		return write(ctx, order)
+	}
+	event, ok := domain.NewOrderLifecycleEvent(uuid.New().String(), order, previous)
+	ok = ok && order.Status != previous
+	// write bumps order.Version, which a rolled back transaction undoes: each
+	// attempt, and the caller after a failure, start from the version read
+	version := order.Version
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		order.Version = version
+		if err := write(ctx, order); err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		return s.outbox.Add(ctx, event)
+	})
+	if err != nil {
+		order.Version = version
+	}
+	return err
+}
+
+// emitDomainEvent hands the lifecycle event for order's new status to the
+// domain event bus; failed handlers are logged, like failed publishes. With
+// an outbox, the relay publishes the event stored by save instead.
+func (s *OrderService) emitDomainEvent(ctx context.Context, order *domain.Order, previous domain.OrderStatus) {
+	if s.domainBus == nil || s.outbox != nil {
+		return
+	}
+	event, ok := domain.NewOrderLifecycleEvent(uuid.New().String(), order, previous)
+	if !ok {
+		return
+	}
+	if err := s.domainBus.Publish(ctx, event); err != nil {
+		s.logg.Warn("order event handler failed", "error", err, "order_id", order.ID, "event", event.EventType())
+	}
+}
+
 // ListOrders retrieves a paginated list of all orders
 func (s *OrderService) ListOrders(ctx context.Context, limit, offset int) ([]*domain.Order, error) {
 	// Business rule: Set reasonable pagination limits
@@ -274,3 +563,55 @@ func (s *OrderService) ListOrders(ctx context.Context, limit, offset int) ([]*do
 
 	return orders, nil
 }
+
+// SearchOrders retrieves a page of orders narrowed and ordered by q, newest
+// first when q has no sort keys
+func (s *OrderService) SearchOrders(ctx context.Context, q domain.ListQuery, limit, offset int) ([]*domain.Order, error) {
+	// Business rule: Set reasonable pagination limits
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	orders, err := s.orderRepo.Search(ctx, q, limit, offset)
+	if err != nil {
+		s.logg.Error("failed to search orders", "error", err)
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// CountOrders counts the orders matching every filter, for pagers
+func (s *OrderService) CountOrders(ctx context.Context, filters []domain.Filter) (int, error) {
+	n, err := s.orderRepo.Count(ctx, filters)
+	if err != nil {
+		s.logg.Error("failed to count orders", "error", err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// ListOrdersPage retrieves one keyset page of orders, with the cursors of
+// the pages around it
+func (s *OrderService) ListOrdersPage(ctx context.Context, page domain.KeysetPage) (*domain.Page[*domain.Order], error) {
+	page = normalizeKeysetPage(page)
+	orders, err := s.orderRepo.ListKeyset(ctx, page)
+	if err != nil {
+		s.logg.Error("failed to list orders", "error", err)
+		return nil, err
+	}
+
+	return keysetResult(orders, page, func(o *domain.Order) domain.Cursor {
+		return domain.Cursor{CreatedAt: o.CreatedAt, ID: o.ID}
+	}), nil
+}
+
+// StreamOrders passes orders to fn in ListOrders order as they are read, for
+// exports too large to page through; a limit of 0 streams every order
+func (s *OrderService) StreamOrders(ctx context.Context, limit, offset int, fn func(*domain.Order) error) error {
+	return s.orderRepo.Stream(ctx, max(limit, 0), max(offset, 0), fn)
+}
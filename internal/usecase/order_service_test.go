@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// errTransient stands for a failure a transaction is retried after
+var errTransient = errors.New("serialization failure")
+
+// txOrders is an order repository whose updates, like those of a database
+// transaction, are kept only when txRetrier commits them
+type txOrders struct {
+	domain.OrderRepository // Only GetByID and Update are called
+
+	mu     sync.Mutex
+	stored domain.Order
+	staged *domain.Order
+}
+
+func (r *txOrders) GetByID(ctx context.Context, id string) (*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := r.stored
+	return &o, nil
+}
+
+// Update bumps order.Version as the Postgres repository does, before the
+// transaction commits
+func (r *txOrders) Update(ctx context.Context, order *domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if order.Version != r.stored.Version {
+		return domain.ErrVersionMismatch
+	}
+	order.Version++
+	staged := *order
+	r.staged = &staged
+	return nil
+}
+
+// txRetrier runs fn again after transient failures, rolling back the
+// updates of orders each time, as the Postgres transactor does
+type txRetrier struct {
+	orders   *txOrders
+	attempts int
+}
+
+func (t *txRetrier) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for range 3 {
+		t.attempts++
+		err = fn(ctx)
+		t.orders.mu.Lock()
+		if err == nil {
+			t.orders.stored = *t.orders.staged
+		}
+		t.orders.staged = nil
+		t.orders.mu.Unlock()
+		if !errors.Is(err, errTransient) {
+			return err
+		}
+	}
+	return err
+}
+
+// flakyOutbox fails its first failures Adds
+type flakyOutbox struct {
+	*memory.Outbox
+	failures int
+}
+
+func (o *flakyOutbox) Add(ctx context.Context, events ...domain.Event) error {
+	if o.failures > 0 {
+		o.failures--
+		return errTransient
+	}
+	return o.Outbox.Add(ctx, events...)
+}
+
+func TestOrderServiceRetriesOutboxFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		wantErr     error
+		wantVersion int64 // Of the stored order
+		wantEvents  int
+	}{
+		{"no failure", 0, nil, 2, 1},
+		{"transient outbox failure", 1, nil, 2, 1},
+		{"outbox failing every attempt", 3, errTransient, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := &txOrders{stored: domain.Order{ID: "o1", UserID: "u1", Status: domain.OrderStatusPending, Amount: 10, Version: 1}}
+			tx := &txRetrier{orders: orders}
+			outbox := &flakyOutbox{Outbox: memory.NewOutbox(), failures: tt.failures}
+			svc := NewOrderService(orders, nil, nil, logger.New("error"), WithOutbox(tx, outbox))
+
+			order, err := svc.ConfirmOrder(context.Background(), "o1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmOrder error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (order.Version != tt.wantVersion || order.Status != domain.OrderStatusConfirmed) {
+				t.Errorf("confirmed order = version %d, %s; want version %d, confirmed", order.Version, order.Status, tt.wantVersion)
+			}
+			if got := orders.stored.Version; got != tt.wantVersion {
+				t.Errorf("stored version = %d, want %d", got, tt.wantVersion)
+			}
+			if want := min(tt.failures+1, 3); tx.attempts != want {
+				t.Errorf("transaction attempts = %d, want %d", tx.attempts, want)
+			}
+
+			var events []string
+			if _, err := outbox.Relay(context.Background(), 10, func(ctx context.Context, entry domain.OutboxEntry) error {
+				events = append(events, entry.EventType)
+				return nil
+			}); err != nil {
+				t.Fatalf("Relay: %v", err)
+			}
+			if len(events) != tt.wantEvents {
+				t.Errorf("outbox events = %v, want %d", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestOrderServiceSaveRestoresVersionOnFailure(t *testing.T) {
+	orders := &txOrders{stored: domain.Order{ID: "o1", UserID: "u1", Status: domain.OrderStatusPending, Version: 4, UpdatedAt: time.Now()}}
+	outbox := &flakyOutbox{Outbox: memory.NewOutbox(), failures: 3}
+	svc := NewOrderService(orders, nil, nil, logger.New("error"), WithOutbox(&txRetrier{orders: orders}, outbox))
+
+	order, _ := orders.GetByID(context.Background(), "o1")
+	if err := order.Confirm(); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if err := svc.save(context.Background(), order, domain.OrderStatusPending, orders.Update); !errors.Is(err, errTransient) {
+		t.Fatalf("save error = %v, want the outbox failure", err)
+	}
+	if order.Version != 4 {
+		t.Errorf("order version after the rolled back save = %d, want 4", order.Version)
+	}
+}
+
+// countingUsers is a user repository recording the lookups made through it
+type countingUsers struct {
+	domain.UserRepository
+
+	getByID  int
+	getByIDs [][]string // IDs of each batched lookup
+}
+
+func (r *countingUsers) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	r.getByID++
+	return r.UserRepository.GetByID(ctx, id)
+}
+
+func (r *countingUsers) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	r.getByIDs = append(r.getByIDs, slices.Clone(ids))
+	return r.UserRepository.GetByIDs(ctx, ids)
+}
+
+func TestOrderServiceGetOrderOwners(t *testing.T) {
+	ctx := context.Background()
+	users := &countingUsers{UserRepository: memory.NewUserRepository()}
+	for _, id := range []string{"u1", "u2"} {
+		user, err := domain.NewUser(id, "User "+id, id+"@example.com")
+		if err != nil {
+			t.Fatalf("NewUser: %v", err)
+		}
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	svc := NewOrderService(memory.NewOrderRepository(), users, nil, logger.New("error"))
+
+	orders := []*domain.Order{
+		{ID: "o1", UserID: "u1"},
+		{ID: "o2", UserID: "u2"},
+		{ID: "o3", UserID: "u1"},
+		{ID: "o4", UserID: "deleted"}, // Owner no longer exists
+	}
+	owners, err := svc.GetOrderOwners(ctx, orders)
+	if err != nil {
+		t.Fatalf("GetOrderOwners: %v", err)
+	}
+
+	if users.getByID != 0 || len(users.getByIDs) != 1 {
+		t.Fatalf("lookups = %d by ID and %d batched, want one batch", users.getByID, len(users.getByIDs))
+	}
+	if got, want := users.getByIDs[0], []string{"u1", "u2", "deleted"}; !slices.Equal(got, want) {
+		t.Errorf("batched IDs = %v, want each owner once: %v", got, want)
+	}
+	if len(owners) != 2 || owners["u1"].Email != "u1@example.com" || owners["u2"].Email != "u2@example.com" {
+		t.Errorf("owners = %v, want u1 and u2", owners)
+	}
+	if _, ok := owners["deleted"]; ok {
+		t.Error("owners holds the missing user, want it absent")
+	}
+
+	owners, err = svc.GetOrderOwners(ctx, nil)
+	if err != nil || len(owners) != 0 {
+		t.Errorf("GetOrderOwners of no orders = %v, %v, want none", owners, err)
+	}
+	if len(users.getByIDs) != 1 {
+		t.Errorf("GetOrderOwners of no orders made a lookup")
+	}
+}
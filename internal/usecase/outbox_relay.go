@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// OutboxRelayPolicy configures how the outbox is drained
+type OutboxRelayPolicy struct {
+	PollInterval time.Duration // How often the outbox is checked for new events
+	BatchSize    int           // Events relayed per transaction
+	Retention    time.Duration // How long published events are kept, for inspection
+	// MaxAttempts is how many times an event is tried before it is moved to
+	// the dead-letter queue, unblocking its aggregate's later events (0
+	// retries forever)
+	MaxAttempts int
+}
+
+// OutboxRelay publishes the events stored in the outbox to the domain event
+// bus, in order, retrying each until it is published. With a dead-letter
+// queue, an event failing MaxAttempts times, such as one whose payload
+// cannot be decoded, is moved there instead.
+type OutboxRelay struct {
+	outbox domain.Outbox
+	bus    domain.EventBus
+	policy OutboxRelayPolicy
+	logg   *logger.Logger
+	dead   domain.DeadLetterQueue // Nil retries events until they are published
+	now    func() time.Time
+}
+
+// OutboxRelayOption configures an OutboxRelay
+type OutboxRelayOption func(*OutboxRelay)
+
+// WithOutboxDeadLetters moves the events that failed MaxAttempts times to
+// dead, to be replayed once whatever failed them is fixed
+func WithOutboxDeadLetters(dead domain.DeadLetterQueue) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.dead = dead
+	}
+}
+
+// NewOutboxRelay creates a relay from outbox to bus
+func NewOutboxRelay(outbox domain.Outbox, bus domain.EventBus, policy OutboxRelayPolicy, logg *logger.Logger, opts ...OutboxRelayOption) *OutboxRelay {
+	r := &OutboxRelay{outbox: outbox, bus: bus, policy: policy, logg: logg, now: time.Now}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// OutboxReplayer replays outbox dead letters by publishing their event to bus
+func OutboxReplayer(bus domain.EventBus) DeadLetterReplayer {
+	return func(ctx context.Context, d *domain.DeadLetter) error {
+		event, err := domain.DecodeEvent(d.Type, d.Payload)
+		if err != nil {
+			return err
+		}
+		return bus.Publish(ctx, event)
+	}
+}
+
+// Run relays events every poll interval until ctx ends, straight away again
+// while full batches keep coming, and purges published events once an hour
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.policy.PollInterval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+	for {
+		for {
+			n, err := r.RelayOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.logg.Warn("outbox relay failed, retrying next interval", "error", err)
+			}
+			if err != nil || n < r.policy.BatchSize {
+				break
+			}
+		}
+		if now := r.now(); now.Sub(lastPurge) >= time.Hour {
+			if _, err := r.outbox.Purge(ctx, now.Add(-r.policy.Retention)); err != nil && ctx.Err() == nil {
+				r.logg.Warn("outbox purge failed", "error", err)
+			}
+			lastPurge = now
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch of events and returns how many were
+// published or dead-lettered
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	return r.outbox.Relay(ctx, r.policy.BatchSize, func(ctx context.Context, entry domain.OutboxEntry) error {
+		err := entry.DecodeError
+		if entry.Event != nil {
+			err = r.bus.Publish(ctx, entry.Event)
+		}
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if attempts := entry.Attempts + 1; r.dead != nil && r.policy.MaxAttempts > 0 && attempts >= r.policy.MaxAttempts {
+			return r.deadLetter(ctx, entry, attempts, err)
+		}
+		r.logg.Warn("outbox event not published, will retry", "error", err,
+			"event", entry.EventType, "event_id", entry.EventID, "attempts", entry.Attempts+1)
+		return err
+	})
+}
+
+// deadLetter moves entry, which failed for the last time with cause, to the
+// dead-letter queue. When that fails too the event stays in the outbox.
+func (r *OutboxRelay) deadLetter(ctx context.Context, entry domain.OutboxEntry, attempts int, cause error) error {
+	err := r.dead.Add(ctx, &domain.DeadLetter{
+		Source:     domain.DeadLetterOutbox,
+		Type:       entry.EventType,
+		MessageID:  entry.EventID,
+		Payload:    entry.Payload,
+		Attributes: map[string]string{"aggregate_id": entry.AggregateID},
+		Error:      cause.Error(),
+		Attempts:   attempts,
+		FailedAt:   r.now().UTC(),
+	})
+	if err != nil {
+		r.logg.Error("failed to dead-letter outbox event, will retry", "error", err, "event", entry.EventType, "event_id", entry.EventID)
+		return errors.Join(cause, fmt.Errorf("dead-lettering: %w", err))
+	}
+	r.logg.Error("outbox event dead-lettered", "error", cause, "event", entry.EventType, "event_id", entry.EventID, "attempts", attempts)
+	return nil
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// failingBus fails to publish the events whose ID is in fail
+type failingBus struct {
+	*memory.EventBus
+	fail      map[string]bool
+	published []string
+}
+
+func (b *failingBus) Publish(ctx context.Context, event domain.Event) error {
+	if b.fail[event.Metadata().ID] {
+		return errors.New("broker unavailable")
+	}
+	b.published = append(b.published, event.Metadata().ID)
+	return nil
+}
+
+// stubOutbox relays entries, recording which publish accepted
+type stubOutbox struct {
+	domain.Outbox
+	entries  []domain.OutboxEntry
+	accepted []int64
+}
+
+func (o *stubOutbox) Relay(ctx context.Context, limit int, publish func(ctx context.Context, entry domain.OutboxEntry) error) (int, error) {
+	n := 0
+	for _, entry := range o.entries {
+		if publish(ctx, entry) == nil {
+			o.accepted = append(o.accepted, entry.Seq)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func orderEvent(t *testing.T, id string, status domain.OrderStatus, previous domain.OrderStatus) domain.Event {
+	t.Helper()
+	event, ok := domain.NewOrderLifecycleEvent(id, &domain.Order{ID: "o1", UserID: "u1", Status: status, UpdatedAt: time.Now()}, previous)
+	if !ok {
+		t.Fatalf("no lifecycle event for %s", status)
+	}
+	return event
+}
+
+func TestOutboxRelayDeadLettersFailingEvents(t *testing.T) {
+	ctx := context.Background()
+	outbox := memory.NewOutbox()
+	if err := outbox.Add(ctx, orderEvent(t, "e1", domain.OrderStatusConfirmed, domain.OrderStatusPending),
+		orderEvent(t, "e2", domain.OrderStatusShipped, domain.OrderStatusConfirmed)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	bus := &failingBus{EventBus: memory.NewEventBus(), fail: map[string]bool{"e1": true}}
+	dead := memory.NewDeadLetterQueue()
+	relay := NewOutboxRelay(outbox, bus, OutboxRelayPolicy{BatchSize: 10, MaxAttempts: 3}, logger.New("error"), WithOutboxDeadLetters(dead))
+
+	// The order's second event waits behind the failing first one...
+	for attempt := 1; attempt < 3; attempt++ {
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 0 {
+			t.Fatalf("attempt %d: RelayOnce = %d, %v; want nothing relayed", attempt, n, err)
+		}
+	}
+	// ...until the first is dead-lettered on its last attempt
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 2 {
+		t.Fatalf("last attempt: RelayOnce = %d, %v; want 2 relayed", n, err)
+	}
+	if len(bus.published) != 1 || bus.published[0] != "e2" {
+		t.Errorf("published = %v, want [e2]", bus.published)
+	}
+
+	letters, err := dead.List(ctx, domain.DeadLetterFilter{})
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("dead letters = %v, %v; want one", letters, err)
+	}
+	d := letters[0]
+	if d.Source != domain.DeadLetterOutbox || d.Type != domain.EventOrderConfirmed || d.MessageID != "e1" ||
+		d.Attempts != 3 || d.Attributes["aggregate_id"] != "o1" || d.Error != "broker unavailable" {
+		t.Errorf("dead letter = %+v", d)
+	}
+
+	// Once the broker is back, a replay publishes it
+	delete(bus.fail, "e1")
+	if err := OutboxReplayer(bus)(ctx, d); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(bus.published) != 2 || bus.published[1] != "e1" {
+		t.Errorf("published after replay = %v, want e1 last", bus.published)
+	}
+}
+
+func TestOutboxRelayUndecodableEvents(t *testing.T) {
+	ctx := context.Background()
+	entry := domain.OutboxEntry{
+		Seq: 7, EventID: "e7", EventType: "order.refunded", AggregateID: "o1",
+		Payload: []byte(`{"order_id":"o1"}`), DecodeError: errors.New("unknown event type"), Attempts: 1,
+	}
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		deadLetters  bool
+		wantAccepted bool
+	}{
+		{"attempts left", 3, true, false},
+		{"last attempt", 2, true, true},
+		{"no dead-letter queue", 2, false, false},
+		{"unlimited attempts", 0, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &stubOutbox{entries: []domain.OutboxEntry{entry}}
+			dead := memory.NewDeadLetterQueue()
+			var opts []OutboxRelayOption
+			if tt.deadLetters {
+				opts = append(opts, WithOutboxDeadLetters(dead))
+			}
+			relay := NewOutboxRelay(outbox, &failingBus{EventBus: memory.NewEventBus()}, OutboxRelayPolicy{BatchSize: 10, MaxAttempts: tt.maxAttempts}, logger.New("error"), opts...)
+
+			if _, err := relay.RelayOnce(ctx); err != nil {
+				t.Fatalf("RelayOnce: %v", err)
+			}
+			if accepted := len(outbox.accepted) == 1; accepted != tt.wantAccepted {
+				t.Errorf("event relayed = %v, want %v", accepted, tt.wantAccepted)
+			}
+			letters, _ := dead.List(ctx, domain.DeadLetterFilter{})
+			if (len(letters) == 1) != tt.wantAccepted {
+				t.Fatalf("dead letters = %d, want one exactly when relayed", len(letters))
+			}
+			if tt.wantAccepted && (string(letters[0].Payload) != string(entry.Payload) || letters[0].Type != entry.EventType) {
+				t.Errorf("dead letter = %+v, want the stored event", letters[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// normalizeKeysetPage applies the list limits to page and asks for one row
+// more, which tells whether another page follows
+func normalizeKeysetPage(page domain.KeysetPage) domain.KeysetPage {
+	// Business rule: Set reasonable pagination limits
+	if page.Limit <= 0 || page.Limit > 100 {
+		page.Limit = 20
+	}
+	page.Limit++
+	return page
+}
+
+// keysetResult trims the extra row fetched for page (see
+// normalizeKeysetPage) and sets the cursors of the adjacent pages
+func keysetResult[T any](items []T, page domain.KeysetPage, cursor func(T) domain.Cursor) *domain.Page[T] {
+	limit := page.Limit - 1
+	result := &domain.Page[T]{}
+	if page.Before != nil {
+		// Read backwards from the cursor: the extra row is the newest one
+		more := len(items) > limit
+		if more {
+			items = items[1:]
+		}
+		result.Items = items
+		if len(items) > 0 {
+			if more {
+				c := cursor(items[0])
+				result.Prev = &c
+			}
+			c := cursor(items[len(items)-1])
+			result.Next = &c
+		}
+		return result
+	}
+
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	result.Items = items
+	if len(items) > 0 {
+		if more {
+			c := cursor(items[len(items)-1])
+			result.Next = &c
+		}
+		if page.After != nil {
+			c := cursor(items[0])
+			result.Prev = &c
+		}
+	}
+	return result
+}
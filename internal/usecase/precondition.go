@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// expectedVersionsKey carries the versions an update is conditional on
+type expectedVersionsKey struct{}
+
+// WithExpectedVersions returns a context under which updates of a user or
+// order only go ahead while it is at one of versions, as an HTTP If-Match
+// asks. Updates without them still never overwrite a concurrent change: the
+// repositories only store an entity at the version it was read at.
+func WithExpectedVersions(ctx context.Context, versions ...int64) context.Context {
+	return context.WithValue(ctx, expectedVersionsKey{}, versions)
+}
+
+// checkVersion returns domain.ErrVersionMismatch when ctx expects other
+// versions than current
+func checkVersion(ctx context.Context, current int64) error {
+	versions, ok := ctx.Value(expectedVersionsKey{}).([]int64)
+	if ok && !slices.Contains(versions, current) {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
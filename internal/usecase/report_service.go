@@ -0,0 +1,275 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/templates"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// JobTypeReportEmail renders a scheduled report, stores it and emails a link
+const JobTypeReportEmail = "report.email"
+
+// ReportPolicy configures scheduled reports
+type ReportPolicy struct {
+	BlobPrefix string        // Key prefix for stored reports
+	LinkTTL    time.Duration // Lifetime of emailed download links (presigning stores only)
+}
+
+// DefaultReportPolicy returns sensible defaults. Seven days is the longest
+// S3 allows for presigned URLs.
+func DefaultReportPolicy() ReportPolicy {
+	return ReportPolicy{
+		BlobPrefix: "reports/",
+		LinkTTL:    7 * 24 * time.Hour,
+	}
+}
+
+// ReportService manages recurring reports: admins schedule them, the
+// scheduler enqueues a job per due run, and the job renders the report,
+// stores it in the blob store and emails recipients a link
+type ReportService struct {
+	schedules domain.ReportScheduleRepository
+	orders    domain.OrderRepository
+	blobs     blob.Store
+	mailer    domain.EmailSender
+	jobs      *JobRunner
+	policy    ReportPolicy
+	templates *templates.Engine // Nil sends plain-text email only
+	logg      *logger.Logger
+}
+
+// ReportServiceOption configures a ReportService
+type ReportServiceOption func(*ReportService)
+
+// WithReportTemplates adds an HTML part rendered from templates.EmailReportReady
+// to report emails
+func WithReportTemplates(engine *templates.Engine) ReportServiceOption {
+	return func(s *ReportService) {
+		s.templates = engine
+	}
+}
+
+// NewReportService creates a report service
+func NewReportService(schedules domain.ReportScheduleRepository, orders domain.OrderRepository, blobs blob.Store, mailer domain.EmailSender, jobs *JobRunner, policy ReportPolicy, logg *logger.Logger, opts ...ReportServiceOption) *ReportService {
+	defaults := DefaultReportPolicy()
+	if policy.LinkTTL <= 0 {
+		policy.LinkTTL = defaults.LinkTTL
+	}
+	s := &ReportService{
+		schedules: schedules,
+		orders:    orders,
+		blobs:     blobs,
+		mailer:    mailer,
+		jobs:      jobs,
+		policy:    policy,
+		logg:      logg,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateSchedule schedules a recurring report
+func (s *ReportService) CreateSchedule(ctx context.Context, name, report, schedule string, recipients []string, createdBy string) (*domain.ReportSchedule, error) {
+	sched, err := domain.NewReportSchedule(uuid.New().String(), name, report, schedule, recipients, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.schedules.Create(ctx, sched); err != nil {
+		return nil, err
+	}
+
+	s.logg.Info("report scheduled",
+		"schedule_id", sched.ID,
+		"report", sched.Report,
+		"schedule", sched.Schedule,
+		"next_run_at", sched.NextRunAt,
+	)
+	return sched, nil
+}
+
+// ListSchedules returns every report schedule
+func (s *ReportService) ListSchedules(ctx context.Context) ([]*domain.ReportSchedule, error) {
+	return s.schedules.List(ctx)
+}
+
+// DeleteSchedule stops a recurring report
+func (s *ReportService) DeleteSchedule(ctx context.Context, id string) error {
+	return s.schedules.Delete(ctx, id)
+}
+
+// reportJob is the payload of a JobTypeReportEmail job
+type reportJob struct {
+	ScheduleID string    `json:"schedule_id"`
+	RunAt      time.Time `json:"run_at"`
+}
+
+// RunDue enqueues a job for every schedule due at now and advances each to
+// its next run. Replicas may call it concurrently: each run is claimed by
+// exactly one of them. A run missed while the service was down is sent once,
+// not once per missed interval.
+func (s *ReportService) RunDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.schedules.ListDue(ctx, now, 100)
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for _, sched := range due {
+		next, err := sched.NextRun(now)
+		if err != nil {
+			s.logg.Error("invalid report schedule, skipping", "error", err, "schedule_id", sched.ID)
+			continue
+		}
+		claimed, err := s.schedules.Advance(ctx, sched.ID, sched.NextRunAt, next, now)
+		if err != nil {
+			return enqueued, err
+		}
+		if !claimed {
+			continue
+		}
+
+		payload := reportJob{ScheduleID: sched.ID, RunAt: sched.NextRunAt}
+		if _, err := s.jobs.Enqueue(ctx, JobTypeReportEmail, domain.JobPriorityDefault, payload,
+			WithJobOwner(sched.CreatedBy)); err != nil {
+			s.logg.Error("failed to enqueue scheduled report", "error", err, "schedule_id", sched.ID)
+			continue
+		}
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+// RunScheduler calls RunDue every interval until ctx is cancelled
+func (s *ReportService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunDue(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			s.logg.Warn("report scheduler run failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EmailReport is the JobFunc for JobTypeReportEmail
+func (s *ReportService) EmailReport(ctx context.Context, run *JobRun) error {
+	var payload reportJob
+	if err := json.Unmarshal(run.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+
+	sched, err := s.schedules.GetByID(ctx, payload.ScheduleID)
+	if err != nil {
+		return err
+	}
+
+	body, err := s.render(ctx, sched, payload.RunAt)
+	if err != nil {
+		return err
+	}
+	run.Progress(ctx, 40)
+
+	key := fmt.Sprintf("%s%s/%s.csv", s.policy.BlobPrefix, sched.ID, payload.RunAt.UTC().Format("20060102T1504Z"))
+	out, err := s.blobs.Upload(ctx, &blob.UploadInput{
+		Key:         key,
+		Body:        bytes.NewReader(body),
+		ContentType: "text/csv",
+		Metadata:    map[string]string{"report": sched.Report, "schedule-id": sched.ID},
+	})
+	if err != nil {
+		return err
+	}
+	run.SetResult(key)
+	run.Progress(ctx, 70)
+
+	link := out.Location
+	if presigner, ok := s.blobs.(blob.PresignedURLGenerator); ok {
+		if link, err = presigner.GeneratePresignedURL(ctx, key, s.policy.LinkTTL); err != nil {
+			return err
+		}
+	}
+
+	runDate := payload.RunAt.UTC().Format("2006-01-02")
+	msg := domain.EmailMessage{
+		To:      sched.Recipients,
+		Subject: fmt.Sprintf("%s: %s", sched.Name, runDate),
+		Body: fmt.Sprintf("Your scheduled report %q is ready:\n\n%s\n\nThe link expires in %s.\n",
+			sched.Name, link, s.policy.LinkTTL),
+	}
+	if s.templates != nil {
+		html, err := s.templates.RenderString(templates.EmailReportReady, templates.ReportReady{
+			Name:    sched.Name,
+			RunDate: runDate,
+			Link:    link,
+			Expires: s.policy.LinkTTL.String(),
+		})
+		if err != nil {
+			// A broken override template shouldn't stop delivery; the text part is enough
+			s.logg.Warn("failed to render report email, sending plain text", "error", err, "schedule_id", sched.ID)
+		}
+		msg.HTML = html
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to email report: %w", err)
+	}
+
+	s.logg.Info("scheduled report sent", "schedule_id", sched.ID, "key", key, "recipients", len(sched.Recipients))
+	return nil
+}
+
+// render produces the report as CSV
+func (s *ReportService) render(ctx context.Context, sched *domain.ReportSchedule, runAt time.Time) ([]byte, error) {
+	switch sched.Report {
+	case domain.ReportDailyOrderSummary:
+		summary, err := s.orders.Summarize(ctx, runAt.Add(-24*time.Hour), runAt)
+		if err != nil {
+			return nil, err
+		}
+		return renderOrderSummary(summary)
+	default:
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnknownReport, sched.Report)
+	}
+}
+
+// renderOrderSummary writes one row per order status plus a total row
+func renderOrderSummary(summary *domain.OrderSummary) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"from", "to"})
+	w.Write([]string{summary.From.UTC().Format(time.RFC3339), summary.To.UTC().Format(time.RFC3339)})
+	w.Write(nil)
+	w.Write([]string{"status", "orders"})
+
+	statuses := make([]domain.OrderStatus, 0, len(summary.ByStatus))
+	for status := range summary.ByStatus {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	for _, status := range statuses {
+		w.Write([]string{string(status), strconv.Itoa(summary.ByStatus[status])})
+	}
+	w.Write([]string{"total", strconv.Itoa(summary.Count)})
+	w.Write(nil)
+	w.Write([]string{"total_amount", strconv.FormatFloat(summary.Total, 'f', 2, 64)})
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
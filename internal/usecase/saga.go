@@ -0,0 +1,303 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// SagaStep is one step of a saga: an action on another service, and the
+// compensation undoing it when a later step fails
+type SagaStep struct {
+	Name string
+	// Action performs the step. It may record what later steps and
+	// compensations need in saga.Data, and must be idempotent: after a
+	// crash, a step whose outcome was not stored is performed again.
+	Action func(ctx context.Context, saga *domain.Saga) error
+	// Compensate undoes the step (nil when there is nothing to undo). It
+	// must be idempotent and succeed when Action never took effect: a step
+	// failing with an error that is not permanent is compensated too, as a
+	// call that timed out may have succeeded.
+	Compensate func(ctx context.Context, saga *domain.Saga) error
+}
+
+// SagaDefinition describes a type of saga
+type SagaDefinition struct {
+	Type  string
+	Steps []SagaStep
+	// Permanent reports the step errors retrying cannot fix, such as a
+	// declined payment. A step failing with one is compensated straight away
+	// and assumed to have done nothing. Nil retries every error.
+	Permanent func(error) bool
+}
+
+// permanent reports whether err is a permanent failure under d
+func (d SagaDefinition) permanent(err error) bool {
+	return d.Permanent != nil && d.Permanent(err)
+}
+
+// SagaPolicy configures how sagas are run and recovered
+type SagaPolicy struct {
+	StepAttempts int           // Tries of an action or compensation, the first included
+	RetryDelay   time.Duration // Before the second try; doubles after every failure
+	StepTimeout  time.Duration // Bounds each try
+	// RecoveryInterval is how often sagas left unfinished by a crash, or by
+	// a failing compensation, are looked for
+	RecoveryInterval time.Duration
+	// StaleAfter is how long an unfinished saga goes without progress
+	// before recovery takes it over. It must exceed an action's attempts.
+	StaleAfter time.Duration
+}
+
+// DefaultSagaPolicy returns sensible defaults
+func DefaultSagaPolicy() SagaPolicy {
+	return SagaPolicy{
+		StepAttempts:     3,
+		RetryDelay:       200 * time.Millisecond,
+		StepTimeout:      30 * time.Second,
+		RecoveryInterval: time.Minute,
+		StaleAfter:       5 * time.Minute,
+	}
+}
+
+// SagaCoordinator runs sagas: it performs their steps in order, storing
+// the saga after each, and when a step fails, runs the compensations of
+// the steps performed so far in reverse order. A saga interrupted by a
+// crash is resumed from its stored step by recovery (see Run), on any
+// instance; a compensation that keeps failing is retried there too.
+type SagaCoordinator struct {
+	repo        domain.SagaRepository
+	definitions map[string]SagaDefinition
+	policy      SagaPolicy
+	logg        *logger.Logger
+	now         func() time.Time
+}
+
+// NewSagaCoordinator creates a saga coordinator storing sagas in repo
+func NewSagaCoordinator(repo domain.SagaRepository, policy SagaPolicy, logg *logger.Logger) *SagaCoordinator {
+	def := DefaultSagaPolicy()
+	if policy.StepAttempts <= 0 {
+		policy.StepAttempts = 1
+	}
+	if policy.RecoveryInterval <= 0 {
+		policy.RecoveryInterval = def.RecoveryInterval
+	}
+	if policy.StaleAfter <= 0 {
+		policy.StaleAfter = def.StaleAfter
+	}
+	return &SagaCoordinator{
+		repo:        repo,
+		definitions: make(map[string]SagaDefinition),
+		policy:      policy,
+		logg:        logg,
+		now:         time.Now,
+	}
+}
+
+// Register adds a saga definition. Every definition stored sagas use must
+// be registered before recovery runs.
+func (c *SagaCoordinator) Register(def SagaDefinition) {
+	c.definitions[def.Type] = def
+}
+
+// Start stores a new saga of sagaType for key and runs it. It returns the
+// saga as it stands when it stops and, unless it completed, the error that
+// stopped it: the failed step's error once compensated. A saga left
+// running or compensating is taken over by recovery. The saga runs on
+// after ctx is cancelled, so a client going away does not abandon it
+// halfway.
+func (c *SagaCoordinator) Start(ctx context.Context, sagaType, key string, data map[string]string) (*domain.Saga, error) {
+	def, ok := c.definitions[sagaType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown saga type %q", domain.ErrInvalidInput, sagaType)
+	}
+	if data == nil {
+		data = map[string]string{}
+	}
+	now := c.now().UTC()
+	saga := &domain.Saga{
+		ID:        uuid.New().String(),
+		Type:      sagaType,
+		Key:       key,
+		Status:    domain.SagaRunning,
+		Data:      data,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+	if err := c.repo.Create(ctx, saga); err != nil {
+		return nil, err
+	}
+	c.logg.Info("saga started", "saga_id", saga.ID, "saga_type", sagaType, "key", key)
+	return saga, c.execute(context.WithoutCancel(ctx), def, saga)
+}
+
+// Get returns the saga id
+func (c *SagaCoordinator) Get(ctx context.Context, id string) (*domain.Saga, error) {
+	return c.repo.Get(ctx, id)
+}
+
+// GetByKey returns the newest saga of sagaType for key
+func (c *SagaCoordinator) GetByKey(ctx context.Context, sagaType, key string) (*domain.Saga, error) {
+	return c.repo.GetByKey(ctx, sagaType, key)
+}
+
+// StepName returns the name of the step saga is performing or undoing,
+// empty once it finished
+func (c *SagaCoordinator) StepName(saga *domain.Saga) string {
+	steps := c.definitions[saga.Type].Steps
+	if saga.Status.Finished() || saga.Step < 0 || saga.Step >= len(steps) {
+		return ""
+	}
+	return steps[saga.Step].Name
+}
+
+// Run resumes stale sagas every recovery interval until ctx ends
+func (c *SagaCoordinator) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.policy.RecoveryInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.RecoverOnce(ctx); err != nil && ctx.Err() == nil {
+			c.logg.Warn("saga recovery failed, retrying next interval", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecoverOnce resumes the sagas that made no progress for the stale
+// period, and returns how many it took over
+func (c *SagaCoordinator) RecoverOnce(ctx context.Context) (int, error) {
+	sagas, err := c.repo.ListUnfinished(ctx, c.now().Add(-c.policy.StaleAfter), 100)
+	if err != nil {
+		return 0, err
+	}
+	resumed := 0
+	for _, saga := range sagas {
+		if ctx.Err() != nil {
+			break
+		}
+		def, ok := c.definitions[saga.Type]
+		if !ok {
+			c.logg.Warn("saga of an unknown type left unfinished", "saga_id", saga.ID, "saga_type", saga.Type)
+			continue
+		}
+		// Claim it: another instance recovering it too fails this update
+		if err := c.store(ctx, saga); err != nil {
+			if !errors.Is(err, domain.ErrVersionMismatch) {
+				c.logg.Warn("failed to claim saga", "error", err, "saga_id", saga.ID)
+			}
+			continue
+		}
+		resumed++
+		c.logg.Info("resuming saga", "saga_id", saga.ID, "saga_type", saga.Type, "status", saga.Status, "step", c.StepName(saga))
+		c.execute(ctx, def, saga)
+	}
+	return resumed, nil
+}
+
+// execute runs saga's remaining steps, or compensations, from its stored
+// step, storing it after each
+func (c *SagaCoordinator) execute(ctx context.Context, def SagaDefinition, saga *domain.Saga) error {
+	logg := c.logg.WithFields("saga_id", saga.ID, "saga_type", saga.Type, "key", saga.Key)
+
+	var cause error
+	for saga.Status == domain.SagaRunning {
+		if saga.Step >= len(def.Steps) {
+			saga.Status = domain.SagaCompleted
+			if err := c.store(ctx, saga); err != nil {
+				return err
+			}
+			logg.Info("saga completed")
+			return nil
+		}
+		step := def.Steps[saga.Step]
+		if err := c.attempt(ctx, def, step.Action, saga); err != nil {
+			logg.Warn("saga step failed, compensating", "error", err, "step", step.Name)
+			cause = fmt.Errorf("%s: %w", step.Name, err)
+			saga.Status, saga.Error = domain.SagaCompensating, cause.Error()
+			if def.permanent(err) {
+				// Refused outright: there is nothing of this step to undo
+				saga.Step--
+			}
+		} else {
+			saga.Step++
+		}
+		if err := c.store(ctx, saga); err != nil {
+			return err
+		}
+	}
+	if cause == nil {
+		// Resumed while compensating
+		cause = errors.New(saga.Error)
+	}
+
+	for saga.Status == domain.SagaCompensating {
+		if saga.Step < 0 {
+			saga.Status = domain.SagaCompensated
+			if err := c.store(ctx, saga); err != nil {
+				return err
+			}
+			logg.Info("saga compensated", "error", saga.Error)
+			return cause
+		}
+		step := def.Steps[saga.Step]
+		if step.Compensate != nil {
+			if err := c.attempt(ctx, def, step.Compensate, saga); err != nil {
+				saga.Attempts++
+				logg.Error("saga compensation failed, recovery will retry it", "error", err, "step", step.Name, "attempts", saga.Attempts)
+				if storeErr := c.store(ctx, saga); storeErr != nil {
+					return storeErr
+				}
+				return cause
+			}
+		}
+		saga.Step--
+		saga.Attempts = 0
+		if err := c.store(ctx, saga); err != nil {
+			return err
+		}
+	}
+	return cause
+}
+
+// attempt runs fn up to the policy's attempts, each bounded by the step
+// timeout, until it succeeds or fails with a permanent error
+func (c *SagaCoordinator) attempt(ctx context.Context, def SagaDefinition, fn func(context.Context, *domain.Saga) error, saga *domain.Saga) error {
+	delay := c.policy.RetryDelay
+	for attempt := 1; ; attempt++ {
+		stepCtx, cancel := ctx, context.CancelFunc(func() {})
+		if c.policy.StepTimeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, c.policy.StepTimeout)
+		}
+		err := fn(stepCtx, saga)
+		cancel()
+		if err == nil || def.permanent(err) || attempt >= c.policy.StepAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// store saves saga's progress
+func (c *SagaCoordinator) store(ctx context.Context, saga *domain.Saga) error {
+	saga.UpdatedAt = c.now().UTC()
+	if err := c.repo.Update(ctx, saga); err != nil {
+		c.logg.Error("failed to store saga, recovery will resume it", "error", err, "saga_id", saga.ID, "status", saga.Status, "step", saga.Step)
+		return err
+	}
+	return nil
+}
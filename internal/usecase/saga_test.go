@@ -0,0 +1,408 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+var (
+	errDeclined = errors.New("declined")
+	errTimeout  = errors.New("timeout")
+)
+
+// scriptedSaga is a saga definition of steps "a", "b" and "c", whose
+// actions and compensations fail with the errors queued for them and
+// record every call
+type scriptedSaga struct {
+	mu    sync.Mutex
+	calls []string
+	fail  map[string][]error // By call, e.g. "do b" or "undo a"; consumed in order
+}
+
+func newScriptedSaga() *scriptedSaga {
+	return &scriptedSaga{fail: make(map[string][]error)}
+}
+
+func (s *scriptedSaga) call(name string) func(ctx context.Context, saga *domain.Saga) error {
+	return func(ctx context.Context, saga *domain.Saga) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.calls = append(s.calls, name)
+		if errs := s.fail[name]; len(errs) > 0 {
+			s.fail[name] = errs[1:]
+			return errs[0]
+		}
+		saga.Data[name] = "done"
+		return nil
+	}
+}
+
+// failing makes name fail with err the next n times
+func (s *scriptedSaga) failing(name string, err error, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range n {
+		s.fail[name] = append(s.fail[name], err)
+	}
+}
+
+func (s *scriptedSaga) log() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.calls, ", ")
+}
+
+func (s *scriptedSaga) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+func (s *scriptedSaga) definition() SagaDefinition {
+	var steps []SagaStep
+	for _, name := range []string{"a", "b", "c"} {
+		steps = append(steps, SagaStep{Name: name, Action: s.call("do " + name), Compensate: s.call("undo " + name)})
+	}
+	return SagaDefinition{
+		Type:      "test.saga",
+		Steps:     steps,
+		Permanent: func(err error) bool { return errors.Is(err, errDeclined) },
+	}
+}
+
+type sagaFixture struct {
+	coordinator *SagaCoordinator
+	repo        domain.SagaRepository
+	script      *scriptedSaga
+	now         time.Time
+}
+
+func newSagaFixture(t *testing.T) *sagaFixture {
+	t.Helper()
+	f := &sagaFixture{
+		repo:   memory.NewSagaRepository(),
+		script: newScriptedSaga(),
+		now:    time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.coordinator = NewSagaCoordinator(f.repo, SagaPolicy{StepAttempts: 2, RetryDelay: time.Millisecond, StaleAfter: time.Minute}, logger.New("error"))
+	f.coordinator.now = func() time.Time { return f.now }
+	f.coordinator.Register(f.script.definition())
+	return f
+}
+
+// stored returns the saga as last stored
+func (f *sagaFixture) stored(t *testing.T, id string) *domain.Saga {
+	t.Helper()
+	saga, err := f.repo.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", id, err)
+	}
+	return saga
+}
+
+func TestSagaCompletes(t *testing.T) {
+	f := newSagaFixture(t)
+	saga, err := f.coordinator.Start(context.Background(), "test.saga", "order-1", map[string]string{"input": "x"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if got := f.script.log(); got != "do a, do b, do c" {
+		t.Errorf("calls = %s, want every action in order", got)
+	}
+	stored := f.stored(t, saga.ID)
+	if stored.Status != domain.SagaCompleted || stored.Step != 3 {
+		t.Errorf("stored saga = %s at step %d, want completed at step 3", stored.Status, stored.Step)
+	}
+	if stored.Data["input"] != "x" || stored.Data["do c"] != "done" {
+		t.Errorf("stored data = %v, want the input and what the steps recorded", stored.Data)
+	}
+	if f.coordinator.StepName(stored) != "" {
+		t.Errorf("StepName() = %q for a finished saga", f.coordinator.StepName(stored))
+	}
+
+	// One saga per key
+	if _, err := f.coordinator.Start(context.Background(), "test.saga", "order-1", nil); !errors.Is(err, domain.ErrSagaAlreadyExists) {
+		t.Errorf("second Start() error = %v, want ErrSagaAlreadyExists", err)
+	}
+	if _, err := f.coordinator.Start(context.Background(), "unknown", "order-2", nil); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Start() of an unknown type error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestSagaCompensatesFailedStep(t *testing.T) {
+	tests := []struct {
+		name      string
+		step      string
+		err       error
+		wantCalls string
+	}{
+		// A transient failure is retried, then compensated as it may have taken effect
+		{"first step transient", "a", errTimeout, "do a, do a, undo a"},
+		{"second step transient", "b", errTimeout, "do a, do b, do b, undo b, undo a"},
+		{"last step transient", "c", errTimeout, "do a, do b, do c, do c, undo c, undo b, undo a"},
+		// A permanent failure is neither retried nor compensated
+		{"first step permanent", "a", errDeclined, "do a"},
+		{"second step permanent", "b", errDeclined, "do a, do b, undo a"},
+		{"last step permanent", "c", errDeclined, "do a, do b, do c, undo b, undo a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSagaFixture(t)
+			attempts := 2 // Every attempt the policy allows
+			if errors.Is(tt.err, errDeclined) {
+				attempts = 1
+			}
+			f.script.failing("do "+tt.step, tt.err, attempts)
+
+			saga, err := f.coordinator.Start(context.Background(), "test.saga", "order-1", nil)
+			if !errors.Is(err, tt.err) || !strings.HasPrefix(err.Error(), tt.step+": ") {
+				t.Errorf("Start() error = %v, want the step's error, named", err)
+			}
+			if got := f.script.log(); got != tt.wantCalls {
+				t.Errorf("calls = %s, want %s", got, tt.wantCalls)
+			}
+			stored := f.stored(t, saga.ID)
+			if stored.Status != domain.SagaCompensated || stored.Step != -1 {
+				t.Errorf("stored saga = %s at step %d, want compensated at -1", stored.Status, stored.Step)
+			}
+			if stored.Error != err.Error() {
+				t.Errorf("stored error = %q, want %q", stored.Error, err)
+			}
+
+			// A compensated saga doesn't block another for the key
+			f.script.reset()
+			if _, err := f.coordinator.Start(context.Background(), "test.saga", "order-1", nil); err != nil {
+				t.Errorf("Start() after compensation error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSagaRetriesTransientFailures(t *testing.T) {
+	f := newSagaFixture(t)
+	f.script.failing("do b", errTimeout, 1)
+
+	saga, err := f.coordinator.Start(context.Background(), "test.saga", "order-1", nil)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if got := f.script.log(); got != "do a, do b, do b, do c" {
+		t.Errorf("calls = %s, want b retried once", got)
+	}
+	if stored := f.stored(t, saga.ID); stored.Status != domain.SagaCompleted {
+		t.Errorf("status = %s, want completed", stored.Status)
+	}
+}
+
+func TestSagaFailingCompensation(t *testing.T) {
+	ctx := context.Background()
+	f := newSagaFixture(t)
+	f.script.failing("do c", errDeclined, 1)
+	f.script.failing("undo a", errTimeout, 4) // Both attempts of Start, then of the first recovery
+
+	saga, err := f.coordinator.Start(ctx, "test.saga", "order-1", nil)
+	if !errors.Is(err, errDeclined) {
+		t.Fatalf("Start() error = %v, want the failed step's", err)
+	}
+	if got := f.script.log(); got != "do a, do b, do c, undo b, undo a, undo a" {
+		t.Errorf("calls = %s", got)
+	}
+	stored := f.stored(t, saga.ID)
+	if stored.Status != domain.SagaCompensating || stored.Step != 0 || stored.Attempts != 1 {
+		t.Fatalf("stored saga = %s at step %d after %d attempts, want compensating at 0 after 1", stored.Status, stored.Step, stored.Attempts)
+	}
+	if f.coordinator.StepName(stored) != "a" {
+		t.Errorf("StepName() = %q, want a", f.coordinator.StepName(stored))
+	}
+
+	// Recovery leaves it alone until it is stale, then retries the compensation
+	f.script.reset()
+	if n, err := f.coordinator.RecoverOnce(ctx); err != nil || n != 0 {
+		t.Errorf("RecoverOnce() before stale = %d, %v, want 0", n, err)
+	}
+	f.now = f.now.Add(2 * time.Minute)
+	if n, err := f.coordinator.RecoverOnce(ctx); err != nil || n != 1 {
+		t.Errorf("RecoverOnce() = %d, %v, want 1", n, err)
+	}
+	if stored := f.stored(t, saga.ID); stored.Status != domain.SagaCompensating || stored.Attempts != 2 {
+		t.Errorf("stored saga = %s after %d attempts, want still compensating after 2", stored.Status, stored.Attempts)
+	}
+
+	f.now = f.now.Add(2 * time.Minute)
+	if n, err := f.coordinator.RecoverOnce(ctx); err != nil || n != 1 {
+		t.Errorf("RecoverOnce() = %d, %v, want 1", n, err)
+	}
+	if got := f.script.log(); got != "undo a, undo a, undo a" {
+		t.Errorf("calls during recovery = %s, want only a's compensation", got)
+	}
+	stored = f.stored(t, saga.ID)
+	if stored.Status != domain.SagaCompensated || stored.Attempts != 0 {
+		t.Errorf("stored saga = %s after %d attempts, want compensated with attempts reset", stored.Status, stored.Attempts)
+	}
+	if !strings.Contains(stored.Error, "c: declined") {
+		t.Errorf("stored error = %q, want the original cause kept", stored.Error)
+	}
+}
+
+func TestSagaRecoversAfterCrash(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		status     domain.SagaStatus
+		step       int
+		cause      string
+		wantCalls  string
+		wantStatus domain.SagaStatus
+	}{
+		// The crash came after a was stored done: b, whose outcome was not stored, is performed again
+		{"while running", domain.SagaRunning, 1, "", "do b, do c", domain.SagaCompleted},
+		{"before completing", domain.SagaRunning, 3, "", "", domain.SagaCompleted},
+		{"while compensating", domain.SagaCompensating, 1, "c: timeout", "undo b, undo a", domain.SagaCompensated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSagaFixture(t)
+			crashed := &domain.Saga{
+				ID:        "saga-1",
+				Type:      "test.saga",
+				Key:       "order-1",
+				Status:    tt.status,
+				Step:      tt.step,
+				Data:      map[string]string{"do a": "done"},
+				Error:     tt.cause,
+				CreatedAt: f.now,
+				UpdatedAt: f.now,
+				Version:   1,
+			}
+			if err := f.repo.Create(ctx, crashed); err != nil {
+				t.Fatal(err)
+			}
+
+			f.now = f.now.Add(2 * time.Minute)
+			if n, err := f.coordinator.RecoverOnce(ctx); err != nil || n != 1 {
+				t.Fatalf("RecoverOnce() = %d, %v, want 1", n, err)
+			}
+			if got := f.script.log(); got != tt.wantCalls {
+				t.Errorf("calls = %q, want %q", got, tt.wantCalls)
+			}
+			if stored := f.stored(t, "saga-1"); stored.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", stored.Status, tt.wantStatus)
+			}
+
+			// Finished sagas aren't resumed again
+			if n, _ := f.coordinator.RecoverOnce(ctx); n != 0 {
+				t.Errorf("second RecoverOnce() resumed %d sagas, want 0", n)
+			}
+		})
+	}
+}
+
+func TestSagaRecoverySkips(t *testing.T) {
+	ctx := context.Background()
+	f := newSagaFixture(t)
+	for _, saga := range []*domain.Saga{
+		{ID: "unknown", Type: "retired.saga", Key: "k1", Status: domain.SagaRunning, Data: map[string]string{}, UpdatedAt: f.now, Version: 1},
+		{ID: "claimed", Type: "test.saga", Key: "k2", Status: domain.SagaRunning, Step: 2, Data: map[string]string{}, UpdatedAt: f.now, Version: 1},
+	} {
+		if err := f.repo.Create(ctx, saga); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.now = f.now.Add(2 * time.Minute)
+
+	// Another instance claims the second saga between listing and claiming
+	repo := &claimingSagas{SagaRepository: f.repo, claim: "claimed"}
+	coordinator := NewSagaCoordinator(repo, SagaPolicy{StaleAfter: time.Minute}, logger.New("error"))
+	coordinator.now = f.coordinator.now
+	coordinator.Register(f.script.definition())
+
+	if n, err := coordinator.RecoverOnce(ctx); err != nil || n != 0 {
+		t.Errorf("RecoverOnce() = %d, %v, want nothing resumed", n, err)
+	}
+	if got := f.script.log(); got != "" {
+		t.Errorf("calls = %s, want none", got)
+	}
+}
+
+// claimingSagas updates the saga claim in the background just before the
+// coordinator does, as a second instance recovering it would
+type claimingSagas struct {
+	domain.SagaRepository
+	claim string
+}
+
+func (r *claimingSagas) Update(ctx context.Context, saga *domain.Saga) error {
+	if saga.ID == r.claim {
+		other := saga.Clone()
+		if err := r.SagaRepository.Update(ctx, other); err != nil {
+			return err
+		}
+	}
+	return r.SagaRepository.Update(ctx, saga)
+}
+
+func TestCheckoutCompensates(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	orders := memory.NewOrderRepository()
+	payments := memory.NewPaymentGateway()
+	inventory := memory.NewInventory()
+	logg := logger.New("error")
+
+	user, err := domain.NewUser("user-1", "Ada", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	orderSvc := NewOrderService(orders, users, nil, logg)
+	sagas := NewSagaCoordinator(memory.NewSagaRepository(), SagaPolicy{StepAttempts: 1}, logg)
+	checkout := NewCheckoutService(sagas, orderSvc, payments, inventory, "EUR", logg)
+
+	inventory.SetStock(ctx, "widget", 1)
+	order, err := orderSvc.CreateOrder(ctx, user.ID, []domain.OrderItem{{ProductID: "widget", Quantity: 2, Price: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Too little stock: the payment is refunded and the order left pending
+	saga, err := checkout.Checkout(ctx, order.ID)
+	if !errors.Is(err, domain.ErrCheckoutFailed) || !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Fatalf("Checkout() error = %v, want ErrCheckoutFailed wrapping ErrInsufficientStock", err)
+	}
+	payment, ok := payments.Payment(saga.ID)
+	if !ok || !payment.Refunded || payment.Capture.Amount != 1000 {
+		t.Errorf("payment = %+v, %v, want 1000 captured then refunded", payment, ok)
+	}
+	if got, _ := orderSvc.GetOrderByID(ctx, order.ID); got.Status != domain.OrderStatusPending {
+		t.Errorf("order status = %s, want pending", got.Status)
+	}
+
+	// With stock, checking out again confirms the order, once
+	inventory.SetStock(ctx, "widget", 5)
+	saga, err = checkout.Checkout(ctx, order.ID)
+	if err != nil || saga.Status != domain.SagaCompleted {
+		t.Fatalf("Checkout() = %+v, %v, want completed", saga, err)
+	}
+	if got, _ := orderSvc.GetOrderByID(ctx, order.ID); got.Status != domain.OrderStatusConfirmed {
+		t.Errorf("order status = %s, want confirmed", got.Status)
+	}
+	if available, _, _ := inventory.Stock(ctx, "widget"); available != 3 {
+		t.Errorf("stock = %d, want 3 after reserving 2", available)
+	}
+	again, err := checkout.Checkout(ctx, order.ID)
+	if err != nil || again.ID != saga.ID {
+		t.Errorf("repeated Checkout() = %+v, %v, want the completed saga", again, err)
+	}
+}
@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Expensive operations guarded by cluster-wide semaphores
+const (
+	SemaphoreReports = "reports"
+	SemaphoreExports = "exports"
+	SemaphoreImports = "imports"
+)
+
+// SemaphorePolicy configures a cluster-wide semaphore
+type SemaphorePolicy struct {
+	Limit       int           // Concurrent holders across all replicas (0 disables limiting)
+	LeaseTTL    time.Duration // A crashed holder's slot is reclaimed after this long
+	WaitTimeout time.Duration // How long Acquire queues for a slot (0 tries once)
+}
+
+// DefaultSemaphorePolicy returns conservative defaults
+func DefaultSemaphorePolicy() SemaphorePolicy {
+	return SemaphorePolicy{
+		Limit:       2,
+		LeaseTTL:    30 * time.Second,
+		WaitTimeout: 5 * time.Second,
+	}
+}
+
+// Semaphores hands out named semaphores sharing one store
+type Semaphores struct {
+	store    domain.SemaphoreStore
+	defaults SemaphorePolicy
+	limits   map[string]int
+	logg     *logger.Logger
+}
+
+// NewSemaphores creates a semaphore registry. limits overrides defaults.Limit per operation.
+func NewSemaphores(store domain.SemaphoreStore, defaults SemaphorePolicy, limits map[string]int, logg *logger.Logger) *Semaphores {
+	return &Semaphores{
+		store:    store,
+		defaults: defaults,
+		limits:   limits,
+		logg:     logg,
+	}
+}
+
+// Get returns the semaphore for operation name
+func (s *Semaphores) Get(name string) *Semaphore {
+	policy := s.defaults
+	if limit, ok := s.limits[name]; ok {
+		policy.Limit = limit
+	}
+	return NewSemaphore(s.store, name, policy, s.logg)
+}
+
+// Semaphore limits how many replicas run an expensive operation at once.
+// Store failures are logged and treated as "acquired" so a Redis outage
+// degrades to per-replica behaviour rather than disabling the feature.
+type Semaphore struct {
+	store  domain.SemaphoreStore
+	name   string
+	policy SemaphorePolicy
+	logg   *logger.Logger
+}
+
+// NewSemaphore creates a semaphore for operation name
+func NewSemaphore(store domain.SemaphoreStore, name string, policy SemaphorePolicy, logg *logger.Logger) *Semaphore {
+	if policy.LeaseTTL <= 0 {
+		policy.LeaseTTL = DefaultSemaphorePolicy().LeaseTTL
+	}
+	return &Semaphore{
+		store:  store,
+		name:   name,
+		policy: policy,
+		logg:   logg,
+	}
+}
+
+// Do runs fn while holding a slot. fn's context is cancelled if the lease is
+// lost (e.g. Redis evicted it), since another replica may now hold the slot.
+func (s *Semaphore) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	permit, err := s.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer permit.Release()
+	return fn(permit.Context())
+}
+
+// Acquire takes a slot, retrying with jittered backoff for up to WaitTimeout.
+// It returns domain.ErrSemaphoreFull if no slot frees up in time.
+func (s *Semaphore) Acquire(ctx context.Context) (*Permit, error) {
+	holder := uuid.New().String()
+	if s.policy.Limit <= 0 {
+		return s.newPermit(ctx, holder, false), nil
+	}
+
+	deadline := time.Now().Add(s.policy.WaitTimeout)
+	backoff := 50 * time.Millisecond
+	for {
+		ok, err := s.store.TryAcquire(ctx, s.name, holder, s.policy.Limit, s.policy.LeaseTTL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			s.logg.Warn("semaphore acquire failed, proceeding without a slot",
+				"error", err, "semaphore", s.name)
+			return s.newPermit(ctx, holder, false), nil
+		}
+		if ok {
+			return s.newPermit(ctx, holder, true), nil
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		if time.Now().Add(wait).After(deadline) {
+			return nil, fmt.Errorf("%w: %s (limit %d)", domain.ErrSemaphoreFull, s.name, s.policy.Limit)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, time.Second)
+	}
+}
+
+// Permit is a held semaphore slot; its lease is refreshed in the background
+// until Release
+type Permit struct {
+	sem    *Semaphore
+	holder string
+	leased bool // False when limiting is disabled or the store was unavailable
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *Semaphore) newPermit(ctx context.Context, holder string, leased bool) *Permit {
+	permitCtx, cancel := context.WithCancel(ctx)
+	p := &Permit{
+		sem:    s,
+		holder: holder,
+		leased: leased,
+		ctx:    permitCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if leased {
+		go p.keepAlive()
+	} else {
+		close(p.done)
+	}
+	return p
+}
+
+// Context is cancelled when the permit is released or its lease is lost
+func (p *Permit) Context() context.Context {
+	return p.ctx
+}
+
+// keepAlive refreshes the lease at a third of its TTL
+func (p *Permit) keepAlive() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.sem.policy.LeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := p.sem.store.Refresh(p.ctx, p.sem.name, p.holder, p.sem.policy.LeaseTTL)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+				// Transient; the lease survives until its TTL
+				p.sem.logg.Warn("semaphore refresh failed", "error", err, "semaphore", p.sem.name)
+				continue
+			}
+			if !ok {
+				p.sem.logg.Error("semaphore lease lost", "semaphore", p.sem.name)
+				p.cancel()
+				return
+			}
+		}
+	}
+}
+
+// Release frees the slot. It is safe to call more than once.
+func (p *Permit) Release() {
+	p.once.Do(func() {
+		p.cancel()
+		<-p.done
+		if !p.leased {
+			return
+		}
+
+		// Release even when the caller's context is already done
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(p.ctx), 2*time.Second)
+		defer cancel()
+		if err := p.sem.store.Release(ctx, p.sem.name, p.holder); err != nil {
+			// The lease expires on its own after LeaseTTL
+			p.sem.logg.Warn("semaphore release failed", "error", err, "semaphore", p.sem.name)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+var errStoreDown = errors.New("connection refused")
+
+// countingSemaphores wraps a semaphore store, counting refreshes and failing
+// acquires or refreshes with the set errors. Its fields are set before the
+// semaphore uses it, except lose.
+type countingSemaphores struct {
+	domain.SemaphoreStore
+	refreshes  atomic.Int32
+	lose       atomic.Bool // Refresh reports every lease as expired
+	acquireErr error
+	refreshErr error
+}
+
+func (s *countingSemaphores) TryAcquire(ctx context.Context, name, holder string, limit int, ttl time.Duration) (bool, error) {
+	if s.acquireErr != nil {
+		return false, s.acquireErr
+	}
+	return s.SemaphoreStore.TryAcquire(ctx, name, holder, limit, ttl)
+}
+
+func (s *countingSemaphores) Refresh(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	s.refreshes.Add(1)
+	if s.refreshErr != nil {
+		return false, s.refreshErr
+	}
+	if s.lose.Load() {
+		return false, nil
+	}
+	return s.SemaphoreStore.Refresh(ctx, name, holder, ttl)
+}
+
+// testSemaphore returns a semaphore over a fresh in-memory store
+func testSemaphore(policy SemaphorePolicy) (*Semaphore, *countingSemaphores) {
+	store := &countingSemaphores{SemaphoreStore: memory.NewSemaphoreStore()}
+	return NewSemaphore(store, SemaphoreReports, policy, logger.New("error")), store
+}
+
+// acquire takes a slot of sem, failing the test if it can't
+func acquire(t *testing.T, sem *Semaphore) *Permit {
+	t.Helper()
+	permit, err := sem.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	t.Cleanup(permit.Release)
+	return permit
+}
+
+func TestSemaphoreCapacity(t *testing.T) {
+	sem, _ := testSemaphore(SemaphorePolicy{Limit: 2, LeaseTTL: time.Minute})
+	first := acquire(t, sem)
+	acquire(t, sem)
+
+	if _, err := sem.Acquire(context.Background()); !errors.Is(err, domain.ErrSemaphoreFull) {
+		t.Fatalf("Acquire past the limit = %v, want ErrSemaphoreFull", err)
+	}
+
+	// A released slot is free for the next holder, once
+	first.Release()
+	first.Release()
+	if first.Context().Err() == nil {
+		t.Error("permit context still live after Release")
+	}
+	acquire(t, sem)
+	if _, err := sem.Acquire(context.Background()); !errors.Is(err, domain.ErrSemaphoreFull) {
+		t.Errorf("Acquire after a double Release = %v, want ErrSemaphoreFull", err)
+	}
+}
+
+func TestSemaphoresLimitPerOperation(t *testing.T) {
+	sems := NewSemaphores(memory.NewSemaphoreStore(), SemaphorePolicy{Limit: 2, LeaseTTL: time.Minute},
+		map[string]int{SemaphoreImports: 1, SemaphoreExports: 0}, logger.New("error"))
+	tests := []struct {
+		name     string
+		slots    int
+		wantFull bool // After taking slots
+	}{
+		{SemaphoreReports, 2, true},
+		{SemaphoreImports, 1, true},
+		// A limit of 0 disables limiting
+		{SemaphoreExports, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sem := sems.Get(tt.name)
+			for range tt.slots {
+				acquire(t, sem)
+			}
+			_, err := sem.Acquire(context.Background())
+			if full := errors.Is(err, domain.ErrSemaphoreFull); full != tt.wantFull {
+				t.Errorf("Acquire after %d slots = %v, want full %v", tt.slots, err, tt.wantFull)
+			}
+		})
+	}
+}
+
+func TestSemaphoreAcquireWaits(t *testing.T) {
+	sem, _ := testSemaphore(SemaphorePolicy{Limit: 1, LeaseTTL: time.Minute, WaitTimeout: 5 * time.Second})
+	held := acquire(t, sem)
+
+	time.AfterFunc(100*time.Millisecond, held.Release)
+	start := time.Now()
+	acquire(t, sem)
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("Acquire returned after %v, before the slot was released", waited)
+	}
+
+	// A caller giving up stops waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := sem.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire with an expiring context = %v, want its error", err)
+	}
+
+	// Waiting ends with ErrSemaphoreFull after WaitTimeout
+	short, _ := testSemaphore(SemaphorePolicy{Limit: 1, LeaseTTL: time.Minute, WaitTimeout: 150 * time.Millisecond})
+	acquire(t, short)
+	start = time.Now()
+	if _, err := short.Acquire(context.Background()); !errors.Is(err, domain.ErrSemaphoreFull) {
+		t.Errorf("Acquire = %v, want ErrSemaphoreFull", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Acquire waited %v, past its WaitTimeout", waited)
+	}
+}
+
+func TestSemaphoreKeepAlive(t *testing.T) {
+	sem, store := testSemaphore(SemaphorePolicy{Limit: 1, LeaseTTL: 60 * time.Millisecond})
+	permit := acquire(t, sem)
+
+	// Held for several TTLs, the lease is refreshed and keeps the slot
+	time.Sleep(300 * time.Millisecond)
+	if n := store.refreshes.Load(); n < 3 {
+		t.Errorf("lease refreshed %d times over 5 TTLs", n)
+	}
+	if permit.Context().Err() != nil {
+		t.Fatal("permit context cancelled while its lease was refreshed")
+	}
+	if _, err := sem.Acquire(context.Background()); !errors.Is(err, domain.ErrSemaphoreFull) {
+		t.Errorf("Acquire while the lease is kept alive = %v, want ErrSemaphoreFull", err)
+	}
+
+	// Refreshing stops with the release
+	permit.Release()
+	refreshes := store.refreshes.Load()
+	time.Sleep(100 * time.Millisecond)
+	if n := store.refreshes.Load(); n != refreshes {
+		t.Errorf("lease refreshed %d times after Release", n-refreshes)
+	}
+}
+
+func TestSemaphoreLostLease(t *testing.T) {
+	sem, store := testSemaphore(SemaphorePolicy{Limit: 1, LeaseTTL: 30 * time.Millisecond})
+	permit := acquire(t, sem)
+
+	// The lease disappears, as when Redis evicts it, and another holder
+	// takes the slot
+	store.Release(context.Background(), SemaphoreReports, permit.holder)
+	if ok, _ := store.TryAcquire(context.Background(), SemaphoreReports, "other", 1, time.Minute); !ok {
+		t.Fatal("slot still held after its lease was evicted")
+	}
+	select {
+	case <-permit.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("permit context live after its lease was lost")
+	}
+	// Releasing the lost permit leaves the new holder's slot alone
+	permit.Release()
+	if _, err := sem.Acquire(context.Background()); !errors.Is(err, domain.ErrSemaphoreFull) {
+		t.Errorf("Acquire after releasing a lost permit = %v, want ErrSemaphoreFull", err)
+	}
+}
+
+func TestSemaphoreDoCancelledOnLostLease(t *testing.T) {
+	sem, store := testSemaphore(SemaphorePolicy{Limit: 1, LeaseTTL: 30 * time.Millisecond})
+	err := sem.Do(context.Background(), func(ctx context.Context) error {
+		store.lose.Store(true)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.New("still running after the lease was lost")
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do = %v, want the holder's context cancelled", err)
+	}
+	// Do releases the slot
+	store.lose.Store(false)
+	acquire(t, sem)
+}
+
+func TestSemaphoreStoreFailures(t *testing.T) {
+	// An unavailable store degrades to unlimited rather than failing callers
+	sem, store := testSemaphore(SemaphorePolicy{Limit: 1, LeaseTTL: 30 * time.Millisecond})
+	store.acquireErr = errStoreDown
+	for range 3 {
+		if permit := acquire(t, sem); permit.leased {
+			t.Error("permit leased from a store that is down")
+		}
+	}
+	if store.refreshes.Load() != 0 {
+		t.Error("refreshed a lease that was never taken")
+	}
+
+	// A failed refresh is transient: the holder keeps running
+	sem, store = testSemaphore(SemaphorePolicy{Limit: 1, LeaseTTL: 30 * time.Millisecond})
+	store.refreshErr = errStoreDown
+	permit := acquire(t, sem)
+	eventually(t, "a failed refresh", func() bool { return store.refreshes.Load() >= 2 })
+	if permit.Context().Err() != nil {
+		t.Error("permit context cancelled by a failed refresh")
+	}
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// SessionService orchestrates access token revocation (logout, logout-all,
+// and revoking every session of a compromised account)
+type SessionService struct {
+	revocations  domain.TokenRevocationStore
+	accessTokens domain.AccessTokenRepository
+	retention    time.Duration // How long user-wide revocations are remembered
+	logg         *logger.Logger
+}
+
+// NewSessionService creates a new session service. User-wide revocations are
+// kept for retention, which must be at least the longest lifetime any live
+// JWT can have, whenever and however it was issued. Personal access tokens
+// can outlive any retention, so revoking every token of a user also revokes
+// their rows in accessTokens.
+func NewSessionService(revocations domain.TokenRevocationStore, accessTokens domain.AccessTokenRepository, retention time.Duration, logg *logger.Logger) *SessionService {
+	return &SessionService{
+		revocations:  revocations,
+		accessTokens: accessTokens,
+		retention:    retention,
+		logg:         logg,
+	}
+}
+
+// RevokeToken revokes a single token until it would have expired anyway
+func (s *SessionService) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return fmt.Errorf("%w: token has no id", domain.ErrInvalidInput)
+	}
+
+	if err := s.revocations.RevokeToken(ctx, tokenID, time.Until(expiresAt)); err != nil {
+		s.logg.Error("failed to revoke token", "error", err, "token_id", tokenID)
+		return fmt.Errorf("%w: failed to revoke token", domain.ErrInternalError)
+	}
+
+	s.logg.Info("token revoked", "token_id", tokenID)
+	return nil
+}
+
+// RevokeAllForUser revokes every token issued to a user up to now, personal
+// access tokens included. Tokens issued afterwards (e.g. after the user logs
+// in again) remain valid
+func (s *SessionService) RevokeAllForUser(ctx context.Context, userID string) (time.Time, error) {
+	if userID == "" {
+		return time.Time{}, domain.ErrInvalidUserID
+	}
+
+	now := time.Now()
+	if err := s.revocations.RevokeAllForUser(ctx, userID, now, s.retention); err != nil {
+		s.logg.Error("failed to revoke user tokens", "error", err, "user_id", userID)
+		return time.Time{}, fmt.Errorf("%w: failed to revoke user tokens", domain.ErrInternalError)
+	}
+	// The marker above expires after the retention; the access token rows
+	// stay revoked for good
+	if err := s.accessTokens.RevokeAllForUser(ctx, userID, now); err != nil {
+		s.logg.Error("failed to revoke user access tokens", "error", err, "user_id", userID)
+		return time.Time{}, fmt.Errorf("%w: failed to revoke user tokens", domain.ErrInternalError)
+	}
+
+	s.logg.Info("all user tokens revoked", "user_id", userID)
+	return now, nil
+}
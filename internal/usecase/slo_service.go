@@ -0,0 +1,250 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+// SLO alert levels, from the burn rate of each window
+const (
+	SLOAlertNone   = ""
+	SLOAlertTicket = "ticket" // The slow window burns too fast: the budget runs out within days
+	SLOAlertPage   = "page"   // The fast window burns too fast: the budget runs out within hours
+)
+
+// SLODefaultRoute is the objective key covering every route without its own
+const SLODefaultRoute = "*"
+
+// sloResolution is the width of one slot of the per-route histories
+const sloResolution = time.Minute
+
+// SLOObjective is a route's service level objective: the share of requests
+// that must be good, i.e. answered without a server error (and within
+// Latency when it is set)
+type SLOObjective struct {
+	Target  float64       // 0..1, e.g. 0.999
+	Latency time.Duration // 0 counts only server errors as bad
+}
+
+// SLOPolicy configures the objectives and burn rate alerting
+type SLOPolicy struct {
+	Objectives   map[string]SLOObjective // By route pattern, SLODefaultRoute for the rest
+	FastWindow   time.Duration
+	SlowWindow   time.Duration // Also how much latency history is kept
+	FastBurnRate float64       // Fast window burn rate that pages
+	SlowBurnRate float64       // Slow window burn rate that opens a ticket
+	MinRequests  int64         // Windows with fewer requests never breach
+}
+
+// SLOWindow is a route's error budget burn over one window
+type SLOWindow struct {
+	Window    time.Duration
+	Requests  int64
+	Bad       int64
+	BurnRate  float64 // Bad share over the error budget; 1 spends the budget exactly by the end of the SLO period
+	Breaching bool    // BurnRate at or above the window's alerting threshold
+}
+
+// SLOStatus is the burn of one route's objective
+type SLOStatus struct {
+	Route     string
+	Objective SLOObjective
+	Fast      SLOWindow
+	Slow      SLOWindow
+	Alert     string // SLOAlertNone, SLOAlertTicket or SLOAlertPage
+}
+
+// LatencyHeatmap holds a route's latency histogram per time step, oldest
+// first; each row has a count per domain.LatencyBoundsMS bucket plus the
+// overflow bucket
+type LatencyHeatmap struct {
+	Route string
+	Step  time.Duration
+	Rows  []LatencyHeatmapRow
+}
+
+// LatencyHeatmapRow is the histogram of the requests in one step
+type LatencyHeatmapRow struct {
+	Start  time.Time
+	Counts []int64
+}
+
+// SLOService tracks good and bad requests per route at minute resolution and
+// computes multiwindow burn rates for alerting, plus latency heatmaps. Counts
+// are per instance and kept in memory; alerting across instances sums the
+// Requests and Bad of each window.
+type SLOService struct {
+	policy SLOPolicy
+	now    func() time.Time
+	slots  int // Slots kept per route, covering SlowWindow
+
+	mu     sync.Mutex
+	routes map[string]*sloSeries
+}
+
+// sloSeries is a ring of per-minute counts for one route
+type sloSeries struct {
+	objective SLOObjective
+	slots     []sloSlot
+}
+
+// sloSlot counts the requests of one minute
+type sloSlot struct {
+	minute   int64 // Unix minute the counts belong to; older slots are stale
+	requests int64
+	bad      int64
+	latency  []int64 // Per domain.LatencyBoundsMS bucket, plus the overflow bucket
+}
+
+// NewSLOService creates an SLO service
+func NewSLOService(policy SLOPolicy) *SLOService {
+	return &SLOService{
+		policy: policy,
+		now:    time.Now,
+		slots:  max(int(policy.SlowWindow/sloResolution), 1),
+		routes: make(map[string]*sloSeries),
+	}
+}
+
+// objectiveFor returns the objective of route, or false when it has none
+func (s *SLOService) objectiveFor(route string) (SLOObjective, bool) {
+	if o, ok := s.policy.Objectives[route]; ok {
+		return o, true
+	}
+	o, ok := s.policy.Objectives[SLODefaultRoute]
+	return o, ok
+}
+
+// ObserveRoute counts one request to route (a mux pattern) that took d;
+// failed marks a server error. Routes without an objective are ignored.
+func (s *SLOService) ObserveRoute(route string, d time.Duration, failed bool) {
+	minute := s.now().Unix() / int64(sloResolution/time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	series, ok := s.routes[route]
+	if !ok {
+		objective, hasObjective := s.objectiveFor(route)
+		if !hasObjective {
+			return
+		}
+		series = &sloSeries{objective: objective, slots: make([]sloSlot, s.slots)}
+		s.routes[route] = series
+	}
+
+	slot := &series.slots[minute%int64(len(series.slots))]
+	if slot.minute != minute {
+		*slot = sloSlot{minute: minute, latency: make([]int64, len(domain.LatencyBoundsMS)+1)}
+	}
+	slot.requests++
+	if failed || (series.objective.Latency > 0 && d > series.objective.Latency) {
+		slot.bad++
+	}
+	slot.latency[latencyBucket(d)]++
+}
+
+// latencyBucket returns the domain.LatencyBoundsMS bucket d falls into
+func latencyBucket(d time.Duration) int {
+	ms := d.Milliseconds()
+	i := 0
+	for i < len(domain.LatencyBoundsMS) && ms > domain.LatencyBoundsMS[i] {
+		i++
+	}
+	return i
+}
+
+// Status returns the burn of every observed route's objective, sorted by route
+func (s *SLOService) Status() []SLOStatus {
+	minute := s.now().Unix() / int64(sloResolution/time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]SLOStatus, 0, len(s.routes))
+	for route, series := range s.routes {
+		status := SLOStatus{
+			Route:     route,
+			Objective: series.objective,
+			Fast:      s.burn(series, minute, s.policy.FastWindow, s.policy.FastBurnRate),
+			Slow:      s.burn(series, minute, s.policy.SlowWindow, s.policy.SlowBurnRate),
+		}
+		switch {
+		case status.Fast.Breaching:
+			status.Alert = SLOAlertPage
+		case status.Slow.Breaching:
+			status.Alert = SLOAlertTicket
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Route < statuses[j].Route })
+	return statuses
+}
+
+// burn sums the slots of the window ending at minute; the caller holds s.mu
+func (s *SLOService) burn(series *sloSeries, minute int64, window time.Duration, threshold float64) SLOWindow {
+	w := SLOWindow{Window: window}
+	oldest := minute - int64(window/sloResolution) + 1
+	for _, slot := range series.slots {
+		if slot.minute >= oldest && slot.minute <= minute {
+			w.Requests += slot.requests
+			w.Bad += slot.bad
+		}
+	}
+	if w.Requests > 0 {
+		budget := 1 - series.objective.Target
+		w.BurnRate = float64(w.Bad) / float64(w.Requests) / budget
+		w.Breaching = w.Requests >= s.policy.MinRequests && w.BurnRate >= threshold
+	}
+	return w
+}
+
+// Heatmap returns the latency histograms of route over the last window, one
+// row per step (a whole number of minutes); the oldest row may be partial
+// when the window spans the whole history. Returns domain.ErrInvalidInput
+// for routes without an objective or windows longer than the kept history.
+func (s *SLOService) Heatmap(route string, window, step time.Duration) (*LatencyHeatmap, error) {
+	if window <= 0 || window > s.policy.SlowWindow {
+		return nil, fmt.Errorf("%w: window must be between 1m and %s", domain.ErrInvalidInput, s.policy.SlowWindow)
+	}
+	if step < sloResolution || step%sloResolution != 0 || step > window {
+		return nil, fmt.Errorf("%w: step must be a whole number of minutes within the window", domain.ErrInvalidInput)
+	}
+	if _, ok := s.objectiveFor(route); !ok {
+		return nil, fmt.Errorf("%w: route %q has no objective", domain.ErrInvalidInput, route)
+	}
+
+	minutesPerStep := int64(step / sloResolution)
+	steps := int64(window / step)
+	now := s.now().Unix() / int64(sloResolution/time.Second)
+	// Rows end with the step holding the current minute
+	first := (now/minutesPerStep - steps + 1) * minutesPerStep
+
+	heatmap := &LatencyHeatmap{Route: route, Step: step, Rows: make([]LatencyHeatmapRow, steps)}
+	for i := range heatmap.Rows {
+		start := first + int64(i)*minutesPerStep
+		heatmap.Rows[i] = LatencyHeatmapRow{
+			Start:  time.Unix(start*int64(sloResolution/time.Second), 0).UTC(),
+			Counts: make([]int64, len(domain.LatencyBoundsMS)+1),
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	series, ok := s.routes[route]
+	if !ok {
+		return heatmap, nil // Not requested yet
+	}
+	for _, slot := range series.slots {
+		if slot.minute < first || slot.minute > now {
+			continue
+		}
+		row := heatmap.Rows[(slot.minute-first)/minutesPerStep]
+		for b, n := range slot.latency {
+			row.Counts[b] += n
+		}
+	}
+	return heatmap, nil
+}
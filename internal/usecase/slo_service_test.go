@@ -0,0 +1,310 @@
+package usecase
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+)
+
+const sloTestRoute = "GET /api/orders/{id}"
+
+// sloTestStart is on an hour, so on every step of a heatmap
+var sloTestStart = time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+func testSLOPolicy() SLOPolicy {
+	return SLOPolicy{
+		Objectives:   map[string]SLOObjective{sloTestRoute: {Target: 0.99, Latency: 100 * time.Millisecond}},
+		FastWindow:   5 * time.Minute,
+		SlowWindow:   time.Hour,
+		FastBurnRate: 14.4,
+		SlowBurnRate: 6,
+		MinRequests:  10,
+	}
+}
+
+// sloObservation is a request some time after sloTestStart
+type sloObservation struct {
+	at      time.Duration
+	n       int
+	latency time.Duration
+	failed  bool
+}
+
+// observeSLO returns a service fed with observations of sloTestRoute, whose
+// clock then reads sloTestStart+at
+func observeSLO(policy SLOPolicy, observations []sloObservation, at time.Duration) *SLOService {
+	s := NewSLOService(policy)
+	var now time.Time
+	s.now = func() time.Time { return now }
+	for _, o := range observations {
+		now = sloTestStart.Add(o.at)
+		for range o.n {
+			s.ObserveRoute(sloTestRoute, o.latency, o.failed)
+		}
+	}
+	now = sloTestStart.Add(at)
+	return s
+}
+
+func TestSLOStatus(t *testing.T) {
+	fast := 10 * time.Millisecond
+	slow := 300 * time.Millisecond
+
+	tests := []struct {
+		name         string
+		observations []sloObservation
+		at           time.Duration
+		wantFast     SLOWindow // Window is not compared
+		wantSlow     SLOWindow
+		wantAlert    string
+	}{
+		{
+			name:         "all good",
+			observations: []sloObservation{{at: 0, n: 20, latency: fast}},
+			wantFast:     SLOWindow{Requests: 20},
+			wantSlow:     SLOWindow{Requests: 20},
+		},
+		{
+			name:         "server errors page",
+			observations: []sloObservation{{at: 0, n: 16, latency: fast}, {at: 0, n: 4, latency: fast, failed: true}},
+			// 20% bad against a 1% budget
+			wantFast:  SLOWindow{Requests: 20, Bad: 4, BurnRate: 20, Breaching: true},
+			wantSlow:  SLOWindow{Requests: 20, Bad: 4, BurnRate: 20, Breaching: true},
+			wantAlert: SLOAlertPage,
+		},
+		{
+			name:         "slow requests are bad",
+			observations: []sloObservation{{at: 0, n: 18, latency: 100 * time.Millisecond}, {at: 0, n: 2, latency: slow}},
+			// At the latency objective is good, past it bad
+			wantFast:  SLOWindow{Requests: 20, Bad: 2, BurnRate: 10},
+			wantSlow:  SLOWindow{Requests: 20, Bad: 2, BurnRate: 10, Breaching: true},
+			wantAlert: SLOAlertTicket,
+		},
+		{
+			name: "burn past the fast window opens a ticket",
+			observations: []sloObservation{
+				{at: 0, n: 90, latency: fast},
+				{at: 0, n: 10, latency: fast, failed: true},
+				{at: 20 * time.Minute, n: 20, latency: fast},
+			},
+			at:        20 * time.Minute,
+			wantFast:  SLOWindow{Requests: 20},
+			wantSlow:  SLOWindow{Requests: 120, Bad: 10, BurnRate: 10.0 / 120 / 0.01, Breaching: true},
+			wantAlert: SLOAlertTicket,
+		},
+		{
+			name:         "too few requests never breach",
+			observations: []sloObservation{{at: 0, n: 9, latency: fast, failed: true}},
+			wantFast:     SLOWindow{Requests: 9, Bad: 9, BurnRate: 100},
+			wantSlow:     SLOWindow{Requests: 9, Bad: 9, BurnRate: 100},
+		},
+		{
+			name:         "no traffic in the fast window",
+			observations: []sloObservation{{at: 0, n: 20, latency: fast, failed: true}},
+			at:           10 * time.Minute,
+			// No requests is no burn, not a division by zero
+			wantFast:  SLOWindow{},
+			wantSlow:  SLOWindow{Requests: 20, Bad: 20, BurnRate: 100, Breaching: true},
+			wantAlert: SLOAlertTicket,
+		},
+		{
+			name:         "no traffic in either window",
+			observations: []sloObservation{{at: 0, n: 20, latency: fast, failed: true}},
+			at:           2 * time.Hour,
+		},
+		{
+			name: "oldest minute of the fast window",
+			observations: []sloObservation{
+				{at: 0, n: 20, latency: fast, failed: true},
+				{at: 4 * time.Minute, n: 20, latency: fast},
+			},
+			at:        4*time.Minute + 59*time.Second,
+			wantFast:  SLOWindow{Requests: 40, Bad: 20, BurnRate: 50, Breaching: true},
+			wantSlow:  SLOWindow{Requests: 40, Bad: 20, BurnRate: 50, Breaching: true},
+			wantAlert: SLOAlertPage,
+		},
+		{
+			name: "just past the fast window",
+			observations: []sloObservation{
+				{at: 0, n: 20, latency: fast, failed: true},
+				{at: 5 * time.Minute, n: 20, latency: fast},
+			},
+			at:        5 * time.Minute,
+			wantFast:  SLOWindow{Requests: 20},
+			wantSlow:  SLOWindow{Requests: 40, Bad: 20, BurnRate: 50, Breaching: true},
+			wantAlert: SLOAlertTicket,
+		},
+		{
+			name: "just past the slow window",
+			observations: []sloObservation{
+				{at: 0, n: 20, latency: fast, failed: true},
+				{at: 59 * time.Minute, n: 20, latency: fast},
+			},
+			// The first minute's slot is reused by the current one, stale
+			at:       time.Hour,
+			wantFast: SLOWindow{Requests: 20},
+			wantSlow: SLOWindow{Requests: 20},
+		},
+	}
+
+	burnEqual := func(got, want SLOWindow) bool {
+		return got.Requests == want.Requests && got.Bad == want.Bad && got.Breaching == want.Breaching &&
+			math.Abs(got.BurnRate-want.BurnRate) < 1e-9
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := observeSLO(testSLOPolicy(), tt.observations, tt.at).Status()
+			if len(statuses) != 1 {
+				t.Fatalf("statuses = %+v, want the route's", statuses)
+			}
+			got := statuses[0]
+			if got.Fast.Window != 5*time.Minute || got.Slow.Window != time.Hour {
+				t.Errorf("windows = %v and %v, want the policy's", got.Fast.Window, got.Slow.Window)
+			}
+			if !burnEqual(got.Fast, tt.wantFast) {
+				t.Errorf("fast window = %+v, want %+v", got.Fast, tt.wantFast)
+			}
+			if !burnEqual(got.Slow, tt.wantSlow) {
+				t.Errorf("slow window = %+v, want %+v", got.Slow, tt.wantSlow)
+			}
+			if got.Alert != tt.wantAlert {
+				t.Errorf("alert = %q, want %q", got.Alert, tt.wantAlert)
+			}
+		})
+	}
+}
+
+func TestSLOStatusRoutes(t *testing.T) {
+	policy := testSLOPolicy()
+	policy.Objectives[SLODefaultRoute] = SLOObjective{Target: 0.9}
+	s := NewSLOService(policy)
+	s.now = func() time.Time { return sloTestStart }
+
+	s.ObserveRoute("GET /api/users", time.Second, false)
+	s.ObserveRoute(sloTestRoute, time.Second, false)
+	s.ObserveRoute("GET /api/users", time.Millisecond, true)
+
+	statuses := s.Status()
+	var routes []string
+	for _, status := range statuses {
+		routes = append(routes, status.Route)
+	}
+	if want := []string{"GET /api/orders/{id}", "GET /api/users"}; !slices.Equal(routes, want) {
+		t.Fatalf("routes = %v, want %v", routes, want)
+	}
+	// The default objective has no latency target: only the failure is bad
+	if users := statuses[1]; users.Objective.Target != 0.9 || users.Fast.Requests != 2 || users.Fast.Bad != 1 {
+		t.Errorf("default route status = %+v, want the default objective over both requests", users)
+	}
+	if orders := statuses[0]; orders.Fast.Bad != 1 {
+		t.Errorf("route status = %+v, want the slow request bad", orders)
+	}
+
+	// Without a default, routes lacking an objective are not tracked
+	s = NewSLOService(testSLOPolicy())
+	s.ObserveRoute("GET /api/users", time.Second, true)
+	if statuses := s.Status(); len(statuses) != 0 {
+		t.Errorf("statuses = %+v, want none", statuses)
+	}
+}
+
+func TestLatencyBucket(t *testing.T) {
+	last := len(domain.LatencyBoundsMS)
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{5 * time.Millisecond, 0}, // Bounds are inclusive
+		{5*time.Millisecond + 999*time.Microsecond, 0},
+		{6 * time.Millisecond, 1},
+		{10 * time.Millisecond, 1},
+		{11 * time.Millisecond, 2},
+		{time.Second, 7},
+		{10 * time.Second, last - 1},
+		{10*time.Second + time.Millisecond, last},
+		{time.Hour, last},
+	}
+	for _, tt := range tests {
+		if got := latencyBucket(tt.d); got != tt.want {
+			t.Errorf("latencyBucket(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSLOHeatmap(t *testing.T) {
+	observations := []sloObservation{
+		{at: -time.Minute, n: 1, latency: time.Millisecond},                       // Before the first row
+		{at: 0, n: 2, latency: 5 * time.Millisecond},                              // First row, first bucket
+		{at: 4*time.Minute + 59*time.Second, n: 1, latency: 6 * time.Millisecond}, // First row, second bucket
+		{at: 5 * time.Minute, n: 3, latency: time.Second},                         // Second row
+		{at: 12 * time.Minute, n: 1, latency: time.Minute},                        // Last row, overflow
+	}
+	s := observeSLO(testSLOPolicy(), observations, 12*time.Minute+30*time.Second)
+
+	heatmap, err := s.Heatmap(sloTestRoute, 15*time.Minute, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Heatmap: %v", err)
+	}
+	if heatmap.Route != sloTestRoute || heatmap.Step != 5*time.Minute || len(heatmap.Rows) != 3 {
+		t.Fatalf("heatmap = %+v, want 3 rows of 5 minutes", heatmap)
+	}
+	last := len(domain.LatencyBoundsMS)
+	buckets := func(counts map[int]int64) []int64 {
+		row := make([]int64, last+1)
+		for b, n := range counts {
+			row[b] = n
+		}
+		return row
+	}
+	want := []LatencyHeatmapRow{
+		{Start: sloTestStart, Counts: buckets(map[int]int64{0: 2, 1: 1})},
+		{Start: sloTestStart.Add(5 * time.Minute), Counts: buckets(map[int]int64{7: 3})},
+		{Start: sloTestStart.Add(10 * time.Minute), Counts: buckets(map[int]int64{last: 1})},
+	}
+	for i, row := range heatmap.Rows {
+		if !row.Start.Equal(want[i].Start) || !slices.Equal(row.Counts, want[i].Counts) {
+			t.Errorf("row %d = %v %v, want %v %v", i, row.Start, row.Counts, want[i].Start, want[i].Counts)
+		}
+	}
+
+	// A route with an objective but no requests yet has empty rows
+	policy := testSLOPolicy()
+	policy.Objectives[SLODefaultRoute] = SLOObjective{Target: 0.99}
+	heatmap, err = observeSLO(policy, nil, 0).Heatmap("GET /api/users", 2*time.Minute, time.Minute)
+	if err != nil || len(heatmap.Rows) != 2 || !slices.Equal(heatmap.Rows[1].Counts, buckets(nil)) ||
+		!heatmap.Rows[1].Start.Equal(sloTestStart) {
+		t.Errorf("heatmap of an unrequested route = %+v, %v; want 2 empty rows ending now", heatmap, err)
+	}
+}
+
+func TestSLOHeatmapValidation(t *testing.T) {
+	s := observeSLO(testSLOPolicy(), nil, 0)
+	tests := []struct {
+		name   string
+		route  string
+		window time.Duration
+		step   time.Duration
+	}{
+		{"no window", sloTestRoute, 0, time.Minute},
+		{"window past the history", sloTestRoute, time.Hour + time.Minute, time.Minute},
+		{"step under a minute", sloTestRoute, time.Hour, 30 * time.Second},
+		{"step not whole minutes", sloTestRoute, time.Hour, 90 * time.Second},
+		{"step past the window", sloTestRoute, 5 * time.Minute, 10 * time.Minute},
+		{"route without an objective", "GET /api/users", time.Hour, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Heatmap(tt.route, tt.window, tt.step); !errors.Is(err, domain.ErrInvalidInput) {
+				t.Errorf("Heatmap error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+	if _, err := s.Heatmap(sloTestRoute, time.Hour, time.Hour); err != nil {
+		t.Errorf("Heatmap of the whole history in one step: %v", err)
+	}
+}
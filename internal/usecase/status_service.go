@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Status indicators, from the last hour's availability
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusMajorOutage = "major_outage"
+)
+
+// StatusPolicy configures request stats collection and the status summary
+type StatusPolicy struct {
+	FlushInterval time.Duration // How often this instance's counts are merged into the shared store
+	CacheTTL      time.Duration // How long a summary is served before the store is read again
+	DegradedBelow float64       // Last-hour availability under which the API is degraded, e.g. 0.999
+	OutageBelow   float64       // Last-hour availability under which it is a major outage, e.g. 0.95
+}
+
+// DefaultStatusPolicy returns the defaults
+func DefaultStatusPolicy() StatusPolicy {
+	return StatusPolicy{
+		FlushInterval: 10 * time.Second,
+		CacheTTL:      30 * time.Second,
+		DegradedBelow: 0.999,
+		OutageBelow:   0.95,
+	}
+}
+
+// StatusWindow rolls up the requests of one window. Latencies are histogram
+// bucket bounds (see domain.LatencyBoundsMS), not exact values.
+type StatusWindow struct {
+	Requests     int64
+	Errors       int64
+	Availability float64 // Share of requests without a server error; 1 without requests
+	LatencyP50MS int64
+	LatencyP95MS int64
+	LatencyP99MS int64
+}
+
+// StatusSummary is the cluster-wide availability and latency of the API
+type StatusSummary struct {
+	Status    string // StatusOperational, StatusDegraded or StatusMajorOutage
+	UpdatedAt time.Time
+	LastHour  StatusWindow
+	Last24h   StatusWindow
+	Last7d    StatusWindow
+}
+
+// StatusService counts API requests per hour and summarises them for the
+// public status page. Each instance counts in memory and merges its counts
+// into the shared store every FlushInterval, so summaries cover every
+// instance and survive restarts, lagging by up to one interval.
+type StatusService struct {
+	store  domain.RequestStatsStore
+	policy StatusPolicy
+	logg   *logger.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[time.Time]*domain.RequestStats // Counts not yet flushed, by hour
+
+	cacheMu  sync.Mutex
+	cached   *StatusSummary
+	cachedAt time.Time
+}
+
+// NewStatusService creates a status service
+func NewStatusService(store domain.RequestStatsStore, policy StatusPolicy, logg *logger.Logger) *StatusService {
+	return &StatusService{
+		store:   store,
+		policy:  policy,
+		logg:    logg,
+		now:     time.Now,
+		pending: make(map[time.Time]*domain.RequestStats),
+	}
+}
+
+// Observe counts one request that took d; failed marks a server error
+func (s *StatusService) Observe(d time.Duration, failed bool) {
+	hour := s.now().UTC().Truncate(time.Hour)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.pending[hour]
+	if !ok {
+		stats = domain.NewRequestStats(hour)
+		s.pending[hour] = stats
+	}
+	stats.Observe(d, failed)
+}
+
+// Flush merges the pending counts into the store. Counts that fail to
+// flush are kept for the next attempt.
+func (s *StatusService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[time.Time]*domain.RequestStats)
+	s.mu.Unlock()
+
+	var firstErr error
+	for hour, stats := range pending {
+		if err := s.store.Add(ctx, stats); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to flush request stats: %w", err)
+			}
+			s.requeue(hour, stats)
+		}
+	}
+	return firstErr
+}
+
+// requeue puts unflushed counts back into pending
+func (s *StatusService) requeue(hour time.Time, stats *domain.RequestStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.pending[hour]; ok {
+		current.Add(stats)
+		return
+	}
+	s.pending[hour] = stats
+}
+
+// Run flushes every FlushInterval until ctx is cancelled. Call Flush once
+// more at shutdown to keep the last interval's counts.
+func (s *StatusService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.policy.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil {
+				s.logg.Warn("request stats not flushed, retrying next interval", "error", err)
+			}
+		}
+	}
+}
+
+// Summary returns the status summary, computed at most once per CacheTTL
+func (s *StatusService) Summary(ctx context.Context) (*StatusSummary, error) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	now := s.now().UTC()
+	if s.cached != nil && now.Sub(s.cachedAt) < s.policy.CacheTTL {
+		return s.cached, nil
+	}
+
+	hours, err := s.store.Range(ctx, now.Add(-7*24*time.Hour), now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+
+	summary := &StatusSummary{
+		UpdatedAt: now,
+		LastHour:  rollUp(hours, now.Add(-time.Hour)),
+		Last24h:   rollUp(hours, now.Add(-24*time.Hour)),
+		Last7d:    rollUp(hours, now.Add(-7*24*time.Hour)),
+	}
+	switch a := summary.LastHour.Availability; {
+	case a < s.policy.OutageBelow:
+		summary.Status = StatusMajorOutage
+	case a < s.policy.DegradedBelow:
+		summary.Status = StatusDegraded
+	default:
+		summary.Status = StatusOperational
+	}
+
+	s.cached, s.cachedAt = summary, now
+	return summary, nil
+}
+
+// rollUp sums the hours overlapping [since, now]
+func rollUp(hours []*domain.RequestStats, since time.Time) StatusWindow {
+	total := domain.NewRequestStats(since)
+	for _, h := range hours {
+		if !h.Hour.Before(since.Truncate(time.Hour)) {
+			total.Add(h)
+		}
+	}
+
+	window := StatusWindow{
+		Requests:     total.Requests,
+		Errors:       total.Errors,
+		Availability: 1,
+		LatencyP50MS: total.Percentile(0.50),
+		LatencyP95MS: total.Percentile(0.95),
+		LatencyP99MS: total.Percentile(0.99),
+	}
+	if total.Requests > 0 {
+		window.Availability = 1 - float64(total.Errors)/float64(total.Requests)
+	}
+	return window
+}
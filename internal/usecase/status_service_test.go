@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// statusHour is a stored hour of requests
+type statusHour struct {
+	ago     time.Duration // Before the hour holding now
+	latency map[time.Duration]int
+	errors  int // Of the requests, counted first
+}
+
+// statusFixture returns a status service over a store holding hours, whose
+// clock reads now, half past an hour
+func statusFixture(t *testing.T, policy StatusPolicy, hours []statusHour) (*StatusService, time.Time) {
+	t.Helper()
+	// The memory store expires hours by the wall clock
+	now := time.Now().UTC().Truncate(time.Hour).Add(30 * time.Minute)
+	store := memory.NewRequestStatsStore()
+	for _, h := range hours {
+		stats := domain.NewRequestStats(now.Add(-h.ago))
+		failed := h.errors
+		for d, n := range h.latency {
+			for range n {
+				stats.Observe(d, failed > 0)
+				failed--
+			}
+		}
+		if err := store.Add(context.Background(), stats); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	s := NewStatusService(store, policy, logger.New("error"))
+	s.now = func() time.Time { return now }
+	return s, now
+}
+
+func TestStatusSummaryWindows(t *testing.T) {
+	ms := time.Millisecond
+	s, now := statusFixture(t, DefaultStatusPolicy(), []statusHour{
+		// This hour: 50% within 5ms, up to 95% within 100ms, 99% within 1s
+		{ago: 0, latency: map[time.Duration]int{ms: 50, 100 * ms: 45, time.Second: 4, time.Minute: 1}},
+		// The previous hour overlaps the last hour
+		{ago: time.Hour, latency: map[time.Duration]int{time.Second: 100}, errors: 10},
+		// In the last 24h only
+		{ago: 3 * time.Hour, latency: map[time.Duration]int{ms: 800}},
+		// The hour holding now-24h overlaps the last 24h, the one before it
+		// does not
+		{ago: 24 * time.Hour, latency: map[time.Duration]int{ms: 100}},
+		{ago: 25 * time.Hour, latency: map[time.Duration]int{ms: 1000}, errors: 1000},
+		// In the last 7d only
+		{ago: 6 * 24 * time.Hour, latency: map[time.Duration]int{time.Minute: 9000}, errors: 9000},
+	})
+
+	summary, err := s.Summary(context.Background())
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	if !summary.UpdatedAt.Equal(now) {
+		t.Errorf("UpdatedAt = %v, want %v", summary.UpdatedAt, now)
+	}
+
+	tests := []struct {
+		name string
+		got  StatusWindow
+		want StatusWindow
+	}{
+		{"last hour", summary.LastHour, StatusWindow{
+			Requests: 200, Errors: 10, Availability: 1 - 10.0/200,
+			LatencyP50MS: 1000, LatencyP95MS: 1000, LatencyP99MS: 1000,
+		}},
+		{"last 24h", summary.Last24h, StatusWindow{
+			Requests: 1100, Errors: 10, Availability: 1 - 10.0/1100,
+			LatencyP50MS: 5, LatencyP95MS: 1000, LatencyP99MS: 1000,
+		}},
+		{"last 7d", summary.Last7d, StatusWindow{
+			Requests: 11100, Errors: 10010, Availability: 1 - 10010.0/11100,
+			// The overflow bucket reports the largest bound
+			LatencyP50MS: 10000, LatencyP95MS: 10000, LatencyP99MS: 10000,
+		}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestStatusSummaryPercentiles(t *testing.T) {
+	ms := time.Millisecond
+	s, _ := statusFixture(t, DefaultStatusPolicy(), []statusHour{
+		{ago: 0, latency: map[time.Duration]int{ms: 50, 100 * ms: 45, time.Second: 4, time.Minute: 1}},
+	})
+	summary, err := s.Summary(context.Background())
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	// Each percentile is the bound of the bucket holding its rank: the
+	// 50th request is within 5ms, the 95th within 100ms, the 99th within 1s
+	got := summary.LastHour
+	if got.LatencyP50MS != 5 || got.LatencyP95MS != 100 || got.LatencyP99MS != 1000 {
+		t.Errorf("percentiles = %d/%d/%d ms, want 5/100/1000", got.LatencyP50MS, got.LatencyP95MS, got.LatencyP99MS)
+	}
+}
+
+func TestStatusSummaryEmpty(t *testing.T) {
+	s, _ := statusFixture(t, DefaultStatusPolicy(), nil)
+	summary, err := s.Summary(context.Background())
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	empty := StatusWindow{Availability: 1}
+	for name, got := range map[string]StatusWindow{"last hour": summary.LastHour, "last 24h": summary.Last24h, "last 7d": summary.Last7d} {
+		if got != empty {
+			t.Errorf("%s = %+v, want no requests and full availability", name, got)
+		}
+	}
+	if summary.Status != StatusOperational {
+		t.Errorf("status = %q, want %s without requests", summary.Status, StatusOperational)
+	}
+}
+
+func TestStatusSummaryThresholds(t *testing.T) {
+	// Thresholds a float states exactly, for the edges
+	policy := DefaultStatusPolicy()
+	policy.DegradedBelow, policy.OutageBelow = 0.75, 0.5
+
+	tests := []struct {
+		name   string
+		errors int // Of 8 requests in the last hour
+		ago    time.Duration
+		want   string
+	}{
+		{"no errors", 0, 0, StatusOperational},
+		{"at the degraded threshold", 2, 0, StatusOperational},
+		{"under the degraded threshold", 3, 0, StatusDegraded},
+		{"at the outage threshold", 4, 0, StatusDegraded},
+		{"under the outage threshold", 5, 0, StatusMajorOutage},
+		{"outage in the previous hour", 8, time.Hour, StatusMajorOutage},
+		{"outage before the last hour", 8, 2 * time.Hour, StatusOperational},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := statusFixture(t, policy, []statusHour{
+				{ago: tt.ago, latency: map[time.Duration]int{time.Millisecond: 8}, errors: tt.errors},
+			})
+			summary, err := s.Summary(context.Background())
+			if err != nil {
+				t.Fatalf("Summary: %v", err)
+			}
+			if summary.Status != tt.want {
+				t.Errorf("status at %v availability = %q, want %q", summary.LastHour.Availability, summary.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusSummaryCache(t *testing.T) {
+	policy := DefaultStatusPolicy()
+	s, now := statusFixture(t, policy, nil)
+	ctx := context.Background()
+
+	if _, err := s.Summary(ctx); err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	s.Observe(time.Millisecond, true)
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	summary, _ := s.Summary(ctx)
+	if summary.LastHour.Requests != 0 {
+		t.Errorf("summary within the cache TTL = %+v, want the cached one", summary.LastHour)
+	}
+	s.now = func() time.Time { return now.Add(policy.CacheTTL) }
+	summary, _ = s.Summary(ctx)
+	if summary.LastHour.Requests != 1 || summary.LastHour.Errors != 1 {
+		t.Errorf("summary after the cache TTL = %+v, want the flushed request", summary.LastHour)
+	}
+}
+
+// failingStatsStore fails every Add while fail is set
+type failingStatsStore struct {
+	domain.RequestStatsStore
+	fail bool
+}
+
+func (s *failingStatsStore) Add(ctx context.Context, stats *domain.RequestStats) error {
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	return s.RequestStatsStore.Add(ctx, stats)
+}
+
+func TestStatusFlushRequeues(t *testing.T) {
+	store := &failingStatsStore{RequestStatsStore: memory.NewRequestStatsStore(), fail: true}
+	s := NewStatusService(store, DefaultStatusPolicy(), logger.New("error"))
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s.Observe(time.Millisecond, false)
+	if err := s.Flush(ctx); err == nil {
+		t.Fatal("Flush succeeded against a failing store")
+	}
+	// Counted between the failed flush and the next are merged with it
+	s.Observe(time.Second, true)
+	store.fail = false
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	hours, _ := store.Range(ctx, now, now)
+	if len(hours) != 1 || hours[0].Requests != 2 || hours[0].Errors != 1 {
+		t.Errorf("stored hours = %+v, want both requests once", hours)
+	}
+	// Nothing is left to flush twice
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if hours, _ = store.Range(ctx, now, now); hours[0].Requests != 2 {
+		t.Errorf("stored requests after another flush = %d, want 2", hours[0].Requests)
+	}
+}
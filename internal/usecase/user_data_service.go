@@ -0,0 +1,285 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+const (
+	// JobTypeUserImport creates the users of a bulk import
+	JobTypeUserImport = "users.import"
+	// JobTypeUserDataExport gathers everything stored about a user (GDPR
+	// Article 15 access requests)
+	JobTypeUserDataExport = "users.data_export"
+)
+
+// UserDataPolicy configures background user imports and data exports
+type UserDataPolicy struct {
+	BlobPrefix  string // Key prefix for job outputs
+	ImportChunk int    // Users inserted per transaction during an import
+}
+
+// DefaultUserDataPolicy returns sensible defaults
+func DefaultUserDataPolicy() UserDataPolicy {
+	return UserDataPolicy{
+		BlobPrefix:  "user-data/",
+		ImportChunk: 100,
+	}
+}
+
+// UserDataService runs user operations too long for one request as
+// background jobs: bulk imports and personal data exports. Callers get the
+// job to poll (see JobRunner.Get); each job stores its output as JSON in the
+// blob store, read back with OpenResult once the job has succeeded.
+type UserDataService struct {
+	users  *UserService
+	orders domain.OrderRepository
+	blobs  blob.Store
+	jobs   *JobRunner
+	policy UserDataPolicy
+	logg   *logger.Logger
+}
+
+// NewUserDataService creates a user data service. Zero policy fields use the defaults.
+func NewUserDataService(users *UserService, orders domain.OrderRepository, blobs blob.Store, jobs *JobRunner, policy UserDataPolicy, logg *logger.Logger) *UserDataService {
+	defaults := DefaultUserDataPolicy()
+	if policy.BlobPrefix == "" {
+		policy.BlobPrefix = defaults.BlobPrefix
+	}
+	if policy.ImportChunk <= 0 {
+		policy.ImportChunk = defaults.ImportChunk
+	}
+	return &UserDataService{
+		users:  users,
+		orders: orders,
+		blobs:  blobs,
+		jobs:   jobs,
+		policy: policy,
+		logg:   logg,
+	}
+}
+
+// Register adds the service's job handlers to its job runner
+func (s *UserDataService) Register() {
+	s.jobs.Handle(JobTypeUserImport, s.Import)
+	s.jobs.Handle(JobTypeUserDataExport, s.ExportData)
+}
+
+// userImportJob is the payload of a JobTypeUserImport job
+type userImportJob struct {
+	Users []UserInput `json:"users"`
+}
+
+// userDataExportJob is the payload of a JobTypeUserDataExport job
+type userDataExportJob struct {
+	UserID string `json:"user_id"`
+}
+
+// StartImport queues the creation of users for owner
+func (s *UserDataService) StartImport(ctx context.Context, owner string, inputs []UserInput) (*domain.Job, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: no users to import", domain.ErrInvalidInput)
+	}
+	job, err := s.jobs.Enqueue(ctx, JobTypeUserImport, domain.JobPriorityBulk,
+		userImportJob{Users: inputs}, WithJobOwner(owner))
+	if err != nil {
+		return nil, err
+	}
+	s.logg.Info("user import queued", "job_id", job.ID, "users", len(inputs))
+	return job, nil
+}
+
+// StartDataExport queues the export of everything stored about userID, for
+// owner to download
+func (s *UserDataService) StartDataExport(ctx context.Context, owner, userID string) (*domain.Job, error) {
+	// Fail now rather than in the job when there is nobody to export
+	if _, err := s.users.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	job, err := s.jobs.Enqueue(ctx, JobTypeUserDataExport, domain.JobPriorityBulk,
+		userDataExportJob{UserID: userID}, WithJobOwner(owner))
+	if err != nil {
+		return nil, err
+	}
+	s.logg.Info("user data export queued", "job_id", job.ID, "user_id", userID)
+	return job, nil
+}
+
+// importOutcome is the output of an import for one user, at the index it
+// had in the import
+type importOutcome struct {
+	Index  int    `json:"index"`
+	UserID string `json:"user_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Import is the JobFunc for JobTypeUserImport. Users are created a chunk at
+// a time, so a retried import reports the users an earlier attempt created
+// as already existing rather than creating them twice.
+func (s *UserDataService) Import(ctx context.Context, run *JobRun) error {
+	var payload userImportJob
+	if err := json.Unmarshal(run.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+
+	outcomes := make([]importOutcome, 0, len(payload.Users))
+	created := 0
+	for start := 0; start < len(payload.Users); start += s.policy.ImportChunk {
+		chunk := payload.Users[start:min(start+s.policy.ImportChunk, len(payload.Users))]
+		results, err := s.users.CreateUsers(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			outcome := importOutcome{Index: start + i}
+			if result.Err != nil {
+				outcome.Error = result.Err.Error()
+			} else {
+				outcome.UserID = result.User.ID
+				created++
+			}
+			outcomes = append(outcomes, outcome)
+		}
+		// The upload below is the last tenth
+		run.Progress(ctx, len(outcomes)*90/len(payload.Users))
+	}
+
+	if err := s.storeResult(ctx, run, "imports/"+run.ID+".json", map[string]any{
+		"created":  created,
+		"failed":   len(outcomes) - created,
+		"outcomes": outcomes,
+	}); err != nil {
+		return err
+	}
+
+	s.logg.Info("user import finished", "job_id", run.ID, "users", len(outcomes), "created", created)
+	return nil
+}
+
+// userDataExportPage is how many orders a data export reads at once
+const userDataExportPage = 500
+
+// userDataExport is the output of a data export: a stable, documented shape
+// rather than whatever the domain types look like today
+type userDataExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	User       exportedUser    `json:"user"`
+	Orders     []exportedOrder `json:"orders"`
+}
+
+type exportedUser struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type exportedOrder struct {
+	ID          string              `json:"id"`
+	Status      string              `json:"status"`
+	Amount      float64             `json:"amount"`
+	Items       []exportedOrderItem `json:"items"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	CancelledAt *time.Time          `json:"cancelled_at,omitempty"`
+}
+
+type exportedOrderItem struct {
+	ProductID string  `json:"product_id"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+// ExportData is the JobFunc for JobTypeUserDataExport
+func (s *UserDataService) ExportData(ctx context.Context, run *JobRun) error {
+	var payload userDataExportJob
+	if err := json.Unmarshal(run.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+
+	user, err := s.users.GetUserByID(ctx, payload.UserID)
+	if err != nil {
+		return err
+	}
+	run.Progress(ctx, 10)
+
+	var orders []*domain.Order
+	for offset := 0; ; offset += userDataExportPage {
+		page, err := s.orders.GetByUserID(ctx, user.ID, userDataExportPage, offset)
+		if err != nil {
+			return err
+		}
+		orders = append(orders, page...)
+		if len(page) < userDataExportPage {
+			break
+		}
+	}
+	run.Progress(ctx, 70)
+
+	export := userDataExport{
+		ExportedAt: time.Now().UTC(),
+		User: exportedUser{
+			ID: user.ID, Name: user.Name, Email: user.Email,
+			CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt,
+		},
+		Orders: make([]exportedOrder, len(orders)),
+	}
+	for i, o := range orders {
+		items := make([]exportedOrderItem, len(o.Items))
+		for j, item := range o.Items {
+			items[j] = exportedOrderItem{ProductID: item.ProductID, Quantity: item.Quantity, Price: item.Price}
+		}
+		export.Orders[i] = exportedOrder{
+			ID: o.ID, Status: string(o.Status), Amount: o.Amount, Items: items,
+			CreatedAt: o.CreatedAt, UpdatedAt: o.UpdatedAt, CancelledAt: o.CancelledAt,
+		}
+	}
+	if err := s.storeResult(ctx, run, "exports/"+user.ID+"/"+run.ID+".json", export); err != nil {
+		return err
+	}
+
+	s.logg.Info("user data exported", "job_id", run.ID, "user_id", user.ID, "orders", len(orders))
+	return nil
+}
+
+// storeResult uploads the output of run as JSON under name and records its key
+func (s *UserDataService) storeResult(ctx context.Context, run *JobRun, name string, output any) error {
+	body, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInternalError, err)
+	}
+	key := s.policy.BlobPrefix + name
+	if _, err := s.blobs.Upload(ctx, &blob.UploadInput{
+		Key:         key,
+		Body:        bytes.NewReader(body),
+		ContentType: "application/json",
+		Metadata:    map[string]string{"job-id": run.ID, "job-type": run.Type},
+	}); err != nil {
+		return err
+	}
+	run.SetResult(key)
+	return nil
+}
+
+// OpenResult opens the output of a job run by this service: ErrJobNotFound
+// for other jobs, ErrJobNotFinished until the job has succeeded. The caller
+// closes the reader.
+func (s *UserDataService) OpenResult(ctx context.Context, record *domain.JobRecord) (io.ReadCloser, error) {
+	if record.Type != JobTypeUserImport && record.Type != JobTypeUserDataExport {
+		return nil, domain.ErrJobNotFound
+	}
+	if record.State != domain.JobSucceeded || !strings.HasPrefix(record.Result, s.policy.BlobPrefix) {
+		return nil, domain.ErrJobNotFinished
+	}
+	return s.blobs.GetObject(ctx, record.Result)
+}
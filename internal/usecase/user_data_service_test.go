@@ -0,0 +1,274 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
+	"github.com/TopThisHat/stdlib-golang-api/internal/memory"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// progressRecords records the progress of every job status update
+type progressRecords struct {
+	domain.JobRecordRepository
+
+	mu       sync.Mutex
+	progress []int
+}
+
+func (r *progressRecords) Update(ctx context.Context, record *domain.JobRecord) error {
+	r.mu.Lock()
+	r.progress = append(r.progress, record.Progress)
+	r.mu.Unlock()
+	return r.JobRecordRepository.Update(ctx, record)
+}
+
+// userDataFixture is a user data service over the memory repositories,
+// with one user (u1) holding two orders
+type userDataFixture struct {
+	svc     *UserDataService
+	jobs    *JobRunner
+	users   domain.UserRepository
+	blobs   *blob.MemoryStore
+	records *progressRecords
+}
+
+func newUserDataFixture(t *testing.T, policy UserDataPolicy) *userDataFixture {
+	t.Helper()
+	ctx := context.Background()
+	logg := logger.New("error")
+	f := &userDataFixture{
+		users:   memory.NewUserRepository(),
+		blobs:   blob.NewMemoryStore(),
+		records: &progressRecords{JobRecordRepository: memory.NewJobRecordRepository()},
+	}
+	user, err := domain.NewUser("u1", "Ada", "ada@example.com")
+	if err != nil {
+		t.Fatalf("NewUser: %v", err)
+	}
+	if err := f.users.Create(ctx, user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	orders := memory.NewOrderRepository()
+	for _, id := range []string{"o1", "o2"} {
+		order, err := domain.NewOrder(id, "u1", []domain.OrderItem{{ProductID: "p1", Quantity: 2, Price: 5}})
+		if err != nil {
+			t.Fatalf("NewOrder: %v", err)
+		}
+		if err := orders.Create(ctx, order); err != nil {
+			t.Fatalf("Create order: %v", err)
+		}
+	}
+
+	f.jobs = NewJobRunner(memory.NewJobQueue(), f.records, testJobPolicy(), logg)
+	f.svc = NewUserDataService(NewUserService(f.users, memory.NewUserCache(), logg), orders, f.blobs, f.jobs, policy, logg)
+	f.svc.Register()
+	return f
+}
+
+// finish waits for job id to succeed and returns its record
+func (f *userDataFixture) finish(t *testing.T, id string) *domain.JobRecord {
+	t.Helper()
+	var record *domain.JobRecord
+	eventually(t, "the job to finish", func() bool {
+		record, _ = f.jobs.Get(context.Background(), id)
+		return record != nil && record.State != domain.JobQueued && record.State != domain.JobRunning
+	})
+	if record.State != domain.JobSucceeded {
+		t.Fatalf("job = %s %q, want succeeded", record.State, record.Error)
+	}
+	return record
+}
+
+// result decodes the output of a finished job into v
+func (f *userDataFixture) result(t *testing.T, record *domain.JobRecord, v any) {
+	t.Helper()
+	body, err := f.svc.OpenResult(context.Background(), record)
+	if err != nil {
+		t.Fatalf("OpenResult: %v", err)
+	}
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		t.Fatalf("decoding the result: %v", err)
+	}
+}
+
+func TestUserDataImport(t *testing.T) {
+	f := newUserDataFixture(t, UserDataPolicy{ImportChunk: 2})
+	defer startRunner(t, f.jobs)()
+	ctx := context.Background()
+
+	job, err := f.svc.StartImport(ctx, "admin-1", []UserInput{
+		{Name: "Grace", Email: "grace@example.com"},
+		{Name: "Taken", Email: "ada@example.com"},
+		{Name: "Invalid", Email: "not-an-email"},
+		// Duplicates within the import fail after the first, even across chunks
+		{Name: "Alan", Email: "alan@example.com"},
+		{Name: "Alan again", Email: "alan@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	if job.Type != JobTypeUserImport || job.Priority != domain.JobPriorityBulk || job.UserID != "admin-1" {
+		t.Errorf("job = %s %s for %q, want a bulk import for admin-1", job.Type, job.Priority, job.UserID)
+	}
+
+	record := f.finish(t, job.ID)
+	if want := "user-data/imports/" + job.ID + ".json"; record.Result != want {
+		t.Errorf("result key = %q, want %q", record.Result, want)
+	}
+	var got struct {
+		Created  int `json:"created"`
+		Failed   int `json:"failed"`
+		Outcomes []struct {
+			Index  int    `json:"index"`
+			UserID string `json:"user_id"`
+			Error  string `json:"error"`
+		} `json:"outcomes"`
+	}
+	f.result(t, record, &got)
+	if got.Created != 2 || got.Failed != 3 || len(got.Outcomes) != 5 {
+		t.Fatalf("result = %+v, want 2 created and 3 failed", got)
+	}
+	wantErrs := []error{nil, domain.ErrUserAlreadyExists, domain.ErrInvalidUserEmail, nil, domain.ErrUserAlreadyExists}
+	for i, outcome := range got.Outcomes {
+		if outcome.Index != i {
+			t.Errorf("outcome %d has index %d", i, outcome.Index)
+		}
+		if wantErrs[i] == nil {
+			if _, err := f.users.GetByID(ctx, outcome.UserID); outcome.Error != "" || err != nil {
+				t.Errorf("outcome %d = %+v, want a created user (lookup: %v)", i, outcome, err)
+			}
+		} else if outcome.UserID != "" || outcome.Error != wantErrs[i].Error() {
+			t.Errorf("outcome %d = %+v, want %q", i, outcome, wantErrs[i])
+		}
+	}
+
+	// Progress is recorded per chunk, the upload being the last tenth
+	f.records.mu.Lock()
+	progress := slices.Compact(slices.Clone(f.records.progress))
+	f.records.mu.Unlock()
+	if want := []int{0, 36, 72, 90, 100}; !slices.Equal(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+
+	// The artifact is stored as JSON, tagged with its job
+	info, err := f.blobs.HeadObject(ctx, record.Result)
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.ContentType != "application/json" || info.Metadata["job-id"] != job.ID || info.Metadata["job-type"] != JobTypeUserImport {
+		t.Errorf("stored result = %s %v, want JSON tagged with the job", info.ContentType, info.Metadata)
+	}
+}
+
+func TestUserDataImportValidation(t *testing.T) {
+	f := newUserDataFixture(t, UserDataPolicy{})
+	if _, err := f.svc.StartImport(context.Background(), "admin-1", nil); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("StartImport without users = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestUserDataExport(t *testing.T) {
+	f := newUserDataFixture(t, UserDataPolicy{BlobPrefix: "gdpr/"})
+	defer startRunner(t, f.jobs)()
+	ctx := context.Background()
+
+	job, err := f.svc.StartDataExport(ctx, "u1", "u1")
+	if err != nil {
+		t.Fatalf("StartDataExport: %v", err)
+	}
+	if job.Type != JobTypeUserDataExport || job.UserID != "u1" {
+		t.Errorf("job = %s for %q, want an export for u1", job.Type, job.UserID)
+	}
+
+	record := f.finish(t, job.ID)
+	if want := "gdpr/exports/u1/" + job.ID + ".json"; record.Result != want {
+		t.Errorf("result key = %q, want %q", record.Result, want)
+	}
+	var got struct {
+		ExportedAt string `json:"exported_at"`
+		User       struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"user"`
+		Orders []struct {
+			ID     string  `json:"id"`
+			Status string  `json:"status"`
+			Amount float64 `json:"amount"`
+			Items  []struct {
+				ProductID string  `json:"product_id"`
+				Quantity  int     `json:"quantity"`
+				Price     float64 `json:"price"`
+			} `json:"items"`
+		} `json:"orders"`
+	}
+	f.result(t, record, &got)
+	if got.ExportedAt == "" || got.User.ID != "u1" || got.User.Name != "Ada" || got.User.Email != "ada@example.com" {
+		t.Errorf("exported user = %+v at %q, want u1", got.User, got.ExportedAt)
+	}
+	ids := make([]string, len(got.Orders))
+	for i, o := range got.Orders {
+		ids[i] = o.ID
+		if o.Status != string(domain.OrderStatusPending) || o.Amount != 10 || len(o.Items) != 1 ||
+			o.Items[0].ProductID != "p1" || o.Items[0].Quantity != 2 || o.Items[0].Price != 5 {
+			t.Errorf("exported order = %+v, want a pending order of two p1 at 5", o)
+		}
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"o1", "o2"}) {
+		t.Errorf("exported orders = %v, want o1 and o2", ids)
+	}
+}
+
+func TestUserDataExportMissingUser(t *testing.T) {
+	f := newUserDataFixture(t, UserDataPolicy{})
+	if _, err := f.svc.StartDataExport(context.Background(), "admin-1", "nobody"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("StartDataExport for a missing user = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestUserDataOpenResult(t *testing.T) {
+	f := newUserDataFixture(t, UserDataPolicy{})
+	ctx := context.Background()
+	if _, err := f.blobs.Upload(ctx, &blob.UploadInput{Key: "user-data/imports/j1.json", Body: strings.NewReader("{}")}); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		record  domain.JobRecord
+		wantErr error
+	}{
+		{"succeeded", domain.JobRecord{Type: JobTypeUserImport, State: domain.JobSucceeded, Result: "user-data/imports/j1.json"}, nil},
+		{"another job type", domain.JobRecord{Type: "reports.run", State: domain.JobSucceeded, Result: "user-data/imports/j1.json"}, domain.ErrJobNotFound},
+		{"queued", domain.JobRecord{Type: JobTypeUserImport, State: domain.JobQueued}, domain.ErrJobNotFinished},
+		{"running", domain.JobRecord{Type: JobTypeUserDataExport, State: domain.JobRunning}, domain.ErrJobNotFinished},
+		{"failed", domain.JobRecord{Type: JobTypeUserImport, State: domain.JobFailed, Error: "boom"}, domain.ErrJobNotFinished},
+		{"result outside the prefix", domain.JobRecord{Type: JobTypeUserImport, State: domain.JobSucceeded, Result: "reports/j1.json"}, domain.ErrJobNotFinished},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := f.svc.OpenResult(ctx, &tt.record)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("OpenResult = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				data, _ := io.ReadAll(body)
+				body.Close()
+				if string(data) != "{}" {
+					t.Errorf("result = %q, want the stored object", data)
+				}
+			}
+		})
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 
 	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
 	"github.com/google/uuid"
 )
 
@@ -62,6 +62,62 @@ func (s *UserService) CreateUser(ctx context.Context, name, email string) (*doma
 	return user, nil
 }
 
+// UserInput holds the fields of one user to create
+type UserInput struct {
+	Name  string
+	Email string
+}
+
+// UserCreation is the outcome of one user of CreateUsers: the created user,
+// or the reason it was not created
+type UserCreation struct {
+	User *domain.User
+	Err  error
+}
+
+// CreateUsers creates several users at once
+// Business logic: Validates every user, then inserts the valid ones in a
+// single transaction. Each input gets its own outcome, in order; an email
+// taken by a stored user or an earlier input fails only that input. The
+// error is for the batch as a whole, which then creates no user.
+func (s *UserService) CreateUsers(ctx context.Context, inputs []UserInput) ([]UserCreation, error) {
+	results := make([]UserCreation, len(inputs))
+
+	var valid []*domain.User
+	var positions []int
+	for i, input := range inputs {
+		user, err := domain.NewUser(uuid.New().String(), input.Name, input.Email)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, user)
+		positions = append(positions, i)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	errs, err := s.userRepo.CreateMany(ctx, valid)
+	if err != nil {
+		s.logg.Error("failed to create users", "error", err, "count", len(valid))
+		return nil, err
+	}
+
+	created := 0
+	for j, user := range valid {
+		if errs[j] != nil {
+			results[positions[j]].Err = errs[j]
+			continue
+		}
+		results[positions[j]].User = user
+		created++
+	}
+
+	s.logg.Info("users created in bulk", "requested", len(inputs), "created", created)
+	return results, nil
+}
+
 // GetUserByID retrieves a user by ID
 // Uses cache-aside pattern: check cache first, then database
 func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
@@ -94,6 +150,22 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.User,
 	return user, nil
 }
 
+// GetUsersByIDs retrieves several users in one repository call, for batch
+// loading (e.g. the owners of a page of orders); unknown IDs are skipped
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		s.logg.Error("failed to get users by ids", "error", err, "count", len(ids))
+		return nil, err
+	}
+
+	return users, nil
+}
+
 // GetUserByEmail retrieves a user by email
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	if email == "" {
@@ -109,24 +181,41 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*domain
 }
 
 // UpdateUser updates a user's information
-// Business logic: Validates changes, ensures email uniqueness if changed
+// Business logic: Empty values leave the field unchanged
 func (s *UserService) UpdateUser(ctx context.Context, id, name, email string) (*domain.User, error) {
+	var patch domain.UserPatch
+	if name != "" {
+		patch.Name = &name
+	}
+	if email != "" {
+		patch.Email = &email
+	}
+	return s.PatchUser(ctx, id, patch)
+}
+
+// PatchUser applies a partial update to a user
+// Business logic: Validates changes, ensures email uniqueness if changed
+func (s *UserService) PatchUser(ctx context.Context, id string, patch domain.UserPatch) (*domain.User, error) {
 	// Retrieve existing user
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(ctx, user.Version); err != nil {
+		return nil, err
+	}
 
 	// Update name if provided
-	if name != "" && name != user.Name {
-		if err := user.UpdateName(name); err != nil {
+	if patch.Name != nil && *patch.Name != user.Name {
+		if err := user.UpdateName(*patch.Name); err != nil {
 			s.logg.Warn("invalid name update", "error", err, "user_id", id)
 			return nil, err
 		}
 	}
 
 	// Update email if provided and different
-	if email != "" && email != user.Email {
+	if patch.Email != nil && *patch.Email != user.Email {
+		email := *patch.Email
 		// Business rule: Check if new email already exists
 		existingUser, err := s.userRepo.GetByEmail(ctx, email)
 		if err != nil && err != domain.ErrUserNotFound {
@@ -170,15 +259,19 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 	}
 
 	// Verify user exists
-	_, err := s.userRepo.GetByID(ctx, id)
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if err := checkVersion(ctx, user.Version); err != nil {
+		return err
+	}
 
 	// Business rule: Add any pre-deletion checks here
 	// For example: check if user has active orders, subscriptions, etc.
 
-	if err := s.userRepo.Delete(ctx, id); err != nil {
+	// The version guards against an update landing between the read and the delete
+	if err := s.userRepo.Delete(ctx, id, user.Version); err != nil {
 		s.logg.Error("failed to delete user", "error", err, "user_id", id)
 		return err
 	}
@@ -213,3 +306,55 @@ func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*doma
 
 	return users, nil
 }
+
+// SearchUsers retrieves a page of users narrowed and ordered by q, newest
+// first when q has no sort keys
+func (s *UserService) SearchUsers(ctx context.Context, q domain.ListQuery, limit, offset int) ([]*domain.User, error) {
+	// Business rule: Set reasonable pagination limits
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	users, err := s.userRepo.Search(ctx, q, limit, offset)
+	if err != nil {
+		s.logg.Error("failed to search users", "error", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CountUsers counts the users matching every filter, for pagers
+func (s *UserService) CountUsers(ctx context.Context, filters []domain.Filter) (int, error) {
+	n, err := s.userRepo.Count(ctx, filters)
+	if err != nil {
+		s.logg.Error("failed to count users", "error", err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// ListUsersPage retrieves one keyset page of users, with the cursors of the
+// pages around it
+func (s *UserService) ListUsersPage(ctx context.Context, page domain.KeysetPage) (*domain.Page[*domain.User], error) {
+	page = normalizeKeysetPage(page)
+	users, err := s.userRepo.ListKeyset(ctx, page)
+	if err != nil {
+		s.logg.Error("failed to list users", "error", err)
+		return nil, err
+	}
+
+	return keysetResult(users, page, func(u *domain.User) domain.Cursor {
+		return domain.Cursor{CreatedAt: u.CreatedAt, ID: u.ID}
+	}), nil
+}
+
+// StreamUsers passes users to fn in ListUsers order as they are read, for
+// exports too large to page through; a limit of 0 streams every user
+func (s *UserService) StreamUsers(ctx context.Context, limit, offset int, fn func(*domain.User) error) error {
+	return s.userRepo.Stream(ctx, max(limit, 0), max(offset, 0), fn)
+}
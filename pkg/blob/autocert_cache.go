@@ -0,0 +1,70 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Ensure AutocertCache implements autocert.Cache at compile time
+var _ autocert.Cache = (*AutocertCache)(nil)
+
+// AutocertCache stores ACME certificates and account keys in a blob store so
+// every instance behind a load balancer shares the same certificates instead
+// of each requesting its own (and hitting CA rate limits).
+//
+// Entries include private keys: restrict access to the prefix accordingly.
+type AutocertCache struct {
+	store  Store
+	prefix string
+}
+
+// NewAutocertCache creates an autocert cache under prefix (e.g. "acme/")
+func NewAutocertCache(store Store, prefix string) *AutocertCache {
+	return &AutocertCache{store: store, prefix: prefix}
+}
+
+func (c *AutocertCache) key(name string) string {
+	return path.Join(c.prefix, name)
+}
+
+// Get returns the cached data for name, or autocert.ErrCacheMiss
+func (c *AutocertCache) Get(ctx context.Context, name string) ([]byte, error) {
+	rc, err := c.store.GetObject(ctx, c.key(name))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, autocert.ErrCacheMiss
+		}
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	return data, nil
+}
+
+// Put stores data for name
+func (c *AutocertCache) Put(ctx context.Context, name string, data []byte) error {
+	_, err := c.store.Upload(ctx, &UploadInput{
+		Key:         c.key(name),
+		Body:        bytes.NewReader(data),
+		ContentType: "application/x-pem-file",
+	})
+	return err
+}
+
+// Delete removes name; missing entries are not an error
+func (c *AutocertCache) Delete(ctx context.Context, name string) error {
+	if err := c.store.Delete(ctx, c.key(name)); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
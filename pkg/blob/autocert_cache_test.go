@@ -0,0 +1,91 @@
+package blob_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestAutocertCache(t *testing.T) {
+	ctx := context.Background()
+	store := blob.NewMemoryStore()
+	cache := blob.NewAutocertCache(store, "acme/")
+
+	if _, err := cache.Get(ctx, "example.com"); !errors.Is(err, autocert.ErrCacheMiss) {
+		t.Fatalf("Get before Put = %v, want autocert.ErrCacheMiss", err)
+	}
+
+	if err := cache.Put(ctx, "example.com", []byte("cert v1")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, err := cache.Get(ctx, "example.com")
+	if err != nil || string(data) != "cert v1" {
+		t.Fatalf("Get = %q, %v, want the stored data", data, err)
+	}
+
+	// Entries are stored under the prefix as PEM files
+	info, err := store.HeadObject(ctx, "acme/example.com")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.ContentType != "application/x-pem-file" || info.Size != int64(len("cert v1")) {
+		t.Errorf("stored entry = %s of %d bytes, want a PEM file", info.ContentType, info.Size)
+	}
+
+	// A renewal replaces the entry
+	if err := cache.Put(ctx, "example.com", []byte("cert v2")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if data, _ := cache.Get(ctx, "example.com"); string(data) != "cert v2" {
+		t.Errorf("Get after renewal = %q, want cert v2", data)
+	}
+
+	if err := cache.Delete(ctx, "example.com"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := cache.Get(ctx, "example.com"); !errors.Is(err, autocert.ErrCacheMiss) {
+		t.Errorf("Get after Delete = %v, want autocert.ErrCacheMiss", err)
+	}
+	if exists, _ := store.Exists(ctx, "acme/example.com"); exists {
+		t.Error("entry still stored after Delete")
+	}
+	// Deleting a missing entry succeeds
+	if err := cache.Delete(ctx, "example.com"); err != nil {
+		t.Errorf("Delete of a missing entry = %v, want nil", err)
+	}
+}
+
+func TestAutocertCachePrefixes(t *testing.T) {
+	ctx := context.Background()
+	store := blob.NewMemoryStore()
+	staging := blob.NewAutocertCache(store, "acme/staging")
+	production := blob.NewAutocertCache(store, "acme/production/")
+
+	// The account key autocert stores alongside certificates
+	if err := staging.Put(ctx, "acme_account+key", []byte("staging key")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := production.Get(ctx, "acme_account+key"); !errors.Is(err, autocert.ErrCacheMiss) {
+		t.Errorf("Get under another prefix = %v, want autocert.ErrCacheMiss", err)
+	}
+	// With or without a trailing slash, the prefix is a directory
+	if exists, _ := store.Exists(ctx, "acme/staging/acme_account+key"); !exists {
+		t.Error("entry not stored under acme/staging/")
+	}
+}
+
+func TestAutocertCacheStoreErrors(t *testing.T) {
+	// Errors other than a missing entry are not reported as misses, so
+	// autocert doesn't request a new certificate for a store that is down
+	cache := blob.NewAutocertCache(blob.NewMemoryStore(), "")
+	_, err := cache.Get(context.Background(), "")
+	if err == nil || errors.Is(err, autocert.ErrCacheMiss) {
+		t.Errorf("Get with an invalid key = %v, want the store's error", err)
+	}
+	if err := cache.Delete(context.Background(), ""); !errors.Is(err, blob.ErrInvalidKey) {
+		t.Errorf("Delete with an invalid key = %v, want ErrInvalidKey", err)
+	}
+}
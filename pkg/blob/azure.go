@@ -0,0 +1,675 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Ensure AzureStore implements the interfaces at compile time
+var (
+	_ Store                 = (*AzureStore)(nil)
+	_ PresignedURLGenerator = (*AzureStore)(nil)
+	_ FullStore             = (*AzureStore)(nil)
+)
+
+// azureAccessTiers are the access tiers (UploadInput.StorageClass) uploads
+// can set, by lowercase name
+var azureAccessTiers = map[string]blob.AccessTier{"hot": blob.AccessTierHot, "cool": blob.AccessTierCool, "archive": blob.AccessTierArchive}
+
+// AzureStore provides operations for interacting with Azure Blob Storage,
+// through the Azure SDK with Shared Key authorization.
+// It implements the Store and PresignedURLGenerator interfaces; pre-signed
+// URLs are service SAS URLs. Azure has no POST policies, so AzureStore does
+// not implement PresignedPostGenerator.
+type AzureStore struct {
+	client     *container.Client
+	credential *container.SharedKeyCredential // Signs SAS URLs
+	container  string
+	https      bool // Whether the endpoint is https, so SAS URLs may require it
+	options    *azureOptions
+	logger     *logger.Logger
+
+	mu      sync.Mutex
+	markers map[string]string // Continuation marker after each listed key, for StartAfter
+}
+
+// AzureOption defines functional options for configuring AzureStore
+type AzureOption func(*azureOptions)
+
+type azureOptions struct {
+	httpClient *http.Client
+	blockSize  int64
+
+	// Retries of throttled and failed requests
+	retryMaxAttempts int
+	retryMaxBackoff  time.Duration
+
+	// Request ID of the caller, sent with every request (nil sends none)
+	requestID func(context.Context) string
+}
+
+// defaultAzureOptions returns sensible defaults for Azure operations
+func defaultAzureOptions() *azureOptions {
+	return &azureOptions{
+		httpClient:       &http.Client{Timeout: 5 * time.Minute},
+		blockSize:        8 * 1024 * 1024, // 8 MB
+		retryMaxAttempts: 3,
+		retryMaxBackoff:  20 * time.Second,
+	}
+}
+
+// WithAzureHTTPClient sets the HTTP client requests are sent with
+func WithAzureHTTPClient(client *http.Client) AzureOption {
+	return func(o *azureOptions) {
+		if client != nil {
+			o.httpClient = client
+		}
+	}
+}
+
+// WithAzureBlockSize sets the size of the blocks larger uploads are staged
+// in (from 1 MB to 4000 MB); smaller uploads are sent in one request
+func WithAzureBlockSize(size int64) AzureOption {
+	return func(o *azureOptions) {
+		if size >= 1024*1024 && size <= 4000*1024*1024 {
+			o.blockSize = size
+		}
+	}
+}
+
+// WithAzureRetry sets how many times requests are tried, the first
+// included, and the longest backoff between tries. Throttling (429, 503),
+// server errors and dropped connections are retried with jittered
+// exponential backoff; maxAttempts of 1 disables retries.
+func WithAzureRetry(maxAttempts int, maxBackoff time.Duration) AzureOption {
+	return func(o *azureOptions) {
+		if maxAttempts > 0 {
+			o.retryMaxAttempts = maxAttempts
+		}
+		if maxBackoff > 0 {
+			o.retryMaxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithAzureRequestID sends the ID that requestID reads from each call's
+// context as the x-ms-client-request-id header, which Azure Storage
+// analytics logs record, so they can be matched to the request that made
+// the call. Pre-signed URLs never carry it.
+func WithAzureRequestID(requestID func(context.Context) string) AzureOption {
+	return func(o *azureOptions) {
+		o.requestID = requestID
+	}
+}
+
+// AzureConfig identifies the container and the account key authorizing
+// requests to it
+type AzureConfig struct {
+	AccountName string
+	AccountKey  string // Base64, as the portal shows it
+	Container   string
+	// Endpoint is the account's blob endpoint, empty for
+	// https://<account>.blob.core.windows.net. Azurite uses
+	// http://127.0.0.1:10000/<account>.
+	Endpoint string
+}
+
+// NewAzureStore creates a new Azure Blob Storage store for cfg.Container,
+// which must exist
+func NewAzureStore(cfg AzureConfig, log *logger.Logger, opts ...AzureOption) (*AzureStore, error) {
+	if cfg.AccountName == "" || cfg.Container == "" {
+		return nil, fmt.Errorf("azure account and container names are required")
+	}
+	credential, err := container.NewSharedKeyCredential(cfg.AccountName, cfg.AccountKey)
+	if err != nil || cfg.AccountKey == "" {
+		return nil, fmt.Errorf("azure account key must be base64")
+	}
+
+	options := defaultAzureOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + cfg.AccountName + ".blob.core.windows.net"
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("azure endpoint must be an http or https URL")
+	}
+
+	clientOptions := &container.ClientOptions{}
+	clientOptions.Transport = options.httpClient
+	clientOptions.Retry = policy.RetryOptions{
+		MaxRetries:    int32(options.retryMaxAttempts - 1),
+		MaxRetryDelay: options.retryMaxBackoff,
+	}
+	if options.retryMaxAttempts == 1 {
+		clientOptions.Retry.MaxRetries = -1 // Zero is the SDK's default of 3
+	}
+	if options.requestID != nil {
+		clientOptions.PerCallPolicies = []policy.Policy{azureRequestIDPolicy(options.requestID)}
+	}
+	client, err := container.NewClientWithSharedKeyCredential(u.JoinPath(cfg.Container).String(), credential, clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("creating azure client: %w", err)
+	}
+
+	log.Info("Azure blob store initialized",
+		"account", cfg.AccountName,
+		"container", cfg.Container,
+	)
+
+	return &AzureStore{
+		client:     client,
+		credential: credential,
+		container:  cfg.Container,
+		https:      u.Scheme == "https",
+		options:    options,
+		logger:     log,
+		markers:    make(map[string]string),
+	}, nil
+}
+
+// azureRequestIDPolicy sets the x-ms-client-request-id header of requests
+// to the request ID of their context, when there is one
+type azureRequestIDPolicy func(context.Context) string
+
+func (p azureRequestIDPolicy) Do(req *policy.Request) (*http.Response, error) {
+	if id := p(req.Raw().Context()); id != "" {
+		req.Raw().Header.Set("x-ms-client-request-id", id)
+	}
+	return req.Next()
+}
+
+// isNotFoundError checks if the error indicates the object was not found
+func (s *AzureStore) isNotFoundError(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// uploadOptions returns the properties, metadata, tags and tier of input,
+// validated
+func uploadOptions(input *UploadInput) (*blockblob.UploadOptions, error) {
+	contentType := input.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	opts := &blockblob.UploadOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	}
+	if input.CacheControl != "" {
+		opts.HTTPHeaders.BlobCacheControl = &input.CacheControl
+	}
+	if input.ContentDisposition != "" {
+		opts.HTTPHeaders.BlobContentDisposition = &input.ContentDisposition
+	}
+	if len(input.Metadata) > 0 {
+		opts.Metadata = make(map[string]*string, len(input.Metadata))
+		for name, value := range input.Metadata {
+			opts.Metadata[name] = &value
+		}
+	}
+	if err := validateTags(input.Tags); err != nil {
+		return nil, err
+	}
+	if len(input.Tags) > 0 {
+		opts.Tags = input.Tags
+	}
+	if input.StorageClass != "" {
+		tier, ok := azureAccessTiers[strings.ToLower(input.StorageClass)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown access tier %q (must be Hot, Cool or Archive)", ErrInvalidInput, input.StorageClass)
+		}
+		opts.Tier = &tier
+	}
+	return opts, nil
+}
+
+// etag returns the value of an optional ETag
+func etag(tag *azcore.ETag) string {
+	if tag == nil {
+		return ""
+	}
+	return string(*tag)
+}
+
+// value returns the value of an optional field
+func value[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Store
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Upload uploads an object as a block blob, replacing any blob under the
+// same key. Bodies larger than the block size are staged block by block,
+// then committed, so no more than one block is held in memory.
+func (s *AzureStore) Upload(ctx context.Context, input *UploadInput) (*UploadOutput, error) {
+	if input.Key == "" {
+		return nil, ErrInvalidKey
+	}
+	if input.Body == nil {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidInput)
+	}
+	opts, err := uploadOptions(input)
+	if err != nil {
+		return nil, err
+	}
+
+	block := make([]byte, s.options.blockSize)
+	n, err := io.ReadFull(input.Body, block)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+
+	client := s.client.NewBlockBlobClient(input.Key)
+	var versionID *string
+	var tag *azcore.ETag
+	if int64(n) < s.options.blockSize {
+		var resp blockblob.UploadResponse
+		resp, err = client.Upload(ctx, streaming.NopCloser(bytes.NewReader(block[:n])), opts)
+		versionID, tag = resp.VersionID, resp.ETag
+	} else {
+		var resp blockblob.UploadStreamResponse
+		resp, err = client.UploadStream(ctx, io.MultiReader(bytes.NewReader(block), input.Body), &blockblob.UploadStreamOptions{
+			BlockSize:   s.options.blockSize,
+			HTTPHeaders: opts.HTTPHeaders,
+			Metadata:    opts.Metadata,
+			AccessTier:  opts.Tier,
+			Tags:        opts.Tags,
+		})
+		versionID, tag = resp.VersionID, resp.ETag
+	}
+	if err != nil {
+		s.logger.Error("failed to upload object",
+			"key", input.Key,
+			"container", s.container,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+
+	location := client.URL()
+	s.logger.Debug("object uploaded successfully",
+		"key", input.Key,
+		"location", location,
+	)
+
+	return &UploadOutput{
+		Location:  location,
+		VersionID: value(versionID),
+		ETag:      etag(tag),
+	}, nil
+}
+
+// Download downloads an object into the provided writer.
+func (s *AzureStore) Download(ctx context.Context, key string, w io.WriterAt) (int64, error) {
+	body, err := s.GetObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	defer body.Close()
+
+	n, err := io.Copy(io.NewOffsetWriter(w, 0), body)
+	if err != nil {
+		s.logger.Error("failed to download object",
+			"key", key,
+			"container", s.container,
+			"error", err,
+		)
+		return n, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	s.logger.Debug("object downloaded successfully",
+		"key", key,
+		"bytes", n,
+	)
+
+	return n, nil
+}
+
+// GetObject retrieves an object and returns it as a ReadCloser.
+// The caller is responsible for closing the returned reader.
+func (s *AzureStore) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	resp, err := s.client.NewBlobClient(key).DownloadStream(ctx, nil)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to get object",
+			"key", key,
+			"container", s.container,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	return resp.Body, nil
+}
+
+// HeadObject retrieves metadata about an object without downloading it.
+func (s *AzureStore) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	props, err := s.client.NewBlobClient(key).GetProperties(ctx, nil)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to head object",
+			"key", key,
+			"container", s.container,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+
+	info := &ObjectInfo{
+		Key:          key,
+		Size:         value(props.ContentLength),
+		ContentType:  value(props.ContentType),
+		ETag:         etag(props.ETag),
+		LastModified: value(props.LastModified),
+		Metadata:     make(map[string]string, len(props.Metadata)),
+
+		StorageClass:       value(props.AccessTier),
+		CacheControl:       value(props.CacheControl),
+		ContentDisposition: value(props.ContentDisposition),
+	}
+	for name, v := range props.Metadata {
+		// The SDK reads names from canonicalized headers
+		info.Metadata[strings.ToLower(name)] = value(v)
+	}
+	return info, nil
+}
+
+// Delete removes an object and its snapshots; deleting a missing object
+// succeeds (idempotent), as it does on S3.
+func (s *AzureStore) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+
+	_, err := s.client.NewBlobClient(key).Delete(ctx, &blob.DeleteOptions{DeleteSnapshots: to.Ptr(blob.DeleteSnapshotsOptionTypeInclude)})
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil
+		}
+		s.logger.Error("failed to delete object",
+			"key", key,
+			"container", s.container,
+			"error", err,
+		)
+		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	}
+
+	s.logger.Debug("object deleted successfully", "key", key)
+	return nil
+}
+
+// DeleteMultiple removes multiple objects, one request each.
+// It returns the keys that failed to delete along with any error.
+func (s *AzureStore) DeleteMultiple(ctx context.Context, keys []string) ([]string, error) {
+	var failedKeys []string
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			failedKeys = append(failedKeys, key)
+		}
+	}
+
+	if len(failedKeys) > 0 {
+		return failedKeys, fmt.Errorf("%w: %d objects failed to delete", ErrDeleteFailed, len(failedKeys))
+	}
+
+	s.logger.Debug("objects deleted successfully", "count", len(keys))
+	return nil, nil
+}
+
+// maxAzureMarkers bounds the continuation markers kept for StartAfter
+const maxAzureMarkers = 1024
+
+// List lists objects in key order with optional filtering by prefix.
+// Azure continues listings from an opaque marker rather than a key, so the
+// marker following each page is kept for the page's last key: paging with
+// NextMarker as StartAfter resumes where the previous page stopped. Any
+// other StartAfter lists from the start of the prefix and skips the keys
+// up to it.
+func (s *AzureStore) List(ctx context.Context, input *ListInput) (*ListOutput, error) {
+	maxKeys := int(input.MaxKeys)
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	maxKeys = min(maxKeys, 5000) // The most a page holds
+
+	marker := ""
+	if input.StartAfter != "" {
+		s.mu.Lock()
+		marker = s.markers[input.Prefix+"\x00"+input.StartAfter]
+		s.mu.Unlock()
+	}
+
+	output := &ListOutput{Objects: []ObjectInfo{}}
+	resume := "" // Marker continuing after the last object listed
+	for {
+		opts := &container.ListBlobsFlatOptions{MaxResults: to.Ptr(int32(maxKeys))}
+		if input.Prefix != "" {
+			opts.Prefix = &input.Prefix
+		}
+		if marker != "" {
+			opts.Marker = &marker
+		}
+		page, err := s.client.NewListBlobsFlatPager(opts).NextPage(ctx)
+		if err != nil {
+			s.logger.Error("failed to list objects",
+				"container", s.container,
+				"prefix", input.Prefix,
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		next := value(page.NextMarker)
+		for _, item := range page.Segment.BlobItems {
+			name := value(item.Name)
+			if input.StartAfter != "" && name <= input.StartAfter {
+				continue
+			}
+			if len(output.Objects) == maxKeys {
+				// The page goes on past the limit, which no marker resumes from
+				output.IsTruncated = true
+				next = ""
+				break
+			}
+			info := ObjectInfo{Key: name}
+			if props := item.Properties; props != nil {
+				info.Size = value(props.ContentLength)
+				info.ContentType = value(props.ContentType)
+				info.ETag = etag(props.ETag)
+				info.LastModified = value(props.LastModified)
+			}
+			output.Objects = append(output.Objects, info)
+		}
+		if output.IsTruncated {
+			break
+		}
+		marker = next
+		if marker == "" {
+			break
+		}
+		if len(output.Objects) == maxKeys {
+			output.IsTruncated, resume = true, marker
+			break
+		}
+	}
+
+	if len(output.Objects) > 0 {
+		output.NextMarker = output.Objects[len(output.Objects)-1].Key
+		if resume != "" {
+			s.rememberMarker(input.Prefix+"\x00"+output.NextMarker, resume)
+		}
+	}
+
+	return output, nil
+}
+
+// rememberMarker keeps the marker continuing a listing after key,
+// forgetting every marker once too many are kept
+func (s *AzureStore) rememberMarker(key, marker string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.markers) >= maxAzureMarkers {
+		clear(s.markers)
+	}
+	s.markers[key] = marker
+}
+
+// Exists checks if an object exists.
+func (s *AzureStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.HeadObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Copy copies an object within the container, with its properties and
+// metadata but not its tags. Azure may finish copies in the background;
+// Copy waits until the copy completed.
+func (s *AzureStore) Copy(ctx context.Context, sourceKey, destKey string) error {
+	if sourceKey == "" || destKey == "" {
+		return ErrInvalidKey
+	}
+
+	source := s.client.NewBlobClient(sourceKey).URL()
+	dest := s.client.NewBlobClient(destKey)
+	resp, err := dest.StartCopyFromURL(ctx, source, nil)
+	if err == nil {
+		err = s.awaitCopy(ctx, dest, value(resp.CopyStatus))
+	}
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return ErrNotFound
+		}
+		s.logger.Error("failed to copy object",
+			"source", sourceKey,
+			"dest", destKey,
+			"container", s.container,
+			"error", err,
+		)
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	s.logger.Debug("object copied successfully",
+		"source", sourceKey,
+		"dest", destKey,
+	)
+	return nil
+}
+
+// awaitCopy polls the copy to dest until it is no longer pending
+func (s *AzureStore) awaitCopy(ctx context.Context, dest *blob.Client, status blob.CopyStatusType) error {
+	for status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+		props, err := dest.GetProperties(ctx, nil)
+		if err != nil {
+			return err
+		}
+		status = value(props.CopyStatus)
+	}
+	if status != "" && status != blob.CopyStatusTypeSuccess {
+		return fmt.Errorf("copy %s", status)
+	}
+	return nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Shared access signatures
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// GeneratePresignedURL generates a SAS URL for downloading an object.
+// The URL is valid for the specified duration.
+func (s *AzureStore) GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	return s.sasURL(key, sas.BlobPermissions{Read: true}, expiration)
+}
+
+// GeneratePresignedUploadURL generates a SAS URL for uploading an object.
+// The URL is valid for the specified duration. Uploads are a PUT with an
+// x-ms-blob-type: BlockBlob header, and should send contentType as their
+// x-ms-blob-content-type: unlike S3, a SAS cannot require a content type.
+func (s *AzureStore) GeneratePresignedUploadURL(ctx context.Context, key string, contentType string, expiration time.Duration) (string, error) {
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	return s.sasURL(key, sas.BlobPermissions{Create: true, Write: true}, expiration)
+}
+
+// sasURL returns the URL of blob key with a service SAS granting
+// permissions until expiration from now
+func (s *AzureStore) sasURL(key string, permissions sas.BlobPermissions, expiration time.Duration) (string, error) {
+	now := time.Now().UTC()
+	protocol := sas.ProtocolHTTPS
+	if !s.https {
+		protocol = sas.ProtocolHTTPSandHTTP // Azurite
+	}
+	query, err := sas.BlobSignatureValues{
+		Protocol:      protocol,
+		StartTime:     now.Add(-5 * time.Minute), // For clocks running behind the service's
+		ExpiryTime:    now.Add(expiration),
+		Permissions:   permissions.String(),
+		ContainerName: s.container,
+		BlobName:      key,
+	}.SignWithSharedKey(s.credential)
+	if err != nil {
+		return "", fmt.Errorf("signing SAS: %w", err)
+	}
+	return s.client.NewBlobClient(key).URL() + "?" + query.Encode(), nil
+}
+
+// Container returns the configured container name
+func (s *AzureStore) Container() string {
+	return s.container
+}
@@ -0,0 +1,404 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// Account of the Azurite storage emulator, whose key is published
+const (
+	azureTestAccount = "devstoreaccount1"
+	azureTestKey     = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+// fakeAzureBlob is a blob of fakeAzure with the headers it was uploaded with
+type fakeAzureBlob struct {
+	data     []byte
+	header   http.Header
+	etag     string
+	modified time.Time
+}
+
+// fakeAzure answers the Blob service calls of one container the tests make
+type fakeAzure struct {
+	mu         sync.Mutex
+	container  string
+	blobs      map[string]*fakeAzureBlob
+	blocks     map[string][]byte // Staged blocks by blob and block ID
+	committed  [][]string        // Block IDs of each committed block list
+	requestIDs []string          // x-ms-client-request-id of every request
+	etags      int
+}
+
+func newFakeAzure(container string) *fakeAzure {
+	return &fakeAzure{container: container, blobs: make(map[string]*fakeAzureBlob), blocks: make(map[string][]byte)}
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requestIDs = append(f.requestIDs, r.Header.Get("x-ms-client-request-id"))
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey "+azureTestAccount+":") {
+		f.fail(w, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+	root := "/" + azureTestAccount + "/" + f.container
+	query := r.URL.Query()
+	if r.URL.Path == root && query.Get("comp") == "list" {
+		f.list(w, query)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, root+"/")
+	if !ok || key == "" {
+		f.fail(w, http.StatusBadRequest, "InvalidUri")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		f.blocks[key+"\x00"+query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			f.fail(w, http.StatusBadRequest, "InvalidXmlDocument")
+			return
+		}
+		var data []byte
+		for _, id := range list.Latest {
+			block, ok := f.blocks[key+"\x00"+id]
+			if !ok {
+				f.fail(w, http.StatusBadRequest, "InvalidBlockList")
+				return
+			}
+			data = append(data, block...)
+		}
+		f.committed = append(f.committed, list.Latest)
+		f.put(w, key, data, r.Header)
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-copy-source") != "":
+		source, err := url.Parse(r.Header.Get("x-ms-copy-source"))
+		if err != nil {
+			f.fail(w, http.StatusBadRequest, "InvalidHeaderValue")
+			return
+		}
+		src, ok := f.blobs[strings.TrimPrefix(source.Path, root+"/")]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "CannotVerifyCopySource")
+			return
+		}
+		w.Header().Set("x-ms-copy-status", "success")
+		f.etags++
+		f.blobs[key] = &fakeAzureBlob{data: bytes.Clone(src.data), header: src.header.Clone(), etag: fmt.Sprintf(`"0x%X"`, f.etags), modified: time.Now()}
+		w.Header().Set("ETag", f.blobs[key].etag)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-blob-type") == "BlockBlob":
+		f.put(w, key, body, r.Header)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		b, ok := f.blobs[key]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		h := w.Header()
+		h.Set("Content-Length", strconv.Itoa(len(b.data)))
+		h.Set("Content-Type", b.header.Get("x-ms-blob-content-type"))
+		h.Set("ETag", b.etag)
+		h.Set("Last-Modified", b.modified.UTC().Format(http.TimeFormat))
+		h.Set("x-ms-blob-type", "BlockBlob")
+		for name, header := range map[string]string{
+			"Cache-Control":       "x-ms-blob-cache-control",
+			"Content-Disposition": "x-ms-blob-content-disposition",
+			"x-ms-access-tier":    "x-ms-access-tier",
+		} {
+			if v := b.header.Get(header); v != "" {
+				h.Set(name, v)
+			}
+		}
+		for name, values := range b.header {
+			if strings.HasPrefix(strings.ToLower(name), "x-ms-meta-") {
+				h[name] = values
+			}
+		}
+		if r.Method == http.MethodGet {
+			w.Write(b.data)
+		}
+	case r.Method == http.MethodDelete:
+		if _, ok := f.blobs[key]; !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(f.blobs, key)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		f.fail(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+// put stores data as blob key, with the properties of header
+func (f *fakeAzure) put(w http.ResponseWriter, key string, data []byte, header http.Header) {
+	f.etags++
+	b := &fakeAzureBlob{data: data, header: header.Clone(), etag: fmt.Sprintf(`"0x%X"`, f.etags), modified: time.Now()}
+	f.blobs[key] = b
+	w.Header().Set("ETag", b.etag)
+	w.Header().Set("Last-Modified", b.modified.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// list answers a flat listing, continuing from markers that are the name
+// of the next blob, reversed so clients cannot take them for keys
+func (f *fakeAzure) list(w http.ResponseWriter, query url.Values) {
+	prefix := query.Get("prefix")
+	start := reverse(query.Get("marker"))
+	maxResults, err := strconv.Atoi(query.Get("maxresults"))
+	if err != nil || maxResults <= 0 {
+		maxResults = 5000
+	}
+
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(f.blobs)) {
+		if strings.HasPrefix(name, prefix) && name >= start {
+			names = append(names, name)
+		}
+	}
+	next := ""
+	if len(names) > maxResults {
+		next = reverse(names[maxResults])
+		names = names[:maxResults]
+	}
+
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults ContainerName="` + f.container + `"><Blobs>`)
+	for _, name := range names {
+		b := f.blobs[name]
+		fmt.Fprintf(&out, `<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified><Etag>%s</Etag>`+
+			`<Content-Length>%d</Content-Length><Content-Type>%s</Content-Type><BlobType>BlockBlob</BlobType></Properties></Blob>`,
+			html.EscapeString(name), b.modified.UTC().Format(http.TimeFormat), html.EscapeString(b.etag),
+			len(b.data), html.EscapeString(b.header.Get("x-ms-blob-content-type")))
+	}
+	out.WriteString(`</Blobs><NextMarker>` + html.EscapeString(next) + `</NextMarker></EnumerationResults>`)
+	w.Header().Set("Content-Type", "application/xml")
+	io.WriteString(w, out.String())
+}
+
+// fail answers with the Blob service error code
+func (f *fakeAzure) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>`+code+`</Code><Message>fake</Message></Error>`)
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	slices.Reverse(r)
+	return string(r)
+}
+
+// newTestAzureStore returns an AzureStore of fake, served over HTTP
+func newTestAzureStore(t *testing.T, fake *fakeAzure, opts ...AzureOption) *AzureStore {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := NewAzureStore(AzureConfig{
+		AccountName: azureTestAccount,
+		AccountKey:  azureTestKey,
+		Container:   fake.container,
+		Endpoint:    srv.URL + "/" + azureTestAccount,
+	}, logger.NewWithOptions("error", io.Discard, false), append([]AzureOption{WithAzureRetry(1, 0)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewAzureStore: %v", err)
+	}
+	return store
+}
+
+// NewFakeAzureStore returns an AzureStore of a fake Blob service, for the
+// conformance tests of package blob_test
+func NewFakeAzureStore(t *testing.T) *AzureStore {
+	return newTestAzureStore(t, newFakeAzure("uploads"))
+}
+
+func TestAzureRequestID(t *testing.T) {
+	fake := newFakeAzure("uploads")
+	store := newTestAzureStore(t, fake, WithAzureRequestID(func(context.Context) string { return "req-42" }))
+	ctx := context.Background()
+
+	if _, err := store.Upload(ctx, &UploadInput{Key: "a.txt", Body: strings.NewReader("a")}); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if _, err := store.HeadObject(ctx, "a.txt"); err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if _, err := store.List(ctx, &ListInput{}); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.requestIDs) != 3 {
+		t.Fatalf("got %d requests, want 3", len(fake.requestIDs))
+	}
+	for _, id := range fake.requestIDs {
+		if id != "req-42" {
+			t.Errorf("x-ms-client-request-id = %q, want the request ID of the context", id)
+		}
+	}
+}
+
+func TestAzureUploadOptions(t *testing.T) {
+	opts, err := uploadOptions(&UploadInput{
+		Key:                "report.csv",
+		Body:               strings.NewReader("a,b"),
+		StorageClass:       "cool",
+		Metadata:           map[string]string{"owner": "ops"},
+		Tags:               map[string]string{"retention": "90 days"},
+		CacheControl:       "no-cache",
+		ContentDisposition: "attachment",
+	})
+	if err != nil {
+		t.Fatalf("uploadOptions: %v", err)
+	}
+	headers := opts.HTTPHeaders
+	if got := *headers.BlobContentType; got != "application/octet-stream" {
+		t.Errorf("content type = %q, want application/octet-stream", got)
+	}
+	if got := *headers.BlobCacheControl; got != "no-cache" {
+		t.Errorf("cache control = %q, want no-cache", got)
+	}
+	if got := *headers.BlobContentDisposition; got != "attachment" {
+		t.Errorf("content disposition = %q, want attachment", got)
+	}
+	if opts.Tier == nil || *opts.Tier != "Cool" {
+		t.Errorf("tier = %v, want Cool", opts.Tier)
+	}
+	if got := opts.Tags["retention"]; got != "90 days" {
+		t.Errorf("retention tag = %q, want 90 days", got)
+	}
+	if got := opts.Metadata["owner"]; got == nil || *got != "ops" {
+		t.Errorf("owner metadata = %v, want ops", got)
+	}
+
+	if _, err := uploadOptions(&UploadInput{Key: "a", StorageClass: StorageClassGlacier}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("S3 storage class error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := uploadOptions(&UploadInput{Key: "a", Tags: map[string]string{"": "v"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("empty tag key error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestAzureUploadStagesLargeBodies(t *testing.T) {
+	fake := newFakeAzure("uploads")
+	store := newTestAzureStore(t, fake, WithAzureBlockSize(1024*1024))
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 160*1024) // 2.5 MiB
+	if _, err := store.Upload(ctx, &UploadInput{
+		Key:          "big.bin",
+		Body:         bytes.NewReader(content),
+		StorageClass: "cool",
+		CacheControl: "no-cache",
+		Metadata:     map[string]string{"owner": "ops"},
+		Tags:         map[string]string{"retention": "90 days"},
+	}); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	fake.mu.Lock()
+	committed := fake.committed
+	header := fake.blobs["big.bin"].header
+	fake.mu.Unlock()
+	if len(committed) != 1 || len(committed[0]) != 3 {
+		t.Fatalf("committed block lists = %v, want one of 3 blocks", committed)
+	}
+	if got := header.Get("x-ms-tags"); got != "retention=90+days" && got != "retention=90%20days" {
+		t.Errorf("x-ms-tags = %q, want the tags of the upload", got)
+	}
+
+	body, err := store.GetObject(ctx, "big.bin")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, content) {
+		t.Errorf("GetObject returned %d bytes, want the %d uploaded", len(got), len(content))
+	}
+
+	info, err := store.HeadObject(ctx, "big.bin")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.StorageClass != "Cool" || info.CacheControl != "no-cache" || info.Metadata["owner"] != "ops" {
+		t.Errorf("HeadObject = tier %q, cache control %q, metadata %v; want the properties of the upload",
+			info.StorageClass, info.CacheControl, info.Metadata)
+	}
+}
+
+func TestAzurePresignedURL(t *testing.T) {
+	store := newTestAzureStore(t, newFakeAzure("uploads"))
+
+	for _, tt := range []struct {
+		name        string
+		presign     func() (string, error)
+		permissions string
+	}{
+		{"download", func() (string, error) {
+			return store.GeneratePresignedURL(context.Background(), "docs/a b.txt", time.Hour)
+		}, "r"},
+		{"upload", func() (string, error) {
+			return store.GeneratePresignedUploadURL(context.Background(), "docs/a b.txt", "text/plain", time.Hour)
+		}, "cw"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.presign()
+			if err != nil {
+				t.Fatalf("presign: %v", err)
+			}
+			u, err := url.Parse(raw)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", raw, err)
+			}
+			if want := "/" + azureTestAccount + "/uploads/docs/a b.txt"; u.Path != want {
+				t.Errorf("path = %q, want %q", u.Path, want)
+			}
+			query := u.Query()
+			if got := query.Get("sp"); got != tt.permissions {
+				t.Errorf("sp = %q, want %q", got, tt.permissions)
+			}
+			if got := query.Get("sr"); got != "b" {
+				t.Errorf("sr = %q, want b", got)
+			}
+			if got := query.Get("spr"); got != "https,http" {
+				t.Errorf("spr = %q, want https,http for an http endpoint", got)
+			}
+			if query.Get("sig") == "" || query.Get("se") == "" {
+				t.Errorf("query %v has no signature or expiry", query)
+			}
+		})
+	}
+
+	if _, err := store.GeneratePresignedURL(context.Background(), "", time.Hour); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("empty key error = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,248 @@
+// Package blob defines a storage-agnostic object store (Store) with S3, Azure
+// Blob Storage, Google Cloud Storage, local filesystem and in-memory
+// implementations, plus an autocert.Cache adapter. Errors are the sentinels in
+// errors.go, and every implementation passes the conformance tests in
+// blobtest. The API is stable and changes are additive.
+package blob
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ObjectInfo contains metadata about a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	Metadata     map[string]string
+	Encryption   string // Server-side encryption algorithm, empty when unknown or none
+	KMSKeyID     string // KMS key of EncryptionKMS objects
+
+	StorageClass       string // Storage class or access tier, empty for the store's default
+	CacheControl       string
+	ContentDisposition string
+}
+
+// Server-side encryption algorithms, as S3 names them
+const (
+	EncryptionS3  = "AES256"  // SSE-S3: keys managed by S3
+	EncryptionKMS = "aws:kms" // SSE-KMS: a KMS key, the account's aws/s3 key unless KMSKeyID is set
+)
+
+// UploadInput contains parameters for uploading an object
+type UploadInput struct {
+	Key         string            // Object key (required)
+	Body        io.Reader         // Content to upload (required)
+	ContentType string            // MIME type (optional, defaults to application/octet-stream)
+	Metadata    map[string]string // Custom metadata (optional)
+	// Encryption is the server-side encryption (EncryptionS3 or
+	// EncryptionKMS) of stores supporting it, empty for the store's default
+	Encryption string
+	KMSKeyID   string // KMS key ID or ARN for EncryptionKMS (optional)
+
+	// StorageClass stores the object in a cheaper tier on stores supporting
+	// it: an S3 storage class (StorageClassStandardIA, StorageClassGlacier...)
+	// or an Azure access tier (Hot, Cool, Cold, Archive). Empty for the default.
+	StorageClass string
+
+	Tags               map[string]string // Object tags on stores supporting them, at most 10 (optional)
+	CacheControl       string            // Cache-Control served with the object, e.g. to CDNs (optional)
+	ContentDisposition string            // Content-Disposition served with the object (optional)
+}
+
+// UploadOutput contains the result of an upload operation
+type UploadOutput struct {
+	Location  string // URL or path of the uploaded object
+	VersionID string // Version ID (if versioning is enabled)
+	ETag      string // Entity tag for the object
+}
+
+// ListInput contains parameters for listing objects
+type ListInput struct {
+	Prefix     string // Filter objects by prefix
+	MaxKeys    int32  // Maximum number of keys to return (default 1000)
+	StartAfter string // Start listing after this key (for pagination)
+}
+
+// ListOutput contains the result of a list operation
+type ListOutput struct {
+	Objects     []ObjectInfo
+	IsTruncated bool   // True if there are more results
+	NextMarker  string // Use this as StartAfter for the next request
+}
+
+// Store defines the contract for blob storage operations.
+// The domain defines the interface, infrastructure implements it.
+// Implementations may include S3, GCS, Azure Blob, local filesystem, etc.
+type Store interface {
+	// Upload uploads an object to the store.
+	// Returns upload metadata including location and ETag.
+	Upload(ctx context.Context, input *UploadInput) (*UploadOutput, error)
+
+	// Download downloads an object into the provided writer.
+	// Returns the number of bytes written.
+	Download(ctx context.Context, key string, w io.WriterAt) (int64, error)
+
+	// GetObject retrieves an object and returns it as a ReadCloser.
+	// The caller is responsible for closing the returned reader.
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// HeadObject retrieves metadata about an object without downloading it.
+	HeadObject(ctx context.Context, key string) (*ObjectInfo, error)
+
+	// Delete removes an object from the store.
+	Delete(ctx context.Context, key string) error
+
+	// DeleteMultiple removes multiple objects from the store.
+	// Returns the keys that failed to delete along with any error.
+	DeleteMultiple(ctx context.Context, keys []string) (failedKeys []string, err error)
+
+	// List lists objects in the store with optional filtering.
+	List(ctx context.Context, input *ListInput) (*ListOutput, error)
+
+	// Exists checks if an object exists in the store.
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// Copy copies an object within the store.
+	Copy(ctx context.Context, sourceKey, destKey string) error
+}
+
+// PresignedURLGenerator defines the contract for generating pre-signed URLs.
+// Not all storage backends support this (e.g., local filesystem).
+type PresignedURLGenerator interface {
+	// GeneratePresignedURL generates a pre-signed URL for downloading an object.
+	// The URL is valid for the specified duration.
+	GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error)
+
+	// GeneratePresignedUploadURL generates a pre-signed URL for uploading an object.
+	// The URL is valid for the specified duration.
+	GeneratePresignedUploadURL(ctx context.Context, key string, contentType string, expiration time.Duration) (string, error)
+}
+
+// PresignedPostInput describes the constraints a presigned POST policy
+// enforces. S3 rejects any form upload that violates them.
+type PresignedPostInput struct {
+	// Key is the exact object key the form uploads to. Ignored when KeyPrefix is set.
+	Key string
+
+	// KeyPrefix allows any key starting with the prefix. The form's key field
+	// defaults to KeyPrefix + "${filename}", which S3 replaces with the
+	// uploaded file's name.
+	KeyPrefix string
+
+	// ContentType prefills the form's Content-Type field and, unless
+	// ContentTypePrefix is set, is the only content type accepted.
+	ContentType string
+
+	// ContentTypePrefix accepts any Content-Type starting with the prefix
+	// (e.g. "image/"); a ContentType set with it must start with it too
+	ContentTypePrefix string
+
+	// MinSize and MaxSize bound the upload's size in bytes. A MaxSize of 0
+	// leaves the store's own limit, 5 GiB for S3.
+	MinSize int64
+	MaxSize int64
+
+	// Expiration is how long the policy is valid for
+	Expiration time.Duration
+}
+
+// PresignedPost is a signed upload form: POST multipart/form-data to URL with
+// every field in Fields, followed by the file itself as the last field.
+type PresignedPost struct {
+	URL    string
+	Fields map[string]string
+}
+
+// PresignedPostGenerator defines the contract for presigned POST policies, which let
+// browsers upload directly to the store with server-enforced constraints.
+type PresignedPostGenerator interface {
+	// GeneratePresignedPost signs a POST policy for input
+	GeneratePresignedPost(ctx context.Context, input *PresignedPostInput) (*PresignedPost, error)
+}
+
+// LifecycleRule expires the objects under a prefix, or moves them to
+// cheaper storage classes, as they age
+type LifecycleRule struct {
+	ID       string // Unique in the bucket, at most 255 characters
+	Prefix   string // Empty for every object
+	Disabled bool
+
+	// ExpirationDays deletes objects this many days after their creation (0 never)
+	ExpirationDays int
+	// Transitions move objects to other storage classes as they age
+	Transitions []LifecycleTransition
+	// AbortIncompleteUploadDays aborts multipart uploads still unfinished
+	// this many days after they started, freeing their parts (0 never)
+	AbortIncompleteUploadDays int
+}
+
+// LifecycleTransition moves objects to StorageClass Days days after their creation
+type LifecycleTransition struct {
+	Days         int
+	StorageClass string
+}
+
+// LifecycleManager defines the contract for bucket lifecycle rules, which
+// let the store expire and archive objects itself
+type LifecycleManager interface {
+	// LifecycleRules returns the bucket's rules, none when it has no lifecycle configuration
+	LifecycleRules(ctx context.Context) ([]LifecycleRule, error)
+
+	// PutLifecycleRules creates rules, replacing the bucket's rules with the
+	// same IDs and keeping the others
+	PutLifecycleRules(ctx context.Context, rules []LifecycleRule) error
+
+	// DeleteLifecycleRules removes the bucket's rules with these IDs, if any
+	DeleteLifecycleRules(ctx context.Context, ids ...string) error
+}
+
+// Multipart upload limits of S3: every part but the last holds at least
+// MinPartSize bytes, and an upload has at most MaxParts parts
+const (
+	MinPartSize = 5 << 20
+	MaxParts    = 10000
+)
+
+// Part is a stored part of a multipart upload
+type Part struct {
+	Number int32 // 1 to MaxParts
+	ETag   string
+	Size   int64
+}
+
+// MultipartUploader defines the contract for multipart uploads driven part by
+// part, such as resumable uploads spread over many requests. The object only
+// exists once the upload is completed; parts of an upload never completed are
+// kept until it is aborted.
+type MultipartUploader interface {
+	// CreateMultipartUpload starts an upload of input.Key with input's
+	// content type, metadata and storage options (Body is ignored) and
+	// returns the upload's ID
+	CreateMultipartUpload(ctx context.Context, input *UploadInput) (string, error)
+
+	// UploadPart stores the size bytes of body as part number of the
+	// upload, replacing any part with that number
+	UploadPart(ctx context.Context, key, uploadID string, number int32, body io.Reader, size int64) (*Part, error)
+
+	// ListParts returns the upload's parts by number. Uploads completed,
+	// aborted or unknown are ErrNotFound.
+	ListParts(ctx context.Context, key, uploadID string) ([]Part, error)
+
+	// CompleteMultipartUpload creates the object from parts, in order
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) (*UploadOutput, error)
+
+	// AbortMultipartUpload discards the upload and its parts; aborting an
+	// upload that is gone is not an error
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// FullStore combines Store with PresignedURLGenerator for backends that support both.
+type FullStore interface {
+	Store
+	PresignedURLGenerator
+}
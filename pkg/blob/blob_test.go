@@ -0,0 +1,29 @@
+package blob_test
+
+import (
+	"testing"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob/blobtest"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	blobtest.TestStore(t, blob.NewMemoryStore())
+}
+
+func TestFileSystemStoreConformance(t *testing.T) {
+	store, err := blob.NewFileSystemStore(t.TempDir(), logger.New("error"))
+	if err != nil {
+		t.Fatalf("NewFileSystemStore: %v", err)
+	}
+	blobtest.TestStore(t, store)
+}
+
+func TestAzureStoreConformance(t *testing.T) {
+	blobtest.TestStore(t, blob.NewFakeAzureStore(t))
+}
+
+func TestGCSStoreConformance(t *testing.T) {
+	blobtest.TestStore(t, blob.NewFakeGCSStore(t))
+}
@@ -0,0 +1,411 @@
+// Package blobtest checks that a blob.Store implementation behaves like the
+// others: one battery of tests, run against every backend, covering uploads,
+// downloads, metadata, list pagination, copies and not-found semantics.
+//
+//	func TestFileSystemStore(t *testing.T) {
+//		store, _ := blob.NewFileSystemStore(t.TempDir(), logger.New("error"))
+//		blobtest.TestStore(t, store)
+//	}
+package blobtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/blob"
+)
+
+// TestStore runs the conformance tests against store. Every object it writes
+// is under a fresh prefix and deleted afterwards, so store may be a bucket
+// other tests or environments share.
+//
+// Object metadata and presigned URLs are optional features and not checked.
+func TestStore(t *testing.T, store blob.Store) {
+	t.Helper()
+	root := fmt.Sprintf("blobtest-%d/", time.Now().UnixNano())
+	t.Cleanup(func() { deletePrefix(t, store, root) })
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store blob.Store, prefix string)
+	}{
+		{"UploadGetObject", testUploadGetObject},
+		{"Download", testDownload},
+		{"HeadObject", testHeadObject},
+		{"Overwrite", testOverwrite},
+		{"NotFound", testNotFound},
+		{"InvalidKey", testInvalidKey},
+		{"ListPrefix", testListPrefix},
+		{"ListPagination", testListPagination},
+		{"Copy", testCopy},
+		{"Delete", testDelete},
+		{"DeleteMultiple", testDeleteMultiple},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, store, root+tt.name+"/")
+		})
+	}
+}
+
+func testUploadGetObject(t *testing.T, store blob.Store, prefix string) {
+	ctx := context.Background()
+	key := prefix + "hello.txt"
+	out, err := store.Upload(ctx, &blob.UploadInput{Key: key, Body: strings.NewReader("hello, world"), ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if out == nil || out.Location == "" {
+		t.Errorf("Upload returned %+v, want a location", out)
+	}
+
+	if got := get(t, store, key); got != "hello, world" {
+		t.Errorf("GetObject = %q, want %q", got, "hello, world")
+	}
+	if ok, err := store.Exists(ctx, key); err != nil || !ok {
+		t.Errorf("Exists = %v, %v, want true", ok, err)
+	}
+
+	// Empty objects are objects too
+	empty := prefix + "empty.txt"
+	upload(t, store, empty, "")
+	if got := get(t, store, empty); got != "" {
+		t.Errorf("GetObject of an empty object = %q", got)
+	}
+}
+
+func testDownload(t *testing.T, store blob.Store, prefix string) {
+	key := prefix + "data.bin"
+	content := strings.Repeat("0123456789", 1000)
+	upload(t, store, key, content)
+
+	var w writeAtBuffer
+	n, err := store.Download(context.Background(), key, &w)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if n != int64(len(content)) || w.String() != content {
+		t.Errorf("Download wrote %d bytes (%d kept), want %d", n, len(w.String()), len(content))
+	}
+}
+
+func testHeadObject(t *testing.T, store blob.Store, prefix string) {
+	key := prefix + "head.txt"
+	before := time.Now().Add(-time.Minute) // Clock skew with remote stores
+	upload(t, store, key, "twelve bytes")
+
+	info, err := store.HeadObject(context.Background(), key)
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.Key != key {
+		t.Errorf("Key = %q, want %q", info.Key, key)
+	}
+	if info.Size != 12 {
+		t.Errorf("Size = %d, want 12", info.Size)
+	}
+	if !strings.HasPrefix(info.ContentType, "text/plain") {
+		t.Errorf("ContentType = %q, want text/plain", info.ContentType)
+	}
+	if info.LastModified.Before(before) {
+		t.Errorf("LastModified = %s, want after %s", info.LastModified, before)
+	}
+}
+
+func testOverwrite(t *testing.T, store blob.Store, prefix string) {
+	key := prefix + "doc.txt"
+	upload(t, store, key, "first version")
+	upload(t, store, key, "second")
+
+	if got := get(t, store, key); got != "second" {
+		t.Errorf("GetObject = %q, want the last upload", got)
+	}
+	info, err := store.HeadObject(context.Background(), key)
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.Size != int64(len("second")) {
+		t.Errorf("Size = %d, want %d", info.Size, len("second"))
+	}
+}
+
+func testNotFound(t *testing.T, store blob.Store, prefix string) {
+	ctx := context.Background()
+	key := prefix + "missing.txt"
+
+	if _, err := store.GetObject(ctx, key); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("GetObject error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Download(ctx, key, &writeAtBuffer{}); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("Download error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.HeadObject(ctx, key); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("HeadObject error = %v, want ErrNotFound", err)
+	}
+	if ok, err := store.Exists(ctx, key); err != nil || ok {
+		t.Errorf("Exists = %v, %v, want false, nil", ok, err)
+	}
+	if err := store.Copy(ctx, key, prefix+"copy.txt"); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("Copy error = %v, want ErrNotFound", err)
+	}
+	if ok, _ := store.Exists(ctx, prefix+"copy.txt"); ok {
+		t.Error("Copy of a missing object created the destination")
+	}
+}
+
+func testInvalidKey(t *testing.T, store blob.Store, prefix string) {
+	ctx := context.Background()
+	if _, err := store.Upload(ctx, &blob.UploadInput{Key: "", Body: strings.NewReader("x")}); !errors.Is(err, blob.ErrInvalidKey) {
+		t.Errorf("Upload error = %v, want ErrInvalidKey", err)
+	}
+	if _, err := store.Upload(ctx, &blob.UploadInput{Key: prefix + "nobody.txt"}); !errors.Is(err, blob.ErrInvalidInput) {
+		t.Errorf("Upload without a body error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := store.GetObject(ctx, ""); !errors.Is(err, blob.ErrInvalidKey) {
+		t.Errorf("GetObject error = %v, want ErrInvalidKey", err)
+	}
+	if _, err := store.HeadObject(ctx, ""); !errors.Is(err, blob.ErrInvalidKey) {
+		t.Errorf("HeadObject error = %v, want ErrInvalidKey", err)
+	}
+	if err := store.Delete(ctx, ""); !errors.Is(err, blob.ErrInvalidKey) {
+		t.Errorf("Delete error = %v, want ErrInvalidKey", err)
+	}
+	if err := store.Copy(ctx, "", prefix+"dest.txt"); !errors.Is(err, blob.ErrInvalidKey) {
+		t.Errorf("Copy error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func testListPrefix(t *testing.T, store blob.Store, prefix string) {
+	for _, key := range []string{"a/1.txt", "a/2.txt", "a/nested/3.txt", "b/1.txt", "ab.txt"} {
+		upload(t, store, prefix+key, key)
+	}
+
+	out, err := store.List(context.Background(), &blob.ListInput{Prefix: prefix + "a/"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []string{prefix + "a/1.txt", prefix + "a/2.txt", prefix + "a/nested/3.txt"}
+	if got := keys(out.Objects); !slices.Equal(got, want) {
+		t.Errorf("List keys = %v, want %v (recursive, in key order)", got, want)
+	}
+	if out.IsTruncated {
+		t.Error("IsTruncated = true, want false for a complete listing")
+	}
+	for _, obj := range out.Objects {
+		if want := int64(len(strings.TrimPrefix(obj.Key, prefix))); obj.Size != want {
+			t.Errorf("Size of %s = %d, want %d", obj.Key, obj.Size, want)
+		}
+	}
+
+	out, err = store.List(context.Background(), &blob.ListInput{Prefix: prefix + "none/"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(out.Objects) != 0 || out.IsTruncated {
+		t.Errorf("List of an empty prefix = %v (truncated %v), want nothing", keys(out.Objects), out.IsTruncated)
+	}
+}
+
+func testListPagination(t *testing.T, store blob.Store, prefix string) {
+	var want []string
+	for i := range 7 {
+		key := fmt.Sprintf("%sobj-%02d.txt", prefix, i)
+		upload(t, store, key, "x")
+		want = append(want, key)
+	}
+
+	var got []string
+	input := &blob.ListInput{Prefix: prefix, MaxKeys: 3}
+	for pages := 1; ; pages++ {
+		out, err := store.List(context.Background(), input)
+		if err != nil {
+			t.Fatalf("List page %d: %v", pages, err)
+		}
+		if len(out.Objects) > 3 {
+			t.Fatalf("List page %d returned %d objects, want at most MaxKeys (3)", pages, len(out.Objects))
+		}
+		got = append(got, keys(out.Objects)...)
+		if !out.IsTruncated {
+			break
+		}
+		if out.NextMarker == "" {
+			t.Fatalf("List page %d is truncated without a NextMarker", pages)
+		}
+		if pages == 10 {
+			t.Fatalf("List still truncated after %d pages of 3 for 7 objects", pages)
+		}
+		input.StartAfter = out.NextMarker
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged List keys = %v, want %v", got, want)
+	}
+
+	out, err := store.List(context.Background(), &blob.ListInput{Prefix: prefix, StartAfter: want[4]})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := keys(out.Objects); !slices.Equal(got, want[5:]) {
+		t.Errorf("List after %s = %v, want %v", want[4], got, want[5:])
+	}
+}
+
+func testCopy(t *testing.T, store blob.Store, prefix string) {
+	ctx := context.Background()
+	src, dst := prefix+"src.txt", prefix+"nested/dst.txt"
+	upload(t, store, src, "copied content")
+
+	if err := store.Copy(ctx, src, dst); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if got := get(t, store, dst); got != "copied content" {
+		t.Errorf("copy = %q, want %q", got, "copied content")
+	}
+	if got := get(t, store, src); got != "copied content" {
+		t.Errorf("source after Copy = %q, want it unchanged", got)
+	}
+
+	// Copies are independent: replacing the source leaves the copy as it was
+	upload(t, store, src, "replaced")
+	if got := get(t, store, dst); got != "copied content" {
+		t.Errorf("copy after replacing the source = %q", got)
+	}
+
+	// Copying onto an existing object replaces it
+	if err := store.Copy(ctx, src, dst); err != nil {
+		t.Fatalf("Copy onto an existing object: %v", err)
+	}
+	if got := get(t, store, dst); got != "replaced" {
+		t.Errorf("copy onto an existing object = %q, want %q", got, "replaced")
+	}
+}
+
+func testDelete(t *testing.T, store blob.Store, prefix string) {
+	ctx := context.Background()
+	key := prefix + "gone.txt"
+	upload(t, store, key, "x")
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if ok, err := store.Exists(ctx, key); err != nil || ok {
+		t.Errorf("Exists after Delete = %v, %v, want false", ok, err)
+	}
+	if _, err := store.GetObject(ctx, key); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("GetObject after Delete error = %v, want ErrNotFound", err)
+	}
+	// Idempotent
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func testDeleteMultiple(t *testing.T, store blob.Store, prefix string) {
+	ctx := context.Background()
+	var toDelete []string
+	for i := range 3 {
+		key := fmt.Sprintf("%sobj-%d.txt", prefix, i)
+		upload(t, store, key, "x")
+		toDelete = append(toDelete, key)
+	}
+	kept := prefix + "kept.txt"
+	upload(t, store, kept, "x")
+
+	// Missing keys are not failures, as for Delete
+	failed, err := store.DeleteMultiple(ctx, append(toDelete, prefix+"missing.txt"))
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("DeleteMultiple = %v, %v, want no failures", failed, err)
+	}
+	out, err := store.List(ctx, &blob.ListInput{Prefix: prefix})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := keys(out.Objects); !slices.Equal(got, []string{kept}) {
+		t.Errorf("left after DeleteMultiple = %v, want %v", got, []string{kept})
+	}
+	if failed, err := store.DeleteMultiple(ctx, nil); err != nil || len(failed) != 0 {
+		t.Errorf("DeleteMultiple of nothing = %v, %v", failed, err)
+	}
+}
+
+// upload stores content under key, failing the test on error
+func upload(t *testing.T, store blob.Store, key, content string) {
+	t.Helper()
+	if _, err := store.Upload(context.Background(), &blob.UploadInput{Key: key, Body: strings.NewReader(content), ContentType: "text/plain"}); err != nil {
+		t.Fatalf("Upload %s: %v", key, err)
+	}
+}
+
+// get returns the content under key, failing the test on error
+func get(t *testing.T, store blob.Store, key string) string {
+	t.Helper()
+	body, err := store.GetObject(context.Background(), key)
+	if err != nil {
+		t.Fatalf("GetObject %s: %v", key, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read %s: %v", key, err)
+	}
+	return string(data)
+}
+
+// keys returns the keys of objects
+func keys(objects []blob.ObjectInfo) []string {
+	out := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		out = append(out, obj.Key)
+	}
+	return out
+}
+
+// deletePrefix removes everything under prefix, reporting what it could not
+func deletePrefix(t *testing.T, store blob.Store, prefix string) {
+	ctx := context.Background()
+	for {
+		out, err := store.List(ctx, &blob.ListInput{Prefix: prefix})
+		if err != nil {
+			t.Errorf("cleanup: List %s: %v", prefix, err)
+			return
+		}
+		if len(out.Objects) == 0 {
+			return
+		}
+		if failed, err := store.DeleteMultiple(ctx, keys(out.Objects)); err != nil {
+			t.Errorf("cleanup: %d objects left under %s: %v", len(failed), prefix, err)
+			return
+		}
+		if !out.IsTruncated {
+			return
+		}
+	}
+}
+
+// writeAtBuffer is an in-memory io.WriterAt for Download
+type writeAtBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *writeAtBuffer) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if end := int(off) + len(p); end > len(w.buf) {
+		w.buf = append(w.buf, make([]byte, end-len(w.buf))...)
+	}
+	return copy(w.buf[off:], p), nil
+}
+
+func (w *writeAtBuffer) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return string(bytes.Clone(w.buf))
+}
@@ -0,0 +1,14 @@
+package blob
+
+import "errors"
+
+// Sentinel errors returned by every Store implementation, comparable with errors.Is()
+var (
+	ErrNotFound       = errors.New("blob not found")
+	ErrAlreadyExists  = errors.New("blob already exists")
+	ErrUploadFailed   = errors.New("blob upload failed")
+	ErrDownloadFailed = errors.New("blob download failed")
+	ErrDeleteFailed   = errors.New("blob delete failed")
+	ErrInvalidKey     = errors.New("invalid blob key")
+	ErrInvalidInput   = errors.New("invalid blob input")
+)
@@ -14,8 +14,7 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
-	"github.com/TopThisHat/stdlib-golang-api/internal/logger"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
 )
 
 // Ensure FileSystemStore implements the Store interface at compile time
@@ -106,13 +105,13 @@ func NewFileSystemStore(basePath string, log *logger.Logger, opts ...FileSystemO
 // fullPath constructs the full file path for a key
 func (f *FileSystemStore) fullPath(key string) (string, error) {
 	if key == "" {
-		return "", domain.ErrInvalidBlobKey
+		return "", ErrInvalidKey
 	}
 
 	// Prevent path traversal attacks
 	cleanKey := filepath.Clean(key)
 	if strings.HasPrefix(cleanKey, "..") || filepath.IsAbs(cleanKey) {
-		return "", fmt.Errorf("%w: invalid key path", domain.ErrInvalidBlobKey)
+		return "", fmt.Errorf("%w: invalid key path", ErrInvalidKey)
 	}
 
 	return filepath.Join(f.basePath, cleanKey), nil
@@ -121,11 +120,11 @@ func (f *FileSystemStore) fullPath(key string) (string, error) {
 // Upload uploads an object to the file system.
 func (f *FileSystemStore) Upload(ctx context.Context, input *UploadInput) (*UploadOutput, error) {
 	if input.Key == "" {
-		return nil, domain.ErrInvalidBlobKey
+		return nil, ErrInvalidKey
 	}
 
 	if input.Body == nil {
-		return nil, fmt.Errorf("%w: body is required", domain.ErrInvalidInput)
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidInput)
 	}
 
 	fullPath, err := f.fullPath(input.Key)
@@ -141,7 +140,7 @@ func (f *FileSystemStore) Upload(ctx context.Context, input *UploadInput) (*Uplo
 			"path", dir,
 			"error", err,
 		)
-		return nil, fmt.Errorf("%w: %v", domain.ErrBlobUploadFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
 	}
 
 	// Check context before starting write
@@ -159,7 +158,7 @@ func (f *FileSystemStore) Upload(ctx context.Context, input *UploadInput) (*Uplo
 			"key", input.Key,
 			"error", err,
 		)
-		return nil, fmt.Errorf("%w: %v", domain.ErrBlobUploadFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
 	}
 	tmpPath := tmpFile.Name()
 	defer func() {
@@ -177,12 +176,12 @@ func (f *FileSystemStore) Upload(ctx context.Context, input *UploadInput) (*Uplo
 			"key", input.Key,
 			"error", err,
 		)
-		return nil, fmt.Errorf("%w: %v", domain.ErrBlobUploadFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
 	}
 
 	// Close the temp file before renaming
 	if err := tmpFile.Close(); err != nil {
-		return nil, fmt.Errorf("%w: %v", domain.ErrBlobUploadFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
 	}
 
 	// Atomic rename
@@ -191,7 +190,7 @@ func (f *FileSystemStore) Upload(ctx context.Context, input *UploadInput) (*Uplo
 			"key", input.Key,
 			"error", err,
 		)
-		return nil, fmt.Errorf("%w: %v", domain.ErrBlobUploadFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
 	}
 
 	etag := hex.EncodeToString(hash.Sum(nil))
@@ -220,13 +219,13 @@ func (f *FileSystemStore) Download(ctx context.Context, key string, w io.WriterA
 	file, err := os.Open(fullPath)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			return 0, domain.ErrBlobNotFound
+			return 0, ErrNotFound
 		}
 		f.logger.Error("failed to open file",
 			"key", key,
 			"error", err,
 		)
-		return 0, fmt.Errorf("%w: %v", domain.ErrBlobDownloadFailed, err)
+		return 0, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
 	}
 	defer file.Close()
 
@@ -242,12 +241,12 @@ func (f *FileSystemStore) Download(ctx context.Context, key string, w io.WriterA
 			"key", key,
 			"error", err,
 		)
-		return 0, fmt.Errorf("%w: %v", domain.ErrBlobDownloadFailed, err)
+		return 0, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
 	}
 
 	n, err := w.WriteAt(data, 0)
 	if err != nil {
-		return int64(n), fmt.Errorf("%w: %v", domain.ErrBlobDownloadFailed, err)
+		return int64(n), fmt.Errorf("%w: %v", ErrDownloadFailed, err)
 	}
 
 	f.logger.Debug("file downloaded successfully",
@@ -272,13 +271,13 @@ func (f *FileSystemStore) GetObject(ctx context.Context, key string) (io.ReadClo
 
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			return nil, domain.ErrBlobNotFound
+			return nil, ErrNotFound
 		}
 		f.logger.Error("failed to open file",
 			"key", key,
 			"error", err,
 		)
-		return nil, fmt.Errorf("%w: %v", domain.ErrBlobDownloadFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
 	}
 
 	return file, nil
@@ -297,7 +296,7 @@ func (f *FileSystemStore) HeadObject(ctx context.Context, key string) (*ObjectIn
 
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			return nil, domain.ErrBlobNotFound
+			return nil, ErrNotFound
 		}
 		f.logger.Error("failed to stat file",
 			"key", key,
@@ -307,7 +306,7 @@ func (f *FileSystemStore) HeadObject(ctx context.Context, key string) (*ObjectIn
 	}
 
 	if info.IsDir() {
-		return nil, domain.ErrBlobNotFound
+		return nil, ErrNotFound
 	}
 
 	return &ObjectInfo{
@@ -337,7 +336,7 @@ func (f *FileSystemStore) Delete(ctx context.Context, key string) error {
 			"key", key,
 			"error", err,
 		)
-		return fmt.Errorf("%w: %v", domain.ErrBlobDeleteFailed, err)
+		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
 	}
 
 	f.logger.Debug("file deleted successfully", "key", key)
@@ -362,7 +361,7 @@ func (f *FileSystemStore) DeleteMultiple(ctx context.Context, keys []string) ([]
 			return failedKeys, err
 		}
 
-		if err := f.Delete(ctx, key); err != nil && !errors.Is(err, domain.ErrBlobNotFound) {
+		if err := f.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
 			mu.Lock()
 			failedKeys = append(failedKeys, key)
 			mu.Unlock()
@@ -370,7 +369,7 @@ func (f *FileSystemStore) DeleteMultiple(ctx context.Context, keys []string) ([]
 	}
 
 	if len(failedKeys) > 0 {
-		return failedKeys, fmt.Errorf("%w: %d files failed to delete", domain.ErrBlobDeleteFailed, len(failedKeys))
+		return failedKeys, fmt.Errorf("%w: %d files failed to delete", ErrDeleteFailed, len(failedKeys))
 	}
 
 	f.logger.Debug("files deleted successfully", "count", len(keys))
@@ -475,7 +474,7 @@ func (f *FileSystemStore) List(ctx context.Context, input *ListInput) (*ListOutp
 func (f *FileSystemStore) Exists(ctx context.Context, key string) (bool, error) {
 	_, err := f.HeadObject(ctx, key)
 	if err != nil {
-		if errors.Is(err, domain.ErrBlobNotFound) {
+		if errors.Is(err, ErrNotFound) {
 			return false, nil
 		}
 		return false, err
@@ -486,7 +485,7 @@ func (f *FileSystemStore) Exists(ctx context.Context, key string) (bool, error)
 // Copy copies an object within the file system.
 func (f *FileSystemStore) Copy(ctx context.Context, sourceKey, destKey string) error {
 	if sourceKey == "" || destKey == "" {
-		return domain.ErrInvalidBlobKey
+		return ErrInvalidKey
 	}
 
 	sourcePath, err := f.fullPath(sourceKey)
@@ -506,7 +505,7 @@ func (f *FileSystemStore) Copy(ctx context.Context, sourceKey, destKey string) e
 	sourceFile, err := os.Open(sourcePath)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			return domain.ErrBlobNotFound
+			return ErrNotFound
 		}
 		return fmt.Errorf("failed to open source file: %w", err)
 	}
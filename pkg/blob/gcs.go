@@ -0,0 +1,502 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// Ensure GCSStore implements the interfaces at compile time
+var (
+	_ Store                 = (*GCSStore)(nil)
+	_ PresignedURLGenerator = (*GCSStore)(nil)
+	_ FullStore             = (*GCSStore)(nil)
+)
+
+// gcsStorageClasses are the storage classes (UploadInput.StorageClass)
+// uploads can set, by lowercase name
+var gcsStorageClasses = map[string]string{"standard": "STANDARD", "nearline": "NEARLINE", "coldline": "COLDLINE", "archive": "ARCHIVE"}
+
+// GCSStore provides operations for interacting with Google Cloud Storage,
+// through the Cloud Storage SDK over its JSON API.
+// It implements the Store and PresignedURLGenerator interfaces; pre-signed
+// URLs are V4 signed URLs. Cloud Storage has no object tags, so UploadInput.Tags
+// are validated but not stored.
+type GCSStore struct {
+	client  *storage.Client
+	bucket  *storage.BucketHandle
+	name    string
+	options *gcsOptions
+	logger  *logger.Logger
+}
+
+// GCSOption defines functional options for configuring GCSStore
+type GCSOption func(*gcsOptions)
+
+type gcsOptions struct {
+	chunkSize int
+
+	// Retries of throttled and failed idempotent requests
+	retryMaxAttempts int
+	retryMaxBackoff  time.Duration
+}
+
+// defaultGCSOptions returns sensible defaults for Cloud Storage operations
+func defaultGCSOptions() *gcsOptions {
+	return &gcsOptions{
+		chunkSize:        8 * 1024 * 1024, // 8 MB
+		retryMaxAttempts: 3,
+		retryMaxBackoff:  20 * time.Second,
+	}
+}
+
+// WithGCSChunkSize sets the size of the chunks larger uploads are sent in
+// (at least 256 KB, rounded up to a multiple of it); smaller uploads are
+// sent in one request
+func WithGCSChunkSize(size int) GCSOption {
+	return func(o *gcsOptions) {
+		if size >= 256*1024 {
+			o.chunkSize = size
+		}
+	}
+}
+
+// WithGCSRetry sets how many times idempotent requests are tried, the first
+// included, and the longest backoff between tries. Throttling (429), server
+// errors and dropped connections are retried with jittered exponential
+// backoff; maxAttempts of 1 disables retries.
+func WithGCSRetry(maxAttempts int, maxBackoff time.Duration) GCSOption {
+	return func(o *gcsOptions) {
+		if maxAttempts > 0 {
+			o.retryMaxAttempts = maxAttempts
+		}
+		if maxBackoff > 0 {
+			o.retryMaxBackoff = maxBackoff
+		}
+	}
+}
+
+// GCSConfig identifies the bucket and the credentials authorizing requests
+// to it
+type GCSConfig struct {
+	Bucket string
+	// CredentialsFile is a service account key file, empty for Application
+	// Default Credentials (GOOGLE_APPLICATION_CREDENTIALS, or the attached
+	// service account on GCE, GKE and Cloud Run). Signed URLs are signed
+	// with the key, or through the IAM signBlob API without one.
+	CredentialsFile string
+	// Endpoint is the JSON API endpoint, empty for
+	// https://storage.googleapis.com/storage/v1/. Emulators such as
+	// fake-gcs-server use http://127.0.0.1:4443/storage/v1/.
+	Endpoint string
+}
+
+// NewGCSStore creates a new Cloud Storage store for cfg.Bucket, which must
+// exist
+func NewGCSStore(ctx context.Context, cfg GCSConfig, log *logger.Logger, opts ...GCSOption) (*GCSStore, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("gcs bucket name is required")
+	}
+
+	options := defaultGCSOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	clientOptions := []option.ClientOption{storage.WithJSONReads(), storage.WithDisabledClientMetrics()}
+	if cfg.CredentialsFile != "" {
+		clientOptions = append(clientOptions, option.WithAuthCredentialsFile(option.ServiceAccount, cfg.CredentialsFile))
+	}
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("gcs endpoint must be an http or https URL")
+		}
+		clientOptions = append(clientOptions, option.WithEndpoint(strings.TrimSuffix(cfg.Endpoint, "/")+"/"))
+	}
+	client, err := storage.NewClient(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("creating gcs client: %w", err)
+	}
+
+	bucket := client.Bucket(cfg.Bucket).Retryer(
+		storage.WithMaxAttempts(options.retryMaxAttempts),
+		storage.WithBackoff(gax.Backoff{Initial: 100 * time.Millisecond, Max: options.retryMaxBackoff, Multiplier: 2}),
+	)
+
+	log.Info("GCS blob store initialized", "bucket", cfg.Bucket)
+
+	return &GCSStore{
+		client:  client,
+		bucket:  bucket,
+		name:    cfg.Bucket,
+		options: options,
+		logger:  log,
+	}, nil
+}
+
+// isNotFoundError checks if the error indicates the object was not found
+func (s *GCSStore) isNotFoundError(err error) bool {
+	return errors.Is(err, storage.ErrObjectNotExist)
+}
+
+// gcsObjectAttrs returns the attributes of an upload of input, validated
+func gcsObjectAttrs(input *UploadInput) (storage.ObjectAttrs, error) {
+	attrs := storage.ObjectAttrs{
+		Name:               input.Key,
+		ContentType:        input.ContentType,
+		CacheControl:       input.CacheControl,
+		ContentDisposition: input.ContentDisposition,
+		Metadata:           input.Metadata,
+	}
+	if attrs.ContentType == "" {
+		attrs.ContentType = "application/octet-stream"
+	}
+	if err := validateTags(input.Tags); err != nil {
+		return attrs, err
+	}
+	if input.StorageClass != "" {
+		class, ok := gcsStorageClasses[strings.ToLower(input.StorageClass)]
+		if !ok {
+			return attrs, fmt.Errorf("%w: unknown storage class %q (must be STANDARD, NEARLINE, COLDLINE or ARCHIVE)", ErrInvalidInput, input.StorageClass)
+		}
+		attrs.StorageClass = class
+	}
+	return attrs, nil
+}
+
+// gcsObjectInfo converts the attributes Cloud Storage returns for an object
+func gcsObjectInfo(attrs *storage.ObjectAttrs) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          attrs.Name,
+		Size:         attrs.Size,
+		ContentType:  attrs.ContentType,
+		ETag:         attrs.Etag,
+		LastModified: attrs.Updated,
+		Metadata:     attrs.Metadata,
+
+		StorageClass:       attrs.StorageClass,
+		CacheControl:       attrs.CacheControl,
+		ContentDisposition: attrs.ContentDisposition,
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Store
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Upload uploads an object, replacing any object under the same key. Bodies
+// larger than the chunk size are sent chunk by chunk in a resumable upload,
+// so no more than one chunk is held in memory. The object only exists once
+// the whole body was read: a body failing halfway stores nothing.
+func (s *GCSStore) Upload(ctx context.Context, input *UploadInput) (*UploadOutput, error) {
+	if input.Key == "" {
+		return nil, ErrInvalidKey
+	}
+	if input.Body == nil {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidInput)
+	}
+	attrs, err := gcsObjectAttrs(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Canceling the writer's context abandons the upload
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := s.bucket.Object(input.Key).NewWriter(ctx)
+	w.ObjectAttrs = attrs
+	w.ChunkSize = s.options.chunkSize
+
+	if _, err = io.Copy(w, input.Body); err != nil {
+		cancel()
+		w.Close()
+	} else {
+		err = w.Close()
+	}
+	if err != nil {
+		s.logger.Error("failed to upload object",
+			"key", input.Key,
+			"bucket", s.name,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+
+	location := "gs://" + s.name + "/" + input.Key
+	s.logger.Debug("object uploaded successfully",
+		"key", input.Key,
+		"location", location,
+	)
+
+	written := w.Attrs()
+	return &UploadOutput{
+		Location:  location,
+		VersionID: strconv.FormatInt(written.Generation, 10),
+		ETag:      written.Etag,
+	}, nil
+}
+
+// Download downloads an object into the provided writer.
+func (s *GCSStore) Download(ctx context.Context, key string, w io.WriterAt) (int64, error) {
+	body, err := s.GetObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	defer body.Close()
+
+	n, err := io.Copy(io.NewOffsetWriter(w, 0), body)
+	if err != nil {
+		s.logger.Error("failed to download object",
+			"key", key,
+			"bucket", s.name,
+			"error", err,
+		)
+		return n, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	s.logger.Debug("object downloaded successfully",
+		"key", key,
+		"bytes", n,
+	)
+
+	return n, nil
+}
+
+// GetObject retrieves an object and returns it as a ReadCloser.
+// The caller is responsible for closing the returned reader.
+func (s *GCSStore) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	r, err := s.bucket.Object(key).NewReader(ctx)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to get object",
+			"key", key,
+			"bucket", s.name,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	return r, nil
+}
+
+// HeadObject retrieves metadata about an object without downloading it.
+func (s *GCSStore) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	attrs, err := s.bucket.Object(key).Attrs(ctx)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to head object",
+			"key", key,
+			"bucket", s.name,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+
+	info := gcsObjectInfo(attrs)
+	if info.Metadata == nil {
+		info.Metadata = map[string]string{}
+	}
+	return info, nil
+}
+
+// Delete removes an object; deleting a missing object succeeds
+// (idempotent), as it does on S3.
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+
+	if err := s.bucket.Object(key).Delete(ctx); err != nil {
+		if s.isNotFoundError(err) {
+			return nil
+		}
+		s.logger.Error("failed to delete object",
+			"key", key,
+			"bucket", s.name,
+			"error", err,
+		)
+		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	}
+
+	s.logger.Debug("object deleted successfully", "key", key)
+	return nil
+}
+
+// DeleteMultiple removes multiple objects, one request each.
+// It returns the keys that failed to delete along with any error.
+func (s *GCSStore) DeleteMultiple(ctx context.Context, keys []string) ([]string, error) {
+	var failedKeys []string
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			failedKeys = append(failedKeys, key)
+		}
+	}
+
+	if len(failedKeys) > 0 {
+		return failedKeys, fmt.Errorf("%w: %d objects failed to delete", ErrDeleteFailed, len(failedKeys))
+	}
+
+	s.logger.Debug("objects deleted successfully", "count", len(keys))
+	return nil, nil
+}
+
+// List lists objects in key order with optional filtering by prefix.
+// Cloud Storage starts listings at a key, so StartAfter is any key, not
+// only a NextMarker.
+func (s *GCSStore) List(ctx context.Context, input *ListInput) (*ListOutput, error) {
+	maxKeys := int(input.MaxKeys)
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	query := &storage.Query{Prefix: input.Prefix}
+	if input.StartAfter != "" {
+		query.StartOffset = input.StartAfter + "\x00" // The first key after it
+	}
+	if err := query.SetAttrSelection([]string{"Name", "Size", "ContentType", "Etag", "Updated"}); err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	it := s.bucket.Objects(ctx, query)
+	it.PageInfo().MaxSize = maxKeys + 1 // One more tells whether the listing goes on
+
+	output := &ListOutput{Objects: []ObjectInfo{}}
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			s.logger.Error("failed to list objects",
+				"bucket", s.name,
+				"prefix", input.Prefix,
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		if len(output.Objects) == maxKeys {
+			output.IsTruncated = true
+			break
+		}
+		output.Objects = append(output.Objects, *gcsObjectInfo(attrs))
+	}
+
+	if output.IsTruncated {
+		output.NextMarker = output.Objects[len(output.Objects)-1].Key
+	}
+
+	return output, nil
+}
+
+// Exists checks if an object exists.
+func (s *GCSStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.HeadObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Copy copies an object within the bucket, with its properties and
+// metadata. Cloud Storage rewrites large objects in several calls, which
+// Copy makes until the copy is complete.
+func (s *GCSStore) Copy(ctx context.Context, sourceKey, destKey string) error {
+	if sourceKey == "" || destKey == "" {
+		return ErrInvalidKey
+	}
+
+	if _, err := s.bucket.Object(destKey).CopierFrom(s.bucket.Object(sourceKey)).Run(ctx); err != nil {
+		if s.isNotFoundError(err) {
+			return ErrNotFound
+		}
+		s.logger.Error("failed to copy object",
+			"source", sourceKey,
+			"dest", destKey,
+			"bucket", s.name,
+			"error", err,
+		)
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	s.logger.Debug("object copied successfully",
+		"source", sourceKey,
+		"dest", destKey,
+	)
+	return nil
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Signed URLs
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// GeneratePresignedURL generates a V4 signed URL for downloading an object.
+// The URL is valid for the specified duration, at most 7 days.
+func (s *GCSStore) GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	return s.signedURL(key, &storage.SignedURLOptions{Method: http.MethodGet}, expiration)
+}
+
+// GeneratePresignedUploadURL generates a V4 signed URL for uploading an
+// object. The URL is valid for the specified duration, at most 7 days.
+// Uploads are a PUT, and must send contentType as their Content-Type: the
+// signature covers it.
+func (s *GCSStore) GeneratePresignedUploadURL(ctx context.Context, key string, contentType string, expiration time.Duration) (string, error) {
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	return s.signedURL(key, &storage.SignedURLOptions{Method: http.MethodPut, ContentType: contentType}, expiration)
+}
+
+// signedURL signs opts for object key until expiration from now
+func (s *GCSStore) signedURL(key string, opts *storage.SignedURLOptions, expiration time.Duration) (string, error) {
+	opts.Scheme = storage.SigningSchemeV4
+	opts.Expires = time.Now().Add(expiration)
+	signed, err := s.bucket.SignedURL(key, opts)
+	if err != nil {
+		return "", fmt.Errorf("signing URL: %w", err)
+	}
+	return signed, nil
+}
+
+// Bucket returns the configured bucket name
+func (s *GCSStore) Bucket() string {
+	return s.name
+}
+
+// Close releases the client's connections
+func (s *GCSStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,518 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// fakeGCSObject is the resource of an object, as the JSON API returns it
+type fakeGCSObject struct {
+	Kind               string            `json:"kind"`
+	Bucket             string            `json:"bucket"`
+	Name               string            `json:"name"`
+	Size               string            `json:"size"`
+	ContentType        string            `json:"contentType,omitempty"`
+	CacheControl       string            `json:"cacheControl,omitempty"`
+	ContentDisposition string            `json:"contentDisposition,omitempty"`
+	StorageClass       string            `json:"storageClass,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	Etag               string            `json:"etag"`
+	Generation         string            `json:"generation"`
+	Metageneration     string            `json:"metageneration"`
+	Updated            string            `json:"updated"`
+
+	data []byte
+}
+
+// fakeGCSUpload is a resumable upload in progress
+type fakeGCSUpload struct {
+	object fakeGCSObject
+	data   []byte
+}
+
+// fakeGCS answers the JSON API calls on one bucket the tests make
+type fakeGCS struct {
+	mu          sync.Mutex
+	bucket      string
+	objects     map[string]*fakeGCSObject
+	uploads     map[string]*fakeGCSUpload
+	chunks      int      // Chunks of resumable uploads received
+	unsigned    int      // Requests without a bearer token
+	uploadTypes []string // uploadType of every upload started
+	generation  int
+}
+
+func newFakeGCS(bucket string) *fakeGCS {
+	return &fakeGCS{bucket: bucket, objects: make(map[string]*fakeGCSObject), uploads: make(map[string]*fakeGCSUpload)}
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token":"fake-token","token_type":"Bearer","expires_in":3600}`)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		f.unsigned++
+		f.fail(w, http.StatusUnauthorized, "Anonymous caller")
+		return
+	}
+
+	// Object names are escaped path segments, which may hold slashes
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		s, err := url.PathUnescape(segment)
+		if err != nil {
+			f.fail(w, http.StatusBadRequest, "Invalid path")
+			return
+		}
+		segments = append(segments, s)
+	}
+	query := r.URL.Query()
+	upload := len(segments) > 0 && segments[0] == "upload"
+	if upload {
+		segments = segments[1:]
+	}
+	if len(segments) < 5 || segments[0] != "storage" || segments[1] != "v1" || segments[2] != "b" || segments[3] != f.bucket || segments[4] != "o" {
+		f.fail(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	segments = segments[5:]
+
+	switch {
+	case upload && query.Get("upload_id") != "":
+		f.uploadChunk(w, r, query.Get("upload_id"), body)
+	case upload && r.Method == http.MethodPost:
+		f.startUpload(w, r, query, body)
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		f.list(w, query)
+	case len(segments) == 6 && segments[1] == "rewriteTo" && r.Method == http.MethodPost:
+		src, ok := f.objects[segments[0]]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "No such object")
+			return
+		}
+		copied := *src
+		copied.Name = segments[5] // <source>/rewriteTo/b/<bucket>/o/<destination>
+		copied.Metadata = maps.Clone(src.Metadata)
+		dst := f.store(copied, bytes.Clone(src.data))
+		f.respond(w, map[string]any{
+			"kind":                "storage#rewriteResponse",
+			"totalBytesRewritten": dst.Size,
+			"objectSize":          dst.Size,
+			"done":                true,
+			"resource":            dst,
+		})
+	case len(segments) == 1:
+		object, ok := f.objects[segments[0]]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "No such object")
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.objects, segments[0])
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && query.Get("alt") == "media":
+			w.Header().Set("Content-Type", object.ContentType)
+			w.Header().Set("Content-Length", object.Size)
+			w.Header().Set("X-Goog-Generation", object.Generation)
+			w.Write(object.data)
+		case r.Method == http.MethodGet:
+			f.respond(w, object)
+		default:
+			f.fail(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	default:
+		f.fail(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// startUpload answers a multipart upload, which holds the whole object, or
+// starts a resumable one
+func (f *fakeGCS) startUpload(w http.ResponseWriter, r *http.Request, query url.Values, body []byte) {
+	f.uploadTypes = append(f.uploadTypes, query.Get("uploadType"))
+	switch query.Get("uploadType") {
+	case "multipart":
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			f.fail(w, http.StatusBadRequest, "Invalid multipart request")
+			return
+		}
+		parts := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		var object fakeGCSObject
+		var data []byte
+		for i := 0; ; i++ {
+			part, err := parts.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.fail(w, http.StatusBadRequest, "Invalid multipart request")
+				return
+			}
+			content, _ := io.ReadAll(part)
+			if i == 0 {
+				if err := json.Unmarshal(content, &object); err != nil {
+					f.fail(w, http.StatusBadRequest, "Invalid metadata")
+					return
+				}
+			} else {
+				data = content
+			}
+		}
+		f.respond(w, f.store(object, data))
+	case "resumable":
+		var object fakeGCSObject
+		if err := json.Unmarshal(body, &object); err != nil {
+			f.fail(w, http.StatusBadRequest, "Invalid metadata")
+			return
+		}
+		id := strconv.Itoa(len(f.uploadTypes))
+		f.uploads[id] = &fakeGCSUpload{object: object}
+		w.Header().Set("Location", "http://"+r.Host+"/upload/storage/v1/b/"+f.bucket+"/o?uploadType=resumable&upload_id="+id)
+		w.WriteHeader(http.StatusOK)
+	default:
+		f.fail(w, http.StatusBadRequest, "Unsupported uploadType")
+	}
+}
+
+// uploadChunk appends a chunk to a resumable upload, storing the object
+// once the chunk giving its size arrives
+func (f *fakeGCS) uploadChunk(w http.ResponseWriter, r *http.Request, id string, body []byte) {
+	upload, ok := f.uploads[id]
+	if !ok {
+		f.fail(w, http.StatusNotFound, "No such upload")
+		return
+	}
+	f.chunks++
+	upload.data = append(upload.data, body...)
+
+	// bytes <first>-<last>/<total or *>, or bytes */<total> for an empty last chunk
+	total := r.Header.Get("Content-Range")[strings.LastIndex(r.Header.Get("Content-Range"), "/")+1:]
+	if total == "*" {
+		// Clients sending X-GUploader-No-308 get a 200 saying it is a 308
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(upload.data)-1))
+		w.Header().Set("X-Http-Status-Code-Override", "308")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if total != strconv.Itoa(len(upload.data)) {
+		f.fail(w, http.StatusBadRequest, "Size mismatch")
+		return
+	}
+	delete(f.uploads, id)
+	f.respond(w, f.store(upload.object, upload.data))
+}
+
+// store saves data as object, with a new generation
+func (f *fakeGCS) store(object fakeGCSObject, data []byte) *fakeGCSObject {
+	f.generation++
+	object.Kind = "storage#object"
+	object.Bucket = f.bucket
+	object.Size = strconv.Itoa(len(data))
+	object.Generation = strconv.Itoa(f.generation)
+	object.Metageneration = "1"
+	object.Etag = fmt.Sprintf("CA%d=", f.generation)
+	object.Updated = time.Now().UTC().Format(time.RFC3339Nano)
+	if object.StorageClass == "" {
+		object.StorageClass = "STANDARD"
+	}
+	object.data = data
+	f.objects[object.Name] = &object
+	return &object
+}
+
+// list answers a listing from startOffset, continuing from page tokens that
+// are the name of the next object, reversed so clients cannot take them for
+// keys
+func (f *fakeGCS) list(w http.ResponseWriter, query url.Values) {
+	prefix := query.Get("prefix")
+	start := max(query.Get("startOffset"), reverse(query.Get("pageToken")))
+	maxResults, err := strconv.Atoi(query.Get("maxResults"))
+	if err != nil || maxResults <= 0 || maxResults > 1000 {
+		maxResults = 1000
+	}
+
+	var items []*fakeGCSObject
+	for _, name := range slices.Sorted(maps.Keys(f.objects)) {
+		if strings.HasPrefix(name, prefix) && name >= start {
+			items = append(items, f.objects[name])
+		}
+	}
+	next := ""
+	if len(items) > maxResults {
+		next = reverse(items[maxResults].Name)
+		items = items[:maxResults]
+	}
+	f.respond(w, map[string]any{"kind": "storage#objects", "items": items, "nextPageToken": next})
+}
+
+func (f *fakeGCS) respond(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// fail answers with a JSON API error
+func (f *fakeGCS) fail(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": status, "message": message}})
+}
+
+// gcsTestCredentials writes a service account key file whose tokens come
+// from tokenURL, returning its path and the account's email
+func gcsTestCredentials(t *testing.T, tokenURL string) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	email := "blob-test@project.iam.gserviceaccount.com"
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "project",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   email,
+		"client_id":      "1",
+		"token_uri":      tokenURL,
+	})
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path, email
+}
+
+// newTestGCSStore returns a GCSStore of fake, served over HTTP
+func newTestGCSStore(t *testing.T, fake *fakeGCS, opts ...GCSOption) *GCSStore {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	credentials, _ := gcsTestCredentials(t, srv.URL+"/token")
+	store, err := NewGCSStore(context.Background(), GCSConfig{
+		Bucket:          fake.bucket,
+		CredentialsFile: credentials,
+		Endpoint:        srv.URL + "/storage/v1/",
+	}, logger.NewWithOptions("error", io.Discard, false), append([]GCSOption{WithGCSRetry(1, 0)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewGCSStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// NewFakeGCSStore returns a GCSStore of a fake JSON API, for the
+// conformance tests of package blob_test
+func NewFakeGCSStore(t *testing.T) *GCSStore {
+	return newTestGCSStore(t, newFakeGCS("uploads"))
+}
+
+func TestGCSObjectAttrs(t *testing.T) {
+	attrs, err := gcsObjectAttrs(&UploadInput{
+		Key:                "report.csv",
+		Body:               strings.NewReader("a,b"),
+		StorageClass:       "nearline",
+		Metadata:           map[string]string{"owner": "ops"},
+		Tags:               map[string]string{"retention": "90 days"},
+		CacheControl:       "no-cache",
+		ContentDisposition: "attachment",
+	})
+	if err != nil {
+		t.Fatalf("gcsObjectAttrs: %v", err)
+	}
+	if attrs.Name != "report.csv" || attrs.ContentType != "application/octet-stream" {
+		t.Errorf("name, content type = %q, %q; want report.csv, application/octet-stream", attrs.Name, attrs.ContentType)
+	}
+	if attrs.StorageClass != "NEARLINE" || attrs.CacheControl != "no-cache" || attrs.ContentDisposition != "attachment" {
+		t.Errorf("attrs = %+v, want the storage class, cache control and disposition of the upload", attrs)
+	}
+	if attrs.Metadata["owner"] != "ops" {
+		t.Errorf("metadata = %v, want owner ops", attrs.Metadata)
+	}
+
+	if _, err := gcsObjectAttrs(&UploadInput{Key: "a", StorageClass: StorageClassGlacier}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("S3 storage class error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := gcsObjectAttrs(&UploadInput{Key: "a", Tags: map[string]string{"": "v"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("empty tag key error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestGCSUploadsLargeBodiesInChunks(t *testing.T) {
+	fake := newFakeGCS("uploads")
+	store := newTestGCSStore(t, fake, WithGCSChunkSize(256*1024))
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 40*1024) // 640 KiB
+	out, err := store.Upload(ctx, &UploadInput{
+		Key:          "big.bin",
+		Body:         bytes.NewReader(content),
+		StorageClass: "coldline",
+		CacheControl: "no-cache",
+		Metadata:     map[string]string{"owner": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if out.Location != "gs://uploads/big.bin" || out.VersionID == "" || out.ETag == "" {
+		t.Errorf("Upload = %+v, want the gs:// location, generation and ETag", out)
+	}
+
+	fake.mu.Lock()
+	uploadTypes, chunks := fake.uploadTypes, fake.chunks
+	fake.mu.Unlock()
+	if !slices.Equal(uploadTypes, []string{"resumable"}) || chunks != 3 {
+		t.Errorf("uploads = %v in %d chunks, want one resumable upload of 3", uploadTypes, chunks)
+	}
+
+	body, err := store.GetObject(ctx, "big.bin")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, content) {
+		t.Errorf("GetObject returned %d bytes, want the %d uploaded", len(got), len(content))
+	}
+
+	info, err := store.HeadObject(ctx, "big.bin")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.StorageClass != "COLDLINE" || info.CacheControl != "no-cache" || info.Metadata["owner"] != "ops" {
+		t.Errorf("HeadObject = class %q, cache control %q, metadata %v; want the attributes of the upload",
+			info.StorageClass, info.CacheControl, info.Metadata)
+	}
+
+	// Small bodies take one request
+	if _, err := store.Upload(ctx, &UploadInput{Key: "small.txt", Body: strings.NewReader("small")}); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if got := fake.uploadTypes[len(fake.uploadTypes)-1]; got != "multipart" {
+		t.Errorf("small upload type = %q, want multipart", got)
+	}
+	if fake.unsigned != 0 {
+		t.Errorf("%d requests without credentials", fake.unsigned)
+	}
+}
+
+func TestGCSUploadBodyFailure(t *testing.T) {
+	fake := newFakeGCS("uploads")
+	store := newTestGCSStore(t, fake, WithGCSChunkSize(256*1024))
+
+	body := io.MultiReader(bytes.NewReader(make([]byte, 300*1024)), iotest.ErrReader(errors.New("client went away")))
+	if _, err := store.Upload(context.Background(), &UploadInput{Key: "partial.bin", Body: body}); !errors.Is(err, ErrUploadFailed) {
+		t.Fatalf("Upload error = %v, want ErrUploadFailed", err)
+	}
+	if ok, err := store.Exists(context.Background(), "partial.bin"); err != nil || ok {
+		t.Errorf("Exists = %v, %v; a failed upload must store nothing", ok, err)
+	}
+}
+
+func TestGCSPresignedURL(t *testing.T) {
+	srv := httptest.NewServer(newFakeGCS("uploads"))
+	t.Cleanup(srv.Close)
+	credentials, email := gcsTestCredentials(t, srv.URL+"/token")
+	// Signed URLs are for clients, so they name Google's host, not the API endpoint
+	store, err := NewGCSStore(context.Background(), GCSConfig{Bucket: "uploads", CredentialsFile: credentials},
+		logger.NewWithOptions("error", io.Discard, false))
+	if err != nil {
+		t.Fatalf("NewGCSStore: %v", err)
+	}
+	defer store.Close()
+
+	for _, tt := range []struct {
+		name    string
+		presign func() (string, error)
+		headers string
+	}{
+		{"download", func() (string, error) {
+			return store.GeneratePresignedURL(context.Background(), "docs/a b.txt", time.Hour)
+		}, "host"},
+		{"upload", func() (string, error) {
+			return store.GeneratePresignedUploadURL(context.Background(), "docs/a b.txt", "text/plain", time.Hour)
+		}, "content-type;host"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.presign()
+			if err != nil {
+				t.Fatalf("presign: %v", err)
+			}
+			u, err := url.Parse(raw)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", raw, err)
+			}
+			if u.Scheme != "https" || u.Host != "storage.googleapis.com" || u.Path != "/uploads/docs/a b.txt" {
+				t.Errorf("URL = %s, want https://storage.googleapis.com/uploads/docs/a b.txt", raw)
+			}
+			query := u.Query()
+			if got := query.Get("X-Goog-Algorithm"); got != "GOOG4-RSA-SHA256" {
+				t.Errorf("X-Goog-Algorithm = %q, want GOOG4-RSA-SHA256", got)
+			}
+			if got := query.Get("X-Goog-Credential"); !strings.HasPrefix(got, email+"/") {
+				t.Errorf("X-Goog-Credential = %q, want the service account's", got)
+			}
+			if got, _ := strconv.Atoi(query.Get("X-Goog-Expires")); got < 3599 || got > 3600 {
+				t.Errorf("X-Goog-Expires = %q, want an hour", query.Get("X-Goog-Expires"))
+			}
+			if got := query.Get("X-Goog-SignedHeaders"); got != tt.headers {
+				t.Errorf("X-Goog-SignedHeaders = %q, want %q", got, tt.headers)
+			}
+			if query.Get("X-Goog-Signature") == "" {
+				t.Errorf("query %v has no signature", query)
+			}
+		})
+	}
+
+	if _, err := store.GeneratePresignedURL(context.Background(), "", time.Hour); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("empty key error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestNewGCSStoreValidatesConfig(t *testing.T) {
+	log := logger.NewWithOptions("error", io.Discard, false)
+	if _, err := NewGCSStore(context.Background(), GCSConfig{}, log); err == nil {
+		t.Error("NewGCSStore without a bucket succeeded")
+	}
+	if _, err := NewGCSStore(context.Background(), GCSConfig{Bucket: "uploads", Endpoint: "127.0.0.1:4443"}, log); err == nil {
+		t.Error("NewGCSStore with an endpoint that is no URL succeeded")
+	}
+}
@@ -0,0 +1,220 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ensure MemoryStore implements the Store interface at compile time
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore keeps blobs in process memory.
+// It implements the Store interface for demos and tests that must not touch
+// disk or network; everything is lost when the process exits.
+// Note: MemoryStore does not implement PresignedURLGenerator.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+// NewMemoryStore creates an empty in-memory blob store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]memoryObject)}
+}
+
+// Upload stores an object, replacing any object under the same key.
+func (m *MemoryStore) Upload(ctx context.Context, input *UploadInput) (*UploadOutput, error) {
+	if input.Key == "" {
+		return nil, ErrInvalidKey
+	}
+	if input.Body == nil {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidInput)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+	sum := md5.Sum(data)
+	etag := hex.EncodeToString(sum[:])
+
+	contentType := input.ContentType
+	if contentType == "" {
+		contentType = detectContentType(input.Key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[input.Key] = memoryObject{
+		data: data,
+		info: ObjectInfo{
+			Key:          input.Key,
+			Size:         int64(len(data)),
+			ContentType:  contentType,
+			ETag:         etag,
+			LastModified: time.Now(),
+			Metadata:     maps.Clone(input.Metadata),
+
+			CacheControl:       input.CacheControl,
+			ContentDisposition: input.ContentDisposition,
+		},
+	}
+
+	return &UploadOutput{
+		Location: "memory://" + input.Key,
+		ETag:     etag,
+	}, nil
+}
+
+// Download writes an object into the provided writer.
+func (m *MemoryStore) Download(ctx context.Context, key string, w io.WriterAt) (int64, error) {
+	obj, err := m.get(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.WriteAt(obj.data, 0)
+	if err != nil {
+		return int64(n), fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	return int64(n), nil
+}
+
+// GetObject returns an object as a ReadCloser.
+func (m *MemoryStore) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+// HeadObject returns an object's metadata.
+func (m *MemoryStore) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	obj, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+	info := obj.info
+	info.Metadata = maps.Clone(info.Metadata)
+	return &info, nil
+}
+
+// Delete removes an object; deleting a missing object succeeds (idempotent).
+func (m *MemoryStore) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+// DeleteMultiple removes several objects.
+func (m *MemoryStore) DeleteMultiple(ctx context.Context, keys []string) ([]string, error) {
+	for _, key := range keys {
+		if err := m.Delete(ctx, key); err != nil {
+			return keys, err
+		}
+	}
+	return nil, nil
+}
+
+// List lists objects in key order with optional filtering.
+func (m *MemoryStore) List(ctx context.Context, input *ListInput) (*ListOutput, error) {
+	maxKeys := int(input.MaxKeys)
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	m.mu.RLock()
+	var objects []ObjectInfo
+	for key, obj := range m.objects {
+		if !strings.HasPrefix(key, input.Prefix) || (input.StartAfter != "" && key <= input.StartAfter) {
+			continue
+		}
+		info := obj.info
+		info.Metadata = maps.Clone(info.Metadata)
+		objects = append(objects, info)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	isTruncated := len(objects) > maxKeys
+	if isTruncated {
+		objects = objects[:maxKeys]
+	}
+
+	output := &ListOutput{
+		Objects:     objects,
+		IsTruncated: isTruncated,
+	}
+	if len(objects) > 0 {
+		output.NextMarker = objects[len(objects)-1].Key
+	}
+	return output, nil
+}
+
+// Exists checks if an object exists.
+func (m *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	if key == "" {
+		return false, ErrInvalidKey
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+// Copy copies an object to another key.
+func (m *MemoryStore) Copy(ctx context.Context, sourceKey, destKey string) error {
+	if sourceKey == "" || destKey == "" {
+		return ErrInvalidKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	obj, ok := m.objects[sourceKey]
+	if !ok {
+		return ErrNotFound
+	}
+	obj.info.Key = destKey
+	obj.info.LastModified = time.Now()
+	obj.info.Metadata = maps.Clone(obj.info.Metadata)
+	m.objects[destKey] = obj // Stored data is never modified in place, so it may be shared
+	return nil
+}
+
+// get returns the object under key
+func (m *MemoryStore) get(key string) (memoryObject, error) {
+	if key == "" {
+		return memoryObject{}, ErrInvalidKey
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	obj, ok := m.objects[key]
+	if !ok {
+		return memoryObject{}, ErrNotFound
+	}
+	return obj, nil
+}
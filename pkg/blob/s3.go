@@ -0,0 +1,1371 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithymiddleware "github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Ensure S3Store implements the interfaces at compile time
+var (
+	_ Store                  = (*S3Store)(nil)
+	_ PresignedURLGenerator  = (*S3Store)(nil)
+	_ PresignedPostGenerator = (*S3Store)(nil)
+	_ LifecycleManager       = (*S3Store)(nil)
+	_ MultipartUploader      = (*S3Store)(nil)
+	_ FullStore              = (*S3Store)(nil)
+)
+
+// S3 storage classes (UploadInput.StorageClass). Objects in the Glacier and
+// Deep Archive classes must be restored before they can be read.
+const (
+	StorageClassStandard           = "STANDARD"
+	StorageClassStandardIA         = "STANDARD_IA"
+	StorageClassOneZoneIA          = "ONEZONE_IA"
+	StorageClassIntelligentTiering = "INTELLIGENT_TIERING"
+	StorageClassGlacierIR          = "GLACIER_IR" // Glacier Instant Retrieval, readable right away
+	StorageClassGlacier            = "GLACIER"
+	StorageClassDeepArchive        = "DEEP_ARCHIVE"
+)
+
+// Object tag limits, the same on S3 and Azure
+const (
+	maxTags           = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// S3Store provides operations for interacting with AWS S3.
+// It implements the Store, PresignedURLGenerator, PresignedPostGenerator,
+// LifecycleManager and MultipartUploader interfaces.
+type S3Store struct {
+	client     *s3.Client
+	uploader   *manager.Uploader
+	downloader *manager.Downloader
+	bucket     string
+	logger     *logger.Logger
+
+	// Server-side encryption of uploads that don't choose one
+	encryption        string
+	kmsKeyID          string
+	requireEncryption bool
+}
+
+// S3Option defines functional options for configuring S3Store
+type S3Option func(*s3Options)
+
+type s3Options struct {
+	// Upload configuration
+	uploadPartSize      int64
+	uploadConcurrency   int
+	uploadLeavePartsErr bool
+
+	// Download configuration
+	downloadPartSize    int64
+	downloadConcurrency int
+
+	// Custom endpoint for testing (e.g., LocalStack, MinIO)
+	customEndpoint string
+	usePathStyle   bool
+
+	// Retries (zero keeps the SDK's standard retryer defaults)
+	retryMaxAttempts int
+	retryMaxBackoff  time.Duration
+
+	// Request ID of the caller, sent with every request (nil sends none)
+	requestID func(context.Context) string
+
+	// Server-side encryption (empty leaves it to the bucket's default)
+	encryption        string
+	kmsKeyID          string
+	requireEncryption bool
+}
+
+// defaultS3Options returns sensible defaults for S3 operations
+func defaultS3Options() *s3Options {
+	return &s3Options{
+		uploadPartSize:      10 * 1024 * 1024, // 10 MB
+		uploadConcurrency:   5,
+		uploadLeavePartsErr: false,
+		downloadPartSize:    10 * 1024 * 1024, // 10 MB
+		downloadConcurrency: 5,
+		usePathStyle:        false,
+	}
+}
+
+// WithUploadPartSize sets the part size for multipart uploads (minimum 5MB)
+func WithUploadPartSize(size int64) S3Option {
+	return func(o *s3Options) {
+		if size >= 5*1024*1024 { // AWS minimum is 5MB
+			o.uploadPartSize = size
+		}
+	}
+}
+
+// WithUploadConcurrency sets the number of concurrent upload goroutines
+func WithUploadConcurrency(n int) S3Option {
+	return func(o *s3Options) {
+		if n > 0 {
+			o.uploadConcurrency = n
+		}
+	}
+}
+
+// WithDownloadPartSize sets the part size for multipart downloads
+func WithDownloadPartSize(size int64) S3Option {
+	return func(o *s3Options) {
+		if size > 0 {
+			o.downloadPartSize = size
+		}
+	}
+}
+
+// WithDownloadConcurrency sets the number of concurrent download goroutines
+func WithDownloadConcurrency(n int) S3Option {
+	return func(o *s3Options) {
+		if n > 0 {
+			o.downloadConcurrency = n
+		}
+	}
+}
+
+// WithCustomEndpoint sets a custom S3 endpoint (for LocalStack, MinIO, etc.)
+func WithCustomEndpoint(endpoint string) S3Option {
+	return func(o *s3Options) {
+		o.customEndpoint = endpoint
+	}
+}
+
+// WithPathStyle enables path-style addressing (required for some S3-compatible services)
+func WithPathStyle(enabled bool) S3Option {
+	return func(o *s3Options) {
+		o.usePathStyle = enabled
+	}
+}
+
+// WithRetry sets how many times requests are tried, the first included, and
+// the longest backoff between tries. The SDK's standard retryer retries
+// throttling (SlowDown, 503), timeouts and dropped connections with jittered
+// exponential backoff; maxAttempts of 1 disables retries.
+func WithRetry(maxAttempts int, maxBackoff time.Duration) S3Option {
+	return func(o *s3Options) {
+		if maxAttempts > 0 {
+			o.retryMaxAttempts = maxAttempts
+		}
+		if maxBackoff > 0 {
+			o.retryMaxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithRequestID sends the ID that requestID reads from each call's context
+// as an X-Request-ID header and a "request-id/<id>" User-Agent suffix, so
+// S3 server access logs and CloudTrail can be matched to the request that
+// made the call. Calls whose context has no ID are sent unchanged, and
+// presigned URLs never carry it.
+func WithRequestID(requestID func(context.Context) string) S3Option {
+	return func(o *s3Options) {
+		o.requestID = requestID
+	}
+}
+
+// WithServerSideEncryption encrypts uploads and copies that don't choose an
+// encryption with algorithm (EncryptionS3 or EncryptionKMS) and, for
+// EncryptionKMS, kmsKeyID (empty for the account's aws/s3 key)
+func WithServerSideEncryption(algorithm, kmsKeyID string) S3Option {
+	return func(o *s3Options) {
+		o.encryption = algorithm
+		o.kmsKeyID = kmsKeyID
+	}
+}
+
+// WithRequireEncryption enforces the WithServerSideEncryption settings:
+// uploads asking for another encryption are rejected with ErrInvalidInput,
+// and NewS3Store fails unless the bucket's default encryption uses the same
+// algorithm, so objects uploaded with presigned URLs are encrypted alike.
+// It needs s3:GetEncryptionConfiguration on the bucket.
+func WithRequireEncryption(required bool) S3Option {
+	return func(o *s3Options) {
+		o.requireEncryption = required
+	}
+}
+
+// requestIDMiddleware adds the caller's request ID to each attempt, after it
+// is signed: the header stays out of the signature, and the presigner, which
+// stops at signing, never sees it
+func requestIDMiddleware(requestID func(context.Context) string) func(*smithymiddleware.Stack) error {
+	return func(stack *smithymiddleware.Stack) error {
+		return stack.Finalize.Add(smithymiddleware.FinalizeMiddlewareFunc("RequestID",
+			func(ctx context.Context, in smithymiddleware.FinalizeInput, next smithymiddleware.FinalizeHandler) (
+				smithymiddleware.FinalizeOutput, smithymiddleware.Metadata, error,
+			) {
+				req, ok := in.Request.(*smithyhttp.Request)
+				if id := requestID(ctx); ok && id != "" {
+					req.Header.Set("X-Request-ID", id)
+					suffix := " request-id/" + id
+					if ua := req.Header.Get("User-Agent"); !strings.HasSuffix(ua, suffix) {
+						req.Header.Set("User-Agent", ua+suffix)
+					}
+				}
+				return next.HandleFinalize(ctx, in)
+			}), smithymiddleware.After)
+	}
+}
+
+// S3Config identifies the bucket and, optionally, static credentials
+type S3Config struct {
+	Region          string
+	AccessKeyID     string // Optional; the default credential chain is used when empty
+	SecretAccessKey string
+	Bucket          string
+}
+
+// HasStaticCredentials reports whether explicit credentials were provided
+func (c S3Config) HasStaticCredentials() bool {
+	return c.AccessKeyID != "" && c.SecretAccessKey != ""
+}
+
+// AWSLoadOptions builds AWS SDK load options from cfg.
+// Explicit credentials are used if provided, otherwise the default credential chain applies.
+func AWSLoadOptions(cfg S3Config) []func(*awsconfig.LoadOptions) error {
+	awsOpts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(cfg.Region),
+	}
+
+	if cfg.HasStaticCredentials() {
+		awsOpts = append(awsOpts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(
+				cfg.AccessKeyID,
+				cfg.SecretAccessKey,
+				"", // session token (optional)
+			),
+		))
+	}
+
+	return awsOpts
+}
+
+// NewS3Store creates a new S3 blob store with the provided configuration.
+// It uses AWS SDK v2 with automatic credential resolution chain:
+// 1. Environment variables (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY)
+// 2. Shared credentials file (~/.aws/credentials)
+// 3. IAM role (for EC2/ECS/Lambda)
+func NewS3Store(ctx context.Context, cfg S3Config, log *logger.Logger, opts ...S3Option) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket name is required")
+	}
+
+	options := defaultS3Options()
+	for _, opt := range opts {
+		opt(options)
+	}
+	if err := validateEncryption(options.encryption, options.kmsKeyID); err != nil {
+		return nil, err
+	}
+	if options.requireEncryption && options.encryption == "" {
+		return nil, fmt.Errorf("required encryption needs a server-side encryption algorithm")
+	}
+
+	// Load AWS configuration
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, AWSLoadOptions(cfg)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	// Build S3 client options
+	var s3Opts []func(*s3.Options)
+	if options.customEndpoint != "" {
+		s3Opts = append(s3Opts, func(o *s3.Options) {
+			o.BaseEndpoint = aws.String(options.customEndpoint)
+			o.UsePathStyle = options.usePathStyle
+		})
+	}
+
+	if options.retryMaxAttempts > 0 || options.retryMaxBackoff > 0 {
+		s3Opts = append(s3Opts, func(o *s3.Options) {
+			o.Retryer = retry.NewStandard(func(so *retry.StandardOptions) {
+				if options.retryMaxAttempts > 0 {
+					so.MaxAttempts = options.retryMaxAttempts
+				}
+				if options.retryMaxBackoff > 0 {
+					so.MaxBackoff = options.retryMaxBackoff
+				}
+			})
+		})
+	}
+
+	if options.requestID != nil {
+		s3Opts = append(s3Opts, func(o *s3.Options) {
+			o.APIOptions = append(o.APIOptions, requestIDMiddleware(options.requestID))
+		})
+	}
+
+	// Create S3 client
+	client := s3.NewFromConfig(awsCfg, s3Opts...)
+
+	// Create upload manager with configured options
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = options.uploadPartSize
+		u.Concurrency = options.uploadConcurrency
+		u.LeavePartsOnError = options.uploadLeavePartsErr
+	})
+
+	// Create download manager with configured options
+	downloader := manager.NewDownloader(client, func(d *manager.Downloader) {
+		d.PartSize = options.downloadPartSize
+		d.Concurrency = options.downloadConcurrency
+	})
+
+	store := &S3Store{
+		client:            client,
+		uploader:          uploader,
+		downloader:        downloader,
+		bucket:            cfg.Bucket,
+		logger:            log,
+		encryption:        options.encryption,
+		kmsKeyID:          options.kmsKeyID,
+		requireEncryption: options.requireEncryption,
+	}
+	if options.requireEncryption {
+		if err := store.checkBucketEncryption(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("S3 blob store initialized",
+		"bucket", cfg.Bucket,
+		"region", cfg.Region,
+		"encryption", options.encryption,
+		"encryption_required", options.requireEncryption,
+	)
+
+	return store, nil
+}
+
+// validateEncryption checks a server-side encryption algorithm and key
+func validateEncryption(algorithm, kmsKeyID string) error {
+	switch algorithm {
+	case "", EncryptionS3:
+		if kmsKeyID != "" {
+			return fmt.Errorf("%w: a KMS key ID needs %s encryption", ErrInvalidInput, EncryptionKMS)
+		}
+	case EncryptionKMS:
+	default:
+		return fmt.Errorf("%w: unknown server-side encryption %q (must be %s or %s)", ErrInvalidInput, algorithm, EncryptionS3, EncryptionKMS)
+	}
+	return nil
+}
+
+// uploadEncryption returns the encryption of an upload asking for
+// algorithm and kmsKeyID: the store's default when it asks for none, and
+// an error when it asks for another one while encryption is required
+func (s *S3Store) uploadEncryption(algorithm, kmsKeyID string) (string, string, error) {
+	if algorithm == "" && kmsKeyID == "" {
+		return s.encryption, s.kmsKeyID, nil
+	}
+	if err := validateEncryption(algorithm, kmsKeyID); err != nil {
+		return "", "", err
+	}
+	if algorithm == s.encryption && kmsKeyID == "" {
+		kmsKeyID = s.kmsKeyID
+	}
+	if s.requireEncryption && (algorithm != s.encryption || kmsKeyID != s.kmsKeyID) {
+		return "", "", fmt.Errorf("%w: uploads must use the store's %s encryption", ErrInvalidInput, s.encryption)
+	}
+	return algorithm, kmsKeyID, nil
+}
+
+// checkBucketEncryption verifies the bucket's default encryption uses the
+// store's algorithm
+func (s *S3Store) checkBucketEncryption(ctx context.Context) error {
+	result, err := s.client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(s.bucket),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ServerSideEncryptionConfigurationNotFoundError" {
+			return fmt.Errorf("bucket %s has no default encryption, %s is required", s.bucket, s.encryption)
+		}
+		return fmt.Errorf("failed to get bucket encryption: %w", err)
+	}
+	if result.ServerSideEncryptionConfiguration != nil {
+		for _, rule := range result.ServerSideEncryptionConfiguration.Rules {
+			if def := rule.ApplyServerSideEncryptionByDefault; def != nil && string(def.SSEAlgorithm) == s.encryption {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("bucket %s default encryption is not %s, which is required", s.bucket, s.encryption)
+}
+
+// validateTags checks tags against the object tag limits
+func validateTags(tags map[string]string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("%w: at most %d tags, got %d", ErrInvalidInput, maxTags, len(tags))
+	}
+	for key, value := range tags {
+		if key == "" || len(key) > maxTagKeyLength || len(value) > maxTagValueLength {
+			return fmt.Errorf("%w: tag keys must be 1 to %d bytes and values at most %d", ErrInvalidInput, maxTagKeyLength, maxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// encodeTags encodes tags as the URL query the tagging headers take,
+// sorted by key
+func encodeTags(tags map[string]string) string {
+	escape := func(s string) string { return strings.ReplaceAll(url.QueryEscape(s), "+", "%20") }
+	pairs := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, escape(key)+"="+escape(tags[key]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// applyEncryption sets algorithm and kmsKeyID on a request's fields
+func applyEncryption(algorithm, kmsKeyID string, sse *types.ServerSideEncryption, keyID **string) {
+	if algorithm == "" {
+		return
+	}
+	*sse = types.ServerSideEncryption(algorithm)
+	if kmsKeyID != "" {
+		*keyID = aws.String(kmsKeyID)
+	}
+}
+
+// Upload uploads an object to S3 using multipart upload for large files.
+// It automatically handles retries and chunking based on the configured part size.
+func (s *S3Store) Upload(ctx context.Context, input *UploadInput) (*UploadOutput, error) {
+	if input.Key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	if input.Body == nil {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidInput)
+	}
+
+	contentType := input.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	encryption, kmsKeyID, err := s.uploadOptions(input)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadInput := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(input.Key),
+		Body:        input.Body,
+		ContentType: aws.String(contentType),
+	}
+	applyEncryption(encryption, kmsKeyID, &uploadInput.ServerSideEncryption, &uploadInput.SSEKMSKeyId)
+
+	if len(input.Metadata) > 0 {
+		uploadInput.Metadata = input.Metadata
+	}
+	if input.StorageClass != "" {
+		uploadInput.StorageClass = types.StorageClass(input.StorageClass)
+	}
+	if len(input.Tags) > 0 {
+		uploadInput.Tagging = aws.String(encodeTags(input.Tags))
+	}
+	if input.CacheControl != "" {
+		uploadInput.CacheControl = aws.String(input.CacheControl)
+	}
+	if input.ContentDisposition != "" {
+		uploadInput.ContentDisposition = aws.String(input.ContentDisposition)
+	}
+
+	result, err := s.uploader.Upload(ctx, uploadInput)
+	if err != nil {
+		s.logger.Error("failed to upload object",
+			"key", input.Key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+
+	s.logger.Debug("object uploaded successfully",
+		"key", input.Key,
+		"location", result.Location,
+	)
+
+	output := &UploadOutput{
+		Location: result.Location,
+		ETag:     aws.ToString(result.ETag),
+	}
+	if result.VersionID != nil {
+		output.VersionID = *result.VersionID
+	}
+
+	return output, nil
+}
+
+// uploadOptions validates input's storage options and returns its encryption
+func (s *S3Store) uploadOptions(input *UploadInput) (encryption, kmsKeyID string, err error) {
+	encryption, kmsKeyID, err = s.uploadEncryption(input.Encryption, input.KMSKeyID)
+	if err != nil {
+		return "", "", err
+	}
+	if input.StorageClass != "" && !slices.Contains(types.StorageClass("").Values(), types.StorageClass(input.StorageClass)) {
+		return "", "", fmt.Errorf("%w: unknown storage class %q", ErrInvalidInput, input.StorageClass)
+	}
+	if err := validateTags(input.Tags); err != nil {
+		return "", "", err
+	}
+	return encryption, kmsKeyID, nil
+}
+
+// CreateMultipartUpload starts a multipart upload of input.Key, encrypted as
+// Upload encrypts objects
+func (s *S3Store) CreateMultipartUpload(ctx context.Context, input *UploadInput) (string, error) {
+	if input.Key == "" {
+		return "", ErrInvalidKey
+	}
+
+	contentType := input.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	encryption, kmsKeyID, err := s.uploadOptions(input)
+	if err != nil {
+		return "", err
+	}
+
+	createInput := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(input.Key),
+		ContentType: aws.String(contentType),
+	}
+	applyEncryption(encryption, kmsKeyID, &createInput.ServerSideEncryption, &createInput.SSEKMSKeyId)
+
+	if len(input.Metadata) > 0 {
+		createInput.Metadata = input.Metadata
+	}
+	if input.StorageClass != "" {
+		createInput.StorageClass = types.StorageClass(input.StorageClass)
+	}
+	if len(input.Tags) > 0 {
+		createInput.Tagging = aws.String(encodeTags(input.Tags))
+	}
+	if input.CacheControl != "" {
+		createInput.CacheControl = aws.String(input.CacheControl)
+	}
+	if input.ContentDisposition != "" {
+		createInput.ContentDisposition = aws.String(input.ContentDisposition)
+	}
+
+	result, err := s.client.CreateMultipartUpload(ctx, createInput)
+	if err != nil {
+		s.logger.Error("failed to create multipart upload",
+			"key", input.Key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+	return aws.ToString(result.UploadId), nil
+}
+
+// UploadPart stores a part of a multipart upload. Bodies that are not an
+// io.ReadSeeker, such as a bytes.Reader, can only be sent over HTTPS.
+func (s *S3Store) UploadPart(ctx context.Context, key, uploadID string, number int32, body io.Reader, size int64) (*Part, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	if uploadID == "" || number < 1 || number > MaxParts || body == nil || size < 0 {
+		return nil, fmt.Errorf("%w: invalid part %d of upload %q", ErrInvalidInput, number, uploadID)
+	}
+
+	result, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(number),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		if s.isNoSuchUploadError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to upload part",
+			"key", key,
+			"bucket", s.bucket,
+			"part", number,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+	return &Part{Number: number, ETag: aws.ToString(result.ETag), Size: size}, nil
+}
+
+// ListParts returns the parts of a multipart upload by number
+func (s *S3Store) ListParts(ctx context.Context, key, uploadID string) ([]Part, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	var parts []Part
+	paginator := s3.NewListPartsPaginator(s.client, &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			if s.isNoSuchUploadError(err) {
+				return nil, ErrNotFound
+			}
+			s.logger.Error("failed to list parts",
+				"key", key,
+				"bucket", s.bucket,
+				"error", err,
+			)
+			return nil, fmt.Errorf("failed to list parts: %w", err)
+		}
+		for _, p := range page.Parts {
+			parts = append(parts, Part{
+				Number: aws.ToInt32(p.PartNumber),
+				ETag:   aws.ToString(p.ETag),
+				Size:   aws.ToInt64(p.Size),
+			})
+		}
+	}
+	return parts, nil
+}
+
+// CompleteMultipartUpload creates the object from the upload's parts
+func (s *S3Store) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) (*UploadOutput, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("%w: an upload needs at least one part", ErrInvalidInput)
+	}
+
+	completed := make([]types.CompletedPart, len(parts))
+	for i, p := range parts {
+		completed[i] = types.CompletedPart{PartNumber: aws.Int32(p.Number), ETag: aws.String(p.ETag)}
+	}
+	result, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		if s.isNoSuchUploadError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to complete multipart upload",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+
+	s.logger.Debug("multipart upload completed", "key", key, "parts", len(parts))
+	return &UploadOutput{
+		Location:  aws.ToString(result.Location),
+		ETag:      aws.ToString(result.ETag),
+		VersionID: aws.ToString(result.VersionId),
+	}, nil
+}
+
+// AbortMultipartUpload discards a multipart upload and its parts
+func (s *S3Store) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil && !s.isNoSuchUploadError(err) {
+		s.logger.Error("failed to abort multipart upload",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	}
+	return nil
+}
+
+// Download downloads an object from S3 into the provided writer.
+// It uses concurrent range requests for large files.
+func (s *S3Store) Download(ctx context.Context, key string, w io.WriterAt) (int64, error) {
+	if key == "" {
+		return 0, ErrInvalidKey
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	n, err := s.downloader.Download(ctx, w, input)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return 0, ErrNotFound
+		}
+		s.logger.Error("failed to download object",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return 0, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	s.logger.Debug("object downloaded successfully",
+		"key", key,
+		"bytes", n,
+	)
+
+	return n, nil
+}
+
+// GetObject retrieves an object from S3 and returns it as a ReadCloser.
+// The caller is responsible for closing the returned reader.
+func (s *S3Store) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	result, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to get object",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	return result.Body, nil
+}
+
+// HeadObject retrieves metadata about an object without downloading it.
+func (s *S3Store) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	result, err := s.client.HeadObject(ctx, input)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to head object",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        aws.ToInt64(result.ContentLength),
+		ContentType: aws.ToString(result.ContentType),
+		ETag:        aws.ToString(result.ETag),
+		Metadata:    result.Metadata,
+		Encryption:  string(result.ServerSideEncryption),
+		KMSKeyID:    aws.ToString(result.SSEKMSKeyId),
+
+		StorageClass:       string(result.StorageClass), // S3 omits STANDARD
+		CacheControl:       aws.ToString(result.CacheControl),
+		ContentDisposition: aws.ToString(result.ContentDisposition),
+	}
+	if result.LastModified != nil {
+		info.LastModified = *result.LastModified
+	}
+
+	return info, nil
+}
+
+// Delete removes an object from S3.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	_, err := s.client.DeleteObject(ctx, input)
+	if err != nil {
+		s.logger.Error("failed to delete object",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	}
+
+	s.logger.Debug("object deleted successfully", "key", key)
+	return nil
+}
+
+// DeleteMultiple removes multiple objects from S3 in a single request.
+// It returns the keys that failed to delete along with any error.
+func (s *S3Store) DeleteMultiple(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	// S3 DeleteObjects has a limit of 1000 keys per request
+	const maxKeysPerRequest = 1000
+	var failedKeys []string
+
+	for i := 0; i < len(keys); i += maxKeysPerRequest {
+		end := i + maxKeysPerRequest
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batch := keys[i:end]
+		objects := make([]types.ObjectIdentifier, len(batch))
+		for j, key := range batch {
+			objects[j] = types.ObjectIdentifier{
+				Key: aws.String(key),
+			}
+		}
+
+		input := &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucket),
+			Delete: &types.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		}
+
+		result, err := s.client.DeleteObjects(ctx, input)
+		if err != nil {
+			s.logger.Error("failed to delete objects batch",
+				"bucket", s.bucket,
+				"count", len(batch),
+				"error", err,
+			)
+			failedKeys = append(failedKeys, batch...)
+			continue
+		}
+
+		// Collect failed deletions
+		for _, errObj := range result.Errors {
+			failedKeys = append(failedKeys, aws.ToString(errObj.Key))
+			s.logger.Warn("failed to delete object",
+				"key", aws.ToString(errObj.Key),
+				"code", aws.ToString(errObj.Code),
+				"message", aws.ToString(errObj.Message),
+			)
+		}
+	}
+
+	if len(failedKeys) > 0 {
+		return failedKeys, fmt.Errorf("%w: %d objects failed to delete", ErrDeleteFailed, len(failedKeys))
+	}
+
+	s.logger.Debug("objects deleted successfully", "count", len(keys))
+	return nil, nil
+}
+
+// List lists objects in the S3 bucket with optional filtering by prefix.
+func (s *S3Store) List(ctx context.Context, input *ListInput) (*ListOutput, error) {
+	maxKeys := input.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	listInput := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucket),
+		MaxKeys: aws.Int32(maxKeys),
+	}
+
+	if input.Prefix != "" {
+		listInput.Prefix = aws.String(input.Prefix)
+	}
+	if input.StartAfter != "" {
+		listInput.StartAfter = aws.String(input.StartAfter)
+	}
+
+	result, err := s.client.ListObjectsV2(ctx, listInput)
+	if err != nil {
+		s.logger.Error("failed to list objects",
+			"bucket", s.bucket,
+			"prefix", input.Prefix,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	objects := make([]ObjectInfo, len(result.Contents))
+	for i, obj := range result.Contents {
+		objects[i] = ObjectInfo{
+			Key:  aws.ToString(obj.Key),
+			Size: aws.ToInt64(obj.Size),
+			ETag: aws.ToString(obj.ETag),
+		}
+		if obj.LastModified != nil {
+			objects[i].LastModified = *obj.LastModified
+		}
+	}
+
+	output := &ListOutput{
+		Objects:     objects,
+		IsTruncated: aws.ToBool(result.IsTruncated),
+	}
+
+	if len(objects) > 0 {
+		output.NextMarker = objects[len(objects)-1].Key
+	}
+
+	return output, nil
+}
+
+// Exists checks if an object exists in S3.
+func (s *S3Store) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.HeadObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Copy copies an object within the same bucket or from another bucket.
+// The copy keeps the source's metadata, tags and caching headers, and gets
+// the default storage class.
+func (s *S3Store) Copy(ctx context.Context, sourceKey, destKey string) error {
+	if sourceKey == "" || destKey == "" {
+		return ErrInvalidKey
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		CopySource: aws.String(fmt.Sprintf("%s/%s", s.bucket, sourceKey)),
+		Key:        aws.String(destKey),
+	}
+	// Copies are encrypted as new uploads are, not as their source was
+	applyEncryption(s.encryption, s.kmsKeyID, &input.ServerSideEncryption, &input.SSEKMSKeyId)
+
+	_, err := s.client.CopyObject(ctx, input)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return ErrNotFound
+		}
+		s.logger.Error("failed to copy object",
+			"source", sourceKey,
+			"dest", destKey,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	s.logger.Debug("object copied successfully",
+		"source", sourceKey,
+		"dest", destKey,
+	)
+	return nil
+}
+
+// GeneratePresignedURL generates a pre-signed URL for downloading an object.
+// The URL is valid for the specified duration.
+func (s *S3Store) GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+
+	presignClient := s3.NewPresignClient(s.client)
+
+	request, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiration))
+	if err != nil {
+		s.logger.Error("failed to generate presigned URL",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	return request.URL, nil
+}
+
+// GeneratePresignedUploadURL generates a pre-signed URL for uploading an object.
+// The URL is valid for the specified duration. Objects uploaded with it get
+// the bucket's default encryption (see WithRequireEncryption).
+func (s *S3Store) GeneratePresignedUploadURL(ctx context.Context, key string, contentType string, expiration time.Duration) (string, error) {
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+
+	presignClient := s3.NewPresignClient(s.client)
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	request, err := presignClient.PresignPutObject(ctx, input, s3.WithPresignExpires(expiration))
+	if err != nil {
+		s.logger.Error("failed to generate presigned upload URL",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return "", fmt.Errorf("failed to generate presigned upload URL: %w", err)
+	}
+
+	return request.URL, nil
+}
+
+// maxPostObjectSize is the largest object a POST upload can create
+const maxPostObjectSize = 5 << 30
+
+// GeneratePresignedPost signs a POST policy so browsers can upload straight
+// to S3 with an HTML form. The policy pins the bucket and key (or key prefix),
+// the content type, the size range and the store's server-side encryption.
+func (s *S3Store) GeneratePresignedPost(ctx context.Context, input *PresignedPostInput) (*PresignedPost, error) {
+	if input == nil {
+		return nil, fmt.Errorf("%w: input is required", ErrInvalidInput)
+	}
+
+	key := input.Key
+	var conditions []interface{}
+	if input.KeyPrefix != "" {
+		key = input.KeyPrefix + "${filename}"
+		conditions = append(conditions, []interface{}{"starts-with", "$key", input.KeyPrefix})
+	}
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+
+	fields := map[string]string{}
+	if input.ContentTypePrefix != "" && input.ContentType != "" && !strings.HasPrefix(input.ContentType, input.ContentTypePrefix) {
+		// The prefilled field would fail the form's own policy
+		return nil, fmt.Errorf("%w: content type %q does not start with %q", ErrInvalidInput, input.ContentType, input.ContentTypePrefix)
+	}
+	switch {
+	case input.ContentTypePrefix != "":
+		conditions = append(conditions, []interface{}{"starts-with", "$Content-Type", input.ContentTypePrefix})
+	case input.ContentType != "":
+		conditions = append(conditions, map[string]string{"Content-Type": input.ContentType})
+	}
+	if input.ContentType != "" {
+		fields["Content-Type"] = input.ContentType
+	}
+
+	if input.MinSize < 0 || (input.MaxSize > 0 && input.MaxSize < input.MinSize) {
+		return nil, fmt.Errorf("%w: invalid size range", ErrInvalidInput)
+	}
+	if maxSize := input.MaxSize; maxSize > 0 || input.MinSize > 0 {
+		if maxSize == 0 {
+			maxSize = maxPostObjectSize
+		}
+		conditions = append(conditions, []interface{}{"content-length-range", input.MinSize, maxSize})
+	}
+	// Forms are encrypted as uploads are; the fields are sent like the others
+	if s.encryption != "" {
+		fields["x-amz-server-side-encryption"] = s.encryption
+		conditions = append(conditions, map[string]string{"x-amz-server-side-encryption": s.encryption})
+	}
+	if s.kmsKeyID != "" {
+		fields["x-amz-server-side-encryption-aws-kms-key-id"] = s.kmsKeyID
+		conditions = append(conditions, map[string]string{"x-amz-server-side-encryption-aws-kms-key-id": s.kmsKeyID})
+	}
+
+	presignClient := s3.NewPresignClient(s.client)
+
+	request, err := presignClient.PresignPostObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, func(o *s3.PresignPostOptions) {
+		o.Expires = input.Expiration
+		o.Conditions = conditions
+	})
+	if err != nil {
+		s.logger.Error("failed to generate presigned post",
+			"key", key,
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to generate presigned post: %w", err)
+	}
+
+	for name, value := range request.Values {
+		fields[name] = value
+	}
+	return &PresignedPost{URL: request.URL, Fields: fields}, nil
+}
+
+// LifecycleRules returns the bucket's lifecycle rules. Rules filtering on
+// more than a prefix (tags, object sizes) are returned with their prefix
+// only, and noncurrent version actions are left out.
+func (s *S3Store) LifecycleRules(ctx context.Context) ([]LifecycleRule, error) {
+	current, err := s.lifecycleConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]LifecycleRule, 0, len(current))
+	for _, r := range current {
+		rule := LifecycleRule{
+			ID:       aws.ToString(r.ID),
+			Prefix:   aws.ToString(r.Prefix), // Deprecated field, still returned for old rules
+			Disabled: r.Status != types.ExpirationStatusEnabled,
+		}
+		if f := r.Filter; f != nil && f.Prefix != nil {
+			rule.Prefix = *f.Prefix
+		} else if f != nil && f.And != nil && f.And.Prefix != nil {
+			rule.Prefix = *f.And.Prefix
+		}
+		if r.Expiration != nil {
+			rule.ExpirationDays = int(aws.ToInt32(r.Expiration.Days))
+		}
+		for _, t := range r.Transitions {
+			rule.Transitions = append(rule.Transitions, LifecycleTransition{Days: int(aws.ToInt32(t.Days)), StorageClass: string(t.StorageClass)})
+		}
+		if r.AbortIncompleteMultipartUpload != nil {
+			rule.AbortIncompleteUploadDays = int(aws.ToInt32(r.AbortIncompleteMultipartUpload.DaysAfterInitiation))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// PutLifecycleRules creates rules, replacing the bucket's rules with the
+// same IDs; its other rules are kept as they are
+func (s *S3Store) PutLifecycleRules(ctx context.Context, rules []LifecycleRule) error {
+	replacing := make(map[string]types.LifecycleRule, len(rules))
+	for _, rule := range rules {
+		if err := validateLifecycleRule(rule); err != nil {
+			return err
+		}
+		if _, ok := replacing[rule.ID]; ok {
+			return fmt.Errorf("%w: duplicate lifecycle rule ID %q", ErrInvalidInput, rule.ID)
+		}
+		replacing[rule.ID] = s3LifecycleRule(rule)
+	}
+
+	current, err := s.lifecycleConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	// Rules keep their place, new ones go last
+	merged := make([]types.LifecycleRule, 0, len(current)+len(rules))
+	for _, r := range current {
+		if replacement, ok := replacing[aws.ToString(r.ID)]; ok {
+			r = replacement
+			delete(replacing, aws.ToString(r.ID))
+		}
+		merged = append(merged, r)
+	}
+	for _, rule := range rules {
+		if r, ok := replacing[rule.ID]; ok {
+			merged = append(merged, r)
+		}
+	}
+	return s.putLifecycleConfiguration(ctx, merged)
+}
+
+// DeleteLifecycleRules removes the bucket's rules with these IDs; the
+// lifecycle configuration is deleted with its last rule
+func (s *S3Store) DeleteLifecycleRules(ctx context.Context, ids ...string) error {
+	current, err := s.lifecycleConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(current), func(r types.LifecycleRule) bool {
+		return slices.Contains(ids, aws.ToString(r.ID))
+	})
+	if len(kept) == len(current) {
+		return nil
+	}
+	return s.putLifecycleConfiguration(ctx, kept)
+}
+
+// lifecycleConfiguration returns the bucket's lifecycle rules as S3 has them
+func (s *S3Store) lifecycleConfiguration(ctx context.Context) ([]types.LifecycleRule, error) {
+	result, err := s.client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(s.bucket),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchLifecycleConfiguration" {
+			return nil, nil
+		}
+		s.logger.Error("failed to get lifecycle rules",
+			"bucket", s.bucket,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to get lifecycle rules: %w", err)
+	}
+	return result.Rules, nil
+}
+
+// putLifecycleConfiguration replaces the bucket's lifecycle rules, deleting
+// the configuration when there are none
+func (s *S3Store) putLifecycleConfiguration(ctx context.Context, rules []types.LifecycleRule) error {
+	var err error
+	if len(rules) == 0 {
+		_, err = s.client.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{
+			Bucket: aws.String(s.bucket),
+		})
+	} else {
+		_, err = s.client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+			Bucket:                 aws.String(s.bucket),
+			LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: rules},
+		})
+	}
+	if err != nil {
+		s.logger.Error("failed to set lifecycle rules",
+			"bucket", s.bucket,
+			"rules", len(rules),
+			"error", err,
+		)
+		return fmt.Errorf("failed to set lifecycle rules: %w", err)
+	}
+
+	s.logger.Info("lifecycle rules set", "bucket", s.bucket, "rules", len(rules))
+	return nil
+}
+
+// validateLifecycleRule checks a rule before it is sent, for errors S3
+// would report less clearly
+func validateLifecycleRule(rule LifecycleRule) error {
+	if rule.ID == "" || len(rule.ID) > 255 {
+		return fmt.Errorf("%w: lifecycle rule IDs must be 1 to 255 characters", ErrInvalidInput)
+	}
+	if rule.ExpirationDays == 0 && len(rule.Transitions) == 0 && rule.AbortIncompleteUploadDays == 0 {
+		return fmt.Errorf("%w: lifecycle rule %s has no action", ErrInvalidInput, rule.ID)
+	}
+	if rule.ExpirationDays < 0 || rule.AbortIncompleteUploadDays < 0 {
+		return fmt.Errorf("%w: lifecycle rule %s days must not be negative", ErrInvalidInput, rule.ID)
+	}
+	for _, t := range rule.Transitions {
+		if t.Days < 0 {
+			return fmt.Errorf("%w: lifecycle rule %s days must not be negative", ErrInvalidInput, rule.ID)
+		}
+		if !slices.Contains(types.TransitionStorageClass("").Values(), types.TransitionStorageClass(t.StorageClass)) {
+			return fmt.Errorf("%w: lifecycle rule %s cannot transition to storage class %q", ErrInvalidInput, rule.ID, t.StorageClass)
+		}
+		if rule.ExpirationDays > 0 && t.Days >= rule.ExpirationDays {
+			return fmt.Errorf("%w: lifecycle rule %s transitions after its objects expire", ErrInvalidInput, rule.ID)
+		}
+	}
+	return nil
+}
+
+// s3LifecycleRule maps rule onto S3's
+func s3LifecycleRule(rule LifecycleRule) types.LifecycleRule {
+	r := types.LifecycleRule{
+		ID:     aws.String(rule.ID),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+	}
+	if rule.Disabled {
+		r.Status = types.ExpirationStatusDisabled
+	}
+	if rule.ExpirationDays > 0 {
+		r.Expiration = &types.LifecycleExpiration{Days: aws.Int32(int32(rule.ExpirationDays))}
+	}
+	for _, t := range rule.Transitions {
+		r.Transitions = append(r.Transitions, types.Transition{
+			Days:         aws.Int32(int32(t.Days)),
+			StorageClass: types.TransitionStorageClass(t.StorageClass),
+		})
+	}
+	if rule.AbortIncompleteUploadDays > 0 {
+		r.AbortIncompleteMultipartUpload = &types.AbortIncompleteMultipartUpload{
+			DaysAfterInitiation: aws.Int32(int32(rule.AbortIncompleteUploadDays)),
+		}
+	}
+	return r
+}
+
+// isNotFoundError checks if the error indicates the object was not found
+func (s *S3Store) isNotFoundError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotFound", "NoSuchKey", "404":
+			return true
+		}
+	}
+
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return true
+	}
+
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+
+	return false
+}
+
+// isNoSuchUploadError checks if the error indicates the multipart upload is gone
+func (s *S3Store) isNoSuchUploadError(err error) bool {
+	var noSuchUpload *types.NoSuchUpload
+	if errors.As(err, &noSuchUpload) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchUpload"
+}
+
+// Bucket returns the configured bucket name
+func (s *S3Store) Bucket() string {
+	return s.bucket
+}
@@ -0,0 +1,523 @@
+package blob
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+// fakeS3 answers the S3 calls the tests make and records the headers of
+// the last request per method
+type fakeS3 struct {
+	mu               sync.Mutex
+	headers          map[string]http.Header
+	bucketEncryption string                 // Default encryption algorithm, empty for none
+	lifecycle        string                 // Lifecycle configuration document, empty for none
+	uploads          map[string]map[int]int // Part sizes by number of the open multipart uploads
+	completed        []string               // Requests completing multipart uploads
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.headers[r.Method] = r.Header.Clone()
+
+	switch {
+	case r.URL.Query().Has("lifecycle"):
+		switch r.Method {
+		case http.MethodGet:
+			if f.lifecycle == "" {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `<Error><Code>NoSuchLifecycleConfiguration</Code><Message>none</Message></Error>`)
+				return
+			}
+			io.WriteString(w, f.lifecycle)
+		case http.MethodPut:
+			f.lifecycle = string(body)
+		case http.MethodDelete:
+			f.lifecycle = ""
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case r.Method == http.MethodGet && r.URL.Query().Has("encryption"):
+		if f.bucketEncryption == "" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>ServerSideEncryptionConfigurationNotFoundError</Code><Message>none</Message></Error>`)
+			return
+		}
+		io.WriteString(w, `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>`+
+			f.bucketEncryption+`</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>`)
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(f.uploads)+len(f.completed)+1)
+		if f.uploads == nil {
+			f.uploads = make(map[string]map[int]int)
+		}
+		f.uploads[id] = make(map[int]int)
+		io.WriteString(w, `<InitiateMultipartUploadResult><UploadId>`+id+`</UploadId></InitiateMultipartUploadResult>`)
+	case r.URL.Query().Has("uploadId"):
+		id := r.URL.Query().Get("uploadId")
+		parts, ok := f.uploads[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchUpload</Code><Message>none</Message></Error>`)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			number, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+			parts[number] = len(body)
+			w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+		case http.MethodGet:
+			numbers := slices.Sorted(maps.Keys(parts))
+			io.WriteString(w, `<ListPartsResult>`)
+			for _, n := range numbers {
+				fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"etag-%d"</ETag><Size>%d</Size></Part>`, n, n, parts[n])
+			}
+			io.WriteString(w, `</ListPartsResult>`)
+		case http.MethodPost:
+			delete(f.uploads, id)
+			f.completed = append(f.completed, html.UnescapeString(string(body)))
+			io.WriteString(w, `<CompleteMultipartUploadResult><Key>`+strings.TrimPrefix(r.URL.Path, "/uploads/")+`</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+		case http.MethodDelete:
+			delete(f.uploads, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		io.WriteString(w, `<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`)
+	case r.Method == http.MethodPut:
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodHead:
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", "arn:aws:kms:us-east-1:111122223333:key/uploads")
+		w.Header().Set("X-Amz-Storage-Class", "GLACIER_IR")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) lifecycleDocument() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lifecycle
+}
+
+func (f *fakeS3) header(method, name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.headers[method].Get(name)
+}
+
+func newFakeS3Store(t *testing.T, fake *fakeS3, opts ...S3Option) (*S3Store, error) {
+	t.Helper()
+	fake.headers = make(map[string]http.Header)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	opts = append([]S3Option{WithCustomEndpoint(srv.URL), WithPathStyle(true), WithRetry(1, 0)}, opts...)
+	return NewS3Store(context.Background(), S3Config{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		Bucket:          "uploads",
+	}, logger.New("error"), opts...)
+}
+
+func TestS3UploadEncryption(t *testing.T) {
+	tests := []struct {
+		name          string
+		store         S3Store
+		algorithm     string
+		kmsKeyID      string
+		wantAlgorithm string
+		wantKeyID     string
+		wantErr       bool
+	}{
+		{"bucket default", S3Store{}, "", "", "", "", false},
+		{"store default", S3Store{encryption: EncryptionKMS, kmsKeyID: "alias/store"}, "", "", EncryptionKMS, "alias/store", false},
+		{"chosen", S3Store{encryption: EncryptionS3}, EncryptionKMS, "alias/mine", EncryptionKMS, "alias/mine", false},
+		{"store key for the same algorithm", S3Store{encryption: EncryptionKMS, kmsKeyID: "alias/store"}, EncryptionKMS, "", EncryptionKMS, "alias/store", false},
+		{"unknown algorithm", S3Store{}, "aws:kms:dsse", "", "", "", true},
+		{"key without kms", S3Store{}, EncryptionS3, "alias/mine", "", "", true},
+		{"required algorithm", S3Store{encryption: EncryptionKMS, requireEncryption: true}, EncryptionS3, "", "", "", true},
+		{"required key", S3Store{encryption: EncryptionKMS, kmsKeyID: "alias/store", requireEncryption: true}, EncryptionKMS, "alias/mine", "", "", true},
+		{"required and matching", S3Store{encryption: EncryptionS3, requireEncryption: true}, EncryptionS3, "", EncryptionS3, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			algorithm, keyID, err := tt.store.uploadEncryption(tt.algorithm, tt.kmsKeyID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("uploadEncryption error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("error = %v, want ErrInvalidInput", err)
+			}
+			if algorithm != tt.wantAlgorithm || keyID != tt.wantKeyID {
+				t.Errorf("uploadEncryption = %q, %q, want %q, %q", algorithm, keyID, tt.wantAlgorithm, tt.wantKeyID)
+			}
+		})
+	}
+}
+
+func TestS3StoreEncryptsUploadsAndCopies(t *testing.T) {
+	fake := &fakeS3{}
+	store, err := newFakeS3Store(t, fake, WithServerSideEncryption(EncryptionKMS, "alias/uploads"))
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := store.Upload(ctx, &UploadInput{Key: "a.txt", Body: strings.NewReader("hello")}); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if got := fake.header(http.MethodPut, "X-Amz-Server-Side-Encryption"); got != EncryptionKMS {
+		t.Errorf("upload encryption header = %q, want %q", got, EncryptionKMS)
+	}
+	if got := fake.header(http.MethodPut, "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); got != "alias/uploads" {
+		t.Errorf("upload KMS key header = %q, want alias/uploads", got)
+	}
+
+	if err := store.Copy(ctx, "a.txt", "b.txt"); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if got := fake.header(http.MethodPut, "X-Amz-Server-Side-Encryption"); got != EncryptionKMS {
+		t.Errorf("copy encryption header = %q, want %q", got, EncryptionKMS)
+	}
+
+	info, err := store.HeadObject(ctx, "a.txt")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.Encryption != EncryptionKMS || info.KMSKeyID != "arn:aws:kms:us-east-1:111122223333:key/uploads" {
+		t.Errorf("HeadObject encryption = %q, %q", info.Encryption, info.KMSKeyID)
+	}
+
+	post, err := store.GeneratePresignedPost(ctx, &PresignedPostInput{Key: "form.txt", Expiration: time.Minute})
+	if err != nil {
+		t.Fatalf("GeneratePresignedPost: %v", err)
+	}
+	if post.Fields["x-amz-server-side-encryption"] != EncryptionKMS || post.Fields["x-amz-server-side-encryption-aws-kms-key-id"] != "alias/uploads" {
+		t.Errorf("presigned post fields = %v, want the store's encryption", post.Fields)
+	}
+}
+
+func TestS3StoreRequireEncryption(t *testing.T) {
+	tests := []struct {
+		name             string
+		bucketEncryption string
+		opts             []S3Option
+		wantErr          bool
+	}{
+		{"bucket default matches", EncryptionKMS, []S3Option{WithServerSideEncryption(EncryptionKMS, ""), WithRequireEncryption(true)}, false},
+		{"bucket default differs", EncryptionS3, []S3Option{WithServerSideEncryption(EncryptionKMS, ""), WithRequireEncryption(true)}, true},
+		{"bucket without default", "", []S3Option{WithServerSideEncryption(EncryptionS3, ""), WithRequireEncryption(true)}, true},
+		{"nothing to require", EncryptionS3, []S3Option{WithRequireEncryption(true)}, true},
+		{"unknown algorithm", "", []S3Option{WithServerSideEncryption("sse-c", "")}, true},
+		{"not required", "", []S3Option{WithServerSideEncryption(EncryptionS3, "")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newFakeS3Store(t, &fakeS3{bucketEncryption: tt.bucketEncryption}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewS3Store error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestS3UploadStorageOptions(t *testing.T) {
+	fake := &fakeS3{}
+	store, err := newFakeS3Store(t, fake)
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	ctx := context.Background()
+
+	_, err = store.Upload(ctx, &UploadInput{
+		Key:                "report.csv",
+		Body:               strings.NewReader("a,b"),
+		StorageClass:       StorageClassGlacierIR,
+		Tags:               map[string]string{"retention": "90 days", "owner": "reports&exports"},
+		CacheControl:       "public, max-age=86400",
+		ContentDisposition: `attachment; filename="report.csv"`,
+	})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	for name, want := range map[string]string{
+		"X-Amz-Storage-Class": "GLACIER_IR",
+		"X-Amz-Tagging":       "owner=reports%26exports&retention=90%20days",
+		"Cache-Control":       "public, max-age=86400",
+		"Content-Disposition": `attachment; filename="report.csv"`,
+	} {
+		if got := fake.header(http.MethodPut, name); got != want {
+			t.Errorf("upload %s = %q, want %q", name, got, want)
+		}
+	}
+
+	info, err := store.HeadObject(ctx, "report.csv")
+	if err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if info.StorageClass != StorageClassGlacierIR || info.CacheControl != "public, max-age=86400" || info.ContentDisposition != `attachment; filename="report.csv"` {
+		t.Errorf("HeadObject = %+v, want the upload's storage class and headers", info)
+	}
+
+	tooMany := map[string]string{}
+	for i := range 11 {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	for name, input := range map[string]*UploadInput{
+		"unknown storage class": {Key: "a", Body: strings.NewReader("x"), StorageClass: "COLD"},
+		"too many tags":         {Key: "a", Body: strings.NewReader("x"), Tags: tooMany},
+		"empty tag key":         {Key: "a", Body: strings.NewReader("x"), Tags: map[string]string{"": "v"}},
+		"long tag value":        {Key: "a", Body: strings.NewReader("x"), Tags: map[string]string{"k": strings.Repeat("v", 257)}},
+	} {
+		if _, err := store.Upload(ctx, input); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: Upload error = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestS3MultipartUpload(t *testing.T) {
+	fake := &fakeS3{}
+	store, err := newFakeS3Store(t, fake, WithServerSideEncryption(EncryptionKMS, "uploads-key"))
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	ctx := context.Background()
+
+	id, err := store.CreateMultipartUpload(ctx, &UploadInput{Key: "video.mp4", ContentType: "video/mp4"})
+	if err != nil {
+		t.Fatalf("CreateMultipartUpload: %v", err)
+	}
+	if got := fake.header(http.MethodPost, "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); got != "uploads-key" {
+		t.Errorf("create KMS key = %q, want the store's", got)
+	}
+	if got := fake.header(http.MethodPost, "Content-Type"); got != "video/mp4" {
+		t.Errorf("create Content-Type = %q, want video/mp4", got)
+	}
+
+	// Parts may arrive out of order; they are listed by number
+	for _, p := range []struct {
+		number int32
+		body   string
+	}{{2, "world"}, {1, "hello "}} {
+		part, err := store.UploadPart(ctx, "video.mp4", id, p.number, strings.NewReader(p.body), int64(len(p.body)))
+		if err != nil {
+			t.Fatalf("UploadPart %d: %v", p.number, err)
+		}
+		if want := fmt.Sprintf(`"etag-%d"`, p.number); part.ETag != want || part.Size != int64(len(p.body)) {
+			t.Errorf("UploadPart %d = %+v, want ETag %s and size %d", p.number, part, want, len(p.body))
+		}
+	}
+	parts, err := store.ListParts(ctx, "video.mp4", id)
+	if err != nil {
+		t.Fatalf("ListParts: %v", err)
+	}
+	want := []Part{{Number: 1, ETag: `"etag-1"`, Size: 6}, {Number: 2, ETag: `"etag-2"`, Size: 5}}
+	if !slices.Equal(parts, want) {
+		t.Errorf("ListParts = %+v, want %+v", parts, want)
+	}
+
+	if _, err := store.CompleteMultipartUpload(ctx, "video.mp4", id, parts); err != nil {
+		t.Fatalf("CompleteMultipartUpload: %v", err)
+	}
+	fake.mu.Lock()
+	completed := fake.completed
+	fake.mu.Unlock()
+	if len(completed) != 1 || !strings.Contains(completed[0], `<ETag>"etag-1"</ETag><PartNumber>1</PartNumber>`) ||
+		!strings.Contains(completed[0], `<ETag>"etag-2"</ETag><PartNumber>2</PartNumber>`) {
+		t.Errorf("complete request = %q, want both parts", completed)
+	}
+
+	// The completed upload is gone: listing reports it, aborting is a no-op
+	if _, err := store.ListParts(ctx, "video.mp4", id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListParts after completion error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.UploadPart(ctx, "video.mp4", id, 3, strings.NewReader("!"), 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UploadPart after completion error = %v, want ErrNotFound", err)
+	}
+	if err := store.AbortMultipartUpload(ctx, "video.mp4", id); err != nil {
+		t.Errorf("AbortMultipartUpload of a gone upload: %v", err)
+	}
+
+	if _, err := store.CompleteMultipartUpload(ctx, "video.mp4", id, nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("CompleteMultipartUpload without parts error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := store.UploadPart(ctx, "video.mp4", id, MaxParts+1, strings.NewReader("!"), 1); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("UploadPart past MaxParts error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestS3LifecycleRules(t *testing.T) {
+	// A rule another tool set up, filtering on a tag this API cannot express
+	fake := &fakeS3{lifecycle: `<LifecycleConfiguration><Rule><ID>ops-temp</ID><Status>Enabled</Status>` +
+		`<Filter><Tag><Key>temp</Key><Value>true</Value></Tag></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`}
+	store, err := newFakeS3Store(t, fake)
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	ctx := context.Background()
+
+	rules := []LifecycleRule{
+		{ID: "reports", Prefix: "reports/", ExpirationDays: 90, Transitions: []LifecycleTransition{{Days: 30, StorageClass: StorageClassStandardIA}}},
+		{ID: "uploads", AbortIncompleteUploadDays: 7},
+	}
+	if err := store.PutLifecycleRules(ctx, rules); err != nil {
+		t.Fatalf("PutLifecycleRules: %v", err)
+	}
+	got, err := store.LifecycleRules(ctx)
+	if err != nil {
+		t.Fatalf("LifecycleRules: %v", err)
+	}
+	if len(got) != 3 || got[0].ID != "ops-temp" {
+		t.Fatalf("LifecycleRules = %+v, want the foreign rule and both new ones", got)
+	}
+	if r := got[1]; r.Prefix != "reports/" || r.ExpirationDays != 90 || len(r.Transitions) != 1 || r.Transitions[0] != rules[0].Transitions[0] {
+		t.Errorf("reports rule = %+v, want %+v", r, rules[0])
+	}
+	if r := got[2]; r.AbortIncompleteUploadDays != 7 || r.Disabled {
+		t.Errorf("uploads rule = %+v, want %+v", r, rules[1])
+	}
+
+	// Putting a rule again replaces it in place
+	if err := store.PutLifecycleRules(ctx, []LifecycleRule{{ID: "reports", Prefix: "reports/", ExpirationDays: 30, Disabled: true}}); err != nil {
+		t.Fatalf("PutLifecycleRules: %v", err)
+	}
+	if got, _ = store.LifecycleRules(ctx); len(got) != 3 || got[1].ExpirationDays != 30 || !got[1].Disabled || len(got[1].Transitions) != 0 {
+		t.Errorf("LifecycleRules after replace = %+v", got)
+	}
+
+	if err := store.DeleteLifecycleRules(ctx, "reports", "uploads", "missing"); err != nil {
+		t.Fatalf("DeleteLifecycleRules: %v", err)
+	}
+	if got, _ = store.LifecycleRules(ctx); len(got) != 1 || got[0].ID != "ops-temp" {
+		t.Errorf("LifecycleRules after delete = %+v, want only the foreign rule", got)
+	}
+	if err := store.DeleteLifecycleRules(ctx, "ops-temp"); err != nil {
+		t.Fatalf("DeleteLifecycleRules: %v", err)
+	}
+	if doc := fake.lifecycleDocument(); doc != "" {
+		t.Errorf("lifecycle configuration = %q, want it deleted with its last rule", doc)
+	}
+	if got, err = store.LifecycleRules(ctx); err != nil || len(got) != 0 {
+		t.Errorf("LifecycleRules without a configuration = %+v, %v", got, err)
+	}
+
+	for name, rule := range map[string]LifecycleRule{
+		"no id":                    {ExpirationDays: 1},
+		"no action":                {ID: "a"},
+		"negative days":            {ID: "a", ExpirationDays: -1},
+		"unknown storage class":    {ID: "a", Transitions: []LifecycleTransition{{Days: 30, StorageClass: "COLD"}}},
+		"transition at expiration": {ID: "a", ExpirationDays: 30, Transitions: []LifecycleTransition{{Days: 30, StorageClass: StorageClassGlacier}}},
+	} {
+		if err := store.PutLifecycleRules(ctx, []LifecycleRule{rule}); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: PutLifecycleRules error = %v, want ErrInvalidInput", name, err)
+		}
+	}
+	if err := store.PutLifecycleRules(ctx, []LifecycleRule{{ID: "a", ExpirationDays: 1}, {ID: "a", ExpirationDays: 2}}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("duplicate ids: PutLifecycleRules error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestS3GeneratePresignedPost(t *testing.T) {
+	store, err := newFakeS3Store(t, &fakeS3{})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+
+	// policy returns the form's policy conditions, each as its JSON text
+	policy := func(t *testing.T, post *PresignedPost) []string {
+		t.Helper()
+		raw, err := base64.StdEncoding.DecodeString(post.Fields["policy"])
+		if err != nil {
+			t.Fatalf("policy field: %v", err)
+		}
+		var doc struct {
+			Conditions []json.RawMessage `json:"conditions"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			t.Fatalf("policy document %s: %v", raw, err)
+		}
+		conditions := make([]string, len(doc.Conditions))
+		for i, c := range doc.Conditions {
+			conditions[i] = string(c)
+		}
+		return conditions
+	}
+
+	tests := []struct {
+		name           string
+		input          PresignedPostInput
+		wantKey        string
+		wantConditions []string
+	}{
+		{
+			name:           "exact key and type",
+			input:          PresignedPostInput{Key: "avatars/1.png", ContentType: "image/png", MaxSize: 1 << 20},
+			wantKey:        "avatars/1.png",
+			wantConditions: []string{`{"Content-Type":"image/png"}`, `["content-length-range",0,1048576]`},
+		},
+		{
+			name:           "key and type prefixes",
+			input:          PresignedPostInput{KeyPrefix: "uploads/u1/", ContentTypePrefix: "image/", MinSize: 1, MaxSize: 10},
+			wantKey:        "uploads/u1/${filename}",
+			wantConditions: []string{`["starts-with","$key","uploads/u1/"]`, `["starts-with","$Content-Type","image/"]`, `["content-length-range",1,10]`},
+		},
+		{
+			name:           "minimum only",
+			input:          PresignedPostInput{Key: "a.bin", MinSize: 1},
+			wantKey:        "a.bin",
+			wantConditions: []string{fmt.Sprintf(`["content-length-range",1,%d]`, int64(maxPostObjectSize))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.Expiration = time.Minute
+			post, err := store.GeneratePresignedPost(context.Background(), &tt.input)
+			if err != nil {
+				t.Fatalf("GeneratePresignedPost: %v", err)
+			}
+			if post.Fields["key"] != tt.wantKey {
+				t.Errorf("key field = %q, want %q", post.Fields["key"], tt.wantKey)
+			}
+			if post.Fields["Content-Type"] != tt.input.ContentType {
+				t.Errorf("Content-Type field = %q, want %q", post.Fields["Content-Type"], tt.input.ContentType)
+			}
+			conditions := strings.Join(policy(t, post), " ")
+			for _, want := range tt.wantConditions {
+				if !strings.Contains(conditions, want) {
+					t.Errorf("policy conditions %s, want %s", conditions, want)
+				}
+			}
+		})
+	}
+
+	for name, input := range map[string]*PresignedPostInput{
+		"no key":              {Expiration: time.Minute},
+		"negative size":       {Key: "a", MinSize: -1, Expiration: time.Minute},
+		"inverted size range": {Key: "a", MinSize: 10, MaxSize: 5, Expiration: time.Minute},
+		"type outside prefix": {Key: "a", ContentType: "text/plain", ContentTypePrefix: "image/", Expiration: time.Minute},
+	} {
+		if _, err := store.GeneratePresignedPost(context.Background(), input); !errors.Is(err, ErrInvalidInput) && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: GeneratePresignedPost error = %v, want invalid input", name, err)
+		}
+	}
+}
@@ -1,22 +1,28 @@
-package redis
+// Package cache is a JSON-encoding cache over Redis with helpers for counters,
+// sets and locks. It has no dependencies on this service's domain, so any
+// service can import it; the API is stable and changes are additive.
+package cache
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/TopThisHat/stdlib-golang-api/internal/domain"
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrMiss is returned by Get when the key doesn't exist
+var ErrMiss = errors.New("cache miss")
+
 // Cache provides common caching operations
 type Cache struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-// NewCache creates a new Cache instance
-func NewCache(client *redis.Client) *Cache {
+// New creates a Cache backed by client (a *redis.Client, cluster or ring client)
+func New(client redis.UniversalClient) *Cache {
 	return &Cache{client: client}
 }
 
@@ -25,7 +31,7 @@ func (c *Cache) Get(ctx context.Context, key string, dest interface{}) error {
 	data, err := c.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return domain.ErrCacheMiss
+			return ErrMiss
 		}
 		return fmt.Errorf("redis get failed: %w", err)
 	}
@@ -1,3 +1,6 @@
+// Package logger is a structured logger built on log/slog with secret
+// scrubbing. It depends only on the standard library so any service can
+// import it; the API is stable and changes are additive.
 package logger
 
 import (
@@ -11,6 +14,7 @@ import (
 // Logger wraps slog.Logger with convenience methods and production defaults
 type Logger struct {
 	*slog.Logger
+	level *slog.LevelVar // Shared by child loggers so level changes apply everywhere
 }
 
 // New creates a production-grade structured logger with the specified log level.
@@ -33,12 +37,14 @@ func New(level string) *Logger {
 // NewWithOptions creates a logger with custom output and format options
 func NewWithOptions(level string, w io.Writer, jsonFormat bool) *Logger {
 	logLevel := parseLevel(level)
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(logLevel)
 
 	var handler slog.Handler
 
 	opts := &slog.HandlerOptions{
 		AddSource: logLevel == slog.LevelDebug, // Include file:line only in debug mode
-		Level:     logLevel,
+		Level:     levelVar,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			// Customize time format for readability
 			if a.Key == slog.TimeKey {
@@ -46,7 +52,8 @@ func NewWithOptions(level string, w io.Writer, jsonFormat bool) *Logger {
 					return slog.String(a.Key, t.Format(time.RFC3339))
 				}
 			}
-			return a
+			// Never let credentials or DSNs reach the log sink (see scrub.go)
+			return scrubAttr(a)
 		},
 	}
 
@@ -60,9 +67,26 @@ func NewWithOptions(level string, w io.Writer, jsonFormat bool) *Logger {
 
 	return &Logger{
 		Logger: slog.New(handler),
+		level:  levelVar,
 	}
 }
 
+// SetLevel changes the minimum log level at runtime
+// The change applies to this logger and every logger derived from it
+func (l *Logger) SetLevel(level string) {
+	if l.level != nil {
+		l.level.Set(parseLevel(level))
+	}
+}
+
+// Level returns the current minimum log level
+func (l *Logger) Level() slog.Level {
+	if l.level == nil {
+		return slog.LevelInfo
+	}
+	return l.level.Level()
+}
+
 // parseLevel converts a string log level to slog.Level
 func parseLevel(level string) slog.Level {
 	switch level {
@@ -81,7 +105,7 @@ func parseLevel(level string) slog.Level {
 
 // WithContext returns a new logger with context values attached
 func (l *Logger) WithContext(ctx context.Context) *Logger {
-	return &Logger{Logger: l.Logger.With()}
+	return &Logger{Logger: l.Logger.With(), level: l.level}
 }
 
 // WithFields returns a new logger with the given fields pre-attached
@@ -92,7 +116,7 @@ func (l *Logger) WithContext(ctx context.Context) *Logger {
 //	reqLogger := logger.WithFields("request_id", reqID, "user_id", userID)
 //	reqLogger.Info("processing request")
 func (l *Logger) WithFields(args ...any) *Logger {
-	return &Logger{Logger: l.Logger.With(args...)}
+	return &Logger{Logger: l.Logger.With(args...), level: l.level}
 }
 
 // WithError is a convenience method to log an error with consistent key naming
@@ -100,7 +124,7 @@ func (l *Logger) WithError(err error) *Logger {
 	if err == nil {
 		return l
 	}
-	return &Logger{Logger: l.Logger.With("error", err.Error())}
+	return &Logger{Logger: l.Logger.With("error", err.Error()), level: l.level}
 }
 
 // Fatal logs at error level and exits with code 1
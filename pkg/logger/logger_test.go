@@ -156,6 +156,27 @@ func TestLogLevels(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithOptions("info", &buf, false)
+	child := logger.WithFields("component", "test")
+
+	child.Debug("hidden debug message")
+	logger.SetLevel("debug")
+	child.Debug("visible debug message")
+
+	output := buf.String()
+	if strings.Contains(output, "hidden debug message") {
+		t.Errorf("expected debug message to be filtered before SetLevel: %s", output)
+	}
+	if !strings.Contains(output, "visible debug message") {
+		t.Errorf("expected child logger to honor new level: %s", output)
+	}
+	if logger.Level().String() != "DEBUG" {
+		t.Errorf("expected level DEBUG, got %v", logger.Level())
+	}
+}
+
 func TestWithFields(t *testing.T) {
 	var buf bytes.Buffer
 	logger := NewWithOptions("info", &buf, true)
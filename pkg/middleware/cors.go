@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// CORS Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// CORSConfig holds CORS configuration
+type CORSConfig struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"), "*" for
+	// any origin, or a scheme and wildcard host ("https://*.example.com") for
+	// every subdomain of a domain, but not the domain itself. Ports match
+	// exactly: a wildcard with no port allows the scheme's default port only.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int // Preflight cache duration in seconds (0 omits Access-Control-Max-Age)
+}
+
+// DefaultCORSConfig returns sensible CORS defaults
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: false,
+		MaxAge:           86400, // 24 hours
+	}
+}
+
+// CORS handles Cross-Origin Resource Sharing
+func CORS(config CORSConfig) Middleware {
+	return NewCORSPolicy(config).Middleware()
+}
+
+// CORSPolicy applies a CORS configuration whose allowed origins can be swapped at runtime
+type CORSPolicy struct {
+	config  CORSConfig
+	origins atomic.Pointer[allowedOrigins]
+}
+
+// allowedOrigins is an immutable snapshot of the allowed origin set
+type allowedOrigins struct {
+	allowAll  bool
+	set       map[string]bool
+	wildcards []originWildcard
+}
+
+// originWildcard matches the origins with a scheme and a host under a domain
+type originWildcard struct {
+	prefix string // "https://"
+	suffix string // ".example.com", or ".example.com:8443" to allow that port only
+}
+
+// match reports whether origin is allowed
+func (a *allowedOrigins) match(origin string) bool {
+	if a.allowAll || a.set[origin] {
+		return true
+	}
+	for _, w := range a.wildcards {
+		if len(origin) > len(w.prefix)+len(w.suffix) &&
+			strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) &&
+			!strings.ContainsAny(origin[len(w.prefix):len(origin)-len(w.suffix)], "/:") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCORSPolicy creates a policy starting with config.AllowedOrigins
+func NewCORSPolicy(config CORSConfig) *CORSPolicy {
+	p := &CORSPolicy{config: config}
+	p.SetAllowedOrigins(config.AllowedOrigins)
+	return p
+}
+
+// SetAllowedOrigins replaces the allowed origins for subsequent requests
+func (p *CORSPolicy) SetAllowedOrigins(origins []string) {
+	snapshot := &allowedOrigins{set: make(map[string]bool)}
+	for _, origin := range origins {
+		if origin == "*" {
+			snapshot.allowAll = true
+		}
+		if scheme, host, ok := strings.Cut(origin, "://*."); ok {
+			snapshot.wildcards = append(snapshot.wildcards, originWildcard{prefix: scheme + "://", suffix: "." + host})
+			continue
+		}
+		snapshot.set[origin] = true
+	}
+	p.origins.Store(snapshot)
+}
+
+// Middleware returns the CORS middleware backed by this policy. Preflight
+// requests (OPTIONS with Origin and Access-Control-Request-Method) are
+// answered here; other OPTIONS requests go on to next.
+func (p *CORSPolicy) Middleware() Middleware {
+	config := p.config
+	methods := strings.Join(config.AllowedMethods, ", ")
+	headers := strings.Join(config.AllowedHeaders, ", ")
+	exposed := strings.Join(config.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowed := p.origins.Load()
+			preflight := r.Method == http.MethodOptions && origin != "" &&
+				r.Header.Get("Access-Control-Request-Method") != ""
+
+			// Unless every origin gets the same "*", the response depends on
+			// Origin, and shared caches must keep one copy per origin
+			echoOrigin := !allowed.allowAll || config.AllowCredentials
+			if echoOrigin {
+				w.Header().Add("Vary", "Origin")
+			}
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !allowed.match(origin) {
+				if preflight {
+					// Answered without CORS headers, so the browser refuses it
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if echoOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			}
+			if config.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+				if config.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", exposed)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InFlightRequest describes a request that has not finished yet
+type InFlightRequest struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	RequestID string    `json:"request_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	AgeMS     int64     `json:"age_ms"`
+}
+
+// InFlightStats is a snapshot of the requests being served
+type InFlightStats struct {
+	Count  int               `json:"count"`
+	Oldest []InFlightRequest `json:"oldest"` // Longest-running first
+}
+
+// InFlight tracks the requests currently being served, so a stuck instance
+// can report what it is stuck on
+type InFlight struct {
+	mu       sync.Mutex
+	next     uint64
+	requests map[uint64]inFlightEntry
+	now      func() time.Time
+}
+
+type inFlightEntry struct {
+	method    string
+	path      string
+	requestID string
+	startedAt time.Time
+}
+
+// NewInFlight creates an empty tracker
+func NewInFlight() *InFlight {
+	return &InFlight{
+		requests: make(map[uint64]inFlightEntry),
+		now:      time.Now,
+	}
+}
+
+// Middleware records each request until its handler returns. Install it
+// after RequestID so entries carry the request ID.
+func (t *InFlight) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := t.start(r)
+			defer t.finish(id)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (t *InFlight) start(r *http.Request) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	t.requests[t.next] = inFlightEntry{
+		method:    r.Method,
+		path:      r.URL.Path,
+		requestID: GetRequestID(r.Context()),
+		startedAt: t.now(),
+	}
+	return t.next
+}
+
+func (t *InFlight) finish(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.requests, id)
+}
+
+// Count returns the number of requests being served
+func (t *InFlight) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.requests)
+}
+
+// Snapshot returns the request count and up to limit of the longest-running
+// requests
+func (t *InFlight) Snapshot(limit int) InFlightStats {
+	t.mu.Lock()
+	now := t.now()
+	oldest := make([]InFlightRequest, 0, len(t.requests))
+	for _, e := range t.requests {
+		oldest = append(oldest, InFlightRequest{
+			Method:    e.method,
+			Path:      e.path,
+			RequestID: e.requestID,
+			StartedAt: e.startedAt,
+			AgeMS:     now.Sub(e.startedAt).Milliseconds(),
+		})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(oldest, func(i, j int) bool { return oldest[i].StartedAt.Before(oldest[j].StartedAt) })
+	stats := InFlightStats{Count: len(oldest)}
+	if len(oldest) > limit {
+		oldest = oldest[:limit]
+	}
+	stats.Oldest = oldest
+	return stats
+}
@@ -0,0 +1,532 @@
+// Package middleware provides reusable net/http middleware: request IDs,
+// access logging, panic recovery, CORS, rate limiting, security headers,
+// in-flight request tracking, timeouts, request body decompression and
+// request size/content-type checks.
+// It depends only on the standard library and pkg/logger; the API is stable
+// and changes are additive.
+//
+// Errors are written in the JSON envelope shared by the org's services:
+//
+//	{"success": false, "error": {"code": "RATE_LIMIT_EXCEEDED", "message": "..."}}
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Middleware is a function that wraps an http.Handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies middlewares in order (first middleware wraps outermost)
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// errorResponse is the shared error envelope
+type errorResponse struct {
+	Success bool `json:"success"`
+	Error   struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// WriteError sends an error response in the shared envelope
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	var response errorResponse
+	response.Error.Code = code
+	response.Error.Message = message
+	json.NewEncoder(w).Encode(response)
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Request ID Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+// maxRequestIDLength bounds the request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// GetRequestID retrieves the request ID from context
+func GetRequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// WithRequestID returns a copy of ctx carrying id, for work outside an HTTP
+// request (jobs, tests) whose downstream calls should be traced under it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to
+// pass on to logs, SQL tracing and outgoing headers: letters, digits and
+// "-_.:", at most maxRequestIDLength of them
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID adds a unique request ID to each request for tracing
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Check for existing request ID (from load balancer/proxy); one
+			// that could not be passed on downstream is replaced
+			requestID := r.Header.Get("X-Request-ID")
+			if !validRequestID(requestID) {
+				requestID = uuid.New().String()
+			}
+
+			// Add to context and response header
+			ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+			w.Header().Set("X-Request-ID", requestID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Logging Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ResponseWriter wraps http.ResponseWriter to capture the status code and
+// the number of body bytes written
+type ResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+// NewResponseWriter wraps w; the status defaults to 200 until WriteHeader
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rw *ResponseWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.written += int64(n)
+	return n, err
+}
+
+// Status returns the response status code
+func (rw *ResponseWriter) Status() int {
+	return rw.status
+}
+
+// BytesWritten returns the number of body bytes written so far
+func (rw *ResponseWriter) BytesWritten() int64 {
+	return rw.written
+}
+
+// Unwrap returns the underlying writer (used by http.ResponseController)
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Logging logs each HTTP request with timing and status
+func Logging(logg *logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := NewResponseWriter(w)
+
+			// Execute request
+			next.ServeHTTP(wrapped, r)
+
+			// Log request details
+			duration := time.Since(start)
+			requestID := GetRequestID(r.Context())
+
+			logg.Info("http request",
+				"request_id", requestID,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", wrapped.status,
+				"duration_ms", duration.Milliseconds(),
+				"bytes", wrapped.written,
+				"remote_addr", r.RemoteAddr,
+				"user_agent", r.UserAgent(),
+			)
+		})
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Recovery Middleware (Panic Handler)
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// Recover recovers from panics and returns a 500 error
+func Recover(logg *logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if err := recover(); err != nil {
+					requestID := GetRequestID(r.Context())
+					stack := debug.Stack()
+
+					// Panic values and stacks can embed headers, DSNs or body
+					// fragments; scrub them before they reach the logs
+					logg.Error("panic recovered",
+						"request_id", requestID,
+						"error", logger.Scrub(fmt.Sprint(err)),
+						"stack", logger.Scrub(string(stack)),
+						"path", r.URL.Path,
+						"method", r.Method,
+					)
+
+					WriteError(w, http.StatusInternalServerError,
+						"INTERNAL_ERROR", "An unexpected error occurred")
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Security Headers Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// SecureHeaders adds security-related HTTP headers
+func SecureHeaders() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Prevent MIME type sniffing
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+
+			// XSS protection (legacy but still useful)
+			w.Header().Set("X-XSS-Protection", "1; mode=block")
+
+			// Prevent clickjacking
+			w.Header().Set("X-Frame-Options", "DENY")
+
+			// Referrer policy
+			w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+
+			// Content Security Policy (adjust based on your needs)
+			w.Header().Set("Content-Security-Policy", "default-src 'self'")
+
+			// HSTS (only meaningful, and only sent, over HTTPS)
+			if r.TLS != nil {
+				w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Request Timeout Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// timeoutWriteGrace keeps the connection writable past a request's timeout
+// for long enough to send the timeout response
+const timeoutWriteGrace = time.Second
+
+// Timeout bounds how long the handler may take, with the semantics of
+// http.TimeoutHandler: the handler's context is cancelled at the deadline
+// and its response is buffered, so a request that runs out of time gets a
+// clean 503 REQUEST_TIMEOUT and any later write by the handler fails with
+// http.ErrHandlerTimeout instead of reaching the client. A handler that
+// flushes commits what it wrote so far and streams from then on; past the
+// deadline its writes fail the same way. Handlers cannot hijack the
+// connection.
+func Timeout(timeout time.Duration) Middleware {
+	return TimeoutFunc(func(*http.Request) time.Duration { return timeout })
+}
+
+// TimeoutFunc is Timeout with the limit chosen per request (e.g. per route);
+// requests given 0 are not limited nor buffered
+func TimeoutFunc(timeoutFor func(*http.Request) time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := timeoutFor(r)
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			// A route allowed longer than the server's write timeout still
+			// gets its response out
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + timeoutWriteGrace))
+
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						if p != http.ErrAbortHandler {
+							// Keep the handler's stack; the panic is raised again below
+							p = fmt.Sprintf("%v\n\n%s", p, debug.Stack())
+						}
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// On the serving goroutine, so Recover answers it
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.commit()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if ctx.Err() == context.DeadlineExceeded && !tw.committed {
+					WriteError(w, http.StatusServiceUnavailable,
+						"REQUEST_TIMEOUT", "Request took too long to process")
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler finishes or flushes,
+// and refuses every write once the request has timed out
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header // The handler's headers, copied to w on commit
+
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	committed   bool // The response has gone to w, later writes go straight through
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader || code < http.StatusOK {
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.status = http.StatusOK
+	}
+	if tw.committed {
+		return tw.w.Write(b)
+	}
+	return tw.buf.Write(b)
+}
+
+// FlushError commits the response so far and flushes it to the client
+// (used by http.ResponseController)
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	tw.commit()
+	return http.NewResponseController(tw.w).Flush()
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.FlushError()
+}
+
+// SetReadDeadline and SetWriteDeadline reach the connection for streaming
+// handlers (used by http.ResponseController)
+func (tw *timeoutWriter) SetReadDeadline(deadline time.Time) error {
+	return http.NewResponseController(tw.w).SetReadDeadline(deadline)
+}
+
+func (tw *timeoutWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(tw.w).SetWriteDeadline(deadline)
+}
+
+// commit sends the buffered response to w. Called with mu held, from the
+// handler's goroutine or once it has returned, so the header is not in use.
+func (tw *timeoutWriter) commit() {
+	if tw.committed {
+		return
+	}
+	tw.committed = true
+	dst := tw.w.Header()
+	for name, values := range tw.header {
+		dst[name] = values
+	}
+	if !tw.wroteHeader {
+		tw.status = http.StatusOK
+	}
+	tw.w.WriteHeader(tw.status)
+	if tw.buf.Len() > 0 {
+		tw.w.Write(tw.buf.Bytes())
+		tw.buf.Reset()
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Content-Type Validation Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// ContentType ensures requests with body have correct Content-Type
+func ContentType(contentTypes ...string) Middleware {
+	allowedTypes := make(map[string]bool)
+	for _, ct := range contentTypes {
+		allowedTypes[ct] = true
+	}
+	message := "Content-Type must be " + strings.Join(contentTypes, " or ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Only check for methods that typically have a body
+			if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+				ct := r.Header.Get("Content-Type")
+				// Extract media type without parameters
+				mediaType := strings.Split(ct, ";")[0]
+				mediaType = strings.TrimSpace(mediaType)
+
+				if ct == "" || !allowedTypes[mediaType] {
+					WriteError(w, http.StatusUnsupportedMediaType,
+						"UNSUPPORTED_MEDIA_TYPE", message)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Request Size Limiter Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// MaxBodySize limits the request body size
+func MaxBodySize(maxBytes int64) Middleware {
+	return MaxBodySizeFunc(func(*http.Request) int64 { return maxBytes })
+}
+
+// MaxBodySizeFunc is MaxBodySize with the limit chosen per request (e.g. per route)
+func MaxBodySizeFunc(maxBytesFor func(*http.Request) int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytesFor(r))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DecompressBody decompresses request bodies sent with Content-Encoding:
+// gzip, so handlers read them as if they were sent plain. maxBytes bounds
+// the decompressed body, which a small compressed one can inflate far beyond
+// (a zip bomb): reading past it fails with *http.MaxBytesError, as reading
+// past MaxBodySize does. Install it after MaxBodySize, which then bounds the
+// compressed body.
+//
+// Bodies in any other encoding are refused with 415 Unsupported Media Type
+// and an Accept-Encoding header naming gzip.
+func DecompressBody(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+			case "", "identity":
+				next.ServeHTTP(w, r)
+				return
+			case "gzip", "x-gzip":
+			default:
+				w.Header().Set("Accept-Encoding", "gzip")
+				WriteError(w, http.StatusUnsupportedMediaType,
+					"UNSUPPORTED_ENCODING", "Request bodies must be sent plain or gzip encoded")
+				return
+			}
+
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					WriteError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request body is too large")
+					return
+				}
+				WriteError(w, http.StatusBadRequest, "INVALID_ENCODING", "Request body is not valid gzip")
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, &decompressedBody{Reader: gz, gz: gz, body: r.Body}, maxBytes)
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// decompressedBody reads a gzip body decompressed, closing both on Close
+type decompressedBody struct {
+	io.Reader
+	gz   *gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *decompressedBody) Close() error {
+	return errors.Join(b.gz.Close(), b.body.Close())
+}
@@ -0,0 +1,445 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TopThisHat/stdlib-golang-api/pkg/logger"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	}), tag("outer"), tag("inner"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := strings.Join(order, ","); got != "outer,inner,handler" {
+		t.Errorf("order = %s, want outer,inner,handler", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := NewRateLimiter(2, time.Minute)
+	var limited int
+	h := RateLimit(limiter, OnRateLimited(func(*http.Request) { limited++ }))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	var codes []int
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+
+		if rec.Code == http.StatusTooManyRequests {
+			var body errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code != "RATE_LIMIT_EXCEEDED" {
+				t.Errorf("unexpected error body: %+v (%v)", body, err)
+			}
+		}
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("codes = %v, want [200 200 429]", codes)
+	}
+	if limited != 1 {
+		t.Errorf("OnRateLimited called %d times, want 1", limited)
+	}
+
+	// A scaled key gets a multiple of the rate
+	for i := range 4 {
+		if !limiter.AllowScaled("premium", 2) {
+			t.Fatalf("scaled request %d rejected, want 4 allowed", i+1)
+		}
+	}
+	if limiter.AllowScaled("premium", 2) {
+		t.Error("expected the 5th scaled request to be rejected")
+	}
+
+	// Rate 0 disables limiting
+	limiter.SetRate(0)
+	if !limiter.Allow("203.0.113.7") {
+		t.Error("expected limiting to be disabled at rate 0")
+	}
+}
+
+func TestRateLimiterMaxEntries(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Minute, WithMaxEntries(64))
+
+	// A spray of distinct keys never grows the limiter past its bound
+	for i := range 10_000 {
+		limiter.Allow(fmt.Sprintf("198.51.%d.%d", i/256, i%256))
+	}
+	if n := limiter.Len(); n > 64 {
+		t.Errorf("limiter tracks %d keys, want at most 64", n)
+	}
+
+	// A key in use survives while others come and go
+	limiter.Allow("203.0.113.7")
+	for i := range 8 {
+		limiter.Allow(fmt.Sprintf("192.0.2.%d", i))
+		if limiter.Allow("203.0.113.7") {
+			t.Fatal("recently used key was evicted and given a fresh budget")
+		}
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	limiter := NewRateLimiter(100, time.Minute)
+	var wg sync.WaitGroup
+	var allowed atomic.Int64
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if limiter.Allow("shared") {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := allowed.Load(); got != 100 {
+		t.Errorf("allowed %d of 400 concurrent requests, want 100", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "198.51.100.4:53211"
+	if got := ClientIP(req); got != "198.51.100.4" {
+		t.Errorf("ClientIP() = %q, want the remote address without its port", got)
+	}
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := ClientIP(req); got != "198.51.100.4" {
+		t.Errorf("ClientIP() = %q, want X-Forwarded-For ignored without ForwardedFor", got)
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.10 ", ""})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an invalid CIDR to be rejected")
+	}
+	if _, err := ParseTrustedProxies([]string{"proxy.internal"}); err == nil {
+		t.Error("expected a hostname to be rejected")
+	}
+
+	tests := []struct {
+		name      string
+		trusted   []netip.Prefix
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"no trusted proxies", nil, "10.0.0.5:4000", []string{"203.0.113.7"}, "10.0.0.5"},
+		{"untrusted peer", trusted, "198.51.100.4:4000", []string{"203.0.113.7"}, "198.51.100.4"},
+		{"trusted peer", trusted, "10.0.0.5:4000", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"single trusted ip", trusted, "192.0.2.10:4000", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"no header", trusted, "10.0.0.5:4000", nil, "10.0.0.5"},
+		{"spoofed prefix", trusted, "10.0.0.5:4000", []string{"1.2.3.4, 203.0.113.7"}, "203.0.113.7"},
+		{"proxy chain", trusted, "10.0.0.5:4000", []string{"203.0.113.7, 10.1.1.1", "10.2.2.2"}, "203.0.113.7"},
+		{"only proxies", trusted, "10.0.0.5:4000", []string{"10.1.1.1, 10.2.2.2"}, "10.1.1.1"},
+		{"garbled hop", trusted, "10.0.0.5:4000", []string{"203.0.113.7, bogus, 10.1.1.1"}, "10.1.1.1"},
+		{"mapped ipv6", trusted, "[::ffff:10.0.0.5]:4000", []string{"::ffff:203.0.113.7"}, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := ForwardedFor(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var seen string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if seen != "abc-123" || rec.Header().Get("X-Request-ID") != "abc-123" {
+		t.Errorf("request ID = %q, header = %q", seen, rec.Header().Get("X-Request-ID"))
+	}
+
+	// IDs that could not be passed on downstream are replaced
+	for _, id := range []string{"two words", "quote\"d", strings.Repeat("a", maxRequestIDLength+1)} {
+		req.Header.Set("X-Request-ID", id)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if seen == id || seen == "" || rec.Header().Get("X-Request-ID") != seen {
+			t.Errorf("X-Request-ID %q: request ID = %q, header = %q", id, seen, rec.Header().Get("X-Request-ID"))
+		}
+	}
+
+	if got := GetRequestID(WithRequestID(context.Background(), "job-7")); got != "job-7" {
+		t.Errorf("GetRequestID(WithRequestID()) = %q", got)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	tracker := NewInFlight()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}), RequestID(), tracker.Middleware())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+		req.Header.Set("X-Request-ID", "stuck-1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-started
+
+	stats := tracker.Snapshot(10)
+	if stats.Count != 1 || len(stats.Oldest) != 1 {
+		t.Fatalf("Snapshot() = %+v, want one request", stats)
+	}
+	if got := stats.Oldest[0]; got.Method != http.MethodGet || got.Path != "/api/orders" || got.RequestID != "stuck-1" {
+		t.Errorf("in-flight request = %+v", got)
+	}
+
+	close(release)
+	<-done
+	if n := tracker.Count(); n != 0 {
+		t.Errorf("Count() after the handler returned = %d, want 0", n)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			w.Header().Set("X-Handler", "yes")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("done"))
+			return
+		}
+		w.Header().Set("X-Handler", "yes")
+		w.Write([]byte("partial"))
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond) // Let the timeout response go out
+		_, err := w.Write([]byte("late"))
+		lateWrite <- err
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusCreated || rec.Body.String() != "done" || rec.Header().Get("X-Handler") != "yes" {
+		t.Errorf("fast response = %d %q %v, want the handler's", rec.Code, rec.Body, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	var body errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Code != http.StatusServiceUnavailable || body.Error.Code != "REQUEST_TIMEOUT" {
+		t.Errorf("slow response = %d %+v (%v), want 503 REQUEST_TIMEOUT", rec.Code, body, err)
+	}
+	if rec.Header().Get("X-Handler") != "" {
+		t.Error("timeout response carries the handler's headers")
+	}
+	if err := <-lateWrite; err != http.ErrHandlerTimeout {
+		t.Errorf("late write error = %v, want http.ErrHandlerTimeout", err)
+	}
+}
+
+func TestTimeoutFlushCommits(t *testing.T) {
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+		}
+		<-r.Context().Done()
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "first\n" || !rec.Flushed {
+		t.Errorf("stream response = %d %q (flushed %v), want the committed part only", rec.Code, rec.Body, rec.Flushed)
+	}
+}
+
+func TestTimeoutRepanics(t *testing.T) {
+	h := Recover(logger.NewWithOptions("error", io.Discard, true))(
+		Timeout(time.Second)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic("boom")
+		})))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500 from Recover", rec.Code)
+	}
+}
+
+func TestDecompressBody(t *testing.T) {
+	gzipped := func(body string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(body))
+		gz.Close()
+		return &buf
+	}
+	var got string
+	var readErr error
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" || r.ContentLength != -1 {
+			t.Errorf("handler saw Content-Encoding %q, length %d; want them cleared",
+				r.Header.Get("Content-Encoding"), r.ContentLength)
+		}
+		body, err := io.ReadAll(r.Body)
+		got, readErr = string(body), err
+	}), MaxBodySize(1<<10), DecompressBody(64))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users/import", gzipped(`{"users":[]}`))
+	req.Header.Set("Content-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if readErr != nil || got != `{"users":[]}` {
+		t.Fatalf("handler read %q, %v; want the decompressed body", got, readErr)
+	}
+
+	// Well under the limit compressed, far over it decompressed
+	req = httptest.NewRequest(http.MethodPost, "/api/users/import", gzipped(strings.Repeat("a", 1<<20)))
+	req.Header.Set("Content-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	var tooLarge *http.MaxBytesError
+	if !errors.As(readErr, &tooLarge) || len(got) != 64 {
+		t.Fatalf("zip bomb read %d bytes, error %v; want MaxBytesError after 64", len(got), readErr)
+	}
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     io.Reader
+		want     int
+	}{
+		{"not gzip", "gzip", strings.NewReader("plain"), http.StatusBadRequest},
+		{"unsupported encoding", "br", strings.NewReader("..."), http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/users/import", tt.body)
+			req.Header.Set("Content-Encoding", tt.encoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	config := DefaultCORSConfig()
+	config.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org", "https://*.example.net:8443"}
+	config.MaxAge = 600
+	handler := CORS(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		preflight  bool
+		wantStatus int
+		wantOrigin string
+	}{
+		{"exact origin", http.MethodGet, "https://app.example.com", false, http.StatusTeapot, "https://app.example.com"},
+		{"subdomain wildcard", http.MethodGet, "https://a.b.example.org", false, http.StatusTeapot, "https://a.b.example.org"},
+		{"wildcard skips the apex", http.MethodGet, "https://example.org", false, http.StatusTeapot, ""},
+		{"wildcard checks the scheme", http.MethodGet, "http://a.example.org", false, http.StatusTeapot, ""},
+		{"wildcard checks the port", http.MethodGet, "https://a.example.org:8443", false, http.StatusTeapot, ""},
+		{"wildcard with a port", http.MethodGet, "https://a.example.net:8443", false, http.StatusTeapot, "https://a.example.net:8443"},
+		{"wildcard with a port needs it", http.MethodGet, "https://a.example.net", false, http.StatusTeapot, ""},
+		{"wildcard with another port", http.MethodGet, "https://a.example.net:9443", false, http.StatusTeapot, ""},
+		{"preflight", http.MethodOptions, "https://app.example.com", true, http.StatusNoContent, "https://app.example.com"},
+		{"preflight from a refused origin", http.MethodOptions, "https://evil.test", true, http.StatusNoContent, ""},
+		{"options without preflight headers", http.MethodOptions, "", false, http.StatusTeapot, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/orders", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if vary := rec.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Origin" {
+				t.Errorf("Vary = %v, want Origin first", vary)
+			}
+			maxAge := rec.Header().Get("Access-Control-Max-Age")
+			if tt.preflight && tt.wantOrigin != "" && maxAge != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", maxAge)
+			}
+		})
+	}
+
+	// Every origin gets "*", so the response does not vary by origin
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/features", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+	CORS(DefaultCORSConfig())(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" || rec.Header().Get("Vary") != "" {
+		t.Errorf("allow all: Access-Control-Allow-Origin = %q, Vary = %q; want *, none", got, rec.Header().Get("Vary"))
+	}
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Rate Limiting Middleware
+// ═══════════════════════════════════════════════════════════════════════════════
+
+const (
+	// DefaultRateLimiterMaxEntries bounds the keys a rate limiter tracks
+	DefaultRateLimiterMaxEntries = 100_000
+
+	// rateLimiterShards spreads keys over independently locked shards, so
+	// concurrent requests rarely wait on each other
+	rateLimiterShards = 32
+)
+
+// RateLimiter implements a fixed window rate limiter per key (usually a
+// client IP). Keys are spread over shards, each an LRU list of bounded
+// size: when a flood of new keys (e.g. a spoofed IP range) fills a shard,
+// the key seen least recently is forgotten rather than memory growing.
+// A forgotten key starts over with a full budget.
+type RateLimiter struct {
+	shards [rateLimiterShards]rateLimiterShard
+	rate   atomic.Int64  // requests per window (0 disables limiting)
+	window time.Duration // time window
+}
+
+// rateLimiterShard holds the keys hashing to one shard, most recently used first
+type rateLimiterShard struct {
+	mu         sync.Mutex
+	visitors   map[string]*list.Element // Of *visitor
+	lru        *list.List
+	maxEntries int
+}
+
+type visitor struct {
+	key       string
+	tokens    int
+	lastReset time.Time
+	lastSeen  time.Time
+}
+
+// RateLimiterOption configures a RateLimiter
+type RateLimiterOption func(*rateLimiterOptions)
+
+type rateLimiterOptions struct {
+	maxEntries int
+}
+
+// WithMaxEntries bounds the keys the limiter tracks (default
+// DefaultRateLimiterMaxEntries)
+func WithMaxEntries(n int) RateLimiterOption {
+	return func(o *rateLimiterOptions) {
+		o.maxEntries = n
+	}
+}
+
+// NewRateLimiter creates a rate limiter with the specified rate per window
+func NewRateLimiter(rate int, window time.Duration, opts ...RateLimiterOption) *RateLimiter {
+	options := rateLimiterOptions{maxEntries: DefaultRateLimiterMaxEntries}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	perShard := max((options.maxEntries+rateLimiterShards-1)/rateLimiterShards, 1)
+
+	rl := &RateLimiter{window: window}
+	rl.rate.Store(int64(rate))
+	for i := range rl.shards {
+		rl.shards[i].visitors = make(map[string]*list.Element)
+		rl.shards[i].lru = list.New()
+		rl.shards[i].maxEntries = perShard
+	}
+
+	// Cleanup old entries periodically
+	go rl.cleanup()
+
+	return rl
+}
+
+func (rl *RateLimiter) cleanup() {
+	ticker := time.NewTicker(rl.window)
+	for range ticker.C {
+		for i := range rl.shards {
+			rl.shards[i].expire(rl.window * 2)
+		}
+	}
+}
+
+// expire forgets the keys not seen for longer than idle; they are at the
+// back of the list
+func (s *rateLimiterShard) expire(idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for e := s.lru.Back(); e != nil; e = s.lru.Back() {
+		v := e.Value.(*visitor)
+		if time.Since(v.lastSeen) <= idle {
+			return
+		}
+		s.lru.Remove(e)
+		delete(s.visitors, v.key)
+	}
+}
+
+// SetRate changes the allowed requests per window at runtime (0 disables limiting)
+func (rl *RateLimiter) SetRate(rate int) {
+	rl.rate.Store(int64(rate))
+}
+
+// Len returns the number of keys the limiter tracks
+func (rl *RateLimiter) Len() int {
+	n := 0
+	for i := range rl.shards {
+		s := &rl.shards[i]
+		s.mu.Lock()
+		n += s.lru.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Allow consumes a token for key and reports whether the request may proceed
+func (rl *RateLimiter) Allow(key string) bool {
+	return rl.AllowScaled(key, 1)
+}
+
+// AllowScaled is Allow for a key entitled to multiplier times the rate (e.g.
+// a client on a higher tier); a multiplier below 1 counts as 1
+func (rl *RateLimiter) AllowScaled(key string, multiplier int) bool {
+	rate := int(rl.rate.Load())
+	if rate <= 0 {
+		return true
+	}
+	rate *= max(multiplier, 1)
+
+	s := rl.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e, exists := s.visitors[key]
+	if !exists {
+		if s.lru.Len() >= s.maxEntries {
+			oldest := s.lru.Back()
+			s.lru.Remove(oldest)
+			delete(s.visitors, oldest.Value.(*visitor).key)
+		}
+		s.visitors[key] = s.lru.PushFront(&visitor{key: key, tokens: rate - 1, lastReset: now, lastSeen: now})
+		return true
+	}
+	s.lru.MoveToFront(e)
+	v := e.Value.(*visitor)
+	v.lastSeen = now
+
+	// Reset tokens if window has passed
+	if now.Sub(v.lastReset) > rl.window {
+		v.tokens = rate - 1
+		v.lastReset = now
+		return true
+	}
+
+	// Check if tokens available
+	if v.tokens > 0 {
+		v.tokens--
+		return true
+	}
+
+	return false
+}
+
+// shard returns the shard holding key (FNV-1a, without allocating)
+func (rl *RateLimiter) shard(key string) *rateLimiterShard {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return &rl.shards[h%rateLimiterShards]
+}
+
+// RateLimitOption configures RateLimit
+type RateLimitOption func(*rateLimitOptions)
+
+type rateLimitOptions struct {
+	onLimited func(*http.Request)
+}
+
+// OnRateLimited calls fn for every rejected request, before the 429 is
+// written (e.g. to record a metric or security event)
+func OnRateLimited(fn func(r *http.Request)) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		o.onLimited = fn
+	}
+}
+
+// RateLimit middleware limits requests per IP
+func RateLimit(limiter *RateLimiter, opts ...RateLimitOption) Middleware {
+	var options rateLimitOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.Allow(ClientIP(r)) {
+				if options.onLimited != nil {
+					options.onLimited(r)
+				}
+				w.Header().Set("Retry-After", "60")
+				WriteError(w, http.StatusTooManyRequests,
+					"RATE_LIMIT_EXCEEDED", "Too many requests, please try again later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalKnownEncodings(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"true", true, []byte{0xc3}},
+		{"positive fixint", 7, []byte{0x07}},
+		{"negative fixint", -1, []byte{0xff}},
+		{"uint8", 200, []byte{0xcc, 0xc8}},
+		{"int8", -100, []byte{0xd0, 0x9c}},
+		{"uint16", 1000, []byte{0xcd, 0x03, 0xe8}},
+		{"json number integer", json.Number("42"), []byte{0x2a}},
+		{"json number float", json.Number("1.5"), []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"fixstr", "hi", []byte{0xa2, 'h', 'i'}},
+		{"bin", []byte{1, 2}, []byte{0xc4, 0x02, 1, 2}},
+		{"fixarray", []any{1, "a"}, []byte{0x92, 0x01, 0xa1, 'a'}},
+		{"fixmap with sorted keys", map[string]any{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.in)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal(%v) = % x, want % x", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	many := make([]any, 20)
+	for i := range many {
+		many[i] = int64(i)
+	}
+	in := map[string]any{
+		"success": true,
+		"data": map[string]any{
+			"id":       "ord-1",
+			"total":    19.99,
+			"count":    int64(-40000),
+			"big":      uint64(math.MaxUint64),
+			"min":      int64(math.MinInt64),
+			"items":    many,
+			"nothing":  nil,
+			"raw":      []byte("bytes"),
+			"long":     long,
+			"str8":     strings.Repeat("y", 40),
+			"nested":   map[string]any{"deep": []any{map[string]any{}}},
+			"negative": int64(-20),
+		},
+	}
+
+	encoded, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	out, err := Unmarshal(encoded)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip mismatch:\n got %#v\nwant %#v", out, in)
+	}
+}
+
+func TestUnmarshalRejectsMalformedInput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"empty", nil},
+		{"truncated string", []byte{0xa5, 'a'}},
+		{"array longer than input", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}},
+		{"map longer than input", []byte{0xdf, 0x7f, 0xff, 0xff, 0xff, 0x01}},
+		{"integer map key", []byte{0x81, 0x01, 0x02}},
+		{"extension type", []byte{0xd4, 0x01, 0x00}},
+		{"trailing bytes", []byte{0xc0, 0xc0}},
+		{"too deep", bytes.Repeat([]byte{0x91}, maxDepth+2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Unmarshal(tt.in); !errors.Is(err, ErrMalformed) {
+				t.Errorf("Unmarshal(% x) error = %v, want ErrMalformed", tt.in, err)
+			}
+		})
+	}
+}
+
+func TestMarshalRejectsUnsupportedTypes(t *testing.T) {
+	if _, err := Marshal(struct{ A int }{1}); err == nil {
+		t.Error("expected an error for a struct")
+	}
+	if _, err := Marshal(map[string]any{"n": json.Number("nope")}); err == nil {
+		t.Error("expected an error for an invalid json.Number")
+	}
+}
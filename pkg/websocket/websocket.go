@@ -0,0 +1,442 @@
+// Package websocket implements the server side of the WebSocket protocol
+// (RFC 6455) on top of net/http: the opening handshake, message framing with
+// fragmentation, ping/pong and the closing handshake. Extensions and
+// subprotocols are not supported.
+//
+// A Conn supports one concurrent reader and any number of concurrent writers.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types (frame opcodes)
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes (RFC 6455 section 7.4.1)
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatusReceived   = 1005 // Never sent; reported when a close frame has no code
+	CloseInvalidPayloadData = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseInternalServerErr  = 1011
+	CloseTryAgainLater      = 1013
+)
+
+// acceptGUID is appended to the client's key to compute Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxControlPayload is the largest payload a control frame may carry
+const maxControlPayload = 125
+
+var (
+	// ErrBadHandshake is returned by Upgrade for requests that are not a valid
+	// WebSocket opening handshake; nothing has been written to the response
+	ErrBadHandshake = errors.New("websocket: not a valid upgrade request")
+	// ErrCloseSent is returned when writing after the close frame was sent
+	ErrCloseSent = errors.New("websocket: close sent")
+)
+
+// CloseError is returned by ReadMessage once the peer closed the connection
+// or the connection was closed for a protocol violation
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("websocket: close %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: close %d: %s", e.Code, e.Text)
+}
+
+// IsUpgrade reports whether r asks to switch to the WebSocket protocol
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// Option configures a Conn created by Upgrade
+type Option func(*Conn)
+
+// WithReadLimit caps the size of a received message in bytes (default 64KB);
+// larger messages close the connection with CloseMessageTooBig
+func WithReadLimit(n int64) Option {
+	return func(c *Conn) {
+		if n > 0 {
+			c.readLimit = n
+		}
+	}
+}
+
+// WithWriteTimeout bounds every write (default 10s), so a client that stops
+// reading cannot block its writers forever
+func WithWriteTimeout(d time.Duration) Option {
+	return func(c *Conn) {
+		if d > 0 {
+			c.writeTimeout = d
+		}
+	}
+}
+
+// Conn is a server-side WebSocket connection
+type Conn struct {
+	conn         net.Conn
+	br           *bufio.Reader
+	readLimit    int64
+	writeTimeout time.Duration
+	pongHandler  func(appData string)
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+// Upgrade completes the opening handshake and takes over the connection.
+// Authentication and origin checks belong to the caller and must happen
+// before Upgrade. On ErrBadHandshake the caller still owns the response.
+func Upgrade(w http.ResponseWriter, r *http.Request, opts ...Option) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !IsUpgrade(r) ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || !validKey(key) {
+		return nil, ErrBadHandshake
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: hijack failed: %w", err)
+	}
+	// Drop the server's request deadlines, the connection now lives on its own
+	netConn.SetDeadline(time.Time{})
+
+	c := &Conn{
+		conn:         netConn,
+		br:           brw.Reader,
+		readLimit:    64 << 10,
+		writeTimeout: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	netConn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("websocket: handshake failed: %w", err)
+	}
+	netConn.SetWriteDeadline(time.Time{})
+	return c, nil
+}
+
+// SetPongHandler sets the function called for every pong received; it runs
+// on the reading goroutine
+func (c *Conn) SetPongHandler(h func(appData string)) {
+	c.pongHandler = h
+}
+
+// SetReadDeadline fails reads after t; a zero t never times out
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// RemoteAddr returns the client's network address
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered
+// and close frames echoed while reading. Once the peer closes, or breaks the
+// protocol, the error is a *CloseError and the connection must be closed.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			if err := c.WriteControl(PongMessage, f.payload); err != nil && !errors.Is(err, ErrCloseSent) {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				c.pongHandler(string(f.payload))
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = f.opcode
+		}
+
+		if int64(len(data))+int64(len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		data = append(data, f.payload...)
+
+		if f.fin {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, c.fail(CloseInvalidPayloadData, "invalid UTF-8")
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+// WriteMessage sends a text or binary message in a single frame
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteControl sends a ping or pong frame
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("websocket: invalid control message type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control payload too long")
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// Ping sends a ping; the client's pong reaches the pong handler
+func (c *Conn) Ping() error {
+	return c.WriteControl(PingMessage, nil)
+}
+
+// WriteClose starts the closing handshake. Further writes fail with
+// ErrCloseSent; keep reading until ReadMessage returns the client's reply
+// (or a read deadline passes), then call Close.
+func (c *Conn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	c.closeSent = true
+	return c.writeFrameLocked(CloseMessage, payload)
+}
+
+// Close closes the network connection without a closing handshake
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Framing
+// ═══════════════════════════════════════════════════════════════════════════════
+
+type frame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+// readFrame reads and unmasks one frame, enforcing the rules for client frames
+func (c *Conn) readFrame() (*frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return nil, err
+	}
+
+	f := &frame{fin: header[0]&0x80 != 0, opcode: int(header[0] & 0x0f)}
+	if header[0]&0x70 != 0 {
+		return nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	if header[1]&0x80 == 0 {
+		return nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	switch f.opcode {
+	case continuationFrame, TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if !f.fin {
+			return nil, c.fail(CloseProtocolError, "fragmented control frame")
+		}
+	default:
+		return nil, c.fail(CloseProtocolError, "unknown opcode")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if f.opcode >= CloseMessage && length > maxControlPayload {
+		return nil, c.fail(CloseProtocolError, "control frame too long")
+	}
+	if length > uint64(c.readLimit) {
+		return nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return nil, err
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return nil, err
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// writeFrameLocked writes one unmasked final frame; c.writeMu must be held
+func (c *Conn) writeFrameLocked(opcode int, payload []byte) error {
+	buf := make([]byte, 0, 10+len(payload))
+	buf = append(buf, 0x80|byte(opcode))
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	buf = append(buf, payload...)
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// handleClose answers the peer's close frame and reports it
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return c.fail(CloseInvalidPayloadData, "invalid UTF-8")
+		}
+	}
+
+	reply := closeErr.Code
+	if reply == CloseNoStatusReceived {
+		reply = CloseNormalClosure
+	}
+	if err := c.WriteClose(reply, ""); err != nil && !errors.Is(err, ErrCloseSent) {
+		return err
+	}
+	return closeErr
+}
+
+// fail sends a close frame for a protocol violation and returns it as an error
+func (c *Conn) fail(code int, text string) error {
+	c.WriteClose(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+// ═══════════════════════════════════════════════════════════════════════════════
+// Handshake helpers
+// ═══════════════════════════════════════════════════════════════════════════════
+
+// acceptKey computes the Sec-WebSocket-Accept value for a client key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// validKey reports whether key is a base64-encoded 16-byte nonce
+func validKey(key string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(decoded) == 16
+}
+
+// validCloseCode reports whether a peer may send code in a close frame
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// headerContains reports whether a comma-separated header lists token
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient speaks just enough of the client side to drive a Conn
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// dial upgrades a connection to srv and checks the handshake response
+func dial(t *testing.T, srv *httptest.Server) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := "GET / HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + testKey + "\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	// The accept value from the RFC 6455 example
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return &testClient{t: t, conn: conn, br: br}
+}
+
+// send writes one masked client frame
+func (c *testClient) send(fin bool, opcode int, payload []byte) {
+	c.t.Helper()
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	buf := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, 0x80|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 0x80|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0x80|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	buf = append(buf, mask...)
+	for i, b := range payload {
+		buf = append(buf, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+// recv reads one server frame, which must be unmasked and final
+func (c *testClient) recv() (int, []byte) {
+	c.t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		c.t.Fatalf("recv: %v", err)
+	}
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		c.t.Fatalf("server frame header %08b %08b, want final and unmasked", header[0], header[1])
+	}
+	n := int(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatalf("recv payload: %v", err)
+	}
+	return int(header[0] & 0x0f), payload
+}
+
+// expectClose reads a close frame and checks its code
+func (c *testClient) expectClose(code int) {
+	c.t.Helper()
+	op, payload := c.recv()
+	if op != CloseMessage || len(payload) < 2 {
+		c.t.Fatalf("got opcode %d payload %q, want close frame", op, payload)
+	}
+	if got := int(binary.BigEndian.Uint16(payload)); got != code {
+		c.t.Fatalf("close code = %d, want %d", got, code)
+	}
+}
+
+// serve runs handle on every upgraded connection and reports its error
+func serve(t *testing.T, handle func(*Conn) error, opts ...Option) (*httptest.Server, <-chan error) {
+	t.Helper()
+	errs := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r, opts...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer c.Close()
+		errs <- handle(c)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, errs
+}
+
+func TestEchoWithFragmentsAndPing(t *testing.T) {
+	srv, errs := serve(t, func(c *Conn) error {
+		for {
+			op, data, err := c.ReadMessage()
+			if err != nil {
+				return err
+			}
+			if err := c.WriteMessage(op, data); err != nil {
+				return err
+			}
+		}
+	})
+	client := dial(t, srv)
+
+	// A fragmented text message with a ping in the middle
+	client.send(false, TextMessage, []byte("hel"))
+	client.send(true, PingMessage, []byte("p"))
+	client.send(true, continuationFrame, []byte("lo"))
+	if op, payload := client.recv(); op != PongMessage || string(payload) != "p" {
+		t.Fatalf("got opcode %d %q, want pong", op, payload)
+	}
+	if op, payload := client.recv(); op != TextMessage || string(payload) != "hello" {
+		t.Fatalf("got opcode %d %q, want echoed text", op, payload)
+	}
+
+	big := []byte(strings.Repeat("x", 60000))
+	client.send(true, BinaryMessage, big)
+	if op, payload := client.recv(); op != BinaryMessage || len(payload) != len(big) {
+		t.Fatalf("got opcode %d with %d bytes, want %d binary bytes", op, len(payload), len(big))
+	}
+
+	client.send(true, CloseMessage, []byte{0x03, 0xe8})
+	client.expectClose(CloseNormalClosure)
+
+	var closeErr *CloseError
+	if err := <-errs; !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure {
+		t.Errorf("handler error = %v, want close 1000", err)
+	}
+}
+
+func TestProtocolViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(*testClient)
+		code int
+	}{
+		{"unmasked frame", func(c *testClient) { c.conn.Write([]byte{0x81, 0x00}) }, CloseProtocolError},
+		{"unexpected continuation", func(c *testClient) { c.send(true, continuationFrame, []byte("x")) }, CloseProtocolError},
+		{"interleaved data frames", func(c *testClient) {
+			c.send(false, TextMessage, []byte("a"))
+			c.send(true, TextMessage, []byte("b"))
+		}, CloseProtocolError},
+		{"fragmented ping", func(c *testClient) { c.send(false, PingMessage, nil) }, CloseProtocolError},
+		{"unknown opcode", func(c *testClient) { c.send(true, 3, nil) }, CloseProtocolError},
+		{"invalid close code", func(c *testClient) { c.send(true, CloseMessage, []byte{0x03, 0xed}) }, CloseProtocolError},
+		{"invalid UTF-8", func(c *testClient) { c.send(true, TextMessage, []byte{0xff, 0xfe}) }, CloseInvalidPayloadData},
+		{"message too big", func(c *testClient) { c.send(true, BinaryMessage, make([]byte, 2000)) }, CloseMessageTooBig},
+		{"fragments too big", func(c *testClient) {
+			c.send(false, BinaryMessage, make([]byte, 600))
+			c.send(true, continuationFrame, make([]byte, 600))
+		}, CloseMessageTooBig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, errs := serve(t, func(c *Conn) error {
+				_, _, err := c.ReadMessage()
+				return err
+			}, WithReadLimit(1024))
+			client := dial(t, srv)
+
+			tt.send(client)
+			client.expectClose(tt.code)
+
+			var closeErr *CloseError
+			if err := <-errs; !errors.As(err, &closeErr) || closeErr.Code != tt.code {
+				t.Errorf("ReadMessage() error = %v, want close %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestServerInitiatedClose(t *testing.T) {
+	pongs := make(chan string, 1)
+	srv, errs := serve(t, func(c *Conn) error {
+		c.SetPongHandler(func(data string) { pongs <- data })
+		if err := c.Ping(); err != nil {
+			return err
+		}
+		if _, _, err := c.ReadMessage(); err != nil {
+			return err
+		}
+		if err := c.WriteClose(CloseTryAgainLater, "slow"); err != nil {
+			return err
+		}
+		if err := c.WriteMessage(TextMessage, []byte("late")); !errors.Is(err, ErrCloseSent) {
+			t.Errorf("WriteMessage() after close error = %v, want ErrCloseSent", err)
+		}
+		_, _, err := c.ReadMessage()
+		return err
+	})
+	client := dial(t, srv)
+
+	if op, _ := client.recv(); op != PingMessage {
+		t.Fatalf("got opcode %d, want ping", op)
+	}
+	client.send(true, PongMessage, []byte("ok"))
+	client.send(true, TextMessage, []byte("go"))
+	client.expectClose(CloseTryAgainLater)
+	client.send(true, CloseMessage, []byte{0x03, 0xf5})
+
+	var closeErr *CloseError
+	if err := <-errs; !errors.As(err, &closeErr) || closeErr.Code != CloseTryAgainLater {
+		t.Errorf("ReadMessage() error = %v, want the echoed close 1013", err)
+	}
+	if got := <-pongs; got != "ok" {
+		t.Errorf("pong handler got %q, want %q", got, "ok")
+	}
+}
+
+func TestUpgradeRejectsBadHandshakes(t *testing.T) {
+	valid := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", testKey)
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*http.Request)
+	}{
+		{"POST", func(r *http.Request) { r.Method = http.MethodPost }},
+		{"no upgrade header", func(r *http.Request) { r.Header.Del("Upgrade") }},
+		{"old version", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }},
+		{"short key", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(r)
+			w := httptest.NewRecorder()
+			if _, err := Upgrade(w, r); !errors.Is(err, ErrBadHandshake) {
+				t.Errorf("Upgrade() error = %v, want ErrBadHandshake", err)
+			}
+			if w.Code != http.StatusOK || w.Body.Len() != 0 {
+				t.Error("Upgrade() must not write a response on a bad handshake")
+			}
+		})
+	}
+
+	if !IsUpgrade(valid()) {
+		t.Error("IsUpgrade() = false for a valid handshake")
+	}
+}